@@ -147,11 +147,11 @@ func (r *ApplicationsACL) ExportApp(req ExportAppRequest) error {
 	return r.applications.ExportApp(req)
 }
 
-func (r *ApplicationsACL) UninstallApp(locator loc.Locator) (*Application, error) {
-	if err := r.checkApp(locator, teleservices.VerbRead); err != nil {
+func (r *ApplicationsACL) UninstallApp(req UninstallAppRequest) (*Application, error) {
+	if err := r.checkApp(req.Package, teleservices.VerbRead); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return r.applications.UninstallApp(locator)
+	return r.applications.UninstallApp(req)
 }
 
 func (r *ApplicationsACL) StatusApp(locator loc.Locator) (*Status, error) {
@@ -243,13 +243,88 @@ func (r *ApplicationsACL) FetchIndexFile() (io.Reader, error) {
 	return r.applications.FetchIndexFile()
 }
 
+// PublishApp publishes an existing application to the catalog.
+// The user must be allowed to publish to the application repository
+func (r *ApplicationsACL) PublishApp(req PublishAppRequest) (*storage.CatalogEntry, error) {
+	if err := r.checkPublish(req.Package.Repository); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := r.checkApp(req.Package, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	req.PublishedBy = r.user.GetName()
+	return r.applications.PublishApp(req)
+}
+
+// UnpublishApp removes the specified application from the catalog
+func (r *ApplicationsACL) UnpublishApp(locator loc.Locator) error {
+	if err := r.checkPublish(locator.Repository); err != nil {
+		return trace.Wrap(err)
+	}
+	return r.applications.UnpublishApp(locator)
+}
+
+// SearchCatalog returns the published applications matching the request.
+// Applications from repositories the user cannot list are omitted
+// when searching all repositories
+func (r *ApplicationsACL) SearchCatalog(req SearchCatalogRequest) ([]CatalogApp, error) {
+	if req.Repository != "" {
+		if err := r.check(req.Repository, teleservices.VerbList); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return r.applications.SearchCatalog(req)
+	}
+	apps, err := r.applications.SearchCatalog(req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	allowed := make(map[string]bool)
+	var result []CatalogApp
+	for _, app := range apps {
+		if _, ok := allowed[app.Repository]; !ok {
+			allowed[app.Repository] = r.check(app.Repository, teleservices.VerbList) == nil
+		}
+		if allowed[app.Repository] {
+			result = append(result, app)
+		}
+	}
+	return result, nil
+}
+
 // check checks whether the user has the requested permissions to read write apps
 func (r *ApplicationsACL) check(repoName, verb string) error {
-	return r.checker.CheckAccessToRule(r.repoContext(repoName), teledefaults.Namespace, storage.KindApp, verb, false)
+	err := r.checker.CheckAccessToRule(r.repoContext(repoName), teledefaults.Namespace, storage.KindApp, verb, false)
+	if err != nil {
+		return r.checkRepository(repoName, verb, err)
+	}
+	return nil
 }
 
 // checkApp checks whether the user has the requested permissions to the specified app
 func (r *ApplicationsACL) checkApp(locator loc.Locator, verb string) error {
-	return r.checker.CheckAccessToRule(r.appContext(locator),
+	err := r.checker.CheckAccessToRule(r.appContext(locator),
 		teledefaults.Namespace, storage.KindApp, verb, false)
+	if err != nil {
+		return r.checkRepository(locator.Repository, verb, err)
+	}
+	return nil
+}
+
+// checkRepository checks whether the user has the requested permissions
+// to the repository the apps belong to, i.e. granted by the repository
+// permissions. Returns the original access error otherwise
+func (r *ApplicationsACL) checkRepository(repoName, verb string, appErr error) error {
+	err := r.checker.CheckAccessToRule(r.repoContext(repoName),
+		teledefaults.Namespace, storage.KindRepository, verb, true)
+	if err != nil {
+		return trace.Wrap(appErr)
+	}
+	return nil
+}
+
+// checkPublish checks whether the user is allowed to publish applications
+// to the catalog of the specified repository
+func (r *ApplicationsACL) checkPublish(repoName string) error {
+	return r.checker.CheckAccessToRule(r.repoContext(repoName),
+		teledefaults.Namespace, storage.KindRepository, storage.VerbPublish, false)
 }
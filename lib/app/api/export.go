@@ -25,4 +25,6 @@ type ExportConfig struct {
 	// packages located on the given master node usually as part
 	// of a cluster expand operation.
 	RegistryHostPort string `json:"registryHostPort"`
+	// Parallel defines the number of image layers to push concurrently
+	Parallel int `json:"parallel,omitempty"`
 }
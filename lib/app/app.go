@@ -50,6 +50,7 @@ const (
 // Applications manages a collection of applications
 type Applications interface {
 	Operations
+	Catalog
 
 	// ListApps lists applications of given type in the specified repository.
 	// If the repository is empty, all applications are listed.
@@ -68,7 +69,7 @@ type Applications interface {
 	ExportApp(ExportAppRequest) error
 
 	// UninstallApp uninstalls a running application from the local cluster
-	UninstallApp(locator loc.Locator) (*Application, error)
+	UninstallApp(UninstallAppRequest) (*Application, error)
 
 	// StatusApp retrieves the status of a running application
 	StatusApp(locator loc.Locator) (*Status, error)
@@ -150,6 +151,15 @@ func (r ListAppsRequest) Check() error {
 	return nil
 }
 
+// UninstallAppRequest defines a request to uninstall an application
+type UninstallAppRequest struct {
+	// Package is the application package to uninstall
+	Package loc.Locator `json:"package"`
+	// Force forcibly finalizes the namespaces and persistent volumes of
+	// the application that have not been removed by its uninstall hooks
+	Force bool `json:"force"`
+}
+
 // ExportAppRequest defines a set of parameters for exporting an application to a docker registry
 type ExportAppRequest struct {
 	// Package represents the package name and version to be exported
@@ -163,6 +173,11 @@ type ExportAppRequest struct {
 
 	// CertName represents the name of the certificate to use for TLS connecting to the registry
 	CertName string
+
+	// Parallel defines the number of image layers to push concurrently.
+	// If < 0, the number of concurrent pushes is not restricted,
+	// if unspecified, it defaults to the number of logical CPU cores
+	Parallel int
 }
 
 // Operations defines a set of operations on applications
@@ -272,6 +287,26 @@ type InstallerRequest struct {
 	CACert string `json:"ca_cert,omitempty"`
 	// EncryptionKey is encryption key to encrypt installer packages with
 	EncryptionKey string `json:"encryption_key,omitempty"`
+	// SigningKey is the optional PEM-encoded private key to sign the installer with.
+	// It is never sent over the wire
+	SigningKey []byte `json:"-"`
+	// Base optionally specifies the application to generate a delta installer
+	// against. It is never sent over the wire
+	Base *InstallerBase `json:"-"`
+}
+
+// InstallerBase describes the application a delta installer is generated against.
+//
+// The delta installer only contains packages and docker image layers that
+// the base application does not have and can only be used to upgrade clusters
+// running the base application
+type InstallerBase struct {
+	// Application is the base application package
+	Application loc.Locator
+	// Apps is the application service with the base application
+	Apps Applications
+	// Packages is the package service with the base application packages
+	Packages pack.PackageService
 }
 
 // Check validates this request
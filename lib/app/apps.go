@@ -17,16 +17,22 @@ limitations under the License.
 package app
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/gravitational/gravity/lib/app/hooks"
+	"github.com/gravitational/gravity/lib/app/resources"
+	"github.com/gravitational/gravity/lib/archive"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/utils"
 
+	dockerarchive "github.com/docker/docker/pkg/archive"
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/client-go/kubernetes"
@@ -104,6 +110,39 @@ func CheckHasAppHook(apps Applications, req HookRunRequest) (*schema.Hook, error
 	return hook, nil
 }
 
+// RunHookWithPolicy runs the hook with the provided function, retrying it
+// as many times as the hook specifies. If all attempts fail, the error
+// is only returned if the hook's failure policy is to abort the operation
+func RunHookWithPolicy(ctx context.Context, hook schema.Hook, hookType schema.HookType, logger log.FieldLogger, fn func() error) error {
+	return runHookWithPolicy(ctx, hook, hookType, defaults.HookRetryInterval, logger, fn)
+}
+
+func runHookWithPolicy(ctx context.Context, hook schema.Hook, hookType schema.HookType, interval time.Duration, logger log.FieldLogger, fn func() error) (err error) {
+	attempts := hook.Attempts()
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		logger.Warnf("Hook %v failed (attempt %v/%v), retry in %v: %v.",
+			hookType, attempt, attempts, interval, trace.UserMessage(err))
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return trace.Wrap(ctx.Err())
+		}
+	}
+	if hook.ContinueOnFailure() {
+		logger.Warnf("Hook %v failed, continuing as its failure policy is %q: %v.",
+			hookType, hook.OnFailure, trace.UserMessage(err))
+		return nil
+	}
+	return trace.Wrap(err)
+}
+
 // WaitAppHook waits for app hook to complete or fail
 func WaitAppHook(ctx context.Context, client *kubernetes.Clientset, ref HookRef) error {
 	runner, err := hooks.NewRunner(client)
@@ -168,6 +207,139 @@ func GetUpdatedDependencies(installed, update Application) ([]loc.Locator, error
 	return updates, nil
 }
 
+// Getter returns applications by locator
+type Getter interface {
+	// GetApp returns the application with the specified locator
+	GetApp(loc.Locator) (*Application, error)
+}
+
+// DependencyDiff describes an updated application along with
+// the updated applications it depends on
+type DependencyDiff struct {
+	// Installed is the installed application, nil if the application is new
+	Installed *loc.Locator `json:"installed,omitempty"`
+	// Update is the update application
+	Update loc.Locator `json:"update"`
+	// Dependencies lists the updated dependencies of the application
+	Dependencies []DependencyDiff `json:"dependencies,omitempty"`
+}
+
+// IsUpdated returns true if the application is new or its version has changed
+func (r DependencyDiff) IsUpdated() bool {
+	return r.Installed == nil || !r.Installed.IsEqualTo(r.Update)
+}
+
+// Updates returns the locators of all updated applications in the tree.
+// Each application is listed once and after all of its updated dependencies
+func (r DependencyDiff) Updates() (updates []loc.Locator) {
+	seen := make(map[string]struct{})
+	var walk func(DependencyDiff)
+	walk = func(diff DependencyDiff) {
+		for _, dep := range diff.Dependencies {
+			walk(dep)
+		}
+		if _, ok := seen[diff.Update.String()]; ok || !diff.IsUpdated() {
+			return
+		}
+		seen[diff.Update.String()] = struct{}{}
+		updates = append(updates, diff.Update)
+	}
+	walk(r)
+	return updates
+}
+
+// String returns a textual representation of the diff tree
+func (r DependencyDiff) String() string {
+	var buf bytes.Buffer
+	r.format(&buf, "")
+	return buf.String()
+}
+
+func (r DependencyDiff) format(w io.Writer, indent string) {
+	if r.Installed == nil {
+		fmt.Fprintf(w, "%v%v (new)\n", indent, r.Update)
+	} else {
+		fmt.Fprintf(w, "%v%v -> %v\n", indent, r.Installed, r.Update.Version)
+	}
+	for _, dep := range r.Dependencies {
+		dep.format(w, indent+"  ")
+	}
+}
+
+// GetUpdatedDependenciesRecursive compares dependencies of the "installed" and "update"
+// apps at any depth and returns the tree of updated (or new) dependencies.
+//
+// Manifests of the nested dependencies are retrieved with the provided getter.
+// Dependencies which have not changed are not walked as their own dependencies
+// cannot change either. If an installed dependency is not available, all its
+// dependencies in the update are considered new
+func GetUpdatedDependenciesRecursive(apps Getter, installed, update Application) (*DependencyDiff, error) {
+	if installed.Package.IsEqualTo(update.Package) {
+		return nil, trace.NotFound("no update for %v", update)
+	}
+	diff, err := diffDependencies(apps, &installed, update, make(map[string]struct{}))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return diff, nil
+}
+
+func diffDependencies(apps Getter, installed *Application, update Application, seen map[string]struct{}) (*DependencyDiff, error) {
+	diff := DependencyDiff{Update: update.Package}
+	var installedDeps []loc.Locator
+	if installed != nil {
+		diff.Installed = &installed.Package
+		manifest, err := schema.ParseManifestYAMLNoValidate(installed.PackageEnvelope.Manifest)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		installedDeps = manifest.Dependencies.GetApps()
+	}
+	manifest, err := schema.ParseManifestYAMLNoValidate(update.PackageEnvelope.Manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, dep := range manifest.Dependencies.GetApps() {
+		isUpdate, err := loc.IsUpdate(dep, installedDeps)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if _, ok := seen[dep.String()]; ok || !isUpdate {
+			continue
+		}
+		seen[dep.String()] = struct{}{}
+		depUpdate, err := apps.GetApp(dep)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		var depInstalled *Application
+		installedDep := findSameApp(dep, installedDeps)
+		if installedDep != nil {
+			depInstalled, err = apps.GetApp(*installedDep)
+			if err != nil && !trace.IsNotFound(err) {
+				return nil, trace.Wrap(err)
+			}
+		}
+		depDiff, err := diffDependencies(apps, depInstalled, *depUpdate, seen)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		depDiff.Installed = installedDep
+		diff.Dependencies = append(diff.Dependencies, *depDiff)
+	}
+	return &diff, nil
+}
+
+func findSameApp(app loc.Locator, apps []loc.Locator) *loc.Locator {
+	for _, other := range apps {
+		if loc.IsSameApp(app, other) {
+			other := other
+			return &other
+		}
+	}
+	return nil
+}
+
 // GetDirectDeps returns the direct application dependencies, without
 // base app resolution
 func GetDirectDeps(app Application) ([]loc.Locator, error) {
@@ -177,3 +349,26 @@ func GetDirectDeps(app Application) ([]loc.Locator, error) {
 	}
 	return append(manifest.Dependencies.GetApps(), app.Package), nil
 }
+
+// ForEachResource invokes the provided function for each Kubernetes object
+// from the resources file of the specified application
+func ForEachResource(apps Applications, locator loc.Locator, fn resources.ResourceFunc) error {
+	reader, err := apps.GetAppResources(locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	stream, err := dockerarchive.DecompressStream(reader)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer stream.Close()
+	err = archive.TarGlob(
+		tar.NewReader(stream),
+		defaults.ResourcesDir,
+		[]string{defaults.ResourcesFile},
+		func(_ string, reader io.Reader) error {
+			return resources.ForEachObject(reader, fn)
+		})
+	return trace.Wrap(err)
+}
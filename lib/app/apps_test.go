@@ -17,12 +17,16 @@ limitations under the License.
 package app
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/schema"
 
 	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
 	. "gopkg.in/check.v1"
 )
 
@@ -58,6 +62,108 @@ func (s *AppUtilsSuite) TestUpdatedDependencies(c *C) {
 	c.Assert(updates, DeepEquals, []loc.Locator(nil))
 }
 
+func (s *AppUtilsSuite) TestUpdatedDependenciesRecursive(c *C) {
+	apps := testApps{}
+	for locator, manifest := range map[string]string{
+		"repo/app:1.0.0":    app1Manifest,
+		"repo/app:2.0.0":    app2Manifest,
+		"repo/dep-1:1.0.0":  depManifest("dep-1", "1.0.0"),
+		"repo/dep-2:1.0.0":  depManifest("dep-2", "1.0.0", "repo/nested:1.0.0"),
+		"repo/dep-2:2.0.0":  depManifest("dep-2", "2.0.0", "repo/nested:2.0.0", "repo/new:1.0.0"),
+		"repo/nested:1.0.0": depManifest("nested", "1.0.0"),
+		"repo/nested:2.0.0": depManifest("nested", "2.0.0"),
+		"repo/new:1.0.0":    depManifest("new", "1.0.0"),
+	} {
+		apps[locator] = Application{
+			Package:         loc.MustParseLocator(locator),
+			PackageEnvelope: pack.PackageEnvelope{Manifest: []byte(manifest)},
+		}
+	}
+
+	diff, err := GetUpdatedDependenciesRecursive(apps, apps["repo/app:1.0.0"], apps["repo/app:2.0.0"])
+	c.Assert(err, IsNil)
+	c.Assert(diff, DeepEquals, &DependencyDiff{
+		Installed: newLocator("repo/app:1.0.0"),
+		Update:    loc.MustParseLocator("repo/app:2.0.0"),
+		Dependencies: []DependencyDiff{{
+			Installed: newLocator("repo/dep-2:1.0.0"),
+			Update:    loc.MustParseLocator("repo/dep-2:2.0.0"),
+			Dependencies: []DependencyDiff{
+				{
+					Installed: newLocator("repo/nested:1.0.0"),
+					Update:    loc.MustParseLocator("repo/nested:2.0.0"),
+				},
+				{
+					Update: loc.MustParseLocator("repo/new:1.0.0"),
+				},
+			},
+		}},
+	})
+	c.Assert(diff.Updates(), DeepEquals, []loc.Locator{
+		loc.MustParseLocator("repo/nested:2.0.0"),
+		loc.MustParseLocator("repo/new:1.0.0"),
+		loc.MustParseLocator("repo/dep-2:2.0.0"),
+		loc.MustParseLocator("repo/app:2.0.0"),
+	})
+
+	_, err = GetUpdatedDependenciesRecursive(apps, apps["repo/app:1.0.0"], apps["repo/app:1.0.0"])
+	c.Assert(trace.IsNotFound(err), Equals, true)
+}
+
+func (s *AppUtilsSuite) TestHookPolicy(c *C) {
+	var tests = []struct {
+		hook     schema.Hook
+		failures int
+		calls    int
+		err      bool
+		comment  string
+	}{
+		{
+			hook:     schema.Hook{},
+			failures: 1,
+			calls:    1,
+			err:      true,
+			comment:  "hook is not retried by default",
+		},
+		{
+			hook:     schema.Hook{Retries: 2},
+			failures: 2,
+			calls:    3,
+			comment:  "hook succeeds on the last attempt",
+		},
+		{
+			hook:     schema.Hook{Retries: 1, OnFailure: schema.HookFailureAbort},
+			failures: 2,
+			calls:    2,
+			err:      true,
+			comment:  "hook aborts the operation after all attempts failed",
+		},
+		{
+			hook:     schema.Hook{Retries: 1, OnFailure: schema.HookFailureContinue},
+			failures: 2,
+			calls:    2,
+			comment:  "hook failure is ignored",
+		},
+	}
+	for _, tc := range tests {
+		comment := Commentf(tc.comment)
+		var calls int
+		err := runHookWithPolicy(context.TODO(), tc.hook, schema.HookUpdate, 0, logrus.StandardLogger(), func() error {
+			calls++
+			if calls <= tc.failures {
+				return trace.BadParameter("hook failed")
+			}
+			return nil
+		})
+		c.Assert(calls, Equals, tc.calls, comment)
+		if tc.err {
+			c.Assert(err, NotNil, comment)
+		} else {
+			c.Assert(err, IsNil, comment)
+		}
+	}
+}
+
 const app1Manifest = `apiVersion: bundle.gravitational.io/v2
 kind: Bundle
 metadata:
@@ -77,3 +183,33 @@ dependencies:
   apps:
     - repo/dep-1:1.0.0
     - repo/dep-2:2.0.0`
+
+func depManifest(name, version string, deps ...string) string {
+	manifest := fmt.Sprintf(`apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: %v
+  resourceVersion: %v`, name, version)
+	if len(deps) != 0 {
+		manifest += "\ndependencies:\n  apps:"
+		for _, dep := range deps {
+			manifest += fmt.Sprintf("\n    - %v", dep)
+		}
+	}
+	return manifest
+}
+
+func newLocator(locator string) *loc.Locator {
+	result := loc.MustParseLocator(locator)
+	return &result
+}
+
+type testApps map[string]Application
+
+func (r testApps) GetApp(locator loc.Locator) (*Application, error) {
+	app, ok := r[locator.String()]
+	if !ok {
+		return nil, trace.NotFound("application %v not found", locator)
+	}
+	return &app, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/gravitational/trace"
+)
+
+// Catalog manages the applications published to the application catalog
+type Catalog interface {
+	// PublishApp publishes an existing application to the catalog
+	// or updates the catalog metadata of an already published one
+	PublishApp(PublishAppRequest) (*storage.CatalogEntry, error)
+	// UnpublishApp removes the specified application from the catalog.
+	// The application itself is not removed
+	UnpublishApp(loc.Locator) error
+	// SearchCatalog returns the published applications matching the request
+	SearchCatalog(SearchCatalogRequest) ([]CatalogApp, error)
+}
+
+// PublishAppRequest is a request to publish an application to the catalog
+type PublishAppRequest struct {
+	// Package is the application to publish
+	Package loc.Locator `json:"package"`
+	// Channel is the release channel to publish the application to.
+	// Defaults to beta for pre-release versions and to stable otherwise
+	Channel string `json:"channel,omitempty"`
+	// Icon is the application icon, either a URL or a data URI
+	Icon string `json:"icon,omitempty"`
+	// Readme is the application description in Markdown format
+	Readme string `json:"readme,omitempty"`
+	// Upgrade is the application upgrade metadata
+	Upgrade storage.CatalogUpgrade `json:"upgrade"`
+	// PublishedBy is the user publishing the application
+	PublishedBy string `json:"published_by,omitempty"`
+}
+
+// CheckAndSetDefaults validates the request and fills in defaults
+func (r *PublishAppRequest) CheckAndSetDefaults() error {
+	if r.Package.IsEmpty() {
+		return trace.BadParameter("missing application to publish")
+	}
+	version, err := semver.NewVersion(r.Package.Version)
+	if err != nil {
+		return trace.BadParameter("invalid application version %q: %v", r.Package.Version, err)
+	}
+	if r.Channel == "" {
+		r.Channel = storage.CatalogChannelStable
+		if version.PreRelease != "" {
+			r.Channel = storage.CatalogChannelBeta
+		}
+	}
+	if r.Channel == storage.CatalogChannelStable && version.PreRelease != "" {
+		return trace.BadParameter("pre-release version %v can only be published to the %v channel",
+			version, storage.CatalogChannelBeta)
+	}
+	if !utils.StringInSlice(storage.CatalogChannels, r.Channel) {
+		return trace.BadParameter("unsupported release channel %q, supported are: %v",
+			r.Channel, storage.CatalogChannels)
+	}
+	return nil
+}
+
+// SearchCatalogRequest is a request to search the application catalog
+type SearchCatalogRequest struct {
+	// Repository restricts the search to the specified repository.
+	// All repositories are searched if unspecified
+	Repository string `json:"repository,omitempty"`
+	// Pattern is an optional application name substring
+	Pattern string `json:"pattern,omitempty"`
+	// Channel restricts the search to the specified release channel
+	Channel string `json:"channel,omitempty"`
+}
+
+// Check validates the request
+func (r SearchCatalogRequest) Check() error {
+	if r.Channel != "" && !utils.StringInSlice(storage.CatalogChannels, r.Channel) {
+		return trace.BadParameter("unsupported release channel %q, supported are: %v",
+			r.Channel, storage.CatalogChannels)
+	}
+	return nil
+}
+
+// CatalogApp describes an application published to the catalog
+type CatalogApp struct {
+	// CatalogEntry is the catalog metadata of the application
+	storage.CatalogEntry
+	// Kind is the application kind, application or cluster image
+	Kind string `json:"kind"`
+	// Description is the application description from its manifest
+	Description string `json:"description,omitempty"`
+	// Labels is the application labels from its manifest
+	Labels map[string]string `json:"labels,omitempty"`
+	// Created is the time the application package was created
+	Created time.Time `json:"created"`
+}
+
+// NewCatalogApp returns a catalog application for the specified
+// catalog entry and application
+func NewCatalogApp(entry storage.CatalogEntry, app Application) CatalogApp {
+	return CatalogApp{
+		CatalogEntry: entry,
+		Kind:         app.Manifest.DescribeKind(),
+		Description:  app.Manifest.Metadata.Description,
+		Labels:       app.Manifest.Metadata.Labels,
+		Created:      app.PackageEnvelope.Created,
+	}
+}
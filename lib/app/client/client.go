@@ -282,10 +282,11 @@ func (c *Client) GetApp(locator loc.Locator) (*app.Application, error) {
 }
 
 // POST app/v1/operations/uninstall/:repository_id/:package_id/:version
-func (c *Client) UninstallApp(locator loc.Locator) (*app.Application, error) {
+func (c *Client) UninstallApp(req app.UninstallAppRequest) (*app.Application, error) {
 	out, err := c.PostJSON(c.Endpoint(
 		"operations", "uninstall",
-		locator.Repository, locator.Name, locator.Version), url.Values{})
+		req.Package.Repository, req.Package.Name, req.Package.Version),
+		&serviceapi.UninstallConfig{Force: req.Force})
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -311,6 +312,7 @@ func (c *Client) DeleteApp(req app.DeleteRequest) error {
 func (c *Client) ExportApp(req app.ExportAppRequest) error {
 	config := serviceapi.ExportConfig{
 		RegistryHostPort: req.RegistryAddress,
+		Parallel:         req.Parallel,
 	}
 	if _, err := c.PostJSON(c.Endpoint(
 		"operations", "export",
@@ -397,6 +399,50 @@ func (c *Client) FetchIndexFile() (io.Reader, error) {
 	return c.getFile(c.Endpoint("charts", "index.yaml"), url.Values{})
 }
 
+// PublishApp publishes an existing application to the catalog
+//
+// POST app/v1/catalog/:repository_id/:package_id/:version
+func (c *Client) PublishApp(req app.PublishAppRequest) (*storage.CatalogEntry, error) {
+	out, err := c.PostJSON(c.Endpoint("catalog",
+		req.Package.Repository, req.Package.Name, req.Package.Version), req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var entry storage.CatalogEntry
+	if err = json.Unmarshal(out.Bytes(), &entry); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &entry, nil
+}
+
+// UnpublishApp removes the specified application from the catalog
+//
+// DELETE app/v1/catalog/:repository_id/:package_id/:version
+func (c *Client) UnpublishApp(locator loc.Locator) error {
+	_, err := c.Delete(c.Endpoint("catalog",
+		locator.Repository, locator.Name, locator.Version), url.Values{})
+	return trace.Wrap(err)
+}
+
+// SearchCatalog returns the published applications matching the request
+//
+// GET app/v1/catalog/:repository_id
+func (c *Client) SearchCatalog(req app.SearchCatalogRequest) (apps []app.CatalogApp, err error) {
+	// repository may be empty, and if it is, there will be extra slashes in the endpoint
+	endpoint := strings.TrimRight(c.Endpoint("catalog", req.Repository), "/")
+	out, err := c.Get(endpoint, url.Values{
+		"pattern": []string{req.Pattern},
+		"channel": []string{req.Channel},
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err = json.Unmarshal(out.Bytes(), &apps); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return apps, nil
+}
+
 // POST app/v1/applications/:repository_id
 func (c *Client) CreateApp(locator loc.Locator, reader io.Reader, labels map[string]string) (*app.Application, error) {
 	return c.createApp(locator, nil, reader, labels, false)
@@ -24,16 +24,42 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// GetDependencies transitively collects dependencies for the specified application package
+// GetDependencies transitively collects dependencies for the specified application package.
+// The dependencies include the intermediate runtimes embedded in the application
+// along with their dependencies
 func GetDependencies(app *Application, apps Applications) (result *Dependencies, err error) {
+	result, err = getAllDependencies(app, apps)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, locator := range app.Manifest.IntermediateRuntimes() {
+		runtime, err := apps.GetApp(locator)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		deps, err := getAllDependencies(runtime, apps)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		result.Packages = append(result.Packages, deps.Packages...)
+		result.Apps = append(result.Apps, deps.Apps...)
+		result.Apps = append(result.Apps, locator)
+	}
+	result.Packages = loc.Deduplicate(result.Packages)
+	result.Apps = loc.Deduplicate(result.Apps)
+	return result, nil
+}
+
+// getAllDependencies transitively collects dependencies for the specified application package
+func getAllDependencies(app *Application, apps Applications) (*Dependencies, error) {
 	state := &state{
 		visitedPackages: map[string]struct{}{},
 		visitedApps:     map[string]struct{}{},
 	}
-	if err = getDependencies(app, apps, state); err != nil {
+	if err := getDependencies(app, apps, state); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	result = &Dependencies{}
+	result := &Dependencies{}
 	result.Packages = append(result.Packages, state.packages...)
 	if state.runtimePackage != nil {
 		result.Packages = append(result.Packages, *state.runtimePackage)
@@ -77,6 +103,58 @@ func VerifyDependencies(app *Application, apps Applications, packages pack.Packa
 	return nil
 }
 
+// GetMissingDependencies returns the dependencies of the specified application
+// that are not available in the provided services.
+//
+// The dependencies of a missing application dependency cannot be determined,
+// so only the application itself is reported for it
+func GetMissingDependencies(app *Application, apps Applications, packages pack.PackageService) (*Dependencies, error) {
+	state := &state{
+		visitedPackages: map[string]struct{}{},
+		visitedApps:     map[string]struct{}{},
+		collectMissing:  true,
+	}
+	if err := getDependencies(app, apps, state); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, locator := range app.Manifest.IntermediateRuntimes() {
+		runtime, err := apps.GetApp(locator)
+		if err != nil {
+			if !trace.IsNotFound(err) {
+				return nil, trace.Wrap(err)
+			}
+			state.missingApps = append(state.missingApps, locator)
+			continue
+		}
+		if err := getDependencies(runtime, apps, state); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	dependencies := state.packages
+	if state.runtimePackage != nil {
+		dependencies = append(dependencies, *state.runtimePackage)
+	}
+	missing := &Dependencies{
+		Apps: loc.Deduplicate(state.missingApps),
+	}
+	for _, dependency := range loc.Deduplicate(dependencies) {
+		_, err := packages.ReadPackageEnvelope(dependency)
+		if err == nil {
+			continue
+		}
+		if !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		missing.Packages = append(missing.Packages, dependency)
+	}
+	return missing, nil
+}
+
+// IsEmpty returns true if there are no dependencies
+func (r Dependencies) IsEmpty() bool {
+	return len(r.Packages) == 0 && len(r.Apps) == 0
+}
+
 // Dependencies defines a set of package and application dependencies
 // for an application
 type Dependencies struct {
@@ -108,6 +186,11 @@ func getDependencies(app *Application, apps Applications, state *state) error {
 		packageName := dependency.String()
 		if _, ok := state.visitedApps[packageName]; !ok {
 			app, err := apps.GetApp(dependency)
+			if err != nil && trace.IsNotFound(err) && state.collectMissing {
+				state.visitedApps[packageName] = struct{}{}
+				state.missingApps = append(state.missingApps, dependency)
+				continue
+			}
 			if err != nil {
 				return trace.Wrap(err)
 			}
@@ -146,4 +229,10 @@ type state struct {
 
 	visitedApps     map[string]struct{}
 	visitedPackages map[string]struct{}
+
+	// collectMissing specifies whether missing application dependencies
+	// are collected instead of failing the traversal
+	collectMissing bool
+	// missingApps lists application dependencies that have not been found
+	missingApps []loc.Locator
 }
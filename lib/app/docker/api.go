@@ -60,6 +60,9 @@ type ImageService interface {
 type DockerPuller interface {
 	// Pull pulls the specified image
 	Pull(image string) error
+	// PullPlatform pulls the variant of the specified image for the given
+	// platform, e.g. linux/arm64
+	PullPlatform(image, platform string) error
 	// IsImagePresent checks if the specified image is available locally
 	IsImagePresent(image string) (bool, error)
 }
@@ -0,0 +1,515 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/docker/distribution"
+	"github.com/gravitational/trace"
+	"github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
+)
+
+// ImageCacheConfig defines the configuration of the image cache
+type ImageCacheConfig struct {
+	// Dir is the cache directory
+	Dir string
+	// MaxSize is the size limit of the cache in bytes.
+	// The least recently used images are evicted when the cache is pruned
+	// and its size exceeds the limit
+	MaxSize uint64
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (r *ImageCacheConfig) CheckAndSetDefaults() error {
+	if r.Dir == "" {
+		return trace.BadParameter("missing Dir")
+	}
+	if r.MaxSize == 0 {
+		return trace.BadParameter("missing MaxSize")
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = logrus.WithField(trace.Component, "imagecache")
+	}
+	return nil
+}
+
+// NewImageCache returns a new image cache in the configured directory
+func NewImageCache(config ImageCacheConfig) (*ImageCache, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, dir := range []string{config.blobsDir(), config.imagesDir()} {
+		if err := os.MkdirAll(dir, defaults.PrivateDirMask); err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+	}
+	return &ImageCache{ImageCacheConfig: config}, nil
+}
+
+// ImageCache is a content-addressed cache of docker images exported
+// in the docker registry format.
+//
+// Image layers are stored once by digest and are shared between the cached
+// images. Images are looked up by their docker image ID so that an image
+// that has not changed between builds is not exported again
+type ImageCache struct {
+	// ImageCacheConfig is the cache configuration
+	ImageCacheConfig
+	// mu serializes pruning with other cache operations
+	mu sync.RWMutex
+}
+
+// Restore adds the image with the specified ID from the cache to the registry
+// in dir as repository:tag.
+//
+// Returns trace.NotFound if the image is not in the cache
+func (r *ImageCache) Restore(ctx context.Context, imageID, dir, repository, tag string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	record, err := r.readImage(imageID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	payload, err := ioutil.ReadFile(r.blobPath(record.Manifest))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	manifest, _, err := distribution.UnmarshalManifest(record.MediaType, payload)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, desc := range manifest.References() {
+		if _, err := os.Stat(r.blobPath(desc.Digest)); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	repo, err := openRepository(ctx, dir, repository)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	blobs := repo.Blobs(ctx)
+	for _, desc := range manifest.References() {
+		if _, err := blobs.Stat(ctx, desc.Digest); err == nil {
+			continue
+		}
+		if err := r.restoreBlob(ctx, blobs, desc); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	manifests, err := repo.Manifests(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	dgst, err := manifests.Put(ctx, manifest)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// Unlike the registry client, the storage manifest service ignores
+	// the tag option so the manifest needs to be tagged explicitly
+	err = repo.Tags(ctx).Tag(ctx, tag, distribution.Descriptor{Digest: dgst})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	r.touch(r.imagePath(imageID))
+	r.Debugf("Restored image %v as %v:%v.", imageID, repository, tag)
+	return nil
+}
+
+// Save stores the image repository:tag from the registry in dir in the cache
+// under the specified image ID
+func (r *ImageCache) Save(ctx context.Context, imageID, dir, repository, tag string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, err := digest.Parse(imageID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	repo, err := openRepository(ctx, dir, repository)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	desc, err := repo.Tags(ctx).Get(ctx, tag)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	manifests, err := repo.Manifests(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	manifest, err := manifests.Get(ctx, desc.Digest)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	blobs := repo.Blobs(ctx)
+	for _, desc := range manifest.References() {
+		if err := r.saveBlob(ctx, blobs, desc.Digest); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	mediaType, payload, err := manifest.Payload()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = r.writeBlob(desc.Digest, ioutil.NopCloser(bytes.NewReader(payload)))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	data, err := json.Marshal(cachedImage{
+		Manifest:  desc.Digest,
+		MediaType: mediaType,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = writeFileAtomic(r.imagePath(id.String()), data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	r.Debugf("Saved %v:%v as image %v.", repository, tag, imageID)
+	return nil
+}
+
+// Prune evicts the least recently used images from the cache until
+// the cache size is within the configured limit.
+//
+// Layers not referenced by any of the cached images are removed
+func (r *ImageCache) Prune() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	images, err := r.listImages()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	refs := make(map[digest.Digest]int)
+	for _, image := range images {
+		for _, dgst := range image.blobs {
+			refs[dgst]++
+		}
+	}
+	sizes, err := r.listBlobs()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var size uint64
+	for dgst, blobSize := range sizes {
+		if refs[dgst] != 0 {
+			size += blobSize
+			continue
+		}
+		if err := r.removeBlob(dgst); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	// Evict the least recently used images first
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].used.Before(images[j].used)
+	})
+	for _, image := range images {
+		if size <= r.MaxSize {
+			break
+		}
+		r.Debugf("Evicting image %v.", image.id)
+		err := os.Remove(r.imagePath(image.id))
+		if err != nil && !os.IsNotExist(err) {
+			return trace.ConvertSystemError(err)
+		}
+		for _, dgst := range image.blobs {
+			refs[dgst]--
+			if refs[dgst] != 0 {
+				continue
+			}
+			if err := r.removeBlob(dgst); err != nil {
+				return trace.Wrap(err)
+			}
+			size -= sizes[dgst]
+		}
+	}
+	return nil
+}
+
+// Size returns the total size of the cached blobs in bytes
+func (r *ImageCache) Size() (size uint64, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sizes, err := r.listBlobs()
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	for _, blobSize := range sizes {
+		size += blobSize
+	}
+	return size, nil
+}
+
+// Clear removes all images from the cache
+func (r *ImageCache) Clear() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, dir := range []string{r.blobsDir(), r.imagesDir()} {
+		if err := os.RemoveAll(dir); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		if err := os.MkdirAll(dir, defaults.PrivateDirMask); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	return nil
+}
+
+func (r *ImageCache) restoreBlob(ctx context.Context, blobs distribution.BlobStore, desc distribution.Descriptor) error {
+	f, err := os.Open(r.blobPath(desc.Digest))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	writer, err := blobs.Create(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer writer.Close()
+	if _, err = io.Copy(writer, f); err != nil {
+		return trace.Wrap(err)
+	}
+	if _, err = writer.Commit(ctx, desc); err != nil {
+		return trace.Wrap(err)
+	}
+	r.touch(r.blobPath(desc.Digest))
+	return nil
+}
+
+func (r *ImageCache) saveBlob(ctx context.Context, blobs distribution.BlobStore, dgst digest.Digest) error {
+	if _, err := os.Stat(r.blobPath(dgst)); err == nil {
+		r.touch(r.blobPath(dgst))
+		return nil
+	}
+	reader, err := blobs.Open(ctx, dgst)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(r.writeBlob(dgst, reader))
+}
+
+// writeBlob writes the blob with the specified digest from reader
+// to the cache verifying its contents
+func (r *ImageCache) writeBlob(dgst digest.Digest, reader io.ReadCloser) error {
+	defer reader.Close()
+	return trace.Wrap(writeVerifiedFile(r.blobPath(dgst), dgst, reader))
+}
+
+// writeVerifiedFile atomically writes the contents of reader to path
+// verifying them against the specified digest
+func writeVerifiedFile(path string, dgst digest.Digest, reader io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), defaults.PrivateDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.Remove(f.Name())
+	verifier := dgst.Verifier()
+	_, err = io.Copy(io.MultiWriter(f, verifier), reader)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if !verifier.Verified() {
+		return trace.BadParameter("blob %v failed verification", dgst)
+	}
+	return trace.ConvertSystemError(os.Rename(f.Name(), path))
+}
+
+func (r *ImageCache) readImage(imageID string) (*cachedImage, error) {
+	id, err := digest.Parse(imageID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	data, err := ioutil.ReadFile(r.imagePath(id.String()))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	var record cachedImage
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &record, nil
+}
+
+// listImages returns all cached images along with the blobs they reference
+func (r *ImageCache) listImages() (images []imageUsage, err error) {
+	files, err := ioutil.ReadDir(r.imagesDir())
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	for _, fi := range files {
+		if strings.HasPrefix(fi.Name(), ".") {
+			// Skip temporary files of images being written
+			continue
+		}
+		id, err := digest.Parse(fi.Name())
+		if err != nil {
+			r.Warnf("Skipping invalid cache entry %v: %v.", fi.Name(), err)
+			continue
+		}
+		record, err := r.readImage(id.String())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		image := imageUsage{
+			id:    id.String(),
+			used:  fi.ModTime(),
+			blobs: []digest.Digest{record.Manifest},
+		}
+		payload, err := ioutil.ReadFile(r.blobPath(record.Manifest))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, trace.ConvertSystemError(err)
+		}
+		if err == nil {
+			manifest, _, err := distribution.UnmarshalManifest(record.MediaType, payload)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			for _, desc := range manifest.References() {
+				image.blobs = append(image.blobs, desc.Digest)
+			}
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+// listBlobs returns the sizes of all cached blobs
+func (r *ImageCache) listBlobs() (map[digest.Digest]uint64, error) {
+	sizes := make(map[digest.Digest]uint64)
+	err := filepath.Walk(r.blobsDir(), func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		algorithm := filepath.Base(filepath.Dir(path))
+		dgst := digest.NewDigestFromHex(algorithm, fi.Name())
+		if dgst.Validate() != nil {
+			// Skip temporary files of blobs being written
+			return nil
+		}
+		sizes[dgst] = uint64(fi.Size())
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return sizes, nil
+}
+
+func (r *ImageCache) removeBlob(dgst digest.Digest) error {
+	r.Debugf("Removing blob %v.", dgst)
+	err := os.Remove(r.blobPath(dgst))
+	if err != nil && !os.IsNotExist(err) {
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+// touch updates the modification time of the specified file
+// to mark it as recently used
+func (r *ImageCache) touch(path string) {
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		r.Warnf("Failed to update access time of %v: %v.", path, err)
+	}
+}
+
+func (r *ImageCache) blobPath(dgst digest.Digest) string {
+	return filepath.Join(r.blobsDir(), string(dgst.Algorithm()), dgst.Hex())
+}
+
+func (r *ImageCache) imagePath(imageID string) string {
+	return filepath.Join(r.imagesDir(), imageID)
+}
+
+func (r ImageCacheConfig) blobsDir() string {
+	return filepath.Join(r.Dir, "blobs")
+}
+
+func (r ImageCacheConfig) imagesDir() string {
+	return filepath.Join(r.Dir, "images")
+}
+
+// cachedImage describes a cached image
+type cachedImage struct {
+	// Manifest is the digest of the image manifest
+	Manifest digest.Digest `json:"manifest"`
+	// MediaType is the media type of the image manifest
+	MediaType string `json:"media_type"`
+}
+
+// imageUsage describes a cached image during pruning
+type imageUsage struct {
+	id    string
+	used  time.Time
+	blobs []digest.Digest
+}
+
+// openRepository opens the repository with the specified name
+// in the registry in dir
+func openRepository(ctx context.Context, dir, name string) (distribution.Repository, error) {
+	store, err := openLocal(dir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	repo, err := store.Repository(ctx, name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return repo, nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return trace.ConvertSystemError(os.Rename(f.Name(), path))
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/docker/distribution"
+	"github.com/gravitational/trace"
+	"github.com/opencontainers/go-digest"
+	. "gopkg.in/check.v1"
+)
+
+type ImageCacheSuite struct {
+	dir   string
+	cache *ImageCache
+}
+
+var _ = Suite(&ImageCacheSuite{})
+
+func (s *ImageCacheSuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+	registry, err := NewRegistry(BasicConfiguration("127.0.0.1:0", s.dir))
+	c.Assert(err, IsNil)
+	c.Assert(registry.Start(), IsNil)
+	pushTestImage(c, registry, "app", "1.0.0", []byte("shared layer"), []byte("layer 1.0.0"))
+	pushTestImage(c, registry, "app", "2.0.0", []byte("shared layer"), []byte("layer 2.0.0"))
+	c.Assert(registry.Close(), IsNil)
+
+	s.cache, err = NewImageCache(ImageCacheConfig{
+		Dir:     c.MkDir(),
+		MaxSize: 1 << 20,
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *ImageCacheSuite) TestRestoresSavedImage(c *C) {
+	ctx := context.Background()
+	imageID := digest.FromString("image").String()
+	dir := c.MkDir()
+
+	err := s.cache.Restore(ctx, imageID, dir, "app", "1.0.0")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	c.Assert(s.cache.Save(ctx, imageID, s.dir, "app", "1.0.0"), IsNil)
+	c.Assert(s.cache.Restore(ctx, imageID, dir, "vendor/app", "latest"), IsNil)
+
+	original := getTestManifest(c, s.dir, "app", "1.0.0")
+	restored := getTestManifest(c, dir, "vendor/app", "latest")
+	c.Assert(restored, DeepEquals, original)
+}
+
+func (s *ImageCacheSuite) TestEvictsLeastRecentlyUsedImages(c *C) {
+	ctx := context.Background()
+	old := digest.FromString("old").String()
+	recent := digest.FromString("recent").String()
+	c.Assert(s.cache.Save(ctx, old, s.dir, "app", "1.0.0"), IsNil)
+	c.Assert(s.cache.Save(ctx, recent, s.dir, "app", "2.0.0"), IsNil)
+	past := time.Now().Add(-time.Hour)
+	c.Assert(os.Chtimes(s.cache.imagePath(old), past, past), IsNil)
+
+	size, err := s.cache.Size()
+	c.Assert(err, IsNil)
+	s.cache.MaxSize = size - 1
+	c.Assert(s.cache.Prune(), IsNil)
+
+	dir := c.MkDir()
+	err = s.cache.Restore(ctx, old, dir, "app", "1.0.0")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+	c.Assert(s.cache.Restore(ctx, recent, dir, "app", "2.0.0"), IsNil)
+
+	pruned, err := s.cache.Size()
+	c.Assert(err, IsNil)
+	c.Assert(pruned < size, Equals, true)
+
+	c.Assert(s.cache.Clear(), IsNil)
+	size, err = s.cache.Size()
+	c.Assert(err, IsNil)
+	c.Assert(size, Equals, uint64(0))
+}
+
+func getTestManifest(c *C, dir, name, tag string) distribution.Manifest {
+	ctx := context.Background()
+	repo, err := openRepository(ctx, dir, name)
+	c.Assert(err, IsNil)
+	desc, err := repo.Tags(ctx).Get(ctx, tag)
+	c.Assert(err, IsNil)
+	manifests, err := repo.Manifests(ctx)
+	c.Assert(err, IsNil)
+	manifest, err := manifests.Get(ctx, desc.Digest)
+	c.Assert(err, IsNil)
+	return manifest
+}
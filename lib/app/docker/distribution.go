@@ -26,6 +26,7 @@ import (
 
 	"github.com/docker/distribution/configuration"
 	registrycontext "github.com/docker/distribution/context"
+	"github.com/docker/distribution/health"
 	"github.com/docker/distribution/registry/handlers"
 	"github.com/docker/distribution/registry/listener"
 	_ "github.com/docker/distribution/registry/storage/driver/filesystem"
@@ -37,10 +38,14 @@ import (
 
 // NewRegistry creates a new registry instance from the specified configuration.
 func NewRegistry(config *configuration.Configuration) (*Registry, error) {
+	if err := checkProxy(config); err != nil {
+		return nil, trace.Wrap(err)
+	}
 	ctx, cancel := defaultContext()
-	app := handlers.NewApp(ctx, config)
-	app.RegisterHealthChecks()
-	handler := alive("/", app)
+	app, appHandler := NewHandler(ctx, config)
+	checks := health.NewRegistry()
+	app.RegisterHealthChecks(checks)
+	handler := alive("/", appHandler)
 
 	server := &http.Server{
 		Handler: handler,
@@ -48,6 +53,7 @@ func NewRegistry(config *configuration.Configuration) (*Registry, error) {
 
 	return &Registry{
 		app:    app,
+		health: checks,
 		config: config,
 		server: server,
 		ctx:    ctx,
@@ -101,6 +107,7 @@ func (r *Registry) Close() error {
 type Registry struct {
 	config *configuration.Configuration
 	app    *handlers.App
+	health *health.Registry
 	server *http.Server
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -127,7 +134,7 @@ func alive(path string, handler http.Handler) http.Handler {
 // BasicConfiguration creates a configuration object for running
 // a local registry server on the specified address addr and using rootdir
 // as a root directory for a filesystem driver
-func BasicConfiguration(addr, rootdir string) *configuration.Configuration {
+func BasicConfiguration(addr, rootdir string, opts ...ConfigOption) *configuration.Configuration {
 	config := &configuration.Configuration{
 		Version: "0.1",
 		Storage: configuration.Storage{
@@ -139,9 +146,15 @@ func BasicConfiguration(addr, rootdir string) *configuration.Configuration {
 	config.HTTP.Headers = http.Header{
 		"X-Content-Type-Options": []string{"nosniff"},
 	}
+	for _, opt := range opts {
+		opt(config)
+	}
 	return config
 }
 
+// ConfigOption customizes the registry configuration
+type ConfigOption func(*configuration.Configuration)
+
 func defaultContext() (context.Context, context.CancelFunc) {
 	ctx := registrycontext.WithVersion(context.Background(), version.Version)
 	ctx = registrycontext.WithLogger(ctx, newLogger())
@@ -17,8 +17,14 @@ limitations under the License.
 package docker
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/opencontainers/go-digest"
 	. "gopkg.in/check.v1"
 )
 
@@ -38,3 +44,70 @@ func (_ *DistributionSuite) TestCorrectlyReportsFailureToServe(c *C) {
 	c.Assert(err, ErrorMatches, ".*listen tcp.*")
 	registry.Close()
 }
+
+func (_ *DistributionSuite) TestServesAsPullThroughCache(c *C) {
+	dir := c.MkDir()
+	upstream, err := NewRegistry(BasicConfiguration("127.0.0.1:0", filepath.Join(dir, "upstream")))
+	c.Assert(err, IsNil)
+	c.Assert(upstream.Start(), IsNil)
+	defer upstream.Close()
+
+	_, err = NewRegistry(BasicConfiguration("127.0.0.1:0", dir, WithProxy(ProxyConfig{
+		RemoteURL: "registry:5000",
+	})))
+	c.Assert(err, NotNil, Commentf("expected upstream URL without scheme to be rejected"))
+
+	cache, err := NewRegistry(BasicConfiguration("127.0.0.1:0", filepath.Join(dir, "cache"),
+		WithProxy(ProxyConfig{RemoteURL: fmt.Sprintf("http://%v", upstream.Addr())})))
+	c.Assert(err, IsNil)
+	c.Assert(cache.Start(), IsNil)
+	defer cache.Close()
+
+	upstreamBlob := pushBlob(c, upstream.Addr(), "upstream blob")
+	localBlob := pushBlob(c, cache.Addr(), "local blob")
+
+	c.Assert(getBlob(c, cache.Addr(), upstreamBlob), Equals, "upstream blob")
+	c.Assert(getBlob(c, cache.Addr(), localBlob), Equals, "local blob")
+
+	resp, err := http.Get(blobURL(cache.Addr(), digest.FromString("missing blob")))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
+
+func pushBlob(c *C, addr, data string) digest.Digest {
+	resp, err := http.Post(fmt.Sprintf("http://%v/v2/test/blobs/uploads/", addr), "", nil)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusAccepted)
+	location, err := resp.Location()
+	c.Assert(err, IsNil)
+
+	dgst := digest.FromString(data)
+	query := location.Query()
+	query.Set("digest", dgst.String())
+	location.RawQuery = query.Encode()
+	req, err := http.NewRequest(http.MethodPut, location.String(), strings.NewReader(data))
+	c.Assert(err, IsNil)
+	resp, err = http.DefaultClient.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusCreated)
+	return dgst
+}
+
+// getBlob returns the contents of the blob from the test repository
+// of the registry at addr
+func getBlob(c *C, addr string, dgst digest.Digest) string {
+	resp, err := http.Get(blobURL(addr, dgst))
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	return string(data)
+}
+
+func blobURL(addr string, dgst digest.Digest) string {
+	return fmt.Sprintf("http://%v/v2/test/blobs/%v", addr, dgst)
+}
@@ -51,6 +51,19 @@ func (r *dockerPuller) Pull(image string) error {
 	return nil
 }
 
+// PullPlatform pulls the variant of the image for the specified platform using
+// "docker pull --platform" command.
+// The platform-specific variant replaces the local image with the same name
+func (r *dockerPuller) PullPlatform(image, platform string) error {
+	cmd := exec.Command("docker", "pull", "--platform", platform, image)
+	var out bytes.Buffer
+	err := utils.ExecL(cmd, &out, log.WithField(trace.Component, constants.ComponentSystem))
+	if err != nil {
+		return trace.Wrap(err, out.String())
+	}
+	return nil
+}
+
 // IsImagePresent determines if the specified image is available in docker
 func (r *dockerPuller) IsImagePresent(image string) (bool, error) {
 	_, err := r.client.InspectImage(image)
@@ -0,0 +1,449 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/configuration"
+	dockerref "github.com/docker/distribution/reference"
+	registrystorage "github.com/docker/distribution/registry/storage"
+	storagedriver "github.com/docker/distribution/registry/storage/driver"
+	"github.com/docker/distribution/registry/storage/driver/factory"
+	"github.com/dustin/go-humanize"
+	"github.com/gravitational/trace"
+	"github.com/opencontainers/go-digest"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// GarbageCollectRequest describes a request to remove unreferenced
+// data from a registry
+type GarbageCollectRequest struct {
+	// DryRun only reports the data that would be removed
+	DryRun bool
+	// RemoveUntagged removes manifests that are not referenced by any tag,
+	// e.g. manifests of images that have been re-tagged during an upgrade
+	RemoveUntagged bool
+	// Retain optionally selects the tagged images to keep.
+	// Tags of the images it rejects are removed along with the manifests.
+	// If unspecified, all tagged images are kept
+	Retain func(TagSpec) bool
+	// GracePeriod protects blobs modified recently from removal so that
+	// blobs of images being pushed concurrently are not removed before
+	// the image manifest referencing them is uploaded
+	GracePeriod time.Duration
+}
+
+// CheckAndSetDefaults validates the request and sets defaults
+func (r *GarbageCollectRequest) CheckAndSetDefaults() error {
+	if r.GracePeriod < 0 {
+		return trace.BadParameter("grace period cannot be negative")
+	}
+	if r.GracePeriod == 0 {
+		r.GracePeriod = defaults.RegistryGCGracePeriod
+	}
+	return nil
+}
+
+// GarbageCollectResult describes the outcome of garbage collection
+type GarbageCollectResult struct {
+	// DryRun indicates that no data has actually been removed
+	DryRun bool
+	// Manifests is the number of removed manifests
+	Manifests int
+	// BlobsMarked is the number of blobs still referenced by the registry
+	BlobsMarked int
+	// Blobs is the number of removed blobs
+	Blobs int
+	// Bytes is the amount of reclaimed space
+	Bytes int64
+}
+
+// String returns a text representation of the result
+func (r GarbageCollectResult) String() string {
+	verb := "removed"
+	if r.DryRun {
+		verb = "eligible for removal"
+	}
+	return fmt.Sprintf("%v manifests and %v blobs (%v) %v, %v blobs in use",
+		r.Manifests, r.Blobs, humanize.Bytes(uint64(r.Bytes)), verb, r.BlobsMarked)
+}
+
+// GarbageCollect removes the data this registry no longer references
+func (r *Registry) GarbageCollect(ctx context.Context, req GarbageCollectRequest) (*GarbageCollectResult, error) {
+	return GarbageCollect(ctx, r.config, req)
+}
+
+// GarbageCollect removes the data no longer referenced from the storage
+// of the registry with the specified configuration.
+//
+// Garbage collection can run while the registry is serving: the grace
+// period protects blobs of concurrent uploads
+func GarbageCollect(ctx context.Context, config *configuration.Configuration, req GarbageCollectRequest) (*GarbageCollectResult, error) {
+	if err := req.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	driver, err := factory.Create(config.Storage.Type(), config.Storage.Parameters())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	namespace, err := registrystorage.NewRegistry(ctx, driver, registrystorage.EnableDelete)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	gc := &collector{
+		GarbageCollectRequest: req,
+		driver:                driver,
+		namespace:             namespace,
+		marked:                make(map[digest.Digest]struct{}),
+		FieldLogger:           log.WithField(trace.Component, "registry-gc"),
+	}
+	gc.result.DryRun = req.DryRun
+	if err := gc.mark(ctx); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := gc.sweep(ctx); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if !req.DryRun {
+		gcBlobsRemoved.Add(float64(gc.result.Blobs))
+		gcBytesReclaimed.Add(float64(gc.result.Bytes))
+	}
+	gc.Infof("Garbage collection completed: %v.", gc.result)
+	return &gc.result, nil
+}
+
+// collector implements mark and sweep garbage collection of registry data
+type collector struct {
+	GarbageCollectRequest
+	log.FieldLogger
+	driver    storagedriver.StorageDriver
+	namespace distribution.Namespace
+	// marked is the set of blobs referenced by the registry
+	marked map[digest.Digest]struct{}
+	result GarbageCollectResult
+}
+
+// mark collects the blobs referenced by the manifests in all repositories.
+// Manifests that are not retained are removed unless running in dry-run mode
+func (r *collector) mark(ctx context.Context) error {
+	enumerator, ok := r.namespace.(distribution.RepositoryEnumerator)
+	if !ok {
+		return trace.BadParameter("registry does not support repository enumeration")
+	}
+	err := enumerator.Enumerate(ctx, func(name string) error {
+		return trace.Wrap(r.markRepository(ctx, name))
+	})
+	return trace.Wrap(err)
+}
+
+func (r *collector) markRepository(ctx context.Context, name string) error {
+	named, err := dockerref.WithName(name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	repo, err := r.namespace.Repository(ctx, named)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	manifests, err := repo.Manifests(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	enumerator, ok := manifests.(distribution.ManifestEnumerator)
+	if !ok {
+		return trace.BadParameter("registry does not support manifest enumeration")
+	}
+
+	// retained lists manifests of the tags to keep,
+	// rejected - manifests of the tags to remove
+	retained := make(map[digest.Digest]struct{})
+	rejected := make(map[digest.Digest]struct{})
+	tagService := repo.Tags(ctx)
+	tags, err := tagService.All(ctx)
+	if err != nil {
+		if _, ok := err.(distribution.ErrRepositoryUnknown); !ok {
+			return trace.Wrap(err)
+		}
+	}
+	for _, tag := range tags {
+		desc, err := tagService.Get(ctx, tag)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if r.Retain == nil || r.Retain(TagSpec{Name: name, Version: tag}) {
+			retained[desc.Digest] = struct{}{}
+			continue
+		}
+		rejected[desc.Digest] = struct{}{}
+		r.Debugf("Untag %v:%v.", name, tag)
+		if !r.DryRun {
+			if err := tagService.Untag(ctx, tag); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+	}
+
+	// referenced lists the manifests referenced by the retained manifest lists
+	referenced := make(map[digest.Digest]struct{})
+	var candidates []digest.Digest
+	err = enumerator.Enumerate(ctx, func(dgst digest.Digest) error {
+		_, isRetained := retained[dgst]
+		_, isRejected := rejected[dgst]
+		if !isRetained && (isRejected || r.RemoveUntagged) {
+			candidates = append(candidates, dgst)
+			return nil
+		}
+		references, err := r.markManifest(ctx, manifests, dgst)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, desc := range references {
+			referenced[desc.Digest] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil && !isPathNotFound(err) {
+		return trace.Wrap(err)
+	}
+
+	for _, dgst := range candidates {
+		if _, ok := referenced[dgst]; ok {
+			if _, err := r.markManifest(ctx, manifests, dgst); err != nil {
+				return trace.Wrap(err)
+			}
+			continue
+		}
+		r.Debugf("Remove manifest %v@%v.", name, dgst)
+		r.result.Manifests++
+		if r.DryRun {
+			continue
+		}
+		if err := manifests.Delete(ctx, dgst); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// markManifest marks the manifest with the specified digest and the blobs it references.
+// Returns the references of the manifest
+func (r *collector) markManifest(ctx context.Context, manifests distribution.ManifestService, dgst digest.Digest) ([]distribution.Descriptor, error) {
+	manifest, err := manifests.Get(ctx, dgst)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	r.marked[dgst] = struct{}{}
+	references := manifest.References()
+	for _, desc := range references {
+		r.marked[desc.Digest] = struct{}{}
+	}
+	return references, nil
+}
+
+// sweep removes the blobs that have not been marked
+func (r *collector) sweep(ctx context.Context) error {
+	type blob struct {
+		digest digest.Digest
+		size   int64
+	}
+	var unreferenced []blob
+	blobs := r.namespace.Blobs()
+	err := blobs.Enumerate(ctx, func(dgst digest.Digest) error {
+		if _, ok := r.marked[dgst]; ok {
+			return nil
+		}
+		info, err := r.driver.Stat(ctx, blobDataPath(dgst))
+		if err != nil {
+			if isPathNotFound(err) {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		if time.Since(info.ModTime()) < r.GracePeriod {
+			r.Debugf("Skip recently modified blob %v.", dgst)
+			return nil
+		}
+		unreferenced = append(unreferenced, blob{digest: dgst, size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	r.result.BlobsMarked = len(r.marked)
+
+	vacuum := registrystorage.NewVacuum(ctx, r.driver)
+	for _, blob := range unreferenced {
+		if !r.DryRun {
+			if err := vacuum.RemoveBlob(blob.digest.String()); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		r.result.Blobs++
+		r.result.Bytes += blob.size
+	}
+	return nil
+}
+
+// blobDataPath returns the path to the data of the blob with the specified digest
+// relative to the storage root
+func blobDataPath(dgst digest.Digest) string {
+	hex := dgst.Hex()
+	return path.Join("/docker/registry/v2/blobs", dgst.Algorithm().String(), hex[:2], hex, "data")
+}
+
+func isPathNotFound(err error) bool {
+	_, ok := trace.Unwrap(err).(storagedriver.PathNotFoundError)
+	return ok
+}
+
+var (
+	gcBlobsRemoved = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "registry_gc_blobs_removed_total",
+			Help: "Number of blobs removed by the registry garbage collection",
+		},
+	)
+	gcBytesReclaimed = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "registry_gc_reclaimed_bytes_total",
+			Help: "Amount of space reclaimed by the registry garbage collection",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(gcBlobsRemoved, gcBytesReclaimed)
+}
+
+// GarbageCollectorConfig defines the scheduled registry garbage collection
+type GarbageCollectorConfig struct {
+	// Config is the configuration of the registry to collect garbage in
+	Config *configuration.Configuration
+	// Request defines the garbage collection parameters
+	Request GarbageCollectRequest
+	// Interval is how often the garbage collection runs
+	Interval time.Duration
+	// ListRetained optionally returns the images to keep on each run.
+	// Tags of all other images are removed before the unreferenced
+	// blobs are swept, see GarbageCollectRequest.Retain
+	ListRetained func(context.Context) ([]TagSpec, error)
+	// FieldLogger is used for logging
+	log.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (r *GarbageCollectorConfig) CheckAndSetDefaults() error {
+	if r.Config == nil {
+		return trace.BadParameter("missing registry configuration")
+	}
+	if err := r.Request.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Interval == 0 {
+		r.Interval = defaults.RegistryGCInterval
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "registry-gc")
+	}
+	return nil
+}
+
+// NewGarbageCollector returns a new scheduled registry garbage collector
+func NewGarbageCollector(config GarbageCollectorConfig) (*GarbageCollector, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &GarbageCollector{
+		GarbageCollectorConfig: config,
+		triggerC:               make(chan struct{}, 1),
+	}, nil
+}
+
+// GarbageCollector periodically removes the data no longer referenced
+// from the registry storage
+type GarbageCollector struct {
+	GarbageCollectorConfig
+	triggerC chan struct{}
+}
+
+// Trigger schedules the garbage collection to run as soon as possible,
+// e.g. after an application has been upgraded or uninstalled
+func (r *GarbageCollector) Trigger() {
+	select {
+	case r.triggerC <- struct{}{}:
+	default:
+		// already scheduled
+	}
+}
+
+// Run runs the garbage collection loop until the context is cancelled
+func (r *GarbageCollector) Run(ctx context.Context) {
+	r.Info("Starting registry garbage collector.")
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.triggerC:
+		case <-ctx.Done():
+			r.Info("Stopping registry garbage collector.")
+			return
+		}
+		if err := r.collect(ctx); err != nil {
+			r.Errorf("Failed to collect registry garbage: %v.", trace.DebugReport(err))
+		}
+	}
+}
+
+// collect runs the garbage collection retaining the images
+// returned by ListRetained, if specified
+func (r *GarbageCollector) collect(ctx context.Context) error {
+	req := r.Request
+	if r.ListRetained != nil {
+		images, err := r.ListRetained(ctx)
+		if err != nil {
+			return trace.Wrap(err, "failed to list images to retain")
+		}
+		if len(images) == 0 {
+			// an empty list would remove all images from the registry
+			return trace.NotFound("no images to retain, skipping garbage collection")
+		}
+		req.Retain = RetainImages(images)
+	}
+	_, err := GarbageCollect(ctx, r.Config, req)
+	return trace.Wrap(err)
+}
+
+// RetainImages returns a function that retains only the specified images
+func RetainImages(images []TagSpec) func(TagSpec) bool {
+	retained := make(map[TagSpec]struct{}, len(images))
+	for _, image := range images {
+		retained[image] = struct{}{}
+	}
+	return func(image TagSpec) bool {
+		_, ok := retained[image]
+		return ok
+	}
+}
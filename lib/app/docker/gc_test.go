@@ -0,0 +1,163 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/opencontainers/go-digest"
+	. "gopkg.in/check.v1"
+)
+
+type GarbageCollectSuite struct {
+	registry *Registry
+}
+
+var _ = Suite(&GarbageCollectSuite{})
+
+func (s *GarbageCollectSuite) SetUpTest(c *C) {
+	s.registry = startTestRegistry(c)
+}
+
+func (s *GarbageCollectSuite) TearDownTest(c *C) {
+	s.registry.Close()
+}
+
+func (s *GarbageCollectSuite) TestRemovesUnreferencedData(c *C) {
+	ctx := context.Background()
+	shared := []byte("shared layer")
+	pushTestImage(c, s.registry, "app", "1.0.0", shared, []byte("layer 1"))
+	// re-tagging the image leaves the previous manifest untagged
+	pushTestImage(c, s.registry, "app", "1.0.0", shared, []byte("layer 1 updated"))
+	pushTestImage(c, s.registry, "other", "1.0.0", []byte("layer 2"))
+
+	// the previous manifest keeps its blobs referenced by default.
+	// All images share the config blob, and the manifests are blobs as well
+	result, err := s.registry.GarbageCollect(ctx, GarbageCollectRequest{GracePeriod: time.Nanosecond})
+	c.Assert(err, IsNil)
+	c.Assert(*result, DeepEquals, GarbageCollectResult{BlobsMarked: 8})
+
+	req := GarbageCollectRequest{
+		DryRun:         true,
+		RemoveUntagged: true,
+		GracePeriod:    time.Nanosecond,
+	}
+	// the previous manifest and its own layer are eligible for removal
+	result, err = s.registry.GarbageCollect(ctx, req)
+	c.Assert(err, IsNil)
+	assertRemoved(c, result, true, 1, 2, 6, len("layer 1"))
+
+	// blobs within the grace period are kept
+	result, err = s.registry.GarbageCollect(ctx, GarbageCollectRequest{DryRun: true, RemoveUntagged: true})
+	c.Assert(err, IsNil)
+	c.Assert(result.Blobs, Equals, 0)
+
+	req.DryRun = false
+	result, err = s.registry.GarbageCollect(ctx, req)
+	c.Assert(err, IsNil)
+	assertRemoved(c, result, false, 1, 2, 6, len("layer 1"))
+
+	result, err = s.registry.GarbageCollect(ctx, req)
+	c.Assert(err, IsNil)
+	c.Assert(*result, DeepEquals, GarbageCollectResult{BlobsMarked: 6})
+
+	// images that are not retained are removed
+	req.Retain = func(image TagSpec) bool { return image.Name != "other" }
+	result, err = s.registry.GarbageCollect(ctx, req)
+	c.Assert(err, IsNil)
+	assertRemoved(c, result, false, 1, 2, 4, len("layer 2"))
+
+	store, err := ConnectRegistry(ctx, RegistryConnectionRequest{RegistryAddress: s.registry.Addr()})
+	c.Assert(err, IsNil)
+	repo, err := store.Repository(ctx, "app")
+	c.Assert(err, IsNil)
+	desc, err := repo.Tags(ctx).Get(ctx, "1.0.0")
+	c.Assert(err, IsNil)
+	manifests, err := repo.Manifests(ctx)
+	c.Assert(err, IsNil)
+	manifest, err := manifests.Get(ctx, desc.Digest)
+	c.Assert(err, IsNil)
+	for _, layer := range manifest.References() {
+		_, err := repo.Blobs(ctx).Stat(ctx, layer.Digest)
+		c.Assert(err, IsNil)
+	}
+}
+
+func (s *GarbageCollectSuite) TestRemovesImagesOfUpgradedApplication(c *C) {
+	ctx := context.Background()
+	shared := []byte("shared layer")
+	pushTestImage(c, s.registry, "app", "1.0.0", shared, []byte("layer 1"))
+	pushTestImage(c, s.registry, "app", "2.0.0", shared, []byte("layer 2"))
+
+	// the registry of the installed application package
+	packageDir := c.MkDir()
+	packageRegistry, err := NewRegistry(BasicConfiguration("127.0.0.1:0", packageDir))
+	c.Assert(err, IsNil)
+	c.Assert(packageRegistry.Start(), IsNil)
+	defer packageRegistry.Close()
+	pushTestImage(c, packageRegistry, "app", "2.0.0", shared, []byte("layer 2"))
+
+	collector, err := NewGarbageCollector(GarbageCollectorConfig{
+		Config: s.registry.config,
+		Request: GarbageCollectRequest{
+			RemoveUntagged: true,
+			GracePeriod:    time.Nanosecond,
+		},
+		ListRetained: func(ctx context.Context) ([]TagSpec, error) {
+			return ListImages(ctx, packageDir)
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(collector.collect(ctx), IsNil)
+
+	store, err := ConnectRegistry(ctx, RegistryConnectionRequest{RegistryAddress: s.registry.Addr()})
+	c.Assert(err, IsNil)
+	repo, err := store.Repository(ctx, "app")
+	c.Assert(err, IsNil)
+	tags, err := repo.Tags(ctx).All(ctx)
+	c.Assert(err, IsNil)
+	c.Assert(tags, DeepEquals, []string{"2.0.0"})
+	// blobs are checked in the storage as the registry caches blob descriptors
+	root := s.registry.config.Storage.Parameters()["rootdirectory"].(string)
+	_, err = os.Stat(filepath.Join(root, blobDataPath(digest.FromBytes([]byte("layer 1")))))
+	c.Assert(os.IsNotExist(err), Equals, true, Commentf("expected layer of the previous version to be removed, got %v", err))
+	for _, layer := range [][]byte{shared, []byte("layer 2")} {
+		_, err = os.Stat(filepath.Join(root, blobDataPath(digest.FromBytes(layer))))
+		c.Assert(err, IsNil)
+	}
+
+	// nothing is removed if there are no images to retain
+	collector.ListRetained = func(context.Context) ([]TagSpec, error) { return nil, nil }
+	c.Assert(trace.IsNotFound(collector.collect(ctx)), Equals, true)
+	tags, err = repo.Tags(ctx).All(ctx)
+	c.Assert(err, IsNil)
+	c.Assert(tags, DeepEquals, []string{"2.0.0"})
+}
+
+// assertRemoved verifies the garbage collection result. The amount of reclaimed
+// space includes the size of manifests so it is verified to exceed the layer size
+func assertRemoved(c *C, result *GarbageCollectResult, dryRun bool, manifests, blobs, marked, layerSize int) {
+	c.Assert(result.DryRun, Equals, dryRun)
+	c.Assert(result.Manifests, Equals, manifests)
+	c.Assert(result.Blobs, Equals, blobs)
+	c.Assert(result.BlobsMarked, Equals, marked)
+	c.Assert(result.Bytes > int64(layerSize), Equals, true, Commentf("reclaimed %v bytes", result.Bytes))
+}
@@ -28,17 +28,20 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/run"
 	"github.com/gravitational/gravity/lib/state"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/docker/distribution"
 	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/manifest/manifestlist"
 	"github.com/docker/distribution/registry/api/errcode"
 	registryclient "github.com/docker/distribution/registry/client"
 	registrystorage "github.com/docker/distribution/registry/storage"
@@ -62,6 +65,10 @@ type RegistryConnectionRequest struct {
 	ClientCertPath string
 	// ClientKeyPath is the full path to the client private key
 	ClientKeyPath string
+	// Parallel defines the number of layers to push concurrently when syncing images.
+	// If < 0, the number of concurrent pushes is not restricted,
+	// if unspecified, the pushes are capped at the number of logical CPU cores
+	Parallel int
 }
 
 // CheckAndSetDefaults makes sure the request is valid and sets some defaults
@@ -85,6 +92,9 @@ func (r *RegistryConnectionRequest) CheckAndSetDefaults() error {
 		r.ClientKeyPath = filepath.Join(
 			defaults.DockerCertsDir, certName, "client.key")
 	}
+	if r.Parallel == 0 {
+		r.Parallel = runtime.NumCPU()
+	}
 	return nil
 }
 
@@ -136,6 +146,9 @@ type imageService struct {
 // with the contents of the remote registry.
 // dir is expected to be in docker registry 2.x format.
 //
+// Layers missing in the remote registry are pushed concurrently, each layer
+// is pushed once even if it is shared by several images.
+//
 // Upon success, returns a list of images pushed to the registry.
 func (r *imageService) Sync(ctx context.Context, dir string, progress utils.Emitter) (installedTags []TagSpec, err error) {
 	if err = r.connect(ctx); err != nil {
@@ -151,6 +164,7 @@ func (r *imageService) Sync(ctx context.Context, dir string, progress utils.Emit
 		return nil, trace.Wrap(err, "failed to list local repositories in %q", dir)
 	}
 
+	var images []syncImage
 	for _, localRepoName := range repos {
 		localRepo, err := localStore.Repository(ctx, localRepoName)
 		if err != nil {
@@ -203,15 +217,26 @@ func (r *imageService) Sync(ctx context.Context, dir string, progress utils.Emit
 			// different from the local one
 			if remoteManifest == nil || !compareManifests(localManifest, remoteManifest) {
 				progress.PrintStep("Pushing image %s", tagSpec)
-				if err = r.remoteStore.updateRepo(ctx, remoteRepo, localRepo, localManifest, tag); err != nil {
-					return nil, trace.Wrap(err, "failed to update remote for tag %q", tagSpec)
+				platformManifests, err := getPlatformManifests(ctx, localManifests, localManifest)
+				if err != nil {
+					return nil, trace.Wrap(err)
 				}
+				images = append(images, syncImage{
+					tag:               tagSpec,
+					local:             localRepo,
+					remote:            remoteRepo,
+					manifest:          localManifest,
+					platformManifests: platformManifests,
+				})
 			} else {
 				progress.PrintStep("Image %s is up-to-date", tagSpec)
 			}
 			installedTags = append(installedTags, tagSpec)
 		}
 	}
+	if err := r.remoteStore.pushImages(ctx, images, r.Parallel); err != nil {
+		return nil, trace.Wrap(err)
+	}
 	return installedTags, nil
 }
 
@@ -443,6 +468,32 @@ func ListRepos(ctx context.Context, namespace registryclient.Registry) (repos []
 	return repos, err
 }
 
+// ListImages returns all tagged images in the registry in registryDir
+func ListImages(ctx context.Context, registryDir string) (images []TagSpec, err error) {
+	store, err := openLocal(registryDir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	repos, err := ListRepos(ctx, store)
+	if err != nil && err != io.EOF {
+		return nil, trace.Wrap(err)
+	}
+	for _, name := range repos {
+		repo, err := store.Repository(ctx, name)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		tags, err := repo.Tags(ctx).All(ctx)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, tag := range tags {
+			images = append(images, TagSpec{Name: name, Version: tag})
+		}
+	}
+	return images, nil
+}
+
 // IsManifestUnknown determines if the specified error is an `unknown manifest` error
 func IsManifestUnknown(err error) bool {
 	return ("MANIFEST_UNKNOWN" == registryErrorCode(err))
@@ -474,45 +525,143 @@ func compareManifests(a, b distribution.Manifest) bool {
 	return bytes.Equal(payloadA, payloadB)
 }
 
-// updateRepo takes a pair of local+remote repositories and makes the remote repo identical
-// to the local one.
-func (s *remoteStore) updateRepo(ctx context.Context, remote, local distribution.Repository, manifest distribution.Manifest, tag string) error {
-	s.Debugf("Pushing %[1]v --> %[2]v/%[1]v.", local.Named(), s.addr)
-	remoteManifests, err := remote.Manifests(ctx)
-	if err != nil {
-		return trace.Wrap(err)
+// syncImage describes an image to push to the remote registry
+type syncImage struct {
+	tag      TagSpec
+	local    distribution.Repository
+	remote   distribution.Repository
+	manifest distribution.Manifest
+	// platformManifests lists the manifests of the platform-specific images
+	// if manifest is a manifest list
+	platformManifests []distribution.Manifest
+}
+
+// layers returns the descriptors of all blobs referenced by this image
+func (r syncImage) layers() []distribution.Descriptor {
+	if len(r.platformManifests) == 0 {
+		return r.manifest.References()
 	}
-	localBlobs := local.Blobs(ctx)
-	remoteBlobs := remote.Blobs(ctx)
-	// copy layers:
-	for _, localDesc := range manifest.References() {
-		desc, err := remoteBlobs.Stat(ctx, localDesc.Digest)
-		if err == nil && desc.Digest == localDesc.Digest {
-			s.Debugf("Skipping layer %v.", localDesc.Digest)
-			continue
-		}
-		reader, err := localBlobs.Open(ctx, localDesc.Digest)
-		if err != nil {
-			return trace.Wrap(err)
-		}
-		defer reader.Close()
-		writer, err := remoteBlobs.Create(ctx)
-		if err != nil {
-			return trace.Wrap(err)
-		}
-		defer writer.Close()
-		s.Debugf("Writing layer %v.", localDesc.Digest)
-		written, err := io.Copy(writer, reader)
+	var layers []distribution.Descriptor
+	for _, manifest := range r.platformManifests {
+		layers = append(layers, manifest.References()...)
+	}
+	return layers
+}
+
+// getPlatformManifests returns the manifests referenced by the specified
+// manifest if it is a manifest list
+func getPlatformManifests(ctx context.Context, manifests distribution.ManifestService, manifest distribution.Manifest) ([]distribution.Manifest, error) {
+	if _, ok := manifest.(*manifestlist.DeserializedManifestList); !ok {
+		return nil, nil
+	}
+	var platformManifests []distribution.Manifest
+	for _, desc := range manifest.References() {
+		platformManifest, err := manifests.Get(ctx, desc.Digest)
 		if err != nil {
-			return trace.Wrap(err)
+			return nil, trace.Wrap(err)
 		}
-		_, err = writer.Commit(ctx, distribution.Descriptor{Digest: localDesc.Digest})
-		if err != nil {
-			return trace.Wrap(err)
+		platformManifests = append(platformManifests, platformManifest)
+	}
+	return platformManifests, nil
+}
+
+// pushImages pushes the specified images from the local to the remote registry.
+//
+// Layers are pushed concurrently with at most parallel pushes at a time.
+// Layers shared by several images of a repository are pushed once and
+// layers already present in the remote repository are skipped.
+// Manifests are updated once all layers have been pushed
+func (s *remoteStore) pushImages(ctx context.Context, images []syncImage, parallel int) error {
+	type layer struct {
+		image syncImage
+		desc  distribution.Descriptor
+	}
+	var layers []layer
+	seen := make(map[string]struct{})
+	for _, image := range images {
+		for _, desc := range image.layers() {
+			key := fmt.Sprintf("%v@%v", image.remote.Named().Name(), desc.Digest)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			layers = append(layers, layer{image: image, desc: desc})
 		}
-		s.Debugf("Written %v bytes.", written)
 	}
-	s.Debugf("Updating manifest for %v.", local.Named())
-	_, err = remoteManifests.Put(ctx, manifest, distribution.WithTag(tag))
-	return trace.Wrap(err)
+
+	group, groupCtx := run.WithContext(ctx, run.WithParallel(parallel))
+	for _, l := range layers {
+		l := l
+		group.Go(groupCtx, func() error {
+			err := s.pushLayer(groupCtx, l.image.remote, l.image.local, l.desc)
+			return trace.Wrap(err, "failed to push layer %v of %v", l.desc.Digest, l.image.tag)
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	group, groupCtx = run.WithContext(ctx, run.WithParallel(parallel))
+	for _, image := range images {
+		image := image
+		group.Go(groupCtx, func() error {
+			s.Debugf("Updating manifest for %v.", image.tag)
+			manifests, err := image.remote.Manifests(groupCtx)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			// platform manifests need to be pushed before the manifest list
+			// that references them
+			for _, manifest := range image.platformManifests {
+				if _, err := manifests.Put(groupCtx, manifest); err != nil {
+					return trace.Wrap(err, "failed to push platform manifest for %q", image.tag)
+				}
+			}
+			_, err = manifests.Put(groupCtx, image.manifest, distribution.WithTag(image.tag.Version))
+			return trace.Wrap(err, "failed to update remote for tag %q", image.tag)
+		})
+	}
+	return trace.Wrap(group.Wait())
+}
+
+// pushLayer pushes the layer specified with desc from the local to
+// the remote repository unless the remote repository already has it
+func (s *remoteStore) pushLayer(ctx context.Context, remote, local distribution.Repository, desc distribution.Descriptor) error {
+	remoteBlobs := remote.Blobs(ctx)
+	existing, err := remoteBlobs.Stat(ctx, desc.Digest)
+	if err == nil && existing.Digest == desc.Digest {
+		s.Debugf("Skipping layer %v.", desc.Digest)
+		return nil
+	}
+	s.Debugf("Writing layer %v.", desc.Digest)
+	written, err := copyBlob(ctx, remoteBlobs, local.Blobs(ctx), desc)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	s.Debugf("Written %v bytes.", written)
+	return nil
+}
+
+// copyBlob copies the blob specified with desc from the src to the dst blob store
+// and returns the number of bytes written
+func copyBlob(ctx context.Context, dst, src distribution.BlobStore, desc distribution.Descriptor) (written int64, err error) {
+	reader, err := src.Open(ctx, desc.Digest)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	defer reader.Close()
+	writer, err := dst.Create(ctx)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	defer writer.Close()
+	written, err = io.Copy(writer, reader)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	_, err = writer.Commit(ctx, distribution.Descriptor{Digest: desc.Digest})
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	return written, nil
 }
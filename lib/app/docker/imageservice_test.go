@@ -19,6 +19,9 @@ package docker
 import (
 	"io"
 
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/docker/distribution"
 	"github.com/docker/distribution/context"
 
 	. "gopkg.in/check.v1"
@@ -82,6 +85,46 @@ func (r *ImageServiceSuite) TestListsRepos(c *C) {
 	c.Assert(repos, DeepEquals, []string{"a", "b", "c", "d", "e"})
 }
 
+func (r *ImageServiceSuite) TestSyncsImagesWithSharedLayers(c *C) {
+	dir := c.MkDir()
+	local, err := NewRegistry(BasicConfiguration("127.0.0.1:0", dir))
+	c.Assert(err, IsNil)
+	c.Assert(local.Start(), IsNil)
+	shared := []byte("shared layer")
+	pushTestImage(c, local, "app", "1.0.0", shared, []byte("layer 1.0.0"))
+	pushTestImage(c, local, "app", "2.0.0", shared, []byte("layer 2.0.0"))
+	pushTestImage(c, local, "other", "1.0.0", shared)
+	c.Assert(local.Close(), IsNil)
+
+	remote := startTestRegistry(c)
+	defer remote.Close()
+	service, err := NewImageService(RegistryConnectionRequest{
+		RegistryAddress: remote.Addr(),
+		Parallel:        4,
+	})
+	c.Assert(err, IsNil)
+
+	tags, err := service.Sync(context.Background(), dir, utils.NopEmitter())
+	c.Assert(err, IsNil)
+	c.Assert(tags, HasLen, 3)
+
+	ctx := context.Background()
+	store, err := ConnectRegistry(ctx, RegistryConnectionRequest{RegistryAddress: remote.Addr()})
+	c.Assert(err, IsNil)
+	for _, tag := range tags {
+		repo, err := store.Repository(ctx, tag.Name)
+		c.Assert(err, IsNil)
+		manifests, err := repo.Manifests(ctx)
+		c.Assert(err, IsNil)
+		manifest, err := manifests.Get(ctx, "", distribution.WithTag(tag.Version))
+		c.Assert(err, IsNil)
+		for _, desc := range manifest.References() {
+			_, err := repo.Blobs(ctx).Stat(ctx, desc.Digest)
+			c.Assert(err, IsNil, Commentf("missing layer %v of %v", desc.Digest, tag))
+		}
+	}
+}
+
 type registry struct {
 	repos []string
 	n     int
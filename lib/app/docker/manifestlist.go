@@ -0,0 +1,118 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/gravitational/trace"
+)
+
+// PlatformImage references the image of a repository built for a specific
+// CPU architecture
+type PlatformImage struct {
+	// Tag is the tag of the image in the repository
+	Tag string
+	// Arch is the CPU architecture of the image, e.g. amd64 or arm64
+	Arch string
+}
+
+// PlatformTag returns the tag for the image built for the specified
+// CPU architecture that is used until it is assembled into a manifest list
+func PlatformTag(tag, arch string) string {
+	return fmt.Sprintf("%v-%v", tag, arch)
+}
+
+// CreateManifestList creates a manifest list for the specified platform images
+// of the repository in the registry stored in the local directory dir and
+// tags it with tag.
+//
+// The platform image tags are removed once the manifest list has been
+// created, the manifests they referenced stay available via the manifest list
+func CreateManifestList(ctx context.Context, dir, repository, tag string, images []PlatformImage) error {
+	store, err := openLocal(dir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	repo, err := store.Repository(ctx, repository)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	manifests, err := repo.Manifests(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	tags := repo.Tags(ctx)
+	descriptors := make([]manifestlist.ManifestDescriptor, 0, len(images))
+	for _, image := range images {
+		desc, err := tags.Get(ctx, image.Tag)
+		if err != nil {
+			return trace.Wrap(err, "failed to find %v:%v", repository, image.Tag)
+		}
+		manifest, err := manifests.Get(ctx, desc.Digest)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if _, ok := manifest.(*manifestlist.DeserializedManifestList); ok {
+			return trace.BadParameter("%v:%v is already a manifest list", repository, image.Tag)
+		}
+		mediaType, payload, err := manifest.Payload()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		descriptors = append(descriptors, manifestlist.ManifestDescriptor{
+			Descriptor: distribution.Descriptor{
+				MediaType: mediaType,
+				Size:      int64(len(payload)),
+				Digest:    desc.Digest,
+			},
+			Platform: manifestlist.PlatformSpec{
+				Architecture: image.Arch,
+				OS:           "linux",
+			},
+		})
+	}
+	list, err := manifestlist.FromDescriptors(descriptors)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	dgst, err := manifests.Put(ctx, list)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	mediaType, _, err := list.Payload()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// the storage manifest service does not tag manifests on its own
+	err = tags.Tag(ctx, tag, distribution.Descriptor{MediaType: mediaType, Digest: dgst})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, image := range images {
+		if image.Tag == tag {
+			continue
+		}
+		if err := tags.Untag(ctx, image.Tag); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
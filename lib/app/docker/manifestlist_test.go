@@ -0,0 +1,80 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/manifest/manifestlist"
+	. "gopkg.in/check.v1"
+)
+
+type ManifestListSuite struct{}
+
+var _ = Suite(&ManifestListSuite{})
+
+func (s *ManifestListSuite) TestCreatesAndSyncsManifestList(c *C) {
+	dir := c.MkDir()
+	local, err := NewRegistry(BasicConfiguration("127.0.0.1:0", dir))
+	c.Assert(err, IsNil)
+	c.Assert(local.Start(), IsNil)
+	pushTestImage(c, local, "app", PlatformTag("1.0.0", "amd64"), []byte("amd64 layer"))
+	pushTestImage(c, local, "app", PlatformTag("1.0.0", "arm64"), []byte("arm64 layer"))
+	c.Assert(local.Close(), IsNil)
+
+	ctx := context.Background()
+	err = CreateManifestList(ctx, dir, "app", "1.0.0", []PlatformImage{
+		{Tag: PlatformTag("1.0.0", "amd64"), Arch: "amd64"},
+		{Tag: PlatformTag("1.0.0", "arm64"), Arch: "arm64"},
+	})
+	c.Assert(err, IsNil)
+
+	remote := startTestRegistry(c)
+	defer remote.Close()
+	service, err := NewImageService(RegistryConnectionRequest{
+		RegistryAddress: remote.Addr(),
+	})
+	c.Assert(err, IsNil)
+	tags, err := service.Sync(ctx, dir, utils.NopEmitter())
+	c.Assert(err, IsNil)
+	c.Assert(tags, DeepEquals, []TagSpec{{Name: "app", Version: "1.0.0"}},
+		Commentf("platform tags should be removed"))
+
+	store, err := ConnectRegistry(ctx, RegistryConnectionRequest{RegistryAddress: remote.Addr()})
+	c.Assert(err, IsNil)
+	repo, err := store.Repository(ctx, "app")
+	c.Assert(err, IsNil)
+	manifests, err := repo.Manifests(ctx)
+	c.Assert(err, IsNil)
+	manifest, err := manifests.Get(ctx, "", distribution.WithTag("1.0.0"))
+	c.Assert(err, IsNil)
+	list, ok := manifest.(*manifestlist.DeserializedManifestList)
+	c.Assert(ok, Equals, true, Commentf("expected manifest list, got %T", manifest))
+	var architectures []string
+	for _, desc := range list.Manifests {
+		architectures = append(architectures, desc.Platform.Architecture)
+		platformManifest, err := manifests.Get(ctx, desc.Digest)
+		c.Assert(err, IsNil)
+		for _, layer := range platformManifest.References() {
+			_, err := repo.Blobs(ctx).Stat(ctx, layer.Digest)
+			c.Assert(err, IsNil, Commentf("missing layer %v for %v", layer.Digest, desc.Platform.Architecture))
+		}
+	}
+	c.Assert(architectures, DeepEquals, []string{"amd64", "arm64"})
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/configuration"
+	registrystorage "github.com/docker/distribution/registry/storage"
+	"github.com/docker/distribution/registry/storage/driver/factory"
+	"github.com/gravitational/trace"
+	"github.com/opencontainers/go-digest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewMetricsCollector returns a collector that reports the number of blobs
+// in the storage of the registry with the specified configuration
+func NewMetricsCollector(ctx context.Context, config *configuration.Configuration) (prometheus.Collector, error) {
+	driver, err := factory.Create(config.Storage.Type(), config.Storage.Parameters())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	namespace, err := registrystorage.NewRegistry(ctx, driver)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &metricsCollector{ctx: ctx, namespace: namespace}, nil
+}
+
+type metricsCollector struct {
+	ctx       context.Context
+	namespace distribution.Namespace
+}
+
+// Describe sends the descriptors of the registry metrics to ch
+func (c *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- registryBlobsDesc
+}
+
+// Collect sends the registry metrics to ch
+func (c *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	var blobs int
+	err := c.namespace.Blobs().Enumerate(c.ctx, func(digest.Digest) error {
+		blobs++
+		return nil
+	})
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(registryBlobsDesc, trace.Wrap(err))
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(registryBlobsDesc, prometheus.GaugeValue, float64(blobs))
+}
+
+var registryBlobsDesc = prometheus.NewDesc(
+	"registry_blobs",
+	"Number of blobs in the registry storage",
+	nil, nil,
+)
@@ -0,0 +1,521 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/gravitational/trace"
+	"github.com/opencontainers/go-digest"
+	log "github.com/sirupsen/logrus"
+)
+
+// ExportOCILayout writes all tagged images from the registry in registryDir
+// to layoutDir as an OCI image layout.
+//
+// The images are added to the layout index under their repository:tag
+// reference so the layout can be consumed by tools like skopeo or crane.
+// If layoutDir already contains a layout, the images are merged into it.
+//
+// Returns the list of exported images
+func ExportOCILayout(ctx context.Context, registryDir, layoutDir string) (exported []TagSpec, err error) {
+	store, err := openLocal(registryDir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	repos, err := ListRepos(ctx, store)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := os.MkdirAll(layoutDir, defaults.SharedDirMask); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	layout := ociLayout{dir: layoutDir}
+	index, err := layout.readIndex()
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if index == nil {
+		index = &ociIndex{SchemaVersion: ociSchemaVersion}
+	}
+	for _, name := range repos {
+		repo, err := store.Repository(ctx, name)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		manifests, err := repo.Manifests(ctx)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		tags, err := repo.Tags(ctx).All(ctx)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, tag := range tags {
+			image := TagSpec{Name: name, Version: tag}
+			desc, err := repo.Tags(ctx).Get(ctx, tag)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			manifest, err := layout.exportManifest(ctx, repo, manifests, desc.Digest)
+			if err != nil {
+				return nil, trace.Wrap(err, "failed to export %v", image)
+			}
+			manifest.Annotations = map[string]string{ociAnnotationRefName: image.String()}
+			index.add(*manifest)
+			exported = append(exported, image)
+		}
+	}
+	if err := layout.writeIndex(*index); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return exported, nil
+}
+
+// ImportOCILayout adds the images from the OCI image layout in layoutDir
+// to the registry in registryDir.
+//
+// Only the images with a repository:tag reference in the layout index
+// are imported. OCI manifests are converted to the docker image manifest
+// format supported by the registry.
+//
+// Returns the list of imported images
+func ImportOCILayout(ctx context.Context, layoutDir, registryDir string) (imported []TagSpec, err error) {
+	layout := ociLayout{dir: layoutDir}
+	index, err := layout.readIndex()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := os.MkdirAll(registryDir, defaults.SharedDirMask); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	store, err := openLocal(registryDir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, desc := range index.Manifests {
+		ref := desc.Annotations[ociAnnotationRefName]
+		if ref == "" {
+			log.Warnf("Skipping manifest %v without image reference.", desc.Digest)
+			continue
+		}
+		image := TagFromString(ref)
+		repo, err := store.Repository(ctx, image.Name)
+		if err != nil {
+			return nil, trace.Wrap(err, "invalid image reference %q", ref)
+		}
+		manifest, err := layout.importManifest(ctx, repo, desc)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to import %v", image)
+		}
+		// the storage manifest service does not tag manifests on its own
+		if err := repo.Tags(ctx).Tag(ctx, image.Version, *manifest); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		imported = append(imported, image)
+	}
+	return imported, nil
+}
+
+// ociLayout is an OCI image layout in a local directory
+type ociLayout struct {
+	dir string
+}
+
+// exportManifest writes the manifest with the specified digest from repo
+// along with all the blobs it references to the layout converting it
+// to the OCI format
+func (r ociLayout) exportManifest(ctx context.Context, repo distribution.Repository, manifests distribution.ManifestService, dgst digest.Digest) (*ociDescriptor, error) {
+	manifest, err := manifests.Get(ctx, dgst)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch m := manifest.(type) {
+	case *schema2.DeserializedManifest:
+		blobs := repo.Blobs(ctx)
+		image := ociManifest{
+			SchemaVersion: ociSchemaVersion,
+			MediaType:     ociMediaTypeManifest,
+		}
+		image.Config, err = r.exportBlob(ctx, blobs, m.Config)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, layer := range m.Layers {
+			desc, err := r.exportBlob(ctx, blobs, layer)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			image.Layers = append(image.Layers, desc)
+		}
+		return r.writeJSON(ociMediaTypeManifest, image)
+	case *manifestlist.DeserializedManifestList:
+		index := ociIndex{
+			SchemaVersion: ociSchemaVersion,
+			MediaType:     ociMediaTypeIndex,
+		}
+		for _, platformManifest := range m.Manifests {
+			desc, err := r.exportManifest(ctx, repo, manifests, platformManifest.Digest)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			platform := platformManifest.Platform
+			desc.Platform = &platform
+			index.Manifests = append(index.Manifests, *desc)
+		}
+		return r.writeJSON(ociMediaTypeIndex, index)
+	default:
+		return nil, trace.BadParameter("unsupported manifest type %T", manifest)
+	}
+}
+
+// exportBlob writes the blob specified with desc from the blob store
+// to the layout and returns its OCI descriptor
+func (r ociLayout) exportBlob(ctx context.Context, blobs distribution.BlobStore, desc distribution.Descriptor) (ociDescriptor, error) {
+	result := ociDescriptor{
+		MediaType: convertMediaType(desc.MediaType, ociMediaTypes),
+		Size:      desc.Size,
+		Digest:    desc.Digest,
+		URLs:      desc.URLs,
+	}
+	if _, err := os.Stat(r.blobPath(desc.Digest)); err == nil {
+		return result, nil
+	}
+	reader, err := blobs.Open(ctx, desc.Digest)
+	if err != nil {
+		if err == distribution.ErrBlobUnknown && len(desc.URLs) != 0 {
+			// foreign layers are not necessarily stored in the registry
+			return result, nil
+		}
+		return result, trace.Wrap(err)
+	}
+	defer reader.Close()
+	if err := writeVerifiedFile(r.blobPath(desc.Digest), desc.Digest, reader); err != nil {
+		return result, trace.Wrap(err)
+	}
+	return result, nil
+}
+
+// importManifest adds the manifest specified with desc along with all
+// the blobs it references from the layout to repo converting it to the
+// docker format and returns the descriptor of the resulting manifest
+func (r ociLayout) importManifest(ctx context.Context, repo distribution.Repository, desc ociDescriptor) (*distribution.Descriptor, error) {
+	payload, err := r.readBlob(desc.Digest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var manifest distribution.Manifest
+	switch desc.MediaType {
+	case ociMediaTypeManifest, schema2.MediaTypeManifest:
+		var image ociManifest
+		if err := json.Unmarshal(payload, &image); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		blobs := repo.Blobs(ctx)
+		config, err := r.importBlob(ctx, blobs, image.Config)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		var layers []distribution.Descriptor
+		for _, layer := range image.Layers {
+			desc, err := r.importBlob(ctx, blobs, layer)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			layers = append(layers, desc)
+		}
+		manifest, err = schema2.FromStruct(schema2.Manifest{
+			Versioned: schema2.SchemaVersion,
+			Config:    config,
+			Layers:    layers,
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	case ociMediaTypeIndex, manifestlist.MediaTypeManifestList:
+		var index ociIndex
+		if err := json.Unmarshal(payload, &index); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		var descriptors []manifestlist.ManifestDescriptor
+		for _, platformManifest := range index.Manifests {
+			desc, err := r.importManifest(ctx, repo, platformManifest)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			descriptor := manifestlist.ManifestDescriptor{Descriptor: *desc}
+			if platformManifest.Platform != nil {
+				descriptor.Platform = *platformManifest.Platform
+			}
+			descriptors = append(descriptors, descriptor)
+		}
+		manifest, err = manifestlist.FromDescriptors(descriptors)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	default:
+		return nil, trace.BadParameter("unsupported manifest media type %q", desc.MediaType)
+	}
+	manifests, err := repo.Manifests(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	dgst, err := manifests.Put(ctx, manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	mediaType, payload, err := manifest.Payload()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &distribution.Descriptor{
+		MediaType: mediaType,
+		Size:      int64(len(payload)),
+		Digest:    dgst,
+	}, nil
+}
+
+// importBlob adds the blob specified with desc from the layout to the blob
+// store unless it is already present and returns its docker descriptor
+func (r ociLayout) importBlob(ctx context.Context, blobs distribution.BlobStore, desc ociDescriptor) (distribution.Descriptor, error) {
+	result := distribution.Descriptor{
+		MediaType: convertMediaType(desc.MediaType, dockerMediaTypes),
+		Size:      desc.Size,
+		Digest:    desc.Digest,
+		URLs:      desc.URLs,
+	}
+	if _, err := blobs.Stat(ctx, desc.Digest); err == nil {
+		return result, nil
+	}
+	f, err := os.Open(r.blobPath(desc.Digest))
+	if err != nil {
+		if os.IsNotExist(err) && len(desc.URLs) != 0 {
+			// foreign layers are not necessarily included in the layout
+			return result, nil
+		}
+		return result, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	writer, err := blobs.Create(ctx)
+	if err != nil {
+		return result, trace.Wrap(err)
+	}
+	defer writer.Close()
+	if _, err := io.Copy(writer, f); err != nil {
+		return result, trace.Wrap(err)
+	}
+	// the blob is verified against the digest on commit
+	if _, err := writer.Commit(ctx, result); err != nil {
+		return result, trace.Wrap(err)
+	}
+	return result, nil
+}
+
+// writeJSON writes the specified object to the layout as a blob
+// and returns its descriptor
+func (r ociLayout) writeJSON(mediaType string, obj interface{}) (*ociDescriptor, error) {
+	payload, err := json.Marshal(obj)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	dgst := digest.FromBytes(payload)
+	if err := writeVerifiedFile(r.blobPath(dgst), dgst, bytes.NewReader(payload)); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &ociDescriptor{
+		MediaType: mediaType,
+		Size:      int64(len(payload)),
+		Digest:    dgst,
+	}, nil
+}
+
+// readBlob returns the contents of the blob with the specified digest
+// verifying them
+func (r ociLayout) readBlob(dgst digest.Digest) ([]byte, error) {
+	if err := dgst.Validate(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	payload, err := ioutil.ReadFile(r.blobPath(dgst))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if digest.FromBytes(payload) != dgst {
+		return nil, trace.BadParameter("blob %v failed verification", dgst)
+	}
+	return payload, nil
+}
+
+// readIndex returns the index of the layout.
+//
+// Returns trace.NotFound if the directory does not contain a layout
+func (r ociLayout) readIndex() (*ociIndex, error) {
+	data, err := ioutil.ReadFile(filepath.Join(r.dir, ociLayoutFile))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	var header ociLayoutHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if header.Version != ociLayoutVersion {
+		return nil, trace.BadParameter("unsupported OCI image layout version %q", header.Version)
+	}
+	data, err = ioutil.ReadFile(filepath.Join(r.dir, ociIndexFile))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	var index ociIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &index, nil
+}
+
+// writeIndex writes the specified index and the layout header
+func (r ociLayout) writeIndex(index ociIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := writeFileAtomic(filepath.Join(r.dir, ociIndexFile), data); err != nil {
+		return trace.Wrap(err)
+	}
+	data, err = json.Marshal(ociLayoutHeader{Version: ociLayoutVersion})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(writeFileAtomic(filepath.Join(r.dir, ociLayoutFile), data))
+}
+
+func (r ociLayout) blobPath(dgst digest.Digest) string {
+	return filepath.Join(r.dir, "blobs", string(dgst.Algorithm()), dgst.Hex())
+}
+
+// add adds the specified manifest to the index replacing
+// the manifest with the same reference if there is one
+func (r *ociIndex) add(desc ociDescriptor) {
+	ref := desc.Annotations[ociAnnotationRefName]
+	for i, existing := range r.Manifests {
+		if existing.Annotations[ociAnnotationRefName] == ref {
+			r.Manifests[i] = desc
+			return
+		}
+	}
+	r.Manifests = append(r.Manifests, desc)
+}
+
+// convertMediaType returns the media type mapped to mediaType in the
+// specified conversion table or mediaType itself if there is no mapping
+func convertMediaType(mediaType string, mediaTypes map[string]string) string {
+	if converted, ok := mediaTypes[mediaType]; ok {
+		return converted
+	}
+	return mediaType
+}
+
+// ociLayoutHeader is the contents of the oci-layout file
+type ociLayoutHeader struct {
+	// Version is the version of the image layout
+	Version string `json:"imageLayoutVersion"`
+}
+
+// ociIndex is an OCI image index
+type ociIndex struct {
+	// SchemaVersion is the image manifest schema version
+	SchemaVersion int `json:"schemaVersion"`
+	// MediaType is the media type of the index
+	MediaType string `json:"mediaType,omitempty"`
+	// Manifests lists the manifests in the index
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// ociManifest is an OCI image manifest
+type ociManifest struct {
+	// SchemaVersion is the image manifest schema version
+	SchemaVersion int `json:"schemaVersion"`
+	// MediaType is the media type of the manifest
+	MediaType string `json:"mediaType,omitempty"`
+	// Config references the image configuration
+	Config ociDescriptor `json:"config"`
+	// Layers lists the image layers
+	Layers []ociDescriptor `json:"layers"`
+}
+
+// ociDescriptor references content in an OCI image layout
+type ociDescriptor struct {
+	// MediaType is the media type of the referenced content
+	MediaType string `json:"mediaType"`
+	// Digest is the digest of the referenced content
+	Digest digest.Digest `json:"digest"`
+	// Size is the size of the referenced content in bytes
+	Size int64 `json:"size"`
+	// URLs lists the locations the content can be downloaded from
+	URLs []string `json:"urls,omitempty"`
+	// Annotations specifies arbitrary metadata of the content
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Platform specifies the platform of an image in an image index
+	Platform *manifestlist.PlatformSpec `json:"platform,omitempty"`
+}
+
+const (
+	// ociLayoutFile is the name of the file with the layout header
+	ociLayoutFile = "oci-layout"
+	// ociIndexFile is the name of the file with the layout index
+	ociIndexFile = "index.json"
+	// ociLayoutVersion is the supported version of the image layout
+	ociLayoutVersion = "1.0.0"
+	// ociSchemaVersion is the schema version of OCI manifests and indexes
+	ociSchemaVersion = 2
+	// ociAnnotationRefName is the annotation with the reference of an image
+	// in the layout index
+	ociAnnotationRefName = "org.opencontainers.image.ref.name"
+
+	ociMediaTypeManifest     = "application/vnd.oci.image.manifest.v1+json"
+	ociMediaTypeIndex        = "application/vnd.oci.image.index.v1+json"
+	ociMediaTypeConfig       = "application/vnd.oci.image.config.v1+json"
+	ociMediaTypeLayer        = "application/vnd.oci.image.layer.v1.tar+gzip"
+	ociMediaTypeForeignLayer = "application/vnd.oci.image.layer.nondistributable.v1.tar+gzip"
+)
+
+// ociMediaTypes maps docker media types to their OCI counterparts
+var ociMediaTypes = map[string]string{
+	schema2.MediaTypeManifest:          ociMediaTypeManifest,
+	manifestlist.MediaTypeManifestList: ociMediaTypeIndex,
+	schema2.MediaTypeImageConfig:       ociMediaTypeConfig,
+	schema2.MediaTypeLayer:             ociMediaTypeLayer,
+	schema2.MediaTypeForeignLayer:      ociMediaTypeForeignLayer,
+}
+
+// dockerMediaTypes maps OCI media types to their docker counterparts
+var dockerMediaTypes = map[string]string{
+	ociMediaTypeManifest:     schema2.MediaTypeManifest,
+	ociMediaTypeIndex:        manifestlist.MediaTypeManifestList,
+	ociMediaTypeConfig:       schema2.MediaTypeImageConfig,
+	ociMediaTypeLayer:        schema2.MediaTypeLayer,
+	ociMediaTypeForeignLayer: schema2.MediaTypeForeignLayer,
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type OCISuite struct{}
+
+var _ = Suite(&OCISuite{})
+
+func (s *OCISuite) TestExportsAndImportsLayout(c *C) {
+	dir := c.MkDir()
+	registry, err := NewRegistry(BasicConfiguration("127.0.0.1:0", dir))
+	c.Assert(err, IsNil)
+	c.Assert(registry.Start(), IsNil)
+	pushTestImage(c, registry, "app", "1.0.0", []byte("shared layer"), []byte("layer 1.0.0"))
+	pushTestImage(c, registry, "vendor/multi", PlatformTag("1.0.0", "amd64"), []byte("amd64 layer"))
+	pushTestImage(c, registry, "vendor/multi", PlatformTag("1.0.0", "arm64"), []byte("arm64 layer"))
+	c.Assert(registry.Close(), IsNil)
+	ctx := context.Background()
+	err = CreateManifestList(ctx, dir, "vendor/multi", "1.0.0", []PlatformImage{
+		{Tag: PlatformTag("1.0.0", "amd64"), Arch: "amd64"},
+		{Tag: PlatformTag("1.0.0", "arm64"), Arch: "arm64"},
+	})
+	c.Assert(err, IsNil)
+
+	layoutDir := c.MkDir()
+	exported, err := ExportOCILayout(ctx, dir, layoutDir)
+	c.Assert(err, IsNil)
+	sortTags(exported)
+	images := []TagSpec{{Name: "app", Version: "1.0.0"}, {Name: "vendor/multi", Version: "1.0.0"}}
+	c.Assert(exported, DeepEquals, images)
+
+	layout := ociLayout{dir: layoutDir}
+	index, err := layout.readIndex()
+	c.Assert(err, IsNil)
+	mediaTypes := make(map[string]string)
+	for _, desc := range index.Manifests {
+		mediaTypes[desc.Annotations[ociAnnotationRefName]] = desc.MediaType
+	}
+	c.Assert(mediaTypes, DeepEquals, map[string]string{
+		"app:1.0.0":          ociMediaTypeManifest,
+		"vendor/multi:1.0.0": ociMediaTypeIndex,
+	})
+	for _, desc := range index.Manifests {
+		if desc.MediaType != ociMediaTypeManifest {
+			continue
+		}
+		payload, err := layout.readBlob(desc.Digest)
+		c.Assert(err, IsNil)
+		var manifest ociManifest
+		c.Assert(json.Unmarshal(payload, &manifest), IsNil)
+		c.Assert(manifest.Layers, HasLen, 2)
+		for _, blob := range append(manifest.Layers, manifest.Config) {
+			_, err := layout.readBlob(blob.Digest)
+			c.Assert(err, IsNil)
+		}
+	}
+
+	// exporting again does not duplicate the images in the index
+	_, err = ExportOCILayout(ctx, dir, layoutDir)
+	c.Assert(err, IsNil)
+	index, err = layout.readIndex()
+	c.Assert(err, IsNil)
+	c.Assert(index.Manifests, HasLen, 2)
+
+	importDir := c.MkDir()
+	imported, err := ImportOCILayout(ctx, layoutDir, importDir)
+	c.Assert(err, IsNil)
+	sortTags(imported)
+	c.Assert(imported, DeepEquals, images)
+	for _, image := range images {
+		c.Assert(getTestManifest(c, importDir, image.Name, image.Version), DeepEquals,
+			getTestManifest(c, dir, image.Name, image.Version), Commentf("image %v", image))
+	}
+}
+
+func (s *OCISuite) TestRejectsInvalidLayout(c *C) {
+	_, err := ImportOCILayout(context.Background(), c.MkDir(), c.MkDir())
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/docker/distribution/configuration"
+	"github.com/docker/distribution/registry/handlers"
+	"github.com/gravitational/trace"
+)
+
+// ProxyConfig defines the upstream registry the registry pulls
+// the images it does not have from
+type ProxyConfig struct {
+	// RemoteURL is the URL of the upstream registry, e.g. https://registry-1.docker.io
+	RemoteURL string `yaml:"remote_url"`
+	// Username is the optional upstream registry user
+	Username string `yaml:"username"`
+	// Password is the upstream registry user password
+	Password string `yaml:"password"`
+}
+
+// Check validates the upstream registry parameters
+func (r ProxyConfig) Check() error {
+	u, err := url.Parse(r.RemoteURL)
+	if err != nil {
+		return trace.BadParameter("invalid upstream registry URL %q: %v", r.RemoteURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return trace.BadParameter("upstream registry URL should be http:// or https://, got %q",
+			r.RemoteURL)
+	}
+	if u.Host == "" {
+		return trace.BadParameter("upstream registry URL %q is missing host", r.RemoteURL)
+	}
+	if r.Password != "" && r.Username == "" {
+		return trace.BadParameter("upstream registry password requires a username")
+	}
+	return nil
+}
+
+// checkProxy validates the upstream registry settings of the registry configuration
+func checkProxy(config *configuration.Configuration) error {
+	if config.Proxy.RemoteURL == "" {
+		return nil
+	}
+	proxy := ProxyConfig{
+		RemoteURL: config.Proxy.RemoteURL,
+		Username:  config.Proxy.Username,
+		Password:  config.Proxy.Password,
+	}
+	return trace.Wrap(proxy.Check())
+}
+
+// WithProxy configures the registry as a pull-through cache of the specified
+// upstream registry. The images in the local storage are served as usual and
+// the images missing locally are fetched from the upstream registry and
+// cached in the local storage
+func WithProxy(proxy ProxyConfig) ConfigOption {
+	return func(config *configuration.Configuration) {
+		config.Proxy = configuration.Proxy{
+			RemoteURL: proxy.RemoteURL,
+			Username:  proxy.Username,
+			Password:  proxy.Password,
+		}
+	}
+}
+
+// NewHandler returns a new HTTP handler serving registry API with the specified
+// configuration.
+//
+// In the pull-through cache mode, the registry serves the local content first
+// and only falls back to the upstream registry if the content is not found
+// locally. Unlike the cache mode of the distribution registry, pushes are
+// still accepted so the registry can serve the content that is not available
+// upstream
+func NewHandler(ctx context.Context, config *configuration.Configuration) (local *handlers.App, handler http.Handler) {
+	if config.Proxy.RemoteURL == "" {
+		local = handlers.NewApp(ctx, config)
+		return local, local
+	}
+	localConfig := *config
+	localConfig.Proxy = configuration.Proxy{}
+	local = handlers.NewApp(ctx, &localConfig)
+	cache := handlers.NewApp(ctx, config)
+	return local, &cacheHandler{local: local, cache: cache}
+}
+
+// cacheHandler serves read requests from the local registry falling back
+// to the pull-through cache for the content the local registry does not have
+type cacheHandler struct {
+	local http.Handler
+	cache http.Handler
+}
+
+// ServeHTTP serves the registry API request
+func (h *cacheHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		h.local.ServeHTTP(w, r)
+		return
+	}
+	writer := &fallbackWriter{ResponseWriter: w, header: make(http.Header)}
+	h.local.ServeHTTP(writer, r)
+	if !writer.notFound {
+		return
+	}
+	h.cache.ServeHTTP(w, r)
+}
+
+// fallbackWriter passes the response through to the underlying writer
+// unless the response status is 404 Not Found in which case the response
+// is discarded
+type fallbackWriter struct {
+	http.ResponseWriter
+	header      http.Header
+	wroteHeader bool
+	notFound    bool
+}
+
+// Header returns the response headers
+func (w *fallbackWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader writes the response status unless it is 404 Not Found
+func (w *fallbackWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if code == http.StatusNotFound {
+		w.notFound = true
+		return
+	}
+	for name, values := range w.header {
+		w.ResponseWriter.Header()[name] = values
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the response body unless the response is discarded
+func (w *fallbackWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.notFound {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush flushes the buffered response to the client
+func (w *fallbackWriter) Flush() {
+	if w.notFound {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// CloseNotify returns the channel that receives a value when the client
+// connection goes away
+func (w *fallbackWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
@@ -0,0 +1,450 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/run"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/docker/distribution"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReplicateRequest describes a request to replicate images between two registries
+type ReplicateRequest struct {
+	// Source is the registry to replicate images from
+	Source RegistryConnectionRequest
+	// Destination is the registry to replicate images to
+	Destination RegistryConnectionRequest
+	// Repositories optionally limits replication to the specified repositories.
+	// If unspecified, all repositories of the source registry are replicated
+	Repositories []string
+	// MissingOnly only replicates images whose tags are missing in the
+	// destination and leaves the tags that point to a different manifest intact
+	MissingOnly bool
+	// Parallel defines the number of images to replicate concurrently.
+	// If < 0, the number of concurrent tasks is not restricted,
+	// if unspecified, the tasks are capped at the number of logical CPU cores
+	Parallel int
+	// Progress is used to report replication progress
+	Progress utils.Emitter
+}
+
+// CheckAndSetDefaults validates the request and sets defaults
+func (r *ReplicateRequest) CheckAndSetDefaults() error {
+	if err := r.Source.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := r.Destination.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Source.RegistryAddress == r.Destination.RegistryAddress {
+		return trace.BadParameter("source and destination registries must be different")
+	}
+	if r.Parallel == 0 {
+		r.Parallel = runtime.NumCPU()
+	}
+	if r.Progress == nil {
+		r.Progress = utils.NopEmitter()
+	}
+	return nil
+}
+
+// ReplicateResult describes the outcome of replication
+type ReplicateResult struct {
+	// Replicated lists images that have been transferred to the destination
+	Replicated []TagSpec
+	// UpToDate lists images that have already been present in the destination
+	UpToDate []TagSpec
+	// Skipped lists images that differ in the destination and have been
+	// left intact because only missing images were requested
+	Skipped []TagSpec
+	// Blobs is the number of transferred blobs
+	Blobs int
+	// Bytes is the number of transferred bytes
+	Bytes int64
+}
+
+// Replicate transfers images from the source registry that are missing
+// or different in the destination registry.
+//
+// Only the blobs missing in the destination repository are transferred
+// and each transferred blob and manifest is verified after the transfer.
+// As the state of the destination is always consulted, an interrupted
+// replication can be resumed by running it again.
+func Replicate(ctx context.Context, req ReplicateRequest) (*ReplicateResult, error) {
+	if err := req.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	source, err := ConnectRegistry(ctx, req.Source)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to connect to registry at %q", req.Source.RegistryAddress)
+	}
+	destination, err := ConnectRegistry(ctx, req.Destination)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to connect to registry at %q", req.Destination.RegistryAddress)
+	}
+	repos := req.Repositories
+	if len(repos) == 0 {
+		repos, err = ListRepos(ctx, source)
+		if err != nil && err != io.EOF {
+			return nil, trace.Wrap(err, "failed to list repositories in %q", req.Source.RegistryAddress)
+		}
+	}
+	r := &replicator{
+		FieldLogger: log.WithField(trace.Component, "replicator"),
+		source:      source,
+		destination: destination,
+		progress:    req.Progress,
+		missingOnly: req.MissingOnly,
+		blobs:       make(map[string]*blobTransfer),
+	}
+	images, err := r.listImages(ctx, repos)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	group, groupCtx := run.WithContext(ctx, run.WithParallel(req.Parallel))
+	for _, image := range images {
+		image := image
+		group.Go(groupCtx, func() error {
+			return trace.Wrap(r.replicateImage(groupCtx, image))
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sortTags(r.result.Replicated)
+	sortTags(r.result.UpToDate)
+	sortTags(r.result.Skipped)
+	return &r.result, nil
+}
+
+type replicator struct {
+	log.FieldLogger
+	source      *remoteStore
+	destination *remoteStore
+	progress    utils.Emitter
+	missingOnly bool
+
+	// mu guards the fields below
+	mu sync.Mutex
+	// blobs tracks blob transfers by destination repository and digest
+	// so that layers shared by several images are transferred once
+	blobs  map[string]*blobTransfer
+	result ReplicateResult
+}
+
+// blobTransfer is the outcome of a single blob transfer
+type blobTransfer struct {
+	once sync.Once
+	err  error
+}
+
+// listImages returns all tagged images in the specified source repositories
+func (r *replicator) listImages(ctx context.Context, repos []string) (images []TagSpec, err error) {
+	for _, name := range repos {
+		repo, err := r.source.Repository(ctx, name)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		tags, err := repo.Tags(ctx).All(ctx)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to list tags of %v", name)
+		}
+		for _, tag := range tags {
+			images = append(images, TagSpec{Name: name, Version: tag})
+		}
+	}
+	return images, nil
+}
+
+// replicateImage transfers the specified image to the destination registry
+// unless the destination already has an identical manifest for it
+func (r *replicator) replicateImage(ctx context.Context, image TagSpec) error {
+	sourceRepo, err := r.source.Repository(ctx, image.Name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	destinationRepo, err := r.destination.Repository(ctx, image.Name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	manifest, err := getManifest(ctx, sourceRepo, image.Version)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if manifest == nil {
+		return trace.NotFound("image %v not found in source registry", image)
+	}
+	existing, err := getManifest(ctx, destinationRepo, image.Version)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if compareManifests(manifest, existing) {
+		r.progress.PrintStep("Image %v is up-to-date", image)
+		r.mu.Lock()
+		r.result.UpToDate = append(r.result.UpToDate, image)
+		r.mu.Unlock()
+		return nil
+	}
+	if existing != nil && r.missingOnly {
+		r.progress.PrintStep("Image %v differs in destination registry, skipping", image)
+		r.mu.Lock()
+		r.result.Skipped = append(r.result.Skipped, image)
+		r.mu.Unlock()
+		return nil
+	}
+
+	r.progress.PrintStep("Replicating image %v", image)
+	sourceManifests, err := sourceRepo.Manifests(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	platformManifests, err := getPlatformManifests(ctx, sourceManifests, manifest)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	syncImage := syncImage{manifest: manifest, platformManifests: platformManifests}
+	for _, desc := range syncImage.layers() {
+		if err := r.transferBlob(ctx, sourceRepo, destinationRepo, desc); err != nil {
+			return trace.Wrap(err, "failed to transfer layer %v of %v", desc.Digest, image)
+		}
+	}
+	destinationManifests, err := destinationRepo.Manifests(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, platformManifest := range platformManifests {
+		if _, err := destinationManifests.Put(ctx, platformManifest); err != nil {
+			return trace.Wrap(err, "failed to replicate platform manifest of %v", image)
+		}
+	}
+	_, err = destinationManifests.Put(ctx, manifest, distribution.WithTag(image.Version))
+	if err != nil {
+		return trace.Wrap(err, "failed to update manifest of %v", image)
+	}
+	existing, err = getManifest(ctx, destinationRepo, image.Version)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !compareManifests(manifest, existing) {
+		return trace.CompareFailed("manifest of %v in destination registry does not match the source", image)
+	}
+	r.mu.Lock()
+	r.result.Replicated = append(r.result.Replicated, image)
+	r.mu.Unlock()
+	return nil
+}
+
+// transferBlob transfers the blob specified with desc to the destination
+// repository unless it is already present or has been transferred by another image
+func (r *replicator) transferBlob(ctx context.Context, source, destination distribution.Repository, desc distribution.Descriptor) error {
+	key := fmt.Sprintf("%v@%v", destination.Named().Name(), desc.Digest)
+	r.mu.Lock()
+	transfer, ok := r.blobs[key]
+	if !ok {
+		transfer = &blobTransfer{}
+		r.blobs[key] = transfer
+	}
+	r.mu.Unlock()
+	transfer.once.Do(func() {
+		transfer.err = r.doTransferBlob(ctx, source, destination, desc)
+	})
+	return transfer.err
+}
+
+func (r *replicator) doTransferBlob(ctx context.Context, source, destination distribution.Repository, desc distribution.Descriptor) error {
+	destinationBlobs := destination.Blobs(ctx)
+	existing, err := destinationBlobs.Stat(ctx, desc.Digest)
+	if err != nil && err != distribution.ErrBlobUnknown {
+		return trace.Wrap(err)
+	}
+	if err == nil && existing.Digest == desc.Digest {
+		r.Debugf("Skipping layer %v: already present in %v.", desc.Digest, destination.Named())
+		return nil
+	}
+	written, err := copyBlob(ctx, destinationBlobs, source.Blobs(ctx), desc)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// the registry validates the content against the digest on commit,
+	// make sure the blob is now actually available in the destination
+	existing, err = destinationBlobs.Stat(ctx, desc.Digest)
+	if err != nil {
+		return trace.Wrap(err, "failed to verify layer %v", desc.Digest)
+	}
+	if existing.Digest != desc.Digest || (desc.Size != 0 && existing.Size != desc.Size) {
+		return trace.CompareFailed("layer %v in destination registry does not match the source: got %v (%v bytes), expected %v bytes",
+			desc.Digest, existing.Digest, existing.Size, desc.Size)
+	}
+	r.mu.Lock()
+	r.result.Blobs++
+	r.result.Bytes += written
+	r.mu.Unlock()
+	return nil
+}
+
+// getManifest returns the manifest for the specified tag in repo
+// or nil if the repository does not have this tag
+func getManifest(ctx context.Context, repo distribution.Repository, tag string) (distribution.Manifest, error) {
+	desc, err := repo.Tags(ctx).Get(ctx, tag)
+	if err != nil {
+		if isManifestMissing(err) {
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	manifests, err := repo.Manifests(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	manifest, err := manifests.Get(ctx, desc.Digest)
+	if err != nil {
+		if isManifestMissing(err) {
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	return manifest, nil
+}
+
+// isManifestMissing returns true if the specified error indicates
+// that either the manifest or the whole repository does not exist
+func isManifestMissing(err error) bool {
+	code := registryErrorCode(err)
+	return code == "MANIFEST_UNKNOWN" || code == "NAME_UNKNOWN"
+}
+
+// PeerReplicatorConfig defines the scheduled replication of images
+// from the registries of the other cluster master nodes
+type PeerReplicatorConfig struct {
+	// Local is the registry to replicate images to
+	Local RegistryConnectionRequest
+	// Peers returns the registries to replicate images from
+	Peers func() ([]RegistryConnectionRequest, error)
+	// Interval is how often the images are replicated
+	Interval time.Duration
+	// Parallel defines the number of images to replicate concurrently
+	Parallel int
+	// FieldLogger is used for logging
+	log.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (r *PeerReplicatorConfig) CheckAndSetDefaults() error {
+	if err := r.Local.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Peers == nil {
+		return trace.BadParameter("missing Peers")
+	}
+	if r.Interval == 0 {
+		r.Interval = defaults.RegistryReplicationInterval
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "registry-replicator")
+	}
+	return nil
+}
+
+// NewPeerReplicator returns a new scheduled replicator of images
+// from the peer registries
+func NewPeerReplicator(config PeerReplicatorConfig) (*PeerReplicator, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &PeerReplicator{PeerReplicatorConfig: config}, nil
+}
+
+// PeerReplicator periodically replicates the images missing in the local
+// registry from the registries of the other master nodes, so that every
+// master can serve all images if the active master fails.
+//
+// Only missing tags are replicated: if the same tag points to different
+// manifests in several registries, the local one is kept to avoid
+// the registries overwriting each other's tags
+type PeerReplicator struct {
+	PeerReplicatorConfig
+}
+
+// Run runs the replication loop until the context is cancelled
+func (r *PeerReplicator) Run(ctx context.Context) {
+	r.Info("Starting registry replicator.")
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			r.Info("Stopping registry replicator.")
+			return
+		}
+		if err := r.ReplicateFromPeers(ctx); err != nil {
+			r.Warnf("Failed to replicate registry images: %v.", trace.DebugReport(err))
+		}
+	}
+}
+
+// ReplicateFromPeers replicates the images missing in the local registry
+// from each of the peer registries.
+// Failure to replicate from a peer, e.g. because its node is down,
+// does not prevent replication from the remaining peers
+func (r *PeerReplicator) ReplicateFromPeers(ctx context.Context) error {
+	peers, err := r.Peers()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var errors []error
+	for _, peer := range peers {
+		result, err := Replicate(ctx, ReplicateRequest{
+			Source:      peer,
+			Destination: r.Local,
+			MissingOnly: true,
+			Parallel:    r.Parallel,
+		})
+		if err != nil {
+			errors = append(errors, trace.Wrap(err, "failed to replicate images from %v",
+				peer.RegistryAddress))
+			continue
+		}
+		if len(result.Replicated) != 0 {
+			r.Infof("Replicated %v images (%v layers) from %v.",
+				len(result.Replicated), result.Blobs, peer.RegistryAddress)
+		}
+		if len(result.Skipped) != 0 {
+			r.Warnf("Images %v differ in %v and have not been replicated.",
+				result.Skipped, peer.RegistryAddress)
+		}
+	}
+	return trace.NewAggregate(errors...)
+}
+
+func sortTags(tags []TagSpec) {
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].String() < tags[j].String()
+	})
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	. "gopkg.in/check.v1"
+)
+
+type ReplicateSuite struct {
+	source      *Registry
+	destination *Registry
+}
+
+var _ = Suite(&ReplicateSuite{})
+
+func (s *ReplicateSuite) SetUpTest(c *C) {
+	s.source = startTestRegistry(c)
+	s.destination = startTestRegistry(c)
+}
+
+func (s *ReplicateSuite) TearDownTest(c *C) {
+	s.source.Close()
+	s.destination.Close()
+}
+
+func (s *ReplicateSuite) TestReplicatesOnlyMissingImages(c *C) {
+	ctx := context.Background()
+	shared := []byte("shared layer")
+	s.pushImage(c, "app", "1.0.0", shared, []byte("layer 1.0.0"))
+	s.pushImage(c, "app", "2.0.0", shared, []byte("layer 2.0.0"))
+
+	req := ReplicateRequest{
+		Source:      RegistryConnectionRequest{RegistryAddress: s.source.Addr()},
+		Destination: RegistryConnectionRequest{RegistryAddress: s.destination.Addr()},
+		Parallel:    2,
+	}
+	result, err := Replicate(ctx, req)
+	c.Assert(err, IsNil)
+	c.Assert(result.Replicated, DeepEquals, []TagSpec{
+		{Name: "app", Version: "1.0.0"},
+		{Name: "app", Version: "2.0.0"},
+	})
+	c.Assert(result.UpToDate, IsNil)
+	// the shared layer is transferred once, each image also has
+	// a config blob and a layer of its own
+	c.Assert(result.Blobs, Equals, 5)
+
+	s.pushImage(c, "app", "3.0.0", shared, []byte("layer 3.0.0"))
+	result, err = Replicate(ctx, req)
+	c.Assert(err, IsNil)
+	c.Assert(result.Replicated, DeepEquals, []TagSpec{{Name: "app", Version: "3.0.0"}})
+	c.Assert(result.UpToDate, DeepEquals, []TagSpec{
+		{Name: "app", Version: "1.0.0"},
+		{Name: "app", Version: "2.0.0"},
+	})
+	c.Assert(result.Blobs, Equals, 2)
+
+	result, err = Replicate(ctx, req)
+	c.Assert(err, IsNil)
+	c.Assert(result.Replicated, IsNil)
+	c.Assert(result.UpToDate, HasLen, 3)
+	c.Assert(result.Blobs, Equals, 0)
+}
+
+func (s *ReplicateSuite) TestReplicatesOnlyMissingTags(c *C) {
+	s.pushImage(c, "app", "1.0.0", []byte("source layer"))
+	s.pushImage(c, "app", "2.0.0", []byte("layer 2.0.0"))
+	pushTestImage(c, s.destination, "app", "1.0.0", []byte("destination layer"))
+
+	result, err := Replicate(context.Background(), ReplicateRequest{
+		Source:      RegistryConnectionRequest{RegistryAddress: s.source.Addr()},
+		Destination: RegistryConnectionRequest{RegistryAddress: s.destination.Addr()},
+		MissingOnly: true,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.Replicated, DeepEquals, []TagSpec{{Name: "app", Version: "2.0.0"}})
+	c.Assert(result.Skipped, DeepEquals, []TagSpec{{Name: "app", Version: "1.0.0"}})
+}
+
+func (s *ReplicateSuite) TestReplicatesFromAvailablePeers(c *C) {
+	s.pushImage(c, "app", "1.0.0", []byte("layer 1.0.0"))
+	failed := startTestRegistry(c)
+	failedAddr := failed.Addr()
+	failed.Close()
+
+	local := startTestRegistry(c)
+	defer local.Close()
+	replicator, err := NewPeerReplicator(PeerReplicatorConfig{
+		Local: RegistryConnectionRequest{RegistryAddress: local.Addr()},
+		Peers: func() ([]RegistryConnectionRequest, error) {
+			return []RegistryConnectionRequest{
+				{RegistryAddress: failedAddr},
+				{RegistryAddress: s.source.Addr()},
+			}, nil
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(replicator.ReplicateFromPeers(context.Background()), NotNil)
+
+	result, err := Replicate(context.Background(), ReplicateRequest{
+		Source:      RegistryConnectionRequest{RegistryAddress: s.source.Addr()},
+		Destination: RegistryConnectionRequest{RegistryAddress: local.Addr()},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.Replicated, IsNil)
+	c.Assert(result.UpToDate, DeepEquals, []TagSpec{{Name: "app", Version: "1.0.0"}})
+}
+
+// pushImage pushes an image with the specified layers to the source registry
+func (s *ReplicateSuite) pushImage(c *C, name, tag string, layers ...[]byte) {
+	pushTestImage(c, s.source, name, tag, layers...)
+}
+
+// pushTestImage pushes an image with the specified layers to the registry
+func pushTestImage(c *C, registry *Registry, name, tag string, layers ...[]byte) {
+	ctx := context.Background()
+	store, err := ConnectRegistry(ctx, RegistryConnectionRequest{RegistryAddress: registry.Addr()})
+	c.Assert(err, IsNil)
+	repo, err := store.Repository(ctx, name)
+	c.Assert(err, IsNil)
+	blobs := repo.Blobs(ctx)
+	config, err := blobs.Put(ctx, schema2.MediaTypeImageConfig, []byte(`{"tag":"`+tag+`"}`))
+	c.Assert(err, IsNil)
+	var descriptors []distribution.Descriptor
+	for _, layer := range layers {
+		desc, err := blobs.Put(ctx, schema2.MediaTypeLayer, layer)
+		c.Assert(err, IsNil)
+		descriptors = append(descriptors, desc)
+	}
+	manifest, err := schema2.FromStruct(schema2.Manifest{
+		Versioned: schema2.SchemaVersion,
+		Config:    config,
+		Layers:    descriptors,
+	})
+	c.Assert(err, IsNil)
+	manifests, err := repo.Manifests(ctx)
+	c.Assert(err, IsNil)
+	_, err = manifests.Put(ctx, manifest, distribution.WithTag(tag))
+	c.Assert(err, IsNil)
+}
+
+func startTestRegistry(c *C) *Registry {
+	registry, err := NewRegistry(BasicConfiguration("127.0.0.1:0", c.MkDir()))
+	c.Assert(err, IsNil)
+	c.Assert(registry.Start(), IsNil)
+	return registry
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/configuration"
+	"github.com/docker/distribution/health"
+	dockerref "github.com/docker/distribution/reference"
+	registrystorage "github.com/docker/distribution/registry/storage"
+	"github.com/docker/distribution/registry/storage/driver/factory"
+	"github.com/gravitational/trace"
+	"github.com/opencontainers/go-digest"
+)
+
+// RegistryStatus describes the health and the storage usage of a registry
+type RegistryStatus struct {
+	// FailedChecks maps the names of the failing health checks to their errors
+	FailedChecks map[string]string
+	// StorageDriver is the name of the storage driver
+	StorageDriver string
+	// Repositories is the number of repositories
+	Repositories int
+	// Manifests is the number of manifests in all repositories
+	Manifests int
+	// Blobs is the number of blobs in the storage
+	Blobs int
+	// SizeBytes is the total size of the blobs
+	SizeBytes int64
+}
+
+// Healthy returns true if all registry health checks pass
+func (r RegistryStatus) Healthy() bool {
+	return len(r.FailedChecks) == 0
+}
+
+// Status returns the status of this registry
+func (r *Registry) Status(ctx context.Context) (*RegistryStatus, error) {
+	return GetStatus(ctx, r.config, r.health)
+}
+
+// GetStatus returns the status of the registry with the specified configuration.
+// The registry health is determined by the checks registered in checks
+func GetStatus(ctx context.Context, config *configuration.Configuration, checks *health.Registry) (*RegistryStatus, error) {
+	driver, err := factory.Create(config.Storage.Type(), config.Storage.Parameters())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	namespace, err := registrystorage.NewRegistry(ctx, driver)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	status := RegistryStatus{
+		FailedChecks:  checks.CheckStatus(),
+		StorageDriver: config.Storage.Type(),
+	}
+	err = namespace.Blobs().Enumerate(ctx, func(dgst digest.Digest) error {
+		desc, err := namespace.BlobStatter().Stat(ctx, dgst)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		status.Blobs++
+		status.SizeBytes += desc.Size
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	enumerator, ok := namespace.(distribution.RepositoryEnumerator)
+	if !ok {
+		return nil, trace.BadParameter("registry does not support repository enumeration")
+	}
+	err = enumerator.Enumerate(ctx, func(name string) error {
+		manifests, err := countManifests(ctx, namespace, name)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		status.Repositories++
+		status.Manifests += manifests
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &status, nil
+}
+
+// countManifests returns the number of manifests in the specified repository
+func countManifests(ctx context.Context, namespace distribution.Namespace, name string) (count int, err error) {
+	named, err := dockerref.WithName(name)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	repo, err := namespace.Repository(ctx, named)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	manifests, err := repo.Manifests(ctx)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	enumerator, ok := manifests.(distribution.ManifestEnumerator)
+	if !ok {
+		return 0, trace.BadParameter("registry does not support manifest enumeration")
+	}
+	err = enumerator.Enumerate(ctx, func(digest.Digest) error {
+		count++
+		return nil
+	})
+	return count, trace.Wrap(err)
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+
+	. "gopkg.in/check.v1"
+)
+
+type StatusSuite struct{}
+
+var _ = Suite(&StatusSuite{})
+
+func (s *StatusSuite) TestReportsStorageUsage(c *C) {
+	registry := startTestRegistry(c)
+	defer registry.Close()
+	pushTestImage(c, registry, "alpine", "1.0.0", []byte("layer1"))
+	pushTestImage(c, registry, "alpine", "2.0.0", []byte("layer1"), []byte("layer2"))
+	pushTestImage(c, registry, "nginx", "1.0.0", []byte("layer3"))
+
+	status, err := registry.Status(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(status.Healthy(), Equals, true)
+	c.Assert(status.StorageDriver, Equals, "filesystem")
+	c.Assert(status.Repositories, Equals, 2)
+	c.Assert(status.Manifests, Equals, 3)
+	// 3 layers, 2 distinct image configs and 3 manifests
+	c.Assert(status.Blobs, Equals, 8)
+	c.Assert(status.SizeBytes > 0, Equals, true)
+}
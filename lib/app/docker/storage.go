@@ -0,0 +1,127 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/docker/distribution/configuration"
+	"github.com/docker/distribution/registry/storage/driver/factory"
+	_ "github.com/docker/distribution/registry/storage/driver/s3-aws"
+	"github.com/gravitational/trace"
+)
+
+// StorageConfig defines the storage driver of a docker registry
+type StorageConfig struct {
+	// Driver is the name of the storage driver.
+	// Defaults to the filesystem driver
+	Driver string `json:"driver" yaml:"driver"`
+	// Parameters are the driver-specific parameters, see
+	// https://docs.docker.com/registry/configuration/#storage
+	// for parameters of the built-in drivers
+	Parameters map[string]interface{} `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+// CheckAndSetDefaults validates the storage configuration and sets defaults
+func (c *StorageConfig) CheckAndSetDefaults() error {
+	if c.Driver == "" {
+		c.Driver = StorageDriverFilesystem
+	}
+	if !isStorageDriverRegistered(c.Driver) {
+		return trace.BadParameter("unsupported registry storage driver %q, supported drivers are %v",
+			c.Driver, StorageDrivers())
+	}
+	// the registry panics if the driver fails to initialize so
+	// make sure the required parameters are present upfront
+	var required []string
+	switch c.Driver {
+	case StorageDriverS3, storageDriverS3AWS:
+		required = []string{"region", "bucket"}
+	}
+	for _, name := range required {
+		if _, ok := c.Parameters[name]; !ok {
+			return trace.BadParameter("missing %q parameter for registry storage driver %q", name, c.Driver)
+		}
+	}
+	return nil
+}
+
+// Apply configures the registry configuration with this storage driver
+func (c StorageConfig) Apply(config *configuration.Configuration) {
+	storage := configuration.Storage{}
+	// preserve the settings that are not a storage driver
+	for name, parameters := range config.Storage {
+		switch name {
+		case "cache", "delete", "maintenance", "redirect":
+			storage[name] = parameters
+		}
+	}
+	storage[c.Driver] = configuration.Parameters(c.Parameters)
+	config.Storage = storage
+}
+
+// RegisterStorageDriver makes the storage driver available to registries
+// under the specified name
+func RegisterStorageDriver(name string, driverFactory factory.StorageDriverFactory) {
+	factory.Register(name, driverFactory)
+	storageDrivers.Lock()
+	defer storageDrivers.Unlock()
+	storageDrivers.names[name] = struct{}{}
+}
+
+// StorageDrivers returns the names of the registered storage drivers
+func StorageDrivers() (names []string) {
+	storageDrivers.Lock()
+	defer storageDrivers.Unlock()
+	for name := range storageDrivers.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isStorageDriverRegistered(name string) bool {
+	storageDrivers.Lock()
+	defer storageDrivers.Unlock()
+	_, ok := storageDrivers.names[name]
+	return ok
+}
+
+func init() {
+	// drivers registered by the distribution packages imported for side effects
+	for _, name := range []string{StorageDriverFilesystem, StorageDriverS3, storageDriverS3AWS} {
+		storageDrivers.names[name] = struct{}{}
+	}
+}
+
+// storageDrivers lists the names of the registered storage drivers
+var storageDrivers = struct {
+	sync.Mutex
+	names map[string]struct{}
+}{
+	names: make(map[string]struct{}),
+}
+
+const (
+	// StorageDriverFilesystem is the name of the filesystem storage driver
+	StorageDriverFilesystem = "filesystem"
+	// StorageDriverS3 is the name of the AWS S3 (and S3-compatible) storage driver
+	StorageDriverS3 = "s3"
+	// storageDriverS3AWS is the alternative name of the S3 storage driver
+	storageDriverS3AWS = "s3aws"
+)
@@ -0,0 +1,62 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"github.com/docker/distribution/configuration"
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type StorageSuite struct{}
+
+var _ = Suite(&StorageSuite{})
+
+func (s *StorageSuite) TestValidatesStorageConfig(c *C) {
+	var config StorageConfig
+	c.Assert(config.CheckAndSetDefaults(), IsNil)
+	c.Assert(config.Driver, Equals, StorageDriverFilesystem)
+
+	config = StorageConfig{Driver: "unknown"}
+	c.Assert(trace.IsBadParameter(config.CheckAndSetDefaults()), Equals, true)
+
+	config = StorageConfig{Driver: StorageDriverS3, Parameters: map[string]interface{}{"region": "us-east-1"}}
+	c.Assert(trace.IsBadParameter(config.CheckAndSetDefaults()), Equals, true,
+		Commentf("expected missing bucket to be rejected"))
+
+	config.Parameters["bucket"] = "registry"
+	c.Assert(config.CheckAndSetDefaults(), IsNil)
+	c.Assert(StorageDrivers(), DeepEquals, []string{"filesystem", "s3", "s3aws"})
+}
+
+func (s *StorageSuite) TestAppliesStorageConfig(c *C) {
+	registryConfig := &configuration.Configuration{
+		Storage: configuration.Storage{
+			"cache":      configuration.Parameters{"blobdescriptor": "inmemory"},
+			"filesystem": configuration.Parameters{"rootdirectory": "/var/lib/registry"},
+		},
+	}
+	StorageConfig{
+		Driver:     StorageDriverS3,
+		Parameters: map[string]interface{}{"region": "us-east-1", "bucket": "registry"},
+	}.Apply(registryConfig)
+	c.Assert(registryConfig.Storage, DeepEquals, configuration.Storage{
+		"cache": configuration.Parameters{"blobdescriptor": "inmemory"},
+		"s3":    configuration.Parameters{"region": "us-east-1", "bucket": "registry"},
+	})
+	c.Assert(registryConfig.Storage.Type(), Equals, StorageDriverS3)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gravitational/gravity/lib/app"
+
+	"github.com/gravitational/roundtrip"
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+)
+
+/* searchCatalog returns the applications published to the catalog
+   (optionally for specific repository)
+
+   GET /app/v1/catalog?pattern=<pattern>&channel=<channel>
+   GET /app/v1/catalog/:repository_id?pattern=<pattern>&channel=<channel>
+
+Success Response:
+
+  [
+    {
+      "repository": "example.com",
+      "name": "app",
+      "version": "1.0.0",
+      "channel": "stable",
+      "icon": "https://example.com/app.png",
+      "readme": "...",
+      "upgrade": {"min_version": "0.9.0"},
+      "published": timestamp RFC 3339,
+      "kind": "Application",
+      "created": timestamp RFC 3339
+    }
+  ]
+*/
+func (h *WebHandler) searchCatalog(w http.ResponseWriter, req *http.Request, params httprouter.Params, context *handlerContext) error {
+	apps, err := context.applications.SearchCatalog(app.SearchCatalogRequest{
+		Repository: params.ByName("repository_id"),
+		Pattern:    req.FormValue("pattern"),
+		Channel:    req.FormValue("channel"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if apps == nil {
+		apps = []app.CatalogApp{}
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, apps)
+	return nil
+}
+
+/* publishApp publishes an application to the catalog
+
+   POST /app/v1/catalog/:repository_id/:package_id/:version
+
+   {
+     "channel": "stable",
+     "icon": "https://example.com/app.png",
+     "readme": "...",
+     "upgrade": {"min_version": "0.9.0", "release_notes": "..."}
+   }
+
+Success Response:
+
+   catalog entry
+*/
+func (h *WebHandler) publishApp(w http.ResponseWriter, req *http.Request, params httprouter.Params, context *handlerContext) error {
+	locator, err := appPackage(params)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var publishReq app.PublishAppRequest
+	if err := json.NewDecoder(req.Body).Decode(&publishReq); err != nil {
+		return trace.BadParameter("failed to decode publish request: %v", err)
+	}
+	publishReq.Package = *locator
+	entry, err := context.applications.PublishApp(publishReq)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, entry)
+	return nil
+}
+
+/* unpublishApp removes an application from the catalog
+
+   DELETE /app/v1/catalog/:repository_id/:package_id/:version
+
+Success Response:
+
+   true
+*/
+func (h *WebHandler) unpublishApp(w http.ResponseWriter, req *http.Request, params httprouter.Params, context *handlerContext) error {
+	locator, err := appPackage(params)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := context.applications.UnpublishApp(*locator); err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, true)
+	return nil
+}
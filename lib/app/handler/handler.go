@@ -109,6 +109,12 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.GET("/telekube/gravity", h.wrap(h.telekubeGravityBinary))
 	h.GET("/telekube/bin/:version/:os/:arch/:binary", h.wrap(h.telekubeBinary))
 
+	// Application catalog handlers.
+	h.GET("/app/v1/catalog", h.needsAuth(h.searchCatalog))
+	h.GET("/app/v1/catalog/:repository_id", h.needsAuth(h.searchCatalog))
+	h.POST("/app/v1/catalog/:repository_id/:package_id/:version", h.needsAuth(h.publishApp))
+	h.DELETE("/app/v1/catalog/:repository_id/:package_id/:version", h.needsAuth(h.unpublishApp))
+
 	// Helm charts repository handlers.
 	h.GET("/charts/:name", h.needsAuth(h.fetchChart))
 	h.GET("/app/v1/charts/:name", h.needsAuth(h.fetchChart)) // Alias for /charts/:name for easier testing.
@@ -509,6 +515,7 @@ func (h *WebHandler) exportApp(w http.ResponseWriter, req *http.Request,
 	if err = context.applications.ExportApp(app.ExportAppRequest{
 		Package:         *locator,
 		RegistryAddress: config.RegistryHostPort,
+		Parallel:        config.Parallel,
 	}); err != nil {
 		return trace.Wrap(err)
 	}
@@ -764,6 +771,10 @@ func (h *WebHandler) deleteAppHookJob(w http.ResponseWriter,
 
 POST /app/v1/operations/uninstall/:repository_id/:package_id/:version
 
+  {
+	"force": true
+  }
+
 Success Response:
 
   {
@@ -778,7 +789,15 @@ func (h *WebHandler) uninstallApp(w http.ResponseWriter, req *http.Request,
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	app, err := context.applications.UninstallApp(*locator)
+	var config serviceapi.UninstallConfig
+	if err := json.NewDecoder(req.Body).Decode(&config); err != nil {
+		return trace.Wrap(err)
+	}
+	uninstallReq := app.UninstallAppRequest{
+		Package: *locator,
+		Force:   config.Force,
+	}
+	app, err := context.applications.UninstallApp(uninstallReq)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -1123,7 +1142,7 @@ else
 fi
 
 ARCH=$(uname -m)
-if [[ ! $ARCH == 'x86_64' ]]; then
+if [[ ! $ARCH == 'x86_64' && ! $ARCH == 'aarch64' ]]; then
     echo "Architecture $ARCH is not supported"
     exit 1
 fi
@@ -372,6 +372,7 @@ cp /opt/bin/gravity {{.StateDir}}/
 {{end}}
 TMPDIR={{.StateDir}} {{.StateDir}}/gravity --state-dir={{.StateDir}} app unpack \
 	--service-uid={{.ServiceUser.UID}} \
+	--service-gid={{.ServiceUser.GID}} \
 	--insecure --ops-url=$ops_url \
 	{{.Package}} {{.ResourcesDir}};
 mv {{.ResourcesDir}}/resources/* {{.ResourcesDir}}
@@ -379,7 +380,7 @@ rm -r {{.ResourcesDir}}/resources
 `))
 
 var initInstallScriptTemplate = template.Must(template.New("sh").Parse(`
-TMPDIR={{.StateDir}} /opt/bin/gravity app unpack --service-uid={{.ServiceUser.UID}} {{.Package}} {{.ResourcesDir}}
+TMPDIR={{.StateDir}} /opt/bin/gravity app unpack --service-uid={{.ServiceUser.UID}} --service-gid={{.ServiceUser.GID}} {{.Package}} {{.ResourcesDir}}
 mv {{.ResourcesDir}}/resources/* {{.ResourcesDir}}
 rm -r {{.ResourcesDir}}/resources
 `))
@@ -367,7 +367,7 @@ func (r *Runner) evalJobStatus(ctx context.Context, eventsC <-chan watch.Event)
 			}
 			if failure := findFailure(*job); failure != nil {
 				log.Debugf("Failed: %v.", failure.Message)
-				return trace.BadParameter(failure.Message)
+				return r.jobError(job, failure.Message)
 			}
 		case <-ctx.Done():
 			return nil
@@ -375,6 +375,46 @@ func (r *Runner) evalJobStatus(ctx context.Context, eventsC <-chan watch.Event)
 	}
 }
 
+// jobError returns the error for the failed job. The error carries
+// the exit code of the most recently failed hook container if it can be determined
+func (r *Runner) jobError(job *batchv1.Job, message string) error {
+	err := trace.BadParameter(message)
+	pods, errCollect := r.collectPods(job)
+	if errCollect != nil {
+		r.Warnf("Failed to collect pods of %v: %v.", describe(job), trace.DebugReport(errCollect))
+		return err
+	}
+	if code := exitCode(pods); code != 0 {
+		return utils.NewExitCodeError(err, code)
+	}
+	return err
+}
+
+// exitCode returns the exit code of the most recently terminated
+// container that has failed among the specified pods
+func exitCode(pods map[string]v1.Pod) int {
+	var last *v1.ContainerStateTerminated
+	for _, pod := range pods {
+		for _, statuses := range [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+			for _, status := range statuses {
+				for _, state := range []v1.ContainerState{status.State, status.LastTerminationState} {
+					terminated := state.Terminated
+					if terminated == nil || terminated.ExitCode == 0 {
+						continue
+					}
+					if last == nil || terminated.FinishedAt.After(last.FinishedAt.Time) {
+						last = terminated
+					}
+				}
+			}
+		}
+	}
+	if last == nil {
+		return 0
+	}
+	return int(last.ExitCode)
+}
+
 func newJobWatch(client batch.BatchV1Interface, ref JobRef) (watch.Interface, error) {
 	watcher, err := client.Jobs(ref.Namespace).Watch(metav1.ListOptions{
 		TypeMeta: metav1.TypeMeta{
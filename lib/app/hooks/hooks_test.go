@@ -292,3 +292,42 @@ func (s *HooksSuite) TestHookFailPodRestart(c *check.C) {
 	err = runner.DeleteJob(context.TODO(), DeleteJobRequest{JobRef: *ref})
 	c.Assert(err, check.IsNil)
 }
+
+type ExitCodeSuite struct{}
+
+var _ = check.Suite(&ExitCodeSuite{})
+
+func (s *ExitCodeSuite) TestReturnsLastFailedContainerExitCode(c *check.C) {
+	start := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	terminated := func(code int32, finished time.Time) v1.ContainerState {
+		return v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+			ExitCode:   code,
+			FinishedAt: metav1.NewTime(finished),
+		}}
+	}
+	pods := map[string]v1.Pod{
+		"hook-1": {
+			Status: v1.PodStatus{
+				InitContainerStatuses: []v1.ContainerStatus{
+					{State: terminated(0, start)},
+				},
+				ContainerStatuses: []v1.ContainerStatus{
+					{State: terminated(2, start.Add(time.Minute))},
+				},
+			},
+		},
+		"hook-2": {
+			Status: v1.PodStatus{
+				ContainerStatuses: []v1.ContainerStatus{
+					{
+						State:                v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+						LastTerminationState: terminated(3, start.Add(2*time.Minute)),
+					},
+				},
+			},
+		},
+	}
+	c.Assert(exitCode(pods), check.Equals, 3)
+	c.Assert(exitCode(map[string]v1.Pod{"hook-1": pods["hook-1"]}), check.Equals, 2)
+	c.Assert(exitCode(nil), check.Equals, 0)
+}
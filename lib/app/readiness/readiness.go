@@ -0,0 +1,308 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package readiness evaluates the application readiness checks
+// declared in the manifest
+package readiness
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/httplib"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Config defines the readiness checker configuration
+type Config struct {
+	// Client is the Kubernetes client
+	Client *kubernetes.Clientset
+	// DNSAddr is the address of the cluster DNS server used
+	// to resolve the cluster-local addresses
+	DNSAddr string
+	// PrometheusURL is the URL of the Prometheus API
+	PrometheusURL string
+	// FieldLogger is used for logging
+	log.FieldLogger
+}
+
+// CheckAndSetDefaults validates the config and sets defaults
+func (r *Config) CheckAndSetDefaults() error {
+	if r.Client == nil {
+		return trace.BadParameter("missing parameter Client")
+	}
+	if r.PrometheusURL == "" {
+		r.PrometheusURL = defaults.PrometheusServiceURL
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "readiness")
+	}
+	return nil
+}
+
+// New returns a new readiness checker
+func New(config Config) (*Checker, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Checker{Config: config}, nil
+}
+
+// Checker evaluates the application readiness checks
+type Checker struct {
+	// Config is the checker configuration
+	Config
+}
+
+// Wait waits for all of the specified checks to succeed.
+// Each check is retried until it succeeds or its timeout expires
+func (r *Checker) Wait(ctx context.Context, checks []schema.ReadinessCheck) error {
+	for _, check := range checks {
+		timeout, err := check.GetTimeout()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Infof("Waiting for readiness check %q.", check.Name)
+		err = utils.RetryFor(ctx, timeout, func() error {
+			return r.Check(ctx, check)
+		})
+		if err != nil {
+			return trace.Wrap(err, "readiness check %q failed", check.Name)
+		}
+		r.Infof("Readiness check %q succeeded.", check.Name)
+	}
+	return nil
+}
+
+// Check evaluates the specified check once
+func (r *Checker) Check(ctx context.Context, check schema.ReadinessCheck) error {
+	switch {
+	case check.HTTP != nil:
+		return r.checkHTTP(ctx, *check.HTTP)
+	case check.Resource != nil:
+		return r.checkResource(*check.Resource)
+	case check.Prometheus != nil:
+		return r.checkPrometheus(ctx, *check.Prometheus)
+	}
+	return trace.BadParameter("readiness check %q does not specify a condition", check.Name)
+}
+
+func (r *Checker) checkHTTP(ctx context.Context, check schema.HTTPReadinessCheck) error {
+	resp, err := r.get(ctx, check.URL, check.Insecure)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if check.Status != 0 {
+		if resp.StatusCode != check.Status {
+			return trace.CompareFailed("%v responded with %v, expected %v",
+				check.URL, resp.StatusCode, check.Status)
+		}
+		return nil
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return trace.CompareFailed("%v responded with %v", check.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+func (r *Checker) checkResource(check schema.ResourceReadinessCheck) error {
+	meta := metav1.ObjectMeta{
+		Name:      check.Name,
+		Namespace: check.GetNamespace(),
+	}
+	switch check.Kind {
+	case rigging.KindDeployment:
+		control, err := rigging.NewDeploymentControl(rigging.DeploymentConfig{
+			Deployment: &appsv1.Deployment{ObjectMeta: meta},
+			Client:     r.Client,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(control.Status())
+	case rigging.KindDaemonSet:
+		control, err := rigging.NewDaemonSetControl(rigging.DSConfig{
+			DaemonSet: &appsv1.DaemonSet{ObjectMeta: meta},
+			Client:    r.Client,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(control.Status())
+	case rigging.KindStatefulSet:
+		control, err := rigging.NewStatefulSetControl(rigging.StatefulSetConfig{
+			StatefulSet: &appsv1.StatefulSet{ObjectMeta: meta},
+			Client:      r.Client,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(control.Status())
+	case rigging.KindJob:
+		control, err := rigging.NewJobControl(rigging.JobConfig{
+			Job:       &batchv1.Job{ObjectMeta: meta},
+			Clientset: r.Client,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(control.Status())
+	case kindPod:
+		pod, err := r.Client.CoreV1().Pods(meta.Namespace).Get(meta.Name, metav1.GetOptions{})
+		if err != nil {
+			return trace.Wrap(rigging.ConvertError(err))
+		}
+		if !isPodReady(*pod) {
+			return trace.CompareFailed("pod %v/%v is not ready", meta.Namespace, meta.Name)
+		}
+		return nil
+	}
+	return trace.BadParameter("unsupported resource kind %q", check.Kind)
+}
+
+func (r *Checker) checkPrometheus(ctx context.Context, check schema.PrometheusReadinessCheck) error {
+	queryURL, err := url.Parse(r.PrometheusURL)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	queryURL.Path = "/api/v1/query"
+	queryURL.RawQuery = url.Values{"query": []string{check.Query}}.Encode()
+	resp, err := r.get(ctx, queryURL.String(), false)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return trace.CompareFailed("prometheus responded with %v to query %q",
+			resp.StatusCode, check.Query)
+	}
+	var result queryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(result.check(check.Query))
+}
+
+func (r *Checker) get(ctx context.Context, addr string, insecure bool) (*http.Response, error) {
+	options := []httplib.ClientOption{httplib.WithTimeout(defaults.ReadinessCheckRequestTimeout)}
+	if r.DNSAddr != "" {
+		options = append(options, httplib.WithLocalResolver(r.DNSAddr))
+	}
+	client := httplib.GetClient(insecure, options...)
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return resp, nil
+}
+
+// queryResponse is the Prometheus instant query API response
+type queryResponse struct {
+	// Status is the response status, either success or error
+	Status string `json:"status"`
+	// Error is the error message if the query failed
+	Error string `json:"error"`
+	// Data is the query result
+	Data struct {
+		// ResultType is the type of the result: vector, scalar, etc.
+		ResultType string `json:"resultType"`
+		// Result is the raw query result
+		Result json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// check returns nil if the query returned a non-empty result
+// and none of the values are zero
+func (r queryResponse) check(query string) error {
+	if r.Status != "success" {
+		return trace.BadParameter("query %q failed: %v", query, r.Error)
+	}
+	var values []string
+	switch r.Data.ResultType {
+	case "vector":
+		var samples []struct {
+			Value []interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(r.Data.Result, &samples); err != nil {
+			return trace.Wrap(err)
+		}
+		for _, sample := range samples {
+			values = append(values, sampleValue(sample.Value))
+		}
+	case "scalar":
+		var sample []interface{}
+		if err := json.Unmarshal(r.Data.Result, &sample); err != nil {
+			return trace.Wrap(err)
+		}
+		values = append(values, sampleValue(sample))
+	default:
+		return trace.BadParameter("query %q returned unsupported result type %q",
+			query, r.Data.ResultType)
+	}
+	if len(values) == 0 {
+		return trace.CompareFailed("query %q returned empty result", query)
+	}
+	for _, value := range values {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return trace.BadParameter("query %q returned invalid value %q", query, value)
+		}
+		if number == 0 {
+			return trace.CompareFailed("query %q returned zero value", query)
+		}
+	}
+	return nil
+}
+
+// sampleValue returns the value of the sample given as [timestamp, "value"]
+func sampleValue(sample []interface{}) string {
+	if len(sample) != 2 {
+		return ""
+	}
+	value, _ := sample[1].(string)
+	return value
+}
+
+// kindPod is the kind of the Kubernetes pod resource
+const kindPod = "Pod"
+
+func isPodReady(pod v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
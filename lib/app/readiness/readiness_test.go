@@ -0,0 +1,119 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+func TestReadiness(t *testing.T) { check.TestingT(t) }
+
+type ReadinessSuite struct{}
+
+var _ = check.Suite(&ReadinessSuite{})
+
+func (s *ReadinessSuite) TestHTTPCheck(c *check.C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ready" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	checker := newTestChecker(server.URL)
+
+	err := checker.Check(context.TODO(), schema.ReadinessCheck{
+		Name: "ready",
+		HTTP: &schema.HTTPReadinessCheck{URL: server.URL + "/ready"},
+	})
+	c.Assert(err, check.IsNil)
+
+	err = checker.Check(context.TODO(), schema.ReadinessCheck{
+		Name: "status",
+		HTTP: &schema.HTTPReadinessCheck{URL: server.URL + "/ready", Status: http.StatusOK},
+	})
+	c.Assert(trace.IsCompareFailed(err), check.Equals, true)
+
+	err = checker.Check(context.TODO(), schema.ReadinessCheck{
+		Name: "unavailable",
+		HTTP: &schema.HTTPReadinessCheck{URL: server.URL + "/unavailable"},
+	})
+	c.Assert(trace.IsCompareFailed(err), check.Equals, true)
+}
+
+func (s *ReadinessSuite) TestPrometheusCheck(c *check.C) {
+	var tests = []struct {
+		result  string
+		healthy bool
+		comment string
+	}{
+		{
+			result:  `{"resultType":"vector","result":[{"metric":{},"value":[1571300000.0,"1"]}]}`,
+			healthy: true,
+			comment: "non-zero vector",
+		},
+		{
+			result:  `{"resultType":"vector","result":[]}`,
+			comment: "empty vector",
+		},
+		{
+			result:  `{"resultType":"vector","result":[{"metric":{"a":"1"},"value":[1571300000.0,"3"]},{"metric":{"a":"2"},"value":[1571300000.0,"0"]}]}`,
+			comment: "vector with zero value",
+		},
+		{
+			result:  `{"resultType":"scalar","result":[1571300000.0,"0.5"]}`,
+			healthy: true,
+			comment: "non-zero scalar",
+		},
+	}
+	for _, tt := range tests {
+		comment := check.Commentf(tt.comment)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.Assert(r.URL.Path, check.Equals, "/api/v1/query", comment)
+			c.Assert(r.URL.Query().Get("query"), check.Equals, "up == 1", comment)
+			fmt.Fprintf(w, `{"status":"success","data":%v}`, tt.result)
+		}))
+		err := newTestChecker(server.URL).Check(context.TODO(), schema.ReadinessCheck{
+			Name:       "up",
+			Prometheus: &schema.PrometheusReadinessCheck{Query: "up == 1"},
+		})
+		server.Close()
+		if tt.healthy {
+			c.Assert(err, check.IsNil, comment)
+		} else {
+			c.Assert(err, check.NotNil, comment)
+		}
+	}
+}
+
+func newTestChecker(prometheusURL string) *Checker {
+	return &Checker{Config: Config{
+		PrometheusURL: prometheusURL,
+		FieldLogger:   log.WithField("test", "readiness"),
+	}}
+}
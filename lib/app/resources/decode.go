@@ -24,13 +24,28 @@ import (
 
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	runtimeserializer "k8s.io/apimachinery/pkg/runtime/serializer"
 	serializer "k8s.io/apimachinery/pkg/runtime/serializer/json"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
+// decoderScheme is the scheme with the types recognized by the decoder:
+// the built-in Kubernetes types and custom resource definitions
+var decoderScheme = runtime.NewScheme()
+
+// decoderCodecs provides the deserializer for the types in decoderScheme
+var decoderCodecs = runtimeserializer.NewCodecFactory(decoderScheme)
+
+func init() {
+	utilruntime.Must(scheme.AddToScheme(decoderScheme))
+	utilruntime.Must(apiextensionsv1beta1.AddToScheme(decoderScheme))
+}
+
 // Decode decodes kubernetes resources from the specified io.Reader
 func Decode(r io.Reader, options ...DecodeOption) (resource *Resource, err error) {
 	decoder, _, encoding := newCodec(r, options...)
@@ -114,7 +129,7 @@ func newCodec(r io.Reader, options ...DecodeOption) (decoder *universalDecoder,
 // decode both YAML and JSON streams
 func newUniversalDecoder(r io.Reader) *universalDecoder {
 	streamDecoder := yaml.NewYAMLOrJSONDecoder(r, bufferSize)
-	decoder := scheme.Codecs.UniversalDeserializer()
+	decoder := decoderCodecs.UniversalDeserializer()
 	return &universalDecoder{
 		streamDecoder: streamDecoder,
 		Decoder:       decoder,
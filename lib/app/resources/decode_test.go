@@ -81,6 +81,22 @@ func (*ResourceCodecSuite) TestDecodeUnrecognizedResource(c *C) {
 	})
 }
 
+func (*ResourceCodecSuite) TestDecodesBootstrapResources(c *C) {
+	resource, err := Decode(strings.NewReader(bootstrapResources))
+	c.Assert(err, IsNil)
+	var types []string
+	for _, object := range resource.Objects {
+		types = append(types, fmt.Sprintf("%T", object))
+	}
+	c.Assert(types, DeepEquals, []string{
+		"*v1.ServiceAccount",
+		"*v1.DaemonSet",
+		"*v1.Deployment",
+		"*v1.NetworkPolicy",
+		"*v1beta1.CustomResourceDefinition",
+	})
+}
+
 func (_ *ResourceCodecSuite) TestEncodesInProperFormat(c *C) {
 	var testCases = []struct {
 		resource string
@@ -276,3 +292,67 @@ spec:
   cronSpec: "* * * * */5"
   image: my-awesome-cron-image
 `
+
+const bootstrapResources = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: agent
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+  namespace: kube-system
+spec:
+  selector:
+    matchLabels:
+      app: agent
+  template:
+    metadata:
+      labels:
+        app: agent
+    spec:
+      serviceAccountName: agent
+      containers:
+      - name: agent
+        image: agent:1.0.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controller
+  namespace: kube-system
+spec:
+  selector:
+    matchLabels:
+      app: controller
+  template:
+    metadata:
+      labels:
+        app: controller
+    spec:
+      containers:
+      - name: controller
+        image: controller:1.0.0
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: deny-all
+  namespace: kube-system
+spec:
+  podSelector: {}
+---
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: crontabs.stable.example.com
+spec:
+  group: stable.example.com
+  version: v1
+  scope: Namespaced
+  names:
+    plural: crontabs
+    kind: CronTab
+`
@@ -0,0 +1,257 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ValidateLimits returns a validator that reports workloads that would be
+// rejected on admission because their containers' resource requests and limits
+// violate a LimitRange, or do not specify the resources tracked by
+// a ResourceQuota, bundled in the same namespace.
+//
+// LimitRange defaults are applied to containers before validation
+// the same way the admission controller does
+func ValidateLimits() ValidateFunc {
+	return func(objects []runtime.Object) (findings []Finding) {
+		limitRanges := make(map[string][]corev1.LimitRange)
+		quotas := make(map[string][]corev1.ResourceQuota)
+		for _, object := range objects {
+			switch resource := object.(type) {
+			case *corev1.LimitRange:
+				namespace := namespaceOrDefault(resource.Namespace)
+				limitRanges[namespace] = append(limitRanges[namespace], *resource)
+			case *corev1.ResourceQuota:
+				namespace := namespaceOrDefault(resource.Namespace)
+				quotas[namespace] = append(quotas[namespace], *resource)
+			}
+		}
+		if len(limitRanges) == 0 && len(quotas) == 0 {
+			return nil
+		}
+		for _, template := range podTemplates(objects) {
+			var messages []string
+			containers := effectiveContainers(template.spec.Containers, limitRanges[template.namespace])
+			for _, limitRange := range limitRanges[template.namespace] {
+				messages = append(messages, checkLimitRange(limitRange, containers)...)
+			}
+			for _, quota := range quotas[template.namespace] {
+				messages = append(messages, checkResourceQuota(quota, containers)...)
+			}
+			for _, message := range messages {
+				findings = append(findings, Finding{
+					Object:  template.object,
+					Message: message,
+				})
+			}
+		}
+		return findings
+	}
+}
+
+// containerResources describes effective compute resources of a container
+type containerResources struct {
+	// name is the container name
+	name string
+	// corev1.ResourceRequirements lists container's requests and limits
+	corev1.ResourceRequirements
+}
+
+// effectiveContainers returns resource requirements of the specified containers
+// after application of defaults from the given limit ranges
+func effectiveContainers(containers []corev1.Container, limitRanges []corev1.LimitRange) (result []containerResources) {
+	for _, container := range containers {
+		resources := containerResources{
+			name: container.Name,
+			ResourceRequirements: corev1.ResourceRequirements{
+				Requests: copyResourceList(container.Resources.Requests),
+				Limits:   copyResourceList(container.Resources.Limits),
+			},
+		}
+		for _, limitRange := range limitRanges {
+			for _, item := range limitRange.Spec.Limits {
+				if item.Type != corev1.LimitTypeContainer {
+					continue
+				}
+				setMissing(resources.Limits, item.Default)
+				setMissing(resources.Requests, item.DefaultRequest)
+			}
+		}
+		// a missing request defaults to the limit
+		setMissing(resources.Requests, resources.Limits)
+		result = append(result, resources)
+	}
+	return result
+}
+
+// checkLimitRange returns violations of the specified limit range by the given containers
+func checkLimitRange(limitRange corev1.LimitRange, containers []containerResources) (messages []string) {
+	prefix := fmt.Sprintf("LimitRange %v", namespacedName(limitRange.Namespace, limitRange.Name))
+	for _, item := range limitRange.Spec.Limits {
+		switch item.Type {
+		case corev1.LimitTypeContainer:
+			for _, container := range containers {
+				for _, violation := range checkLimitRangeItem(item, container.ResourceRequirements) {
+					messages = append(messages, fmt.Sprintf("container %v violates %v: %v",
+						container.name, prefix, violation))
+				}
+			}
+		case corev1.LimitTypePod:
+			for _, violation := range checkLimitRangeItem(item, podResources(containers)) {
+				messages = append(messages, fmt.Sprintf("pod violates %v: %v", prefix, violation))
+			}
+		}
+	}
+	return messages
+}
+
+// checkLimitRangeItem returns violations of the limit range item by the specified resources
+func checkLimitRangeItem(item corev1.LimitRangeItem, resources corev1.ResourceRequirements) (violations []string) {
+	for _, name := range sortedResourceNames(item.Min) {
+		minimum := item.Min[name]
+		request, ok := resources.Requests[name]
+		if !ok {
+			violations = append(violations, fmt.Sprintf("minimum %v is %v but no request is specified", name, minimum.String()))
+			continue
+		}
+		if request.Cmp(minimum) < 0 {
+			violations = append(violations, fmt.Sprintf("%v request %v is below minimum %v", name, request.String(), minimum.String()))
+		}
+	}
+	for _, name := range sortedResourceNames(item.Max) {
+		maximum := item.Max[name]
+		limit, ok := resources.Limits[name]
+		if !ok {
+			violations = append(violations, fmt.Sprintf("maximum %v is %v but no limit is specified", name, maximum.String()))
+			continue
+		}
+		if limit.Cmp(maximum) > 0 {
+			violations = append(violations, fmt.Sprintf("%v limit %v exceeds maximum %v", name, limit.String(), maximum.String()))
+		}
+	}
+	for _, name := range sortedResourceNames(item.MaxLimitRequestRatio) {
+		ratio := item.MaxLimitRequestRatio[name]
+		request, hasRequest := resources.Requests[name]
+		limit, hasLimit := resources.Limits[name]
+		if !hasRequest || !hasLimit || request.IsZero() {
+			violations = append(violations, fmt.Sprintf("maximum %v limit to request ratio is %v but request or limit is not specified",
+				name, ratio.String()))
+			continue
+		}
+		actual := float64(limit.MilliValue()) / float64(request.MilliValue())
+		if actual > float64(ratio.MilliValue())/1000 {
+			violations = append(violations, fmt.Sprintf("%v limit to request ratio %.2f exceeds maximum %v",
+				name, actual, ratio.String()))
+		}
+	}
+	return violations
+}
+
+// checkResourceQuota returns resources tracked by the specified quota
+// that are not specified by the given containers
+func checkResourceQuota(quota corev1.ResourceQuota, containers []containerResources) (messages []string) {
+	for _, name := range sortedResourceNames(quota.Spec.Hard) {
+		var kind string
+		var resource corev1.ResourceName
+		switch name {
+		case corev1.ResourceCPU, corev1.ResourceMemory:
+			kind, resource = "request", name
+		case corev1.ResourceRequestsCPU, corev1.ResourceRequestsMemory:
+			kind, resource = "request", corev1.ResourceName(strings.TrimPrefix(string(name), "requests."))
+		case corev1.ResourceLimitsCPU, corev1.ResourceLimitsMemory:
+			kind, resource = "limit", corev1.ResourceName(strings.TrimPrefix(string(name), "limits."))
+		default:
+			continue
+		}
+		for _, container := range containers {
+			list := container.Requests
+			if kind == "limit" {
+				list = container.Limits
+			}
+			if _, ok := list[resource]; !ok {
+				messages = append(messages, fmt.Sprintf("container %v does not specify %v %v required by ResourceQuota %v",
+					container.name, resource, kind, namespacedName(quota.Namespace, quota.Name)))
+			}
+		}
+	}
+	return messages
+}
+
+// podResources returns the total of resources of the specified containers.
+// Resources not specified by all of the containers are omitted
+func podResources(containers []containerResources) corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: sumResources(containers, func(r containerResources) corev1.ResourceList { return r.Requests }),
+		Limits:   sumResources(containers, func(r containerResources) corev1.ResourceList { return r.Limits }),
+	}
+}
+
+func sumResources(containers []containerResources, get func(containerResources) corev1.ResourceList) corev1.ResourceList {
+	result := corev1.ResourceList{}
+	for i, container := range containers {
+		list := get(container)
+		for name, quantity := range list {
+			if i == 0 {
+				result[name] = quantity.DeepCopy()
+				continue
+			}
+			if total, ok := result[name]; ok {
+				total.Add(quantity)
+				result[name] = total
+			}
+		}
+		for name := range result {
+			if _, ok := list[name]; !ok {
+				delete(result, name)
+			}
+		}
+	}
+	return result
+}
+
+// setMissing sets the resources from defaults that are missing in list
+func setMissing(list, defaults corev1.ResourceList) {
+	for name, quantity := range defaults {
+		if _, ok := list[name]; !ok {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func copyResourceList(list corev1.ResourceList) corev1.ResourceList {
+	result := make(corev1.ResourceList, len(list))
+	for name, quantity := range list {
+		result[name] = quantity.DeepCopy()
+	}
+	return result
+}
+
+func sortedResourceNames(list corev1.ResourceList) (names []corev1.ResourceName) {
+	for name := range list {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+	return names
+}
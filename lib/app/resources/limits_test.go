@@ -0,0 +1,126 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type LimitsSuite struct{}
+
+var _ = Suite(&LimitsSuite{})
+
+func (*LimitsSuite) TestValidatesLimits(c *C) {
+	const limitRange = `apiVersion: v1
+kind: LimitRange
+metadata:
+  name: limits
+spec:
+  limits:
+  - type: Container
+    min:
+      memory: 64Mi
+    max:
+      cpu: "1"
+      memory: 1Gi
+    default:
+      cpu: 500m
+      memory: 256Mi
+    maxLimitRequestRatio:
+      cpu: "2"
+---
+`
+	var testCases = []struct {
+		input    string
+		findings []Finding
+		comment  string
+	}{
+		{
+			input:   limitRange + podWithResources("default", "{}"),
+			comment: "defaults satisfy the limit range",
+		},
+		{
+			input:   limitRange + podWithResources("default", "{limits: {cpu: 500m, memory: 512Mi}, requests: {cpu: 250m, memory: 128Mi}}"),
+			comment: "resources are within limits",
+		},
+		{
+			input:   limitRange + podWithResources("kube-system", "{limits: {cpu: '4'}, requests: {cpu: 100m}}"),
+			comment: "limit range is in another namespace",
+		},
+		{
+			input:   limitRange + podWithResources("default", "{limits: {cpu: '2', memory: 2Gi}, requests: {cpu: 500m, memory: 32Mi}}"),
+			comment: "resources violate the limit range",
+			findings: []Finding{
+				{
+					Object:  "Pod default/app",
+					Message: "container app violates LimitRange default/limits: memory request 32Mi is below minimum 64Mi",
+				},
+				{
+					Object:  "Pod default/app",
+					Message: "container app violates LimitRange default/limits: cpu limit 2 exceeds maximum 1",
+				},
+				{
+					Object:  "Pod default/app",
+					Message: "container app violates LimitRange default/limits: memory limit 2Gi exceeds maximum 1Gi",
+				},
+				{
+					Object:  "Pod default/app",
+					Message: "container app violates LimitRange default/limits: cpu limit to request ratio 4.00 exceeds maximum 2",
+				},
+			},
+		},
+		{
+			input: `apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: quota
+spec:
+  hard:
+    limits.memory: 4Gi
+    requests.cpu: "2"
+---
+` + podWithResources("default", "{limits: {memory: 128Mi}}"),
+			comment: "container does not specify resources tracked by quota",
+			findings: []Finding{{
+				Object:  "Pod default/app",
+				Message: "container app does not specify cpu request required by ResourceQuota default/quota",
+			}},
+		},
+	}
+	for _, testCase := range testCases {
+		comment := Commentf(testCase.comment)
+		resource, err := Decode(strings.NewReader(testCase.input))
+		c.Assert(err, IsNil, comment)
+		findings := Validate(resource.Objects, ValidateLimits())
+		c.Assert(findings, DeepEquals, testCase.findings, comment)
+	}
+}
+
+func podWithResources(namespace, resources string) string {
+	return `apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+  namespace: ` + namespace + `
+spec:
+  containers:
+  - name: app
+    image: nginx:1.9
+    resources: ` + resources + "\n"
+}
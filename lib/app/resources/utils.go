@@ -48,37 +48,64 @@ func UpdateSecurityContextInDir(dir string, serviceUser systeminfo.User) error {
 }
 
 // UpdateSecurityContext updates the security context for the given Pod (including
-// security contexts of all containers) using the specified service user.
-// Only the security contexts using a special defaults.PlaceholderServiceUserID
-// are updated.
+// security contexts of all containers and init containers) using the specified service user.
+// Only the user and group IDs set to special defaults.PlaceholderUserID and
+// defaults.PlaceholderGroupID are updated: runAsUser and runAsGroup of pod and
+// container security contexts, as well as fsGroup and supplementalGroups of the pod
+// security context.
 func UpdateSecurityContext(pod *v1.PodSpec, serviceUser systeminfo.User) (updated bool) {
-	updateContext := func(securityCtx *v1.SecurityContext) (updated bool) {
-		if securityCtx.RunAsUser != nil && *securityCtx.RunAsUser == defaults.PlaceholderUserID {
-			*securityCtx.RunAsUser = int64(serviceUser.UID)
-			return true
+	if pod.SecurityContext != nil {
+		if updatePodSecurityContext(pod.SecurityContext, serviceUser) {
+			updated = true
 		}
-		return false
 	}
-	updatePodContext := func(securityCtx *v1.PodSecurityContext) (updated bool) {
-		if securityCtx.RunAsUser != nil && *securityCtx.RunAsUser == defaults.PlaceholderUserID {
-			*securityCtx.RunAsUser = int64(serviceUser.UID)
-			return true
+	for _, containers := range [][]v1.Container{pod.InitContainers, pod.Containers} {
+		for _, container := range containers {
+			if container.SecurityContext != nil && updateSecurityContext(container.SecurityContext, serviceUser) {
+				updated = true
+			}
 		}
-		return false
 	}
-	if pod.SecurityContext != nil {
-		if updatePodContext(pod.SecurityContext) {
-			updated = true
-		}
+	return updated
+}
+
+func updateSecurityContext(securityCtx *v1.SecurityContext, serviceUser systeminfo.User) (updated bool) {
+	if replaceID(securityCtx.RunAsUser, defaults.PlaceholderUserID, serviceUser.UID) {
+		updated = true
+	}
+	if replaceID(securityCtx.RunAsGroup, defaults.PlaceholderGroupID, serviceUser.GID) {
+		updated = true
 	}
-	for _, container := range pod.Containers {
-		if container.SecurityContext != nil && updateContext(container.SecurityContext) {
+	return updated
+}
+
+func updatePodSecurityContext(securityCtx *v1.PodSecurityContext, serviceUser systeminfo.User) (updated bool) {
+	if replaceID(securityCtx.RunAsUser, defaults.PlaceholderUserID, serviceUser.UID) {
+		updated = true
+	}
+	if replaceID(securityCtx.RunAsGroup, defaults.PlaceholderGroupID, serviceUser.GID) {
+		updated = true
+	}
+	if replaceID(securityCtx.FSGroup, defaults.PlaceholderGroupID, serviceUser.GID) {
+		updated = true
+	}
+	for i := range securityCtx.SupplementalGroups {
+		if replaceID(&securityCtx.SupplementalGroups[i], defaults.PlaceholderGroupID, serviceUser.GID) {
 			updated = true
 		}
 	}
 	return updated
 }
 
+// replaceID sets the ID to the specified value if it is set to the given placeholder
+func replaceID(id *int64, placeholder, value int) bool {
+	if id == nil || *id != int64(placeholder) {
+		return false
+	}
+	*id = int64(value)
+	return true
+}
+
 func renderResourceTemplate(path string, serviceUser systeminfo.User) error {
 	in, err := os.Open(path)
 	if err != nil {
@@ -113,6 +140,8 @@ func renderResourceTemplate(path string, serviceUser systeminfo.User) error {
 			spec = &resource.Spec.Template.Spec
 		case *extensions.Deployment:
 			spec = &resource.Spec.Template.Spec
+		case *appsv1.Deployment:
+			spec = &resource.Spec.Template.Spec
 		case *appsv1beta1.Deployment:
 			spec = &resource.Spec.Template.Spec
 		case *appsv1beta2.Deployment:
@@ -25,6 +25,7 @@ import (
 	"github.com/gravitational/gravity/lib/compare"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/systeminfo"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
 
 	. "gopkg.in/check.v1"
@@ -80,6 +81,32 @@ func (*S) TestUpdatesSecurityContext(c *C) {
 			},
 			comment: "Updates service user ID",
 		},
+		{
+			input: resource{fileName: "deployment.yaml", data: []byte(deploymentWithGroups)},
+			verify: func(c *C, data []byte) {
+				res, err := Decode(bytes.NewReader(data))
+				c.Assert(err, IsNil)
+				c.Assert(res.Objects, HasLen, 1)
+				deployment, ok := res.Objects[0].(*appsv1.Deployment)
+				c.Assert(ok, Equals, true, Commentf("unexpected object of type %T", res.Objects[0]))
+				uid, gid, otherGID := int64(serviceUser.UID), int64(serviceUser.GID), int64(2000)
+				spec := deployment.Spec.Template.Spec
+				compare.DeepCompare(c, spec.SecurityContext, &v1.PodSecurityContext{
+					RunAsUser:          &uid,
+					RunAsGroup:         &gid,
+					FSGroup:            &gid,
+					SupplementalGroups: []int64{gid, otherGID},
+				})
+				compare.DeepCompare(c, spec.InitContainers[0].SecurityContext, &v1.SecurityContext{
+					RunAsUser:  &uid,
+					RunAsGroup: &gid,
+				})
+				compare.DeepCompare(c, spec.Containers[0].SecurityContext, &v1.SecurityContext{
+					RunAsGroup: &gid,
+				})
+			},
+			comment: "Updates service group IDs in pod templates and init containers",
+		},
 		{
 			input: resource{fileName: "resource.yaml", data: []byte(`
 # this is a comment
@@ -166,3 +193,34 @@ spec:
   containers:
   - name: foo
     image: foo:latest`
+
+const deploymentWithGroups = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+spec:
+  selector:
+    matchLabels:
+      app: nginx
+  template:
+    metadata:
+      labels:
+        app: nginx
+    spec:
+      securityContext:
+        runAsUser: -1
+        runAsGroup: -1
+        fsGroup: -1
+        supplementalGroups: [-1, 2000]
+      initContainers:
+      - name: init
+        image: busybox
+        securityContext:
+          runAsUser: -1
+          runAsGroup: -1
+      containers:
+      - name: nginx
+        image: nginx
+        securityContext:
+          runAsGroup: -1`
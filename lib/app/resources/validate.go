@@ -0,0 +1,322 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/utils"
+
+	appsv1 "k8s.io/api/apps/v1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	appsv1beta2 "k8s.io/api/apps/v1beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	batchv2alpha1 "k8s.io/api/batch/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Finding describes a potential problem discovered in application resources
+type Finding struct {
+	// Object identifies the offending object, e.g. "Deployment default/nginx"
+	Object string `json:"object"`
+	// Message describes the problem
+	Message string `json:"message"`
+}
+
+// String returns a text representation of this finding
+func (r Finding) String() string {
+	return fmt.Sprintf("%v: %v", r.Object, r.Message)
+}
+
+// ValidateFunc inspects the specified objects and returns found problems
+type ValidateFunc func(objects []runtime.Object) []Finding
+
+// Validate runs the specified validators on objects and returns
+// all found problems
+func Validate(objects []runtime.Object, validators ...ValidateFunc) (findings []Finding) {
+	for _, validate := range validators {
+		findings = append(findings, validate(objects)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Object < findings[j].Object
+	})
+	return findings
+}
+
+// Objects returns all objects from these resource files
+func (r ResourceFiles) Objects() (objects []runtime.Object) {
+	for _, file := range r {
+		objects = append(objects, file.Objects...)
+	}
+	return objects
+}
+
+// ValidateImagePullSecrets returns a validator that reports images that are
+// not served by any of the specified local registries and are likely to fail to pull:
+// neither the pod nor its service account declare an image pull secret, or
+// none of the declared secrets is part of the application resources.
+//
+// If no registries are specified, the cluster-local registry addresses are used
+func ValidateImagePullSecrets(localRegistries ...string) ValidateFunc {
+	if len(localRegistries) == 0 {
+		localRegistries = []string{constants.DockerRegistry, constants.LocalRegistryAddr}
+	}
+	return func(objects []runtime.Object) (findings []Finding) {
+		secrets := make(map[string]corev1.Secret)
+		accounts := make(map[string]corev1.ServiceAccount)
+		for _, object := range objects {
+			switch resource := object.(type) {
+			case *corev1.Secret:
+				secrets[namespacedName(resource.Namespace, resource.Name)] = *resource
+			case *corev1.ServiceAccount:
+				accounts[namespacedName(resource.Namespace, resource.Name)] = *resource
+			}
+		}
+		for _, template := range podTemplates(objects) {
+			pullSecrets := template.spec.ImagePullSecrets
+			if len(pullSecrets) == 0 {
+				accountName := template.spec.ServiceAccountName
+				if accountName == "" {
+					accountName = defaultServiceAccount
+				}
+				account := accounts[namespacedName(template.namespace, accountName)]
+				pullSecrets = account.ImagePullSecrets
+			}
+			for _, image := range template.images() {
+				if isLocalImage(image, localRegistries) {
+					continue
+				}
+				message := checkPullSecrets(template.namespace, pullSecrets, secrets)
+				if message != "" {
+					findings = append(findings, Finding{
+						Object:  template.object,
+						Message: fmt.Sprintf("image %v is likely to fail to pull: %v", image, message),
+					})
+				}
+			}
+		}
+		return findings
+	}
+}
+
+// ValidateServiceSelectors returns a validator that reports Services whose
+// selector does not match pod template labels of any workload in the same
+// namespace, i.e. Services that would have no endpoints.
+//
+// Services without selector and ExternalName Services are skipped, other Services
+// can be exempted with the constants.AnnotationSkipSelectorCheck annotation
+func ValidateServiceSelectors() ValidateFunc {
+	return func(objects []runtime.Object) (findings []Finding) {
+		templates := podTemplates(objects)
+		for _, object := range objects {
+			service, ok := object.(*corev1.Service)
+			if !ok {
+				continue
+			}
+			if len(service.Spec.Selector) == 0 || service.Spec.Type == corev1.ServiceTypeExternalName {
+				continue
+			}
+			if skip, _ := strconv.ParseBool(service.Annotations[constants.AnnotationSkipSelectorCheck]); skip {
+				continue
+			}
+			if !hasMatchingPods(*service, templates) {
+				findings = append(findings, Finding{
+					Object: describeObject(service),
+					Message: fmt.Sprintf("selector %v does not match any pods, the service will have no endpoints",
+						labels.SelectorFromSet(service.Spec.Selector)),
+				})
+			}
+		}
+		return findings
+	}
+}
+
+// hasMatchingPods returns true if the selector of the specified service
+// matches labels of at least one of the pod templates
+func hasMatchingPods(service corev1.Service, templates []podTemplate) bool {
+	selector := labels.SelectorFromSet(service.Spec.Selector)
+	for _, template := range templates {
+		if template.namespace != namespaceOrDefault(service.Namespace) {
+			continue
+		}
+		if selector.Matches(labels.Set(template.labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPullSecrets returns a message describing why none of the specified
+// image pull secrets can be used, or an empty string if at least one of them
+// is bundled with the application
+func checkPullSecrets(namespace string, refs []corev1.LocalObjectReference, secrets map[string]corev1.Secret) string {
+	if len(refs) == 0 {
+		return "no imagePullSecrets declared for the pod or its service account"
+	}
+	var problems []string
+	for _, ref := range refs {
+		secret, ok := secrets[namespacedName(namespace, ref.Name)]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("secret %v/%v is not bundled", namespace, ref.Name))
+			continue
+		}
+		if secret.Type != corev1.SecretTypeDockerConfigJson && secret.Type != corev1.SecretTypeDockercfg {
+			problems = append(problems, fmt.Sprintf("secret %v/%v has type %q, expected %q",
+				namespace, ref.Name, secret.Type, corev1.SecretTypeDockerConfigJson))
+			continue
+		}
+		return ""
+	}
+	return strings.Join(problems, ", ")
+}
+
+// isLocalImage returns true if the specified image is served
+// by one of the provided registries
+func isLocalImage(image string, registries []string) bool {
+	parsed, err := loc.ParseDockerImage(image)
+	if err != nil {
+		return false
+	}
+	return utils.StringInSlice(registries, parsed.Registry)
+}
+
+// podTemplate describes a pod specification of a workload
+type podTemplate struct {
+	// object identifies the workload
+	object string
+	// namespace is the workload namespace
+	namespace string
+	// labels are the labels of pods created from this template
+	labels map[string]string
+	// spec is the pod specification
+	spec *corev1.PodSpec
+}
+
+// images returns all container images referenced by this pod template
+func (r podTemplate) images() (images []string) {
+	for _, containers := range [][]corev1.Container{r.spec.InitContainers, r.spec.Containers} {
+		for _, container := range containers {
+			if !utils.StringInSlice(images, container.Image) {
+				images = append(images, container.Image)
+			}
+		}
+	}
+	return images
+}
+
+// podTemplates returns pod templates of all supported workloads among objects
+func podTemplates(objects []runtime.Object) (templates []podTemplate) {
+	for _, object := range objects {
+		var template *corev1.PodTemplateSpec
+		switch resource := object.(type) {
+		case *corev1.Pod:
+			template = &corev1.PodTemplateSpec{ObjectMeta: resource.ObjectMeta, Spec: resource.Spec}
+		case *corev1.ReplicationController:
+			template = resource.Spec.Template
+		case *batchv1.Job:
+			template = &resource.Spec.Template
+		case *extensions.Deployment:
+			template = &resource.Spec.Template
+		case *appsv1beta1.Deployment:
+			template = &resource.Spec.Template
+		case *appsv1beta2.Deployment:
+			template = &resource.Spec.Template
+		case *appsv1.Deployment:
+			template = &resource.Spec.Template
+		case *extensions.DaemonSet:
+			template = &resource.Spec.Template
+		case *appsv1beta2.DaemonSet:
+			template = &resource.Spec.Template
+		case *appsv1.DaemonSet:
+			template = &resource.Spec.Template
+		case *extensions.ReplicaSet:
+			template = &resource.Spec.Template
+		case *appsv1beta2.ReplicaSet:
+			template = &resource.Spec.Template
+		case *appsv1.ReplicaSet:
+			template = &resource.Spec.Template
+		case *appsv1beta1.StatefulSet:
+			template = &resource.Spec.Template
+		case *appsv1beta2.StatefulSet:
+			template = &resource.Spec.Template
+		case *appsv1.StatefulSet:
+			template = &resource.Spec.Template
+		case *batchv2alpha1.CronJob:
+			template = &resource.Spec.JobTemplate.Spec.Template
+		case *batchv1beta1.CronJob:
+			template = &resource.Spec.JobTemplate.Spec.Template
+		}
+		if template == nil {
+			continue
+		}
+		objectMeta, err := meta.Accessor(object)
+		if err != nil {
+			continue
+		}
+		templates = append(templates, podTemplate{
+			object:    describeObject(object),
+			namespace: namespaceOrDefault(objectMeta.GetNamespace()),
+			labels:    template.Labels,
+			spec:      &template.Spec,
+		})
+	}
+	return templates
+}
+
+// describeObject returns a human-readable identifier of the specified object
+func describeObject(object runtime.Object) string {
+	kind := object.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		kind = strings.TrimPrefix(fmt.Sprintf("%T", object), "*")
+	}
+	objectMeta, err := meta.Accessor(object)
+	if err != nil {
+		return kind
+	}
+	return fmt.Sprintf("%v %v", kind, namespacedName(objectMeta.GetNamespace(), objectMeta.GetName()))
+}
+
+// namespacedName returns the name of the object qualified with its namespace
+func namespacedName(namespace, name string) string {
+	return fmt.Sprintf("%v/%v", namespaceOrDefault(namespace), name)
+}
+
+// namespaceOrDefault returns the specified namespace or the default
+// namespace if it is empty
+func namespaceOrDefault(namespace string) string {
+	if namespace == "" {
+		return metav1.NamespaceDefault
+	}
+	return namespace
+}
+
+const (
+	// defaultServiceAccount is the name of the service account
+	// pods use when none is specified
+	defaultServiceAccount = "default"
+)
@@ -0,0 +1,191 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type ValidateSuite struct{}
+
+var _ = Suite(&ValidateSuite{})
+
+func (*ValidateSuite) TestImagePullSecrets(c *C) {
+	var testCases = []struct {
+		input    string
+		findings []Finding
+		comment  string
+	}{
+		{
+			input:   podWithImage("leader.telekube.local:5000/nginx:1.9", ""),
+			comment: "image is served by the local registry",
+		},
+		{
+			input:   podWithImage("quay.io/private/app:1.0", "") + "---\n" + pullSecret("default", "quay"),
+			comment: "secret is declared but not used",
+			findings: []Finding{{
+				Object:  "Pod default/app",
+				Message: "image quay.io/private/app:1.0 is likely to fail to pull: no imagePullSecrets declared for the pod or its service account",
+			}},
+		},
+		{
+			input:   podWithImage("quay.io/private/app:1.0", "quay") + "---\n" + pullSecret("default", "quay"),
+			comment: "pod declares a bundled secret",
+		},
+		{
+			input:   podWithImage("quay.io/private/app:1.0", "quay"),
+			comment: "pod declares a secret that is not bundled",
+			findings: []Finding{{
+				Object:  "Pod default/app",
+				Message: "image quay.io/private/app:1.0 is likely to fail to pull: secret default/quay is not bundled",
+			}},
+		},
+		{
+			input: podWithImage("quay.io/private/app:1.0", "") + `---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: default
+imagePullSecrets:
+- name: quay
+---
+` + pullSecret("default", "quay"),
+			comment: "service account declares a bundled secret",
+		},
+		{
+			input:   podWithImage("quay.io/private/app:1.0", "quay") + "---\n" + pullSecret("kube-system", "quay"),
+			comment: "secret is bundled in another namespace",
+			findings: []Finding{{
+				Object:  "Pod default/app",
+				Message: "image quay.io/private/app:1.0 is likely to fail to pull: secret default/quay is not bundled",
+			}},
+		},
+	}
+	for _, testCase := range testCases {
+		comment := Commentf(testCase.comment)
+		resource, err := Decode(strings.NewReader(testCase.input))
+		c.Assert(err, IsNil, comment)
+		findings := Validate(resource.Objects, ValidateImagePullSecrets())
+		c.Assert(findings, DeepEquals, testCase.findings, comment)
+	}
+}
+
+func (*ValidateSuite) TestServiceSelectors(c *C) {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: kube-system
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+        tier: frontend
+    spec:
+      containers:
+      - name: app
+        image: nginx:1.9
+---
+`
+	var testCases = []struct {
+		input    string
+		findings []Finding
+		comment  string
+	}{
+		{
+			input:   deployment + service("kube-system", "app: web", ""),
+			comment: "selector matches deployment pods",
+		},
+		{
+			input:   deployment + service("kube-system", "app: db", ""),
+			comment: "selector does not match any pods",
+			findings: []Finding{{
+				Object:  "Service kube-system/app",
+				Message: "selector app=db does not match any pods, the service will have no endpoints",
+			}},
+		},
+		{
+			input:   deployment + service("default", "app: web", ""),
+			comment: "matching pods are in another namespace",
+			findings: []Finding{{
+				Object:  "Service default/app",
+				Message: "selector app=web does not match any pods, the service will have no endpoints",
+			}},
+		},
+		{
+			input:   deployment + service("kube-system", "app: db", `"gravitational.io/skip-selector-check": "true"`),
+			comment: "service is exempted with annotation",
+		},
+	}
+	for _, testCase := range testCases {
+		comment := Commentf(testCase.comment)
+		resource, err := Decode(strings.NewReader(testCase.input))
+		c.Assert(err, IsNil, comment)
+		findings := Validate(resource.Objects, ValidateServiceSelectors())
+		c.Assert(findings, DeepEquals, testCase.findings, comment)
+	}
+}
+
+func service(namespace, selector, annotation string) string {
+	spec := `apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: ` + namespace + "\n"
+	if annotation != "" {
+		spec += "  annotations:\n    " + annotation + "\n"
+	}
+	return spec + `spec:
+  selector:
+    ` + selector + `
+  ports:
+  - port: 80
+`
+}
+
+func podWithImage(image, pullSecret string) string {
+	spec := `apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+spec:
+  containers:
+  - name: app
+    image: ` + image + "\n"
+	if pullSecret != "" {
+		spec += "  imagePullSecrets:\n  - name: " + pullSecret + "\n"
+	}
+	return spec
+}
+
+func pullSecret(namespace, name string) string {
+	return `apiVersion: v1
+kind: Secret
+type: kubernetes.io/dockerconfigjson
+metadata:
+  name: ` + name + `
+  namespace: ` + namespace + `
+data:
+  .dockerconfigjson: e30=
+`
+}
@@ -134,6 +134,9 @@ func (r *applications) DeleteApp(req appservice.DeleteRequest) error {
 	if err := r.deleteResourcesPackage(req.Package); err != nil {
 		return trace.Wrap(err)
 	}
+	if err := r.UnpublishApp(req.Package); err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
 	if err := r.Packages.DeletePackage(req.Package); err != nil {
 		return trace.Wrap(err)
 	}
@@ -141,16 +144,16 @@ func (r *applications) DeleteApp(req appservice.DeleteRequest) error {
 }
 
 // UninstallApp uninstalls the specified application from the runtime, with all its dependencies
-func (r *applications) UninstallApp(locator loc.Locator) (*appservice.Application, error) {
-	app, err := r.withApp(locator, func(dir string, app *appservice.Application) error {
+func (r *applications) UninstallApp(req appservice.UninstallAppRequest) (*appservice.Application, error) {
+	app, err := r.withApp(req.Package, func(dir string, app *appservice.Application) error {
 		// first uninstall the app
-		err := r.uninstallApp(locator)
+		err := r.uninstallApp(req.Package, req.Force)
 		if err != nil {
 			return trace.Wrap(err)
 		}
 		// then the app's dependencies
 		for _, dependency := range app.Manifest.Dependencies.Apps {
-			err = r.uninstallApp(dependency.Locator)
+			err = r.uninstallApp(dependency.Locator, req.Force)
 			if err != nil {
 				return trace.Wrap(err)
 			}
@@ -166,6 +169,7 @@ func (r *applications) ExportApp(req appservice.ExportAppRequest) error {
 	imageService, err := docker.NewImageService(docker.RegistryConnectionRequest{
 		RegistryAddress: req.RegistryAddress,
 		CertName:        req.CertName,
+		Parallel:        req.Parallel,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -259,7 +263,8 @@ func (r *applications) exportApp(ctx context.Context, dir string, imageService d
 }
 
 // uninstallApp calls "pre-uninstall" and "uninstall" hooks for the specified app
-func (r *applications) uninstallApp(locator loc.Locator) error {
+// and makes sure the namespaces and persistent volumes of the app are gone
+func (r *applications) uninstallApp(locator loc.Locator, force bool) error {
 	r.Infof("Uninstalling %v.", locator)
 	err := r.runAppHook(context.TODO(), appservice.HookRunRequest{
 		Application: locator,
@@ -275,6 +280,10 @@ func (r *applications) uninstallApp(locator loc.Locator) error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	err = r.finalizeUninstall(context.TODO(), locator, force)
+	if err != nil {
+		return trace.Wrap(err)
+	}
 	return nil
 }
 
@@ -288,6 +297,12 @@ func (r *applications) StartAppHook(ctx context.Context, req appservice.HookRunR
 		req.Env = make(map[string]string)
 	}
 	req.Env[constants.DevmodeEnvVar] = strconv.FormatBool(r.Devmode)
+	if req.Timeout == 0 {
+		req.Timeout, err = hook.GetTimeout()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
 
 	client, err := r.getKubeClient()
 	if err != nil {
@@ -608,6 +623,14 @@ func (r *applications) createApp(locator loc.Locator, packageBytes io.Reader, ma
 		}
 	}
 
+	if manifest.Kind != schema.KindRuntime && r.Charts != nil {
+		err = r.Charts.AddVendoredCharts(locator)
+		if err != nil {
+			r.Warnf("Failed to add charts vendored in %v to chart repository: %v.",
+				locator, trace.DebugReport(err))
+		}
+	}
+
 	return &appservice.Application{
 		Package:         locator,
 		PackageEnvelope: *envelope,
@@ -630,6 +653,11 @@ func (r *applications) CreateImportOperation(req *appservice.ImportRequest) (*st
 		return nil, trace.Wrap(err)
 	}
 
+	if err = r.validateManifest(manifestBytes); err != nil {
+		cleanup()
+		return nil, trace.Wrap(err)
+	}
+
 	manifest, err := r.resolveManifest(manifestBytes)
 	if err != nil {
 		cleanup()
@@ -782,6 +810,19 @@ func (r *applications) FetchIndexFile() (io.Reader, error) {
 	return r.Charts.GetIndexFile()
 }
 
+// validateManifest validates the application manifest data against the manifest schema.
+// The returned error lists all schema violations along with their locations in the manifest
+func (r *applications) validateManifest(manifestBytes []byte) error {
+	result, err := schema.ValidateManifestYAML(manifestBytes)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, warning := range result.Warnings {
+		r.Warnf("Application manifest: %v.", warning)
+	}
+	return trace.Wrap(result.Check())
+}
+
 func (r *applications) resolveManifest(manifestBytes []byte) (*schema.Manifest, error) {
 	manifest, err := schema.ParseManifestYAMLNoValidate(manifestBytes)
 	if err != nil {
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"sort"
+	"strings"
+
+	appservice "github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/gravitational/trace"
+)
+
+// PublishApp publishes an existing application to the catalog
+// or updates the catalog metadata of an already published one
+func (r *applications) PublishApp(req appservice.PublishAppRequest) (*storage.CatalogEntry, error) {
+	if err := req.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	app, err := r.GetApp(req.Package)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	entry, err := r.Backend.UpsertCatalogEntry(storage.CatalogEntry{
+		Repository:  app.Package.Repository,
+		Name:        app.Package.Name,
+		Version:     app.Package.Version,
+		Channel:     req.Channel,
+		Icon:        req.Icon,
+		Readme:      req.Readme,
+		Upgrade:     req.Upgrade,
+		Published:   r.Backend.Now().UTC(),
+		PublishedBy: req.PublishedBy,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	r.Infof("Published %v to the %v channel.", app.Package, entry.Channel)
+	return entry, nil
+}
+
+// UnpublishApp removes the specified application from the catalog
+func (r *applications) UnpublishApp(locator loc.Locator) error {
+	err := r.Backend.DeleteCatalogEntry(locator.Repository, locator.Name, locator.Version)
+	return trace.Wrap(err)
+}
+
+// SearchCatalog returns the published applications matching the request
+// ordered by repository, name and version
+func (r *applications) SearchCatalog(req appservice.SearchCatalogRequest) (apps []appservice.CatalogApp, err error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	repositories := []string{req.Repository}
+	if req.Repository == "" {
+		repositories, err = r.getRepositories()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	for _, repository := range repositories {
+		entries, err := r.Backend.GetCatalogEntries(repository)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, entry := range entries {
+			if req.Channel != "" && entry.Channel != req.Channel {
+				continue
+			}
+			if req.Pattern != "" && !strings.Contains(entry.Name, req.Pattern) {
+				continue
+			}
+			app, err := r.GetApp(entry.Locator())
+			if err != nil {
+				if trace.IsNotFound(err) {
+					r.Warnf("Published application %v not found.", entry.Locator())
+					continue
+				}
+				return nil, trace.Wrap(err)
+			}
+			apps = append(apps, appservice.NewCatalogApp(entry, *app))
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].Repository != apps[j].Repository {
+			return apps[i].Repository < apps[j].Repository
+		}
+		if apps[i].Name != apps[j].Name {
+			return apps[i].Name < apps[j].Name
+		}
+		return semver.New(apps[i].Version).LessThan(*semver.New(apps[j].Version))
+	})
+	return apps, nil
+}
+
+func (r *applications) getRepositories() (names []string, err error) {
+	repositories, err := r.Backend.GetRepositories()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, repository := range repositories {
+		names = append(names, repository.GetName())
+	}
+	return names, nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/service/test"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	check "gopkg.in/check.v1"
+)
+
+type catalogSuite struct {
+	apps *applications
+}
+
+var _ = check.Suite(&catalogSuite{})
+
+func (s *catalogSuite) SetUpTest(c *check.C) {
+	_, _, s.apps = setupServices(c)
+}
+
+func (s *catalogSuite) TestPublishAndSearch(c *check.C) {
+	stable := loc.MustParseLocator("gravitational.io/alpine:0.1.0")
+	test.CreateHelmChartApp(c, s.apps, stable)
+	beta := loc.MustParseLocator("gravitational.io/alpine:0.2.0-beta.1")
+	test.CreateHelmChartApp(c, s.apps, beta)
+	nginx := loc.MustParseLocator("gravitational.io/nginx:0.1.0")
+	test.CreateHelmChartApp(c, s.apps, nginx)
+
+	// Applications are not in the catalog until they are published
+	apps, err := s.apps.SearchCatalog(app.SearchCatalogRequest{})
+	c.Assert(err, check.IsNil)
+	c.Assert(apps, check.HasLen, 0)
+
+	entry, err := s.apps.PublishApp(app.PublishAppRequest{
+		Package: stable,
+		Icon:    "https://example.com/alpine.png",
+		Readme:  "# Alpine",
+		Upgrade: storage.CatalogUpgrade{ReleaseNotes: "Initial release"},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(entry.Channel, check.Equals, storage.CatalogChannelStable)
+	entry, err = s.apps.PublishApp(app.PublishAppRequest{Package: beta})
+	c.Assert(err, check.IsNil)
+	c.Assert(entry.Channel, check.Equals, storage.CatalogChannelBeta)
+	_, err = s.apps.PublishApp(app.PublishAppRequest{Package: nginx})
+	c.Assert(err, check.IsNil)
+
+	apps, err = s.apps.SearchCatalog(app.SearchCatalogRequest{Pattern: "alp"})
+	c.Assert(err, check.IsNil)
+	c.Assert(catalogLocators(apps), check.DeepEquals, []loc.Locator{stable, beta})
+	c.Assert(apps[0].Readme, check.Equals, "# Alpine")
+	c.Assert(apps[0].Icon, check.Equals, "https://example.com/alpine.png")
+	c.Assert(apps[0].Kind, check.Not(check.Equals), "")
+
+	apps, err = s.apps.SearchCatalog(app.SearchCatalogRequest{
+		Repository: "gravitational.io",
+		Channel:    storage.CatalogChannelStable,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(catalogLocators(apps), check.DeepEquals, []loc.Locator{stable, nginx})
+
+	// Deleted applications are removed from the catalog
+	c.Assert(s.apps.UnpublishApp(nginx), check.IsNil)
+	c.Assert(s.apps.DeleteApp(app.DeleteRequest{Package: stable}), check.IsNil)
+	apps, err = s.apps.SearchCatalog(app.SearchCatalogRequest{})
+	c.Assert(err, check.IsNil)
+	c.Assert(catalogLocators(apps), check.DeepEquals, []loc.Locator{beta})
+}
+
+func (s *catalogSuite) TestPublishValidation(c *check.C) {
+	beta := loc.MustParseLocator("gravitational.io/alpine:0.2.0-beta.1")
+	test.CreateHelmChartApp(c, s.apps, beta)
+
+	_, err := s.apps.PublishApp(app.PublishAppRequest{
+		Package: beta,
+		Channel: storage.CatalogChannelStable,
+	})
+	c.Assert(trace.IsBadParameter(err), check.Equals, true, check.Commentf("%v", err))
+
+	_, err = s.apps.PublishApp(app.PublishAppRequest{
+		Package: loc.MustParseLocator("gravitational.io/missing:1.0.0"),
+	})
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func catalogLocators(apps []app.CatalogApp) (result []loc.Locator) {
+	for _, app := range apps {
+		result = append(result, app.Locator())
+	}
+	return result
+}
@@ -125,6 +125,40 @@ func (s *chartsSuite) TestFetchChart(c *check.C) {
 	compare.DeepCompare(c, chart, test.Chart(alpine))
 }
 
+func (s *chartsSuite) TestVendoredCharts(c *check.C) {
+	// Create an application that vendors a chart...
+	app1 := loc.MustParseLocator("gravitational.io/app1:0.1.0")
+	test.CreateAppWithVendoredChart(c, s.apps, app1, "mattermost", "2.2.1")
+
+	// ... and verify the chart was added to the index.
+	index := s.getIndex(c)
+	c.Assert(len(index.Entries), check.Equals, 1)
+	c.Assert(index.Has("mattermost", "2.2.1"), check.Equals, true)
+
+	// Another application vendoring the same chart does not add a duplicate.
+	app2 := loc.MustParseLocator("gravitational.io/app2:0.1.0")
+	test.CreateAppWithVendoredChart(c, s.apps, app2, "mattermost", "2.2.1")
+	index = s.getIndex(c)
+	c.Assert(index.Entries["mattermost"], check.HasLen, 1)
+
+	// The chart can be fetched as a chart archive.
+	reader, err := s.apps.FetchChart(loc.MustParseLocator("gravitational.io/mattermost:2.2.1"))
+	c.Assert(err, check.IsNil)
+	defer reader.Close()
+	chart, err := chartutil.LoadArchive(reader)
+	c.Assert(err, check.IsNil)
+	c.Assert(chart.Metadata.Name, check.Equals, "mattermost")
+	c.Assert(chart.Metadata.Version, check.Equals, "2.2.1")
+
+	// The vendored chart survives the index rebuild.
+	err = s.backend.UpsertIndexFile(*repo.NewIndexFile())
+	c.Assert(err, check.IsNil)
+	err = s.apps.Charts.RebuildIndex()
+	c.Assert(err, check.IsNil)
+	index = s.getIndex(c)
+	c.Assert(index.Has("mattermost", "2.2.1"), check.Equals, true)
+}
+
 // getIndex returns the suite's app service's chart repo index file.
 //
 // The index file can also be retrieved directly from the backend in the
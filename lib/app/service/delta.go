@@ -0,0 +1,414 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+
+	appservice "github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// makeDelta turns the installer packages collected in localApps into a delta
+// against the base application: packages the base application already has are
+// removed and updated applications only retain the image layers that are new
+// to their base versions
+func (r *applications) makeDelta(base appservice.InstallerBase, app *appservice.Application, localApps *applications) error {
+	baseApp, err := base.Apps.GetApp(base.Application)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !loc.IsSameApp(baseApp.Package, app.Package) {
+		return trace.BadParameter("cannot generate delta of %v against %v",
+			app.Package, baseApp.Package)
+	}
+	updates, err := getDeltaUpdates(*baseApp, base.Apps, *app, localApps)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	baseDeps, err := appservice.GetDependencies(baseApp, base.Apps)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	existing := make(map[loc.Locator]struct{})
+	for _, locator := range append(append(baseDeps.Packages, baseDeps.Apps...), baseApp.Package) {
+		existing[locator] = struct{}{}
+	}
+	deps, err := appservice.GetDependencies(app, localApps)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, locator := range append(deps.Packages, deps.Apps...) {
+		if _, ok := existing[locator]; !ok {
+			continue
+		}
+		r.Debugf("Package %v is not updated, removing it from delta.", locator)
+		if err := localApps.Packages.DeletePackage(locator); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	for _, update := range updates {
+		baseLocator := findSameApp(update, baseDeps.Apps, baseApp.Package)
+		if baseLocator == nil {
+			r.Debugf("Application %v is new, shipping it in full.", update)
+			continue
+		}
+		r.Infof("Generating delta of %v against %v.", update, baseLocator)
+		err := createDeltaPackage(localApps.Packages, update, base.Packages, *baseLocator)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// getDeltaUpdates returns the applications updated between the base and
+// the specified application, including the updates of their runtimes
+func getDeltaUpdates(baseApp appservice.Application, baseApps appservice.Applications, app appservice.Application, apps appservice.Applications) ([]loc.Locator, error) {
+	updates, err := appservice.GetUpdatedDependencies(baseApp, app)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.BadParameter("%v is already the base application of the delta", app.Package)
+		}
+		return nil, trace.Wrap(err)
+	}
+	baseRuntime, runtime := baseApp.Manifest.Base(), app.Manifest.Base()
+	if baseRuntime == nil || runtime == nil {
+		return updates, nil
+	}
+	baseRuntimeApp, err := baseApps.GetApp(*baseRuntime)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	runtimeApp, err := apps.GetApp(*runtime)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	runtimeUpdates, err := appservice.GetUpdatedDependencies(*baseRuntimeApp, *runtimeApp)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	return loc.Deduplicate(append(updates, runtimeUpdates...)), nil
+}
+
+// findSameApp returns the locator of the same application as update
+// among the provided locators or nil if there's none
+func findSameApp(update loc.Locator, locators []loc.Locator, extra ...loc.Locator) *loc.Locator {
+	for _, locator := range append(locators, extra...) {
+		if loc.IsSameApp(update, locator) {
+			return &locator
+		}
+	}
+	return nil
+}
+
+// GetDeltaBase returns the locator of the application package the specified
+// package of a delta installer has been generated against.
+//
+// Returns trace.NotFound if the package is not a delta
+func GetDeltaBase(packages pack.PackageService, locator loc.Locator) (*loc.Locator, error) {
+	env, err := packages.ReadPackageEnvelope(locator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return getDeltaBase(*env)
+}
+
+func getDeltaBase(env pack.PackageEnvelope) (*loc.Locator, error) {
+	base, ok := env.RuntimeLabels[pack.DeltaFromLabel]
+	if !ok {
+		return nil, trace.NotFound("%v is not a delta", env.Locator)
+	}
+	locator, err := loc.ParseLocator(base)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return locator, nil
+}
+
+// MergeDelta restores the image layers stripped from the application packages
+// of a delta installer in packages. The layers are read from the packages
+// the delta has been generated against in basePackages.
+//
+// The restored packages replace the delta packages
+func MergeDelta(packages, basePackages pack.PackageService, log logrus.FieldLogger) error {
+	var deltas []pack.PackageEnvelope
+	err := pack.ForeachPackage(packages, func(env pack.PackageEnvelope) error {
+		if _, ok := env.RuntimeLabels[pack.DeltaFromLabel]; ok {
+			deltas = append(deltas, env)
+		}
+		return nil
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, env := range deltas {
+		base, err := getDeltaBase(env)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		log.Infof("Restoring %v from %v.", env.Locator, base)
+		err = replacePackage(packages, env.Locator, func(w io.Writer, r io.Reader) error {
+			_, baseReader, err := basePackages.ReadPackage(*base)
+			if err != nil {
+				if trace.IsNotFound(err) {
+					return trace.NotFound("%v is a delta against %v which is not available",
+						env.Locator, base)
+				}
+				return trace.Wrap(err)
+			}
+			defer baseReader.Close()
+			return trace.Wrap(mergeLayers(w, r, baseReader))
+		}, pack.DeltaFromLabel, "")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// createDeltaPackage replaces the specified application package with
+// its delta against the base package
+func createDeltaPackage(packages pack.PackageService, locator loc.Locator, basePackages pack.PackageService, base loc.Locator) error {
+	return replacePackage(packages, locator, func(w io.Writer, r io.Reader) error {
+		_, baseReader, err := basePackages.ReadPackage(base)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		defer baseReader.Close()
+		return trace.Wrap(stripLayers(w, r, baseReader))
+	}, pack.DeltaFromLabel, base.String())
+}
+
+// replacePackage replaces the data of the specified package with the result
+// of the provided transformation and sets the label to the specified value.
+// The label is removed if value is empty
+func replacePackage(packages pack.PackageService, locator loc.Locator, transform func(io.Writer, io.Reader) error, label, value string) error {
+	f, err := ioutil.TempFile("", "package")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	env, reader, err := packages.ReadPackage(locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = transform(f, reader)
+	reader.Close()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	labels := make(map[string]string, len(env.RuntimeLabels)+1)
+	for k, v := range env.RuntimeLabels {
+		labels[k] = v
+	}
+	if value != "" {
+		labels[label] = value
+	} else {
+		delete(labels, label)
+	}
+	_, err = packages.UpsertPackage(locator, f,
+		pack.WithLabels(labels), pack.WithManifest(env.Type, env.Manifest))
+	return trace.Wrap(err)
+}
+
+// stripLayers writes the application package data read from r to w
+// omitting the image layers present in the base package data.
+// Image manifests are always retained so the images can still be resolved
+func stripLayers(w io.Writer, r, base io.Reader) error {
+	baseLayers, err := registryLayers(base)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(copyPackage(w, r, func(header *tar.Header) bool {
+		digest := matchDigest(blobDataRe, header.Name)
+		if digest == "" {
+			return true
+		}
+		_, exists := baseLayers[digest]
+		return !exists
+	}))
+}
+
+// mergeLayers writes the delta application package data read from r to w
+// restoring the image layers missing from it from the base package data
+func mergeLayers(w io.Writer, r, base io.Reader) error {
+	stream, err := dockerarchive.DecompressStream(r)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer stream.Close()
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	present := make(map[string]struct{})
+	referenced := make(map[string]struct{})
+	tr := tar.NewReader(stream)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if digest := matchDigest(blobDataRe, header.Name); digest != "" {
+			present[digest] = struct{}{}
+		}
+		if digest := matchDigest(layerLinkRe, header.Name); digest != "" {
+			referenced[digest] = struct{}{}
+		}
+		if err := copyEntry(tw, header, tr); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	missing := make(map[string]struct{})
+	for digest := range referenced {
+		if _, ok := present[digest]; !ok {
+			missing[digest] = struct{}{}
+		}
+	}
+	if len(missing) != 0 {
+		err = forEachEntry(base, func(header *tar.Header, r io.Reader) error {
+			digest := matchDigest(blobDataRe, header.Name)
+			if _, ok := missing[digest]; !ok || digest == "" {
+				return nil
+			}
+			delete(missing, digest)
+			return trace.Wrap(copyEntry(tw, header, r))
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if len(missing) != 0 {
+		return trace.NotFound("%v image layers are missing from the base package", len(missing))
+	}
+	if err := tw.Close(); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(gz.Close())
+}
+
+// registryLayers returns digests of the image layers, i.e. registry blobs
+// that are not image manifests, in the application package data read from r
+func registryLayers(r io.Reader) (map[string]struct{}, error) {
+	blobs := make(map[string]struct{})
+	manifests := make(map[string]struct{})
+	err := forEachEntry(r, func(header *tar.Header, _ io.Reader) error {
+		if digest := matchDigest(blobDataRe, header.Name); digest != "" {
+			blobs[digest] = struct{}{}
+		}
+		if digest := matchDigest(manifestLinkRe, header.Name); digest != "" {
+			manifests[digest] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for digest := range manifests {
+		delete(blobs, digest)
+	}
+	return blobs, nil
+}
+
+// copyPackage writes the package data read from r to w as a compressed
+// tarball retaining only the entries accepted by the filter
+func copyPackage(w io.Writer, r io.Reader, filter func(*tar.Header) bool) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := forEachEntry(r, func(header *tar.Header, r io.Reader) error {
+		if !filter(header) {
+			return nil
+		}
+		return trace.Wrap(copyEntry(tw, header, r))
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := tw.Close(); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(gz.Close())
+}
+
+// forEachEntry invokes fn for every entry of the (optionally compressed)
+// tarball read from r
+func forEachEntry(r io.Reader, fn func(*tar.Header, io.Reader) error) error {
+	stream, err := dockerarchive.DecompressStream(r)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer stream.Close()
+	tr := tar.NewReader(stream)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := fn(header, tr); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+}
+
+func copyEntry(tw *tar.Writer, header *tar.Header, r io.Reader) error {
+	if err := tw.WriteHeader(header); err != nil {
+		return trace.Wrap(err)
+	}
+	_, err := io.Copy(tw, r)
+	return trace.Wrap(err)
+}
+
+// matchDigest returns the digest captured by the expression from the
+// specified archive path or an empty string if the path does not match
+func matchDigest(re *regexp.Regexp, name string) string {
+	match := re.FindStringSubmatch(path.Clean(name))
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+var (
+	// blobDataRe matches the data files of the application registry blobs
+	blobDataRe = regexp.MustCompile(`^registry/docker/registry/v2/blobs/sha256/[0-9a-f]{2}/([0-9a-f]{64})/data$`)
+	// layerLinkRe matches the links to the layers of images in the application registry
+	layerLinkRe = regexp.MustCompile(`^registry/docker/registry/v2/repositories/.+/_layers/sha256/([0-9a-f]{64})/link$`)
+	// manifestLinkRe matches the links to the manifests of images in the application registry
+	manifestLinkRe = regexp.MustCompile(`^registry/docker/registry/v2/repositories/.+/_manifests/revisions/sha256/([0-9a-f]{64})/link$`)
+)
@@ -0,0 +1,129 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type DeltaSuite struct{}
+
+var _ = Suite(&DeltaSuite{})
+
+func (s *DeltaSuite) TestStripAndMergeLayers(c *C) {
+	shared, base, manifest, update := digest("a"), digest("b"), digest("c"), digest("d")
+	baseData := makeTarball(c, map[string]string{
+		blobPath(shared):       "shared layer",
+		blobPath(base):         "base layer",
+		blobPath(manifest):     "manifest",
+		layerPath(shared):      "sha256:" + shared,
+		layerPath(base):        "sha256:" + base,
+		manifestPath(manifest): "sha256:" + manifest,
+	})
+	updateFiles := map[string]string{
+		"resources/app.yaml":   "kind: Application",
+		blobPath(shared):       "shared layer",
+		blobPath(update):       "updated layer",
+		blobPath(manifest):     "manifest",
+		layerPath(shared):      "sha256:" + shared,
+		layerPath(update):      "sha256:" + update,
+		manifestPath(manifest): "sha256:" + manifest,
+	}
+	updateData := makeTarball(c, updateFiles)
+
+	var delta bytes.Buffer
+	err := stripLayers(&delta, bytes.NewReader(updateData), bytes.NewReader(baseData))
+	c.Assert(err, IsNil)
+	deltaFiles := readTarball(c, delta.Bytes())
+	_, ok := deltaFiles[blobPath(shared)]
+	c.Assert(ok, Equals, false, Commentf("shared layer should be stripped"))
+	c.Assert(deltaFiles[blobPath(update)], Equals, "updated layer")
+	c.Assert(deltaFiles[blobPath(manifest)], Equals, "manifest")
+
+	var merged bytes.Buffer
+	err = mergeLayers(&merged, bytes.NewReader(delta.Bytes()), bytes.NewReader(baseData))
+	c.Assert(err, IsNil)
+	c.Assert(readTarball(c, merged.Bytes()), DeepEquals, updateFiles)
+}
+
+func (s *DeltaSuite) TestMergeFailsWithMissingLayers(c *C) {
+	layer := digest("a")
+	delta := makeTarball(c, map[string]string{
+		layerPath(layer): "sha256:" + layer,
+	})
+	base := makeTarball(c, map[string]string{
+		blobPath(digest("b")): "other layer",
+	})
+	err := mergeLayers(ioutil.Discard, bytes.NewReader(delta), bytes.NewReader(base))
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
+
+func makeTarball(c *C, files map[string]string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, data := range files {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		})
+		c.Assert(err, IsNil)
+		_, err = tw.Write([]byte(data))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(tw.Close(), IsNil)
+	return buf.Bytes()
+}
+
+func readTarball(c *C, data []byte) map[string]string {
+	files := make(map[string]string)
+	err := forEachEntry(bytes.NewReader(data), func(header *tar.Header, r io.Reader) error {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		files[header.Name] = string(data)
+		return nil
+	})
+	c.Assert(err, IsNil)
+	return files
+}
+
+func digest(char string) string {
+	return strings.Repeat(char, 64)
+}
+
+func blobPath(digest string) string {
+	return fmt.Sprintf("registry/docker/registry/v2/blobs/sha256/%v/%v/data", digest[:2], digest)
+}
+
+func layerPath(digest string) string {
+	return fmt.Sprintf("registry/docker/registry/v2/repositories/app/_layers/sha256/%v/link", digest)
+}
+
+func manifestPath(digest string) string {
+	return fmt.Sprintf("registry/docker/registry/v2/repositories/app/_manifests/revisions/sha256/%v/link", digest)
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	appservice "github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/helm"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+
+	"github.com/gravitational/trace"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// AppDiff describes changes between two versions of an application
+type AppDiff struct {
+	// From is the application the changes are computed against
+	From loc.Locator `json:"from"`
+	// To is the changed application
+	To loc.Locator `json:"to"`
+	// Manifest is the unified diff of the application manifests,
+	// empty if the manifests are the same
+	Manifest string `json:"manifest,omitempty"`
+	// Dependencies is the tree of the updated application dependencies
+	Dependencies *appservice.DependencyDiff `json:"dependencies,omitempty"`
+	// ImagesAdded lists the images only present in the changed application
+	// in the name:tag@digest format
+	ImagesAdded []string `json:"images_added,omitempty"`
+	// ImagesRemoved lists the images only present in the original application
+	// in the name:tag@digest format
+	ImagesRemoved []string `json:"images_removed,omitempty"`
+	// Resources lists the changed Kubernetes resource files
+	Resources []ResourceDiff `json:"resources,omitempty"`
+}
+
+// IsEmpty returns true if the applications have no differences
+func (r AppDiff) IsEmpty() bool {
+	return r.Manifest == "" && (r.Dependencies == nil || len(r.Dependencies.Dependencies) == 0) &&
+		len(r.ImagesAdded) == 0 && len(r.ImagesRemoved) == 0 && len(r.Resources) == 0
+}
+
+// ResourceDiff describes changes of a Kubernetes resource file
+type ResourceDiff struct {
+	// Path is the path of the resource file relative to the application
+	// resources directory. Helm charts are rendered and their resources
+	// are compared as a single file with the path of the chart directory
+	Path string `json:"path"`
+	// Diff is the unified diff of the resource file
+	Diff string `json:"diff"`
+}
+
+// DiffApps computes changes between the specified versions of an application:
+// the manifest changes, updated dependencies at any depth, images added
+// and removed and changes of the Kubernetes resources with Helm charts rendered
+// with their default values
+func DiffApps(apps appservice.Applications, packages pack.PackageService, from, to loc.Locator) (*AppDiff, error) {
+	fromApp, err := apps.GetApp(from)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	toApp, err := apps.GetApp(to)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	diff := AppDiff{
+		From: fromApp.Package,
+		To:   toApp.Package,
+		Manifest: diffText(string(fromApp.PackageEnvelope.Manifest), string(toApp.PackageEnvelope.Manifest),
+			fromApp.Package.String(), toApp.Package.String()),
+	}
+	diff.Dependencies, err = appservice.GetUpdatedDependenciesRecursive(apps, *fromApp, *toApp)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	fromContents, err := readAppContents(packages, fromApp.Package)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	toContents, err := readAppContents(packages, toApp.Package)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	diff.ImagesAdded = subtractImages(toContents.images, fromContents.images)
+	diff.ImagesRemoved = subtractImages(fromContents.images, toContents.images)
+	diff.Resources = diffResources(fromContents.resources, toContents.resources,
+		fromApp.Package.String(), toApp.Package.String())
+	return &diff, nil
+}
+
+// appContents describes the images and resources of an application package
+type appContents struct {
+	// images is the set of images in the name:tag@digest format
+	images map[string]struct{}
+	// resources maps resource file paths to their contents
+	resources map[string]string
+}
+
+// readAppContents returns the images and the Kubernetes resources
+// of the specified application package
+func readAppContents(packages pack.PackageService, locator loc.Locator) (*appContents, error) {
+	_, reader, err := packages.ReadPackage(locator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer reader.Close()
+	dir, err := ioutil.TempDir("", "appdiff")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+	contents, err := extractAppContents(reader, dir)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to read %v", locator)
+	}
+	return contents, nil
+}
+
+// extractAppContents reads the application package data from r collecting
+// the image references and extracting the resources into dir to render
+// them
+func extractAppContents(r io.Reader, dir string) (*appContents, error) {
+	contents := appContents{images: make(map[string]struct{})}
+	err := forEachEntry(r, func(header *tar.Header, r io.Reader) error {
+		name := path.Clean(header.Name)
+		if match := tagLinkRe.FindStringSubmatch(name); match != nil {
+			digest, err := ioutil.ReadAll(r)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			image := match[1] + ":" + match[2] + "@" + strings.TrimSpace(string(digest))
+			contents.images[image] = struct{}{}
+			return nil
+		}
+		if header.Typeflag != tar.TypeReg || !strings.HasPrefix(name, defaults.ResourcesDir+"/") {
+			return nil
+		}
+		return trace.Wrap(writeResourceFile(filepath.Join(dir, filepath.FromSlash(name)), r))
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	contents.resources, err = renderResources(filepath.Join(dir, defaults.ResourcesDir))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &contents, nil
+}
+
+// renderResources returns the contents of the Kubernetes resource files
+// in the specified directory keyed by the relative file path.
+//
+// Helm charts are rendered with their default values and returned under
+// the relative path of the chart directory. The application manifest
+// is compared separately and is omitted
+func renderResources(dir string) (map[string]string, error) {
+	resources := make(map[string]string)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return resources, nil
+	}
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if fi.IsDir() {
+			if _, err := os.Stat(filepath.Join(path, constants.HelmChartFile)); err != nil {
+				return nil
+			}
+			rendered, err := helm.Render(helm.RenderParameters{Path: path})
+			if err != nil {
+				return trace.Wrap(err, "failed to render chart %v", rel)
+			}
+			resources[filepath.ToSlash(rel)] = string(rendered)
+			return filepath.SkipDir
+		}
+		if rel == defaults.ManifestFileName || !isResourceFile(rel) {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		resources[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resources, nil
+}
+
+// diffResources returns the diffs of resource files that have been
+// added, removed or changed, sorted by path
+func diffResources(from, to map[string]string, fromName, toName string) (diffs []ResourceDiff) {
+	paths := make(map[string]struct{})
+	for path := range from {
+		paths[path] = struct{}{}
+	}
+	for path := range to {
+		paths[path] = struct{}{}
+	}
+	for path := range paths {
+		diff := diffText(from[path], to[path],
+			fromName+"/"+path, toName+"/"+path)
+		if diff == "" {
+			continue
+		}
+		diffs = append(diffs, ResourceDiff{Path: path, Diff: diff})
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs
+}
+
+// diffText returns the unified diff of the provided texts or an empty string
+// if they are the same
+func diffText(from, to, fromName, toName string) string {
+	if from == to {
+		return ""
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from),
+		B:        difflib.SplitLines(to),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+	if err != nil {
+		// the diff is written into a buffer so it never fails
+		return err.Error()
+	}
+	return diff
+}
+
+// subtractImages returns the sorted list of images in a that are not in b
+func subtractImages(a, b map[string]struct{}) (images []string) {
+	for image := range a {
+		if _, ok := b[image]; !ok {
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+func writeResourceFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, defaults.SharedReadMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return trace.ConvertSystemError(err)
+}
+
+func isResourceFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// tagLinkRe matches the links to the image tags in the application registry
+// capturing the image name and tag
+var tagLinkRe = regexp.MustCompile(`^registry/docker/registry/v2/repositories/(.+)/_manifests/tags/([^/]+)/current/link$`)
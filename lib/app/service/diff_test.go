@@ -0,0 +1,87 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type DiffSuite struct{}
+
+var _ = Suite(&DiffSuite{})
+
+func (s *DiffSuite) TestDiffAppContents(c *C) {
+	from := s.extract(c, map[string]string{
+		"resources/app.yaml":                "kind: Application",
+		"resources/config.yaml":             "kind: ConfigMap\nmetadata:\n  name: config\n",
+		"resources/removed.yaml":            "kind: Secret\n",
+		"resources/chart/Chart.yaml":        "name: chart\nversion: 0.0.1\n",
+		"resources/chart/values.yaml":       "replicas: 1\n",
+		"resources/chart/templates/rc.yaml": "replicas: {{ .Values.replicas }}\n",
+		"resources/README.md":               "not a resource",
+		tagPath("nginx", "1.0"):             "sha256:" + digest("a"),
+		tagPath("library/redis", "5.0"):     "sha256:" + digest("b"),
+		blobPath(digest("a")):               "layer",
+		manifestPath(digest("a")):           "sha256:" + digest("a"),
+		"registry/docker/registry/v2/other": "ignored",
+		"resources/../outside/escaped.yaml": "kind: Pod",
+	})
+	to := s.extract(c, map[string]string{
+		"resources/app.yaml":                "kind: Application",
+		"resources/config.yaml":             "kind: ConfigMap\nmetadata:\n  name: config\n",
+		"resources/added.yaml":              "kind: Service\n",
+		"resources/chart/Chart.yaml":        "name: chart\nversion: 0.0.2\n",
+		"resources/chart/values.yaml":       "replicas: 2\n",
+		"resources/chart/templates/rc.yaml": "replicas: {{ .Values.replicas }}\n",
+		tagPath("nginx", "1.0"):             "sha256:" + digest("c"),
+		tagPath("library/redis", "5.0"):     "sha256:" + digest("b"),
+	})
+
+	c.Assert(from.resources["chart"], Matches, "(?s).*replicas: 1.*")
+	_, ok := from.resources["app.yaml"]
+	c.Assert(ok, Equals, false, Commentf("manifest is compared separately"))
+	_, ok = from.resources["README.md"]
+	c.Assert(ok, Equals, false, Commentf("only resource files are compared"))
+
+	c.Assert(subtractImages(to.images, from.images), DeepEquals,
+		[]string{fmt.Sprintf("nginx:1.0@sha256:%v", digest("c"))})
+	c.Assert(subtractImages(from.images, to.images), DeepEquals,
+		[]string{fmt.Sprintf("nginx:1.0@sha256:%v", digest("a"))})
+
+	diffs := diffResources(from.resources, to.resources, "app:1.0.0", "app:2.0.0")
+	var paths []string
+	for _, diff := range diffs {
+		paths = append(paths, diff.Path)
+	}
+	c.Assert(paths, DeepEquals, []string{"added.yaml", "chart", "removed.yaml"})
+	c.Assert(diffs[1].Diff, Matches, "(?s).*--- app:1.0.0/chart.*-replicas: 1.*\\+replicas: 2.*")
+}
+
+func (s *DiffSuite) extract(c *C, files map[string]string) *appContents {
+	contents, err := extractAppContents(bytes.NewReader(makeTarball(c, files)), c.MkDir())
+	c.Assert(err, IsNil)
+	return contents
+}
+
+func tagPath(name, tag string) string {
+	return fmt.Sprintf("registry/docker/registry/v2/repositories/%v/_manifests/tags/%v/current/link",
+		name, strings.Replace(tag, "/", "", -1))
+}
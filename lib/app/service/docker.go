@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/gravitational/gravity/lib/app/docker"
@@ -35,9 +36,16 @@ import (
 
 // exportLayers exports the layers of the specified set of images into
 // the specified local directory
-func exportLayers(ctx context.Context, dir string, images []string, dockerClient docker.DockerInterface, log log.FieldLogger,
+//
+// If cache is set, images found in the cache are restored from it instead of
+// being pushed and newly pushed images are added to the cache.
+//
+// If more than one CPU architecture is specified, the image for each
+// architecture is pulled with puller and the images are exported as a manifest list
+func exportLayers(ctx context.Context, dir string, images []string, dockerClient docker.DockerInterface,
+	puller docker.DockerPuller, architectures []string, cache *docker.ImageCache, log log.FieldLogger,
 	parallel int, progress utils.Progress) error {
-	layerExporter, err := newLayerExporter(dir, dockerClient, log, progress)
+	layerExporter, err := newLayerExporter(dir, dockerClient, cache, log, progress)
 	if err != nil {
 		return trace.Wrap(err, "failed to create layer export")
 	}
@@ -47,6 +55,8 @@ func exportLayers(ctx context.Context, dir string, images []string, dockerClient
 		}
 	}()
 
+	layerExporter.puller = puller
+	layerExporter.architectures = architectures
 	if err = layerExporter.push(ctx, images, parallel); err != nil {
 		return trace.Wrap(err, "failed to push images to local registry")
 	}
@@ -55,7 +65,7 @@ func exportLayers(ctx context.Context, dir string, images []string, dockerClient
 }
 
 // newLayerExporter creates an instance of layer exporter
-func newLayerExporter(exportDir string, client docker.DockerInterface, log log.FieldLogger, progress utils.Progress) (*layerExporter, error) {
+func newLayerExporter(exportDir string, client docker.DockerInterface, cache *docker.ImageCache, log log.FieldLogger, progress utils.Progress) (*layerExporter, error) {
 	outputDir := filepath.Join(exportDir, defaults.RegistryDir)
 	config := docker.BasicConfiguration("127.0.0.1:0", outputDir)
 	registry, err := docker.NewRegistry(config)
@@ -69,6 +79,8 @@ func newLayerExporter(exportDir string, client docker.DockerInterface, log log.F
 		FieldLogger:      log,
 		dockerClient:     client,
 		registry:         registry,
+		registryDir:      outputDir,
+		cache:            cache,
 		progressReporter: progress,
 	}, nil
 }
@@ -80,14 +92,20 @@ type layerExporter struct {
 	log.FieldLogger
 	dockerClient     docker.DockerInterface
 	registry         *docker.Registry
+	registryDir      string
+	cache            *docker.ImageCache
 	progressReporter utils.Progress
+	// puller pulls platform-specific images for multi-arch exports
+	puller docker.DockerPuller
+	// architectures lists CPU architectures to export images for
+	architectures []string
 }
 
 // push pushes the list of specified images into the temporary local registry
 func (r *layerExporter) push(ctx context.Context, images []string, parallel int) error {
 	group, ctx := run.WithContext(ctx, run.WithParallel(parallel))
 	for _, image := range images {
-		group.Go(ctx, r.pushImage(image))
+		group.Go(ctx, r.pushImage(ctx, image))
 	}
 	if err := group.Wait(); err != nil {
 		return trace.Wrap(err)
@@ -100,12 +118,26 @@ func (r *layerExporter) stop() error {
 	return r.registry.Close()
 }
 
-func (r *layerExporter) pushImage(image string) func() error {
+func (r *layerExporter) pushImage(ctx context.Context, image string) func() error {
 	return func() error {
 		parsed, err := loc.ParseDockerImage(image)
 		if err != nil {
 			return trace.Wrap(err)
 		}
+		if r.isMultiArch(parsed) {
+			return trace.Wrap(r.pushMultiArchImage(ctx, image, parsed))
+		}
+		var imageID string
+		if r.cache != nil {
+			imageID, err = r.restoreCached(ctx, image, parsed)
+			if err == nil {
+				r.progressReporter.PrintSubStep("Using cached image %v", image)
+				return nil
+			}
+			if !trace.IsNotFound(err) {
+				r.Warnf("Failed to restore %v from cache: %v.", image, err)
+			}
+		}
 		if err = r.tagCmd(image, parsed.Repository, parsed.Tag); err != nil {
 			return trace.Wrap(err)
 		}
@@ -114,6 +146,12 @@ func (r *layerExporter) pushImage(image string) func() error {
 			return trace.Wrap(err)
 		}
 		r.progressReporter.PrintSubStep("Vendored image %v", image)
+		if imageID != "" {
+			err := r.cache.Save(ctx, imageID, r.registryDir, parsed.Repository, imageTag(parsed))
+			if err != nil {
+				r.Warnf("Failed to cache %v: %v.", image, err)
+			}
+		}
 		if err = r.removeTagCmd(parsed.Repository, parsed.Tag); err != nil {
 			r.Warnf("Failed to remove %v.", image)
 		}
@@ -121,6 +159,73 @@ func (r *layerExporter) pushImage(image string) func() error {
 	}
 }
 
+// isMultiArch returns true if the specified image should be exported
+// for multiple CPU architectures.
+// Images referenced by digest are always exported as is
+func (r *layerExporter) isMultiArch(image *loc.DockerImage) bool {
+	return len(r.architectures) > 1 && !strings.HasPrefix(image.Tag, "sha256:")
+}
+
+// pushMultiArchImage pulls the specified image for each of the exporter's
+// architectures, pushes the images into the temporary local registry
+// and combines them into a manifest list tagged with the image's tag
+func (r *layerExporter) pushMultiArchImage(ctx context.Context, image string, parsed *loc.DockerImage) error {
+	tag := imageTag(parsed)
+	var platformImages []docker.PlatformImage
+	for _, arch := range nativeArchLast(r.architectures) {
+		platformTag := docker.PlatformTag(tag, arch)
+		if err := r.puller.PullPlatform(image, fmt.Sprintf("linux/%v", arch)); err != nil {
+			return trace.Wrap(err, "failed to pull %v for %v", image, arch)
+		}
+		if err := r.tagCmd(image, parsed.Repository, platformTag); err != nil {
+			return trace.Wrap(err)
+		}
+		if err := r.pushCmd(parsed.Repository, platformTag); err != nil {
+			r.Warnf("Failed to push %v for %v: %v.", image, arch, err)
+			return trace.Wrap(err)
+		}
+		if err := r.removeTagCmd(parsed.Repository, platformTag); err != nil {
+			r.Warnf("Failed to remove %v for %v.", image, arch)
+		}
+		platformImages = append(platformImages, docker.PlatformImage{Tag: platformTag, Arch: arch})
+	}
+	err := docker.CreateManifestList(ctx, r.registryDir, parsed.Repository, tag, platformImages)
+	if err != nil {
+		return trace.Wrap(err, "failed to create manifest list for %v", image)
+	}
+	r.progressReporter.PrintSubStep("Vendored image %v for %v", image, strings.Join(r.architectures, ", "))
+	return nil
+}
+
+// nativeArchLast returns the list of architectures with the architecture
+// of this host moved to the end, so the local docker image is left in the
+// native variant after the platform-specific images have been pulled
+func nativeArchLast(architectures []string) (result []string) {
+	var native []string
+	for _, arch := range architectures {
+		if arch == runtime.GOARCH {
+			native = append(native, arch)
+			continue
+		}
+		result = append(result, arch)
+	}
+	return append(result, native...)
+}
+
+// restoreCached restores the specified image from the cache into the registry.
+// Returns the ID of the image
+func (r *layerExporter) restoreCached(ctx context.Context, image string, parsed *loc.DockerImage) (imageID string, err error) {
+	info, err := r.dockerClient.InspectImage(image)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	err = r.cache.Restore(ctx, info.ID, r.registryDir, parsed.Repository, imageTag(parsed))
+	if err != nil {
+		return info.ID, trace.Wrap(err)
+	}
+	return info.ID, nil
+}
+
 func (r *layerExporter) tagCmd(image, repository, tag string) error {
 	opts := dockerapi.TagImageOptions{
 		Repo:  fmt.Sprintf("%v/%v", r.registry.Addr(), repository),
@@ -140,6 +245,14 @@ func (r *layerExporter) pushCmd(name, tag string) error {
 	return r.dockerClient.PushImage(opts, dockerapi.AuthConfiguration{})
 }
 
+// imageTag returns the tag of the specified image
+func imageTag(image *loc.DockerImage) string {
+	if image.Tag == "" {
+		return "latest"
+	}
+	return image.Tag
+}
+
 func (r *layerExporter) removeTagCmd(name, tag string) error {
 	if tag == "" {
 		tag = "latest"
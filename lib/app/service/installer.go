@@ -18,6 +18,8 @@ package service
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -35,6 +37,7 @@ import (
 	"github.com/gravitational/gravity/lib/pack/encryptedpack"
 	"github.com/gravitational/gravity/lib/pack/localpack"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/signature"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
 	fileutils "github.com/gravitational/gravity/lib/utils"
@@ -66,7 +69,7 @@ func (r *applications) getClusterInstaller(
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	binary, err := r.getGravityBinaryForApp(app)
+	binaries, err := r.getGravityBinariesForApp(app)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -78,7 +81,7 @@ func (r *applications) getClusterInstaller(
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return []*archive.Item{binary}, nil
+	return binaries, nil
 }
 
 // GetAppInstaller builds an installer package for the
@@ -86,7 +89,8 @@ func (r *applications) getClusterInstaller(
 //
 // Steps to generate an installer:
 //
-//  * copy the gravity binary as ./gravity
+//  * copy the gravity binary as ./gravity (and ./gravity-<arch> for each
+//    additional CPU architecture of a multi-arch application)
 //  * start new backend as ./gravity.db to persist package metadata
 //  * start new package service in ./packages
 //  * import {web-assets,gravity,dns,teleport,planet-master,planet-node,application}
@@ -176,6 +180,22 @@ func (r *applications) GetAppInstaller(req appservice.InstallerRequest) (install
 		return nil, trace.Wrap(err)
 	}
 
+	if req.Base != nil {
+		err = r.makeDelta(*req.Base, app, localApps)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+
+	if len(req.SigningKey) != 0 {
+		var signatureItem *archive.Item
+		signatureItem, err = signInstaller(tempDir, manifestBytes, req.SigningKey)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		items = append(items, signatureItem)
+	}
+
 	reader, writer := io.Pipe()
 	go func() {
 		uploadScript, err := renderUploadScript(*app)
@@ -210,6 +230,25 @@ func (r *applications) GetAppInstaller(req appservice.InstallerRequest) (install
 	}, nil
 }
 
+// signInstaller signs the installer manifest and packages collected in dir
+// and returns the signature file to put into the installer
+func signInstaller(dir string, manifestBytes, signingKey []byte) (*archive.Item, error) {
+	digests, err := signature.Digests(dir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	digests = append(digests, signature.NewDigest(defaults.ManifestFileName, manifestBytes))
+	sig, err := signature.Sign(digests, signingKey)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	data, err := json.Marshal(sig)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return archive.ItemFromStringMode(defaults.SignatureFileName, string(data), defaults.SharedReadMask), nil
+}
+
 func renderUploadScript(app appservice.Application) (uploadScript []byte, err error) {
 	var buf bytes.Buffer
 	err = uploadScriptTemplate.Execute(&buf, &struct{}{})
@@ -219,21 +258,29 @@ func renderUploadScript(app appservice.Application) (uploadScript []byte, err er
 	return buf.Bytes(), nil
 }
 
-func (r *applications) getGravityBinaryForApp(app *appservice.Application) (*archive.Item, error) {
-	var gravityPackage *loc.Locator
+// getGravityBinariesForApp returns the gravity binaries for all CPU architectures
+// the application supports.
+// The binary for the default architecture is named gravity, binaries for other
+// architectures have the architecture appended, e.g. gravity-arm64
+func (r *applications) getGravityBinariesForApp(app *appservice.Application) (items []*archive.Item, err error) {
 	gravityPackage, err := app.Manifest.Dependencies.ByName(constants.GravityPackage)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-
-	var envelope *pack.PackageEnvelope
-	var packageBytes io.ReadCloser
-	envelope, packageBytes, err = r.Packages.ReadPackage(*gravityPackage)
-	if err != nil {
-		return nil, trace.Wrap(err)
+	for _, arch := range app.Manifest.Architectures() {
+		archPackage := schema.PackageForArch(*gravityPackage, arch)
+		envelope, packageBytes, err := r.Packages.ReadPackage(archPackage)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		name := constants.GravityBin
+		if arch != schema.ArchAMD64 {
+			name = fmt.Sprintf("%v-%v", name, arch)
+		}
+		items = append(items, archive.ItemFromStream(name, packageBytes,
+			envelope.SizeBytes, defaults.SharedExecutableMask))
 	}
-
-	return archive.ItemFromStream("gravity", packageBytes, envelope.SizeBytes, defaults.SharedExecutableMask), nil
+	return items, nil
 }
 
 // pullDependencies transitively pulls all dependent packages for app to localApps
@@ -345,10 +392,15 @@ REQMSG="This installer requires a 64-bit Linux desktop"
 main() {
     case $(uname) in
         "Linux")
-            arch=$(uname -m)
-            if [ $arch = "x86_64" ]; then
-                launchInstaller "$@"
-            fi
+            case $(uname -m) in
+                "x86_64") launchInstaller ./gravity "$@"
+                    ;;
+                "aarch64")
+                    if [ -x "$(dirname $0)/gravity-arm64" ]; then
+                        launchInstaller ./gravity-arm64 "$@"
+                    fi
+                    ;;
+            esac
             ;;
         "Darwin") osxError
             ;;
@@ -370,9 +422,11 @@ EOM
 }
 
 launchInstaller() {
+    binary=$1
+    shift
     # make the directory of the script current
     # and launch the install wizard:
-    cd $(dirname $0) && ./gravity wizard "$@"
+    cd $(dirname $0) && $binary wizard "$@"
     exit 0
 }
 
@@ -389,8 +443,12 @@ if [[ $(id -u) -ne 0 ]]; then
 fi
 
 scriptdir=$(dirname $(realpath $0))
-app=$($scriptdir/gravity app-package --state-dir=$scriptdir)
-$scriptdir/upload && $scriptdir/gravity --insecure update trigger $app
+gravity=$scriptdir/gravity
+if [[ $(uname -m) == "aarch64" ]]; then
+  gravity=$scriptdir/gravity-arm64
+fi
+app=$($gravity app-package --state-dir=$scriptdir)
+$scriptdir/upload && $gravity --insecure update trigger $app
 `
 
 	readme = `Requirements
@@ -457,5 +515,9 @@ var uploadScriptTemplate = template.Must(template.New("uploadScript").Parse(`#!/
 #
 #    http://www.apache.org/licenses/LICENSE-2.0
 #
-./gravity --insecure update upload --state-dir=.
+gravity=./gravity
+if [[ $(uname -m) == "aarch64" ]]; then
+  gravity=./gravity-arm64
+fi
+$gravity --insecure update upload --state-dir=.
 `))
@@ -0,0 +1,70 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/signature"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+type InstallerSuite struct{}
+
+var _ = check.Suite(&InstallerSuite{})
+
+func (s *InstallerSuite) TestSignsInstallerManifestAndPackages(c *check.C) {
+	dir := c.MkDir()
+	blobPath := filepath.Join(dir, defaults.PackagesDir, "blobs", "abc", "abcdef")
+	c.Assert(os.MkdirAll(filepath.Dir(blobPath), defaults.SharedDirMask), check.IsNil)
+	c.Assert(ioutil.WriteFile(blobPath, []byte("package"), defaults.SharedReadMask), check.IsNil)
+	manifestBytes := []byte("kind: Cluster")
+	privateKey, publicKey, err := signature.GenerateKey()
+	c.Assert(err, check.IsNil)
+
+	item, err := signInstaller(dir, manifestBytes, privateKey)
+	c.Assert(err, check.IsNil)
+	c.Assert(item.Name, check.Equals, defaults.SignatureFileName)
+
+	// lay out the installer the way it is unpacked on the target node
+	unpackedDir := c.MkDir()
+	data, err := ioutil.ReadAll(item.Data)
+	c.Assert(err, check.IsNil)
+	for path, contents := range map[string][]byte{
+		defaults.SignatureFileName:                                    data,
+		defaults.ManifestFileName:                                     manifestBytes,
+		filepath.Join(defaults.PackagesDir, "blobs", "abc", "abcdef"): []byte("package"),
+	} {
+		path = filepath.Join(unpackedDir, path)
+		c.Assert(os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask), check.IsNil)
+		c.Assert(ioutil.WriteFile(path, contents, defaults.SharedReadMask), check.IsNil)
+	}
+	trusted := []storage.TrustedKey{{Name: "release", PublicKey: publicKey}}
+	_, err = signature.Verify(unpackedDir, trusted)
+	c.Assert(err, check.IsNil)
+
+	c.Assert(ioutil.WriteFile(filepath.Join(unpackedDir, defaults.ManifestFileName),
+		[]byte("kind: Application"), defaults.SharedReadMask), check.IsNil)
+	_, err = signature.Verify(unpackedDir, trusted)
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/app/docker"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// ExportOCILayout writes the docker images of the specified application
+// and its dependencies to dir as an OCI image layout
+func ExportOCILayout(ctx context.Context, packages pack.PackageService, locator loc.Locator, dir string) (exported []docker.TagSpec, err error) {
+	envelope, err := packages.ReadPackageEnvelope(locator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	manifest, err := schema.ParseManifestYAMLNoValidate(envelope.Manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	locators := make([]loc.Locator, 0, len(manifest.Dependencies.Apps)+1)
+	for _, dependency := range manifest.Dependencies.Apps {
+		locators = append(locators, dependency.Locator)
+	}
+	locators = append(locators, locator)
+	for _, locator := range locators {
+		images, err := exportOCILayout(ctx, packages, locator, dir)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to export images of %v", locator)
+		}
+		exported = append(exported, images...)
+	}
+	return exported, nil
+}
+
+func exportOCILayout(ctx context.Context, packages pack.PackageService, locator loc.Locator, dir string) ([]docker.TagSpec, error) {
+	tempDir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(tempDir)
+	if err := pack.Unpack(packages, locator, tempDir, nil); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	registryDir := filepath.Join(tempDir, defaults.RegistryDir)
+	if ok, _ := utils.IsDirectory(registryDir); !ok {
+		// the application does not have any images
+		return nil, nil
+	}
+	images, err := docker.ExportOCILayout(ctx, registryDir, dir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return images, nil
+}
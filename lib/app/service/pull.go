@@ -262,6 +262,14 @@ func pullApp(req AppPullRequest, state *pullState) (*app.Application, error) {
 
 	application, err := req.SrcApp.GetApp(req.Package)
 	if err != nil {
+		if !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		// Delta installers do not ship applications that have not been
+		// updated so accept the application that's already present
+		if _, errDst := req.DstApp.GetApp(req.Package); errDst == nil {
+			return nil, trace.AlreadyExists("application %v already exists", req.Package)
+		}
 		return nil, trace.Wrap(err)
 	}
 
@@ -357,8 +365,12 @@ func pullAppDeps(req AppPullRequest, manifest schema.Manifest, state *pullState)
 	}
 
 	// pull dependent packages
+	deps, err := resolveDeps(req.SrcPack, manifest.AllPackageDependencies())
+	if err != nil {
+		return trace.Wrap(err)
+	}
 	group, ctx := run.WithContext(context.TODO(), run.WithParallel(req.Parallel))
-	for _, dep := range manifest.AllPackageDependencies() {
+	for _, dep := range deps {
 		if state.pulled(dep) {
 			req.Infof("Package %v already pulled.", dep)
 			continue
@@ -370,7 +382,11 @@ func pullAppDeps(req AppPullRequest, manifest schema.Manifest, state *pullState)
 	}
 
 	// pull dependent applications
-	for _, dep := range manifest.Dependencies.GetApps() {
+	apps, err := resolveDeps(req.SrcPack, manifest.Dependencies.GetApps())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, dep := range apps {
 		_, err := pullApp(req.Clone(dep), state)
 		if err != nil {
 			if !trace.IsAlreadyExists(err) {
@@ -383,6 +399,24 @@ func pullAppDeps(req AppPullRequest, manifest schema.Manifest, state *pullState)
 	return nil
 }
 
+// resolveDeps resolves dependencies specified with semver ranges
+// to the latest matching versions available in the provided package service
+func resolveDeps(packages pack.PackageService, deps []loc.Locator) ([]loc.Locator, error) {
+	resolved := make([]loc.Locator, 0, len(deps))
+	for _, dep := range deps {
+		if !dep.IsConstraint() {
+			resolved = append(resolved, dep)
+			continue
+		}
+		locator, err := pack.FindLatestMatchingPackage(packages, dep)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resolved = append(resolved, *locator)
+	}
+	return resolved, nil
+}
+
 func newPullState() *pullState {
 	return &pullState{
 		packages: make(map[loc.Locator]struct{}),
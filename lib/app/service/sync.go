@@ -140,6 +140,53 @@ func SyncApp(ctx context.Context, req SyncRequest) error {
 	return nil
 }
 
+// ListAppImages returns the docker images in the registries of the specified
+// application packages, their base applications and dependencies.
+// The packages are unpacked in unpackedDir unless they have already been
+func ListAppImages(ctx context.Context, apps app.Applications, packages pack.PackageService, unpackedDir string, locators ...loc.Locator) (images []docker.TagSpec, err error) {
+	visited := make(map[loc.Locator]struct{})
+	var list func(loc.Locator) error
+	list = func(locator loc.Locator) error {
+		if _, ok := visited[locator]; ok {
+			return nil
+		}
+		visited[locator] = struct{}{}
+		application, err := apps.GetApp(locator)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		dependencies := application.Manifest.Dependencies.GetApps()
+		if base := application.Manifest.Base(); base != nil {
+			dependencies = append(dependencies, *base)
+		}
+		for _, dependency := range dependencies {
+			if err := list(dependency); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		unpackedPath := pack.PackagePath(unpackedDir, locator)
+		if err := pack.UnpackIfNotUnpacked(packages, locator, unpackedPath, nil); err != nil {
+			return trace.Wrap(err)
+		}
+		registryDir := filepath.Join(unpackedPath, defaults.RegistryDir)
+		if exists, _ := utils.IsDirectory(registryDir); !exists {
+			return nil
+		}
+		appImages, err := docker.ListImages(ctx, registryDir)
+		if err != nil {
+			return trace.Wrap(err, "failed to list images of %v", locator)
+		}
+		images = append(images, appImages...)
+		return nil
+	}
+	for _, locator := range locators {
+		if err := list(locator); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return images, nil
+}
+
 func unpackRemotePackage(ctx context.Context, packages pack.PackageService, package_ loc.Locator, unpackPath string) error {
 	b := backoff.NewConstantBackOff(defaults.RetryInterval)
 	err := utils.RetryTransient(ctx, b, func() error {
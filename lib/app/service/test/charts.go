@@ -41,6 +41,22 @@ func CreateHelmChartApp(c *check.C, apps app.Applications, locator loc.Locator)
 	}, c)
 }
 
+// CreateAppWithVendoredChart creates a new test application with the
+// specified locator that vendors a Helm chart with the given name and version.
+func CreateAppWithVendoredChart(c *check.C, apps app.Applications, locator loc.Locator, chartName, chartVersion string) *app.Application {
+	return CreateApplicationFromData(apps, locator, []*archive.Item{
+		archive.DirItem("resources"),
+		archive.DirItem("resources/charts"),
+		archive.DirItem(fmt.Sprintf("resources/charts/%v", chartName)),
+		archive.ItemFromString(fmt.Sprintf("resources/charts/%v/Chart.yaml", chartName),
+			fmt.Sprintf(chartYAML, chartName, chartVersion)),
+		archive.ItemFromString(fmt.Sprintf("resources/charts/%v/values.yaml", chartName),
+			valuesYAML),
+		archive.ItemFromString("resources/app.yaml", fmt.Sprintf(
+			systemAppYAML, locator.Name, locator.Version)),
+	}, c)
+}
+
 // Chart returns chart object corresponding to the test chart defined below.
 func Chart(locator loc.Locator) *chart.Chart {
 	return &chart.Chart{
@@ -69,6 +85,12 @@ version: %v`
     localhost:5000`
 	appYAML = `apiVersion: bundle.gravitational.io/v2
 kind: Application
+metadata:
+  name: %v
+  resourceVersion: %v
+  repository: gravitational.io`
+	systemAppYAML = `apiVersion: bundle.gravitational.io/v2
+kind: SystemApplication
 metadata:
   name: %v
   resourceVersion: %v
@@ -0,0 +1,215 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"strings"
+
+	appservice "github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// finalizeUninstall waits for the namespaces and persistent volumes created
+// by the specified application to be removed by its uninstall hooks.
+//
+// If the resources are still present once the wait times out, they are either
+// forcibly finalized if force is set or the uninstall fails.
+// Applications without uninstall hooks are not expected to clean up after
+// themselves so their resources are not verified
+func (r *applications) finalizeUninstall(ctx context.Context, locator loc.Locator, force bool) error {
+	app, err := r.GetApp(locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !app.Manifest.HasHook(schema.HookUninstall) && !app.Manifest.HasHook(schema.HookUninstalling) {
+		return nil
+	}
+	var resources uninstallResources
+	err = appservice.ForEachResource(r, locator, resources.add)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if resources.empty() {
+		return nil
+	}
+	client, err := r.getKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	r.Infof("Waiting for %v of %v to be removed.", resources, locator)
+	err = utils.RetryFor(ctx, defaults.UninstallFinalizeTimeout, func() error {
+		remaining, err := resources.remaining(client)
+		if err != nil {
+			return utils.Abort(err)
+		}
+		if !remaining.empty() {
+			return trace.CompareFailed("%v are still present", remaining)
+		}
+		return nil
+	})
+	if err == nil {
+		return nil
+	}
+	if !force {
+		return trace.Wrap(err, "%v has not been removed by the uninstall hooks, "+
+			"use force to finalize the remaining resources", locator)
+	}
+	r.Warnf("Forcibly finalizing resources of %v: %v.", locator, err)
+	remaining, err := resources.remaining(client)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(remaining.finalize(client))
+}
+
+// uninstallResources lists the cluster-scoped resources created by an application
+type uninstallResources struct {
+	// namespaces lists the names of the application namespaces
+	namespaces []string
+	// volumes lists the names of the application persistent volumes
+	volumes []string
+}
+
+// add records the specified object from the application resources
+// if it is a namespace or a persistent volume.
+//
+// System namespaces are never considered to be owned by an application
+func (r *uninstallResources) add(object runtime.Object) error {
+	switch resource := object.(type) {
+	case *v1.Namespace:
+		if utils.StringInSlice(systemNamespaces, resource.Name) ||
+			utils.StringInSlice(r.namespaces, resource.Name) {
+			return nil
+		}
+		r.namespaces = append(r.namespaces, resource.Name)
+	case *v1.PersistentVolume:
+		if utils.StringInSlice(r.volumes, resource.Name) {
+			return nil
+		}
+		r.volumes = append(r.volumes, resource.Name)
+	}
+	return nil
+}
+
+// remaining returns the resources that are still present in the cluster.
+//
+// Besides the volumes defined in the application resources, this includes
+// the volumes bound to claims in the application namespaces
+func (r uninstallResources) remaining(client kubernetes.Interface) (*uninstallResources, error) {
+	var remaining uninstallResources
+	for _, name := range r.namespaces {
+		_, err := client.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+		if err != nil {
+			err = rigging.ConvertError(err)
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		remaining.namespaces = append(remaining.namespaces, name)
+	}
+	volumes, err := client.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, trace.Wrap(rigging.ConvertError(err))
+	}
+	for _, volume := range volumes.Items {
+		claim := volume.Spec.ClaimRef
+		if utils.StringInSlice(r.volumes, volume.Name) ||
+			(claim != nil && utils.StringInSlice(r.namespaces, claim.Namespace)) {
+			remaining.volumes = append(remaining.volumes, volume.Name)
+		}
+	}
+	return &remaining, nil
+}
+
+// finalize deletes the resources and removes their finalizers
+// so they are removed without waiting for the cleanup
+func (r uninstallResources) finalize(client kubernetes.Interface) error {
+	for _, name := range r.volumes {
+		err := client.CoreV1().PersistentVolumes().Delete(name, &metav1.DeleteOptions{})
+		if err != nil && !trace.IsNotFound(rigging.ConvertError(err)) {
+			return trace.Wrap(rigging.ConvertError(err))
+		}
+		volume, err := client.CoreV1().PersistentVolumes().Get(name, metav1.GetOptions{})
+		if err != nil {
+			err = rigging.ConvertError(err)
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return trace.Wrap(err)
+		}
+		volume.Finalizers = nil
+		_, err = client.CoreV1().PersistentVolumes().Update(volume)
+		if err != nil && !trace.IsNotFound(rigging.ConvertError(err)) {
+			return trace.Wrap(rigging.ConvertError(err))
+		}
+	}
+	for _, name := range r.namespaces {
+		err := client.CoreV1().Namespaces().Delete(name, &metav1.DeleteOptions{})
+		if err != nil && !trace.IsNotFound(rigging.ConvertError(err)) {
+			return trace.Wrap(rigging.ConvertError(err))
+		}
+		namespace, err := client.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+		if err != nil {
+			err = rigging.ConvertError(err)
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return trace.Wrap(err)
+		}
+		namespace.Spec.Finalizers = nil
+		_, err = client.CoreV1().Namespaces().Finalize(namespace)
+		if err != nil && !trace.IsNotFound(rigging.ConvertError(err)) {
+			return trace.Wrap(rigging.ConvertError(err))
+		}
+	}
+	return nil
+}
+
+func (r uninstallResources) empty() bool {
+	return len(r.namespaces) == 0 && len(r.volumes) == 0
+}
+
+// String returns a textual representation of the resources
+func (r uninstallResources) String() string {
+	var resources []string
+	if len(r.namespaces) != 0 {
+		resources = append(resources, "namespaces "+strings.Join(r.namespaces, ", "))
+	}
+	if len(r.volumes) != 0 {
+		resources = append(resources, "persistent volumes "+strings.Join(r.volumes, ", "))
+	}
+	return strings.Join(resources, " and ")
+}
+
+// systemNamespaces lists the namespaces that are not removed with applications
+var systemNamespaces = []string{
+	metav1.NamespaceDefault,
+	metav1.NamespaceSystem,
+	metav1.NamespacePublic,
+}
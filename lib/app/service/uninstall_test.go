@@ -0,0 +1,68 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"strings"
+
+	"github.com/gravitational/gravity/lib/app/resources"
+
+	"gopkg.in/check.v1"
+)
+
+type UninstallSuite struct{}
+
+var _ = check.Suite(&UninstallSuite{})
+
+func (s *UninstallSuite) TestCollectsApplicationResources(c *check.C) {
+	spec := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: app
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: kube-system
+---
+apiVersion: v1
+kind: PersistentVolume
+metadata:
+  name: app-data
+spec:
+  capacity:
+    storage: 1Gi
+  accessModes: ["ReadWriteOnce"]
+  hostPath:
+    path: /var/data
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: app
+`
+	var collected uninstallResources
+	err := resources.ForEachObject(strings.NewReader(spec), collected.add)
+	c.Assert(err, check.IsNil)
+	c.Assert(collected, check.DeepEquals, uninstallResources{
+		namespaces: []string{"app"},
+		volumes:    []string{"app-data"},
+	})
+	c.Assert(collected.String(), check.Equals, "namespaces app and persistent volumes app-data")
+	c.Assert(uninstallResources{}.empty(), check.Equals, true)
+}
@@ -25,6 +25,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -37,6 +38,7 @@ import (
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/helm"
 	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/network/calico"
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/run"
 	"github.com/gravitational/gravity/lib/schema"
@@ -60,6 +62,9 @@ type VendorerConfig struct {
 	RegistryURL string
 	// Packages is the pack service
 	Packages pack.PackageService
+	// ImageCache is an optional cache of exported images.
+	// If set, images found in the cache are not exported again
+	ImageCache *docker.ImageCache
 }
 
 // NewVendorer creates a new vendorer instance.
@@ -74,7 +79,12 @@ func NewVendorer(conf VendorerConfig) (*vendorer, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return NewVendorerFromClients(dockerClient, imageService, conf.RegistryURL, conf.Packages)
+	v, err := NewVendorerFromClients(dockerClient, imageService, conf.RegistryURL, conf.Packages)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	v.imageCache = conf.ImageCache
+	return v, nil
 }
 
 // NewVendorerFromClients creates a new vendorer helper from existing docker client - useful in tests.
@@ -118,6 +128,9 @@ type VendorRequest struct {
 	SetImages []loc.DockerImage
 	// SetDeps is a list of app dependencies to rewrite to new versions
 	SetDeps []loc.Locator
+	// IntermediateRuntimes optionally lists the intermediate runtimes
+	// to embed into the cluster image for multi-hop upgrades
+	IntermediateRuntimes []loc.Locator
 	// VendorRuntime specifies whether to translate runtime images into packages.
 	// The vendoring of the runtime package is a multi-step process which also requires
 	// access to the package store used for building the final application installer
@@ -132,6 +145,9 @@ type VendorRequest struct {
 	// ProgressReporter is a special writer, if set, vendorer will output user-friendly
 	// information during vendoring
 	ProgressReporter utils.Progress
+	// PulledImagesFn is an optional function invoked with the list of
+	// application images once they have been pulled to the local docker
+	PulledImagesFn func(images []string)
 }
 
 // vendorer is a helper struct that encapsulates all services needed to vendor/rewrite images in
@@ -142,6 +158,7 @@ type vendorer struct {
 	dockerPuller docker.DockerPuller
 	registryURL  string
 	packages     pack.PackageService
+	imageCache   *docker.ImageCache
 }
 
 // VendorTarball is the same as VendorDir but accepts a tarball stream and unpacks it before vendoring.
@@ -178,7 +195,7 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 	}
 
 	// parse all resources
-	resourceFiles, chartResources, err := resourcesFromPath(unpackedDir, req.ResourcePatterns, req.IgnoreResourcePatterns)
+	resourceFiles, chartResources, err := ResourcesFromPath(unpackedDir, req.ResourcePatterns, req.IgnoreResourcePatterns)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -232,11 +249,14 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 		return trace.Wrap(err)
 	}
 
-	var runtimeImages []string
+	var runtimeImages, architectures, networkImages []string
 	manifestRewrites := []resources.ManifestRewriteFunc{
 		makeRewriteDepsFunc(req.SetDeps),
 		makeRewritePackagesMetadataFunc(v.packages),
 		makeRewriteAppMetadataFunc(req.Repository, req.PackageName, req.PackageVersion),
+		makeRewriteIntermediateRuntimesFunc(req.IntermediateRuntimes),
+		fetchArchitectures(&architectures),
+		fetchNetworkImages(&networkImages),
 	}
 	if req.VendorRuntime {
 		manifestRewrites = append(manifestRewrites, fetchRuntimeImages(&runtimeImages))
@@ -250,6 +270,7 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 	// pull the default container image along with the rest of images
 	imagesToPull := append(images, defaults.ContainerImage)
 	imagesToPull = append(imagesToPull, runtimeImages...)
+	imagesToPull = append(imagesToPull, networkImages...)
 
 	group, groupCtx := run.WithContext(ctx, run.WithParallel(req.Parallel))
 	for _, image := range imagesToPull {
@@ -279,6 +300,16 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 		return trace.Wrap(err)
 	}
 
+	if req.PulledImagesFn != nil {
+		var pulled []string
+		for _, image := range images {
+			if !strings.HasPrefix(image, v.registryURL) {
+				pulled = append(pulled, image)
+			}
+		}
+		req.PulledImagesFn(pulled)
+	}
+
 	if req.VendorRuntime {
 		err = resourceFiles.RewriteManifest(v.translateRuntimeImages)
 		if err != nil {
@@ -308,11 +339,11 @@ func (v *vendorer) VendorDir(ctx context.Context, unpackedDir string, req Vendor
 	}
 
 	log.Infof("No registry layers found, will pull and export images %q.", images)
-	if err = v.pullAndExportImages(ctx, teleutils.Deduplicate(images), unpackedDir, req.Parallel, req.ProgressReporter); err != nil {
+	if err = v.pullAndExportImages(ctx, teleutils.Deduplicate(images), unpackedDir, architectures, req.Parallel, req.ProgressReporter); err != nil {
 		return trace.Wrap(err)
 	}
 
-	if err = v.pullAndExportImages(ctx, teleutils.Deduplicate(chartImages), unpackedDir, req.Parallel, req.ProgressReporter); err != nil {
+	if err = v.pullAndExportImages(ctx, teleutils.Deduplicate(chartImages), unpackedDir, architectures, req.Parallel, req.ProgressReporter); err != nil {
 		return trace.Wrap(err)
 	}
 
@@ -352,8 +383,10 @@ func printResourceStatus(resourceFile resources.ResourceFile, manifestPath strin
 
 // pullAndExportImages pulls the docker images of all referenced container images (if not yet
 // present locally), pushes them into an instance of a private docker registry and then
-// dumps the contents of this private registry into the specified directory
-func (v *vendorer) pullAndExportImages(ctx context.Context, images []string, exportDir string, parallel int, progress utils.Progress) error {
+// dumps the contents of this private registry into the specified directory.
+// If more than one CPU architecture is given, images are exported as manifest lists
+// of images for each architecture
+func (v *vendorer) pullAndExportImages(ctx context.Context, images []string, exportDir string, architectures []string, parallel int, progress utils.Progress) error {
 	resourcesDir := filepath.Join(exportDir, "resources")
 	if err := os.MkdirAll(resourcesDir, defaults.PrivateDirMask); err != nil {
 		return trace.Wrap(trace.ConvertSystemError(err),
@@ -371,8 +404,8 @@ func (v *vendorer) pullAndExportImages(ctx context.Context, images []string, exp
 			"failed to create %q", layersDir)
 	}
 
-	if err := exportLayers(ctx, exportDir, images, v.dockerClient,
-		log.WithField("export-directory", exportDir), parallel, progress); err != nil {
+	if err := exportLayers(ctx, exportDir, images, v.dockerClient, v.dockerPuller, architectures,
+		v.imageCache, log.WithField("export-directory", exportDir), parallel, progress); err != nil {
 		return trace.Wrap(err)
 	}
 	return nil
@@ -554,18 +587,19 @@ func makeRewriteWormholeJobFunc() resources.ManifestRewriteFunc {
 				m.Hooks = &schema.Hooks{}
 			}
 
+			settings := m.Providers.Generic.Networking.Wireguard
 			var err error
-			m.Hooks.NetworkInstall, err = generateWormholeHook(schema.HookNetworkInstall)
+			m.Hooks.NetworkInstall, err = generateWormholeHook(schema.HookNetworkInstall, settings)
 			if err != nil {
 				return trace.Wrap(err)
 			}
 
-			m.Hooks.NetworkUpdate, err = generateWormholeHook(schema.HookNetworkUpdate)
+			m.Hooks.NetworkUpdate, err = generateWormholeHook(schema.HookNetworkUpdate, settings)
 			if err != nil {
 				return trace.Wrap(err)
 			}
 
-			m.Hooks.NetworkRollback, err = generateWormholeHook(schema.HookNetworkRollback)
+			m.Hooks.NetworkRollback, err = generateWormholeHook(schema.HookNetworkRollback, settings)
 			if err != nil {
 				return trace.Wrap(err)
 			}
@@ -574,8 +608,13 @@ func makeRewriteWormholeJobFunc() resources.ManifestRewriteFunc {
 	}
 }
 
-// generateWormholeHook generates a gravity hook for installing wormhole encrypted network plugin
-func generateWormholeHook(hook schema.HookType) (*schema.Hook, error) {
+// wormholePortEnv is the environment variable that passes the WireGuard
+// port to the wormhole hooks
+const wormholePortEnv = "WORMHOLE_PORT"
+
+// generateWormholeHook generates a gravity hook for installing wormhole encrypted network plugin.
+// The optional settings are passed to the hook as environment variables
+func generateWormholeHook(hook schema.HookType, settings *schema.WireguardNetworking) (*schema.Hook, error) {
 	script := ""
 
 	switch hook {
@@ -589,6 +628,14 @@ func generateWormholeHook(hook schema.HookType) (*schema.Hook, error) {
 		return nil, trace.BadParameter("unsupported hook: %v", hook)
 	}
 
+	var env []corev1.EnvVar
+	if settings != nil && settings.Port != 0 {
+		env = append(env, corev1.EnvVar{
+			Name:  wormholePortEnv,
+			Value: strconv.Itoa(settings.Port),
+		})
+	}
+
 	job := batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: constants.WireguardNetworkType,
@@ -602,6 +649,7 @@ func generateWormholeHook(hook schema.HookType) (*schema.Hook, error) {
 							Name:    "hook",
 							Image:   defaults.WormholeImg,
 							Command: []string{script},
+							Env:     env,
 						},
 					},
 				},
@@ -682,6 +730,22 @@ func makeRewritePackagesMetadataFunc(packages pack.PackageService) resources.Man
 	}
 }
 
+// makeRewriteIntermediateRuntimesFunc returns a function that sets the intermediate
+// runtimes in the manifest to the specified list unless it is empty
+func makeRewriteIntermediateRuntimesFunc(runtimes []loc.Locator) resources.ManifestRewriteFunc {
+	return func(m *schema.Manifest) error {
+		if len(runtimes) == 0 {
+			return nil
+		}
+		if m.Base() == nil {
+			return trace.BadParameter("intermediate runtimes can only be specified for cluster images")
+		}
+		log.Infof("Intermediate runtimes: %v.", runtimes)
+		m.SetIntermediateRuntimes(runtimes)
+		return nil
+	}
+}
+
 func fetchRuntimeImages(images *[]string) resources.ManifestRewriteFunc {
 	return func(m *schema.Manifest) error {
 		*images = m.RuntimeImages()
@@ -689,6 +753,28 @@ func fetchRuntimeImages(images *[]string) resources.ManifestRewriteFunc {
 	}
 }
 
+// fetchNetworkImages returns a function that collects the images
+// of the network bundled with gravity selected in the manifest
+func fetchNetworkImages(images *[]string) resources.ManifestRewriteFunc {
+	return func(m *schema.Manifest) error {
+		if m.Providers == nil {
+			return nil
+		}
+		if m.Providers.Generic.Networking.Type == schema.NetworkingCalico ||
+			m.Providers.AWS.Networking.Type == schema.NetworkingCalico {
+			*images = calico.Images()
+		}
+		return nil
+	}
+}
+
+func fetchArchitectures(architectures *[]string) resources.ManifestRewriteFunc {
+	return func(m *schema.Manifest) error {
+		*architectures = m.Architectures()
+		return nil
+	}
+}
+
 func isChartDirectory(path string) (bool, error) {
 	fi, err := os.Stat(filepath.Join(path, constants.HelmChartFile))
 	err = trace.ConvertSystemError(err)
@@ -710,11 +796,11 @@ func resourceFromChart(path string) (*resources.Resource, error) {
 	return resources.Decode(bytes.NewReader(out))
 }
 
-// resourcesFromPath collects resource files in root for further processing.
+// ResourcesFromPath collects resource files in root for further processing.
 // It will search for files starting with root and matching a set of file path patterns
 // specified with patterns.
 // Returns a list of collected resource files upon success.
-func resourcesFromPath(root string, includePatterns []string, ignorePatterns []string) (result resources.ResourceFiles, chartResources resources.ResourceFiles, err error) {
+func ResourcesFromPath(root string, includePatterns []string, ignorePatterns []string) (result resources.ResourceFiles, chartResources resources.ResourceFiles, err error) {
 	err = filepath.Walk(root, func(path string, fileInfo os.FileInfo, err error) error {
 		if err != nil {
 			return trace.Wrap(err)
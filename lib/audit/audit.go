@@ -0,0 +1,114 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit implements the audit log of operator API calls.
+//
+// Events are persisted through the storage backend and can optionally
+// be forwarded to syslog or a webhook.
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/pborman/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Config defines the audit log configuration
+type Config struct {
+	// Backend is the storage backend the events are persisted in
+	Backend storage.Backend
+	// Retention is how long the events are kept
+	Retention time.Duration
+	// Forwarders is an optional list of destinations the events
+	// are additionally sent to
+	Forwarders []Forwarder
+	// Clock is used to timestamp events
+	Clock clockwork.Clock
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Backend == nil {
+		return trace.BadParameter("missing Backend")
+	}
+	if c.Retention == 0 {
+		c.Retention = defaults.AuditEventRetention
+	}
+	if c.Clock == nil {
+		c.Clock = clockwork.NewRealClock()
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "audit")
+	}
+	return nil
+}
+
+// Forwarder sends audit events to an external destination
+type Forwarder interface {
+	// Forward sends the event to the destination
+	Forward(ctx context.Context, event storage.AuditEvent) error
+}
+
+// Log records audit events of operator API calls
+type Log struct {
+	Config
+}
+
+// New returns a new audit log
+func New(config Config) (*Log, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Log{Config: config}, nil
+}
+
+// Record persists the event and sends it to the configured forwarders
+// in the background.
+//
+// Failure to forward the event is logged but not returned
+func (l *Log) Record(event storage.AuditEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.New()
+	}
+	if event.Time.IsZero() {
+		event.Time = l.Clock.Now().UTC()
+	}
+	err := l.Backend.CreateAuditEvent(event, l.Retention)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(l.Forwarders) != 0 {
+		go l.forward(event)
+	}
+	return nil
+}
+
+func (l *Log) forward(event storage.AuditEvent) {
+	for _, forwarder := range l.Forwarders {
+		if err := forwarder.Forward(context.Background(), event); err != nil {
+			l.Warnf("Failed to forward audit event %v: %v.", event.ID, trace.DebugReport(err))
+		}
+	}
+}
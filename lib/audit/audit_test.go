@@ -0,0 +1,97 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/jonboulle/clockwork"
+	. "gopkg.in/check.v1"
+)
+
+func TestAudit(t *testing.T) { TestingT(t) }
+
+type AuditSuite struct {
+	backend storage.Backend
+	clock   clockwork.FakeClock
+}
+
+var _ = Suite(&AuditSuite{})
+
+func (s *AuditSuite) SetUpTest(c *C) {
+	var err error
+	s.backend, err = keyval.NewBolt(keyval.BoltConfig{
+		Path: filepath.Join(c.MkDir(), "bolt.db"),
+	})
+	c.Assert(err, IsNil)
+	s.clock = clockwork.NewFakeClockAt(time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC))
+}
+
+func (s *AuditSuite) TearDownTest(c *C) {
+	if s.backend != nil {
+		s.backend.Close()
+	}
+}
+
+func (s *AuditSuite) TestRecordsAndForwardsEvents(c *C) {
+	received := make(chan storage.AuditEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event storage.AuditEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	log, err := New(Config{
+		Backend:    s.backend,
+		Forwarders: []Forwarder{NewWebhookForwarder(server.URL)},
+		Clock:      s.clock,
+	})
+	c.Assert(err, IsNil)
+
+	err = log.Record(storage.AuditEvent{
+		User:   "alice@example.com",
+		Action: "POST /portal/v1/accounts/:account_id/sites",
+	})
+	c.Assert(err, IsNil)
+
+	events, err := s.backend.GetAuditEvents(s.clock.Now().Add(-time.Minute))
+	c.Assert(err, IsNil)
+	c.Assert(events, HasLen, 1)
+	c.Assert(events[0].ID, Not(Equals), "")
+	c.Assert(events[0].Time.Equal(s.clock.Now()), Equals, true)
+	c.Assert(events[0].User, Equals, "alice@example.com")
+
+	select {
+	case event := <-received:
+		c.Assert(event.ID, Equals, events[0].ID)
+		c.Assert(event.Action, Equals, events[0].Action)
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for the forwarded event")
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/syslog"
+	"net/http"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/httplib"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// NewSyslogForwarder returns a forwarder that writes events
+// to the local syslog daemon as JSON with the specified tag
+func NewSyslogForwarder(tag string) (*SyslogForwarder, error) {
+	if tag == "" {
+		tag = defaults.AuditSyslogTag
+	}
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &SyslogForwarder{writer: writer}, nil
+}
+
+// SyslogForwarder forwards events to syslog
+type SyslogForwarder struct {
+	writer *syslog.Writer
+}
+
+// Forward writes the event to syslog
+func (f *SyslogForwarder) Forward(ctx context.Context, event storage.AuditEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(f.writer.Info(string(data)))
+}
+
+// NewWebhookForwarder returns a forwarder that posts events
+// as JSON to the specified URL
+func NewWebhookForwarder(url string) *WebhookForwarder {
+	client := httplib.GetClient(false)
+	client.Timeout = defaults.AuditWebhookTimeout
+	return &WebhookForwarder{url: url, client: client}
+}
+
+// WebhookForwarder forwards events to a webhook
+type WebhookForwarder struct {
+	url    string
+	client *http.Client
+}
+
+// Forward posts the event to the webhook
+func (f *WebhookForwarder) Forward(ctx context.Context, event storage.AuditEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, f.url, bytes.NewReader(data))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := f.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return trace.BadParameter("webhook %v responded with %v", f.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+
+	"github.com/gravitational/teleport"
+	teledefaults "github.com/gravitational/teleport/lib/defaults"
+	"github.com/gravitational/teleport/lib/events"
+	"github.com/gravitational/teleport/lib/session"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// SessionConfig defines the configuration of an interactive session recorder
+type SessionConfig struct {
+	// Operator is the cluster operator the session events and
+	// the recording are submitted to
+	Operator ops.Audit
+	// ClusterKey identifies the cluster the session takes place on
+	ClusterKey ops.SiteKey
+	// DataDir is the directory the recording is kept in until it is uploaded
+	DataDir string
+	// ServerID identifies the server the session takes place on
+	ServerID string
+	// User is the name of the cluster user who started the session
+	User string
+	// Login is the OS login the session runs as
+	Login string
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (c *SessionConfig) CheckAndSetDefaults() error {
+	if c.Operator == nil {
+		return trace.BadParameter("missing Operator")
+	}
+	if err := c.ClusterKey.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if c.DataDir == "" {
+		return trace.BadParameter("missing DataDir")
+	}
+	if c.ServerID == "" {
+		return trace.BadParameter("missing ServerID")
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "session")
+	}
+	return nil
+}
+
+// SessionRecorder records an interactive session in the format
+// used by teleport so it can be played back from the web UI.
+//
+// The session output is written to the local data directory while
+// the session is active. Session events are forwarded to the cluster
+// audit log as they happen and the recording is uploaded to the cluster
+// once the session has ended.
+type SessionRecorder struct {
+	SessionConfig
+	ctx      context.Context
+	id       session.ID
+	recorder *events.ForwardRecorder
+}
+
+// NewSessionRecorder returns a new recorder for a session with a new ID
+func NewSessionRecorder(ctx context.Context, config SessionConfig) (*SessionRecorder, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// the recorder expects the session log directory to exist
+	err := os.MkdirAll(filepath.Join(config.DataDir, teleport.ComponentUpload,
+		events.SessionLogsDir, teledefaults.Namespace), defaults.PrivateDirMask)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	id := session.NewID()
+	recorder, err := events.NewForwardRecorder(events.ForwardRecorderConfig{
+		DataDir:        config.DataDir,
+		SessionID:      id,
+		Namespace:      teledefaults.Namespace,
+		RecordSessions: true,
+		Component:      "session",
+		ForwardTo: &sessionEventForwarder{
+			ctx:        ctx,
+			operator:   config.Operator,
+			clusterKey: config.ClusterKey,
+		},
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &SessionRecorder{
+		SessionConfig: config,
+		ctx:           ctx,
+		id:            id,
+		recorder:      recorder,
+	}, nil
+}
+
+// ID returns the ID of the recorded session
+func (r *SessionRecorder) ID() session.ID {
+	return r.id
+}
+
+// Start records the beginning of the session with the specified terminal size
+func (r *SessionRecorder) Start(params session.TerminalParams) error {
+	return r.emit(events.SessionStartEvent, events.EventFields{
+		events.SessionServerID: r.ServerID,
+		events.EventLogin:      r.Login,
+		events.TerminalSize:    params.Serialize(),
+	})
+}
+
+// Resize records the change of the session terminal size
+func (r *SessionRecorder) Resize(params session.TerminalParams) error {
+	return r.emit(events.ResizeEvent, events.EventFields{
+		events.EventLogin:   r.Login,
+		events.TerminalSize: params.Serialize(),
+	})
+}
+
+// Write records a chunk of the session output
+func (r *SessionRecorder) Write(data []byte) (int, error) {
+	return r.recorder.Write(data)
+}
+
+// Stop records the end of the session and uploads the recording
+// to the cluster
+func (r *SessionRecorder) Stop() error {
+	err := r.emit(events.SessionEndEvent, nil)
+	if err != nil {
+		r.Warnf("Failed to record end of session %v: %v.", r.id, trace.DebugReport(err))
+	}
+	if err := r.recorder.Close(); err != nil {
+		return trace.Wrap(err)
+	}
+	archive, err := events.NewSessionArchive(r.DataDir, teleport.ComponentUpload,
+		teledefaults.Namespace, r.id)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer archive.Close()
+	err = r.Operator.UploadSessionRecording(r.ctx, ops.UploadSessionRecordingRequest{
+		SiteKey:   r.ClusterKey,
+		SessionID: r.id.String(),
+	}, archive)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+func (r *SessionRecorder) emit(eventType string, fields events.EventFields) error {
+	if fields == nil {
+		fields = events.EventFields{}
+	}
+	fields[events.SessionEventID] = r.id.String()
+	fields[events.EventUser] = r.User
+	fields[events.EventNamespace] = teledefaults.Namespace
+	return trace.Wrap(r.recorder.GetAuditLog().EmitAuditEvent(eventType, fields))
+}
+
+// sessionEventForwarder submits the session events other than the
+// session output to the cluster audit log
+type sessionEventForwarder struct {
+	events.DiscardAuditLog
+	ctx        context.Context
+	operator   ops.Audit
+	clusterKey ops.SiteKey
+}
+
+// PostSessionSlice submits the events from the provided session slice
+// to the cluster audit log
+func (f *sessionEventForwarder) PostSessionSlice(slice events.SessionSlice) error {
+	for _, chunk := range slice.Chunks {
+		fields, err := events.EventFromChunk(slice.SessionID, chunk)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = f.operator.EmitAuditEvent(f.ctx, ops.AuditEventRequest{
+			SiteKey: f.clusterKey,
+			Type:    chunk.EventType,
+			Fields:  fields,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/teleport/lib/events"
+	"github.com/gravitational/teleport/lib/session"
+	. "gopkg.in/check.v1"
+)
+
+type SessionSuite struct{}
+
+var _ = Suite(&SessionSuite{})
+
+func (s *SessionSuite) TestRecordsSession(c *C) {
+	audit := &testAudit{}
+	key := ops.SiteKey{AccountID: "account", SiteDomain: "example.com"}
+	recorder, err := NewSessionRecorder(context.TODO(), SessionConfig{
+		Operator:   audit,
+		ClusterKey: key,
+		DataDir:    c.MkDir(),
+		ServerID:   "node-1",
+		User:       "alice@example.com",
+		Login:      "root",
+	})
+	c.Assert(err, IsNil)
+
+	params, err := session.NewTerminalParamsFromInt(80, 25)
+	c.Assert(err, IsNil)
+	c.Assert(recorder.Start(*params), IsNil)
+	_, err = recorder.Write([]byte("hello"))
+	c.Assert(err, IsNil)
+	c.Assert(recorder.Stop(), IsNil)
+
+	// session output is only part of the recording
+	c.Assert(audit.events, HasLen, 2)
+	c.Assert(audit.events[0].SiteKey, DeepEquals, key)
+	c.Assert(audit.events[0].Type, Equals, events.SessionStartEvent)
+	c.Assert(audit.events[0].Fields.GetString(events.SessionEventID), Equals, string(recorder.ID()))
+	c.Assert(audit.events[0].Fields.GetString(events.SessionServerID), Equals, "node-1")
+	c.Assert(audit.events[0].Fields.GetString(events.EventUser), Equals, "alice@example.com")
+	c.Assert(audit.events[0].Fields.GetString(events.TerminalSize), Equals, "80:25")
+	c.Assert(audit.events[1].Type, Equals, events.SessionEndEvent)
+
+	c.Assert(audit.recordingID, Equals, string(recorder.ID()))
+	c.Assert(audit.recording, Not(HasLen), 0)
+	for _, name := range audit.recording {
+		c.Assert(strings.HasPrefix(name, string(recorder.ID())), Equals, true,
+			Commentf("unexpected file %v in the recording", name))
+	}
+}
+
+func (s *SessionSuite) TestValidatesConfig(c *C) {
+	_, err := NewSessionRecorder(context.TODO(), SessionConfig{
+		Operator: &testAudit{},
+		DataDir:  c.MkDir(),
+		ServerID: "node-1",
+	})
+	c.Assert(err, NotNil)
+}
+
+// testAudit collects the session events and the names of the files
+// in the uploaded session recording
+type testAudit struct {
+	events      []ops.AuditEventRequest
+	recordingID string
+	recording   []string
+}
+
+func (a *testAudit) EmitAuditEvent(ctx context.Context, req ops.AuditEventRequest) error {
+	a.events = append(a.events, req)
+	return nil
+}
+
+func (a *testAudit) GetAuditEvents(ctx context.Context, req ops.GetAuditEventsRequest) ([]storage.AuditEvent, error) {
+	return nil, nil
+}
+
+func (a *testAudit) UploadSessionRecording(ctx context.Context, req ops.UploadSessionRecordingRequest, reader io.Reader) error {
+	a.recordingID = req.SessionID
+	tarball := tar.NewReader(reader)
+	for {
+		header, err := tarball.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		a.recording = append(a.recording, header.Name)
+	}
+}
@@ -18,6 +18,10 @@ package aws
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -170,6 +174,108 @@ func (s *AutoscalerSuite) TestInstanceLaunching(c *check.C) {
 	}
 }
 
+func (s *AutoscalerSuite) TestSyncMembershipRemovesTerminatedNodes(c *check.C) {
+	ec := newMockEC2(&ec2.Instance{
+		InstanceId: aws.String("instance-1"),
+		State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+	}, &ec2.Instance{
+		InstanceId: aws.String("instance-2"),
+		State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameTerminated)},
+	})
+	a := newAutoscaler(c, ec)
+	op := newMockOperator(ops.Site{
+		AccountID: "1",
+		Domain:    "example.com",
+		State:     ops.SiteStateActive,
+		ClusterState: storage.ClusterState{
+			Servers: []storage.Server{
+				{InstanceID: "instance-1", Hostname: "node-1"},
+				{InstanceID: "instance-2", Hostname: "node-2"},
+				{InstanceID: "instance-3", Hostname: "node-3"},
+			},
+		},
+	})
+
+	// terminated nodes are removed one at a time
+	c.Assert(a.syncMembership(context.TODO(), op), check.IsNil)
+	c.Assert(op.shrinksC, check.HasLen, 1)
+	c.Assert((<-op.shrinksC).Servers, check.DeepEquals, []string{"node-2"})
+
+	servers := op.site.ClusterState.Servers
+	op.site.ClusterState.Servers = []storage.Server{servers[0], servers[2]}
+	c.Assert(a.syncMembership(context.TODO(), op), check.IsNil)
+	c.Assert((<-op.shrinksC).Servers, check.DeepEquals, []string{"node-3"})
+
+	// nothing is removed while another operation is in progress
+	op.site.State = ops.SiteStateShrinking
+	c.Assert(a.syncMembership(context.TODO(), op), check.IsNil)
+	c.Assert(op.shrinksC, check.HasLen, 0)
+
+	// nothing is removed if none of the instances can be found
+	op.site.State = ops.SiteStateActive
+	ec.instances = nil
+	err := a.syncMembership(context.TODO(), op)
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+	c.Assert(op.shrinksC, check.HasLen, 0)
+}
+
+func (s *AutoscalerSuite) TestWebhook(c *check.C) {
+	ec := newMockEC2(&ec2.Instance{
+		InstanceId: aws.String("instance-1"),
+	})
+	a := newAutoscaler(c, ec)
+	op := newMockOperator(ops.Site{
+		AccountID: "1",
+		Domain:    "example.com",
+	})
+	server := httptest.NewServer(a.WebhookHandler(context.TODO(), op))
+	defer server.Close()
+
+	post := func(token, body string) int {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"?access_token="+token,
+			strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, check.IsNil)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	event := mustMarshalHook(HookEvent{
+		InstanceID: "instance-1",
+		Type:       InstanceLaunching,
+	})
+	c.Assert(post("wrong", event), check.Equals, http.StatusForbidden)
+
+	// lifecycle hook events are accepted as is or wrapped into SNS notifications
+	notification, err := json.Marshal(snsMessage{Type: snsNotification, Message: event})
+	c.Assert(err, check.IsNil)
+	for _, body := range []string{event, string(notification)} {
+		c.Assert(post("token", body), check.Equals, http.StatusAccepted)
+		select {
+		case input := <-ec.modifyC:
+			c.Assert(aws.StringValue(input.InstanceId), check.Equals, "instance-1")
+		case <-time.After(time.Second):
+			c.Fatalf("timeout")
+		}
+	}
+
+	unsupported := mustMarshalHook(HookEvent{InstanceID: "instance-1", Type: "autoscaling:TEST_NOTIFICATION"})
+	c.Assert(post("token", unsupported), check.Equals, http.StatusBadRequest)
+}
+
+func newAutoscaler(c *check.C, ec *mockEC2) *Autoscaler {
+	a, err := New(Config{
+		ClusterName: "bob",
+		NewLocalInstance: func() (*gaws.Instance, error) {
+			return &gaws.Instance{ID: "instance-1"}, nil
+		},
+		Queue: newMockQueue("queue-1"),
+		Cloud: ec,
+	})
+	c.Assert(err, check.IsNil)
+	return a
+}
+
 func newMockQueue(url string) *mockQueue {
 	return &mockQueue{
 		url:       url,
@@ -179,14 +285,14 @@ func newMockQueue(url string) *mockQueue {
 }
 
 type mockEC2 struct {
-	modifyC  chan *ec2.ModifyInstanceAttributeInput
-	instance *ec2.Instance
+	modifyC   chan *ec2.ModifyInstanceAttributeInput
+	instances []*ec2.Instance
 }
 
-func newMockEC2(instance *ec2.Instance) *mockEC2 {
+func newMockEC2(instances ...*ec2.Instance) *mockEC2 {
 	return &mockEC2{
-		modifyC:  make(chan *ec2.ModifyInstanceAttributeInput, 10),
-		instance: instance,
+		modifyC:   make(chan *ec2.ModifyInstanceAttributeInput, 10),
+		instances: instances,
 	}
 }
 
@@ -205,7 +311,7 @@ func (m *mockEC2) DescribeInstancesWithContext(ctx aws.Context, input *ec2.Descr
 	return &ec2.DescribeInstancesOutput{
 		Reservations: []*ec2.Reservation{
 			{
-				Instances: []*ec2.Instance{m.instance},
+				Instances: m.instances,
 			},
 		},
 	}, nil
@@ -276,6 +382,10 @@ func (o *mockOperator) GetLocalSite() (*ops.Site, error) {
 	return &o.site, nil
 }
 
+func (o *mockOperator) GetExpandToken(ops.SiteKey) (*storage.ProvisioningToken, error) {
+	return &storage.ProvisioningToken{Token: "token"}, nil
+}
+
 func (o *mockOperator) CreateSiteShrinkOperation(ctx context.Context, req ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error) {
 	select {
 	case o.shrinksC <- &req:
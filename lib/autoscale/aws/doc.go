@@ -58,6 +58,11 @@ queue associated with the cluster.
 * Autoscaler receives the notification on the scale down and removes the node
 from the cluster in forced mode (as the instance is offline by the time
 notification is received)
+* Alternatively, the lifecycle hook events can be posted to the
+/autoscale/events endpoint of the cluster, either directly or via SNS topic
+subscription, authenticated with the published join token
+* Autoscaler periodically removes the nodes whose instances have been
+terminated without notification, for example when the event has been lost
 
 */
 package aws
@@ -43,6 +43,11 @@ type HookEvent struct {
 	Type string `json:"LifecycleTransition"`
 }
 
+// IsSupported returns true if the autoscaler handles events of this type
+func (e HookEvent) IsSupported() bool {
+	return e.Type == InstanceLaunching || e.Type == InstanceTerminating
+}
+
 // GetQueueURL returns queue URL associated with this cluster
 func (a *Autoscaler) GetQueueURL(ctx context.Context) (string, error) {
 	expr, err := regexp.Compile("[^a-zA-Z0-9\\-]")
@@ -98,29 +103,38 @@ func (a *Autoscaler) ProcessEvents(ctx context.Context, queueURL string, operato
 
 func (a *Autoscaler) processEvent(ctx context.Context, operator Operator, event HookEvent) error {
 	a.WithField("event", event).Info("Received autoscale event.")
+	if !event.IsSupported() {
+		log.Debugf("Discarding unsupported event %#v.", event)
+		if err := a.DeleteEvent(ctx, event); err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.BadParameter("unsupported event: %v", event.Type)
+	}
+	if err := a.handleEvent(ctx, operator, event); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := a.DeleteEvent(ctx, event); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// handleEvent prepares the launched instance to join the cluster
+// or removes the terminated instance from the cluster
+func (a *Autoscaler) handleEvent(ctx context.Context, operator Operator, event HookEvent) error {
 	switch event.Type {
 	case InstanceLaunching:
 		if err := a.TurnOffSourceDestinationCheck(ctx, event.InstanceID); err != nil {
 			return trace.Wrap(err)
 		}
-		if err := a.DeleteEvent(ctx, event); err != nil {
-			return trace.Wrap(err)
-		}
 	case InstanceTerminating:
 		if err := a.ensureInstanceTerminated(ctx, event); err != nil {
 			return trace.Wrap(err)
 		}
-		if err := a.removeInstance(ctx, operator, event); err != nil && !trace.IsNotFound(err) {
-			return trace.Wrap(err)
-		}
-		if err := a.DeleteEvent(ctx, event); err != nil {
+		if err := a.removeInstance(ctx, operator, event.InstanceID); err != nil && !trace.IsNotFound(err) {
 			return trace.Wrap(err)
 		}
 	default:
-		log.Debugf("Discarding unsupported event %#v.", event)
-		if err := a.DeleteEvent(ctx, event); err != nil {
-			return trace.Wrap(err)
-		}
 		return trace.BadParameter("unsupported event: %v", event.Type)
 	}
 	return nil
@@ -148,13 +162,15 @@ func (a *Autoscaler) ensureInstanceTerminated(ctx context.Context, event HookEve
 	return nil
 }
 
-func (a *Autoscaler) removeInstance(ctx context.Context, operator Operator, event HookEvent) error {
+// removeInstance starts the operation to remove the node
+// of the instance with the specified ID from the cluster
+func (a *Autoscaler) removeInstance(ctx context.Context, operator Operator, instanceID string) error {
 	cluster, err := operator.GetLocalSite()
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	server, err := ops.FindServerByInstanceID(cluster, event.InstanceID)
+	server, err := ops.FindServerByInstanceID(cluster, instanceID)
 	if err != nil {
 		return trace.Wrap(err)
 	}
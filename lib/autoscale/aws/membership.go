@@ -0,0 +1,118 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gravitational/trace"
+)
+
+// SyncMembership periodically removes the nodes whose instances have been
+// terminated from the cluster.
+//
+// It catches up with the scale down events that have been missed, for example
+// when the lifecycle hook has timed out or the queue message has expired
+func (a *Autoscaler) SyncMembership(ctx context.Context, operator Operator) {
+	a.Info("Start syncing cluster membership.")
+	ticker := time.NewTicker(defaults.AutoscaleMembershipSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			a.Info("Stop syncing cluster membership.")
+			return
+		case <-ticker.C:
+			if err := a.syncMembership(ctx, operator); err != nil {
+				a.Errorf("Failed to sync cluster membership: %v.", trace.DebugReport(err))
+			}
+		}
+	}
+}
+
+// syncMembership removes the node of the first terminated instance
+// from the cluster. Only one node is removed at a time since the cluster
+// can run a single operation, the rest are removed on the next iterations
+func (a *Autoscaler) syncMembership(ctx context.Context, operator Operator) error {
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if cluster.State != ops.SiteStateActive {
+		a.WithField("state", cluster.State).Debug("Cluster is not active, skip membership sync.")
+		return nil
+	}
+	instanceIDs := make([]string, 0, len(cluster.ClusterState.Servers))
+	for _, server := range cluster.ClusterState.Servers {
+		if server.InstanceID != "" {
+			instanceIDs = append(instanceIDs, server.InstanceID)
+		}
+	}
+	if len(instanceIDs) == 0 {
+		return nil
+	}
+	terminated, err := a.terminatedInstances(ctx, instanceIDs)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(terminated) == 0 {
+		return nil
+	}
+	a.WithField("instance", terminated[0]).Info("Remove node of terminated instance.")
+	return trace.Wrap(a.removeInstance(ctx, operator, terminated[0]))
+}
+
+// terminatedInstances returns the IDs of the specified instances that
+// have been terminated or no longer exist
+func (a *Autoscaler) terminatedInstances(ctx context.Context, instanceIDs []string) (terminated []string, err error) {
+	// filtering by ID instead of listing the IDs does not fail
+	// if some of the instances no longer exist
+	resp, err := a.Cloud.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{{
+			Name:   aws.String(instanceIDFilter),
+			Values: aws.StringSlice(instanceIDs),
+		}},
+	})
+	if err != nil {
+		return nil, utils.ConvertEC2Error(err)
+	}
+	states := make(map[string]string)
+	for _, reservation := range resp.Reservations {
+		for _, instance := range reservation.Instances {
+			states[aws.StringValue(instance.InstanceId)] = instanceState(*instance)
+		}
+	}
+	// the cluster always has running instances, this one included, so if none
+	// has been found the instances are looked up in the wrong region or account
+	if len(states) == 0 {
+		return nil, trace.NotFound("none of the cluster instances %v found", instanceIDs)
+	}
+	for _, instanceID := range instanceIDs {
+		state, ok := states[instanceID]
+		if !ok || state == ec2.InstanceStateNameTerminated {
+			terminated = append(terminated, instanceID)
+		}
+	}
+	return terminated, nil
+}
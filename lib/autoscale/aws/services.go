@@ -21,6 +21,7 @@ import (
 
 	gaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -53,6 +54,7 @@ type EC2 interface {
 type Operator interface {
 	GetLocalSite() (*ops.Site, error)
 	CreateSiteShrinkOperation(context.Context, ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error)
+	GetExpandToken(ops.SiteKey) (*storage.ProvisioningToken, error)
 }
 
 type NewLocalInstance func() (*gaws.Instance, error)
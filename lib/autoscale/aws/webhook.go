@@ -0,0 +1,163 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/gravitational/roundtrip"
+	"github.com/gravitational/trace"
+)
+
+// WebhookHandler returns the handler that receives the auto scaling group
+// lifecycle hook events over HTTP, as an alternative to the SQS queue.
+//
+// The events are accepted either as the lifecycle hook JSON or wrapped
+// into SNS notifications, so the handler can be subscribed to the SNS topic
+// of the lifecycle hook directly. The requests are authenticated with
+// the cluster join token published to the parameter store, passed either
+// as a bearer token or in the access_token query parameter.
+//
+// The events are handled in the background with the provided context
+// since removing a node waits for its instance to terminate
+func (a *Autoscaler) WebhookHandler(ctx context.Context, operator Operator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.authenticateWebhook(operator, r); err != nil {
+			trace.WriteError(w, err)
+			return
+		}
+		event, err := a.readWebhookEvent(r.Context(), r.Body)
+		if err != nil {
+			trace.WriteError(w, err)
+			return
+		}
+		if event == nil {
+			roundtrip.ReplyJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+			return
+		}
+		a.WithField("event", event).Info("Received autoscale webhook.")
+		if !event.IsSupported() {
+			trace.WriteError(w, trace.BadParameter("unsupported event: %v", event.Type))
+			return
+		}
+		go func() {
+			if err := a.handleEvent(ctx, operator, *event); err != nil {
+				a.Errorf("Failed to process webhook: %v.", trace.DebugReport(err))
+			}
+		}()
+		roundtrip.ReplyJSON(w, http.StatusAccepted, map[string]string{"status": "accepted"})
+	})
+}
+
+// authenticateWebhook verifies that the request carries the cluster join token
+func (a *Autoscaler) authenticateWebhook(operator Operator, r *http.Request) error {
+	creds, err := roundtrip.ParseAuthHeaders(r)
+	if err != nil || !creds.IsToken() {
+		return trace.AccessDenied("missing join token")
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	token, err := operator.GetExpandToken(cluster.Key())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if subtle.ConstantTimeCompare([]byte(token.Token), []byte(creds.Password)) != 1 {
+		return trace.AccessDenied("invalid join token")
+	}
+	return nil
+}
+
+// readWebhookEvent reads the lifecycle hook event from the request body.
+// It confirms SNS subscriptions and returns nil event for them
+func (a *Autoscaler) readWebhookEvent(ctx context.Context, body io.Reader) (*HookEvent, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxWebhookSize))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	var message snsMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, trace.BadParameter("invalid webhook payload: %v", err)
+	}
+	switch message.Type {
+	case snsSubscriptionConfirmation:
+		return nil, trace.Wrap(a.confirmSubscription(ctx, message))
+	case snsNotification:
+		data = []byte(message.Message)
+	}
+	event, err := unmarshalHook(string(data))
+	if err != nil {
+		return nil, trace.BadParameter("invalid lifecycle hook event: %v", err)
+	}
+	return event, nil
+}
+
+// confirmSubscription confirms the subscription of the webhook to the SNS topic
+func (a *Autoscaler) confirmSubscription(ctx context.Context, message snsMessage) error {
+	u, err := url.Parse(message.SubscribeURL)
+	if err != nil {
+		return trace.BadParameter("invalid subscription URL %q: %v", message.SubscribeURL, err)
+	}
+	if u.Scheme != "https" || !strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+		return trace.BadParameter("subscription URL %q does not point to SNS", message.SubscribeURL)
+	}
+	a.WithField("topic", message.TopicArn).Info("Confirm SNS subscription.")
+	ctx, cancel := context.WithTimeout(ctx, defaults.DialTimeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return trace.BadParameter("failed to confirm SNS subscription: %v", resp.Status)
+	}
+	return nil
+}
+
+// snsMessage is the SNS message delivered to HTTP subscribers
+type snsMessage struct {
+	// Type is the message type
+	Type string `json:"Type"`
+	// TopicArn is the topic the message has been published to
+	TopicArn string `json:"TopicArn"`
+	// Message is the notification payload
+	Message string `json:"Message"`
+	// SubscribeURL confirms the subscription
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+const (
+	snsSubscriptionConfirmation = "SubscriptionConfirmation"
+	snsNotification             = "Notification"
+	// maxWebhookSize limits the size of the webhook payload
+	maxWebhookSize = 64 * 1024
+)
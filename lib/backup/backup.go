@@ -0,0 +1,163 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backup implements the format of the cluster backup archive.
+//
+// The archive is a compressed tarball with the following layout:
+//
+//	backup.json     - backup metadata, see Metadata
+//	etcd.snapshot   - etcd snapshot of the cluster backend
+//	app/            - results of the application backup hook, if any
+//
+// Archives created by older versions only contain the results of the
+// application backup hook at the top level and do not have metadata
+package backup
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+
+	"github.com/gravitational/trace"
+)
+
+const (
+	// MetadataFile is the name of the file with the backup metadata
+	MetadataFile = "backup.json"
+	// SnapshotFile is the name of the cluster backend snapshot file
+	SnapshotFile = "etcd.snapshot"
+	// AppDir is the name of the directory with the application backup
+	AppDir = "app"
+)
+
+// Metadata describes the contents of a backup archive
+type Metadata struct {
+	// Version is the version of gravity that created the backup
+	Version string `json:"version"`
+	// Application is the cluster application the backup was taken of
+	Application loc.Locator `json:"application"`
+	// Created is the backup creation time
+	Created time.Time `json:"created"`
+	// Packages lists the packages in the cluster package store at the
+	// time of the backup. The package blobs are not part of the backup
+	// and must be present on the cluster the backup is restored on
+	Packages []Package `json:"packages"`
+	// HasAppBackup is true if the archive contains the results of
+	// the application backup hook
+	HasAppBackup bool `json:"has_app_backup"`
+}
+
+// Package describes a package in the cluster package store
+type Package struct {
+	// Locator references the package
+	Locator loc.Locator `json:"locator"`
+	// SHA512 is the checksum of the package contents
+	SHA512 string `json:"sha512"`
+	// SizeBytes is the size of the package in bytes
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+// CheckCompatible makes sure that the backup can be restored on the cluster
+// running the specified application with the specified version of gravity
+func (m Metadata) CheckCompatible(application loc.Locator, version string) error {
+	if !m.Application.IsEqualTo(application) {
+		return trace.BadParameter("backup of %v cannot be restored on cluster running %v",
+			m.Application, application)
+	}
+	if m.Version != version {
+		return trace.BadParameter("backup created with gravity %v cannot be restored with gravity %v",
+			m.Version, version)
+	}
+	return nil
+}
+
+// GetPackages returns the list of all packages in the specified package service
+func GetPackages(packages pack.PackageService) (result []Package, err error) {
+	err = pack.ForeachPackage(packages, func(env pack.PackageEnvelope) error {
+		result = append(result, Package{
+			Locator:   env.Locator,
+			SHA512:    env.SHA512,
+			SizeBytes: env.SizeBytes,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return result, nil
+}
+
+// CheckPackages makes sure all the specified packages are present
+// in the package service with the same contents
+func CheckPackages(packages pack.PackageService, expected []Package) error {
+	var missing []string
+	for _, p := range expected {
+		env, err := packages.ReadPackageEnvelope(p.Locator)
+		if err != nil {
+			if !trace.IsNotFound(err) {
+				return trace.Wrap(err)
+			}
+			missing = append(missing, p.Locator.String())
+			continue
+		}
+		if env.SHA512 != p.SHA512 {
+			return trace.BadParameter("package %v has checksum %v, expected %v",
+				p.Locator, env.SHA512, p.SHA512)
+		}
+	}
+	if len(missing) != 0 {
+		return trace.NotFound("packages %v are missing from the cluster package store",
+			strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// WriteMetadata writes the backup metadata into the backup directory dir
+func WriteMetadata(dir string, metadata Metadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, MetadataFile), data, defaults.SharedReadMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+// ReadMetadata reads the backup metadata from the backup directory dir.
+// Returns NotFound if the directory contains a backup without metadata
+func ReadMetadata(dir string) (*Metadata, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, MetadataFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, trace.NotFound("backup in %v does not have metadata", dir)
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+	var metadata Metadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, trace.Wrap(err, "invalid backup metadata")
+	}
+	return &metadata, nil
+}
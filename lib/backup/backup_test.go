@@ -0,0 +1,98 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	apptest "github.com/gravitational/gravity/lib/app/service/test"
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+func TestBackup(t *testing.T) { TestingT(t) }
+
+type BackupSuite struct{}
+
+var _ = Suite(&BackupSuite{})
+
+func (s *BackupSuite) TestMetadata(c *C) {
+	dir := c.MkDir()
+	_, err := ReadMetadata(dir)
+	c.Assert(trace.IsNotFound(err), Equals, true)
+
+	metadata := Metadata{
+		Version:     "5.5.0",
+		Application: loc.MustParseLocator("example.com/app:1.0.0"),
+		Created:     time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		Packages: []Package{{
+			Locator:   loc.MustParseLocator("example.com/package:1.0.0"),
+			SHA512:    "sha",
+			SizeBytes: 1,
+		}},
+		HasAppBackup: true,
+	}
+	c.Assert(WriteMetadata(dir, metadata), IsNil)
+	read, err := ReadMetadata(dir)
+	c.Assert(err, IsNil)
+	c.Assert(*read, DeepEquals, metadata)
+
+	c.Assert(metadata.CheckCompatible(metadata.Application, "5.5.0"), IsNil)
+	c.Assert(metadata.CheckCompatible(loc.MustParseLocator("example.com/app:2.0.0"), "5.5.0"), NotNil)
+	c.Assert(metadata.CheckCompatible(metadata.Application, "5.5.1"), NotNil)
+}
+
+func (s *BackupSuite) TestCheckPackages(c *C) {
+	src, dst := newPackages(c), newPackages(c)
+	locator := loc.MustParseLocator("example.com/package:1.0.0")
+	apptest.CreateDummyPackage(locator, src, c)
+
+	packages, err := GetPackages(src)
+	c.Assert(err, IsNil)
+	c.Assert(packages, HasLen, 1)
+	c.Assert(packages[0].Locator, Equals, locator)
+
+	c.Assert(trace.IsNotFound(CheckPackages(dst, packages)), Equals, true)
+	apptest.CreateDummyPackage(locator, dst, c)
+	c.Assert(CheckPackages(dst, packages), IsNil)
+}
+
+func newPackages(c *C) pack.PackageService {
+	dir := c.MkDir()
+	backend, err := keyval.NewBolt(keyval.BoltConfig{
+		Path: filepath.Join(dir, "bolt.db"),
+	})
+	c.Assert(err, IsNil)
+	objects, err := fs.New(dir)
+	c.Assert(err, IsNil)
+	packages, err := localpack.New(localpack.Config{
+		Backend:     backend,
+		UnpackedDir: filepath.Join(dir, defaults.UnpackedDir),
+		Objects:     objects,
+	})
+	c.Assert(err, IsNil)
+	return packages
+}
@@ -17,8 +17,12 @@ limitations under the License.
 package blob
 
 import (
+	"crypto/sha512"
+	"fmt"
 	"io"
 	"time"
+
+	"github.com/gravitational/trace"
 )
 
 // Envelope specifies the metadata about BLOB - it's SHA512 hash and size
@@ -54,3 +58,39 @@ type Objects interface {
 	// GetBLOBEnvelope returns BLOB envelope
 	GetBLOBEnvelope(hash string) (*Envelope, error)
 }
+
+// Repairer is implemented by BLOB storages that can restore
+// a corrupted local copy of a BLOB from other replicas
+type Repairer interface {
+	// RepairBLOB replaces the local copy of the BLOB identified by hash
+	// with a copy fetched from another replica
+	RepairBLOB(hash string) error
+}
+
+// Checksum reads the data from r and returns its half SHA512 hash
+// in the format used to identify BLOBs
+func Checksum(r io.Reader) (string, error) {
+	hasher := sha512.New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", trace.Wrap(err)
+	}
+	return fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2]), nil
+}
+
+// Verify recomputes the checksum of the BLOB identified by hash and
+// returns trace.CompareFailed if it does not match the hash
+func Verify(objects Objects, hash string) error {
+	f, err := objects.OpenBLOB(hash)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer f.Close()
+	checksum, err := Checksum(f)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if checksum != hash {
+		return trace.CompareFailed("checksum mismatch for BLOB %v: got %v", hash, checksum)
+	}
+	return nil
+}
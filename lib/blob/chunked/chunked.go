@@ -0,0 +1,576 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chunked implements a content-addressed BLOB storage that
+// splits BLOBs into variable-sized chunks and stores each distinct chunk
+// only once.
+//
+// Chunk boundaries are determined by the content (using a rolling hash),
+// so identical data shared between BLOBs (e.g. the same container image
+// layers vendored into different application versions) is deduplicated
+// even if it is found at different offsets.
+//
+// The storage directory has the following layout:
+//
+//	chunks/<sha256[0:3]>/<sha256>   chunk data addressed by its SHA256 hash
+//	index/<hash[0:3]>/<hash>        list of chunks comprising the BLOB with the given hash
+//	blobs/<hash[0:3]>/<hash>        legacy whole BLOBs written by the fs storage
+//	tmp/                            temporary files
+//	format                          marks the directory as using the chunked storage
+//
+// BLOBs are identified by the same half SHA512 hash as with the fs
+// storage, so existing references to BLOBs remain valid. Legacy BLOBs
+// are read transparently and can be converted with Migrate.
+package chunked
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// Config defines the chunked BLOB storage configuration
+type Config struct {
+	// Path is the storage directory
+	Path string
+	// MinChunkSize is the minimum chunk size in bytes
+	MinChunkSize int
+	// AvgChunkSize is the average chunk size in bytes.
+	// Must be a power of two
+	AvgChunkSize int
+	// MaxChunkSize is the maximum chunk size in bytes
+	MaxChunkSize int
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Path == "" {
+		return trace.BadParameter("missing Path parameter")
+	}
+	if c.MinChunkSize == 0 {
+		c.MinChunkSize = MinChunkSize
+	}
+	if c.AvgChunkSize == 0 {
+		c.AvgChunkSize = AvgChunkSize
+	}
+	if c.MaxChunkSize == 0 {
+		c.MaxChunkSize = MaxChunkSize
+	}
+	if c.AvgChunkSize&(c.AvgChunkSize-1) != 0 {
+		return trace.BadParameter("average chunk size should be a power of two, got %v",
+			c.AvgChunkSize)
+	}
+	if c.MinChunkSize > c.AvgChunkSize || c.AvgChunkSize > c.MaxChunkSize {
+		return trace.BadParameter("expected min (%v) <= average (%v) <= max (%v) chunk size",
+			c.MinChunkSize, c.AvgChunkSize, c.MaxChunkSize)
+	}
+	return nil
+}
+
+// New returns a new BLOB storage in the specified directory.
+//
+// Existing directories with BLOBs written by the fs storage keep using it
+// until they are migrated with Migrate, so the BLOBs remain accessible
+// to older versions. Other directories use the chunked storage
+func New(path string) (blob.Objects, error) {
+	legacy, err := isLegacy(path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if legacy {
+		return fs.New(path)
+	}
+	return NewWithConfig(Config{Path: path})
+}
+
+// NewWithConfig returns a new chunked BLOB storage with the specified configuration
+func NewWithConfig(config Config) (*Objects, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	o := &Objects{
+		Config: config,
+		refs:   make(map[string]int),
+	}
+	for _, d := range []string{o.tempDir(), o.chunkDir(), o.indexDir()} {
+		if err := os.MkdirAll(d, defaults.SharedDirMask); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	if err := o.loadRefs(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err := ioutil.WriteFile(o.formatPath(), []byte(formatChunked), defaults.SharedReadMask)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return o, nil
+}
+
+// isLegacy returns true if the specified directory contains
+// BLOBs written by the fs storage and has not been migrated
+func isLegacy(path string) (bool, error) {
+	_, err := os.Stat(filepath.Join(path, formatFile))
+	if err == nil {
+		return false, nil
+	}
+	if !os.IsNotExist(err) {
+		return false, trace.ConvertSystemError(err)
+	}
+	hashes, err := listFiles(filepath.Join(path, legacyDir))
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	return len(hashes) != 0, nil
+}
+
+// Objects is the chunked BLOB storage
+type Objects struct {
+	// Config is the storage configuration
+	Config
+	// Mutex guards the chunk reference counts and
+	// serializes changes to BLOB indexes
+	sync.Mutex
+	// refs maps the chunk hash to the number of references to the chunk
+	// from the BLOB indexes and the BLOBs being written
+	refs map[string]int
+}
+
+// index describes the BLOB as a list of chunks
+type index struct {
+	// SizeBytes is the BLOB size in bytes
+	SizeBytes int64 `json:"size_bytes"`
+	// Chunks lists the BLOB chunks in order
+	Chunks []chunkRef `json:"chunks"`
+}
+
+// chunkRef references a single chunk of a BLOB
+type chunkRef struct {
+	// SHA256 is the SHA256 hash of the chunk data
+	SHA256 string `json:"sha256"`
+	// SizeBytes is the chunk size in bytes
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+// Close closes the storage
+func (o *Objects) Close() error {
+	return nil
+}
+
+// GetBLOBs returns a list of BLOBs in the storage
+func (o *Objects) GetBLOBs() ([]string, error) {
+	hashes, err := listFiles(o.indexDir())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	legacy, err := listFiles(o.legacyDir())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	seen := make(map[string]struct{}, len(hashes))
+	for _, hash := range hashes {
+		seen[hash] = struct{}{}
+	}
+	for _, hash := range legacy {
+		if _, ok := seen[hash]; !ok {
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+// WriteBLOB splits the data into chunks, writes the chunks not yet
+// in the storage and returns the BLOB envelope
+func (o *Objects) WriteBLOB(data io.Reader) (*blob.Envelope, error) {
+	hasher := sha512.New()
+	chunker := newChunker(io.TeeReader(data, hasher),
+		o.MinChunkSize, o.AvgChunkSize, o.MaxChunkSize)
+	var idx index
+	// acquired lists the chunk references to drop if the BLOB is not stored
+	var acquired []chunkRef
+	defer func() {
+		if len(acquired) != 0 {
+			o.release(acquired)
+		}
+	}()
+	for {
+		chunk, err := chunker.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		ref, err := o.writeChunk(chunk)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		idx.Chunks = append(idx.Chunks, *ref)
+		idx.SizeBytes += ref.SizeBytes
+		acquired = idx.Chunks
+	}
+	hash := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+
+	o.Lock()
+	defer o.Unlock()
+	if _, err := os.Stat(o.indexPath(hash)); err == nil {
+		// The BLOB is already in the storage and its index
+		// holds the references to the chunks
+		o.releaseLocked(acquired)
+		acquired = nil
+		return o.getEnvelope(hash)
+	}
+	if err := o.writeIndex(hash, idx); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// The references are now held by the index
+	acquired = nil
+	// The BLOB supersedes the legacy copy, if any
+	if err := os.Remove(o.legacyPath(hash)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Failed to remove legacy BLOB %v: %v.", hash, err)
+	}
+	return o.getEnvelope(hash)
+}
+
+// GetBLOBEnvelope returns BLOB information identified by hash
+func (o *Objects) GetBLOBEnvelope(hash string) (*blob.Envelope, error) {
+	return o.getEnvelope(hash)
+}
+
+// OpenBLOB opens the BLOB identified by hash and returns
+// a reader that reassembles it from chunks
+func (o *Objects) OpenBLOB(hash string) (blob.ReadSeekCloser, error) {
+	idx, err := o.readIndex(hash)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if err != nil {
+		f, err := os.Open(o.legacyPath(hash))
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+		return f, nil
+	}
+	return newReader(o.chunkPath, *idx), nil
+}
+
+// DeleteBLOB deletes the BLOB from the storage along with
+// the chunks no longer referenced by other BLOBs
+func (o *Objects) DeleteBLOB(hash string) error {
+	o.Lock()
+	defer o.Unlock()
+	idx, err := o.readIndex(hash)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if err != nil {
+		err := os.Remove(o.legacyPath(hash))
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		return nil
+	}
+	if err := os.Remove(o.indexPath(hash)); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	o.releaseLocked(idx.Chunks)
+	return nil
+}
+
+// Usage returns the total size of all BLOBs in the storage and
+// the size of the chunks actually stored on disk
+func (o *Objects) Usage() (logicalBytes, storedBytes int64, err error) {
+	hashes, err := o.GetBLOBs()
+	if err != nil {
+		return 0, 0, trace.Wrap(err)
+	}
+	for _, hash := range hashes {
+		envelope, err := o.getEnvelope(hash)
+		if err != nil {
+			return 0, 0, trace.Wrap(err)
+		}
+		logicalBytes += envelope.SizeBytes
+	}
+	err = walkFiles(o.chunkDir(), func(_ string, info os.FileInfo) {
+		storedBytes += info.Size()
+	})
+	if err != nil {
+		return 0, 0, trace.Wrap(err)
+	}
+	err = walkFiles(o.legacyDir(), func(_ string, info os.FileInfo) {
+		storedBytes += info.Size()
+	})
+	if err != nil {
+		return 0, 0, trace.Wrap(err)
+	}
+	return logicalBytes, storedBytes, nil
+}
+
+// Prune removes the chunks not referenced by any BLOB,
+// for example, left behind by interrupted writes.
+// Returns the number of removed chunks
+func (o *Objects) Prune() (removed int, err error) {
+	o.Lock()
+	defer o.Unlock()
+	var orphans []string
+	err = walkFiles(o.chunkDir(), func(path string, info os.FileInfo) {
+		if o.refs[info.Name()] == 0 {
+			orphans = append(orphans, path)
+		}
+	})
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	for _, path := range orphans {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return removed, trace.ConvertSystemError(err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// writeChunk writes the chunk unless it is already in the storage
+// and adds a reference to it
+func (o *Objects) writeChunk(data []byte) (*chunkRef, error) {
+	hash := fmt.Sprintf("%x", sha256.Sum256(data))
+	o.Lock()
+	defer o.Unlock()
+	targetPath := o.chunkPath(hash)
+	if _, err := os.Stat(targetPath); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, trace.ConvertSystemError(err)
+		}
+		if err := o.writeFile(targetPath, data); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	o.refs[hash]++
+	return &chunkRef{SHA256: hash, SizeBytes: int64(len(data))}, nil
+}
+
+// release drops the references to the specified chunks
+func (o *Objects) release(chunks []chunkRef) {
+	o.Lock()
+	defer o.Unlock()
+	o.releaseLocked(chunks)
+}
+
+// releaseLocked drops the references to the specified chunks and
+// removes the chunks no longer referenced.
+// Must be called with the lock held
+func (o *Objects) releaseLocked(chunks []chunkRef) {
+	for _, chunk := range chunks {
+		o.refs[chunk.SHA256]--
+		if o.refs[chunk.SHA256] > 0 {
+			continue
+		}
+		delete(o.refs, chunk.SHA256)
+		err := os.Remove(o.chunkPath(chunk.SHA256))
+		if err != nil && !os.IsNotExist(err) {
+			log.Warnf("Failed to remove chunk %v: %v.", chunk.SHA256, err)
+		}
+	}
+}
+
+// loadRefs computes the chunk reference counts from the BLOB indexes
+func (o *Objects) loadRefs() error {
+	hashes, err := listFiles(o.indexDir())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, hash := range hashes {
+		idx, err := o.readIndex(hash)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, chunk := range idx.Chunks {
+			o.refs[chunk.SHA256]++
+		}
+	}
+	return nil
+}
+
+func (o *Objects) getEnvelope(hash string) (*blob.Envelope, error) {
+	idx, err := o.readIndex(hash)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if err != nil {
+		fileInfo, err := os.Stat(o.legacyPath(hash))
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+		return &blob.Envelope{
+			SizeBytes: fileInfo.Size(),
+			SHA512:    hash,
+			Modified:  fileInfo.ModTime().UTC(),
+		}, nil
+	}
+	fileInfo, err := os.Stat(o.indexPath(hash))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return &blob.Envelope{
+		SizeBytes: idx.SizeBytes,
+		SHA512:    hash,
+		Modified:  fileInfo.ModTime().UTC(),
+	}, nil
+}
+
+func (o *Objects) readIndex(hash string) (*index, error) {
+	data, err := ioutil.ReadFile(o.indexPath(hash))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	var idx index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, trace.Wrap(err, "failed to read index of BLOB %v", hash)
+	}
+	return &idx, nil
+}
+
+func (o *Objects) writeIndex(hash string, idx index) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(o.writeFile(o.indexPath(hash), data))
+}
+
+// writeFile atomically writes the data to the file at the specified path
+func (o *Objects) writeFile(targetPath string, data []byte) error {
+	f, err := ioutil.TempFile(o.tempDir(), "chunk")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		defer os.Remove(f.Name())
+		return trace.ConvertSystemError(err)
+	}
+	if err := f.Close(); err != nil {
+		defer os.Remove(f.Name())
+		return trace.ConvertSystemError(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(targetPath), defaults.SharedDirMask); err != nil {
+		defer os.Remove(f.Name())
+		return trace.ConvertSystemError(err)
+	}
+	if err := os.Rename(f.Name(), targetPath); err != nil {
+		defer os.Remove(f.Name())
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+func (o *Objects) tempDir() string {
+	return filepath.Join(o.Path, "tmp")
+}
+
+func (o *Objects) chunkDir() string {
+	return filepath.Join(o.Path, "chunks")
+}
+
+func (o *Objects) indexDir() string {
+	return filepath.Join(o.Path, "index")
+}
+
+// legacyDir returns the directory with BLOBs written by the fs storage
+func (o *Objects) legacyDir() string {
+	return filepath.Join(o.Path, legacyDir)
+}
+
+func (o *Objects) formatPath() string {
+	return filepath.Join(o.Path, formatFile)
+}
+
+// chunkPath returns the path to the chunk with the specified hash.
+// As with the fs storage, files are grouped into directories
+// by the first 3 characters of the hash
+func (o *Objects) chunkPath(hash string) string {
+	return filepath.Join(o.chunkDir(), hash[0:3], hash)
+}
+
+func (o *Objects) indexPath(hash string) string {
+	return filepath.Join(o.indexDir(), hash[0:3], hash)
+}
+
+func (o *Objects) legacyPath(hash string) string {
+	return filepath.Join(o.legacyDir(), hash[0:3], hash)
+}
+
+// listFiles returns the names of all files under the specified directory
+func listFiles(dir string) (names []string, err error) {
+	err = walkFiles(dir, func(_ string, info os.FileInfo) {
+		names = append(names, info.Name())
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return names, nil
+}
+
+// walkFiles invokes fn for every file under the specified directory.
+// Missing directory is treated as empty
+func walkFiles(dir string, fn func(path string, info os.FileInfo)) error {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			log.Warnf("Error while traversing %v: %v.", dir, err)
+			return nil
+		}
+		if !info.IsDir() {
+			fn(path, info)
+		}
+		return nil
+	})
+	return trace.Wrap(err)
+}
+
+const (
+	// MinChunkSize is the default minimum chunk size
+	MinChunkSize = 256 * 1024
+	// AvgChunkSize is the default average chunk size
+	AvgChunkSize = 1024 * 1024
+	// MaxChunkSize is the default maximum chunk size
+	MaxChunkSize = 4 * 1024 * 1024
+
+	// legacyDir is the name of the directory with BLOBs written by the fs storage
+	legacyDir = "blobs"
+	// formatFile is the name of the file that marks the directory
+	// as using the chunked storage
+	formatFile = "format"
+	// formatChunked is the contents of the format file
+	formatChunked = "chunked"
+)
@@ -0,0 +1,227 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chunked
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/blob/suite"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	. "gopkg.in/check.v1"
+)
+
+func TestChunked(t *testing.T) { TestingT(t) }
+
+type ChunkedSuite struct {
+	suite   suite.BLOBSuite
+	dir     string
+	objects *Objects
+}
+
+var _ = Suite(&ChunkedSuite{})
+
+func (s *ChunkedSuite) SetUpTest(c *C) {
+	log.SetOutput(os.Stderr)
+	s.dir = c.MkDir()
+
+	var err error
+	s.objects, err = NewWithConfig(testConfig(s.dir))
+	c.Assert(err, IsNil)
+
+	s.suite.Objects = s.objects
+}
+
+func (s *ChunkedSuite) TestBLOB(c *C) {
+	s.suite.BLOB(c)
+}
+
+func (s *ChunkedSuite) TestBLOBSeek(c *C) {
+	s.suite.BLOBSeek(c)
+}
+
+func (s *ChunkedSuite) TestBLOBWriteTwice(c *C) {
+	s.suite.BLOBWriteTwice(c)
+}
+
+func (s *ChunkedSuite) TestBLOBList(c *C) {
+	s.suite.BLOBList(c)
+}
+
+func (s *ChunkedSuite) TestDeduplicatesSharedData(c *C) {
+	shared := randomData(64 * 1024)
+	blob1 := append(append(randomData(3000), shared...), randomData(5000)...)
+	blob2 := append(append(randomData(7000), shared...), randomData(1000)...)
+
+	e1, err := s.objects.WriteBLOB(bytes.NewReader(blob1))
+	c.Assert(err, IsNil)
+	c.Assert(e1.SHA512, Equals, utils.MustSHA512Half(blob1))
+	e2, err := s.objects.WriteBLOB(bytes.NewReader(blob2))
+	c.Assert(err, IsNil)
+
+	logical, stored, err := s.objects.Usage()
+	c.Assert(err, IsNil)
+	c.Assert(logical, Equals, int64(len(blob1)+len(blob2)))
+	// Most of the shared data is stored once
+	c.Assert(stored < logical-int64(len(shared))/2, Equals, true,
+		Commentf("stored %v bytes out of %v", stored, logical))
+
+	// Deleting one BLOB keeps the chunks shared with the other
+	c.Assert(s.objects.DeleteBLOB(e1.SHA512), IsNil)
+	c.Assert(readBLOB(c, s.objects, e2.SHA512), DeepEquals, blob2)
+
+	// Reference counts are restored when the storage is reopened
+	objects, err := NewWithConfig(testConfig(s.dir))
+	c.Assert(err, IsNil)
+	c.Assert(objects.DeleteBLOB(e2.SHA512), IsNil)
+	chunks, err := listFiles(objects.chunkDir())
+	c.Assert(err, IsNil)
+	c.Assert(chunks, HasLen, 0)
+}
+
+func (s *ChunkedSuite) TestSeekAcrossChunks(c *C) {
+	data := randomData(50 * 1024)
+	e, err := s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+
+	r, err := s.objects.OpenBLOB(e.SHA512)
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	for _, offset := range []int64{0, 4095, 4096, 20000, 49999} {
+		pos, err := r.Seek(offset, io.SeekStart)
+		c.Assert(err, IsNil)
+		c.Assert(pos, Equals, offset)
+		buf := make([]byte, 3000)
+		n, err := io.ReadFull(r, buf)
+		if int(offset)+len(buf) > len(data) {
+			c.Assert(err, Equals, io.ErrUnexpectedEOF)
+		} else {
+			c.Assert(err, IsNil)
+		}
+		c.Assert(buf[:n], DeepEquals, data[offset:int(offset)+n])
+	}
+
+	pos, err := r.Seek(-10, io.SeekEnd)
+	c.Assert(err, IsNil)
+	c.Assert(pos, Equals, int64(len(data)-10))
+	out, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Assert(out, DeepEquals, data[len(data)-10:])
+}
+
+func (s *ChunkedSuite) TestMigratesLegacyBLOBs(c *C) {
+	legacy, err := fs.New(s.dir)
+	c.Assert(err, IsNil)
+	data := randomData(30 * 1024)
+	e, err := legacy.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	corrupted, err := legacy.WriteBLOB(bytes.NewReader(randomData(100)))
+	c.Assert(err, IsNil)
+	corruptedPath := s.objects.legacyPath(corrupted.SHA512)
+	c.Assert(ioutil.WriteFile(corruptedPath, []byte("corrupted"), 0600), IsNil)
+
+	// Legacy BLOBs are available before the migration
+	c.Assert(readBLOB(c, s.objects, e.SHA512), DeepEquals, data)
+	hashes, err := s.objects.GetBLOBs()
+	c.Assert(err, IsNil)
+	c.Assert(hashes, HasLen, 2)
+
+	// Simulate a chunk left behind by an interrupted write
+	orphan := filepath.Join(s.objects.chunkDir(), "abc", "abcdef")
+	c.Assert(os.MkdirAll(filepath.Dir(orphan), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(orphan, []byte("orphan"), 0600), IsNil)
+
+	result, err := Migrate(s.objects, log.StandardLogger())
+	c.Assert(err, IsNil)
+	c.Assert(result.Migrated, Equals, 1)
+	c.Assert(result.Corrupted, DeepEquals, []string{corrupted.SHA512})
+	c.Assert(result.Pruned, Equals, 1)
+
+	_, err = os.Stat(s.objects.legacyPath(e.SHA512))
+	c.Assert(os.IsNotExist(err), Equals, true)
+	_, err = os.Stat(corruptedPath)
+	c.Assert(err, IsNil)
+	c.Assert(readBLOB(c, s.objects, e.SHA512), DeepEquals, data)
+	envelope, err := s.objects.GetBLOBEnvelope(e.SHA512)
+	c.Assert(err, IsNil)
+	c.Assert(envelope.SizeBytes, Equals, int64(len(data)))
+
+	err = s.objects.DeleteBLOB(corrupted.SHA512)
+	c.Assert(err, IsNil)
+	_, err = s.objects.OpenBLOB(corrupted.SHA512)
+	c.Assert(trace.IsNotFound(err), Equals, true)
+}
+
+func (s *ChunkedSuite) TestKeepsUsingLegacyStorageUntilMigrated(c *C) {
+	dir := c.MkDir()
+	legacy, err := fs.New(dir)
+	c.Assert(err, IsNil)
+	_, err = legacy.WriteBLOB(bytes.NewReader([]byte("hello, blob 1")))
+	c.Assert(err, IsNil)
+
+	objects, err := New(dir)
+	c.Assert(err, IsNil)
+	_, ok := objects.(*Objects)
+	c.Assert(ok, Equals, false)
+
+	_, err = NewWithConfig(Config{Path: dir})
+	c.Assert(err, IsNil)
+	objects, err = New(dir)
+	c.Assert(err, IsNil)
+	_, ok = objects.(*Objects)
+	c.Assert(ok, Equals, true)
+
+	// New storage directories use chunks
+	objects, err = New(c.MkDir())
+	c.Assert(err, IsNil)
+	_, ok = objects.(*Objects)
+	c.Assert(ok, Equals, true)
+}
+
+func readBLOB(c *C, objects *Objects, hash string) []byte {
+	r, err := objects.OpenBLOB(hash)
+	c.Assert(err, IsNil)
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	return data
+}
+
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	rand.Read(data)
+	return data
+}
+
+func testConfig(dir string) Config {
+	return Config{
+		Path:         dir,
+		MinChunkSize: 1024,
+		AvgChunkSize: 4096,
+		MaxChunkSize: 16 * 1024,
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chunked
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"math/bits"
+
+	"github.com/gravitational/trace"
+)
+
+// newChunker returns a new content-defined chunker for the specified reader
+func newChunker(r io.Reader, minSize, avgSize, maxSize int) *chunker {
+	// Only the most significant bits of the rolling hash depend
+	// on the last 64 bytes of input, so use them for the cut point
+	maskBits := uint(bits.TrailingZeros(uint(avgSize)))
+	return &chunker{
+		r:       bufio.NewReader(r),
+		minSize: minSize,
+		maxSize: maxSize,
+		mask:    ^uint64(0) << (64 - maskBits),
+		buf:     make([]byte, 0, maxSize),
+	}
+}
+
+// chunker splits the input into chunks using the gear rolling hash.
+// A chunk ends where the hash matches the mask, so the boundaries
+// only depend on the data around them
+type chunker struct {
+	r       *bufio.Reader
+	minSize int
+	maxSize int
+	mask    uint64
+	buf     []byte
+}
+
+// next returns the next chunk of input.
+// The returned slice is only valid until the next call.
+// Returns io.EOF when there is no more input
+func (c *chunker) next() ([]byte, error) {
+	c.buf = c.buf[:0]
+	var hash uint64
+	for {
+		b, err := c.r.ReadByte()
+		if err == io.EOF {
+			if len(c.buf) == 0 {
+				return nil, io.EOF
+			}
+			return c.buf, nil
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		c.buf = append(c.buf, b)
+		hash = (hash << 1) + gear[b]
+		if len(c.buf) >= c.maxSize {
+			return c.buf, nil
+		}
+		if len(c.buf) >= c.minSize && hash&c.mask == 0 {
+			return c.buf, nil
+		}
+	}
+}
+
+// gear is the table of random values for the rolling hash.
+// The values are derived deterministically so the chunk boundaries
+// are stable across versions
+var gear [256]uint64
+
+func init() {
+	for i := range gear {
+		sum := sha256.Sum256([]byte{byte(i)})
+		gear[i] = binary.LittleEndian.Uint64(sum[:8])
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chunked
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// MigrationResult describes the outcome of the storage migration
+type MigrationResult struct {
+	// Migrated is the number of converted legacy BLOBs
+	Migrated int
+	// Corrupted lists the legacy BLOBs whose contents do not match their hashes
+	Corrupted []string
+	// Pruned is the number of removed unreferenced chunks
+	Pruned int
+	// LogicalBytes is the total size of all BLOBs in the storage
+	LogicalBytes int64
+	// StoredBytes is the size of the data actually stored on disk
+	StoredBytes int64
+}
+
+// Migrate converts the legacy BLOBs written by the fs storage into chunks
+// and removes the unreferenced chunks.
+// Legacy BLOBs that fail verification are left intact and reported.
+//
+// The storage remains usable during the migration and the migration
+// can be safely restarted if interrupted. Once the storage has been opened
+// with NewWithConfig, New no longer falls back to the fs storage for it
+func Migrate(objects *Objects, logger log.FieldLogger) (*MigrationResult, error) {
+	legacy, err := listFiles(objects.legacyDir())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var result MigrationResult
+	for _, hash := range legacy {
+		err := migrateBLOB(objects, hash)
+		if trace.IsBadParameter(err) {
+			logger.WithField("blob", hash).Warnf("Skip corrupted BLOB: %v.", err)
+			result.Corrupted = append(result.Corrupted, hash)
+			continue
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		logger.WithField("blob", hash).Info("Migrated BLOB.")
+		result.Migrated++
+	}
+	result.Pruned, err = objects.Prune()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result.LogicalBytes, result.StoredBytes, err = objects.Usage()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &result, nil
+}
+
+// migrateBLOB converts the legacy BLOB with the specified hash into chunks
+func migrateBLOB(objects *Objects, hash string) error {
+	f, err := os.Open(objects.legacyPath(hash))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	// Verify the legacy BLOB first: a corrupted BLOB is left
+	// intact for investigation
+	hasher := sha512.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if actual := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2]); actual != hash {
+		return trace.BadParameter("hash mismatch for BLOB %v: got %v", hash, actual)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	_, err = objects.WriteBLOB(f)
+	return trace.Wrap(err)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chunked
+
+import (
+	"io"
+	"os"
+	"sort"
+
+	"github.com/gravitational/trace"
+)
+
+// newReader returns a reader that reassembles the BLOB described
+// by idx from its chunks
+func newReader(chunkPath func(hash string) string, idx index) *reader {
+	offsets := make([]int64, 0, len(idx.Chunks))
+	var offset int64
+	for _, chunk := range idx.Chunks {
+		offsets = append(offsets, offset)
+		offset += chunk.SizeBytes
+	}
+	return &reader{
+		chunkPath: chunkPath,
+		chunks:    idx.Chunks,
+		offsets:   offsets,
+		size:      idx.SizeBytes,
+		current:   -1,
+	}
+}
+
+// reader reads the BLOB chunk by chunk, opening chunk files as needed
+type reader struct {
+	chunkPath func(hash string) string
+	chunks    []chunkRef
+	// offsets lists the offsets of chunks within the BLOB
+	offsets []int64
+	// size is the BLOB size
+	size int64
+	// pos is the current read position within the BLOB
+	pos int64
+	// file is the currently open chunk file
+	file *os.File
+	// current is the index of the currently open chunk
+	current int
+}
+
+// Read reads up to len(p) bytes from the BLOB
+func (r *reader) Read(p []byte) (n int, err error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	i := sort.Search(len(r.offsets), func(i int) bool {
+		return r.offsets[i] > r.pos
+	}) - 1
+	if err := r.open(i); err != nil {
+		return 0, trace.Wrap(err)
+	}
+	// Do not read past the end of the chunk
+	remaining := r.offsets[i] + r.chunks[i].SizeBytes - r.pos
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err = r.file.Read(p)
+	r.pos += int64(n)
+	if err == io.EOF {
+		if n != 0 {
+			return n, nil
+		}
+		return 0, trace.Wrap(io.ErrUnexpectedEOF, "chunk %v is truncated", r.chunks[i].SHA256)
+	}
+	return n, trace.Wrap(err)
+}
+
+// Seek sets the offset for the next Read
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.size + offset
+	default:
+		return 0, trace.BadParameter("invalid whence %v", whence)
+	}
+	if pos < 0 {
+		return 0, trace.BadParameter("negative position %v", pos)
+	}
+	if pos != r.pos {
+		r.pos = pos
+		// Reposition within the chunk on the next read
+		r.closeFile()
+	}
+	return pos, nil
+}
+
+// Close closes the reader
+func (r *reader) Close() error {
+	return trace.Wrap(r.closeFile())
+}
+
+// open makes sure the chunk with the specified index is open
+// and positioned at the current offset
+func (r *reader) open(i int) error {
+	if r.file != nil && r.current == i {
+		return nil
+	}
+	r.closeFile()
+	f, err := os.Open(r.chunkPath(r.chunks[i].SHA256))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if _, err := f.Seek(r.pos-r.offsets[i], io.SeekStart); err != nil {
+		f.Close()
+		return trace.ConvertSystemError(err)
+	}
+	r.file = f
+	r.current = i
+	return nil
+}
+
+func (r *reader) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	r.current = -1
+	return err
+}
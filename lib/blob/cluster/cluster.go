@@ -239,19 +239,49 @@ func (c *cluster) fetchNewObjects() error {
 	return nil
 }
 
+// RepairBLOB replaces the local copy of the BLOB with a copy fetched
+// from another peer, e.g. after the local copy has been found corrupted
+func (c *cluster) RepairBLOB(hash string) error {
+	peers, err := c.remotePeers(hash)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// The local copy is only dropped when there is a replica to restore
+	// it from
+	err = c.Local.DeleteBLOB(hash)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(c.fetchObjectFrom(hash, peers))
+}
+
 func (c *cluster) fetchObject(hash string) error {
-	peerIDs, err := c.Backend.GetObjectPeers(hash)
+	peers, err := c.remotePeers(hash)
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	return trace.Wrap(c.fetchObjectFrom(hash, peers))
+}
+
+// remotePeers returns the active peers other than this one
+// that have the object
+func (c *cluster) remotePeers(hash string) ([]storage.Peer, error) {
+	peerIDs, err := c.Backend.GetObjectPeers(hash)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 	peers, err := c.getPeers(peerIDs)
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
 	peers = c.withoutSelf(peers)
 	if len(peers) == 0 {
-		return trace.NotFound("no active remote peers found for %v", hash)
+		return nil, trace.NotFound("no active remote peers found for %v", hash)
 	}
+	return peers, nil
+}
+
+func (c *cluster) fetchObjectFrom(hash string, peers []storage.Peer) error {
 	var errors []error
 	for _, p := range peers {
 		objects, err := c.getObjects(p)
@@ -272,6 +302,16 @@ func (c *cluster) fetchObject(hash string) error {
 			errors = append(errors, err)
 			continue
 		}
+		if envelope.SHA512 != hash {
+			// The copy on the peer is corrupted, try another one
+			c.Errorf("Fetched %v from %v with checksum %v.", hash, p, envelope.SHA512)
+			if err := c.Local.DeleteBLOB(envelope.SHA512); err != nil {
+				c.Warningf("Failed to delete %v: %v.", envelope.SHA512, err)
+			}
+			errors = append(errors, trace.CompareFailed("checksum mismatch for %v from %v: got %v",
+				hash, p, envelope.SHA512))
+			continue
+		}
 		c.Infof("Successfully fetched %v from %v.", envelope, p)
 		err = c.Backend.UpsertObjectPeers(hash, []string{c.ID}, 0)
 		if err != nil {
@@ -151,8 +151,11 @@ func (s *ClusterMultiPeers) SetUpTest(c *C) {
 		return peers[id], nil
 	}
 
+	dirs := make([]string, peersCount)
+
 	for i := 0; i < peersCount; i++ {
-		local, err := fs.New(c.MkDir())
+		dirs[i] = c.MkDir()
+		local, err := fs.New(dirs[i])
 		c.Assert(err, IsNil)
 		peers[i] = local
 		obj, err := New(Config{
@@ -178,6 +181,7 @@ func (s *ClusterMultiPeers) SetUpTest(c *C) {
 	s.clusterSuite.objects = objects
 	s.clusterSuite.clients = clients
 	s.clusterSuite.clock = fakeClock
+	s.clusterSuite.dirs = dirs
 }
 
 func (s *ClusterMultiPeers) TearDownTest(c *C) {
@@ -210,6 +214,10 @@ func (s *ClusterMultiPeers) TestCleanup(c *C) {
 	s.clusterSuite.Cleanup(c)
 }
 
+func (s *ClusterMultiPeers) TestRepair(c *C) {
+	s.clusterSuite.Repair(c)
+}
+
 type RPCSuite struct {
 	suite        suite.BLOBSuite
 	clusterSuite clusterSuite
@@ -252,8 +260,11 @@ func (s *RPCSuite) SetUpTest(c *C) {
 
 	fakeClock := clockwork.NewFakeClockAt(time.Now().UTC())
 
+	dirs := make([]string, peersCount)
+
 	for i := 0; i < 3; i++ {
-		local, err := fs.New(c.MkDir())
+		dirs[i] = c.MkDir()
+		local, err := fs.New(dirs[i])
 		c.Assert(err, IsNil)
 		peers[i] = local
 
@@ -310,6 +321,7 @@ func (s *RPCSuite) SetUpTest(c *C) {
 	s.clusterSuite.objects = objects
 	s.clusterSuite.clients = clients
 	s.clusterSuite.clock = fakeClock
+	s.clusterSuite.dirs = dirs
 
 	c.Assert(err, IsNil)
 
@@ -339,10 +351,16 @@ func (s *RPCSuite) TestCleanup(c *C) {
 	s.clusterSuite.Cleanup(c)
 }
 
+func (s *RPCSuite) TestRepair(c *C) {
+	s.clusterSuite.Repair(c)
+}
+
 type clusterSuite struct {
 	objects []*cluster
 	clients []blob.Objects
 	clock   clockwork.FakeClock
+	// dirs lists the local storage directories of the peers
+	dirs []string
 }
 
 func (s *clusterSuite) Replication(c *C) {
@@ -402,3 +420,32 @@ func (s *clusterSuite) Cleanup(c *C) {
 		c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%#v", err))
 	}
 }
+
+func (s *clusterSuite) Repair(c *C) {
+	peer1, peer2 := s.objects[1], s.objects[2]
+
+	data := []byte("hello, there, cluster!")
+
+	envelope, err := s.clients[0].WriteBLOB(bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+	time.Sleep(2 * heartbeatPeriod)
+
+	c.Assert(peer1.fetchNewObjects(), IsNil)
+	c.Assert(peer2.fetchNewObjects(), IsNil)
+
+	// corrupt the local copy of the second peer
+	path := filepath.Join(s.dirs[2], "blobs", envelope.SHA512[0:3], envelope.SHA512)
+	c.Assert(ioutil.WriteFile(path, []byte("corrupted"), 0644), IsNil)
+	err = blob.Verify(peer2.Local, envelope.SHA512)
+	c.Assert(trace.IsCompareFailed(err), Equals, true, Commentf("%#v", err))
+
+	c.Assert(peer2.RepairBLOB(envelope.SHA512), IsNil)
+	c.Assert(blob.Verify(peer2.Local, envelope.SHA512), IsNil)
+
+	f, err := peer2.Local.OpenBLOB(envelope.SHA512)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	out, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Equals, string(data))
+}
@@ -48,17 +48,35 @@ func Build(ctx context.Context, builder *Builder) error {
 		}
 	}
 
+	err = builder.OpenDeltaBase()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	var steps int
 	switch builder.Manifest.Kind {
 	case schema.KindBundle, schema.KindCluster:
-		builder.Config.Progress = utils.NewProgress(ctx, "Build",
-			clusterBuildSteps, builder.Config.Silent)
+		steps = clusterBuildSteps
 	case schema.KindApplication:
-		builder.Config.Progress = utils.NewProgress(ctx, "Build",
-			appBuildSteps, builder.Config.Silent)
+		steps = appBuildSteps
 	default:
 		return trace.BadParameter("unknown manifest kind %q",
 			builder.Manifest.Kind)
 	}
+	if builder.Scan != nil {
+		steps++
+	}
+	if len(builder.UpgradeVia) != 0 {
+		if builder.Manifest.Kind == schema.KindApplication {
+			return trace.BadParameter("--upgrade-via is only supported for cluster images")
+		}
+		steps++
+	}
+	if builder.FIPS && builder.Manifest.Kind == schema.KindApplication {
+		return trace.BadParameter("--fips is only supported for cluster images")
+	}
+	builder.Config.Progress = utils.NewProgress(ctx, "Build",
+		steps, builder.Config.Silent)
 
 	switch builder.Manifest.Kind {
 	case schema.KindBundle, schema.KindCluster:
@@ -73,11 +91,22 @@ func Build(ctx context.Context, builder *Builder) error {
 		}
 		err = builder.SyncPackageCache(runtimeVersion)
 		if err != nil {
-			if trace.IsNotFound(err) {
+			if trace.IsNotFound(err) && !builder.Offline {
 				return trace.NotFound("base image version %v not found", runtimeVersion)
 			}
 			return trace.Wrap(err)
 		}
+		if len(builder.UpgradeVia) != 0 {
+			builder.NextStep("Embedding intermediate base images")
+			runtimes, err := builder.SelectIntermediateRuntimes(runtimeVersion)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			err = builder.SyncIntermediateRuntimes(runtimes)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+		}
 	}
 
 	builder.NextStep("Embedding application container images")
@@ -28,8 +28,9 @@ import (
 	"time"
 
 	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/docker"
 	"github.com/gravitational/gravity/lib/app/service"
-	blobfs "github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/blob/chunked"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
@@ -41,6 +42,7 @@ import (
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
 	"github.com/gravitational/gravity/lib/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/helm/pkg/chartutil"
 
 	"github.com/coreos/go-semver/semver"
@@ -87,6 +89,34 @@ type Config struct {
 	utils.Progress
 	// Silent suppresses all std output when set to true
 	Silent bool
+	// ImageCacheDir is the directory with the cache of exported docker images.
+	// Defaults to the image cache directory in the user's home directory
+	ImageCacheDir string
+	// ImageCacheSize is the size limit of the image cache
+	ImageCacheSize utils.Capacity
+	// NoCache disables the image cache
+	NoCache bool
+	// Scan optionally configures scanning of application images
+	// for vulnerabilities. Images are not scanned if unset
+	Scan *ScanConfig
+	// SigningKey is the optional PEM-encoded private key to sign
+	// the installer with
+	SigningKey []byte
+	// DeltaFrom optionally specifies the version of the application
+	// to generate a delta installer against
+	DeltaFrom string
+	// UpgradeVia optionally lists the intermediate runtime versions
+	// to embed into the cluster image for multi-hop upgrades
+	UpgradeVia []string
+	// Offline specifies whether all dependencies must be resolved from
+	// the package cache in StateDir without accessing the network
+	Offline bool
+	// Values optionally specifies the values to render the manifest with.
+	// The manifest is treated as a Go template only if values are provided
+	Values map[string]interface{}
+	// FIPS specifies whether to build the cluster image with the base image
+	// variant built with FIPS-validated crypto
+	FIPS bool
 }
 
 // CheckAndSetDefaults validates builder config and fills in defaults
@@ -112,6 +142,10 @@ func (c *Config) CheckAndSetDefaults() error {
 				defaults.ManifestFileName)
 		}
 	}
+	if c.Offline && c.StateDir == "" {
+		return trace.BadParameter("offline build requires a state directory " +
+			"with the pre-seeded package cache, see --state-dir")
+	}
 	if c.VendorReq.Parallel == 0 {
 		c.VendorReq.Parallel = runtime.NumCPU()
 	}
@@ -130,12 +164,35 @@ func (c *Config) CheckAndSetDefaults() error {
 	if c.Progress == nil {
 		c.Progress = utils.NewProgress(c.Context, "Build", 6, false)
 	}
+	if c.ImageCacheSize == 0 {
+		c.ImageCacheSize = utils.MustParseCapacity(defaults.ImageCacheSize)
+	}
+	if c.Scan != nil {
+		if err := c.Scan.CheckAndSetDefaults(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	return nil
 }
 
-// New creates a new builder instance from the provided config
-func New(config Config) (*Builder, error) {
-	err := config.CheckAndSetDefaults()
+// New creates a new builder instance from the provided config.
+//
+// The manifest path can point to an application manifest file,
+// a Helm chart directory or a packaged Helm chart
+func New(config Config) (builder *Builder, err error) {
+	var chartDir string
+	if isChartArchive(config.ManifestPath) {
+		chartDir, config.ManifestPath, err = unpackChart(config.ManifestPath)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		defer func() {
+			if err != nil {
+				os.RemoveAll(chartDir)
+			}
+		}()
+	}
+	err = config.CheckAndSetDefaults()
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -144,6 +201,7 @@ func New(config Config) (*Builder, error) {
 		return nil, trace.ConvertSystemError(err)
 	}
 	var manifest *schema.Manifest
+	var renderedManifest []byte
 	if fi.IsDir() {
 		// If this is a Helm chart directory, extract the chart metadata
 		// and generate a basic application manifest.
@@ -167,6 +225,17 @@ func New(config Config) (*Builder, error) {
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
+		if len(config.Values) != 0 {
+			manifestBytes, err = schema.RenderManifestTemplate(config.manifestFilename,
+				manifestBytes, config.Values)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			renderedManifest = manifestBytes
+		}
+		if err := validateManifest(manifestBytes, config.Progress); err != nil {
+			return nil, trace.Wrap(err)
+		}
 		manifest, err = schema.ParseManifestYAMLNoValidate(manifestBytes)
 		if err != nil {
 			logrus.Errorf(trace.DebugReport(err))
@@ -175,8 +244,10 @@ func New(config Config) (*Builder, error) {
 		}
 	}
 	b := &Builder{
-		Config:   config,
-		Manifest: *manifest,
+		Config:           config,
+		Manifest:         *manifest,
+		chartDir:         chartDir,
+		renderedManifest: renderedManifest,
 	}
 	err = b.initServices()
 	if err != nil {
@@ -186,6 +257,22 @@ func New(config Config) (*Builder, error) {
 	return b, nil
 }
 
+// validateManifest validates the application manifest data against
+// the manifest schema and prints warnings about deprecated values.
+//
+// The returned error lists all schema violations annotated with
+// their locations in the manifest
+func validateManifest(data []byte, progress utils.Progress) error {
+	result, err := schema.ValidateManifestYAML(data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, warning := range result.Warnings {
+		progress.PrintWarn(nil, "Warning: %v", warning)
+	}
+	return trace.Wrap(result.Check())
+}
+
 // Builder implements the installer builder
 type Builder struct {
 	// Config is the builder configuration
@@ -204,6 +291,21 @@ type Builder struct {
 	Packages pack.PackageService
 	// Apps is the application service based on the layered package service
 	Apps app.Applications
+	// ImageCache is the cache of exported docker images.
+	// It is nil if the cache is disabled
+	ImageCache *docker.ImageCache
+	// chartDir is the temporary directory with the unpacked Helm chart
+	// if the application is built from a packaged chart
+	chartDir string
+	// renderedManifest is the manifest data rendered with the configured
+	// values, nil if the manifest is not a template
+	renderedManifest []byte
+	// deltaEnv is the environment of the unpacked installer
+	// the delta installer is generated against
+	deltaEnv *localenv.LocalEnvironment
+	// deltaBase describes the application the delta installer
+	// is generated against
+	deltaBase *app.InstallerBase
 }
 
 // Locator returns locator of the application that's being built
@@ -234,9 +336,14 @@ func (b *Builder) SelectRuntime() (*semver.Version, error) {
 	}
 	// If runtime version is explicitly set in the manifest, use it.
 	if runtime.Version != loc.LatestVersion {
-		b.Infof("Using pinned runtime version: %s.", runtime.Version)
-		b.PrintSubStep("Will use base image version %s set in manifest", runtime.Version)
-		return semver.NewVersion(runtime.Version)
+		runtimeVersion, err := semver.NewVersion(runtime.Version)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		runtimeVersion = b.runtimeVariant(runtimeVersion)
+		b.Infof("Using pinned runtime version: %s.", runtimeVersion)
+		b.PrintSubStep("Will use base image version %s set in manifest", runtimeVersion)
+		return runtimeVersion, nil
 	}
 	// Otherwise, default to the version of this tele binary to ensure
 	// compatibility.
@@ -244,9 +351,132 @@ func (b *Builder) SelectRuntime() (*semver.Version, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	b.Infof("Selected runtime version based on tele version: %s.", teleVersion)
-	b.PrintSubStep("Will use base image version %s", teleVersion)
-	return teleVersion, nil
+	runtimeVersion := b.runtimeVariant(teleVersion)
+	b.Infof("Selected runtime version based on tele version: %s.", runtimeVersion)
+	b.PrintSubStep("Will use base image version %s", runtimeVersion)
+	return runtimeVersion, nil
+}
+
+// runtimeVariant returns the version of the runtime variant selected
+// for this build.
+//
+// When building in FIPS mode, the version refers to the runtime built
+// with FIPS-validated crypto, otherwise the version is returned unchanged
+func (b *Builder) runtimeVariant(runtimeVersion *semver.Version) *semver.Version {
+	if !b.FIPS {
+		return runtimeVersion
+	}
+	fipsVersion := *runtimeVersion
+	fipsVersion.Metadata = constants.FIPSVersionMetadata
+	return &fipsVersion
+}
+
+// SelectIntermediateRuntimes returns the intermediate runtimes to embed
+// into the cluster image based on the versions requested with --upgrade-via,
+// sorted in the ascending version order.
+//
+// All intermediate versions must be older than the runtime version of the
+// cluster image and each upgrade hop must be supported directly
+func (b *Builder) SelectIntermediateRuntimes(runtimeVersion *semver.Version) (runtimes []loc.Locator, err error) {
+	var versions semver.Versions
+	for _, upgradeVia := range b.UpgradeVia {
+		version, err := semver.NewVersion(upgradeVia)
+		if err != nil {
+			return nil, trace.BadParameter("invalid intermediate runtime version %q: %v", upgradeVia, err)
+		}
+		if !version.LessThan(*runtimeVersion) {
+			return nil, trace.BadParameter("intermediate runtime version %v should be older "+
+				"than the base image version %v", version, runtimeVersion)
+		}
+		versions = append(versions, version)
+	}
+	semver.Sort(versions)
+	for i, version := range versions {
+		if i > 0 && versions[i-1].Equal(*version) {
+			return nil, trace.BadParameter("duplicate intermediate runtime version %v", version)
+		}
+		runtimes = append(runtimes, loc.Runtime.WithVersion(b.runtimeVariant(version)))
+	}
+	if len(runtimes) == 0 {
+		return nil, nil
+	}
+	// Make sure each hop between the intermediate runtimes is supported
+	_, err = pack.RuntimeUpgradePath(runtimes[0], loc.Runtime.WithVersion(runtimeVersion), runtimes[1:])
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return runtimes, nil
+}
+
+// SyncIntermediateRuntimes makes sure that the specified intermediate
+// runtimes and their dependencies are present in the local cache directory
+// and configures the vendorer to embed them into the cluster image
+func (b *Builder) SyncIntermediateRuntimes(runtimes []loc.Locator) error {
+	apps, err := b.Env.AppServiceLocal(localenv.AppConfig{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var syncer Syncer
+	for _, runtime := range runtimes {
+		runtimeApp, err := apps.GetApp(runtime)
+		if err == nil {
+			err = app.VerifyDependencies(runtimeApp, apps, b.Env.Packages)
+		}
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		if err == nil {
+			b.Infof("Intermediate runtime %v is up-to-date.", runtime)
+			continue
+		}
+		if b.Offline {
+			return trace.Wrap(b.checkOfflineDependencies(runtimeManifest(runtime)))
+		}
+		if syncer == nil {
+			syncer, err = b.NewSyncer(b)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		b.PrintSubStep("Downloading intermediate base image version %v", runtime.Version)
+		if err := b.syncIntermediateRuntime(syncer, runtime); err != nil {
+			if trace.IsNotFound(err) {
+				return trace.NotFound("intermediate base image version %v not found", runtime.Version)
+			}
+			return trace.Wrap(err)
+		}
+	}
+	b.VendorReq.IntermediateRuntimes = runtimes
+	return nil
+}
+
+// syncIntermediateRuntime synchronizes the package cache with the
+// dependencies of the specified intermediate runtime
+func (b *Builder) syncIntermediateRuntime(syncer Syncer, runtime loc.Locator) error {
+	version, err := runtime.SemVer()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// The syncer pulls dependencies of the builder's manifest, so use
+	// a copy of the builder with the manifest that only depends on the
+	// intermediate runtime
+	builder := *b
+	builder.Manifest = runtimeManifest(runtime)
+	return syncer.Sync(&builder, version)
+}
+
+// runtimeManifest returns a cluster image manifest that only depends
+// on the specified runtime
+func runtimeManifest(runtime loc.Locator) schema.Manifest {
+	manifest := schema.Manifest{
+		Header: schema.Header{
+			TypeMeta: metav1.TypeMeta{
+				Kind: schema.KindCluster,
+			},
+		},
+	}
+	manifest.SetBase(runtime)
+	return manifest
 }
 
 // SyncPackageCache ensures that all system dependencies are present in
@@ -270,6 +500,9 @@ func (b *Builder) SyncPackageCache(runtimeVersion *semver.Version) error {
 		b.NextStep("Local package cache is up-to-date")
 		return nil
 	}
+	if b.Offline {
+		return trace.Wrap(b.checkOfflineDependencies(b.Manifest))
+	}
 	repository, err := b.GetRepository(b)
 	if err != nil {
 		return trace.Wrap(err)
@@ -284,7 +517,9 @@ func (b *Builder) SyncPackageCache(runtimeVersion *semver.Version) error {
 }
 
 // Vendor vendors the application images in the provided directory and
-// returns the compressed data stream with the application data
+// returns the compressed data stream with the application data.
+// If image scanning is configured, the application images are scanned
+// once they have been pulled
 func (b *Builder) Vendor(ctx context.Context, dir string) (io.ReadCloser, error) {
 	err := utils.CopyDirContents(b.manifestDir, filepath.Join(dir, defaults.ResourcesDir))
 	if err != nil {
@@ -294,6 +529,12 @@ func (b *Builder) Vendor(ctx context.Context, dir string) (io.ReadCloser, error)
 	// If manifest filename is empty, it means it was auto-generated
 	// out of a Helm chart so write the generated manifest to the
 	// vendor directory as well.
+	if b.renderedManifest != nil {
+		err = ioutil.WriteFile(manifestPath, b.renderedManifest, defaults.SharedReadMask)
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+	}
 	if b.manifestFilename == "" {
 		data, err := yaml.Marshal(b.Manifest)
 		if err != nil {
@@ -308,6 +549,7 @@ func (b *Builder) Vendor(ctx context.Context, dir string) (io.ReadCloser, error)
 		DockerURL:   constants.DockerEngineURL,
 		RegistryURL: constants.DockerRegistry,
 		Packages:    b.Packages,
+		ImageCache:  b.ImageCache,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -315,10 +557,25 @@ func (b *Builder) Vendor(ctx context.Context, dir string) (io.ReadCloser, error)
 	vendorReq := b.VendorReq
 	vendorReq.ManifestPath = manifestPath
 	vendorReq.ProgressReporter = b.Progress
+	var images []string
+	vendorReq.PulledImagesFn = func(pulled []string) {
+		images = pulled
+	}
 	err = vendorer.VendorDir(ctx, dir, vendorReq)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	if b.Scan != nil {
+		b.NextStep("Scanning application container images for vulnerabilities")
+		if err := b.ScanImages(ctx, images); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	if b.ImageCache != nil {
+		if err := b.ImageCache.Prune(); err != nil {
+			b.Warnf("Failed to prune image cache: %v.", trace.DebugReport(err))
+		}
+	}
 	return archive.Tar(dir, archive.Uncompressed)
 }
 
@@ -381,7 +638,7 @@ func (b *Builder) initServices() (err error) {
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	objects, err := blobfs.New(filepath.Join(b.Dir, defaults.PackagesDir))
+	objects, err := chunked.New(filepath.Join(b.Dir, defaults.PackagesDir))
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -400,6 +657,22 @@ func (b *Builder) initServices() (err error) {
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if b.NoCache {
+		return nil
+	}
+	cacheDir, err := utils.EnsureLocalPath(b.ImageCacheDir, defaults.LocalImageCacheDir, "")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	b.Infof("Using image cache from %v.", cacheDir)
+	b.ImageCache, err = docker.NewImageCache(docker.ImageCacheConfig{
+		Dir:         cacheDir,
+		MaxSize:     b.ImageCacheSize.Bytes(),
+		FieldLogger: b.FieldLogger,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
 	return nil
 }
 
@@ -478,12 +751,18 @@ func (b *Builder) Close() error {
 	if b.Env != nil {
 		errors = append(errors, b.Env.Close())
 	}
+	if b.deltaEnv != nil {
+		errors = append(errors, b.deltaEnv.Close())
+	}
 	if b.Backend != nil {
 		errors = append(errors, b.Backend.Close())
 	}
 	if b.Dir != "" {
 		errors = append(errors, os.RemoveAll(b.Dir))
 	}
+	if b.chartDir != "" {
+		errors = append(errors, os.RemoveAll(b.chartDir))
+	}
 	if b.Progress != nil {
 		b.Progress.Stop()
 	}
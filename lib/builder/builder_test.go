@@ -17,15 +17,21 @@ limitations under the License.
 package builder
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/coreos/go-semver/semver"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/utils"
 	"github.com/gravitational/trace"
 	"github.com/gravitational/version"
 	"github.com/sirupsen/logrus"
 	check "gopkg.in/check.v1"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
 func TestBuilder(t *testing.T) { check.TestingT(t) }
@@ -91,12 +97,116 @@ func (s *BuilderSuite) TestSelectRuntimeVersion(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(ver, check.DeepEquals, semver.New("5.4.2"))
 
+	b.FIPS = true
+	ver, err = b.SelectRuntime()
+	c.Assert(err, check.IsNil)
+	c.Assert(ver, check.DeepEquals, semver.New("5.4.2+fips"))
+
+	b.Manifest = schema.MustParseManifestYAML([]byte(manifestWithBase))
+	ver, err = b.SelectRuntime()
+	c.Assert(err, check.IsNil)
+	c.Assert(ver, check.DeepEquals, semver.New("5.5.0+fips"))
+	b.FIPS = false
+
 	b.Manifest = schema.MustParseManifestYAML([]byte(manifestInvalidBase))
 	ver, err = b.SelectRuntime()
 	c.Assert(err, check.FitsTypeOf, trace.BadParameter(""))
 	c.Assert(err, check.ErrorMatches, "unsupported base image .*")
 }
 
+func (s *BuilderSuite) TestSelectIntermediateRuntimes(c *check.C) {
+	b := &Builder{
+		Config: Config{
+			FieldLogger: logrus.WithField(trace.Component, "test"),
+			Progress:    utils.NewNopProgress(),
+			UpgradeVia:  []string{"6.1.20", "5.5.40"},
+		},
+	}
+	runtimes, err := b.SelectIntermediateRuntimes(semver.New("7.0.0"))
+	c.Assert(err, check.IsNil)
+	c.Assert(runtimes, check.DeepEquals, []loc.Locator{
+		loc.Runtime.WithVersion(semver.New("5.5.40")),
+		loc.Runtime.WithVersion(semver.New("6.1.20")),
+	})
+
+	testCases := []struct {
+		upgradeVia []string
+		comment    string
+	}{
+		{
+			upgradeVia: []string{"latest"},
+			comment:    "invalid version",
+		},
+		{
+			upgradeVia: []string{"7.0.0"},
+			comment:    "intermediate version is not older than the base image version",
+		},
+		{
+			upgradeVia: []string{"6.1.20", "6.1.20"},
+			comment:    "duplicate intermediate version",
+		},
+		{
+			upgradeVia: []string{"5.5.40"},
+			comment:    "unsupported upgrade hop to the base image version",
+		},
+	}
+	for _, t := range testCases {
+		b.UpgradeVia = t.upgradeVia
+		_, err := b.SelectIntermediateRuntimes(semver.New("7.0.0"))
+		c.Assert(err, check.FitsTypeOf, trace.BadParameter(""), check.Commentf(t.comment))
+	}
+}
+
+func (s *BuilderSuite) TestOfflineBuildReportsMissingDependencies(c *check.C) {
+	stateDir := c.MkDir()
+	env, err := localenv.New(stateDir)
+	c.Assert(err, check.IsNil)
+	defer env.Close()
+	b := &Builder{
+		Config: Config{
+			FieldLogger: logrus.WithField(trace.Component, "test"),
+			Progress:    utils.NewNopProgress(),
+			StateDir:    stateDir,
+			Offline:     true,
+			NewSyncer: func(*Builder) (Syncer, error) {
+				return nil, trace.BadParameter("offline build should not synchronize package cache")
+			},
+		},
+		Env:      env,
+		Manifest: schema.MustParseManifestYAML([]byte(manifestWithBase)),
+	}
+	err = b.SyncPackageCache(semver.New("5.5.0"))
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+	c.Assert(err, check.ErrorMatches, "(?s).*missing from the package cache.*gravitational.io/kubernetes:5.5.0.*")
+}
+
+func (s *BuilderSuite) TestUnpacksChartArchive(c *check.C) {
+	chartDir, err := chartutil.Create(&chart.Metadata{
+		Name:    "example",
+		Version: "0.0.1",
+	}, c.MkDir())
+	c.Assert(err, check.IsNil)
+	ch, err := chartutil.Load(chartDir)
+	c.Assert(err, check.IsNil)
+	path, err := chartutil.Save(ch, c.MkDir())
+	c.Assert(err, check.IsNil)
+	c.Assert(filepath.Base(path), check.Equals, "example-0.0.1.tgz")
+	c.Assert(isChartArchive(path), check.Equals, true)
+	c.Assert(isChartArchive(chartDir), check.Equals, false)
+
+	dir, unpackedDir, err := unpackChart(path)
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	c.Assert(unpackedDir, check.Equals, filepath.Join(dir, "example"))
+
+	ch, err = chartutil.Load(unpackedDir)
+	c.Assert(err, check.IsNil)
+	manifest, err := generateManifest(ch)
+	c.Assert(err, check.IsNil)
+	c.Assert(manifest.Metadata.Name, check.Equals, "example")
+	c.Assert(manifest.Metadata.ResourceVersion, check.Equals, "0.0.1")
+}
+
 const (
 	manifestWithBase = `apiVersion: cluster.gravitational.io/v2
 kind: Cluster
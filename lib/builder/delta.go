@@ -0,0 +1,121 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/hub"
+	"github.com/gravitational/gravity/lib/install"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/localenv"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+)
+
+// OpenDeltaBase unpacks the installer of the application version specified
+// with DeltaFrom the delta installer is generated against.
+//
+// The installer is looked up next to the output tarball first and is
+// downloaded from the hub if it is not found there
+func (b *Builder) OpenDeltaBase() error {
+	if b.DeltaFrom == "" {
+		return nil
+	}
+	locator := b.Locator()
+	if locator.Version == b.DeltaFrom {
+		return trace.BadParameter("cannot generate delta of %v against itself", locator)
+	}
+	reader, err := b.openInstaller(loc.Locator{
+		Repository: locator.Repository,
+		Name:       locator.Name,
+		Version:    b.DeltaFrom,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	dir := filepath.Join(b.Dir, deltaBaseDir)
+	stream, err := dockerarchive.DecompressStream(reader)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer stream.Close()
+	if err := archive.Extract(stream, dir); err != nil {
+		return trace.Wrap(err)
+	}
+	b.deltaEnv, err = localenv.New(dir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	apps, err := b.deltaEnv.AppServiceLocal(localenv.AppConfig{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	base, err := install.GetAppPackage(apps)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	b.Infof("Generating delta against %v.", base)
+	b.deltaBase = &app.InstallerBase{
+		Application: *base,
+		Apps:        apps,
+		Packages:    b.deltaEnv.Packages,
+	}
+	return nil
+}
+
+// openInstaller returns the installer tarball of the specified application
+func (b *Builder) openInstaller(locator loc.Locator) (io.ReadCloser, error) {
+	path := filepath.Join(filepath.Dir(b.OutPath),
+		fmt.Sprintf("%v-%v.tar", locator.Name, locator.Version))
+	f, err := os.Open(path)
+	if err == nil {
+		b.Infof("Using installer %v.", path)
+		return f, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if b.Offline {
+		return nil, trace.NotFound("installer of %v not found in %v", locator, path)
+	}
+	b.Infof("Installer %v not found, downloading %v.", path, locator)
+	hub, err := hub.New(hub.Config{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	reader, err := hub.Get(locator)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("installer of %v not found in %v or the hub",
+				locator, path)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return reader, nil
+}
+
+// deltaBaseDir is the name of the build directory the installer
+// the delta is generated against is unpacked into
+const deltaBaseDir = "delta-base"
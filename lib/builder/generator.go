@@ -36,5 +36,7 @@ type generator struct{}
 func (g *generator) Generate(builder *Builder, application app.Application) (io.ReadCloser, error) {
 	return builder.Apps.GetAppInstaller(app.InstallerRequest{
 		Application: application.Package,
+		SigningKey:  builder.SigningKey,
+		Base:        builder.deltaBase,
 	})
 }
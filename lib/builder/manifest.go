@@ -17,10 +17,18 @@ limitations under the License.
 package builder
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/archive"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/schema"
 
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 )
@@ -49,3 +57,49 @@ func generateManifest(chart *chart.Chart) (*schema.Manifest, error) {
 		Logo: chart.Metadata.Icon,
 	}, nil
 }
+
+// isChartArchive returns true if the provided path points to a packaged
+// Helm chart, e.g. the one created with "helm package"
+func isChartArchive(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil || fi.IsDir() {
+		return false
+	}
+	return strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz")
+}
+
+// unpackChart unpacks the packaged Helm chart at path into a temporary
+// directory.
+//
+// Returns the temporary directory which the caller is responsible for removing
+// and the path to the chart directory inside it
+func unpackChart(path string) (dir, chartDir string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	dir, err = ioutil.TempDir("", "chart")
+	if err != nil {
+		return "", "", trace.ConvertSystemError(err)
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+	err = dockerarchive.Untar(f, dir, archive.DefaultOptions())
+	if err != nil {
+		return "", "", trace.Wrap(err, "failed to unpack chart %v", path)
+	}
+	// Packaged charts have all files under the top-level directory
+	// named after the chart
+	matches, err := filepath.Glob(filepath.Join(dir, "*", constants.HelmChartFile))
+	if err != nil {
+		return "", "", trace.Wrap(err)
+	}
+	if len(matches) != 1 {
+		return "", "", trace.BadParameter("%v is not a packaged Helm chart", path)
+	}
+	return dir, filepath.Dir(matches[0]), nil
+}
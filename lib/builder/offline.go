@@ -0,0 +1,117 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/install"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/schema"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// checkOfflineDependencies returns an error listing the dependencies of the
+// specified manifest that are missing from the local package cache.
+//
+// It is used in offline mode in place of synchronizing the package cache
+func (b *Builder) checkOfflineDependencies(manifest schema.Manifest) error {
+	apps, err := b.Env.AppServiceLocal(localenv.AppConfig{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	missing, err := app.GetMissingDependencies(&app.Application{
+		Manifest: manifest,
+		Package:  manifest.Locator(),
+	}, apps, b.Env.Packages)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if missing.IsEmpty() {
+		return nil
+	}
+	var lines []string
+	for _, locator := range append(missing.Apps, missing.Packages...) {
+		lines = append(lines, fmt.Sprintf("  * %v", locator))
+	}
+	return trace.NotFound(`the following dependencies are missing from the package cache in %[1]v:
+%[2]v
+
+Populate the package cache on a machine with network access with "tele pull --state-dir=%[1]v gravity:<version>"
+and copy the directory to this machine.`, b.StateDir, strings.Join(lines, "\n"))
+}
+
+// SeedPackageCache imports the application from the installer tarball read
+// from the provided reader along with all its dependencies into the package
+// cache of the specified environment so it can be used for offline builds.
+//
+// Returns the locator of the imported application
+func SeedPackageCache(env *localenv.LocalEnvironment, reader io.Reader, logger logrus.FieldLogger) (*loc.Locator, error) {
+	unpackedDir, err := ioutil.TempDir("", "installer-unpacked")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(unpackedDir)
+	stream, err := dockerarchive.DecompressStream(reader)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer stream.Close()
+	if err := archive.Extract(stream, unpackedDir); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	installerEnv, err := localenv.New(unpackedDir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer installerEnv.Close()
+	installerApps, err := installerEnv.AppServiceLocal(localenv.AppConfig{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	locator, err := install.GetAppPackage(installerApps)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cacheApps, err := env.AppServiceLocal(localenv.AppConfig{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	_, err = service.PullApp(service.AppPullRequest{
+		FieldLogger: logger,
+		SrcPack:     installerEnv.Packages,
+		SrcApp:      installerApps,
+		DstPack:     env.Packages,
+		DstApp:      cacheApps,
+		Package:     *locator,
+	})
+	if err != nil && !trace.IsAlreadyExists(err) {
+		return nil, trace.Wrap(err)
+	}
+	return locator, nil
+}
@@ -0,0 +1,357 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/tool/common"
+
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+)
+
+// Severity is the severity of a vulnerability
+type Severity int
+
+const (
+	// SeverityNone is the zero severity, no vulnerability has it
+	SeverityNone Severity = iota
+	// SeverityUnknown is the severity of vulnerabilities not yet rated
+	SeverityUnknown
+	// SeverityLow is the low severity
+	SeverityLow
+	// SeverityMedium is the medium severity
+	SeverityMedium
+	// SeverityHigh is the high severity
+	SeverityHigh
+	// SeverityCritical is the critical severity
+	SeverityCritical
+)
+
+// AllSeverities lists all vulnerability severities from the most severe
+var AllSeverities = []Severity{
+	SeverityCritical,
+	SeverityHigh,
+	SeverityMedium,
+	SeverityLow,
+	SeverityUnknown,
+}
+
+// ParseSeverity parses the severity from its name, case-insensitive
+func ParseSeverity(name string) (Severity, error) {
+	for _, severity := range AllSeverities {
+		if strings.EqualFold(name, severity.String()) {
+			return severity, nil
+		}
+	}
+	return SeverityNone, trace.BadParameter("unknown severity %q, expected one of %v",
+		name, AllSeverities)
+}
+
+// String returns the severity name
+func (s Severity) String() string {
+	switch s {
+	case SeverityUnknown:
+		return "UNKNOWN"
+	case SeverityLow:
+		return "LOW"
+	case SeverityMedium:
+		return "MEDIUM"
+	case SeverityHigh:
+		return "HIGH"
+	case SeverityCritical:
+		return "CRITICAL"
+	default:
+		return "NONE"
+	}
+}
+
+// MarshalText encodes the severity as its name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes the severity from its name.
+// Unrecognized severities are decoded as SeverityUnknown
+func (s *Severity) UnmarshalText(data []byte) error {
+	severity, err := ParseSeverity(string(data))
+	if err != nil {
+		severity = SeverityUnknown
+	}
+	*s = severity
+	return nil
+}
+
+// Vulnerability describes a vulnerability found in an image
+type Vulnerability struct {
+	// ID is the vulnerability ID, e.g. CVE number
+	ID string `json:"id"`
+	// Package is the name of the vulnerable package
+	Package string `json:"package"`
+	// InstalledVersion is the version of the vulnerable package
+	InstalledVersion string `json:"installed_version"`
+	// FixedVersion is the package version that fixes the vulnerability
+	FixedVersion string `json:"fixed_version,omitempty"`
+	// Severity is the vulnerability severity
+	Severity Severity `json:"severity"`
+	// Title is the short vulnerability description
+	Title string `json:"title,omitempty"`
+}
+
+// ImageReport is the result of scanning a single image
+type ImageReport struct {
+	// Image is the scanned image
+	Image string `json:"image"`
+	// Vulnerabilities lists vulnerabilities found in the image
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// Count returns the number of vulnerabilities of the specified severity
+func (r ImageReport) Count(severity Severity) (count int) {
+	for _, vulnerability := range r.Vulnerabilities {
+		if vulnerability.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// Summary returns the number of vulnerabilities by severity as text
+func (r ImageReport) Summary() string {
+	var counts []string
+	for _, severity := range AllSeverities {
+		counts = append(counts, fmt.Sprintf("%v %v",
+			r.Count(severity), strings.ToLower(severity.String())))
+	}
+	return strings.Join(counts, ", ")
+}
+
+// ScanReport is the result of scanning application images
+type ScanReport struct {
+	// Images lists reports of individual images
+	Images []ImageReport `json:"images"`
+}
+
+// CountAtLeast returns the number of vulnerabilities in all images
+// with the specified or higher severity
+func (r ScanReport) CountAtLeast(severity Severity) (count int) {
+	for _, image := range r.Images {
+		for _, vulnerability := range image.Vulnerabilities {
+			if vulnerability.Severity >= severity {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// WriteScanReport writes the report to w in the specified format
+func WriteScanReport(w io.Writer, report ScanReport, format constants.Format) error {
+	switch format {
+	case constants.EncodingJSON:
+		data, err := json.MarshalIndent(report, "", "    ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return trace.Wrap(err)
+	case constants.EncodingText:
+		var t tabwriter.Writer
+		t.Init(w, 0, 10, 5, ' ', 0)
+		common.PrintTableHeader(&t, []string{"Image", "Vulnerability", "Severity", "Package", "Installed", "Fixed"})
+		for _, image := range report.Images {
+			for _, v := range image.Vulnerabilities {
+				fmt.Fprintf(&t, "%v\t%v\t%v\t%v\t%v\t%v\n", image.Image, v.ID, v.Severity,
+					v.Package, v.InstalledVersion, formatFixedVersion(v.FixedVersion))
+			}
+		}
+		return trace.Wrap(t.Flush())
+	default:
+		return trace.BadParameter("unsupported report format %q, expected one of %v",
+			format, []constants.Format{constants.EncodingText, constants.EncodingJSON})
+	}
+}
+
+func formatFixedVersion(version string) string {
+	if version == "" {
+		return "-"
+	}
+	return version
+}
+
+// Scanner scans container images for vulnerabilities
+type Scanner interface {
+	// Scan scans the specified image in the local docker
+	Scan(ctx context.Context, image string) (*ImageReport, error)
+}
+
+// ScanConfig configures scanning of application images during build
+type ScanConfig struct {
+	// Scanner is the image scanner
+	Scanner Scanner
+	// ReportPath is the optional path of the file to write the report to
+	ReportPath string
+	// ReportFormat is the format of the report file
+	ReportFormat constants.Format
+	// FailSeverity fails the build if any vulnerability with this or
+	// higher severity is found. SeverityNone never fails the build
+	FailSeverity Severity
+}
+
+// CheckAndSetDefaults validates the config and sets defaults
+func (c *ScanConfig) CheckAndSetDefaults() error {
+	if c.Scanner == nil {
+		return trace.BadParameter("missing Scanner")
+	}
+	if c.ReportFormat == "" {
+		c.ReportFormat = constants.EncodingText
+	}
+	if c.ReportFormat != constants.EncodingText && c.ReportFormat != constants.EncodingJSON {
+		return trace.BadParameter("unsupported report format %q, expected one of %v",
+			c.ReportFormat, []constants.Format{constants.EncodingText, constants.EncodingJSON})
+	}
+	return nil
+}
+
+// ScanImages scans the specified images for vulnerabilities, writes the
+// report and returns an error if vulnerabilities with the configured
+// severity have been found
+func (b *Builder) ScanImages(ctx context.Context, images []string) error {
+	images = teleutils.Deduplicate(images)
+	sort.Strings(images)
+	var report ScanReport
+	for _, image := range images {
+		b.PrintSubStep("Scanning %v", image)
+		imageReport, err := b.Scan.Scanner.Scan(ctx, image)
+		if err != nil {
+			return trace.Wrap(err, "failed to scan image %v", image)
+		}
+		b.PrintSubStep("Found %v in %v", imageReport.Summary(), image)
+		report.Images = append(report.Images, *imageReport)
+	}
+	if b.Scan.ReportPath != "" {
+		f, err := os.OpenFile(b.Scan.ReportPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, defaults.SharedReadMask)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		defer f.Close()
+		if err := WriteScanReport(f, report, b.Scan.ReportFormat); err != nil {
+			return trace.Wrap(err)
+		}
+		b.PrintSubStep("Saved vulnerability report to %v", b.Scan.ReportPath)
+	}
+	if b.Scan.FailSeverity == SeverityNone {
+		return nil
+	}
+	if count := report.CountAtLeast(b.Scan.FailSeverity); count != 0 {
+		return trace.CompareFailed("found %v vulnerabilities with severity %v or higher "+
+			"in application images", count, b.Scan.FailSeverity)
+	}
+	return nil
+}
+
+// NewTrivyScanner returns a scanner that uses the trivy binary
+// at the specified path
+func NewTrivyScanner(path string) Scanner {
+	if path == "" {
+		path = defaults.ImageScannerPath
+	}
+	return &trivyScanner{path: path, runner: utils.Runner}
+}
+
+type trivyScanner struct {
+	// path is the path to the trivy binary
+	path string
+	// runner executes scanner commands
+	runner utils.CommandRunner
+}
+
+// Scan scans the specified image in the local docker
+func (s *trivyScanner) Scan(ctx context.Context, image string) (*ImageReport, error) {
+	var out bytes.Buffer
+	err := s.runner.RunStream(ctx, &out, s.path, "image",
+		"--quiet", "--no-progress", "--format", "json", image)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to run %v", s.path)
+	}
+	vulnerabilities, err := parseTrivyReport(out.Bytes())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &ImageReport{Image: image, Vulnerabilities: vulnerabilities}, nil
+}
+
+// parseTrivyReport parses vulnerabilities from the trivy JSON report.
+// Both the list of results of earlier versions and the report object
+// of later versions are accepted
+func parseTrivyReport(data []byte) (vulnerabilities []Vulnerability, err error) {
+	var results []trivyResult
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		err = json.Unmarshal(data, &results)
+	} else if len(data) != 0 {
+		var report trivyReport
+		err = json.Unmarshal(data, &report)
+		results = report.Results
+	}
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to parse scanner report")
+	}
+	for _, result := range results {
+		for _, v := range result.Vulnerabilities {
+			vulnerabilities = append(vulnerabilities, Vulnerability{
+				ID:               v.VulnerabilityID,
+				Package:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				Severity:         v.Severity,
+				Title:            v.Title,
+			})
+		}
+	}
+	return vulnerabilities, nil
+}
+
+type trivyReport struct {
+	Results []trivyResult `json:"Results"`
+}
+
+type trivyResult struct {
+	Target          string               `json:"Target"`
+	Vulnerabilities []trivyVulnerability `json:"Vulnerabilities"`
+}
+
+type trivyVulnerability struct {
+	VulnerabilityID  string   `json:"VulnerabilityID"`
+	PkgName          string   `json:"PkgName"`
+	InstalledVersion string   `json:"InstalledVersion"`
+	FixedVersion     string   `json:"FixedVersion"`
+	Severity         Severity `json:"Severity"`
+	Title            string   `json:"Title"`
+}
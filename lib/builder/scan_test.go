@@ -0,0 +1,144 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	check "gopkg.in/check.v1"
+)
+
+type ScanSuite struct{}
+
+var _ = check.Suite(&ScanSuite{})
+
+func (s *ScanSuite) TestParsesTrivyReports(c *check.C) {
+	for _, report := range []string{trivyReportList, trivyReportObject} {
+		vulnerabilities, err := parseTrivyReport([]byte(report))
+		c.Assert(err, check.IsNil)
+		c.Assert(vulnerabilities, check.DeepEquals, []Vulnerability{
+			{
+				ID:               "CVE-2019-1234",
+				Package:          "openssl",
+				InstalledVersion: "1.1.1a",
+				FixedVersion:     "1.1.1b",
+				Severity:         SeverityHigh,
+				Title:            "openssl: buffer overflow",
+			},
+			{
+				ID:               "CVE-2019-5678",
+				Package:          "zlib",
+				InstalledVersion: "1.2.11",
+				Severity:         SeverityUnknown,
+			},
+		})
+	}
+}
+
+func (s *ScanSuite) TestFailsBuildAboveSeverityThreshold(c *check.C) {
+	runner := utils.CommandRunnerFunc(func(ctx context.Context, w io.Writer, args ...string) error {
+		c.Assert(args[:2], check.DeepEquals, []string{"trivy", "image"})
+		if args[len(args)-1] == "nginx:1.14" {
+			_, err := io.WriteString(w, trivyReportList)
+			return err
+		}
+		_, err := io.WriteString(w, "null")
+		return err
+	})
+	reportPath := filepath.Join(c.MkDir(), "report.json")
+	b := &Builder{Config: Config{
+		Progress: utils.NewNopProgress(),
+		Scan: &ScanConfig{
+			Scanner:      &trivyScanner{path: "trivy", runner: runner},
+			ReportPath:   reportPath,
+			ReportFormat: constants.EncodingJSON,
+			FailSeverity: SeverityCritical,
+		},
+	}}
+
+	err := b.ScanImages(context.TODO(), []string{"nginx:1.14", "busybox:1.30", "nginx:1.14"})
+	c.Assert(err, check.IsNil)
+	data, err := ioutil.ReadFile(reportPath)
+	c.Assert(err, check.IsNil)
+	var report ScanReport
+	c.Assert(json.Unmarshal(data, &report), check.IsNil)
+	c.Assert(len(report.Images), check.Equals, 2)
+	c.Assert(report.Images[0].Image, check.Equals, "busybox:1.30")
+	c.Assert(report.Images[1].Summary(), check.Equals, "0 critical, 1 high, 0 medium, 0 low, 1 unknown")
+
+	b.Scan.FailSeverity = SeverityHigh
+	err = b.ScanImages(context.TODO(), []string{"nginx:1.14"})
+	c.Assert(trace.IsCompareFailed(err), check.Equals, true, check.Commentf("%v", err))
+	c.Assert(strings.Contains(err.Error(), "found 1 vulnerabilities with severity HIGH"), check.Equals, true)
+}
+
+const trivyReportList = `[
+  {
+    "Target": "nginx:1.14 (debian 9.8)",
+    "Vulnerabilities": [
+      {
+        "VulnerabilityID": "CVE-2019-1234",
+        "PkgName": "openssl",
+        "InstalledVersion": "1.1.1a",
+        "FixedVersion": "1.1.1b",
+        "Severity": "HIGH",
+        "Title": "openssl: buffer overflow"
+      },
+      {
+        "VulnerabilityID": "CVE-2019-5678",
+        "PkgName": "zlib",
+        "InstalledVersion": "1.2.11",
+        "Severity": "NEGLIGIBLE"
+      }
+    ]
+  }
+]`
+
+const trivyReportObject = `{
+  "SchemaVersion": 2,
+  "ArtifactName": "nginx:1.14",
+  "Results": [
+    {
+      "Target": "nginx:1.14 (debian 9.8)",
+      "Vulnerabilities": [
+        {
+          "VulnerabilityID": "CVE-2019-1234",
+          "PkgName": "openssl",
+          "InstalledVersion": "1.1.1a",
+          "FixedVersion": "1.1.1b",
+          "Severity": "HIGH",
+          "Title": "openssl: buffer overflow"
+        },
+        {
+          "VulnerabilityID": "CVE-2019-5678",
+          "PkgName": "zlib",
+          "InstalledVersion": "1.2.11",
+          "Severity": "NEGLIGIBLE"
+        }
+      ]
+    }
+  ]
+}`
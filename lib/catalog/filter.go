@@ -0,0 +1,89 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"path"
+
+	"github.com/Masterminds/semver"
+	"github.com/gravitational/trace"
+)
+
+// Filter restricts a list of application and cluster images.
+//
+// Empty fields do not restrict the list.
+type Filter struct {
+	// Repository is the image repository.
+	Repository string
+	// Name is the image name or a shell pattern, e.g. "kube*".
+	Name string
+	// Version is the image version constraint, e.g. ">= 5.5, < 6.0".
+	Version string
+	// Labels is the labels the image must have.
+	Labels map[string]string
+}
+
+// Check validates the filter.
+func (f Filter) Check() error {
+	if f.Name != "" {
+		if _, err := path.Match(f.Name, ""); err != nil {
+			return trace.BadParameter("invalid name pattern %q: %v", f.Name, err)
+		}
+	}
+	if f.Version != "" {
+		if _, err := semver.NewConstraint(f.Version); err != nil {
+			return trace.BadParameter("invalid version constraint %q: %v", f.Version, err)
+		}
+	}
+	return nil
+}
+
+// Match returns true if the provided item matches the filter.
+func (f Filter) Match(item ListItem) (bool, error) {
+	if f.Repository != "" && f.Repository != item.GetRepository() {
+		return false, nil
+	}
+	if f.Name != "" {
+		match, err := path.Match(f.Name, item.GetName())
+		if err != nil {
+			return false, trace.BadParameter("invalid name pattern %q: %v", f.Name, err)
+		}
+		if !match {
+			return false, nil
+		}
+	}
+	for key, value := range f.Labels {
+		if itemValue, ok := item.GetLabels()[key]; !ok || itemValue != value {
+			return false, nil
+		}
+	}
+	if f.Version != "" {
+		constraint, err := semver.NewConstraint(f.Version)
+		if err != nil {
+			return false, trace.BadParameter("invalid version constraint %q: %v", f.Version, err)
+		}
+		version := item.GetVersion()
+		itemVersion, err := semver.NewVersion(version.String())
+		if err != nil {
+			return false, trace.Wrap(err)
+		}
+		if !constraint.Check(itemVersion) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
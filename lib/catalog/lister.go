@@ -27,8 +27,10 @@ import (
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/hub"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
 
 	"github.com/coreos/go-semver/semver"
 	"github.com/ghodss/yaml"
@@ -53,6 +55,10 @@ type ListItem interface {
 	GetCreated() time.Time
 	// GetDescription returns the image description.
 	GetDescription() string
+	// GetRepository returns the image repository.
+	GetRepository() string
+	// GetLabels returns the image labels.
+	GetLabels() map[string]string
 }
 
 // listItem implements ListItem interface.
@@ -67,6 +73,10 @@ type listItem struct {
 	Type string `json:"type"`
 	// Description is the image description.
 	Description string `json:"description"`
+	// Repository is the image repository.
+	Repository string `json:"repository"`
+	// Labels is the image labels.
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // NewListItemFromHubApp makes a list item from the hub application item.
@@ -81,6 +91,8 @@ func NewListItemFromHubApp(app hub.App) (*listItem, error) {
 		Created:     app.Created,
 		Type:        app.Type,
 		Description: app.Description,
+		Repository:  repository(app.Repository),
+		Labels:      app.Labels,
 	}, nil
 }
 
@@ -96,6 +108,34 @@ func NewListItemFromApp(app app.Application) (*listItem, error) {
 		Created:     app.PackageEnvelope.Created,
 		Type:        app.Manifest.DescribeKind(),
 		Description: app.Manifest.Metadata.Description,
+		Repository:  repository(app.Package.Repository),
+		Labels:      app.Manifest.Metadata.Labels,
+	}, nil
+}
+
+// NewListItemFromCatalogApp makes a list item from the application
+// published to the Ops Center application catalog.
+//
+// The release channel is reported as the "channel" label.
+func NewListItemFromCatalogApp(app app.CatalogApp) (*listItem, error) {
+	semver, err := semver.NewVersion(app.Version)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	labels := map[string]string{ChannelLabel: app.Channel}
+	for key, value := range app.Labels {
+		if key != ChannelLabel {
+			labels[key] = value
+		}
+	}
+	return &listItem{
+		Name:        convertName(app.Name),
+		Version:     *semver,
+		Created:     app.Published,
+		Type:        app.Kind,
+		Description: app.Description,
+		Repository:  repository(app.Repository),
+		Labels:      labels,
 	}, nil
 }
 
@@ -114,6 +154,12 @@ func (i listItem) GetCreated() time.Time { return i.Created }
 // GetDescription returns the image description.
 func (i listItem) GetDescription() string { return i.Description }
 
+// GetRepository returns the image repository.
+func (i listItem) GetRepository() string { return i.Repository }
+
+// GetLabels returns the image labels.
+func (i listItem) GetLabels() map[string]string { return i.Labels }
+
 // ListItems is a collection of application and cluster images.
 type ListItems []ListItem
 
@@ -140,6 +186,20 @@ func (l ListItems) Latest() (result ListItems, err error) {
 	return result, nil
 }
 
+// Filter returns a list of items from this list that match the filter.
+func (l ListItems) Filter(filter Filter) (result ListItems, err error) {
+	for _, item := range l {
+		match, err := filter.Match(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if match {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
 // Len implements sort.Interface.
 func (l ListItems) Len() int {
 	return len(l)
@@ -173,9 +233,15 @@ type hubLister struct {
 	hub hub.Hub
 }
 
-// NewLister returns a lister with S3 hub backend.
+// NewLister returns a lister with the default S3 hub backend.
 func NewLister() (*hubLister, error) {
-	hub, err := hub.New(hub.Config{})
+	return NewListerFor(hub.Config{})
+}
+
+// NewListerFor returns a lister with the S3 hub backend
+// with the specified configuration.
+func NewListerFor(config hub.Config) (*hubLister, error) {
+	hub, err := hub.New(config)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -198,25 +264,81 @@ func (l *hubLister) List(all bool) (result ListItems, err error) {
 	return result, nil
 }
 
+type catalogLister struct {
+	catalog app.Catalog
+	channel string
+}
+
+// NewCatalogLister returns a lister for the applications published to
+// the application catalog of an Ops Center.
+//
+// If channel is empty, only the stable channel is listed unless all
+// versions are requested.
+func NewCatalogLister(catalog app.Catalog, channel string) *catalogLister {
+	return &catalogLister{catalog: catalog, channel: channel}
+}
+
+// List returns application and cluster images published to the catalog.
+func (l *catalogLister) List(all bool) (result ListItems, err error) {
+	channel := l.channel
+	if channel == "" && !all {
+		channel = storage.CatalogChannelStable
+	}
+	apps, err := l.catalog.SearchCatalog(app.SearchCatalogRequest{
+		Channel: channel,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, app := range apps {
+		i, err := NewListItemFromCatalogApp(app)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+// ChannelLabel is the label with the release channel of a catalog application.
+const ChannelLabel = "channel"
+
+// ListRequest describes a request to display application and cluster images.
+type ListRequest struct {
+	// All displays all available versions instead of the latest stable ones.
+	All bool
+	// Quiet displays only the list itself.
+	Quiet bool
+	// Format is the output format.
+	Format constants.Format
+	// Filter restricts the displayed images.
+	Filter Filter
+}
+
 // List uses the provided lister to obtain a list of application and
-// cluster images and displays them in the specified format.
-func List(lister Lister, all bool, format constants.Format) error {
-	items, err := lister.List(all)
+// cluster images matching the request filter and displays them in the
+// requested format.
+func List(lister Lister, req ListRequest) error {
+	items, err := lister.List(req.All)
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	if !all {
+	items, err = items.Filter(req.Filter)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !req.All {
 		items, err = items.Latest()
 		if err != nil {
 			return trace.Wrap(err)
 		}
 	}
 	sort.Sort(items)
-	switch format {
+	switch req.Format {
 	case constants.EncodingText:
 		w := new(tabwriter.Writer)
 		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
-		if !all {
+		if !req.All && !req.Quiet {
 			fmt.Printf("Displaying latest stable versions of images. Use --all flag to show all.\n\n")
 		}
 		fmt.Fprintf(w, "Name:Version\tImage Type\tCreated (UTC)\tDescription\n")
@@ -243,11 +365,20 @@ func List(lister Lister, all bool, format constants.Format) error {
 		fmt.Println(string(bytes))
 	default:
 		return trace.BadParameter("unknown output format %q, supported are: %v",
-			format, constants.OutputFormats)
+			req.Format, constants.OutputFormats)
 	}
 	return nil
 }
 
+// repository returns the provided image repository or the default
+// one if it's not set.
+func repository(repository string) string {
+	if repository == "" {
+		return defaults.SystemAccountOrg
+	}
+	return repository
+}
+
 func convertName(name string) string {
 	if name == constants.LegacyBaseImageName {
 		name = constants.BaseImageName
@@ -18,9 +18,15 @@ package catalog
 
 import (
 	"sort"
+	"time"
 
-	"github.com/coreos/go-semver/semver"
+	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/gravitational/trace"
 
 	check "gopkg.in/check.v1"
 )
@@ -65,6 +71,128 @@ func (s *listerSuite) TestLatestAndSorting(c *check.C) {
 	})
 }
 
+func (s *listerSuite) TestFilter(c *check.C) {
+	items := ListItems{
+		listItem{Name: "alpine", Version: v("1.0.0"), Repository: "gravitational.io", Labels: map[string]string{"channel": "stable"}},
+		listItem{Name: "alpine", Version: v("2.0.0"), Repository: "gravitational.io", Labels: map[string]string{"channel": "beta"}},
+		listItem{Name: "kafka", Version: v("1.5.0"), Repository: "example.com"},
+		listItem{Name: "kubernetes", Version: v("5.5.1"), Repository: "gravitational.io"},
+		listItem{Name: "kubernetes", Version: v("6.0.0"), Repository: "gravitational.io"},
+	}
+	testCases := []struct {
+		filter   Filter
+		expected ListItems
+		comment  string
+	}{
+		{
+			filter:   Filter{},
+			expected: items,
+			comment:  "empty filter matches all items",
+		},
+		{
+			filter:   Filter{Repository: "example.com"},
+			expected: ListItems{items[2]},
+			comment:  "filter by repository",
+		},
+		{
+			filter:   Filter{Name: "k*"},
+			expected: ListItems{items[2], items[3], items[4]},
+			comment:  "filter by name pattern",
+		},
+		{
+			filter:   Filter{Name: "kubernetes", Version: ">= 5.5, < 6.0"},
+			expected: ListItems{items[3]},
+			comment:  "filter by name and version constraint",
+		},
+		{
+			filter:   Filter{Labels: map[string]string{"channel": "stable"}},
+			expected: ListItems{items[0]},
+			comment:  "filter by labels",
+		},
+	}
+	for _, tc := range testCases {
+		comment := check.Commentf(tc.comment)
+		c.Assert(tc.filter.Check(), check.IsNil, comment)
+		filtered, err := items.Filter(tc.filter)
+		c.Assert(err, check.IsNil, comment)
+		c.Assert(filtered, compare.DeepEquals, tc.expected, comment)
+	}
+	c.Assert(Filter{Version: "not a version"}.Check(), check.NotNil)
+}
+
+func (s *listerSuite) TestCatalogLister(c *check.C) {
+	published := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	catalog := &testCatalog{apps: []app.CatalogApp{
+		{
+			CatalogEntry: storage.CatalogEntry{
+				Repository: "example.com",
+				Name:       "alpine",
+				Version:    "1.0.0",
+				Channel:    storage.CatalogChannelStable,
+				Published:  published,
+			},
+			Kind:        "Application",
+			Description: "Alpine",
+			Labels:      map[string]string{"tier": "web", "channel": "ignored"},
+		},
+		{
+			CatalogEntry: storage.CatalogEntry{
+				Repository: "example.com",
+				Name:       "alpine",
+				Version:    "2.0.0-beta.1",
+				Channel:    storage.CatalogChannelBeta,
+				Published:  published,
+			},
+			Kind: "Application",
+		},
+	}}
+	items, err := NewCatalogLister(catalog, "").List(false)
+	c.Assert(err, check.IsNil)
+	c.Assert(catalog.requests, compare.DeepEquals, []app.SearchCatalogRequest{
+		{Channel: storage.CatalogChannelStable},
+	})
+	c.Assert(items, compare.DeepEquals, ListItems{
+		&listItem{
+			Name:        "alpine",
+			Version:     v("1.0.0"),
+			Created:     published,
+			Type:        "Application",
+			Description: "Alpine",
+			Repository:  "example.com",
+			Labels:      map[string]string{"channel": "stable", "tier": "web"},
+		},
+	})
+
+	items, err = NewCatalogLister(catalog, "").List(true)
+	c.Assert(err, check.IsNil)
+	c.Assert(catalog.requests[1], compare.DeepEquals, app.SearchCatalogRequest{})
+	c.Assert(items, check.HasLen, 2)
+	c.Assert(items[1].GetLabels(), compare.DeepEquals, map[string]string{"channel": "beta"})
+}
+
+type testCatalog struct {
+	apps     []app.CatalogApp
+	requests []app.SearchCatalogRequest
+}
+
+func (r *testCatalog) PublishApp(app.PublishAppRequest) (*storage.CatalogEntry, error) {
+	return nil, trace.NotImplemented("not implemented")
+}
+
+func (r *testCatalog) UnpublishApp(loc.Locator) error {
+	return trace.NotImplemented("not implemented")
+}
+
+func (r *testCatalog) SearchCatalog(req app.SearchCatalogRequest) (apps []app.CatalogApp, err error) {
+	r.requests = append(r.requests, req)
+	for _, app := range r.apps {
+		if req.Channel == "" || app.Channel == req.Channel {
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
+}
+
 func v(ver string) semver.Version {
 	return *semver.New(ver)
 }
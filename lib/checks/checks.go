@@ -64,34 +64,6 @@ func New(remote Remote, servers []Server, manifest schema.Manifest, requirements
 	}, nil
 }
 
-// ValidateManifest verifies the specified manifest against the host environment.
-// Returns list of failed health probes.
-func ValidateManifest(
-	manifest schema.Manifest,
-	profile schema.NodeProfile,
-	dockerConfig storage.DockerConfig,
-	stateDir string,
-) (failedProbes []*agentpb.Probe, err error) {
-	var errors []error
-	failed, err := schema.ValidateRequirements(profile.Requirements, stateDir)
-	if err != nil {
-		errors = append(errors, trace.Wrap(err,
-			"error validating profile requirements, see syslog for details"))
-	}
-	failedProbes = append(failedProbes, failed...)
-
-	dockerSchema := schema.Docker{StorageDriver: dockerConfig.StorageDriver}
-	failed, err = schema.ValidateDocker(dockerSchema, stateDir)
-	if err != nil {
-		errors = append(errors, trace.Wrap(err,
-			"error validating docker requirements, see syslog for details"))
-	}
-	failedProbes = append(failedProbes, failed...)
-
-	failedProbes = append(failedProbes, schema.ValidateKubelet(profile, manifest)...)
-	return failedProbes, trace.NewAggregate(errors...)
-}
-
 // RunBasicChecks executes a set of additional health checks.
 // Returns list of failed health probes.
 func RunBasicChecks(ctx context.Context, options *validationpb.ValidateOptions) (failed []*agentpb.Probe) {
@@ -119,8 +91,13 @@ type LocalChecksRequest struct {
 	Options *validationpb.ValidateOptions
 	// Docker specifies Docker configuration overrides (if any)
 	Docker storage.DockerConfig
+	// Subnets specifies the pod and service subnets of the cluster
+	Subnets storage.Subnets
 	// AutoFix when set to true attempts to fix some common problems
 	AutoFix bool
+	// Checks optionally lists names of the checks to run.
+	// If unspecified, all registered and custom checks are run
+	Checks []string
 	// Progress is used to report information about auto-fixed problems
 	utils.Progress
 }
@@ -141,12 +118,16 @@ func (r *LocalChecksRequest) CheckAndSetDefaults() error {
 
 // LocalChecksResult describes the outcome of local checks execution
 type LocalChecksResult struct {
+	// Checks lists outcomes of individual named checks
+	Checks []CheckResult `json:"checks,omitempty"`
 	// Failed is a list of failed probes
-	Failed []*agentpb.Probe
+	Failed []*agentpb.Probe `json:"failed,omitempty"`
 	// Fixed is a list of probes that failed but have been auto-fixed
-	Fixed []*agentpb.Probe
+	Fixed []*agentpb.Probe `json:"fixed,omitempty"`
 	// Fixable is a list of probes that can be attempted to auto-fix
-	Fixable []*agentpb.Probe
+	Fixable []*agentpb.Probe `json:"fixable,omitempty"`
+	// Warnings is a list of failed probes that do not fail the checks
+	Warnings []*agentpb.Probe `json:"warnings,omitempty"`
 }
 
 // GetFailed returns a list of all failed probes
@@ -178,30 +159,35 @@ func ValidateLocal(req LocalChecksRequest) (*LocalChecksResult, error) {
 
 	dockerConfig := DockerConfigFromSchemaValue(req.Manifest.SystemDocker())
 	OverrideDockerConfig(&dockerConfig, req.Docker)
-	failedProbes, err := ValidateManifest(req.Manifest, *profile, dockerConfig, stateDir)
+	results, err := RunChecks(req.Context, CheckerConfig{
+		Manifest: req.Manifest,
+		Profile:  *profile,
+		Docker:   dockerConfig,
+		StateDir: stateDir,
+		Options:  req.Options,
+		Subnets:  req.Subnets,
+	}, req.Checks...)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	failedProbes = append(failedProbes, RunBasicChecks(req.Context, req.Options)...)
+	result := &LocalChecksResult{
+		Checks:   results,
+		Warnings: WarningProbes(results),
+	}
+	failedProbes := FailedProbes(results)
 	if len(failedProbes) == 0 {
-		return &LocalChecksResult{}, nil
+		return result, nil
 	}
 
 	if !req.AutoFix {
-		failed, fixable := autofix.GetFixable(failedProbes)
-		return &LocalChecksResult{
-			Failed:  failed,
-			Fixable: fixable,
-		}, nil
+		result.Failed, result.Fixable = autofix.GetFixable(failedProbes)
+		return result, nil
 	}
 
 	// try to auto-fix some of the issues
-	fixed, unfixed := autofix.Fix(req.Context, failedProbes, req.Progress)
-	return &LocalChecksResult{
-		Failed: unfixed,
-		Fixed:  fixed,
-	}, nil
+	result.Fixed, result.Failed = autofix.Fix(req.Context, failedProbes, req.Progress)
+	return result, nil
 }
 
 // RunLocalChecks performs all preflight checks for an application that can
@@ -334,6 +320,8 @@ type Requirements struct {
 	CPU *schema.CPU
 	// RAM describes RAM requirements
 	RAM *schema.RAM
+	// GPU describes GPU requirements
+	GPU *schema.GPU
 	// OS describes OS requirements
 	OS []schema.OS
 	// Network describes network requirements
@@ -388,6 +376,11 @@ func (r *checker) Run(ctx context.Context) error {
 			errors = append(errors, err)
 		}
 
+		err = checkArch(server, r.manifest)
+		if err != nil {
+			errors = append(errors, err)
+		}
+
 		dockerConfig := r.manifest.SystemDocker()
 		if r.TestDockerDevice {
 			err = checkDockerDevice(server, dockerConfig)
@@ -709,6 +702,13 @@ func checkServerProfile(server Server, requirements Requirements) error {
 		}
 	}
 
+	if requirements.GPU != nil {
+		err := checkGPU(server.ServerInfo, *requirements.GPU)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
 	return nil
 }
 
@@ -745,6 +745,26 @@ func checkRAM(info ServerInfo, ram schema.RAM) error {
 	return nil
 }
 
+// checkGPU makes sure server's GPU count satisfies the profile
+func checkGPU(info ServerInfo, gpu schema.GPU) error {
+	if !gpu.IsRequired() {
+		return nil
+	}
+	var count int
+	for _, device := range info.GetGPUs() {
+		if device.Vendor == gpu.GetVendor() {
+			count++
+		}
+	}
+	if count < gpu.Min {
+		return trace.BadParameter("server %q has %v %v GPUs which is less than required minimum of %v",
+			info.GetHostname(), count, gpu.GetVendor(), gpu.Min)
+	}
+
+	log.Infof("Server %q passed GPU check: %v.", info.GetHostname(), count)
+	return nil
+}
+
 // checkDockerDevice makes sure the selected docker device satisfies the profile
 func checkDockerDevice(server Server, docker schema.Docker) error {
 	dockerDevice := storage.DeviceName(server.DockerDevice)
@@ -776,6 +796,18 @@ func checkDockerDevice(server Server, docker schema.Docker) error {
 	return nil
 }
 
+// checkArch makes sure the server's CPU architecture is supported by the
+// cluster image
+func checkArch(server Server, manifest schema.Manifest) error {
+	arch := server.GetOS().Arch
+	if !manifest.SupportsArch(arch) {
+		return trace.BadParameter("server %q has %v architecture which is not "+
+			"supported by the cluster image, supported architectures: %v",
+			server.ServerInfo.GetHostname(), arch, strings.Join(manifest.Architectures(), ", "))
+	}
+	return nil
+}
+
 // checkSameOS makes sure all servers have the same OS/version
 func checkSameOS(servers []Server) error {
 	osToNodes := make(map[string][]string)
@@ -865,16 +897,14 @@ func checkSystemPackages(server Server, dockerConfig schema.Docker) error {
 }
 
 func basicCheckers(options *validationpb.ValidateOptions) health.Checker {
+	checkers, _ := sysctlCheckers(CheckerConfig{})
 	return monitoring.NewCompositeChecker(
 		"local",
-		[]health.Checker{
-			monitoring.NewIPForwardChecker(),
-			monitoring.NewBridgeNetfilterChecker(),
-			monitoring.NewMayDetachMountsChecker(),
+		append(checkers,
 			monitoring.DefaultProcessChecker(),
 			defaultPortChecker(options),
 			monitoring.DefaultBootConfigParams(),
-		},
+		),
 	)
 }
 
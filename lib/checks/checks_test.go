@@ -17,13 +17,17 @@ limitations under the License.
 package checks
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/system/selinux"
 
+	"github.com/gravitational/satellite/agent/health"
+	"github.com/gravitational/satellite/agent/proto/agentpb"
 	"github.com/gravitational/trace"
 	. "gopkg.in/check.v1"
 )
@@ -53,6 +57,10 @@ func (s *ChecksSuite) SetUpSuite(c *C) {
 		},
 		Memory: storage.Memory{Total: 1000, Free: 500, ActualFree: 640},
 		NumCPU: 4,
+		GPUs: []storage.GPU{
+			{Vendor: "nvidia", Address: "0000:00:1e.0"},
+			{Vendor: "nvidia", Address: "0000:00:1f.0"},
+		},
 	})
 	s.info = ServerInfo{
 		System: sysinfo,
@@ -75,6 +83,16 @@ func (s *ChecksSuite) TestCheckRAM(c *C) {
 	c.Assert(checkRAM(s.info, notEnoughRAM), NotNil)
 }
 
+func (s *ChecksSuite) TestCheckGPU(c *C) {
+	c.Assert(checkGPU(s.info, schema.GPU{}), IsNil)
+
+	enoughGPU := schema.GPU{Min: 2}
+	c.Assert(checkGPU(s.info, enoughGPU), IsNil)
+
+	notEnoughGPU := schema.GPU{Min: 3}
+	c.Assert(checkGPU(s.info, notEnoughGPU), NotNil)
+}
+
 func (s *ChecksSuite) TestTime(c *C) {
 	server := storage.NewSystemInfo(storage.SystemSpecV2{
 		Hostname: "node-1",
@@ -156,3 +174,288 @@ func (s *ChecksSuite) TestCheckSameOS(c *C) {
 	c.Assert(checkSameOS(infos[:2]), NotNil)
 	c.Assert(checkSameOS(infos[1:]), IsNil)
 }
+
+func (s *ChecksSuite) TestCheckArch(c *C) {
+	newServer := func(arch string) Server {
+		return Server{
+			ServerInfo: ServerInfo{
+				System: storage.NewSystemInfo(storage.SystemSpecV2{
+					Hostname: "node-1",
+					OS:       storage.OSInfo{ID: "ubuntu", Version: "18.04", Arch: arch},
+				}),
+			},
+		}
+	}
+	manifest := schema.Manifest{}
+	c.Assert(checkArch(newServer(""), manifest), IsNil)
+	c.Assert(checkArch(newServer(schema.ArchAMD64), manifest), IsNil)
+	c.Assert(checkArch(newServer(schema.ArchARM64), manifest), NotNil)
+
+	manifest.SystemOptions = &schema.SystemOptions{
+		Architectures: []string{schema.ArchAMD64, schema.ArchARM64},
+	}
+	c.Assert(checkArch(newServer(schema.ArchARM64), manifest), IsNil)
+}
+
+func (s *ChecksSuite) TestRegisteredCheckers(c *C) {
+	c.Assert(Registered(), DeepEquals, []string{
+		CheckerCgroup,
+		CheckerDisk,
+		CheckerDNS,
+		CheckerGPU,
+		CheckerHost,
+		CheckerKernelModules,
+		CheckerPorts,
+		CheckerSELinux,
+		CheckerSysctl,
+		CheckerTimeSkew,
+	})
+	c.Assert(trace.IsAlreadyExists(Register(CheckerDisk, diskCheckers)), Equals, true)
+}
+
+func (s *ChecksSuite) TestChecksIPv6ForIPv6Subnets(c *C) {
+	checkers, err := sysctlCheckers(CheckerConfig{Subnets: storage.DefaultSubnets})
+	c.Assert(err, IsNil)
+	c.Assert(hasChecker(checkers, ipv6CheckerID), Equals, false)
+
+	checkers, err = sysctlCheckers(CheckerConfig{Subnets: storage.Subnets{
+		Overlay: "10.244.0.0/16,fd00:244::/56",
+		Service: "10.100.0.0/16",
+	}})
+	c.Assert(err, IsNil)
+	c.Assert(hasChecker(checkers, ipv6CheckerID), Equals, true)
+
+	var testCases = []struct {
+		params map[string]string
+		status agentpb.Probe_Type
+		detail string
+	}{
+		{
+			params: map[string]string{ipv6DisableParam: "0", ipv6ForwardingParam: "1"},
+			status: agentpb.Probe_Running,
+		},
+		{
+			params: map[string]string{ipv6DisableParam: "1", ipv6ForwardingParam: "1"},
+			status: agentpb.Probe_Failed,
+			detail: "IPv6 is disabled on the node, set net.ipv6.conf.all.disable_ipv6=0",
+		},
+		{
+			params: map[string]string{ipv6DisableParam: "0", ipv6ForwardingParam: "0"},
+			status: agentpb.Probe_Failed,
+			detail: "IPv6 forwarding is disabled on the node, set net.ipv6.conf.all.forwarding=1",
+		},
+		{
+			params: map[string]string{},
+			status: agentpb.Probe_Failed,
+			detail: "IPv6 is not available on the node, make sure the ipv6 kernel module is loaded",
+		},
+	}
+	for _, tc := range testCases {
+		params := tc.params
+		checker := ipv6Checker{readParam: func(path string) (string, error) {
+			value, ok := params[path]
+			if !ok {
+				return "", trace.NotFound("%v not found", path)
+			}
+			return value, nil
+		}}
+		var probes health.Probes
+		checker.Check(context.TODO(), &probes)
+		comment := Commentf("params %v", tc.params)
+		c.Assert(probes, HasLen, 1, comment)
+		c.Assert(probes[0].Status, Equals, tc.status, comment)
+		c.Assert(probes[0].Detail, Equals, tc.detail, comment)
+	}
+}
+
+func hasChecker(checkers []health.Checker, name string) bool {
+	for _, checker := range checkers {
+		if checker.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ChecksSuite) TestReportsSELinuxState(c *C) {
+	installed := func(string) (string, error) { return "/usr/sbin/tool", nil }
+	missing := func(name string) (string, error) { return "", trace.NotFound("%v not found", name) }
+	var testCases = []struct {
+		state    selinux.State
+		lookPath func(string) (string, error)
+		status   agentpb.Probe_Type
+		severity agentpb.Probe_Severity
+		detail   string
+	}{
+		{
+			state:    selinux.StateDisabled,
+			lookPath: missing,
+			status:   agentpb.Probe_Running,
+			detail:   "SELinux is disabled",
+		},
+		{
+			state:    selinux.StatePermissive,
+			lookPath: missing,
+			status:   agentpb.Probe_Failed,
+			severity: agentpb.Probe_Warning,
+			detail:   "SELinux is in permissive mode, policy violations will be logged but not enforced",
+		},
+		{
+			state:    selinux.StateEnforcing,
+			lookPath: installed,
+			status:   agentpb.Probe_Running,
+			detail:   "SELinux is enforcing",
+		},
+		{
+			state:    selinux.StateEnforcing,
+			lookPath: missing,
+			status:   agentpb.Probe_Failed,
+			detail:   "SELinux is in enforcing mode, install semodule, semanage, restorecon (policycoreutils) to load the SELinux policy",
+		},
+	}
+	for _, tc := range testCases {
+		checker := seLinuxChecker{
+			getState: func() (selinux.State, error) { return tc.state, nil },
+			lookPath: tc.lookPath,
+		}
+		var probes health.Probes
+		checker.Check(context.TODO(), &probes)
+		comment := Commentf("state %v", tc.state)
+		c.Assert(probes, HasLen, 1, comment)
+		c.Assert(probes[0].Status, Equals, tc.status, comment)
+		c.Assert(probes[0].Severity, Equals, tc.severity, comment)
+		c.Assert(probes[0].Detail, Equals, tc.detail, comment)
+	}
+}
+
+func (s *ChecksSuite) TestDetectsResolverConflicts(c *C) {
+	var testCases = []struct {
+		comment    string
+		servers    []string
+		processes  []string
+		severities []agentpb.Probe_Severity
+		details    []string
+	}{
+		{
+			comment:    "upstream nameservers",
+			servers:    []string{"127.0.0.53", "10.0.0.1"},
+			processes:  []string{"systemd-resolve"},
+			severities: []agentpb.Probe_Severity{agentpb.Probe_None},
+			details:    []string{""},
+		},
+		{
+			comment:    "systemd-resolved stub resolver",
+			servers:    []string{"127.0.0.53"},
+			processes:  []string{"systemd", "systemd-resolve"},
+			severities: []agentpb.Probe_Severity{agentpb.Probe_Warning},
+			details: []string{"/etc/resolv.conf only lists the systemd-resolved stub resolver, " +
+				"link it to /run/systemd/resolve/resolv.conf or set the upstream nameservers " +
+				"of the cluster DNS with the clusterdns resource"},
+		},
+		{
+			comment:    "dnsmasq",
+			servers:    []string{"127.0.0.1"},
+			processes:  []string{"dnsmasq"},
+			severities: []agentpb.Probe_Severity{agentpb.Probe_Warning, agentpb.Probe_Warning},
+			details: []string{
+				"dnsmasq is running on the node and may conflict with the cluster DNS on port 53",
+				"/etc/resolv.conf does not list any non-local nameservers, set the upstream " +
+					"nameservers of the cluster DNS with the clusterdns resource",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		servers, processes := tc.servers, tc.processes
+		checker := resolverChecker{
+			readResolvConf: func() (*storage.ResolvConf, error) {
+				return &storage.ResolvConf{Servers: servers}, nil
+			},
+			getProcesses: func() ([]string, error) { return processes, nil },
+		}
+		var probes health.Probes
+		checker.Check(context.TODO(), &probes)
+		comment := Commentf(tc.comment)
+		c.Assert(probes, HasLen, len(tc.details), comment)
+		for i, probe := range probes {
+			c.Assert(probe.Severity, Equals, tc.severities[i], comment)
+			c.Assert(probe.Detail, Equals, tc.details[i], comment)
+		}
+	}
+}
+
+func (s *ChecksSuite) TestChecksNvidiaDriver(c *C) {
+	var testCases = []struct {
+		comment string
+		version string
+		err     error
+		devices []string
+		status  agentpb.Probe_Type
+		detail  string
+	}{
+		{
+			comment: "driver loaded",
+			version: "NVRM version: NVIDIA UNIX x86_64 Kernel Module  418.67\nGCC version: gcc 4.8.5",
+			devices: []string{"/dev/nvidia0", "/dev/nvidia1"},
+			status:  agentpb.Probe_Running,
+			detail:  "2 GPU(s), NVRM version: NVIDIA UNIX x86_64 Kernel Module  418.67",
+		},
+		{
+			comment: "driver not loaded",
+			err:     trace.NotFound("no such file"),
+			status:  agentpb.Probe_Failed,
+			detail:  "NVIDIA driver is not loaded, install the NVIDIA driver on the node",
+		},
+		{
+			comment: "not enough GPUs",
+			version: "NVRM version: NVIDIA UNIX x86_64 Kernel Module  418.67",
+			devices: []string{"/dev/nvidia0"},
+			status:  agentpb.Probe_Failed,
+			detail:  "NVIDIA driver has created 1 GPU device(s), but the node profile requires at least 2",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		checker := nvidiaDriverChecker{
+			minGPUs:           2,
+			readDriverVersion: func() (string, error) { return tc.version, tc.err },
+			listDevices:       func() ([]string, error) { return tc.devices, nil },
+		}
+		var probes health.Probes
+		checker.Check(context.TODO(), &probes)
+		comment := Commentf(tc.comment)
+		c.Assert(probes, HasLen, 1, comment)
+		c.Assert(probes[0].Status, Equals, tc.status, comment)
+		c.Assert(probes[0].Detail, Equals, tc.detail, comment)
+	}
+}
+
+func (s *ChecksSuite) TestRunsCustomChecks(c *C) {
+	config := CheckerConfig{
+		Profile: schema.NodeProfile{
+			Requirements: schema.Requirements{
+				CustomChecks: []schema.CustomCheck{
+					{Name: "passing", Script: "exit 0"},
+					{Description: "failing", Script: "exit 1"},
+				},
+			},
+		},
+	}
+	results, err := RunChecks(context.TODO(), config, "passing", "custom-2")
+	c.Assert(err, IsNil)
+	c.Assert(results, HasLen, 2)
+	c.Assert(results[0].Name, Equals, "passing")
+	c.Assert(results[0].Passed, Equals, true)
+	c.Assert(results[1].Name, Equals, "custom-2")
+	c.Assert(results[1].Description, Equals, "failing")
+	c.Assert(results[1].Passed, Equals, false)
+	c.Assert(FailedProbes(results), HasLen, 1)
+
+	_, err = RunChecks(context.TODO(), config, "unknown")
+	c.Assert(trace.IsNotFound(err), Equals, true)
+
+	config.Profile.Requirements.CustomChecks = []schema.CustomCheck{
+		{Name: CheckerDisk, Script: "exit 0"},
+	}
+	_, err = RunChecks(context.TODO(), config)
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+}
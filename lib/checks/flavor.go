@@ -0,0 +1,316 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/systeminfo"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gravitational/trace"
+)
+
+// SelectFlavor selects the install flavor of the manifest that best matches
+// the hardware of the specified servers.
+//
+// A flavor matches if every server satisfies the requirements of a distinct
+// node of the flavor. Among the matching flavors, the one that needs the fewest
+// additional nodes is selected, then the one with the most demanding node
+// profiles so the available hardware is used best, then the default flavor
+func SelectFlavor(manifest schema.Manifest, servers []ServerInfo) (*FlavorSelection, error) {
+	if manifest.Installer == nil || len(manifest.Installer.Flavors.Items) == 0 {
+		return nil, trace.NotFound("application does not define any install flavors")
+	}
+	if len(servers) == 0 {
+		return nil, trace.BadParameter("no servers to select install flavor for")
+	}
+	flavors := manifest.Installer.Flavors
+	var candidates []flavorCandidate
+	reports := make([]FlavorReport, 0, len(flavors.Items))
+	for i, flavor := range flavors.Items {
+		profiles, err := matchFlavor(manifest, flavor, servers)
+		if err != nil {
+			reports = append(reports, FlavorReport{
+				Flavor: flavor.Name,
+				Reason: trace.UserMessage(err),
+			})
+			continue
+		}
+		candidates = append(candidates, flavorCandidate{
+			index:     i,
+			flavor:    flavor,
+			profiles:  profiles,
+			missing:   flavorNodeCount(flavor) - len(servers),
+			gpu:       flavorGPU(manifest, flavor),
+			cpu:       flavorCPU(manifest, flavor),
+			ram:       flavorRAM(manifest, flavor),
+			isDefault: flavor.Name == flavors.Default,
+		})
+		reports = append(reports, FlavorReport{
+			Flavor:  flavor.Name,
+			Matches: true,
+		})
+	}
+	if len(candidates) == 0 {
+		return nil, trace.NotFound("none of the install flavors matches the hardware of %v: %v",
+			formatServers(servers), formatFlavorReports(reports))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].betterThan(candidates[j])
+	})
+	selected := candidates[0]
+	for i, report := range reports {
+		if !report.Matches {
+			continue
+		}
+		if report.Flavor == selected.flavor.Name {
+			reports[i].Selected = true
+			reports[i].Reason = selected.describe(len(servers))
+		} else {
+			reports[i].Reason = fmt.Sprintf("matches, but %q is a better fit", selected.flavor.Name)
+		}
+	}
+	return &FlavorSelection{
+		Flavor:   selected.flavor,
+		Profiles: selected.profiles,
+		Reports:  reports,
+	}, nil
+}
+
+// FlavorSelection describes the install flavor selected for a set of servers
+type FlavorSelection struct {
+	// Flavor is the selected flavor
+	Flavor schema.Flavor
+	// Profiles lists the node profiles assigned to the servers
+	// in the order the servers were specified
+	Profiles []string
+	// Reports explains why each flavor was selected or rejected
+	Reports []FlavorReport
+}
+
+// FlavorReport explains why a flavor was selected or rejected
+type FlavorReport struct {
+	// Flavor is the flavor name
+	Flavor string
+	// Matches is whether the servers satisfy the flavor requirements
+	Matches bool
+	// Selected is whether the flavor has been selected
+	Selected bool
+	// Reason explains why the flavor was selected or rejected
+	Reason string
+}
+
+// String returns a textual representation of this report
+func (r FlavorReport) String() string {
+	switch {
+	case r.Selected:
+		return fmt.Sprintf("%v: selected, %v", r.Flavor, r.Reason)
+	case r.Matches:
+		return fmt.Sprintf("%v: %v", r.Flavor, r.Reason)
+	default:
+		return fmt.Sprintf("%v: rejected, %v", r.Flavor, r.Reason)
+	}
+}
+
+// CheckProfileHardware verifies that the server satisfies the CPU, RAM, GPU
+// and volume capacity requirements of the specified node profile
+func CheckProfileHardware(server ServerInfo, profile schema.NodeProfile) error {
+	if err := checkCPU(server, profile.Requirements.CPU); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := checkRAM(server, profile.Requirements.RAM); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := checkGPU(server, profile.Requirements.GPU); err != nil {
+		return trace.Wrap(err)
+	}
+	for _, volume := range profile.Requirements.Volumes {
+		if volume.Capacity == 0 || volume.Path == "" {
+			continue
+		}
+		fs, err := systeminfo.FilesystemForDir(server.System, volume.Path)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return trace.Wrap(err)
+		}
+		if fs.TotalBytes() < volume.Capacity.Bytes() {
+			return trace.BadParameter("server %q has %v disk capacity at %v which is less than required minimum of %v",
+				server.GetHostname(), humanize.Bytes(fs.TotalBytes()), volume.Path, volume.Capacity)
+		}
+	}
+	return nil
+}
+
+// matchFlavor assigns the servers to the nodes of the flavor based on their
+// hardware and returns the node profile of each server
+func matchFlavor(manifest schema.Manifest, flavor schema.Flavor, servers []ServerInfo) ([]string, error) {
+	if count := flavorNodeCount(flavor); count < len(servers) {
+		return nil, trace.BadParameter("flavor has %v node(s), but %v server(s) were discovered",
+			count, len(servers))
+	}
+	// fits maps each server to the indexes of the flavor nodes it can run as
+	fits := make([][]int, len(servers))
+	for i, server := range servers {
+		var errors []string
+		for j, node := range flavor.Nodes {
+			profile, err := manifest.NodeProfiles.ByName(node.Profile)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			if err := CheckProfileHardware(server, *profile); err != nil {
+				errors = append(errors, fmt.Sprintf("%v: %v", node.Profile, trace.UserMessage(err)))
+				continue
+			}
+			fits[i] = append(fits[i], j)
+		}
+		if len(fits[i]) == 0 {
+			return nil, trace.BadParameter("server %q does not satisfy any node profile (%v)",
+				server.GetHostname(), strings.Join(errors, "; "))
+		}
+	}
+	remaining := make([]int, len(flavor.Nodes))
+	for j, node := range flavor.Nodes {
+		remaining[j] = node.Count
+	}
+	assigned := make([]int, len(servers))
+	if !assignNodes(fits, remaining, assigned, 0) {
+		return nil, trace.BadParameter("flavor does not have enough nodes matching the hardware of %v",
+			formatServers(servers))
+	}
+	profiles := make([]string, len(servers))
+	for i, node := range assigned {
+		profiles[i] = flavor.Nodes[node].Profile
+	}
+	return profiles, nil
+}
+
+// assignNodes assigns servers starting at the specified index to the flavor
+// nodes they fit so that no node is assigned more servers than its count.
+// Returns false if no such assignment exists
+func assignNodes(fits [][]int, remaining, assigned []int, server int) bool {
+	if server == len(fits) {
+		return true
+	}
+	for _, node := range fits[server] {
+		if remaining[node] == 0 {
+			continue
+		}
+		remaining[node]--
+		assigned[server] = node
+		if assignNodes(fits, remaining, assigned, server+1) {
+			return true
+		}
+		remaining[node]++
+	}
+	return false
+}
+
+type flavorCandidate struct {
+	index     int
+	flavor    schema.Flavor
+	profiles  []string
+	missing   int
+	gpu       int
+	cpu       int
+	ram       uint64
+	isDefault bool
+}
+
+// betterThan returns true if this candidate is a better fit than other
+func (r flavorCandidate) betterThan(other flavorCandidate) bool {
+	if r.missing != other.missing {
+		return r.missing < other.missing
+	}
+	if r.gpu != other.gpu {
+		return r.gpu > other.gpu
+	}
+	if r.cpu != other.cpu {
+		return r.cpu > other.cpu
+	}
+	if r.ram != other.ram {
+		return r.ram > other.ram
+	}
+	if r.isDefault != other.isDefault {
+		return r.isDefault
+	}
+	return r.index < other.index
+}
+
+// describe explains why this candidate has been selected
+func (r flavorCandidate) describe(servers int) string {
+	reason := fmt.Sprintf("all %v discovered server(s) match its node profiles", servers)
+	if r.missing > 0 {
+		reason = fmt.Sprintf("%v, %v more node(s) should join", reason, r.missing)
+	}
+	return reason
+}
+
+func flavorNodeCount(flavor schema.Flavor) (count int) {
+	for _, node := range flavor.Nodes {
+		count += node.Count
+	}
+	return count
+}
+
+// flavorGPU returns the total number of GPUs the flavor nodes require
+func flavorGPU(manifest schema.Manifest, flavor schema.Flavor) (gpu int) {
+	for _, node := range flavor.Nodes {
+		if profile, err := manifest.NodeProfiles.ByName(node.Profile); err == nil {
+			gpu += profile.Requirements.GPU.Min * node.Count
+		}
+	}
+	return gpu
+}
+
+// flavorCPU returns the total number of CPUs the flavor nodes require
+func flavorCPU(manifest schema.Manifest, flavor schema.Flavor) (cpu int) {
+	for _, node := range flavor.Nodes {
+		if profile, err := manifest.NodeProfiles.ByName(node.Profile); err == nil {
+			cpu += profile.Requirements.CPU.Min * node.Count
+		}
+	}
+	return cpu
+}
+
+// flavorRAM returns the total amount of RAM the flavor nodes require
+func flavorRAM(manifest schema.Manifest, flavor schema.Flavor) (ram uint64) {
+	for _, node := range flavor.Nodes {
+		if profile, err := manifest.NodeProfiles.ByName(node.Profile); err == nil {
+			ram += profile.Requirements.RAM.Min.Bytes() * uint64(node.Count)
+		}
+	}
+	return ram
+}
+
+func formatServers(servers []ServerInfo) string {
+	hostnames := ServerInfos(servers).Hostnames()
+	return fmt.Sprintf("server(s) %v", strings.Join(hostnames, ", "))
+}
+
+func formatFlavorReports(reports []FlavorReport) string {
+	out := make([]string, 0, len(reports))
+	for _, report := range reports {
+		out = append(out, report.String())
+	}
+	return strings.Join(out, "; ")
+}
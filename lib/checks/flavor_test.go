@@ -0,0 +1,159 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type FlavorSuite struct {
+	manifest schema.Manifest
+}
+
+var _ = Suite(&FlavorSuite{})
+
+func (s *FlavorSuite) SetUpTest(c *C) {
+	s.manifest = schema.Manifest{
+		NodeProfiles: schema.NodeProfiles{
+			newNodeProfile("small", 2, "4GB", "/var/lib/gravity", "50GB"),
+			newNodeProfile("large", 8, "32GB", "/var/lib/gravity", "200GB"),
+		},
+		Installer: &schema.Installer{
+			Flavors: schema.Flavors{
+				Default: "one-small",
+				Items: []schema.Flavor{
+					{Name: "one-small", Nodes: []schema.FlavorNode{{Profile: "small", Count: 1}}},
+					{Name: "one-large", Nodes: []schema.FlavorNode{{Profile: "large", Count: 1}}},
+					{Name: "three-small", Nodes: []schema.FlavorNode{{Profile: "small", Count: 3}}},
+				},
+			},
+		},
+	}
+}
+
+func (s *FlavorSuite) TestSelectsMostDemandingMatchingFlavor(c *C) {
+	selection, err := SelectFlavor(s.manifest, []ServerInfo{
+		newServerInfo("node-1", 8, "32GB", "500GB"),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(selection.Flavor.Name, Equals, "one-large")
+	c.Assert(selection.Profiles, DeepEquals, []string{"large"})
+	c.Assert(selection.Reports, DeepEquals, []FlavorReport{
+		{Flavor: "one-small", Matches: true, Reason: `matches, but "one-large" is a better fit`},
+		{Flavor: "one-large", Matches: true, Selected: true, Reason: "all 1 discovered server(s) match its node profiles"},
+		{Flavor: "three-small", Matches: true, Reason: `matches, but "one-large" is a better fit`},
+	})
+}
+
+func (s *FlavorSuite) TestRejectsFlavorsWithInsufficientHardware(c *C) {
+	selection, err := SelectFlavor(s.manifest, []ServerInfo{
+		newServerInfo("node-1", 8, "32GB", "100GB"),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(selection.Flavor.Name, Equals, "one-small")
+	c.Assert(selection.Reports[1].Matches, Equals, false)
+	c.Assert(selection.Reports[1].Reason, Matches, `server "node-1" does not satisfy any node profile.*disk capacity.*`)
+}
+
+func (s *FlavorSuite) TestPrefersFlavorWithFewestMissingNodes(c *C) {
+	servers := []ServerInfo{
+		newServerInfo("node-1", 4, "8GB", "100GB"),
+		newServerInfo("node-2", 4, "8GB", "100GB"),
+	}
+	selection, err := SelectFlavor(s.manifest, servers)
+	c.Assert(err, IsNil)
+	c.Assert(selection.Flavor.Name, Equals, "three-small")
+	c.Assert(selection.Profiles, DeepEquals, []string{"small", "small"})
+	c.Assert(selection.Reports[2].Reason, Equals,
+		"all 2 discovered server(s) match its node profiles, 1 more node(s) should join")
+	c.Assert(selection.Reports[0].Matches, Equals, false)
+}
+
+func (s *FlavorSuite) TestFailsIfNoFlavorMatches(c *C) {
+	_, err := SelectFlavor(s.manifest, []ServerInfo{
+		newServerInfo("node-1", 1, "2GB", "100GB"),
+	})
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *FlavorSuite) TestAssignsServersToDistinctNodes(c *C) {
+	s.manifest.Installer.Flavors.Items = []schema.Flavor{{
+		Name: "mixed",
+		Nodes: []schema.FlavorNode{
+			{Profile: "small", Count: 1},
+			{Profile: "large", Count: 1},
+		},
+	}}
+	selection, err := SelectFlavor(s.manifest, []ServerInfo{
+		newServerInfo("node-1", 8, "32GB", "500GB"),
+		newServerInfo("node-2", 2, "4GB", "100GB"),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(selection.Profiles, DeepEquals, []string{"large", "small"})
+}
+
+func (s *FlavorSuite) TestPrefersGPUFlavorForGPUServers(c *C) {
+	gpu := newNodeProfile("gpu", 2, "4GB", "/var/lib/gravity", "50GB")
+	gpu.Requirements.GPU = schema.GPU{Min: 1}
+	s.manifest.NodeProfiles = append(s.manifest.NodeProfiles, gpu)
+	s.manifest.Installer.Flavors.Items = append(s.manifest.Installer.Flavors.Items,
+		schema.Flavor{Name: "one-gpu", Nodes: []schema.FlavorNode{{Profile: "gpu", Count: 1}}})
+
+	server := newServerInfo("node-1", 8, "32GB", "500GB")
+	selection, err := SelectFlavor(s.manifest, []ServerInfo{server})
+	c.Assert(err, IsNil)
+	c.Assert(selection.Flavor.Name, Equals, "one-large")
+	c.Assert(selection.Reports[3].Reason, Matches, `.*has 0 nvidia GPUs which is less than required minimum of 1.*`)
+
+	server.System.(*storage.SystemV2).Spec.GPUs = []storage.GPU{{Vendor: "nvidia", Address: "0000:00:1e.0"}}
+	selection, err = SelectFlavor(s.manifest, []ServerInfo{server})
+	c.Assert(err, IsNil)
+	c.Assert(selection.Flavor.Name, Equals, "one-gpu")
+}
+
+func newNodeProfile(name string, cpu int, ram, path, capacity string) schema.NodeProfile {
+	return schema.NodeProfile{
+		Name: name,
+		Requirements: schema.Requirements{
+			CPU: schema.CPU{Min: cpu},
+			RAM: schema.RAM{Min: utils.MustParseCapacity(ram)},
+			Volumes: []schema.Volume{{
+				Path:     path,
+				Capacity: utils.MustParseCapacity(capacity),
+			}},
+		},
+	}
+}
+
+func newServerInfo(hostname string, cpu uint, ram, disk string) ServerInfo {
+	return ServerInfo{
+		System: storage.NewSystemInfo(storage.SystemSpecV2{
+			Hostname:    hostname,
+			NumCPU:      cpu,
+			Memory:      storage.Memory{Total: utils.MustParseCapacity(ram).Bytes()},
+			Filesystems: []storage.Filesystem{{DirName: "/", Type: "ext4"}},
+			FilesystemStats: map[string]storage.FilesystemUsage{
+				"/": {TotalKB: utils.MustParseCapacity(disk).Bytes() / 1024},
+			},
+		}),
+	}
+}
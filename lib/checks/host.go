@@ -0,0 +1,414 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/coredns"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/system/selinux"
+	"github.com/gravitational/gravity/lib/systeminfo"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/satellite/agent/health"
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/satellite/monitoring"
+	"github.com/gravitational/trace"
+	ps "github.com/mitchellh/go-ps"
+	"golang.org/x/sys/unix"
+)
+
+func newTimeSyncChecker() health.Checker {
+	return timeSyncChecker{}
+}
+
+// Name returns name of the checker.
+// Implements health.Checker
+func (timeSyncChecker) Name() string {
+	return timeSyncCheckerID
+}
+
+// Check verifies that the kernel considers the system clock synchronized.
+// An unsynchronized clock is reported as a warning since it does not
+// necessarily mean the clock has drifted.
+// Implements health.Checker
+func (r timeSyncChecker) Check(ctx context.Context, reporter health.Reporter) {
+	var timex unix.Timex
+	state, err := unix.Adjtimex(&timex)
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			"failed to query system clock state", trace.ConvertSystemError(err)))
+		return
+	}
+	if state == timeError || timex.Status&statusUnsync != 0 {
+		probe := monitoring.NewProbeFromErr(r.Name(),
+			"system clock is not synchronized, make sure NTP is configured",
+			trace.BadParameter("system clock is not synchronized"))
+		probe.Severity = agentpb.Probe_Warning
+		reporter.Add(probe)
+		return
+	}
+	reporter.Add(monitoring.NewSuccessProbe(r.Name()))
+}
+
+// timeSyncChecker verifies that the system clock is synchronized
+type timeSyncChecker struct{}
+
+func newCgroupVersionChecker() health.Checker {
+	return cgroupVersionChecker{path: cgroupRoot}
+}
+
+// Name returns name of the checker.
+// Implements health.Checker
+func (cgroupVersionChecker) Name() string {
+	return cgroupVersionCheckerID
+}
+
+// Check verifies that the cgroup hierarchy is not mounted in unified (v2) mode.
+// Implements health.Checker
+func (r cgroupVersionChecker) Check(ctx context.Context, reporter health.Reporter) {
+	var stat unix.Statfs_t
+	err := unix.Statfs(r.path, &stat)
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			"failed to determine cgroup version", trace.ConvertSystemError(err)))
+		return
+	}
+	if stat.Type == cgroup2SuperMagic {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			"cgroup v2 (unified hierarchy) is not supported, boot the node with cgroup v1 enabled",
+			trace.BadParameter("unsupported cgroup version")))
+		return
+	}
+	reporter.Add(monitoring.NewSuccessProbe(r.Name()))
+}
+
+// cgroupVersionChecker verifies that the node uses cgroup v1
+type cgroupVersionChecker struct {
+	path string
+}
+
+func newSELinuxChecker() health.Checker {
+	return seLinuxChecker{
+		getState: selinux.GetState,
+		lookPath: exec.LookPath,
+	}
+}
+
+// Name returns name of the checker.
+// Implements health.Checker
+func (seLinuxChecker) Name() string {
+	return seLinuxCheckerID
+}
+
+// Check reports the SELinux state of the node.
+// The permissive mode is reported as a warning since policy violations
+// are only logged. In enforcing mode, the checker verifies that the tools
+// required to load the gravity policy module are installed.
+// Implements health.Checker
+func (r seLinuxChecker) Check(ctx context.Context, reporter health.Reporter) {
+	state, err := r.getState()
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			"failed to determine SELinux state", trace.Wrap(err)))
+		return
+	}
+	switch state {
+	case selinux.StatePermissive:
+		probe := monitoring.NewProbeFromErr(r.Name(),
+			"SELinux is in permissive mode, policy violations will be logged but not enforced",
+			trace.BadParameter("SELinux is in permissive mode"))
+		probe.Severity = agentpb.Probe_Warning
+		reporter.Add(probe)
+		return
+	case selinux.StateEnforcing:
+		var missing []string
+		for _, tool := range selinux.PolicyTools {
+			if _, err := r.lookPath(tool); err != nil {
+				missing = append(missing, tool)
+			}
+		}
+		if len(missing) != 0 {
+			reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+				fmt.Sprintf("SELinux is in enforcing mode, install %v (policycoreutils) "+
+					"to load the SELinux policy", strings.Join(missing, ", ")),
+				trace.NotFound("SELinux policy tools are not installed")))
+			return
+		}
+	}
+	probe := monitoring.NewSuccessProbe(r.Name())
+	probe.Detail = fmt.Sprintf("SELinux is %v", state)
+	reporter.Add(probe)
+}
+
+// seLinuxChecker reports the SELinux state of the node
+type seLinuxChecker struct {
+	// getState returns the SELinux state of the node
+	getState func() (selinux.State, error)
+	// lookPath searches for the specified executable in PATH
+	lookPath func(string) (string, error)
+}
+
+func newIPv6Checker() health.Checker {
+	return ipv6Checker{readParam: readKernelParam}
+}
+
+// Name returns name of the checker.
+// Implements health.Checker
+func (ipv6Checker) Name() string {
+	return ipv6CheckerID
+}
+
+// Check verifies that IPv6 is enabled on the node and that the node
+// forwards IPv6 packets which is required for IPv6 pod and service networks.
+// Implements health.Checker
+func (r ipv6Checker) Check(ctx context.Context, reporter health.Reporter) {
+	disabled, err := r.readParam(ipv6DisableParam)
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			"IPv6 is not available on the node, make sure the ipv6 kernel module is loaded",
+			trace.Wrap(err)))
+		return
+	}
+	if disabled == "1" {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			"IPv6 is disabled on the node, set net.ipv6.conf.all.disable_ipv6=0",
+			trace.BadParameter("IPv6 is disabled")))
+		return
+	}
+	forwarding, err := r.readParam(ipv6ForwardingParam)
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			"failed to query IPv6 forwarding state", trace.Wrap(err)))
+		return
+	}
+	if forwarding != "1" {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			"IPv6 forwarding is disabled on the node, set net.ipv6.conf.all.forwarding=1",
+			trace.BadParameter("IPv6 forwarding is disabled")))
+		return
+	}
+	reporter.Add(monitoring.NewSuccessProbe(r.Name()))
+}
+
+// ipv6Checker verifies that the node can be used with IPv6 pod and service networks
+type ipv6Checker struct {
+	// readParam returns the value of the kernel parameter at the specified path
+	readParam func(path string) (string, error)
+}
+
+func newResolverChecker() health.Checker {
+	return resolverChecker{
+		readResolvConf: func() (*storage.ResolvConf, error) {
+			return systeminfo.ResolvFromFile(resolvConfPath)
+		},
+		getProcesses: runningProcesses,
+	}
+}
+
+// Name returns name of the checker.
+// Implements health.Checker
+func (resolverChecker) Name() string {
+	return resolverCheckerID
+}
+
+// Check verifies that the resolver configuration of the node is compatible
+// with the cluster DNS.
+// A local dnsmasq can conflict with the cluster DNS on port 53, and resolv.conf
+// that only lists local nameservers, such as the systemd-resolved stub resolver,
+// leaves the cluster DNS without upstream nameservers. Both are reported as
+// warnings since the upstream nameservers can be set with the clusterdns resource.
+// Implements health.Checker
+func (r resolverChecker) Check(ctx context.Context, reporter health.Reporter) {
+	resolvConf, err := r.readResolvConf()
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			fmt.Sprintf("failed to read %v", resolvConfPath), trace.Wrap(err)))
+		return
+	}
+	processes, err := r.getProcesses()
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			"failed to obtain running process list", trace.Wrap(err)))
+		return
+	}
+	var warnings []*agentpb.Probe
+	if utils.StringInSlice(processes, dnsmasqProcess) {
+		warnings = append(warnings, monitoring.NewProbeFromErr(r.Name(),
+			"dnsmasq is running on the node and may conflict with the cluster DNS on port 53",
+			trace.AlreadyExists("dnsmasq is running")))
+	}
+	if len(coredns.UpstreamNameservers(*resolvConf)) == 0 {
+		detail := fmt.Sprintf("%v does not list any non-local nameservers, set the upstream "+
+			"nameservers of the cluster DNS with the clusterdns resource", resolvConfPath)
+		if utils.StringInSlice(processes, systemdResolvedProcess) {
+			detail = fmt.Sprintf("%v only lists the systemd-resolved stub resolver, link it to %v "+
+				"or set the upstream nameservers of the cluster DNS with the clusterdns resource",
+				resolvConfPath, systemdResolvedResolvConfPath)
+		}
+		warnings = append(warnings, monitoring.NewProbeFromErr(r.Name(), detail,
+			trace.NotFound("no upstream nameservers")))
+	}
+	for _, probe := range warnings {
+		probe.Severity = agentpb.Probe_Warning
+		reporter.Add(probe)
+	}
+	if len(warnings) == 0 {
+		reporter.Add(monitoring.NewSuccessProbe(r.Name()))
+	}
+}
+
+// resolverChecker verifies that the resolver configuration of the node
+// is compatible with the cluster DNS
+type resolverChecker struct {
+	// readResolvConf returns the resolver configuration of the node
+	readResolvConf func() (*storage.ResolvConf, error)
+	// getProcesses returns the names of the running processes
+	getProcesses func() ([]string, error)
+}
+
+func newNvidiaDriverChecker(minGPUs int) health.Checker {
+	return nvidiaDriverChecker{
+		minGPUs: minGPUs,
+		readDriverVersion: func() (string, error) {
+			return readKernelParam(nvidiaDriverVersionPath)
+		},
+		listDevices: func() ([]string, error) {
+			return filepath.Glob(nvidiaDevicesGlob)
+		},
+	}
+}
+
+// Name returns name of the checker.
+// Implements health.Checker
+func (nvidiaDriverChecker) Name() string {
+	return nvidiaDriverCheckerID
+}
+
+// Check verifies that the NVIDIA driver is loaded and has created
+// the devices for the number of GPUs the node profile requires.
+// Implements health.Checker
+func (r nvidiaDriverChecker) Check(ctx context.Context, reporter health.Reporter) {
+	version, err := r.readDriverVersion()
+	if err != nil {
+		if trace.IsNotFound(err) {
+			reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+				"NVIDIA driver is not loaded, install the NVIDIA driver on the node",
+				trace.NotFound("NVIDIA driver is not loaded")))
+			return
+		}
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			"failed to determine NVIDIA driver version", trace.Wrap(err)))
+		return
+	}
+	devices, err := r.listDevices()
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			"failed to list NVIDIA devices", trace.Wrap(err)))
+		return
+	}
+	if len(devices) < r.minGPUs {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			fmt.Sprintf("NVIDIA driver has created %v GPU device(s), but the node profile "+
+				"requires at least %v", len(devices), r.minGPUs),
+			trace.BadParameter("not enough GPUs")))
+		return
+	}
+	probe := monitoring.NewSuccessProbe(r.Name())
+	probe.Detail = fmt.Sprintf("%v GPU(s), %v", len(devices), strings.SplitN(version, "\n", 2)[0])
+	reporter.Add(probe)
+}
+
+// nvidiaDriverChecker verifies the NVIDIA driver of the node
+type nvidiaDriverChecker struct {
+	// minGPUs is the number of GPUs the node profile requires
+	minGPUs int
+	// readDriverVersion returns the version of the loaded NVIDIA driver
+	readDriverVersion func() (string, error)
+	// listDevices returns the paths of the GPU devices
+	listDevices func() ([]string, error)
+}
+
+// runningProcesses returns the names of the running processes
+func runningProcesses() (names []string, err error) {
+	processes, err := ps.Processes()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, process := range processes {
+		names = append(names, process.Executable())
+	}
+	return names, nil
+}
+
+// readKernelParam returns the value of the kernel parameter at the specified path
+func readKernelParam(path string) (string, error) {
+	value, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+const (
+	// timeSyncCheckerID is the ID of the system clock synchronization checker
+	timeSyncCheckerID = "time-sync"
+	// seLinuxCheckerID is the ID of the SELinux checker
+	seLinuxCheckerID = "selinux"
+	// cgroupVersionCheckerID is the ID of the cgroup version checker
+	cgroupVersionCheckerID = "cgroup-version"
+	// ipv6CheckerID is the ID of the IPv6 checker
+	ipv6CheckerID = "ipv6"
+	// resolverCheckerID is the ID of the resolver configuration checker
+	resolverCheckerID = "resolver"
+	// nvidiaDriverCheckerID is the ID of the NVIDIA driver checker
+	nvidiaDriverCheckerID = "nvidia-driver"
+	// nvidiaDriverVersionPath is the path to the version of the loaded NVIDIA driver
+	nvidiaDriverVersionPath = "/proc/driver/nvidia/version"
+	// nvidiaDevicesGlob matches the devices the NVIDIA driver creates for GPUs
+	nvidiaDevicesGlob = "/dev/nvidia[0-9]*"
+	// resolvConfPath is the path to the resolver configuration of the node
+	resolvConfPath = "/etc/resolv.conf"
+	// systemdResolvedResolvConfPath is the path to the resolver configuration
+	// maintained by systemd-resolved that lists the upstream nameservers
+	systemdResolvedResolvConfPath = "/run/systemd/resolve/resolv.conf"
+	// systemdResolvedProcess is the process name of systemd-resolved
+	// as truncated by the kernel to 15 characters
+	systemdResolvedProcess = "systemd-resolve"
+	// dnsmasqProcess is the process name of dnsmasq
+	dnsmasqProcess = "dnsmasq"
+	// ipv6DisableParam is the kernel parameter that disables IPv6
+	ipv6DisableParam = "/proc/sys/net/ipv6/conf/all/disable_ipv6"
+	// ipv6ForwardingParam is the kernel parameter that enables IPv6 forwarding
+	ipv6ForwardingParam = "/proc/sys/net/ipv6/conf/all/forwarding"
+	// timeError is the clock state returned by adjtimex if the clock
+	// is not synchronized
+	timeError = 5
+	// statusUnsync is the adjtimex status bit set when the clock
+	// is not synchronized
+	statusUnsync = 0x40
+	// cgroup2SuperMagic is the filesystem magic number of cgroup v2
+	cgroup2SuperMagic = 0x63677270
+	// cgroupRoot is the mount point of the cgroup hierarchy
+	cgroupRoot = "/sys/fs/cgroup"
+)
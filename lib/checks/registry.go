@@ -0,0 +1,324 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	validationpb "github.com/gravitational/gravity/lib/network/validation/proto"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/satellite/agent/health"
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/satellite/monitoring"
+	"github.com/gravitational/trace"
+)
+
+const (
+	// CheckerHost is the name of the check that verifies CPU, RAM,
+	// OS requirements and conflicting processes
+	CheckerHost = "host"
+	// CheckerDisk is the name of the check that verifies volume
+	// requirements and filesystem compatibility
+	CheckerDisk = "disk"
+	// CheckerKernelModules is the name of the check that verifies
+	// required kernel modules and kernel configuration
+	CheckerKernelModules = "kernel-modules"
+	// CheckerSysctl is the name of the check that verifies kernel parameters
+	CheckerSysctl = "sysctl"
+	// CheckerTimeSkew is the name of the check that verifies that the
+	// system clock is synchronized
+	CheckerTimeSkew = "time-skew"
+	// CheckerPorts is the name of the check that verifies port availability
+	CheckerPorts = "ports"
+	// CheckerCgroup is the name of the check that verifies the cgroup
+	// version and required cgroup mounts
+	CheckerCgroup = "cgroup"
+	// CheckerSELinux is the name of the check that reports the SELinux state
+	CheckerSELinux = "selinux"
+	// CheckerDNS is the name of the check that detects local resolvers
+	// conflicting with the cluster DNS
+	CheckerDNS = "dns"
+	// CheckerGPU is the name of the check that verifies the GPU driver
+	// and the number of GPUs required by the node profile
+	CheckerGPU = "gpu"
+)
+
+// CheckerConfig describes the environment a named checker is created for
+type CheckerConfig struct {
+	// Manifest is the application manifest to check against
+	Manifest schema.Manifest
+	// Profile is the node profile to check against
+	Profile schema.NodeProfile
+	// Docker specifies the effective Docker configuration
+	Docker storage.DockerConfig
+	// StateDir is the gravity state directory
+	StateDir string
+	// Options is additional validation options
+	Options *validationpb.ValidateOptions
+	// Subnets specifies the pod and service subnets of the cluster
+	Subnets storage.Subnets
+}
+
+// CheckerFunc creates health checkers for the specified configuration
+type CheckerFunc func(CheckerConfig) ([]health.Checker, error)
+
+// Register adds a named checker to the registry of local preflight checks.
+// Returns an error if a checker with the same name has already been registered
+func Register(name string, fn CheckerFunc) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, exists := registry.checkers[name]; exists {
+		return trace.AlreadyExists("checker %q is already registered", name)
+	}
+	registry.checkers[name] = fn
+	return nil
+}
+
+// Registered returns the sorted list of names of all registered checkers
+func Registered() (names []string) {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registeredNames()
+}
+
+func registeredNames() (names []string) {
+	for name := range registry.checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckResult describes the outcome of a single named check
+type CheckResult struct {
+	// Name is the name of the check
+	Name string `json:"name"`
+	// Description is the optional description of the check
+	Description string `json:"description,omitempty"`
+	// Passed is whether the check has passed
+	Passed bool `json:"passed"`
+	// Failed lists failed probes of the check
+	Failed []*agentpb.Probe `json:"failed,omitempty"`
+	// Warnings lists failed probes of the check that do not
+	// prevent the node from passing the checks
+	Warnings []*agentpb.Probe `json:"warnings,omitempty"`
+}
+
+// RunChecks executes the named checks with the specified configuration
+// followed by custom checks declared in the node profile.
+// If names is empty, all registered checks are executed.
+func RunChecks(ctx context.Context, config CheckerConfig, names ...string) (results []CheckResult, err error) {
+	checks, err := namedCheckers(config, names)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, check := range checks {
+		checker := monitoring.NewCompositeChecker(check.name, check.checkers)
+		var probes health.Probes
+		checker.Check(ctx, &probes)
+		result := CheckResult{
+			Name:        check.name,
+			Description: check.description,
+		}
+		for _, probe := range probes.GetFailed() {
+			if probe.Severity == agentpb.Probe_Warning {
+				result.Warnings = append(result.Warnings, probe)
+			} else {
+				result.Failed = append(result.Failed, probe)
+			}
+		}
+		result.Passed = len(result.Failed) == 0
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// FailedProbes returns all failed probes from the specified check results
+func FailedProbes(results []CheckResult) (failed []*agentpb.Probe) {
+	for _, result := range results {
+		failed = append(failed, result.Failed...)
+	}
+	return failed
+}
+
+// WarningProbes returns all warning probes from the specified check results
+func WarningProbes(results []CheckResult) (warnings []*agentpb.Probe) {
+	for _, result := range results {
+		warnings = append(warnings, result.Warnings...)
+	}
+	return warnings
+}
+
+func namedCheckers(config CheckerConfig, names []string) (checks []namedChecker, err error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	custom, err := customCheckers(config.Profile)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(names) == 0 {
+		names = append(registeredNames(), customCheckNames(custom)...)
+	}
+	for _, name := range names {
+		if fn, exists := registry.checkers[name]; exists {
+			checkers, err := fn(config)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			checks = append(checks, namedChecker{name: name, checkers: checkers})
+			continue
+		}
+		check, exists := findCustomCheck(custom, name)
+		if !exists {
+			return nil, trace.NotFound("unknown check %q, available checks: %v",
+				name, append(registeredNames(), customCheckNames(custom)...))
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// customCheckers returns checkers for the custom checks declared in the profile.
+// Custom checks without a name are named after their position in the profile
+func customCheckers(profile schema.NodeProfile) (checks []namedChecker, err error) {
+	for i, check := range profile.Requirements.CustomChecks {
+		name := check.Name
+		if name == "" {
+			name = fmt.Sprintf("custom-%v", i+1)
+		}
+		if _, exists := registry.checkers[name]; exists {
+			return nil, trace.BadParameter(
+				"custom check %q conflicts with a built-in check", name)
+		}
+		checks = append(checks, namedChecker{
+			name:        name,
+			description: check.Description,
+			checkers:    []health.Checker{check.Checker()},
+		})
+	}
+	return checks, nil
+}
+
+func customCheckNames(checks []namedChecker) (names []string) {
+	for _, check := range checks {
+		names = append(names, check.name)
+	}
+	return names
+}
+
+func findCustomCheck(checks []namedChecker, name string) (namedChecker, bool) {
+	for _, check := range checks {
+		if check.name == name {
+			return check, true
+		}
+	}
+	return namedChecker{}, false
+}
+
+type namedChecker struct {
+	name        string
+	description string
+	checkers    []health.Checker
+}
+
+func hostCheckers(config CheckerConfig) ([]health.Checker, error) {
+	return append(schema.HostCheckers(config.Profile.Requirements),
+		monitoring.DefaultProcessChecker()), nil
+}
+
+func diskCheckers(config CheckerConfig) ([]health.Checker, error) {
+	dockerSchema := schema.Docker{StorageDriver: config.Docker.StorageDriver}
+	return append(schema.VolumeCheckers(config.Profile.Requirements, config.StateDir),
+		schema.DockerFilesystemCheckers(dockerSchema, config.StateDir)...), nil
+}
+
+func kernelModuleCheckers(config CheckerConfig) ([]health.Checker, error) {
+	dockerSchema := schema.Docker{StorageDriver: config.Docker.StorageDriver}
+	checkers := []health.Checker{monitoring.DefaultBootConfigParams()}
+	checkers = append(checkers, schema.DockerKernelCheckers(dockerSchema)...)
+	return append(checkers, schema.KubeletKernelCheckers(config.Profile, config.Manifest)...), nil
+}
+
+func sysctlCheckers(config CheckerConfig) ([]health.Checker, error) {
+	checkers := []health.Checker{
+		monitoring.NewIPForwardChecker(),
+		monitoring.NewBridgeNetfilterChecker(),
+		monitoring.NewMayDetachMountsChecker(),
+	}
+	if utils.HasIPv6Subnet(config.Subnets.Overlay, config.Subnets.Service) {
+		checkers = append(checkers, newIPv6Checker())
+	}
+	return checkers, nil
+}
+
+func timeSkewCheckers(CheckerConfig) ([]health.Checker, error) {
+	return []health.Checker{newTimeSyncChecker()}, nil
+}
+
+func portCheckers(config CheckerConfig) ([]health.Checker, error) {
+	checkers, err := schema.PortCheckers(config.Profile.Requirements)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return append(checkers, defaultPortChecker(config.Options)), nil
+}
+
+func seLinuxCheckers(CheckerConfig) ([]health.Checker, error) {
+	return []health.Checker{newSELinuxChecker()}, nil
+}
+
+func dnsCheckers(CheckerConfig) ([]health.Checker, error) {
+	return []health.Checker{newResolverChecker()}, nil
+}
+
+func gpuCheckers(config CheckerConfig) ([]health.Checker, error) {
+	gpu := config.Profile.Requirements.GPU
+	if !gpu.IsRequired() {
+		return nil, nil
+	}
+	return append(schema.GPUCheckers(config.Profile.Requirements),
+		newNvidiaDriverChecker(gpu.Min)), nil
+}
+
+func cgroupCheckers(config CheckerConfig) ([]health.Checker, error) {
+	return append([]health.Checker{newCgroupVersionChecker()},
+		schema.KubeletCgroupCheckers(config.Profile, config.Manifest)...), nil
+}
+
+var registry = struct {
+	sync.RWMutex
+	checkers map[string]CheckerFunc
+}{
+	checkers: map[string]CheckerFunc{
+		CheckerHost:          hostCheckers,
+		CheckerDisk:          diskCheckers,
+		CheckerKernelModules: kernelModuleCheckers,
+		CheckerSysctl:        sysctlCheckers,
+		CheckerTimeSkew:      timeSkewCheckers,
+		CheckerPorts:         portCheckers,
+		CheckerCgroup:        cgroupCheckers,
+		CheckerSELinux:       seLinuxCheckers,
+		CheckerDNS:           dnsCheckers,
+		CheckerGPU:           gpuCheckers,
+	},
+}
@@ -185,6 +185,12 @@ const (
 	// TerraformGravityPackage specifies the package name of the gravity terraform provider
 	TerraformGravityPackage = "terraform-provider-gravity"
 
+	// SELinuxPolicyPackage is the name of the package with the SELinux policy module
+	SELinuxPolicyPackage = "selinux-policy"
+
+	// SELinuxPolicyModule is the name of the policy module file in the SELinux policy package
+	SELinuxPolicyModule = "gravity.pp"
+
 	// DevmodeEnvVar is the name of environment variable that is passed inside hook
 	// container indicating whether the OpsCenter/Site is started in dev mode
 	DevmodeEnvVar = "DEVMODE"
@@ -193,6 +199,10 @@ const (
 	// is in manual mode
 	ManualUpdateEnvVar = "MANUAL_UPDATE"
 
+	// UpdateNodeEnvVar names the environment variable that specifies the name
+	// of the Kubernetes node a node update hook is run for
+	UpdateNodeEnvVar = "UPDATE_NODE"
+
 	// ServiceUserEnvVar names the environment variable that specifies the service user ID
 	ServiceUserEnvVar = "GRAVITY_SERVICE_USER"
 
@@ -207,6 +217,16 @@ const (
 	// BlockingOperationEnvVar specifies whether to wait for operation to complete
 	BlockingOperationEnvVar = "GRAVITY_BLOCKING_OPERATION"
 
+	// HTTPProxyEnvVar names the environment variable with the proxy for HTTP requests
+	HTTPProxyEnvVar = "HTTP_PROXY"
+
+	// HTTPSProxyEnvVar names the environment variable with the proxy for HTTPS requests
+	HTTPSProxyEnvVar = "HTTPS_PROXY"
+
+	// NoProxyEnvVar names the environment variable with the comma-separated list
+	// of hosts, domains and CIDR ranges that are accessed without the proxy
+	NoProxyEnvVar = "NO_PROXY"
+
 	// DockerRegistry is a default name for private docker registry
 	DockerRegistry = "leader.telekube.local:5000"
 
@@ -254,6 +274,19 @@ const (
 
 	// RootKeyPair is a name of the K8s root certificate authority keypair
 	RootKeyPair = "root"
+	// TransitionRootKeyPair is a name of the additional root certificate authority
+	// keypair that is trusted during certificate authority rotation
+	TransitionRootKeyPair = "root-transition"
+	// PreviousRootKeyPair is a name of the replaced root certificate authority
+	// keypair kept after certificate authority rotation to be able to roll back
+	PreviousRootKeyPair = "root-previous"
+	// CertAuthorityChainKeyPair is a name of the key pair with the certificate
+	// chain of the user-provided intermediate certificate authority
+	CertAuthorityChainKeyPair = "root-chain"
+
+	// FIPSVersionMetadata is the semver build metadata that marks the
+	// base image variant with binaries built with FIPS-validated crypto
+	FIPSVersionMetadata = "fips"
 	// APIServerKeyPair is a name of the K8s apiserver key pair
 	APIServerKeyPair = "apiserver"
 	// APIServerKubeletClientKeyPair is the name of the cert for the API server to connect to kubelet
@@ -277,6 +310,9 @@ const (
 	CoreDNSKeyPair = "coredns"
 	// FrontProxyClientKeyPair is a cert/key used for accessing external APIs through aggregation layer
 	FrontProxyClientKeyPair = "front-proxy-client"
+	// DockerRegistryKeyPair is a client cert/key used for authenticating with
+	// docker registries that verify client certificates against the cluster CA
+	DockerRegistryKeyPair = "docker-registry"
 
 	// ClusterAdminGroup is a group name for Kubernetes cluster amdin
 	ClusterAdminGroup = "system:masters"
@@ -480,6 +516,9 @@ const (
 	RoleReader = "@reader"
 	// RoleOneTimeLink is a role for one-time link installation
 	RoleOneTimeLink = "@onetimelink"
+	// RolePermissions is a prefix of the roles granting
+	// permissions assigned to users
+	RolePermissions = "@permissions"
 
 	// WebSessionContext is for web sessions stored in the current context
 	WebSessionContext = "telekube.web_session.context"
@@ -527,6 +566,12 @@ const (
 	// ClusterEnvironmentMap is the name of the ConfigMap that contains cluster environment
 	ClusterEnvironmentMap = "runtimeenvironment"
 
+	// CoreDNSConfigMap is the name of the ConfigMap with the cluster DNS configuration
+	CoreDNSConfigMap = "coredns"
+
+	// CoreDNSConfigKey is the key of the cluster DNS configuration in the CoreDNSConfigMap
+	CoreDNSConfigKey = "Corefile"
+
 	// PreviousKeyValuesAnnotationKey defines the annotation field that keeps the old
 	// environment variables after the update
 	PreviousKeyValuesAnnotationKey = "previous-values"
@@ -537,6 +582,12 @@ const (
 	// SMTPSecret specifies the name of the Secret with cluster SMTP configuration
 	SMTPSecret = "smtp-configuration-update"
 
+	// HealingPolicyConfigMap specifies the name of the ConfigMap with cluster healing policy
+	HealingPolicyConfigMap = "healing-policy"
+
+	// ClusterDNSConfigMap specifies the name of the ConfigMap with cluster DNS resource
+	ClusterDNSConfigMap = "cluster-dns"
+
 	// AlertTargetConfigMap specifies the name of the ConfigMap with alert target configuration
 	AlertTargetConfigMap = "alert-target-update"
 
@@ -552,6 +603,12 @@ const (
 	// MonitoringTypeSMTP specifies the value of the component label for monitoring SMTP updates
 	MonitoringTypeSMTP = "smtp"
 
+	// AlertmanagerConfigSecret specifies the name of the Secret with Alertmanager configuration
+	AlertmanagerConfigSecret = "alertmanager-main"
+
+	// AlertmanagerConfigKey specifies the name of the key with Alertmanager configuration
+	AlertmanagerConfigKey = "alertmanager.yaml"
+
 	// ResourceSpecKey specifies the name of the key with raw resource specification
 	ResourceSpecKey = "spec"
 
@@ -681,6 +738,23 @@ const (
 	AnnotationLogo = "gravitational.io/logo"
 	// AnnotationSize contains image size in bytes.
 	AnnotationSize = "gravitational.io/size"
+	// AnnotationRepository contains the repository of the image package.
+	AnnotationRepository = "gravitational.io/repository"
+	// AnnotationLabelPrefix prefixes annotations that contain image labels,
+	// e.g. "label.gravitational.io/channel: stable".
+	AnnotationLabelPrefix = "label.gravitational.io/"
+	// AnnotationSkipSelectorCheck exempts a Service from the check that its
+	// selector matches pods of an application workload, e.g. for headless
+	// or externally-backed Services.
+	AnnotationSkipSelectorCheck = "gravitational.io/skip-selector-check"
+	// AnnotationLastAppliedConfiguration contains the configuration of
+	// a bootstrap resource as it was last applied. It is the base of the
+	// three-way merge when the resource is applied again.
+	AnnotationLastAppliedConfiguration = "gravitational.io/last-applied-configuration"
+
+	// FieldManager identifies gravity as the writer of Kubernetes resources
+	// it applies.
+	FieldManager = "gravity"
 
 	// ServiceAutoscaler is the name of the service that monitors autoscaling
 	// events and launches appropriate operations.
@@ -692,6 +766,16 @@ const (
 	//
 	// Used in audit events.
 	ServiceStatusChecker = "@statuschecker"
+	// ServiceHealingController is the name of the service that monitors
+	// cluster components and executes automated remediations.
+	//
+	// Used in audit events.
+	ServiceHealingController = "@healingcontroller"
+	// ServiceOperationScheduler is the name of the service that starts
+	// scheduled operations once they are due.
+	//
+	// Used in audit events.
+	ServiceOperationScheduler = "@operationscheduler"
 )
 
 var (
@@ -703,6 +787,10 @@ var (
 	EncodingText Format = "text"
 	// EncodingYAML is for the YAML encoding format
 	EncodingYAML Format = "yaml"
+	// EncodingDOT is for the Graphviz DOT graph format
+	EncodingDOT Format = "dot"
+	// EncodingMermaid is for the Mermaid flowchart format
+	EncodingMermaid Format = "mermaid"
 	// OutputFormats is a list of recognized output formats for gravity CLI commands
 	OutputFormats = []Format{
 		EncodingText,
@@ -711,6 +799,13 @@ var (
 	}
 )
 
+const (
+	// ImageFormatRegistry is the format of application images pushed to a docker registry
+	ImageFormatRegistry = "registry"
+	// ImageFormatOCI is the format of application images exported as an OCI image layout
+	ImageFormatOCI = "oci"
+)
+
 var (
 	// KubeLegacyVersion defines the version of kubernetes used for compatibility
 	KubeLegacyVersion = version.Info{
@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package coredns generates the configuration of the cluster DNS service
+package coredns
+
+import (
+	"bytes"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/alecthomas/template"
+	"github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+)
+
+// GenerateCorefile will generate a coredns configuration file to be used from within the cluster
+func GenerateCorefile(config Config) (string, error) {
+	var coredns bytes.Buffer
+	err := coreDNSTemplate.Execute(&coredns, config)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return coredns.String(), nil
+}
+
+// Config represents the CoreDNS configuration options to apply to our template
+type Config struct {
+	// Zones maps a DNS zone to nameservers it will be served by as provided by a user at install time
+	Zones map[string][]string
+	// Hosts  maps a hostname to an IP address it will resolve to as provided by a user at install time
+	Hosts map[string]string
+	// UpstreamNameservers is a list of nameservers to use as resolvers as detected from the system resolv.conf
+	UpstreamNameservers []string
+	// Rotate indicates whether the upstream servers should be round-robin load balanced as detected from the system
+	// resolv.conf
+	Rotate bool
+}
+
+// NewConfig returns the CoreDNS configuration for the cluster.
+//
+// Queries are forwarded to the upstream nameservers from the cluster DNS
+// resource or, if the resource does not specify any, to the non-local
+// nameservers from resolvConf. Host and zone overrides from the resource
+// take precedence over the overrides the cluster was installed with.
+// dns is nil if the cluster DNS resource has not been configured
+func NewConfig(dns storage.ClusterDNS, overrides storage.DNSOverrides, resolvConf storage.ResolvConf) Config {
+	config := Config{
+		UpstreamNameservers: UpstreamNameservers(resolvConf),
+		Rotate:              resolvConf.Rotate,
+		Hosts:               overrides.Hosts,
+		Zones:               overrides.Zones,
+	}
+	if dns == nil {
+		return config
+	}
+	if len(dns.GetUpstreams()) != 0 {
+		config.UpstreamNameservers = dns.GetUpstreams()
+		config.Rotate = dns.IsRotate()
+	}
+	config.Hosts = make(map[string]string)
+	for hostname, ip := range overrides.Hosts {
+		config.Hosts[hostname] = ip
+	}
+	for hostname, ip := range dns.GetHosts() {
+		config.Hosts[hostname] = ip
+	}
+	config.Zones = make(map[string][]string)
+	for zone, nameservers := range overrides.Zones {
+		config.Zones[zone] = nameservers
+	}
+	for zone, nameservers := range dns.GetZones() {
+		config.Zones[zone] = nameservers
+	}
+	return config
+}
+
+// UpstreamNameservers returns the nameservers from the specified resolv.conf
+// that can be used as CoreDNS upstream servers.
+//
+// Local nameservers are filtered out to avoid CoreDNS forwarding requests
+// to itself and triggering loop detection, see for more details:
+// https://github.com/coredns/coredns/tree/master/plugin/loop#troubleshooting
+func UpstreamNameservers(resolvConf storage.ResolvConf) (upstreams []string) {
+	for _, nameserver := range resolvConf.Servers {
+		if !utils.IsLocalhost(nameserver) {
+			upstreams = append(upstreams, nameserver)
+		}
+	}
+	return upstreams
+}
+
+var coreDNSTemplate = template.Must(template.New("coredns").Parse(coreDNSTemplateText))
+
+const coreDNSTemplateText = `
+.:53 {
+  reload
+  errors
+  health
+  prometheus :9153
+  cache 30
+  loop
+  reload
+  loadbalance
+  hosts { {{range $hostname, $ip := .Hosts}}
+    {{$ip}} {{$hostname}}{{end}}
+    fallthrough
+  }
+  kubernetes cluster.local in-addr.arpa ip6.arpa {
+    pods verified
+    fallthrough in-addr.arpa ip6.arpa
+  }{{range $zone, $servers := .Zones}}
+  proxy {{$zone}} {{range $server := $servers}}{{$server}} {{end}}{
+    policy sequential
+  }{{end}}
+  {{if .UpstreamNameservers}}forward . {{range $server := .UpstreamNameservers}}{{$server}} {{end}}{
+    {{if .Rotate}}policy random{{else}}policy sequential{{end}}
+    health_check 0
+  }{{end}}
+}
+`
@@ -14,28 +14,30 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package phases
+package coredns
 
 import (
 	"testing"
 
+	"github.com/gravitational/gravity/lib/storage"
+
 	"gopkg.in/check.v1"
 )
 
 // Hook up gocheck into the "go test" runner.
 func Test(t *testing.T) { check.TestingT(t) }
 
-type StartSuite struct{}
+type CoreDNSSuite struct{}
 
-var _ = check.Suite(&StartSuite{})
+var _ = check.Suite(&CoreDNSSuite{})
 
-func (*StartSuite) TestCoreDNSConf(c *check.C) {
+func (*CoreDNSSuite) TestCoreDNSConf(c *check.C) {
 	var configTable = []struct {
-		config   CorednsConfig
+		config   Config
 		expected string
 	}{
 		{
-			CorednsConfig{
+			Config{
 				Zones: map[string][]string{
 					"example.com":  []string{"1.1.1.1", "2.2.2.2"},
 					"example2.com": []string{"1.1.1.1", "2.2.2.2"},
@@ -79,7 +81,7 @@ func (*StartSuite) TestCoreDNSConf(c *check.C) {
 `,
 		},
 		{
-			CorednsConfig{
+			Config{
 				UpstreamNameservers: []string{"1.1.1.1"},
 				Rotate:              true,
 			},
@@ -117,3 +119,34 @@ func (*StartSuite) TestCoreDNSConf(c *check.C) {
 	}
 
 }
+
+func (*CoreDNSSuite) TestConfigFromClusterDNS(c *check.C) {
+	overrides := storage.DNSOverrides{
+		Hosts: map[string]string{"a.example.com": "10.0.0.1", "b.example.com": "10.0.0.2"},
+		Zones: map[string][]string{"example.com": {"10.0.0.3"}},
+	}
+	resolvConf := storage.ResolvConf{Servers: []string{"127.0.0.53", "192.168.1.1"}}
+
+	config := NewConfig(nil, overrides, resolvConf)
+	c.Assert(config, check.DeepEquals, Config{
+		UpstreamNameservers: []string{"192.168.1.1"},
+		Hosts:               overrides.Hosts,
+		Zones:               overrides.Zones,
+	})
+
+	config = NewConfig(storage.NewClusterDNS(storage.ClusterDNSSpecV1{
+		Upstreams: []string{"1.1.1.1", "8.8.8.8"},
+		Rotate:    true,
+		Hosts:     map[string]string{"b.example.com": "10.0.0.4"},
+		Zones:     map[string][]string{"corp.example.com": {"10.0.0.5"}},
+	}), overrides, resolvConf)
+	c.Assert(config, check.DeepEquals, Config{
+		UpstreamNameservers: []string{"1.1.1.1", "8.8.8.8"},
+		Rotate:              true,
+		Hosts:               map[string]string{"a.example.com": "10.0.0.1", "b.example.com": "10.0.0.4"},
+		Zones: map[string][]string{
+			"example.com":      {"10.0.0.3"},
+			"corp.example.com": {"10.0.0.5"},
+		},
+	})
+}
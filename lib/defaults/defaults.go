@@ -18,6 +18,7 @@ package defaults
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"path/filepath"
 	"time"
@@ -42,6 +43,10 @@ const (
 	// MaxUserResetTokenTTL is a maximum TTL for password reset token
 	MaxUserResetTokenTTL = 24 * time.Hour
 
+	// PackageGCProtectionWindow is the default period after import during
+	// which unreachable packages are not garbage collected
+	PackageGCProtectionWindow = 24 * time.Hour
+
 	// AgentTokenBytes is a default length in bytes of random auth token
 	// generated for agent
 	AgentTokenBytes = 32
@@ -84,12 +89,52 @@ const (
 	// EtcdRetryInterval is the retry interval for some etcd commands
 	EtcdRetryInterval = 3 * time.Second
 
+	// EtcdSnapshotTimeout is the max allowed time to save or restore etcd snapshot
+	EtcdSnapshotTimeout = 10 * time.Minute
+
+	// EtcdStatusTimeout is the max allowed time to query the status of etcd members
+	EtcdStatusTimeout = 10 * time.Second
+
+	// EtcdUpgradeVerifyTimeout is the max allowed time for etcd members to
+	// become healthy after the upgrade
+	EtcdUpgradeVerifyTimeout = 5 * time.Minute
+
+	// EtcdBackupInterval is the default interval between periodic etcd snapshots
+	EtcdBackupInterval = 24 * time.Hour
+
+	// EtcdBackupKeep is the default number of periodic etcd snapshots to keep
+	EtcdBackupKeep = 7
+
 	// InstallApplicationTimeout is the max allowed time for k8s application to install
 	InstallApplicationTimeout = 90 * time.Minute // 1.5 hours
 
 	// PhaseTimeout is the default phase execution timeout
 	PhaseTimeout = "1h"
 
+	// OperationInterruptPollInterval is how often a running operation
+	// checks whether it has been requested to pause or cancel
+	OperationInterruptPollInterval = 5 * time.Second
+
+	// PhaseRetryInitialBackoff is the default delay before the first retry
+	// of a failed phase with a retry policy
+	PhaseRetryInitialBackoff = 5 * time.Second
+
+	// PhaseRetryMaxBackoff is the default maximum delay between retries
+	// of a failed phase with a retry policy
+	PhaseRetryMaxBackoff = 1 * time.Minute
+
+	// PhaseLogEntryMaxSize is the maximum size of a single recorded
+	// operation phase log entry
+	PhaseLogEntryMaxSize = 1024 * 1024
+
+	// HealthCheckPhaseAttempts is the number of times the application
+	// health check phase is attempted during update before failing
+	HealthCheckPhaseAttempts = 3
+
+	// UpdateParallelism is the default number of nodes updated concurrently
+	// during the regular nodes phase of the update operation
+	UpdateParallelism = 1
+
 	// UpdateTimeout is the max allowed time for system update
 	UpdateTimeout = 30 * time.Minute
 
@@ -126,6 +171,13 @@ const (
 	// has been completed/or failed
 	InstallTokenTTL = time.Hour
 
+	// JoinTokenTTL is the default TTL of a scoped join token
+	JoinTokenTTL = 24 * time.Hour
+
+	// JoinTokenRevocationDelay is the time a join token remains valid after
+	// it has been used up so the nodes joining with it can complete the operation
+	JoinTokenRevocationDelay = time.Hour
+
 	// MaxOperationConcurrency defines a number of servers an operation can run on concurrently
 	MaxOperationConcurrency = 5
 
@@ -147,6 +199,13 @@ const (
 	// HookJobDeadline sets the default limit on the hook job running time
 	HookJobDeadline = 20 * time.Minute
 
+	// HookRetryInterval is the interval between attempts to run a failed hook
+	HookRetryInterval = 5 * time.Second
+
+	// UninstallFinalizeTimeout is the time to wait for the namespaces and persistent
+	// volumes of an uninstalled application to be removed
+	UninstallFinalizeTimeout = 5 * time.Minute
+
 	// CertTTL is Teleport's SSH cert default TTL
 	CertTTL = 10 * time.Hour
 
@@ -294,6 +353,10 @@ const (
 	// HelmBin is the location of helm binary inside planet
 	HelmBin = "/usr/bin/helm"
 
+	// TerraformBin is the name of the terraform binary used to provision
+	// infrastructure before installation, it is looked up in PATH
+	TerraformBin = "terraform"
+
 	// HelmScript is the location of the helm script, which the host's helm
 	// is symlinked to, inside the planet
 	HelmScript = "/usr/local/bin/helm"
@@ -405,6 +468,9 @@ const (
 	// StateRegistryDir is the name of the docker registry directory inside the planet state directory
 	StateRegistryDir = "registry"
 
+	// UploadDir is the directory with the state of resumable package uploads
+	UploadDir = "uploads"
+
 	// ResourcesFile is the default name of the file with application k8s resources
 	ResourcesFile = "resources.yaml"
 
@@ -440,6 +506,14 @@ const (
 	// SystemAccountOrg is the default name of Gravitational organization
 	SystemAccountOrg = "gravitational.io"
 
+	// ChartsRepository is the name of the package repository with the Helm
+	// charts vendored in applications
+	ChartsRepository = "charts.gravitational.io"
+
+	// VendoredChartsDir is the directory with the Helm charts vendored
+	// in application resources
+	VendoredChartsDir = "charts"
+
 	// WizardUser is a default auto-created user used in wizard mode
 	WizardUser = "wizard@gravitational.io"
 
@@ -485,6 +559,33 @@ const (
 	// DBOpenTimeout is a default timeout for opening the DB
 	DBOpenTimeout = 30 * time.Second
 
+	// BoltAutoCompactSize is the amount of free space in a BoltDB database
+	// file that triggers its compaction after deletions
+	BoltAutoCompactSize = 64 * 1024 * 1024
+
+	// AuditEventRetention is how long audit events of operator API calls are kept
+	AuditEventRetention = 30 * 24 * time.Hour
+
+	// StatusHistoryRetention is how long cluster status snapshots are kept
+	StatusHistoryRetention = 7 * 24 * time.Hour
+
+	// StatusSnapshotInterval is how often the cluster status snapshot is
+	// recorded when the cluster status does not change
+	StatusSnapshotInterval = time.Hour
+
+	// AuditWebhookTimeout is the timeout for forwarding an audit event to a webhook
+	AuditWebhookTimeout = 10 * time.Second
+
+	// WebhookDeliveryTimeout is how long the delivery of a cluster event
+	// to a webhook is retried
+	WebhookDeliveryTimeout = 5 * time.Minute
+
+	// WebhookRequestTimeout is the timeout for a single webhook request
+	WebhookRequestTimeout = 10 * time.Second
+
+	// AuditSyslogTag is the syslog tag of forwarded audit events
+	AuditSyslogTag = "gravity-audit"
+
 	// AgentRequestTimeout defines the maximum amount of time an agent is blocked on a request
 	AgentRequestTimeout = 10 * time.Second
 
@@ -522,12 +623,24 @@ const (
 
 	// LogForwardersConfigMap is the name of the config map that contains log forwarders configuration
 	LogForwardersConfigMap = "log-forwarders"
+	// LogForwarderElasticsearchIndex is the default Elasticsearch index logs are written to
+	LogForwarderElasticsearchIndex = "gravity"
+	// LogForwarderBufferSize is the default maximum size of logs, in bytes,
+	// buffered by the log collector while the remote endpoint is unavailable
+	LogForwarderBufferSize = 64 * 1000 * 1000
+	// LogForwarderFlushInterval is the default interval buffered logs are sent with
+	LogForwarderFlushInterval = 5 * time.Second
 
 	// GrafanaServiceName is the name of Grafana service
 	GrafanaServiceName = "grafana"
 	// GrafanaServicePort is the port Grafana service is listening on
 	GrafanaServicePort = 3000
 
+	// PrometheusServiceAddr is the address of Prometheus service
+	PrometheusServiceAddr = "prometheus-k8s.monitoring.svc.cluster.local"
+	// PrometheusServicePort is the API port of Prometheus service
+	PrometheusServicePort = 9090
+
 	// InfluxDBServiceAddr is the address of InfluxDB service
 	InfluxDBServiceAddr = "influxdb.monitoring.svc.cluster.local"
 	// InfluxDBServicePort is the API port of InfluxDB service
@@ -557,6 +670,10 @@ const (
 	// ManifestFileName is the name of the application manifest
 	ManifestFileName = "app.yaml"
 
+	// SignatureFileName is the name of the signature file inside a signed
+	// cluster or application image
+	SignatureFileName = "signature.json"
+
 	// RegistryDir is the name of the layers directory inside an application tarball
 	RegistryDir = "registry"
 
@@ -567,14 +684,59 @@ const (
 	// LicenseCheckInterval is how often local gravity site will check the installed license
 	LicenseCheckInterval = 1 * time.Minute
 
+	// LicenseGracePeriod is how long a cluster keeps operating normally
+	// after its license has expired
+	LicenseGracePeriod = 14 * 24 * time.Hour
+
 	// SiteStatusCheckInterval is how often local gravity site will invoke app status hook
 	SiteStatusCheckInterval = 1 * time.Minute
 
 	// OfflineCheckInterval is how often OpsCenter checks whether its sites are online/offline
 	OfflineCheckInterval = 10 * time.Second
 
+	// FleetUpgradePollInterval is how often the Ops Center polls the update
+	// operations of remote clusters upgraded as a part of a fleet upgrade
+	FleetUpgradePollInterval = 10 * time.Second
+
+	// FleetUpgradeClusterTimeout is how long the Ops Center waits for a single
+	// remote cluster to upgrade as a part of a fleet upgrade
+	FleetUpgradeClusterTimeout = 2 * time.Hour
+
+	// HealingCheckInterval is how often the cluster health controller checks
+	// the health of cluster components
+	HealingCheckInterval = 1 * time.Minute
+
+	// HealingFailureThreshold is the default number of consecutive failed health
+	// checks after which the cluster health controller attempts a remediation
+	HealingFailureThreshold = 3
+
+	// HealingCooldown is the default minimum interval between two remediations
+	// of the same kind on the same node
+	HealingCooldown = 30 * time.Minute
+
 	// RegistrySyncInterval is how often cluster images are synced with the local registry
 	RegistrySyncInterval = 20 * time.Second
+
+	// RegistryGCInterval is how often unreferenced data is removed from the local registry
+	RegistryGCInterval = 24 * time.Hour
+
+	// RegistryReplicationInterval is how often images missing in the local registry
+	// are replicated from the registries of the other master nodes
+	RegistryReplicationInterval = 5 * time.Minute
+
+	// PackageVerificationInterval is how often the checksums of the package data
+	// in the cluster package storage of each master node are verified
+	PackageVerificationInterval = 6 * time.Hour
+
+	// RegistryHealthCheckInterval is how often the cluster registry checks its storage driver
+	RegistryHealthCheckInterval = 30 * time.Second
+
+	// RegistryHealthCheckThreshold is the number of consecutive failed storage driver
+	// checks after which the cluster registry is reported unhealthy
+	RegistryHealthCheckThreshold = 3
+
+	// RegistryGCGracePeriod is the minimum age of unreferenced registry blobs to remove
+	RegistryGCGracePeriod = time.Hour
 	// AppSyncInterval is how often app images are synced with the local registry
 	AppSyncInterval = 30 * time.Second
 
@@ -630,6 +792,16 @@ const (
 	// ReportTarball is the name of the gzipped tarball with collected site report information
 	ReportTarball = "report.tar.gz"
 
+	// ReportOperations is the default number of most recent operations
+	// the cluster report includes the logs and plans of
+	ReportOperations = 20
+
+	// ReportMaxFileSize is the default maximum size of a single file in the cluster report
+	ReportMaxFileSize = "500MB"
+
+	// ReportMaxSize is the default maximum total size of the files in the cluster report
+	ReportMaxSize = "2GB"
+
 	// ServiceSubnet is a subnet dedicated to the services in cluster
 	ServiceSubnet = "10.100.0.0/16"
 	// PodSubnet is a subnet dedicated to the pods in the cluster
@@ -647,6 +819,11 @@ const (
 	// KubernetesAdvertiseIPLabel is the kubernetes node label of the advertise IP address
 	KubernetesAdvertiseIPLabel = "gravitational.io/advertise-ip"
 
+	// KubernetesGPULabel is the Kubernetes node label with the GPU vendor
+	// of the nodes whose profile requires GPUs. The GPU device plugin
+	// is scheduled on the nodes with this label
+	KubernetesGPULabel = "gravitational.io/gpu"
+
 	// RunLevelLabel is the Kubernetes node taint label representing a run-level
 	RunLevelLabel = "gravitational.io/runlevel"
 
@@ -772,6 +949,23 @@ const (
 	// DiskTransferRate is the minimum required disk speed for some default locations
 	DiskTransferRate = "10MB/s"
 
+	// ImageCacheSize is the default size limit of the tele image cache
+	ImageCacheSize = "10GB"
+
+	// ImageScannerPath is the default path of the image vulnerability scanner
+	ImageScannerPath = "trivy"
+
+	// TransferChunkSize is the size of a single chunk of a resumable upload
+	TransferChunkSize = 8 * 1024 * 1024
+	// TransferRetryAttempts is the number of times an interrupted transfer is resumed
+	TransferRetryAttempts = 10
+	// TransferRetryPeriod is the pause before resuming an interrupted transfer
+	TransferRetryPeriod = 5 * time.Second
+	// UploadTTL is how long an unfinished package upload is kept since its last update
+	UploadTTL = 24 * time.Hour
+	// PartialFileSuffix is appended to the name of a file that is being downloaded
+	PartialFileSuffix = ".partial"
+
 	// PingPongDuration is the duration of a ping-pong game agents play
 	PingPongDuration = 10 * time.Second
 	// BandwidthTestPort is the port for the bandwidth test agents do
@@ -837,6 +1031,9 @@ const (
 	EtcdLocalAddr = "https://127.0.0.1:2379"
 	// EtcdKey is the key under which gravity data is stored in etcd
 	EtcdKey = "/gravity/local"
+	// EtcdBackupServiceName is the name of the systemd unit that takes
+	// periodic etcd snapshots
+	EtcdBackupServiceName = "gravity-etcd-backup.service"
 	// EtcdKeyFilename is the etcd private key filename
 	EtcdKeyFilename = "etcd.key"
 	// EtcdCertFilename is the etcd certificate filename
@@ -846,6 +1043,8 @@ const (
 
 	// EtcdUpgradeBackupFile is the filename to store a temporary backup of the etcd database when recreating the etcd datastore
 	EtcdUpgradeBackupFile = "etcd.bak"
+	// EtcdUpgradeSnapshotFile is the filename to store etcd snapshot taken before the etcd upgrade
+	EtcdUpgradeSnapshotFile = "etcd-snapshot.db"
 
 	// EtcdPeerPort is etcd inter-cluster communication port
 	EtcdPeerPort = 2380
@@ -884,6 +1083,14 @@ const (
 	// EndpointsWaitTimeout specifies the timeout for waiting for system service endpoints
 	EndpointsWaitTimeout = 5 * time.Minute
 
+	// ReadinessCheckTimeout specifies the default timeout for an application
+	// readiness check to succeed at the end of install or upgrade
+	ReadinessCheckTimeout = 5 * time.Minute
+
+	// ReadinessCheckRequestTimeout specifies the timeout for a single request
+	// made by an application readiness check
+	ReadinessCheckRequestTimeout = 10 * time.Second
+
 	// DrainErrorTimeout specifies the timeout for the initial failures of drain operation.
 	// Drain operation might experience transient errors (e.g. api server connect failures)
 	// in which case the timeout defines the maximum time frame to retry such failed attempts.
@@ -894,6 +1101,10 @@ const (
 	// DrainTimeout defines the total drain operation timeout
 	DrainTimeout = 1 * time.Hour
 
+	// DrainEvictionTimeout defines the default amount of time to wait for pods
+	// to be evicted from a node being drained
+	DrainEvictionTimeout = 15 * time.Minute
+
 	// TerminationWaitTimeout defines an amount of time above the Kubernetes
 	// TerminationGracePeriod to wait for a pod to be terminated. Kubernetes
 	// may take some amount of time to force kill a pod, which we want to
@@ -1020,6 +1231,12 @@ var (
 	// LocalCacheDir is the location where gravity stores downloaded packages
 	LocalCacheDir = filepath.Join(LocalDataDir, "cache")
 
+	// LocalImageCacheDir is the location where tele caches exported docker images
+	LocalImageCacheDir = filepath.Join(LocalDataDir, "image-cache")
+
+	// EtcdBackupDir is the default location of periodic etcd snapshots
+	EtcdBackupDir = filepath.Join(GravityDir, BackupDir, "etcd")
+
 	// ClusterRegistryDir is the location of the cluster's Docker registry backend.
 	ClusterRegistryDir = filepath.Join(GravityDir, PlanetDir, StateRegistryDir)
 
@@ -1034,6 +1251,10 @@ var (
 	LogServiceURL = fmt.Sprintf("http://%v:%v",
 		fmt.Sprintf(ServiceAddr, LogServiceName, KubeSystemNamespace), LogServicePort)
 
+	// PrometheusServiceURL is the URL of Prometheus API running in the cluster
+	PrometheusServiceURL = fmt.Sprintf("http://%v:%v",
+		PrometheusServiceAddr, PrometheusServicePort)
+
 	// RSAPrivateKeyBits is default bits for RSA private key
 	RSAPrivateKeyBits = 4096
 
@@ -1086,6 +1307,18 @@ var (
 	DiscoveryPublishInterval = 5 * time.Second
 	// DiscoveryResyncInterval specifies the frequency to force publish cluster discovery details
 	DiscoveryResyncInterval = 10 * time.Minute
+	// AutoscaleMembershipSyncInterval specifies the frequency to check for nodes
+	// whose instances have been terminated by the auto scaling group
+	AutoscaleMembershipSyncInterval = 5 * time.Minute
+	// NodeProfileReconcileInterval specifies the frequency to check the nodes
+	// for the labels and taints of node profiles that have been removed or changed
+	NodeProfileReconcileInterval = 5 * time.Minute
+	// OperationSchedulerInterval specifies the frequency to check for
+	// scheduled operations that are due to start
+	OperationSchedulerInterval = time.Minute
+	// MaintenanceWindowDuration is how long a maintenance window
+	// scheduled operations start within stays open by default
+	MaintenanceWindowDuration = 4 * time.Hour
 
 	// CACertificateExpiry is the validity period of self-signed CA generated
 	// for clusters during installation
@@ -1093,6 +1326,9 @@ var (
 	// CertificateExpiry is the validity period of certificates generated
 	// during cluster installation (such as apiserver, etcd, kubelet, etc.)
 	CertificateExpiry = 10 * 365 * 24 * time.Hour // 10 years
+	// CertSignerTimeout is the timeout of a single request to the external
+	// certificate signer
+	CertSignerTimeout = 30 * time.Second
 
 	// GravitySystemLog defines the default location for the system log
 	GravitySystemLog = filepath.Join(SystemLogDir, GravitySystemLogFile)
@@ -1133,6 +1369,24 @@ var (
 		"hmac-sha2-256-etm@openssh.com",
 		"hmac-sha2-256",
 	}
+
+	// FIPSCipherSuites is the whitelist of FIPS-approved TLS cipher suites
+	// to enable in clusters installed in FIPS mode
+	FIPSCipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	}
+
+	// FIPSCipherSuiteNames lists FIPSCipherSuites by name in the format
+	// accepted by etcd and docker registry
+	FIPSCipherSuiteNames = []string{
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+		"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	}
 )
 
 // HookSecurityContext returns default securityContext for hook pods
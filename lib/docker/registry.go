@@ -20,11 +20,12 @@ import (
 	"context"
 	"net/http"
 
+	dockerapp "github.com/gravitational/gravity/lib/app/docker"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/users"
 
 	"github.com/docker/distribution/configuration"
-	"github.com/docker/distribution/registry/handlers"
+	"github.com/docker/distribution/health"
 
 	"github.com/gravitational/trace"
 )
@@ -35,6 +36,13 @@ type Config struct {
 	Context context.Context
 	// Users is the cluster users service.
 	Users users.Identity
+	// Storage is the registry storage driver configuration.
+	//
+	// Defaults to the filesystem driver in the cluster registry directory.
+	Storage dockerapp.StorageConfig
+	// Proxy optionally configures the registry as a pull-through cache
+	// of an upstream registry.
+	Proxy *dockerapp.ProxyConfig
 }
 
 // Check validates the registry handler configuration.
@@ -45,36 +53,89 @@ func (c Config) Check() error {
 	if c.Users == nil {
 		return trace.BadParameter("missing Users")
 	}
+	if c.Proxy != nil {
+		if err := c.Proxy.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	return nil
 }
 
-// NewRegistry returns a new HTTP handler that serves Docker registry API.
-func NewRegistry(config Config) (http.Handler, error) {
-	err := config.Check()
-	if err != nil {
+// Configuration returns the configuration of the cluster registry
+// with the specified storage.
+//
+// The filesystem storage defaults to the cluster registry directory.
+func Configuration(storage dockerapp.StorageConfig) (*configuration.Configuration, error) {
+	if err := storage.CheckAndSetDefaults(); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	app := handlers.NewApp(config.Context, &configuration.Configuration{
+	if storage.Driver == dockerapp.StorageDriverFilesystem {
+		if _, ok := storage.Parameters["rootdirectory"]; !ok {
+			parameters := map[string]interface{}{"rootdirectory": defaults.ClusterRegistryDir}
+			for name, value := range storage.Parameters {
+				parameters[name] = value
+			}
+			storage.Parameters = parameters
+		}
+	}
+	config := &configuration.Configuration{
 		Version: configuration.CurrentVersion,
 		Storage: configuration.Storage{
 			"cache": configuration.Parameters{
 				"blobdescriptor": "inmemory",
 			},
-			"filesystem": configuration.Parameters{
-				"rootdirectory": defaults.ClusterRegistryDir,
-			},
 		},
-		// Configure the registry with the access controller that uses the
-		// cluster's users service for authentication and authorization.
-		//
-		// See acl.go for details.
-		Auth: configuration.Auth{
-			// The parameters here will be passed to the access controller's
-			// constructor.
-			"gravityACL": configuration.Parameters{
-				"users": config.Users,
-			},
+	}
+	storage.Apply(config)
+	return config, nil
+}
+
+// NewRegistry returns a new HTTP handler that serves Docker registry API.
+func NewRegistry(config Config) (*Registry, error) {
+	err := config.Check()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	registryConfig, err := Configuration(config.Storage)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// Configure the registry with the access controller that uses the
+	// cluster's users service for authentication and authorization.
+	//
+	// See acl.go for details.
+	registryConfig.Auth = configuration.Auth{
+		// The parameters here will be passed to the access controller's
+		// constructor.
+		"gravityACL": configuration.Parameters{
+			"users": config.Users,
 		},
-	})
-	return app, nil
+	}
+	if config.Proxy != nil {
+		dockerapp.WithProxy(*config.Proxy)(registryConfig)
+	}
+	registryConfig.Health.StorageDriver.Enabled = true
+	registryConfig.Health.StorageDriver.Interval = defaults.RegistryHealthCheckInterval
+	registryConfig.Health.StorageDriver.Threshold = defaults.RegistryHealthCheckThreshold
+	app, handler := dockerapp.NewHandler(config.Context, registryConfig)
+	checks := health.NewRegistry()
+	app.RegisterHealthChecks(checks)
+	return &Registry{
+		Handler: handler,
+		config:  registryConfig,
+		health:  checks,
+	}, nil
+}
+
+// Registry serves Docker registry API
+type Registry struct {
+	// Handler is the registry API handler
+	http.Handler
+	config *configuration.Configuration
+	health *health.Registry
+}
+
+// Status returns the health and the storage usage of the registry
+func (r *Registry) Status(ctx context.Context) (*dockerapp.RegistryStatus, error) {
+	return dockerapp.GetStatus(ctx, r.config, r.health)
 }
@@ -291,10 +291,6 @@ func (p *Peer) getPlanBuilder(ctx operationContext) (*planBuilder, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	planetPackage, err := application.Manifest.RuntimePackageForProfile(p.Role)
-	if err != nil {
-		return nil, trace.Wrap(err)
-	}
 	adminAgent, err := ctx.Operator.GetClusterAgent(ops.ClusterAgentRequest{
 		AccountID:   ctx.Operation.AccountID,
 		ClusterName: ctx.Operation.SiteDomain,
@@ -318,12 +314,18 @@ func (p *Peer) getPlanBuilder(ctx operationContext) (*planBuilder, error) {
 		return nil, trace.NotFound("operation does not have servers: %v",
 			operation)
 	}
+	joiningNode := operation.Servers[0]
+	planetPackage, err := application.Manifest.RuntimePackageForProfileArch(
+		joiningNode.Role, joiningNode.Arch())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 	return &planBuilder{
 		Application:     *application,
 		Runtime:         *runtime,
 		TeleportPackage: *teleportPackage,
 		PlanetPackage:   *planetPackage,
-		JoiningNode:     operation.Servers[0],
+		JoiningNode:     joiningNode,
 		ClusterNodes:    storage.Servers(ctx.Cluster.ClusterState.Servers),
 		Peer:            ctx.Peer,
 		Master:          storage.Servers(ctx.Cluster.ClusterState.Servers).Masters()[0],
@@ -162,6 +162,7 @@ func (e *fsmEngine) ChangePhaseState(ctx context.Context, change fsm.StateChange
 		NewState:    change.State,
 		Error:       utils.ToRawTrace(change.Error),
 		Created:     time.Now().UTC(),
+		Attempt:     change.Attempt,
 	}
 	_, err := e.JoinBackend.CreateOperationPlanChange(planChange)
 	if err != nil {
@@ -39,6 +39,7 @@ func FSMSpec(config FSMConfig) fsm.FSMSpecFunc {
 			return installphases.NewBootstrap(p,
 				config.Operator,
 				config.Apps,
+				config.Packages,
 				config.LocalBackend,
 				remote)
 
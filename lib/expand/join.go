@@ -176,7 +176,7 @@ func formatClusterURL(addr string) string {
 
 func (p *Peer) dialSite(addr string) (*operationContext, error) {
 	targetURL := formatClusterURL(addr)
-	httpClient := httplib.GetClient(true)
+	httpClient := httplib.GetClient(true, httplib.WithoutProxy())
 	operator, err := opsclient.NewBearerClient(targetURL, p.Token, opsclient.HTTPClient(httpClient))
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -194,6 +194,10 @@ func (p *Peer) dialSite(addr string) (*operationContext, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	err = utils.CheckFIPS(cluster.ClusterState.FIPS)
+	if err != nil {
+		return nil, utils.Abort(err)
+	}
 	err = p.checkAndSetServerProfile(cluster.App)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -241,8 +245,14 @@ func (p *Peer) createExpandOperation(operator ops.Operator, cluster ops.Site) (*
 		SiteDomain:  cluster.Domain,
 		Provisioner: schema.ProvisionerOnPrem,
 		Servers:     map[string]int{p.Role: 1},
+		JoinToken:   p.Token,
 	})
 	if err != nil {
+		if trace.IsAccessDenied(err) {
+			// the join token has expired, has been used up or
+			// does not allow this node's role
+			return nil, utils.Abort(err)
+		}
 		return nil, trace.Wrap(err)
 	}
 	err = operator.SetOperationState(*key, ops.SetOperationStateRequest{
@@ -285,6 +295,10 @@ func (p *Peer) dialWizard(addr string) (*operationContext, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	err = utils.CheckFIPS(cluster.ClusterState.FIPS)
+	if err != nil {
+		return nil, utils.Abort(err)
+	}
 	err = p.checkAndSetServerProfile(cluster.App)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -331,6 +345,10 @@ func (p *Peer) checkAndSetServerProfile(app ops.Application) error {
 
 // runLocalChecks makes sure node satisfies system requirements
 func (p *Peer) runLocalChecks(cluster ops.Site, installOperation ops.SiteOperation) error {
+	var subnets storage.Subnets
+	if installOperation.InstallExpand != nil {
+		subnets = installOperation.InstallExpand.Subnets
+	}
 	return checks.RunLocalChecks(checks.LocalChecksRequest{
 		Context:  p.Context,
 		Manifest: cluster.App.Manifest,
@@ -341,6 +359,7 @@ func (p *Peer) runLocalChecks(cluster ops.Site, installOperation ops.SiteOperati
 			DnsAddrs:  cluster.DNSConfig.Addrs,
 			DnsPort:   int32(cluster.DNSConfig.Port),
 		},
+		Subnets: subnets,
 		AutoFix: true,
 	})
 }
@@ -94,7 +94,7 @@ func (p *agentStartExecutor) Execute(ctx context.Context) error {
 
 func (p *agentStartExecutor) getProxyClient(ctx context.Context) (*client.ProxyClient, error) {
 	operator, err := opsclient.NewBearerClient(p.Phase.Data.Agent.OpsCenterURL,
-		p.Phase.Data.Agent.Password, opsclient.HTTPClient(httplib.GetClient(true)))
+		p.Phase.Data.Agent.Password, opsclient.HTTPClient(httplib.GetClient(true, httplib.WithoutProxy())))
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -0,0 +1,187 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/app/resources"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ResourceLister invokes the provided function for each Kubernetes
+// resource the specified phase would create
+type ResourceLister func(storage.OperationPhase, resources.ResourceFunc) error
+
+// DryRunConfig describes the part of the operation plan to dry-run
+type DryRunConfig struct {
+	// Plan is the operation plan
+	Plan storage.OperationPlan
+	// PhaseID is the ID of the phase to dry-run, RootPhase for the whole plan
+	PhaseID string
+	// Force includes phases that have already been completed
+	Force bool
+	// Resources optionally lists Kubernetes resources created by phases
+	Resources ResourceLister
+}
+
+// CheckAndSetDefaults validates the config and sets defaults
+func (c *DryRunConfig) CheckAndSetDefaults() error {
+	if c.PhaseID == "" {
+		c.PhaseID = RootPhase
+	}
+	return nil
+}
+
+// DryRun prints the phases that would be executed for the configured phase
+// in execution order along with the nodes they would touch, the packages
+// they would install and the Kubernetes resources they would create.
+// No phase is executed and the plan is left intact
+func DryRun(w io.Writer, config DryRunConfig) error {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	phases := config.Plan.Phases
+	if config.PhaseID != RootPhase {
+		phase, err := FindPhase(&config.Plan, config.PhaseID)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		phases = []storage.OperationPhase{*phase}
+	}
+	d := dryRunner{w: w, config: config}
+	for _, phase := range phases {
+		if err := d.describePhase(phase, 0); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if d.count == 0 {
+		fmt.Fprintf(w, "No phases to execute.\n")
+	}
+	return nil
+}
+
+type dryRunner struct {
+	w      io.Writer
+	config DryRunConfig
+	// count is the number of phases that would be executed
+	count int
+}
+
+func (d *dryRunner) describePhase(phase storage.OperationPhase, indent int) error {
+	if phase.IsCompleted() && !d.config.Force {
+		return nil
+	}
+	d.count++
+	prefix := strings.Repeat("  ", indent)
+	var notes []string
+	if phase.Parallel && len(phase.Phases) != 0 {
+		notes = append(notes, "parallel")
+	}
+	if len(phase.Requires) != 0 {
+		notes = append(notes, fmt.Sprintf("requires %v", strings.Join(phase.Requires, ",")))
+	}
+	fmt.Fprintf(d.w, "%v* %v: %v", prefix, phase.ID, phase.Description)
+	if len(notes) != 0 {
+		fmt.Fprintf(d.w, " (%v)", strings.Join(notes, ", "))
+	}
+	fmt.Fprintf(d.w, "\n")
+	for _, server := range phaseServers(phase) {
+		fmt.Fprintf(d.w, "%v    node:     %v (%v)\n", prefix, server.Hostname, server.AdvertiseIP)
+	}
+	for _, pkg := range phasePackages(phase) {
+		fmt.Fprintf(d.w, "%v    package:  %v\n", prefix, pkg)
+	}
+	if d.config.Resources != nil && len(phase.Phases) == 0 {
+		err := d.config.Resources(phase, func(object runtime.Object) error {
+			resource, err := DescribeBootstrapResource(object)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			fmt.Fprintf(d.w, "%v    resource: %v\n", prefix, resource)
+			return nil
+		})
+		if err != nil {
+			return trace.Wrap(err, "failed to list resources of phase %v", phase.ID)
+		}
+	}
+	for _, subphase := range phase.Phases {
+		if err := d.describePhase(subphase, indent+1); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// phaseServers returns the nodes the specified phase would touch
+func phaseServers(phase storage.OperationPhase) (servers []storage.Server) {
+	if phase.Data == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	add := func(server *storage.Server) {
+		if server == nil || seen[server.AdvertiseIP] {
+			return
+		}
+		seen[server.AdvertiseIP] = true
+		servers = append(servers, *server)
+	}
+	add(phase.Data.Server)
+	add(phase.Data.ExecServer)
+	if phase.Data.Update != nil {
+		for i := range phase.Data.Update.Servers {
+			add(&phase.Data.Update.Servers[i].Server)
+		}
+	}
+	return servers
+}
+
+// phasePackages returns the packages the specified phase would install
+func phasePackages(phase storage.OperationPhase) (packages []loc.Locator) {
+	if phase.Data == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	add := func(pkg *loc.Locator) {
+		if pkg == nil || seen[pkg.String()] {
+			return
+		}
+		seen[pkg.String()] = true
+		packages = append(packages, *pkg)
+	}
+	add(phase.Data.Package)
+	add(phase.Data.RuntimePackage)
+	if phase.Data.Update != nil {
+		for _, server := range phase.Data.Update.Servers {
+			if server.Runtime.Update != nil {
+				add(&server.Runtime.Update.Package)
+				add(&server.Runtime.Update.ConfigPackage)
+			}
+			if server.Teleport.Update != nil {
+				add(&server.Teleport.Update.Package)
+				add(&server.Teleport.Update.NodeConfigPackage)
+			}
+		}
+	}
+	return packages
+}
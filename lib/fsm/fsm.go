@@ -20,7 +20,9 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"time"
 
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/utils"
@@ -99,6 +101,9 @@ type FSM struct {
 	preExecFn PhaseHookFn
 	// postExecFn is called after phase execution if set
 	postExecFn PhaseHookFn
+	// interruptFn is called between phases and during phase
+	// execution to check whether the operation should stop
+	interruptFn InterruptCheckFn
 }
 
 // PhaseHookFn defines the phase hook function
@@ -114,6 +119,12 @@ type Config struct {
 	Insecure bool
 	// Logger allows to override default logger
 	Logger logrus.FieldLogger
+	// Parallelism limits the number of subphases of a parallel phase
+	// executed concurrently. Zero means no limit
+	Parallelism int
+	// InterruptPollInterval is how often the interrupt check is consulted
+	// while a phase is executing
+	InterruptPollInterval time.Duration
 }
 
 // CheckAndSetDefaults makes sure the config is valid and sets some defaults
@@ -124,6 +135,9 @@ func (c *Config) CheckAndSetDefaults() error {
 	if c.Logger == nil {
 		c.Logger = logrus.WithField(trace.Component, "fsm")
 	}
+	if c.InterruptPollInterval == 0 {
+		c.InterruptPollInterval = defaults.OperationInterruptPollInterval
+	}
 	return nil
 }
 
@@ -139,19 +153,29 @@ func New(config Config) (*FSM, error) {
 	}, nil
 }
 
-// ExecutePlan iterates over all phases of the plan and executes them in order
+// ExecutePlan iterates over all phases of the plan and executes them in order.
+// If the interrupt check is set, the execution stops before the next phase
+// when the operation has been paused or cancelled
 func (f *FSM) ExecutePlan(ctx context.Context, progress utils.Progress, force bool) error {
 	plan, err := f.GetPlan()
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	for _, phase := range plan.Phases {
+		if !phase.IsCompleted() {
+			if interrupt := f.checkInterrupt(ctx); interrupt != "" {
+				return &InterruptedError{Interrupt: interrupt, Phase: phase.ID}
+			}
+		}
 		f.Debugf("Executing phase %q.", phase.ID)
-		err := f.ExecutePhase(ctx, Params{
+		err := f.executeInterruptible(ctx, Params{
 			PhaseID:  phase.ID,
 			Progress: progress,
 			Force:    force,
 		})
+		if IsInterrupted(err) {
+			return trace.Wrap(err)
+		}
 		if err != nil {
 			return trace.Wrap(err, "failed to execute phase %q", phase.ID)
 		}
@@ -201,12 +225,43 @@ func (f *FSM) ExecutePhase(ctx context.Context, p Params) error {
 	return nil
 }
 
-// RollbackPhase rolls back the specified phase of the plan
+// RollbackPlan iterates over all phases of the plan in reverse order and
+// rolls back those that have been executed. Phases that have already been
+// rolled back are skipped so an interrupted rollback can be resumed
+func (f *FSM) RollbackPlan(ctx context.Context, progress utils.Progress, force bool) error {
+	plan, err := f.GetPlan()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for i := len(plan.Phases) - 1; i >= 0; i-- {
+		phase := plan.Phases[i]
+		if phase.IsUnstarted() || phase.IsRolledBack() {
+			f.Debugf("Skipping phase %q in state %q.", phase.ID, phase.GetState())
+			continue
+		}
+		f.Debugf("Rolling back phase %q.", phase.ID)
+		err := f.RollbackPhase(ctx, Params{
+			PhaseID:  phase.ID,
+			Progress: progress,
+			Force:    force,
+		})
+		if err != nil {
+			return trace.Wrap(err, "failed to rollback phase %q", phase.ID)
+		}
+	}
+	return nil
+}
+
+// RollbackPhase rolls back the specified phase of the plan.
+// If the phase is the root phase, the whole plan is rolled back
 func (f *FSM) RollbackPhase(ctx context.Context, p Params) error {
 	err := p.CheckAndSetDefaults()
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if p.PhaseID == RootPhase {
+		return trace.Wrap(f.RollbackPlan(ctx, p.Progress, p.Force))
+	}
 	plan, err := f.GetPlan()
 	if err != nil {
 		return trace.Wrap(err)
@@ -254,7 +309,12 @@ func (f *FSM) RollbackPhase(ctx context.Context, p Params) error {
 		return nil
 	}
 	for i := len(phase.Phases) - 1; i >= 0; i-- {
-		p.PhaseID = phase.Phases[i].ID
+		subphase := phase.Phases[i]
+		if subphase.IsUnstarted() || subphase.IsRolledBack() {
+			// Nothing to undo
+			continue
+		}
+		p.PhaseID = subphase.ID
 		err = f.RollbackPhase(ctx, p)
 		if err != nil {
 			return trace.Wrap(err)
@@ -273,6 +333,12 @@ func (f *FSM) SetPostExec(fn PhaseHookFn) {
 	f.postExecFn = fn
 }
 
+// SetInterruptCheck sets the function that's consulted during plan
+// execution to find out whether the operation should pause or cancel
+func (f *FSM) SetInterruptCheck(fn InterruptCheckFn) {
+	f.interruptFn = fn
+}
+
 // Close releases all FSM resources
 func (f *FSM) Close() error {
 	return trace.Wrap(f.Runner.Close())
@@ -371,20 +437,60 @@ func (f *FSM) executeSubphasesSequentially(ctx context.Context, p Params, phase
 	return nil
 }
 
+// executeSubphasesConcurrently executes subphases of the specified phase concurrently.
+// A subphase that requires any of its siblings is only started once the required
+// siblings have completed, and at most Parallelism subphases are executed at a time.
+// No new subphases are started after a failure
 func (f *FSM) executeSubphasesConcurrently(ctx context.Context, p Params, phase storage.OperationPhase) error {
-	errorsCh := make(chan error, len(phase.Phases))
-	for _, subphase := range phase.Phases {
-		go func(p Params, subphase storage.OperationPhase) {
-			p.PhaseID = subphase.ID
-			err := f.ExecutePhase(ctx, p)
-			if err != nil {
-				logrus.Warnf("Failed to execute phase %q: %v.",
-					p.PhaseID, trace.DebugReport(err))
+	deps, err := subphaseDependencies(phase)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	limit := f.Parallelism
+	if limit <= 0 || limit > len(phase.Phases) {
+		limit = len(phase.Phases)
+	}
+	type result struct {
+		index int
+		err   error
+	}
+	resultsCh := make(chan result, len(phase.Phases))
+	started := make([]bool, len(phase.Phases))
+	completed := make([]bool, len(phase.Phases))
+	var running int
+	var errors []error
+	for {
+		for i := 0; i < len(phase.Phases) && running < limit && len(errors) == 0; i++ {
+			if started[i] || !allCompleted(deps[i], completed) {
+				continue
+			}
+			started[i] = true
+			running++
+			go func(p Params, i int) {
+				p.PhaseID = phase.Phases[i].ID
+				err := f.ExecutePhase(ctx, p)
+				if err != nil {
+					logrus.Warnf("Failed to execute phase %q: %v.",
+						p.PhaseID, trace.DebugReport(err))
+				}
+				resultsCh <- result{index: i, err: trace.Wrap(err, "failed to execute phase %q", p.PhaseID)}
+			}(p, i)
+		}
+		if running == 0 {
+			return trace.NewAggregate(errors...)
+		}
+		select {
+		case <-ctx.Done():
+			errors = append(errors, trace.Errorf("timed out"))
+			return trace.NewAggregate(errors...)
+		case result := <-resultsCh:
+			running--
+			completed[result.index] = true
+			if result.err != nil {
+				errors = append(errors, result.err)
 			}
-			errorsCh <- trace.Wrap(err, "failed to execute phase %q", p.PhaseID)
-		}(p, subphase)
+		}
 	}
-	return utils.CollectErrors(ctx, errorsCh)
 }
 
 func (f *FSM) executeOnePhase(ctx context.Context, p Params, phase storage.OperationPhase) error {
@@ -407,29 +513,36 @@ func (f *FSM) executeOnePhase(ctx context.Context, p Params, phase storage.Opera
 		return trace.Wrap(err)
 	}
 
-	err = f.ChangePhaseState(ctx,
-		StateChange{
-			Phase: phase.ID,
-			State: storage.OperationPhaseStateInProgress,
-		})
-	if err != nil {
-		return trace.Wrap(err)
+	// the phase has been attempted before if it has left the unstarted state
+	if current, err := FindPhase(plan, phase.ID); err == nil && !current.IsUnstarted() {
+		phaseRetries.WithLabelValues(phase.Executor).Inc()
 	}
 
-	executor.Infof("Executing phase: %v.", phase.ID)
+	if phase.Retry != nil {
+		if err := phase.Retry.Check(); err != nil {
+			return trace.Wrap(err, "invalid retry policy of phase %q", phase.ID)
+		}
+	}
 
-	err = executor.Execute(ctx)
-	if err != nil {
-		executor.Errorf("Phase execution failed: %v.", err)
-		if err := f.ChangePhaseState(ctx,
-			StateChange{
-				Phase: phase.ID,
-				State: storage.OperationPhaseStateFailed,
-				Error: trace.Wrap(err),
-			}); err != nil {
+	attempt := 1
+	for {
+		err = f.executeAttempt(ctx, executor, phase, attempt)
+		if err == nil {
+			break
+		}
+		delay, retry := retryDelay(phase.Retry, attempt, err)
+		if !retry {
 			return trace.Wrap(err)
 		}
-		return trace.Wrap(err)
+		executor.Warnf("Phase execution attempt %v of %v failed, will retry in %v.",
+			attempt, phase.Retry.MaxAttempts, delay)
+		phaseRetries.WithLabelValues(phase.Executor).Inc()
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return trace.Wrap(err)
+		}
+		attempt++
 	}
 
 	err = executor.PostCheck(ctx)
@@ -440,13 +553,46 @@ func (f *FSM) executeOnePhase(ctx context.Context, p Params, phase storage.Opera
 
 	err = f.ChangePhaseState(ctx,
 		StateChange{
-			Phase: phase.ID,
-			State: storage.OperationPhaseStateCompleted,
+			Phase:   phase.ID,
+			State:   storage.OperationPhaseStateCompleted,
+			Attempt: attempt,
+		})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return nil
+}
+
+// executeAttempt makes a single attempt to execute the phase and records
+// the attempt in the phase state changes
+func (f *FSM) executeAttempt(ctx context.Context, executor PhaseExecutor, phase storage.OperationPhase, attempt int) error {
+	err := f.ChangePhaseState(ctx,
+		StateChange{
+			Phase:   phase.ID,
+			State:   storage.OperationPhaseStateInProgress,
+			Attempt: attempt,
 		})
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
+	executor.Infof("Executing phase: %v.", phase.ID)
+
+	err = executor.Execute(ctx)
+	if err != nil {
+		executor.Errorf("Phase execution failed: %v.", err)
+		if err := f.ChangePhaseState(ctx,
+			StateChange{
+				Phase:   phase.ID,
+				State:   storage.OperationPhaseStateFailed,
+				Error:   trace.Wrap(err),
+				Attempt: attempt,
+			}); err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(err)
+	}
 	return nil
 }
 
@@ -533,6 +679,8 @@ type StateChange struct {
 	State string
 	// Error is the error that happened during phase execution
 	Error trace.Error
+	// Attempt is the number of the phase execution attempt
+	Attempt int
 }
 
 // String returns a textual representation of this state change
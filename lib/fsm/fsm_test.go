@@ -0,0 +1,734 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app/resources"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	. "gopkg.in/check.v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestFSM(t *testing.T) { TestingT(t) }
+
+type FSMSuite struct{}
+
+var _ = Suite(&FSMSuite{})
+
+func (s *FSMSuite) TestRollsBackExecutedPhasesInReverseOrder(c *C) {
+	engine := newTestEngine(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init", State: storage.OperationPhaseStateCompleted},
+			{ID: "/masters", Phases: []storage.OperationPhase{
+				{ID: "/masters/node-1", State: storage.OperationPhaseStateCompleted},
+				{ID: "/masters/node-2", State: storage.OperationPhaseStateFailed},
+				{ID: "/masters/node-3", State: storage.OperationPhaseStateUnstarted},
+			}},
+			{ID: "/app", State: storage.OperationPhaseStateUnstarted},
+		},
+	})
+	machine, err := New(Config{Engine: engine})
+	c.Assert(err, IsNil)
+
+	err = machine.RollbackPhase(context.TODO(), Params{PhaseID: RootPhase})
+	c.Assert(err, IsNil)
+	c.Assert(engine.rolledBack, DeepEquals, []string{"/masters/node-2", "/masters/node-1", "/init"})
+	for _, id := range engine.rolledBack {
+		phase, err := FindPhase(&engine.plan, id)
+		c.Assert(err, IsNil)
+		c.Assert(phase.IsRolledBack(), Equals, true)
+	}
+
+	// rolling back again is a no-op
+	engine.rolledBack = nil
+	err = machine.RollbackPlan(context.TODO(), nil, false)
+	c.Assert(err, IsNil)
+	c.Assert(engine.rolledBack, IsNil)
+}
+
+func (s *FSMSuite) TestResumesInterruptedRollback(c *C) {
+	engine := newTestEngine(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init", State: storage.OperationPhaseStateCompleted},
+			{ID: "/checks", State: storage.OperationPhaseStateCompleted},
+			{ID: "/app", State: storage.OperationPhaseStateCompleted},
+		},
+	})
+	engine.failRollback = "/checks"
+	machine, err := New(Config{Engine: engine})
+	c.Assert(err, IsNil)
+
+	err = machine.RollbackPlan(context.TODO(), nil, false)
+	c.Assert(err, NotNil)
+	c.Assert(engine.rolledBack, DeepEquals, []string{"/app"})
+
+	engine.failRollback = ""
+	engine.rolledBack = nil
+	err = machine.RollbackPlan(context.TODO(), nil, false)
+	c.Assert(err, IsNil)
+	c.Assert(engine.rolledBack, DeepEquals, []string{"/checks", "/init"})
+}
+
+func (s *FSMSuite) TestExecutesIndependentPhasesConcurrently(c *C) {
+	engine := newTestEngine(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/nodes", Parallel: true, Phases: []storage.OperationPhase{
+				{ID: "/nodes/node-1"},
+				{ID: "/nodes/node-2"},
+				{ID: "/nodes/node-3", Requires: []string{"/nodes/node-1"}},
+				{ID: "/nodes/node-4"},
+			}},
+		},
+	})
+	engine.executeDelay = 50 * time.Millisecond
+	machine, err := New(Config{Engine: engine, Parallelism: 2})
+	c.Assert(err, IsNil)
+
+	err = machine.ExecutePlan(context.TODO(), nil, false)
+	c.Assert(err, IsNil)
+	c.Assert(engine.maxRunning, Equals, 2)
+	c.Assert(len(engine.executed), Equals, 4)
+	c.Assert(indexOf(engine.executed, "/nodes/node-3") > indexOf(engine.executed, "/nodes/node-1"), Equals, true,
+		Commentf("%v", engine.executed))
+	c.Assert(IsCompleted(&engine.plan), Equals, true)
+}
+
+func (s *FSMSuite) TestPausesBetweenPhases(c *C) {
+	engine := newTestEngine(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init"},
+			{ID: "/checks"},
+			{ID: "/app"},
+		},
+	})
+	machine, err := New(Config{Engine: engine})
+	c.Assert(err, IsNil)
+	interrupt := ""
+	machine.SetInterruptCheck(func(context.Context) (string, error) {
+		engine.Lock()
+		defer engine.Unlock()
+		if len(engine.executed) == 1 {
+			return interrupt, nil
+		}
+		return "", nil
+	})
+
+	interrupt = storage.OperationInterruptPause
+	err = machine.ExecutePlan(context.TODO(), nil, false)
+	c.Assert(IsPaused(err), Equals, true, Commentf("%v", err))
+	c.Assert(engine.executed, DeepEquals, []string{"/init"})
+
+	interrupt = ""
+	err = machine.ExecutePlan(context.TODO(), nil, false)
+	c.Assert(err, IsNil)
+	c.Assert(engine.executed, DeepEquals, []string{"/init", "/checks", "/app"})
+	c.Assert(engine.rolledBack, IsNil)
+}
+
+func (s *FSMSuite) TestCancelRollsBackPhaseInProgress(c *C) {
+	engine := newTestEngine(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init", State: storage.OperationPhaseStateCompleted},
+			{ID: "/checks"},
+			{ID: "/app"},
+		},
+	})
+	engine.executeDelay = time.Minute
+	machine, err := New(Config{Engine: engine, InterruptPollInterval: 10 * time.Millisecond})
+	c.Assert(err, IsNil)
+	machine.SetInterruptCheck(func(context.Context) (string, error) {
+		engine.Lock()
+		defer engine.Unlock()
+		if engine.running != 0 {
+			return storage.OperationInterruptCancel, nil
+		}
+		return "", nil
+	})
+
+	err = machine.ExecutePlan(context.TODO(), nil, false)
+	c.Assert(IsCancelled(err), Equals, true, Commentf("%v", err))
+	c.Assert(engine.executed, IsNil)
+	c.Assert(engine.rolledBack, DeepEquals, []string{"/checks"})
+	plan, err := engine.GetPlan()
+	c.Assert(err, IsNil)
+	c.Assert(plan.Phases[0].IsCompleted(), Equals, true)
+	c.Assert(plan.Phases[1].IsRolledBack(), Equals, true)
+	c.Assert(plan.Phases[2].IsUnstarted(), Equals, true)
+}
+
+func (s *FSMSuite) TestRejectsCyclicRequirements(c *C) {
+	_, err := subphaseDependencies(storage.OperationPhase{
+		ID: "/nodes",
+		Phases: []storage.OperationPhase{
+			{ID: "/nodes/node-1", Requires: []string{"/nodes/node-2"}},
+			{ID: "/nodes/node-2", Phases: []storage.OperationPhase{
+				{ID: "/nodes/node-2/drain", Requires: []string{"/nodes/node-1"}},
+			}},
+		},
+	})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *FSMSuite) TestDryRunDescribesPhasesToExecute(c *C) {
+	node := storage.Server{Hostname: "node-1", AdvertiseIP: "10.0.0.1"}
+	runtimePackage := loc.MustParseLocator("gravitational.io/planet:0.0.2")
+	appPackage := loc.MustParseLocator("example.com/app:0.0.2")
+	plan := storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init", Description: "Initialize operation", State: storage.OperationPhaseStateCompleted},
+			{ID: "/masters", Description: "Update masters", Phases: []storage.OperationPhase{
+				{
+					ID:          "/masters/node-1",
+					Description: "Update system software on node-1",
+					Data: &storage.OperationPhaseData{
+						Server:         &node,
+						ExecServer:     &node,
+						RuntimePackage: &runtimePackage,
+					},
+				},
+			}},
+			{
+				ID:          "/app",
+				Description: "Update application",
+				Requires:    []string{"/masters"},
+				Data: &storage.OperationPhaseData{
+					Package: &appPackage,
+				},
+			},
+		},
+	}
+	var out bytes.Buffer
+	err := DryRun(&out, DryRunConfig{
+		Plan: plan,
+		Resources: func(phase storage.OperationPhase, fn resources.ResourceFunc) error {
+			if phase.ID != "/app" {
+				return nil
+			}
+			return fn(&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "app"}})
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(out.String(), Equals, `* /masters: Update masters
+  * /masters/node-1: Update system software on node-1
+      node:     node-1 (10.0.0.1)
+      package:  gravitational.io/planet:0.0.2
+* /app: Update application (requires /masters)
+    package:  example.com/app:0.0.2
+    resource: ClusterRole "app"
+`)
+	c.Assert(plan.Phases[1].Phases[0].IsUnstarted(), Equals, true)
+}
+
+func (s *FSMSuite) TestRetriesFailedPhase(c *C) {
+	engine := newTestEngine(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/wait", Retry: &storage.RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+			}},
+		},
+	})
+	engine.failures = map[string][]error{
+		"/wait": {
+			trace.ConnectionProblem(nil, "connection refused"),
+			trace.LimitExceeded("timed out"),
+		},
+	}
+	machine, err := New(Config{Engine: engine})
+	c.Assert(err, IsNil)
+
+	err = machine.ExecutePlan(context.TODO(), nil, false)
+	c.Assert(err, IsNil)
+	c.Assert(engine.executed, DeepEquals, []string{"/wait"})
+	var attempts []string
+	for _, change := range engine.changes {
+		attempts = append(attempts, fmt.Sprintf("%v:%v", change.Attempt, change.State))
+	}
+	c.Assert(attempts, DeepEquals, []string{
+		"1:in_progress", "1:failed",
+		"2:in_progress", "2:failed",
+		"3:in_progress", "3:completed",
+	})
+}
+
+func (s *FSMSuite) TestDoesNotRetryPermanentErrors(c *C) {
+	engine := newTestEngine(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/wait", Retry: &storage.RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+			}},
+		},
+	})
+	engine.failures = map[string][]error{
+		"/wait": {trace.BadParameter("invalid configuration")},
+	}
+	machine, err := New(Config{Engine: engine})
+	c.Assert(err, IsNil)
+
+	err = machine.ExecutePlan(context.TODO(), nil, false)
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+	c.Assert(engine.changes, HasLen, 2)
+	c.Assert(engine.plan.Phases[0].IsFailed(), Equals, true)
+}
+
+func (s *FSMSuite) TestResolvesAttemptHistory(c *C) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	plan := storage.OperationPlan{
+		Phases: []storage.OperationPhase{{ID: "/wait"}},
+	}
+	changelog := storage.PlanChangelog{
+		{PhaseID: "/wait", NewState: storage.OperationPhaseStateFailed, Attempt: 2, Created: now.Add(time.Minute),
+			Error: utils.ToRawTrace(trace.LimitExceeded("timed out").(trace.Error))},
+		{PhaseID: "/wait", NewState: storage.OperationPhaseStateFailed, Attempt: 1, Created: now,
+			Error: utils.ToRawTrace(trace.ConnectionProblem(nil, "connection refused").(trace.Error))},
+		{PhaseID: "/wait", NewState: storage.OperationPhaseStateInProgress, Attempt: 3, Created: now.Add(2 * time.Minute)},
+	}
+	resolved := ResolvePlan(plan, changelog)
+	c.Assert(resolved.Phases[0].Attempts, DeepEquals, []storage.PhaseAttempt{
+		{Attempt: 1, Time: now, Error: "connection refused"},
+		{Attempt: 2, Time: now.Add(time.Minute), Error: "timed out"},
+	})
+	c.Assert(resolved.Phases[0].IsInProgress(), Equals, true)
+}
+
+func (s *FSMSuite) TestRetryBackoff(c *C) {
+	policy := &storage.RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     3 * time.Second,
+	}
+	err := trace.ConnectionProblem(nil, "connection refused")
+	var delays []time.Duration
+	for attempt := 1; ; attempt++ {
+		delay, retry := retryDelay(policy, attempt, err)
+		if !retry {
+			break
+		}
+		delays = append(delays, delay)
+	}
+	c.Assert(delays, DeepEquals, []time.Duration{
+		time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second,
+	})
+	_, retry := retryDelay(nil, 1, err)
+	c.Assert(retry, Equals, false)
+	_, retry = retryDelay(&storage.RetryPolicy{MaxAttempts: 2, RetryOn: []string{storage.RetryOnAny}},
+		1, trace.BadParameter("invalid configuration"))
+	c.Assert(retry, Equals, true)
+}
+
+func (s *FSMSuite) TestCreatesBootstrapResource(c *C) {
+	server := &fakeAPIServer{}
+	upsert := newUpsertFunc(c, server)
+
+	err := upsert(&apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "crontabs.example.com"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(server.requests, DeepEquals, []string{
+		"GET /apis/apiextensions.k8s.io/v1beta1/customresourcedefinitions/crontabs.example.com",
+		"POST /apis/apiextensions.k8s.io/v1beta1/customresourcedefinitions",
+	})
+	c.Assert(server.fieldManager, Equals, constants.FieldManager)
+	var created apiextensionsv1beta1.CustomResourceDefinition
+	c.Assert(json.Unmarshal(server.body, &created), IsNil)
+	c.Assert(created.Kind, Equals, "CustomResourceDefinition")
+	c.Assert(created.Annotations[constants.AnnotationLastAppliedConfiguration], Not(Equals), "")
+}
+
+func (s *FSMSuite) TestPatchesBootstrapResourcePreservingOtherFields(c *C) {
+	server := &fakeAPIServer{
+		current: liveObject(c, newClusterRole("get"), map[string]string{"added-by": "admission"}),
+	}
+	upsert := newUpsertFunc(c, server)
+
+	err := upsert(newClusterRole("get", "list"))
+	c.Assert(err, IsNil)
+	c.Assert(server.requests, DeepEquals, []string{
+		"GET /apis/rbac.authorization.k8s.io/v1/clusterroles/test",
+		"PATCH /apis/rbac.authorization.k8s.io/v1/clusterroles/test",
+	})
+	c.Assert(server.fieldManager, Equals, constants.FieldManager)
+	c.Assert(server.contentType, Equals, string(types.StrategicMergePatchType))
+	patched, err := strategicpatch.StrategicMergePatch(server.current, server.body, &rbacv1.ClusterRole{})
+	c.Assert(err, IsNil)
+	var role rbacv1.ClusterRole
+	c.Assert(json.Unmarshal(patched, &role), IsNil)
+	c.Assert(role.Rules[0].Verbs, DeepEquals, []string{"get", "list"})
+	c.Assert(role.Labels, DeepEquals, map[string]string{"added-by": "admission"})
+	c.Assert(role.ResourceVersion, Equals, "7")
+}
+
+func (s *FSMSuite) TestDetectsConflictingChanges(c *C) {
+	live := newClusterRole("get")
+	live.Rules[0].Verbs = []string{"delete"}
+	current := liveObject(c, newClusterRole("get"), nil)
+	// Another writer has changed the verbs after the role was applied
+	var role rbacv1.ClusterRole
+	c.Assert(json.Unmarshal(current, &role), IsNil)
+	role.Rules = live.Rules
+	current, err := json.Marshal(role)
+	c.Assert(err, IsNil)
+	server := &fakeAPIServer{current: current}
+	upsert := newUpsertFunc(c, server)
+
+	err = upsert(newClusterRole("list"))
+	c.Assert(trace.IsCompareFailed(err), Equals, true, Commentf("%v", err))
+	c.Assert(server.requests, DeepEquals, []string{
+		"GET /apis/rbac.authorization.k8s.io/v1/clusterroles/test",
+	})
+}
+
+func (s *FSMSuite) TestUpsertsCustomResourceDefinition(c *C) {
+	live := newCustomResourceDefinition("v1")
+	server := &fakeAPIServer{
+		current: liveObject(c, live, map[string]string{"added-by": "admission"}),
+	}
+	upsert := newUpsertFunc(c, server)
+
+	crd := newCustomResourceDefinition("v1")
+	crd.Spec.Names.ShortNames = []string{"ct"}
+	err := upsert(crd)
+	c.Assert(err, IsNil)
+	c.Assert(server.requests, DeepEquals, []string{
+		"GET /apis/apiextensions.k8s.io/v1beta1/customresourcedefinitions/crontabs.example.com",
+		"PATCH /apis/apiextensions.k8s.io/v1beta1/customresourcedefinitions/crontabs.example.com",
+	})
+	c.Assert(server.contentType, Equals, string(types.MergePatchType))
+	patched, err := jsonpatch.MergePatch(server.current, server.body)
+	c.Assert(err, IsNil)
+	var updated apiextensionsv1beta1.CustomResourceDefinition
+	c.Assert(json.Unmarshal(patched, &updated), IsNil)
+	c.Assert(updated.Spec.Names.ShortNames, DeepEquals, []string{"ct"})
+	c.Assert(updated.Spec.Version, Equals, "v1")
+	c.Assert(updated.Labels, DeepEquals, map[string]string{"added-by": "admission"})
+	c.Assert(updated.ResourceVersion, Equals, "7")
+}
+
+func (s *FSMSuite) TestDetectsConflictingChangesToCustomResourceDefinition(c *C) {
+	current := liveObject(c, newCustomResourceDefinition("v1"), nil)
+	// Another writer has changed the version after the definition was applied
+	var live apiextensionsv1beta1.CustomResourceDefinition
+	c.Assert(json.Unmarshal(current, &live), IsNil)
+	live.Spec.Version = "v1alpha1"
+	current, err := json.Marshal(live)
+	c.Assert(err, IsNil)
+	server := &fakeAPIServer{current: current}
+	upsert := newUpsertFunc(c, server)
+
+	err = upsert(newCustomResourceDefinition("v2"))
+	c.Assert(trace.IsCompareFailed(err), Equals, true, Commentf("%v", err))
+	c.Assert(server.requests, HasLen, 1)
+}
+
+func (s *FSMSuite) TestUpsertsCustomResource(c *C) {
+	resource, err := resources.Decode(strings.NewReader(`apiVersion: example.com/v1
+kind: CronTab
+metadata:
+  name: backup
+  namespace: kube-system
+spec:
+  schedule: "0 * * * *"
+`))
+	c.Assert(err, IsNil)
+	c.Assert(resource.Objects, HasLen, 1)
+	server := &fakeAPIServer{
+		current: liveObject(c, resource.Objects[0], map[string]string{"added-by": "admission"}),
+	}
+	upsert := newUpsertFunc(c, server)
+
+	resource, err = resources.Decode(strings.NewReader(`apiVersion: example.com/v1
+kind: CronTab
+metadata:
+  name: backup
+  namespace: kube-system
+spec:
+  schedule: "30 * * * *"
+`))
+	c.Assert(err, IsNil)
+	err = upsert(resource.Objects[0])
+	c.Assert(err, IsNil)
+	c.Assert(server.requests, DeepEquals, []string{
+		"GET /apis/example.com/v1/namespaces/kube-system/crontabs/backup",
+		"PATCH /apis/example.com/v1/namespaces/kube-system/crontabs/backup",
+	})
+	c.Assert(server.contentType, Equals, string(types.MergePatchType))
+	patched, err := jsonpatch.MergePatch(server.current, server.body)
+	c.Assert(err, IsNil)
+	var updated map[string]interface{}
+	c.Assert(json.Unmarshal(patched, &updated), IsNil)
+	c.Assert(updated["spec"], DeepEquals, map[string]interface{}{"schedule": "30 * * * *"})
+	metadata := updated["metadata"].(map[string]interface{})
+	c.Assert(metadata["labels"], DeepEquals, map[string]interface{}{"added-by": "admission"})
+}
+
+func (s *FSMSuite) TestRetriesConcurrentlyModifiedResource(c *C) {
+	server := &fakeAPIServer{
+		current:   liveObject(c, newClusterRole("get"), nil),
+		conflicts: 1,
+	}
+	upsert := newUpsertFunc(c, server)
+
+	err := upsert(newClusterRole("get", "list"))
+	c.Assert(err, IsNil)
+	c.Assert(server.requests, DeepEquals, []string{
+		"GET /apis/rbac.authorization.k8s.io/v1/clusterroles/test",
+		"PATCH /apis/rbac.authorization.k8s.io/v1/clusterroles/test",
+		"GET /apis/rbac.authorization.k8s.io/v1/clusterroles/test",
+		"PATCH /apis/rbac.authorization.k8s.io/v1/clusterroles/test",
+	})
+}
+
+func newUpsertFunc(c *C, server *fakeAPIServer) resources.ResourceFunc {
+	srv := httptest.NewServer(server)
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	c.Assert(err, IsNil)
+	return func(object runtime.Object) error {
+		defer srv.Close()
+		return GetUpsertBootstrapResourceFunc(client)(object)
+	}
+}
+
+func newClusterRole(verbs ...string) *rbacv1.ClusterRole {
+	return &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: verbs},
+		},
+	}
+}
+
+func newCustomResourceDefinition(version string) *apiextensionsv1beta1.CustomResourceDefinition {
+	return &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "crontabs.example.com"},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group:   "example.com",
+			Version: version,
+			Scope:   apiextensionsv1beta1.NamespaceScoped,
+			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
+				Plural: "crontabs",
+				Kind:   "CronTab",
+			},
+		},
+	}
+}
+
+// liveObject returns the live object for the specified bootstrap resource
+// as if it had been applied and then labeled by another writer
+func liveObject(c *C, object runtime.Object, labels map[string]string) []byte {
+	resource, err := newBootstrapResource(object)
+	c.Assert(err, IsNil)
+	applied, err := resource.modifiedConfiguration()
+	c.Assert(err, IsNil)
+	var live map[string]interface{}
+	c.Assert(json.Unmarshal(applied, &live), IsNil)
+	metadata := live["metadata"].(map[string]interface{})
+	metadata["resourceVersion"] = "7"
+	if labels != nil {
+		metadata["labels"] = labels
+	}
+	data, err := json.Marshal(live)
+	c.Assert(err, IsNil)
+	return data
+}
+
+// fakeAPIServer serves a single Kubernetes object
+type fakeAPIServer struct {
+	// current is the live object, nil if it does not exist
+	current []byte
+	// requests lists received requests
+	requests []string
+	// body is the body of the last create or patch request
+	body []byte
+	// fieldManager is the field manager of the last create or patch request
+	fieldManager string
+	// contentType is the content type of the last create or patch request
+	contentType string
+	// conflicts is the number of patch requests to reject with a conflict
+	conflicts int
+}
+
+func (s *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests = append(s.requests, fmt.Sprintf("%v %v", r.Method, r.URL.Path))
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodGet {
+		if s.current == nil {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+			return
+		}
+		w.Write(s.current)
+		return
+	}
+	s.body, _ = ioutil.ReadAll(r.Body)
+	s.fieldManager = r.URL.Query().Get("fieldManager")
+	s.contentType = r.Header.Get("Content-Type")
+	if r.Method == http.MethodPatch && s.conflicts > 0 {
+		s.conflicts--
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Conflict","code":409}`)
+		return
+	}
+	w.Write(s.body)
+}
+
+func indexOf(items []string, item string) int {
+	for i := range items {
+		if items[i] == item {
+			return i
+		}
+	}
+	return -1
+}
+
+func newTestEngine(plan storage.OperationPlan) *testEngine {
+	return &testEngine{plan: plan}
+}
+
+// testEngine is an FSM engine that records executed and rolled back phases
+type testEngine struct {
+	sync.Mutex
+	plan storage.OperationPlan
+	// executed lists IDs of phases in the order they were executed
+	executed []string
+	// executeDelay is how long each phase takes to execute
+	executeDelay time.Duration
+	// running is the number of phases currently executing
+	running int
+	// maxRunning is the maximum number of phases executed concurrently
+	maxRunning int
+	// rolledBack lists IDs of phases in the order they were rolled back
+	rolledBack []string
+	// failRollback is the ID of the phase that fails to roll back
+	failRollback string
+	// failures maps phase IDs to the errors returned by their
+	// consecutive executions
+	failures map[string][]error
+	// changes lists the applied phase state changes
+	changes []StateChange
+}
+
+func (e *testEngine) GetExecutor(p ExecutorParams, remote Remote) (PhaseExecutor, error) {
+	return &testExecutor{FieldLogger: logrus.WithField("phase", p.Phase.ID), engine: e, phase: p.Phase.ID}, nil
+}
+
+func (e *testEngine) ChangePhaseState(ctx context.Context, change StateChange) error {
+	e.Lock()
+	defer e.Unlock()
+	phase, err := FindPhase(&e.plan, change.Phase)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	phase.State = change.State
+	e.changes = append(e.changes, change)
+	return nil
+}
+
+func (e *testEngine) GetPlan() (*storage.OperationPlan, error) {
+	e.Lock()
+	defer e.Unlock()
+	plan := e.plan
+	plan.Phases = clonePhases(e.plan.Phases)
+	return &plan, nil
+}
+
+func clonePhases(phases []storage.OperationPhase) []storage.OperationPhase {
+	result := make([]storage.OperationPhase, len(phases))
+	for i, phase := range phases {
+		result[i] = phase
+		result[i].Phases = clonePhases(phase.Phases)
+	}
+	return result
+}
+
+func (e *testEngine) RunCommand(context.Context, RemoteRunner, storage.Server, Params) error {
+	return trace.NotImplemented("not implemented")
+}
+
+func (e *testEngine) Complete(error) error {
+	return nil
+}
+
+type testExecutor struct {
+	logrus.FieldLogger
+	engine *testEngine
+	phase  string
+}
+
+func (e *testExecutor) PreCheck(context.Context) error  { return nil }
+func (e *testExecutor) PostCheck(context.Context) error { return nil }
+
+func (e *testExecutor) Execute(ctx context.Context) error {
+	e.engine.Lock()
+	e.engine.running++
+	if e.engine.running > e.engine.maxRunning {
+		e.engine.maxRunning = e.engine.running
+	}
+	e.engine.Unlock()
+	defer func() {
+		e.engine.Lock()
+		e.engine.running--
+		e.engine.Unlock()
+	}()
+	select {
+	case <-time.After(e.engine.executeDelay):
+	case <-ctx.Done():
+		return trace.Wrap(ctx.Err())
+	}
+	e.engine.Lock()
+	defer e.engine.Unlock()
+	if failures := e.engine.failures[e.phase]; len(failures) != 0 {
+		e.engine.failures[e.phase] = failures[1:]
+		return failures[0]
+	}
+	e.engine.executed = append(e.engine.executed, e.phase)
+	return nil
+}
+
+func (e *testExecutor) Rollback(context.Context) error {
+	if e.phase == e.engine.failRollback {
+		return trace.BadParameter("rollback of %v failed", e.phase)
+	}
+	e.engine.rolledBack = append(e.engine.rolledBack, e.phase)
+	return nil
+}
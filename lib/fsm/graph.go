@@ -0,0 +1,198 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// FormatOperationPlanDot writes the operation plan as a graph in the
+// Graphviz DOT language.
+//
+// Phases with subphases are rendered as clusters, phase dependencies
+// as edges. Phases are colored according to their state
+func FormatOperationPlanDot(w io.Writer, plan storage.OperationPlan, now time.Time) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "digraph %q {\n", plan.OperationID)
+	buf.WriteString("  compound=true;\n")
+	buf.WriteString("  rankdir=LR;\n")
+	buf.WriteString("  node [shape=box, style=\"rounded,filled\"];\n")
+	for _, phase := range plan.Phases {
+		writeDotPhase(&buf, phase, now, 1)
+	}
+	groups := make(map[string]bool)
+	for _, phase := range FlattenPlan(&plan) {
+		groups[phase.ID] = phase.HasSubphases()
+	}
+	for _, phase := range FlattenPlan(&plan) {
+		for _, requires := range phase.Requires {
+			if _, ok := groups[requires]; !ok {
+				continue
+			}
+			// edges are clipped at the borders of the clusters
+			var attrs []string
+			if groups[requires] {
+				attrs = append(attrs, fmt.Sprintf("ltail=%q", "cluster_"+requires))
+			}
+			if groups[phase.ID] {
+				attrs = append(attrs, fmt.Sprintf("lhead=%q", "cluster_"+phase.ID))
+			}
+			if len(attrs) == 0 {
+				fmt.Fprintf(&buf, "  %q -> %q;\n", requires, phase.ID)
+				continue
+			}
+			fmt.Fprintf(&buf, "  %q -> %q [%v];\n", requires, phase.ID, strings.Join(attrs, ", "))
+		}
+	}
+	buf.WriteString("}\n")
+	_, err := w.Write(buf.Bytes())
+	return trace.Wrap(err)
+}
+
+// FormatOperationPlanMermaid writes the operation plan as a Mermaid
+// flowchart.
+//
+// Phases with subphases are rendered as subgraphs, phase dependencies
+// as links. Phases are colored according to their state
+func FormatOperationPlanMermaid(w io.Writer, plan storage.OperationPlan, now time.Time) error {
+	// Mermaid identifiers cannot contain slashes so phases are
+	// identified by their position in the plan
+	ids := make(map[string]string)
+	for i, phase := range FlattenPlan(&plan) {
+		ids[phase.ID] = fmt.Sprintf("phase%v", i)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("flowchart LR\n")
+	for _, phase := range plan.Phases {
+		writeMermaidPhase(&buf, phase, ids, now, 1)
+	}
+	for _, phase := range FlattenPlan(&plan) {
+		for _, requires := range phase.Requires {
+			if _, ok := ids[requires]; !ok {
+				continue
+			}
+			fmt.Fprintf(&buf, "  %v --> %v\n", ids[requires], ids[phase.ID])
+		}
+	}
+	for _, state := range phaseStates {
+		fmt.Fprintf(&buf, "  classDef %v fill:%v\n", mermaidClass(state), phaseColor(state))
+	}
+	_, err := w.Write(buf.Bytes())
+	return trace.Wrap(err)
+}
+
+func writeDotPhase(w io.Writer, phase storage.OperationPhase, now time.Time, indent int) {
+	prefix := strings.Repeat("  ", indent)
+	state := phase.GetState()
+	if !phase.HasSubphases() {
+		fmt.Fprintf(w, "%v%q [label=%q, fillcolor=%q];\n",
+			prefix, phase.ID, graphLabel(phase, now), phaseColor(state))
+		return
+	}
+	fmt.Fprintf(w, "%vsubgraph %q {\n", prefix, "cluster_"+phase.ID)
+	fmt.Fprintf(w, "%v  label=%q;\n", prefix, graphLabel(phase, now))
+	fmt.Fprintf(w, "%v  style=\"rounded,filled\";\n", prefix)
+	fmt.Fprintf(w, "%v  fillcolor=%q;\n", prefix, phaseColor(state))
+	// the invisible node is the target of the dependencies on this phase
+	fmt.Fprintf(w, "%v  %q [shape=point, style=invis];\n", prefix, phase.ID)
+	for _, subphase := range phase.Phases {
+		writeDotPhase(w, subphase, now, indent+1)
+	}
+	fmt.Fprintf(w, "%v}\n", prefix)
+}
+
+func writeMermaidPhase(w io.Writer, phase storage.OperationPhase, ids map[string]string, now time.Time, indent int) {
+	prefix := strings.Repeat("  ", indent)
+	label := strings.Replace(graphLabel(phase, now), "\n", "<br/>", -1)
+	if !phase.HasSubphases() {
+		fmt.Fprintf(w, "%v%v[%q]:::%v\n",
+			prefix, ids[phase.ID], label, mermaidClass(phase.GetState()))
+		return
+	}
+	fmt.Fprintf(w, "%vsubgraph %v [%q]\n", prefix, ids[phase.ID], label)
+	for _, subphase := range phase.Phases {
+		writeMermaidPhase(w, subphase, ids, now, indent+1)
+	}
+	fmt.Fprintf(w, "%vend\n", prefix)
+	fmt.Fprintf(w, "%vstyle %v fill:%v\n", prefix, ids[phase.ID], phaseColor(phase.GetState()))
+}
+
+// graphLabel returns the graph label of the phase with its ID,
+// description, state and duration
+func graphLabel(phase storage.OperationPhase, now time.Time) string {
+	label := []string{phase.ID}
+	if phase.Description != "" {
+		// quotes break Mermaid labels
+		label = append(label, strings.Replace(phase.Description, `"`, "'", -1))
+	}
+	state := formatState(phase.GetState())
+	if duration := phaseDuration(phase, now); duration != 0 {
+		state = fmt.Sprintf("%v (%v)", state, duration)
+	}
+	return strings.Join(append(label, state), "\n")
+}
+
+// phaseDuration returns how long the phase has been executing
+// or zero if it has not been started
+func phaseDuration(phase storage.OperationPhase, now time.Time) time.Duration {
+	started := phase.GetStartTime()
+	if started.IsZero() {
+		return 0
+	}
+	switch phase.GetState() {
+	case storage.OperationPhaseStateUnstarted:
+		return 0
+	case storage.OperationPhaseStateInProgress:
+		return now.Sub(started).Round(time.Second)
+	default:
+		return phase.GetLastUpdateTime().Sub(started).Round(time.Second)
+	}
+}
+
+func phaseColor(state string) string {
+	switch state {
+	case storage.OperationPhaseStateInProgress:
+		return "#fff59d"
+	case storage.OperationPhaseStateCompleted:
+		return "#c8e6c9"
+	case storage.OperationPhaseStateFailed, storage.OperationPhaseStateRolledBack:
+		return "#ffcdd2"
+	default:
+		return "#eeeeee"
+	}
+}
+
+func mermaidClass(state string) string {
+	return strings.Replace(state, "_", "", -1)
+}
+
+// phaseStates lists the phase states that have distinct graph styles
+var phaseStates = []string{
+	storage.OperationPhaseStateUnstarted,
+	storage.OperationPhaseStateInProgress,
+	storage.OperationPhaseStateCompleted,
+	storage.OperationPhaseStateFailed,
+	storage.OperationPhaseStateRolledBack,
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	. "gopkg.in/check.v1"
+)
+
+type GraphSuite struct {
+	plan storage.OperationPlan
+	now  time.Time
+}
+
+var _ = Suite(&GraphSuite{})
+
+func (s *GraphSuite) SetUpTest(c *C) {
+	s.now = time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	plan := storage.OperationPlan{
+		OperationID: "op",
+		Phases: []storage.OperationPhase{
+			{ID: "/init", Description: "Initialize operation"},
+			{ID: "/masters", Description: "Update masters", Requires: []string{"/init"}, Phases: []storage.OperationPhase{
+				{ID: "/masters/node-1", Description: "Update node-1"},
+			}},
+			{ID: "/app", Description: "Update application", Requires: []string{"/masters"}},
+		},
+	}
+	changelog := storage.PlanChangelog{
+		{PhaseID: "/init", NewState: storage.OperationPhaseStateInProgress, Created: s.now.Add(-10 * time.Minute)},
+		{PhaseID: "/init", NewState: storage.OperationPhaseStateCompleted, Created: s.now.Add(-9 * time.Minute)},
+		{PhaseID: "/masters/node-1", NewState: storage.OperationPhaseStateInProgress, Created: s.now.Add(-5 * time.Minute)},
+	}
+	s.plan = *ResolvePlan(plan, changelog)
+}
+
+func (s *GraphSuite) TestResolvesPhaseDurations(c *C) {
+	c.Assert(phaseDuration(s.plan.Phases[0], s.now), Equals, time.Minute)
+	c.Assert(phaseDuration(s.plan.Phases[1], s.now), Equals, 5*time.Minute)
+	c.Assert(phaseDuration(s.plan.Phases[2], s.now), Equals, time.Duration(0))
+}
+
+func (s *GraphSuite) TestFormatsDot(c *C) {
+	var out bytes.Buffer
+	c.Assert(FormatOperationPlanDot(&out, s.plan, s.now), IsNil)
+	c.Assert(out.String(), Equals, `digraph "op" {
+  compound=true;
+  rankdir=LR;
+  node [shape=box, style="rounded,filled"];
+  "/init" [label="/init\nInitialize operation\nCompleted (1m0s)", fillcolor="#c8e6c9"];
+  subgraph "cluster_/masters" {
+    label="/masters\nUpdate masters\nIn Progress (5m0s)";
+    style="rounded,filled";
+    fillcolor="#fff59d";
+    "/masters" [shape=point, style=invis];
+    "/masters/node-1" [label="/masters/node-1\nUpdate node-1\nIn Progress (5m0s)", fillcolor="#fff59d"];
+  }
+  "/app" [label="/app\nUpdate application\nUnstarted", fillcolor="#eeeeee"];
+  "/init" -> "/masters" [lhead="cluster_/masters"];
+  "/masters" -> "/app" [ltail="cluster_/masters"];
+}
+`)
+}
+
+func (s *GraphSuite) TestFormatsMermaid(c *C) {
+	var out bytes.Buffer
+	c.Assert(FormatOperationPlanMermaid(&out, s.plan, s.now), IsNil)
+	c.Assert(out.String(), Equals, `flowchart LR
+  phase0["/init<br/>Initialize operation<br/>Completed (1m0s)"]:::completed
+  subgraph phase1 ["/masters<br/>Update masters<br/>In Progress (5m0s)"]
+    phase2["/masters/node-1<br/>Update node-1<br/>In Progress (5m0s)"]:::inprogress
+  end
+  style phase1 fill:#fff59d
+  phase3["/app<br/>Update application<br/>Unstarted"]:::unstarted
+  phase0 --> phase1
+  phase1 --> phase3
+  classDef unstarted fill:#eeeeee
+  classDef inprogress fill:#fff59d
+  classDef completed fill:#c8e6c9
+  classDef failed fill:#ffcdd2
+  classDef rolledback fill:#ffcdd2
+`)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// InterruptCheckFn returns the pending interrupt request of the operation:
+// either storage.OperationInterruptPause or storage.OperationInterruptCancel.
+// Empty result means the operation should continue
+type InterruptCheckFn func(context.Context) (string, error)
+
+// InterruptedError is returned when the plan execution has been stopped
+// because the operation was paused or cancelled
+type InterruptedError struct {
+	// Interrupt is the interrupt request that stopped the execution
+	Interrupt string
+	// Phase is the ID of the phase the execution stopped at
+	Phase string
+}
+
+// Error returns the error description
+func (e *InterruptedError) Error() string {
+	if e.Interrupt == storage.OperationInterruptPause {
+		return fmt.Sprintf("operation paused before phase %q, "+
+			"use 'gravity plan resume' to continue", e.Phase)
+	}
+	return fmt.Sprintf("operation cancelled at phase %q, "+
+		"use 'gravity plan rollback' to roll back the executed phases", e.Phase)
+}
+
+// IsInterrupted returns true if the error indicates that the plan
+// execution was paused or cancelled
+func IsInterrupted(err error) bool {
+	_, ok := trace.Unwrap(err).(*InterruptedError)
+	return ok
+}
+
+// IsPaused returns true if the error indicates that the plan
+// execution was paused
+func IsPaused(err error) bool {
+	interrupted, ok := trace.Unwrap(err).(*InterruptedError)
+	return ok && interrupted.Interrupt == storage.OperationInterruptPause
+}
+
+// IsCancelled returns true if the error indicates that the plan
+// execution was cancelled
+func IsCancelled(err error) bool {
+	interrupted, ok := trace.Unwrap(err).(*InterruptedError)
+	return ok && interrupted.Interrupt == storage.OperationInterruptCancel
+}
+
+// executeInterruptible executes the specified phase while watching for
+// a cancellation request. If the operation is cancelled, the phase is
+// aborted and rolled back
+func (f *FSM) executeInterruptible(ctx context.Context, p Params) error {
+	if f.interruptFn == nil {
+		return trace.Wrap(f.ExecutePhase(ctx, p))
+	}
+	phaseCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var cancelled bool
+	watchDone := make(chan struct{})
+	go func() {
+		defer close(watchDone)
+		cancelled = f.waitForCancel(phaseCtx)
+		if cancelled {
+			cancel()
+		}
+	}()
+	err := f.ExecutePhase(phaseCtx, p)
+	cancel()
+	<-watchDone
+	if !cancelled {
+		return trace.Wrap(err)
+	}
+	f.Warnf("Operation cancelled during phase %q, rolling it back.", p.PhaseID)
+	if err := f.rollbackInterrupted(ctx, p); err != nil {
+		f.Errorf("Failed to roll back phase %q: %v.", p.PhaseID, trace.DebugReport(err))
+	}
+	return &InterruptedError{
+		Interrupt: storage.OperationInterruptCancel,
+		Phase:     p.PhaseID,
+	}
+}
+
+// rollbackInterrupted rolls back the parts of the specified phase
+// that have been executed before the operation was cancelled
+func (f *FSM) rollbackInterrupted(ctx context.Context, p Params) error {
+	plan, err := f.GetPlan()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	phase, err := FindPhase(plan, p.PhaseID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if phase.IsUnstarted() || phase.IsRolledBack() {
+		return nil
+	}
+	p.Force = true
+	return trace.Wrap(f.RollbackPhase(ctx, p))
+}
+
+// waitForCancel polls the interrupt check until the operation is cancelled
+// or the context expires. Returns true if the operation has been cancelled
+func (f *FSM) waitForCancel(ctx context.Context) bool {
+	ticker := time.NewTicker(f.InterruptPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if f.checkInterrupt(ctx) == storage.OperationInterruptCancel {
+				return true
+			}
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// checkInterrupt returns the pending interrupt request of the operation.
+// Failure to query the request is not fatal as the operation might be
+// running while the cluster state is temporarily unavailable
+func (f *FSM) checkInterrupt(ctx context.Context) string {
+	if f.interruptFn == nil {
+		return ""
+	}
+	interrupt, err := f.interruptFn(ctx)
+	if err != nil {
+		f.WithError(err).Warn("Failed to check operation interrupt request.")
+		return ""
+	}
+	return interrupt
+}
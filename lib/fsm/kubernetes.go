@@ -17,97 +17,551 @@ limitations under the License.
 package fsm
 
 import (
+	"encoding/json"
+	"fmt"
+	"time"
+
 	"github.com/gravitational/gravity/lib/app/resources"
+	"github.com/gravitational/gravity/lib/constants"
 
+	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/gravitational/rigging"
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/mergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 // GetUpsertBootstrapResourceFunc returns a function that takes a Kubernetes
-// object representing a bootstrap resource (ClusterRole, ClusterRoleBinding
-// or PodSecurityPolicy) and creates or updates it using the provided client
+// object representing a bootstrap resource (RBAC resource, PodSecurityPolicy,
+// ServiceAccount, DaemonSet, Deployment, NetworkPolicy,
+// CustomResourceDefinition or custom resource) and applies it using
+// the provided client.
+//
+// New resources are created as-is. Existing resources are updated with
+// a three-way patch computed from the configuration applied last time,
+// the new configuration and the live object, so fields set by admission
+// controllers or other writers are preserved. Built-in resources are updated
+// with a strategic merge patch, custom resource definitions and custom
+// resources, which do not support it, with a JSON merge patch.
+// Changes made by other writers to the fields the new configuration also
+// changes are reported as a conflict
 func GetUpsertBootstrapResourceFunc(client *kubernetes.Clientset) resources.ResourceFunc {
-	return func(object runtime.Object) (err error) {
-		switch resource := object.(type) {
-		case *rbacv1.ClusterRole:
-			_, err = client.RbacV1().ClusterRoles().Create(resource)
-			if err == nil {
-				log.Debugf("Created ClusterRole %q.", resource.Name)
-				return nil
-			}
-			if !trace.IsAlreadyExists(rigging.ConvertError(err)) {
-				return trace.Wrap(rigging.ConvertError(err))
-			}
-			_, err = client.RbacV1().ClusterRoles().Update(resource)
-			if err != nil {
-				return trace.Wrap(rigging.ConvertError(err))
-			}
-			log.Debugf("Updated ClusterRole %q.", resource.Name)
-		case *rbacv1.ClusterRoleBinding:
-			_, err = client.RbacV1().ClusterRoleBindings().Create(resource)
-			if err == nil {
-				log.Debugf("Created ClusterRoleBinding %q.", resource.Name)
-				return nil
-			}
-			if !trace.IsAlreadyExists(rigging.ConvertError(err)) {
-				return trace.Wrap(rigging.ConvertError(err))
-			}
-			_, err = client.RbacV1().ClusterRoleBindings().Update(resource)
-			if err != nil {
-				return trace.Wrap(rigging.ConvertError(err))
-			}
-			log.Debugf("Updated ClusterRoleBinding %q.", resource.Name)
-		case *rbacv1.Role:
-			_, err = client.RbacV1().Roles(resource.Namespace).Create(resource)
-			if err == nil {
-				log.Debugf("Created Role %q.", resource.Name)
-				return nil
-			}
-			if !trace.IsAlreadyExists(rigging.ConvertError(err)) {
-				return trace.Wrap(rigging.ConvertError(err))
-			}
-			_, err = client.RbacV1().Roles(resource.Namespace).Update(resource)
-			if err != nil {
-				return trace.Wrap(rigging.ConvertError(err))
-			}
-			log.Debugf("Updated Role %q.", resource.Name)
-		case *rbacv1.RoleBinding:
-			_, err = client.RbacV1().RoleBindings(resource.Namespace).Create(resource)
-			if err == nil {
-				log.Debugf("Created RoleBinding %q.", resource.Name)
-				return nil
-			}
-			if !trace.IsAlreadyExists(rigging.ConvertError(err)) {
-				return trace.Wrap(rigging.ConvertError(err))
-			}
-			_, err = client.RbacV1().RoleBindings(resource.Namespace).Update(resource)
-			if err != nil {
-				return trace.Wrap(rigging.ConvertError(err))
+	return func(object runtime.Object) error {
+		resource, err := newBootstrapResource(object)
+		if err != nil {
+			log.Warnf("Unsupported bootstrap resource: %#v.", object)
+			return trace.Wrap(err)
+		}
+		// All resources are written using the discovery REST client which
+		// shares the transport and credentials of the clientset and is not
+		// bound to a particular API group
+		return trace.Wrap(resource.apply(client.Discovery().RESTClient()))
+	}
+}
+
+// DescribeBootstrapResource returns a description of the specified bootstrap
+// resource suitable for display. It accepts the same set of resources
+// as the function returned by GetUpsertBootstrapResourceFunc
+func DescribeBootstrapResource(object runtime.Object) (string, error) {
+	resource, err := newBootstrapResource(object)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return resource.String(), nil
+}
+
+// bootstrapResource describes a bootstrap resource and where it is served
+type bootstrapResource struct {
+	// object is the resource object
+	object runtime.Object
+	// gvk is the resource group, version and kind
+	gvk schema.GroupVersionKind
+	// resource is the plural API resource name
+	resource string
+	// namespaced is whether the resource is namespaced
+	namespaced bool
+	// name is the resource name
+	name string
+	// namespace is the resource namespace if it is namespaced
+	namespace string
+	// mergePatch is whether the resource is updated with a JSON merge patch
+	// instead of a strategic merge patch
+	mergePatch bool
+}
+
+func newBootstrapResource(object runtime.Object) (*bootstrapResource, error) {
+	var gvk schema.GroupVersionKind
+	var resource string
+	var namespaced, mergePatch bool
+	switch obj := object.(type) {
+	case *rbacv1.ClusterRole:
+		gvk, resource = rbacv1.SchemeGroupVersion.WithKind("ClusterRole"), "clusterroles"
+	case *rbacv1.ClusterRoleBinding:
+		gvk, resource = rbacv1.SchemeGroupVersion.WithKind("ClusterRoleBinding"), "clusterrolebindings"
+	case *rbacv1.Role:
+		gvk, resource, namespaced = rbacv1.SchemeGroupVersion.WithKind("Role"), "roles", true
+	case *rbacv1.RoleBinding:
+		gvk, resource, namespaced = rbacv1.SchemeGroupVersion.WithKind("RoleBinding"), "rolebindings", true
+	case *v1beta1.PodSecurityPolicy:
+		gvk, resource = v1beta1.SchemeGroupVersion.WithKind("PodSecurityPolicy"), "podsecuritypolicies"
+	case *corev1.ConfigMap:
+		gvk, resource, namespaced = corev1.SchemeGroupVersion.WithKind("ConfigMap"), "configmaps", true
+	case *corev1.ServiceAccount:
+		gvk, resource, namespaced = corev1.SchemeGroupVersion.WithKind("ServiceAccount"), "serviceaccounts", true
+	case *appsv1.DaemonSet:
+		gvk, resource, namespaced = appsv1.SchemeGroupVersion.WithKind("DaemonSet"), "daemonsets", true
+	case *appsv1.Deployment:
+		gvk, resource, namespaced = appsv1.SchemeGroupVersion.WithKind("Deployment"), "deployments", true
+	case *networkingv1.NetworkPolicy:
+		gvk, resource, namespaced = networkingv1.SchemeGroupVersion.WithKind("NetworkPolicy"), "networkpolicies", true
+	case *apiextensionsv1beta1.CustomResourceDefinition:
+		gvk, resource = apiextensionsv1beta1.SchemeGroupVersion.WithKind("CustomResourceDefinition"), "customresourcedefinitions"
+		mergePatch = true
+	case *resources.Unknown:
+		// Kinds not known to the decoder are expected to be custom resources
+		custom, err := newCustomResource(obj)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		object = custom
+		gvk = custom.GroupVersionKind()
+		plural, _ := meta.UnsafeGuessKindToResource(gvk)
+		// The scope of a custom resource is defined by its definition
+		// which might not exist yet, so it is inferred from the object
+		resource, namespaced, mergePatch = plural.Resource, custom.GetNamespace() != "", true
+	default:
+		return nil, trace.BadParameter("Unsupported bootstrap resource: %#v.", object.GetObjectKind().GroupVersionKind())
+	}
+	object = object.DeepCopyObject()
+	object.GetObjectKind().SetGroupVersionKind(gvk)
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	r := &bootstrapResource{
+		object:     object,
+		gvk:        gvk,
+		resource:   resource,
+		namespaced: namespaced,
+		name:       accessor.GetName(),
+		mergePatch: mergePatch,
+	}
+	if namespaced {
+		r.namespace = accessor.GetNamespace()
+		if r.namespace == "" {
+			r.namespace = metav1.NamespaceDefault
+		}
+		accessor.SetNamespace(r.namespace)
+	}
+	return r, nil
+}
+
+// newCustomResource returns the custom resource described by the specified
+// object of a kind unknown to the decoder
+func newCustomResource(object *resources.Unknown) (*unstructured.Unstructured, error) {
+	gv, err := schema.ParseGroupVersion(object.APIVersion)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if gv.Group == "" || object.Kind == "" {
+		return nil, trace.BadParameter("Unsupported bootstrap resource: %#v.", object.GetObjectKind().GroupVersionKind())
+	}
+	var custom unstructured.Unstructured
+	if err := custom.UnmarshalJSON(object.Raw); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &custom, nil
+}
+
+// String returns a textual description of this resource
+func (r bootstrapResource) String() string {
+	if r.namespaced {
+		return fmt.Sprintf("%v %q in namespace %q", r.gvk.Kind, r.name, r.namespace)
+	}
+	return fmt.Sprintf("%v %q", r.gvk.Kind, r.name)
+}
+
+// apply creates this resource or patches the existing one.
+// It is retried if the resource is created or modified by another writer
+// between reading and writing it
+func (r bootstrapResource) apply(client rest.Interface) (err error) {
+	modified, err := r.modifiedConfiguration()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	delay := applyRetryInitialDelay
+	for attempt := 1; attempt <= applyAttempts; attempt++ {
+		err = r.tryApply(client, modified)
+		if !isConcurrentModification(err) || attempt == applyAttempts {
+			break
+		}
+		log.Debugf("%v has been modified concurrently, will retry in %v.", r, delay)
+		// Back off to let the other writer finish its update
+		time.Sleep(delay)
+		delay *= 2
+	}
+	return trace.Wrap(rigging.ConvertError(trace.Unwrap(err)))
+}
+
+func (r bootstrapResource) tryApply(client rest.Interface, modified []byte) error {
+	current, err := r.get(client)
+	if err != nil {
+		if !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(r.create(client, modified))
+	}
+	return trace.Wrap(r.patch(client, modified, current))
+}
+
+func (r bootstrapResource) get(client rest.Interface) ([]byte, error) {
+	current, err := client.Get().
+		AbsPath(r.path(r.name)...).
+		Do().
+		Raw()
+	if err != nil {
+		return nil, trace.Wrap(rigging.ConvertError(err))
+	}
+	return current, nil
+}
+
+func (r bootstrapResource) create(client rest.Interface, modified []byte) error {
+	err := client.Post().
+		AbsPath(r.path()...).
+		Param("fieldManager", constants.FieldManager).
+		SetHeader("Content-Type", "application/json").
+		Body(modified).
+		Do().
+		Error()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	log.Debugf("Created %v.", r)
+	return nil
+}
+
+func (r bootstrapResource) patch(client rest.Interface, modified, current []byte) error {
+	original, err := lastAppliedConfiguration(current)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// The last applied configuration changes with every apply and is not
+	// subject to conflict detection
+	currentConfig, err := withoutLastAppliedConfiguration(current)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// Without the last applied configuration the resource has been created
+	// before it was managed with three-way merge and there is nothing
+	// to detect conflicts against, so the new configuration takes precedence
+	overwrite := original == nil
+	patch, patchType, err := r.createPatch(original, modified, currentConfig, overwrite)
+	if err != nil {
+		if mergepatch.IsConflict(trace.Unwrap(err)) {
+			return trace.CompareFailed("%v has been modified by another writer: %v", r, err)
+		}
+		return trace.Wrap(err)
+	}
+	if string(patch) == "{}" {
+		log.Debugf("%v is up to date.", r)
+		return nil
+	}
+	// Make the server reject the patch if the resource has been
+	// modified since it was read
+	patch, err = withResourceVersion(patch, current)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = client.Patch(patchType).
+		AbsPath(r.path(r.name)...).
+		Param("fieldManager", constants.FieldManager).
+		Body(patch).
+		Do().
+		Error()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	log.Debugf("Updated %v.", r)
+	return nil
+}
+
+// createPatch returns the three-way patch that updates the current
+// configuration of this resource to the modified one along with its type
+func (r bootstrapResource) createPatch(original, modified, current []byte, overwrite bool) ([]byte, types.PatchType, error) {
+	if r.mergePatch {
+		patch, err := createThreeWayJSONMergePatch(original, modified, current, overwrite)
+		if err != nil {
+			return nil, "", trace.Wrap(err)
+		}
+		return patch, types.MergePatchType, nil
+	}
+	patchMeta, err := strategicpatch.NewPatchMetaFromStruct(r.object)
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	patch, err := strategicpatch.CreateThreeWayMergePatch(original, modified, current, patchMeta, overwrite)
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	return patch, types.StrategicMergePatchType, nil
+}
+
+// createThreeWayJSONMergePatch returns the JSON merge patch that deletes
+// the fields present in the original configuration but missing from
+// the modified one and adds or changes the fields that differ between
+// the current and modified configurations.
+//
+// Unless overwrite is set, a mergepatch.ErrConflict error is returned if
+// the changes made to the original configuration by other writers
+// conflict with the patch
+func createThreeWayJSONMergePatch(original, modified, current []byte, overwrite bool) ([]byte, error) {
+	if original == nil {
+		original = []byte("{}")
+	}
+	currentToModified, err := createMergePatch(current, modified)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	originalToModified, err := createMergePatch(original, modified)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// Only delete the fields this writer has set before
+	patch := filterMergePatch(originalToModified, true)
+	for key, value := range filterMergePatch(currentToModified, false) {
+		patch[key] = mergeValues(patch[key], value)
+	}
+	if !overwrite {
+		originalToCurrent, err := createMergePatch(original, current)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		conflicts, err := mergepatch.HasConflicts(patch, originalToCurrent)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if conflicts {
+			return nil, mergepatch.NewErrConflict(mergepatch.ToYAMLOrError(patch),
+				mergepatch.ToYAMLOrError(originalToCurrent))
+		}
+	}
+	return json.Marshal(patch)
+}
+
+// createMergePatch returns the JSON merge patch that transforms
+// the original document to the modified one
+func createMergePatch(original, modified []byte) (map[string]interface{}, error) {
+	data, err := jsonpatch.CreateMergePatch(original, modified)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var patch map[string]interface{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return patch, nil
+}
+
+// filterMergePatch returns the deletions (null values) of the specified
+// JSON merge patch if deletions is set or its additions and changes otherwise
+func filterMergePatch(patch map[string]interface{}, deletions bool) map[string]interface{} {
+	result := make(map[string]interface{})
+	for key, value := range patch {
+		switch value := value.(type) {
+		case nil:
+			if deletions {
+				result[key] = nil
 			}
-			log.Debugf("Updated RoleBinding %q.", resource.Name)
-		case *v1beta1.PodSecurityPolicy:
-			_, err = client.Extensions().PodSecurityPolicies().Create(resource)
-			if err == nil {
-				log.Debugf("Created PodSecurityPolicy %q.", resource.Name)
-				return nil
+		case map[string]interface{}:
+			filtered := filterMergePatch(value, deletions)
+			if len(filtered) != 0 || (!deletions && len(value) == 0) {
+				result[key] = filtered
 			}
-			if !trace.IsAlreadyExists(rigging.ConvertError(err)) {
-				return trace.Wrap(rigging.ConvertError(err))
+		default:
+			if !deletions {
+				result[key] = value
 			}
-			_, err = client.Extensions().PodSecurityPolicies().Update(resource)
-			if err != nil {
-				return trace.Wrap(rigging.ConvertError(err))
+		}
+	}
+	return result
+}
+
+// mergeValues merges the values of the same field from the deletions
+// and additions parts of a JSON merge patch
+func mergeValues(deletions, additions interface{}) interface{} {
+	deletionsMap, ok := deletions.(map[string]interface{})
+	if !ok {
+		return additions
+	}
+	additionsMap, ok := additions.(map[string]interface{})
+	if !ok {
+		return additions
+	}
+	for key, value := range additionsMap {
+		deletionsMap[key] = mergeValues(deletionsMap[key], value)
+	}
+	return deletionsMap
+}
+
+// modifiedConfiguration returns the JSON configuration of this resource
+// annotated with itself as the last applied configuration
+func (r bootstrapResource) modifiedConfiguration() ([]byte, error) {
+	accessor, err := meta.Accessor(r.object)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	annotations := accessor.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	delete(annotations, constants.AnnotationLastAppliedConfiguration)
+	accessor.SetAnnotations(annotations)
+	config, err := encodeConfiguration(r.object)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	annotations[constants.AnnotationLastAppliedConfiguration] = string(config)
+	accessor.SetAnnotations(annotations)
+	modified, err := encodeConfiguration(r.object)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return modified, nil
+}
+
+// path returns the API path of the collection of resources of this kind
+// followed by the optional name segment
+func (r bootstrapResource) path(name ...string) []string {
+	segments := []string{"/apis", r.gvk.Group, r.gvk.Version}
+	if r.gvk.Group == "" {
+		segments = []string{"/api", r.gvk.Version}
+	}
+	if r.namespaced {
+		segments = append(segments, "namespaces", r.namespace)
+	}
+	segments = append(segments, r.resource)
+	return append(segments, name...)
+}
+
+// encodeConfiguration encodes the object as JSON configuration
+// without the status and unset fields
+func encodeConfiguration(object runtime.Object) ([]byte, error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	delete(config, "status")
+	removeNulls(config)
+	data, err = json.Marshal(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return data, nil
+}
+
+// removeNulls recursively removes null values from the specified map
+func removeNulls(values map[string]interface{}) {
+	for key, value := range values {
+		switch value := value.(type) {
+		case nil:
+			delete(values, key)
+		case map[string]interface{}:
+			removeNulls(value)
+		case []interface{}:
+			for _, item := range value {
+				if item, ok := item.(map[string]interface{}); ok {
+					removeNulls(item)
+				}
 			}
-			log.Debugf("Updated PodSecurityPolicy %q.", resource.Name)
-		default:
-			log.Warnf("Unsupported bootstrap resource: %#v.", resource)
-			return trace.BadParameter("Unsupported bootstrap resource: %#v.", resource.GetObjectKind().GroupVersionKind())
 		}
-		return nil
 	}
 }
+
+// lastAppliedConfiguration returns the last applied configuration
+// of the specified live object or nil if it has none
+func lastAppliedConfiguration(current []byte) ([]byte, error) {
+	var object objectMetadata
+	if err := json.Unmarshal(current, &object); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	config, ok := object.Metadata.Annotations[constants.AnnotationLastAppliedConfiguration]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(config), nil
+}
+
+// withoutLastAppliedConfiguration returns the specified live object
+// without the last applied configuration annotation
+func withoutLastAppliedConfiguration(current []byte) ([]byte, error) {
+	var object map[string]interface{}
+	if err := json.Unmarshal(current, &object); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	metadata, _ := object["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	delete(annotations, constants.AnnotationLastAppliedConfiguration)
+	return json.Marshal(object)
+}
+
+// withResourceVersion adds the resource version of the live object
+// to the specified patch
+func withResourceVersion(patch, current []byte) ([]byte, error) {
+	var object objectMetadata
+	if err := json.Unmarshal(current, &object); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var patchMap map[string]interface{}
+	if err := json.Unmarshal(patch, &patchMap); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	metadata, _ := patchMap["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+		patchMap["metadata"] = metadata
+	}
+	metadata["resourceVersion"] = object.Metadata.ResourceVersion
+	return json.Marshal(patchMap)
+}
+
+// isConcurrentModification returns true if the error indicates that
+// the resource has been created or modified by another writer
+func isConcurrentModification(err error) bool {
+	err = trace.Unwrap(err)
+	return errors.IsAlreadyExists(err) || errors.IsConflict(err)
+}
+
+// objectMetadata is used to decode metadata of a live object
+type objectMetadata struct {
+	// Metadata is the object metadata
+	Metadata metav1.ObjectMeta `json:"metadata"`
+}
+
+const (
+	// applyAttempts is the number of times a bootstrap resource patch
+	// is attempted when the resource is modified concurrently
+	applyAttempts = 5
+	// applyRetryInitialDelay is the delay before the first retry of
+	// a bootstrap resource patch, doubled with every subsequent retry
+	applyRetryInitialDelay = 100 * time.Millisecond
+)
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
 
@@ -38,6 +39,9 @@ type Logger struct {
 	Operator ops.Operator
 	// Server is the optional server that will be attached to log entries
 	Server *storage.Server
+	// PhaseID is the optional ID of the phase that will be attached to log entries.
+	// If unspecified, the phase set as a field of the underlying logger is used
+	PhaseID string
 }
 
 // Debug logs a debug message
@@ -119,6 +123,20 @@ func (l *Logger) makeLogEntry(message, severity string) ops.LogEntry {
 		Severity:    severity,
 		Message:     message,
 		Server:      l.Server,
+		PhaseID:     l.phaseID(),
 		Created:     time.Now().UTC(),
 	}
 }
+
+// phaseID returns the ID of the phase this logger logs for
+func (l *Logger) phaseID() string {
+	if l.PhaseID != "" {
+		return l.PhaseID
+	}
+	if entry, ok := l.FieldLogger.(*logrus.Entry); ok {
+		if phaseID, ok := entry.Data[constants.FieldPhase].(string); ok {
+			return phaseID
+		}
+	}
+	return ""
+}
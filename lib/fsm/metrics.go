@@ -0,0 +1,31 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var phaseRetries = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "fsm_phase_retries_total",
+		Help: "Number of times operation phases have been re-executed after a previous attempt",
+	},
+	[]string{"executor"},
+)
+
+func init() {
+	prometheus.MustRegister(phaseRetries)
+}
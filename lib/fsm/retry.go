@@ -0,0 +1,76 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// retryDelay returns the delay before the next attempt to execute a phase
+// with the specified retry policy after the specified attempt has failed
+// with the provided error.
+// The returned flag is false if the phase should not be retried
+func retryDelay(policy *storage.RetryPolicy, attempt int, err error) (time.Duration, bool) {
+	if policy == nil || attempt >= policy.MaxAttempts || !isRetryable(policy.RetryOn, err) {
+		return 0, false
+	}
+	delay := policy.InitialBackoff
+	if delay == 0 {
+		delay = defaults.PhaseRetryInitialBackoff
+	}
+	maxDelay := policy.MaxBackoff
+	if maxDelay == 0 {
+		maxDelay = defaults.PhaseRetryMaxBackoff
+	}
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay, true
+}
+
+// isRetryable returns true if the error belongs to any of the specified
+// error classes or to the default retried classes if none are specified
+func isRetryable(classes []string, err error) bool {
+	if len(classes) == 0 {
+		classes = []string{
+			storage.RetryOnConnectionProblem,
+			storage.RetryOnLimitExceeded,
+			storage.RetryOnRetryError,
+		}
+	}
+	if utils.StringInSlice(classes, storage.RetryOnAny) {
+		return true
+	}
+	switch {
+	case trace.IsConnectionProblem(err):
+		return utils.StringInSlice(classes, storage.RetryOnConnectionProblem)
+	case trace.IsLimitExceeded(err):
+		return utils.StringInSlice(classes, storage.RetryOnLimitExceeded)
+	case trace.IsRetryError(err):
+		return utils.StringInSlice(classes, storage.RetryOnRetryError)
+	}
+	return false
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package fsm
 
 import (
+	"strings"
+
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
@@ -79,6 +81,72 @@ func IsFailed(plan *storage.OperationPlan) bool {
 	return true
 }
 
+// subphaseDependencies returns the dependency graph of subphases of the specified
+// phase as a list of sibling indexes each subphase requires. A subphase requires
+// a sibling if it or any of its own subphases requires the sibling or any phase
+// nested under the sibling
+func subphaseDependencies(phase storage.OperationPhase) ([][]int, error) {
+	deps := make([][]int, len(phase.Phases))
+	for i := range phase.Phases {
+		requires := collectRequires(phase.Phases[i])
+		for j, sibling := range phase.Phases {
+			if i == j {
+				continue
+			}
+			for _, required := range requires {
+				if required == sibling.ID || strings.HasPrefix(required, sibling.ID+"/") {
+					deps[i] = append(deps[i], j)
+					break
+				}
+			}
+		}
+	}
+	// 0 - unvisited, 1 - being visited, 2 - visited
+	visited := make([]int, len(deps))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch visited[i] {
+		case 1:
+			return trace.BadParameter("phase %q has cyclic requirements", phase.Phases[i].ID)
+		case 2:
+			return nil
+		}
+		visited[i] = 1
+		for _, j := range deps[i] {
+			if err := visit(j); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		visited[i] = 2
+		return nil
+	}
+	for i := range deps {
+		if err := visit(i); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return deps, nil
+}
+
+// collectRequires returns requirements of the specified phase and all its subphases
+func collectRequires(phase storage.OperationPhase) (requires []string) {
+	requires = append(requires, phase.Requires...)
+	for _, subphase := range phase.Phases {
+		requires = append(requires, collectRequires(subphase)...)
+	}
+	return requires
+}
+
+// allCompleted returns true if all of the specified indexes are marked completed
+func allCompleted(indexes []int, completed []bool) bool {
+	for _, i := range indexes {
+		if !completed[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // FindPhase finds a phase with the specified id in the provided plan
 func FindPhase(plan *storage.OperationPlan, phaseID string) (*storage.OperationPhase, error) {
 	allPhases := FlattenPlan(plan)
@@ -123,6 +191,11 @@ func ResolvePlan(plan storage.OperationPlan, changelog storage.PlanChangelog) *s
 			allPhases[i].Updated = latest.Created
 			allPhases[i].Error = latest.Error
 		}
+		started := changelog.LatestInState(phase.ID, storage.OperationPhaseStateInProgress)
+		if started != nil {
+			allPhases[i].Started = started.Created
+		}
+		allPhases[i].Attempts = changelog.Attempts(phase.ID)
 	}
 	return &plan
 }
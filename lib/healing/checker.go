@@ -0,0 +1,92 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healing
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/status"
+
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/trace"
+)
+
+// NewPlanetChecker returns a checker that detects problems
+// from the probes reported by the planet agents
+func NewPlanetChecker() Checker {
+	return planetChecker{}
+}
+
+type planetChecker struct{}
+
+// Check returns the problems reported by the planet agents.
+// It implements Checker
+func (planetChecker) Check(ctx context.Context) ([]Problem, error) {
+	systemStatus, err := status.PlanetAgentStatus(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return problemsFromProbes(status.FailedProbes(*systemStatus)), nil
+}
+
+// problemsFromProbes converts the failed probes of the monitored
+// components into problems ordered by node
+func problemsFromProbes(probes map[string][]agentpb.Probe) (problems []Problem) {
+	var nodes []string
+	for node := range probes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		for _, probe := range probes[node] {
+			component, ok := componentForChecker(probe.Checker)
+			if !ok {
+				continue
+			}
+			detail := probe.Detail
+			if probe.Error != "" {
+				detail = strings.TrimSpace(strings.Join([]string{detail, probe.Error}, " "))
+			}
+			problems = append(problems, Problem{
+				Component: component,
+				Node:      node,
+				Detail:    detail,
+			})
+		}
+	}
+	return problems
+}
+
+// componentForChecker returns the component monitored by the checker
+// with the specified name
+func componentForChecker(checker string) (Component, bool) {
+	checker = strings.ToLower(checker)
+	switch {
+	case strings.HasPrefix(checker, "etcd"):
+		return ComponentEtcd, true
+	case strings.Contains(checker, "registry"):
+		return ComponentRegistry, true
+	case strings.Contains(checker, "dns"):
+		return ComponentDNS, true
+	case checker == "networking", strings.Contains(checker, "flannel"),
+		strings.Contains(checker, "overlay"):
+		return ComponentOverlay, true
+	}
+	return "", false
+}
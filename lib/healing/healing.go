@@ -0,0 +1,461 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package healing implements the cluster health controller that watches
+// the health of cluster components and executes automated remediations
+// permitted by the cluster healing policy
+package healing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/events"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/sirupsen/logrus"
+)
+
+// Component names a monitored cluster component
+type Component string
+
+const (
+	// ComponentEtcd is the etcd cluster
+	ComponentEtcd Component = "etcd"
+	// ComponentRegistry is the cluster docker registry
+	ComponentRegistry Component = "registry"
+	// ComponentDNS is the cluster DNS service
+	ComponentDNS Component = "dns"
+	// ComponentOverlay is the overlay network
+	ComponentOverlay Component = "overlay"
+)
+
+// Problem describes a failed health check of a cluster component
+type Problem struct {
+	// Component is the failed component
+	Component Component
+	// Node is the advertise IP of the node that has reported the problem
+	Node string
+	// Detail describes the problem
+	Detail string
+}
+
+// String returns a textual representation of this problem
+func (r Problem) String() string {
+	return fmt.Sprintf("%v on %v: %v", r.Component, r.Node, r.Detail)
+}
+
+// Checker detects problems with cluster components
+type Checker interface {
+	// Check returns the list of currently detected problems
+	Check(context.Context) ([]Problem, error)
+}
+
+// Remediator executes remediations
+type Remediator interface {
+	// RestartPlanet restarts the planet container on the specified node
+	RestartPlanet(ctx context.Context, cluster ops.Site, node storage.Server) error
+	// ResyncRegistry pushes the cluster application images into the cluster registry
+	ResyncRegistry(ctx context.Context, cluster ops.Site) error
+	// RecreateDNSConfig recreates the cluster DNS configuration if it is
+	// missing and restarts the cluster DNS service
+	RecreateDNSConfig(ctx context.Context, cluster ops.Site) error
+}
+
+// Config defines the controller configuration
+type Config struct {
+	// Operator is the cluster operator service
+	Operator ops.Operator
+	// Checker detects problems with cluster components
+	Checker Checker
+	// Remediator executes remediations
+	Remediator Remediator
+	// Interval is the health check interval
+	Interval time.Duration
+	// Clock is used to track failure and remediation times
+	Clock clockwork.Clock
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (r *Config) CheckAndSetDefaults() error {
+	if r.Operator == nil {
+		return trace.BadParameter("missing Operator")
+	}
+	if r.Checker == nil {
+		return trace.BadParameter("missing Checker")
+	}
+	if r.Remediator == nil {
+		return trace.BadParameter("missing Remediator")
+	}
+	if r.Interval == 0 {
+		r.Interval = defaults.HealingCheckInterval
+	}
+	if r.Clock == nil {
+		r.Clock = clockwork.NewRealClock()
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = logrus.WithField(trace.Component, "healing")
+	}
+	return nil
+}
+
+// New returns a new cluster health controller
+func New(config Config) (*Controller, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Controller{
+		Config:       config,
+		failures:     make(map[problemKey]int),
+		remediations: make(map[actionKey]time.Time),
+	}, nil
+}
+
+// Controller periodically checks the health of cluster components and
+// executes remediations for the problems that persist
+type Controller struct {
+	// Config is the controller configuration
+	Config
+	// failures counts consecutive failed checks per problem
+	failures map[problemKey]int
+	// remediations records the time of the last remediation per node
+	remediations map[actionKey]time.Time
+}
+
+// Run checks the cluster health periodically until the context is cancelled
+func (r *Controller) Run(ctx context.Context) error {
+	r.Info("Starting cluster health controller.")
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	ctx = context.WithValue(ctx, constants.UserContext, constants.ServiceHealingController)
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Check(ctx); err != nil {
+				r.Errorf("Cluster health check failed: %v.", trace.DebugReport(err))
+			}
+		case <-ctx.Done():
+			r.Info("Stopping cluster health controller.")
+			return nil
+		}
+	}
+}
+
+// Check runs a single health check and executes remediations for
+// the problems that have reached the failure threshold
+func (r *Controller) Check(ctx context.Context) error {
+	cluster, err := r.Operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// Do not interfere with operations in progress
+	switch cluster.State {
+	case ops.SiteStateActive, ops.SiteStateDegraded:
+	default:
+		r.Debugf("Health checks are paused, cluster is %v.", cluster.State)
+		r.failures = make(map[problemKey]int)
+		return nil
+	}
+	policy, err := r.getPolicy(cluster.Key())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	problems, err := r.Checker.Check(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	persistent := r.recordFailures(problems, policy.GetFailureThreshold())
+	if len(persistent) == 0 {
+		return nil
+	}
+	if !policy.IsEnabled() {
+		r.Warnf("Detected problems %v, automated remediations are disabled.", persistent)
+		return nil
+	}
+	// Planet is restarted on at most one node at a time to avoid
+	// taking down several etcd members at once
+	var restarted bool
+	for _, action := range planActions(persistent, cluster.ClusterState.Servers) {
+		if action.node != nil && restarted {
+			r.Debugf("Postpone %v until the next check.", action)
+			continue
+		}
+		if r.execute(ctx, *cluster, policy, action) && action.node != nil {
+			restarted = true
+		}
+	}
+	return nil
+}
+
+// recordFailures updates the consecutive failure counters with the specified
+// problems and returns the problems that have reached the threshold
+func (r *Controller) recordFailures(problems []Problem, threshold int) (persistent []Problem) {
+	failures := make(map[problemKey]int)
+	for _, problem := range problems {
+		key := problemKey{component: problem.Component, node: problem.Node}
+		if _, ok := failures[key]; ok {
+			continue
+		}
+		failures[key] = r.failures[key] + 1
+		if failures[key] >= threshold {
+			persistent = append(persistent, problem)
+		}
+	}
+	r.failures = failures
+	return persistent
+}
+
+// execute runs the specified action unless the policy or the cooldown forbids it.
+// Every executed or skipped action is recorded in the audit log.
+// Returns true if the remediation has been attempted
+func (r *Controller) execute(ctx context.Context, cluster ops.Site, policy storage.HealingPolicy, action action) bool {
+	key := actionKey{remediation: action.remediation, node: action.nodeAddr()}
+	if last, ok := r.remediations[key]; ok && r.Clock.Now().Sub(last) < policy.GetCooldown() {
+		r.Debugf("Skip %v: last executed at %v.", action, last)
+		return false
+	}
+	r.remediations[key] = r.Clock.Now()
+	fields := action.fields()
+	switch {
+	case action.reason != "":
+		r.Warnf("Will not %v: %v.", action, action.reason)
+		events.Emit(ctx, r.Operator, events.RemediationSkipped, fields.WithField(
+			events.FieldReason, action.reason))
+		return false
+	case !policy.IsAllowed(action.remediation):
+		r.Warnf("Will not %v: not permitted by the healing policy.", action)
+		events.Emit(ctx, r.Operator, events.RemediationSkipped, fields.WithField(
+			events.FieldReason, "not permitted by the healing policy"))
+		return false
+	case policy.IsDryRun():
+		r.Infof("Would %v (dry run).", action)
+		events.Emit(ctx, r.Operator, events.RemediationSkipped, fields.WithField(
+			events.FieldReason, "dry run"))
+		return false
+	}
+	r.Infof("Will %v.", action)
+	if err := r.remediate(ctx, cluster, action); err != nil {
+		r.Errorf("Failed to %v: %v.", action, trace.DebugReport(err))
+		events.Emit(ctx, r.Operator, events.RemediationFailed, fields.WithField(
+			events.FieldError, trace.UserMessage(err)))
+		return true
+	}
+	events.Emit(ctx, r.Operator, events.RemediationSucceeded, fields)
+	// Start counting failures anew to give the component time to recover
+	for _, problem := range action.problems {
+		delete(r.failures, problemKey{component: problem.Component, node: problem.Node})
+	}
+	return true
+}
+
+func (r *Controller) remediate(ctx context.Context, cluster ops.Site, action action) error {
+	switch action.remediation {
+	case storage.RemediationRestartPlanet:
+		return r.Remediator.RestartPlanet(ctx, cluster, *action.node)
+	case storage.RemediationResyncRegistry:
+		return r.Remediator.ResyncRegistry(ctx, cluster)
+	case storage.RemediationRecreateDNSConfig:
+		return r.Remediator.RecreateDNSConfig(ctx, cluster)
+	}
+	return trace.BadParameter("unsupported remediation %q", action.remediation)
+}
+
+// getPolicy returns the configured healing policy or the default
+// policy if none has been configured
+func (r *Controller) getPolicy(key ops.SiteKey) (storage.HealingPolicy, error) {
+	policy, err := r.Operator.GetHealingPolicy(key)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if trace.IsNotFound(err) {
+		return storage.DefaultHealingPolicy(), nil
+	}
+	return policy, nil
+}
+
+// planActions returns the remediations for the specified problems.
+//
+// If the problems indicate that etcd has lost quorum, restarting planet
+// will not help and the action is reported as skipped
+func planActions(problems []Problem, servers []storage.Server) (actions []action) {
+	var cluster []Problem
+	nodes := make(map[string][]Problem)
+	for _, problem := range problems {
+		switch problem.Component {
+		case ComponentRegistry, ComponentDNS:
+			cluster = append(cluster, problem)
+		default:
+			nodes[problem.Node] = append(nodes[problem.Node], problem)
+		}
+	}
+	actions = append(actions, clusterActions(cluster)...)
+	if len(nodes) == 0 {
+		return actions
+	}
+	if etcdQuorumLost(problems, servers) {
+		for _, problem := range problems {
+			if problem.Component == ComponentEtcd {
+				return append(actions, action{
+					remediation: storage.RemediationRestartPlanet,
+					component:   ComponentEtcd,
+					problems:    []Problem{problem},
+					reason:      "etcd cluster has lost quorum, manual intervention is required",
+				})
+			}
+		}
+	}
+	var addrs []string
+	for addr := range nodes {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	for _, addr := range addrs {
+		server := findServer(servers, addr)
+		if server == nil {
+			continue
+		}
+		actions = append(actions, action{
+			remediation: storage.RemediationRestartPlanet,
+			component:   nodes[addr][0].Component,
+			node:        server,
+			problems:    nodes[addr],
+		})
+	}
+	return actions
+}
+
+func clusterActions(problems []Problem) (actions []action) {
+	byComponent := make(map[Component][]Problem)
+	for _, problem := range problems {
+		byComponent[problem.Component] = append(byComponent[problem.Component], problem)
+	}
+	if problems, ok := byComponent[ComponentRegistry]; ok {
+		actions = append(actions, action{
+			remediation: storage.RemediationResyncRegistry,
+			component:   ComponentRegistry,
+			problems:    problems,
+		})
+	}
+	if problems, ok := byComponent[ComponentDNS]; ok {
+		actions = append(actions, action{
+			remediation: storage.RemediationRecreateDNSConfig,
+			component:   ComponentDNS,
+			problems:    problems,
+		})
+	}
+	return actions
+}
+
+// etcdQuorumLost returns true if the etcd members failing the health
+// checks leave the cluster without quorum
+func etcdQuorumLost(problems []Problem, servers []storage.Server) bool {
+	failed := make(map[string]struct{})
+	for _, problem := range problems {
+		if problem.Component == ComponentEtcd {
+			failed[problem.Node] = struct{}{}
+		}
+	}
+	if len(failed) == 0 {
+		return false
+	}
+	var members, healthy int
+	for _, server := range servers {
+		if !server.IsMaster() {
+			continue
+		}
+		members++
+		if _, ok := failed[server.AdvertiseIP]; !ok {
+			healthy++
+		}
+	}
+	return healthy < members/2+1
+}
+
+func findServer(servers []storage.Server, addr string) *storage.Server {
+	for _, server := range servers {
+		if server.AdvertiseIP == addr {
+			server := server
+			return &server
+		}
+	}
+	return nil
+}
+
+// action describes a remediation planned for a set of problems
+type action struct {
+	// remediation is the remediation to execute
+	remediation string
+	// component is the component the remediation is executed for
+	component Component
+	// node is the node to execute the remediation on.
+	// Empty for cluster-wide remediations
+	node *storage.Server
+	// problems lists the problems the remediation addresses
+	problems []Problem
+	// reason is set if the remediation cannot be executed
+	reason string
+}
+
+// String returns a textual representation of this action
+func (r action) String() string {
+	if r.node != nil {
+		return fmt.Sprintf("%v on %v to fix %v", r.remediation, r.node.AdvertiseIP, r.problems)
+	}
+	return fmt.Sprintf("%v to fix %v", r.remediation, r.problems)
+}
+
+// nodeAddr returns the advertise IP of the node the action is executed on
+// or an empty string for cluster-wide actions
+func (r action) nodeAddr() string {
+	if r.node == nil {
+		return ""
+	}
+	return r.node.AdvertiseIP
+}
+
+func (r action) fields() events.Fields {
+	fields := events.Fields{
+		events.FieldRemediation: r.remediation,
+		events.FieldComponent:   string(r.component),
+	}
+	if r.node != nil {
+		fields[events.FieldNodeIP] = r.node.AdvertiseIP
+		fields[events.FieldNodeHostname] = r.node.Hostname
+	}
+	if len(r.problems) != 0 {
+		fields[events.FieldDetail] = r.problems[0].Detail
+	}
+	return fields
+}
+
+type problemKey struct {
+	component Component
+	node      string
+}
+
+type actionKey struct {
+	remediation string
+	node        string
+}
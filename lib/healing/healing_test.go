@@ -0,0 +1,281 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/events"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/sirupsen/logrus"
+	check "gopkg.in/check.v1"
+)
+
+func TestHealing(t *testing.T) { check.TestingT(t) }
+
+type HealingSuite struct {
+	operator   *testOperator
+	checker    *testChecker
+	remediator *testRemediator
+	clock      clockwork.FakeClock
+	controller *Controller
+}
+
+var _ = check.Suite(&HealingSuite{})
+
+func (s *HealingSuite) SetUpTest(c *check.C) {
+	s.operator = &testOperator{
+		cluster: ops.Site{
+			Domain:    "example.com",
+			AccountID: "account",
+			State:     ops.SiteStateActive,
+			ClusterState: storage.ClusterState{
+				Servers: []storage.Server{
+					{AdvertiseIP: "192.168.1.1", Hostname: "node-1", ClusterRole: string(schema.ServiceRoleMaster)},
+					{AdvertiseIP: "192.168.1.2", Hostname: "node-2", ClusterRole: string(schema.ServiceRoleMaster)},
+					{AdvertiseIP: "192.168.1.3", Hostname: "node-3", ClusterRole: string(schema.ServiceRoleMaster)},
+				},
+			},
+		},
+	}
+	s.checker = &testChecker{}
+	s.remediator = &testRemediator{}
+	s.clock = clockwork.NewFakeClock()
+	var err error
+	s.controller, err = New(Config{
+		Operator:    s.operator,
+		Checker:     s.checker,
+		Remediator:  s.remediator,
+		Clock:       s.clock,
+		FieldLogger: logrus.WithField(trace.Component, "healing"),
+	})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *HealingSuite) TestRestartsPlanetAfterThreshold(c *check.C) {
+	s.operator.policy = newPolicy(storage.HealingPolicySpecV1{Enabled: true, FailureThreshold: 2})
+	s.checker.problems = []Problem{{Component: ComponentOverlay, Node: "192.168.1.2", Detail: "no route"}}
+
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	c.Assert(s.remediator.restarted, check.HasLen, 0)
+
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	c.Assert(s.remediator.restarted, check.DeepEquals, []string{"192.168.1.2"})
+	c.Assert(s.operator.events, check.HasLen, 1)
+	c.Assert(s.operator.events[0].Type, check.Equals, events.RemediationSucceeded)
+	c.Assert(s.operator.events[0].Fields[events.FieldRemediation], check.Equals, storage.RemediationRestartPlanet)
+	c.Assert(s.operator.events[0].Fields[events.FieldNodeIP], check.Equals, "192.168.1.2")
+}
+
+func (s *HealingSuite) TestRecoveredProblemResetsFailures(c *check.C) {
+	s.operator.policy = newPolicy(storage.HealingPolicySpecV1{Enabled: true, FailureThreshold: 2})
+	problems := []Problem{{Component: ComponentDNS, Node: "192.168.1.1"}}
+
+	s.checker.problems = problems
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	s.checker.problems = nil
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	s.checker.problems = problems
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	c.Assert(s.remediator.dnsRecreated, check.Equals, 0)
+}
+
+func (s *HealingSuite) TestNoRemediationsWithoutPolicy(c *check.C) {
+	s.checker.problems = []Problem{{Component: ComponentRegistry, Node: "192.168.1.1"}}
+	for i := 0; i < 5; i++ {
+		c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	}
+	c.Assert(s.remediator.registrySynced, check.Equals, 0)
+	c.Assert(s.operator.events, check.HasLen, 0)
+}
+
+func (s *HealingSuite) TestPolicyRestrictsRemediations(c *check.C) {
+	s.operator.policy = newPolicy(storage.HealingPolicySpecV1{
+		Enabled:          true,
+		FailureThreshold: 1,
+		Remediations:     []string{storage.RemediationResyncRegistry},
+	})
+	s.checker.problems = []Problem{
+		{Component: ComponentRegistry, Node: "192.168.1.1"},
+		{Component: ComponentDNS, Node: "192.168.1.1"},
+	}
+
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	c.Assert(s.remediator.registrySynced, check.Equals, 1)
+	c.Assert(s.remediator.dnsRecreated, check.Equals, 0)
+	c.Assert(eventTypes(s.operator.events), check.DeepEquals, []string{
+		events.RemediationSucceeded,
+		events.RemediationSkipped,
+	})
+}
+
+func (s *HealingSuite) TestDryRun(c *check.C) {
+	s.operator.policy = newPolicy(storage.HealingPolicySpecV1{Enabled: true, DryRun: true, FailureThreshold: 1})
+	s.checker.problems = []Problem{{Component: ComponentDNS, Node: "192.168.1.1"}}
+
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	c.Assert(s.remediator.dnsRecreated, check.Equals, 0)
+	c.Assert(eventTypes(s.operator.events), check.DeepEquals, []string{events.RemediationSkipped})
+	c.Assert(s.operator.events[0].Fields[events.FieldReason], check.Equals, "dry run")
+}
+
+func (s *HealingSuite) TestCooldown(c *check.C) {
+	s.operator.policy = newPolicy(storage.HealingPolicySpecV1{Enabled: true, FailureThreshold: 1})
+	s.checker.problems = []Problem{{Component: ComponentRegistry, Node: "192.168.1.1"}}
+	s.remediator.err = trace.ConnectionProblem(nil, "registry is not available")
+
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	c.Assert(s.remediator.registrySynced, check.Equals, 1)
+	c.Assert(eventTypes(s.operator.events), check.DeepEquals, []string{events.RemediationFailed})
+
+	s.clock.Advance(31 * time.Minute)
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	c.Assert(s.remediator.registrySynced, check.Equals, 2)
+}
+
+func (s *HealingSuite) TestRestartsOneNodeAtATime(c *check.C) {
+	s.operator.policy = newPolicy(storage.HealingPolicySpecV1{Enabled: true, FailureThreshold: 1})
+	s.checker.problems = []Problem{
+		{Component: ComponentOverlay, Node: "192.168.1.3"},
+		{Component: ComponentEtcd, Node: "192.168.1.2"},
+	}
+
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	c.Assert(s.remediator.restarted, check.DeepEquals, []string{"192.168.1.2"})
+
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	c.Assert(s.remediator.restarted, check.DeepEquals, []string{"192.168.1.2", "192.168.1.3"})
+}
+
+func (s *HealingSuite) TestDoesNotRestartPlanetWithoutEtcdQuorum(c *check.C) {
+	s.operator.policy = newPolicy(storage.HealingPolicySpecV1{Enabled: true, FailureThreshold: 1})
+	s.checker.problems = []Problem{
+		{Component: ComponentEtcd, Node: "192.168.1.1"},
+		{Component: ComponentEtcd, Node: "192.168.1.2"},
+	}
+
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	c.Assert(s.remediator.restarted, check.HasLen, 0)
+	c.Assert(eventTypes(s.operator.events), check.DeepEquals, []string{events.RemediationSkipped})
+}
+
+func (s *HealingSuite) TestPausedDuringOperations(c *check.C) {
+	s.operator.policy = newPolicy(storage.HealingPolicySpecV1{Enabled: true, FailureThreshold: 1})
+	s.operator.cluster.State = ops.SiteStateUpdating
+	s.checker.problems = []Problem{{Component: ComponentDNS, Node: "192.168.1.1"}}
+
+	c.Assert(s.controller.Check(context.TODO()), check.IsNil)
+	c.Assert(s.remediator.dnsRecreated, check.Equals, 0)
+}
+
+func (s *HealingSuite) TestProblemsFromProbes(c *check.C) {
+	problems := problemsFromProbes(map[string][]agentpb.Probe{
+		"192.168.1.2": {
+			{Checker: "networking", Detail: "pod ping failed"},
+			{Checker: "disk-space", Detail: "disk is full"},
+		},
+		"192.168.1.1": {
+			{Checker: "etcd-healthz", Error: "connection refused"},
+			{Checker: "docker-registry", Detail: "unhealthy"},
+			{Checker: "dns", Detail: "lookup failed"},
+		},
+	})
+	c.Assert(problems, check.DeepEquals, []Problem{
+		{Component: ComponentEtcd, Node: "192.168.1.1", Detail: "connection refused"},
+		{Component: ComponentRegistry, Node: "192.168.1.1", Detail: "unhealthy"},
+		{Component: ComponentDNS, Node: "192.168.1.1", Detail: "lookup failed"},
+		{Component: ComponentOverlay, Node: "192.168.1.2", Detail: "pod ping failed"},
+	})
+}
+
+func newPolicy(spec storage.HealingPolicySpecV1) storage.HealingPolicy {
+	policy := storage.NewHealingPolicy(spec)
+	if err := policy.CheckAndSetDefaults(); err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+func eventTypes(events []ops.AuditEventRequest) (types []string) {
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+type testOperator struct {
+	ops.Operator
+	cluster ops.Site
+	policy  storage.HealingPolicy
+	events  []ops.AuditEventRequest
+}
+
+func (r *testOperator) GetLocalSite() (*ops.Site, error) {
+	cluster := r.cluster
+	return &cluster, nil
+}
+
+func (r *testOperator) GetHealingPolicy(ops.SiteKey) (storage.HealingPolicy, error) {
+	if r.policy == nil {
+		return nil, trace.NotFound("no healing policy found")
+	}
+	return r.policy, nil
+}
+
+func (r *testOperator) EmitAuditEvent(ctx context.Context, req ops.AuditEventRequest) error {
+	r.events = append(r.events, req)
+	return nil
+}
+
+type testChecker struct {
+	problems []Problem
+}
+
+func (r *testChecker) Check(context.Context) ([]Problem, error) {
+	return r.problems, nil
+}
+
+type testRemediator struct {
+	restarted      []string
+	registrySynced int
+	dnsRecreated   int
+	err            error
+}
+
+func (r *testRemediator) RestartPlanet(ctx context.Context, cluster ops.Site, node storage.Server) error {
+	r.restarted = append(r.restarted, node.AdvertiseIP)
+	return r.err
+}
+
+func (r *testRemediator) ResyncRegistry(ctx context.Context, cluster ops.Site) error {
+	r.registrySynced++
+	return r.err
+}
+
+func (r *testRemediator) RecreateDNSConfig(ctx context.Context, cluster ops.Site) error {
+	r.dnsRecreated++
+	return r.err
+}
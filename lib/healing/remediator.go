@@ -0,0 +1,190 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/coredns"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/systeminfo"
+	"github.com/gravitational/gravity/lib/systemservice"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RemediatorConfig defines the configuration of the default remediator
+type RemediatorConfig struct {
+	// Apps is the cluster application service
+	Apps app.Applications
+	// Proxy is used to execute commands on cluster nodes
+	Proxy ops.TeleportProxyService
+	// Client is the kubernetes client
+	Client kubernetes.Interface
+}
+
+// CheckAndSetDefaults validates the configuration
+func (r RemediatorConfig) CheckAndSetDefaults() error {
+	if r.Apps == nil {
+		return trace.BadParameter("missing Apps")
+	}
+	if r.Proxy == nil {
+		return trace.BadParameter("missing Proxy")
+	}
+	if r.Client == nil {
+		return trace.BadParameter("missing Client")
+	}
+	return nil
+}
+
+// NewRemediator returns the default remediator
+func NewRemediator(config RemediatorConfig) (Remediator, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &remediator{RemediatorConfig: config}, nil
+}
+
+type remediator struct {
+	RemediatorConfig
+}
+
+// RestartPlanet restarts the planet container on the specified node
+// by restarting its systemd unit over the teleport node service
+func (r *remediator) RestartPlanet(ctx context.Context, cluster ops.Site, node storage.Server) error {
+	runtimePackage, err := cluster.App.Manifest.RuntimePackageForProfile(node.Role)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	servers, err := r.Proxy.GetServers(ctx, cluster.Domain, map[string]string{
+		ops.AdvertiseIP: node.AdvertiseIP,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(servers) == 0 {
+		return trace.NotFound("node %v is not reachable", node.AdvertiseIP)
+	}
+	var out bytes.Buffer
+	command := fmt.Sprintf("systemctl restart %v", systemservice.PackageServiceName(*runtimePackage))
+	err = r.Proxy.ExecuteCommand(ctx, cluster.Domain, servers[0].GetAddr(), command, &out)
+	if err != nil {
+		return trace.Wrap(err, out.String())
+	}
+	return nil
+}
+
+// ResyncRegistry pushes the cluster application images into the cluster registry
+func (r *remediator) ResyncRegistry(ctx context.Context, cluster ops.Site) error {
+	err := r.Apps.ExportApp(app.ExportAppRequest{
+		Package:         cluster.App.Package,
+		RegistryAddress: constants.LocalRegistryAddr,
+		CertName:        constants.DockerRegistry,
+	})
+	return trace.Wrap(err)
+}
+
+// RecreateDNSConfig recreates the CoreDNS configuration if it is missing
+// and restarts the cluster DNS pods to make them reload the configuration
+func (r *remediator) RecreateDNSConfig(ctx context.Context, cluster ops.Site) error {
+	configMaps := r.Client.CoreV1().ConfigMaps(constants.KubeSystemNamespace)
+	_, err := configMaps.Get(corednsConfigMap, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if trace.IsNotFound(err) {
+		conf, err := r.generateCorefile(cluster.DNSOverrides)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		_, err = configMaps.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      corednsConfigMap,
+				Namespace: constants.KubeSystemNamespace,
+			},
+			Data: map[string]string{
+				"Corefile": conf,
+			},
+		})
+		err = rigging.ConvertError(err)
+		if err != nil && !trace.IsAlreadyExists(err) {
+			return trace.Wrap(err)
+		}
+	}
+	err = r.Client.CoreV1().Pods(constants.KubeSystemNamespace).DeleteCollection(
+		&metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: dnsPodSelector})
+	return trace.Wrap(rigging.ConvertError(err))
+}
+
+// generateCorefile generates the CoreDNS configuration using the
+// cluster DNS resource if one has been configured, and the
+// nameservers of this host as upstream servers otherwise
+func (r *remediator) generateCorefile(overrides storage.DNSOverrides) (string, error) {
+	dns, err := r.getClusterDNS()
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	resolvConf, err := systeminfo.ResolvFromFile("/etc/resolv.conf")
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	conf, err := coredns.GenerateCorefile(coredns.NewConfig(dns, overrides, *resolvConf))
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return conf, nil
+}
+
+// getClusterDNS returns the cluster DNS resource or nil
+// if it has not been configured
+func (r *remediator) getClusterDNS() (storage.ClusterDNS, error) {
+	configMap, err := r.Client.CoreV1().ConfigMaps(constants.KubeSystemNamespace).Get(
+		constants.ClusterDNSConfigMap, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	data, ok := configMap.Data[constants.ResourceSpecKey]
+	if !ok {
+		return nil, nil
+	}
+	dns, err := storage.UnmarshalClusterDNS([]byte(data))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return dns, nil
+}
+
+const (
+	// corednsConfigMap is the name of the ConfigMap with CoreDNS configuration
+	corednsConfigMap = "coredns"
+	// dnsPodSelector selects the cluster DNS pods
+	dnsPodSelector = "k8s-app in (kube-dns, kube-dns-worker)"
+)
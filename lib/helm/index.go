@@ -49,15 +49,20 @@ func GenerateIndexFile(apps []app.Application) *repo.IndexFile {
 
 // generateChartMetadata generates chart metadata for the provided application.
 func generateChartMetadata(item app.Application) *chart.Metadata {
+	annotations := map[string]string{
+		constants.AnnotationKind:       item.Manifest.ImageType(),
+		constants.AnnotationLogo:       item.Manifest.Logo,
+		constants.AnnotationSize:       fmt.Sprintf("%v", item.PackageEnvelope.SizeBytes),
+		constants.AnnotationRepository: item.Package.Repository,
+	}
+	for key, value := range item.Manifest.Metadata.Labels {
+		annotations[constants.AnnotationLabelPrefix+key] = value
+	}
 	return &chart.Metadata{
 		Name:        item.Manifest.Metadata.Name,
 		Version:     item.Manifest.Metadata.ResourceVersion,
 		Description: item.Manifest.Metadata.Description,
-		Annotations: map[string]string{
-			constants.AnnotationKind: item.Manifest.ImageType(),
-			constants.AnnotationLogo: item.Manifest.Logo,
-			constants.AnnotationSize: fmt.Sprintf("%v", item.PackageEnvelope.SizeBytes),
-		},
+		Annotations: annotations,
 	}
 }
 
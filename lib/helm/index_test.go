@@ -45,6 +45,7 @@ func (s *HelmSuite) TestGenerateIndexFile(c *check.C) {
 		"Base image with Kubernetes v1.13", "111", 1)
 	app1 := makeApp(c, schema.KindApplication, "alpine", "0.0.1",
 		"Alpine Linux 3.3", "222", 2)
+	app1.Manifest.Metadata.Labels = map[string]string{"channel": "stable"}
 	cluster2 := makeApp(c, schema.KindBundle, "telekube", "1.0.0",
 		"Base image with Kubernetes v1.10", "333", 3)
 	runtime1 := makeApp(c, schema.KindRuntime, "k8s", "1.0.0",
@@ -68,9 +69,11 @@ func (s *HelmSuite) TestGenerateIndexFile(c *check.C) {
 					Version:     "0.0.1",
 					Description: "Alpine Linux 3.3",
 					Annotations: map[string]string{
-						constants.AnnotationKind: schema.KindApplication,
-						constants.AnnotationLogo: "",
-						constants.AnnotationSize: "2",
+						constants.AnnotationKind:                    schema.KindApplication,
+						constants.AnnotationLogo:                    "",
+						constants.AnnotationSize:                    "2",
+						constants.AnnotationRepository:              defaults.SystemAccountOrg,
+						constants.AnnotationLabelPrefix + "channel": "stable",
 					},
 				},
 				Digest: "sha512:222",
@@ -84,9 +87,10 @@ func (s *HelmSuite) TestGenerateIndexFile(c *check.C) {
 					Version:     "3.0.0",
 					Description: "Nginx 1.10",
 					Annotations: map[string]string{
-						constants.AnnotationKind: schema.KindApplication,
-						constants.AnnotationLogo: "",
-						constants.AnnotationSize: "5",
+						constants.AnnotationKind:       schema.KindApplication,
+						constants.AnnotationLogo:       "",
+						constants.AnnotationSize:       "5",
+						constants.AnnotationRepository: defaults.SystemAccountOrg,
 					},
 				},
 				Digest: "sha512:555",
@@ -100,9 +104,10 @@ func (s *HelmSuite) TestGenerateIndexFile(c *check.C) {
 					Version:     "2.0.0",
 					Description: "Base image with Kubernetes v1.13",
 					Annotations: map[string]string{
-						constants.AnnotationKind: schema.KindCluster,
-						constants.AnnotationLogo: "",
-						constants.AnnotationSize: "1",
+						constants.AnnotationKind:       schema.KindCluster,
+						constants.AnnotationLogo:       "",
+						constants.AnnotationSize:       "1",
+						constants.AnnotationRepository: defaults.SystemAccountOrg,
 					},
 				},
 				Digest: "sha512:111",
@@ -114,9 +119,10 @@ func (s *HelmSuite) TestGenerateIndexFile(c *check.C) {
 					Version:     "1.0.0",
 					Description: "Base image with Kubernetes v1.10",
 					Annotations: map[string]string{
-						constants.AnnotationKind: schema.KindCluster,
-						constants.AnnotationLogo: "",
-						constants.AnnotationSize: "3",
+						constants.AnnotationKind:       schema.KindCluster,
+						constants.AnnotationLogo:       "",
+						constants.AnnotationSize:       "3",
+						constants.AnnotationRepository: defaults.SystemAccountOrg,
 					},
 				},
 				Digest: "sha512:333",
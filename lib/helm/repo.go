@@ -23,7 +23,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
@@ -51,6 +53,9 @@ type Repository interface {
 	GetIndexFile() (io.Reader, error)
 	// AddToIndex adds the specified application to the repository index.
 	AddToIndex(locator loc.Locator, upsert bool) error
+	// AddVendoredCharts adds the charts vendored in the resources of the
+	// specified application to the repository.
+	AddVendoredCharts(loc.Locator) error
 	// RemoveFromIndex removes the specified application from the repository index.
 	RemoveFromIndex(loc.Locator) error
 	// RebuildIndex fully rebuilds the chart repository index.
@@ -81,11 +86,21 @@ func NewRepository(config Config) (*clusterRepository, error) {
 }
 
 // FetchChart returns the specified application as a Helm chart tarball.
+//
+// If there is no such application, the chart is looked up among the charts
+// vendored in applications.
 func (r *clusterRepository) FetchChart(locator loc.Locator) (io.ReadCloser, error) {
 	_, reader, err := r.Packages.ReadPackage(locator)
-	if err != nil {
+	if err != nil && !trace.IsNotFound(err) {
 		return nil, trace.Wrap(err)
 	}
+	if trace.IsNotFound(err) {
+		_, reader, err = r.Packages.ReadPackage(vendoredChartLocator(locator.Name, locator.Version))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return reader, nil
+	}
 	defer reader.Close()
 	tmpDir, err := ioutil.TempDir("", "package")
 	if err != nil {
@@ -152,34 +167,59 @@ func (r *clusterRepository) AddToIndex(locator loc.Locator, upsert bool) error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	indexFile, err := r.Backend.GetIndexFile()
-	if err != nil && !trace.IsNotFound(err) {
+	return r.addChartToIndex(locator, chart, digest, upsert)
+}
+
+// AddVendoredCharts adds the charts vendored in the resources of the
+// specified application to the repository.
+//
+// The vendored charts are packaged and stored in the package service
+// so they can be served independently of the application. Charts are
+// immutable once added: a chart vendored in several applications is
+// only stored once.
+func (r *clusterRepository) AddVendoredCharts(locator loc.Locator) error {
+	_, reader, err := r.Packages.ReadPackage(locator)
+	if err != nil {
 		return trace.Wrap(err)
 	}
-	if trace.IsNotFound(err) {
-		indexFile = repo.NewIndexFile()
-		indexFile.Add(chart.Metadata, r.chartURL(chart), "", digest)
-		return r.Backend.CompareAndSwapIndexFile(indexFile, nil)
+	defer reader.Close()
+	tmpDir, err := ioutil.TempDir("", "package")
+	if err != nil {
+		return trace.Wrap(err)
 	}
-	if indexFile.Has(chart.Metadata.Name, chart.Metadata.Version) {
-		if !upsert {
-			return trace.AlreadyExists("index file already has chart %v:%v",
-				chart.Metadata.Name, chart.Metadata.Version)
-		}
-		// Delete the old entry first because "add" will not check for dupes.
-		err := r.RemoveFromIndex(locator)
+	defer os.RemoveAll(tmpDir)
+	// Unpack application resources (w/o registry) into temporary directory.
+	err = archive.Untar(reader, tmpDir, &archive.TarOptions{
+		NoLchown:        true,
+		ExcludePatterns: []string{"registry"},
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	chartFiles, err := filepath.Glob(filepath.Join(tmpDir, "resources",
+		defaults.VendoredChartsDir, "*", constants.HelmChartFile))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(chartFiles) == 0 {
+		return nil
+	}
+	err = r.Packages.UpsertRepository(defaults.ChartsRepository, time.Time{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, chartFile := range chartFiles {
+		chart, err := chartutil.LoadDir(filepath.Dir(chartFile))
 		if err != nil {
 			return trace.Wrap(err)
 		}
-		indexFile, err = r.Backend.GetIndexFile()
+		err = r.addVendoredChart(chart)
 		if err != nil {
-			return trace.Wrap(err)
+			return trace.Wrap(err, "failed to add chart %v vendored in %v",
+				filepath.Base(filepath.Dir(chartFile)), locator)
 		}
 	}
-	prevIndexFile := helmutils.CopyIndexFile(*indexFile)
-	indexFile.Add(chart.Metadata, r.chartURL(chart), "", digest)
-	indexFile.SortEntries()
-	return r.Backend.CompareAndSwapIndexFile(indexFile, prevIndexFile)
+	return nil
 }
 
 // RemoveFromIndex removes the specified application from the repository index.
@@ -248,10 +288,118 @@ func (r *clusterRepository) RebuildIndex() error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	err = pack.ForeachPackageInRepo(r.Packages, defaults.ChartsRepository,
+		func(e pack.PackageEnvelope) error {
+			chart, err := r.chartForLocator(e.Locator)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			// Applications take precedence over vendored charts
+			// with the same name and version.
+			if indexFile.Has(chart.Metadata.Name, chart.Metadata.Version) {
+				return nil
+			}
+			digest, err := r.digest(e.Locator)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			r.Debugf("Adding to the index: %v.", e.Locator)
+			indexFile.Add(chart.Metadata, r.chartURL(chart), "", digest)
+			return nil
+		})
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
 	indexFile.SortEntries()
 	return r.Backend.UpsertIndexFile(*indexFile)
 }
 
+// addChartToIndex adds the provided chart stored in the specified package
+// to the repository index.
+func (r *clusterRepository) addChartToIndex(locator loc.Locator, chart *chart.Chart, digest string, upsert bool) error {
+	indexFile, err := r.Backend.GetIndexFile()
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if trace.IsNotFound(err) {
+		indexFile = repo.NewIndexFile()
+		indexFile.Add(chart.Metadata, r.chartURL(chart), "", digest)
+		return r.Backend.CompareAndSwapIndexFile(indexFile, nil)
+	}
+	if indexFile.Has(chart.Metadata.Name, chart.Metadata.Version) {
+		if !upsert {
+			return trace.AlreadyExists("index file already has chart %v:%v",
+				chart.Metadata.Name, chart.Metadata.Version)
+		}
+		// Delete the old entry first because "add" will not check for dupes.
+		err := r.RemoveFromIndex(locator)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		indexFile, err = r.Backend.GetIndexFile()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	prevIndexFile := helmutils.CopyIndexFile(*indexFile)
+	indexFile.Add(chart.Metadata, r.chartURL(chart), "", digest)
+	indexFile.SortEntries()
+	return r.Backend.CompareAndSwapIndexFile(indexFile, prevIndexFile)
+}
+
+// addVendoredChart packages the provided chart into the package service
+// and adds it to the repository index unless the chart with the same
+// name and version is already there.
+func (r *clusterRepository) addVendoredChart(chart *chart.Chart) error {
+	locator := vendoredChartLocator(chart.Metadata.Name, chart.Metadata.Version)
+	_, err := r.Packages.ReadPackageEnvelope(locator)
+	if err == nil {
+		r.Debugf("Chart %v is already in the repository.", locator)
+		return nil
+	}
+	if !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	r.Infof("Adding vendored chart %v to chart repo.", locator)
+	chartDir, err := ioutil.TempDir("", "chart")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer os.RemoveAll(chartDir)
+	path, err := chartutil.Save(chart, chartDir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	chartReader, err := os.Open(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer chartReader.Close()
+	_, err = r.Packages.CreatePackage(locator, chartReader, pack.WithHidden(true))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	digest, err := r.digest(locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = r.addChartToIndex(locator, chart, digest, false)
+	if err != nil && !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// vendoredChartLocator returns the locator of the package
+// with the vendored chart of the specified name and version.
+func vendoredChartLocator(name, version string) loc.Locator {
+	return loc.Locator{
+		Repository: defaults.ChartsRepository,
+		Name:       name,
+		Version:    version,
+	}
+}
+
 // chartForLocator returns the specified application as a Helm chart archive.
 func (r *clusterRepository) chartForLocator(locator loc.Locator) (*chart.Chart, error) {
 	_, reader, err := r.Packages.ReadPackage(locator)
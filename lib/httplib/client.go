@@ -151,10 +151,20 @@ func WithIdleConnTimeout(timeout time.Duration) ClientOption {
 	}
 }
 
-// GetClient returns secure or insecure client based on settings
+// WithoutProxy disables the use of HTTP proxy configured in the environment.
+// It is used for clients that only talk to cluster-local endpoints
+func WithoutProxy() ClientOption {
+	return func(c *http.Client) {
+		c.Transport.(*http.Transport).Proxy = nil
+	}
+}
+
+// GetClient returns secure or insecure client based on settings.
+// The client honors the HTTP proxy configured in the environment
 func GetClient(insecure bool, options ...ClientOption) *http.Client {
 	transport := &http.Transport{
 		TLSClientConfig: &tls.Config{},
+		Proxy:           http.ProxyFromEnvironment,
 	}
 	if insecure {
 		transport.TLSClientConfig.InsecureSkipVerify = true
@@ -185,3 +185,34 @@ func (w *WebSocketReader) Handler() http.Handler {
 		Handler: w.handleConnection,
 	}
 }
+
+// WebSocketJSONWriter is a web socket handler,
+// ignores all input and sends values produced by Stream
+// to the web socket connection as JSON frames
+type WebSocketJSONWriter struct {
+	// Stream passes values to send until it returns.
+	// The context is cancelled once the client disconnects
+	Stream func(ctx context.Context, send func(interface{}) error) error
+}
+
+func (w *WebSocketJSONWriter) handleConnection(ws *websocket.Conn) {
+	defer ws.Close()
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+	go func() {
+		io.Copy(ioutil.Discard, ws)
+		cancel()
+	}()
+	err := w.Stream(ctx, func(value interface{}) error {
+		return trace.Wrap(websocket.JSON.Send(ws, value))
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Warnf("Failed to stream to web socket: %v.", trace.DebugReport(err))
+	}
+}
+
+func (w *WebSocketJSONWriter) Handler() http.Handler {
+	return &websocket.Server{
+		Handler: w.handleConnection,
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -40,6 +41,7 @@ import (
 	"k8s.io/helm/pkg/repo"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -72,7 +74,9 @@ import (
 type Hub interface {
 	// List returns a list of applications in the hub
 	List(withPrereleases bool) ([]App, error)
-	// Downloads downloads the specified application installer into provided file
+	// Downloads downloads the specified application installer into provided file.
+	// If the file is not empty, e.g. left over from an interrupted download,
+	// the download resumes from the end of the file
 	Download(*os.File, loc.Locator, utils.Progress) error
 	// Get returns application installer tarball of the specified version
 	Get(loc.Locator) (io.ReadCloser, error)
@@ -94,6 +98,10 @@ type App struct {
 	Description string `json:"description"`
 	// Type is the image type, for example application or cluster
 	Type string `json:"type"`
+	// Repository is the repository of the image package
+	Repository string `json:"repository,omitempty"`
+	// Labels is the labels attached to the image
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // s3Hub is the S3-backed hub implementation
@@ -171,13 +179,35 @@ func (h *s3Hub) List(withPrereleases bool) (items []App, err error) {
 				Created:     entry.Created,
 				Description: strings.TrimSpace(entry.Description),
 				Type:        entry.Annotations[constants.AnnotationKind],
+				Repository:  entry.Annotations[constants.AnnotationRepository],
+				Labels:      labelsFromAnnotations(entry.Annotations),
 			})
 		}
 	}
 	return items, nil
 }
 
-// Downloads downloads the specified application installer into provided file
+// labelsFromAnnotations returns image labels stored in the index
+// entry annotations
+func labelsFromAnnotations(annotations map[string]string) map[string]string {
+	var labels map[string]string
+	for key, value := range annotations {
+		if !strings.HasPrefix(key, constants.AnnotationLabelPrefix) {
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[strings.TrimPrefix(key, constants.AnnotationLabelPrefix)] = value
+	}
+	return labels
+}
+
+// Downloads downloads the specified application installer into provided file.
+// If the file is not empty, e.g. left over from an interrupted download,
+// the download resumes from the end of the file.
+//
+// Interrupted downloads are retried a few times before giving up
 func (h *s3Hub) Download(f *os.File, locator loc.Locator, progress utils.Progress) (err error) {
 	version := locator.Version
 	// in case the provided version is a special 'latest' or 'stable' label,
@@ -193,25 +223,63 @@ func (h *s3Hub) Download(f *os.File, locator loc.Locator, progress utils.Progres
 	}
 	progress.NextStep(fmt.Sprintf("Downloading %v:%v", locator.Name, locator.Version))
 	h.Infof("Downloading: %v.", h.appPath(locator.Name, locator.Version))
-	n, err := h.downloader.Download(f, &s3.GetObjectInput{
-		Bucket: aws.String(h.Bucket),
-		Key:    aws.String(h.appPath(locator.Name, locator.Version)),
+	err = utils.Retry(defaults.TransferRetryPeriod, defaults.TransferRetryAttempts, func() error {
+		err := h.download(f, locator)
+		switch {
+		case err == nil:
+			return nil
+		case trace.IsNotFound(err), trace.IsAccessDenied(err):
+			return utils.Abort(err)
+		}
+		h.Warnf("Download of %v interrupted: %v.", locator, err)
+		return utils.Continue("download interrupted: %v", err)
 	})
 	if err != nil {
-		err := utils.ConvertS3Error(err)
 		if trace.IsNotFound(err) {
 			return trace.NotFound("application %v:%v not found in %v, use 'tele ls' to see available applications",
 				locator.Name, locator.Version, h.Bucket)
 		}
 		return trace.Wrap(err)
 	}
-	h.Infof("Download complete: %v %v.", locator, humanize.Bytes(uint64(n)))
+	fi, err := f.Stat()
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	h.Infof("Download complete: %v %v.", locator, humanize.Bytes(uint64(fi.Size())))
 	if err := h.verifyChecksum(locator.Name, locator.Version, f.Name()); err != nil {
 		return trace.Wrap(err, "failed to verify %v:%v checksum", locator.Name, locator.Version)
 	}
 	return nil
 }
 
+// download downloads the specified application installer into the provided
+// file starting from the end of the file
+func (h *s3Hub) download(f *os.File, locator loc.Locator) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	offset := fi.Size()
+	if offset != 0 {
+		h.Infof("Resuming download of %v from %v.", locator, humanize.Bytes(uint64(offset)))
+	}
+	// Download the remainder as a single range so the file never has
+	// gaps and its size always reflects the downloaded data
+	_, err = h.downloader.Download(&offsetWriter{WriterAt: f, offset: offset}, &s3.GetObjectInput{
+		Bucket: aws.String(h.Bucket),
+		Key:    aws.String(h.appPath(locator.Name, locator.Version)),
+		Range:  aws.String(fmt.Sprintf("bytes=%v-", offset)),
+	})
+	if err != nil {
+		if offset != 0 && isRangeNotSatisfiable(err) {
+			// The file has already been downloaded completely
+			return nil
+		}
+		return trace.Wrap(utils.ConvertS3Error(err))
+	}
+	return nil
+}
+
 // Get returns application installer tarball of the specified version
 func (h *s3Hub) Get(locator loc.Locator) (io.ReadCloser, error) {
 	tarFile, err := ioutil.TempFile("", locator.Name)
@@ -348,7 +416,7 @@ func (h *s3Hub) verifyChecksum(name, version, path string) error {
 	}
 	checksum := fmt.Sprintf("%x", hash.Sum(nil))
 	if storedChecksum != checksum {
-		return trace.BadParameter("checksum mismatch: stored %q, calculated %q",
+		return trace.CompareFailed("checksum mismatch: stored %q, calculated %q",
 			storedChecksum, checksum)
 	}
 	h.Infof("Checksum for %v:%v verified: %v.", name, version, checksum)
@@ -385,6 +453,27 @@ func makeFilename(name, version string) string {
 	return fmt.Sprintf("%v-%v-linux-x86_64.tar", name, version)
 }
 
+// offsetWriter writes to the underlying writer at positions
+// shifted by the specified offset
+type offsetWriter struct {
+	io.WriterAt
+	offset int64
+}
+
+// WriteAt writes p at the offset off relative to the writer offset
+func (w *offsetWriter) WriteAt(p []byte, off int64) (int, error) {
+	return w.WriterAt.WriteAt(p, w.offset+off)
+}
+
+// isRangeNotSatisfiable returns true if the error indicates that the requested
+// range starts past the end of the object
+func isRangeNotSatisfiable(err error) bool {
+	if err, ok := trace.Unwrap(err).(awserr.RequestFailure); ok {
+		return err.StatusCode() == http.StatusRequestedRangeNotSatisfiable
+	}
+	return false
+}
+
 const (
 	// indexFileName is the repository index file name.
 	indexFileName = "index.yaml"
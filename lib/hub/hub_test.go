@@ -103,6 +103,7 @@ func toHubApp(s3App testutils.S3App) App {
 	return App{
 		Name:    s3App.Name,
 		Version: s3App.Version,
+		Labels:  s3App.Labels,
 	}
 }
 
@@ -120,5 +121,6 @@ var (
 		Created:  time.Now(),
 		Data:     []byte("version 2 (latest)"),
 		Checksum: "5c99c4996ac2f6d7eb12420f908fc0897360de6011f458716f36e3f14898777e",
+		Labels:   map[string]string{"channel": "beta"},
 	}
 )
@@ -48,7 +48,7 @@ func NewAgent(ctx context.Context, config AgentConfig, log log.FieldLogger, watc
 		addr = fmt.Sprintf("https://%v:%v", host, port)
 	}
 
-	httpClient := roundtrip.HTTPClient(httplib.GetClient(true))
+	httpClient := roundtrip.HTTPClient(httplib.GetClient(true, httplib.WithoutProxy()))
 	packages, err := webpack.NewBearerClient(addr, config.Token, httpClient)
 	if err != nil {
 		return nil, trace.Wrap(err)
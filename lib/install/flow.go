@@ -37,6 +37,7 @@ import (
 	rpcserver "github.com/gravitational/gravity/lib/rpc/server"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/systeminfo"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/cenkalti/backoff"
@@ -120,9 +121,11 @@ func (i *Installer) NewClusterRequest() ops.NewSiteRequest {
 		CloudConfig: storage.CloudConfig{
 			GCENodeTags: i.Config.GCENodeTags,
 		},
-		DNSOverrides: i.DNSOverrides,
-		DNSConfig:    i.DNSConfig,
-		Docker:       i.Docker,
+		DNSOverrides:  i.DNSOverrides,
+		DNSConfig:     i.DNSConfig,
+		Docker:        i.Docker,
+		CertAuthority: i.CertAuthority,
+		FIPS:          i.FIPS,
 	}
 }
 
@@ -150,6 +153,10 @@ func (i *Installer) StartCLIInstall() (err error) {
 			DnsAddrs:  i.DNSConfig.Addrs,
 			DnsPort:   int32(i.DNSConfig.Port),
 		},
+		Subnets: storage.Subnets{
+			Overlay: i.PodCIDR,
+			Service: i.ServiceCIDR,
+		},
 		AutoFix: true,
 	})
 	if err != nil {
@@ -482,6 +489,9 @@ func (i *Installer) UpdateOperationState() error {
 func (i *Installer) getFlavor() (*schema.Flavor, error) {
 	// pick flavor and server profile
 	flavors := i.Cluster.App.Manifest.Installer.Flavors
+	if i.Flavor == schema.FlavorAuto && i.Cluster.App.Manifest.FindFlavor(i.Flavor) == nil {
+		return i.selectFlavor()
+	}
 	if i.Flavor == "" {
 		if flavors.Default != "" {
 			i.Flavor = flavors.Default
@@ -498,6 +508,29 @@ func (i *Installer) getFlavor() (*schema.Flavor, error) {
 	return flavor, nil
 }
 
+// selectFlavor selects the flavor that best matches the hardware of this node
+// and reports why each flavor was selected or rejected
+func (i *Installer) selectFlavor() (*schema.Flavor, error) {
+	info, err := systeminfo.New()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	selection, err := checks.SelectFlavor(i.Cluster.App.Manifest,
+		[]checks.ServerInfo{{System: info}})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, report := range selection.Reports {
+		i.sendMessage("Flavor %v.", report)
+	}
+	i.Flavor = selection.Flavor.Name
+	if i.Role == "" {
+		i.Role = selection.Profiles[0]
+		i.Infof("Picked server profile %q matching the hardware of this node.", i.Role)
+	}
+	return &selection.Flavor, nil
+}
+
 func (i *Installer) checkAndSetServerProfile() error {
 	if i.Role == "" {
 		for _, node := range i.flavor.Nodes {
@@ -222,6 +222,7 @@ func (f *fsmEngine) ChangePhaseState(ctx context.Context, change fsm.StateChange
 			NewState:    change.State,
 			Error:       utils.ToRawTrace(change.Error),
 			Created:     time.Now().UTC(),
+			Attempt:     change.Attempt,
 		})
 	if err != nil {
 		return trace.Wrap(err)
@@ -23,7 +23,6 @@ import (
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/install/phases"
 	"github.com/gravitational/gravity/lib/ops/resources/gravity"
-	"github.com/gravitational/gravity/lib/schema"
 
 	"github.com/gravitational/trace"
 	"k8s.io/client-go/kubernetes"
@@ -47,6 +46,7 @@ func FSMSpec(config FSMConfig) fsm.FSMSpecFunc {
 			return phases.NewBootstrap(p,
 				config.Operator,
 				config.Apps,
+				config.Packages,
 				config.LocalBackend, remote)
 
 		case strings.HasPrefix(p.Phase.ID, phases.PullPhase):
@@ -74,6 +74,24 @@ func FSMSpec(config FSMConfig) fsm.FSMSpecFunc {
 			return phases.NewHealth(p,
 				config.Operator)
 
+		case p.Phase.ID == phases.NodeProfilesPhase:
+			client, err := getKubeClient(p)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			return phases.NewNodeProfiles(p,
+				config.Operator,
+				client)
+
+		case p.Phase.ID == phases.ReadinessPhase:
+			client, err := getKubeClient(p)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			return phases.NewReadiness(p,
+				config.Operator,
+				client)
+
 		case p.Phase.ID == phases.RBACPhase:
 			client, err := getKubeClient(p)
 			if err != nil {
@@ -116,10 +134,14 @@ func FSMSpec(config FSMConfig) fsm.FSMSpecFunc {
 			return phases.NewEnableElectionPhase(p, config.Operator)
 
 		case strings.HasPrefix(p.Phase.ID, phases.InstallOverlayPhase):
-			return phases.NewHook(p,
+			client, err := getKubeClient(p)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			return phases.NewInstallOverlay(p,
 				config.Operator,
 				config.LocalApps,
-				schema.HookNetworkInstall)
+				client)
 
 		case strings.HasPrefix(p.Phase.ID, phases.GravityResourcesPhase):
 			operator, err := config.LocalClusterClient()
@@ -192,6 +192,11 @@ type Config struct {
 	DNSConfig storage.DNSConfig
 	// Docker specifies docker configuration
 	Docker storage.DockerConfig
+	// CertAuthority optionally specifies the intermediate certificate authority
+	// to issue the cluster certificates from
+	CertAuthority *ops.ExternalCertAuthority
+	// FIPS specifies whether to install the cluster in FIPS mode
+	FIPS bool
 	// Insecure allows to turn off cert validation
 	Insecure bool
 	// Process is the gravity process running inside the installer
@@ -109,7 +109,7 @@ func (p *hookExecutor) runHooks(ctx context.Context, hooks ...schema.HookType) e
 			req.HostNetwork = true
 		}
 
-		_, err := app.CheckHasAppHook(p.Apps, req)
+		spec, err := app.CheckHasAppHook(p.Apps, req)
 		if err != nil {
 			if trace.IsNotFound(err) {
 				p.Debugf("Application %v does not have %v hook.",
@@ -120,26 +120,39 @@ func (p *hookExecutor) runHooks(ctx context.Context, hooks ...schema.HookType) e
 		}
 		p.Progress.NextStep("Executing %v hook for %v:%v", hook,
 			locator.Name, locator.Version)
-		p.Infof("Executing %v hook for %v:%v.", hook, locator.Name, locator.Version)
-		reader, writer := io.Pipe()
-		go func() {
-			defer reader.Close()
-			err := p.Operator.StreamOperationLogs(p.Key(), reader)
-			if err != nil && !utils.IsStreamClosedError(err) {
-				logrus.Warnf("Error streaming hook logs: %v.",
-					trace.DebugReport(err))
-			}
-		}()
-		_, err = app.StreamAppHook(ctx, p.Apps, req, writer)
+		err = app.RunHookWithPolicy(ctx, *spec, hook, p.FieldLogger, func() error {
+			return p.streamHook(ctx, req)
+		})
 		if err != nil {
 			return trace.Wrap(err, "%v %s hook failed", locator, hook)
 		}
-		// closing the writer will result in the reader returning io.EOF
-		// so the goroutine above will gracefully finish streaming
-		err = writer.Close()
-		if err != nil {
-			logrus.Warnf("Failed to close pipe writer: %v.", err)
+	}
+	return nil
+}
+
+// streamHook runs the hook specified with req streaming its logs
+// into the operation logs
+func (p *hookExecutor) streamHook(ctx context.Context, req app.HookRunRequest) error {
+	locator := req.Application
+	p.Infof("Executing %v hook for %v:%v.", req.Hook, locator.Name, locator.Version)
+	reader, writer := io.Pipe()
+	go func() {
+		defer reader.Close()
+		err := p.Operator.StreamOperationLogs(p.Key(), reader)
+		if err != nil && !utils.IsStreamClosedError(err) {
+			logrus.Warnf("Error streaming hook logs: %v.",
+				trace.DebugReport(err))
 		}
+	}()
+	_, err := app.StreamAppHook(ctx, p.Apps, req, writer)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// closing the writer will result in the reader returning io.EOF
+	// so the goroutine above will gracefully finish streaming
+	err = writer.Close()
+	if err != nil {
+		logrus.Warnf("Failed to close pipe writer: %v.", err)
 	}
 	return nil
 }
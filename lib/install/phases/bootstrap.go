@@ -19,6 +19,7 @@ package phases
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -31,9 +32,11 @@ import (
 	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/ops/opsservice"
+	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/state"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/system/selinux"
 	"github.com/gravitational/gravity/lib/systeminfo"
 	"github.com/gravitational/gravity/lib/utils"
 
@@ -42,8 +45,8 @@ import (
 )
 
 // NewBootstrap returns a new "bootstrap" phase executor
-func NewBootstrap(p fsm.ExecutorParams, operator ops.Operator, apps app.Applications, backend storage.Backend,
-	remote fsm.Remote) (*bootstrapExecutor, error) {
+func NewBootstrap(p fsm.ExecutorParams, operator ops.Operator, apps app.Applications, packages pack.PackageService,
+	backend storage.Backend, remote fsm.Remote) (*bootstrapExecutor, error) {
 	if p.Phase.Data == nil || p.Phase.Data.ServiceUser == nil {
 		return nil, trace.BadParameter("service user is required: %#v", p.Phase.Data)
 	}
@@ -87,6 +90,7 @@ func NewBootstrap(p fsm.ExecutorParams, operator ops.Operator, apps app.Applicat
 		FieldLogger:      logger,
 		InstallOperation: *operation,
 		Application:      *application,
+		Packages:         packages,
 		LocalBackend:     backend,
 		ExecutorParams:   p,
 		ServiceUser:      *serviceUser,
@@ -102,6 +106,8 @@ type bootstrapExecutor struct {
 	InstallOperation ops.SiteOperation
 	// Application is the application being installed
 	Application app.Application
+	// Packages is the cluster package service
+	Packages pack.PackageService
 	// LocalBackend is the machine-local backend
 	LocalBackend storage.Backend
 	// ServiceUser is the user used for services and system storage
@@ -130,6 +136,10 @@ func (p *bootstrapExecutor) Execute(ctx context.Context) error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	err = p.configureSELinux(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
 	err = p.configureApplicationVolumes()
 	if err != nil {
 		return trace.Wrap(err)
@@ -254,6 +264,48 @@ func (p *bootstrapExecutor) configureSystemDirectories() error {
 	return nil
 }
 
+// configureSELinux loads the SELinux policy module shipped with the
+// application and labels the state directory if SELinux is enabled
+func (p *bootstrapExecutor) configureSELinux(ctx context.Context) error {
+	seState, err := selinux.GetState()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !seState.Enabled() {
+		p.Info("SELinux is disabled.")
+		return nil
+	}
+	policyPackage, err := p.Application.Manifest.Dependencies.ByName(constants.SELinuxPolicyPackage)
+	if err != nil {
+		if !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		p.Warnf("SELinux is %v but the application does not include the SELinux policy.", seState)
+		return nil
+	}
+	p.Progress.NextStep("Loading SELinux policy")
+	p.Infof("Loading SELinux policy from %v, SELinux is %v.", policyPackage, seState)
+	dir, err := ioutil.TempDir("", "selinux")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+	err = pack.Unpack(p.Packages, *policyPackage, dir, nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	stateDir, err := state.GetStateDir()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = selinux.LoadPolicy(ctx, filepath.Join(dir, constants.SELinuxPolicyModule),
+		stateDir, p.FieldLogger)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
 // configureApplicationVolumes creates necessary directories for
 // application mounts
 func (p *bootstrapExecutor) configureApplicationVolumes() error {
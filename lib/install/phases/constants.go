@@ -37,6 +37,9 @@ const (
 	WaitPhase = "/wait"
 	// HealthPhase is a phase that waits for the cluster to be healthy
 	HealthPhase = "/health"
+	// NodeProfilesPhase is a phase that applies the labels and taints
+	// of the node profiles
+	NodeProfilesPhase = "/node-profiles"
 	// RBACPhase is a phase that creates Kubernetes RBAC resources
 	RBACPhase = "/rbac"
 	// CorednsPhase is a phase that generates coredns configuration for the cluster
@@ -51,6 +54,9 @@ const (
 	RuntimePhase = "/runtime"
 	// AppPhase is a phase that installs user application
 	AppPhase = "/app"
+	// ReadinessPhase is a phase that waits for the application
+	// readiness checks to succeed
+	ReadinessPhase = "/readiness"
 	// ConnectInstallerPhase is a phase that connects cluster to the installer
 	ConnectInstallerPhase = "/connect-installer"
 	// EnableElectionPhase turns on election participation for master nodes
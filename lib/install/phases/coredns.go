@@ -17,18 +17,16 @@ limitations under the License.
 package phases
 
 import (
-	"bytes"
 	"context"
 
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/coredns"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/systeminfo"
-	"github.com/gravitational/teleport/lib/utils"
 
-	"github.com/alecthomas/template"
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
@@ -94,18 +92,8 @@ func (r *corednsExecutor) Execute(ctx context.Context) error {
 		return trace.Wrap(err)
 	}
 
-	// Filter out local nameservers to avoid CoreDNS forwarding requests
-	// to itself and triggering loop detection, see for more details:
-	// https://github.com/coredns/coredns/tree/master/plugin/loop#troubleshooting
-	var upstreams []string
-	for _, nameserver := range resolvConf.Servers {
-		if !utils.IsLocalhost(nameserver) {
-			upstreams = append(upstreams, nameserver)
-		}
-	}
-
-	conf, err := GenerateCorefile(CorednsConfig{
-		UpstreamNameservers: upstreams,
+	conf, err := coredns.GenerateCorefile(coredns.Config{
+		UpstreamNameservers: coredns.UpstreamNameservers(*resolvConf),
 		Rotate:              resolvConf.Rotate,
 		Hosts:               r.DNSOverrides.Hosts,
 		Zones:               r.DNSOverrides.Zones,
@@ -139,56 +127,3 @@ func (r *corednsExecutor) Rollback(context.Context) error {
 
 	return nil
 }
-
-// GenerateCorefile will generate a coredns configuration file to be used from within the cluster
-func GenerateCorefile(config CorednsConfig) (string, error) {
-	var coredns bytes.Buffer
-	err := coreDNSTemplate.Execute(&coredns, config)
-	if err != nil {
-		return "", trace.Wrap(err)
-	}
-	return coredns.String(), nil
-}
-
-// CoreDNSConfig represents the CoreDNS configuration options to apply to our template
-type CorednsConfig struct {
-	// Zones maps a DNS zone to nameservers it will be served by as provided by a user at install time
-	Zones map[string][]string
-	// Hosts  maps a hostname to an IP address it will resolve to as provided by a user at install time
-	Hosts map[string]string
-	// UpstreamNameservers is a list of nameservers to use as resolvers as detected from the system resolv.conf
-	UpstreamNameservers []string
-	// Rotate indicates whether the upstream servers should be round-robin load balanced as detected from the system
-	// resolv.conf
-	Rotate bool
-}
-
-var coreDNSTemplate = template.Must(template.New("coredns").Parse(coreDNSTemplateText))
-
-const coreDNSTemplateText = `
-.:53 {
-  reload
-  errors
-  health
-  prometheus :9153
-  cache 30
-  loop
-  reload
-  loadbalance
-  hosts { {{range $hostname, $ip := .Hosts}}
-    {{$ip}} {{$hostname}}{{end}}
-    fallthrough
-  }
-  kubernetes cluster.local in-addr.arpa ip6.arpa {
-    pods verified
-    fallthrough in-addr.arpa ip6.arpa
-  }{{range $zone, $servers := .Zones}}
-  proxy {{$zone}} {{range $server := $servers}}{{$server}} {{end}}{
-    policy sequential
-  }{{end}}
-  {{if .UpstreamNameservers}}forward . {{range $server := .UpstreamNameservers}}{{$server}} {{end}}{
-    {{if .Rotate}}policy random{{else}}policy sequential{{end}}
-    health_check 0
-  }{{end}}
-}
-`
@@ -0,0 +1,139 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/network/calico"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NewInstallOverlay returns a new executor that installs the overlay network
+// selected in the cluster manifest.
+//
+// The calico network is installed from the resources bundled with gravity,
+// other networks are installed with the network install hook
+func NewInstallOverlay(p fsm.ExecutorParams, operator ops.Operator, apps app.Applications, client *kubernetes.Clientset) (fsm.PhaseExecutor, error) {
+	cluster, err := operator.GetSite(ops.SiteKey{
+		AccountID:  defaults.SystemAccountID,
+		SiteDomain: p.Plan.ClusterName,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	operation, err := operator.GetSiteOperation(opKey(p.Plan))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	networking := cluster.App.Manifest.GetNetworking(cluster.Provider, operation.Provisioner)
+	if networking.Type != schema.NetworkingCalico {
+		return NewHook(p, operator, apps, schema.HookNetworkInstall)
+	}
+	podSubnet, err := getPodSubnet(operator, cluster.Key(), *operation)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	config := calico.Config{PodSubnet: podSubnet}
+	if networking.Calico != nil {
+		config.CalicoNetworking = *networking.Calico
+	}
+	logger := &fsm.Logger{
+		FieldLogger: logrus.WithFields(logrus.Fields{
+			constants.FieldPhase: p.Phase.ID,
+		}),
+		Key:      opKey(p.Plan),
+		Operator: operator,
+		Server:   p.Phase.Data.Server,
+	}
+	return &calicoExecutor{
+		FieldLogger:    logger,
+		Client:         client,
+		Config:         config,
+		ExecutorParams: p,
+	}, nil
+}
+
+type calicoExecutor struct {
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+	// Client is the Kubernetes client
+	Client *kubernetes.Clientset
+	// Config is the calico network configuration
+	Config calico.Config
+	// ExecutorParams is common executor params
+	fsm.ExecutorParams
+}
+
+// Execute creates the calico network resources
+func (p *calicoExecutor) Execute(ctx context.Context) error {
+	p.Progress.NextStep("Installing calico network")
+	objects, err := calico.Resources(p.Config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	upsert := fsm.GetUpsertBootstrapResourceFunc(p.Client)
+	for _, object := range objects {
+		if err := upsert(object); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	p.Infof("Installed calico network for pod subnet %v.", p.Config.PodSubnet)
+	return nil
+}
+
+// Rollback is no-op for this phase
+func (*calicoExecutor) Rollback(ctx context.Context) error {
+	return nil
+}
+
+// PreCheck makes sure this phase is executed on a master node
+func (p *calicoExecutor) PreCheck(ctx context.Context) error {
+	return trace.Wrap(fsm.CheckMasterServer(p.Plan.Servers))
+}
+
+// PostCheck is no-op for this phase
+func (*calicoExecutor) PostCheck(ctx context.Context) error {
+	return nil
+}
+
+// getPodSubnet returns the pod subnet of the cluster being installed.
+// The subnet from the cluster configuration takes precedence
+func getPodSubnet(operator ops.Operator, key ops.SiteKey, operation ops.SiteOperation) (string, error) {
+	config, err := operator.GetClusterConfiguration(key)
+	if err != nil && !trace.IsNotFound(err) {
+		return "", trace.Wrap(err)
+	}
+	if config != nil {
+		if global := config.GetGlobalConfig(); global != nil && global.PodCIDR != "" {
+			return global.PodCIDR, nil
+		}
+	}
+	if operation.InstallExpand == nil || operation.InstallExpand.Subnets.Overlay == "" {
+		return "", trace.NotFound("pod subnet is not set for operation %v", operation.ID)
+	}
+	return operation.InstallExpand.Subnets.Overlay, nil
+}
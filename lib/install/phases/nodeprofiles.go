@@ -0,0 +1,95 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
+	libkube "github.com/gravitational/gravity/lib/kubernetes"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NewNodeProfiles returns executor that makes sure the cluster nodes
+// carry the labels and taints declared by their node profiles
+func NewNodeProfiles(p fsm.ExecutorParams, operator ops.Operator, client *kubernetes.Clientset) (*nodeProfilesExecutor, error) {
+	logger := &fsm.Logger{
+		FieldLogger: log.WithField(constants.FieldPhase, p.Phase.ID),
+		Key:         opKey(p.Plan),
+		Operator:    operator,
+	}
+	cluster, err := operator.GetSite(ops.SiteKey{
+		AccountID:  defaults.SystemAccountID,
+		SiteDomain: p.Plan.ClusterName,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &nodeProfilesExecutor{
+		FieldLogger:    logger,
+		ExecutorParams: p,
+		Client:         client,
+		Manifest:       cluster.App.Manifest,
+	}, nil
+}
+
+type nodeProfilesExecutor struct {
+	// FieldLogger is used for logging
+	log.FieldLogger
+	// ExecutorParams is common executor params
+	fsm.ExecutorParams
+	// Client is the Kubernetes client
+	Client *kubernetes.Clientset
+	// Manifest is the manifest of the cluster application
+	Manifest schema.Manifest
+}
+
+// Execute applies the labels and taints missing on the nodes
+func (r *nodeProfilesExecutor) Execute(ctx context.Context) error {
+	r.Progress.NextStep("Applying node profile labels and taints")
+	updated, err := libkube.ReconcileNodeProfiles(ctx, r.Client.CoreV1().Nodes(),
+		r.Manifest, r.Plan.Servers)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(updated) != 0 {
+		r.Infof("Restored node profile labels and taints on %v.", updated)
+	}
+	return nil
+}
+
+// Rollback is no-op for this phase
+func (*nodeProfilesExecutor) Rollback(context.Context) error {
+	return nil
+}
+
+// PreCheck is no-op for this phase
+func (*nodeProfilesExecutor) PreCheck(context.Context) error {
+	return nil
+}
+
+// PostCheck is no-op for this phase
+func (*nodeProfilesExecutor) PostCheck(context.Context) error {
+	return nil
+}
@@ -17,14 +17,10 @@ limitations under the License.
 package phases
 
 import (
-	"archive/tar"
 	"context"
-	"io"
 	"time"
 
 	"github.com/gravitational/gravity/lib/app"
-	"github.com/gravitational/gravity/lib/app/resources"
-	"github.com/gravitational/gravity/lib/archive"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/fsm"
@@ -33,7 +29,6 @@ import (
 	"github.com/gravitational/gravity/lib/utils"
 	"github.com/gravitational/satellite/agent/proto/agentpb"
 
-	dockerarchive "github.com/docker/docker/pkg/archive"
 	"github.com/gravitational/trace"
 	"github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -226,25 +221,8 @@ type rbacExecutor struct {
 // Execute executes the rbac phase
 func (p *rbacExecutor) Execute(ctx context.Context) error {
 	p.Progress.NextStep("Creating Kubernetes RBAC resources")
-	reader, err := p.Apps.GetAppResources(*p.Phase.Data.Package)
-	if err != nil {
-		return trace.Wrap(err)
-	}
-	defer reader.Close()
-	stream, err := dockerarchive.DecompressStream(reader)
-	if err != nil {
-		return trace.Wrap(err)
-	}
-	defer stream.Close()
-	err = archive.TarGlob(
-		tar.NewReader(stream),
-		defaults.ResourcesDir,
-		[]string{defaults.ResourcesFile},
-		func(_ string, reader io.Reader) error {
-			return resources.ForEachObject(
-				reader,
-				fsm.GetUpsertBootstrapResourceFunc(p.Client))
-		})
+	err := app.ForEachResource(p.Apps, *p.Phase.Data.Package,
+		fsm.GetUpsertBootstrapResourceFunc(p.Client))
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -0,0 +1,100 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/app/readiness"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NewReadiness returns executor that waits for the readiness checks
+// declared in the application manifest to succeed
+func NewReadiness(p fsm.ExecutorParams, operator ops.Operator, client *kubernetes.Clientset) (*readinessExecutor, error) {
+	logger := &fsm.Logger{
+		FieldLogger: log.WithField(constants.FieldPhase, p.Phase.ID),
+		Key:         opKey(p.Plan),
+		Operator:    operator,
+	}
+	cluster, err := operator.GetSite(ops.SiteKey{
+		AccountID:  defaults.SystemAccountID,
+		SiteDomain: p.Plan.ClusterName,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	checker, err := readiness.New(readiness.Config{
+		Client:      client,
+		DNSAddr:     p.Plan.DNSConfig.Addr(),
+		FieldLogger: logger,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &readinessExecutor{
+		FieldLogger:    logger,
+		ExecutorParams: p,
+		Checker:        checker,
+		Manifest:       cluster.App.Manifest,
+	}, nil
+}
+
+type readinessExecutor struct {
+	// FieldLogger is used for logging
+	log.FieldLogger
+	// ExecutorParams is common executor params
+	fsm.ExecutorParams
+	// Checker evaluates the readiness checks
+	Checker *readiness.Checker
+	// Manifest is the manifest of the cluster application
+	Manifest schema.Manifest
+}
+
+// Execute waits for the application readiness checks to succeed
+func (r *readinessExecutor) Execute(ctx context.Context) error {
+	r.Progress.NextStep("Waiting for the application to become ready")
+	err := r.Checker.Wait(ctx, r.Manifest.ReadinessChecks)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	r.Info("Application is ready.")
+	return nil
+}
+
+// Rollback is no-op for this phase
+func (*readinessExecutor) Rollback(context.Context) error {
+	return nil
+}
+
+// PreCheck is no-op for this phase
+func (*readinessExecutor) PreCheck(context.Context) error {
+	return nil
+}
+
+// PostCheck is no-op for this phase
+func (*readinessExecutor) PostCheck(context.Context) error {
+	return nil
+}
@@ -114,11 +114,18 @@ func (i *Installer) GetOperationPlan(cluster ops.Site, op ops.SiteOperation) (*s
 	// export applications to registries
 	builder.AddExportPhase(plan)
 
-	if cluster.App.Manifest.HasHook(schema.HookNetworkInstall) {
+	networkType := cluster.App.Manifest.GetNetworkType(cluster.Provider, op.Provisioner)
+	if cluster.App.Manifest.HasHook(schema.HookNetworkInstall) || networkType == schema.NetworkingCalico {
 		builder.AddInstallOverlayPhase(plan, &cluster.App.Package)
 	}
 	builder.AddHealthPhase(plan)
 
+	// (optional) make sure the nodes carry the labels and taints
+	// declared by their profiles
+	if hasNodeProfileLabels(cluster.App.Manifest) {
+		builder.AddNodeProfilesPhase(plan)
+	}
+
 	// install runtime application
 	err = builder.AddRuntimePhase(plan)
 	if err != nil {
@@ -131,6 +138,11 @@ func (i *Installer) GetOperationPlan(cluster ops.Site, op ops.SiteOperation) (*s
 		return nil, trace.Wrap(err)
 	}
 
+	// (optional) wait for the application readiness checks
+	if len(cluster.App.Manifest.ReadinessChecks) != 0 {
+		builder.AddReadinessPhase(plan)
+	}
+
 	// establish trust b/w installed cluster and installer process
 	err = builder.AddConnectInstallerPhase(plan)
 	if err != nil {
@@ -145,3 +157,14 @@ func (i *Installer) GetOperationPlan(cluster ops.Site, op ops.SiteOperation) (*s
 
 	return plan, nil
 }
+
+// hasNodeProfileLabels returns true if any of the node profiles of the manifest
+// declares labels or taints
+func hasNodeProfileLabels(manifest schema.Manifest) bool {
+	for _, profile := range manifest.NodeProfiles {
+		if len(profile.Labels) != 0 || len(profile.Taints) != 0 {
+			return true
+		}
+	}
+	return false
+}
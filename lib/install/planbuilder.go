@@ -179,7 +179,7 @@ func (b *PlanBuilder) AddPullPhase(plan *storage.OperationPlan) {
 func (b *PlanBuilder) AddMastersPhase(plan *storage.OperationPlan) error {
 	var masterPhases []storage.OperationPhase
 	for i, node := range b.Masters {
-		planetPackage, err := b.Application.Manifest.RuntimePackageForProfile(node.Role)
+		planetPackage, err := b.Application.Manifest.RuntimePackageForProfileArch(node.Role, node.Arch())
 		if err != nil {
 			return trace.Wrap(err)
 		}
@@ -233,7 +233,7 @@ func (b *PlanBuilder) AddMastersPhase(plan *storage.OperationPlan) error {
 func (b *PlanBuilder) AddNodesPhase(plan *storage.OperationPlan) error {
 	var nodePhases []storage.OperationPhase
 	for i, node := range b.Nodes {
-		planetPackage, err := b.Application.Manifest.RuntimePackageForProfile(node.Role)
+		planetPackage, err := b.Application.Manifest.RuntimePackageForProfileArch(node.Role, node.Arch())
 		if err != nil {
 			return trace.Wrap(err)
 		}
@@ -309,6 +309,20 @@ func (b *PlanBuilder) AddHealthPhase(plan *storage.OperationPlan) {
 	})
 }
 
+// AddNodeProfilesPhase appends phase that makes sure the labels and taints
+// of the node profiles have been applied to the cluster nodes
+func (b *PlanBuilder) AddNodeProfilesPhase(plan *storage.OperationPlan) {
+	plan.Phases = append(plan.Phases, storage.OperationPhase{
+		ID:          phases.NodeProfilesPhase,
+		Description: "Apply node profile labels and taints",
+		Requires:    []string{phases.HealthPhase},
+		Data: &storage.OperationPhaseData{
+			Server: &b.Master,
+		},
+		Step: 4,
+	})
+}
+
 // AddRBACPhase appends K8s RBAC initialization phase to the provided plan
 func (b *PlanBuilder) AddRBACPhase(plan *storage.OperationPlan) {
 	plan.Phases = append(plan.Phases, storage.OperationPhase{
@@ -483,6 +497,20 @@ func (b *PlanBuilder) AddApplicationPhase(plan *storage.OperationPlan) error {
 	return nil
 }
 
+// AddReadinessPhase appends phase that waits for the readiness checks
+// declared in the application manifest to succeed
+func (b *PlanBuilder) AddReadinessPhase(plan *storage.OperationPlan) {
+	plan.Phases = append(plan.Phases, storage.OperationPhase{
+		ID:          phases.ReadinessPhase,
+		Description: "Wait for the application to become ready",
+		Requires:    []string{phases.AppPhase},
+		Data: &storage.OperationPhaseData{
+			Server: &b.Master,
+		},
+		Step: 7,
+	})
+}
+
 // AddConnectInstallerPhase appends installer/cluster connection phase
 func (b *PlanBuilder) AddConnectInstallerPhase(plan *storage.OperationPlan) error {
 	bytes, err := storage.MarshalTrustedCluster(b.InstallerTrustedCluster)
@@ -44,15 +44,30 @@ func (d *drain) drainPods(ctx context.Context) error {
 		return trace.Wrap(err)
 	}
 
-	err = d.deleteOrEvictPods(ctx, pods)
-	if err != nil {
-		pendingPods, err := d.getPodsForDeletion()
-		if err != nil {
-			return trace.Wrap(err)
-		}
-		log.Warningf("error deleting pods: %v\npending pods: %v",
+	evictCtx := ctx
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		evictCtx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+	err = d.deleteOrEvictPods(evictCtx, pods)
+	if err == nil {
+		return nil
+	}
+	pendingPods, errPending := d.getPodsForDeletion()
+	if errPending != nil {
+		return trace.NewAggregate(err, errPending)
+	}
+	if len(pendingPods) == 0 {
+		return nil
+	}
+	if d.force && ctx.Err() == nil {
+		log.Warnf("Failed to evict pods: %v, will force delete pending pods: %v.",
 			trace.DebugReport(err), formatPodList(pendingPods))
+		return trace.Wrap(d.deletePods(ctx, pendingPods))
 	}
+	log.Warningf("error deleting pods: %v\npending pods: %v",
+		trace.DebugReport(err), formatPodList(pendingPods))
 	return trace.Wrap(err)
 }
 
@@ -92,7 +107,9 @@ func (d *drain) evictPods(ctx context.Context, pods []v1.Pod, policyGroupVersion
 }
 
 func (d *drain) evictPodAndWait(ctx context.Context, pod v1.Pod, policyGroupVersion string) error {
-	b := backoff.NewExponentialBackOff()
+	// Eviction is retried for as long as it is blocked by a pod disruption budget
+	// and is bounded by the context
+	b := utils.NewUnlimitedExponentialBackOff()
 	err := utils.RetryWithInterval(ctx, b,
 		func() error {
 			err := d.evictPod(pod, policyGroupVersion)
@@ -216,9 +233,12 @@ type drain struct {
 	// gracePeriodSeconds defines the grace period for eviction.
 	// -1 means default grace period defined for a pod is used
 	gracePeriodSeconds int64
-	// timeout sets the timeout for the operation.
+	// timeout sets the timeout for pod eviction.
 	// zero value means no timeout
 	timeout time.Duration
+	// force specifies whether to delete the pods that could not be evicted
+	// within timeout
+	force bool
 }
 
 // queryEvictionPolicyGroupVersion uses Discovery API to find out if the server supports eviction subresource.
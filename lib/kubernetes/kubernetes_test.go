@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/cenkalti/backoff"
@@ -32,10 +33,12 @@ import (
 	log "github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
 	extensionsv1 "k8s.io/api/extensions/v1beta1"
+	policy "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
@@ -134,7 +137,7 @@ func (s *S) TestDrainsNode(c *C) {
 	c.Assert(err, IsNil)
 
 	// exercise
-	err = Drain(ctx, s.Clientset, s.Name)
+	err = Drain(ctx, s.Clientset, s.Name, storage.DrainOptions{})
 	c.Assert(err, IsNil)
 
 	podList, err = s.Core().Pods(testNamespace).List(
@@ -151,6 +154,51 @@ func (s *S) TestDrainsNode(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *S) TestForceDrainsPodsBlockedByDisruptionBudget(c *C) {
+	client := s.CoreV1().Nodes()
+	ctx, cancel := context.WithTimeout(context.TODO(), testTimeout)
+	defer cancel()
+
+	// setup
+	d := newDeployment("bar", newPod("bar").Spec)
+	_, err := s.Extensions().Deployments(testNamespace).Create(d)
+	c.Assert(err, IsNil)
+
+	minAvailable := intstr.FromInt(1)
+	_, err = s.Policy().PodDisruptionBudgets(testNamespace).Create(&policy.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "bar"},
+		Spec: policy.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"test-app": "bar"}},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	listOptions := metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": s.Name}).String(),
+		LabelSelector: labels.SelectorFromSet(labels.Set{"test-app": "bar"}).String(),
+	}
+	podList, err := s.Core().Pods(testNamespace).List(listOptions)
+	c.Assert(err, IsNil)
+	err = waitForPods(ctx, s.CoreV1(), podList.Items, v1.PodRunning)
+	c.Assert(err, IsNil)
+
+	// exercise & verify
+	err = Drain(ctx, s.Clientset, s.Name, storage.DrainOptions{Timeout: 5 * time.Second})
+	c.Assert(err, NotNil, Commentf("expected eviction to be blocked by the disruption budget"))
+
+	err = Drain(ctx, s.Clientset, s.Name, storage.DrainOptions{Timeout: 5 * time.Second, Force: true})
+	c.Assert(err, IsNil)
+
+	pendingPods, err := waitForDelete(ctx, s.CoreV1(), podList.Items, usingEviction(false))
+	c.Assert(err, IsNil)
+	c.Assert(pendingPods, HasLen, 0)
+
+	// Clean up
+	err = SetUnschedulable(ctx, client, s.Name, false)
+	c.Assert(err, IsNil)
+}
+
 func (s *S) TestUpdatesNodeTaints(c *C) {
 	client := s.CoreV1().Nodes()
 	ctx, cancel := context.WithTimeout(context.TODO(), testTimeout)
@@ -174,6 +222,31 @@ func (s *S) TestUpdatesNodeTaints(c *C) {
 	c.Assert(hasTaint(updatedNode.Spec.Taints, taintsToAdd), Equals, false)
 }
 
+func (s *S) TestReconcilesNode(c *C) {
+	client := s.CoreV1().Nodes()
+	ctx, cancel := context.WithTimeout(context.TODO(), testTimeout)
+	defer cancel()
+	labels := map[string]string{"gravitational.io/test": "reconcile"}
+	taints := []v1.Taint{
+		{Key: "reconcile", Value: "test", Effect: v1.TaintEffectPreferNoSchedule},
+	}
+	defer UpdateTaints(ctx, client, s.Name, nil, taints)
+
+	updated, err := ReconcileNode(ctx, client, s.Name, labels, taints)
+	c.Assert(err, IsNil)
+	c.Assert(updated, Equals, true)
+
+	node, err := client.Get(s.Name, metav1.GetOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(node.Labels["gravitational.io/test"], Equals, "reconcile")
+	c.Assert(hasTaint(node.Spec.Taints, taints), Equals, true)
+
+	// nothing has drifted
+	updated, err = ReconcileNode(ctx, client, s.Name, labels, taints)
+	c.Assert(err, IsNil)
+	c.Assert(updated, Equals, false)
+}
+
 func (s *S) TestCordonsUncordonsNode(c *C) {
 	client := s.CoreV1().Nodes()
 	ctx, cancel := context.WithTimeout(context.TODO(), testTimeout)
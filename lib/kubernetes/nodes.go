@@ -20,6 +20,7 @@ import (
 	"context"
 
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/utils"
 
@@ -34,7 +35,9 @@ import (
 )
 
 // Drain safely drains the specified node and uses Eviction API if supported on the api server.
-func Drain(ctx context.Context, client *kubernetes.Clientset, nodeName string) error {
+// Eviction honors pod disruption budgets: if the pods cannot be evicted within the configured
+// timeout, they are deleted if options.Force is set, otherwise the drain fails
+func Drain(ctx context.Context, client *kubernetes.Clientset, nodeName string, options storage.DrainOptions) error {
 	err := SetUnschedulable(ctx, client.CoreV1().Nodes(), nodeName, true)
 	if err != nil {
 		return trace.Wrap(err)
@@ -44,6 +47,11 @@ func Drain(ctx context.Context, client *kubernetes.Clientset, nodeName string) e
 		client:             client,
 		nodeName:           nodeName,
 		gracePeriodSeconds: defaults.ResourceGracePeriod,
+		timeout:            options.Timeout,
+		force:              options.Force,
+	}
+	if d.timeout == 0 {
+		d.timeout = defaults.DrainEvictionTimeout
 	}
 	err = d.drainPods(ctx)
 	return trace.Wrap(err)
@@ -111,6 +119,87 @@ func UpdateLabels(ctx context.Context, client corev1.NodeInterface, nodeName str
 	return rigging.ConvertError(err)
 }
 
+// ReconcileNode restores the specified labels and taints on the node
+// if they have been removed or changed. Labels and taints not in the lists
+// are left intact. Returns true if the node has been updated
+func ReconcileNode(ctx context.Context, client corev1.NodeInterface, nodeName string, labels map[string]string, taints []v1.Taint) (updated bool, err error) {
+	node, err := client.Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return false, rigging.ConvertError(err)
+	}
+	driftedLabels := make(map[string]string)
+	for name, value := range labels {
+		if current, ok := node.Labels[name]; !ok || current != value {
+			driftedLabels[name] = value
+		}
+	}
+	var driftedTaints []v1.Taint
+	for _, taint := range taints {
+		if !containsTaint(node.Spec.Taints, taint) {
+			driftedTaints = append(driftedTaints, taint)
+		}
+	}
+	if len(driftedLabels) != 0 {
+		if err := UpdateLabels(ctx, client, nodeName, driftedLabels); err != nil {
+			return false, trace.Wrap(err)
+		}
+	}
+	if len(driftedTaints) != 0 {
+		if err := UpdateTaints(ctx, client, nodeName, driftedTaints, nil); err != nil {
+			return false, trace.Wrap(err)
+		}
+	}
+	return len(driftedLabels) != 0 || len(driftedTaints) != 0, nil
+}
+
+// ReconcileNodeProfiles restores the labels and taints declared by the node
+// profiles of the manifest on the nodes of the specified servers.
+// Returns the names of the nodes that have been updated
+func ReconcileNodeProfiles(ctx context.Context, client corev1.NodeInterface, manifest schema.Manifest, servers []storage.Server) (updated []string, err error) {
+	for _, server := range servers {
+		profile, err := manifest.NodeProfiles.ByName(server.Role)
+		if err != nil {
+			return updated, trace.Wrap(err)
+		}
+		if len(profile.Labels) == 0 && len(profile.Taints) == 0 {
+			continue
+		}
+		nodeUpdated, err := ReconcileNode(ctx, client, server.KubeNodeID(),
+			profileLabels(server, *profile), profile.Taints)
+		if err != nil {
+			return updated, trace.Wrap(err, "failed to reconcile node %v", server.KubeNodeID())
+		}
+		if nodeUpdated {
+			updated = append(updated, server.KubeNodeID())
+		}
+	}
+	return updated, nil
+}
+
+// profileLabels returns the labels of the node profile with the value of
+// the system role label matching the role of the server
+func profileLabels(server storage.Server, profile schema.NodeProfile) map[string]string {
+	labels := make(map[string]string, len(profile.Labels))
+	for name, value := range profile.Labels {
+		labels[name] = value
+	}
+	if _, ok := labels[defaults.KubernetesRoleLabel]; ok {
+		labels[defaults.KubernetesRoleLabel] = server.ClusterRole
+	}
+	return labels
+}
+
+// containsTaint returns true if the list of taints contains the specified taint
+// with the same value
+func containsTaint(taints []v1.Taint, taint v1.Taint) bool {
+	for _, existing := range taints {
+		if existing.MatchTaint(&taint) && existing.Value == taint.Value {
+			return true
+		}
+	}
+	return false
+}
+
 // GetNode returns Kubernetes node corresponding to the provided server
 func GetNode(client *kubernetes.Clientset, server storage.Server) (*v1.Node, error) {
 	nodes, err := client.Core().Nodes().List(metav1.ListOptions{
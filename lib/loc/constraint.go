@@ -0,0 +1,97 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loc
+
+import (
+	"strings"
+
+	constraints "github.com/Masterminds/semver"
+	"github.com/coreos/go-semver/semver"
+	"github.com/gravitational/configure/cstrings"
+	"github.com/gravitational/trace"
+)
+
+// NewConstraintLocator returns a new locator for the package with the specified
+// repository and name. Unlike NewLocator, the version can also be a semver range
+// such as ^1.2.0 or >=1.2.0, <2.0.0 to be resolved against a package repository
+// later, see IsConstraint
+func NewConstraintLocator(repository, name, ver string) (*Locator, error) {
+	if _, err := semver.NewVersion(ver); err == nil {
+		return NewLocator(repository, name, ver)
+	}
+	if !cstrings.IsValidDomainName(repository) {
+		return nil, trace.BadParameter(
+			"repository %q has invalid format, should be valid domain name, e.g. example.com", repository)
+	}
+	if name == "" {
+		return nil, trace.BadParameter(
+			"package name %q has invalid format, should be valid identifier e.g. package-name", name)
+	}
+	if _, err := constraints.NewConstraint(ver); err != nil {
+		return nil, trace.BadParameter(
+			"unsupported version format %q, need semver version or range, e.g 1.0.0 or ^1.0.0: %v",
+			ver, err)
+	}
+	return &Locator{Repository: repository, Name: name, Version: strings.TrimSpace(ver)}, nil
+}
+
+// ParseConstraintLocator parses the locator in the repository/name:version
+// format where version can be an exact version or a semver range
+func ParseConstraintLocator(v string) (*Locator, error) {
+	parts := strings.SplitN(v, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, trace.BadParameter(
+			"package locator should be repository/name:semver, e.g. example.com/test:^1.0.0, got %v", v)
+	}
+	m := locRe.FindStringSubmatch(parts[1])
+	if len(m) != 3 {
+		return nil, trace.BadParameter(
+			"invalid package locator, should be repository/name:semver, e.g. example.com/test:^1.0.0")
+	}
+	return NewConstraintLocator(parts[0], m[1], m[2])
+}
+
+// IsConstraint returns true if the locator version is a semver range
+// rather than an exact version
+func (l Locator) IsConstraint() bool {
+	if _, err := semver.NewVersion(l.Version); err == nil {
+		return false
+	}
+	_, err := constraints.NewConstraint(l.Version)
+	return err == nil
+}
+
+// Matches returns true if the version of the specified locator of the same
+// package satisfies the version range of this locator.
+// A locator with an exact version only matches the same version
+func (l Locator) Matches(other Locator) bool {
+	if l.Repository != other.Repository || l.Name != other.Name {
+		return false
+	}
+	if !l.IsConstraint() {
+		return l.Version == other.Version
+	}
+	constraint, err := constraints.NewConstraint(l.Version)
+	if err != nil {
+		return false
+	}
+	version, err := constraints.NewVersion(other.Version)
+	if err != nil {
+		return false
+	}
+	return constraint.Check(version)
+}
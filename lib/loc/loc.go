@@ -243,6 +243,10 @@ var (
 	Planet = MustParseLocator(
 		fmt.Sprintf("%v/%v:%v", defaults.SystemAccountOrg, constants.PlanetPackage, ZeroVersion))
 
+	// SELinuxPolicy is the SELinux policy package locator
+	SELinuxPolicy = MustParseLocator(
+		fmt.Sprintf("%v/%v:%v", defaults.SystemAccountOrg, constants.SELinuxPolicyPackage, ZeroVersion))
+
 	// TrustedCluster is the trusted-cluster package locator
 	TrustedCluster = MustParseLocator(
 		fmt.Sprintf("%v/%v:0.0.1", defaults.SystemAccountOrg, constants.TrustedClusterPackage))
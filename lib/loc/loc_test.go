@@ -86,6 +86,55 @@ func (s *LocatorSuite) TestLocatorFail(c *C) {
 	}
 }
 
+func (s *LocatorSuite) TestConstraintLocator(c *C) {
+	tcs := []struct {
+		loc        string
+		constraint bool
+		matches    []string
+		mismatches []string
+	}{
+		{
+			loc:        "example.com/package:1.2.0",
+			matches:    []string{"1.2.0"},
+			mismatches: []string{"1.2.1"},
+		},
+		{
+			loc:        "example.com/package:^1.2.0",
+			constraint: true,
+			matches:    []string{"1.2.0", "1.9.3"},
+			mismatches: []string{"1.1.9", "2.0.0"},
+		},
+		{
+			loc:        "example.com/package:>=1.2.0, <1.4.0",
+			constraint: true,
+			matches:    []string{"1.2.0", "1.3.7"},
+			mismatches: []string{"1.4.0"},
+		},
+	}
+	for _, tc := range tcs {
+		comment := Commentf("loc=%v", tc.loc)
+		loc, err := ParseConstraintLocator(tc.loc)
+		c.Assert(err, IsNil, comment)
+		c.Assert(loc.IsConstraint(), Equals, tc.constraint, comment)
+		for _, version := range tc.matches {
+			c.Assert(loc.Matches(MustCreateLocator(loc.Repository, loc.Name, version)), Equals, true, comment)
+		}
+		for _, version := range tc.mismatches {
+			c.Assert(loc.Matches(MustCreateLocator(loc.Repository, loc.Name, version)), Equals, false, comment)
+		}
+		c.Assert(loc.Matches(MustCreateLocator(loc.Repository, "other", tc.matches[0])), Equals, false, comment)
+	}
+
+	for _, v := range []string{"example.com/package:blabla", "example.com/package:", "package:^1.0.0"} {
+		_, err := ParseConstraintLocator(v)
+		c.Assert(err, NotNil, Commentf("loc=%v", v))
+		_, err = ParseLocator(v)
+		c.Assert(err, NotNil, Commentf("loc=%v", v))
+	}
+	_, err := ParseLocator("example.com/package:^1.0.0")
+	c.Assert(err, NotNil)
+}
+
 func (s *LocatorSuite) TestParseDockerImage(c *C) {
 	tcs := []struct {
 		input    string
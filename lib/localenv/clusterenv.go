@@ -22,7 +22,8 @@ import (
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/app/service"
-	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/blob/chunked"
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/ops/opsservice"
 	"github.com/gravitational/gravity/lib/pack"
@@ -66,6 +67,8 @@ type ClusterEnvironment struct {
 	Backend storage.Backend
 	// Packages is the package service that talks to local storage
 	Packages pack.PackageService
+	// Objects is the local copy of the cluster package data
+	Objects blob.Objects
 	// ClusterPackages is the package service that talks to cluster API
 	ClusterPackages pack.PackageService
 	// Apps is the cluster apps service
@@ -120,7 +123,7 @@ func newClusterEnvironment(config clusterEnvironmentConfig) (*ClusterEnvironment
 		return nil, trace.Wrap(err)
 	}
 
-	objects, err := fs.New(packagesDir)
+	objects, err := chunked.New(packagesDir)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -183,6 +186,7 @@ func newClusterEnvironment(config clusterEnvironmentConfig) (*ClusterEnvironment
 	return &ClusterEnvironment{
 		Backend:         backend,
 		Packages:        packages,
+		Objects:         objects,
 		ClusterPackages: clusterPackages,
 		Apps:            apps,
 		Users:           users,
@@ -30,7 +30,7 @@ import (
 	"github.com/gravitational/gravity/lib/app/docker"
 	appservice "github.com/gravitational/gravity/lib/app/service"
 	"github.com/gravitational/gravity/lib/blob"
-	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/blob/chunked"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/httplib"
@@ -181,7 +181,7 @@ func (env *LocalEnvironment) init() error {
 		env.DNS = DNSConfig(*dns)
 	}
 
-	env.Objects, err = fs.New(filepath.Join(env.StateDir, defaults.PackagesDir))
+	env.Objects, err = chunked.New(filepath.Join(env.StateDir, defaults.PackagesDir))
 	if err != nil {
 		return trace.Wrap(err)
 	}
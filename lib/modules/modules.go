@@ -154,12 +154,12 @@ type defaultResources struct{}
 
 // SupportedResources returns a list of resources that can be created/viewed
 func (*defaultResources) SupportedResources() []string {
-	return storage.SupportedGravityResources
+	return withResourceKinds(storage.SupportedGravityResources)
 }
 
 // SupportedResourcesToRemove returns a list of resources that can be removed
 func (*defaultResources) SupportedResourcesToRemove() []string {
-	return storage.SupportedGravityResourcesToRemove
+	return withResourceKinds(storage.SupportedGravityResourcesToRemove)
 }
 
 // CanonicalKind translates the specified kind to canonical form.
@@ -168,6 +168,12 @@ func (*defaultResources) CanonicalKind(kind string) string {
 	return storage.CanonicalKind(kind)
 }
 
+// withResourceKinds returns the specified resource kinds along with
+// the kinds registered with storage.RegisterResourceKind
+func withResourceKinds(kinds []string) []string {
+	return append(append([]string(nil), kinds...), storage.ResourceKinds()...)
+}
+
 // Version represents gravity version
 type Version struct {
 	// Edition is the gravity edition, e.g. open-source
@@ -0,0 +1,379 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package calico renders the Kubernetes resources of the Calico
+// network bundled with cluster images
+package calico
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/gravitational/gravity/lib/app/resources"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// NodeImage is the image of the Calico node agent
+	NodeImage = "quay.io/calico/node:v3.8.4"
+	// CNIImage is the image that installs Calico CNI plugins and
+	// network configuration on the nodes
+	CNIImage = "quay.io/calico/cni:v3.8.4"
+
+	// DefaultMTU is the default MTU of the pod network interfaces.
+	// It accounts for the 20-byte IP-in-IP header
+	DefaultMTU = 1440
+)
+
+// Images returns the list of images used by the Calico network.
+// They are vendored into cluster images that select the calico network type
+func Images() []string {
+	return []string{NodeImage, CNIImage}
+}
+
+// Config describes the Calico network configuration
+type Config struct {
+	// PodSubnet is the CIDR range of the pod network
+	PodSubnet string
+	// Registry is the address of the registry the images are pulled from
+	Registry string
+	// CalicoNetworking specifies the Calico settings from the cluster manifest
+	schema.CalicoNetworking
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (r *Config) CheckAndSetDefaults() error {
+	if r.PodSubnet == "" {
+		return trace.BadParameter("missing PodSubnet")
+	}
+	if r.Registry == "" {
+		r.Registry = constants.DockerRegistry
+	}
+	if r.IPIPMode == "" {
+		r.IPIPMode = schema.CalicoIPIPAlways
+	}
+	if r.MTU == 0 {
+		r.MTU = DefaultMTU
+	}
+	return nil
+}
+
+// Resources returns the Kubernetes resources that install the Calico
+// network: the custom resource definitions and RBAC resources of the
+// Calico datastore, the CNI configuration and the node agent DaemonSet
+// which lays down the CNI configuration on every node
+func Resources(config Config) ([]runtime.Object, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	nodeImage, err := wrapImage(NodeImage, config.Registry)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cniImage, err := wrapImage(CNIImage, config.Registry)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var buf bytes.Buffer
+	err = resourcesTemplate.Execute(&buf, map[string]interface{}{
+		"Config":    config,
+		"NodeImage": nodeImage,
+		"CNIImage":  cniImage,
+		"CRDs":      customResources,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var objects []runtime.Object
+	err = resources.ForEachObject(&buf, func(object runtime.Object) error {
+		objects = append(objects, object)
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return objects, nil
+}
+
+// wrapImage returns the image reference that points to the specified registry
+func wrapImage(image, registry string) (string, error) {
+	parsed, err := loc.ParseDockerImage(image)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	parsed.Registry = registry
+	return parsed.String(), nil
+}
+
+// customResource describes a custom resource of the Calico datastore
+type customResource struct {
+	// Kind is the resource kind
+	Kind string
+	// Plural is the plural resource name
+	Plural string
+	// Namespaced is whether the resource is namespaced
+	Namespaced bool
+}
+
+var customResources = []customResource{
+	{Kind: "FelixConfiguration", Plural: "felixconfigurations"},
+	{Kind: "IPAMBlock", Plural: "ipamblocks"},
+	{Kind: "BlockAffinity", Plural: "blockaffinities"},
+	{Kind: "IPAMHandle", Plural: "ipamhandles"},
+	{Kind: "IPAMConfig", Plural: "ipamconfigs"},
+	{Kind: "BGPPeer", Plural: "bgppeers"},
+	{Kind: "BGPConfiguration", Plural: "bgpconfigurations"},
+	{Kind: "IPPool", Plural: "ippools"},
+	{Kind: "HostEndpoint", Plural: "hostendpoints"},
+	{Kind: "ClusterInformation", Plural: "clusterinformations"},
+	{Kind: "GlobalNetworkPolicy", Plural: "globalnetworkpolicies"},
+	{Kind: "GlobalNetworkSet", Plural: "globalnetworksets"},
+	{Kind: "NetworkPolicy", Plural: "networkpolicies", Namespaced: true},
+	{Kind: "NetworkSet", Plural: "networksets", Namespaced: true},
+}
+
+var resourcesTemplate = template.Must(template.New("calico").Parse(`
+{{- range .CRDs}}
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: {{.Plural}}.crd.projectcalico.org
+spec:
+  scope: {{if .Namespaced}}Namespaced{{else}}Cluster{{end}}
+  group: crd.projectcalico.org
+  version: v1
+  names:
+    kind: {{.Kind}}
+    plural: {{.Plural}}
+    singular: {{.Plural}}
+---
+{{- end}}
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: calico-node
+  namespace: kube-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: calico-node
+rules:
+  - apiGroups: [""]
+    resources: ["pods", "nodes", "namespaces", "endpoints", "services", "configmaps", "serviceaccounts"]
+    verbs: ["get", "list", "watch"]
+  - apiGroups: [""]
+    resources: ["nodes/status", "pods/status"]
+    verbs: ["patch", "update"]
+  - apiGroups: ["networking.k8s.io"]
+    resources: ["networkpolicies"]
+    verbs: ["get", "list", "watch"]
+  - apiGroups: ["crd.projectcalico.org"]
+    resources: ["*"]
+    verbs: ["*"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: calico-node
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: calico-node
+subjects:
+  - kind: ServiceAccount
+    name: calico-node
+    namespace: kube-system
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: calico-config
+  namespace: kube-system
+data:
+  veth_mtu: "{{.Config.MTU}}"
+  cni_network_config: |-
+    {
+      "name": "k8s-pod-network",
+      "cniVersion": "0.3.1",
+      "plugins": [
+        {
+          "type": "calico",
+          "log_level": "info",
+          "datastore_type": "kubernetes",
+          "nodename": "__KUBERNETES_NODE_NAME__",
+          "mtu": __CNI_MTU__,
+          "ipam": {"type": "calico-ipam"},
+          "policy": {"type": "k8s"},
+          "kubernetes": {"kubeconfig": "__KUBECONFIG_FILEPATH__"}
+        },
+        {
+          "type": "portmap",
+          "snat": true,
+          "capabilities": {"portMappings": true}
+        }
+      ]
+    }
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: calico-node
+  namespace: kube-system
+  labels:
+    k8s-app: calico-node
+spec:
+  selector:
+    matchLabels:
+      k8s-app: calico-node
+  updateStrategy:
+    type: RollingUpdate
+    rollingUpdate:
+      maxUnavailable: 1
+  template:
+    metadata:
+      labels:
+        k8s-app: calico-node
+    spec:
+      hostNetwork: true
+      serviceAccountName: calico-node
+      priorityClassName: system-node-critical
+      terminationGracePeriodSeconds: 0
+      nodeSelector:
+        beta.kubernetes.io/os: linux
+      tolerations:
+        - effect: NoSchedule
+          operator: Exists
+        - effect: NoExecute
+          operator: Exists
+        - key: CriticalAddonsOnly
+          operator: Exists
+      initContainers:
+        - name: install-cni
+          image: {{.CNIImage}}
+          command: ["/install-cni.sh"]
+          env:
+            - name: CNI_CONF_NAME
+              value: "10-calico.conflist"
+            - name: CNI_NETWORK_CONFIG
+              valueFrom:
+                configMapKeyRef:
+                  name: calico-config
+                  key: cni_network_config
+            - name: KUBERNETES_NODE_NAME
+              valueFrom:
+                fieldRef:
+                  fieldPath: spec.nodeName
+            - name: CNI_MTU
+              valueFrom:
+                configMapKeyRef:
+                  name: calico-config
+                  key: veth_mtu
+            - name: SLEEP
+              value: "false"
+          volumeMounts:
+            - name: cni-bin-dir
+              mountPath: /host/opt/cni/bin
+            - name: cni-net-dir
+              mountPath: /host/etc/cni/net.d
+      containers:
+        - name: calico-node
+          image: {{.NodeImage}}
+          env:
+            - name: DATASTORE_TYPE
+              value: "kubernetes"
+            - name: WAIT_FOR_DATASTORE
+              value: "true"
+            - name: NODENAME
+              valueFrom:
+                fieldRef:
+                  fieldPath: spec.nodeName
+            - name: CALICO_NETWORKING_BACKEND
+              value: "bird"
+            - name: CLUSTER_TYPE
+              value: "k8s,bgp"
+            - name: IP
+              value: "autodetect"
+            - name: CALICO_IPV4POOL_CIDR
+              value: "{{.Config.PodSubnet}}"
+            - name: CALICO_IPV4POOL_IPIP
+              value: "{{.Config.IPIPMode}}"
+            - name: FELIX_IPINIPMTU
+              valueFrom:
+                configMapKeyRef:
+                  name: calico-config
+                  key: veth_mtu
+            - name: CALICO_DISABLE_FILE_LOGGING
+              value: "true"
+            - name: FELIX_DEFAULTENDPOINTTOHOSTACTION
+              value: "ACCEPT"
+            - name: FELIX_IPV6SUPPORT
+              value: "false"
+            - name: FELIX_HEALTHENABLED
+              value: "true"
+          securityContext:
+            privileged: true
+          resources:
+            requests:
+              cpu: 250m
+          livenessProbe:
+            httpGet:
+              path: /liveness
+              port: 9099
+              host: localhost
+            periodSeconds: 10
+            initialDelaySeconds: 10
+            failureThreshold: 6
+          readinessProbe:
+            exec:
+              command: ["/bin/calico-node", "-bird-ready", "-felix-ready"]
+            periodSeconds: 10
+          volumeMounts:
+            - name: lib-modules
+              mountPath: /lib/modules
+              readOnly: true
+            - name: xtables-lock
+              mountPath: /run/xtables.lock
+            - name: var-run-calico
+              mountPath: /var/run/calico
+            - name: var-lib-calico
+              mountPath: /var/lib/calico
+      volumes:
+        - name: lib-modules
+          hostPath:
+            path: /lib/modules
+        - name: xtables-lock
+          hostPath:
+            path: /run/xtables.lock
+            type: FileOrCreate
+        - name: var-run-calico
+          hostPath:
+            path: /var/run/calico
+        - name: var-lib-calico
+          hostPath:
+            path: /var/lib/calico
+        - name: cni-bin-dir
+          hostPath:
+            path: /opt/cni/bin
+        - name: cni-net-dir
+          hostPath:
+            path: /etc/cni/net.d
+`))
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package calico
+
+import (
+	"testing"
+
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+)
+
+func TestCalico(t *testing.T) { check.TestingT(t) }
+
+type CalicoSuite struct{}
+
+var _ = check.Suite(&CalicoSuite{})
+
+func (s *CalicoSuite) TestRendersResources(c *check.C) {
+	objects, err := Resources(Config{
+		PodSubnet: "10.244.0.0/16",
+		CalicoNetworking: schema.CalicoNetworking{
+			IPIPMode: schema.CalicoIPIPCrossSubnet,
+		},
+	})
+	c.Assert(err, check.IsNil)
+
+	var crds int
+	var daemonSet *appsv1.DaemonSet
+	var configMap *corev1.ConfigMap
+	for _, object := range objects {
+		switch object := object.(type) {
+		case *apiextensionsv1beta1.CustomResourceDefinition:
+			crds++
+		case *appsv1.DaemonSet:
+			daemonSet = object
+		case *corev1.ConfigMap:
+			configMap = object
+		}
+	}
+	c.Assert(crds, check.Equals, len(customResources))
+	c.Assert(configMap, check.NotNil)
+	c.Assert(configMap.Data["veth_mtu"], check.Equals, "1440")
+	c.Assert(daemonSet, check.NotNil)
+
+	spec := daemonSet.Spec.Template.Spec
+	c.Assert(spec.InitContainers, check.HasLen, 1)
+	c.Assert(spec.InitContainers[0].Image, check.Equals, "leader.telekube.local:5000/calico/cni:v3.8.4")
+	c.Assert(spec.Containers, check.HasLen, 1)
+	c.Assert(spec.Containers[0].Image, check.Equals, "leader.telekube.local:5000/calico/node:v3.8.4")
+	env := make(map[string]string)
+	for _, variable := range spec.Containers[0].Env {
+		env[variable.Name] = variable.Value
+	}
+	c.Assert(env["CALICO_IPV4POOL_CIDR"], check.Equals, "10.244.0.0/16")
+	c.Assert(env["CALICO_IPV4POOL_IPIP"], check.Equals, schema.CalicoIPIPCrossSubnet)
+}
+
+func (s *CalicoSuite) TestRequiresPodSubnet(c *check.C) {
+	_, err := Resources(Config{})
+	c.Assert(trace.IsBadParameter(err), check.Equals, true)
+}
@@ -144,8 +144,18 @@ func (_ *Server) Validate(ctx context.Context, req *pb.ValidateRequest) (resp *p
 		StorageDriver: req.Docker.StorageDriver,
 	}
 	if req.FullRequirements {
-		failedProbes, err = checks.ValidateManifest(manifest, *profile, dockerConfig, stateDir)
-		failedProbes = append(failedProbes, checks.RunBasicChecks(ctx, req.Options)...)
+		var results []checks.CheckResult
+		results, err = checks.RunChecks(ctx, checks.CheckerConfig{
+			Manifest: manifest,
+			Profile:  *profile,
+			Docker:   dockerConfig,
+			StateDir: stateDir,
+			Options:  req.Options,
+		})
+		if warnings := checks.WarningProbes(results); len(warnings) != 0 {
+			log.Warnf("Preflight check warnings:\n%v", checks.FormatFailedChecks(warnings))
+		}
+		failedProbes = checks.FailedProbes(results)
 	} else {
 		failedProbes, err = validateManifest(*profile, manifest, stateDir)
 		failedProbes = append(failedProbes, runLocalChecks(ctx)...)
@@ -118,6 +118,7 @@ func requirementsFromManifest(manifest schema.Manifest) (map[string]checks.Requi
 		req := checks.Requirements{
 			CPU:     &manifest.NodeProfiles[i].Requirements.CPU,
 			RAM:     &manifest.NodeProfiles[i].Requirements.RAM,
+			GPU:     &manifest.NodeProfiles[i].Requirements.GPU,
 			OS:      profile.Requirements.OS,
 			Volumes: profile.Requirements.Volumes,
 			Network: checks.Network{
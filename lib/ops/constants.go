@@ -89,6 +89,10 @@ const (
 	SiteStateUpdatingEnviron = "updating_cluster_environ"
 	// SiteStateUpdatingConfig is the state of the cluster when it's updating configuration
 	SiteStateUpdatingConfig = "updating_cluster_config"
+	// SiteStateRotatingCertificates is the state of the cluster when it's rotating certificates
+	SiteStateRotatingCertificates = "rotating_certificates"
+	// SiteStatePatching is the state of the cluster when it's applying a configuration patch
+	SiteStatePatching = "patching"
 	// SiteStateDegraded means that the application installed on a deployed site is failing its health check
 	SiteStateDegraded = "degraded"
 	// SiteStateOffline means that OpsCenter cannot connect to remote site
@@ -136,6 +140,27 @@ const (
 	OperationUpdateConfig           = "operation_update_config"
 	OperationUpdateConfigInProgress = "update_config_in_progress"
 
+	// certificate rotation operation
+	OperationRotateCertificates           = "operation_rotate_certs"
+	OperationRotateCertificatesInProgress = "rotate_certs_in_progress"
+
+	// configuration patch operation
+	OperationPatch           = "operation_patch"
+	OperationPatchInProgress = "patch_in_progress"
+
+	// CertAuthorityStageInitial is the certificate authority stage with a single
+	// root certificate that is both trusted and used for signing
+	CertAuthorityStageInitial = "initial"
+	// CertAuthorityStageTrust is the certificate authority stage where a new
+	// root certificate is trusted alongside the active one
+	CertAuthorityStageTrust = "trust"
+	// CertAuthorityStageSwitch is the certificate authority stage where the new
+	// root certificate is used for signing while the previous one is still trusted
+	CertAuthorityStageSwitch = "switch"
+	// CertAuthorityStageFinalize is the certificate authority stage where
+	// the previous root certificate is no longer trusted
+	CertAuthorityStageFinalize = "finalize"
+
 	// common operation states
 	OperationStateCompleted = "completed"
 	OperationStateFailed    = "failed"
@@ -207,6 +232,14 @@ const (
 )
 
 var (
+	// CertAuthorityStages lists the certificate authority rotation stages in order
+	CertAuthorityStages = []string{
+		CertAuthorityStageInitial,
+		CertAuthorityStageTrust,
+		CertAuthorityStageSwitch,
+		CertAuthorityStageFinalize,
+	}
+
 	// AllRetentions is a list of names of all retention policies
 	AllRetentions = []string{RetentionDefault, RetentionMedium, RetentionLong}
 
@@ -228,6 +261,8 @@ var (
 		OperationGarbageCollect:       SiteStateGarbageCollecting,
 		OperationUpdateRuntimeEnviron: SiteStateUpdatingEnviron,
 		OperationUpdateConfig:         SiteStateUpdatingConfig,
+		OperationRotateCertificates:   SiteStateRotatingCertificates,
+		OperationPatch:                SiteStatePatching,
 	}
 
 	// OperationSucceededToClusterState defines states the cluster transitions
@@ -241,6 +276,8 @@ var (
 		OperationGarbageCollect:       SiteStateActive,
 		OperationUpdateRuntimeEnviron: SiteStateActive,
 		OperationUpdateConfig:         SiteStateActive,
+		OperationRotateCertificates:   SiteStateActive,
+		OperationPatch:                SiteStateActive,
 	}
 
 	// OperationFailedToClusterState defines states the cluster transitions
@@ -256,5 +293,7 @@ var (
 		OperationGarbageCollect:       SiteStateActive,
 		OperationUpdateRuntimeEnviron: SiteStateUpdatingEnviron,
 		OperationUpdateConfig:         SiteStateUpdatingConfig,
+		OperationRotateCertificates:   SiteStateRotatingCertificates,
+		OperationPatch:                SiteStatePatching,
 	}
 )
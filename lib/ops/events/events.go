@@ -79,8 +79,26 @@ const (
 	// InviteCreated fires when a new user invitation is generated.
 	InviteCreated = "invite.created"
 
+	// JoinTokenCreated fires when a new scoped join token is generated.
+	JoinTokenCreated = "join_token.created"
+	// JoinTokenRevoked fires when a scoped join token is revoked manually
+	// or after it has been used up.
+	JoinTokenRevoked = "join_token.revoked"
+
 	// ClusterDegraded fires when cluster health check fails.
 	ClusterDegraded = "cluster.degraded"
 	// ClusterActivated fires when cluster becomes healthy again.
 	ClusterActivated = "cluster.activated"
+
+	// LicenseUpdated fires when the cluster license is replaced.
+	LicenseUpdated = "license.updated"
+
+	// RemediationSucceeded fires when the cluster health controller
+	// has executed an automated remediation.
+	RemediationSucceeded = "remediation.succeeded"
+	// RemediationFailed fires when an automated remediation has failed.
+	RemediationFailed = "remediation.failed"
+	// RemediationSkipped fires when the cluster health controller has
+	// detected a problem but the healing policy did not permit the remediation.
+	RemediationSkipped = "remediation.skipped"
 )
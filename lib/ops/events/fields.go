@@ -125,6 +125,18 @@ const (
 	FieldReason = "reason"
 	// FieldTime contains event time.
 	FieldTime = "time"
-	// FieldRoles contains roles of a new user.
+	// FieldRoles contains roles of a new user or the node roles a join token is restricted to.
 	FieldRoles = "roles"
+	// FieldExpires contains expiration time of a token.
+	FieldExpires = "expires"
+	// FieldMaxUses contains the maximum number of uses of a join token.
+	FieldMaxUses = "maxUses"
+	// FieldComponent contains name of the cluster component a remediation was executed for.
+	FieldComponent = "component"
+	// FieldRemediation contains name of the executed remediation.
+	FieldRemediation = "remediation"
+	// FieldDetail contains problem details.
+	FieldDetail = "detail"
+	// FieldError contains error message.
+	FieldError = "error"
 )
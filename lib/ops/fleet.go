@@ -0,0 +1,99 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// FleetUpgrades upgrades multiple remote clusters connected
+// to an Ops Center to a new application version
+type FleetUpgrades interface {
+	// CreateFleetUpgrade starts upgrading the remote clusters matching
+	// the request selector to the requested application version
+	CreateFleetUpgrade(context.Context, CreateFleetUpgradeRequest) (*storage.FleetUpgrade, error)
+	// GetFleetUpgrade returns the fleet upgrade specified with the key
+	GetFleetUpgrade(context.Context, FleetUpgradeKey) (*storage.FleetUpgrade, error)
+	// GetFleetUpgrades returns all fleet upgrades of the specified account
+	GetFleetUpgrades(ctx context.Context, accountID string) ([]storage.FleetUpgrade, error)
+	// CancelFleetUpgrade stops the fleet upgrade specified with the key.
+	// The update operations already started in the clusters are not interrupted
+	CancelFleetUpgrade(context.Context, FleetUpgradeKey) error
+}
+
+// CreateFleetUpgradeRequest is a request to upgrade multiple remote clusters
+// to a new application version
+type CreateFleetUpgradeRequest struct {
+	// AccountID is the ID of the account the upgraded clusters belong to
+	AccountID string `json:"account_id"`
+	// App is the application package to upgrade the clusters to
+	App string `json:"app"`
+	// Selector selects the upgraded clusters by their labels.
+	// All clusters running the application are upgraded if it is empty
+	Selector map[string]string `json:"selector,omitempty"`
+	// Canaries is the number of clusters upgraded one at a time
+	// before upgrading the remaining clusters
+	Canaries int `json:"canaries"`
+	// Concurrency is the maximum number of clusters upgraded at the same time
+	Concurrency int `json:"concurrency"`
+	// MaxFailures is the number of failed cluster upgrades that halts the upgrade
+	MaxFailures int `json:"max_failures"`
+}
+
+// CheckAndSetDefaults validates the request and sets defaults
+func (r *CreateFleetUpgradeRequest) CheckAndSetDefaults() error {
+	if r.AccountID == "" {
+		return trace.BadParameter("missing AccountID")
+	}
+	if _, err := loc.ParseLocator(r.App); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Canaries < 0 || r.Concurrency < 0 || r.MaxFailures < 0 {
+		return trace.BadParameter("canaries, concurrency and max failures cannot be negative")
+	}
+	if r.Concurrency == 0 {
+		r.Concurrency = 1
+	}
+	if r.MaxFailures == 0 {
+		r.MaxFailures = 1
+	}
+	return nil
+}
+
+// FleetUpgradeKey identifies a fleet upgrade
+type FleetUpgradeKey struct {
+	// AccountID is the ID of the account the upgraded clusters belong to
+	AccountID string `json:"account_id"`
+	// ID is the fleet upgrade ID
+	ID string `json:"id"`
+}
+
+// Check validates the key
+func (k FleetUpgradeKey) Check() error {
+	if k.AccountID == "" {
+		return trace.BadParameter("missing AccountID")
+	}
+	if k.ID == "" {
+		return trace.BadParameter("missing ID")
+	}
+	return nil
+}
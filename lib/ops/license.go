@@ -0,0 +1,127 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	licenseapi "github.com/gravitational/license"
+	"github.com/gravitational/trace"
+)
+
+// UpdateLicenseRequest is a request to replace the license installed on a cluster
+type UpdateLicenseRequest struct {
+	// AccountID is the ID of the account the cluster belongs to
+	AccountID string `json:"account_id"`
+	// SiteDomain is the name of the cluster to update the license for
+	SiteDomain string `json:"site_domain"`
+	// License is the new license
+	License string `json:"license"`
+}
+
+// Check validates the request
+func (r UpdateLicenseRequest) Check() error {
+	if r.SiteDomain == "" {
+		return trace.BadParameter("missing SiteDomain")
+	}
+	if r.License == "" {
+		return trace.BadParameter("missing License")
+	}
+	return nil
+}
+
+// SiteKey returns the key of the cluster the license is updated for
+func (r UpdateLicenseRequest) SiteKey() SiteKey {
+	return SiteKey{AccountID: r.AccountID, SiteDomain: r.SiteDomain}
+}
+
+// LicenseStatus describes the state of the license installed on a cluster
+type LicenseStatus struct {
+	// State is the license state, one of valid, grace_period or invalid
+	State string `json:"state"`
+	// Nodes is the number of cluster nodes counted against the license
+	Nodes int `json:"nodes"`
+	// MaxNodes is the maximum number of nodes the license allows, 0 if unlimited
+	MaxNodes int `json:"max_nodes,omitempty"`
+	// Expiration is the license expiration time, zero if the license does not expire
+	Expiration time.Time `json:"expiration,omitempty"`
+	// GracePeriodEnd is the time the cluster can keep operating after
+	// the license has expired
+	GracePeriodEnd time.Time `json:"grace_period_end,omitempty"`
+	// Reason explains why the license is not valid
+	Reason string `json:"reason,omitempty"`
+}
+
+// NewLicenseStatus returns the status of the license with the specified payload
+// for a cluster with the given number of nodes at the time now.
+//
+// An expired license remains usable during the grace period so the cluster
+// is only degraded once the grace period is over
+func NewLicenseStatus(payload licenseapi.Payload, nodes int, now time.Time) LicenseStatus {
+	status := LicenseStatus{
+		State:      LicenseStateValid,
+		Nodes:      nodes,
+		MaxNodes:   payload.MaxNodes,
+		Expiration: payload.Expiration,
+	}
+	if err := payload.CheckCount(nodes); err != nil {
+		status.State = LicenseStateInvalid
+		status.Reason = trace.UserMessage(err)
+		return status
+	}
+	if payload.Expiration.IsZero() || now.Before(payload.Expiration) {
+		return status
+	}
+	status.GracePeriodEnd = payload.Expiration.Add(defaults.LicenseGracePeriod)
+	if now.Before(status.GracePeriodEnd) {
+		status.State = LicenseStateGracePeriod
+		return status
+	}
+	status.State = LicenseStateInvalid
+	status.Reason = fmt.Sprintf("the license expired on %v",
+		payload.Expiration.Format(constants.ShortDateFormat))
+	return status
+}
+
+// CheckLicenseExpiration returns an error if the license with the specified
+// payload has expired and its grace period is over at the time now
+func CheckLicenseExpiration(payload licenseapi.Payload, now time.Time) error {
+	return NewLicenseStatus(payload, 0, now).Check()
+}
+
+// Check returns an error if the license is not valid
+func (s LicenseStatus) Check() error {
+	if s.State == LicenseStateInvalid {
+		return trace.AccessDenied("%v", s.Reason)
+	}
+	return nil
+}
+
+const (
+	// LicenseStateValid means that the license is valid
+	LicenseStateValid = "valid"
+	// LicenseStateGracePeriod means that the license has expired
+	// but the grace period is not over yet
+	LicenseStateGracePeriod = "grace_period"
+	// LicenseStateInvalid means that the license has expired past the grace
+	// period or does not permit the number of nodes in the cluster
+	LicenseStateInvalid = "invalid"
+)
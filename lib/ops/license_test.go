@@ -0,0 +1,101 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	licenseapi "github.com/gravitational/license"
+	"github.com/gravitational/trace"
+	check "gopkg.in/check.v1"
+)
+
+type LicenseSuite struct{}
+
+var _ = check.Suite(&LicenseSuite{})
+
+func (s *LicenseSuite) TestLicenseStatus(c *check.C) {
+	now := time.Date(2019, time.March, 1, 0, 0, 0, 0, time.UTC)
+	expiration := now.Add(-time.Hour)
+	graceEnd := expiration.Add(defaults.LicenseGracePeriod)
+
+	var testCases = []struct {
+		comment string
+		payload licenseapi.Payload
+		nodes   int
+		now     time.Time
+		state   string
+	}{
+		{
+			comment: "license without constraints",
+			nodes:   10,
+			now:     now,
+			state:   LicenseStateValid,
+		},
+		{
+			comment: "license not yet expired",
+			payload: licenseapi.Payload{Expiration: now.Add(time.Hour), MaxNodes: 3},
+			nodes:   3,
+			now:     now,
+			state:   LicenseStateValid,
+		},
+		{
+			comment: "expired license within grace period",
+			payload: licenseapi.Payload{Expiration: expiration},
+			nodes:   1,
+			now:     now,
+			state:   LicenseStateGracePeriod,
+		},
+		{
+			comment: "expired license past grace period",
+			payload: licenseapi.Payload{Expiration: expiration},
+			nodes:   1,
+			now:     graceEnd,
+			state:   LicenseStateInvalid,
+		},
+		{
+			comment: "too many nodes",
+			payload: licenseapi.Payload{MaxNodes: 3},
+			nodes:   4,
+			now:     now,
+			state:   LicenseStateInvalid,
+		},
+	}
+	for _, tc := range testCases {
+		comment := check.Commentf(tc.comment)
+		status := NewLicenseStatus(tc.payload, tc.nodes, tc.now)
+		c.Assert(status.State, check.Equals, tc.state, comment)
+		c.Assert(status.Nodes, check.Equals, tc.nodes, comment)
+		if tc.state == LicenseStateInvalid {
+			c.Assert(trace.IsAccessDenied(status.Check()), check.Equals, true, comment)
+			c.Assert(status.Reason, check.Not(check.Equals), "", comment)
+		} else {
+			c.Assert(status.Check(), check.IsNil, comment)
+		}
+	}
+}
+
+func (s *LicenseSuite) TestGracePeriodEnd(c *check.C) {
+	expiration := time.Date(2019, time.March, 1, 0, 0, 0, 0, time.UTC)
+	status := NewLicenseStatus(licenseapi.Payload{Expiration: expiration}, 1, expiration)
+	c.Assert(status.State, check.Equals, LicenseStateGracePeriod)
+	c.Assert(status.GracePeriodEnd, check.Equals, expiration.Add(defaults.LicenseGracePeriod))
+	c.Assert(CheckLicenseExpiration(licenseapi.Payload{Expiration: expiration},
+		status.GracePeriodEnd), check.NotNil)
+}
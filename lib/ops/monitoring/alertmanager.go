@@ -0,0 +1,209 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AlertmanagerConfig returns the Alertmanager configuration that routes
+// all alerts to the specified alert targets, sending emails via the
+// provided SMTP server. SMTP configuration is optional: email targets
+// are not notified until it is set
+func AlertmanagerConfig(smtp storage.SMTPConfig, targets []storage.AlertTarget) ([]byte, error) {
+	receiver := alertmanagerReceiver{Name: defaultReceiver}
+	for _, target := range targets {
+		if email := target.GetEmail(); email != "" && smtp != nil {
+			receiver.EmailConfigs = append(receiver.EmailConfigs,
+				alertmanagerEmailConfig{To: email, SendResolved: true})
+		}
+		if url := target.GetWebhookURL(); url != "" {
+			receiver.WebhookConfigs = append(receiver.WebhookConfigs,
+				alertmanagerWebhookConfig{URL: url, SendResolved: true})
+		}
+	}
+	config := alertmanagerConfig{
+		Route: alertmanagerRoute{
+			Receiver:       defaultReceiver,
+			GroupBy:        []string{"alertname"},
+			GroupWait:      "30s",
+			GroupInterval:  "5m",
+			RepeatInterval: "3h",
+		},
+		Receivers: []alertmanagerReceiver{receiver},
+	}
+	if smtp != nil {
+		config.Global = &alertmanagerGlobal{
+			SMTPSmarthost:    fmt.Sprintf("%v:%v", smtp.GetHost(), smtp.GetPort()),
+			SMTPFrom:         smtp.GetFrom(),
+			SMTPAuthUsername: smtp.GetUsername(),
+			SMTPAuthPassword: smtp.GetPassword(),
+		}
+	}
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return data, nil
+}
+
+// NewPrometheusRule returns the Prometheus Operator rule resource
+// for the alert with a Prometheus alerting rule
+func NewPrometheusRule(alert storage.Alert) (*PrometheusRule, error) {
+	rule := alert.GetRule()
+	if rule == nil {
+		return nil, trace.BadParameter("alert %q does not define a Prometheus rule",
+			alert.GetName())
+	}
+	labels := map[string]string{"severity": rule.Severity}
+	for name, value := range rule.Labels {
+		labels[name] = value
+	}
+	return &PrometheusRule{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       PrometheusRuleKind,
+			APIVersion: PrometheusRuleAPIVersion,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      PrometheusRuleName(alert.GetName()),
+			Namespace: defaults.MonitoringNamespace,
+			Labels: map[string]string{
+				"prometheus": "k8s",
+				"role":       "alert-rules",
+			},
+		},
+		Spec: PrometheusRuleSpec{
+			Groups: []PrometheusRuleGroup{{
+				Name: alert.GetName(),
+				Rules: []PrometheusAlertingRule{{
+					Alert:       alert.GetName(),
+					Expr:        rule.Expr,
+					For:         rule.For,
+					Labels:      labels,
+					Annotations: rule.Annotations,
+				}},
+			}},
+		},
+	}, nil
+}
+
+// PrometheusRuleName returns the name of the Prometheus Operator rule
+// resource for the alert with the specified name
+func PrometheusRuleName(alertName string) string {
+	return fmt.Sprintf("gravity-alert-%v", alertName)
+}
+
+// PrometheusRulesPath returns the API path of the Prometheus Operator rule
+// resources, or of the rule with the specified name
+func PrometheusRulesPath(name string) string {
+	path := fmt.Sprintf("/apis/%v/namespaces/%v/prometheusrules",
+		PrometheusRuleAPIVersion, defaults.MonitoringNamespace)
+	if name != "" {
+		path = fmt.Sprintf("%v/%v", path, name)
+	}
+	return path
+}
+
+// PrometheusRule is the Prometheus Operator resource with alerting rules
+type PrometheusRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// Spec contains the rule groups
+	Spec PrometheusRuleSpec `json:"spec"`
+}
+
+// PrometheusRuleSpec is the Prometheus Operator rule resource spec
+type PrometheusRuleSpec struct {
+	// Groups is a list of rule groups
+	Groups []PrometheusRuleGroup `json:"groups"`
+}
+
+// PrometheusRuleGroup is a group of Prometheus rules evaluated together
+type PrometheusRuleGroup struct {
+	// Name is the group name
+	Name string `json:"name"`
+	// Rules is a list of alerting rules
+	Rules []PrometheusAlertingRule `json:"rules"`
+}
+
+// PrometheusAlertingRule is a Prometheus alerting rule
+type PrometheusAlertingRule struct {
+	// Alert is the alert name
+	Alert string `json:"alert"`
+	// Expr is the PromQL expression
+	Expr string `json:"expr"`
+	// For is how long the expression should hold before the alert fires
+	For string `json:"for,omitempty"`
+	// Labels are the labels attached to the alert
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are the informational labels attached to the alert
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+const (
+	// PrometheusRuleKind is the kind of Prometheus Operator rule resources
+	PrometheusRuleKind = "PrometheusRule"
+	// PrometheusRuleAPIVersion is the API version of Prometheus Operator rule resources
+	PrometheusRuleAPIVersion = "monitoring.coreos.com/v1"
+)
+
+type alertmanagerConfig struct {
+	Global    *alertmanagerGlobal    `json:"global,omitempty"`
+	Route     alertmanagerRoute      `json:"route"`
+	Receivers []alertmanagerReceiver `json:"receivers"`
+}
+
+type alertmanagerGlobal struct {
+	SMTPSmarthost    string `json:"smtp_smarthost"`
+	SMTPFrom         string `json:"smtp_from"`
+	SMTPAuthUsername string `json:"smtp_auth_username"`
+	SMTPAuthPassword string `json:"smtp_auth_password"`
+}
+
+type alertmanagerRoute struct {
+	Receiver       string   `json:"receiver"`
+	GroupBy        []string `json:"group_by"`
+	GroupWait      string   `json:"group_wait"`
+	GroupInterval  string   `json:"group_interval"`
+	RepeatInterval string   `json:"repeat_interval"`
+}
+
+type alertmanagerReceiver struct {
+	Name           string                      `json:"name"`
+	EmailConfigs   []alertmanagerEmailConfig   `json:"email_configs,omitempty"`
+	WebhookConfigs []alertmanagerWebhookConfig `json:"webhook_configs,omitempty"`
+}
+
+type alertmanagerEmailConfig struct {
+	To           string `json:"to"`
+	SendResolved bool   `json:"send_resolved"`
+}
+
+type alertmanagerWebhookConfig struct {
+	URL          string `json:"url"`
+	SendResolved bool   `json:"send_resolved"`
+}
+
+// defaultReceiver is the name of the Alertmanager receiver all alerts are routed to
+const defaultReceiver = "default"
@@ -100,6 +100,9 @@ func (o *OperatorACL) clusterContext(clusterName string) (*users.Context, storag
 			return nil, nil, trace.Wrap(err)
 		}
 	}
+	if err := users.CheckAccountAccess(o.user, site.AccountID); err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
 	cluster := NewClusterFromSite(*site)
 	return o.resourceContext(cluster), cluster, nil
 }
@@ -110,6 +113,24 @@ func (o *OperatorACL) Action(resourceKind, action string) error {
 		resourceKind, action, false)
 }
 
+// accountAction checks access to the specified action on the specified
+// resource kind within the specified account
+func (o *OperatorACL) accountAction(accountID, resourceKind, action string) error {
+	if err := users.CheckAccountAccess(o.user, accountID); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.Action(resourceKind, action)
+}
+
+// systemAccountAction checks access to the specified action on the specified
+// resource kind that is only available to the users of the system account
+func (o *OperatorACL) systemAccountAction(resourceKind, action string) error {
+	if !users.IsSystemAccountUser(o.user) {
+		return trace.AccessDenied("user %v does not belong to the system account", o.username)
+	}
+	return o.Action(resourceKind, action)
+}
+
 func (o *OperatorACL) ClusterAction(clusterName, resourceKind, action string) error {
 	ctx, cluster, err := o.clusterContext(clusterName)
 	if err != nil {
@@ -132,6 +153,20 @@ func (o *OperatorACL) currentUserActions(username string, actions ...string) err
 	return o.userActions(actions...)
 }
 
+// userAccountAccess returns an error if the current user cannot access
+// the resources of the account the user with the specified name belongs to.
+// A user that does not exist yet is not checked
+func (o *OperatorACL) userAccountAccess(name string) error {
+	user, err := o.users.GetTelekubeUser(name)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(users.CheckAccountAccess(o.user, user.GetAccountID()))
+}
+
 // userActions checks access to the specified actions on the "user" resource
 func (o *OperatorACL) userActions(actions ...string) error {
 	for _, action := range actions {
@@ -175,28 +210,51 @@ func (o *OperatorACL) AuthConnectorActions(connectorKind string, actions ...stri
 }
 
 func (o *OperatorACL) GetAccount(accountID string) (*Account, error) {
-	if err := o.Action(storage.KindCluster, teleservices.VerbRead); err != nil {
+	if err := o.accountAction(accountID, storage.KindCluster, teleservices.VerbRead); err != nil {
 		return nil, trace.Wrap(err)
 	}
 	return o.operator.GetAccount(accountID)
 }
 
 func (o *OperatorACL) CreateAccount(req NewAccountRequest) (*Account, error) {
-	if err := o.Action(storage.KindCluster, teleservices.VerbCreate); err != nil {
+	if err := o.systemAccountAction(storage.KindCluster, teleservices.VerbCreate); err != nil {
 		return nil, trace.Wrap(err)
 	}
 	return o.operator.CreateAccount(req)
 }
 
+// DeleteAccount deletes the account with the specified ID
+func (o *OperatorACL) DeleteAccount(accountID string) error {
+	if err := o.systemAccountAction(storage.KindCluster, teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteAccount(accountID)
+}
+
 func (o *OperatorACL) GetAccounts() ([]Account, error) {
 	if err := o.Action(storage.KindCluster, teleservices.VerbList); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return o.operator.GetAccounts()
+	allAccounts, err := o.operator.GetAccounts()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// return only the accounts the user belongs to
+	var accounts []Account
+	for _, account := range allAccounts {
+		if users.CheckAccountAccess(o.user, account.ID) == nil {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
 }
 
 func (o *OperatorACL) CreateUser(req NewUserRequest) error {
-	if err := o.Action(teleservices.KindUser, teleservices.VerbCreate); err != nil {
+	// users are created in the system account
+	if err := o.systemAccountAction(teleservices.KindUser, teleservices.VerbCreate); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := o.userAccountAccess(req.Name); err != nil {
 		return trace.Wrap(err)
 	}
 	return o.operator.CreateUser(req)
@@ -204,7 +262,10 @@ func (o *OperatorACL) CreateUser(req NewUserRequest) error {
 
 // UpdateUser updates the specified user information.
 func (o *OperatorACL) UpdateUser(ctx context.Context, req UpdateUserRequest) error {
-	if err := o.Action(teleservices.KindUser, teleservices.VerbUpdate); err != nil {
+	if err := o.accountAction(req.AccountID, teleservices.KindUser, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := o.userAccountAccess(req.Name); err != nil {
 		return trace.Wrap(err)
 	}
 	return o.operator.UpdateUser(ctx, req)
@@ -214,6 +275,9 @@ func (o *OperatorACL) DeleteLocalUser(name string) error {
 	if err := o.Action(teleservices.KindUser, teleservices.VerbDelete); err != nil {
 		return trace.Wrap(err)
 	}
+	if err := o.userAccountAccess(name); err != nil {
+		return trace.Wrap(err)
+	}
 	return o.operator.DeleteLocalUser(name)
 }
 
@@ -239,14 +303,56 @@ func (o *OperatorACL) GetClusterNodes(key SiteKey) ([]Node, error) {
 	return o.operator.GetClusterNodes(key)
 }
 
+// GetClusterStatusHistory returns the recorded status snapshots of the cluster
+func (o *OperatorACL) GetClusterStatusHistory(ctx context.Context, req GetClusterStatusHistoryRequest) ([]storage.StatusSnapshot, error) {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetClusterStatusHistory(ctx, req)
+}
+
+// GetRegistryStatus returns the status of the cluster registry
+func (o *OperatorACL) GetRegistryStatus(ctx context.Context, key SiteKey) (*RegistryStatus, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetRegistryStatus(ctx, key)
+}
+
+// GetPackageVerificationStatus returns the results of the last cluster package verification
+func (o *OperatorACL) GetPackageVerificationStatus(ctx context.Context, key SiteKey) (*PackageVerificationStatus, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetPackageVerificationStatus(ctx, key)
+}
+
+// GetClusterHealth returns the health of cluster nodes
+func (o *OperatorACL) GetClusterHealth(ctx context.Context, key SiteKey) (*ClusterHealth, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetClusterHealth(ctx, key)
+}
+
 func (o *OperatorACL) ResetUserPassword(req ResetUserPasswordRequest) (string, error) {
-	if err := o.Action(teleservices.KindUser, teleservices.VerbUpdate); err != nil {
+	if err := o.accountAction(req.AccountID, teleservices.KindUser, teleservices.VerbUpdate); err != nil {
+		return "", trace.Wrap(err)
+	}
+	if err := o.userAccountAccess(req.Email); err != nil {
 		return "", trace.Wrap(err)
 	}
 	return o.operator.ResetUserPassword(req)
 }
 
 func (o *OperatorACL) CreateAPIKey(req NewAPIKeyRequest) (*storage.APIKey, error) {
+	// a restricted API key must not be able to create keys with broader
+	// permissions for its own user so creating keys has to be within its permissions
+	if users.IsRestrictedChecker(o.checker) {
+		if err := o.userActions(teleservices.VerbCreate); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
 	if err := o.currentUserActions(req.UserEmail, teleservices.VerbCreate); err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -294,6 +400,31 @@ func (o *OperatorACL) GetExpandToken(key SiteKey) (*storage.ProvisioningToken, e
 	return o.operator.GetExpandToken(key)
 }
 
+// CreateJoinToken creates a new join token limited in time, number of uses
+// or roles of the joining nodes
+func (o *OperatorACL) CreateJoinToken(ctx context.Context, req CreateJoinTokenRequest) (*storage.ProvisioningToken, error) {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.CreateJoinToken(ctx, req)
+}
+
+// GetJoinTokens returns the active scoped join tokens of the cluster
+func (o *OperatorACL) GetJoinTokens(ctx context.Context, key SiteKey) ([]storage.ProvisioningToken, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetJoinTokens(ctx, key)
+}
+
+// DeleteJoinToken revokes the specified scoped join token
+func (o *OperatorACL) DeleteJoinToken(ctx context.Context, req DeleteJoinTokenRequest) error {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteJoinToken(ctx, req)
+}
+
 func (o *OperatorACL) GetTrustedClusterToken(key SiteKey) (storage.Token, error) {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
 		return nil, trace.Wrap(err)
@@ -302,6 +433,9 @@ func (o *OperatorACL) GetTrustedClusterToken(key SiteKey) (storage.Token, error)
 }
 
 func (o *OperatorACL) CreateSite(req NewSiteRequest) (*Site, error) {
+	if err := users.CheckAccountAccess(o.user, req.AccountID); err != nil {
+		return nil, trace.Wrap(err)
+	}
 	err := o.Action(storage.KindCluster, teleservices.VerbCreate)
 	if err == nil {
 		return o.operator.CreateSite(req)
@@ -335,6 +469,9 @@ func (o *OperatorACL) CreateSite(req NewSiteRequest) (*Site, error) {
 }
 
 func (o *OperatorACL) GetSites(accountID string) ([]Site, error) {
+	if err := users.CheckAccountAccess(o.user, accountID); err != nil {
+		return nil, trace.Wrap(err)
+	}
 	allClusters, err := o.operator.GetSites(accountID)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -396,6 +533,13 @@ func (o *OperatorACL) ActivateSite(req ActivateSiteRequest) error {
 	return o.operator.ActivateSite(req)
 }
 
+func (o *OperatorACL) UpdateLicense(ctx context.Context, req UpdateLicenseRequest) error {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindLicense, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpdateLicense(ctx, req)
+}
+
 func (o *OperatorACL) CompleteFinalInstallStep(req CompleteFinalInstallStepRequest) error {
 	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
 		return trace.Wrap(err)
@@ -505,6 +649,14 @@ func (o *OperatorACL) CreateClusterGarbageCollectOperation(ctx context.Context,
 	return o.operator.CreateClusterGarbageCollectOperation(ctx, req)
 }
 
+// CollectPackageGarbage removes unreachable packages from the cluster package service
+func (o *OperatorACL) CollectPackageGarbage(ctx context.Context, req CollectPackageGarbageRequest) (*CollectPackageGarbageResponse, error) {
+	if err := o.ClusterAction(req.ClusterKey.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.CollectPackageGarbage(ctx, req)
+}
+
 // CreateUpdateEnvarsOperation creates a new operation to update cluster environment variables
 func (o *OperatorACL) CreateUpdateEnvarsOperation(ctx context.Context, req CreateUpdateEnvarsOperationRequest) (*SiteOperationKey, error) {
 	if err := o.ClusterAction(req.ClusterKey.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
@@ -521,6 +673,14 @@ func (o *OperatorACL) CreateUpdateConfigOperation(ctx context.Context, req Creat
 	return o.operator.CreateUpdateConfigOperation(ctx, req)
 }
 
+// CreatePatchOperation creates a new operation to apply configuration-only changes to the cluster
+func (o *OperatorACL) CreatePatchOperation(ctx context.Context, req CreatePatchOperationRequest) (*SiteOperationKey, error) {
+	if err := o.ClusterAction(req.ClusterKey.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.CreatePatchOperation(ctx, req)
+}
+
 func (o *OperatorACL) GetSiteOperationLogs(key SiteOperationKey) (io.ReadCloser, error) {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
 		return nil, trace.Wrap(err)
@@ -535,6 +695,15 @@ func (o *OperatorACL) CreateLogEntry(key SiteOperationKey, entry LogEntry) error
 	return o.operator.CreateLogEntry(key, entry)
 }
 
+// GetOperationPhaseLogs returns the log entries recorded while executing
+// the specified phase of the operation and any of its subphases
+func (o *OperatorACL) GetOperationPhaseLogs(key SiteOperationKey, phaseID string) ([]LogEntry, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetOperationPhaseLogs(key, phaseID)
+}
+
 // StreamOperationLogs appends the logs from the provided reader to the
 // specified operation (user-facing) log file
 func (o *OperatorACL) StreamOperationLogs(key SiteOperationKey, reader io.Reader) error {
@@ -565,6 +734,13 @@ func (o *OperatorACL) GetSiteOperationProgress(key SiteOperationKey) (*ProgressE
 	return o.operator.GetSiteOperationProgress(key)
 }
 
+func (o *OperatorACL) WatchSiteOperationProgress(ctx context.Context, key SiteOperationKey, progressC chan<- ProgressEntry) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.WatchSiteOperationProgress(ctx, key, progressC)
+}
+
 func (o *OperatorACL) CreateProgressEntry(key SiteOperationKey, entry ProgressEntry) error {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
 		return trace.Wrap(err)
@@ -579,11 +755,11 @@ func (o *OperatorACL) GetSiteOperationCrashReport(key SiteOperationKey) (io.Read
 	return o.operator.GetSiteOperationCrashReport(key)
 }
 
-func (o *OperatorACL) GetSiteReport(key SiteKey) (io.ReadCloser, error) {
-	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+func (o *OperatorACL) GetSiteReport(req GetClusterReportRequest) (io.ReadCloser, error) {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return o.operator.GetSiteReport(key)
+	return o.operator.GetSiteReport(req)
 }
 
 func (o *OperatorACL) ValidateDomainName(domainName string) error {
@@ -633,6 +809,30 @@ func (o *OperatorACL) SetOperationState(key SiteOperationKey, req SetOperationSt
 	return o.operator.SetOperationState(key, req)
 }
 
+// CancelSiteOperation requests cancellation of the specified operation
+func (o *OperatorACL) CancelSiteOperation(ctx context.Context, key SiteOperationKey) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.CancelSiteOperation(ctx, key)
+}
+
+// PauseSiteOperation requests the specified operation to pause
+func (o *OperatorACL) PauseSiteOperation(ctx context.Context, key SiteOperationKey) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.PauseSiteOperation(ctx, key)
+}
+
+// ResumeSiteOperation clears the pause request of the specified operation
+func (o *OperatorACL) ResumeSiteOperation(ctx context.Context, key SiteOperationKey) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.ResumeSiteOperation(ctx, key)
+}
+
 // CreateOperationPlan saves the provided operation plan
 func (o *OperatorACL) CreateOperationPlan(key SiteOperationKey, plan storage.OperationPlan) error {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
@@ -693,6 +893,13 @@ func (o *OperatorACL) ConfigureNode(req ConfigureNodeRequest) error {
 	return o.operator.ConfigureNode(req)
 }
 
+func (o *OperatorACL) RotateCertAuthority(req RotateCertAuthorityRequest) error {
+	if err := o.ClusterAction(req.Key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.RotateCertAuthority(req)
+}
+
 // GetLogForwarders returns a list of configured log forwarders
 func (o *OperatorACL) GetLogForwarders(key SiteKey) ([]storage.LogForwarder, error) {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindLogForwarder, teleservices.VerbList); err != nil {
@@ -760,6 +967,48 @@ func (o *OperatorACL) DeleteSMTPConfig(key SiteKey) error {
 	return o.operator.DeleteSMTPConfig(key)
 }
 
+func (o *OperatorACL) GetHealingPolicy(key SiteKey) (storage.HealingPolicy, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindHealingPolicy, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetHealingPolicy(key)
+}
+
+func (o *OperatorACL) UpdateHealingPolicy(key SiteKey, policy storage.HealingPolicy) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindHealingPolicy, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpdateHealingPolicy(key, policy)
+}
+
+func (o *OperatorACL) DeleteHealingPolicy(key SiteKey) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindHealingPolicy, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteHealingPolicy(key)
+}
+
+func (o *OperatorACL) GetClusterDNS(key SiteKey) (storage.ClusterDNS, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindClusterDNS, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetClusterDNS(key)
+}
+
+func (o *OperatorACL) UpdateClusterDNS(key SiteKey, dns storage.ClusterDNS) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindClusterDNS, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpdateClusterDNS(key, dns)
+}
+
+func (o *OperatorACL) DeleteClusterDNS(key SiteKey) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindClusterDNS, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteClusterDNS(key)
+}
+
 func (o *OperatorACL) GetAlerts(key SiteKey) ([]storage.Alert, error) {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindAlert, teleservices.VerbList); err != nil {
 		return nil, trace.Wrap(err)
@@ -941,6 +1190,21 @@ func (o *OperatorACL) DeleteClusterCertificate(key SiteKey) error {
 	return o.operator.DeleteClusterCertificate(key)
 }
 
+func (o *OperatorACL) IssueClusterCertificate(key SiteKey) (*ClusterCertificate, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.IssueClusterCertificate(key)
+}
+
+// CreateRotateCertificatesOperation creates a new operation to rotate cluster certificates
+func (o *OperatorACL) CreateRotateCertificatesOperation(ctx context.Context, req CreateRotateCertificatesOperationRequest) (*SiteOperationKey, error) {
+	if err := o.ClusterAction(req.ClusterKey.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.CreateRotateCertificatesOperation(ctx, req)
+}
+
 // StepDown asks the process to pause its leader election heartbeat so it can
 // give up its leadership
 func (o *OperatorACL) StepDown(key SiteKey) error {
@@ -949,6 +1213,17 @@ func (o *OperatorACL) StepDown(key SiteKey) error {
 
 // UpsertUser creates or updates a user
 func (o *OperatorACL) UpsertUser(key SiteKey, user teleservices.User) error {
+	if err := users.CheckAccountAccess(o.user, key.AccountID); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := o.userAccountAccess(user.GetName()); err != nil {
+		return trace.Wrap(err)
+	}
+	if storageUser, ok := user.(storage.User); ok && storageUser.GetAccountID() != "" {
+		if err := users.CheckAccountAccess(o.user, storageUser.GetAccountID()); err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	if err := o.currentUserActions(user.GetName(), teleservices.VerbCreate, teleservices.VerbUpdate); err != nil {
 		return trace.Wrap(err)
 	}
@@ -957,14 +1232,23 @@ func (o *OperatorACL) UpsertUser(key SiteKey, user teleservices.User) error {
 
 // GetUser returns a user by name
 func (o *OperatorACL) GetUser(key SiteKey, name string) (teleservices.User, error) {
+	if err := users.CheckAccountAccess(o.user, key.AccountID); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := o.userAccountAccess(name); err != nil {
+		return nil, trace.Wrap(err)
+	}
 	if err := o.currentUserActions(name, teleservices.VerbList, teleservices.VerbRead); err != nil {
 		return nil, trace.Wrap(err)
 	}
 	return o.operator.GetUser(key, name)
 }
 
-// GetUsers returns all users
+// GetUsers returns all users of the cluster account
 func (o *OperatorACL) GetUsers(key SiteKey) ([]teleservices.User, error) {
+	if err := users.CheckAccountAccess(o.user, key.AccountID); err != nil {
+		return nil, trace.Wrap(err)
+	}
 	if err := o.userActions(teleservices.VerbList, teleservices.VerbRead); err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -973,6 +1257,12 @@ func (o *OperatorACL) GetUsers(key SiteKey) ([]teleservices.User, error) {
 
 // DeleteUser deletes a user by name
 func (o *OperatorACL) DeleteUser(key SiteKey, name string) error {
+	if err := users.CheckAccountAccess(o.user, key.AccountID); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := o.userAccountAccess(name); err != nil {
+		return trace.Wrap(err)
+	}
 	if err := o.userActions(teleservices.VerbDelete); err != nil {
 		return trace.Wrap(err)
 	}
@@ -1031,6 +1321,207 @@ func (o *OperatorACL) DeleteGithubConnector(key SiteKey, name string) error {
 	return o.operator.DeleteGithubConnector(key, name)
 }
 
+// UpsertSAMLConnector creates or updates a SAML connector
+func (o *OperatorACL) UpsertSAMLConnector(key SiteKey, connector teleservices.SAMLConnector) error {
+	if err := o.AuthConnectorActions(teleservices.KindSAMLConnector, teleservices.VerbCreate, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertSAMLConnector(key, connector)
+}
+
+// GetSAMLConnector returns a SAML connector by name
+//
+// Returned connector excludes signing key unless withSecrets is true.
+func (o *OperatorACL) GetSAMLConnector(key SiteKey, name string, withSecrets bool) (teleservices.SAMLConnector, error) {
+	if err := o.AuthConnectorActions(teleservices.KindSAMLConnector, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetSAMLConnector(key, name, withSecrets)
+}
+
+// GetSAMLConnectors returns all SAML connectors
+//
+// Returned connectors exclude signing key unless withSecrets is true.
+func (o *OperatorACL) GetSAMLConnectors(key SiteKey, withSecrets bool) ([]teleservices.SAMLConnector, error) {
+	if err := o.AuthConnectorActions(teleservices.KindSAMLConnector, teleservices.VerbList, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetSAMLConnectors(key, withSecrets)
+}
+
+// DeleteSAMLConnector deletes a SAML connector by name
+func (o *OperatorACL) DeleteSAMLConnector(key SiteKey, name string) error {
+	if err := o.AuthConnectorActions(teleservices.KindSAMLConnector, teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteSAMLConnector(key, name)
+}
+
+// UpsertRoleMapping creates or updates a mapping of OIDC claims to roles
+func (o *OperatorACL) UpsertRoleMapping(key SiteKey, mapping storage.RoleMapping) error {
+	if err := o.Action(teleservices.KindRole, teleservices.VerbCreate); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := o.Action(teleservices.KindRole, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertRoleMapping(key, mapping)
+}
+
+// GetRoleMapping returns a role mapping by name
+func (o *OperatorACL) GetRoleMapping(key SiteKey, name string) (storage.RoleMapping, error) {
+	if err := o.Action(teleservices.KindRole, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetRoleMapping(key, name)
+}
+
+// GetRoleMappings returns all role mappings
+func (o *OperatorACL) GetRoleMappings(key SiteKey) ([]storage.RoleMapping, error) {
+	if err := o.Action(teleservices.KindRole, teleservices.VerbList); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := o.Action(teleservices.KindRole, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetRoleMappings(key)
+}
+
+// DeleteRoleMapping deletes a role mapping by name
+func (o *OperatorACL) DeleteRoleMapping(key SiteKey, name string) error {
+	if err := o.Action(teleservices.KindRole, teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteRoleMapping(key, name)
+}
+
+// UpsertConfigResource creates or updates a configuration resource
+func (o *OperatorACL) UpsertConfigResource(key SiteKey, resource storage.UnknownResource) error {
+	kind := storage.CanonicalKind(resource.Kind)
+	if err := o.ClusterAction(key.SiteDomain, kind, teleservices.VerbCreate); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := o.ClusterAction(key.SiteDomain, kind, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertConfigResource(key, resource)
+}
+
+// GetConfigResource returns a configuration resource by kind and name
+func (o *OperatorACL) GetConfigResource(key SiteKey, kind, name string) (*storage.UnknownResource, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.CanonicalKind(kind), teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetConfigResource(key, kind, name)
+}
+
+// GetConfigResources returns all configuration resources of the specified kind
+func (o *OperatorACL) GetConfigResources(key SiteKey, kind string) ([]storage.UnknownResource, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.CanonicalKind(kind), teleservices.VerbList); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := o.ClusterAction(key.SiteDomain, storage.CanonicalKind(kind), teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetConfigResources(key, kind)
+}
+
+// DeleteConfigResource deletes a configuration resource by kind and name
+func (o *OperatorACL) DeleteConfigResource(key SiteKey, kind, name string) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.CanonicalKind(kind), teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteConfigResource(key, kind, name)
+}
+
+// UpsertWebhook creates or updates a webhook
+func (o *OperatorACL) UpsertWebhook(key SiteKey, webhook storage.Webhook) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindWebhook, teleservices.VerbCreate); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := o.ClusterAction(key.SiteDomain, storage.KindWebhook, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertWebhook(key, webhook)
+}
+
+// GetWebhook returns a webhook by name
+func (o *OperatorACL) GetWebhook(key SiteKey, name string) (storage.Webhook, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindWebhook, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetWebhook(key, name)
+}
+
+// GetWebhooks returns all webhooks
+func (o *OperatorACL) GetWebhooks(key SiteKey) ([]storage.Webhook, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindWebhook, teleservices.VerbList); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := o.ClusterAction(key.SiteDomain, storage.KindWebhook, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetWebhooks(key)
+}
+
+// DeleteWebhook deletes a webhook by name
+func (o *OperatorACL) DeleteWebhook(key SiteKey, name string) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindWebhook, teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteWebhook(key, name)
+}
+
+// UpsertProvisioner creates or updates a provisioner
+func (o *OperatorACL) UpsertProvisioner(key SiteKey, provisioner storage.Provisioner) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindProvisioner, teleservices.VerbCreate); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := o.ClusterAction(key.SiteDomain, storage.KindProvisioner, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertProvisioner(key, provisioner)
+}
+
+// GetProvisioner returns a provisioner by name
+func (o *OperatorACL) GetProvisioner(key SiteKey, name string) (storage.Provisioner, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindProvisioner, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetProvisioner(key, name)
+}
+
+// GetProvisioners returns all provisioners
+func (o *OperatorACL) GetProvisioners(key SiteKey) ([]storage.Provisioner, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindProvisioner, teleservices.VerbList); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := o.ClusterAction(key.SiteDomain, storage.KindProvisioner, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetProvisioners(key)
+}
+
+// DeleteProvisioner deletes a provisioner by name
+func (o *OperatorACL) DeleteProvisioner(key SiteKey, name string) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindProvisioner, teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteProvisioner(key, name)
+}
+
+// ProvisionNodes creates the machines of new cluster nodes with
+// the specified provisioner and joins them to the cluster
+func (o *OperatorACL) ProvisionNodes(ctx context.Context, req ProvisionNodesRequest) (*ProvisionNodesResponse, error) {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindProvisioner, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.ProvisionNodes(ctx, req)
+}
+
 // UpsertAuthGateway updates auth gateway configuration.
 func (o *OperatorACL) UpsertAuthGateway(key SiteKey, gw storage.AuthGateway) error {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
@@ -1072,6 +1563,23 @@ func (o *OperatorACL) EmitAuditEvent(ctx context.Context, req AuditEventRequest)
 	return o.operator.EmitAuditEvent(ctx, req)
 }
 
+// GetAuditEvents returns the recorded audit events of operator API calls.
+func (o *OperatorACL) GetAuditEvents(ctx context.Context, req GetAuditEventsRequest) ([]storage.AuditEvent, error) {
+	if err := o.accountAction(req.AccountID, teleservices.KindEvent, teleservices.VerbList); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetAuditEvents(ctx, req)
+}
+
+// UploadSessionRecording stores the recording of an interactive session
+// read from the provided reader for later playback.
+func (o *OperatorACL) UploadSessionRecording(ctx context.Context, req UploadSessionRecordingRequest, reader io.Reader) error {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UploadSessionRecording(ctx, req, reader)
+}
+
 // CreateUserInvite creates a new invite token for a user.
 func (o *OperatorACL) CreateUserInvite(ctx context.Context, req CreateUserInviteRequest) (*storage.UserToken, error) {
 	if err := o.ClusterAction(req.SiteDomain, storage.KindInvite, teleservices.VerbCreate); err != nil {
@@ -1098,8 +1606,69 @@ func (o *OperatorACL) DeleteUserInvite(ctx context.Context, req DeleteUserInvite
 
 // CreateUserReset creates a new reset token for a user.
 func (o *OperatorACL) CreateUserReset(ctx context.Context, req CreateUserResetRequest) (*storage.UserToken, error) {
-	if err := o.Action(teleservices.KindUser, teleservices.VerbUpdate); err != nil {
+	if err := o.accountAction(req.AccountID, teleservices.KindUser, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := o.userAccountAccess(req.Name); err != nil {
 		return nil, trace.Wrap(err)
 	}
 	return o.operator.CreateUserReset(ctx, req)
 }
+
+// CreateFleetUpgrade starts upgrading the remote clusters matching
+// the request selector to the requested application version
+func (o *OperatorACL) CreateFleetUpgrade(ctx context.Context, req CreateFleetUpgradeRequest) (*storage.FleetUpgrade, error) {
+	if err := o.accountAction(req.AccountID, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.CreateFleetUpgrade(ctx, req)
+}
+
+// GetFleetUpgrade returns the fleet upgrade specified with the key
+func (o *OperatorACL) GetFleetUpgrade(ctx context.Context, key FleetUpgradeKey) (*storage.FleetUpgrade, error) {
+	if err := o.accountAction(key.AccountID, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetFleetUpgrade(ctx, key)
+}
+
+// GetFleetUpgrades returns all fleet upgrades of the specified account
+func (o *OperatorACL) GetFleetUpgrades(ctx context.Context, accountID string) ([]storage.FleetUpgrade, error) {
+	if err := o.accountAction(accountID, storage.KindCluster, teleservices.VerbList); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetFleetUpgrades(ctx, accountID)
+}
+
+// CancelFleetUpgrade stops the fleet upgrade specified with the key
+func (o *OperatorACL) CancelFleetUpgrade(ctx context.Context, key FleetUpgradeKey) error {
+	if err := o.accountAction(key.AccountID, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.CancelFleetUpgrade(ctx, key)
+}
+
+// CreateScheduledOperation queues the operation to start according
+// to the request schedule
+func (o *OperatorACL) CreateScheduledOperation(ctx context.Context, req CreateScheduledOperationRequest) (*storage.ScheduledOperation, error) {
+	if err := o.ClusterAction(req.ClusterName, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.CreateScheduledOperation(ctx, req)
+}
+
+// GetScheduledOperations returns all scheduled operations of the cluster
+func (o *OperatorACL) GetScheduledOperations(ctx context.Context, key SiteKey) ([]storage.ScheduledOperation, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetScheduledOperations(ctx, key)
+}
+
+// CancelScheduledOperation cancels the pending scheduled operation
+func (o *OperatorACL) CancelScheduledOperation(ctx context.Context, key ScheduledOperationKey) error {
+	if err := o.ClusterAction(key.ClusterName, storage.KindCluster, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.CancelScheduledOperation(ctx, key)
+}
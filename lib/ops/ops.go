@@ -32,6 +32,7 @@ import (
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops/monitoring"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/verify"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/clusterconfig"
@@ -46,6 +47,7 @@ import (
 	teleclient "github.com/gravitational/teleport/lib/client"
 	"github.com/gravitational/teleport/lib/events"
 	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/teleport/lib/session"
 	"github.com/gravitational/trace"
 	"k8s.io/helm/pkg/proto/hapi/release"
 )
@@ -120,6 +122,8 @@ type Operator interface {
 	LogForwarders
 	Monitoring
 	SMTP
+	Healing
+	ClusterDNS
 	Endpoints
 	Tokens
 	Certificates
@@ -130,6 +134,11 @@ type Operator interface {
 	RuntimeEnvironment
 	ClusterConfiguration
 	Audit
+	FleetUpgrades
+	ScheduledOperations
+	ConfigResources
+	Webhooks
+	Provisioners
 }
 
 // Accounts represents a collection of accounts in the portal
@@ -142,6 +151,10 @@ type Accounts interface {
 
 	// CreateAccount creates a new account
 	CreateAccount(NewAccountRequest) (*Account, error)
+
+	// DeleteAccount deletes the account with the specified ID.
+	// The account must not have any clusters
+	DeleteAccount(accountID string) error
 }
 
 // UserInfo represents information about current user
@@ -332,6 +345,64 @@ func (r *DeleteUserInviteRequest) Check() error {
 	return nil
 }
 
+// CreateJoinTokenRequest is a request to generate a new scoped join token.
+type CreateJoinTokenRequest struct {
+	// SiteKey is the key of the cluster to route request to.
+	SiteKey
+	// TTL specifies how long the generated token is valid for.
+	TTL time.Duration `json:"ttl"`
+	// MaxUses limits the number of nodes that can join with the token,
+	// zero means no limit.
+	MaxUses int `json:"max_uses"`
+	// Roles restricts the service roles (master or node) of the nodes
+	// joining with the token, empty means any role.
+	Roles []string `json:"roles"`
+}
+
+// CheckAndSetDefaults validates the request and sets default values.
+func (r *CreateJoinTokenRequest) CheckAndSetDefaults() error {
+	if err := r.SiteKey.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.TTL < 0 {
+		return trace.BadParameter("ttl can't be negative")
+	}
+	if r.MaxUses < 0 {
+		return trace.BadParameter("max uses can't be negative")
+	}
+	for _, role := range r.Roles {
+		switch schema.ServiceRole(role) {
+		case schema.ServiceRoleMaster, schema.ServiceRoleNode:
+		default:
+			return trace.BadParameter("unsupported role %q, supported roles are %q and %q",
+				role, schema.ServiceRoleMaster, schema.ServiceRoleNode)
+		}
+	}
+	if r.TTL == 0 {
+		r.TTL = defaults.JoinTokenTTL
+	}
+	return nil
+}
+
+// DeleteJoinTokenRequest is a request to revoke a scoped join token.
+type DeleteJoinTokenRequest struct {
+	// SiteKey is the key of the cluster to route request to.
+	SiteKey
+	// Token is the join token to revoke.
+	Token string `json:"token"`
+}
+
+// Check validates the request.
+func (r *DeleteJoinTokenRequest) Check() error {
+	if err := r.SiteKey.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Token == "" {
+		return trace.BadParameter("token can't be empty")
+	}
+	return nil
+}
+
 // ResetUserPasswordRequest is a request to reset gravity site user password
 type ResetUserPasswordRequest struct {
 	// AccountID is the ID of the account the site belongs to
@@ -372,6 +443,13 @@ type Tokens interface {
 	CreateProvisioningToken(storage.ProvisioningToken) error
 	// GetExpandToken returns the cluster's expand token
 	GetExpandToken(SiteKey) (*storage.ProvisioningToken, error)
+	// CreateJoinToken creates a new join token limited in time, number of uses
+	// or roles of the joining nodes
+	CreateJoinToken(context.Context, CreateJoinTokenRequest) (*storage.ProvisioningToken, error)
+	// GetJoinTokens returns the active scoped join tokens of the cluster
+	GetJoinTokens(context.Context, SiteKey) ([]storage.ProvisioningToken, error)
+	// DeleteJoinToken revokes the specified scoped join token
+	DeleteJoinToken(context.Context, DeleteJoinTokenRequest) error
 	// GetTrustedClusterToken returns the cluster's trusted cluster token
 	GetTrustedClusterToken(SiteKey) (storage.Token, error)
 }
@@ -409,11 +487,14 @@ type Sites interface {
 	// an application
 	ActivateSite(ActivateSiteRequest) error
 
+	// UpdateLicense replaces the license installed on the cluster
+	UpdateLicense(context.Context, UpdateLicenseRequest) error
+
 	// CompleteFinalInstallStep marks the site as having completed the mandatory last installation step
 	CompleteFinalInstallStep(CompleteFinalInstallStepRequest) error
 
 	// GetSiteReport returns a tarball that contains all debugging information gathered for the site
-	GetSiteReport(SiteKey) (io.ReadCloser, error)
+	GetSiteReport(GetClusterReportRequest) (io.ReadCloser, error)
 
 	// SignTLSKey signs X509 Public Key with X509 certificate authority of this site
 	SignTLSKey(TLSSignRequest) (*TLSSignResponse, error)
@@ -589,6 +670,12 @@ type Certificates interface {
 	UpdateClusterCertificate(UpdateCertificateRequest) (*ClusterCertificate, error)
 	// DeleteClusterCertificate deletes the cluster TLS certificate
 	DeleteClusterCertificate(SiteKey) error
+	// IssueClusterCertificate issues a new cluster TLS certificate from the
+	// user-provided certificate authority of the cluster and installs it
+	IssueClusterCertificate(SiteKey) (*ClusterCertificate, error)
+	// CreateRotateCertificatesOperation creates a new operation to rotate
+	// the certificates of the cluster nodes
+	CreateRotateCertificatesOperation(context.Context, CreateRotateCertificatesOperationRequest) (*SiteOperationKey, error)
 }
 
 // RuntimeEnvironment manages runtime environment variables in cluster
@@ -610,6 +697,9 @@ type ClusterConfiguration interface {
 	GetClusterConfiguration(SiteKey) (clusterconfig.Interface, error)
 	// UpdateClusterConfiguration updates the cluster configuration from the specified request
 	UpdateClusterConfiguration(UpdateClusterConfigRequest) error
+	// CreatePatchOperation creates a new operation to apply configuration-only
+	// changes to the cluster
+	CreatePatchOperation(context.Context, CreatePatchOperationRequest) (*SiteOperationKey, error)
 }
 
 // ClusterCertificate represents the cluster certificate
@@ -682,6 +772,72 @@ type Status interface {
 	CheckSiteStatus(ctx context.Context, key SiteKey) error
 	// GetClusterNodes returns a real-time information about cluster nodes
 	GetClusterNodes(SiteKey) ([]Node, error)
+	// GetClusterStatusHistory returns the recorded status snapshots of the cluster
+	GetClusterStatusHistory(context.Context, GetClusterStatusHistoryRequest) ([]storage.StatusSnapshot, error)
+	// GetRegistryStatus returns the health and the storage usage
+	// of the cluster Docker registry
+	GetRegistryStatus(context.Context, SiteKey) (*RegistryStatus, error)
+	// GetPackageVerificationStatus returns the results of the last
+	// integrity verification of the cluster packages
+	GetPackageVerificationStatus(context.Context, SiteKey) (*PackageVerificationStatus, error)
+	// GetClusterHealth returns the health of cluster nodes with the reasons
+	// of the failed health probes
+	GetClusterHealth(context.Context, SiteKey) (*ClusterHealth, error)
+}
+
+// PackageVerificationStatus describes the results of the last integrity
+// verification of the cluster packages stored on a master node
+type PackageVerificationStatus struct {
+	// Healthy is whether no corrupted packages have been left unrepaired
+	Healthy bool `json:"healthy"`
+	// Report is the report of the last verification
+	verify.Report
+}
+
+// ClusterHealth describes the health of cluster nodes
+type ClusterHealth struct {
+	// Status is the overall system status, e.g. running or degraded
+	Status string `json:"status"`
+	// Nodes lists the health of individual nodes
+	Nodes []NodeHealth `json:"nodes"`
+}
+
+// NodeHealth describes the health of a cluster node
+type NodeHealth struct {
+	// Hostname is the node hostname
+	Hostname string `json:"hostname"`
+	// AdvertiseIP is the node advertise IP address
+	AdvertiseIP string `json:"advertise_ip"`
+	// Status is the node status, e.g. healthy, degraded or offline
+	Status string `json:"status"`
+	// Probes describes the failed health probes if the node is not healthy
+	Probes []storage.ProbeFailure `json:"probes,omitempty"`
+}
+
+// RegistryStatus describes the health and the storage usage of the cluster registry
+type RegistryStatus struct {
+	// Healthy is whether all registry health checks pass
+	Healthy bool `json:"healthy"`
+	// FailedChecks maps the names of the failing health checks to their errors
+	FailedChecks map[string]string `json:"failed_checks,omitempty"`
+	// StorageDriver is the name of the registry storage driver
+	StorageDriver string `json:"storage_driver"`
+	// Repositories is the number of image repositories
+	Repositories int `json:"repositories"`
+	// Manifests is the number of image manifests
+	Manifests int `json:"manifests"`
+	// Blobs is the number of blobs in the registry storage
+	Blobs int `json:"blobs"`
+	// SizeBytes is the total size of the blobs
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+// GetClusterStatusHistoryRequest is a request to list cluster status snapshots
+type GetClusterStatusHistoryRequest struct {
+	// SiteKey is the ID of the cluster the request is for
+	SiteKey
+	// Since limits the snapshots to those recorded after the specified time
+	Since time.Time `json:"since"`
 }
 
 // Node represents a cluster node information based on Teleport node
@@ -739,6 +895,10 @@ type Operations interface {
 	// in the cluster
 	CreateClusterGarbageCollectOperation(context.Context, CreateClusterGarbageCollectOperationRequest) (*SiteOperationKey, error)
 
+	// CollectPackageGarbage removes the packages not reachable from the applications
+	// in use from the cluster package service
+	CollectPackageGarbage(context.Context, CollectPackageGarbageRequest) (*CollectPackageGarbageResponse, error)
+
 	// GetsiteOperation returns the operation information based on it's key
 	GetSiteOperation(SiteOperationKey) (*SiteOperation, error)
 
@@ -752,12 +912,23 @@ type Operations interface {
 	// CreateLogEntry appends the provided log entry to the operation's log file
 	CreateLogEntry(SiteOperationKey, LogEntry) error
 
+	// GetOperationPhaseLogs returns the log entries recorded while executing
+	// the specified phase of the operation and any of its subphases
+	GetOperationPhaseLogs(key SiteOperationKey, phaseID string) ([]LogEntry, error)
+
 	// GetSiteOperationProgress returns last progress entry of a given operation
 	//
 	// This method is called periodically after operation start
 	// process to get the progress report
 	GetSiteOperationProgress(SiteOperationKey) (*ProgressEntry, error)
 
+	// WatchSiteOperationProgress sends progress entries of the specified
+	// operation to progressC as they change.
+	//
+	// It blocks until the operation completes, the context is cancelled
+	// or an error occurs
+	WatchSiteOperationProgress(ctx context.Context, key SiteOperationKey, progressC chan<- ProgressEntry) error
+
 	// CreateProgressEntry creates a new progress entry for the specified
 	// operation
 	CreateProgressEntry(SiteOperationKey, ProgressEntry) error
@@ -812,6 +983,18 @@ type Operations interface {
 	// SetOperationState moves operation into specified state
 	SetOperationState(key SiteOperationKey, req SetOperationStateRequest) error
 
+	// CancelSiteOperation requests cancellation of the specified operation.
+	// The operation aborts before the next phase and rolls back the phase in progress
+	CancelSiteOperation(context.Context, SiteOperationKey) error
+
+	// PauseSiteOperation requests the specified operation to stop after
+	// the phase in progress
+	PauseSiteOperation(context.Context, SiteOperationKey) error
+
+	// ResumeSiteOperation clears the pause request of the specified operation
+	// so its plan can be resumed
+	ResumeSiteOperation(context.Context, SiteOperationKey) error
+
 	// CreateOperationPlan saves the provided operation plan
 	CreateOperationPlan(SiteOperationKey, storage.OperationPlan) error
 
@@ -836,10 +1019,25 @@ type LogEntry struct {
 	Message string `json:"message"`
 	// Server is an optional server that generated the log entry
 	Server *storage.Server `json:"server,omitempty"`
+	// PhaseID is the optional ID of the operation phase that generated the log entry
+	PhaseID string `json:"phase_id,omitempty"`
 	// Created is the log entry timestamp
 	Created time.Time `json:"created"`
 }
 
+// IsFromPhase returns true if the log entry has been generated by the specified
+// phase or any of its subphases
+func (l LogEntry) IsFromPhase(phaseID string) bool {
+	if l.PhaseID == "" {
+		return false
+	}
+	if phaseID == "/" {
+		// the root phase encompasses the whole plan
+		return true
+	}
+	return l.PhaseID == phaseID || strings.HasPrefix(l.PhaseID, phaseID+"/")
+}
+
 // String formats the log entry as a string
 func (l LogEntry) String() string {
 	var server string
@@ -873,6 +1071,10 @@ type Updates interface {
 
 	// ConfigureNode prepares the node for the upgrade
 	ConfigureNode(ConfigureNodeRequest) error
+
+	// RotateCertAuthority moves the cluster certificate authority package
+	// to the stage specified in the request
+	RotateCertAuthority(RotateCertAuthorityRequest) error
 }
 
 // RotatePackageResponse describes a response to generate a new package for an existing one.
@@ -1140,6 +1342,10 @@ func (s *SiteOperation) TypeString() string {
 		return "update runtime environment"
 	case OperationUpdateConfig:
 		return "update configuration"
+	case OperationRotateCertificates:
+		return "rotate certificates"
+	case OperationPatch:
+		return "patch configuration"
 	default:
 		return s.Type
 	}
@@ -1245,6 +1451,9 @@ type CreateSiteExpandOperationRequest struct {
 	Servers map[string]int `json:"servers"`
 	// Provisioner to use for this operation
 	Provisioner string `json:"provisioner"`
+	// JoinToken is the token the joining node authenticated with.
+	// Scoped join tokens are validated against the roles of the new servers
+	JoinToken string `json:"join_token,omitempty"`
 }
 
 // CheckAndSetDefaults makes sure the request is correct and fills in some unset
@@ -1283,6 +1492,8 @@ type CreateSiteShrinkOperationRequest struct {
 	// Used in cases where we recieve an event where the node is being terminated, but may
 	// not have disconnected from the cluster yet.
 	NodeRemoved bool `json:"node_removed"`
+	// Drain specifies optional parameters for draining the node
+	Drain *storage.DrainOptions `json:"drain,omitempty"`
 }
 
 // CheckAndSetDefaults makes sure the request is correct and fills in some unset
@@ -1300,6 +1511,11 @@ func (r *CreateSiteShrinkOperationRequest) CheckAndSetDefaults() error {
 	if len(r.Servers) != 1 {
 		return trace.BadParameter("can delete only one server at a time, got: %v", r.Servers)
 	}
+	if r.Drain != nil {
+		if err := r.Drain.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	return nil
 }
 
@@ -1314,6 +1530,11 @@ type CreateSiteAppUpdateOperationRequest struct {
 	App string `json:"package"`
 	// StartAgents specifies whether the operation will automatically start the update agents
 	StartAgents bool `json:"start_agents"`
+	// Drain specifies optional parameters for draining the nodes being updated
+	Drain *storage.DrainOptions `json:"drain,omitempty"`
+	// Canary optionally specifies the subset of regular nodes
+	// to update before the rest of the cluster
+	Canary *storage.CanaryOptions `json:"canary,omitempty"`
 }
 
 // Check validates this request
@@ -1336,6 +1557,41 @@ type CreateClusterGarbageCollectOperationRequest struct {
 	ClusterName string `json:"cluster_name"`
 }
 
+// Check validates this request
+func (r CollectPackageGarbageRequest) Check() error {
+	if err := r.ClusterKey.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.ProtectionWindow < 0 {
+		return trace.BadParameter("protection window cannot be negative")
+	}
+	return nil
+}
+
+// CollectPackageGarbageRequest is a request to remove unreachable packages
+// from the cluster package service
+type CollectPackageGarbageRequest struct {
+	// ClusterKey identifies the cluster
+	ClusterKey SiteKey `json:"cluster_key"`
+	// DryRun specifies whether to only report the unreachable packages
+	DryRun bool `json:"dry_run"`
+	// ProtectionWindow specifies the period after import during which
+	// unreachable packages are kept
+	ProtectionWindow time.Duration `json:"protection_window"`
+}
+
+// CollectPackageGarbageResponse describes the result of the package
+// garbage collection
+type CollectPackageGarbageResponse struct {
+	// Deleted lists the removed packages
+	Deleted []loc.Locator `json:"deleted"`
+	// Protected lists the unreachable packages kept because they
+	// have been imported within the protection window
+	Protected []loc.Locator `json:"protected"`
+	// ReclaimedBytes is the total size of the removed packages
+	ReclaimedBytes int64 `json:"reclaimed_bytes"`
+}
+
 // CreateUpdateEnvarsOperationRequest is a request
 // to update cluster environment variables
 type CreateUpdateEnvarsOperationRequest struct {
@@ -1354,6 +1610,59 @@ type CreateUpdateConfigOperationRequest struct {
 	Config []byte `json:"config"`
 }
 
+// CreatePatchOperationRequest is a request to create an operation
+// to apply configuration-only changes to the cluster
+type CreatePatchOperationRequest struct {
+	// ClusterKey identifies the cluster
+	ClusterKey SiteKey `json:"cluster_key"`
+	// Env specifies the runtime environment variables to set.
+	// The variables are merged into the existing cluster environment
+	Env map[string]string `json:"env,omitempty"`
+	// DNSUpstreams specifies the new upstream nameservers for the cluster DNS
+	DNSUpstreams []string `json:"dns_upstreams,omitempty"`
+}
+
+// Check validates the configuration changes requested by this request
+func (r CreatePatchOperationRequest) Check() error {
+	if len(r.Env) == 0 && len(r.DNSUpstreams) == 0 {
+		return trace.BadParameter("nothing to patch: specify environment variables or DNS upstreams")
+	}
+	for _, nameserver := range r.DNSUpstreams {
+		if err := utils.ValidateNameserver(nameserver); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// CreateRotateCertificatesOperationRequest is a request
+// to create an operation to rotate cluster certificates
+type CreateRotateCertificatesOperationRequest struct {
+	// ClusterKey identifies the cluster
+	ClusterKey SiteKey `json:"cluster_key"`
+	// RotateCA specifies whether to replace the cluster certificate authority.
+	// If false, only the node certificates are reissued
+	RotateCA bool `json:"rotate_ca"`
+}
+
+// RotateCertAuthorityRequest is a request to move the cluster
+// certificate authority to a different rotation stage
+type RotateCertAuthorityRequest struct {
+	// Key identifies the certificate rotation operation
+	Key SiteOperationKey `json:"key"`
+	// Stage specifies the target certificate authority stage
+	Stage string `json:"stage"`
+}
+
+// Check validates this request
+func (r RotateCertAuthorityRequest) Check() error {
+	if !utils.StringInSlice(CertAuthorityStages, r.Stage) {
+		return trace.BadParameter("unknown certificate authority stage %q, expected one of %v",
+			r.Stage, CertAuthorityStages)
+	}
+	return nil
+}
+
 // UpdateClusterEnvironRequest is a request
 // to update cluster runtime environment
 type UpdateClusterEnvironRequest struct {
@@ -1449,6 +1758,8 @@ type NewAPIKeyRequest struct {
 	Token string `json:"token"`
 	// Upsert controls whether existing key should be updated
 	Upsert bool `json:"upsert"`
+	// Permissions optionally restricts the key to the specified permissions
+	Permissions storage.ResourcePermissions `json:"permissions,omitempty"`
 }
 
 // NewInstallTokenRequest is a request to generate a one-time install token
@@ -1524,6 +1835,66 @@ type NewSiteRequest struct {
 	DNSConfig storage.DNSConfig `json:"dns_config"`
 	// Docker specifies the cluster Docker configuration
 	Docker storage.DockerConfig `json:"docker"`
+	// CertAuthority optionally specifies the user-provided certificate authority
+	// to issue the cluster certificates from instead of a self-generated one
+	CertAuthority *ExternalCertAuthority `json:"cert_authority,omitempty"`
+	// FIPS specifies whether the cluster is installed in FIPS mode
+	FIPS bool `json:"fips,omitempty"`
+}
+
+// ExternalCertAuthority describes the user-provided intermediate certificate
+// authority the cluster certificates are issued from.
+//
+// The certificates are either signed locally with the private key of the
+// certificate authority or by the external signer if the key is not provided
+type ExternalCertAuthority struct {
+	// CertPEM is the PEM-encoded certificate of the intermediate certificate
+	// authority optionally followed by the rest of its chain
+	CertPEM []byte `json:"cert_pem"`
+	// KeyPEM is the PEM-encoded private key of the intermediate certificate authority
+	KeyPEM []byte `json:"key_pem,omitempty"`
+	// SignerURL is the URL of the external signer to send the certificate
+	// signing requests to
+	SignerURL string `json:"signer_url,omitempty"`
+}
+
+// Check makes sure the certificate authority is either
+// accompanied by its private key or by the signer URL
+func (r ExternalCertAuthority) Check() error {
+	if len(r.CertPEM) == 0 {
+		return trace.BadParameter("missing certificate authority certificate")
+	}
+	if len(r.KeyPEM) == 0 && r.SignerURL == "" {
+		return trace.BadParameter("either certificate authority private key or signer URL is required")
+	}
+	if len(r.KeyPEM) != 0 && r.SignerURL != "" {
+		return trace.BadParameter("certificate authority private key and signer URL are mutually exclusive")
+	}
+	if r.SignerURL != "" {
+		u, err := url.Parse(r.SignerURL)
+		if err != nil {
+			return trace.BadParameter("invalid signer URL %q: %v", r.SignerURL, err)
+		}
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return trace.BadParameter("signer URL %q should use HTTP or HTTPS scheme", r.SignerURL)
+		}
+	}
+	return nil
+}
+
+// GetClusterReportRequest is a request to collect the cluster diagnostics report
+type GetClusterReportRequest struct {
+	// SiteKey identifies the cluster
+	SiteKey
+	// MaxFileSize is the maximum size of a single file in the report.
+	// Files are truncated to this size. Zero means no limit
+	MaxFileSize int64 `json:"max_file_size"`
+	// MaxSize is the maximum total size of the files in the report.
+	// Zero means no limit
+	MaxSize int64 `json:"max_size"`
+	// Operations is the number of most recent operations to include
+	// the logs and plans of. Zero means the default
+	Operations int `json:"operations"`
 }
 
 // SiteKey is a key used to identify site
@@ -1611,6 +1982,9 @@ type Site struct {
 	DNSOverrides storage.DNSOverrides `json:"dns_overrides"`
 	// DNSConfig specifies the cluster local DNS server configuration
 	DNSConfig storage.DNSConfig `json:"dns_config"`
+	// PKI describes the user-provided certificate authority the cluster
+	// certificates are issued from, if any
+	PKI *storage.ExternalPKI `json:"pki,omitempty"`
 }
 
 // IsOnline returns whether this site is online
@@ -1695,6 +2069,8 @@ type License struct {
 	Raw string `json:"raw"`
 	// Payload is the parsed license payload
 	Payload license.Payload `json:"payload"`
+	// Status is the license status computed for the cluster
+	Status LicenseStatus `json:"status"`
 }
 
 // Key is a helper function to return site key from a site
@@ -1851,6 +2227,113 @@ type LogForwarders interface {
 	DeleteLogForwarder(key SiteKey, name string) error
 }
 
+// Healing defines the interface to manage the policy of the cluster
+// health controller
+type Healing interface {
+	// GetHealingPolicy returns the cluster healing policy
+	GetHealingPolicy(SiteKey) (storage.HealingPolicy, error)
+	// UpdateHealingPolicy updates the cluster healing policy
+	UpdateHealingPolicy(SiteKey, storage.HealingPolicy) error
+	// DeleteHealingPolicy deletes the cluster healing policy
+	DeleteHealingPolicy(SiteKey) error
+}
+
+// ClusterDNS defines the interface to manage the configuration
+// of the cluster DNS service
+type ClusterDNS interface {
+	// GetClusterDNS returns the cluster DNS configuration
+	GetClusterDNS(SiteKey) (storage.ClusterDNS, error)
+	// UpdateClusterDNS updates the cluster DNS configuration
+	UpdateClusterDNS(SiteKey, storage.ClusterDNS) error
+	// DeleteClusterDNS resets the cluster DNS configuration to defaults
+	DeleteClusterDNS(SiteKey) error
+}
+
+// ConfigResources defines the interface to manage configuration resources
+// of the kinds registered with storage.RegisterResourceKind
+type ConfigResources interface {
+	// UpsertConfigResource creates or updates a configuration resource
+	UpsertConfigResource(SiteKey, storage.UnknownResource) error
+	// GetConfigResource returns a configuration resource by kind and name
+	GetConfigResource(key SiteKey, kind, name string) (*storage.UnknownResource, error)
+	// GetConfigResources returns all configuration resources of the specified kind
+	GetConfigResources(key SiteKey, kind string) ([]storage.UnknownResource, error)
+	// DeleteConfigResource deletes a configuration resource by kind and name
+	DeleteConfigResource(key SiteKey, kind, name string) error
+}
+
+// Webhooks defines the interface to manage webhooks notified about
+// cluster operation events
+type Webhooks interface {
+	// UpsertWebhook creates or updates a webhook
+	UpsertWebhook(SiteKey, storage.Webhook) error
+	// GetWebhook returns a webhook by name
+	GetWebhook(key SiteKey, name string) (storage.Webhook, error)
+	// GetWebhooks returns all webhooks
+	GetWebhooks(SiteKey) ([]storage.Webhook, error)
+	// DeleteWebhook deletes a webhook by name
+	DeleteWebhook(key SiteKey, name string) error
+}
+
+// Provisioners defines the interface to manage cloud provisioners
+// of new cluster nodes
+type Provisioners interface {
+	// UpsertProvisioner creates or updates a provisioner
+	UpsertProvisioner(SiteKey, storage.Provisioner) error
+	// GetProvisioner returns a provisioner by name
+	GetProvisioner(key SiteKey, name string) (storage.Provisioner, error)
+	// GetProvisioners returns all provisioners
+	GetProvisioners(SiteKey) ([]storage.Provisioner, error)
+	// DeleteProvisioner deletes a provisioner by name
+	DeleteProvisioner(key SiteKey, name string) error
+	// ProvisionNodes creates the machines of new cluster nodes with
+	// the specified provisioner and joins them to the cluster
+	ProvisionNodes(context.Context, ProvisionNodesRequest) (*ProvisionNodesResponse, error)
+}
+
+// ProvisionNodesRequest describes the nodes to provision
+type ProvisionNodesRequest struct {
+	// SiteKey identifies the cluster
+	SiteKey `json:"site_key"`
+	// Provisioner is the name of the provisioner to create the machines with
+	Provisioner string `json:"provisioner"`
+	// Profile is the node profile of the new nodes
+	Profile string `json:"profile"`
+	// Count is the number of nodes to provision
+	Count int `json:"count"`
+}
+
+// Check validates the request
+func (r ProvisionNodesRequest) Check() error {
+	if err := r.SiteKey.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Provisioner == "" {
+		return trace.BadParameter("missing provisioner name")
+	}
+	if r.Profile == "" {
+		return trace.BadParameter("missing node profile")
+	}
+	if r.Count <= 0 {
+		return trace.BadParameter("node count should be positive, got %v", r.Count)
+	}
+	return nil
+}
+
+// ProvisionNodesResponse describes the provisioned machines
+type ProvisionNodesResponse struct {
+	// Machines lists the created machines
+	Machines []ProvisionedMachine `json:"machines"`
+}
+
+// ProvisionedMachine describes a machine created for a new cluster node
+type ProvisionedMachine struct {
+	// ID is the cloud provider specific machine identifier
+	ID string `json:"id"`
+	// Name is the machine name
+	Name string `json:"name"`
+}
+
 // SMTP defines the interface to manage cluster SMTP configuration
 type SMTP interface {
 	// GetSMTPConfig returns the cluster SMTP configuration
@@ -1992,6 +2475,22 @@ type Identity interface {
 	GetGithubConnectors(key SiteKey, withSecrets bool) ([]teleservices.GithubConnector, error)
 	// DeleteGithubConnector deletes a Github connector by name
 	DeleteGithubConnector(key SiteKey, name string) error
+	// UpsertSAMLConnector creates or updates a SAML connector
+	UpsertSAMLConnector(key SiteKey, conn teleservices.SAMLConnector) error
+	// GetSAMLConnector returns a SAML connector by its name
+	GetSAMLConnector(key SiteKey, name string, withSecrets bool) (teleservices.SAMLConnector, error)
+	// GetSAMLConnectors returns all SAML connectors
+	GetSAMLConnectors(key SiteKey, withSecrets bool) ([]teleservices.SAMLConnector, error)
+	// DeleteSAMLConnector deletes a SAML connector by name
+	DeleteSAMLConnector(key SiteKey, name string) error
+	// UpsertRoleMapping creates or updates a mapping of OIDC claims to roles
+	UpsertRoleMapping(SiteKey, storage.RoleMapping) error
+	// GetRoleMapping returns a role mapping by name
+	GetRoleMapping(key SiteKey, name string) (storage.RoleMapping, error)
+	// GetRoleMappings returns all role mappings
+	GetRoleMappings(SiteKey) ([]storage.RoleMapping, error)
+	// DeleteRoleMapping deletes a role mapping by name
+	DeleteRoleMapping(key SiteKey, name string) error
 	// UpsertAuthGateway updates auth gateway configuration
 	UpsertAuthGateway(SiteKey, storage.AuthGateway) error
 	// GetAuthGateway returns auth gateway configuration
@@ -2024,8 +2523,41 @@ func (r AuditEventRequest) String() string {
 	return fmt.Sprintf("AuditEvent(Type=%v, Fields=%v)", r.Type, r.Fields)
 }
 
+// GetAuditEventsRequest is a request to list audit events of operator API calls.
+type GetAuditEventsRequest struct {
+	// SiteKey is the ID of the cluster the request is for.
+	SiteKey
+	// Since limits the events to those recorded after the specified time.
+	Since time.Time `json:"since"`
+}
+
+// UploadSessionRecordingRequest is a request to upload the recording
+// of an interactive session.
+type UploadSessionRecordingRequest struct {
+	// SiteKey is the ID of the cluster the session was recorded on.
+	SiteKey
+	// SessionID is the ID of the recorded session.
+	SessionID string `json:"session_id"`
+}
+
+// Check validates the session recording upload request.
+func (r UploadSessionRecordingRequest) Check() error {
+	if err := r.SiteKey.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if _, err := session.ParseID(r.SessionID); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
 // Audit provides interface for emitting audit log events.
 type Audit interface {
 	// EmitAuditEvent saves the provided event in the audit log.
 	EmitAuditEvent(context.Context, AuditEventRequest) error
+	// GetAuditEvents returns the recorded audit events of operator API calls.
+	GetAuditEvents(context.Context, GetAuditEventsRequest) ([]storage.AuditEvent, error)
+	// UploadSessionRecording stores the recording of an interactive session
+	// read from the provided reader for later playback.
+	UploadSessionRecording(context.Context, UploadSessionRecordingRequest, io.Reader) error
 }
@@ -133,6 +133,12 @@ func (c *Client) CreateAccount(req ops.NewAccountRequest) (*ops.Account, error)
 	return &account, nil
 }
 
+// DeleteAccount deletes the account with the specified ID
+func (c *Client) DeleteAccount(accountID string) error {
+	_, err := c.Delete(c.Endpoint("accounts", accountID))
+	return trace.Wrap(err)
+}
+
 func (c *Client) GetAccounts() ([]ops.Account, error) {
 	out, err := c.Get(c.Endpoint("accounts"), url.Values{})
 	if err != nil {
@@ -235,6 +241,42 @@ func (c *Client) GetExpandToken(key ops.SiteKey) (*storage.ProvisioningToken, er
 	return &token, nil
 }
 
+// CreateJoinToken creates a new join token limited in time, number of uses
+// or roles of the joining nodes
+func (c *Client) CreateJoinToken(ctx context.Context, req ops.CreateJoinTokenRequest) (*storage.ProvisioningToken, error) {
+	out, err := c.PostJSON(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "tokens", "join"), req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var token storage.ProvisioningToken
+	if err := json.Unmarshal(out.Bytes(), &token); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &token, nil
+}
+
+// GetJoinTokens returns the active scoped join tokens of the cluster
+func (c *Client) GetJoinTokens(ctx context.Context, key ops.SiteKey) ([]storage.ProvisioningToken, error) {
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "tokens", "join"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var tokens []storage.ProvisioningToken
+	if err := json.Unmarshal(out.Bytes(), &tokens); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return tokens, nil
+}
+
+// DeleteJoinToken revokes the specified scoped join token
+func (c *Client) DeleteJoinToken(ctx context.Context, req ops.DeleteJoinTokenRequest) error {
+	_, err := c.Delete(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "tokens", "join", req.Token))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
 // TODO(r0mant) Move to enterprise.
 func (c *Client) GetTrustedClusterToken(key ops.SiteKey) (storage.Token, error) {
 	out, err := c.Get(c.Endpoint(
@@ -367,6 +409,62 @@ func (c *Client) GetClusterNodes(key ops.SiteKey) ([]ops.Node, error) {
 	return nodes, nil
 }
 
+// GetClusterStatusHistory returns the recorded status snapshots of the cluster
+func (c *Client) GetClusterStatusHistory(ctx context.Context, req ops.GetClusterStatusHistoryRequest) ([]storage.StatusSnapshot, error) {
+	query := url.Values{}
+	if !req.Since.IsZero() {
+		query.Set("since", req.Since.Format(time.RFC3339))
+	}
+	out, err := c.Get(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "status", "history"), query)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var snapshots []storage.StatusSnapshot
+	if err := json.Unmarshal(out.Bytes(), &snapshots); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return snapshots, nil
+}
+
+// GetRegistryStatus returns the status of the cluster registry
+func (c *Client) GetRegistryStatus(ctx context.Context, key ops.SiteKey) (*ops.RegistryStatus, error) {
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "status", "registry"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var status ops.RegistryStatus
+	if err := json.Unmarshal(out.Bytes(), &status); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &status, nil
+}
+
+// GetPackageVerificationStatus returns the results of the last cluster package verification
+func (c *Client) GetPackageVerificationStatus(ctx context.Context, key ops.SiteKey) (*ops.PackageVerificationStatus, error) {
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "status", "packages"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var status ops.PackageVerificationStatus
+	if err := json.Unmarshal(out.Bytes(), &status); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &status, nil
+}
+
+// GetClusterHealth returns the health of cluster nodes
+func (c *Client) GetClusterHealth(ctx context.Context, key ops.SiteKey) (*ops.ClusterHealth, error) {
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "status", "health"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var health ops.ClusterHealth
+	if err := json.Unmarshal(out.Bytes(), &health); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &health, nil
+}
+
 func (c *Client) ResetUserPassword(req ops.ResetUserPasswordRequest) (string, error) {
 	out, err := c.PutJSON(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "reset-password"), req)
 	if err != nil {
@@ -420,6 +518,12 @@ func (c *Client) ActivateSite(req ops.ActivateSiteRequest) error {
 	return trace.Wrap(err)
 }
 
+// UpdateLicense replaces the license installed on the cluster
+func (c *Client) UpdateLicense(ctx context.Context, req ops.UpdateLicenseRequest) error {
+	_, err := c.PutJSON(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "license"), req)
+	return trace.Wrap(err)
+}
+
 // CompleteFinalInstallStep marks the site as having completed the mandatory last installation step
 func (c *Client) CompleteFinalInstallStep(req ops.CompleteFinalInstallStepRequest) error {
 	_, err := c.PostJSON(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "complete"), req)
@@ -561,6 +665,20 @@ func (c *Client) CreateClusterGarbageCollectOperation(ctx context.Context, req o
 	return &key, nil
 }
 
+// CollectPackageGarbage removes unreachable packages from the cluster package service
+func (c *Client) CollectPackageGarbage(ctx context.Context, req ops.CollectPackageGarbageRequest) (*ops.CollectPackageGarbageResponse, error) {
+	out, err := c.PostJSON(c.Endpoint("accounts", req.ClusterKey.AccountID, "sites", req.ClusterKey.SiteDomain, "packages", "gc"), req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var resp ops.CollectPackageGarbageResponse
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &resp, nil
+}
+
 // CreateUpdateEnvarsOperation creates a new operation to update cluster runtime environment variables
 func (c *Client) CreateUpdateEnvarsOperation(ctx context.Context, req ops.CreateUpdateEnvarsOperationRequest) (*ops.SiteOperationKey, error) {
 	out, err := c.PostJSON(c.Endpoint("accounts", req.ClusterKey.AccountID, "sites", req.ClusterKey.SiteDomain, "operations", "envars"), req)
@@ -589,6 +707,20 @@ func (c *Client) CreateUpdateConfigOperation(ctx context.Context, req ops.Create
 	return &key, nil
 }
 
+// CreatePatchOperation creates a new operation to apply configuration-only changes to the cluster
+func (c *Client) CreatePatchOperation(ctx context.Context, req ops.CreatePatchOperationRequest) (*ops.SiteOperationKey, error) {
+	out, err := c.PostJSON(c.Endpoint("accounts", req.ClusterKey.AccountID, "sites", req.ClusterKey.SiteDomain, "operations", "patch"), req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var key ops.SiteOperationKey
+	if err := json.Unmarshal(out.Bytes(), &key); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &key, nil
+}
+
 func (c *Client) SiteUninstallOperationStart(req ops.SiteOperationKey) error {
 	_, err := c.PostJSON(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "operations", "uninstall", req.OperationID, "start"), map[string]interface{}{})
 	if err != nil {
@@ -646,6 +778,21 @@ func (c *Client) CreateLogEntry(key ops.SiteOperationKey, entry ops.LogEntry) er
 	return nil
 }
 
+// GetOperationPhaseLogs returns the log entries recorded while executing
+// the specified phase of the operation and any of its subphases
+func (c *Client) GetOperationPhaseLogs(key ops.SiteOperationKey, phaseID string) ([]ops.LogEntry, error) {
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "operations", "common", key.OperationID, "logs", "phases"),
+		url.Values{"phase": []string{phaseID}})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var entries []ops.LogEntry
+	if err := json.Unmarshal(out.Bytes(), &entries); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return entries, nil
+}
+
 // StreamOperationLogs appends the logs from the provided reader to the
 // specified operation (user-facing) log file
 func (c *Client) StreamOperationLogs(key ops.SiteOperationKey, reader io.Reader) error {
@@ -668,6 +815,51 @@ func (c *Client) GetSiteOperationProgress(key ops.SiteOperationKey) (*ops.Progre
 	return &progressEntry, nil
 }
 
+// WatchSiteOperationProgress streams progress entries of the specified
+// operation over a websocket connection and sends them to progressC
+func (c *Client) WatchSiteOperationProgress(ctx context.Context, key ops.SiteOperationKey, progressC chan<- ops.ProgressEntry) error {
+	endpoint := c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "operations", "common", key.OperationID, "progress", "stream")
+	stream, err := httplib.SetupWebsocketClient(ctx, &c.Client, endpoint, c.dialer)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer stream.Close()
+	doneC := make(chan struct{})
+	defer close(doneC)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.Close()
+		case <-doneC:
+		}
+	}()
+	var last ops.ProgressEntry
+	decoder := json.NewDecoder(stream)
+	for {
+		var entry ops.ProgressEntry
+		err := decoder.Decode(&entry)
+		if ctx.Err() != nil {
+			return trace.Wrap(ctx.Err())
+		}
+		if err == io.EOF {
+			if last.IsCompleted() {
+				return nil
+			}
+			return trace.ConnectionProblem(nil, "progress stream closed before operation %v completed",
+				key.OperationID)
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		select {
+		case progressC <- entry:
+		case <-ctx.Done():
+			return trace.Wrap(ctx.Err())
+		}
+		last = entry
+	}
+}
+
 func (c *Client) CreateProgressEntry(key ops.SiteOperationKey, entry ops.ProgressEntry) error {
 	_, err := c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "operations", "common", key.OperationID, "progress"), entry)
 	if err != nil {
@@ -684,8 +876,12 @@ func (c *Client) GetSiteOperationCrashReport(key ops.SiteOperationKey) (io.ReadC
 	return file.Body(), nil
 }
 
-func (c *Client) GetSiteReport(key ops.SiteKey) (io.ReadCloser, error) {
-	file, err := c.GetFile(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "report"), url.Values{})
+func (c *Client) GetSiteReport(req ops.GetClusterReportRequest) (io.ReadCloser, error) {
+	file, err := c.GetFile(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "report"), url.Values{
+		"max_file_size": []string{strconv.FormatInt(req.MaxFileSize, 10)},
+		"max_size":      []string{strconv.FormatInt(req.MaxSize, 10)},
+		"operations":    []string{strconv.Itoa(req.Operations)},
+	})
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -853,6 +1049,30 @@ func (c *Client) SetOperationState(key ops.SiteOperationKey, req ops.SetOperatio
 	return nil
 }
 
+// CancelSiteOperation requests cancellation of the specified operation
+func (c *Client) CancelSiteOperation(ctx context.Context, key ops.SiteOperationKey) error {
+	_, err := c.PostJSON(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "operations", "common", key.OperationID, "cancel"),
+		key)
+	return trace.Wrap(err)
+}
+
+// PauseSiteOperation requests the specified operation to pause
+func (c *Client) PauseSiteOperation(ctx context.Context, key ops.SiteOperationKey) error {
+	_, err := c.PostJSON(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "operations", "common", key.OperationID, "pause"),
+		key)
+	return trace.Wrap(err)
+}
+
+// ResumeSiteOperation clears the pause request of the specified operation
+func (c *Client) ResumeSiteOperation(ctx context.Context, key ops.SiteOperationKey) error {
+	_, err := c.PostJSON(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "operations", "common", key.OperationID, "resume"),
+		key)
+	return trace.Wrap(err)
+}
+
 // CreateOperationPlan saves the provided operation plan
 func (c *Client) CreateOperationPlan(key ops.SiteOperationKey, plan storage.OperationPlan) error {
 	_, err := c.PostJSON(c.Endpoint(
@@ -925,6 +1145,11 @@ func (c *Client) ConfigureNode(req ops.ConfigureNodeRequest) error {
 	return trace.BadParameter("this method is only supported by local operator")
 }
 
+// RotateCertAuthority moves the cluster certificate authority to the stage specified in the request
+func (c *Client) RotateCertAuthority(req ops.RotateCertAuthorityRequest) error {
+	return trace.NotImplemented("this method is only supported by local operator")
+}
+
 // GetLogForwarders returns a list of configured log forwarders
 func (c *Client) GetLogForwarders(key ops.SiteKey) ([]storage.LogForwarder, error) {
 	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "logs", "forwarders"), url.Values{})
@@ -1048,6 +1273,68 @@ func (c *Client) DeleteSMTPConfig(key ops.SiteKey) error {
 	return trace.Wrap(err)
 }
 
+// GetHealingPolicy returns the cluster healing policy
+func (c *Client) GetHealingPolicy(key ops.SiteKey) (storage.HealingPolicy, error) {
+	response, err := c.Get(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "healing"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	policy, err := storage.UnmarshalHealingPolicy(response.Bytes())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return policy, nil
+}
+
+// UpdateHealingPolicy updates the cluster healing policy
+func (c *Client) UpdateHealingPolicy(key ops.SiteKey, policy storage.HealingPolicy) error {
+	bytes, err := storage.MarshalHealingPolicy(policy)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = c.PutJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "healing"),
+		&UpsertResourceRawReq{Resource: bytes})
+	return trace.Wrap(err)
+}
+
+// DeleteHealingPolicy deletes the cluster healing policy
+func (c *Client) DeleteHealingPolicy(key ops.SiteKey) error {
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "healing"))
+	return trace.Wrap(err)
+}
+
+// GetClusterDNS returns the cluster DNS configuration
+func (c *Client) GetClusterDNS(key ops.SiteKey) (storage.ClusterDNS, error) {
+	response, err := c.Get(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "dns"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	dns, err := storage.UnmarshalClusterDNS(response.Bytes())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return dns, nil
+}
+
+// UpdateClusterDNS updates the cluster DNS configuration
+func (c *Client) UpdateClusterDNS(key ops.SiteKey, dns storage.ClusterDNS) error {
+	bytes, err := storage.MarshalClusterDNS(dns)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = c.PutJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "dns"),
+		&UpsertResourceRawReq{Resource: bytes})
+	return trace.Wrap(err)
+}
+
+// DeleteClusterDNS resets the cluster DNS configuration to defaults
+func (c *Client) DeleteClusterDNS(key ops.SiteKey) error {
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "dns"))
+	return trace.Wrap(err)
+}
+
 // GetAlerts returns a list of monitoring alerts for the cluster
 func (c *Client) GetAlerts(key ops.SiteKey) ([]storage.Alert, error) {
 	response, err := c.Get(c.Endpoint(
@@ -1318,6 +1605,33 @@ func (c *Client) DeleteClusterCertificate(key ops.SiteKey) error {
 	return trace.Wrap(err)
 }
 
+// IssueClusterCertificate issues a new cluster certificate from the cluster certificate authority
+func (c *Client) IssueClusterCertificate(key ops.SiteKey) (*ops.ClusterCertificate, error) {
+	out, err := c.PostJSON(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "certificate", "issue"), key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var info ops.ClusterCertificate
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &info, nil
+}
+
+// CreateRotateCertificatesOperation creates a new operation to rotate cluster certificates
+func (c *Client) CreateRotateCertificatesOperation(ctx context.Context, req ops.CreateRotateCertificatesOperationRequest) (*ops.SiteOperationKey, error) {
+	out, err := c.PostJSON(c.Endpoint("accounts", req.ClusterKey.AccountID, "sites", req.ClusterKey.SiteDomain, "operations", "certificates"), req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var key ops.SiteOperationKey
+	if err := json.Unmarshal(out.Bytes(), &key); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &key, nil
+}
+
 // StepDown asks the process to pause its leader election heartbeat so it can
 // give up its leadership
 func (c *Client) StepDown(key ops.SiteKey) error {
@@ -1506,6 +1820,317 @@ func (c *Client) DeleteGithubConnector(key ops.SiteKey, name string) error {
 	return trace.Wrap(err)
 }
 
+// UpsertSAMLConnector creates or updates a SAML connector
+func (c *Client) UpsertSAMLConnector(key ops.SiteKey, connector teleservices.SAMLConnector) error {
+	data, err := teleservices.GetSAMLConnectorMarshaler().MarshalSAMLConnector(connector)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "saml", "connectors"),
+		&UpsertResourceRawReq{
+			Resource: data,
+		})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetSAMLConnector returns a SAML connector by name
+//
+// Returned connector excludes signing key unless withSecrets is true.
+func (c *Client) GetSAMLConnector(key ops.SiteKey, name string, withSecrets bool) (teleservices.SAMLConnector, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing connector name")
+	}
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "saml", "connectors", name),
+		url.Values{constants.WithSecretsParam: []string{fmt.Sprintf("%t", withSecrets)}})
+	if err != nil {
+		return nil, err
+	}
+	return teleservices.GetSAMLConnectorMarshaler().UnmarshalSAMLConnector(out.Bytes())
+}
+
+// GetSAMLConnectors returns all SAML connectors
+//
+// Returned connectors exclude signing key unless withSecrets is true.
+func (c *Client) GetSAMLConnectors(key ops.SiteKey, withSecrets bool) ([]teleservices.SAMLConnector, error) {
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "saml", "connectors"),
+		url.Values{constants.WithSecretsParam: []string{fmt.Sprintf("%t", withSecrets)}})
+	if err != nil {
+		return nil, err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(out.Bytes(), &items); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	connectors := make([]teleservices.SAMLConnector, len(items))
+	for i, raw := range items {
+		connector, err := teleservices.GetSAMLConnectorMarshaler().UnmarshalSAMLConnector(raw)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		connectors[i] = connector
+	}
+	return connectors, nil
+}
+
+// DeleteSAMLConnector deletes a SAML connector by name
+func (c *Client) DeleteSAMLConnector(key ops.SiteKey, name string) error {
+	if name == "" {
+		return trace.BadParameter("missing connector name")
+	}
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "saml", "connectors", name))
+	return trace.Wrap(err)
+}
+
+// UpsertRoleMapping creates or updates a mapping of OIDC claims to roles
+func (c *Client) UpsertRoleMapping(key ops.SiteKey, mapping storage.RoleMapping) error {
+	data, err := storage.MarshalRoleMapping(mapping)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "rolemappings"),
+		&UpsertResourceRawReq{
+			Resource: data,
+		})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetRoleMapping returns a role mapping by name
+func (c *Client) GetRoleMapping(key ops.SiteKey, name string) (storage.RoleMapping, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing role mapping name")
+	}
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "rolemappings", name),
+		url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return storage.UnmarshalRoleMapping(out.Bytes())
+}
+
+// GetRoleMappings returns all role mappings
+func (c *Client) GetRoleMappings(key ops.SiteKey) ([]storage.RoleMapping, error) {
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "rolemappings"),
+		url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(out.Bytes(), &items); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	mappings := make([]storage.RoleMapping, 0, len(items))
+	for _, raw := range items {
+		mapping, err := storage.UnmarshalRoleMapping(raw)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+// DeleteRoleMapping deletes a role mapping by name
+func (c *Client) DeleteRoleMapping(key ops.SiteKey, name string) error {
+	if name == "" {
+		return trace.BadParameter("missing role mapping name")
+	}
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "rolemappings", name))
+	return trace.Wrap(err)
+}
+
+// UpsertConfigResource creates or updates a configuration resource
+func (c *Client) UpsertConfigResource(key ops.SiteKey, resource storage.UnknownResource) error {
+	_, err := c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "resources", resource.Kind),
+		&UpsertResourceRawReq{
+			Resource: resource.Raw,
+		})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetConfigResource returns a configuration resource by kind and name
+func (c *Client) GetConfigResource(key ops.SiteKey, kind, name string) (*storage.UnknownResource, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing %v name", kind)
+	}
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "resources", kind, name),
+		url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var resource storage.UnknownResource
+	if err := json.Unmarshal(out.Bytes(), &resource); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &resource, nil
+}
+
+// GetConfigResources returns all configuration resources of the specified kind
+func (c *Client) GetConfigResources(key ops.SiteKey, kind string) ([]storage.UnknownResource, error) {
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "resources", kind),
+		url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var resources []storage.UnknownResource
+	if err := json.Unmarshal(out.Bytes(), &resources); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resources, nil
+}
+
+// DeleteConfigResource deletes a configuration resource by kind and name
+func (c *Client) DeleteConfigResource(key ops.SiteKey, kind, name string) error {
+	if name == "" {
+		return trace.BadParameter("missing %v name", kind)
+	}
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "resources", kind, name))
+	return trace.Wrap(err)
+}
+
+// UpsertWebhook creates or updates a webhook
+func (c *Client) UpsertWebhook(key ops.SiteKey, webhook storage.Webhook) error {
+	data, err := storage.MarshalWebhook(webhook)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "webhooks"),
+		&UpsertResourceRawReq{
+			Resource: data,
+		})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetWebhook returns a webhook by name
+func (c *Client) GetWebhook(key ops.SiteKey, name string) (storage.Webhook, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing webhook name")
+	}
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "webhooks", name),
+		url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return storage.UnmarshalWebhook(out.Bytes())
+}
+
+// GetWebhooks returns all webhooks
+func (c *Client) GetWebhooks(key ops.SiteKey) ([]storage.Webhook, error) {
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "webhooks"),
+		url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(out.Bytes(), &items); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	webhooks := make([]storage.Webhook, 0, len(items))
+	for _, raw := range items {
+		webhook, err := storage.UnmarshalWebhook(raw)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes a webhook by name
+func (c *Client) DeleteWebhook(key ops.SiteKey, name string) error {
+	if name == "" {
+		return trace.BadParameter("missing webhook name")
+	}
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "webhooks", name))
+	return trace.Wrap(err)
+}
+
+// UpsertProvisioner creates or updates a provisioner
+func (c *Client) UpsertProvisioner(key ops.SiteKey, provisioner storage.Provisioner) error {
+	data, err := storage.MarshalProvisioner(provisioner)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "provisioners"),
+		&UpsertResourceRawReq{
+			Resource: data,
+		})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetProvisioner returns a provisioner by name
+func (c *Client) GetProvisioner(key ops.SiteKey, name string) (storage.Provisioner, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing provisioner name")
+	}
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "provisioners", name),
+		url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return storage.UnmarshalProvisioner(out.Bytes())
+}
+
+// GetProvisioners returns all provisioners
+func (c *Client) GetProvisioners(key ops.SiteKey) ([]storage.Provisioner, error) {
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "provisioners"),
+		url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(out.Bytes(), &items); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	provisioners := make([]storage.Provisioner, 0, len(items))
+	for _, raw := range items {
+		provisioner, err := storage.UnmarshalProvisioner(raw)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		provisioners = append(provisioners, provisioner)
+	}
+	return provisioners, nil
+}
+
+// DeleteProvisioner deletes a provisioner by name
+func (c *Client) DeleteProvisioner(key ops.SiteKey, name string) error {
+	if name == "" {
+		return trace.BadParameter("missing provisioner name")
+	}
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "provisioners", name))
+	return trace.Wrap(err)
+}
+
+// ProvisionNodes creates the machines of new cluster nodes with
+// the specified provisioner and joins them to the cluster
+func (c *Client) ProvisionNodes(ctx context.Context, req ops.ProvisionNodesRequest) (*ops.ProvisionNodesResponse, error) {
+	out, err := telehttplib.ConvertResponse(c.Client.PostJSON(ctx, c.Endpoint("accounts", req.AccountID,
+		"sites", req.SiteDomain, "provisioners", req.Provisioner, "nodes"), req))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var resp ops.ProvisionNodesResponse
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &resp, nil
+}
+
 // UpsertAuthGateway updates auth gateway configuration.
 func (c *Client) UpsertAuthGateway(key ops.SiteKey, gw storage.AuthGateway) error {
 	bytes, err := storage.MarshalAuthGateway(gw)
@@ -1563,6 +2188,33 @@ func (c *Client) EmitAuditEvent(ctx context.Context, req ops.AuditEventRequest)
 	return nil
 }
 
+// GetAuditEvents returns the recorded audit events of operator API calls.
+func (c *Client) GetAuditEvents(ctx context.Context, req ops.GetAuditEventsRequest) ([]storage.AuditEvent, error) {
+	query := url.Values{}
+	if !req.Since.IsZero() {
+		query.Set("since", req.Since.Format(time.RFC3339))
+	}
+	out, err := c.Get(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "audit", "events"), query)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var events []storage.AuditEvent
+	if err := json.Unmarshal(out.Bytes(), &events); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return events, nil
+}
+
+// UploadSessionRecording stores the recording of an interactive session
+// read from the provided reader for later playback.
+func (c *Client) UploadSessionRecording(ctx context.Context, req ops.UploadSessionRecordingRequest, reader io.Reader) error {
+	_, err := c.PostStream(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "sessions", req.SessionID, "recording"), reader)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
 // PostJSON issues HTTP POST request to the server with the provided JSON data
 func (c *Client) PostJSON(endpoint string, data interface{}) (*roundtrip.Response, error) {
 	return telehttplib.ConvertResponse(c.Client.PostJSON(context.TODO(), endpoint, data))
@@ -1634,3 +2286,85 @@ func (c *Client) LocalClusterKey() (ops.SiteKey, error) {
 	}
 	return siteKey, nil
 }
+
+// CreateFleetUpgrade starts upgrading the remote clusters matching
+// the request selector to the requested application version
+func (c *Client) CreateFleetUpgrade(ctx context.Context, req ops.CreateFleetUpgradeRequest) (*storage.FleetUpgrade, error) {
+	out, err := c.PostJSON(c.Endpoint("accounts", req.AccountID, "fleetupgrades"), req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var upgrade storage.FleetUpgrade
+	if err := json.Unmarshal(out.Bytes(), &upgrade); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &upgrade, nil
+}
+
+// GetFleetUpgrade returns the fleet upgrade specified with the key
+func (c *Client) GetFleetUpgrade(ctx context.Context, key ops.FleetUpgradeKey) (*storage.FleetUpgrade, error) {
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "fleetupgrades", key.ID), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var upgrade storage.FleetUpgrade
+	if err := json.Unmarshal(out.Bytes(), &upgrade); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &upgrade, nil
+}
+
+// GetFleetUpgrades returns all fleet upgrades of the specified account
+func (c *Client) GetFleetUpgrades(ctx context.Context, accountID string) ([]storage.FleetUpgrade, error) {
+	out, err := c.Get(c.Endpoint("accounts", accountID, "fleetupgrades"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var upgrades []storage.FleetUpgrade
+	if err := json.Unmarshal(out.Bytes(), &upgrades); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return upgrades, nil
+}
+
+// CancelFleetUpgrade stops the fleet upgrade specified with the key
+func (c *Client) CancelFleetUpgrade(ctx context.Context, key ops.FleetUpgradeKey) error {
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "fleetupgrades", key.ID))
+	return trace.Wrap(err)
+}
+
+// CreateScheduledOperation queues the operation to start according
+// to the request schedule
+func (c *Client) CreateScheduledOperation(ctx context.Context, req ops.CreateScheduledOperationRequest) (*storage.ScheduledOperation, error) {
+	out, err := c.PostJSON(c.Endpoint(
+		"accounts", req.AccountID, "sites", req.ClusterName, "scheduledoperations"), req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var op storage.ScheduledOperation
+	if err := json.Unmarshal(out.Bytes(), &op); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &op, nil
+}
+
+// GetScheduledOperations returns all scheduled operations of the cluster
+func (c *Client) GetScheduledOperations(ctx context.Context, key ops.SiteKey) ([]storage.ScheduledOperation, error) {
+	out, err := c.Get(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "scheduledoperations"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var scheduled []storage.ScheduledOperation
+	if err := json.Unmarshal(out.Bytes(), &scheduled); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return scheduled, nil
+}
+
+// CancelScheduledOperation cancels the pending scheduled operation
+func (c *Client) CancelScheduledOperation(ctx context.Context, key ops.ScheduledOperationKey) error {
+	_, err := c.Delete(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.ClusterName, "scheduledoperations", key.ID))
+	return trace.Wrap(err)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opshandler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gravitational/gravity/lib/audit"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+)
+
+// audited returns a handler that records the calls that modify state
+// in the provided audit log.
+//
+// The call is recorded with the route template it has been matched with
+// instead of the request path, and the values of the route parameters
+// that carry secrets are redacted
+func audited(auditLog *audit.Log, fn ServiceHandle) ServiceHandle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+		err := fn(w, r, p, ctx)
+		if !isModifying(r.Method) {
+			return err
+		}
+		route := routeFromContext(r.Context())
+		event := storage.AuditEvent{
+			User:   ctx.User.GetName(),
+			Action: fmt.Sprintf("%v %v", r.Method, route),
+			Path:   route,
+			Params: auditParams(p),
+		}
+		event.AccountID = p.ByName("account_id")
+		if event.AccountID == "" {
+			event.AccountID = ctx.User.GetAccountID()
+		}
+		if err != nil {
+			event.Error = trace.UserMessage(err)
+		}
+		if errRecord := auditLog.Record(event); errRecord != nil {
+			auditLog.Warnf("Failed to record audit event for %v: %v.",
+				event.Action, trace.DebugReport(errRecord))
+		}
+		return err
+	}
+}
+
+// auditParams returns the route parameters to record in the audit log
+// with the values of the parameters that carry secrets redacted
+func auditParams(params httprouter.Params) map[string]string {
+	result := make(map[string]string, len(params))
+	for _, param := range params {
+		result[param.Key] = param.Value
+		if isSecretParam(param.Key, params) {
+			result[param.Key] = redacted
+		}
+	}
+	return result
+}
+
+// isSecretParam returns true if the value of the route parameter
+// with the specified name is a secret, e.g. an API key
+func isSecretParam(name string, params httprouter.Params) bool {
+	switch name {
+	case "api_key", "token":
+		return true
+	case "name":
+		// names of token resources are the tokens themselves
+		return storage.CanonicalKind(params.ByName("kind")) == storage.KindToken
+	}
+	return false
+}
+
+// withRoute returns a handler that makes the route template
+// it has been registered with available in the request context
+func withRoute(route string, handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		handle(w, r.WithContext(context.WithValue(r.Context(), routeKey, route)), p)
+	}
+}
+
+// routeFromContext returns the route template the request has been matched with
+func routeFromContext(ctx context.Context) string {
+	if route, ok := ctx.Value(routeKey).(string); ok {
+		return route
+	}
+	return unknownRoute
+}
+
+func isModifying(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+type contextKey string
+
+const (
+	// routeKey is the request context key of the matched route template
+	routeKey contextKey = "route"
+	// unknownRoute is recorded for requests without a route template
+	unknownRoute = "<unknown>"
+	// redacted replaces the values of secret route parameters
+	redacted = "<redacted>"
+)
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opshandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/audit"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/julienschmidt/httprouter"
+	. "gopkg.in/check.v1"
+)
+
+type AuditSuite struct {
+	backend storage.Backend
+	handler *WebHandler
+}
+
+var _ = Suite(&AuditSuite{})
+
+func (s *AuditSuite) SetUpTest(c *C) {
+	var err error
+	s.backend, err = keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(c.MkDir(), "bolt.db")})
+	c.Assert(err, IsNil)
+	auditLog, err := audit.New(audit.Config{Backend: s.backend})
+	c.Assert(err, IsNil)
+	user := storage.NewUser("alice@example.com", storage.UserSpecV2{})
+	handle := audited(auditLog, func(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+		return nil
+	})
+	s.handler = &WebHandler{}
+	for _, route := range []string{
+		"/portal/v1/apikeys/user/:user_email/:api_key",
+		"/portal/v1/accounts/:account_id/sites/:site_domain/tokens/join/:token",
+		"/portal/v1/accounts/:account_id/sites/:site_domain/resources/:kind/:name",
+	} {
+		s.handler.DELETE(route, func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			c.Assert(handle(w, r, p, &HandlerContext{User: user}), IsNil)
+		})
+	}
+}
+
+func (s *AuditSuite) TearDownTest(c *C) {
+	c.Assert(s.backend.Close(), IsNil)
+}
+
+func (s *AuditSuite) TestDoesNotRecordSecrets(c *C) {
+	const secret = "5ecre7-t0ken-va1ue"
+	for _, path := range []string{
+		"/portal/v1/apikeys/user/alice@example.com/" + secret,
+		"/portal/v1/accounts/acme/sites/example.com/tokens/join/" + secret,
+		"/portal/v1/accounts/acme/sites/example.com/resources/tokens/" + secret,
+	} {
+		request := httptest.NewRequest(http.MethodDelete, path, nil)
+		s.handler.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	events, err := s.backend.GetAuditEvents(time.Time{})
+	c.Assert(err, IsNil)
+	c.Assert(events, HasLen, 3)
+	var actions []string
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		c.Assert(err, IsNil)
+		c.Assert(strings.Contains(string(data), secret), Equals, false,
+			Commentf("event contains secret: %s", data))
+		c.Assert(event.Path, Equals, strings.TrimPrefix(event.Action, "DELETE "))
+		actions = append(actions, event.Action)
+	}
+	c.Assert(actions, DeepEquals, []string{
+		"DELETE /portal/v1/apikeys/user/:user_email/:api_key",
+		"DELETE /portal/v1/accounts/:account_id/sites/:site_domain/tokens/join/:token",
+		"DELETE /portal/v1/accounts/:account_id/sites/:site_domain/resources/:kind/:name",
+	})
+}
+
+func (s *AuditSuite) TestRecordsRouteParams(c *C) {
+	request := httptest.NewRequest(http.MethodDelete,
+		"/portal/v1/accounts/acme/sites/example.com/resources/logforwarder/forwarder1", nil)
+	s.handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	events, err := s.backend.GetAuditEvents(time.Time{})
+	c.Assert(err, IsNil)
+	c.Assert(events, HasLen, 1)
+	c.Assert(events[0].AccountID, Equals, "acme")
+	c.Assert(events[0].Params, DeepEquals, map[string]string{
+		"account_id":  "acme",
+		"site_domain": "example.com",
+		"kind":        "logforwarder",
+		"name":        "forwarder1",
+	})
+}
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/audit"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/httplib"
@@ -64,6 +65,8 @@ type WebHandlerConfig struct {
 	Authenticator httplib.Authenticator
 	// Devmode is whether the process is started in dev mode
 	Devmode bool
+	// Audit is an optional audit log of the API calls
+	Audit *audit.Log
 }
 
 type WebHandler struct {
@@ -71,6 +74,26 @@ type WebHandler struct {
 	cfg WebHandlerConfig
 }
 
+// POST registers the handler for POST requests on the specified route
+func (h *WebHandler) POST(path string, handle httprouter.Handle) {
+	h.Router.POST(path, withRoute(path, handle))
+}
+
+// PUT registers the handler for PUT requests on the specified route
+func (h *WebHandler) PUT(path string, handle httprouter.Handle) {
+	h.Router.PUT(path, withRoute(path, handle))
+}
+
+// PATCH registers the handler for PATCH requests on the specified route
+func (h *WebHandler) PATCH(path string, handle httprouter.Handle) {
+	h.Router.PATCH(path, withRoute(path, handle))
+}
+
+// DELETE registers the handler for DELETE requests on the specified route
+func (h *WebHandler) DELETE(path string, handle httprouter.Handle) {
+	h.Router.DELETE(path, withRoute(path, handle))
+}
+
 // GetConfig returns config web handler was initialized with
 func (h *WebHandler) GetConfig() WebHandlerConfig {
 	return h.cfg
@@ -105,6 +128,7 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	// Accounts API
 	h.POST("/portal/v1/accounts", h.needsAuth(h.createAccount))
 	h.GET("/portal/v1/accounts/:account_id", h.needsAuth(h.getAccount))
+	h.DELETE("/portal/v1/accounts/:account_id", h.needsAuth(h.deleteAccount))
 	h.GET("/portal/v1/accounts", h.needsAuth(h.getAccounts))
 
 	// Users API
@@ -130,6 +154,9 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/tokens/provision", h.needsAuth(h.createProvisioningToken))
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/tokens/expand", h.needsAuth(h.getExpandToken))
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/tokens/trustedcluster", h.needsAuth(h.getTrustedClusterToken))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/tokens/join", h.needsAuth(h.createJoinToken))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/tokens/join", h.needsAuth(h.getJoinTokens))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/tokens/join/:token", h.needsAuth(h.deleteJoinToken))
 
 	// Sites API
 	h.GET("/portal/v1/localsite", h.needsAuth(h.getLocalSite))
@@ -140,6 +167,7 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/report", h.needsAuth(h.getSiteReport))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/deactivate", h.needsAuth(h.deactivateSite))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/activate", h.needsAuth(h.activateSite))
+	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/license", h.needsAuth(h.updateLicense))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/complete", h.needsAuth(h.completeFinalInstallStep))
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/localuser", h.needsAuth(h.getLocalUser))
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/reset-password", h.needsAuth(h.resetUserPassword))
@@ -148,6 +176,10 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 
 	// Status API
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/status", h.needsAuth(h.checkSiteStatus))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/status/history", h.needsAuth(h.getClusterStatusHistory))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/status/registry", h.needsAuth(h.getRegistryStatus))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/status/packages", h.needsAuth(h.getPackageVerificationStatus))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/status/health", h.needsAuth(h.getClusterHealth))
 
 	// TODO(klizhetas) refactor this method
 	h.GET("/portal/v1/sites/domain/:domain", h.needsAuth(h.getSiteByDomain))
@@ -164,6 +196,8 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/certificate", h.needsAuth(h.getClusterCert))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/certificate", h.needsAuth(h.updateClusterCert))
 	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/certificate", h.needsAuth(h.deleteClusterCert))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/certificate/issue", h.needsAuth(h.issueClusterCert))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/certificates", h.needsAuth(h.createRotateCertificatesOperation))
 
 	// Prechecks API
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/prechecks", h.needsAuth(h.validateServers))
@@ -195,6 +229,7 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 
 	// garbage collection
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/gc", h.needsAuth(h.createClusterGarbageCollectOperation))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/packages/gc", h.needsAuth(h.collectPackageGarbage))
 
 	// update - update installed application to a new version
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/update", h.needsAuth(h.createSiteUpdateOperation))
@@ -206,11 +241,16 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id", h.needsAuth(h.deleteOperation))
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/logs", h.needsAuth(h.getSiteOperationLogs))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/logs/entry", h.needsAuth(h.createLogEntry))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/logs/phases", h.needsAuth(h.getOperationPhaseLogs))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/logs", h.needsAuth(h.streamOperationLogs))
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/progress", h.needsAuth(h.getSiteOperationProgress))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/progress", h.needsAuth(h.createProgressEntry))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/progress/stream", h.needsAuth(h.watchSiteOperationProgress))
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/crash-report", h.needsAuth(h.getSiteOperationCrashReport))
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/complete", h.needsAuth(h.completeSiteOperation))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/cancel", h.needsAuth(h.cancelSiteOperation))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/pause", h.needsAuth(h.pauseSiteOperation))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/resume", h.needsAuth(h.resumeSiteOperation))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/plan", h.needsAuth(h.createOperationPlan))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/plan/changelog", h.needsAuth(h.createOperationPlanChange))
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/plan", h.needsAuth(h.getOperationPlan))
@@ -228,6 +268,16 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/smtp", h.needsAuth(h.updateSMTPConfig))
 	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/smtp", h.needsAuth(h.deleteSMTPConfig))
 
+	// healing policy
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/healing", h.needsAuth(h.getHealingPolicy))
+	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/healing", h.needsAuth(h.updateHealingPolicy))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/healing", h.needsAuth(h.deleteHealingPolicy))
+
+	// cluster DNS
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/dns", h.needsAuth(h.getClusterDNS))
+	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/dns", h.needsAuth(h.updateClusterDNS))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/dns", h.needsAuth(h.deleteClusterDNS))
+
 	// monitoring
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/retention", h.needsAuth(h.getRetentionPolicies))
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/retention", h.needsAuth(h.updateRetentionPolicy))
@@ -247,6 +297,7 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/config", h.needsAuth(h.getClusterConfiguration))
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/config", h.needsAuth(h.updateClusterConfig))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/config", h.needsAuth(h.createUpdateConfigOperation))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/patch", h.needsAuth(h.createPatchOperation))
 
 	// validation
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/validation/remoteaccess", h.needsAuth(h.validateRemoteAccess))
@@ -270,6 +321,56 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/github/connectors/:id",
 		h.needsAuth(h.deleteGithubConnector))
 
+	// SAML connector handlers
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/saml/connectors",
+		h.needsAuth(h.upsertSAMLConnector))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/saml/connectors/:id",
+		h.needsAuth(h.getSAMLConnector))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/saml/connectors",
+		h.needsAuth(h.getSAMLConnectors))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/saml/connectors/:id",
+		h.needsAuth(h.deleteSAMLConnector))
+
+	// role mapping handlers
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/rolemappings",
+		h.needsAuth(h.upsertRoleMapping))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/rolemappings/:name",
+		h.needsAuth(h.getRoleMapping))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/rolemappings",
+		h.needsAuth(h.getRoleMappings))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/rolemappings/:name",
+		h.needsAuth(h.deleteRoleMapping))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/resources/:kind",
+		h.needsAuth(h.upsertConfigResource))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/resources/:kind/:name",
+		h.needsAuth(h.getConfigResource))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/resources/:kind",
+		h.needsAuth(h.getConfigResources))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/resources/:kind/:name",
+		h.needsAuth(h.deleteConfigResource))
+
+	// webhook handlers
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/webhooks",
+		h.needsAuth(h.upsertWebhook))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/webhooks/:name",
+		h.needsAuth(h.getWebhook))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/webhooks",
+		h.needsAuth(h.getWebhooks))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/webhooks/:name",
+		h.needsAuth(h.deleteWebhook))
+
+	// provisioner handlers
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/provisioners",
+		h.needsAuth(h.upsertProvisioner))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/provisioners/:name",
+		h.needsAuth(h.getProvisioner))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/provisioners",
+		h.needsAuth(h.getProvisioners))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/provisioners/:name",
+		h.needsAuth(h.deleteProvisioner))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/provisioners/:name/nodes",
+		h.needsAuth(h.provisionNodes))
+
 	// user handlers
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/users",
 		h.needsAuth(h.upsertUser))
@@ -299,6 +400,28 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	// audit log events
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/events",
 		h.needsAuth(h.emitAuditEvent))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/audit/events",
+		h.needsAuth(h.getAuditEvents))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/sessions/:session_id/recording",
+		h.needsAuth(h.uploadSessionRecording))
+
+	// fleet upgrades
+	h.POST("/portal/v1/accounts/:account_id/fleetupgrades",
+		h.needsAuth(h.createFleetUpgrade))
+	h.GET("/portal/v1/accounts/:account_id/fleetupgrades",
+		h.needsAuth(h.getFleetUpgrades))
+	h.GET("/portal/v1/accounts/:account_id/fleetupgrades/:id",
+		h.needsAuth(h.getFleetUpgrade))
+	h.DELETE("/portal/v1/accounts/:account_id/fleetupgrades/:id",
+		h.needsAuth(h.cancelFleetUpgrade))
+
+	// scheduled operations
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/scheduledoperations",
+		h.needsAuth(h.createScheduledOperation))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/scheduledoperations",
+		h.needsAuth(h.getScheduledOperations))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/scheduledoperations/:id",
+		h.needsAuth(h.cancelScheduledOperation))
 
 	return h, nil
 }
@@ -372,7 +495,10 @@ func (h *WebHandler) getApps(w http.ResponseWriter, r *http.Request, p httproute
 
 /* getGravityBinary exports the cluster's gravity binary.
 
-   GET /portal/v1/gravity
+   GET /portal/v1/gravity?arch=<arch>
+
+   arch is the optional CPU architecture of the binary either in Go
+   notation (arm64) or as reported by uname -m (aarch64), defaults to amd64
 */
 func (h *WebHandler) getGravityBinary(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
 	cluster, err := ctx.Operator.GetLocalSite()
@@ -383,7 +509,11 @@ func (h *WebHandler) getGravityBinary(w http.ResponseWriter, r *http.Request, p
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	_, reader, err := h.cfg.Packages.ReadPackage(*gravityPackage)
+	arch, err := archFromQuery(cluster.App.Manifest, r.URL.Query().Get("arch"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, reader, err := h.cfg.Packages.ReadPackage(schema.PackageForArch(*gravityPackage, arch))
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -394,6 +524,22 @@ func (h *WebHandler) getGravityBinary(w http.ResponseWriter, r *http.Request, p
 	return trace.Wrap(err)
 }
 
+// archFromQuery returns the CPU architecture specified with the query
+// parameter value in Go notation.
+// Returns an error if the architecture is not supported by the cluster image
+func archFromQuery(manifest schema.Manifest, value string) (string, error) {
+	if value == "" {
+		return schema.ArchAMD64, nil
+	}
+	for _, arch := range manifest.Architectures() {
+		if value == arch || value == schema.MachineArch(arch) {
+			return arch, nil
+		}
+	}
+	return "", trace.BadParameter("architecture %q is not supported by the cluster image, supported architectures: %v",
+		value, manifest.Architectures())
+}
+
 /*  inviteUser resets user credentials and returns a user token
 
     POST /portal/v1/accounts/:account_id/sites/:site_domain/usertokens/resets
@@ -517,6 +663,24 @@ func (h *WebHandler) getAccount(w http.ResponseWriter, r *http.Request, p httpro
 	return nil
 }
 
+/* deleteAccount deletes the account with the specified ID
+
+   DELETE /portal/v1/accounts/:account_id
+
+Success response:
+  {
+     "message": "account deleted"
+  }
+
+*/
+func (h *WebHandler) deleteAccount(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	if err := context.Operator.DeleteAccount(p.ByName("account_id")); err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("account deleted"))
+	return nil
+}
+
 /* getAccounts returns a list of accounts in the system
 
    GET /portal/v1/accounts/:account_id
@@ -771,6 +935,62 @@ func (h *WebHandler) getExpandToken(w http.ResponseWriter, r *http.Request, p ht
 	return nil
 }
 
+/*  createJoinToken creates a new join token limited in time, number of uses
+    or roles of the joining nodes
+
+    POST /portal/v1/accounts/:account_id/sites/:site_domain/tokens/join
+
+    Success response:
+
+    storage.ProvisioningToken
+*/
+func (h *WebHandler) createJoinToken(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req ops.CreateJoinTokenRequest
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	req.SiteKey = siteKey(p)
+	token, err := context.Operator.CreateJoinToken(r.Context(), req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, token)
+	return nil
+}
+
+/*  getJoinTokens returns the active scoped join tokens of the cluster
+
+    GET /portal/v1/accounts/:account_id/sites/:site_domain/tokens/join
+
+    Success response:
+
+    []storage.ProvisioningToken
+*/
+func (h *WebHandler) getJoinTokens(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	tokens, err := context.Operator.GetJoinTokens(r.Context(), siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, tokens)
+	return nil
+}
+
+/*  deleteJoinToken revokes the specified scoped join token
+
+    DELETE /portal/v1/accounts/:account_id/sites/:site_domain/tokens/join/:token
+*/
+func (h *WebHandler) deleteJoinToken(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.DeleteJoinToken(r.Context(), ops.DeleteJoinTokenRequest{
+		SiteKey: siteKey(p),
+		Token:   p.ByName("token"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("join token revoked"))
+	return nil
+}
+
 /*  getTrustedClusterToken returns the cluster's trusted cluster token
 
     GET /portal/v1/accounts/:account_id/tokens/trustedcluster
@@ -1000,12 +1220,39 @@ func (h *WebHandler) activateSite(w http.ResponseWriter, r *http.Request, p http
 	return nil
 }
 
+/*  updateLicense replaces the license installed on the cluster
+
+    PUT /portal/v1/accounts/:account_id/sites/:site_domain/license
+
+    Input: ops.UpdateLicenseRequest
+
+    Success response:
+    {
+      "message": "license updated"
+    }
+*/
+func (h *WebHandler) updateLicense(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req ops.UpdateLicenseRequest
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := context.Operator.UpdateLicense(r.Context(), req); err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("license updated"))
+	return nil
+}
+
 /* getSiteReport returns a tarball with collected information about the site
 
-   GET /portal/v1/accounts/:account_id/sites/:site_domain/report
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/report?max_file_size=<bytes>&max_size=<bytes>&operations=<count>
 */
 func (h *WebHandler) getSiteReport(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
-	report, err := context.Operator.GetSiteReport(siteKey(p))
+	req, err := parseClusterReportRequest(r, siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	report, err := context.Operator.GetSiteReport(*req)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -1224,6 +1471,75 @@ func (h *WebHandler) checkSiteStatus(w http.ResponseWriter, r *http.Request, p h
 	return nil
 }
 
+/*  getClusterStatusHistory returns the recorded status snapshots of the cluster
+
+    GET /portal/v1/accounts/:account_id/sites/:site_domain/status/history?since=<RFC3339 time>
+
+    Success response: []storage.StatusSnapshot
+*/
+func (h *WebHandler) getClusterStatusHistory(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	req := ops.GetClusterStatusHistoryRequest{SiteKey: siteKey(p)}
+	if since := r.URL.Query().Get("since"); since != "" {
+		var err error
+		req.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return trace.BadParameter("invalid since time %q: %v", since, err)
+		}
+	}
+	snapshots, err := context.Operator.GetClusterStatusHistory(r.Context(), req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, snapshots)
+	return nil
+}
+
+/*  getRegistryStatus returns the health and the storage usage of the cluster registry
+
+    GET /portal/v1/accounts/:account_id/sites/:site_domain/status/registry
+
+    Success response: ops.RegistryStatus
+*/
+func (h *WebHandler) getRegistryStatus(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	status, err := context.Operator.GetRegistryStatus(r.Context(), siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, status)
+	return nil
+}
+
+/*  getPackageVerificationStatus returns the results of the last integrity
+    verification of the cluster packages
+
+    GET /portal/v1/accounts/:account_id/sites/:site_domain/status/packages
+
+    Success response: ops.PackageVerificationStatus
+*/
+func (h *WebHandler) getPackageVerificationStatus(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	status, err := context.Operator.GetPackageVerificationStatus(r.Context(), siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, status)
+	return nil
+}
+
+/*  getClusterHealth returns the health of cluster nodes with the reasons of failed probes
+
+    GET /portal/v1/accounts/:account_id/sites/:site_domain/status/health
+
+    Success response: ops.ClusterHealth
+*/
+func (h *WebHandler) getClusterHealth(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	health, err := context.Operator.GetClusterHealth(r.Context(), siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, health)
+	return nil
+}
+
 /*  validateDomainName checks if the specified domain name has already been allocated
 
     GET /portal/v1/domains/:domain
@@ -1477,6 +1793,66 @@ func (h *WebHandler) completeSiteOperation(w http.ResponseWriter, r *http.Reques
 	return nil
 }
 
+/* cancelSiteOperation requests cancellation of the specified operation
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/cancel
+
+Success response:
+
+   {
+      "status": "ok",
+      "message": "operation cancellation requested"
+   }
+*/
+func (h *WebHandler) cancelSiteOperation(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.CancelSiteOperation(r.Context(), siteOperationKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("operation cancellation requested"))
+	return nil
+}
+
+/* pauseSiteOperation requests the specified operation to pause
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/pause
+
+Success response:
+
+   {
+      "status": "ok",
+      "message": "operation pause requested"
+   }
+*/
+func (h *WebHandler) pauseSiteOperation(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.PauseSiteOperation(r.Context(), siteOperationKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("operation pause requested"))
+	return nil
+}
+
+/* resumeSiteOperation clears the pause request of the specified operation
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/resume
+
+Success response:
+
+   {
+      "status": "ok",
+      "message": "operation resumed"
+   }
+*/
+func (h *WebHandler) resumeSiteOperation(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.ResumeSiteOperation(r.Context(), siteOperationKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("operation resumed"))
+	return nil
+}
+
 /* createOperationPlan saves the provided operation plan
 
    POST /portal/v1/accos/:account_id/sites/:site_domain/operations/common/:operation_id/plan
@@ -1646,6 +2022,10 @@ func (h *WebHandler) createSiteExpandOperation(w http.ResponseWriter, r *http.Re
 	key := siteKey(p)
 	req.AccountID = key.AccountID
 	req.SiteDomain = key.SiteDomain
+	if context.Token != "" {
+		// validate the token the node has actually authenticated with
+		req.JoinToken = context.Token
+	}
 	if req.Provisioner == "" {
 		installOp, err := ops.GetCompletedInstallOperation(key, context.Operator)
 		if err != nil {
@@ -1664,6 +2044,38 @@ func (h *WebHandler) createSiteExpandOperation(w http.ResponseWriter, r *http.Re
 	return nil
 }
 
+/* collectPackageGarbage removes unreachable packages from the cluster package service
+
+   POST	/portal/v1/accounts/:account_id/sites/:site_domain/packages/gc
+
+   {
+      "dry_run": false,
+      "protection_window": 86400000000000
+   }
+
+
+Success response:
+
+   {
+      "deleted": ["gravitational.io/planet:5.0.0"],
+      "protected": [],
+      "reclaimed_bytes": 1024
+   }
+*/
+func (h *WebHandler) collectPackageGarbage(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req ops.CollectPackageGarbageRequest
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	req.ClusterKey = siteKey(p)
+	resp, err := context.Operator.CollectPackageGarbage(r.Context(), req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, resp)
+	return nil
+}
+
 /*getSiteExpandOperationAgent report is used for on prem installations and displays the
 information collected by the runtime agents started by user on hosts about server and
 their parameters so user can configure it
@@ -1787,6 +2199,26 @@ func (h *WebHandler) createLogEntry(w http.ResponseWriter, r *http.Request, p ht
 	return nil
 }
 
+/* getOperationPhaseLogs returns the log entries recorded while executing
+   the specified phase of the operation and any of its subphases
+
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/logs/phases?phase=<phase ID>
+
+   Success response: []ops.LogEntry
+*/
+func (h *WebHandler) getOperationPhaseLogs(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	phaseID := r.URL.Query().Get("phase")
+	if phaseID == "" {
+		return trace.BadParameter("missing phase ID")
+	}
+	entries, err := context.Operator.GetOperationPhaseLogs(siteOperationKey(p), phaseID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, entries)
+	return nil
+}
+
 /* streamOperationLogs appends the logs from the provided reader to the
    specified operation (user-facing) log file
 
@@ -1840,6 +2272,26 @@ func (h *WebHandler) getSiteOperationProgress(w http.ResponseWriter, r *http.Req
 	return nil
 }
 
+/*watchSiteOperationProgress is a web socket method that streams progress entries of this operation
+
+  GET /portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/progress/stream
+
+Each progress entry is sent as a separate JSON-encoded frame when it changes.
+The stream is closed once the operation completes
+*/
+func (h *WebHandler) watchSiteOperationProgress(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	ws := &httplib.WebSocketJSONWriter{
+		Stream: func(streamCtx context.Context, send func(interface{}) error) error {
+			return ops.SendOperationProgress(streamCtx, siteOperationKey(p), ctx.Operator,
+				func(entry ops.ProgressEntry) error {
+					return send(entry)
+				})
+		},
+	}
+	ws.Handler().ServeHTTP(w, r)
+	return nil
+}
+
 /* createProgressEntry creates a new operation progress entry
 
    POST /portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/progress
@@ -2148,6 +2600,132 @@ func (h *WebHandler) deleteSMTPConfig(w http.ResponseWriter, r *http.Request, p
 	return nil
 }
 
+/* getHealingPolicy returns the cluster healing policy
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/healing
+
+   Success Response:
+
+     storage.HealingPolicy
+*/
+func (h *WebHandler) getHealingPolicy(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	policy, err := context.Operator.GetHealingPolicy(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, policy)
+	return nil
+}
+
+/* updateHealingPolicy updates the cluster healing policy
+
+     PUT /portal/v1/accounts/:account_id/sites/:site_domain/healing
+
+   Success Response:
+
+     {
+       "message": "healing policy updated"
+     }
+*/
+func (h *WebHandler) updateHealingPolicy(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	policy, err := storage.UnmarshalHealingPolicy(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = context.Operator.UpdateHealingPolicy(siteKey(p), policy)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("healing policy updated"))
+	return nil
+}
+
+/* deleteHealingPolicy deletes the cluster healing policy
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/healing
+
+   Success Response:
+
+     {
+       "message": "healing policy deleted"
+     }
+*/
+func (h *WebHandler) deleteHealingPolicy(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.DeleteHealingPolicy(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("healing policy deleted"))
+	return nil
+}
+
+/* getClusterDNS returns the cluster DNS configuration
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/dns
+
+   Success Response:
+
+     storage.ClusterDNS
+*/
+func (h *WebHandler) getClusterDNS(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	dns, err := context.Operator.GetClusterDNS(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, dns)
+	return nil
+}
+
+/* updateClusterDNS updates the cluster DNS configuration
+
+     PUT /portal/v1/accounts/:account_id/sites/:site_domain/dns
+
+   Success Response:
+
+     {
+       "message": "cluster DNS configuration updated"
+     }
+*/
+func (h *WebHandler) updateClusterDNS(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	dns, err := storage.UnmarshalClusterDNS(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = context.Operator.UpdateClusterDNS(siteKey(p), dns)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("cluster DNS configuration updated"))
+	return nil
+}
+
+/* deleteClusterDNS resets the cluster DNS configuration to defaults
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/dns
+
+   Success Response:
+
+     {
+       "message": "cluster DNS configuration deleted"
+     }
+*/
+func (h *WebHandler) deleteClusterDNS(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.DeleteClusterDNS(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("cluster DNS configuration deleted"))
+	return nil
+}
+
 /* getApplicationEndpoints returns application endpoints for a deployed cluster
 
      GET /portal/v1/accounts/:account_id/sites/:site_domain/endpoints
@@ -2270,6 +2848,23 @@ func (h *WebHandler) deleteClusterCert(w http.ResponseWriter, r *http.Request, p
 	return nil
 }
 
+/* issueClusterCert issues a new cluster certificate from the cluster certificate authority
+
+     POST /portal/v1/accounts/:account_id/sites/:site_domain/certificate/issue
+
+   Success Response:
+
+     ops.ClusterCertificate
+*/
+func (h *WebHandler) issueClusterCert(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	cert, err := context.Operator.IssueClusterCertificate(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, cert)
+	return nil
+}
+
 /* emitAuditEvent saves the provided event in the audit log.
 
      POST /portal/v1/accounts/:account_id/sites/:site_domain/events
@@ -2289,6 +2884,206 @@ func (h *WebHandler) emitAuditEvent(w http.ResponseWriter, r *http.Request, p ht
 	return nil
 }
 
+/* getAuditEvents returns the recorded audit events of operator API calls.
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/audit/events?since=<RFC3339 time>
+
+   Success response:
+
+     [{"id": "...", "time": "...", "user": "...", "action": "POST /portal/v1/...", ...}, ...]
+*/
+func (h *WebHandler) getAuditEvents(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	req := ops.GetAuditEventsRequest{SiteKey: siteKey(p)}
+	if since := r.URL.Query().Get("since"); since != "" {
+		var err error
+		req.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return trace.BadParameter("invalid since time %q: %v", since, err)
+		}
+	}
+	events, err := ctx.Operator.GetAuditEvents(r.Context(), req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, events)
+	return nil
+}
+
+/* uploadSessionRecording stores the recording of an interactive session
+   streamed in the request body.
+
+     POST /portal/v1/accounts/:account_id/sites/:site_domain/sessions/:session_id/recording
+
+   Success response:
+
+     { "message": "session recording uploaded" }
+*/
+func (h *WebHandler) uploadSessionRecording(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	err := ctx.Operator.UploadSessionRecording(r.Context(), ops.UploadSessionRecordingRequest{
+		SiteKey:   siteKey(p),
+		SessionID: p.ByName("session_id"),
+	}, r.Body)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, message("session recording uploaded"))
+	return nil
+}
+
+/* createFleetUpgrade starts upgrading the remote clusters matching
+   the request selector to the requested application version.
+
+     POST /portal/v1/accounts/:account_id/fleetupgrades
+
+   Input: ops.CreateFleetUpgradeRequest
+
+   Success response:
+
+     storage.FleetUpgrade
+*/
+func (h *WebHandler) createFleetUpgrade(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	var req ops.CreateFleetUpgradeRequest
+	err := telehttplib.ReadJSON(r, &req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req.AccountID = p.ByName("account_id")
+	upgrade, err := ctx.Operator.CreateFleetUpgrade(r.Context(), req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, upgrade)
+	return nil
+}
+
+/* getFleetUpgrades returns all fleet upgrades of the account.
+
+     GET /portal/v1/accounts/:account_id/fleetupgrades
+
+   Success response:
+
+     []storage.FleetUpgrade
+*/
+func (h *WebHandler) getFleetUpgrades(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	upgrades, err := ctx.Operator.GetFleetUpgrades(r.Context(), p.ByName("account_id"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, upgrades)
+	return nil
+}
+
+/* getFleetUpgrade returns the fleet upgrade with the specified ID.
+
+     GET /portal/v1/accounts/:account_id/fleetupgrades/:id
+
+   Success response:
+
+     storage.FleetUpgrade
+*/
+func (h *WebHandler) getFleetUpgrade(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	upgrade, err := ctx.Operator.GetFleetUpgrade(r.Context(), ops.FleetUpgradeKey{
+		AccountID: p.ByName("account_id"),
+		ID:        p.ByName("id"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, upgrade)
+	return nil
+}
+
+/* cancelFleetUpgrade stops the fleet upgrade with the specified ID.
+
+     DELETE /portal/v1/accounts/:account_id/fleetupgrades/:id
+
+   Success response:
+
+     {
+       "status": "ok",
+       "message": "fleet upgrade canceled"
+     }
+*/
+func (h *WebHandler) cancelFleetUpgrade(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	err := ctx.Operator.CancelFleetUpgrade(r.Context(), ops.FleetUpgradeKey{
+		AccountID: p.ByName("account_id"),
+		ID:        p.ByName("id"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("fleet upgrade canceled"))
+	return nil
+}
+
+/* createScheduledOperation queues the operation to start according
+   to the request schedule.
+
+     POST /portal/v1/accounts/:account_id/sites/:site_domain/scheduledoperations
+
+   Input: ops.CreateScheduledOperationRequest
+
+   Success response:
+
+     storage.ScheduledOperation
+*/
+func (h *WebHandler) createScheduledOperation(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	var req ops.CreateScheduledOperationRequest
+	err := telehttplib.ReadJSON(r, &req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req.AccountID = p.ByName("account_id")
+	req.ClusterName = p.ByName("site_domain")
+	op, err := ctx.Operator.CreateScheduledOperation(r.Context(), req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, op)
+	return nil
+}
+
+/* getScheduledOperations returns all scheduled operations of the cluster.
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/scheduledoperations
+
+   Success response:
+
+     []storage.ScheduledOperation
+*/
+func (h *WebHandler) getScheduledOperations(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	scheduled, err := ctx.Operator.GetScheduledOperations(r.Context(), siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, scheduled)
+	return nil
+}
+
+/* cancelScheduledOperation cancels the pending scheduled operation.
+
+     DELETE /portal/v1/accounts/:account_id/sites/:site_domain/scheduledoperations/:id
+
+   Success response:
+
+     {
+       "status": "ok",
+       "message": "scheduled operation canceled"
+     }
+*/
+func (h *WebHandler) cancelScheduledOperation(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	err := ctx.Operator.CancelScheduledOperation(r.Context(), ops.ScheduledOperationKey{
+		AccountID:   p.ByName("account_id"),
+		ClusterName: p.ByName("site_domain"),
+		ID:          p.ByName("id"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("scheduled operation canceled"))
+	return nil
+}
+
 func (s *WebHandler) wrap(fn func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		if err := fn(w, r, p); err != nil {
@@ -2298,6 +3093,9 @@ func (s *WebHandler) wrap(fn func(w http.ResponseWriter, r *http.Request, p http
 }
 
 func (s *WebHandler) needsAuth(fn ServiceHandle) httprouter.Handle {
+	if s.cfg.Audit != nil {
+		fn = audited(s.cfg.Audit, fn)
+	}
 	return NeedsAuth(s.cfg.Devmode, s.cfg.Backend, s.cfg.Operator, s.cfg.Authenticator, s.cfg.Users, fn)
 }
 
@@ -2475,3 +3273,29 @@ func siteKey(p httprouter.Params) ops.SiteKey {
 		SiteDomain: p[1].Value,
 	}
 }
+
+// parseClusterReportRequest returns the cluster report request
+// from the query parameters of the specified HTTP request
+func parseClusterReportRequest(r *http.Request, key ops.SiteKey) (*ops.GetClusterReportRequest, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	req := ops.GetClusterReportRequest{SiteKey: key}
+	var err error
+	if value := r.Form.Get("max_file_size"); value != "" {
+		if req.MaxFileSize, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, trace.BadParameter("invalid max_file_size %q", value)
+		}
+	}
+	if value := r.Form.Get("max_size"); value != "" {
+		if req.MaxSize, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, trace.BadParameter("invalid max_size %q", value)
+		}
+	}
+	if value := r.Form.Get("operations"); value != "" {
+		if req.Operations, err = strconv.Atoi(value); err != nil {
+			return nil, trace.BadParameter("invalid operations %q", value)
+		}
+	}
+	return &req, nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package opshandler
 
 import (
+	"context"
 	"net/http/httptest"
 	"os"
 	"strconv"
@@ -24,12 +25,14 @@ import (
 	"time"
 
 	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/ops/opsclient"
 	"github.com/gravitational/gravity/lib/ops/opsservice"
 	"github.com/gravitational/gravity/lib/ops/suite"
+	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/users"
 
@@ -88,8 +91,9 @@ func (s *OpsHandlerSuite) SetUpTest(c *C) {
 	}))
 	c.Assert(err, IsNil)
 
-	_, err = s.suite.SetUpTestPackage(services.Apps, services.Packages, c)
+	app, err := s.suite.SetUpTestPackage(services.Apps, services.Packages, c)
 	c.Assert(err, IsNil)
+	s.testApp = *app
 
 	handler, err := NewWebHandler(WebHandlerConfig{
 		Users:        s.users,
@@ -354,3 +358,58 @@ func (s *OpsHandlerSuite) TestClusterAuthConfiguration(c *C) {
 	c.Assert(actual.GetType(), Equals, cap.GetType())
 	c.Assert(actual.GetSecondFactor(), Equals, cap.GetSecondFactor())
 }
+
+func (s *OpsHandlerSuite) TestWatchOperationProgress(c *C) {
+	account, err := s.client.CreateAccount(ops.NewAccountRequest{Org: "example.com"})
+	c.Assert(err, IsNil)
+	site, err := s.client.CreateSite(ops.NewSiteRequest{
+		AppPackage: s.testApp.String(),
+		AccountID:  account.ID,
+		Provider:   schema.ProviderOnPrem,
+		DomainName: "example.com",
+	})
+	c.Assert(err, IsNil)
+	key := ops.SiteOperationKey{
+		AccountID:   account.ID,
+		SiteDomain:  site.Domain,
+		OperationID: "operation-1",
+	}
+	created := time.Now().UTC()
+	newEntry := func(completion int, message string) ops.ProgressEntry {
+		created = created.Add(time.Second)
+		return ops.ProgressEntry{
+			SiteDomain:  key.SiteDomain,
+			OperationID: key.OperationID,
+			Completion:  completion,
+			Message:     message,
+			Created:     created,
+		}
+	}
+	c.Assert(s.client.CreateProgressEntry(key, newEntry(10, "started")), IsNil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	progressC := make(chan ops.ProgressEntry)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- s.client.WatchSiteOperationProgress(ctx, key, progressC)
+	}()
+
+	var messages []string
+	for {
+		select {
+		case entry := <-progressC:
+			messages = append(messages, entry.Message)
+			switch entry.Message {
+			case "started":
+				c.Assert(s.client.CreateProgressEntry(key, newEntry(50, "running")), IsNil)
+			case "running":
+				c.Assert(s.client.CreateProgressEntry(key, newEntry(constants.Completed, "completed")), IsNil)
+			}
+		case err := <-errC:
+			c.Assert(err, IsNil)
+			c.Assert(messages, DeepEquals, []string{"started", "running", "completed"})
+			return
+		}
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opshandler
+
+import (
+	"net/http"
+
+	"github.com/gravitational/gravity/lib/ops"
+
+	"github.com/gravitational/roundtrip"
+	telehttplib "github.com/gravitational/teleport/lib/httplib"
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+)
+
+/* createPatchOperation initiates the operation of applying configuration-only changes
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/operations/patch
+
+   {
+      "env": {"<name>": "<value>"},
+      "dns_upstreams": ["<ip>[:<port>]"]
+   }
+
+Success response:
+
+   {
+      "account_id": "account id",
+      "site_id": "site_id",
+      "operation_id": "operation id"
+   }
+*/
+func (h *WebHandler) createPatchOperation(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req ops.CreatePatchOperationRequest
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	req.ClusterKey = siteKey(p)
+	op, err := context.Operator.CreatePatchOperation(r.Context(), req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, op)
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 	"net/http"
 
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/ops/opsclient"
 	"github.com/gravitational/gravity/lib/storage"
 
@@ -303,6 +304,382 @@ func (h *WebHandler) deleteGithubConnector(w http.ResponseWriter, r *http.Reques
 	return nil
 }
 
+/* upsertSAMLConnector creates or updates a SAML connector
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/saml/connectors
+*/
+func (h *WebHandler) upsertSAMLConnector(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	var req *opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	connector, err := teleservices.GetSAMLConnectorMarshaler().UnmarshalSAMLConnector(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := connector.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if req.TTL != 0 {
+		connector.SetTTL(clockwork.NewRealClock(), req.TTL)
+	}
+	err = ctx.Identity.UpsertSAMLConnector(connector)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, message("upserted SAML connector"))
+	return nil
+}
+
+/* getSAMLConnector returns a SAML connector by name
+
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/saml/connectors/:id
+*/
+func (h *WebHandler) getSAMLConnector(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	withSecrets, _, err := telehttplib.ParseBool(r.URL.Query(), constants.WithSecretsParam)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	connector, err := ctx.Identity.GetSAMLConnector(p.ByName("id"), withSecrets)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	out, err := teleservices.GetSAMLConnectorMarshaler().MarshalSAMLConnector(connector)
+	return rawMessage(w, out, err)
+}
+
+/* getSAMLConnectors returns all SAML connectors
+
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/saml/connectors
+*/
+func (h *WebHandler) getSAMLConnectors(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	withSecrets, _, err := telehttplib.ParseBool(r.URL.Query(), constants.WithSecretsParam)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	connectors, err := ctx.Identity.GetSAMLConnectors(withSecrets)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	items := make([]json.RawMessage, len(connectors))
+	for i, connector := range connectors {
+		data, err := teleservices.GetSAMLConnectorMarshaler().MarshalSAMLConnector(connector)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		items[i] = data
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, items)
+	return nil
+}
+
+/* deleteSAMLConnector deletes a connector by its name
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/saml/connectors/:id
+*/
+func (h *WebHandler) deleteSAMLConnector(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	name := p.ByName("id")
+	err := ctx.Identity.DeleteSAMLConnector(name)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("SAML connector %q not found", name)
+		}
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, message("SAML connector deleted"))
+	return nil
+}
+
+/* upsertRoleMapping creates or updates a mapping of OIDC claims to roles
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/rolemappings
+*/
+func (h *WebHandler) upsertRoleMapping(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	var req *opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	mapping, err := storage.UnmarshalRoleMapping(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = ctx.Identity.UpsertRoleMapping(mapping)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, message("upserted role mapping"))
+	return nil
+}
+
+/* getRoleMapping returns a role mapping by name
+
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/rolemappings/:name
+*/
+func (h *WebHandler) getRoleMapping(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	mapping, err := ctx.Identity.GetRoleMapping(p.ByName("name"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	out, err := storage.MarshalRoleMapping(mapping)
+	return rawMessage(w, out, err)
+}
+
+/* getRoleMappings returns all role mappings
+
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/rolemappings
+*/
+func (h *WebHandler) getRoleMappings(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	mappings, err := ctx.Identity.GetRoleMappings()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	items := make([]json.RawMessage, len(mappings))
+	for i, mapping := range mappings {
+		data, err := storage.MarshalRoleMapping(mapping)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		items[i] = data
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, items)
+	return nil
+}
+
+/* deleteRoleMapping deletes a role mapping by name
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/rolemappings/:name
+*/
+func (h *WebHandler) deleteRoleMapping(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	err := ctx.Identity.DeleteRoleMapping(p.ByName("name"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, message("role mapping deleted"))
+	return nil
+}
+
+/* upsertConfigResource creates or updates a configuration resource
+   of a registered kind
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/resources/:kind
+*/
+func (h *WebHandler) upsertConfigResource(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	var req *opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	var resource storage.UnknownResource
+	if err := json.Unmarshal(req.Resource, &resource); err != nil {
+		return trace.Wrap(err)
+	}
+	if storage.CanonicalKind(resource.Kind) != storage.CanonicalKind(p.ByName("kind")) {
+		return trace.BadParameter("expected resource of kind %q, got %q",
+			p.ByName("kind"), resource.Kind)
+	}
+	err := ctx.Operator.UpsertConfigResource(siteKey(p), resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, message("upserted %v %q", resource.Kind, resource.Metadata.Name))
+	return nil
+}
+
+/* getConfigResource returns a configuration resource by kind and name
+
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/resources/:kind/:name
+*/
+func (h *WebHandler) getConfigResource(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	resource, err := ctx.Operator.GetConfigResource(siteKey(p), p.ByName("kind"), p.ByName("name"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return rawMessage(w, resource.Raw, nil)
+}
+
+/* getConfigResources returns all configuration resources of the specified kind
+
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/resources/:kind
+*/
+func (h *WebHandler) getConfigResources(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	resources, err := ctx.Operator.GetConfigResources(siteKey(p), p.ByName("kind"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if resources == nil {
+		resources = []storage.UnknownResource{}
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, resources)
+	return nil
+}
+
+/* deleteConfigResource deletes a configuration resource by kind and name
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/resources/:kind/:name
+*/
+func (h *WebHandler) deleteConfigResource(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	err := ctx.Operator.DeleteConfigResource(siteKey(p), p.ByName("kind"), p.ByName("name"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, message("%v %q deleted", p.ByName("kind"), p.ByName("name")))
+	return nil
+}
+
+/* upsertWebhook creates or updates a webhook
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/webhooks
+*/
+func (h *WebHandler) upsertWebhook(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	var req *opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	webhook, err := storage.UnmarshalWebhook(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = ctx.Operator.UpsertWebhook(siteKey(p), webhook)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, message("upserted webhook %q", webhook.GetName()))
+	return nil
+}
+
+/* getWebhook returns a webhook by name
+
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/webhooks/:name
+*/
+func (h *WebHandler) getWebhook(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	webhook, err := ctx.Operator.GetWebhook(siteKey(p), p.ByName("name"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	out, err := storage.MarshalWebhook(webhook)
+	return rawMessage(w, out, err)
+}
+
+/* getWebhooks returns all webhooks
+
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/webhooks
+*/
+func (h *WebHandler) getWebhooks(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	webhooks, err := ctx.Operator.GetWebhooks(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	items := make([]json.RawMessage, len(webhooks))
+	for i, webhook := range webhooks {
+		data, err := storage.MarshalWebhook(webhook)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		items[i] = data
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, items)
+	return nil
+}
+
+/* deleteWebhook deletes a webhook by name
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/webhooks/:name
+*/
+func (h *WebHandler) deleteWebhook(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	err := ctx.Operator.DeleteWebhook(siteKey(p), p.ByName("name"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, message("webhook %q deleted", p.ByName("name")))
+	return nil
+}
+
+/* upsertProvisioner creates or updates a provisioner
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/provisioners
+*/
+func (h *WebHandler) upsertProvisioner(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	var req *opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	provisioner, err := storage.UnmarshalProvisioner(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = ctx.Operator.UpsertProvisioner(siteKey(p), provisioner)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, message("upserted provisioner %q", provisioner.GetName()))
+	return nil
+}
+
+/* getProvisioner returns a provisioner by name
+
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/provisioners/:name
+*/
+func (h *WebHandler) getProvisioner(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	provisioner, err := ctx.Operator.GetProvisioner(siteKey(p), p.ByName("name"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	out, err := storage.MarshalProvisioner(provisioner)
+	return rawMessage(w, out, err)
+}
+
+/* getProvisioners returns all provisioners
+
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/provisioners
+*/
+func (h *WebHandler) getProvisioners(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	provisioners, err := ctx.Operator.GetProvisioners(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	items := make([]json.RawMessage, len(provisioners))
+	for i, provisioner := range provisioners {
+		data, err := storage.MarshalProvisioner(provisioner)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		items[i] = data
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, items)
+	return nil
+}
+
+/* deleteProvisioner deletes a provisioner by name
+
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/provisioners/:name
+*/
+func (h *WebHandler) deleteProvisioner(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	err := ctx.Operator.DeleteProvisioner(siteKey(p), p.ByName("name"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, message("provisioner %q deleted", p.ByName("name")))
+	return nil
+}
+
+/* provisionNodes creates the machines of new cluster nodes with the provisioner
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/provisioners/:name/nodes
+*/
+func (h *WebHandler) provisionNodes(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
+	var req ops.ProvisionNodesRequest
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	req.SiteKey = siteKey(p)
+	req.Provisioner = p.ByName("name")
+	resp, err := ctx.Operator.ProvisionNodes(r.Context(), req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, resp)
+	return nil
+}
+
 func rawMessage(w http.ResponseWriter, data []byte, err error) error {
 	if err != nil {
 		return trace.Wrap(err)
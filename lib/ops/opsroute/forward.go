@@ -137,6 +137,10 @@ func (r *Router) CreateAccount(req ops.NewAccountRequest) (*ops.Account, error)
 	return r.Local.CreateAccount(req)
 }
 
+func (r *Router) DeleteAccount(accountID string) error {
+	return r.Local.DeleteAccount(accountID)
+}
+
 func (r *Router) GetAccounts() ([]ops.Account, error) {
 	return r.Local.GetAccounts()
 }
@@ -183,6 +187,42 @@ func (r *Router) GetClusterNodes(key ops.SiteKey) ([]ops.Node, error) {
 	return client.GetClusterNodes(key)
 }
 
+// GetClusterStatusHistory returns the recorded status snapshots of the cluster
+func (r *Router) GetClusterStatusHistory(ctx context.Context, req ops.GetClusterStatusHistoryRequest) ([]storage.StatusSnapshot, error) {
+	client, err := r.PickClient(req.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetClusterStatusHistory(ctx, req)
+}
+
+// GetRegistryStatus returns the status of the cluster registry
+func (r *Router) GetRegistryStatus(ctx context.Context, key ops.SiteKey) (*ops.RegistryStatus, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetRegistryStatus(ctx, key)
+}
+
+// GetPackageVerificationStatus returns the results of the last cluster package verification
+func (r *Router) GetPackageVerificationStatus(ctx context.Context, key ops.SiteKey) (*ops.PackageVerificationStatus, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetPackageVerificationStatus(ctx, key)
+}
+
+// GetClusterHealth returns the health of cluster nodes
+func (r *Router) GetClusterHealth(ctx context.Context, key ops.SiteKey) (*ops.ClusterHealth, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetClusterHealth(ctx, key)
+}
+
 func (r *Router) ResetUserPassword(req ops.ResetUserPasswordRequest) (string, error) {
 	client, err := r.PickClient(req.SiteDomain)
 	if err != nil {
@@ -251,6 +291,15 @@ func (r *Router) ActivateSite(req ops.ActivateSiteRequest) error {
 	return client.ActivateSite(req)
 }
 
+// UpdateLicense replaces the license installed on the cluster
+func (r *Router) UpdateLicense(ctx context.Context, req ops.UpdateLicenseRequest) error {
+	client, err := r.PickClient(req.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpdateLicense(ctx, req)
+}
+
 func (r *Router) CompleteFinalInstallStep(req ops.CompleteFinalInstallStepRequest) error {
 	client, err := r.RemoteClient(req.SiteDomain)
 	if err != nil {
@@ -349,6 +398,15 @@ func (r *Router) CreateClusterGarbageCollectOperation(ctx context.Context, req o
 	return r.Local.CreateClusterGarbageCollectOperation(ctx, req)
 }
 
+// CollectPackageGarbage removes unreachable packages from the cluster package service
+func (r *Router) CollectPackageGarbage(ctx context.Context, req ops.CollectPackageGarbageRequest) (*ops.CollectPackageGarbageResponse, error) {
+	client, err := r.PickClient(req.ClusterKey.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.CollectPackageGarbage(ctx, req)
+}
+
 // CreateUpdateEnvarsOperation creates a new operation to update cluster runtime environment variables
 func (r *Router) CreateUpdateEnvarsOperation(ctx context.Context, req ops.CreateUpdateEnvarsOperationRequest) (*ops.SiteOperationKey, error) {
 	return r.Local.CreateUpdateEnvarsOperation(ctx, req)
@@ -359,6 +417,11 @@ func (r *Router) CreateUpdateConfigOperation(ctx context.Context, req ops.Create
 	return r.Local.CreateUpdateConfigOperation(ctx, req)
 }
 
+// CreatePatchOperation creates a new operation to apply configuration-only changes to the cluster
+func (r *Router) CreatePatchOperation(ctx context.Context, req ops.CreatePatchOperationRequest) (*ops.SiteOperationKey, error) {
+	return r.Local.CreatePatchOperation(ctx, req)
+}
+
 func (r *Router) GetSiteOperationLogs(key ops.SiteOperationKey) (io.ReadCloser, error) {
 	client, err := r.PickOperationClient(key.SiteDomain)
 	if err != nil {
@@ -375,6 +438,16 @@ func (r *Router) CreateLogEntry(key ops.SiteOperationKey, entry ops.LogEntry) er
 	return client.CreateLogEntry(key, entry)
 }
 
+// GetOperationPhaseLogs returns the log entries recorded while executing
+// the specified phase of the operation and any of its subphases
+func (r *Router) GetOperationPhaseLogs(key ops.SiteOperationKey, phaseID string) ([]ops.LogEntry, error) {
+	client, err := r.PickOperationClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetOperationPhaseLogs(key, phaseID)
+}
+
 // StreamOperationLogs appends the logs from the provided reader to the
 // specified operation (user-facing) log file
 func (r *Router) StreamOperationLogs(key ops.SiteOperationKey, reader io.Reader) error {
@@ -409,6 +482,14 @@ func (r *Router) GetSiteOperationProgress(key ops.SiteOperationKey) (*ops.Progre
 	return client.GetSiteOperationProgress(key)
 }
 
+func (r *Router) WatchSiteOperationProgress(ctx context.Context, key ops.SiteOperationKey, progressC chan<- ops.ProgressEntry) error {
+	client, err := r.PickOperationClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.WatchSiteOperationProgress(ctx, key, progressC)
+}
+
 func (r *Router) CreateProgressEntry(key ops.SiteOperationKey, entry ops.ProgressEntry) error {
 	client, err := r.PickOperationClient(key.SiteDomain)
 	if err != nil {
@@ -425,12 +506,12 @@ func (r *Router) GetSiteOperationCrashReport(key ops.SiteOperationKey) (io.ReadC
 	return client.GetSiteOperationCrashReport(key)
 }
 
-func (r *Router) GetSiteReport(key ops.SiteKey) (io.ReadCloser, error) {
-	client, err := r.PickClient(key.SiteDomain)
+func (r *Router) GetSiteReport(req ops.GetClusterReportRequest) (io.ReadCloser, error) {
+	client, err := r.PickClient(req.SiteDomain)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return client.GetSiteReport(key)
+	return client.GetSiteReport(req)
 }
 
 // ValidateServers runs pre-installation checks
@@ -488,6 +569,33 @@ func (r *Router) SetOperationState(key ops.SiteOperationKey, req ops.SetOperatio
 	return client.SetOperationState(key, req)
 }
 
+// CancelSiteOperation requests cancellation of the specified operation
+func (r *Router) CancelSiteOperation(ctx context.Context, key ops.SiteOperationKey) error {
+	client, err := r.PickOperationClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.CancelSiteOperation(ctx, key)
+}
+
+// PauseSiteOperation requests the specified operation to pause
+func (r *Router) PauseSiteOperation(ctx context.Context, key ops.SiteOperationKey) error {
+	client, err := r.PickOperationClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.PauseSiteOperation(ctx, key)
+}
+
+// ResumeSiteOperation clears the pause request of the specified operation
+func (r *Router) ResumeSiteOperation(ctx context.Context, key ops.SiteOperationKey) error {
+	client, err := r.PickOperationClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.ResumeSiteOperation(ctx, key)
+}
+
 // CreateOperationPlan saves the provided operation plan
 func (r *Router) CreateOperationPlan(key ops.SiteOperationKey, plan storage.OperationPlan) error {
 	client, err := r.PickOperationClient(key.SiteDomain)
@@ -540,6 +648,10 @@ func (r *Router) ConfigureNode(req ops.ConfigureNodeRequest) error {
 	return r.Local.ConfigureNode(req)
 }
 
+func (r *Router) RotateCertAuthority(req ops.RotateCertAuthorityRequest) error {
+	return r.Local.RotateCertAuthority(req)
+}
+
 // GetLogForwarders returns a list of configured log forwarders
 func (r *Router) GetLogForwarders(key ops.SiteKey) ([]storage.LogForwarder, error) {
 	client, err := r.RemoteClient(key.SiteDomain)
@@ -621,6 +733,60 @@ func (r *Router) DeleteSMTPConfig(key ops.SiteKey) error {
 	return client.DeleteSMTPConfig(key)
 }
 
+// GetHealingPolicy returns the cluster healing policy
+func (r *Router) GetHealingPolicy(key ops.SiteKey) (storage.HealingPolicy, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetHealingPolicy(key)
+}
+
+// UpdateHealingPolicy updates the cluster healing policy
+func (r *Router) UpdateHealingPolicy(key ops.SiteKey, policy storage.HealingPolicy) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpdateHealingPolicy(key, policy)
+}
+
+// DeleteHealingPolicy deletes the cluster healing policy
+func (r *Router) DeleteHealingPolicy(key ops.SiteKey) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteHealingPolicy(key)
+}
+
+// GetClusterDNS returns the cluster DNS configuration
+func (r *Router) GetClusterDNS(key ops.SiteKey) (storage.ClusterDNS, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetClusterDNS(key)
+}
+
+// UpdateClusterDNS updates the cluster DNS configuration
+func (r *Router) UpdateClusterDNS(key ops.SiteKey, dns storage.ClusterDNS) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpdateClusterDNS(key, dns)
+}
+
+// DeleteClusterDNS resets the cluster DNS configuration to defaults
+func (r *Router) DeleteClusterDNS(key ops.SiteKey) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteClusterDNS(key)
+}
+
 // GetAlerts returns a list of monitoring alerts
 func (r *Router) GetAlerts(key ops.SiteKey) ([]storage.Alert, error) {
 	client, err := r.RemoteClient(key.SiteDomain)
@@ -736,6 +902,34 @@ func (r *Router) GetExpandToken(key ops.SiteKey) (*storage.ProvisioningToken, er
 	return client.GetExpandToken(key)
 }
 
+// CreateJoinToken creates a new join token limited in time, number of uses
+// or roles of the joining nodes
+func (r *Router) CreateJoinToken(ctx context.Context, req ops.CreateJoinTokenRequest) (*storage.ProvisioningToken, error) {
+	client, err := r.PickClient(req.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.CreateJoinToken(ctx, req)
+}
+
+// GetJoinTokens returns the active scoped join tokens of the cluster
+func (r *Router) GetJoinTokens(ctx context.Context, key ops.SiteKey) ([]storage.ProvisioningToken, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetJoinTokens(ctx, key)
+}
+
+// DeleteJoinToken revokes the specified scoped join token
+func (r *Router) DeleteJoinToken(ctx context.Context, req ops.DeleteJoinTokenRequest) error {
+	client, err := r.PickClient(req.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteJoinToken(ctx, req)
+}
+
 func (r *Router) GetTrustedClusterToken(key ops.SiteKey) (storage.Token, error) {
 	return r.Local.GetTrustedClusterToken(key)
 }
@@ -781,6 +975,20 @@ func (r *Router) DeleteClusterCertificate(key ops.SiteKey) error {
 	return client.DeleteClusterCertificate(key)
 }
 
+// IssueClusterCertificate issues a new cluster certificate
+func (r *Router) IssueClusterCertificate(key ops.SiteKey) (*ops.ClusterCertificate, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.IssueClusterCertificate(key)
+}
+
+// CreateRotateCertificatesOperation creates a new operation to rotate cluster certificates
+func (r *Router) CreateRotateCertificatesOperation(ctx context.Context, req ops.CreateRotateCertificatesOperationRequest) (*ops.SiteOperationKey, error) {
+	return r.Local.CreateRotateCertificatesOperation(ctx, req)
+}
+
 // StepDown asks the process to pause its leader election heartbeat so it can
 // give up its leadership
 func (r *Router) StepDown(key ops.SiteKey) error {
@@ -881,6 +1089,200 @@ func (r *Router) DeleteGithubConnector(key ops.SiteKey, name string) error {
 	return client.DeleteGithubConnector(key, name)
 }
 
+// UpsertSAMLConnector creates or updates a SAML connector
+func (r *Router) UpsertSAMLConnector(key ops.SiteKey, connector teleservices.SAMLConnector) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertSAMLConnector(key, connector)
+}
+
+// GetSAMLConnector returns a SAML connector by name
+//
+// Returned connector excludes signing key unless withSecrets is true.
+func (r *Router) GetSAMLConnector(key ops.SiteKey, name string, withSecrets bool) (teleservices.SAMLConnector, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetSAMLConnector(key, name, withSecrets)
+}
+
+// GetSAMLConnectors returns all SAML connectors
+//
+// Returned connectors exclude signing key unless withSecrets is true.
+func (r *Router) GetSAMLConnectors(key ops.SiteKey, withSecrets bool) ([]teleservices.SAMLConnector, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetSAMLConnectors(key, withSecrets)
+}
+
+// DeleteSAMLConnector deletes a SAML connector by name
+func (r *Router) DeleteSAMLConnector(key ops.SiteKey, name string) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteSAMLConnector(key, name)
+}
+
+// UpsertConfigResource creates or updates a configuration resource
+func (r *Router) UpsertConfigResource(key ops.SiteKey, resource storage.UnknownResource) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertConfigResource(key, resource)
+}
+
+// GetConfigResource returns a configuration resource by kind and name
+func (r *Router) GetConfigResource(key ops.SiteKey, kind, name string) (*storage.UnknownResource, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetConfigResource(key, kind, name)
+}
+
+// GetConfigResources returns all configuration resources of the specified kind
+func (r *Router) GetConfigResources(key ops.SiteKey, kind string) ([]storage.UnknownResource, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetConfigResources(key, kind)
+}
+
+// DeleteConfigResource deletes a configuration resource by kind and name
+func (r *Router) DeleteConfigResource(key ops.SiteKey, kind, name string) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteConfigResource(key, kind, name)
+}
+
+// UpsertWebhook creates or updates a webhook
+func (r *Router) UpsertWebhook(key ops.SiteKey, webhook storage.Webhook) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertWebhook(key, webhook)
+}
+
+// GetWebhook returns a webhook by name
+func (r *Router) GetWebhook(key ops.SiteKey, name string) (storage.Webhook, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetWebhook(key, name)
+}
+
+// GetWebhooks returns all webhooks
+func (r *Router) GetWebhooks(key ops.SiteKey) ([]storage.Webhook, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetWebhooks(key)
+}
+
+// DeleteWebhook deletes a webhook by name
+func (r *Router) DeleteWebhook(key ops.SiteKey, name string) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteWebhook(key, name)
+}
+
+// UpsertProvisioner creates or updates a provisioner
+func (r *Router) UpsertProvisioner(key ops.SiteKey, provisioner storage.Provisioner) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertProvisioner(key, provisioner)
+}
+
+// GetProvisioner returns a provisioner by name
+func (r *Router) GetProvisioner(key ops.SiteKey, name string) (storage.Provisioner, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetProvisioner(key, name)
+}
+
+// GetProvisioners returns all provisioners
+func (r *Router) GetProvisioners(key ops.SiteKey) ([]storage.Provisioner, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetProvisioners(key)
+}
+
+// DeleteProvisioner deletes a provisioner by name
+func (r *Router) DeleteProvisioner(key ops.SiteKey, name string) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteProvisioner(key, name)
+}
+
+// ProvisionNodes creates the machines of new cluster nodes with
+// the specified provisioner and joins them to the cluster
+func (r *Router) ProvisionNodes(ctx context.Context, req ops.ProvisionNodesRequest) (*ops.ProvisionNodesResponse, error) {
+	client, err := r.PickClient(req.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.ProvisionNodes(ctx, req)
+}
+
+// UpsertRoleMapping creates or updates a mapping of OIDC claims to roles
+func (r *Router) UpsertRoleMapping(key ops.SiteKey, mapping storage.RoleMapping) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertRoleMapping(key, mapping)
+}
+
+// GetRoleMapping returns a role mapping by name
+func (r *Router) GetRoleMapping(key ops.SiteKey, name string) (storage.RoleMapping, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetRoleMapping(key, name)
+}
+
+// GetRoleMappings returns all role mappings
+func (r *Router) GetRoleMappings(key ops.SiteKey) ([]storage.RoleMapping, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetRoleMappings(key)
+}
+
+// DeleteRoleMapping deletes a role mapping by name
+func (r *Router) DeleteRoleMapping(key ops.SiteKey, name string) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteRoleMapping(key, name)
+}
+
 // UpsertAuthGateway updates auth gateway configuration.
 func (r *Router) UpsertAuthGateway(key ops.SiteKey, gw storage.AuthGateway) error {
 	return r.Local.UpsertAuthGateway(key, gw)
@@ -905,6 +1307,17 @@ func (r *Router) EmitAuditEvent(ctx context.Context, req ops.AuditEventRequest)
 	return r.Local.EmitAuditEvent(ctx, req)
 }
 
+// GetAuditEvents returns the recorded audit events of operator API calls.
+func (r *Router) GetAuditEvents(ctx context.Context, req ops.GetAuditEventsRequest) ([]storage.AuditEvent, error) {
+	return r.Local.GetAuditEvents(ctx, req)
+}
+
+// UploadSessionRecording stores the recording of an interactive session
+// read from the provided reader for later playback.
+func (r *Router) UploadSessionRecording(ctx context.Context, req ops.UploadSessionRecordingRequest, reader io.Reader) error {
+	return r.Local.UploadSessionRecording(ctx, req, reader)
+}
+
 // CreateUserInvite creates a new invite token for a user.
 func (r *Router) CreateUserInvite(ctx context.Context, req ops.CreateUserInviteRequest) (*storage.UserToken, error) {
 	client, err := r.PickClient(req.SiteDomain)
@@ -940,3 +1353,52 @@ func (r *Router) CreateUserReset(ctx context.Context, req ops.CreateUserResetReq
 	}
 	return client.CreateUserReset(ctx, req)
 }
+
+// CreateFleetUpgrade starts upgrading the remote clusters matching
+// the request selector to the requested application version
+func (r *Router) CreateFleetUpgrade(ctx context.Context, req ops.CreateFleetUpgradeRequest) (*storage.FleetUpgrade, error) {
+	return r.Local.CreateFleetUpgrade(ctx, req)
+}
+
+// GetFleetUpgrade returns the fleet upgrade specified with the key
+func (r *Router) GetFleetUpgrade(ctx context.Context, key ops.FleetUpgradeKey) (*storage.FleetUpgrade, error) {
+	return r.Local.GetFleetUpgrade(ctx, key)
+}
+
+// GetFleetUpgrades returns all fleet upgrades of the specified account
+func (r *Router) GetFleetUpgrades(ctx context.Context, accountID string) ([]storage.FleetUpgrade, error) {
+	return r.Local.GetFleetUpgrades(ctx, accountID)
+}
+
+// CancelFleetUpgrade stops the fleet upgrade specified with the key
+func (r *Router) CancelFleetUpgrade(ctx context.Context, key ops.FleetUpgradeKey) error {
+	return r.Local.CancelFleetUpgrade(ctx, key)
+}
+
+// CreateScheduledOperation queues the operation to start according
+// to the request schedule
+func (r *Router) CreateScheduledOperation(ctx context.Context, req ops.CreateScheduledOperationRequest) (*storage.ScheduledOperation, error) {
+	client, err := r.PickClient(req.ClusterName)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.CreateScheduledOperation(ctx, req)
+}
+
+// GetScheduledOperations returns all scheduled operations of the cluster
+func (r *Router) GetScheduledOperations(ctx context.Context, key ops.SiteKey) ([]storage.ScheduledOperation, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetScheduledOperations(ctx, key)
+}
+
+// CancelScheduledOperation cancels the pending scheduled operation
+func (r *Router) CancelScheduledOperation(ctx context.Context, key ops.ScheduledOperationKey) error {
+	client, err := r.PickClient(key.ClusterName)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.CancelScheduledOperation(ctx, key)
+}
@@ -61,6 +61,7 @@ func (p *ClientPool) httpClient() *http.Client {
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: true,
 			},
+			Proxy: http.ProxyFromEnvironment,
 		}
 	}
 	return client
@@ -450,6 +450,11 @@ func (r *AgentPeerStore) checkLicense(ctx context.Context, numPeers int, cluster
 		return trace.Wrap(err)
 	}
 
+	err = ops.CheckLicenseExpiration(license.GetPayload(), time.Now().UTC())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	count, err := r.teleport.GetServerCount(ctx, clusterName)
 	if err != nil {
 		return trace.Wrap(err)
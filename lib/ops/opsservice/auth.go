@@ -21,7 +21,6 @@ import (
 	"github.com/gravitational/gravity/lib/ops"
 
 	"github.com/cloudflare/cfssl/signer"
-	"github.com/gravitational/license/authority"
 	"github.com/gravitational/trace"
 )
 
@@ -36,19 +35,23 @@ func (s *site) signTLSKey(req ops.TLSSignRequest) (*ops.TLSSignResponse, error)
 		return nil, trace.Wrap(err)
 	}
 
-	caKeyPair, err := archive.GetKeyPair(constants.RootKeyPair)
+	certSigner, err := s.newCertSigner(archive)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	cert, err := authority.ProcessCSR(signer.SignRequest{
+	cert, err := certSigner.SignCSR(signer.SignRequest{
 		Request: string(req.CSR),
 		Subject: req.Subject,
-	}, req.TTL, caKeyPair)
+	}, req.TTL)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	caBundle, err := certAuthorityBundle(archive)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 	return &ops.TLSSignResponse{
 		Cert:   cert,
-		CACert: caKeyPair.CertPEM,
+		CACert: caBundle,
 	}, nil
 }
@@ -0,0 +1,244 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/cloudflare/cfssl/csr"
+	"github.com/gravitational/license/authority"
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+)
+
+// CreateRotateCertificatesOperation creates a new operation to rotate cluster certificates
+func (o *Operator) CreateRotateCertificatesOperation(ctx context.Context, req ops.CreateRotateCertificatesOperationRequest) (*ops.SiteOperationKey, error) {
+	err := req.ClusterKey.Check()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cluster, err := o.openSite(req.ClusterKey)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if req.RotateCA && cluster.usesExternalPKI() {
+		return nil, trace.BadParameter("cluster certificate authority is provided externally " +
+			"and cannot be rotated, rotate the certificates without replacing the certificate authority instead")
+	}
+	op := ops.SiteOperation{
+		ID:         uuid.New(),
+		AccountID:  req.ClusterKey.AccountID,
+		SiteDomain: req.ClusterKey.SiteDomain,
+		Type:       ops.OperationRotateCertificates,
+		Created:    cluster.clock().UtcNow(),
+		CreatedBy:  storage.UserFromContext(ctx),
+		Updated:    cluster.clock().UtcNow(),
+		State:      ops.OperationRotateCertificatesInProgress,
+		RotateCertificates: &storage.RotateCertificatesOperationState{
+			RotateCA: req.RotateCA,
+		},
+	}
+	key, err := cluster.getOperationGroup().createSiteOperation(op)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return key, nil
+}
+
+// RotateCertAuthority moves the cluster certificate authority package
+// to the stage specified in the request.
+// Moving the package to the stage it is already in is a no-op
+func (o *Operator) RotateCertAuthority(req ops.RotateCertAuthorityRequest) error {
+	if err := req.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	operation, err := o.GetSiteOperation(req.Key)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if operation.Type != ops.OperationRotateCertificates {
+		return trace.BadParameter("expected certificate rotation operation, got %v", operation.TypeString())
+	}
+	cluster, err := o.openSite(req.Key.SiteKey())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(cluster.rotateCertAuthority(req.Stage, operation.ID))
+}
+
+func (s *site) rotateCertAuthority(stage, operationID string) error {
+	caPackage, err := s.planetCertAuthorityPackage()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	envelope, err := s.packages().ReadPackageEnvelope(*caPackage)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	currentStage := certAuthorityStage(*envelope)
+	if currentStage == stage {
+		s.WithField("stage", stage).Info("Certificate authority is already at requested stage.")
+		return nil
+	}
+	archive, err := s.readCertAuthorityPackage()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = moveCertAuthority(archive, currentStage, stage, s.generateCertAuthority)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	reader, err := utils.CreateTLSArchive(archive)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	_, err = s.packages().UpsertPackage(*caPackage, reader, pack.WithLabels(
+		map[string]string{
+			pack.PurposeLabel:            pack.PurposeCA,
+			pack.OperationIDLabel:        operationID,
+			pack.CertAuthorityStageLabel: stage,
+		}))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	s.WithField("stage", stage).Info("Moved certificate authority to new stage.")
+	return nil
+}
+
+// generateCertAuthority generates a new self-signed certificate authority for this cluster
+func (s *site) generateCertAuthority() (*authority.TLSKeyPair, error) {
+	return authority.GenerateSelfSignedCA(csr.CertificateRequest{
+		CN: s.siteRepoName(),
+		CA: &csr.CAConfig{
+			Expiry: defaults.CACertificateExpiry.String(),
+		},
+	})
+}
+
+// certAuthorityStage returns the rotation stage of the certificate authority
+// package described by envelope
+func certAuthorityStage(envelope pack.PackageEnvelope) string {
+	if stage, ok := envelope.RuntimeLabels[pack.CertAuthorityStageLabel]; ok {
+		return stage
+	}
+	return ops.CertAuthorityStageInitial
+}
+
+// moveCertAuthority updates the key pairs in the certificate authority archive
+// to move it from one rotation stage to an adjacent one:
+//
+//  * initial:  root certificate authority is the only one
+//  * trust:    a new certificate authority is trusted as a transition key pair
+//  * switch:   the new certificate authority becomes the root and the former
+//              root is still trusted as the transition key pair
+//  * finalize: the former root is no longer trusted and is only kept
+//              to be able to roll back
+//
+// A new rotation can be started from the finalize stage, in which case
+// the former root is discarded
+func moveCertAuthority(archive utils.TLSArchive, from, to string, generate func() (*authority.TLSKeyPair, error)) error {
+	switch {
+	case (from == ops.CertAuthorityStageInitial || from == ops.CertAuthorityStageFinalize) &&
+		to == ops.CertAuthorityStageTrust:
+		keyPair, err := generate()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		delete(archive, constants.PreviousRootKeyPair)
+		archive[constants.TransitionRootKeyPair] = keyPair
+		return nil
+	case from == ops.CertAuthorityStageTrust && to == ops.CertAuthorityStageInitial:
+		if _, err := archive.GetKeyPair(constants.TransitionRootKeyPair); err != nil {
+			return trace.Wrap(err)
+		}
+		delete(archive, constants.TransitionRootKeyPair)
+		return nil
+	case from == ops.CertAuthorityStageTrust && to == ops.CertAuthorityStageSwitch,
+		from == ops.CertAuthorityStageSwitch && to == ops.CertAuthorityStageTrust:
+		return trace.Wrap(swapKeyPairs(archive, constants.RootKeyPair, constants.TransitionRootKeyPair))
+	case from == ops.CertAuthorityStageSwitch && to == ops.CertAuthorityStageFinalize:
+		return trace.Wrap(renameKeyPair(archive, constants.TransitionRootKeyPair, constants.PreviousRootKeyPair))
+	case from == ops.CertAuthorityStageFinalize && to == ops.CertAuthorityStageSwitch:
+		return trace.Wrap(renameKeyPair(archive, constants.PreviousRootKeyPair, constants.TransitionRootKeyPair))
+	}
+	return trace.BadParameter("cannot move certificate authority from %q to %q stage", from, to)
+}
+
+// certAuthorityBundle returns the PEM-encoded certificates of all certificate
+// authorities trusted at the current rotation stage.
+// The root certificate authority always comes first followed by the rest
+// of its chain if the certificate authority is an intermediate one
+func certAuthorityBundle(archive utils.TLSArchive) ([]byte, error) {
+	root, err := archive.GetKeyPair(constants.RootKeyPair)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	bundle := [][]byte{root.CertPEM}
+	for _, name := range []string{constants.CertAuthorityChainKeyPair, constants.TransitionRootKeyPair} {
+		keyPair, err := archive.GetKeyPair(name)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		bundle = append(bundle, keyPair.CertPEM)
+	}
+	if len(bundle) == 1 {
+		return root.CertPEM, nil
+	}
+	var buf bytes.Buffer
+	for i, certPEM := range bundle {
+		if i != len(bundle)-1 {
+			certPEM = append(bytes.TrimSpace(certPEM), '\n')
+		}
+		buf.Write(certPEM)
+	}
+	return buf.Bytes(), nil
+}
+
+func swapKeyPairs(archive utils.TLSArchive, a, b string) error {
+	first, err := archive.GetKeyPair(a)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	second, err := archive.GetKeyPair(b)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	archive[a], archive[b] = second, first
+	return nil
+}
+
+func renameKeyPair(archive utils.TLSArchive, from, to string) error {
+	keyPair, err := archive.GetKeyPair(from)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	delete(archive, from)
+	archive[to] = keyPair
+	return nil
+}
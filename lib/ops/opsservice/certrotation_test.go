@@ -0,0 +1,115 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/license/authority"
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+type CertRotationSuite struct{}
+
+var _ = check.Suite(&CertRotationSuite{})
+
+func (s *CertRotationSuite) TestMovesCertAuthorityThroughStages(c *check.C) {
+	oldCA := keyPair("old")
+	newCA := keyPair("new")
+	archive := utils.TLSArchive{constants.RootKeyPair: oldCA}
+	generate := func() (*authority.TLSKeyPair, error) { return newCA, nil }
+
+	err := moveCertAuthority(archive, ops.CertAuthorityStageInitial, ops.CertAuthorityStageTrust, generate)
+	c.Assert(err, check.IsNil)
+	c.Assert(archive, check.DeepEquals, utils.TLSArchive{
+		constants.RootKeyPair:           oldCA,
+		constants.TransitionRootKeyPair: newCA,
+	})
+	bundle, err := certAuthorityBundle(archive)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(bundle), check.Equals, "old\nnew")
+
+	err = moveCertAuthority(archive, ops.CertAuthorityStageTrust, ops.CertAuthorityStageSwitch, generate)
+	c.Assert(err, check.IsNil)
+	c.Assert(archive, check.DeepEquals, utils.TLSArchive{
+		constants.RootKeyPair:           newCA,
+		constants.TransitionRootKeyPair: oldCA,
+	})
+	bundle, err = certAuthorityBundle(archive)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(bundle), check.Equals, "new\nold")
+
+	err = moveCertAuthority(archive, ops.CertAuthorityStageSwitch, ops.CertAuthorityStageFinalize, generate)
+	c.Assert(err, check.IsNil)
+	c.Assert(archive, check.DeepEquals, utils.TLSArchive{
+		constants.RootKeyPair:         newCA,
+		constants.PreviousRootKeyPair: oldCA,
+	})
+	bundle, err = certAuthorityBundle(archive)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(bundle), check.Equals, "new")
+}
+
+func (s *CertRotationSuite) TestRollsBackCertAuthorityStages(c *check.C) {
+	oldCA := keyPair("old")
+	newCA := keyPair("new")
+	archive := utils.TLSArchive{
+		constants.RootKeyPair:         newCA,
+		constants.PreviousRootKeyPair: oldCA,
+	}
+	generate := func() (*authority.TLSKeyPair, error) {
+		return nil, trace.BadParameter("unexpected certificate authority generation")
+	}
+
+	err := moveCertAuthority(archive, ops.CertAuthorityStageFinalize, ops.CertAuthorityStageSwitch, generate)
+	c.Assert(err, check.IsNil)
+	err = moveCertAuthority(archive, ops.CertAuthorityStageSwitch, ops.CertAuthorityStageTrust, generate)
+	c.Assert(err, check.IsNil)
+	err = moveCertAuthority(archive, ops.CertAuthorityStageTrust, ops.CertAuthorityStageInitial, generate)
+	c.Assert(err, check.IsNil)
+	c.Assert(archive, check.DeepEquals, utils.TLSArchive{constants.RootKeyPair: oldCA})
+}
+
+func (s *CertRotationSuite) TestStartsNewRotationAfterFinalize(c *check.C) {
+	archive := utils.TLSArchive{
+		constants.RootKeyPair:         keyPair("current"),
+		constants.PreviousRootKeyPair: keyPair("previous"),
+	}
+	next := keyPair("next")
+	generate := func() (*authority.TLSKeyPair, error) { return next, nil }
+
+	err := moveCertAuthority(archive, ops.CertAuthorityStageFinalize, ops.CertAuthorityStageTrust, generate)
+	c.Assert(err, check.IsNil)
+	c.Assert(archive, check.DeepEquals, utils.TLSArchive{
+		constants.RootKeyPair:           keyPair("current"),
+		constants.TransitionRootKeyPair: next,
+	})
+}
+
+func (s *CertRotationSuite) TestRejectsNonAdjacentStages(c *check.C) {
+	archive := utils.TLSArchive{constants.RootKeyPair: keyPair("root")}
+	err := moveCertAuthority(archive, ops.CertAuthorityStageInitial, ops.CertAuthorityStageSwitch, nil)
+	c.Assert(trace.IsBadParameter(err), check.Equals, true)
+	c.Assert(archive, check.DeepEquals, utils.TLSArchive{constants.RootKeyPair: keyPair("root")})
+}
+
+func keyPair(cert string) *authority.TLSKeyPair {
+	return &authority.TLSKeyPair{CertPEM: []byte(cert), KeyPEM: []byte(cert + "-key")}
+}
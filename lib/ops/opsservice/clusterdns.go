@@ -0,0 +1,140 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/coredns"
+	"github.com/gravitational/gravity/lib/kubernetes"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/systeminfo"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// GetClusterDNS returns the cluster DNS configuration
+func (o *Operator) GetClusterDNS(key ops.SiteKey) (storage.ClusterDNS, error) {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	data, err := getConfigMap(client.Core().ConfigMaps(constants.KubeSystemNamespace),
+		constants.ClusterDNSConfigMap)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("no cluster DNS configuration found")
+		}
+		return nil, trace.Wrap(err)
+	}
+
+	dns, err := storage.UnmarshalClusterDNS([]byte(data))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return dns, nil
+}
+
+// UpdateClusterDNS updates the cluster DNS configuration.
+//
+// The new configuration is applied to the running cluster DNS service
+// which picks up the changes without a restart
+func (o *Operator) UpdateClusterDNS(key ops.SiteKey, dns storage.ClusterDNS) error {
+	if err := dns.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	configMaps := client.Core().ConfigMaps(constants.KubeSystemNamespace)
+	if err := o.applyClusterDNS(key, configMaps, dns); err != nil {
+		return trace.Wrap(err)
+	}
+
+	data, err := storage.MarshalClusterDNS(dns)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return updateConfigMap(configMaps, constants.ClusterDNSConfigMap,
+		constants.KubeSystemNamespace, string(data), nil)
+}
+
+// DeleteClusterDNS deletes the cluster DNS configuration and restores
+// the default configuration of the cluster DNS service
+func (o *Operator) DeleteClusterDNS(key ops.SiteKey) error {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	configMaps := client.Core().ConfigMaps(constants.KubeSystemNamespace)
+	err = rigging.ConvertError(configMaps.Delete(constants.ClusterDNSConfigMap, nil))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("no cluster DNS configuration found")
+		}
+		return trace.Wrap(err)
+	}
+
+	return trace.Wrap(o.applyClusterDNS(key, configMaps, nil))
+}
+
+// applyClusterDNS regenerates the CoreDNS configuration with the specified
+// cluster DNS resource, or the defaults if dns is nil
+func (o *Operator) applyClusterDNS(key ops.SiteKey, configMaps corev1.ConfigMapInterface, dns storage.ClusterDNS) error {
+	cluster, err := o.GetSite(key)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	// gravity-site runs in the host network namespace so the
+	// nameservers of the host are used by default
+	resolvConf, err := systeminfo.ResolvFromFile("/etc/resolv.conf")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	conf, err := coredns.GenerateCorefile(coredns.NewConfig(dns, cluster.DNSOverrides, *resolvConf))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = kubernetes.Retry(context.TODO(), func() error {
+		configMap, err := configMaps.Get(constants.CoreDNSConfigMap, metav1.GetOptions{})
+		if err != nil {
+			return trace.Wrap(rigging.ConvertError(err))
+		}
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		configMap.Data[constants.CoreDNSConfigKey] = conf
+		_, err = configMaps.Update(configMap)
+		return trace.Wrap(err)
+	})
+	return trace.Wrap(err)
+}
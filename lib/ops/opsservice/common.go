@@ -128,6 +128,9 @@ func (s *site) createLogEntry(key ops.SiteOperationKey, entry ops.LogEntry) erro
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if entry.PhaseID != "" {
+		return trace.Wrap(s.recordPhaseLogEntry(key, entry))
+	}
 	return nil
 }
 
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// UpsertConfigResource creates or updates a configuration resource
+func (o *Operator) UpsertConfigResource(key ops.SiteKey, resource storage.UnknownResource) error {
+	return trace.Wrap(o.backend().UpsertConfigResource(resource))
+}
+
+// GetConfigResource returns a configuration resource by kind and name
+func (o *Operator) GetConfigResource(key ops.SiteKey, kind, name string) (*storage.UnknownResource, error) {
+	resource, err := o.backend().GetConfigResource(kind, name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resource, nil
+}
+
+// GetConfigResources returns all configuration resources of the specified kind
+func (o *Operator) GetConfigResources(key ops.SiteKey, kind string) ([]storage.UnknownResource, error) {
+	resources, err := o.backend().GetConfigResources(kind)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resources, nil
+}
+
+// DeleteConfigResource deletes a configuration resource by kind and name
+func (o *Operator) DeleteConfigResource(key ops.SiteKey, kind, name string) error {
+	return trace.Wrap(o.backend().DeleteConfigResource(kind, name))
+}
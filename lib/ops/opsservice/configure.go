@@ -46,8 +46,6 @@ import (
 	teleutils "github.com/gravitational/teleport/lib/utils"
 
 	"github.com/cloudflare/cfssl/csr"
-	"github.com/gravitational/configure"
-	"github.com/gravitational/license/authority"
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
@@ -66,6 +64,10 @@ const (
 	etcdExistingCluster = "existing"
 	etcdPeerPort        = 2380
 	etcdEndpointPort    = 2379
+
+	// registryCipherSuitesEnv is the environment variable that overrides
+	// the list of TLS cipher suites of the docker registry
+	registryCipherSuitesEnv = "REGISTRY_HTTP_TLS_CIPHERSUITES"
 )
 
 // Configure packages configures packages for the specified install operation
@@ -201,7 +203,7 @@ func (s *site) configureExpandPackages(ctx context.Context, opCtx *operationCont
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	planetPackage, err := s.app.Manifest.RuntimePackage(provisionedServer.Profile)
+	planetPackage, err := s.app.Manifest.RuntimePackageArch(provisionedServer.Profile, provisionedServer.Arch())
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -336,7 +338,7 @@ func (s *site) configurePackages(ctx *operationContext, req ops.ConfigurePackage
 			return trace.Wrap(err)
 		}
 
-		planetPackage, err := s.app.Manifest.RuntimePackage(master.Profile)
+		planetPackage, err := s.app.Manifest.RuntimePackageArch(master.Profile, master.Arch())
 		if err != nil {
 			return trace.Wrap(err)
 		}
@@ -414,7 +416,7 @@ func (s *site) configurePackages(ctx *operationContext, req ops.ConfigurePackage
 			return trace.Wrap(err)
 		}
 
-		planetPackage, err := s.app.Manifest.RuntimePackage(node.Profile)
+		planetPackage, err := s.app.Manifest.RuntimePackageArch(node.Profile, node.Arch())
 		if err != nil {
 			return trace.Wrap(err)
 		}
@@ -509,18 +511,29 @@ func (s *site) configurePlanetCertAuthority(ctx *operationContext) error {
 		return trace.Wrap(err)
 	}
 
+	archive := make(utils.TLSArchive)
 	if _, err := s.packages().ReadPackageEnvelope(*caPackage); err == nil {
-		s.Debugf("%v already created", caPackage)
-		return nil
+		// the package seeded with the user-provided certificate authority
+		// is completed with the rest of the key pairs
+		if archive, err = s.readCertAuthorityPackage(); err != nil {
+			return trace.Wrap(err)
+		}
+		if _, err := archive.GetKeyPair(constants.APIServerKeyPair); err == nil {
+			s.Debugf("%v already created", caPackage)
+			return nil
+		}
 	}
 
-	s.Debugf("generating certificate authority package")
-	planetCertAuthority, err := authority.GenerateSelfSignedCA(csr.CertificateRequest{
-		CN: s.siteRepoName(),
-		CA: &csr.CAConfig{
-			Expiry: defaults.CACertificateExpiry.String(),
-		},
-	})
+	if _, err := archive.GetKeyPair(constants.RootKeyPair); err != nil {
+		s.Debugf("generating certificate authority package")
+		planetCertAuthority, err := s.generateCertAuthority()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		archive[constants.RootKeyPair] = planetCertAuthority
+	}
+
+	certSigner, err := s.newCertSigner(archive)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -529,7 +542,7 @@ func (s *site) configurePlanetCertAuthority(ctx *operationContext) error {
 	// we have to share the same private key for various apiservers
 	// due to this issue:
 	// https://github.com/kubernetes/kubernetes/issues/11000#issuecomment-232469678
-	apiServer, err := authority.GenerateCertificate(csr.CertificateRequest{
+	apiServer, err := certSigner.GenerateCertificate(csr.CertificateRequest{
 		CN:    constants.APIServerKeyPair,
 		Hosts: []string{"127.0.0.1"},
 		Names: []csr.Name{
@@ -537,7 +550,7 @@ func (s *site) configurePlanetCertAuthority(ctx *operationContext) error {
 				O: defaults.SystemAccountOrg,
 			},
 		},
-	}, planetCertAuthority, nil, defaults.CertificateExpiry)
+	}, nil, defaults.CertificateExpiry)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -549,17 +562,16 @@ func (s *site) configurePlanetCertAuthority(ctx *operationContext) error {
 	}
 	opsCertAuthority.KeyPEM = nil
 
-	reader, err := utils.CreateTLSArchive(utils.TLSArchive{
-		constants.APIServerKeyPair: apiServer,
-		constants.RootKeyPair:      planetCertAuthority,
-		constants.OpsCenterKeyPair: opsCertAuthority,
-	})
+	archive[constants.APIServerKeyPair] = apiServer
+	archive[constants.OpsCenterKeyPair] = opsCertAuthority
+
+	reader, err := utils.CreateTLSArchive(archive)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	defer reader.Close()
 
-	_, err = s.packages().CreatePackage(*caPackage, reader, pack.WithLabels(
+	_, err = s.packages().UpsertPackage(*caPackage, reader, pack.WithLabels(
 		map[string]string{
 			pack.PurposeLabel:     pack.PurposeCA,
 			pack.OperationIDLabel: ctx.operation.ID,
@@ -604,20 +616,39 @@ func (s *site) getPlanetMasterSecretsPackage(ctx *operationContext, p planetMast
 		return nil, trace.Wrap(err)
 	}
 
+	certSigner, err := s.newCertSigner(archive)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	baseKeyPair, err := archive.GetKeyPair(constants.APIServerKeyPair)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	serviceSubnet, err := configure.ParseCIDR(p.serviceSubnetCIDR)
+	// With dual-stack networking, the API server has a service IP
+	// in each of the service subnets
+	apiServerIPs, err := utils.FirstSubnetIPs(p.serviceSubnetCIDR)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(apiServerIPs) == 0 {
+		return nil, trace.BadParameter("missing service subnet")
+	}
+
+	// During certificate authority rotation, the root certificate
+	// is a bundle of all trusted certificate authorities
+	caBundle, err := certAuthorityBundle(archive)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	apiServerIP := serviceSubnet.FirstIP().String()
 
 	newArchive := make(utils.TLSArchive)
 
-	if err := newArchive.AddKeyPair(constants.RootKeyPair, *caKeyPair); err != nil {
+	caBundleKeyPair := *caKeyPair
+	caBundleKeyPair.CertPEM = caBundle
+
+	if err := newArchive.AddKeyPair(constants.RootKeyPair, caBundleKeyPair); err != nil {
 		return nil, trace.Wrap(err)
 	}
 
@@ -635,6 +666,7 @@ func (s *site) getPlanetMasterSecretsPackage(ctx *operationContext, p planetMast
 		constants.PlanetRpcKeyPair:              {},
 		constants.CoreDNSKeyPair:                {},
 		constants.FrontProxyClientKeyPair:       {},
+		constants.DockerRegistryKeyPair:         {},
 	}
 
 	for name, config := range keyPairTypes {
@@ -655,8 +687,10 @@ func (s *site) getPlanetMasterSecretsPackage(ctx *operationContext, p planetMast
 				constants.APIServerDomainNameGravity,
 				constants.APIServerDomainName,
 				constants.LegacyAPIServerDomainName,
-				constants.RegistryDomainName,
-				apiServerIP)
+				constants.RegistryDomainName)
+			for _, ip := range apiServerIPs {
+				req.Hosts = append(req.Hosts, ip.String())
+			}
 			req.Hosts = append(req.Hosts, constants.KubernetesServiceDomainNames...)
 			if p.master.Nodename != "" {
 				req.Hosts = append(req.Hosts, p.master.Nodename)
@@ -679,7 +713,7 @@ func (s *site) getPlanetMasterSecretsPackage(ctx *operationContext, p planetMast
 				constants.APIServerDomainNameGravity,
 				constants.APIServerDomainName)
 		}
-		keyPair, err := authority.GenerateCertificate(req, caKeyPair, baseKeyPair.KeyPEM, defaults.CertificateExpiry)
+		keyPair, err := certSigner.GenerateCertificate(req, baseKeyPair.KeyPEM, defaults.CertificateExpiry)
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
@@ -726,9 +760,20 @@ func (s *site) getPlanetNodeSecretsPackage(ctx *operationContext, node *Provisio
 		return nil, trace.Wrap(err)
 	}
 
+	certSigner, err := s.newCertSigner(archive)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	caBundle, err := certAuthorityBundle(archive)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	newArchive := make(utils.TLSArchive)
 
 	caCertKeyPair := *caKeyPair
+	caCertKeyPair.CertPEM = caBundle
 	caCertKeyPair.KeyPEM = nil
 
 	if err := newArchive.AddKeyPair(constants.RootKeyPair, caCertKeyPair); err != nil {
@@ -736,13 +781,14 @@ func (s *site) getPlanetNodeSecretsPackage(ctx *operationContext, node *Provisio
 	}
 
 	keyPairTypes := map[string]rbacConfig{
-		constants.APIServerKeyPair: {},
-		constants.ETCDKeyPair:      {},
-		constants.KubectlKeyPair:   {group: constants.ClusterNodeGroup},
-		constants.ProxyKeyPair:     {userName: constants.ClusterKubeProxyUser, group: constants.ClusterNodeGroup},
-		constants.KubeletKeyPair:   {userName: constants.ClusterNodeNamePrefix + ":" + node.KubeNodeID(), group: constants.ClusterNodeGroup},
-		constants.PlanetRpcKeyPair: {},
-		constants.CoreDNSKeyPair:   {},
+		constants.APIServerKeyPair:      {},
+		constants.ETCDKeyPair:           {},
+		constants.KubectlKeyPair:        {group: constants.ClusterNodeGroup},
+		constants.ProxyKeyPair:          {userName: constants.ClusterKubeProxyUser, group: constants.ClusterNodeGroup},
+		constants.KubeletKeyPair:        {userName: constants.ClusterNodeNamePrefix + ":" + node.KubeNodeID(), group: constants.ClusterNodeGroup},
+		constants.PlanetRpcKeyPair:      {},
+		constants.CoreDNSKeyPair:        {},
+		constants.DockerRegistryKeyPair: {},
 	}
 
 	var privateKeyPEM []byte
@@ -766,7 +812,7 @@ func (s *site) getPlanetNodeSecretsPackage(ctx *operationContext, node *Provisio
 		if config.group != "" {
 			req.Names = []csr.Name{{O: config.group}}
 		}
-		keyPair, err := authority.GenerateCertificate(req, caKeyPair, privateKeyPEM, defaults.CertificateExpiry)
+		keyPair, err := certSigner.GenerateCertificate(req, privateKeyPEM, defaults.CertificateExpiry)
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
@@ -916,6 +962,7 @@ func (s *site) getPlanetConfig(config planetConfig) (args []string, err error) {
 		fmt.Sprintf("--volume=%v:/var/log", node.InGravity("planet", "log")),
 		fmt.Sprintf("--volume=%v:%v", node.StateDir(), defaults.GravityDir),
 		fmt.Sprintf("--service-uid=%v", s.uid()),
+		fmt.Sprintf("--service-gid=%v", s.gid()),
 	}
 	overrideArgs := map[string]string{
 		"service-subnet": config.installExpand.InstallExpand.Subnets.Service,
@@ -926,6 +973,13 @@ func (s *site) getPlanetConfig(config planetConfig) (args []string, err error) {
 		args = append(args, fmt.Sprintf("--env=%v=%v", k, v))
 	}
 
+	fips := s.backendSite.ClusterState.FIPS
+	if fips {
+		// Restrict docker registry to FIPS-approved cipher suites
+		args = append(args, fmt.Sprintf("--env=%v=[%v]", registryCipherSuitesEnv,
+			strings.Join(defaults.FIPSCipherSuiteNames, ",")))
+	}
+
 	args = append(args, s.addCloudConfig(config.config)...)
 	args = append(args, s.addClusterConfig(config.config, overrideArgs)...)
 
@@ -969,6 +1023,10 @@ func (s *site) getPlanetConfig(config planetConfig) (args []string, err error) {
 	args = append(args, dockerArgs...)
 
 	etcdArgs := manifest.EtcdArgs(*profile)
+	if fips {
+		etcdArgs = append(etcdArgs, fmt.Sprintf("--cipher-suites=%v",
+			strings.Join(defaults.FIPSCipherSuiteNames, ",")))
+	}
 	if len(etcdArgs) != 0 {
 		args = append(args, fmt.Sprintf("--etcd-options=%v", strings.Join(etcdArgs, " ")))
 	}
@@ -988,6 +1046,10 @@ func (s *site) getPlanetConfig(config planetConfig) (args []string, err error) {
 		kubeletArgs = append(kubeletArgs, "--hairpin-mode=none")
 	}
 
+	if profile.Reserved != nil {
+		kubeletArgs = append(kubeletArgs, profile.Reserved.KubeletArgs()...)
+	}
+
 	args = append(args, fmt.Sprintf("--kubelet-options=%v", strings.Join(kubeletArgs, " ")))
 
 	mounts, err := GetMounts(manifest, node.Server)
@@ -1022,8 +1084,10 @@ func (s *site) getPlanetConfig(config planetConfig) (args []string, err error) {
 		args = append(args, fmt.Sprintf("--node-label=%v=%v", k, v))
 	}
 
-	// If the manifest contains an install hook to install a separate overlay network, disable flannel inside planet
-	if manifest.Hooks != nil && manifest.Hooks.NetworkInstall != nil {
+	// If the manifest contains an install hook to install a separate overlay network
+	// or selects a network bundled with gravity, disable flannel inside planet
+	networking := manifest.GetNetworking(s.provider, config.installExpand.Provisioner)
+	if (manifest.Hooks != nil && manifest.Hooks.NetworkInstall != nil) || networking.IsBundled() {
 		args = append(args, "--disable-flannel=true")
 	}
 
@@ -1049,6 +1113,9 @@ func getNodeLabels(node ProvisionedServer, profile *schema.NodeProfile) map[stri
 		labels[defaults.KubernetesRoleLabel] = string(role)
 	}
 	labels[defaults.KubernetesAdvertiseIPLabel] = node.AdvertiseIP
+	if profile.Requirements.GPU.IsRequired() {
+		labels[defaults.KubernetesGPULabel] = profile.Requirements.GPU.GetVendor()
+	}
 	return labels
 }
 
@@ -1464,7 +1531,7 @@ func (s *site) serverPackages(server *ProvisionedServer) ([]loc.Locator, error)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	planetPackage, err := s.app.Manifest.RuntimePackageForProfile(server.Role)
+	planetPackage, err := s.app.Manifest.RuntimePackageForProfileArch(server.Role, server.Arch())
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -181,6 +181,7 @@ multizone=true`,
 		"secrets-dir":                {"/var/lib/gravity/secrets"},
 		"election-enabled":           {"true"},
 		"service-uid":                {"1000"},
+		"service-gid":                {"1000"},
 		"env":                        {"VAR=value", "VAR2=value2"},
 		"volume": {
 			"/var/lib/gravity/planet/etcd:/ext/etcd",
@@ -276,6 +277,7 @@ func (s *ConfigureSuite) TestCanSetCloudProviderWithoutCloudConfig(c *check.C) {
 		"secrets-dir":                {"/var/lib/gravity/secrets"},
 		"election-enabled":           {"true"},
 		"service-uid":                {"1000"},
+		"service-gid":                {"1000"},
 		"volume": {
 			"/var/lib/gravity/planet/etcd:/ext/etcd",
 			"/var/lib/gravity/planet/docker:/ext/docker",
@@ -300,6 +302,99 @@ func (s *ConfigureSuite) TestCanSetCloudProviderWithoutCloudConfig(c *check.C) {
 	}))
 }
 
+func (s *ConfigureSuite) TestRestrictsCipherSuitesInFIPSMode(c *check.C) {
+	s.cluster.backendSite.ClusterState.FIPS = true
+	server := storage.Server{
+		Hostname:    "node-1",
+		ClusterRole: "master",
+		Role:        "node",
+		AdvertiseIP: "172.12.13.0",
+	}
+	config := planetConfig{
+		master: masterConfig{addr: server.AdvertiseIP},
+		manifest: schema.Manifest{
+			NodeProfiles: schema.NodeProfiles{{Name: "node"}},
+		},
+		installExpand: ops.SiteOperation{
+			ID:         "operation-id",
+			AccountID:  "local",
+			SiteDomain: s.cluster.domainName,
+			InstallExpand: &storage.InstallExpandOperationState{
+				Servers: []storage.Server{server},
+			},
+		},
+		server: ProvisionedServer{Server: server},
+		etcd: etcdConfig{
+			initialCluster:      fmt.Sprintf("172.12.13.0.%v", s.cluster.domainName),
+			initialClusterState: "new",
+			proxyMode:           etcdProxyOff,
+		},
+		docker:        storage.DockerConfig{StorageDriver: "overlay2"},
+		planetPackage: loc.MustParseLocator("gravitational.io/planet:0.0.1"),
+		configPackage: loc.MustParseLocator("gravitational.io/planet-config:0.0.1"),
+		config: &clusterconfig.Resource{
+			Kind:    storage.KindClusterConfiguration,
+			Version: "v1",
+			Metadata: teleservices.Metadata{
+				Name:      constants.ClusterConfigurationMap,
+				Namespace: defaults.KubeSystemNamespace,
+			},
+		},
+	}
+	args, err := s.cluster.getPlanetConfig(config)
+	c.Assert(err, check.IsNil)
+	etcdOptions, args := stripItem(args, "--etcd-options")
+	c.Assert(etcdOptions, check.Equals, "--etcd-options=--cipher-suites="+
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,"+
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384")
+	registryEnv, _ := stripItem(args, "--env=REGISTRY_HTTP_TLS_CIPHERSUITES")
+	c.Assert(registryEnv, check.Equals, "--env=REGISTRY_HTTP_TLS_CIPHERSUITES=["+
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,"+
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384]")
+}
+
+func (s *ConfigureSuite) TestDisablesFlannelForBundledNetwork(c *check.C) {
+	server := storage.Server{
+		Hostname:    "node-1",
+		ClusterRole: "master",
+		Role:        "node",
+		AdvertiseIP: "172.12.13.0",
+	}
+	config := planetConfig{
+		master: masterConfig{addr: server.AdvertiseIP},
+		manifest: schema.Manifest{
+			NodeProfiles: schema.NodeProfiles{{Name: "node"}},
+			Providers: &schema.Providers{
+				Generic: schema.Generic{
+					Networking: schema.Networking{Type: schema.NetworkingCalico},
+				},
+			},
+		},
+		installExpand: ops.SiteOperation{
+			ID:          "operation-id",
+			AccountID:   "local",
+			SiteDomain:  s.cluster.domainName,
+			Provisioner: schema.ProvisionerOnPrem,
+			InstallExpand: &storage.InstallExpandOperationState{
+				Servers: []storage.Server{server},
+			},
+		},
+		server: ProvisionedServer{Server: server},
+		etcd: etcdConfig{
+			initialCluster:      fmt.Sprintf("172.12.13.0.%v", s.cluster.domainName),
+			initialClusterState: "new",
+			proxyMode:           etcdProxyOff,
+		},
+		docker:        storage.DockerConfig{StorageDriver: "overlay2"},
+		planetPackage: loc.MustParseLocator("gravitational.io/planet:0.0.1"),
+		configPackage: loc.MustParseLocator("gravitational.io/planet-config:0.0.1"),
+	}
+	args, err := s.cluster.getPlanetConfig(config)
+	c.Assert(err, check.IsNil)
+	disableFlannel, _ := stripItem(args, "--disable-flannel")
+	c.Assert(disableFlannel, check.Equals, "--disable-flannel=true")
+}
+
 func mapToArgs(args map[string][]string) sort.Interface {
 	var result []string
 	for k, v := range args {
@@ -32,6 +32,7 @@ func (s *site) createExpandOperation(ctx context.Context, req ops.CreateSiteExpa
 	log.Debugf("createExpandOperation(%#v)", req)
 
 	profiles := make(map[string]storage.ServerProfile)
+	var serviceRoles []string
 	for role, count := range req.Servers {
 		profile, err := s.app.Manifest.NodeProfiles.ByName(role)
 		if err != nil {
@@ -45,6 +46,12 @@ func (s *site) createExpandOperation(ctx context.Context, req ops.CreateSiteExpa
 				Count: count,
 			},
 		}
+		serviceRoles = append(serviceRoles, string(profile.ServiceRole))
+	}
+	if req.JoinToken != "" {
+		if err := s.useJoinToken(ctx, req.JoinToken, serviceRoles); err != nil {
+			return nil, trace.Wrap(err)
+		}
 	}
 	return s.createInstallExpandOperation(ctx, createInstallExpandOperationRequest{
 		Type:        ops.OperationExpand,
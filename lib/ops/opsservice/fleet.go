@@ -0,0 +1,545 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// CreateFleetUpgrade starts upgrading the active remote clusters matching
+// the request selector to the requested application version.
+//
+// The first canary clusters are upgraded one at a time, the rest are
+// upgraded concurrently. The upgrade is halted once the specified number
+// of clusters have failed to upgrade.
+//
+// The application package must already be available in the upgraded
+// clusters, for example after they have pulled updates from the Ops Center.
+//
+// The upgrade is run by the fleet upgrade runner of the active Ops Center
+// process, see RunFleetUpgrades
+func (o *Operator) CreateFleetUpgrade(ctx context.Context, req ops.CreateFleetUpgradeRequest) (*storage.FleetUpgrade, error) {
+	if err := req.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if !o.fleet.isActive() {
+		return nil, trace.ConnectionProblem(nil,
+			"fleet upgrades are run by the active Ops Center process, retry later")
+	}
+	app, err := loc.ParseLocator(req.App)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	clusters, err := o.getFleetUpgradeClusters(req.AccountID, req.Selector, *app)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	upgrade := storage.FleetUpgrade{
+		ID:          uuid.New(),
+		Created:     o.clock().UtcNow(),
+		CreatedBy:   storage.UserFromContext(ctx),
+		AccountID:   req.AccountID,
+		App:         app.String(),
+		Selector:    req.Selector,
+		Canaries:    req.Canaries,
+		Concurrency: req.Concurrency,
+		MaxFailures: req.MaxFailures,
+		State:       storage.FleetUpgradeStateInProgress,
+	}
+	for _, cluster := range clusters {
+		upgrade.Clusters = append(upgrade.Clusters, storage.FleetUpgradeCluster{
+			Name:  cluster,
+			State: storage.FleetUpgradeStatePending,
+		})
+	}
+	if _, err := o.backend().CreateFleetUpgrade(upgrade); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := o.startFleetUpgrade(upgrade); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &upgrade, nil
+}
+
+// RunFleetUpgrades resumes the fleet upgrades that are in progress and runs
+// the fleet upgrades created while the context is active.
+//
+// The upgrades are stopped once the context is canceled and are resumed
+// by the next runner, e.g. after another Ops Center process is elected leader
+func (o *Operator) RunFleetUpgrades(ctx context.Context) error {
+	o.Info("Starting fleet upgrades.")
+	o.fleet.activate(ctx)
+	defer o.fleet.deactivate()
+	upgrades, err := o.backend().GetFleetUpgrades()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, upgrade := range upgrades {
+		if upgrade.State != storage.FleetUpgradeStateInProgress {
+			continue
+		}
+		o.Infof("Resuming fleet upgrade %v to %v.", upgrade.ID, upgrade.App)
+		if err := o.startFleetUpgrade(upgrade); err != nil {
+			o.Warnf("Failed to resume fleet upgrade %v: %v.", upgrade.ID, trace.DebugReport(err))
+		}
+	}
+	<-ctx.Done()
+	o.fleet.wait()
+	o.Info("Stopped fleet upgrades.")
+	return nil
+}
+
+// CancelFleetUpgrade stops the fleet upgrade specified with the key.
+//
+// The clusters that have not been upgraded yet are skipped, the update
+// operations already started in the clusters are not interrupted
+func (o *Operator) CancelFleetUpgrade(ctx context.Context, key ops.FleetUpgradeKey) error {
+	upgrade, err := o.GetFleetUpgrade(ctx, key)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if upgrade.State != storage.FleetUpgradeStateInProgress {
+		return trace.BadParameter("fleet upgrade %v is %v", upgrade.ID, upgrade.State)
+	}
+	if !o.fleet.isActive() {
+		return trace.ConnectionProblem(nil,
+			"fleet upgrades are run by the active Ops Center process, retry later")
+	}
+	if upgrader := o.fleet.get(upgrade.ID); upgrader != nil {
+		upgrader.stop()
+		select {
+		case <-upgrader.done:
+			return nil
+		case <-ctx.Done():
+			return trace.Wrap(ctx.Err())
+		}
+	}
+	// the upgrade is not running, e.g. it has failed to resume
+	// or has just finished
+	upgrade, err = o.GetFleetUpgrade(ctx, key)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if upgrade.State != storage.FleetUpgradeStateInProgress {
+		return trace.BadParameter("fleet upgrade %v is %v", upgrade.ID, upgrade.State)
+	}
+	cancelFleetUpgrade(upgrade)
+	_, err = o.backend().UpdateFleetUpgrade(*upgrade)
+	return trace.Wrap(err)
+}
+
+// startFleetUpgrade runs the specified fleet upgrade in the background
+func (o *Operator) startFleetUpgrade(upgrade storage.FleetUpgrade) error {
+	return o.fleet.start(&fleetUpgrader{
+		backend:      o.backend(),
+		getOperator:  o.cfg.Clients.OpsClient,
+		pollInterval: defaults.FleetUpgradePollInterval,
+		timeout:      defaults.FleetUpgradeClusterTimeout,
+		upgrade:      upgrade,
+		FieldLogger:  o.WithField("fleet-upgrade", upgrade.ID),
+	})
+}
+
+// GetFleetUpgrade returns the fleet upgrade specified with the key
+func (o *Operator) GetFleetUpgrade(ctx context.Context, key ops.FleetUpgradeKey) (*storage.FleetUpgrade, error) {
+	if err := key.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	upgrade, err := o.backend().GetFleetUpgrade(key.ID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if upgrade.AccountID != key.AccountID {
+		return nil, trace.NotFound("fleet upgrade %v not found", key.ID)
+	}
+	return upgrade, nil
+}
+
+// GetFleetUpgrades returns all fleet upgrades of the specified account
+func (o *Operator) GetFleetUpgrades(ctx context.Context, accountID string) ([]storage.FleetUpgrade, error) {
+	upgrades, err := o.backend().GetFleetUpgrades()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var result []storage.FleetUpgrade
+	for _, upgrade := range upgrades {
+		if upgrade.AccountID == accountID {
+			result = append(result, upgrade)
+		}
+	}
+	return result, nil
+}
+
+// getFleetUpgradeClusters returns the names of the active remote clusters
+// of the account that match the selector and can be upgraded to the
+// specified application package, ordered by name
+func (o *Operator) getFleetUpgradeClusters(accountID string, selector map[string]string, app loc.Locator) ([]string, error) {
+	clusters, err := o.backend().GetSites(accountID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var names []string
+	for _, cluster := range clusters {
+		if cluster.Local || !utils.MatchesLabels(cluster.Labels, selector) {
+			continue
+		}
+		if err := pack.CheckUpdatePackage(cluster.App.Locator(), app); err != nil {
+			o.Debugf("Skipping cluster %v: %v.", cluster.Domain, err)
+			continue
+		}
+		if cluster.State != ops.SiteStateActive {
+			o.Warnf("Skipping cluster %v in state %v.", cluster.Domain, cluster.State)
+			continue
+		}
+		names = append(names, cluster.Domain)
+	}
+	if len(names) == 0 {
+		return nil, trace.NotFound("no active clusters matching %v can be upgraded to %v",
+			selector, app)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func newFleetUpgrades() *fleetUpgrades {
+	return &fleetUpgrades{running: make(map[string]*fleetUpgrader)}
+}
+
+// fleetUpgrades keeps track of the fleet upgrades run by this process
+type fleetUpgrades struct {
+	// mu guards the fields below
+	mu sync.Mutex
+	// ctx is the context the upgrades run in, nil if the runner is not active
+	ctx context.Context
+	// running maps the IDs of the running fleet upgrades to their upgraders
+	running map[string]*fleetUpgrader
+	// wg tracks the running fleet upgrades
+	wg sync.WaitGroup
+}
+
+func (r *fleetUpgrades) activate(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ctx = ctx
+}
+
+func (r *fleetUpgrades) deactivate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ctx = nil
+}
+
+func (r *fleetUpgrades) isActive() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ctx != nil
+}
+
+// wait blocks until all running fleet upgrades have stopped
+func (r *fleetUpgrades) wait() {
+	r.wg.Wait()
+}
+
+// get returns the upgrader of the running fleet upgrade with the specified ID
+func (r *fleetUpgrades) get(id string) *fleetUpgrader {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running[id]
+}
+
+// start runs the upgrader in the background
+func (r *fleetUpgrades) start(upgrader *fleetUpgrader) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ctx == nil {
+		return trace.ConnectionProblem(nil, "fleet upgrade runner is not active")
+	}
+	id := upgrader.upgrade.ID
+	if _, ok := r.running[id]; ok {
+		return trace.AlreadyExists("fleet upgrade %v is already running", id)
+	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	upgrader.cancel = cancel
+	upgrader.done = make(chan struct{})
+	r.running[id] = upgrader
+	r.wg.Add(1)
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.running, id)
+			r.mu.Unlock()
+			cancel()
+			close(upgrader.done)
+			r.wg.Done()
+		}()
+		if err := upgrader.run(ctx); err != nil {
+			upgrader.Errorf("Fleet upgrade failed: %v.", trace.DebugReport(err))
+		}
+	}()
+	return nil
+}
+
+// fleetUpgrader upgrades the clusters of a fleet upgrade
+type fleetUpgrader struct {
+	// backend persists the fleet upgrade progress
+	backend storage.Backend
+	// getOperator returns the operator service of the specified remote cluster
+	getOperator func(clusterName string) (ops.Operator, error)
+	// pollInterval is how often the cluster update operations are polled
+	pollInterval time.Duration
+	// timeout is how long a single cluster is allowed to upgrade
+	timeout time.Duration
+	// FieldLogger is used for logging
+	log.FieldLogger
+	// cancel stops the upgrade
+	cancel context.CancelFunc
+	// done is closed when the upgrade has stopped
+	done chan struct{}
+	// mu guards the fields below
+	mu sync.Mutex
+	// upgrade is the fleet upgrade in progress
+	upgrade storage.FleetUpgrade
+	// canceled is set when the upgrade has been canceled
+	canceled bool
+}
+
+// run upgrades the canary clusters one at a time followed by the
+// remaining clusters with the configured concurrency until all clusters
+// have been upgraded or the failure threshold has been reached.
+//
+// The clusters that have already been upgraded are skipped, so an
+// interrupted upgrade can be resumed. If the context is canceled, the
+// upgrade is left in progress unless it has been canceled explicitly
+func (u *fleetUpgrader) run(ctx context.Context) error {
+	canaries := u.upgrade.Canaries
+	if canaries > len(u.upgrade.Clusters) {
+		canaries = len(u.upgrade.Clusters)
+	}
+	for i := 0; i < canaries && !u.halted() && ctx.Err() == nil; i++ {
+		u.upgradeCluster(ctx, i)
+	}
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, u.upgrade.Concurrency)
+	for i := canaries; i < len(u.upgrade.Clusters); i++ {
+		semaphore <- struct{}{}
+		if u.halted() || ctx.Err() != nil {
+			<-semaphore
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			u.upgradeCluster(ctx, i)
+		}(i)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		if u.isCanceled() {
+			return trace.Wrap(u.finishCanceled())
+		}
+		u.Info("Fleet upgrade interrupted.")
+		return nil
+	}
+	return trace.Wrap(u.finish())
+}
+
+// stop cancels the upgrade
+func (u *fleetUpgrader) stop() {
+	u.mu.Lock()
+	u.canceled = true
+	u.mu.Unlock()
+	if u.cancel != nil {
+		u.cancel()
+	}
+}
+
+func (u *fleetUpgrader) isCanceled() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.canceled
+}
+
+// cluster returns the cluster with the specified index
+func (u *fleetUpgrader) cluster(i int) storage.FleetUpgradeCluster {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.upgrade.Clusters[i]
+}
+
+// upgradeCluster upgrades the cluster with the specified index
+// and records the result.
+//
+// The cluster is left in progress if the context is canceled
+func (u *fleetUpgrader) upgradeCluster(ctx context.Context, i int) {
+	cluster := u.cluster(i)
+	switch cluster.State {
+	case storage.FleetUpgradeStatePending:
+		u.Infof("Upgrading cluster %v to %v.", cluster.Name, u.upgrade.App)
+		u.updateCluster(i, func(cluster *storage.FleetUpgradeCluster) {
+			cluster.State = storage.FleetUpgradeStateInProgress
+		})
+	case storage.FleetUpgradeStateInProgress:
+		u.Infof("Resuming upgrade of cluster %v to %v.", cluster.Name, u.upgrade.App)
+	default:
+		return
+	}
+	err := u.upgradeClusterAndWait(ctx, i)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		u.Warnf("Failed to upgrade cluster %v: %v.", cluster.Name, trace.DebugReport(err))
+	}
+	u.updateCluster(i, func(cluster *storage.FleetUpgradeCluster) {
+		if err != nil {
+			cluster.State = storage.FleetUpgradeStateFailed
+			cluster.Error = trace.UserMessage(err)
+			return
+		}
+		cluster.State = storage.FleetUpgradeStateCompleted
+	})
+}
+
+// upgradeClusterAndWait starts the update operation in the cluster
+// with the specified index, unless it has already been started,
+// and waits for it to finish
+func (u *fleetUpgrader) upgradeClusterAndWait(ctx context.Context, i int) error {
+	cluster := u.cluster(i)
+	operator, err := u.getOperator(cluster.Name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	key := &ops.SiteOperationKey{
+		AccountID:   u.upgrade.AccountID,
+		SiteDomain:  cluster.Name,
+		OperationID: cluster.OperationID,
+	}
+	if key.OperationID == "" {
+		key, err = operator.CreateSiteAppUpdateOperation(ctx, ops.CreateSiteAppUpdateOperationRequest{
+			AccountID:   u.upgrade.AccountID,
+			SiteDomain:  cluster.Name,
+			App:         u.upgrade.App,
+			StartAgents: true,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		u.updateCluster(i, func(cluster *storage.FleetUpgradeCluster) {
+			cluster.OperationID = key.OperationID
+		})
+	}
+	timeout := time.NewTimer(u.timeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(u.pollInterval)
+	defer ticker.Stop()
+	for {
+		operation, err := operator.GetSiteOperation(*key)
+		if err != nil {
+			u.Warnf("Failed to query operation %v in cluster %v: %v.",
+				key.OperationID, cluster.Name, trace.DebugReport(err))
+		} else if operation.IsCompleted() {
+			return nil
+		} else if operation.IsFailed() {
+			return trace.BadParameter("update operation %v failed", key.OperationID)
+		}
+		select {
+		case <-ticker.C:
+		case <-timeout.C:
+			return trace.LimitExceeded("update operation %v did not finish in %v",
+				key.OperationID, u.timeout)
+		case <-ctx.Done():
+			return trace.Wrap(ctx.Err())
+		}
+	}
+}
+
+// halted returns true if the failure threshold has been reached
+func (u *fleetUpgrader) halted() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.upgrade.Failures() >= u.upgrade.MaxFailures
+}
+
+// finish records the final state of the fleet upgrade
+func (u *fleetUpgrader) finish() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.upgrade.State = storage.FleetUpgradeStateCompleted
+	if u.upgrade.Failures() >= u.upgrade.MaxFailures {
+		u.upgrade.State = storage.FleetUpgradeStateHalted
+		for i := range u.upgrade.Clusters {
+			if u.upgrade.Clusters[i].State == storage.FleetUpgradeStatePending {
+				u.upgrade.Clusters[i].State = storage.FleetUpgradeStateSkipped
+			}
+		}
+	}
+	u.Infof("Fleet upgrade to %v %v.", u.upgrade.App, u.upgrade.State)
+	_, err := u.backend.UpdateFleetUpgrade(u.upgrade)
+	return trace.Wrap(err)
+}
+
+// finishCanceled records the canceled fleet upgrade
+func (u *fleetUpgrader) finishCanceled() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	cancelFleetUpgrade(&u.upgrade)
+	u.Infof("Fleet upgrade to %v %v.", u.upgrade.App, u.upgrade.State)
+	_, err := u.backend.UpdateFleetUpgrade(u.upgrade)
+	return trace.Wrap(err)
+}
+
+// cancelFleetUpgrade marks the fleet upgrade canceled, the clusters that
+// have not been upgraded yet are skipped
+func cancelFleetUpgrade(upgrade *storage.FleetUpgrade) {
+	upgrade.State = storage.FleetUpgradeStateCanceled
+	for i, cluster := range upgrade.Clusters {
+		switch cluster.State {
+		case storage.FleetUpgradeStatePending:
+			upgrade.Clusters[i].State = storage.FleetUpgradeStateSkipped
+		case storage.FleetUpgradeStateInProgress:
+			upgrade.Clusters[i].State = storage.FleetUpgradeStateCanceled
+		}
+	}
+}
+
+// updateCluster applies the update to the cluster with the specified index
+// and persists the fleet upgrade
+func (u *fleetUpgrader) updateCluster(i int, update func(*storage.FleetUpgradeCluster)) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	update(&u.upgrade.Clusters[i])
+	if _, err := u.backend.UpdateFleetUpgrade(u.upgrade); err != nil {
+		u.Warnf("Failed to save fleet upgrade: %v.", trace.DebugReport(err))
+	}
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+type FleetSuite struct {
+	backend storage.Backend
+}
+
+var _ = check.Suite(&FleetSuite{})
+
+func (s *FleetSuite) SetUpTest(c *check.C) {
+	var err error
+	s.backend, err = keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(c.MkDir(), "bolt.db")})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *FleetSuite) TearDownTest(c *check.C) {
+	s.backend.Close()
+}
+
+func (s *FleetSuite) TestUpgradesAllClusters(c *check.C) {
+	upgrade := s.runUpgrade(c, storage.FleetUpgrade{
+		Canaries:    1,
+		Concurrency: 2,
+		MaxFailures: 2,
+	}, []string{"a", "b", "c", "d"}, map[string]bool{"c": true})
+
+	c.Assert(upgrade.State, check.Equals, storage.FleetUpgradeStateCompleted)
+	c.Assert(clusterStates(upgrade), check.DeepEquals, []string{
+		storage.FleetUpgradeStateCompleted,
+		storage.FleetUpgradeStateCompleted,
+		storage.FleetUpgradeStateFailed,
+		storage.FleetUpgradeStateCompleted,
+	})
+	c.Assert(upgrade.Clusters[2].Error, check.Not(check.Equals), "")
+	c.Assert(upgrade.Clusters[0].OperationID, check.Equals, "a-update")
+}
+
+func (s *FleetSuite) TestHaltsOnFailedCanary(c *check.C) {
+	upgrade := s.runUpgrade(c, storage.FleetUpgrade{
+		Canaries:    2,
+		Concurrency: 2,
+		MaxFailures: 1,
+	}, []string{"a", "b", "c", "d"}, map[string]bool{"a": true})
+
+	c.Assert(upgrade.State, check.Equals, storage.FleetUpgradeStateHalted)
+	c.Assert(clusterStates(upgrade), check.DeepEquals, []string{
+		storage.FleetUpgradeStateFailed,
+		storage.FleetUpgradeStateSkipped,
+		storage.FleetUpgradeStateSkipped,
+		storage.FleetUpgradeStateSkipped,
+	})
+}
+
+func (s *FleetSuite) TestResumesUpgrade(c *check.C) {
+	upgrade := newFleetUpgrade([]string{"a", "b"})
+	upgrade.Clusters[0].State = storage.FleetUpgradeStateInProgress
+	upgrade.Clusters[0].OperationID = "a-update-started"
+	_, err := s.backend.CreateFleetUpgrade(upgrade)
+	c.Assert(err, check.IsNil)
+	operator := &fakeUpdateOperator{}
+	upgrader := s.newUpgrader(upgrade, func(string) (ops.Operator, error) {
+		return operator, nil
+	})
+
+	c.Assert(upgrader.run(context.TODO()), check.IsNil)
+	out, err := s.backend.GetFleetUpgrade(upgrade.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(out.State, check.Equals, storage.FleetUpgradeStateCompleted)
+	c.Assert(clusterStates(*out), check.DeepEquals, []string{
+		storage.FleetUpgradeStateCompleted,
+		storage.FleetUpgradeStateCompleted,
+	})
+	c.Assert(out.Clusters[0].OperationID, check.Equals, "a-update-started",
+		check.Commentf("started update operation should not be restarted"))
+	c.Assert(operator.created, check.DeepEquals, []string{"b"})
+}
+
+func (s *FleetSuite) TestCancelsUpgrade(c *check.C) {
+	upgrade := newFleetUpgrade([]string{"a", "b", "c"})
+	_, err := s.backend.CreateFleetUpgrade(upgrade)
+	c.Assert(err, check.IsNil)
+	upgrader := s.newUpgrader(upgrade, func(string) (ops.Operator, error) {
+		return &fakeUpdateOperator{inProgress: true}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	upgrades := newFleetUpgrades()
+	upgrades.activate(ctx)
+	c.Assert(upgrades.start(upgrader), check.IsNil)
+	c.Assert(trace.IsAlreadyExists(upgrades.start(upgrader)), check.Equals, true)
+	for upgrader.cluster(0).OperationID == "" {
+		select {
+		case <-time.After(time.Millisecond):
+		case <-upgrader.done:
+			c.Fatal("Upgrade stopped before it was canceled.")
+		}
+	}
+
+	upgrader.stop()
+	select {
+	case <-upgrader.done:
+	case <-time.After(time.Minute):
+		c.Fatal("Timeout waiting for the upgrade to stop.")
+	}
+	c.Assert(upgrades.get(upgrade.ID), check.IsNil)
+	out, err := s.backend.GetFleetUpgrade(upgrade.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(out.State, check.Equals, storage.FleetUpgradeStateCanceled)
+	c.Assert(clusterStates(*out), check.DeepEquals, []string{
+		storage.FleetUpgradeStateCanceled,
+		storage.FleetUpgradeStateSkipped,
+		storage.FleetUpgradeStateSkipped,
+	})
+}
+
+func (s *FleetSuite) TestLeavesInterruptedUpgradeInProgress(c *check.C) {
+	upgrade := newFleetUpgrade([]string{"a", "b"})
+	_, err := s.backend.CreateFleetUpgrade(upgrade)
+	c.Assert(err, check.IsNil)
+	upgrader := s.newUpgrader(upgrade, func(string) (ops.Operator, error) {
+		return &fakeUpdateOperator{inProgress: true}, nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	c.Assert(upgrader.run(ctx), check.IsNil)
+	out, err := s.backend.GetFleetUpgrade(upgrade.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(out.State, check.Equals, storage.FleetUpgradeStateInProgress)
+	c.Assert(clusterStates(*out), check.DeepEquals, []string{
+		storage.FleetUpgradeStateInProgress,
+		storage.FleetUpgradeStatePending,
+	})
+	c.Assert(out.Clusters[0].OperationID, check.Equals, "a-update")
+}
+
+// runUpgrade upgrades the specified clusters with the failing
+// clusters failing their update operations and returns the
+// persisted fleet upgrade
+func (s *FleetSuite) runUpgrade(c *check.C, upgrade storage.FleetUpgrade, clusters []string, failing map[string]bool) storage.FleetUpgrade {
+	template := newFleetUpgrade(clusters)
+	upgrade.ID, upgrade.Created, upgrade.AccountID = template.ID, template.Created, template.AccountID
+	upgrade.App, upgrade.State, upgrade.Clusters = template.App, template.State, template.Clusters
+	_, err := s.backend.CreateFleetUpgrade(upgrade)
+	c.Assert(err, check.IsNil)
+	upgrader := s.newUpgrader(upgrade, func(clusterName string) (ops.Operator, error) {
+		return &fakeUpdateOperator{failed: failing[clusterName]}, nil
+	})
+	c.Assert(upgrader.run(context.TODO()), check.IsNil)
+	out, err := s.backend.GetFleetUpgrade(upgrade.ID)
+	c.Assert(err, check.IsNil)
+	return *out
+}
+
+func (s *FleetSuite) newUpgrader(upgrade storage.FleetUpgrade, getOperator func(string) (ops.Operator, error)) *fleetUpgrader {
+	return &fleetUpgrader{
+		backend:      s.backend,
+		getOperator:  getOperator,
+		pollInterval: time.Millisecond,
+		timeout:      time.Minute,
+		upgrade:      upgrade,
+		FieldLogger:  logrus.WithField(trace.Component, "test"),
+	}
+}
+
+// newFleetUpgrade returns a fleet upgrade of the specified clusters
+// that upgrades the canary cluster first and the rest one at a time
+func newFleetUpgrade(clusters []string) storage.FleetUpgrade {
+	upgrade := storage.FleetUpgrade{
+		ID:          "upgrade",
+		Created:     time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		AccountID:   "system",
+		App:         "example.com/app:2.0.0",
+		State:       storage.FleetUpgradeStateInProgress,
+		Canaries:    1,
+		Concurrency: 1,
+		MaxFailures: 1,
+	}
+	for _, cluster := range clusters {
+		upgrade.Clusters = append(upgrade.Clusters, storage.FleetUpgradeCluster{
+			Name:  cluster,
+			State: storage.FleetUpgradeStatePending,
+		})
+	}
+	return upgrade
+}
+
+func clusterStates(upgrade storage.FleetUpgrade) (states []string) {
+	for _, cluster := range upgrade.Clusters {
+		states = append(states, cluster.State)
+	}
+	return states
+}
+
+// fakeUpdateOperator is a remote cluster operator whose update
+// operations finish immediately unless inProgress is set
+type fakeUpdateOperator struct {
+	ops.Operator
+	// failed specifies whether the update operation fails
+	failed bool
+	// inProgress specifies whether the update operation never finishes
+	inProgress bool
+	// mu guards created
+	mu sync.Mutex
+	// created lists the clusters the update operations have been created in
+	created []string
+}
+
+func (o *fakeUpdateOperator) CreateSiteAppUpdateOperation(ctx context.Context, req ops.CreateSiteAppUpdateOperationRequest) (*ops.SiteOperationKey, error) {
+	o.mu.Lock()
+	o.created = append(o.created, req.SiteDomain)
+	o.mu.Unlock()
+	return &ops.SiteOperationKey{
+		AccountID:   req.AccountID,
+		SiteDomain:  req.SiteDomain,
+		OperationID: req.SiteDomain + "-update",
+	}, nil
+}
+
+func (o *fakeUpdateOperator) GetSiteOperation(key ops.SiteOperationKey) (*ops.SiteOperation, error) {
+	state := ops.OperationStateCompleted
+	switch {
+	case o.failed:
+		state = ops.OperationStateFailed
+	case o.inProgress:
+		state = ops.OperationStateUpdateInProgress
+	}
+	return &ops.SiteOperation{ID: key.OperationID, State: state}, nil
+}
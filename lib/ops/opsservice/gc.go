@@ -19,13 +19,74 @@ package opsservice
 import (
 	"context"
 
+	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/pack/gc"
 	"github.com/gravitational/gravity/lib/storage"
 
 	"github.com/gravitational/trace"
 	"github.com/pborman/uuid"
 )
 
+// CollectPackageGarbage removes packages that are not reachable from the
+// applications in use from the package service.
+// Applications in use are the applications installed in the clusters
+// and the applications the active update operations are updating to
+func (o *Operator) CollectPackageGarbage(ctx context.Context, req ops.CollectPackageGarbageRequest) (*ops.CollectPackageGarbageResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if _, err := o.openSite(req.ClusterKey); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	roots, err := o.packageRoots()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	report, err := gc.Collect(gc.Config{
+		Packages:         o.packages(),
+		Roots:            roots,
+		ProtectionWindow: req.ProtectionWindow,
+		DryRun:           req.DryRun,
+		Clock:            o.clock(),
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &ops.CollectPackageGarbageResponse{
+		Deleted:        report.Deleted,
+		Protected:      report.Protected,
+		ReclaimedBytes: report.ReclaimedBytes,
+	}, nil
+}
+
+// packageRoots returns the application packages in use by all clusters
+// managed by this operator
+func (o *Operator) packageRoots() (roots []loc.Locator, err error) {
+	clusters, err := o.backend().GetAllSites()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, cluster := range clusters {
+		roots = append(roots, cluster.App.Locator())
+		operations, err := o.backend().GetSiteOperations(cluster.Domain)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, operation := range operations {
+			if (*ops.SiteOperation)(&operation).IsFinished() || operation.Update == nil {
+				continue
+			}
+			update, err := operation.Update.Package()
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			roots = append(roots, *update)
+		}
+	}
+	return loc.Deduplicate(roots), nil
+}
+
 // createGarbageCollectOperation creates a new garbage collection operation in the cluster
 func (s *site) createGarbageCollectOperation(ctx context.Context, req ops.CreateClusterGarbageCollectOperationRequest) (*ops.SiteOperationKey, error) {
 	_, err := ops.GetCompletedInstallOperation(s.key, s.service)
@@ -0,0 +1,85 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+)
+
+// GetHealingPolicy returns the cluster healing policy
+func (o *Operator) GetHealingPolicy(key ops.SiteKey) (storage.HealingPolicy, error) {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	data, err := getConfigMap(client.Core().ConfigMaps(constants.KubeSystemNamespace),
+		constants.HealingPolicyConfigMap)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("no healing policy found")
+		}
+		return nil, trace.Wrap(err)
+	}
+
+	policy, err := storage.UnmarshalHealingPolicy([]byte(data))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return policy, nil
+}
+
+// UpdateHealingPolicy updates the cluster healing policy
+func (o *Operator) UpdateHealingPolicy(key ops.SiteKey, policy storage.HealingPolicy) error {
+	if err := policy.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	data, err := storage.MarshalHealingPolicy(policy)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return updateConfigMap(client.Core().ConfigMaps(constants.KubeSystemNamespace),
+		constants.HealingPolicyConfigMap, constants.KubeSystemNamespace, string(data), nil)
+}
+
+// DeleteHealingPolicy deletes the cluster healing policy
+func (o *Operator) DeleteHealingPolicy(key ops.SiteKey) error {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = rigging.ConvertError(client.Core().ConfigMaps(constants.KubeSystemNamespace).Delete(
+		constants.HealingPolicyConfigMap, nil))
+	if trace.IsNotFound(err) {
+		return trace.NotFound("no healing policy found")
+	}
+	return trace.Wrap(err)
+}
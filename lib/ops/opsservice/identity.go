@@ -18,30 +18,88 @@ package opsservice
 
 import (
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/users"
 
 	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
 )
 
 // UpsertUser creates or updates a user
+//
+// For accounts other than the system account, new users are created
+// in the account of the key
 func (o *Operator) UpsertUser(key ops.SiteKey, user teleservices.User) error {
+	if !users.IsSystemAccount(key.AccountID) {
+		existing, err := o.cfg.Users.GetTelekubeUser(user.GetName())
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		if err == nil && existing.GetAccountID() != key.AccountID {
+			return trace.NotFound("user %v not found", user.GetName())
+		}
+		if userV2, ok := user.(*storage.UserV2); ok && userV2.Spec.AccountID == "" {
+			userV2.Spec.AccountID = key.AccountID
+		}
+		if storageUser, ok := user.(storage.User); ok && storageUser.GetAccountID() != key.AccountID {
+			return trace.AccessDenied("user %v does not belong to account %v",
+				user.GetName(), key.AccountID)
+		}
+	}
 	return o.cfg.Users.UpsertUser(user)
 }
 
 // GetUser returns a user by name
 func (o *Operator) GetUser(key ops.SiteKey, name string) (teleservices.User, error) {
+	if err := o.checkUserAccount(key.AccountID, name); err != nil {
+		return nil, trace.Wrap(err)
+	}
 	return o.cfg.Users.GetUser(name)
 }
 
-// GetUsers returns all users
+// GetUsers returns all users of the cluster account.
+// Users of all accounts are returned for the system account
 func (o *Operator) GetUsers(key ops.SiteKey) ([]teleservices.User, error) {
-	return o.cfg.Users.GetUsers()
+	if users.IsSystemAccount(key.AccountID) {
+		return o.cfg.Users.GetUsers()
+	}
+	accountUsers, err := o.cfg.Users.GetUsersByAccountID(key.AccountID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result := make([]teleservices.User, 0, len(accountUsers))
+	for _, user := range accountUsers {
+		result = append(result, user)
+	}
+	return result, nil
 }
 
 // DeleteUser deletes a user by name
 func (o *Operator) DeleteUser(key ops.SiteKey, name string) error {
+	if err := o.checkUserAccount(key.AccountID, name); err != nil {
+		return trace.Wrap(err)
+	}
 	return o.cfg.Users.DeleteUser(name)
 }
 
+// checkUserAccount returns an error if the user with the specified name
+// does not belong to the specified account.
+// Users of other accounts are reported as not found so their existence
+// is not disclosed. The system account can access users of all accounts
+func (o *Operator) checkUserAccount(accountID, name string) error {
+	if users.IsSystemAccount(accountID) {
+		return nil
+	}
+	user, err := o.cfg.Users.GetTelekubeUser(name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if user.GetAccountID() != accountID {
+		return trace.NotFound("user %v not found", name)
+	}
+	return nil
+}
+
 // UpsertClusterAuthPreference updates cluster authentication preference
 func (o *Operator) UpsertClusterAuthPreference(key ops.SiteKey, auth teleservices.AuthPreference) error {
 	return o.cfg.Users.SetAuthPreference(auth)
@@ -75,3 +133,47 @@ func (o *Operator) GetGithubConnectors(key ops.SiteKey, withSecrets bool) ([]tel
 func (o *Operator) DeleteGithubConnector(key ops.SiteKey, name string) error {
 	return o.cfg.Users.DeleteGithubConnector(name)
 }
+
+// UpsertSAMLConnector creates or updates a SAML connector
+func (o *Operator) UpsertSAMLConnector(key ops.SiteKey, connector teleservices.SAMLConnector) error {
+	return o.cfg.Users.UpsertSAMLConnector(connector)
+}
+
+// GetSAMLConnector returns a SAML connector by name
+//
+// Returned connector excludes signing key unless withSecrets is true.
+func (o *Operator) GetSAMLConnector(key ops.SiteKey, name string, withSecrets bool) (teleservices.SAMLConnector, error) {
+	return o.cfg.Users.GetSAMLConnector(name, withSecrets)
+}
+
+// GetSAMLConnectors returns all SAML connectors
+//
+// Returned connectors exclude signing key unless withSecrets is true.
+func (o *Operator) GetSAMLConnectors(key ops.SiteKey, withSecrets bool) ([]teleservices.SAMLConnector, error) {
+	return o.cfg.Users.GetSAMLConnectors(withSecrets)
+}
+
+// DeleteSAMLConnector deletes a SAML connector by name
+func (o *Operator) DeleteSAMLConnector(key ops.SiteKey, name string) error {
+	return o.cfg.Users.DeleteSAMLConnector(name)
+}
+
+// UpsertRoleMapping creates or updates a mapping of OIDC claims to roles
+func (o *Operator) UpsertRoleMapping(key ops.SiteKey, mapping storage.RoleMapping) error {
+	return o.cfg.Users.UpsertRoleMapping(mapping)
+}
+
+// GetRoleMapping returns a role mapping by name
+func (o *Operator) GetRoleMapping(key ops.SiteKey, name string) (storage.RoleMapping, error) {
+	return o.cfg.Users.GetRoleMapping(name)
+}
+
+// GetRoleMappings returns all role mappings
+func (o *Operator) GetRoleMappings(key ops.SiteKey) ([]storage.RoleMapping, error) {
+	return o.cfg.Users.GetRoleMappings()
+}
+
+// DeleteRoleMapping deletes a role mapping by name
+func (o *Operator) DeleteRoleMapping(key ops.SiteKey, name string) error {
+	return o.cfg.Users.DeleteRoleMapping(name)
+}
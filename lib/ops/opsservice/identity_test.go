@@ -0,0 +1,222 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/users"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+type IdentitySuite struct {
+	services TestServices
+	// tenant is the operator of a user of account tenant-a
+	tenant ops.Operator
+}
+
+var _ = check.Suite(&IdentitySuite{})
+
+func (s *IdentitySuite) SetUpTest(c *check.C) {
+	s.services = SetupTestServices(c)
+	role, err := users.NewAdminRole()
+	c.Assert(err, check.IsNil)
+	c.Assert(s.services.Users.UpsertRole(role, 0), check.IsNil)
+	for name, accountID := range map[string]string{
+		"admin@example.com": defaults.SystemAccountID,
+		"alice@example.com": "tenant-a",
+		"bob@example.com":   "tenant-b",
+	} {
+		err := s.services.Users.UpsertUser(storage.NewUser(name, storage.UserSpecV2{
+			Type:      storage.AgentUser,
+			Roles:     []string{role.GetName()},
+			AccountID: accountID,
+		}))
+		c.Assert(err, check.IsNil)
+	}
+	alice, err := s.services.Users.GetTelekubeUser("alice@example.com")
+	c.Assert(err, check.IsNil)
+	checker, err := s.services.Users.GetAccessChecker(alice)
+	c.Assert(err, check.IsNil)
+	s.tenant = ops.OperatorWithACL(s.services.Operator, s.services.Users, alice, checker)
+}
+
+func (s *IdentitySuite) TestDeniesCrossAccountAccess(c *check.C) {
+	const bob = "bob@example.com"
+	ownKey := ops.SiteKey{AccountID: "tenant-a", SiteDomain: "example.com"}
+	otherKey := ops.SiteKey{AccountID: "tenant-b", SiteDomain: "example.com"}
+	update := func(key ops.SiteKey) error {
+		return s.tenant.UpdateUser(context.TODO(), ops.UpdateUserRequest{
+			SiteKey: key,
+			Name:    bob,
+			Roles:   []string{"admin"},
+		})
+	}
+	reset := func(key ops.SiteKey) error {
+		_, err := s.tenant.CreateUserReset(context.TODO(), ops.CreateUserResetRequest{
+			SiteKey: key,
+			Name:    bob,
+			TTL:     time.Hour,
+		})
+		return err
+	}
+	upsert := func(key ops.SiteKey, accountID string) error {
+		return s.tenant.UpsertUser(key, storage.NewUser(bob, storage.UserSpecV2{
+			Type:      storage.AgentUser,
+			AccountID: accountID,
+		}))
+	}
+	testCases := []struct {
+		comment string
+		call    func() error
+	}{
+		{
+			comment: "get user of another account",
+			call: func() error {
+				_, err := s.tenant.GetUser(ownKey, bob)
+				return err
+			},
+		},
+		{
+			comment: "get user with the key of another account",
+			call: func() error {
+				_, err := s.tenant.GetUser(otherKey, bob)
+				return err
+			},
+		},
+		{
+			comment: "upsert user of another account",
+			call:    func() error { return upsert(ownKey, "tenant-a") },
+		},
+		{
+			comment: "upsert user with the key of another account",
+			call:    func() error { return upsert(otherKey, "tenant-b") },
+		},
+		{
+			comment: "create user in the system account",
+			call: func() error {
+				return s.tenant.CreateUser(ops.NewUserRequest{
+					Name: "carol@example.com",
+					Type: storage.AgentUser,
+				})
+			},
+		},
+		{
+			comment: "update user of another account",
+			call:    func() error { return update(ownKey) },
+		},
+		{
+			comment: "update user with the key of another account",
+			call:    func() error { return update(otherKey) },
+		},
+		{
+			comment: "delete local user of another account",
+			call:    func() error { return s.tenant.DeleteLocalUser(bob) },
+		},
+		{
+			comment: "delete user of another account",
+			call:    func() error { return s.tenant.DeleteUser(ownKey, bob) },
+		},
+		{
+			comment: "reset user of another account",
+			call:    func() error { return reset(ownKey) },
+		},
+		{
+			comment: "reset user with the key of another account",
+			call:    func() error { return reset(otherKey) },
+		},
+		{
+			comment: "reset password of user of another account",
+			call: func() error {
+				_, err := s.tenant.ResetUserPassword(ops.ResetUserPasswordRequest{
+					AccountID:  "tenant-a",
+					SiteDomain: "example.com",
+					Email:      bob,
+				})
+				return err
+			},
+		},
+	}
+	for _, tc := range testCases {
+		err := tc.call()
+		c.Assert(trace.IsAccessDenied(err), check.Equals, true,
+			check.Commentf("%v: expected AccessDenied, got %v", tc.comment, err))
+	}
+
+	user, err := s.services.Users.GetTelekubeUser(bob)
+	c.Assert(err, check.IsNil)
+	c.Assert(user.GetAccountID(), check.Equals, "tenant-b")
+	c.Assert(user.GetRoles(), check.DeepEquals, []string{constants.RoleAdmin})
+}
+
+func (s *IdentitySuite) TestAllowsAccessWithinAccount(c *check.C) {
+	key := ops.SiteKey{AccountID: "tenant-a", SiteDomain: "example.com"}
+	_, err := s.tenant.GetUser(key, "alice@example.com")
+	c.Assert(err, check.IsNil)
+
+	err = s.tenant.UpsertUser(key, storage.NewUser("carol@example.com", storage.UserSpecV2{
+		Type:  storage.AgentUser,
+		Roles: []string{constants.RoleAdmin},
+	}))
+	c.Assert(err, check.IsNil)
+	carol, err := s.services.Users.GetTelekubeUser("carol@example.com")
+	c.Assert(err, check.IsNil)
+	c.Assert(carol.GetAccountID(), check.Equals, "tenant-a",
+		check.Commentf("new user should be created in the account of the key"))
+
+	c.Assert(s.tenant.DeleteUser(key, "carol@example.com"), check.IsNil)
+}
+
+func (s *IdentitySuite) TestHidesUsersOfOtherAccounts(c *check.C) {
+	operator := s.services.Operator
+	key := ops.SiteKey{AccountID: "tenant-a", SiteDomain: "example.com"}
+	_, err := operator.GetUser(key, "bob@example.com")
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+	err = operator.UpdateUser(context.TODO(), ops.UpdateUserRequest{
+		SiteKey: key,
+		Name:    "bob@example.com",
+		Roles:   []string{"admin"},
+	})
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+	_, err = operator.CreateUserReset(context.TODO(), ops.CreateUserResetRequest{
+		SiteKey: key,
+		Name:    "bob@example.com",
+	})
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+	_, err = operator.ResetUserPassword(ops.ResetUserPasswordRequest{
+		AccountID: key.AccountID,
+		Email:     "bob@example.com",
+	})
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+	err = operator.UpsertUser(key, storage.NewUser("bob@example.com", storage.UserSpecV2{
+		Type:      storage.AgentUser,
+		AccountID: "tenant-a",
+	}))
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+	err = operator.UpsertUser(key, storage.NewUser("dave@example.com", storage.UserSpecV2{
+		Type:      storage.AgentUser,
+		AccountID: "tenant-b",
+	}))
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+}
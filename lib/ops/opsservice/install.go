@@ -604,6 +604,11 @@ func (s *site) checkLicense(site *storage.Site, op *ops.SiteOperation, req ops.O
 		return trace.Wrap(err, "failed to parse license")
 	}
 
+	err = ops.CheckLicenseExpiration(license.GetPayload(), s.clock().UtcNow())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	// for on-prem and AWS scenarios the license is checked a little bit differently
 	if op.Provisioner == schema.ProvisionerAWSTerraform {
 		err = s.checkLicenseAWS(license, op)
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"html/template"
 	"net/url"
+	"path/filepath"
 	"strings"
 
 	"github.com/gravitational/gravity/lib/constants"
@@ -40,8 +41,12 @@ var (
 set -e
 
 CURL_OPTS="--retry 100 --retry-delay 0 --connect-timeout 10 --max-time 300 --tlsv1.2 --silent --show-error --http1.0"
+case $(uname -m) in
+{{range $arch, $url := .gravity_urls}}    {{$arch}}) GRAVITY_URL={{$url}} ;;
+{{end}}    *) echo "$(date) [ERROR] Architecture $(uname -m) is not supported by the cluster image" && exit 1 ;;
+esac
 echo "$(date) [INFO] Downloading install agent..."
-curl $CURL_OPTS {{if .devmode}}-k{{end}} -H "Authorization: Bearer {{.ops_token}}" {{.gravity_url}} -o {{.gravity_bin_path}}
+curl $CURL_OPTS {{if .devmode}}-k{{end}} -H "Authorization: Bearer {{.ops_token}}" $GRAVITY_URL -o {{.gravity_bin_path}}
 chmod 755 {{.gravity_bin_path}}
 
 echo "$(date) [INFO] Install agent will be using ${TMPDIR:-/tmp} for temporary files"
@@ -85,6 +90,25 @@ echo "$(date) [INFO] Install agent will be using ${TMPDIR:-/tmp} for temporary f
     --operation-id={{.operation_id}} {{if .background}}1>/dev/null 2>&1 &{{end}}
 `, gravityTemplateSource)))
 
+	// provisionTemplate is a template for the script provisioned machines
+	// run on boot to join the cluster. Some cloud providers run the script
+	// on every boot so it exits once the node has joined
+	provisionTemplate = template.Must(
+		template.New("instructions").Parse(fmt.Sprintf(`#!/bin/bash
+if [ -e {{.marker_path}} ]; then
+    echo "$(date) [INFO] Node has already joined the cluster"
+    exit 0
+fi
+%v
+{{.service_user_env}}={{.service_uid}} \
+{{.service_group_env}}={{.service_gid}} \
+{{.gravity_bin_path}} {{if .devmode}}--insecure{{end}} --debug join {{.peer_addrs}} \
+    --token={{.join_token}} \
+    --role={{.profile}} \
+    --cloud-provider={{.cloud_provider}}
+touch {{.marker_path}}
+`, strings.TrimPrefix(gravityTemplateSource, "\n#!/bin/bash\n"))))
+
 	downloadInstructionsTemplate = template.Must(
 		template.New("instructions").Parse(`
 curl -s --tlsv1.2 -0 {{if .devmode}}-k{{end}} "{{.url}}" | sudo bash
@@ -130,7 +154,7 @@ func (s *site) getInstallInstructions(token storage.ProvisioningToken, serverPro
 		"devmode":           s.shouldUseInsecure(),
 		"service_uid":       s.uid(),
 		"service_gid":       s.gid(),
-		"gravity_url":       s.packages().PackageDownloadURL(s.gravityPackage),
+		"gravity_urls":      s.gravityDownloadURLs(),
 		"advertise_addr":    params.Get(schema.AdvertiseAddr),
 		"install_token":     token.Token,
 		"cluster_name":      token.SiteDomain,
@@ -157,6 +181,18 @@ func (s *site) getInstallInstructions(token storage.ProvisioningToken, serverPro
 	return out.String(), nil
 }
 
+// gravityDownloadURLs returns the download URLs of the gravity binary
+// for each CPU architecture the cluster image supports, keyed by
+// the architecture name as reported by uname -m
+func (s *site) gravityDownloadURLs() map[string]string {
+	urls := make(map[string]string)
+	for _, arch := range s.app.Manifest.Architectures() {
+		urls[schema.MachineArch(arch)] = s.packages().PackageDownloadURL(
+			schema.PackageForArch(s.gravityPackage, arch))
+	}
+	return urls
+}
+
 // getJoinInstructions returns a bash script source that starts agents for
 // a wizard installation or expand
 func (s *site) getJoinInstructions(token storage.ProvisioningToken, serverProfile string, params url.Values) (string, error) {
@@ -171,7 +207,7 @@ func (s *site) getJoinInstructions(token storage.ProvisioningToken, serverProfil
 		"devmode":           s.shouldUseInsecure(),
 		"service_uid":       s.uid(),
 		"service_gid":       s.gid(),
-		"gravity_url":       s.packages().PackageDownloadURL(s.gravityPackage),
+		"gravity_urls":      s.gravityDownloadURLs(),
 		"advertise_addr":    params.Get(schema.AdvertiseAddr),
 		"install_token":     token.Token,
 		"profile":           serverProfile,
@@ -192,3 +228,49 @@ func (s *site) getJoinInstructions(token storage.ProvisioningToken, serverProfil
 	}
 	return out.String(), nil
 }
+
+// getProvisionInstructions returns a bash script source that provisioned
+// machines run on boot to join the cluster as nodes of the specified profile
+func (s *site) getProvisionInstructions(serverProfile string) (string, error) {
+	agentToken, err := s.service.GetClusterAgent(ops.ClusterAgentRequest{
+		AccountID:   s.key.AccountID,
+		ClusterName: s.key.SiteDomain,
+	})
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	joinToken, err := s.service.GetExpandToken(s.key)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	var peers []string
+	for _, server := range s.backendSite.ClusterState.Servers {
+		if server.IsMaster() {
+			peers = append(peers, server.AdvertiseIP)
+		}
+	}
+	if len(peers) == 0 {
+		return "", trace.NotFound("cluster has no master nodes to join")
+	}
+	vars := map[string]interface{}{
+		"devmode":           s.shouldUseInsecure(),
+		"service_uid":       s.uid(),
+		"service_gid":       s.gid(),
+		"gravity_urls":      s.gravityDownloadURLs(),
+		"peer_addrs":        strings.Join(peers, ","),
+		"join_token":        joinToken.Token,
+		"profile":           serverProfile,
+		"ops_token":         agentToken.Password,
+		"service_user_env":  constants.ServiceUserEnvVar,
+		"service_group_env": constants.ServiceGroupEnvVar,
+		"gravity_bin_path":  defaults.GravityBin,
+		"cloud_provider":    s.provider,
+		"marker_path":       filepath.Join(defaults.GravityDir, "provisioned"),
+	}
+	var out bytes.Buffer
+	err = provisionTemplate.Execute(&out, vars)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// CancelSiteOperation requests cancellation of the specified operation.
+// The operation plan checks for the request between phases and aborts
+// rolling back the phase in progress
+func (o *Operator) CancelSiteOperation(ctx context.Context, key ops.SiteOperationKey) error {
+	return trace.Wrap(o.setOperationInterrupt(key, storage.OperationInterruptCancel))
+}
+
+// PauseSiteOperation requests the specified operation to stop after
+// the phase in progress so it can be resumed with 'gravity plan resume'
+func (o *Operator) PauseSiteOperation(ctx context.Context, key ops.SiteOperationKey) error {
+	return trace.Wrap(o.setOperationInterrupt(key, storage.OperationInterruptPause))
+}
+
+// ResumeSiteOperation clears the pause request of the specified operation.
+// A cancelled operation cannot be resumed
+func (o *Operator) ResumeSiteOperation(ctx context.Context, key ops.SiteOperationKey) error {
+	cluster, err := o.openSite(key.SiteKey())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	operation, err := cluster.getSiteOperation(key.OperationID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch operation.Interrupt {
+	case "":
+		return nil
+	case storage.OperationInterruptCancel:
+		return trace.BadParameter("operation %v has been cancelled and can only be rolled back",
+			operation)
+	}
+	o.Infof("Resuming operation %v.", operation)
+	operation.Interrupt = ""
+	_, err = cluster.updateSiteOperation(operation)
+	return trace.Wrap(err)
+}
+
+func (o *Operator) setOperationInterrupt(key ops.SiteOperationKey, interrupt string) error {
+	cluster, err := o.openSite(key.SiteKey())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	operation, err := cluster.getSiteOperation(key.OperationID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if operation.IsFinished() {
+		return trace.BadParameter("operation %v has already finished", operation)
+	}
+	if operation.Interrupt == storage.OperationInterruptCancel {
+		if interrupt == storage.OperationInterruptCancel {
+			return nil
+		}
+		return trace.BadParameter("operation %v is being cancelled", operation)
+	}
+	o.Infof("Requesting %v of operation %v.", interrupt, operation)
+	operation.Interrupt = interrupt
+	_, err = cluster.updateSiteOperation(operation)
+	return trace.Wrap(err)
+}
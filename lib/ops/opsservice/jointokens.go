@@ -0,0 +1,178 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/events"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/users"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// CreateJoinToken creates a new join token limited in time, number of uses
+// or roles of the joining nodes
+func (o *Operator) CreateJoinToken(ctx context.Context, req ops.CreateJoinTokenRequest) (*storage.ProvisioningToken, error) {
+	err := req.CheckAndSetDefaults()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	site, err := o.openSite(req.SiteKey)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	agent, err := site.agentUser()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	tokenID, err := users.CryptoRandomToken(defaults.ProvisioningTokenBytes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	token, err := o.users().CreateProvisioningToken(storage.ProvisioningToken{
+		Token:      tokenID,
+		Type:       storage.ProvisioningTokenTypeExpand,
+		AccountID:  req.AccountID,
+		SiteDomain: req.SiteDomain,
+		UserEmail:  agent.GetName(),
+		Expires:    o.clock().UtcNow().Add(req.TTL),
+		MaxUses:    req.MaxUses,
+		Roles:      req.Roles,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	events.Emit(ctx, o, events.JoinTokenCreated, events.Fields{
+		events.FieldName:    maskToken(token.Token),
+		events.FieldRoles:   token.Roles,
+		events.FieldMaxUses: token.MaxUses,
+		events.FieldExpires: token.Expires,
+	})
+	return token, nil
+}
+
+// GetJoinTokens returns the active scoped join tokens of the cluster
+func (o *Operator) GetJoinTokens(ctx context.Context, key ops.SiteKey) ([]storage.ProvisioningToken, error) {
+	tokens, err := o.backend().GetSiteProvisioningTokens(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var joinTokens []storage.ProvisioningToken
+	for _, token := range tokens {
+		if token.IsScopedJoinToken() {
+			joinTokens = append(joinTokens, token)
+		}
+	}
+	return joinTokens, nil
+}
+
+// DeleteJoinToken revokes the specified scoped join token
+func (o *Operator) DeleteJoinToken(ctx context.Context, req ops.DeleteJoinTokenRequest) error {
+	err := req.Check()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	token, err := o.backend().GetProvisioningToken(req.Token)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !token.IsScopedJoinToken() || token.SiteDomain != req.SiteDomain {
+		return trace.NotFound("join token %v not found", maskToken(req.Token))
+	}
+	err = o.backend().DeleteProvisioningToken(req.Token)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	events.Emit(ctx, o, events.JoinTokenRevoked, events.Fields{
+		events.FieldName:   maskToken(token.Token),
+		events.FieldReason: "revoked by user",
+	})
+	return nil
+}
+
+// useJoinToken validates the join token a node has authenticated with
+// against the service roles of the servers the node is going to add and
+// records the use.
+// Tokens other than scoped join tokens are not restricted.
+// A token that has been used up is revoked after a grace period that lets
+// the joining node complete the operation
+func (s *site) useJoinToken(ctx context.Context, tokenID string, roles []string) error {
+	var exhausted bool
+	err := utils.Retry(defaults.ProvisionRetryInterval, defaults.RetryLessAttempts, func() error {
+		token, err := s.backend().GetProvisioningToken(tokenID)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				// the node has authenticated with credentials other than
+				// a provisioning token
+				return nil
+			}
+			return utils.Abort(err)
+		}
+		if !token.IsScopedJoinToken() {
+			return nil
+		}
+		if token.SiteDomain != s.key.SiteDomain {
+			return utils.Abort(trace.AccessDenied("join token is not valid for cluster %v",
+				s.key.SiteDomain))
+		}
+		now := s.clock().UtcNow()
+		for _, role := range roles {
+			if err := token.CheckJoin(role, now); err != nil {
+				return utils.Abort(err)
+			}
+		}
+		updated := *token
+		updated.Uses++
+		exhausted = updated.MaxUses != 0 && updated.Uses >= updated.MaxUses
+		if exhausted {
+			revokeAt := now.Add(defaults.JoinTokenRevocationDelay)
+			if revokeAt.Before(updated.Expires) || updated.Expires.IsZero() {
+				updated.Expires = revokeAt
+			}
+		}
+		err = s.backend().CompareAndSwapProvisioningToken(updated, *token)
+		if err != nil && !trace.IsCompareFailed(err) {
+			return utils.Abort(err)
+		}
+		return trace.Wrap(err)
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if exhausted {
+		events.Emit(ctx, s.service, events.JoinTokenRevoked, events.Fields{
+			events.FieldName:   maskToken(tokenID),
+			events.FieldReason: "maximum number of uses reached",
+		})
+	}
+	return nil
+}
+
+// maskToken returns the token with all but the first few characters
+// replaced so it can be displayed in the audit log
+func maskToken(token string) string {
+	const visible = 6
+	if len(token) <= visible {
+		return token
+	}
+	return token[:visible] + "..."
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/suite"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+type JoinTokenSuite struct {
+	operator *Operator
+	cluster  *ops.Site
+}
+
+var _ = check.Suite(&JoinTokenSuite{})
+
+func (s *JoinTokenSuite) SetUpTest(c *check.C) {
+	services := SetupTestServices(c)
+	s.operator = services.Operator
+
+	suite := &suite.OpsSuite{}
+	app, err := suite.SetUpTestPackage(services.Apps, services.Packages, c)
+	c.Assert(err, check.IsNil)
+
+	account, err := s.operator.CreateAccount(ops.NewAccountRequest{
+		Org: "jointoken.test",
+	})
+	c.Assert(err, check.IsNil)
+
+	s.cluster, err = s.operator.CreateSite(ops.NewSiteRequest{
+		AccountID:  account.ID,
+		AppPackage: app.String(),
+		Provider:   schema.ProvisionerOnPrem,
+		DomainName: "jointoken.test",
+	})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *JoinTokenSuite) TestRevokesTokenAfterMaxUses(c *check.C) {
+	token, err := s.operator.CreateJoinToken(context.TODO(), ops.CreateJoinTokenRequest{
+		SiteKey: s.cluster.Key(),
+		TTL:     48 * time.Hour,
+		MaxUses: 2,
+	})
+	c.Assert(err, check.IsNil)
+	site, err := s.operator.openSite(s.cluster.Key())
+	c.Assert(err, check.IsNil)
+
+	for i := 0; i < 2; i++ {
+		err = site.useJoinToken(context.TODO(), token.Token, []string{string(schema.ServiceRoleNode)})
+		c.Assert(err, check.IsNil)
+	}
+
+	used, err := s.operator.backend().GetProvisioningToken(token.Token)
+	c.Assert(err, check.IsNil)
+	c.Assert(used.Uses, check.Equals, 2)
+	// the exhausted token only remains valid for the nodes already joining
+	c.Assert(used.Expires.Before(token.Expires), check.Equals, true)
+	c.Assert(used.Expires.After(s.operator.clock().UtcNow().Add(defaults.JoinTokenRevocationDelay)),
+		check.Equals, false)
+
+	err = site.useJoinToken(context.TODO(), token.Token, []string{string(schema.ServiceRoleNode)})
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *JoinTokenSuite) TestRestrictsNodeRoles(c *check.C) {
+	token, err := s.operator.CreateJoinToken(context.TODO(), ops.CreateJoinTokenRequest{
+		SiteKey: s.cluster.Key(),
+		Roles:   []string{string(schema.ServiceRoleNode)},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(token.Expires.IsZero(), check.Equals, false)
+	site, err := s.operator.openSite(s.cluster.Key())
+	c.Assert(err, check.IsNil)
+
+	err = site.useJoinToken(context.TODO(), token.Token, []string{string(schema.ServiceRoleMaster)})
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+
+	err = site.useJoinToken(context.TODO(), token.Token, []string{string(schema.ServiceRoleNode)})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *JoinTokenSuite) TestManagesScopedTokensOnly(c *check.C) {
+	token, err := s.operator.CreateJoinToken(context.TODO(), ops.CreateJoinTokenRequest{
+		SiteKey: s.cluster.Key(),
+		MaxUses: 1,
+	})
+	c.Assert(err, check.IsNil)
+
+	tokens, err := s.operator.GetJoinTokens(context.TODO(), s.cluster.Key())
+	c.Assert(err, check.IsNil)
+	c.Assert(tokens, check.HasLen, 1)
+	c.Assert(tokens[0].Token, check.Equals, token.Token)
+
+	// the long-lived cluster join token is unaffected
+	expandToken, err := s.operator.GetExpandToken(s.cluster.Key())
+	c.Assert(err, check.IsNil)
+	c.Assert(expandToken.Token, check.Not(check.Equals), token.Token)
+	err = s.operator.DeleteJoinToken(context.TODO(), ops.DeleteJoinTokenRequest{
+		SiteKey: s.cluster.Key(),
+		Token:   expandToken.Token,
+	})
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+
+	err = s.operator.DeleteJoinToken(context.TODO(), ops.DeleteJoinTokenRequest{
+		SiteKey: s.cluster.Key(),
+		Token:   token.Token,
+	})
+	c.Assert(err, check.IsNil)
+	tokens, err = s.operator.GetJoinTokens(context.TODO(), s.cluster.Key())
+	c.Assert(err, check.IsNil)
+	c.Assert(tokens, check.HasLen, 0)
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/events"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	licenseapi "github.com/gravitational/license"
+	"github.com/gravitational/trace"
+)
+
+// UpdateLicense replaces the license installed on the cluster.
+//
+// The new license must be valid for the current number of cluster nodes.
+// If the cluster has been degraded because of the previous license,
+// it is activated back
+func (o *Operator) UpdateLicense(ctx context.Context, req ops.UpdateLicenseRequest) error {
+	if err := req.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := o.openSite(req.SiteKey())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = ops.VerifyLicense(o.packages(), req.License)
+	if err != nil {
+		return trace.Wrap(err, "failed to validate provided license")
+	}
+	license, err := licenseapi.ParseLicense(req.License)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	status := cluster.newLicenseStatus(license.GetPayload(), o.clock().UtcNow())
+	if err := status.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	record := *cluster.backendSite
+	record.License = req.License
+	if _, err := o.backend().UpdateSite(record); err != nil {
+		return trace.Wrap(err)
+	}
+	licensePackage, err := cluster.licensePackage()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = o.packages().UpsertPackage(*licensePackage, strings.NewReader(req.License),
+		pack.WithLabels(map[string]string{pack.PurposeLabel: pack.PurposeLicense}))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	o.Infof("Updated license of %v: %v.", cluster.domainName, status.State)
+	events.Emit(ctx, o, events.LicenseUpdated, events.Fields{
+		events.FieldExpires: status.Expiration,
+	})
+	if record.State == ops.SiteStateDegraded && record.Reason == storage.ReasonLicenseInvalid {
+		// the application has been stopped if the previous license requested it
+		previous, err := licenseapi.ParseLicense(cluster.license)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = o.ActivateSite(ops.ActivateSiteRequest{
+			AccountID:  record.AccountID,
+			SiteDomain: record.Domain,
+			StartApp:   previous.GetPayload().Shutdown,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		events.Emit(ctx, o, events.ClusterActivated, events.Fields{})
+	}
+	if !cluster.app.Manifest.HasHook(schema.HookLicenseUpdated) {
+		return nil
+	}
+	_, _, err = app.RunAppHook(ctx, o.cfg.Apps, app.HookRunRequest{
+		Application: record.App.Locator(),
+		Hook:        schema.HookLicenseUpdated,
+		ServiceUser: record.ServiceUser,
+	})
+	return trace.Wrap(err)
+}
+
+// checkLicense degrades the cluster if its license is no longer valid.
+//
+// An expired license is tolerated during the grace period
+func (o *Operator) checkLicense(ctx context.Context, cluster *site) error {
+	status, err := cluster.licenseStatus(o.clock().UtcNow())
+	if err != nil || status == nil {
+		return trace.Wrap(err)
+	}
+	if status.State == ops.LicenseStateGracePeriod {
+		o.Warnf("License of %v expired on %v, the cluster will be degraded after %v.",
+			cluster.domainName, status.Expiration, status.GracePeriodEnd)
+	}
+	licenseErr := status.Check()
+	if licenseErr == nil {
+		return nil
+	}
+	if cluster.backendSite.State == ops.SiteStateDegraded {
+		if cluster.backendSite.Reason == storage.ReasonLicenseInvalid {
+			return trace.Wrap(licenseErr)
+		}
+		// the cluster has been degraded by the status checks that pass now
+		record := *cluster.backendSite
+		record.Reason = storage.ReasonLicenseInvalid
+		if _, err := o.backend().UpdateSite(record); err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(licenseErr)
+	}
+	license, err := licenseapi.ParseLicense(cluster.license)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = o.DeactivateSite(ops.DeactivateSiteRequest{
+		AccountID:  cluster.key.AccountID,
+		SiteDomain: cluster.domainName,
+		Reason:     storage.ReasonLicenseInvalid,
+		StopApp:    license.GetPayload().Shutdown,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	events.Emit(ctx, o, events.ClusterDegraded, events.Fields{
+		events.FieldReason: storage.ReasonLicenseInvalid,
+	})
+	return trace.Wrap(licenseErr)
+}
+
+// licenseStatus returns the status of the license installed on the cluster
+// or nil if the cluster does not have a license
+func (s *site) licenseStatus(now time.Time) (*ops.LicenseStatus, error) {
+	if s.license == "" {
+		return nil, nil
+	}
+	license, err := licenseapi.ParseLicense(s.license)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	status := s.newLicenseStatus(license.GetPayload(), now)
+	return &status, nil
+}
+
+// newLicenseStatus returns the status of the license with the specified
+// payload for the current cluster nodes
+func (s *site) newLicenseStatus(payload licenseapi.Payload, now time.Time) ops.LicenseStatus {
+	return ops.NewLicenseStatus(payload, len(s.backendSite.ClusterState.Servers), now)
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package opsservice
 
 import (
+	"encoding/json"
+
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/ops"
@@ -28,6 +30,7 @@ import (
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubelabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
@@ -103,8 +106,17 @@ func (o *Operator) UpdateAlert(key ops.SiteKey, alert storage.Alert) error {
 	labels := map[string]string{
 		constants.MonitoringType: constants.MonitoringTypeAlert,
 	}
-	return updateConfigMap(client.Core().ConfigMaps(defaults.MonitoringNamespace),
+	err = updateConfigMap(client.Core().ConfigMaps(defaults.MonitoringNamespace),
 		alert.GetName(), defaults.MonitoringNamespace, string(data), labels)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	if alert.GetRule() == nil {
+		// the alert might have been converted from a Prometheus rule
+		return trace.Wrap(deletePrometheusRule(client, alert.GetName()))
+	}
+	return trace.Wrap(updatePrometheusRule(client, alert))
 }
 
 // DeleteAlert deletes the specified monitoring alert
@@ -137,7 +149,11 @@ func (o *Operator) DeleteAlert(key ops.SiteKey, name string) error {
 	}
 
 	err = client.Core().ConfigMaps(defaults.MonitoringNamespace).Delete(name, nil)
-	return trace.Wrap(rigging.ConvertError(err))
+	if err != nil {
+		return trace.Wrap(rigging.ConvertError(err))
+	}
+
+	return trace.Wrap(deletePrometheusRule(client, name))
 }
 
 // GetAlertTargets returns a list of configured monitoring alert targets
@@ -179,8 +195,13 @@ func (o *Operator) UpdateAlertTarget(key ops.SiteKey, target storage.AlertTarget
 	labels := map[string]string{
 		constants.MonitoringType: constants.MonitoringTypeAlertTarget,
 	}
-	return updateConfigMap(client.Core().ConfigMaps(defaults.MonitoringNamespace),
+	err = updateConfigMap(client.Core().ConfigMaps(defaults.MonitoringNamespace),
 		constants.AlertTargetConfigMap, defaults.MonitoringNamespace, string(data), labels)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return trace.Wrap(o.updateAlertmanagerConfig(key, client))
 }
 
 // DeleteAlertTarget deletes the cluster monitoring alert target
@@ -194,7 +215,114 @@ func (o *Operator) DeleteAlertTarget(key ops.SiteKey) error {
 	if trace.IsNotFound(err) {
 		return trace.NotFound("no alert targets found")
 	}
-	return trace.Wrap(err)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return trace.Wrap(o.updateAlertmanagerConfig(key, client))
+}
+
+// updateAlertmanagerConfig regenerates Alertmanager configuration
+// from the cluster SMTP configuration and alert targets
+func (o *Operator) updateAlertmanagerConfig(key ops.SiteKey, client *kubernetes.Clientset) error {
+	var smtp storage.SMTPConfig
+	data, err := getSMTPConfig(client.Core().Secrets(defaults.MonitoringNamespace))
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if err == nil {
+		smtp, err = storage.UnmarshalSMTPConfig(data)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
+	targets, err := o.GetAlertTargets(key)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+
+	config, err := monitoring.AlertmanagerConfig(smtp, targets)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.AlertmanagerConfigSecret,
+			Namespace: defaults.MonitoringNamespace,
+		},
+		Data: map[string][]byte{
+			constants.AlertmanagerConfigKey: config,
+		},
+		Type: v1.SecretTypeOpaque,
+	}
+	secrets := client.Core().Secrets(defaults.MonitoringNamespace)
+	_, err = secrets.Create(secret)
+	err = rigging.ConvertError(err)
+	if err == nil || !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+
+	_, err = secrets.Update(secret)
+	return trace.Wrap(rigging.ConvertError(err))
+}
+
+// updatePrometheusRule creates or updates the Prometheus Operator rule
+// resource for the specified alert
+func updatePrometheusRule(client *kubernetes.Clientset, alert storage.Alert) error {
+	rule, err := monitoring.NewPrometheusRule(alert)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	restClient := client.Core().RESTClient()
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = rigging.ConvertError(restClient.Post().
+		AbsPath(monitoring.PrometheusRulesPath("")).
+		Body(data).
+		Do().
+		Error())
+	if trace.IsNotFound(err) {
+		return trace.NotFound("monitoring does not support Prometheus alerting rules")
+	}
+	if err == nil || !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+
+	path := monitoring.PrometheusRulesPath(rule.Name)
+	data, err = restClient.Get().AbsPath(path).Do().Raw()
+	if err != nil {
+		return trace.Wrap(rigging.ConvertError(err))
+	}
+	var existing monitoring.PrometheusRule
+	if err := json.Unmarshal(data, &existing); err != nil {
+		return trace.Wrap(err)
+	}
+	rule.ResourceVersion = existing.ResourceVersion
+	data, err = json.Marshal(rule)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = restClient.Put().AbsPath(path).Body(data).Do().Error()
+	return trace.Wrap(rigging.ConvertError(err))
+}
+
+// deletePrometheusRule deletes the Prometheus Operator rule resource
+// for the alert with the specified name if it exists
+func deletePrometheusRule(client *kubernetes.Clientset, alertName string) error {
+	err := client.Core().RESTClient().Delete().
+		AbsPath(monitoring.PrometheusRulesPath(monitoring.PrometheusRuleName(alertName))).
+		Do().
+		Error()
+	err = rigging.ConvertError(err)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	return nil
 }
 
 func getConfigMap(client corev1.ConfigMapInterface, name string) (string, error) {
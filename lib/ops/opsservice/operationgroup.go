@@ -28,6 +28,7 @@ import (
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -97,6 +98,7 @@ func (g *operationGroup) createSiteOperation(operation ops.SiteOperation) (*ops.
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	g.operator.notifyWebhooks(*op)
 
 	key := op.Key()
 	return &key, nil
@@ -141,8 +143,9 @@ func (g *operationGroup) canCreateOperation(operation ops.SiteOperation) error {
 		if err != nil {
 			return trace.Wrap(err)
 		}
-	case ops.OperationShrink, ops.OperationGarbageCollect, ops.OperationUpdateRuntimeEnviron:
-		// shrink, gc and updating environment are allowed for degraded clusters
+	case ops.OperationShrink, ops.OperationGarbageCollect, ops.OperationUpdateRuntimeEnviron, ops.OperationPatch:
+		// shrink, gc, updating environment and patching configuration are allowed
+		// for degraded clusters
 		switch cluster.State {
 		case ops.SiteStateActive, ops.SiteStateDegraded:
 		default:
@@ -256,10 +259,13 @@ func (g *operationGroup) compareAndSwapOperationState(swap swap) (*ops.SiteOpera
 	// if we've just moved the operation to one of the final states (completed/failed),
 	// see if we also need to update the site state
 	if operation.IsFinished() {
+		operationDuration.WithLabelValues(operation.Type, operation.State).Observe(
+			site.clock().UtcNow().Sub(operation.Created).Seconds())
 		err = g.emitAuditEvent(context.TODO(), *operation)
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
+		g.operator.notifyWebhooks(*operation)
 		err = g.onSiteOperationComplete(swap.key)
 		if err != nil {
 			return nil, trace.Wrap(err)
@@ -357,3 +363,16 @@ func (g *operationGroup) removeClusterStateServers(hostnames []string) error {
 
 	return nil
 }
+
+var operationDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "cluster_operation_duration_seconds",
+		Help:    "Duration of finished cluster operations",
+		Buckets: prometheus.ExponentialBuckets(30, 2, 10),
+	},
+	[]string{"type", "state"},
+)
+
+func init() {
+	prometheus.MustRegister(operationDuration)
+}
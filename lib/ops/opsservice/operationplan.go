@@ -20,6 +20,7 @@ import (
 	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/webhooks"
 
 	"github.com/gravitational/trace"
 )
@@ -39,6 +40,12 @@ func (o *Operator) CreateOperationPlanChange(key ops.SiteOperationKey, change st
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	operation, err := o.GetSiteOperation(key)
+	if err != nil {
+		o.Warnf("Failed to query operation %v: %v.", key, trace.DebugReport(err))
+		return nil
+	}
+	o.notifier.Notify(webhooks.NewPhaseEvent(*operation, change))
 	return nil
 }
 
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CreatePatchOperation creates a new operation to apply configuration-only changes to the cluster
+func (o *Operator) CreatePatchOperation(ctx context.Context, req ops.CreatePatchOperationRequest) (*ops.SiteOperationKey, error) {
+	err := req.ClusterKey.Check()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = req.Check()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cluster, err := o.openSite(req.ClusterKey)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	op := ops.SiteOperation{
+		ID:         uuid.New(),
+		AccountID:  req.ClusterKey.AccountID,
+		SiteDomain: req.ClusterKey.SiteDomain,
+		Type:       ops.OperationPatch,
+		Created:    cluster.clock().UtcNow(),
+		CreatedBy:  storage.UserFromContext(ctx),
+		Updated:    cluster.clock().UtcNow(),
+		State:      ops.OperationPatchInProgress,
+		Patch: &storage.PatchOperationState{
+			DNSUpstreams: req.DNSUpstreams,
+		},
+	}
+	if len(req.Env) != 0 {
+		prevEnv, err := o.getClusterEnvironment()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		op.UpdateEnviron = &storage.UpdateEnvarsOperationState{
+			PrevEnv: prevEnv,
+			Env:     mergeEnvironment(prevEnv, req.Env),
+		}
+	}
+	if len(req.DNSUpstreams) != 0 {
+		op.Patch.PrevCorefile, err = o.getCorefile()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	key, err := cluster.getOperationGroup().createSiteOperation(op)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return key, nil
+}
+
+// getCorefile returns the current cluster DNS configuration
+func (o *Operator) getCorefile() (string, error) {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	configmap, err := client.CoreV1().ConfigMaps(constants.KubeSystemNamespace).
+		Get(constants.CoreDNSConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return "", trace.Wrap(rigging.ConvertError(err))
+	}
+	return configmap.Data[constants.CoreDNSConfigKey], nil
+}
+
+// mergeEnvironment returns a new environment with the variables from update
+// set on top of the variables from env
+func mergeEnvironment(env, update map[string]string) map[string]string {
+	result := make(map[string]string, len(env)+len(update))
+	for name, value := range env {
+		result[name] = value
+	}
+	for name, value := range update {
+		result[name] = value
+	}
+	return result
+}
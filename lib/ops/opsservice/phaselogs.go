@@ -0,0 +1,103 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+
+	"github.com/gravitational/trace"
+)
+
+// GetOperationPhaseLogs returns the log entries recorded while executing
+// the specified phase of the operation and any of its subphases
+func (o *Operator) GetOperationPhaseLogs(key ops.SiteOperationKey, phaseID string) ([]ops.LogEntry, error) {
+	cluster, err := o.openSite(key.SiteKey())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return cluster.getPhaseLogs(key, phaseID)
+}
+
+// phaseLogPath returns the path to the file with the log entries
+// of the operation phases
+func (s *site) phaseLogPath(key ops.SiteOperationKey) string {
+	return s.siteDir(key.OperationID, fmt.Sprintf("%v-phases.json", key.OperationID))
+}
+
+// recordPhaseLogEntry appends the provided log entry to the operation's
+// phase log.
+// Each entry is stored as a separate JSON object on its own line
+func (s *site) recordPhaseLogEntry(key ops.SiteOperationKey, entry ops.LogEntry) error {
+	path := s.phaseLogPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, defaults.SharedReadMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	// write the entry with a single call so entries of the concurrently
+	// executed phases do not interleave
+	_, err = f.Write(append(data, '\n'))
+	return trace.ConvertSystemError(err)
+}
+
+// getPhaseLogs returns the log entries of the specified operation phase
+// and its subphases in the order they have been recorded
+func (s *site) getPhaseLogs(key ops.SiteOperationKey, phaseID string) ([]ops.LogEntry, error) {
+	_, err := s.backend().GetSiteOperation(key.SiteDomain, key.OperationID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	f, err := os.Open(s.phaseLogPath(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	var entries []ops.LogEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, defaults.PhaseLogEntryMaxSize)
+	for scanner.Scan() {
+		var entry ops.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			s.Warnf("Skipping malformed phase log entry: %v.", err)
+			continue
+		}
+		if entry.IsFromPhase(phaseID) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return entries, nil
+}
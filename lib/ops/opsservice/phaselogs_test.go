@@ -0,0 +1,107 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/suite"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"gopkg.in/check.v1"
+)
+
+type PhaseLogsSuite struct {
+	operator *Operator
+	key      ops.SiteOperationKey
+}
+
+var _ = check.Suite(&PhaseLogsSuite{})
+
+func (s *PhaseLogsSuite) SetUpTest(c *check.C) {
+	services := SetupTestServices(c)
+	s.operator = services.Operator
+
+	suite := &suite.OpsSuite{}
+	app, err := suite.SetUpTestPackage(services.Apps, services.Packages, c)
+	c.Assert(err, check.IsNil)
+
+	account, err := s.operator.CreateAccount(ops.NewAccountRequest{
+		Org: "phaselogs.test",
+	})
+	c.Assert(err, check.IsNil)
+
+	cluster, err := s.operator.CreateSite(ops.NewSiteRequest{
+		AccountID:  account.ID,
+		AppPackage: app.String(),
+		Provider:   schema.ProvisionerOnPrem,
+		DomainName: "phaselogs.test",
+	})
+	c.Assert(err, check.IsNil)
+
+	key, err := s.operator.getOperationGroup(cluster.Key()).createSiteOperation(ops.SiteOperation{
+		AccountID:  cluster.AccountID,
+		SiteDomain: cluster.Domain,
+		Type:       ops.OperationInstall,
+		State:      ops.OperationStateInstallInitiated,
+	})
+	c.Assert(err, check.IsNil)
+	s.key = *key
+}
+
+func (s *PhaseLogsSuite) TestRecordsLogsByPhase(c *check.C) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []ops.LogEntry{
+		s.newEntry("/masters/node-1/drain", "draining node-1", now),
+		s.newEntry("", "operation started", now.Add(time.Second)),
+		s.newEntry("/masters/node-2/drain", "draining node-2", now.Add(2*time.Second)),
+		s.newEntry("/masters-extra", "unrelated phase", now.Add(3*time.Second)),
+		s.newEntry("/masters/node-1/drain", "node-1 drained", now.Add(4*time.Second)),
+	}
+	for _, entry := range entries {
+		c.Assert(s.operator.CreateLogEntry(s.key, entry), check.IsNil)
+	}
+
+	logs, err := s.operator.GetOperationPhaseLogs(s.key, "/masters/node-1/drain")
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.DeepEquals, []ops.LogEntry{entries[0], entries[4]})
+
+	logs, err = s.operator.GetOperationPhaseLogs(s.key, "/masters")
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.DeepEquals, []ops.LogEntry{entries[0], entries[2], entries[4]})
+
+	logs, err = s.operator.GetOperationPhaseLogs(s.key, "/")
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.DeepEquals, []ops.LogEntry{entries[0], entries[2], entries[3], entries[4]})
+
+	logs, err = s.operator.GetOperationPhaseLogs(s.key, "/nodes")
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 0)
+}
+
+func (s *PhaseLogsSuite) newEntry(phaseID, message string, created time.Time) ops.LogEntry {
+	return ops.LogEntry{
+		AccountID:   s.key.AccountID,
+		ClusterName: s.key.SiteDomain,
+		OperationID: s.key.OperationID,
+		Severity:    "info",
+		Message:     message,
+		PhaseID:     phaseID,
+		Created:     created,
+	}
+}
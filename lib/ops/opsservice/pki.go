@@ -0,0 +1,335 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/httplib"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/cloudflare/cfssl/csr"
+	"github.com/cloudflare/cfssl/signer"
+	"github.com/gravitational/license/authority"
+	"github.com/gravitational/trace"
+)
+
+// certSigner issues certificates from the cluster certificate authority
+type certSigner interface {
+	// GenerateCertificate issues a new certificate for the specified request.
+	// If privateKeyPEM is empty, a new private key is generated
+	GenerateCertificate(req csr.CertificateRequest, privateKeyPEM []byte, validFor time.Duration) (*authority.TLSKeyPair, error)
+	// SignCSR signs the specified certificate signing request
+	// and returns the PEM-encoded certificate
+	SignCSR(req signer.SignRequest, validFor time.Duration) ([]byte, error)
+}
+
+// newCertSigner returns the signer for the certificate authority from the specified archive.
+//
+// Clusters installed with an external signer send certificate signing requests
+// to the signer, other clusters sign certificates with the private key
+// of the root certificate authority from the archive
+func (s *site) newCertSigner(archive utils.TLSArchive) (certSigner, error) {
+	if s.backendSite != nil && s.backendSite.PKI != nil && s.backendSite.PKI.UsesSigner() {
+		return newWebhookSigner(s.backendSite.PKI.SignerURL), nil
+	}
+	caKeyPair, err := archive.GetKeyPair(constants.RootKeyPair)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(caKeyPair.KeyPEM) == 0 {
+		return nil, trace.BadParameter("certificate authority private key is not available")
+	}
+	return &localSigner{ca: caKeyPair}, nil
+}
+
+// localSigner signs certificates with the private key of the certificate authority
+type localSigner struct {
+	ca *authority.TLSKeyPair
+}
+
+// GenerateCertificate issues a new certificate for the specified request
+func (r *localSigner) GenerateCertificate(req csr.CertificateRequest, privateKeyPEM []byte, validFor time.Duration) (*authority.TLSKeyPair, error) {
+	return authority.GenerateCertificate(req, r.ca, privateKeyPEM, validFor)
+}
+
+// SignCSR signs the specified certificate signing request
+func (r *localSigner) SignCSR(req signer.SignRequest, validFor time.Duration) ([]byte, error) {
+	return authority.ProcessCSR(req, validFor, r.ca)
+}
+
+func newWebhookSigner(url string) *webhookSigner {
+	return &webhookSigner{
+		url:    url,
+		client: httplib.GetClient(false, httplib.WithTimeout(defaults.CertSignerTimeout)),
+	}
+}
+
+// webhookSigner sends certificate signing requests to the external signer.
+//
+// The signer receives a POST request with the JSON-encoded SignerRequest
+// and replies with the JSON-encoded SignerResponse
+type webhookSigner struct {
+	url    string
+	client *http.Client
+}
+
+// SignerRequest is the request sent to the external certificate signer
+type SignerRequest struct {
+	// CSR is the PEM-encoded certificate signing request
+	CSR string `json:"csr"`
+	// Hosts optionally overrides the subject alternative names requested in the CSR
+	Hosts []string `json:"hosts,omitempty"`
+	// Subject optionally overrides the subject requested in the CSR
+	Subject *signer.Subject `json:"subject,omitempty"`
+	// TTL is the requested validity period of the certificate
+	TTL string `json:"ttl"`
+}
+
+// SignerResponse is the response of the external certificate signer
+type SignerResponse struct {
+	// Certificate is the PEM-encoded signed certificate
+	Certificate string `json:"certificate"`
+}
+
+// GenerateCertificate issues a new certificate for the specified request
+func (r *webhookSigner) GenerateCertificate(req csr.CertificateRequest, privateKeyPEM []byte, validFor time.Duration) (*authority.TLSKeyPair, error) {
+	csrPEM, keyPEM, err := authority.GenerateCSR(req, privateKeyPEM)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	certPEM, err := r.SignCSR(signer.SignRequest{
+		Request: string(csrPEM),
+		Hosts:   req.Hosts,
+	}, validFor)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &authority.TLSKeyPair{
+		CertPEM: certPEM,
+		KeyPEM:  keyPEM,
+	}, nil
+}
+
+// SignCSR sends the specified certificate signing request to the signer
+func (r *webhookSigner) SignCSR(req signer.SignRequest, validFor time.Duration) ([]byte, error) {
+	data, err := json.Marshal(SignerRequest{
+		CSR:     req.Request,
+		Hosts:   req.Hosts,
+		Subject: req.Subject,
+		TTL:     validFor.String(),
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	resp, err := r.client.Post(r.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to send certificate signing request to %v", r.url)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, trace.BadParameter("certificate signer at %v returned %v: %s",
+			r.url, resp.Status, bytes.TrimSpace(body))
+	}
+	var signed SignerResponse
+	if err := json.Unmarshal(body, &signed); err != nil {
+		return nil, trace.BadParameter("invalid certificate signer response: %v", err)
+	}
+	certs, err := parseCertificateChain([]byte(signed.Certificate))
+	if err != nil {
+		return nil, trace.Wrap(err, "invalid certificate signer response")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certs[0].Raw}), nil
+}
+
+// newExternalCertAuthority validates the user-provided certificate authority
+// and returns the key pairs to seed the cluster certificate authority package with
+func newExternalCertAuthority(ca ops.ExternalCertAuthority) (utils.TLSArchive, error) {
+	if err := ca.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	chain, err := parseCertificateChain(ca.CertPEM)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	root := chain[0]
+	if !root.BasicConstraintsValid || !root.IsCA {
+		return nil, trace.BadParameter("certificate %q is not a certificate authority",
+			root.Subject.CommonName)
+	}
+	if root.NotAfter.Before(time.Now()) {
+		return nil, trace.BadParameter("certificate authority %q expired on %v",
+			root.Subject.CommonName, root.NotAfter.Format(constants.HumanDateFormat))
+	}
+	rootPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw})
+	if len(ca.KeyPEM) != 0 {
+		if _, err := tls.X509KeyPair(rootPEM, ca.KeyPEM); err != nil {
+			return nil, trace.BadParameter("certificate authority private key does not match its certificate: %v", err)
+		}
+	}
+	archive := utils.TLSArchive{
+		constants.RootKeyPair: &authority.TLSKeyPair{
+			CertPEM: rootPEM,
+			KeyPEM:  ca.KeyPEM,
+		},
+	}
+	if len(chain) > 1 {
+		var buf bytes.Buffer
+		for _, cert := range chain[1:] {
+			pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+		}
+		archive[constants.CertAuthorityChainKeyPair] = &authority.TLSKeyPair{CertPEM: buf.Bytes()}
+	}
+	return archive, nil
+}
+
+// seedCertAuthority creates the certificate authority package of the cluster
+// from the user-provided certificate authority.
+//
+// The rest of the package is generated during installation
+func (s *site) seedCertAuthority(ca ops.ExternalCertAuthority) error {
+	archive, err := newExternalCertAuthority(ca)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	caPackage, err := s.planetCertAuthorityPackage()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = s.packages().UpsertRepository(caPackage.Repository, time.Time{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	reader, err := utils.CreateTLSArchive(archive)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	_, err = s.packages().CreatePackage(*caPackage, reader, pack.WithLabels(
+		map[string]string{
+			pack.PurposeLabel: pack.PurposeCA,
+		}))
+	return trace.Wrap(err)
+}
+
+// IssueClusterCertificate issues a new cluster web certificate from the
+// user-provided certificate authority of the cluster and installs it
+func (o *Operator) IssueClusterCertificate(key ops.SiteKey) (*ops.ClusterCertificate, error) {
+	cluster, err := o.openSite(key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if cluster.backendSite.PKI == nil {
+		return nil, trace.BadParameter("cluster %v does not use an external certificate authority", key.SiteDomain)
+	}
+	req, err := cluster.issueClusterCertificate()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.UpdateClusterCertificate(*req)
+}
+
+// issueClusterCertificate issues a new cluster web certificate
+// valid for the cluster name and addresses of all master nodes
+func (s *site) issueClusterCertificate() (*ops.UpdateCertificateRequest, error) {
+	archive, err := s.readCertAuthorityPackage()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	certSigner, err := s.newCertSigner(archive)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	bundle, err := certAuthorityBundle(archive)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	hosts := []string{s.domainName, constants.LoopbackIP}
+	for _, server := range s.backendSite.ClusterState.Servers {
+		if server.IsMaster() {
+			hosts = append(hosts, server.AdvertiseIP, server.Hostname)
+		}
+	}
+	keyPair, err := certSigner.GenerateCertificate(csr.CertificateRequest{
+		CN:    s.domainName,
+		Hosts: hosts,
+	}, nil, defaults.CertificateExpiry)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &ops.UpdateCertificateRequest{
+		AccountID:    s.key.AccountID,
+		SiteDomain:   s.key.SiteDomain,
+		Certificate:  keyPair.CertPEM,
+		PrivateKey:   keyPair.KeyPEM,
+		Intermediate: bundle,
+	}, nil
+}
+
+// usesExternalPKI returns true if the cluster certificates are issued
+// from the user-provided certificate authority
+func (s *site) usesExternalPKI() bool {
+	return s.backendSite != nil && s.backendSite.PKI != nil
+}
+
+// newExternalPKI returns the cluster PKI configuration for the specified
+// user-provided certificate authority
+func newExternalPKI(ca ops.ExternalCertAuthority) *storage.ExternalPKI {
+	return &storage.ExternalPKI{
+		CertPEM:   ca.CertPEM,
+		SignerURL: ca.SignerURL,
+	}
+}
+
+// parseCertificateChain parses the PEM-encoded certificates from the provided data
+func parseCertificateChain(data []byte) (chain []*x509.Certificate, err error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, trace.BadParameter("no PEM-encoded certificates found")
+	}
+	return chain, nil
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/ops"
+
+	"github.com/cloudflare/cfssl/csr"
+	"github.com/cloudflare/cfssl/signer"
+	"github.com/gravitational/license/authority"
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+type PKISuite struct {
+	root         testCA
+	intermediate testCA
+}
+
+var _ = check.Suite(&PKISuite{})
+
+func (s *PKISuite) SetUpSuite(c *check.C) {
+	s.root = newTestCA(c, "root", nil)
+	s.intermediate = newTestCA(c, "intermediate", &s.root)
+}
+
+func (s *PKISuite) TestIssuesCertificatesFromIntermediateCA(c *check.C) {
+	archive, err := newExternalCertAuthority(ops.ExternalCertAuthority{
+		CertPEM: append(s.intermediate.certPEM, s.root.certPEM...),
+		KeyPEM:  s.intermediate.keyPEM,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(string(archive[constants.RootKeyPair].CertPEM), check.Equals, string(s.intermediate.certPEM))
+	c.Assert(string(archive[constants.CertAuthorityChainKeyPair].CertPEM), check.Equals, string(s.root.certPEM))
+
+	bundle, err := certAuthorityBundle(archive)
+	c.Assert(err, check.IsNil)
+	chain, err := parseCertificateChain(bundle)
+	c.Assert(err, check.IsNil)
+	c.Assert(chain, check.HasLen, 2)
+
+	signer := &localSigner{ca: archive[constants.RootKeyPair]}
+	keyPair, err := signer.GenerateCertificate(csr.CertificateRequest{
+		CN:    constants.APIServerKeyPair,
+		Hosts: []string{"127.0.0.1"},
+	}, nil, time.Hour)
+	c.Assert(err, check.IsNil)
+	s.verify(c, keyPair.CertPEM)
+}
+
+func (s *PKISuite) TestSignsCertificatesWithWebhook(c *check.C) {
+	ca := &localSigner{ca: s.intermediate.keyPair()}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req SignerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		cert, err := ca.SignCSR(signer.SignRequest{
+			Request: req.CSR,
+			Hosts:   req.Hosts,
+			Subject: req.Subject,
+		}, ttl)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(SignerResponse{Certificate: string(cert)})
+	}))
+	defer server.Close()
+
+	keyPair, err := newWebhookSigner(server.URL).GenerateCertificate(csr.CertificateRequest{
+		CN:    constants.ETCDKeyPair,
+		Hosts: []string{"127.0.0.1", "node-1"},
+	}, nil, time.Hour)
+	c.Assert(err, check.IsNil)
+	c.Assert(len(keyPair.KeyPEM), check.Not(check.Equals), 0)
+	cert := s.verify(c, keyPair.CertPEM)
+	c.Assert(cert.Subject.CommonName, check.Equals, constants.ETCDKeyPair)
+	c.Assert(cert.DNSNames, check.DeepEquals, []string{"node-1"})
+}
+
+func (s *PKISuite) TestReportsWebhookErrors(c *check.C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "request denied", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := newWebhookSigner(server.URL).SignCSR(signer.SignRequest{Request: "csr"}, time.Hour)
+	c.Assert(trace.IsBadParameter(err), check.Equals, true)
+	c.Assert(trace.UserMessage(err), check.Matches, ".*403 Forbidden: request denied")
+}
+
+func (s *PKISuite) TestRejectsInvalidCertAuthority(c *check.C) {
+	leaf, err := (&localSigner{ca: s.intermediate.keyPair()}).GenerateCertificate(
+		csr.CertificateRequest{CN: "leaf"}, nil, time.Hour)
+	c.Assert(err, check.IsNil)
+
+	var testCases = []struct {
+		ca      ops.ExternalCertAuthority
+		comment string
+	}{
+		{
+			ca:      ops.ExternalCertAuthority{CertPEM: s.intermediate.certPEM},
+			comment: "neither private key nor signer",
+		},
+		{
+			ca: ops.ExternalCertAuthority{
+				CertPEM:   s.intermediate.certPEM,
+				KeyPEM:    s.intermediate.keyPEM,
+				SignerURL: "https://signer.example.com",
+			},
+			comment: "both private key and signer",
+		},
+		{
+			ca:      ops.ExternalCertAuthority{CertPEM: leaf.CertPEM, KeyPEM: leaf.KeyPEM},
+			comment: "not a certificate authority",
+		},
+		{
+			ca:      ops.ExternalCertAuthority{CertPEM: s.intermediate.certPEM, KeyPEM: s.root.keyPEM},
+			comment: "mismatched private key",
+		},
+		{
+			ca:      ops.ExternalCertAuthority{CertPEM: []byte("not a certificate"), SignerURL: "https://signer.example.com"},
+			comment: "invalid certificate",
+		},
+		{
+			ca:      ops.ExternalCertAuthority{CertPEM: s.intermediate.certPEM, SignerURL: "signer.example.com"},
+			comment: "invalid signer URL",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := newExternalCertAuthority(tc.ca)
+		c.Assert(trace.IsBadParameter(err), check.Equals, true, check.Commentf(tc.comment))
+	}
+}
+
+// verify makes sure the certificate chains up to the root certificate
+// authority via the intermediate one
+func (s *PKISuite) verify(c *check.C, certPEM []byte) *x509.Certificate {
+	chain, err := parseCertificateChain(certPEM)
+	c.Assert(err, check.IsNil)
+	roots := x509.NewCertPool()
+	roots.AddCert(s.root.cert)
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(s.intermediate.cert)
+	_, err = chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	c.Assert(err, check.IsNil)
+	return chain[0]
+}
+
+// newTestCA returns a new certificate authority signed by the parent
+// certificate authority or self-signed if parent is nil
+func newTestCA(c *check.C, commonName string, parent *testCA) testCA {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, check.IsNil)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	issuer, issuerKey := template, key
+	if parent != nil {
+		issuer, issuerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	c.Assert(err, check.IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, check.IsNil)
+	return testCA{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM: pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		}),
+	}
+}
+
+type testCA struct {
+	cert    *x509.Certificate
+	key     *rsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func (r testCA) keyPair() *authority.TLSKeyPair {
+	return &authority.TLSKeyPair{CertPEM: r.certPEM, KeyPEM: r.keyPEM}
+}
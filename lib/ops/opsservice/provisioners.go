@@ -0,0 +1,114 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/provisioner"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// UpsertProvisioner creates or updates a provisioner
+func (o *Operator) UpsertProvisioner(key ops.SiteKey, provisioner storage.Provisioner) error {
+	return trace.Wrap(o.backend().UpsertProvisioner(provisioner))
+}
+
+// GetProvisioner returns a provisioner by name
+func (o *Operator) GetProvisioner(key ops.SiteKey, name string) (storage.Provisioner, error) {
+	provisioner, err := o.backend().GetProvisioner(name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return provisioner, nil
+}
+
+// GetProvisioners returns all provisioners
+func (o *Operator) GetProvisioners(key ops.SiteKey) ([]storage.Provisioner, error) {
+	out, err := o.backend().GetProvisioners()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return out, nil
+}
+
+// DeleteProvisioner deletes a provisioner by name
+func (o *Operator) DeleteProvisioner(key ops.SiteKey, name string) error {
+	return trace.Wrap(o.backend().DeleteProvisioner(name))
+}
+
+// ProvisionNodes creates the machines of new cluster nodes with
+// the specified provisioner.
+//
+// The machines run the script that joins them to the cluster on boot
+// so the expand operations start once the machines have booted
+func (o *Operator) ProvisionNodes(ctx context.Context, req ops.ProvisionNodesRequest) (*ops.ProvisionNodesResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cluster, err := o.openSite(req.SiteKey)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	profile, err := cluster.app.Manifest.NodeProfiles.ByName(req.Profile)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if profile.ExpandPolicy == schema.ExpandPolicyFixed {
+		return nil, trace.BadParameter("nodes of profile %q can't be added to the cluster", req.Profile)
+	}
+	resource, err := o.backend().GetProvisioner(req.Provisioner)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	p, err := provisioner.New(resource)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	bootstrap, err := cluster.getProvisionInstructions(req.Profile)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	logger := log.WithFields(log.Fields{
+		"provisioner": req.Provisioner,
+		"profile":     req.Profile,
+	})
+	logger.Infof("Provisioning %v node(s).", req.Count)
+	machines, err := p.Provision(ctx, provisioner.Request{
+		ClusterName: req.SiteDomain,
+		Profile:     req.Profile,
+		Count:       req.Count,
+		Bootstrap:   bootstrap,
+	})
+	resp := &ops.ProvisionNodesResponse{Machines: make([]ops.ProvisionedMachine, 0, len(machines))}
+	for _, machine := range machines {
+		logger.Infof("Provisioned %v.", machine)
+		resp.Machines = append(resp.Machines, ops.ProvisionedMachine{
+			ID:   machine.ID,
+			Name: machine.Name,
+		})
+	}
+	if err != nil {
+		return nil, trace.Wrap(err, "provisioned %v of %v node(s)", len(machines), req.Count)
+	}
+	return resp, nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/teleport"
+	"github.com/gravitational/trace"
+)
+
+// UpdateRemoteClusterStates synchronizes the states of the installed remote
+// clusters that connect to this Ops Center over reverse tunnels.
+//
+// A cluster whose tunnel is down is marked offline. Connected clusters
+// are queried for their own state so the Ops Center reflects whether
+// they are active or degraded
+func (o *Operator) UpdateRemoteClusterStates(ctx context.Context) error {
+	clusters, err := o.backend().GetAllSites()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, cluster := range clusters {
+		if cluster.Local || !ops.IsInstalledState(cluster.State) {
+			continue
+		}
+		state, reason := o.getRemoteClusterState(cluster)
+		if state == cluster.State && reason == cluster.Reason {
+			continue
+		}
+		o.Infof("Cluster %v changed state from %v to %v.",
+			cluster.Domain, cluster.State, state)
+		cluster.State = state
+		cluster.Reason = reason
+		if _, err := o.backend().UpdateSite(cluster); err != nil {
+			o.Warnf("Failed to update state of cluster %v: %v.",
+				cluster.Domain, trace.DebugReport(err))
+			continue
+		}
+		o.recordStatusSnapshot(newStatusSnapshot(cluster.Domain,
+			state, reason, nil, nil, o.clock().UtcNow()))
+	}
+	return nil
+}
+
+// getRemoteClusterState returns the state of the specified remote cluster.
+// The last known state is returned if the cluster cannot be queried
+func (o *Operator) getRemoteClusterState(cluster storage.Site) (state string, reason storage.Reason) {
+	remote, err := o.cfg.Tunnel.GetSite(cluster.Domain)
+	if err != nil || remote.GetStatus() != teleport.RemoteClusterStatusOnline {
+		return ops.SiteStateOffline, ""
+	}
+	client, err := o.cfg.Clients.OpsClient(cluster.Domain)
+	if err != nil {
+		o.Warnf("Failed to connect to cluster %v: %v.",
+			cluster.Domain, trace.DebugReport(err))
+		return cluster.State, cluster.Reason
+	}
+	remoteCluster, err := client.GetLocalSite()
+	if err != nil {
+		o.Warnf("Failed to query state of cluster %v: %v.",
+			cluster.Domain, trace.DebugReport(err))
+		return cluster.State, cluster.Reason
+	}
+	return remoteCluster.State, remoteCluster.Reason
+}
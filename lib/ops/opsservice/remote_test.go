@@ -0,0 +1,94 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+	"github.com/gravitational/gravity/lib/testutils"
+
+	"github.com/gravitational/teleport"
+	"github.com/gravitational/trace"
+	"github.com/mailgun/timetools"
+	"github.com/pborman/uuid"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+type RemoteSuite struct{}
+
+var _ = check.Suite(&RemoteSuite{})
+
+func (s *RemoteSuite) TestMarksDisconnectedClustersOffline(c *check.C) {
+	backend, err := keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(c.MkDir(), "bolt.db")})
+	c.Assert(err, check.IsNil)
+	defer backend.Close()
+	clock := &timetools.FreezedTime{CurrentTime: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)}
+	operator := &Operator{
+		cfg: Config{
+			Backend: backend,
+			Clock:   clock,
+			Tunnel: testutils.FakeReverseTunnel{Sites: []testutils.FakeRemoteSite{
+				{Name: "disconnected.example.com", Status: teleport.RemoteClusterStatusOffline},
+			}},
+		},
+		statusSnapshots: map[string]storage.StatusSnapshot{},
+		FieldLogger:     logrus.WithField(trace.Component, "test"),
+	}
+	account, err := backend.CreateAccount(storage.Account{ID: uuid.New(), Org: "example.com"})
+	c.Assert(err, check.IsNil)
+	for _, cluster := range []storage.Site{
+		{Domain: "local.example.com", State: ops.SiteStateActive, Local: true},
+		{Domain: "disconnected.example.com", State: ops.SiteStateActive},
+		{Domain: "unknown.example.com", State: ops.SiteStateDegraded,
+			Reason: storage.ReasonClusterDegraded},
+		{Domain: "installing.example.com", State: ops.SiteStateInstalling},
+	} {
+		cluster.AccountID = account.ID
+		cluster.Created = clock.UtcNow()
+		_, err := backend.CreateSite(cluster)
+		c.Assert(err, check.IsNil)
+	}
+
+	err = operator.UpdateRemoteClusterStates(context.TODO())
+	c.Assert(err, check.IsNil)
+
+	states := map[string]string{
+		"local.example.com":        ops.SiteStateActive,
+		"disconnected.example.com": ops.SiteStateOffline,
+		"unknown.example.com":      ops.SiteStateOffline,
+		"installing.example.com":   ops.SiteStateInstalling,
+	}
+	for domain, state := range states {
+		cluster, err := backend.GetSite(domain)
+		c.Assert(err, check.IsNil)
+		c.Assert(cluster.State, check.Equals, state, check.Commentf(domain))
+	}
+	cluster, err := backend.GetSite("unknown.example.com")
+	c.Assert(err, check.IsNil)
+	c.Assert(cluster.Reason, check.Equals, storage.Reason(""))
+
+	snapshots, err := backend.GetStatusSnapshots("disconnected.example.com", time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Assert(snapshots, check.HasLen, 1)
+	c.Assert(snapshots[0].State, check.Equals, ops.SiteStateOffline)
+}
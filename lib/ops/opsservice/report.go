@@ -35,6 +35,7 @@ import (
 	"github.com/gravitational/gravity/lib/report"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/clusterconfig"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
@@ -63,10 +64,10 @@ func (s *site) getSiteOperationCrashReport(op ops.SiteOperation) (io.ReadCloser,
 	}
 
 	runner := s.agentRunner(ctx)
-	return s.getReport(runner, remoteServers, master)
+	return s.getReport(runner, remoteServers, master, ops.GetClusterReportRequest{SiteKey: s.key})
 }
 
-func (s *site) getSiteReport() (io.ReadCloser, error) {
+func (s *site) getSiteReport(req ops.GetClusterReportRequest) (io.ReadCloser, error) {
 	const noRetry = 1
 	servers, err := s.getTeleportServersWithTimeout(
 		nil,
@@ -93,23 +94,34 @@ func (s *site) getSiteReport() (io.ReadCloser, error) {
 		remoteServers = append(remoteServers, teleportServer)
 	}
 
-	return s.getReport(teleportRunner, remoteServers, master)
+	return s.getReport(teleportRunner, remoteServers, master, req)
 }
 
-func (s *site) getReport(runner remoteRunner, servers []remoteServer, master remoteServer) (io.ReadCloser, error) {
+func (s *site) getReport(runner remoteRunner, servers []remoteServer, master remoteServer, req ops.GetClusterReportRequest) (io.ReadCloser, error) {
 	dir, err := ioutil.TempDir("", "report")
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	err = runCollectors(*s, dir, runner)
+	limiter := report.NewLimiter(report.Limits{
+		MaxFileSize: req.MaxFileSize,
+		MaxSize:     req.MaxSize,
+	})
+	reportWriter := limiter.Writer(report.NewFileWriter(dir))
+
+	operations, err := getRecentOperations(*s, req.Operations)
+	if err != nil {
+		log.Errorf("failed to query cluster operations: %v", trace.DebugReport(err))
+	}
+
+	err = runCollectors(*s, reportWriter, operations)
 	if err != nil {
 		// Intermediate steps in diagnostics collection are not fatal
 		// to collect all possible pieces in best-effort
 		log.Errorf("failed to run cluster collectors: %v", trace.DebugReport(err))
 	}
 
-	collectOperationsLogs(*s, dir)
+	collectOperationsLogs(*s, reportWriter, operations)
 
 	if len(servers) > 0 {
 		// Use the first master server to collect kubernetes diagnostics
@@ -119,10 +131,9 @@ func (s *site) getReport(runner remoteRunner, servers []remoteServer, master rem
 			log.Warningf("no master servers, collecting kubernetes diagnostics from %v", server)
 		}
 		serverRunner := &serverRunner{server: server, runner: runner}
-		reportWriter := getReportWriterForServer(dir, server)
-		s.collectKubernetesInfo(reportWriter, serverRunner)
+		s.collectKubernetesInfo(limiter.Writer(getReportWriterForServer(dir, server)), serverRunner)
 
-		err = s.collectDebugInfoFromServers(dir, servers, runner)
+		err = s.collectDebugInfoFromServers(dir, servers, runner, limiter)
 		if err != nil {
 			log.Errorf("failed to collect diagnostics from some nodes: %v", trace.DebugReport(err))
 		}
@@ -153,14 +164,14 @@ func (s *site) getReport(runner remoteRunner, servers []remoteServer, master rem
 //
 //   <server-name>-<resource>
 //
-func (s *site) collectDebugInfoFromServers(dir string, servers []remoteServer, runner remoteRunner) error {
+func (s *site) collectDebugInfoFromServers(dir string, servers []remoteServer, runner remoteRunner, limiter *report.Limiter) error {
 	err := s.executeOnServers(context.TODO(), servers, func(c context.Context, server remoteServer) error {
 		log.Debugf("collectDebugInfo for %v", server)
 		r := &serverRunner{
 			server: server,
 			runner: runner,
 		}
-		reportWriter := getReportWriterForServer(dir, server)
+		reportWriter := limiter.Writer(getReportWriterForServer(dir, server))
 		err := s.collectDebugInfo(reportWriter, r)
 		return trace.Wrap(err)
 	})
@@ -201,7 +212,7 @@ func (s *site) collectKubernetesInfo(reportWriter report.Writer, runner *serverR
 	return nil
 }
 
-func runCollectors(site site, dir string, runner remoteRunner) error {
+func runCollectors(site site, reportWriter report.Writer, operations []ops.SiteOperation) error {
 	storageSite, err := site.service.cfg.Backend.GetSite(site.domainName)
 	if err != nil {
 		return trace.Wrap(err)
@@ -209,9 +220,11 @@ func runCollectors(site site, dir string, runner remoteRunner) error {
 
 	collectors := []collectorFn{
 		collectSiteInfo(*storageSite),
+		collectOperations(operations),
+		collectOperationPlans(operations),
+		collectBackend,
 		collectDumpHook,
 	}
-	reportWriter := report.NewFileWriter(dir)
 
 	// collect information from all collectors
 	for _, collector := range collectors {
@@ -223,22 +236,34 @@ func runCollectors(site site, dir string, runner remoteRunner) error {
 	return nil
 }
 
-func collectOperationsLogs(site site, dir string) error {
+// getRecentOperations returns up to the specified number of the most recent
+// cluster operations
+func getRecentOperations(site site, limit int) ([]ops.SiteOperation, error) {
 	operations, err := site.service.GetSiteOperations(site.key)
 	if err != nil {
-		return trace.Wrap(err, "failed to get cluster operations")
+		return nil, trace.Wrap(err, "failed to get cluster operations")
 	}
+	if limit <= 0 {
+		limit = defaults.ReportOperations
+	}
+	// Operations are sorted starting with the most recent one
+	if len(operations) > limit {
+		operations = operations[:limit]
+	}
+	result := make([]ops.SiteOperation, 0, len(operations))
+	for _, operation := range operations {
+		result = append(result, ops.SiteOperation(operation))
+	}
+	return result, nil
+}
 
-	reportWriter := report.NewFileWriter(dir)
-
-	for _, op := range operations {
-		operation := ops.SiteOperation(op)
-		err = collectOperationLogs(site, operation, reportWriter)
+func collectOperationsLogs(site site, reportWriter report.Writer, operations []ops.SiteOperation) {
+	for _, operation := range operations {
+		err := collectOperationLogs(site, operation, reportWriter)
 		if err != nil {
-			log.Errorf("failed to collect logs for %q: %v", op.Type, trace.DebugReport(err))
+			log.Errorf("failed to collect logs for %q: %v", operation.Type, trace.DebugReport(err))
 		}
 	}
-	return nil
 }
 
 // collectSiteInfo returns JSON-formatted site information
@@ -252,7 +277,7 @@ func collectSiteInfo(s storage.Site) collectorFn {
 
 		// do not leak license in cluster debug report
 		if s.License != "" {
-			s.License = "redacted"
+			s.License = report.Redacted
 		}
 		enc := json.NewEncoder(w)
 		err = enc.Encode(s)
@@ -260,6 +285,115 @@ func collectSiteInfo(s storage.Site) collectorFn {
 	}
 }
 
+// collectOperations returns JSON-formatted list of the specified operations
+// with secrets redacted
+func collectOperations(operations []ops.SiteOperation) collectorFn {
+	return func(reportWriter report.Writer, site site) error {
+		w, err := reportWriter(operationsFilename)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		defer w.Close()
+		return trace.Wrap(report.WriteRedactedJSON(w, operations))
+	}
+}
+
+// collectOperationPlans returns JSON-formatted plans of the specified operations
+// with secrets redacted
+func collectOperationPlans(operations []ops.SiteOperation) collectorFn {
+	return func(reportWriter report.Writer, site site) error {
+		var errors []error
+		for _, operation := range operations {
+			plan, err := site.backend().GetOperationPlan(operation.SiteDomain, operation.ID)
+			if err != nil {
+				if !trace.IsNotFound(err) {
+					errors = append(errors, err)
+				}
+				continue
+			}
+			err = writeRedactedJSON(reportWriter,
+				fmt.Sprintf(opPlanFilename, operation.Type, operation.ID), plan)
+			if err != nil {
+				errors = append(errors, err)
+			}
+		}
+		return trace.NewAggregate(errors...)
+	}
+}
+
+// collectBackend returns JSON-formatted snapshot of the cluster state
+// stored in the backend with secrets redacted
+func collectBackend(reportWriter report.Writer, site site) error {
+	backend := site.backend()
+	var export backendExport
+	var errors []error
+	collect := func(err error) {
+		if err != nil && !trace.IsNotFound(err) {
+			errors = append(errors, err)
+		}
+	}
+	var err error
+	export.Accounts, err = backend.GetAccounts()
+	collect(err)
+	export.Repositories, err = backend.GetRepositories()
+	collect(err)
+	export.Peers, err = backend.GetPeers()
+	collect(err)
+	export.DNSConfig, err = backend.GetDNSConfig()
+	collect(err)
+	export.OpsCenterLinks, err = backend.GetOpsCenterLinks(site.domainName)
+	collect(err)
+	export.ProvisioningTokens, err = backend.GetSiteProvisioningTokens(site.domainName)
+	collect(err)
+	export.TrustedKeys, err = backend.GetTrustedKeys()
+	collect(err)
+	export.FleetUpgrades, err = backend.GetFleetUpgrades()
+	collect(err)
+	export.Environment, err = site.service.GetClusterEnvironmentVariables(site.key)
+	collect(err)
+	export.Configuration, err = site.service.GetClusterConfiguration(site.key)
+	collect(err)
+	if err := writeRedactedJSON(reportWriter, backendFilename, export); err != nil {
+		errors = append(errors, err)
+	}
+	return trace.NewAggregate(errors...)
+}
+
+// backendExport is the snapshot of the cluster state stored in the backend
+type backendExport struct {
+	// Accounts lists the accounts
+	Accounts []storage.Account `json:"accounts,omitempty"`
+	// Repositories lists the package repositories
+	Repositories []storage.Repository `json:"repositories,omitempty"`
+	// Peers lists the cluster controller peers
+	Peers []storage.Peer `json:"peers,omitempty"`
+	// DNSConfig is the cluster DNS configuration
+	DNSConfig *storage.DNSConfig `json:"dns_config,omitempty"`
+	// OpsCenterLinks lists the links to remote Ops Centers
+	OpsCenterLinks []storage.OpsCenterLink `json:"ops_center_links,omitempty"`
+	// ProvisioningTokens lists the cluster provisioning tokens
+	ProvisioningTokens []storage.ProvisioningToken `json:"provisioning_tokens,omitempty"`
+	// TrustedKeys lists the keys trusted to sign installers
+	TrustedKeys []storage.TrustedKey `json:"trusted_keys,omitempty"`
+	// FleetUpgrades lists the fleet upgrades
+	FleetUpgrades []storage.FleetUpgrade `json:"fleet_upgrades,omitempty"`
+	// Environment is the cluster runtime environment
+	Environment storage.EnvironmentVariables `json:"environment,omitempty"`
+	// Configuration is the cluster configuration
+	Configuration clusterconfig.Interface `json:"configuration,omitempty"`
+}
+
+// writeRedactedJSON writes the JSON representation of the specified value
+// with secrets redacted into the report file with the given name
+func writeRedactedJSON(reportWriter report.Writer, name string, v interface{}) error {
+	w, err := reportWriter(name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer w.Close()
+	return trace.Wrap(report.WriteRedactedJSON(w, v))
+}
+
 // collectDumpHook returns the output of the dump hook
 func collectDumpHook(reportWriter report.Writer, site site) error {
 	if !site.app.Manifest.HasHook(schema.HookDump) {
@@ -315,6 +449,13 @@ func getReportWriterForServer(dir string, server remoteServer) report.Writer {
 const (
 	// siteInfoFilename is the name of the file with JSON-dumped site
 	siteInfoFilename = "site.json"
+	// operationsFilename is the name of the file with JSON-dumped recent operations
+	operationsFilename = "operations.json"
+	// opPlanFilename defines the file pattern that stores the JSON-dumped plan
+	// of a particular cluster operation
+	opPlanFilename = "%v.%v.plan.json"
+	// backendFilename is the name of the file with JSON-dumped cluster state
+	backendFilename = "backend.json"
 	// dumpHookFilename is the name of the file with dump hook output
 	dumpHookFilename = "dump-hook"
 	// opLogsFilename defines the file pattern that stores operation log for a particular
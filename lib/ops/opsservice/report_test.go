@@ -22,6 +22,8 @@ import (
 	"io"
 
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/report"
 	"github.com/gravitational/gravity/lib/storage"
 
 	"gopkg.in/check.v1"
@@ -49,6 +51,38 @@ func (s *ReportSuite) TestClusterInfo(c *check.C) {
 	c.Assert(fromReport.License, check.Equals, "redacted")
 }
 
+func (s *ReportSuite) TestOperationsRedactSecrets(c *check.C) {
+	operation := ops.SiteOperation{
+		ID:         "op-1",
+		SiteDomain: "example.com",
+		Type:       ops.OperationInstall,
+		InstallExpand: &storage.InstallExpandOperationState{
+			Vars: storage.OperationVariables{
+				System: storage.SystemVariables{Token: "agent-token"},
+				AWS: storage.AWSVariables{
+					Region:    "us-west-2",
+					AccessKey: "access-key",
+					SecretKey: "secret-key",
+				},
+			},
+		},
+	}
+	var b bytes.Buffer
+	err := collectOperations([]ops.SiteOperation{operation})(func(name string) (io.WriteCloser, error) {
+		c.Assert(name, check.Equals, operationsFilename)
+		return &nopCloser{&b}, nil
+	}, site{})
+	c.Assert(err, check.IsNil)
+	var fromReport []ops.SiteOperation
+	c.Assert(json.Unmarshal(b.Bytes(), &fromReport), check.IsNil)
+	c.Assert(fromReport, check.HasLen, 1)
+	vars := fromReport[0].InstallExpand.Vars
+	c.Assert(vars.System.Token, check.Equals, report.Redacted)
+	c.Assert(vars.AWS.AccessKey, check.Equals, report.Redacted)
+	c.Assert(vars.AWS.SecretKey, check.Equals, report.Redacted)
+	c.Assert(vars.AWS.Region, check.Equals, "us-west-2")
+}
+
 type nopCloser struct {
 	io.Writer
 }
@@ -0,0 +1,178 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// CreateScheduledOperation queues the operation to start according
+// to the request schedule.
+//
+// Queued operations are started by the cluster operation scheduler,
+// see StartScheduledOperations
+func (o *Operator) CreateScheduledOperation(ctx context.Context, req ops.CreateScheduledOperationRequest) (*storage.ScheduledOperation, error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cluster, err := o.backend().GetSite(req.ClusterName)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	app, err := loc.ParseLocator(req.App)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := pack.CheckUpdatePackage(cluster.App.Locator(), *app); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	now := o.clock().UtcNow()
+	start, err := req.Schedule.NextStart(now)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if start.IsZero() {
+		return nil, trace.BadParameter("maintenance window %q never opens",
+			req.Schedule.Window.Cron)
+	}
+	op := storage.ScheduledOperation{
+		ID:          uuid.New(),
+		AccountID:   req.AccountID,
+		ClusterName: req.ClusterName,
+		Type:        req.Type,
+		Created:     now,
+		CreatedBy:   storage.UserFromContext(ctx),
+		Schedule:    req.Schedule,
+		App:         app.String(),
+		State:       storage.ScheduledOperationStatePending,
+	}
+	if _, err := o.backend().CreateScheduledOperation(op); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	o.Infof("Scheduled %v to start at %v.", op.Type, start.Format(time.RFC3339))
+	return &op, nil
+}
+
+// GetScheduledOperations returns all scheduled operations of the cluster
+func (o *Operator) GetScheduledOperations(ctx context.Context, key ops.SiteKey) ([]storage.ScheduledOperation, error) {
+	if err := key.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.backend().GetScheduledOperations(key.SiteDomain)
+}
+
+// CancelScheduledOperation cancels the pending scheduled operation
+func (o *Operator) CancelScheduledOperation(ctx context.Context, key ops.ScheduledOperationKey) error {
+	if err := key.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	op, err := o.backend().GetScheduledOperation(key.ClusterName, key.ID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !op.IsPending() {
+		return trace.CompareFailed("scheduled operation %v is %v and cannot be canceled",
+			op.ID, op.State)
+	}
+	op.State = storage.ScheduledOperationStateCanceled
+	_, err = o.backend().UpdateScheduledOperation(*op)
+	return trace.Wrap(err)
+}
+
+// StartScheduledOperations starts the pending scheduled operations
+// of the specified cluster that are due
+func (o *Operator) StartScheduledOperations(ctx context.Context, key ops.SiteKey) error {
+	scheduler := &operationScheduler{
+		backend:     o.backend(),
+		operator:    o,
+		FieldLogger: o.WithField("cluster", key.SiteDomain),
+	}
+	return trace.Wrap(scheduler.startDue(ctx, key, o.clock().UtcNow()))
+}
+
+// operationScheduler starts scheduled operations
+type operationScheduler struct {
+	// backend persists scheduled operations
+	backend storage.Backend
+	// operator creates cluster operations
+	operator ops.Operator
+	// FieldLogger is used for logging
+	log.FieldLogger
+}
+
+// startDue starts the pending operations of the cluster that can be
+// started at the specified time. Only the first due operation is started
+// since a cluster can run a single operation at a time
+func (s *operationScheduler) startDue(ctx context.Context, key ops.SiteKey, now time.Time) error {
+	scheduled, err := s.backend.GetScheduledOperations(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, op := range scheduled {
+		if !op.IsPending() {
+			continue
+		}
+		start, err := op.Schedule.NextStart(now)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if start.IsZero() || start.After(now) {
+			continue
+		}
+		active, err := ops.GetActiveOperations(key, s.operator)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		if len(active) != 0 {
+			s.Debugf("Delaying scheduled operation %v until %v completes.", op.ID, &active[0])
+			return nil
+		}
+		return trace.Wrap(s.start(ctx, op))
+	}
+	return nil
+}
+
+// start starts the scheduled operation and records the outcome
+func (s *operationScheduler) start(ctx context.Context, op storage.ScheduledOperation) error {
+	s.Infof("Starting scheduled %v %v.", op.Type, op.ID)
+	key, err := s.operator.CreateSiteAppUpdateOperation(ctx, ops.CreateSiteAppUpdateOperationRequest{
+		AccountID:   op.AccountID,
+		SiteDomain:  op.ClusterName,
+		App:         op.App,
+		StartAgents: true,
+	})
+	if err != nil {
+		s.Warnf("Failed to start scheduled operation %v: %v.", op.ID, trace.DebugReport(err))
+		op.State = storage.ScheduledOperationStateFailed
+		op.Error = trace.UserMessage(err)
+	} else {
+		op.State = storage.ScheduledOperationStateStarted
+		op.OperationID = key.OperationID
+	}
+	_, err = s.backend.UpdateScheduledOperation(op)
+	return trace.Wrap(err)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+type ScheduleSuite struct {
+	backend storage.Backend
+}
+
+var _ = check.Suite(&ScheduleSuite{})
+
+func (s *ScheduleSuite) SetUpTest(c *check.C) {
+	var err error
+	s.backend, err = keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(c.MkDir(), "bolt.db")})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *ScheduleSuite) TearDownTest(c *check.C) {
+	s.backend.Close()
+}
+
+func (s *ScheduleSuite) TestStartsDueOperations(c *check.C) {
+	now := time.Date(2019, time.October, 16, 10, 30, 0, 0, time.UTC)
+	s.createOperation(c, "canceled", storage.OperationSchedule{NotBefore: now},
+		storage.ScheduledOperationStateCanceled)
+	s.createOperation(c, "later", storage.OperationSchedule{NotBefore: now.Add(time.Hour)},
+		storage.ScheduledOperationStatePending)
+	s.createOperation(c, "window", storage.OperationSchedule{
+		Window: &storage.MaintenanceWindow{Cron: "0 10 * * *", Duration: time.Hour},
+	}, storage.ScheduledOperationStatePending)
+
+	scheduler := s.newScheduler(&fakeScheduleOperator{})
+	c.Assert(scheduler.startDue(context.TODO(), clusterKey, now), check.IsNil)
+	c.Assert(s.operationStates(c), check.DeepEquals, map[string]string{
+		"canceled": storage.ScheduledOperationStateCanceled,
+		"later":    storage.ScheduledOperationStatePending,
+		"window":   storage.ScheduledOperationStateStarted,
+	})
+
+	c.Assert(scheduler.startDue(context.TODO(), clusterKey, now.Add(time.Hour)), check.IsNil)
+	c.Assert(s.operationStates(c)["later"], check.Equals, storage.ScheduledOperationStateStarted)
+}
+
+func (s *ScheduleSuite) TestWaitsForActiveOperation(c *check.C) {
+	now := time.Date(2019, time.October, 16, 10, 30, 0, 0, time.UTC)
+	s.createOperation(c, "due", storage.OperationSchedule{NotBefore: now},
+		storage.ScheduledOperationStatePending)
+
+	scheduler := s.newScheduler(&fakeScheduleOperator{active: true})
+	c.Assert(scheduler.startDue(context.TODO(), clusterKey, now), check.IsNil)
+	c.Assert(s.operationStates(c)["due"], check.Equals, storage.ScheduledOperationStatePending)
+}
+
+func (s *ScheduleSuite) createOperation(c *check.C, id string, schedule storage.OperationSchedule, state string) {
+	_, err := s.backend.CreateScheduledOperation(storage.ScheduledOperation{
+		ID:          id,
+		AccountID:   clusterKey.AccountID,
+		ClusterName: clusterKey.SiteDomain,
+		Type:        ops.OperationUpdate,
+		Created:     time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		Schedule:    schedule,
+		App:         "example.com/app:2.0.0",
+		State:       state,
+	})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *ScheduleSuite) operationStates(c *check.C) map[string]string {
+	scheduled, err := s.backend.GetScheduledOperations(clusterKey.SiteDomain)
+	c.Assert(err, check.IsNil)
+	states := make(map[string]string)
+	for _, op := range scheduled {
+		states[op.ID] = op.State
+	}
+	return states
+}
+
+func (s *ScheduleSuite) newScheduler(operator ops.Operator) *operationScheduler {
+	return &operationScheduler{
+		backend:     s.backend,
+		operator:    operator,
+		FieldLogger: logrus.WithField(trace.Component, "test"),
+	}
+}
+
+var clusterKey = ops.SiteKey{AccountID: "system", SiteDomain: "example.com"}
+
+// fakeScheduleOperator is a cluster operator that optionally
+// has an operation in progress
+type fakeScheduleOperator struct {
+	fakeUpdateOperator
+	// active specifies whether the cluster has an active operation
+	active bool
+}
+
+func (o *fakeScheduleOperator) GetSiteOperations(key ops.SiteKey) (ops.SiteOperations, error) {
+	if !o.active {
+		return nil, nil
+	}
+	return ops.SiteOperations{{ID: "expand", State: ops.OperationStateExpandProvisioning}}, nil
+}
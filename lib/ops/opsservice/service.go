@@ -27,6 +27,7 @@ import (
 	"time"
 
 	appservice "github.com/gravitational/gravity/lib/app"
+	dockerapp "github.com/gravitational/gravity/lib/app/docker"
 	"github.com/gravitational/gravity/lib/checks"
 	"github.com/gravitational/gravity/lib/clients"
 	"github.com/gravitational/gravity/lib/constants"
@@ -38,18 +39,22 @@ import (
 	"github.com/gravitational/gravity/lib/ops/monitoring"
 	"github.com/gravitational/gravity/lib/ops/opsclient"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/verify"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/users"
 	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/lib/webhooks"
 
 	"github.com/cloudflare/cfssl/signer"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/gravitational/configure/cstrings"
 	"github.com/gravitational/license/authority"
+	teledefaults "github.com/gravitational/teleport/lib/defaults"
 	"github.com/gravitational/teleport/lib/events"
 	"github.com/gravitational/teleport/lib/reversetunnel"
 	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/teleport/lib/session"
 	teleutils "github.com/gravitational/teleport/lib/utils"
 	"github.com/gravitational/trace"
 	"github.com/mailgun/timetools"
@@ -135,6 +140,26 @@ type Config struct {
 
 	// GetHelmClient is a factory method for creating a Helm client.
 	GetHelmClient helm.GetClientFunc
+
+	// Registry optionally specifies the cluster Docker registry
+	// served by this process
+	Registry RegistryStatusReporter
+
+	// PackageVerifier optionally specifies the verifier of the cluster
+	// packages stored by this process
+	PackageVerifier PackageVerificationReporter
+}
+
+// RegistryStatusReporter reports the status of a Docker registry
+type RegistryStatusReporter interface {
+	// Status returns the health and the storage usage of the registry
+	Status(context.Context) (*dockerapp.RegistryStatus, error)
+}
+
+// PackageVerificationReporter reports the results of the package verification
+type PackageVerificationReporter interface {
+	// LastReport returns the report of the last completed verification
+	LastReport() (*verify.Report, error)
 }
 
 // Operator implements Operator interface
@@ -154,6 +179,15 @@ type Operator struct {
 	// operationGroups maintains operation group for each site
 	operationGroups map[ops.SiteKey]*operationGroup
 
+	// statusSnapshots maps a cluster name to its last recorded status snapshot
+	statusSnapshots map[string]storage.StatusSnapshot
+
+	// notifier delivers operation events to the registered webhooks
+	notifier *webhooks.Notifier
+
+	// fleet runs the fleet upgrades of this Ops Center
+	fleet *fleetUpgrades
+
 	// FieldLogger allows this operator to log messages
 	log.FieldLogger
 }
@@ -165,10 +199,18 @@ func New(cfg Config) (*Operator, error) {
 		return nil, trace.Wrap(err)
 	}
 
+	notifier, err := webhooks.New(webhooks.Config{Webhooks: cfg.Backend})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	operator := &Operator{
 		cfg:             cfg,
 		providers:       map[ops.SiteKey]CloudProvider{},
 		operationGroups: map[ops.SiteKey]*operationGroup{},
+		statusSnapshots: map[string]storage.StatusSnapshot{},
+		notifier:        notifier,
+		fleet:           newFleetUpgrades(),
 		kubeClient:      cfg.Client,
 		FieldLogger:     log.WithField(trace.Component, constants.ComponentOps),
 	}
@@ -183,9 +225,16 @@ func NewLocalOperator(cfg Config) (*Operator, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	notifier, err := webhooks.New(webhooks.Config{Webhooks: cfg.Backend})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 	return &Operator{
 		cfg:             cfg,
 		operationGroups: map[ops.SiteKey]*operationGroup{},
+		statusSnapshots: map[string]storage.StatusSnapshot{},
+		notifier:        notifier,
+		fleet:           newFleetUpgrades(),
 		kubeClient:      cfg.Client,
 		FieldLogger:     log.WithField(trace.Component, constants.ComponentOps),
 	}, nil
@@ -315,6 +364,30 @@ func (o *Operator) CreateAccount(req ops.NewAccountRequest) (*ops.Account, error
 	return &a, nil
 }
 
+// DeleteAccount deletes the account with the specified ID.
+// The system account and accounts with clusters cannot be deleted
+func (o *Operator) DeleteAccount(accountID string) error {
+	if accountID == defaults.SystemAccountID {
+		return trace.BadParameter("system account cannot be deleted")
+	}
+	if _, err := o.backend().GetAccount(accountID); err != nil {
+		return trace.Wrap(err)
+	}
+	clusters, err := o.backend().GetSites(accountID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(clusters) != 0 {
+		return trace.CompareFailed("account %v has %v cluster(s), delete them first",
+			accountID, len(clusters))
+	}
+	if err := o.backend().DeleteAccount(accountID); err != nil {
+		return trace.Wrap(err)
+	}
+	o.Infof("Deleted account %v.", accountID)
+	return nil
+}
+
 func (o *Operator) GetAccounts() ([]ops.Account, error) {
 	accts, err := o.backend().GetAccounts()
 	if err != nil {
@@ -352,9 +425,10 @@ func (o *Operator) DeleteLocalUser(email string) error {
 
 func (o *Operator) CreateAPIKey(req ops.NewAPIKeyRequest) (*storage.APIKey, error) {
 	key, err := o.cfg.Users.CreateAPIKey(storage.APIKey{
-		UserEmail: req.UserEmail,
-		Expires:   req.Expires,
-		Token:     req.Token,
+		UserEmail:   req.UserEmail,
+		Expires:     req.Expires,
+		Token:       req.Token,
+		Permissions: req.Permissions,
 	}, req.Upsert)
 	return key, trace.Wrap(err)
 }
@@ -413,7 +487,7 @@ func (o *Operator) GetExpandToken(key ops.SiteKey) (*storage.ProvisioningToken,
 
 	for _, token := range tokens {
 		// return long-lived join token
-		if token.Type == storage.ProvisioningTokenTypeExpand && token.Expires.IsZero() {
+		if token.Type == storage.ProvisioningTokenTypeExpand && token.Expires.IsZero() && !token.IsScopedJoinToken() {
 			return &token, nil
 		}
 	}
@@ -484,6 +558,12 @@ func (o *Operator) validateNewSiteRequest(req *ops.NewSiteRequest) error {
 		req.DNSConfig = storage.DefaultDNSConfig
 	}
 
+	if req.CertAuthority != nil {
+		if _, err := newExternalCertAuthority(*req.CertAuthority); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
 	if req.License == "" {
 		if app.RequiresLicense() {
 			return trace.BadParameter("the app requires a license")
@@ -597,8 +677,12 @@ func (o *Operator) CreateSite(r ops.NewSiteRequest) (*ops.Site, error) {
 		DNSConfig:    r.DNSConfig,
 		ClusterState: storage.ClusterState{
 			Docker: dockerConfig,
+			FIPS:   r.FIPS,
 		},
 	}
+	if r.CertAuthority != nil {
+		clusterData.PKI = newExternalPKI(*r.CertAuthority)
+	}
 	if runtimeLoc := app.Manifest.Base(); runtimeLoc != nil {
 		runtimeApp, err := o.cfg.Apps.GetApp(*runtimeLoc)
 		if err != nil {
@@ -613,13 +697,14 @@ func (o *Operator) CreateSite(r ops.NewSiteRequest) (*ops.Site, error) {
 		return nil, trace.Wrap(err)
 	}
 	st, err := newSite(&site{
-		domainName: clusterData.Domain,
-		key:        ops.SiteKey{AccountID: account.ID, SiteDomain: clusterData.Domain},
-		provider:   clusterData.Provider,
-		service:    o,
-		appService: o.cfg.Apps,
-		app:        app,
-		seedConfig: o.cfg.SeedConfig,
+		domainName:  clusterData.Domain,
+		key:         ops.SiteKey{AccountID: account.ID, SiteDomain: clusterData.Domain},
+		provider:    clusterData.Provider,
+		service:     o,
+		appService:  o.cfg.Apps,
+		app:         app,
+		seedConfig:  o.cfg.SeedConfig,
+		backendSite: clusterData,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -633,6 +718,15 @@ func (o *Operator) CreateSite(r ops.NewSiteRequest) (*ops.Site, error) {
 		SiteDomain: clusterData.Domain,
 	}
 
+	if r.CertAuthority != nil {
+		if err := st.seedCertAuthority(*r.CertAuthority); err != nil {
+			if errDelete := o.DeleteSite(siteKey); errDelete != nil {
+				log.Errorf("Failed to remove cluster %v: %v.", siteKey, trace.DebugReport(errDelete))
+			}
+			return nil, trace.Wrap(err)
+		}
+	}
+
 	agent, err := o.cfg.Users.CreateClusterAgent(clusterData.Domain, storage.NewUser(
 		storage.ClusterAgent(clusterData.Domain), storage.UserSpecV2{
 			AccountID: clusterData.AccountID,
@@ -691,6 +785,9 @@ func (o *Operator) GetLocalUser(key ops.SiteKey) (storage.User, error) {
 
 // ResetUserPassword resets the user password and returns the new one
 func (o *Operator) ResetUserPassword(req ops.ResetUserPasswordRequest) (string, error) {
+	if err := o.checkUserAccount(req.AccountID, req.Email); err != nil {
+		return "", trace.Wrap(err)
+	}
 	password, err := o.cfg.Users.ResetPassword(req.Email)
 	return password, trace.Wrap(err)
 }
@@ -1089,17 +1186,17 @@ func (o *Operator) GetSiteOperationCrashReport(key ops.SiteOperationKey) (io.Rea
 	case ops.OperationInstall:
 		return site.getSiteOperationCrashReport(*op)
 	default:
-		return site.getSiteReport()
+		return site.getSiteReport(ops.GetClusterReportRequest{SiteKey: key.SiteKey()})
 	}
 }
 
-func (o *Operator) GetSiteReport(key ops.SiteKey) (io.ReadCloser, error) {
-	site, err := o.openSite(key)
+func (o *Operator) GetSiteReport(req ops.GetClusterReportRequest) (io.ReadCloser, error) {
+	site, err := o.openSite(req.SiteKey)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	return site.getSiteReport()
+	return site.getSiteReport(req)
 }
 
 func (o *Operator) GetSiteOperationProgress(key ops.SiteOperationKey) (*ops.ProgressEntry, error) {
@@ -1114,6 +1211,36 @@ func (o *Operator) GetSiteOperationProgress(key ops.SiteOperationKey) (*ops.Prog
 	return &progressEntry, nil
 }
 
+// WatchSiteOperationProgress polls the progress of the specified operation
+// and sends new entries to progressC until the operation completes
+func (o *Operator) WatchSiteOperationProgress(ctx context.Context, key ops.SiteOperationKey, progressC chan<- ops.ProgressEntry) error {
+	ticker := time.NewTicker(defaults.ProgressPollTimeout)
+	defer ticker.Stop()
+	var last *ops.ProgressEntry
+	for {
+		entry, err := o.GetSiteOperationProgress(key)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		if entry != nil && (last == nil || !last.IsEqual(*entry)) {
+			select {
+			case progressC <- *entry:
+			case <-ctx.Done():
+				return trace.Wrap(ctx.Err())
+			}
+			if entry.IsCompleted() {
+				return nil
+			}
+			last = entry
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return trace.Wrap(ctx.Err())
+		}
+	}
+}
+
 func (o *Operator) CreateProgressEntry(key ops.SiteOperationKey, entry ops.ProgressEntry) error {
 	_, err := o.backend().CreateProgressEntry(storage.ProgressEntry(entry))
 	if err != nil {
@@ -1391,6 +1518,44 @@ func (o *Operator) EmitAuditEvent(ctx context.Context, req ops.AuditEventRequest
 	return nil
 }
 
+// GetAuditEvents returns the recorded audit events of operator API calls.
+func (o *Operator) GetAuditEvents(ctx context.Context, req ops.GetAuditEventsRequest) ([]storage.AuditEvent, error) {
+	events, err := o.backend().GetAuditEvents(req.Since)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if users.IsSystemAccount(req.AccountID) {
+		return events, nil
+	}
+	// only return the events recorded in the requested account
+	var result []storage.AuditEvent
+	for _, event := range events {
+		if event.AccountID == req.AccountID {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+// UploadSessionRecording stores the recording of an interactive session
+// read from the provided reader for later playback.
+func (o *Operator) UploadSessionRecording(ctx context.Context, req ops.UploadSessionRecordingRequest, reader io.Reader) error {
+	err := req.Check()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	o.Infof("Upload recording of session %v.", req.SessionID)
+	err = o.cfg.AuditLog.UploadSessionRecording(events.SessionRecording{
+		Namespace: teledefaults.Namespace,
+		SessionID: session.ID(req.SessionID),
+		Recording: reader,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
 func (o *Operator) openSite(key ops.SiteKey) (*site, error) {
 	site, err := o.backend().GetSite(key.SiteDomain)
 	if err != nil {
@@ -23,6 +23,7 @@ import (
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/kubernetes"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
@@ -82,6 +83,7 @@ func (s *site) createShrinkOperation(context context.Context, req ops.CreateSite
 		Force:       req.Force,
 		Vars:        req.Variables,
 		NodeRemoved: req.NodeRemoved,
+		Drain:       req.Drain,
 	}
 	op.Shrink.Vars.System.ClusterName = s.key.SiteDomain
 
@@ -282,6 +284,23 @@ func (s *site) shrinkOperationStart(ctx *operationContext) (err error) {
 		}
 	}
 
+	// evict the workloads before the node is removed from Kubernetes and
+	// the system software is stopped on it
+	if online {
+		s.reportProgress(ctx, ops.ProgressEntry{
+			State:      ops.ProgressStateInProgress,
+			Completion: 25,
+			Message:    "draining the node",
+		})
+
+		if err = s.drainNode(ctx, *server, state.Drain); err != nil {
+			if !force {
+				return trace.Wrap(err, "failed to drain the node")
+			}
+			ctx.Warningf("failed to drain the node, force continue: %v", trace.DebugReport(err))
+		}
+	}
+
 	s.reportProgress(ctx, ops.ProgressEntry{
 		State:      ops.ProgressStateInProgress,
 		Completion: 30,
@@ -598,6 +617,26 @@ func (s *site) unlabelNode(server storage.Server, runner *serverRunner) error {
 	return trace.Wrap(err)
 }
 
+// drainNode cordons the Kubernetes node of the specified server and evicts its pods
+func (s *site) drainNode(ctx *operationContext, server storage.Server, options *storage.DrainOptions) error {
+	client, err := s.service.GetKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var drainOptions storage.DrainOptions
+	if options != nil {
+		drainOptions = *options
+	}
+	ctx.Infof("Drain node %v.", server.KubeNodeID())
+	drainCtx, cancel := context.WithTimeout(context.TODO(), defaults.DrainTimeout)
+	defer cancel()
+	err = kubernetes.Drain(drainCtx, client, server.KubeNodeID(), drainOptions)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
 func (s *site) removeNodeFromCluster(server storage.Server, runner *serverRunner) (err error) {
 	provisionedServer := ProvisionedServer{Server: server}
 	commands := [][]string{
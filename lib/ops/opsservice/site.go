@@ -26,6 +26,7 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	appservice "github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/constants"
@@ -641,7 +642,7 @@ func (s site) gid() string {
 	if !s.backendSite.ServiceUser.IsEmpty() {
 		return s.backendSite.ServiceUser.GID
 	}
-	return defaults.ServiceUserID
+	return defaults.ServiceGroupID
 }
 
 func convertSite(in storage.Site, apps appservice.Applications) (*ops.Site, error) {
@@ -688,6 +689,7 @@ func convertSite(in storage.Site, apps appservice.Applications) (*ops.Site, erro
 		CloudConfig:              in.CloudConfig,
 		DNSOverrides:             in.DNSOverrides,
 		DNSConfig:                in.DNSConfig,
+		PKI:                      in.PKI,
 	}
 	if in.License != "" {
 		parsed, err := license.ParseLicense(in.License)
@@ -699,6 +701,8 @@ func convertSite(in storage.Site, apps appservice.Applications) (*ops.Site, erro
 		site.License = &ops.License{
 			Raw:     in.License,
 			Payload: payload,
+			Status: ops.NewLicenseStatus(payload,
+				len(in.ClusterState.Servers), time.Now().UTC()),
 		}
 	}
 	return site, nil
@@ -56,7 +56,12 @@ func (o *Operator) UpdateSMTPConfig(key ops.SiteKey, config storage.SMTPConfig)
 		return trace.Wrap(err)
 	}
 
-	return updateSMTPConfig(client.Core().Secrets(defaults.MonitoringNamespace), config)
+	err = updateSMTPConfig(client.Core().Secrets(defaults.MonitoringNamespace), config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return trace.Wrap(o.updateAlertmanagerConfig(key, client))
 }
 
 // DeleteSMTPConfig deletes the cluster SMTP configuration
@@ -70,7 +75,11 @@ func (o *Operator) DeleteSMTPConfig(key ops.SiteKey) error {
 	if trace.IsNotFound(err) {
 		return trace.NotFound("no SMTP configuration found")
 	}
-	return trace.Wrap(err)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return trace.Wrap(o.updateAlertmanagerConfig(key, client))
 }
 
 func getSMTPConfig(client corev1.SecretInterface) ([]byte, error) {
@@ -18,8 +18,10 @@ package opsservice
 
 import (
 	"context"
+	"time"
 
 	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/ops/events"
 	"github.com/gravitational/gravity/lib/schema"
@@ -28,6 +30,7 @@ import (
 
 	"github.com/gravitational/satellite/agent/proto/agentpb"
 	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
 )
 
 // CheckSiteStatus runs application status hook and updates cluster status appropriately
@@ -46,7 +49,7 @@ func (o *Operator) CheckSiteStatus(ctx context.Context, key ops.SiteKey) error {
 		return nil
 	}
 
-	statusErr := cluster.checkPlanetStatus(context.TODO())
+	planetStatus, statusErr := cluster.checkPlanetStatus(context.TODO())
 	reason := storage.ReasonClusterDegraded
 	if statusErr == nil {
 		statusErr = cluster.checkStatusHook(context.TODO())
@@ -62,12 +65,20 @@ func (o *Operator) CheckSiteStatus(ctx context.Context, key ops.SiteKey) error {
 		if err != nil {
 			return trace.Wrap(err)
 		}
+		o.recordStatusSnapshot(newStatusSnapshot(cluster.backendSite.Domain,
+			ops.SiteStateDegraded, reason, statusErr, planetStatus, o.cfg.Clock.UtcNow()))
 		events.Emit(ctx, o, events.ClusterDegraded, events.Fields{
 			events.FieldReason: reason,
 		})
 		return trace.Wrap(statusErr)
 	}
 
+	if err := o.checkLicense(ctx, cluster); err != nil {
+		o.recordStatusSnapshot(newStatusSnapshot(cluster.backendSite.Domain,
+			ops.SiteStateDegraded, storage.ReasonLicenseInvalid, err, planetStatus, o.cfg.Clock.UtcNow()))
+		return trace.Wrap(err)
+	}
+
 	// all status checks passed so if the cluster was previously disabled
 	// because of those checks, enable it back
 	if cluster.canActivate() {
@@ -79,27 +90,156 @@ func (o *Operator) CheckSiteStatus(ctx context.Context, key ops.SiteKey) error {
 			return trace.Wrap(err)
 		}
 		events.Emit(ctx, o, events.ClusterActivated, events.Fields{})
+		o.recordStatusSnapshot(newStatusSnapshot(cluster.backendSite.Domain,
+			ops.SiteStateActive, "", nil, planetStatus, o.cfg.Clock.UtcNow()))
+		return nil
 	}
 
+	o.recordStatusSnapshot(newStatusSnapshot(cluster.backendSite.Domain,
+		cluster.backendSite.State, cluster.backendSite.Reason, nil, planetStatus, o.cfg.Clock.UtcNow()))
 	return nil
 }
 
-// canActivate retursn true if the cluster is disabled b/c of status checks
+// GetClusterStatusHistory returns the recorded status snapshots of the cluster
+func (o *Operator) GetClusterStatusHistory(ctx context.Context, req ops.GetClusterStatusHistoryRequest) ([]storage.StatusSnapshot, error) {
+	snapshots, err := o.backend().GetStatusSnapshots(req.SiteDomain, req.Since)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return snapshots, nil
+}
+
+// GetRegistryStatus returns the health and the storage usage of the cluster registry
+func (o *Operator) GetRegistryStatus(ctx context.Context, key ops.SiteKey) (*ops.RegistryStatus, error) {
+	if _, err := o.openSite(key); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if o.cfg.Registry == nil {
+		return nil, trace.NotFound("cluster registry is not served by this process")
+	}
+	status, err := o.cfg.Registry.Status(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &ops.RegistryStatus{
+		Healthy:       status.Healthy(),
+		FailedChecks:  status.FailedChecks,
+		StorageDriver: status.StorageDriver,
+		Repositories:  status.Repositories,
+		Manifests:     status.Manifests,
+		Blobs:         status.Blobs,
+		SizeBytes:     status.SizeBytes,
+	}, nil
+}
+
+// GetPackageVerificationStatus returns the results of the last integrity
+// verification of the cluster packages stored by this process
+func (o *Operator) GetPackageVerificationStatus(ctx context.Context, key ops.SiteKey) (*ops.PackageVerificationStatus, error) {
+	if _, err := o.openSite(key); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if o.cfg.PackageVerifier == nil {
+		return nil, trace.NotFound("cluster packages are not verified by this process")
+	}
+	report, err := o.cfg.PackageVerifier.LastReport()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &ops.PackageVerificationStatus{
+		Healthy: report.Healthy(),
+		Report:  *report,
+	}, nil
+}
+
+// GetClusterHealth returns the health of cluster nodes with the reasons
+// of the failed health probes as reported by planet agents
+func (o *Operator) GetClusterHealth(ctx context.Context, key ops.SiteKey) (*ops.ClusterHealth, error) {
+	cluster, err := o.openSite(key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	agent, err := status.FromPlanetAgent(ctx, cluster.backendSite.ClusterState.Servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	health := &ops.ClusterHealth{
+		Status: agent.SystemStatus.String(),
+	}
+	for _, node := range agent.Nodes {
+		health.Nodes = append(health.Nodes, ops.NodeHealth{
+			Hostname:    node.Hostname,
+			AdvertiseIP: node.AdvertiseIP,
+			Status:      node.Status,
+			Probes:      node.Probes,
+		})
+	}
+	return health, nil
+}
+
+// recordStatusSnapshot saves the cluster status snapshot if the cluster status
+// has changed since the last recorded snapshot or the last snapshot is too old
+func (o *Operator) recordStatusSnapshot(snapshot storage.StatusSnapshot) {
+	o.mu.Lock()
+	last, exists := o.statusSnapshots[snapshot.ClusterName]
+	o.mu.Unlock()
+	if exists && !snapshot.IsTransition(last) &&
+		snapshot.Time.Sub(last.Time) < defaults.StatusSnapshotInterval {
+		return
+	}
+	err := o.backend().CreateStatusSnapshot(snapshot, defaults.StatusHistoryRetention)
+	if err != nil {
+		o.Warnf("Failed to record cluster status snapshot: %v.", trace.DebugReport(err))
+		return
+	}
+	o.mu.Lock()
+	o.statusSnapshots[snapshot.ClusterName] = snapshot
+	o.mu.Unlock()
+}
+
+// newStatusSnapshot returns a new status snapshot of the specified cluster
+func newStatusSnapshot(clusterName, state string, reason storage.Reason, statusErr error, planetStatus *status.Agent, now time.Time) storage.StatusSnapshot {
+	snapshot := storage.StatusSnapshot{
+		ID:          uuid.New(),
+		ClusterName: clusterName,
+		Time:        now,
+		State:       state,
+		Reason:      reason,
+	}
+	if statusErr != nil {
+		snapshot.Error = trace.UserMessage(statusErr)
+	}
+	if planetStatus != nil {
+		for _, node := range planetStatus.Nodes {
+			snapshot.Nodes = append(snapshot.Nodes, storage.NodeStatusSnapshot{
+				Hostname:     node.Hostname,
+				AdvertiseIP:  node.AdvertiseIP,
+				Status:       node.Status,
+				FailedProbes: node.FailedProbes,
+				Probes:       node.Probes,
+			})
+		}
+	}
+	return snapshot
+}
+
+// canActivate retursn true if the cluster is disabled b/c of status checks.
+// The license is verified along with the status checks so a cluster degraded
+// because of the license is activated once the license is valid again
 func (s *site) canActivate() bool {
-	return s.backendSite.State == ops.SiteStateDegraded &&
-		s.backendSite.Reason != storage.ReasonLicenseInvalid
+	return s.backendSite.State == ops.SiteStateDegraded
 }
 
-// checkPlanetStatus checks the cluster health using planet agents
-func (s *site) checkPlanetStatus(ctx context.Context) error {
+// checkPlanetStatus checks the cluster health using planet agents.
+// Returns the cluster status if it could be queried
+func (s *site) checkPlanetStatus(ctx context.Context) (*status.Agent, error) {
 	planetStatus, err := status.FromPlanetAgent(ctx, nil)
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
 	if planetStatus.GetSystemStatus() != agentpb.SystemStatus_Running {
-		return trace.BadParameter("cluster is not healthy: %#v", planetStatus)
+		return planetStatus, trace.BadParameter("cluster is not healthy: %#v", planetStatus)
 	}
-	return nil
+	return planetStatus, nil
 }
 
 // checkStatusHook executes the application's status hook
@@ -0,0 +1,80 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+type StatusSuite struct{}
+
+var _ = check.Suite(&StatusSuite{})
+
+func (s *StatusSuite) TestRecordsStatusTransitions(c *check.C) {
+	backend, err := keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(c.MkDir(), "bolt.db")})
+	c.Assert(err, check.IsNil)
+	defer backend.Close()
+	operator := &Operator{
+		cfg:             Config{Backend: backend},
+		statusSnapshots: map[string]storage.StatusSnapshot{},
+		FieldLogger:     logrus.WithField(trace.Component, "test"),
+	}
+
+	healthy := &status.Agent{Nodes: []status.ClusterServer{
+		{Hostname: "node-1", AdvertiseIP: "10.0.0.1", Status: status.NodeHealthy},
+	}}
+	degraded := &status.Agent{Nodes: []status.ClusterServer{
+		{Hostname: "node-1", AdvertiseIP: "10.0.0.1", Status: status.NodeDegraded,
+			FailedProbes: []string{"docker is not running"}},
+	}}
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := func(state string, reason storage.Reason, agent *status.Agent, at time.Time) {
+		operator.recordStatusSnapshot(newStatusSnapshot("example.com",
+			state, reason, nil, agent, at))
+	}
+	record(ops.SiteStateActive, "", healthy, now)
+	// unchanged status is not recorded until the snapshot interval elapses
+	record(ops.SiteStateActive, "", healthy, now.Add(time.Minute))
+	record(ops.SiteStateDegraded, storage.ReasonClusterDegraded, degraded, now.Add(2*time.Minute))
+	record(ops.SiteStateActive, "", healthy, now.Add(3*time.Minute))
+	record(ops.SiteStateActive, "", healthy, now.Add(3*time.Minute+2*time.Hour))
+
+	snapshots, err := backend.GetStatusSnapshots("example.com", time.Time{})
+	c.Assert(err, check.IsNil)
+	var states []string
+	for _, snapshot := range snapshots {
+		states = append(states, snapshot.State)
+	}
+	c.Assert(states, check.DeepEquals, []string{
+		ops.SiteStateActive,
+		ops.SiteStateDegraded,
+		ops.SiteStateActive,
+		ops.SiteStateActive,
+	})
+	c.Assert(snapshots[1].Reason, check.Equals, storage.ReasonClusterDegraded)
+	c.Assert(snapshots[1].Nodes[0].FailedProbes, check.DeepEquals, []string{"docker is not running"})
+}
@@ -202,7 +202,11 @@ func (s *site) uninstallUserApp(ctx *operationContext) error {
 		return trace.Wrap(err)
 	}
 
-	command := s.planetGravityCommand("app", "package-uninstall", userAppPackage.String())
+	args := []string{"app", "package-uninstall", userAppPackage.String()}
+	if ctx.operation.Uninstall.Force {
+		args = append(args, "--force")
+	}
+	command := s.planetGravityCommand(args...)
 	out, err := runner.Run(master, command...)
 	if err != nil {
 		return trace.Wrap(err)
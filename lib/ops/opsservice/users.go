@@ -32,6 +32,10 @@ func (o *Operator) UpdateUser(ctx context.Context, req ops.UpdateUserRequest) er
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	err = o.checkUserAccount(req.AccountID, req.Name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
 	err = o.users().UpdateUser(req.Name, storage.UpdateUserReq{
 		FullName: &req.FullName,
 		Roles:    &req.Roles,
@@ -53,6 +57,7 @@ func (o *Operator) CreateUserInvite(ctx context.Context, req ops.CreateUserInvit
 		CreatedBy: storage.UserFromContext(ctx),
 		Roles:     req.Roles,
 		ExpiresIn: req.TTL,
+		AccountID: req.AccountID,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -70,6 +75,10 @@ func (o *Operator) CreateUserReset(ctx context.Context, req ops.CreateUserResetR
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	err = o.checkUserAccount(req.AccountID, req.Name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 	reset, err := o.users().CreateResetToken(o.publicURL(), req.Name, req.TTL)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -0,0 +1,60 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/webhooks"
+
+	"github.com/gravitational/trace"
+)
+
+// UpsertWebhook creates or updates a webhook of the cluster
+func (o *Operator) UpsertWebhook(key ops.SiteKey, webhook storage.Webhook) error {
+	return trace.Wrap(o.backend().UpsertWebhook(key.SiteDomain, webhook))
+}
+
+// GetWebhook returns a webhook of the cluster by name
+func (o *Operator) GetWebhook(key ops.SiteKey, name string) (storage.Webhook, error) {
+	webhook, err := o.backend().GetWebhook(key.SiteDomain, name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return webhook, nil
+}
+
+// GetWebhooks returns all webhooks of the cluster
+func (o *Operator) GetWebhooks(key ops.SiteKey) ([]storage.Webhook, error) {
+	out, err := o.backend().GetWebhooks(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return out, nil
+}
+
+// DeleteWebhook deletes a webhook of the cluster by name
+func (o *Operator) DeleteWebhook(key ops.SiteKey, name string) error {
+	return trace.Wrap(o.backend().DeleteWebhook(key.SiteDomain, name))
+}
+
+// notifyWebhooks sends the event for the current state of the operation
+// to the webhooks registered for the operation's cluster
+func (o *Operator) notifyWebhooks(operation ops.SiteOperation) {
+	o.notifier.Notify(webhooks.NewOperationEvent(
+		webhooks.EventTypeForOperation(operation), operation))
+}
@@ -93,6 +93,120 @@ func (c *githubCollection) WriteYAML(w io.Writer) error {
 	return utils.WriteYAML(c, w)
 }
 
+type samlCollection struct {
+	connectors []teleservices.SAMLConnector
+}
+
+// Resources returns the resources collection in the generic format
+func (c *samlCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range c.connectors {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+// WriteText serializes collection in human-friendly text format
+func (c *samlCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Name", "SSO URL", "Mapping"})
+	for _, conn := range c.connectors {
+		fmt.Fprintf(t, "%v\t%v\t%v\n",
+			conn.GetName(),
+			conn.GetSSO(),
+			formatSAMLMapping(conn.GetAttributesToRoles()))
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+func formatSAMLMapping(mappings []teleservices.AttributeMapping) string {
+	var formatted []string
+	for _, m := range mappings {
+		formatted = append(formatted, fmt.Sprintf("%v: %v -> %v",
+			m.Name, m.Value, strings.Join(m.Roles, ",")))
+	}
+	return strings.Join(formatted, "\n")
+}
+
+// WriteJSON serializes collection into JSON format
+func (c *samlCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(c, w)
+}
+
+func (c *samlCollection) ToMarshal() interface{} {
+	if len(c.connectors) == 1 {
+		return c.connectors[0]
+	}
+	return c.connectors
+}
+
+// WriteYAML serializes collection into YAML format
+func (c *samlCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(c, w)
+}
+
+// authConnectorCollection combines connectors of all supported kinds
+type authConnectorCollection struct {
+	github *githubCollection
+	saml   *samlCollection
+}
+
+// Resources returns the resources collection in the generic format
+func (c *authConnectorCollection) Resources() ([]teleservices.UnknownResource, error) {
+	github, err := c.github.Resources()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	saml, err := c.saml.Resources()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return append(github, saml...), nil
+}
+
+// WriteText serializes collection in human-friendly text format
+func (c *authConnectorCollection) WriteText(w io.Writer) error {
+	if len(c.github.connectors) != 0 {
+		if err := c.github.WriteText(w); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if len(c.saml.connectors) != 0 {
+		if err := c.saml.WriteText(w); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// WriteJSON serializes collection into JSON format
+func (c *authConnectorCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(c, w)
+}
+
+func (c *authConnectorCollection) ToMarshal() interface{} {
+	var connectors []interface{}
+	for _, connector := range c.github.connectors {
+		connectors = append(connectors, connector)
+	}
+	for _, connector := range c.saml.connectors {
+		connectors = append(connectors, connector)
+	}
+	if len(connectors) == 1 {
+		return connectors[0]
+	}
+	return connectors
+}
+
+// WriteYAML serializes collection into YAML format
+func (c *authConnectorCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(c, w)
+}
+
 type userCollection struct {
 	users []teleservices.User
 }
@@ -247,12 +361,13 @@ func (c *tokenCollection) Resources() (resources []teleservices.UnknownResource,
 // WriteText serializes collection in human-friendly text format
 func (c *tokenCollection) WriteText(w io.Writer) error {
 	t := goterm.NewTable(0, 10, 5, ' ', 0)
-	common.PrintTableHeader(t, []string{"Token", "User", "Expires"})
+	common.PrintTableHeader(t, []string{"Token", "User", "Expires", "Permissions"})
 	for _, token := range c.tokens {
-		fmt.Fprintf(t, "%v\t%v\t%v\n",
+		fmt.Fprintf(t, "%v\t%v\t%v\t%v\n",
 			token.GetName(),
 			token.GetUser(),
-			formatExpiry(token.Expiry()))
+			formatExpiry(token.Expiry()),
+			formatPermissions(token.GetPermissions()))
 	}
 	_, err := io.WriteString(w, t.String())
 	return trace.Wrap(err)
@@ -275,6 +390,13 @@ func (c *tokenCollection) WriteYAML(w io.Writer) error {
 	return utils.WriteYAML(c, w)
 }
 
+func formatPermissions(permissions storage.ResourcePermissions) string {
+	if len(permissions) == 0 {
+		return "all"
+	}
+	return permissions.String()
+}
+
 func formatExpiry(t time.Time) string {
 	if t.IsZero() {
 		return "never"
@@ -301,12 +423,18 @@ func (c *logForwardersCollection) Resources() (resources []teleservices.UnknownR
 // WriteText serializes collection in human-friendly text format
 func (c *logForwardersCollection) WriteText(w io.Writer) error {
 	t := goterm.NewTable(0, 10, 5, ' ', 0)
-	common.PrintTableHeader(t, []string{"Name", "Address", "Protocol"})
+	common.PrintTableHeader(t, []string{"Name", "Sink", "Address", "Protocol", "Sources"})
 	for _, forwarder := range c.logForwarders {
-		fmt.Fprintf(t, "%v\t%v\t%v\n",
+		protocol := forwarder.GetProtocol()
+		if protocol == "" {
+			protocol = "-"
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\t%v\t%v\n",
 			forwarder.GetName(),
+			forwarder.GetSink(),
 			forwarder.GetAddress(),
-			forwarder.GetProtocol())
+			protocol,
+			formatList(forwarder.GetSources()))
 	}
 	_, err := io.WriteString(w, t.String())
 	return trace.Wrap(err)
@@ -433,9 +561,13 @@ type smtpConfigCollection []storage.SMTPConfig
 // WriteText serializes collection in human-friendly text format
 func (r alertCollection) WriteText(w io.Writer) error {
 	t := goterm.NewTable(0, 10, 5, ' ', 0)
-	common.PrintTableHeader(t, []string{"Name", "Formula"})
+	common.PrintTableHeader(t, []string{"Name", "Formula", "Severity"})
 	for _, alert := range r {
-		fmt.Fprintf(t, "%v\t%v\n", alert.GetName(), alert.GetFormula())
+		formula, severity := alert.GetFormula(), "-"
+		if rule := alert.GetRule(); rule != nil {
+			formula, severity = rule.Expr, rule.Severity
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\n", alert.GetName(), formula, severity)
 	}
 	_, err := io.WriteString(w, t.String())
 	return trace.Wrap(err)
@@ -475,9 +607,11 @@ type alertCollection []storage.Alert
 // WriteText serializes collection in human-friendly text format
 func (r alertTargetCollection) WriteText(w io.Writer) error {
 	t := goterm.NewTable(0, 10, 5, ' ', 0)
-	common.PrintTableHeader(t, []string{"Email"})
+	common.PrintTableHeader(t, []string{"Email", "Webhook"})
 	for _, target := range r {
-		fmt.Fprintf(t, "%v\n", target.GetEmail())
+		fmt.Fprintf(t, "%v\t%v\n",
+			formatValue(target.GetEmail()),
+			formatValue(target.GetWebhookURL()))
 	}
 	_, err := io.WriteString(w, t.String())
 	return trace.Wrap(err)
@@ -560,6 +694,13 @@ func formatList(list []string) string {
 	return strings.Join(list, ", ")
 }
 
+func formatValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
 // WriteJSON serializes collection into JSON format
 func (c *authGatewayCollection) WriteJSON(w io.Writer) error {
 	return utils.WriteJSON(c, w)
@@ -575,6 +716,101 @@ func (c *authGatewayCollection) ToMarshal() interface{} {
 	return c.item
 }
 
+type healingPolicyCollection struct {
+	item storage.HealingPolicy
+}
+
+// Resources returns the resources collection in the generic format
+func (c *healingPolicyCollection) Resources() ([]teleservices.UnknownResource, error) {
+	resource, err := utils.ToUnknownResource(c.item)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return []teleservices.UnknownResource{*resource}, nil
+}
+
+// WriteText serializes healing policy in human-friendly text format
+func (c *healingPolicyCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Parameter", "Value"})
+	fmt.Fprintf(t, "Enabled:\t%v\n", c.item.IsEnabled())
+	fmt.Fprintf(t, "Dry Run:\t%v\n", c.item.IsDryRun())
+	fmt.Fprintf(t, "Failure Threshold:\t%v\n", c.item.GetFailureThreshold())
+	fmt.Fprintf(t, "Cooldown:\t%v\n", c.item.GetCooldown())
+	var allowed []string
+	for _, remediation := range storage.Remediations {
+		if c.item.IsAllowed(remediation) {
+			allowed = append(allowed, remediation)
+		}
+	}
+	fmt.Fprintf(t, "Remediations:\t%v\n", formatList(allowed))
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (c *healingPolicyCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(c, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (c *healingPolicyCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(c, w)
+}
+
+// ToMarshal returns object that should be marshaled.
+func (c *healingPolicyCollection) ToMarshal() interface{} {
+	return c.item
+}
+
+type clusterDNSCollection struct {
+	item storage.ClusterDNS
+}
+
+// Resources returns the resources collection in the generic format
+func (c *clusterDNSCollection) Resources() ([]teleservices.UnknownResource, error) {
+	resource, err := utils.ToUnknownResource(c.item)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return []teleservices.UnknownResource{*resource}, nil
+}
+
+// WriteText serializes cluster DNS configuration in human-friendly text format
+func (c *clusterDNSCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Parameter", "Value"})
+	upstreams := "nameservers of the host"
+	if len(c.item.GetUpstreams()) != 0 {
+		upstreams = strings.Join(c.item.GetUpstreams(), ", ")
+	}
+	fmt.Fprintf(t, "Upstreams:\t%v\n", upstreams)
+	fmt.Fprintf(t, "Rotate:\t%v\n", c.item.IsRotate())
+	overrides := storage.DNSOverrides{
+		Hosts: c.item.GetHosts(),
+		Zones: c.item.GetZones(),
+	}
+	fmt.Fprintf(t, "Hosts:\t%v\n", formatValue(overrides.FormatHosts()))
+	fmt.Fprintf(t, "Zones:\t%v\n", formatValue(overrides.FormatZones()))
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (c *clusterDNSCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(c, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (c *clusterDNSCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(c, w)
+}
+
+// ToMarshal returns object that should be marshaled.
+func (c *clusterDNSCollection) ToMarshal() interface{} {
+	return c.item
+}
+
 // WriteText serializes collection in human-friendly text format
 func (r envCollection) WriteText(w io.Writer) error {
 	t := goterm.NewTable(0, 10, 5, ' ', 0)
@@ -696,3 +932,191 @@ func formatFeatureGates(features map[string]bool) string {
 	}
 	return strings.Join(result, ",")
 }
+
+// WriteText serializes collection in human-friendly text format
+func (r roleMappingCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Name", "Connector", "Groups", "Email Domains", "Roles"})
+	for _, mapping := range r {
+		connector := mapping.GetConnector()
+		if connector == "" {
+			connector = "*"
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\t%v\t%v\n",
+			mapping.GetName(),
+			connector,
+			strings.Join(mapping.GetGroups(), ","),
+			strings.Join(mapping.GetEmailDomains(), ","),
+			strings.Join(mapping.GetRoles(), ","))
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r roleMappingCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r roleMappingCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r roleMappingCollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+// Resources returns the resources collection in the generic format
+func (r roleMappingCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range r {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+type roleMappingCollection []storage.RoleMapping
+
+// WriteText serializes collection in human-friendly text format
+func (r webhookCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Name", "URL", "Events", "Signed"})
+	for _, webhook := range r {
+		fmt.Fprintf(t, "%v\t%v\t%v\t%v\n",
+			webhook.GetName(),
+			webhook.GetURL(),
+			strings.Join(webhook.GetEvents(), ","),
+			webhook.GetSecret() != "")
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r webhookCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r webhookCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r webhookCollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+// Resources returns the resources collection in the generic format
+func (r webhookCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range r {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+type webhookCollection []storage.Webhook
+
+// WriteText serializes collection in human-friendly text format
+func (r provisionerCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Name", "Type", "Profiles"})
+	for _, provisioner := range r {
+		fmt.Fprintf(t, "%v\t%v\t%v\n",
+			provisioner.GetName(),
+			provisioner.GetType(),
+			strings.Join(provisioner.GetProfiles(), ","))
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r provisionerCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r provisionerCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r provisionerCollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+// Resources returns the resources collection in the generic format
+func (r provisionerCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range r {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+type provisionerCollection []storage.Provisioner
+
+// configResourceCollection is a collection of configuration resources
+// of a kind registered with storage.RegisterResourceKind
+type configResourceCollection []storage.UnknownResource
+
+// WriteText serializes collection in human-friendly text format
+func (r configResourceCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Kind", "Name", "Description"})
+	for _, resource := range r {
+		fmt.Fprintf(t, "%v\t%v\t%v\n",
+			resource.Kind,
+			resource.Metadata.Name,
+			resource.Metadata.Description)
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r configResourceCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r configResourceCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r configResourceCollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+// Resources returns the resources collection in the generic format
+func (r configResourceCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range r {
+		resources = append(resources, teleservices.UnknownResource{
+			ResourceHeader: item.ResourceHeader,
+			Raw:            item.Raw,
+		})
+	}
+	return resources, nil
+}
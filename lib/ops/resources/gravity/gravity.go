@@ -92,6 +92,18 @@ func (r *Resources) Create(ctx context.Context, req resources.CreateRequest) err
 			return trace.Wrap(err)
 		}
 		r.Printf("Created Github connector %q\n", conn.GetName())
+	case teleservices.KindSAMLConnector:
+		conn, err := teleservices.GetSAMLConnectorMarshaler().UnmarshalSAMLConnector(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := conn.CheckAndSetDefaults(); err != nil {
+			return trace.Wrap(err)
+		}
+		if err := r.Operator.UpsertSAMLConnector(r.cluster.Key(), conn); err != nil {
+			return trace.Wrap(err)
+		}
+		r.Printf("Created SAML connector %q\n", conn.GetName())
 	case teleservices.KindUser:
 		user, err := teleservices.GetUserMarshaler().UnmarshalUser(req.Resource.Raw)
 		if err != nil {
@@ -117,10 +129,11 @@ func (r *Resources) Create(ctx context.Context, req resources.CreateRequest) err
 		// using existing keys API here which is compatible so we don't
 		// have to roll out separate tokens API for now
 		_, err = r.Operator.CreateAPIKey(ops.NewAPIKeyRequest{
-			Token:     token.GetName(),
-			UserEmail: token.GetUser(),
-			Expires:   token.Expiry(),
-			Upsert:    req.Upsert,
+			Token:       token.GetName(),
+			UserEmail:   token.GetUser(),
+			Expires:     token.Expiry(),
+			Upsert:      req.Upsert,
+			Permissions: token.GetPermissions(),
 		})
 		if err != nil {
 			return trace.Wrap(err)
@@ -194,6 +207,26 @@ func (r *Resources) Create(ctx context.Context, req resources.CreateRequest) err
 			return trace.Wrap(err)
 		}
 		r.Println("Updated cluster SMTP configuration")
+	case storage.KindHealingPolicy:
+		policy, err := storage.UnmarshalHealingPolicy(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpdateHealingPolicy(r.cluster.Key(), policy)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Println("Updated cluster healing policy")
+	case storage.KindClusterDNS:
+		dns, err := storage.UnmarshalClusterDNS(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpdateClusterDNS(r.cluster.Key(), dns)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Println("Updated cluster DNS configuration")
 	case storage.KindAlert:
 		alert, err := storage.UnmarshalAlert(req.Resource.Raw)
 		if err != nil {
@@ -230,14 +263,49 @@ func (r *Resources) Create(ctx context.Context, req resources.CreateRequest) err
 			return trace.Wrap(err)
 		}
 		r.Println("Updated auth gateway configuration")
+	case storage.KindRoleMapping:
+		mapping, err := storage.UnmarshalRoleMapping(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpsertRoleMapping(r.cluster.Key(), mapping)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Printf("Updated role mapping %q\n", mapping.GetName())
+	case storage.KindWebhook:
+		webhook, err := storage.UnmarshalWebhook(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpsertWebhook(r.cluster.Key(), webhook)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Printf("Updated webhook %q\n", webhook.GetName())
+	case storage.KindProvisioner:
+		provisioner, err := storage.UnmarshalProvisioner(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpsertProvisioner(r.cluster.Key(), provisioner)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Printf("Updated provisioner %q\n", provisioner.GetName())
 	case storage.KindRuntimeEnvironment, storage.KindClusterConfiguration:
 		err := r.ClusterOperationHandler.UpdateResource(req)
 		return trace.Wrap(err)
 	case "":
 		return trace.BadParameter("missing resource kind")
 	default:
-		return trace.BadParameter("unsupported resource %q, supported are: %v",
-			req.Resource.Kind, modules.GetResources().SupportedResources())
+		if _, err := storage.GetResourceKind(req.Resource.Kind); err != nil {
+			return trace.BadParameter("unsupported resource %q, supported are: %v",
+				req.Resource.Kind, modules.GetResources().SupportedResources())
+		}
+		if err := r.createConfigResource(req); err != nil {
+			return trace.Wrap(err)
+		}
 	}
 	r.EmitAuditEvent(ctx, events.ResourceCreated,
 		req.Resource.Kind,
@@ -252,19 +320,12 @@ func (r *Resources) GetCollection(req resources.ListRequest) (resources.Collecti
 		return nil, trace.Wrap(err)
 	}
 	switch req.Kind {
-	case teleservices.KindGithubConnector, teleservices.KindAuthConnector:
-		if req.Name != "" {
-			connector, err := r.Operator.GetGithubConnector(r.cluster.Key(), req.Name, req.WithSecrets)
-			if err != nil {
-				return nil, trace.Wrap(err)
-			}
-			return &githubCollection{connectors: []teleservices.GithubConnector{connector}}, nil
-		}
-		connectors, err := r.Operator.GetGithubConnectors(r.cluster.Key(), req.WithSecrets)
-		if err != nil {
-			return nil, trace.Wrap(err)
-		}
-		return &githubCollection{connectors: connectors}, nil
+	case teleservices.KindGithubConnector:
+		return r.getGithubConnectors(req)
+	case teleservices.KindSAMLConnector:
+		return r.getSAMLConnectors(req)
+	case teleservices.KindAuthConnector:
+		return r.getAuthConnectors(req)
 	case teleservices.KindUser:
 		if req.Name != "" {
 			user, err := r.Operator.GetUser(r.cluster.Key(), req.Name)
@@ -351,6 +412,18 @@ func (r *Resources) GetCollection(req resources.ListRequest) (resources.Collecti
 			return nil, trace.Wrap(err)
 		}
 		return smtpConfigCollection{config}, nil
+	case storage.KindHealingPolicy:
+		policy, err := r.Operator.GetHealingPolicy(r.cluster.Key())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &healingPolicyCollection{policy}, nil
+	case storage.KindClusterDNS:
+		dns, err := r.Operator.GetClusterDNS(r.cluster.Key())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &clusterDNSCollection{dns}, nil
 	case storage.KindAlert:
 		alerts, err := r.Operator.GetAlerts(r.cluster.Key())
 		if err != nil {
@@ -389,13 +462,146 @@ func (r *Resources) GetCollection(req resources.ListRequest) (resources.Collecti
 			return nil, trace.Wrap(err)
 		}
 		return configCollection{Interface: config}, nil
+	case storage.KindRoleMapping:
+		if req.Name != "" {
+			mapping, err := r.Operator.GetRoleMapping(r.cluster.Key(), req.Name)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			return roleMappingCollection{mapping}, nil
+		}
+		mappings, err := r.Operator.GetRoleMappings(r.cluster.Key())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return roleMappingCollection(mappings), nil
+	case storage.KindWebhook:
+		if req.Name != "" {
+			webhook, err := r.Operator.GetWebhook(r.cluster.Key(), req.Name)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			return webhookCollection{webhook}, nil
+		}
+		webhooks, err := r.Operator.GetWebhooks(r.cluster.Key())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return webhookCollection(webhooks), nil
+	case storage.KindProvisioner:
+		if req.Name != "" {
+			provisioner, err := r.Operator.GetProvisioner(r.cluster.Key(), req.Name)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			return provisionerCollection{provisioner}, nil
+		}
+		provisioners, err := r.Operator.GetProvisioners(r.cluster.Key())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return provisionerCollection(provisioners), nil
 	case "":
 		return nil, trace.BadParameter("missing resource kind")
 	}
+	if _, err := storage.GetResourceKind(req.Kind); err == nil {
+		return r.getConfigResources(req)
+	}
 	return nil, trace.BadParameter("unsupported resource %q, supported are: %v",
 		req.Kind, modules.GetResources().SupportedResources())
 }
 
+// createConfigResource creates a configuration resource of a registered kind
+func (r *Resources) createConfigResource(req resources.CreateRequest) error {
+	if !req.Upsert {
+		_, err := r.Operator.GetConfigResource(r.cluster.Key(), req.Resource.Kind, req.Resource.Metadata.Name)
+		if err == nil {
+			return trace.AlreadyExists("%v %q already exists", req.Resource.Kind, req.Resource.Metadata.Name)
+		}
+		if !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+	}
+	err := r.Operator.UpsertConfigResource(r.cluster.Key(), storage.UnknownResource{
+		ResourceHeader: req.Resource.ResourceHeader,
+		Raw:            req.Resource.Raw,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	r.Printf("Created %v %q\n", req.Resource.Kind, req.Resource.Metadata.Name)
+	return nil
+}
+
+// getConfigResources returns configuration resources of a registered kind
+func (r *Resources) getConfigResources(req resources.ListRequest) (configResourceCollection, error) {
+	if req.Name != "" {
+		resource, err := r.Operator.GetConfigResource(r.cluster.Key(), req.Kind, req.Name)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return configResourceCollection{*resource}, nil
+	}
+	resources, err := r.Operator.GetConfigResources(r.cluster.Key(), req.Kind)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return configResourceCollection(resources), nil
+}
+
+func (r *Resources) getGithubConnectors(req resources.ListRequest) (*githubCollection, error) {
+	if req.Name != "" {
+		connector, err := r.Operator.GetGithubConnector(r.cluster.Key(), req.Name, req.WithSecrets)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &githubCollection{connectors: []teleservices.GithubConnector{connector}}, nil
+	}
+	connectors, err := r.Operator.GetGithubConnectors(r.cluster.Key(), req.WithSecrets)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &githubCollection{connectors: connectors}, nil
+}
+
+func (r *Resources) getSAMLConnectors(req resources.ListRequest) (*samlCollection, error) {
+	if req.Name != "" {
+		connector, err := r.Operator.GetSAMLConnector(r.cluster.Key(), req.Name, req.WithSecrets)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &samlCollection{connectors: []teleservices.SAMLConnector{connector}}, nil
+	}
+	connectors, err := r.Operator.GetSAMLConnectors(r.cluster.Key(), req.WithSecrets)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &samlCollection{connectors: connectors}, nil
+}
+
+// getAuthConnectors returns the connectors of all supported kinds.
+// If the name is specified, the connector of any kind with this name is returned
+func (r *Resources) getAuthConnectors(req resources.ListRequest) (*authConnectorCollection, error) {
+	github, err := r.getGithubConnectors(req)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	saml, err := r.getSAMLConnectors(req)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if github == nil && saml == nil {
+		return nil, trace.NotFound("auth connector %q is not found", req.Name)
+	}
+	if github == nil {
+		github = &githubCollection{}
+	}
+	if saml == nil {
+		saml = &samlCollection{}
+	}
+	return &authConnectorCollection{github: github, saml: saml}, nil
+}
+
 // Remove removes the specified resource
 func (r *Resources) Remove(ctx context.Context, req resources.RemoveRequest) error {
 	if err := req.Check(); err != nil {
@@ -410,6 +616,14 @@ func (r *Resources) Remove(ctx context.Context, req resources.RemoveRequest) err
 			return trace.Wrap(err)
 		}
 		r.Printf("Github connector %q has been deleted\n", req.Name)
+	case teleservices.KindSAMLConnector:
+		if err := r.Operator.DeleteSAMLConnector(r.cluster.Key(), req.Name); err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		r.Printf("SAML connector %q has been deleted\n", req.Name)
 	case teleservices.KindUser:
 		if err := r.Operator.DeleteUser(r.cluster.Key(), req.Name); err != nil {
 			if trace.IsNotFound(err) && req.Force {
@@ -456,6 +670,22 @@ func (r *Resources) Remove(ctx context.Context, req resources.RemoveRequest) err
 			return trace.Wrap(err)
 		}
 		r.Println("SMTP configuration has been deleted")
+	case storage.KindHealingPolicy:
+		if err := r.Operator.DeleteHealingPolicy(r.cluster.Key()); err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		r.Println("Healing policy has been deleted")
+	case storage.KindClusterDNS:
+		if err := r.Operator.DeleteClusterDNS(r.cluster.Key()); err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		r.Println("Cluster DNS configuration has been reset to defaults")
 	case storage.KindAlert:
 		if err := r.Operator.DeleteAlert(r.cluster.Key(), req.Name); err != nil {
 			if trace.IsNotFound(err) && req.Force {
@@ -472,14 +702,47 @@ func (r *Resources) Remove(ctx context.Context, req resources.RemoveRequest) err
 			return trace.Wrap(err)
 		}
 		r.Println("Alert target has been deleted")
+	case storage.KindRoleMapping:
+		if err := r.Operator.DeleteRoleMapping(r.cluster.Key(), req.Name); err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		r.Printf("Role mapping %q has been deleted\n", req.Name)
+	case storage.KindWebhook:
+		if err := r.Operator.DeleteWebhook(r.cluster.Key(), req.Name); err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		r.Printf("Webhook %q has been deleted\n", req.Name)
+	case storage.KindProvisioner:
+		if err := r.Operator.DeleteProvisioner(r.cluster.Key(), req.Name); err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		r.Printf("Provisioner %q has been deleted\n", req.Name)
 	case storage.KindRuntimeEnvironment, storage.KindClusterConfiguration:
 		err := r.ClusterOperationHandler.RemoveResource(req)
 		return trace.Wrap(err)
 	case "":
 		return trace.BadParameter("missing resource kind")
 	default:
-		return trace.BadParameter("unsupported resource %q, supported are: %v",
-			req.Kind, modules.GetResources().SupportedResourcesToRemove())
+		if _, err := storage.GetResourceKind(req.Kind); err != nil {
+			return trace.BadParameter("unsupported resource %q, supported are: %v",
+				req.Kind, modules.GetResources().SupportedResourcesToRemove())
+		}
+		if err := r.Operator.DeleteConfigResource(r.cluster.Key(), req.Kind, req.Name); err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		r.Printf("%v %q has been deleted\n", req.Kind, req.Name)
 	}
 	r.EmitAuditEvent(ctx, events.ResourceDeleted, req.Kind, req.Name, req.Owner)
 	return nil
@@ -508,6 +771,8 @@ func Validate(resource storage.UnknownResource) (err error) {
 	switch resource.Kind {
 	case teleservices.KindGithubConnector:
 		_, err = teleservices.GetGithubConnectorMarshaler().Unmarshal(resource.Raw)
+	case teleservices.KindSAMLConnector:
+		_, err = teleservices.GetSAMLConnectorMarshaler().UnmarshalSAMLConnector(resource.Raw)
 	case teleservices.KindUser:
 		_, err = teleservices.GetUserMarshaler().UnmarshalUser(resource.Raw)
 	case storage.KindToken:
@@ -526,13 +791,28 @@ func Validate(resource storage.UnknownResource) (err error) {
 		_, err = storage.UnmarshalAlertTarget(resource.Raw)
 	case storage.KindAuthGateway:
 		_, err = storage.UnmarshalAuthGateway(resource.Raw)
+	case storage.KindRoleMapping:
+		_, err = storage.UnmarshalRoleMapping(resource.Raw)
+	case storage.KindWebhook:
+		_, err = storage.UnmarshalWebhook(resource.Raw)
+	case storage.KindProvisioner:
+		_, err = storage.UnmarshalProvisioner(resource.Raw)
+	case storage.KindHealingPolicy:
+		_, err = storage.UnmarshalHealingPolicy(resource.Raw)
+	case storage.KindClusterDNS:
+		_, err = storage.UnmarshalClusterDNS(resource.Raw)
 	case storage.KindRuntimeEnvironment:
 		_, err = storage.UnmarshalEnvironmentVariables(resource.Raw)
 	case storage.KindClusterConfiguration:
 		_, err = clusterconfig.Unmarshal(resource.Raw)
 	default:
-		return trace.NotImplemented("unsupported resource %q, supported are: %v",
-			resource.Kind, modules.GetResources().SupportedResources())
+		kind, err := storage.GetResourceKind(resource.Kind)
+		if err != nil {
+			return trace.NotImplemented("unsupported resource %q, supported are: %v",
+				resource.Kind, modules.GetResources().SupportedResources())
+		}
+		_, err = kind.Unmarshal(resource.Raw)
+		return trace.Wrap(err)
 	}
 	if err != nil {
 		return trace.Wrap(err)
@@ -19,6 +19,7 @@ package gravity
 import (
 	"context"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gravitational/gravity/lib/compare"
@@ -29,6 +30,7 @@ import (
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/utils"
 
+	telefixtures "github.com/gravitational/teleport/lib/fixtures"
 	teleservices "github.com/gravitational/teleport/lib/services"
 	"github.com/gravitational/trace"
 	"gopkg.in/check.v1"
@@ -84,6 +86,29 @@ func (s *GravityResourcesSuite) TestGithubConnectorResource(c *check.C) {
 	compare.DeepCompare(c, collection, &githubCollection{[]teleservices.GithubConnector{}})
 }
 
+func (s *GravityResourcesSuite) TestSAMLConnectorResource(c *check.C) {
+	c.Assert(samlConnector.CheckAndSetDefaults(), check.IsNil)
+	err := s.r.Create(context.TODO(), resources.CreateRequest{Resource: toUnknown(c, samlConnector)})
+	c.Assert(err, check.IsNil)
+
+	collection, err := s.r.GetCollection(resources.ListRequest{Kind: teleservices.KindSAMLConnector, WithSecrets: true})
+	c.Assert(err, check.IsNil)
+	compare.DeepCompare(c, collection, &samlCollection{[]teleservices.SAMLConnector{samlConnector}})
+
+	collection, err = s.r.GetCollection(resources.ListRequest{Kind: teleservices.KindAuthConnector, Name: "saml", WithSecrets: true})
+	c.Assert(err, check.IsNil)
+	compare.DeepCompare(c, collection, &authConnectorCollection{
+		github: &githubCollection{},
+		saml:   &samlCollection{[]teleservices.SAMLConnector{samlConnector}},
+	})
+
+	err = s.r.Remove(context.TODO(), resources.RemoveRequest{Kind: teleservices.KindSAMLConnector, Name: "saml"})
+	c.Assert(err, check.IsNil)
+
+	_, err = s.r.GetCollection(resources.ListRequest{Kind: teleservices.KindAuthConnector, Name: "saml"})
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+}
+
 func (s *GravityResourcesSuite) TestUser(c *check.C) {
 	err := s.r.Create(context.TODO(), resources.CreateRequest{Resource: toUnknown(c, user)})
 	c.Assert(err, check.IsNil)
@@ -121,6 +146,56 @@ func (s *GravityResourcesSuite) TestToken(c *check.C) {
 	c.Assert(err, check.FitsTypeOf, trace.NotFound(""))
 }
 
+func (s *GravityResourcesSuite) TestConfigResource(c *check.C) {
+	err := storage.RegisterResourceKind(storage.ResourceKind{
+		Kind:       "testquota",
+		Aliases:    []string{"testquotas"},
+		SpecSchema: `{"type": "object", "required": ["limit"], "properties": {"limit": {"type": "number"}}}`,
+	})
+	if err != nil && !trace.IsAlreadyExists(err) {
+		c.Assert(err, check.IsNil)
+	}
+	control := resources.NewControl(s.r)
+	data := `kind: testquotas
+version: v2
+metadata:
+  name: cpu
+  description: CPU quota
+spec:
+  limit: 4
+`
+	err = control.Create(context.TODO(), strings.NewReader(data), resources.CreateRequest{})
+	c.Assert(err, check.IsNil)
+	err = control.Create(context.TODO(), strings.NewReader(data), resources.CreateRequest{})
+	c.Assert(trace.IsAlreadyExists(err), check.Equals, true, check.Commentf("%v", err))
+	err = control.Create(context.TODO(), strings.NewReader(data), resources.CreateRequest{Upsert: true})
+	c.Assert(err, check.IsNil)
+
+	err = control.Create(context.TODO(), strings.NewReader(`kind: testquota
+version: v2
+metadata:
+  name: memory
+spec:
+  limit: "4GB"
+`), resources.CreateRequest{})
+	c.Assert(err, check.NotNil)
+
+	collection, err := s.r.GetCollection(resources.ListRequest{Kind: "testquotas"})
+	c.Assert(err, check.IsNil)
+	items, err := collection.Resources()
+	c.Assert(err, check.IsNil)
+	c.Assert(items, check.HasLen, 1)
+	c.Assert(items[0].Kind, check.Equals, "testquota")
+	c.Assert(items[0].Metadata.Name, check.Equals, "cpu")
+	c.Assert(items[0].Metadata.Description, check.Equals, "CPU quota")
+
+	err = s.r.Remove(context.TODO(), resources.RemoveRequest{Kind: "testquota", Name: "cpu"})
+	c.Assert(err, check.IsNil)
+
+	_, err = s.r.GetCollection(resources.ListRequest{Kind: "testquota", Name: "cpu"})
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+}
+
 func toUnknown(c *check.C, resource teleservices.Resource) teleservices.UnknownResource {
 	unknown, err := utils.ToUnknownResource(resource)
 	c.Assert(err, check.IsNil)
@@ -141,6 +216,30 @@ var (
 		},
 	})
 
+	samlConnector = &teleservices.SAMLConnectorV2{
+		Kind:    teleservices.KindSAMLConnector,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      "saml",
+			Namespace: defaults.Namespace,
+		},
+		Spec: teleservices.SAMLConnectorSpecV2{
+			Issuer:                   "http://example.com",
+			SSO:                      "https://example.com/saml/sso",
+			AssertionConsumerService: "https://ops.example.com/portalapi/v1/saml/callback",
+			Audience:                 "https://ops.example.com/aud",
+			ServiceProviderIssuer:    "https://ops.example.com/iss",
+			AttributesToRoles: []teleservices.AttributeMapping{
+				{Name: "groups", Value: "admin", Roles: []string{"@teleadmin"}},
+			},
+			Cert: telefixtures.SigningCertPEM,
+			SigningKeyPair: &teleservices.SigningKeyPair{
+				PrivateKey: telefixtures.SigningKeyPEM,
+				Cert:       telefixtures.SigningCertPEM,
+			},
+		},
+	}
+
 	user = storage.NewUser("test", storage.UserSpecV2{
 		AccountID: defaults.SystemAccountID,
 		Type:      storage.AgentUser,
@@ -156,6 +156,8 @@ func (r *RemoveRequest) Check() error {
 	switch kind {
 	case storage.KindAlertTarget:
 	case storage.KindSMTPConfig:
+	case storage.KindHealingPolicy:
+	case storage.KindClusterDNS:
 	case storage.KindRuntimeEnvironment:
 	case storage.KindClusterConfiguration:
 	default:
@@ -0,0 +1,93 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// ScheduledOperations queues cluster operations to start at a later time
+// or within a recurring maintenance window
+type ScheduledOperations interface {
+	// CreateScheduledOperation queues the operation to start according
+	// to the request schedule
+	CreateScheduledOperation(context.Context, CreateScheduledOperationRequest) (*storage.ScheduledOperation, error)
+	// GetScheduledOperations returns all scheduled operations of the cluster
+	GetScheduledOperations(context.Context, SiteKey) ([]storage.ScheduledOperation, error)
+	// CancelScheduledOperation cancels the pending scheduled operation
+	CancelScheduledOperation(context.Context, ScheduledOperationKey) error
+}
+
+// CreateScheduledOperationRequest is a request to queue a cluster operation
+type CreateScheduledOperationRequest struct {
+	// AccountID is the ID of the account the cluster belongs to
+	AccountID string `json:"account_id"`
+	// ClusterName is the name of the cluster to run the operation in
+	ClusterName string `json:"cluster_name"`
+	// Type is the operation type. Only update operations can be scheduled
+	Type string `json:"type"`
+	// App is the application package to update the cluster to
+	App string `json:"app"`
+	// Schedule defines when the operation is started
+	Schedule storage.OperationSchedule `json:"schedule"`
+}
+
+// Check validates the request
+func (r CreateScheduledOperationRequest) Check() error {
+	if r.AccountID == "" {
+		return trace.BadParameter("missing AccountID")
+	}
+	if r.ClusterName == "" {
+		return trace.BadParameter("missing ClusterName")
+	}
+	if r.Type != OperationUpdate {
+		return trace.BadParameter("only update operations can be scheduled, got %q", r.Type)
+	}
+	if _, err := loc.ParseLocator(r.App); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(r.Schedule.Check())
+}
+
+// ScheduledOperationKey identifies a scheduled operation
+type ScheduledOperationKey struct {
+	// AccountID is the ID of the account the cluster belongs to
+	AccountID string `json:"account_id"`
+	// ClusterName is the name of the cluster the operation is scheduled for
+	ClusterName string `json:"cluster_name"`
+	// ID is the scheduled operation ID
+	ID string `json:"id"`
+}
+
+// Check validates the key
+func (k ScheduledOperationKey) Check() error {
+	if k.AccountID == "" {
+		return trace.BadParameter("missing AccountID")
+	}
+	if k.ClusterName == "" {
+		return trace.BadParameter("missing ClusterName")
+	}
+	if k.ID == "" {
+		return trace.BadParameter("missing ID")
+	}
+	return nil
+}
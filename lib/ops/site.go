@@ -94,6 +94,7 @@ func ConvertOpsSite(in Site) storage.Site {
 		CloudConfig:     in.CloudConfig,
 		DNSOverrides:    in.DNSOverrides,
 		DNSConfig:       in.DNSConfig,
+		PKI:             in.PKI,
 	}
 	if in.License != nil {
 		cluster.License = in.License.Raw
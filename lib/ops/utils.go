@@ -17,6 +17,7 @@ limitations under the License.
 package ops
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -361,3 +362,25 @@ func MatchByType(opType string) OperationMatcher {
 
 // OperationMatcher is a function type that matches the given operation
 type OperationMatcher func(SiteOperation) bool
+
+// SendOperationProgress watches the progress of the specified operation
+// and passes each new progress entry to send until the operation completes
+func SendOperationProgress(ctx context.Context, key SiteOperationKey, operator Operator, send func(ProgressEntry) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	progressC := make(chan ProgressEntry)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- operator.WatchSiteOperationProgress(ctx, key, progressC)
+	}()
+	for {
+		select {
+		case entry := <-progressC:
+			if err := send(entry); err != nil {
+				return trace.Wrap(err)
+			}
+		case err := <-errC:
+			return trace.Wrap(err)
+		}
+	}
+}
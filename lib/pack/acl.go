@@ -31,7 +31,9 @@ import (
 	"github.com/gravitational/trace"
 )
 
-func PackagesWithACL(packages PackageService, users users.Users, user storage.User, checker teleservices.AccessChecker) PackageService {
+// PackagesWithACL returns a package service that checks every action
+// against the privileges and the account of the specified user
+func PackagesWithACL(packages PackageService, users users.Identity, user storage.User, checker teleservices.AccessChecker) PackageService {
 	return &ACLService{
 		packages: packages,
 		users:    users,
@@ -44,7 +46,7 @@ func PackagesWithACL(packages PackageService, users users.Users, user storage.Us
 // regular service and applies checks before every operation
 type ACLService struct {
 	packages PackageService
-	users    users.Users
+	users    users.Identity
 	user     storage.User
 	checker  teleservices.AccessChecker
 }
@@ -71,6 +73,9 @@ func (a *ACLService) PackageDownloadURL(loc loc.Locator) string {
 }
 
 func (a *ACLService) UpsertRepository(repository string, expires time.Time) error {
+	if err := users.CheckRepositoryAccess(a.users, a.user, repository, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
 	if err := a.checker.CheckAccessToRule(a.repoContext(repository), teledefaults.Namespace, storage.KindRepository, teleservices.VerbCreate, false); err != nil {
 		return trace.Wrap(err)
 	}
@@ -83,6 +88,9 @@ func (a *ACLService) UpsertRepository(repository string, expires time.Time) erro
 // DeleteRepository deletes repository - packages will remain in the
 // packages repository
 func (a *ACLService) DeleteRepository(repository string) error {
+	if err := users.CheckRepositoryAccess(a.users, a.user, repository, teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
 	if err := a.checker.CheckAccessToRule(a.repoContext(repository), teledefaults.Namespace, storage.KindRepository, teleservices.VerbDelete, false); err != nil {
 		return trace.Wrap(err)
 	}
@@ -94,7 +102,21 @@ func (a *ACLService) GetRepositories() ([]string, error) {
 	if err := a.checker.CheckAccessToRule(a.context(), teledefaults.Namespace, storage.KindRepository, teleservices.VerbList, false); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return a.packages.GetRepositories()
+	repositories, err := a.packages.GetRepositories()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if users.IsSystemAccountUser(a.user) {
+		return repositories, nil
+	}
+	// return only the repositories of the user's account
+	var filtered []string
+	for _, repository := range repositories {
+		if users.CheckRepositoryAccess(a.users, a.user, repository, teleservices.VerbList) == nil {
+			filtered = append(filtered, repository)
+		}
+	}
+	return filtered, nil
 }
 
 // GetRepository returns repository by name, returns error if it does not exist
@@ -106,6 +128,9 @@ func (a *ACLService) GetRepository(repository string) (storage.Repository, error
 }
 
 func (a *ACLService) repoAction(repository string, verb string) error {
+	if err := users.CheckRepositoryAccess(a.users, a.user, repository, verb); err != nil {
+		return trace.Wrap(err)
+	}
 	// we have a higher level app kind, that grants access to default
 	// repository,  with name defaults.SystemAccountOrg, check for it
 	if repository == defaults.SystemAccountOrg {
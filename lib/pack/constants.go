@@ -29,6 +29,11 @@ const (
 	AdvertiseIPLabel = "advertise-ip"
 	// OperationIDLabel contains ID of the operation the package was configured for
 	OperationIDLabel = "operation-id"
+	// DeltaFromLabel marks an application package of a delta installer
+	// and contains the locator of the package it has been generated against
+	DeltaFromLabel = "delta-from"
+	// CertAuthorityStageLabel contains the rotation stage of the certificate authority package
+	CertAuthorityStageLabel = "ca-stage"
 
 	// PurposeCA marks the planet certificate authority package
 	PurposeCA = "ca"
@@ -0,0 +1,270 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gc implements garbage collection in a package service.
+//
+// A package is reachable if it is one of the root application packages
+// or one of their dependencies, transitively. Dependencies include
+// package and application dependencies, the base runtime application
+// and intermediate runtimes embedded for multi-hop upgrades.
+//
+// The collector only removes the packages it can reason about: other
+// versions of reachable packages and configuration or resource packages
+// that belong to such versions. Packages unrelated to any reachable package
+// are left intact
+package gc
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/timetools"
+	log "github.com/sirupsen/logrus"
+)
+
+// Config defines the package garbage collector configuration
+type Config struct {
+	// Packages specifies the package service to collect garbage in
+	Packages PackageService
+	// Roots lists the application packages in use
+	Roots []loc.Locator
+	// ProtectionWindow specifies the period after import during which
+	// an unreachable package is not removed
+	ProtectionWindow time.Duration
+	// DryRun specifies whether to only report the unreachable packages
+	DryRun bool
+	// Clock specifies the time provider
+	Clock timetools.TimeProvider
+	// FieldLogger specifies the logger
+	log.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets default values
+func (r *Config) CheckAndSetDefaults() error {
+	if r.Packages == nil {
+		return trace.BadParameter("package service is required")
+	}
+	if len(r.Roots) == 0 {
+		return trace.BadParameter("at least one root application is required")
+	}
+	if r.ProtectionWindow < 0 {
+		return trace.BadParameter("protection window cannot be negative")
+	}
+	if r.Clock == nil {
+		r.Clock = &timetools.RealTime{}
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "gc:pack")
+	}
+	return nil
+}
+
+// PackageService defines the subset of package APIs required for garbage collection
+type PackageService interface {
+	// GetRepositories returns the list of repositories
+	GetRepositories() ([]string, error)
+	// GetPackages returns the list of packages in the specified repository
+	GetPackages(repository string) ([]pack.PackageEnvelope, error)
+	// ReadPackageEnvelope returns the envelope of the specified package
+	ReadPackageEnvelope(loc.Locator) (*pack.PackageEnvelope, error)
+	// DeletePackage removes the specified package
+	DeletePackage(loc.Locator) error
+}
+
+// Report describes the outcome of the garbage collection
+type Report struct {
+	// Reachable is the number of packages in use
+	Reachable int
+	// Deleted lists the removed unreachable packages.
+	// In dry-run mode, lists the packages that would be removed
+	Deleted []loc.Locator
+	// Protected lists the unreachable packages kept because they
+	// have been imported within the protection window
+	Protected []loc.Locator
+	// ReclaimedBytes is the total size of the deleted packages
+	ReclaimedBytes int64
+}
+
+// Collect removes unreachable packages from the configured package service
+func Collect(config Config) (*Report, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	c := &collector{
+		Config:    config,
+		reachable: make(map[loc.Locator]struct{}),
+		families:  make(map[loc.Locator]struct{}),
+	}
+	for _, root := range config.Roots {
+		if err := c.markApp(root, true); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	garbage, err := c.sweep()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	report := Report{Reachable: len(c.reachable)}
+	now := c.Clock.UtcNow()
+	for _, envelope := range garbage {
+		logger := c.WithField("package", envelope.Locator)
+		if now.Sub(envelope.Created) < c.ProtectionWindow {
+			logger.Info("Keep recently imported package.")
+			report.Protected = append(report.Protected, envelope.Locator)
+			continue
+		}
+		if !c.DryRun {
+			err := c.Packages.DeletePackage(envelope.Locator)
+			if err != nil && !trace.IsNotFound(err) {
+				return nil, trace.Wrap(err)
+			}
+		}
+		logger.Info("Delete unreachable package.")
+		report.Deleted = append(report.Deleted, envelope.Locator)
+		report.ReclaimedBytes += envelope.SizeBytes
+	}
+	return &report, nil
+}
+
+type collector struct {
+	Config
+	// reachable is the set of reachable packages
+	reachable map[loc.Locator]struct{}
+	// families is the set of reachable packages without versions
+	families map[loc.Locator]struct{}
+}
+
+// markApp marks the specified application package and its dependencies
+// as reachable.
+// A missing root application is an error as its dependencies
+// cannot be determined
+func (r *collector) markApp(app loc.Locator, root bool) error {
+	if _, ok := r.reachable[app]; ok {
+		return nil
+	}
+	r.mark(app)
+	envelope, err := r.Packages.ReadPackageEnvelope(app)
+	if err != nil {
+		if trace.IsNotFound(err) && !root {
+			r.WithField("package", app).Warn("Dependency not found.")
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	if len(envelope.Manifest) == 0 {
+		if root {
+			return trace.BadParameter("package %v is not an application package", app)
+		}
+		return nil
+	}
+	manifest, err := schema.ParseManifestYAMLNoValidate(envelope.Manifest)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, dependency := range manifest.AllPackageDependencies() {
+		r.mark(dependency)
+	}
+	apps := manifest.Dependencies.GetApps()
+	if base := manifest.Base(); base != nil {
+		apps = append(apps, *base)
+	}
+	apps = append(apps, manifest.IntermediateRuntimes()...)
+	for _, dependency := range apps {
+		if err := r.markApp(dependency, false); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func (r *collector) mark(locator loc.Locator) {
+	r.reachable[locator] = struct{}{}
+	r.families[locator.ZeroVersion()] = struct{}{}
+}
+
+// sweep returns the unreachable packages eligible for removal.
+// Configuration and resource packages are ordered before the packages they belong to
+func (r *collector) sweep() (garbage []pack.PackageEnvelope, err error) {
+	repositories, err := r.Packages.GetRepositories()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, repository := range repositories {
+		envelopes, err := r.Packages.GetPackages(repository)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, envelope := range envelopes {
+			if r.isGarbage(envelope) {
+				garbage = append(garbage, envelope)
+			}
+		}
+	}
+	sort.SliceStable(garbage, func(i, j int) bool {
+		return owner(garbage[i]) != nil && owner(garbage[j]) == nil
+	})
+	return garbage, nil
+}
+
+// isGarbage determines whether the specified package is unreachable and
+// can be safely removed
+func (r *collector) isGarbage(envelope pack.PackageEnvelope) bool {
+	if _, ok := r.reachable[envelope.Locator]; ok {
+		return false
+	}
+	if owner := owner(envelope); owner != nil {
+		if envelope.HasLabel(pack.InstalledLabel, pack.InstalledLabel) {
+			return false
+		}
+		if _, ok := r.reachable[*owner]; ok {
+			return false
+		}
+		_, ok := r.families[owner.ZeroVersion()]
+		return ok
+	}
+	_, ok := r.families[envelope.Locator.ZeroVersion()]
+	return ok
+}
+
+// owner returns the package the specified configuration or resource package
+// belongs to, or nil for other packages
+func owner(envelope pack.PackageEnvelope) *loc.Locator {
+	if ref, ok := envelope.RuntimeLabels[pack.ConfigLabel]; ok {
+		filter, err := loc.ParseLocator(ref)
+		if err != nil {
+			return nil
+		}
+		return &loc.Locator{
+			Repository: filter.Repository,
+			Name:       filter.Name,
+			Version:    envelope.Locator.Version,
+		}
+	}
+	if strings.HasSuffix(envelope.Locator.Name, "-resources") {
+		return &loc.Locator{
+			Repository: envelope.Locator.Repository,
+			Name:       strings.TrimSuffix(envelope.Locator.Name, "-resources"),
+			Version:    envelope.Locator.Version,
+		}
+	}
+	return nil
+}
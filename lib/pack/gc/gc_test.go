@@ -0,0 +1,266 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/ghodss/yaml"
+	"github.com/mailgun/timetools"
+	log "github.com/sirupsen/logrus"
+	. "gopkg.in/check.v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGC(t *testing.T) { TestingT(t) }
+
+type GCSuite struct {
+	backend  storage.Backend
+	packages *localpack.PackageServer
+	clock    *timetools.FreezedTime
+}
+
+var _ = Suite(&GCSuite{})
+
+func (s *GCSuite) SetUpTest(c *C) {
+	log.SetOutput(os.Stderr)
+	dir := c.MkDir()
+	s.clock = &timetools.FreezedTime{
+		CurrentTime: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	var err error
+	s.backend, err = keyval.NewBolt(keyval.BoltConfig{
+		Path: filepath.Join(dir, "storage.db"),
+	})
+	c.Assert(err, IsNil)
+
+	objects, err := fs.New(dir)
+	c.Assert(err, IsNil)
+
+	s.packages, err = localpack.New(localpack.Config{
+		Backend:     s.backend,
+		UnpackedDir: filepath.Join(dir, defaults.UnpackedDir),
+		Clock:       s.clock,
+		Objects:     objects,
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *GCSuite) TearDownTest(c *C) {
+	c.Assert(s.backend.Close(), IsNil)
+}
+
+func (s *GCSuite) TestCollectsUnreachablePackages(c *C) {
+	// setup
+	s.createPackage(c, "gravitational.io/planet:0.0.1", nil)
+	s.createPackage(c, "gravitational.io/planet:0.0.2", nil)
+	s.createPackage(c, "gravitational.io/teleport:0.0.1", nil)
+	s.createPackage(c, "gravitational.io/teleport:0.0.2", nil)
+	s.createPackage(c, "gravitational.io/unknown:0.0.1", nil)
+	s.createPackage(c, "example.com/planet-config-10.0.0.1:0.0.1", pack.ConfigLabels(
+		loc.MustParseLocator("gravitational.io/planet:0.0.1"), pack.PurposePlanetConfig))
+	s.createPackage(c, "example.com/planet-config-10.0.0.1:0.0.2", pack.ConfigLabels(
+		loc.MustParseLocator("gravitational.io/planet:0.0.2"), pack.PurposePlanetConfig))
+	s.createApp(c, "gravitational.io/runtime:0.0.1", "gravitational.io/teleport:0.0.1",
+		"gravitational.io/planet:0.0.1", "")
+	s.createApp(c, "gravitational.io/runtime:0.0.2", "gravitational.io/teleport:0.0.2",
+		"gravitational.io/planet:0.0.2", "")
+	s.createApp(c, "gravitational.io/app:0.0.1", "", "", "gravitational.io/runtime:0.0.1")
+	s.createApp(c, "gravitational.io/app:0.0.2", "", "", "gravitational.io/runtime:0.0.2")
+	s.createPackage(c, "gravitational.io/app-resources:0.0.1", nil)
+	s.clock.Sleep(2 * time.Hour)
+
+	// exercise
+	result, err := Collect(Config{
+		Packages:         s.packages,
+		Roots:            []loc.Locator{loc.MustParseLocator("gravitational.io/app:0.0.2")},
+		ProtectionWindow: time.Hour,
+		Clock:            s.clock,
+	})
+	c.Assert(err, IsNil)
+
+	// verify
+	c.Assert(result.Deleted, compare.DeepEquals, []loc.Locator{
+		loc.MustParseLocator("example.com/planet-config-10.0.0.1:0.0.1"),
+		loc.MustParseLocator("gravitational.io/app-resources:0.0.1"),
+		loc.MustParseLocator("gravitational.io/app:0.0.1"),
+		loc.MustParseLocator("gravitational.io/planet:0.0.1"),
+		loc.MustParseLocator("gravitational.io/runtime:0.0.1"),
+		loc.MustParseLocator("gravitational.io/teleport:0.0.1"),
+	})
+	c.Assert(result.Protected, HasLen, 0)
+	c.Assert(result.ReclaimedBytes, Equals, int64(6*len(packageData)))
+	c.Assert(s.listPackages(c), compare.DeepEquals, []string{
+		"example.com/planet-config-10.0.0.1:0.0.2",
+		"gravitational.io/app:0.0.2",
+		"gravitational.io/planet:0.0.2",
+		"gravitational.io/runtime:0.0.2",
+		"gravitational.io/teleport:0.0.2",
+		"gravitational.io/unknown:0.0.1",
+	})
+}
+
+func (s *GCSuite) TestKeepsIntermediateRuntimes(c *C) {
+	// setup
+	s.createApp(c, "gravitational.io/runtime:0.0.1", "", "gravitational.io/planet:0.0.1", "")
+	s.createApp(c, "gravitational.io/runtime:0.0.2", "", "gravitational.io/planet:0.0.2", "")
+	s.createApp(c, "gravitational.io/runtime:0.0.3", "", "gravitational.io/planet:0.0.3", "")
+	s.createPackage(c, "gravitational.io/planet:0.0.1", nil)
+	s.createPackage(c, "gravitational.io/planet:0.0.2", nil)
+	s.createPackage(c, "gravitational.io/planet:0.0.3", nil)
+	manifest := appManifest("gravitational.io/app:0.0.3", "", "", "gravitational.io/runtime:0.0.3")
+	manifest.SetIntermediateRuntimes([]loc.Locator{loc.MustParseLocator("gravitational.io/runtime:0.0.2")})
+	s.createAppWithManifest(c, manifest)
+	s.clock.Sleep(2 * time.Hour)
+
+	// exercise
+	result, err := Collect(Config{
+		Packages: s.packages,
+		Roots:    []loc.Locator{loc.MustParseLocator("gravitational.io/app:0.0.3")},
+		DryRun:   true,
+		Clock:    s.clock,
+	})
+	c.Assert(err, IsNil)
+
+	// verify
+	c.Assert(result.Deleted, compare.DeepEquals, []loc.Locator{
+		loc.MustParseLocator("gravitational.io/planet:0.0.1"),
+		loc.MustParseLocator("gravitational.io/runtime:0.0.1"),
+	})
+	// Nothing is removed in dry-run mode
+	c.Assert(s.listPackages(c), HasLen, 7)
+}
+
+func (s *GCSuite) TestKeepsRecentlyImportedPackages(c *C) {
+	// setup
+	s.createPackage(c, "gravitational.io/planet:0.0.1", nil)
+	s.createApp(c, "gravitational.io/app:0.0.1", "", "gravitational.io/planet:0.0.1", "")
+	s.clock.Sleep(2 * time.Hour)
+	s.createPackage(c, "gravitational.io/planet:0.0.2", nil)
+
+	// exercise
+	result, err := Collect(Config{
+		Packages:         s.packages,
+		Roots:            []loc.Locator{loc.MustParseLocator("gravitational.io/app:0.0.1")},
+		ProtectionWindow: time.Hour,
+		Clock:            s.clock,
+	})
+	c.Assert(err, IsNil)
+
+	// verify
+	c.Assert(result.Deleted, HasLen, 0)
+	c.Assert(result.Protected, compare.DeepEquals, []loc.Locator{
+		loc.MustParseLocator("gravitational.io/planet:0.0.2"),
+	})
+	c.Assert(s.listPackages(c), HasLen, 3)
+}
+
+func (s *GCSuite) TestFailsForMissingRoot(c *C) {
+	_, err := Collect(Config{
+		Packages: s.packages,
+		Roots:    []loc.Locator{loc.MustParseLocator("gravitational.io/app:0.0.1")},
+	})
+	c.Assert(err, NotNil)
+}
+
+func (s *GCSuite) createPackage(c *C, locator string, labels map[string]string) {
+	loc := loc.MustParseLocator(locator)
+	c.Assert(s.packages.UpsertRepository(loc.Repository, time.Time{}), IsNil)
+	_, err := s.packages.CreatePackage(loc, bytes.NewReader(packageData), pack.WithLabels(labels))
+	c.Assert(err, IsNil)
+}
+
+func (s *GCSuite) createApp(c *C, locator, teleport, planet, base string) {
+	s.createAppWithManifest(c, appManifest(locator, teleport, planet, base))
+}
+
+func (s *GCSuite) createAppWithManifest(c *C, manifest schema.Manifest) {
+	data, err := yaml.Marshal(manifest)
+	c.Assert(err, IsNil)
+	appType := storage.AppUser
+	if manifest.Kind == schema.KindRuntime {
+		appType = storage.AppRuntime
+	}
+	c.Assert(s.packages.UpsertRepository(manifest.Locator().Repository, time.Time{}), IsNil)
+	_, err = s.packages.CreatePackage(manifest.Locator(), bytes.NewReader(packageData),
+		pack.WithManifest(string(appType), data))
+	c.Assert(err, IsNil)
+}
+
+func (s *GCSuite) listPackages(c *C) (packages []string) {
+	err := pack.ForeachPackage(s.packages, func(env pack.PackageEnvelope) error {
+		packages = append(packages, env.Locator.String())
+		return nil
+	})
+	c.Assert(err, IsNil)
+	return packages
+}
+
+// appManifest returns a manifest for the specified application.
+// Applications without a base application are runtime applications
+func appManifest(locator, teleport, planet, base string) schema.Manifest {
+	app := loc.MustParseLocator(locator)
+	kind := schema.KindRuntime
+	if base != "" {
+		kind = schema.KindBundle
+	}
+	manifest := schema.Manifest{
+		Header: schema.Header{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       kind,
+				APIVersion: schema.APIVersionV2,
+			},
+			Metadata: schema.Metadata{
+				Repository:      app.Repository,
+				Name:            app.Name,
+				ResourceVersion: app.Version,
+			},
+		},
+	}
+	if teleport != "" {
+		manifest.Dependencies.Packages = append(manifest.Dependencies.Packages,
+			schema.Dependency{Locator: loc.MustParseLocator(teleport)})
+	}
+	if planet != "" {
+		manifest.SystemOptions = &schema.SystemOptions{
+			Dependencies: schema.SystemDependencies{
+				Runtime: &schema.Dependency{Locator: loc.MustParseLocator(planet)},
+			},
+		}
+	}
+	if base != "" {
+		manifest.BaseImage = &schema.BaseImage{Locator: loc.MustParseLocator(base)}
+	}
+	return manifest
+}
+
+var packageData = []byte("package data")
@@ -100,6 +100,10 @@ func (s *LocalSuite) TestDeleteRepository(c *C) {
 	s.suite.DeleteRepository(c)
 }
 
+func (s *LocalSuite) TestConstraintLocators(c *C) {
+	s.suite.ConstraintLocators(c)
+}
+
 func (s *LocalSuite) TestDeletesBlob(c *C) {
 	// setup
 	packageBytes := []byte(`package contents`)
@@ -0,0 +1,75 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pack
+
+import (
+	"github.com/gravitational/trace"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewMetricsCollector returns a collector that reports the number and
+// the total size of packages in each repository of the specified package service
+func NewMetricsCollector(packages PackageService) prometheus.Collector {
+	return &metricsCollector{packages: packages}
+}
+
+type metricsCollector struct {
+	packages PackageService
+}
+
+// Describe sends the descriptors of the package store metrics to ch
+func (c *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- packageStoreSizeDesc
+	ch <- packageStorePackagesDesc
+}
+
+// Collect sends the package store metrics to ch
+func (c *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	repositories, err := c.packages.GetRepositories()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(packageStoreSizeDesc, trace.Wrap(err))
+		return
+	}
+	for _, repository := range repositories {
+		packages, err := c.packages.GetPackages(repository)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(packageStoreSizeDesc, trace.Wrap(err))
+			return
+		}
+		var size int64
+		for _, envelope := range packages {
+			size += envelope.SizeBytes
+		}
+		ch <- prometheus.MustNewConstMetric(packageStoreSizeDesc,
+			prometheus.GaugeValue, float64(size), repository)
+		ch <- prometheus.MustNewConstMetric(packageStorePackagesDesc,
+			prometheus.GaugeValue, float64(len(packages)), repository)
+	}
+}
+
+var (
+	packageStoreSizeDesc = prometheus.NewDesc(
+		"package_store_size_bytes",
+		"Total size of the packages in the repository",
+		[]string{"repository"}, nil,
+	)
+	packageStorePackagesDesc = prometheus.NewDesc(
+		"package_store_packages",
+		"Number of packages in the repository",
+		[]string{"repository"}, nil,
+	)
+)
@@ -184,6 +184,47 @@ func (s *PackageSuite) UpsertPackages(c *C) {
 	c.Assert(r2.Close(), IsNil)
 }
 
+// ConstraintLocators makes sure that locators with semver ranges
+// resolve to the latest matching package version
+func (s *PackageSuite) ConstraintLocators(c *C) {
+	c.Assert(s.S.UpsertRepository("example.com", time.Time{}), IsNil)
+	for _, version := range []string{"1.1.0", "1.2.0", "1.2.5", "2.0.0"} {
+		_, err := s.S.CreatePackage(loc.MustParseLocator("example.com/package:"+version),
+			bytes.NewBufferString(version))
+		c.Assert(err, IsNil)
+	}
+
+	for _, tc := range []struct {
+		constraint string
+		expected   string
+	}{
+		{constraint: "^1.2.0", expected: "1.2.5"},
+		{constraint: "~1.1", expected: "1.1.0"},
+		{constraint: ">=1.0.0, <1.2.0", expected: "1.1.0"},
+		{constraint: ">=1.0.0", expected: "2.0.0"},
+	} {
+		comment := Commentf("constraint %v", tc.constraint)
+		filter, err := loc.ParseConstraintLocator("example.com/package:" + tc.constraint)
+		c.Assert(err, IsNil, comment)
+		locator, err := pack.ProcessMetadata(s.S, filter)
+		c.Assert(err, IsNil, comment)
+		c.Assert(locator.Version, Equals, tc.expected, comment)
+
+		env, reader, err := s.S.ReadPackage(*filter)
+		c.Assert(err, IsNil, comment)
+		data, err := ioutil.ReadAll(reader)
+		c.Assert(err, IsNil, comment)
+		c.Assert(reader.Close(), IsNil)
+		c.Assert(env.Locator.Version, Equals, tc.expected, comment)
+		c.Assert(string(data), Equals, tc.expected, comment)
+	}
+
+	filter, err := loc.ParseConstraintLocator("example.com/package:^3.0.0")
+	c.Assert(err, IsNil)
+	_, err = pack.ProcessMetadata(s.S, filter)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
+
 // DeleteRepository makes sure that when repository is deleted, all its package blobs are also deleted
 func (s *PackageSuite) DeleteRepository(c *C) {
 	err := s.S.UpsertRepository("example.com", time.Time{})
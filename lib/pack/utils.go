@@ -342,8 +342,12 @@ func FindInstalledPackageWithConfig(packages PackageService, filter loc.Locator)
 	return installed, config, nil
 }
 
-// ProcessMetadata processes some special metadata conventions, e.g. 'latest' metadata label
+// ProcessMetadata processes some special metadata conventions, e.g. 'latest' metadata label,
+// and resolves semver ranges to the latest matching package version
 func ProcessMetadata(packages PackageService, loc *loc.Locator) (*loc.Locator, error) {
+	if loc.IsConstraint() {
+		return FindLatestMatchingPackage(packages, *loc)
+	}
 	ver, err := loc.SemVer()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -384,6 +388,22 @@ func FindLatestPackage(packages PackageService, filter loc.Locator) (*loc.Locato
 	return loc, trace.Wrap(err)
 }
 
+// FindLatestMatchingPackage returns the latest package with the version
+// satisfying the semver range of the provided locator
+func FindLatestMatchingPackage(packages PackageService, filter loc.Locator) (*loc.Locator, error) {
+	loc, err := FindLatestPackageCustom(FindLatestPackageRequest{
+		Packages:   packages,
+		Repository: filter.Repository,
+		Match: func(e PackageEnvelope) bool {
+			return filter.Matches(e.Locator)
+		},
+	})
+	if err != nil && trace.IsNotFound(err) {
+		return nil, trace.NotFound("no package matching %v found", filter)
+	}
+	return loc, trace.Wrap(err)
+}
+
 // FindLatestPackageByName returns latest package with the specified name (across all repositories)
 func FindLatestPackageByName(packages PackageService, name string) (*loc.Locator, error) {
 	loc, err := FindLatestPackageCustom(FindLatestPackageRequest{
@@ -539,6 +559,63 @@ func CheckUpdatePackage(from, to loc.Locator) error {
 	return nil
 }
 
+// RuntimeUpgradePath returns the intermediate runtimes to upgrade through
+// when upgrading from the installed to the update runtime.
+//
+// Only intermediate runtimes with versions between the installed and the
+// update runtime are returned. Returns an error if any of the upgrade hops
+// is not supported directly
+func RuntimeUpgradePath(installed, update loc.Locator, intermediates []loc.Locator) (path []loc.Locator, err error) {
+	fromVer, err := installed.SemVer()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	toVer, err := update.SemVer()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	from := installed
+	for _, intermediate := range intermediates {
+		ver, err := intermediate.SemVer()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if !fromVer.LessThan(*ver) || !ver.LessThan(*toVer) {
+			continue
+		}
+		if err := checkDirectUpgrade(from, intermediate); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		path = append(path, intermediate)
+		from, fromVer = intermediate, ver
+	}
+	if err := checkDirectUpgrade(from, update); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return path, nil
+}
+
+// checkDirectUpgrade makes sure the runtime can be upgraded directly
+// from one version to another.
+// A runtime can be upgraded directly within the same major version
+// or to the next major version
+func checkDirectUpgrade(from, to loc.Locator) error {
+	fromVer, err := from.SemVer()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	toVer, err := to.SemVer()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if toVer.Major > fromVer.Major+1 {
+		return trace.BadParameter(
+			"direct upgrade from %v to %v is not supported, the cluster image should be built "+
+				"with intermediate runtime versions, see tele build --upgrade-via", fromVer, toVer)
+	}
+	return nil
+}
+
 // ConfigLabels returns the label set to assign a configuration role for the specified package loc
 func ConfigLabels(loc loc.Locator, purpose string) map[string]string {
 	return map[string]string{
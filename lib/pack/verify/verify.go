@@ -0,0 +1,303 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package verify implements integrity verification of the package data.
+//
+// The data of every package is read from the BLOB storage and its checksum
+// is recomputed and compared with the checksum recorded in the package
+// envelope. The data of corrupted packages can optionally be restored
+// from another replica, e.g. another master node or the Ops Center
+package verify
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/timetools"
+	log "github.com/sirupsen/logrus"
+)
+
+// Config defines the package verification configuration
+type Config struct {
+	// Packages specifies the package service with the packages to verify
+	Packages PackageService
+	// Objects specifies the BLOB storage with the package data
+	Objects blob.Objects
+	// Repair optionally specifies how to restore the data of corrupted packages
+	Repair RepairFunc
+	// Clock specifies the time provider
+	Clock timetools.TimeProvider
+	// FieldLogger specifies the logger
+	log.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets default values
+func (r *Config) CheckAndSetDefaults() error {
+	if r.Packages == nil {
+		return trace.BadParameter("package service is required")
+	}
+	if r.Objects == nil {
+		return trace.BadParameter("object storage is required")
+	}
+	if r.Clock == nil {
+		r.Clock = &timetools.RealTime{}
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "verify:pack")
+	}
+	return nil
+}
+
+// PackageService defines the subset of package APIs required for verification
+type PackageService interface {
+	// GetRepositories returns the list of repositories
+	GetRepositories() ([]string, error)
+	// GetPackages returns the list of packages in the specified repository
+	GetPackages(repository string) ([]pack.PackageEnvelope, error)
+	// ReadPackageEnvelope returns the envelope of the specified package
+	ReadPackageEnvelope(loc.Locator) (*pack.PackageEnvelope, error)
+}
+
+// RepairFunc restores the data of the specified corrupted package
+type RepairFunc func(pack.PackageEnvelope) error
+
+// Report describes the outcome of the package verification
+type Report struct {
+	// Started is when the verification has started
+	Started time.Time `json:"started"`
+	// Completed is when the verification has completed
+	Completed time.Time `json:"completed"`
+	// Verified is the number of verified packages
+	Verified int `json:"verified"`
+	// Corrupted lists the packages with missing or corrupted data
+	Corrupted []CorruptedPackage `json:"corrupted,omitempty"`
+}
+
+// Healthy returns true if there are no corrupted packages
+// left unrepaired
+func (r Report) Healthy() bool {
+	for _, corrupted := range r.Corrupted {
+		if !corrupted.Repaired {
+			return false
+		}
+	}
+	return true
+}
+
+// CorruptedPackage describes a package with missing or corrupted data
+type CorruptedPackage struct {
+	// Locator identifies the package
+	Locator loc.Locator `json:"locator"`
+	// Checksum is the checksum recorded in the package envelope
+	Checksum string `json:"checksum"`
+	// Error describes the problem with the package data
+	Error string `json:"error"`
+	// Repaired is whether the package data has been restored
+	Repaired bool `json:"repaired"`
+	// RepairError describes why the package data could not be restored
+	RepairError string `json:"repair_error,omitempty"`
+}
+
+// Verify recomputes the checksums of the data of all packages and reports
+// the packages whose data is missing or does not match the checksum.
+// If configured, the data of such packages is repaired
+func Verify(ctx context.Context, config Config) (*Report, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	report := Report{Started: config.Clock.UtcNow()}
+	repositories, err := config.Packages.GetRepositories()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, repository := range repositories {
+		envelopes, err := config.Packages.GetPackages(repository)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, envelope := range envelopes {
+			select {
+			case <-ctx.Done():
+				return nil, trace.Wrap(ctx.Err())
+			default:
+			}
+			corrupted := verifyPackage(config, envelope)
+			report.Verified++
+			if corrupted != nil {
+				report.Corrupted = append(report.Corrupted, *corrupted)
+			}
+		}
+	}
+	report.Completed = config.Clock.UtcNow()
+	return &report, nil
+}
+
+// verifyPackage verifies the data of the specified package and returns
+// the description of the problem if the data is missing or corrupted
+func verifyPackage(config Config, envelope pack.PackageEnvelope) *CorruptedPackage {
+	logger := config.WithField("package", envelope.Locator)
+	err := blob.Verify(config.Objects, envelope.SHA512)
+	if err == nil {
+		return nil
+	}
+	if trace.IsNotFound(err) {
+		// The package might have been removed after it has been listed
+		_, errEnvelope := config.Packages.ReadPackageEnvelope(envelope.Locator)
+		if trace.IsNotFound(errEnvelope) {
+			return nil
+		}
+	}
+	logger.WithError(err).Warn("Package data is corrupted.")
+	corrupted := CorruptedPackage{
+		Locator:  envelope.Locator,
+		Checksum: envelope.SHA512,
+		Error:    trace.UserMessage(err),
+	}
+	if config.Repair == nil {
+		return &corrupted
+	}
+	if err := config.Repair(envelope); err != nil {
+		logger.WithError(err).Warn("Failed to repair package data.")
+		corrupted.RepairError = trace.UserMessage(err)
+		return &corrupted
+	}
+	if err := blob.Verify(config.Objects, envelope.SHA512); err != nil {
+		logger.WithError(err).Warn("Package data is corrupted after repair.")
+		corrupted.RepairError = trace.UserMessage(err)
+		return &corrupted
+	}
+	logger.Info("Repaired package data.")
+	corrupted.Repaired = true
+	return &corrupted
+}
+
+// RefetchFrom returns a repair function that replaces the data of corrupted
+// packages in objects with the data downloaded from the source package service.
+// The downloaded data is verified before it replaces the corrupted copy
+func RefetchFrom(objects blob.Objects, source pack.PackageService) RepairFunc {
+	return func(envelope pack.PackageEnvelope) error {
+		_, reader, err := source.ReadPackage(envelope.Locator)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		defer reader.Close()
+		f, err := ioutil.TempFile("", "package")
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		defer func() {
+			f.Close()
+			os.Remove(f.Name())
+		}()
+		checksum, err := blob.Checksum(io.TeeReader(reader, f))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if checksum != envelope.SHA512 {
+			return trace.CompareFailed("package %v from the source has checksum %v, expected %v",
+				envelope.Locator, checksum, envelope.SHA512)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		// The storage keeps the existing copy of a BLOB on write,
+		// so the corrupted copy is removed first
+		err = objects.DeleteBLOB(envelope.SHA512)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		_, err = objects.WriteBLOB(f)
+		return trace.Wrap(err)
+	}
+}
+
+// VerifierConfig defines the configuration of the periodic verification
+type VerifierConfig struct {
+	Config
+	// Interval specifies how often the packages are verified
+	Interval time.Duration
+}
+
+// CheckAndSetDefaults validates the configuration and sets default values
+func (r *VerifierConfig) CheckAndSetDefaults() error {
+	if err := r.Config.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Interval == 0 {
+		r.Interval = defaults.PackageVerificationInterval
+	}
+	return nil
+}
+
+// NewVerifier returns a new periodic package verifier
+func NewVerifier(config VerifierConfig) (*Verifier, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Verifier{VerifierConfig: config}, nil
+}
+
+// Verifier periodically verifies the packages and keeps
+// the report of the last verification
+type Verifier struct {
+	VerifierConfig
+	mu     sync.Mutex
+	report *Report
+}
+
+// Run runs the verification loop until the context is cancelled
+func (r *Verifier) Run(ctx context.Context) {
+	r.Info("Starting package verifier.")
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			r.Info("Stopping package verifier.")
+			return
+		}
+		report, err := Verify(ctx, r.Config)
+		if err != nil {
+			r.Warnf("Failed to verify packages: %v.", trace.DebugReport(err))
+			continue
+		}
+		r.mu.Lock()
+		r.report = report
+		r.mu.Unlock()
+	}
+}
+
+// LastReport returns the report of the last completed verification
+func (r *Verifier) LastReport() (*Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.report == nil {
+		return nil, trace.NotFound("packages have not been verified yet")
+	}
+	report := *r.report
+	return &report, nil
+}
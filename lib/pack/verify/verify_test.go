@@ -0,0 +1,162 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	log "github.com/sirupsen/logrus"
+	. "gopkg.in/check.v1"
+)
+
+func TestVerify(t *testing.T) { TestingT(t) }
+
+type VerifySuite struct {
+	dir      string
+	backends []storage.Backend
+	objects  blob.Objects
+	packages *localpack.PackageServer
+	source   *localpack.PackageServer
+}
+
+var _ = Suite(&VerifySuite{})
+
+func (s *VerifySuite) SetUpTest(c *C) {
+	log.SetOutput(os.Stderr)
+	s.dir = c.MkDir()
+	s.backends = nil
+	s.objects, s.packages = s.newPackages(c, s.dir)
+	_, s.source = s.newPackages(c, c.MkDir())
+}
+
+func (s *VerifySuite) TearDownTest(c *C) {
+	for _, backend := range s.backends {
+		c.Assert(backend.Close(), IsNil)
+	}
+}
+
+func (s *VerifySuite) TestVerifiesAndRepairs(c *C) {
+	intact := loc.MustParseLocator("gravitational.io/intact:0.0.1")
+	corrupted := loc.MustParseLocator("gravitational.io/corrupted:0.0.1")
+	for _, locator := range []loc.Locator{intact, corrupted} {
+		data := []byte(locator.String())
+		_, err := s.packages.CreatePackage(locator, bytes.NewReader(data), pack.WithLabels(map[string]string{"purpose": "test"}))
+		c.Assert(err, IsNil)
+		_, err = s.source.CreatePackage(locator, bytes.NewReader(data))
+		c.Assert(err, IsNil)
+	}
+	envelope, err := s.packages.ReadPackageEnvelope(corrupted)
+	c.Assert(err, IsNil)
+	path := filepath.Join(s.dir, "blobs", envelope.SHA512[0:3], envelope.SHA512)
+	c.Assert(ioutil.WriteFile(path, []byte("corrupted"), defaults.SharedReadMask), IsNil)
+
+	report, err := Verify(context.TODO(), Config{
+		Packages: s.packages,
+		Objects:  s.objects,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(report.Verified, Equals, 2)
+	c.Assert(report.Healthy(), Equals, false)
+	c.Assert(report.Corrupted, HasLen, 1)
+	c.Assert(report.Corrupted[0].Locator, Equals, corrupted)
+	c.Assert(report.Corrupted[0].Repaired, Equals, false)
+
+	report, err = Verify(context.TODO(), Config{
+		Packages: s.packages,
+		Objects:  s.objects,
+		Repair:   RefetchFrom(s.objects, s.source),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(report.Healthy(), Equals, true)
+	c.Assert(report.Corrupted, HasLen, 1)
+	c.Assert(report.Corrupted[0].Repaired, Equals, true)
+
+	_, reader, err := s.packages.ReadPackage(corrupted)
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, corrupted.String())
+
+	envelope, err = s.packages.ReadPackageEnvelope(corrupted)
+	c.Assert(err, IsNil)
+	c.Assert(envelope.RuntimeLabels, DeepEquals, map[string]string{"purpose": "test"})
+
+	report, err = Verify(context.TODO(), Config{
+		Packages: s.packages,
+		Objects:  s.objects,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(report.Verified, Equals, 2)
+	c.Assert(report.Corrupted, HasLen, 0)
+}
+
+func (s *VerifySuite) TestDoesNotRepairFromCorruptedSource(c *C) {
+	locator := loc.MustParseLocator("gravitational.io/package:0.0.1")
+	_, err := s.packages.CreatePackage(locator, bytes.NewReader([]byte("data")))
+	c.Assert(err, IsNil)
+	_, err = s.source.CreatePackage(locator, bytes.NewReader([]byte("other data")))
+	c.Assert(err, IsNil)
+	envelope, err := s.packages.ReadPackageEnvelope(locator)
+	c.Assert(err, IsNil)
+	path := filepath.Join(s.dir, "blobs", envelope.SHA512[0:3], envelope.SHA512)
+	c.Assert(ioutil.WriteFile(path, []byte("corrupted"), defaults.SharedReadMask), IsNil)
+
+	report, err := Verify(context.TODO(), Config{
+		Packages: s.packages,
+		Objects:  s.objects,
+		Repair:   RefetchFrom(s.objects, s.source),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(report.Healthy(), Equals, false)
+	c.Assert(report.Corrupted, HasLen, 1)
+	c.Assert(report.Corrupted[0].Repaired, Equals, false)
+	c.Assert(report.Corrupted[0].RepairError, Not(Equals), "")
+}
+
+func (s *VerifySuite) newPackages(c *C, dir string) (blob.Objects, *localpack.PackageServer) {
+	backend, err := keyval.NewBolt(keyval.BoltConfig{
+		Path: filepath.Join(dir, "storage.db"),
+	})
+	c.Assert(err, IsNil)
+	s.backends = append(s.backends, backend)
+	objects, err := fs.New(dir)
+	c.Assert(err, IsNil)
+	packages, err := localpack.New(localpack.Config{
+		Backend:     backend,
+		UnpackedDir: filepath.Join(dir, defaults.UnpackedDir),
+		Objects:     objects,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(packages.UpsertRepository("gravitational.io", time.Time{}), IsNil)
+	return objects, packages
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webpack
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+
+	"github.com/gravitational/form"
+	"github.com/gravitational/roundtrip"
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// Upload describes the state of a resumable package upload
+type Upload struct {
+	// ID is the unique upload ID
+	ID string `json:"id"`
+	// Repository is the repository the package is uploaded to
+	Repository string `json:"repository"`
+	// Offset is the number of bytes the server has received so far
+	Offset int64 `json:"offset"`
+	// Created is the upload creation time
+	Created time.Time `json:"created"`
+}
+
+// createUpload starts a new resumable package upload
+//
+// POST /pack/v1/repositories/:repository/uploads
+func (s *Server) createUpload(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+	s.removeExpiredUploads()
+	upload := Upload{
+		ID:         uuid.New(),
+		Repository: p.ByName("repository"),
+		Created:    time.Now().UTC(),
+	}
+	dir := s.uploadDir(upload.ID)
+	if err := os.MkdirAll(dir, defaults.PrivateDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, uploadMetadataFile), data, defaults.PrivateFileMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, uploadDataFile), os.O_CREATE|os.O_WRONLY, defaults.PrivateFileMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	f.Close()
+	log.Debugf("Created upload %v.", upload.ID)
+	roundtrip.ReplyJSON(w, http.StatusOK, upload)
+	return nil
+}
+
+// getUpload returns the state of the upload
+//
+// GET /pack/v1/repositories/:repository/uploads/:upload_id
+func (s *Server) getUpload(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+	upload, err := s.readUpload(p.ByName("repository"), p.ByName("upload_id"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, upload)
+	return nil
+}
+
+// writeUpload appends the request body to the upload data.
+// The offset query parameter must match the current upload offset
+//
+// PUT /pack/v1/repositories/:repository/uploads/:upload_id?offset=<offset>
+func (s *Server) writeUpload(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil {
+		return trace.BadParameter("invalid offset %q", r.URL.Query().Get("offset"))
+	}
+	id := p.ByName("upload_id")
+	if err := s.lockUpload(id); err != nil {
+		return trace.Wrap(err)
+	}
+	defer s.unlockUpload(id)
+	upload, err := s.readUpload(p.ByName("repository"), id)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if upload.Offset != offset {
+		return trace.CompareFailed("upload %v is at offset %v, got %v",
+			upload.ID, upload.Offset, offset)
+	}
+	f, err := os.OpenFile(filepath.Join(s.uploadDir(id), uploadDataFile), os.O_APPEND|os.O_WRONLY, defaults.PrivateFileMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	// Whatever has been received before an interruption is kept,
+	// the client picks up from the resulting offset
+	n, err := io.Copy(f, r.Body)
+	upload.Offset += n
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, upload)
+	return nil
+}
+
+// completeUpload creates the package from the uploaded data and
+// removes the upload. It accepts the same form parameters as createPackage
+// with the package locator in the "package" parameter
+//
+// POST /pack/v1/repositories/:repository/uploads/:upload_id/complete
+func (s *Server) completeUpload(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+	var locator string
+	var labelsMap string
+	var upsertS string
+	var hiddenS string
+	var packageType string
+	var manifest string
+
+	err := form.Parse(r,
+		form.String("package", &locator, form.Required()),
+		form.String("labels", &labelsMap),
+		form.String("upsert", &upsertS),
+		form.String("hidden", &hiddenS),
+		form.String("type", &packageType),
+		form.String("manifest", &manifest),
+	)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	loc, err := loc.ParseLocator(locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if loc.Repository != p.ByName("repository") {
+		return trace.BadParameter("package %v does not belong to repository %v",
+			loc, p.ByName("repository"))
+	}
+
+	var labels map[string]string
+	if labelsMap != "" {
+		if err := json.Unmarshal([]byte(labelsMap), &labels); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
+	var upsert bool
+	if upsertS != "" {
+		upsert, err = strconv.ParseBool(upsertS)
+		if err != nil {
+			return trace.BadParameter("upsert should be either 'true' or 'false', got %v", upsertS)
+		}
+	}
+
+	var hidden bool
+	if hiddenS != "" {
+		hidden, err = strconv.ParseBool(hiddenS)
+		if err != nil {
+			return trace.BadParameter("hidden should be either 'true' or 'false', got %v", hiddenS)
+		}
+	}
+
+	id := p.ByName("upload_id")
+	if err := s.lockUpload(id); err != nil {
+		return trace.Wrap(err)
+	}
+	defer s.unlockUpload(id)
+	if _, err := s.readUpload(loc.Repository, id); err != nil {
+		return trace.Wrap(err)
+	}
+	f, err := os.Open(filepath.Join(s.uploadDir(id), uploadDataFile))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+
+	opts := []pack.PackageOption{pack.WithLabels(labels), pack.WithHidden(hidden)}
+	if manifest != "" {
+		opts = append(opts, pack.WithManifest(packageType, []byte(manifest)))
+	}
+
+	var envelope *pack.PackageEnvelope
+	if upsert {
+		envelope, err = service.UpsertPackage(*loc, f, opts...)
+	} else {
+		envelope, err = service.CreatePackage(*loc, f, opts...)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := os.RemoveAll(s.uploadDir(id)); err != nil {
+		log.Warnf("Failed to remove upload %v: %v.", id, err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, envelope)
+	return nil
+}
+
+// deleteUpload aborts the upload and removes the uploaded data
+//
+// DELETE /pack/v1/repositories/:repository/uploads/:upload_id
+func (s *Server) deleteUpload(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+	id := p.ByName("upload_id")
+	if err := s.lockUpload(id); err != nil {
+		return trace.Wrap(err)
+	}
+	defer s.unlockUpload(id)
+	if _, err := s.readUpload(p.ByName("repository"), id); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := os.RemoveAll(s.uploadDir(id)); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+// readUpload returns the upload with the specified ID from the given repository
+func (s *Server) readUpload(repository, id string) (*Upload, error) {
+	if uuid.Parse(id) == nil {
+		return nil, trace.BadParameter("invalid upload ID %q", id)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(s.uploadDir(id), uploadMetadataFile))
+	if err != nil {
+		err = trace.ConvertSystemError(err)
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("upload %v not found", id)
+		}
+		return nil, trace.Wrap(err)
+	}
+	var upload Upload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if upload.Repository != repository {
+		return nil, trace.NotFound("upload %v not found", id)
+	}
+	fi, err := os.Stat(filepath.Join(s.uploadDir(id), uploadDataFile))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	upload.Offset = fi.Size()
+	return &upload, nil
+}
+
+// lockUpload marks the upload with the specified ID as being modified.
+// Concurrent modifications of the same upload are rejected
+func (s *Server) lockUpload(id string) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.activeUploads[id] {
+		return trace.CompareFailed("upload %v is being modified by another request", id)
+	}
+	s.activeUploads[id] = true
+	return nil
+}
+
+// unlockUpload releases the upload lock acquired with lockUpload
+func (s *Server) unlockUpload(id string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.activeUploads, id)
+}
+
+// removeExpiredUploads removes uploads that have not been modified
+// for longer than defaults.UploadTTL
+func (s *Server) removeExpiredUploads() {
+	dirs, err := ioutil.ReadDir(s.cfg.UploadDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("Failed to read uploads directory: %v.", err)
+		}
+		return
+	}
+	for _, dir := range dirs {
+		fi, err := os.Stat(filepath.Join(s.cfg.UploadDir, dir.Name(), uploadDataFile))
+		if err != nil || time.Since(fi.ModTime()) < defaults.UploadTTL {
+			continue
+		}
+		if err := s.lockUpload(dir.Name()); err != nil {
+			continue
+		}
+		log.Debugf("Removing expired upload %v.", dir.Name())
+		if err := os.RemoveAll(filepath.Join(s.cfg.UploadDir, dir.Name())); err != nil {
+			log.Warnf("Failed to remove upload %v: %v.", dir.Name(), err)
+		}
+		s.unlockUpload(dir.Name())
+	}
+}
+
+func (s *Server) uploadDir(id string) string {
+	return filepath.Join(s.cfg.UploadDir, id)
+}
+
+const (
+	// uploadMetadataFile is the name of the file with upload metadata
+	uploadMetadataFile = "upload.json"
+	// uploadDataFile is the name of the file with the uploaded data
+	uploadDataFile = "data"
+)
@@ -18,20 +18,27 @@ package webpack
 
 import (
 	"context"
+	"crypto/sha512"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
+	"strconv"
 	"time"
 
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/roundtrip"
 	telehttplib "github.com/gravitational/teleport/lib/httplib"
 	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
 )
 
 const CurrentVersion = "pack/v1"
@@ -258,3 +265,322 @@ func (c *Client) Get(u string, params url.Values) (*roundtrip.Response, error) {
 func (c *Client) Delete(u string) (*roundtrip.Response, error) {
 	return telehttplib.ConvertResponse(c.Client.Delete(context.TODO(), u))
 }
+
+// UploadPackageRequest describes a resumable package upload
+type UploadPackageRequest struct {
+	// Locator identifies the package to create
+	Locator loc.Locator
+	// Reader provides the package data
+	Reader io.ReadSeeker
+	// StateFile is the path to the file where the upload progress is persisted.
+	// If the file refers to an unfinished upload of the same package, the upload
+	// is resumed from where it stopped
+	StateFile string
+	// Upsert overwrites the package if it already exists
+	Upsert bool
+	// Options specifies additional package attributes
+	Options []pack.PackageOption
+	// ChunkSize is the maximum size of data sent in a single request
+	ChunkSize int64
+}
+
+// uploadState is the upload progress persisted to disk
+type uploadState struct {
+	// ID is the upload ID
+	ID string `json:"id"`
+	// Endpoint is the upload URL
+	Endpoint string `json:"endpoint"`
+	// Package is the package being uploaded
+	Package string `json:"package"`
+	// Size is the package size
+	Size int64 `json:"size"`
+}
+
+// UploadPackage uploads the package in chunks and creates it once all data
+// has been received by the server. Interrupted requests are retried from the
+// last offset acknowledged by the server
+func (c *Client) UploadPackage(ctx context.Context, req UploadPackageRequest) (*pack.PackageEnvelope, error) {
+	if req.ChunkSize <= 0 {
+		req.ChunkSize = defaults.TransferChunkSize
+	}
+	size, err := req.Reader.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	upload, err := c.startUpload(req, size)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for upload.Offset < size {
+		var interrupted bool
+		err = utils.RetryOnNetworkError(defaults.TransferRetryPeriod, defaults.TransferRetryAttempts, func() error {
+			if interrupted {
+				// Some data may have reached the server before the interruption
+				current, err := c.GetUpload(req.Locator.Repository, upload.ID)
+				if err != nil {
+					return trace.Wrap(err)
+				}
+				upload = current
+				if upload.Offset >= size {
+					return nil
+				}
+			}
+			next, err := c.writeUpload(ctx, *upload, req.Reader, min(req.ChunkSize, size-upload.Offset))
+			if err != nil {
+				interrupted = true
+				return trace.Wrap(err)
+			}
+			upload = next
+			return nil
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	if upload.Offset != size {
+		return nil, trace.CompareFailed("server received %v bytes of %v", upload.Offset, size)
+	}
+	envelope, err := c.completeUpload(req, upload.ID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if req.StateFile != "" {
+		if err := os.Remove(req.StateFile); err != nil && !os.IsNotExist(err) {
+			return nil, trace.ConvertSystemError(err)
+		}
+	}
+	return envelope, nil
+}
+
+// GetUpload returns the state of the upload with the specified ID
+func (c *Client) GetUpload(repository, id string) (*Upload, error) {
+	out, err := c.Get(c.Endpoint("repositories", repository, "uploads", id), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var upload Upload
+	if err := json.Unmarshal(out.Bytes(), &upload); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &upload, nil
+}
+
+// DeleteUpload aborts the upload with the specified ID
+func (c *Client) DeleteUpload(repository, id string) error {
+	_, err := c.Delete(c.Endpoint("repositories", repository, "uploads", id))
+	return trace.Wrap(err)
+}
+
+// startUpload resumes the upload recorded in the state file or starts a new one
+func (c *Client) startUpload(req UploadPackageRequest, size int64) (*Upload, error) {
+	state := uploadState{
+		Endpoint: c.Endpoint("repositories", req.Locator.Repository, "uploads"),
+		Package:  req.Locator.String(),
+		Size:     size,
+	}
+	if req.StateFile != "" {
+		var prev uploadState
+		data, err := ioutil.ReadFile(req.StateFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, trace.ConvertSystemError(err)
+		}
+		if err == nil && json.Unmarshal(data, &prev) == nil {
+			state.ID = prev.ID
+		}
+		if state.ID != "" && prev == state {
+			upload, err := c.GetUpload(req.Locator.Repository, state.ID)
+			if err == nil {
+				log.Infof("Resuming upload of %v from offset %v.", req.Locator, upload.Offset)
+				return upload, nil
+			}
+			if !trace.IsNotFound(err) {
+				return nil, trace.Wrap(err)
+			}
+		}
+	}
+	out, err := c.PostForm(state.Endpoint, url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var upload Upload
+	if err := json.Unmarshal(out.Bytes(), &upload); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if req.StateFile != "" {
+		state.ID = upload.ID
+		data, err := json.Marshal(state)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if err := ioutil.WriteFile(req.StateFile, data, defaults.PrivateFileMask); err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+	}
+	return &upload, nil
+}
+
+// writeUpload sends the next chunk of the specified size starting at the upload offset
+func (c *Client) writeUpload(ctx context.Context, upload Upload, r io.ReadSeeker, size int64) (*Upload, error) {
+	if _, err := r.Seek(upload.Offset, io.SeekStart); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	endpoint := c.Endpoint("repositories", upload.Repository, "uploads", upload.ID)
+	out, err := telehttplib.ConvertResponse(c.RoundTrip(func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodPut, endpoint, io.LimitReader(r, size))
+		if err != nil {
+			return nil, err
+		}
+		req.URL.RawQuery = url.Values{"offset": []string{strconv.FormatInt(upload.Offset, 10)}}.Encode()
+		req.ContentLength = size
+		c.SetAuthHeader(req.Header)
+		return c.HTTPClient().Do(req.WithContext(ctx))
+	}))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var result Upload
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &result, nil
+}
+
+// completeUpload creates the package from the uploaded data
+func (c *Client) completeUpload(req UploadPackageRequest, id string) (*pack.PackageEnvelope, error) {
+	pkg := storage.Package{
+		Repository: req.Locator.Repository,
+		Name:       req.Locator.Name,
+		Version:    req.Locator.Version,
+	}
+	for _, option := range req.Options {
+		option(&pkg)
+	}
+	labelsJSON, err := json.Marshal(pkg.RuntimeLabels)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	values := url.Values{
+		"package": []string{req.Locator.String()},
+		"labels":  []string{string(labelsJSON)},
+		"hidden":  []string{fmt.Sprintf("%t", pkg.Hidden)},
+		"upsert":  []string{fmt.Sprintf("%t", req.Upsert)},
+	}
+	if pkg.Type != "" {
+		values["type"] = []string{pkg.Type}
+	}
+	if len(pkg.Manifest) > 0 {
+		values["manifest"] = []string{string(pkg.Manifest)}
+	}
+	out, err := c.PostForm(c.Endpoint("repositories", req.Locator.Repository, "uploads", id, "complete"), values)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var envelope *pack.PackageEnvelope
+	if err := json.Unmarshal(out.Bytes(), &envelope); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return envelope, nil
+}
+
+// DownloadPackage downloads the package into the file at the specified path.
+// The data is first written into a partial file next to the destination so an
+// interrupted download is resumed from where it stopped, including by a
+// subsequent call with the same path
+func (c *Client) DownloadPackage(ctx context.Context, loc loc.Locator, path string) (*pack.PackageEnvelope, error) {
+	envelope, err := c.ReadPackageEnvelope(loc)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	partialPath := path + defaults.PartialFileSuffix
+	f, err := os.OpenFile(partialPath, os.O_CREATE|os.O_RDWR, defaults.SharedReadWriteMask)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	err = utils.RetryOnNetworkError(defaults.TransferRetryPeriod, defaults.TransferRetryAttempts, func() error {
+		return trace.Wrap(c.downloadPackage(ctx, loc, f, envelope.SizeBytes))
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	hash := sha512.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	// Blob storage records the checksum truncated to the first half
+	// of the sha512 digest so only compare the recorded part
+	sum := hash.Sum(nil)
+	if len(envelope.SHA512)/2 < len(sum) {
+		sum = sum[:len(envelope.SHA512)/2]
+	}
+	if checksum := fmt.Sprintf("%x", sum); checksum != envelope.SHA512 {
+		// The partial data is corrupted, start from scratch next time
+		os.Remove(partialPath)
+		return nil, trace.CompareFailed("checksum mismatch for %v: expected %v, got %v",
+			loc, envelope.SHA512, checksum)
+	}
+	if err := os.Rename(partialPath, path); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return envelope, nil
+}
+
+// downloadPackage appends the remainder of the package data to the specified file
+func (c *Client) downloadPackage(ctx context.Context, loc loc.Locator, f *os.File, size int64) error {
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if offset == size {
+		return nil
+	}
+	if offset > size {
+		return trace.BadParameter("%v is larger than package %v", f.Name(), loc)
+	}
+	if offset != 0 {
+		log.Infof("Resuming download of %v from offset %v.", loc, offset)
+	}
+	req, err := http.NewRequest(http.MethodGet,
+		c.Endpoint("repositories", loc.Repository, "packages", loc.Name, loc.Version, "file"), nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%v-", offset))
+	c.SetAuthHeader(req.Header)
+	resp, err := c.HTTPClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return trace.ConnectionProblem(err, "failed to download %v", loc)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return trace.ReadError(resp.StatusCode, data)
+	}
+	if resp.StatusCode == http.StatusOK && offset != 0 {
+		// The server ignored the range request and sent the whole package
+		if err := f.Truncate(0); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	_, err = io.Copy(f, resp.Body)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return trace.ConnectionProblem(err, "failed to download %v", loc)
+		}
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+func min(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
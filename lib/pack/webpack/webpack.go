@@ -22,9 +22,13 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
@@ -43,12 +47,18 @@ type Config struct {
 	Packages      pack.PackageService
 	Users         users.Identity
 	Authenticator httplib.Authenticator
+	// UploadDir is the directory with the state of resumable uploads
+	UploadDir string
 }
 
 type Server struct {
 	httprouter.Router
+	// Mutex guards activeUploads
+	sync.Mutex
 	cfg        Config
 	fileServer http.Handler
+	// activeUploads is the set of uploads that are being modified
+	activeUploads map[string]bool
 }
 
 func NewHandler(cfg Config) (*Server, error) {
@@ -58,8 +68,12 @@ func NewHandler(cfg Config) (*Server, error) {
 	if cfg.Users == nil {
 		return nil, trace.BadParameter("missing parameter Users")
 	}
+	if cfg.UploadDir == "" {
+		cfg.UploadDir = filepath.Join(os.TempDir(), defaults.UploadDir)
+	}
 	h := &Server{
-		cfg: cfg,
+		cfg:           cfg,
+		activeUploads: make(map[string]bool),
 	}
 
 	h.POST("/pack/v1/repositories", h.needsAuth(h.createRepository))
@@ -74,6 +88,13 @@ func NewHandler(cfg Config) (*Server, error) {
 	h.POST("/pack/v1/repositories/:repository/packages/:package_name/:package_version", h.needsAuth(h.updatePackageLabels))
 	h.DELETE("/pack/v1/repositories/:repository/packages/:package_name/:package_version", h.needsAuth(h.deletePackage))
 
+	// resumable uploads
+	h.POST("/pack/v1/repositories/:repository/uploads", h.needsAuth(h.createUpload))
+	h.GET("/pack/v1/repositories/:repository/uploads/:upload_id", h.needsAuth(h.getUpload))
+	h.PUT("/pack/v1/repositories/:repository/uploads/:upload_id", h.needsAuth(h.writeUpload))
+	h.POST("/pack/v1/repositories/:repository/uploads/:upload_id/complete", h.needsAuth(h.completeUpload))
+	h.DELETE("/pack/v1/repositories/:repository/uploads/:upload_id", h.needsAuth(h.deleteUpload))
+
 	return h, nil
 }
 
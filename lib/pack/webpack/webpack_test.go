@@ -18,7 +18,9 @@ package webpack
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -36,6 +38,7 @@ import (
 	"github.com/gravitational/gravity/lib/storage/keyval"
 	"github.com/gravitational/gravity/lib/users"
 	"github.com/gravitational/gravity/lib/users/usersservice"
+	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/roundtrip"
 	teleservices "github.com/gravitational/teleport/lib/services"
@@ -106,8 +109,9 @@ func (s *WebpackSuite) SetUpTest(c *C) {
 	})
 	c.Assert(err, IsNil)
 	webHandler, err := NewHandler(Config{
-		Users:    s.users,
-		Packages: service,
+		Users:     s.users,
+		Packages:  service,
+		UploadDir: filepath.Join(s.dir, defaults.UploadDir),
 	})
 	c.Assert(err, IsNil)
 	mux := http.NewServeMux()
@@ -293,3 +297,72 @@ func (s *WebpackSuite) TestUpsertPackages(c *C) {
 func (s *WebpackSuite) TestDeleteRepository(c *C) {
 	s.suite.DeleteRepository(c)
 }
+
+func (s *WebpackSuite) TestResumesUpload(c *C) {
+	client := s.suite.S.(*Client)
+	ctx := context.Background()
+	locator := loc.MustParseLocator("example.com/app:0.0.1")
+	c.Assert(client.UpsertRepository(locator.Repository, time.Time{}), IsNil)
+	data := bytes.Repeat([]byte("package data"), 100)
+	stateFile := filepath.Join(c.MkDir(), "upload.json")
+
+	// simulate an upload interrupted after the first chunk
+	upload, err := client.startUpload(UploadPackageRequest{
+		Locator:   locator,
+		StateFile: stateFile,
+	}, int64(len(data)))
+	c.Assert(err, IsNil)
+	upload, err = client.writeUpload(ctx, *upload, bytes.NewReader(data), 100)
+	c.Assert(err, IsNil)
+	c.Assert(upload.Offset, Equals, int64(100))
+
+	_, err = client.writeUpload(ctx, Upload{ID: upload.ID, Repository: upload.Repository},
+		bytes.NewReader(data), 100)
+	c.Assert(trace.IsCompareFailed(err), Equals, true, Commentf("%v", err))
+
+	envelope, err := client.UploadPackage(ctx, UploadPackageRequest{
+		Locator:   locator,
+		Reader:    bytes.NewReader(data),
+		StateFile: stateFile,
+		ChunkSize: 256,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(envelope.Locator, Equals, locator)
+	_, err = utils.StatFile(stateFile)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	_, reader, err := client.ReadPackage(locator)
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	uploaded, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(uploaded, DeepEquals, data)
+
+	_, err = client.GetUpload(locator.Repository, upload.ID)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *WebpackSuite) TestResumesDownload(c *C) {
+	client := s.suite.S.(*Client)
+	locator := loc.MustParseLocator("example.com/app:0.0.1")
+	c.Assert(client.UpsertRepository(locator.Repository, time.Time{}), IsNil)
+	data := bytes.Repeat([]byte("package data"), 100)
+	_, err := client.UploadPackage(context.Background(), UploadPackageRequest{
+		Locator: locator,
+		Reader:  bytes.NewReader(data),
+	})
+	c.Assert(err, IsNil)
+
+	// simulate a download interrupted half way
+	path := filepath.Join(c.MkDir(), "package.tar")
+	err = ioutil.WriteFile(path+defaults.PartialFileSuffix, data[:len(data)/2], defaults.SharedReadWriteMask)
+	c.Assert(err, IsNil)
+
+	_, err = client.DownloadPackage(context.Background(), locator, path)
+	c.Assert(err, IsNil)
+	downloaded, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(downloaded, DeepEquals, data)
+	_, err = utils.StatFile(path + defaults.PartialFileSuffix)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
@@ -28,18 +28,21 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gravitational/gravity/lib/app"
+	dockerapp "github.com/gravitational/gravity/lib/app/docker"
 	apphandler "github.com/gravitational/gravity/lib/app/handler"
 	appservice "github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/audit"
 	"github.com/gravitational/gravity/lib/autoscale/aws"
 	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/blob/chunked"
 	blobclient "github.com/gravitational/gravity/lib/blob/client"
 	blobcluster "github.com/gravitational/gravity/lib/blob/cluster"
-	blobfs "github.com/gravitational/gravity/lib/blob/fs"
 	blobhandler "github.com/gravitational/gravity/lib/blob/handler"
 	"github.com/gravitational/gravity/lib/clients"
 	cloudaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
@@ -47,7 +50,9 @@ import (
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/docker"
 	"github.com/gravitational/gravity/lib/helm"
+	"github.com/gravitational/gravity/lib/healing"
 	"github.com/gravitational/gravity/lib/httplib"
+	libkube "github.com/gravitational/gravity/lib/kubernetes"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/modules"
 	"github.com/gravitational/gravity/lib/ops"
@@ -58,6 +63,7 @@ import (
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/pack/layerpack"
 	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/pack/verify"
 	"github.com/gravitational/gravity/lib/pack/webpack"
 	"github.com/gravitational/gravity/lib/processconfig"
 	"github.com/gravitational/gravity/lib/rpc"
@@ -90,6 +96,7 @@ import (
 	"github.com/gravitational/teleport"
 	"github.com/gravitational/trace"
 	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/kubernetes"
@@ -118,6 +125,8 @@ type Process struct {
 	reverseTunnel  reversetunnel.Server
 	proxy          *teleportProxyService
 	client         *kubernetes.Clientset
+	// packageVerifier verifies the cluster packages stored on this node
+	packageVerifier *verify.Verifier
 	// resumeOperationCh relays requests to resume last active cluster operation
 	resumeOperationCh chan struct{}
 	// clusterServices contains registered cluster services that start when
@@ -135,6 +144,8 @@ type Process struct {
 	// a config that gets applied on top of teleport's config the process
 	// was started with)
 	authGatewayConfig storage.AuthGateway
+	// autoscaleWebhook receives autoscaling events when running on AWS
+	autoscaleWebhook http.Handler
 }
 
 // Handlers combines all the process' web and API Handlers
@@ -156,7 +167,7 @@ type Handlers struct {
 	// BLOB is object storage service web handler
 	BLOB *blobhandler.Server
 	// Registry is the Docker registry handler.
-	Registry http.Handler
+	Registry *docker.Registry
 }
 
 // rpcCredentials holds generated RPC agents credentials
@@ -209,7 +220,7 @@ func New(ctx context.Context, cfg processconfig.Config, tcfg telecfg.FileConfig)
 		return nil, trace.Wrap(err)
 	}
 
-	objects, err := blobfs.New(filepath.Join(cfg.DataDir, defaults.PackagesDir))
+	objects, err := chunked.New(filepath.Join(cfg.DataDir, defaults.PackagesDir))
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -517,9 +528,34 @@ func (p *Process) startAutoscale(ctx context.Context) error {
 		autoscaler.PublishDiscovery(localCtx, p.operator)
 		return nil
 	})
+	// remove nodes whose instances have been terminated without notification
+	p.RegisterClusterService(func(ctx context.Context) error {
+		localCtx := context.WithValue(ctx, constants.UserContext,
+			constants.ServiceAutoscaler)
+		autoscaler.SyncMembership(localCtx, p.operator)
+		return nil
+	})
+	webhookCtx := context.WithValue(ctx, constants.UserContext,
+		constants.ServiceAutoscaler)
+	p.Lock()
+	p.autoscaleWebhook = autoscaler.WebhookHandler(webhookCtx, p.operator)
+	p.Unlock()
 	return nil
 }
 
+// handleAutoscaleWebhook receives autoscaling events from the auto scaling
+// group lifecycle hooks
+func (p *Process) handleAutoscaleWebhook(w http.ResponseWriter, r *http.Request) {
+	p.Lock()
+	handler := p.autoscaleWebhook
+	p.Unlock()
+	if handler == nil {
+		trace.WriteError(w, trace.NotFound("autoscaling is not enabled"))
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
 // startApplicationsSynchronizer starts a service that periodically exports
 // Docker images of the cluster's application images to the local Docker
 // registry.
@@ -598,6 +634,159 @@ func (p *Process) startRegistrySynchronizer(ctx context.Context) error {
 	return nil
 }
 
+// startRegistryGarbageCollector starts a goroutine that periodically removes
+// the data no longer referenced from the local registry. The garbage collection
+// is also triggered when the set of installed applications changes, e.g. after
+// an application has been upgraded or uninstalled
+func (p *Process) startRegistryGarbageCollector(ctx context.Context) error {
+	gcConfig := p.cfg.Registry.GarbageCollection
+	if gcConfig.Disabled {
+		p.Info("Registry garbage collection is disabled.")
+		return nil
+	}
+	config, err := docker.Configuration(p.cfg.Registry.Storage)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	collector, err := dockerapp.NewGarbageCollector(dockerapp.GarbageCollectorConfig{
+		Config: config,
+		Request: dockerapp.GarbageCollectRequest{
+			DryRun:         gcConfig.DryRun,
+			RemoveUntagged: true,
+		},
+		Interval:     gcConfig.Interval,
+		ListRetained: p.listInstalledImages,
+		FieldLogger:  p.WithField(trace.Component, "registry-gc"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	go collector.Run(ctx)
+	go func() {
+		ticker := time.NewTicker(defaults.AppSyncInterval)
+		defer ticker.Stop()
+		var installed map[loc.Locator]struct{}
+		for {
+			select {
+			case <-ticker.C:
+				apps, err := p.applications.ListApps(app.ListAppsRequest{
+					Repository: defaults.SystemAccountOrg,
+				})
+				if err != nil {
+					p.Errorf("Failed to query applications: %v.",
+						trace.DebugReport(err))
+					continue
+				}
+				current := make(map[loc.Locator]struct{}, len(apps))
+				for _, a := range apps {
+					current[a.Package] = struct{}{}
+				}
+				if installed != nil && !reflect.DeepEqual(installed, current) {
+					p.Info("Installed applications have changed, scheduling registry garbage collection.")
+					collector.Trigger()
+				}
+				installed = current
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// listInstalledImages returns the images of the installed applications:
+// the cluster application and the applications exported to the local
+// registry by the application synchronizer, along with their dependencies.
+// The registry garbage collector removes all other images
+func (p *Process) listInstalledImages(ctx context.Context) ([]dockerapp.TagSpec, error) {
+	cluster, err := p.operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	installed := []loc.Locator{cluster.App.Package}
+	apps, err := p.applications.ListApps(app.ListAppsRequest{
+		Repository: defaults.SystemAccountOrg,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, a := range apps {
+		if a.Manifest.Kind == schema.KindApplication {
+			installed = append(installed, a.Package)
+		}
+	}
+	return appservice.ListAppImages(ctx, p.applications, p.packages,
+		filepath.Join(p.cfg.DataDir, defaults.PackagesDir, defaults.UnpackedDir), installed...)
+}
+
+// startRegistryReplicator starts a goroutine that periodically replicates
+// the images missing in the local registry from the registries of the other
+// master nodes, so that image pulls survive the failure of the active master
+func (p *Process) startRegistryReplicator(ctx context.Context) error {
+	if p.cfg.Registry.Replication.Disabled {
+		p.Info("Registry replication is disabled.")
+		return nil
+	}
+	replicator, err := dockerapp.NewPeerReplicator(dockerapp.PeerReplicatorConfig{
+		Local: dockerapp.RegistryConnectionRequest{
+			RegistryAddress: constants.LocalRegistryAddr,
+			CertName:        constants.DockerRegistry,
+		},
+		Peers:       p.peerRegistries,
+		Interval:    p.cfg.Registry.Replication.Interval,
+		FieldLogger: p.WithField(trace.Component, "registry-replicator"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	go replicator.Run(ctx)
+	return nil
+}
+
+// newPackageVerifier returns the verifier of the cluster packages stored
+// on this node. Packages found corrupted are restored from the other
+// master nodes
+func (p *Process) newPackageVerifier() (*verify.Verifier, error) {
+	var repair verify.RepairFunc
+	if repairer, ok := p.clusterObjects.(blob.Repairer); ok {
+		repair = func(envelope pack.PackageEnvelope) error {
+			return repairer.RepairBLOB(envelope.SHA512)
+		}
+	}
+	verifier, err := verify.NewVerifier(verify.VerifierConfig{
+		Config: verify.Config{
+			Packages:    p.packages,
+			Objects:     p.localObjects,
+			Repair:      repair,
+			FieldLogger: p.WithField(trace.Component, "package-verifier"),
+		},
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return verifier, nil
+}
+
+// peerRegistries returns the registries of the master nodes
+// other than the one this process is running on
+func (p *Process) peerRegistries() (peers []dockerapp.RegistryConnectionRequest, err error) {
+	cluster, err := p.operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, master := range storage.Servers(cluster.ClusterState.Servers).Masters() {
+		if master.AdvertiseIP == os.Getenv(constants.EnvPodIP) {
+			continue
+		}
+		// use the cert name of default registry, but connect via IP without relying on DNS
+		peers = append(peers, dockerapp.RegistryConnectionRequest{
+			RegistryAddress: defaults.DockerRegistryAddr(master.AdvertiseIP),
+			CertName:        constants.DockerRegistry,
+		})
+	}
+	return peers, nil
+}
+
 // startSiteStatusChecker periodically invokes app status hook; should be run in a goroutine
 func (p *Process) startSiteStatusChecker(ctx context.Context) error {
 	site, err := p.operator.GetLocalSite()
@@ -627,6 +816,123 @@ func (p *Process) startSiteStatusChecker(ctx context.Context) error {
 	}
 }
 
+// startOperationScheduler periodically starts the scheduled operations
+// of the local cluster that are due
+func (p *Process) startOperationScheduler(ctx context.Context, operator *opsservice.Operator) error {
+	site, err := p.operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	p.Info("Starting operation scheduler.")
+	ticker := time.NewTicker(defaults.OperationSchedulerInterval)
+	defer ticker.Stop()
+	localCtx := context.WithValue(ctx, constants.UserContext,
+		constants.ServiceOperationScheduler)
+	key := ops.SiteKey{
+		AccountID:  site.AccountID,
+		SiteDomain: site.Domain,
+	}
+	for {
+		select {
+		case <-ticker.C:
+			if err := operator.StartScheduledOperations(localCtx, key); err != nil {
+				p.Errorf("Failed to start scheduled operations: %v.",
+					trace.DebugReport(err))
+			}
+		case <-ctx.Done():
+			p.Info("Stopping operation scheduler.")
+			return nil
+		}
+	}
+}
+
+// startNodeProfileReconciler periodically restores the labels and taints
+// declared by the node profiles of the cluster application if they have been
+// removed or changed on the nodes
+func (p *Process) startNodeProfileReconciler(ctx context.Context, client *kubernetes.Clientset) error {
+	p.Info("Starting node profile reconciler.")
+	ticker := time.NewTicker(defaults.NodeProfileReconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.reconcileNodeProfiles(ctx, client); err != nil {
+				p.Errorf("Failed to reconcile node profiles: %v.",
+					trace.DebugReport(err))
+			}
+		case <-ctx.Done():
+			p.Info("Stopping node profile reconciler.")
+			return nil
+		}
+	}
+}
+
+// reconcileNodeProfiles restores the node profile labels and taints on
+// the cluster nodes. The nodes are left alone while an operation is in progress
+// since it can be changing the profiles
+func (p *Process) reconcileNodeProfiles(ctx context.Context, client *kubernetes.Clientset) error {
+	cluster, err := p.operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if cluster.State != ops.SiteStateActive {
+		return nil
+	}
+	updated, err := libkube.ReconcileNodeProfiles(ctx, client.CoreV1().Nodes(),
+		cluster.App.Manifest, cluster.ClusterState.Servers)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(updated) != 0 {
+		p.WithField("nodes", updated).Warn("Restored drifted node profile labels and taints.")
+	}
+	return nil
+}
+
+// startHealingController registers the cluster service that watches the health
+// of cluster components and executes remediations permitted by the healing policy
+func (p *Process) startHealingController(client *kubernetes.Clientset) error {
+	remediator, err := healing.NewRemediator(healing.RemediatorConfig{
+		Apps:   p.applications,
+		Proxy:  p.proxy,
+		Client: client,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	controller, err := healing.New(healing.Config{
+		Operator:    p.operator,
+		Checker:     healing.NewPlanetChecker(),
+		Remediator:  remediator,
+		FieldLogger: p.WithField(trace.Component, "healing"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	p.RegisterClusterService(controller.Run)
+	return nil
+}
+
+// startRemoteClusterStatusChecker periodically updates the states of the remote
+// clusters connected to this Ops Center; should be run in a goroutine
+func (p *Process) startRemoteClusterStatusChecker(ctx context.Context, operator *opsservice.Operator) error {
+	p.Info("Starting remote cluster status checker.")
+	ticker := time.NewTicker(defaults.OfflineCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := operator.UpdateRemoteClusterStates(ctx); err != nil {
+				p.Errorf("Failed to update remote cluster states: %v.",
+					trace.DebugReport(err))
+			}
+		case <-ctx.Done():
+			p.Info("Stopping remote cluster status checker.")
+			return nil
+		}
+	}
+}
+
 // startElection starts leader election process and watches the changes
 func (p *Process) startElection() error {
 	// elect gravity site leader - all other sites will remain
@@ -807,7 +1113,7 @@ func (p *Process) syncRegistry(site *ops.Site, leaderIP string) error {
 	p.Infof("Syncing registry.")
 	targetRegistry := constants.DockerRegistry
 	if leaderIP != "" {
-		targetRegistry = fmt.Sprintf("%s:%s", leaderIP, constants.DockerRegistryPort)
+		targetRegistry = net.JoinHostPort(leaderIP, constants.DockerRegistryPort)
 	}
 	start := time.Now()
 	// use the cert name of default registry, but connect via IP without relying on DNS
@@ -1068,7 +1374,7 @@ func (p *Process) initService(ctx context.Context) (err error) {
 		if err != nil {
 			return trace.Wrap(err)
 		}
-		objects, err := blobfs.New(filepath.Join(p.cfg.Pack.ReadDir, defaults.PackagesDir))
+		objects, err := chunked.New(filepath.Join(p.cfg.Pack.ReadDir, defaults.PackagesDir))
 		if err != nil {
 			return trace.Wrap(err)
 		}
@@ -1095,6 +1401,7 @@ func (p *Process) initService(ctx context.Context) (err error) {
 		Packages:      p.packages,
 		Users:         p.identity,
 		Authenticator: p.handlers.WebProxy.GetHandler().AuthenticateRequest,
+		UploadDir:     filepath.Join(p.cfg.DataDir, defaults.UploadDir),
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -1146,6 +1453,8 @@ func (p *Process) initService(ctx context.Context) (err error) {
 		p.handlers.Registry, err = docker.NewRegistry(docker.Config{
 			Context: ctx,
 			Users:   p.identity,
+			Storage: p.cfg.Registry.Storage,
+			Proxy:   p.cfg.Registry.Proxy,
 		})
 		if err != nil {
 			return trace.Wrap(err)
@@ -1189,6 +1498,20 @@ func (p *Process) initService(ctx context.Context) (err error) {
 	}
 
 	// start operator service and HTTP API
+	var registry opsservice.RegistryStatusReporter
+	if p.handlers.Registry != nil {
+		registry = p.handlers.Registry
+	}
+
+	var packageVerifier opsservice.PackageVerificationReporter
+	if p.inKubernetes() {
+		p.packageVerifier, err = p.newPackageVerifier()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		packageVerifier = p.packageVerifier
+	}
+
 	operator, err := opsservice.New(opsservice.Config{
 		Devmode:         p.cfg.Devmode,
 		StateDir:        p.cfg.DataDir,
@@ -1212,6 +1535,8 @@ func (p *Process) initService(ctx context.Context) (err error) {
 		InstallLogFiles: p.cfg.InstallLogFiles,
 		LogForwarders:   logs,
 		AuditLog:        authClient,
+		Registry:        registry,
+		PackageVerifier: packageVerifier,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -1234,10 +1559,23 @@ func (p *Process) initService(ctx context.Context) (err error) {
 		if err != nil {
 			return trace.Wrap(err)
 		}
+		if p.mode != constants.ComponentInstaller {
+			// Ops Center keeps the states of the remote clusters up-to-date
+			p.RegisterClusterService(func(ctx context.Context) error {
+				return p.startRemoteClusterStatusChecker(ctx, operator)
+			})
+			// and runs the fleet upgrades of the remote clusters
+			p.RegisterClusterService(operator.RunFleetUpgrades)
+		}
 	} else {
 		p.operator = operator
 	}
 
+	auditLog, err := p.newAuditLog()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	p.handlers.Operator, err = opshandler.NewWebHandler(opshandler.WebHandlerConfig{
 		Users:         p.identity,
 		Operator:      p.operator,
@@ -1245,6 +1583,7 @@ func (p *Process) initService(ctx context.Context) (err error) {
 		Packages:      p.packages,
 		Authenticator: p.handlers.WebProxy.GetHandler().AuthenticateRequest,
 		Backend:       p.backend,
+		Audit:         auditLog,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -1253,6 +1592,11 @@ func (p *Process) initService(ctx context.Context) (err error) {
 	// site status checker executes status hook periodically
 	p.RegisterClusterService(p.startSiteStatusChecker)
 
+	// operation scheduler starts queued operations once they are due
+	p.RegisterClusterService(func(ctx context.Context) error {
+		return p.startOperationScheduler(ctx, operator)
+	})
+
 	// a few services that are running only when gravity is started in
 	// local site mode
 	if p.inKubernetes() {
@@ -1287,10 +1631,28 @@ func (p *Process) initService(ctx context.Context) (err error) {
 			return trace.Wrap(err)
 		}
 
+		if err := p.startRegistryGarbageCollector(p.context); err != nil {
+			return trace.Wrap(err)
+		}
+
+		if err := p.startRegistryReplicator(p.context); err != nil {
+			return trace.Wrap(err)
+		}
+
+		go p.packageVerifier.Run(p.context)
+
 		if err := p.startAutoscale(p.context); err != nil {
 			return trace.Wrap(err)
 		}
 
+		if err := p.startHealingController(client); err != nil {
+			return trace.Wrap(err)
+		}
+
+		p.RegisterClusterService(func(ctx context.Context) error {
+			return p.startNodeProfileReconciler(ctx, client)
+		})
+
 		if err := p.startElection(); err != nil {
 			return trace.Wrap(err)
 		}
@@ -1499,6 +1861,10 @@ func (p *Process) proxyConfig() (*proxyConfig, error) {
 func (p *Process) initMux(ctx context.Context) error {
 	p.Info("Initializing mux.")
 
+	if err := p.initMetrics(ctx); err != nil {
+		return trace.Wrap(err)
+	}
+
 	mux := &httprouter.Router{}
 	for _, method := range httplib.Methods {
 		mux.Handler(method, "/web", p.handlers.Web) // to handle redirect
@@ -1517,13 +1883,44 @@ func (p *Process) initMux(ctx context.Context) error {
 		mux.Handler(method, "/v2/*rest", p.handlers.Registry)
 		mux.HandlerFunc(method, "/readyz", p.ReportReadiness)
 		mux.HandlerFunc(method, "/healthz", p.ReportHealth)
+		mux.Handler(method, "/metrics", prometheus.Handler())
 	}
+	mux.HandlerFunc(http.MethodPost, "/autoscale/events", p.handleAutoscaleWebhook)
 	mux.NotFound = p.handlers.Web.NotFound
 
 	return trace.Wrap(p.ServeLocal(ctx, httplib.GRPCHandlerFunc(
 		p.agentServer, mux), p.cfg.Pack.ListenAddr.Addr))
 }
 
+// initMetrics registers collectors of the package store and
+// the registry metrics exposed on the /metrics endpoint
+func (p *Process) initMetrics(ctx context.Context) error {
+	collectors := []prometheus.Collector{pack.NewMetricsCollector(p.packages)}
+	if p.handlers.Registry != nil {
+		config, err := docker.Configuration(p.cfg.Registry.Storage)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		collector, err := dockerapp.NewMetricsCollector(ctx, config)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		collectors = append(collectors, collector)
+	}
+	for _, collector := range collectors {
+		err := prometheus.Register(collector)
+		if existing, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			// replace the collector registered by a previous process instance
+			prometheus.Unregister(existing.ExistingCollector)
+			err = prometheus.Register(collector)
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
 // ServeLocal starts serving provided handler mux on the specified address
 //
 // The listener is restarted when a certificate change event is detected.
@@ -1591,9 +1988,16 @@ func (p *Process) startListening(handler http.Handler, addr string) (net.Listene
 // In case we're running inside Kubernetes cluster, certificate and key are
 // retrieved from the cluster-tls secret. Otherwise (or if that fails) it
 // falls back to self-signed certificate and key.
+//
+// Only FIPS-approved cipher suites are enabled if the process or
+// the local cluster runs in FIPS mode
 func (p *Process) getTLSConfig() (*tls.Config, error) {
+	fips, err := p.isFIPS()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 	if p.inKubernetes() {
-		config, err := p.tryGetTLSConfig()
+		config, err := p.tryGetTLSConfig(fips)
 		if err == nil {
 			return config, nil
 		}
@@ -1610,7 +2014,7 @@ func (p *Process) getTLSConfig() (*tls.Config, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	config, err := p.newTLSConfig(cert, key)
+	config, err := p.newTLSConfig(cert, key, fips)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -1618,8 +2022,27 @@ func (p *Process) getTLSConfig() (*tls.Config, error) {
 	return config, nil
 }
 
+// isFIPS returns true if this process runs with FIPS-validated crypto or
+// the local cluster has been installed in FIPS mode.
+// The local cluster is looked up in the backend so the failure to
+// read it is not mistaken for a problem with the cluster certificate
+func (p *Process) isFIPS() (bool, error) {
+	if utils.IsFIPS() {
+		return true, nil
+	}
+	cluster, err := p.backend.GetLocalSite(defaults.SystemAccountID)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			// not installed yet, e.g. in wizard mode
+			return false, nil
+		}
+		return false, trace.Wrap(err)
+	}
+	return cluster.ClusterState.FIPS, nil
+}
+
 // tryGetTLSConfig returns certificate/key pair from the cluster-tls secret.
-func (p *Process) tryGetTLSConfig() (*tls.Config, error) {
+func (p *Process) tryGetTLSConfig(fips bool) (*tls.Config, error) {
 	client, err := tryGetPrivilegedKubeClient()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -1632,7 +2055,7 @@ func (p *Process) tryGetTLSConfig() (*tls.Config, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	config, err := p.newTLSConfig(cert, key)
+	config, err := p.newTLSConfig(cert, key, fips)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -1640,8 +2063,9 @@ func (p *Process) tryGetTLSConfig() (*tls.Config, error) {
 }
 
 // newTLSConfig builds TLS configuration from the provided cert
-// and key PEM data
-func (p *Process) newTLSConfig(certPEM, keyPEM []byte) (*tls.Config, error) {
+// and key PEM data.
+// If fips is true, only FIPS-approved cipher suites are enabled
+func (p *Process) newTLSConfig(certPEM, keyPEM []byte, fips bool) (*tls.Config, error) {
 	httpCert, err := tls.X509KeyPair(certPEM, keyPEM)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -1662,6 +2086,9 @@ func (p *Process) newTLSConfig(certPEM, keyPEM []byte) (*tls.Config, error) {
 	}
 
 	config.CipherSuites = teleutils.DefaultCipherSuites()
+	if fips {
+		config.CipherSuites = defaults.FIPSCipherSuites
+	}
 
 	// Prefer the server ciphers, as curl will use invalid h2 ciphers
 	// https://github.com/nghttp2/nghttp2/issues/140
@@ -1696,6 +2123,17 @@ func (p *Process) initClusterCertificate(client *kubernetes.Clientset) error {
 
 	p.Info("Initializing cluster certificate.")
 
+	if site.PKI != nil {
+		// issue the certificate from the user-provided certificate authority
+		// instead of using the self-signed one
+		_, err = p.operator.IssueClusterCertificate(site.Key())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		p.Info("Cluster certificate has been issued.")
+		return nil
+	}
+
 	certificateData, err := ioutil.ReadFile(p.teleportConfig.Proxy.TLSCert)
 	if err != nil {
 		return trace.Wrap(err)
@@ -2206,3 +2644,26 @@ type proxyConfig struct {
 	sshPort           string
 	reverseTunnelAddr teleutils.NetAddr
 }
+
+// newAuditLog returns the audit log of operator API calls configured
+// with the forwarders enabled in the process configuration
+func (p *Process) newAuditLog() (*audit.Log, error) {
+	config := p.cfg.Audit
+	var forwarders []audit.Forwarder
+	if config.Syslog.Enabled {
+		forwarder, err := audit.NewSyslogForwarder(config.Syslog.Tag)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		forwarders = append(forwarders, forwarder)
+	}
+	if config.Webhook != "" {
+		forwarders = append(forwarders, audit.NewWebhookForwarder(config.Webhook))
+	}
+	return audit.New(audit.Config{
+		Backend:     p.backend,
+		Retention:   config.Retention,
+		Forwarders:  forwarders,
+		FieldLogger: p.WithField(trace.Component, "audit"),
+	})
+}
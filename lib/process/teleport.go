@@ -21,6 +21,7 @@ import (
 	"github.com/gravitational/gravity/lib/ops/opsservice"
 	"github.com/gravitational/gravity/lib/processconfig"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/users"
 
 	"github.com/gravitational/teleport/lib/config"
 	"github.com/gravitational/teleport/lib/service"
@@ -65,7 +66,7 @@ func (p *Process) buildTeleportConfig(authGatewayConfig storage.AuthGateway) (*s
 		serviceConfig.AuthServers = append(serviceConfig.AuthServers, serviceConfig.Auth.SSHAddr)
 	}
 	// Teleport will be using Gravity backend implementation.
-	serviceConfig.Identity = p.identity
+	serviceConfig.Identity = users.WithRoleMappings(p.identity)
 	serviceConfig.Trust = p.identity
 	serviceConfig.Presence = p.backend
 	serviceConfig.Provisioner = p.identity
@@ -19,7 +19,9 @@ package process
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"path/filepath"
+	"strconv"
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
@@ -62,7 +64,7 @@ func WizardProcessConfig(hostname, readStateDir, stateDir string) (*processconfi
 			},
 			AdvertiseAddr: teleutils.NetAddr{
 				AddrNetwork: "tcp",
-				Addr:        fmt.Sprintf("%v:%v", hostname, defaults.WizardPackServerPort),
+				Addr:        net.JoinHostPort(hostname, strconv.Itoa(defaults.WizardPackServerPort)),
 			},
 			ReadDir: readStateDir,
 		},
@@ -18,12 +18,14 @@ package processconfig
 
 import (
 	"bytes"
-	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	dockerapp "github.com/gravitational/gravity/lib/app/docker"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/helm"
@@ -135,12 +137,16 @@ type Config struct {
 	// HealthAddr provides HTTP API for health and readiness checks
 	HealthAddr teleutils.NetAddr `yaml:"health_addr"`
 
-	// BackendType is a type of storage backend
+	// BackendType is a type of storage backend, one of the types
+	// registered with keyval.Register
 	BackendType string `yaml:"backend_type"`
 
 	// ETCD provides etcd config options
 	ETCD keyval.ETCDConfig `yaml:"etcd"`
 
+	// Backend provides configuration options for backend types other than etcd
+	Backend keyval.Params `yaml:"backend"`
+
 	// OpsCenter provides settings for OpsCenter
 	OpsCenter OpsCenterConfig `yaml:"ops"`
 
@@ -150,6 +156,12 @@ type Config struct {
 	// Charts is Helm chart repository configuration.
 	Charts ChartsConfig `yaml:"charts"`
 
+	// Registry is the cluster Docker registry configuration.
+	Registry RegistryConfig `yaml:"registry"`
+
+	// Audit is the audit log configuration.
+	Audit AuditConfig `yaml:"audit"`
+
 	// Users list allows to add registered users to the application
 	// e.g. application admins, what is handy for development purposes
 	Users Users `yaml:"users"`
@@ -208,7 +220,10 @@ func (cfg *Config) CheckAndSetDefaults() error {
 			return trace.Wrap(err)
 		}
 	default:
-		return trace.BadParameter("unsupported backend type: %v", cfg.BackendType)
+		if !keyval.IsRegistered(cfg.BackendType) {
+			return trace.BadParameter("unsupported backend type: %v, supported types are %v",
+				cfg.BackendType, keyval.Types())
+		}
 	}
 
 	// Set default service user if unspecified
@@ -220,21 +235,36 @@ func (cfg *Config) CheckAndSetDefaults() error {
 		return trace.Wrap(err)
 	}
 
+	if err := cfg.Registry.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := cfg.Audit.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
 	return nil
 }
 
-func (cfg Config) CreateBackend() (backend storage.Backend, err error) {
-	switch cfg.BackendType {
-	case constants.BoltBackend:
-		log.Debug("using bolt backend")
-		backend, err = keyval.NewBolt(keyval.BoltConfig{
-			Path: filepath.Join(cfg.DataDir, defaults.GravityDBFile),
-		})
-	case constants.ETCDBackend:
-		log.Debug("using ETCD backend")
-		backend, err = keyval.NewETCD(cfg.ETCD)
+// CreateBackend creates the storage backend of the configured type
+func (cfg Config) CreateBackend() (storage.Backend, error) {
+	params := cfg.Backend
+	if cfg.BackendType == constants.ETCDBackend {
+		// etcd is configured with its own section for compatibility
+		var err error
+		params, err = keyval.NewParams(cfg.ETCD)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	log.Debugf("Using %v backend.", cfg.BackendType)
+	backend, err := keyval.New(cfg.BackendType, keyval.FactoryConfig{
+		DataDir: cfg.DataDir,
+		Params:  params,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
 	}
-	return backend, trace.Wrap(err)
+	return backend, nil
 }
 
 func (cfg Config) ProcessID() string {
@@ -324,7 +354,7 @@ func (p *PackageServiceConfig) PeerAddr() (*teleutils.NetAddr, error) {
 	}
 	return &teleutils.NetAddr{
 		AddrNetwork: p.ListenAddr.AddrNetwork,
-		Addr:        fmt.Sprintf("%v:%v", podIP, port),
+		Addr:        net.JoinHostPort(podIP, port),
 	}, nil
 }
 
@@ -365,6 +395,120 @@ func (c *ChartsConfig) CheckAndSetDefaults() error {
 	return nil
 }
 
+// RegistryConfig defines the cluster Docker registry configuration.
+type RegistryConfig struct {
+	// Storage is the registry storage driver configuration.
+	//
+	// By default, images are stored on the local disk. Large clusters
+	// can offload image blobs to an object storage, for example:
+	//
+	//   registry:
+	//     storage:
+	//       driver: s3
+	//       parameters:
+	//         region: us-east-1
+	//         bucket: registry
+	Storage dockerapp.StorageConfig `yaml:"storage"`
+
+	// GarbageCollection configures the scheduled removal of the data
+	// no longer referenced by the registry.
+	GarbageCollection RegistryGCConfig `yaml:"gc"`
+
+	// Replication configures the scheduled replication of the images
+	// missing in the registry of this master node from the registries
+	// of the other master nodes.
+	Replication RegistryReplicationConfig `yaml:"replication"`
+
+	// Proxy configures the registry as a pull-through cache of an upstream
+	// registry in connected environments. The images that are not found in
+	// the cluster registry are fetched from the upstream registry on demand:
+	//
+	//   registry:
+	//     proxy:
+	//       remote_url: https://registry-1.docker.io
+	Proxy *dockerapp.ProxyConfig `yaml:"proxy"`
+}
+
+// CheckAndSetDefaults validates the registry configuration.
+func (c *RegistryConfig) CheckAndSetDefaults() error {
+	if err := c.Storage.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if c.GarbageCollection.Interval < 0 {
+		return trace.BadParameter("registry garbage collection interval cannot be negative")
+	}
+	if c.Replication.Interval < 0 {
+		return trace.BadParameter("registry replication interval cannot be negative")
+	}
+	if c.Proxy != nil {
+		if err := c.Proxy.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// RegistryGCConfig defines the registry garbage collection schedule.
+type RegistryGCConfig struct {
+	// Disabled disables the scheduled garbage collection.
+	Disabled bool `yaml:"disabled"`
+	// DryRun only reports the amount of data eligible for removal.
+	DryRun bool `yaml:"dry_run"`
+	// Interval is how often the garbage collection runs.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// RegistryReplicationConfig defines the registry replication schedule.
+type RegistryReplicationConfig struct {
+	// Disabled disables the scheduled replication.
+	Disabled bool `yaml:"disabled"`
+	// Interval is how often the replication runs.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// AuditConfig defines the audit log of operator API calls.
+//
+// Events are always persisted in the backend. They can additionally be
+// forwarded to syslog and/or a webhook, for example:
+//
+//	audit:
+//	  retention: 720h
+//	  syslog:
+//	    enabled: true
+//	  webhook: https://audit.example.com/events
+type AuditConfig struct {
+	// Retention is how long the events are kept in the backend.
+	Retention time.Duration `yaml:"retention"`
+	// Syslog configures forwarding of the events to the local syslog.
+	Syslog AuditSyslogConfig `yaml:"syslog"`
+	// Webhook is an optional URL the events are posted to.
+	Webhook string `yaml:"webhook"`
+}
+
+// AuditSyslogConfig configures forwarding of audit events to syslog.
+type AuditSyslogConfig struct {
+	// Enabled enables forwarding to syslog.
+	Enabled bool `yaml:"enabled"`
+	// Tag is the syslog tag of the events.
+	Tag string `yaml:"tag"`
+}
+
+// CheckAndSetDefaults validates the audit log configuration.
+func (c *AuditConfig) CheckAndSetDefaults() error {
+	if c.Retention < 0 {
+		return trace.BadParameter("audit event retention cannot be negative")
+	}
+	if c.Retention == 0 {
+		c.Retention = defaults.AuditEventRetention
+	}
+	if c.Webhook != "" {
+		if _, err := url.ParseRequestURI(c.Webhook); err != nil {
+			return trace.BadParameter("invalid audit webhook URL %q: %v", c.Webhook, err)
+		}
+	}
+	return nil
+}
+
 // OpsCenterConfig provides settings for access and installation portal
 type OpsCenterConfig struct {
 	// SeedConfig defines optional configuration to apply on OpsCenter start
@@ -0,0 +1,129 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gravitational/trace"
+)
+
+func newAWS(spec storage.AWSProvisionerSpec) *awsProvisioner {
+	config := &aws.Config{Region: aws.String(spec.Region)}
+	if spec.AccessKey != "" {
+		config.Credentials = credentials.NewStaticCredentials(
+			spec.AccessKey, spec.SecretKey, "")
+	}
+	return &awsProvisioner{
+		spec: spec,
+		ec2:  ec2.New(session.New(), config),
+	}
+}
+
+// awsProvisioner creates AWS instances
+type awsProvisioner struct {
+	spec storage.AWSProvisionerSpec
+	ec2  instanceRunner
+}
+
+// instanceRunner launches EC2 instances
+type instanceRunner interface {
+	RunInstancesWithContext(aws.Context, *ec2.RunInstancesInput, ...request.Option) (*ec2.Reservation, error)
+}
+
+// Provision launches the requested number of AWS instances
+func (r *awsProvisioner) Provision(ctx context.Context, req Request) ([]Machine, error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	spec, ok := r.spec.Profiles[req.Profile]
+	if !ok {
+		return nil, profileNotFound(req.Profile)
+	}
+	machines := make([]Machine, 0, req.Count)
+	// instances are launched one at a time so each gets a distinct name
+	for i := 0; i < req.Count; i++ {
+		name := machineName(req.ClusterName, req.Profile)
+		reservation, err := r.ec2.RunInstancesWithContext(ctx,
+			runInstancesInput(spec, req, name))
+		if err != nil {
+			return machines, trace.Wrap(err, "failed to launch AWS instance %v", name)
+		}
+		for _, instance := range reservation.Instances {
+			machines = append(machines, Machine{
+				ID:   aws.StringValue(instance.InstanceId),
+				Name: name,
+			})
+		}
+	}
+	return machines, nil
+}
+
+// runInstancesInput returns the request to launch a single instance
+// with the specified configuration
+func runInstancesInput(spec storage.AWSMachineSpec, req Request, name string) *ec2.RunInstancesInput {
+	input := &ec2.RunInstancesInput{
+		ImageId:      aws.String(spec.ImageID),
+		InstanceType: aws.String(spec.InstanceType),
+		MinCount:     aws.Int64(1),
+		MaxCount:     aws.Int64(1),
+		UserData:     aws.String(base64.StdEncoding.EncodeToString([]byte(req.Bootstrap))),
+		TagSpecifications: []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeInstance),
+			Tags: []*ec2.Tag{
+				{Key: aws.String("Name"), Value: aws.String(name)},
+				// the tag the Kubernetes AWS cloud provider uses to identify
+				// the resources of the cluster
+				{Key: aws.String("KubernetesCluster"), Value: aws.String(req.ClusterName)},
+			},
+		}},
+	}
+	if spec.SubnetID != "" {
+		input.SubnetId = aws.String(spec.SubnetID)
+	}
+	if len(spec.SecurityGroupIDs) != 0 {
+		input.SecurityGroupIds = aws.StringSlice(spec.SecurityGroupIDs)
+	}
+	if spec.KeyName != "" {
+		input.KeyName = aws.String(spec.KeyName)
+	}
+	if spec.InstanceProfile != "" {
+		input.IamInstanceProfile = &ec2.IamInstanceProfileSpecification{
+			Name: aws.String(spec.InstanceProfile),
+		}
+	}
+	if spec.DiskSizeGB != 0 {
+		input.BlockDeviceMappings = []*ec2.BlockDeviceMapping{{
+			// the root device name of the CentOS and Ubuntu AMIs
+			DeviceName: aws.String("/dev/sda1"),
+			Ebs: &ec2.EbsBlockDevice{
+				VolumeSize:          aws.Int64(spec.DiskSizeGB),
+				VolumeType:          aws.String(ec2.VolumeTypeGp2),
+				DeleteOnTermination: aws.Bool(true),
+			},
+		}}
+	}
+	return input
+}
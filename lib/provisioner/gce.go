@@ -0,0 +1,178 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/oauth2/google"
+)
+
+func newGCE(spec storage.GCEProvisionerSpec) (*gceProvisioner, error) {
+	config, err := google.JWTConfigFromJSON([]byte(spec.Credentials), gceComputeScope)
+	if err != nil {
+		return nil, trace.Wrap(err, "invalid GCE service account credentials")
+	}
+	return &gceProvisioner{
+		spec:     spec,
+		endpoint: gceEndpoint,
+		client:   config.Client(context.Background()),
+	}, nil
+}
+
+// gceProvisioner creates GCE instances with the Compute Engine REST API
+type gceProvisioner struct {
+	spec storage.GCEProvisionerSpec
+	// endpoint is the Compute Engine API endpoint
+	endpoint string
+	// client is the HTTP client authorized with the service account
+	client *http.Client
+}
+
+// Provision creates the requested number of GCE instances
+func (r *gceProvisioner) Provision(ctx context.Context, req Request) ([]Machine, error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	spec, ok := r.spec.Profiles[req.Profile]
+	if !ok {
+		return nil, profileNotFound(req.Profile)
+	}
+	url := fmt.Sprintf("%v/projects/%v/zones/%v/instances", r.endpoint, r.spec.Project, r.spec.Zone)
+	machines := make([]Machine, 0, req.Count)
+	for i := 0; i < req.Count; i++ {
+		name := machineName(req.ClusterName, req.Profile)
+		var op gceOperation
+		err := doJSON(ctx, r.client, http.MethodPost, url, nil,
+			r.instance(spec, req, name), &op)
+		if err != nil {
+			return machines, trace.Wrap(err, "failed to create GCE instance %v", name)
+		}
+		if op.Error != nil && len(op.Error.Errors) != 0 {
+			return machines, trace.BadParameter("failed to create GCE instance %v: %v",
+				name, op.Error.Errors[0].Message)
+		}
+		machines = append(machines, Machine{ID: name, Name: name})
+	}
+	return machines, nil
+}
+
+// instance returns the definition of the GCE instance with the specified name
+func (r *gceProvisioner) instance(spec storage.GCEMachineSpec, req Request, name string) gceInstance {
+	network := spec.Network
+	if network == "" {
+		network = "global/networks/default"
+	}
+	diskSize := spec.DiskSizeGB
+	if diskSize == 0 {
+		diskSize = defaultDiskSizeGB
+	}
+	instance := gceInstance{
+		Name:        name,
+		MachineType: fmt.Sprintf("zones/%v/machineTypes/%v", r.spec.Zone, spec.MachineType),
+		Disks: []gceDisk{{
+			Boot:       true,
+			AutoDelete: true,
+			InitializeParams: gceDiskParams{
+				SourceImage: spec.Image,
+				DiskSizeGb:  fmt.Sprint(diskSize),
+			},
+		}},
+		NetworkInterfaces: []gceNetworkInterface{{
+			Network:       network,
+			Subnetwork:    spec.Subnetwork,
+			AccessConfigs: []gceAccessConfig{{Type: "ONE_TO_ONE_NAT"}},
+		}},
+		Metadata: gceMetadata{
+			Items: []gceMetadataItem{{Key: "startup-script", Value: req.Bootstrap}},
+		},
+		Labels: map[string]string{
+			"kubernetescluster": invalidNameChars.ReplaceAllString(strings.ToLower(req.ClusterName), "-"),
+		},
+	}
+	if len(spec.Tags) != 0 {
+		instance.Tags = &gceTags{Items: spec.Tags}
+	}
+	return instance
+}
+
+type gceInstance struct {
+	Name              string                `json:"name"`
+	MachineType       string                `json:"machineType"`
+	Disks             []gceDisk             `json:"disks"`
+	NetworkInterfaces []gceNetworkInterface `json:"networkInterfaces"`
+	Metadata          gceMetadata           `json:"metadata"`
+	Labels            map[string]string     `json:"labels,omitempty"`
+	Tags              *gceTags              `json:"tags,omitempty"`
+}
+
+type gceDisk struct {
+	Boot             bool          `json:"boot"`
+	AutoDelete       bool          `json:"autoDelete"`
+	InitializeParams gceDiskParams `json:"initializeParams"`
+}
+
+type gceDiskParams struct {
+	SourceImage string `json:"sourceImage"`
+	DiskSizeGb  string `json:"diskSizeGb"`
+}
+
+type gceNetworkInterface struct {
+	Network       string            `json:"network"`
+	Subnetwork    string            `json:"subnetwork,omitempty"`
+	AccessConfigs []gceAccessConfig `json:"accessConfigs"`
+}
+
+type gceAccessConfig struct {
+	Type string `json:"type"`
+}
+
+type gceMetadata struct {
+	Items []gceMetadataItem `json:"items"`
+}
+
+type gceMetadataItem struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type gceTags struct {
+	Items []string `json:"items"`
+}
+
+// gceOperation is the Compute Engine API operation
+type gceOperation struct {
+	Name  string `json:"name"`
+	Error *struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"error,omitempty"`
+}
+
+const (
+	gceEndpoint     = "https://compute.googleapis.com/compute/v1"
+	gceComputeScope = "https://www.googleapis.com/auth/compute"
+	// defaultDiskSizeGB is the default size of the boot disk
+	defaultDiskSizeGB = 100
+)
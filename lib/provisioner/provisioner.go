@@ -0,0 +1,177 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package provisioner creates the machines of new cluster nodes
+// with cloud provider APIs.
+//
+// A provisioned machine runs the bootstrap script on first boot which
+// downloads gravity from the cluster and joins the machine to the cluster.
+package provisioner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+)
+
+// Provisioner creates machines with a cloud provider API
+type Provisioner interface {
+	// Provision creates the requested number of machines and returns
+	// them once the cloud provider has accepted the request
+	Provision(context.Context, Request) ([]Machine, error)
+}
+
+// Request describes the machines to create
+type Request struct {
+	// ClusterName is the name of the cluster the machines join
+	ClusterName string
+	// Profile is the node profile of the machines
+	Profile string
+	// Count is the number of machines to create
+	Count int
+	// Bootstrap is the script the machines run on first boot
+	Bootstrap string
+}
+
+// Check validates the request
+func (r Request) Check() error {
+	if r.ClusterName == "" {
+		return trace.BadParameter("missing cluster name")
+	}
+	if r.Profile == "" {
+		return trace.BadParameter("missing node profile")
+	}
+	if r.Count <= 0 {
+		return trace.BadParameter("node count should be positive, got %v", r.Count)
+	}
+	if r.Bootstrap == "" {
+		return trace.BadParameter("missing bootstrap script")
+	}
+	return nil
+}
+
+// Machine describes a created machine
+type Machine struct {
+	// ID is the cloud provider specific machine identifier
+	ID string `json:"id"`
+	// Name is the machine name
+	Name string `json:"name"`
+}
+
+// String returns a textual representation of this machine
+func (r Machine) String() string {
+	if r.Name == "" || r.Name == r.ID {
+		return r.ID
+	}
+	return fmt.Sprintf("%v (%v)", r.Name, r.ID)
+}
+
+// New returns the provisioner configured with the specified resource
+func New(resource storage.Provisioner) (Provisioner, error) {
+	if err := resource.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch resource.GetType() {
+	case storage.ProvisionerTypeAWS:
+		return newAWS(*resource.GetAWS()), nil
+	case storage.ProvisionerTypeGCE:
+		return newGCE(*resource.GetGCE())
+	case storage.ProvisionerTypeVSphere:
+		return newVSphere(*resource.GetVSphere()), nil
+	}
+	return nil, trace.BadParameter("unsupported provisioner type %q", resource.GetType())
+}
+
+// machineName returns a unique name for a new machine of the specified
+// cluster and node profile that is a valid host name
+func machineName(clusterName, profile string) string {
+	name := fmt.Sprintf("%v-%v-%v", clusterName, profile, uuid.New()[:8])
+	name = invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	// names are limited by the length of a DNS label
+	if len(name) > maxNameLength {
+		name = name[len(name)-maxNameLength:]
+	}
+	return strings.Trim(name, "-")
+}
+
+// profileNotFound returns the error for a node profile the provisioner
+// does not configure
+func profileNotFound(profile string) error {
+	return trace.NotFound("provisioner does not configure node profile %q", profile)
+}
+
+// doJSON sends the request with the specified JSON body and decodes
+// the JSON response into out
+func doJSON(ctx context.Context, client *http.Client, method, url string, header http.Header, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &body)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.ReadError(resp.StatusCode, data)
+	}
+	if out == nil {
+		return nil
+	}
+	return trace.Wrap(decodeJSON(resp.Body, out))
+}
+
+// decodeJSON decodes the JSON from the specified reader into out
+func decodeJSON(r io.Reader, out interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	return trace.Wrap(json.Unmarshal(data, out))
+}
+
+var invalidNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+const maxNameLength = 63
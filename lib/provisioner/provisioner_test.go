@@ -0,0 +1,211 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+func TestProvisioner(t *testing.T) { TestingT(t) }
+
+type ProvisionerSuite struct{}
+
+var _ = Suite(&ProvisionerSuite{})
+
+func (s *ProvisionerSuite) TestMachineName(c *C) {
+	name := machineName("Example.Com", "worker")
+	c.Assert(name, Matches, "example-com-worker-[0-9a-f]{8}")
+
+	name = machineName(strings.Repeat("a", 100), "worker")
+	c.Assert(len(name) <= maxNameLength, Equals, true)
+	c.Assert(name, Matches, "a+-worker-[0-9a-f]{8}")
+}
+
+func (s *ProvisionerSuite) TestAWSLaunchesNamedInstances(c *C) {
+	runner := &fakeRunner{}
+	provisioner := &awsProvisioner{
+		spec: storage.AWSProvisionerSpec{
+			Region: "us-west-2",
+			Profiles: map[string]storage.AWSMachineSpec{
+				"worker": {
+					InstanceType:     "m5.xlarge",
+					ImageID:          "ami-1",
+					SubnetID:         "subnet-1",
+					SecurityGroupIDs: []string{"sg-1"},
+					DiskSizeGB:       200,
+				},
+			},
+		},
+		ec2: runner,
+	}
+	machines, err := provisioner.Provision(context.TODO(), newRequest(2))
+	c.Assert(err, IsNil)
+	c.Assert(machines, HasLen, 2)
+	c.Assert(runner.inputs, HasLen, 2)
+	input := runner.inputs[0]
+	c.Assert(aws.StringValue(input.ImageId), Equals, "ami-1")
+	c.Assert(aws.StringValue(input.SubnetId), Equals, "subnet-1")
+	c.Assert(aws.StringValueSlice(input.SecurityGroupIds), DeepEquals, []string{"sg-1"})
+	c.Assert(aws.Int64Value(input.BlockDeviceMappings[0].Ebs.VolumeSize), Equals, int64(200))
+	userData, err := base64.StdEncoding.DecodeString(aws.StringValue(input.UserData))
+	c.Assert(err, IsNil)
+	c.Assert(string(userData), Equals, "#!/bin/bash\necho join\n")
+	c.Assert(aws.StringValue(input.TagSpecifications[0].Tags[0].Value), Equals, machines[0].Name)
+	c.Assert(machines[0].ID, Equals, "i-1")
+	c.Assert(machines[0].Name, Not(Equals), machines[1].Name)
+
+	_, err = provisioner.Provision(context.TODO(), Request{
+		ClusterName: "example.com",
+		Profile:     "db",
+		Count:       1,
+		Bootstrap:   "#!/bin/bash",
+	})
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *ProvisionerSuite) TestGCECreatesInstances(c *C) {
+	var instances []gceInstance
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, Equals, http.MethodPost)
+		c.Assert(r.URL.Path, Equals, "/projects/project/zones/us-central1-a/instances")
+		var instance gceInstance
+		c.Assert(json.NewDecoder(r.Body).Decode(&instance), IsNil)
+		instances = append(instances, instance)
+		w.Write([]byte(`{"name": "operation-1"}`))
+	}))
+	defer server.Close()
+	provisioner := &gceProvisioner{
+		spec: storage.GCEProvisionerSpec{
+			Project: "project",
+			Zone:    "us-central1-a",
+			Profiles: map[string]storage.GCEMachineSpec{
+				"worker": {MachineType: "n1-standard-4", Image: "projects/centos-cloud/global/images/family/centos-7"},
+			},
+		},
+		endpoint: server.URL,
+		client:   http.DefaultClient,
+	}
+	machines, err := provisioner.Provision(context.TODO(), newRequest(1))
+	c.Assert(err, IsNil)
+	c.Assert(machines, HasLen, 1)
+	c.Assert(instances, HasLen, 1)
+	c.Assert(instances[0].Name, Equals, machines[0].Name)
+	c.Assert(instances[0].MachineType, Equals, "zones/us-central1-a/machineTypes/n1-standard-4")
+	c.Assert(instances[0].Disks[0].InitializeParams.DiskSizeGb, Equals, "100")
+	c.Assert(instances[0].Metadata.Items, DeepEquals, []gceMetadataItem{
+		{Key: "startup-script", Value: "#!/bin/bash\necho join\n"},
+	})
+}
+
+func (s *ProvisionerSuite) TestVSphereClonesTemplate(c *C) {
+	var mu sync.Mutex
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		mu.Unlock()
+		if r.URL.Path == "/api/session" && r.Method == http.MethodPost {
+			user, password, _ := r.BasicAuth()
+			if user != "admin" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`"session-1"`))
+			return
+		}
+		c.Assert(r.Header.Get(vsphereSessionHeader), Equals, "session-1")
+		switch r.URL.RequestURI() {
+		case "/api/vcenter/vm?action=clone":
+			var clone vsphereCloneSpec
+			c.Assert(json.NewDecoder(r.Body).Decode(&clone), IsNil)
+			c.Assert(clone.Source, Equals, "vm-42")
+			c.Assert(clone.Placement, IsNil)
+			w.Write([]byte(`"vm-100"`))
+		case "/api/vcenter/vm/vm-100/guest/customization":
+			var customization vsphereCustomization
+			c.Assert(json.NewDecoder(r.Body).Decode(&customization), IsNil)
+			cloudInit := customization.Spec.ConfigurationSpec.CloudConfig.CloudInit
+			c.Assert(cloudInit.Userdata, Equals, "#!/bin/bash\necho join\n")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	spec := storage.VSphereProvisionerSpec{
+		Server:   server.URL,
+		Username: "admin",
+		Password: "secret",
+		Profiles: map[string]storage.VSphereMachineSpec{
+			"worker": {Template: "vm-42"},
+		},
+	}
+	machines, err := newVSphere(spec).Provision(context.TODO(), newRequest(1))
+	c.Assert(err, IsNil)
+	c.Assert(machines, HasLen, 1)
+	c.Assert(machines[0].ID, Equals, "vm-100")
+	c.Assert(calls, DeepEquals, []string{
+		"POST /api/session",
+		"POST /api/vcenter/vm?action=clone",
+		"PUT /api/vcenter/vm/vm-100/guest/customization",
+		"POST /api/vcenter/vm/vm-100/power?action=start",
+		"DELETE /api/session",
+	})
+
+	spec.Password = "wrong"
+	_, err = newVSphere(spec).Provision(context.TODO(), newRequest(1))
+	c.Assert(trace.IsAccessDenied(err), Equals, true, Commentf("%v", err))
+}
+
+func newRequest(count int) Request {
+	return Request{
+		ClusterName: "example.com",
+		Profile:     "worker",
+		Count:       count,
+		Bootstrap:   "#!/bin/bash\necho join\n",
+	}
+}
+
+type fakeRunner struct {
+	inputs []*ec2.RunInstancesInput
+}
+
+func (r *fakeRunner) RunInstancesWithContext(ctx aws.Context, input *ec2.RunInstancesInput, opts ...request.Option) (*ec2.Reservation, error) {
+	r.inputs = append(r.inputs, input)
+	return &ec2.Reservation{
+		Instances: []*ec2.Instance{{
+			InstanceId: aws.String(fmt.Sprintf("i-%v", len(r.inputs))),
+		}},
+	}, nil
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+func newVSphere(spec storage.VSphereProvisionerSpec) *vsphereProvisioner {
+	endpoint := spec.Server
+	if !strings.HasPrefix(endpoint, "https://") && !strings.HasPrefix(endpoint, "http://") {
+		endpoint = "https://" + endpoint
+	}
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: spec.Insecure,
+		},
+	}
+	return &vsphereProvisioner{
+		spec:     spec,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Transport: transport},
+	}
+}
+
+// vsphereProvisioner creates vSphere virtual machines by cloning a template
+// with the vCenter REST API.
+//
+// The machines are configured with cloud-init guest customization which
+// requires vCenter 7.0 Update 3 or later and cloud-init in the template
+type vsphereProvisioner struct {
+	spec storage.VSphereProvisionerSpec
+	// endpoint is the vCenter server URL
+	endpoint string
+	client   *http.Client
+}
+
+// Provision clones the requested number of virtual machines
+// from the template of the node profile and powers them on
+func (r *vsphereProvisioner) Provision(ctx context.Context, req Request) ([]Machine, error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	spec, ok := r.spec.Profiles[req.Profile]
+	if !ok {
+		return nil, profileNotFound(req.Profile)
+	}
+	header, err := r.login(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer r.logout(header)
+	machines := make([]Machine, 0, req.Count)
+	for i := 0; i < req.Count; i++ {
+		name := machineName(req.ClusterName, req.Profile)
+		id, err := r.create(ctx, header, spec, req, name)
+		if err != nil {
+			return machines, trace.Wrap(err, "failed to create vSphere virtual machine %v", name)
+		}
+		machines = append(machines, Machine{ID: id, Name: name})
+	}
+	return machines, nil
+}
+
+// create clones the template into a new virtual machine with the specified
+// name, configures it to run the bootstrap script and powers it on
+func (r *vsphereProvisioner) create(ctx context.Context, header http.Header, spec storage.VSphereMachineSpec, req Request, name string) (id string, err error) {
+	clone := vsphereCloneSpec{
+		Name:   name,
+		Source: spec.Template,
+	}
+	placement := vspherePlacement{
+		Folder:       spec.Folder,
+		ResourcePool: spec.ResourcePool,
+		Datastore:    spec.Datastore,
+	}
+	// without placement the clone is created next to the template
+	if placement != (vspherePlacement{}) {
+		clone.Placement = &placement
+	}
+	err = doJSON(ctx, r.client, http.MethodPost, r.url("/api/vcenter/vm?action=clone"),
+		header, clone, &id)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	customization := vsphereCustomization{
+		Spec: vsphereCustomizationSpec{
+			ConfigurationSpec: vsphereConfigurationSpec{
+				CloudConfig: vsphereCloudConfig{
+					Type: "CLOUDINIT",
+					CloudInit: vsphereCloudInit{
+						Metadata: fmt.Sprintf("instance-id: %v\nlocal-hostname: %v\n", name, name),
+						Userdata: req.Bootstrap,
+					},
+				},
+			},
+			Interfaces: []interface{}{},
+		},
+	}
+	err = doJSON(ctx, r.client, http.MethodPut, r.url("/api/vcenter/vm/%v/guest/customization", id),
+		header, customization, nil)
+	if err != nil {
+		return id, trace.Wrap(err)
+	}
+	err = doJSON(ctx, r.client, http.MethodPost, r.url("/api/vcenter/vm/%v/power?action=start", id),
+		header, nil, nil)
+	if err != nil {
+		return id, trace.Wrap(err)
+	}
+	return id, nil
+}
+
+// login creates a new API session and returns the header that authenticates
+// the requests with it
+func (r *vsphereProvisioner) login(ctx context.Context) (http.Header, error) {
+	req, err := http.NewRequest(http.MethodPost, r.url("/api/session"), nil)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.SetBasicAuth(r.spec.Username, r.spec.Password)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, trace.AccessDenied("failed to log into vCenter %v: %v",
+			r.spec.Server, resp.Status)
+	}
+	var session string
+	if err := decodeJSON(resp.Body, &session); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return http.Header{vsphereSessionHeader: []string{session}}, nil
+}
+
+// logout deletes the API session
+func (r *vsphereProvisioner) logout(header http.Header) {
+	err := doJSON(context.TODO(), r.client, http.MethodDelete, r.url("/api/session"),
+		header, nil, nil)
+	if err != nil {
+		log.WithError(err).Warn("Failed to log out of vCenter.")
+	}
+}
+
+func (r *vsphereProvisioner) url(format string, args ...interface{}) string {
+	return r.endpoint + fmt.Sprintf(format, args...)
+}
+
+type vsphereCloneSpec struct {
+	Name      string            `json:"name"`
+	Source    string            `json:"source"`
+	Placement *vspherePlacement `json:"placement,omitempty"`
+	PowerOn   bool              `json:"power_on"`
+}
+
+type vspherePlacement struct {
+	Folder       string `json:"folder,omitempty"`
+	ResourcePool string `json:"resource_pool,omitempty"`
+	Datastore    string `json:"datastore,omitempty"`
+}
+
+type vsphereCustomization struct {
+	Spec vsphereCustomizationSpec `json:"spec"`
+}
+
+type vsphereCustomizationSpec struct {
+	ConfigurationSpec vsphereConfigurationSpec `json:"configuration_spec"`
+	GlobalDNSSettings struct{}                 `json:"global_DNS_settings"`
+	Interfaces        []interface{}            `json:"interfaces"`
+}
+
+type vsphereConfigurationSpec struct {
+	CloudConfig vsphereCloudConfig `json:"cloud_config"`
+}
+
+type vsphereCloudConfig struct {
+	Type      string           `json:"type"`
+	CloudInit vsphereCloudInit `json:"cloudinit"`
+}
+
+type vsphereCloudInit struct {
+	Metadata string `json:"metadata"`
+	Userdata string `json:"userdata"`
+}
+
+// vsphereSessionHeader is the header with the vCenter API session ID
+const vsphereSessionHeader = "Vmware-Api-Session-Id"
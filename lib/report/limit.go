@@ -0,0 +1,114 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"io"
+	"sync"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// Limits defines the size limits of the collected diagnostics
+type Limits struct {
+	// MaxFileSize is the maximum size of a single collected file.
+	// Zero means no limit
+	MaxFileSize int64
+	// MaxSize is the maximum total size of all collected files.
+	// Zero means no limit
+	MaxSize int64
+}
+
+// NewLimiter returns a new limiter for the specified limits.
+// The limiter is shared by all report writers that contribute
+// to the same report
+func NewLimiter(limits Limits) *Limiter {
+	return &Limiter{limits: limits}
+}
+
+// Writer returns a new Writer that forwards data to the specified
+// writer while enforcing the limits.
+// Data in excess of the limits is discarded
+func (r *Limiter) Writer(writer Writer) Writer {
+	return func(name string) (io.WriteCloser, error) {
+		w, err := writer(name)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &limitedWriter{name: name, w: w, limiter: r}, nil
+	}
+}
+
+// Limiter enforces the size limits of the collected diagnostics
+type Limiter struct {
+	limits Limits
+	mu     sync.Mutex
+	total  int64
+}
+
+// reserve returns the number of bytes out of size the caller
+// is allowed to write given that it has already written written bytes
+func (r *Limiter) reserve(written, size int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.limits.MaxFileSize > 0 && written+size > r.limits.MaxFileSize {
+		size = r.limits.MaxFileSize - written
+	}
+	if r.limits.MaxSize > 0 && r.total+size > r.limits.MaxSize {
+		size = r.limits.MaxSize - r.total
+	}
+	if size < 0 {
+		size = 0
+	}
+	r.total += size
+	return size
+}
+
+// Write forwards the data to the underlying writer as long as
+// the limits permit.
+// It implements io.Writer
+func (r *limitedWriter) Write(data []byte) (n int, err error) {
+	allowed := r.limiter.reserve(r.written, int64(len(data)))
+	if allowed > 0 {
+		n, err = r.w.Write(data[:allowed])
+		r.written += int64(n)
+		if err != nil {
+			return n, err
+		}
+	}
+	if allowed < int64(len(data)) && !r.truncated {
+		log.Warnf("Truncated %v to %v bytes: report size limit reached.", r.name, r.written)
+		r.truncated = true
+	}
+	// Pretend the whole data has been written to keep the collector going
+	return len(data), nil
+}
+
+// Close closes the underlying writer.
+// It implements io.Closer
+func (r *limitedWriter) Close() error {
+	return r.w.Close()
+}
+
+type limitedWriter struct {
+	name      string
+	w         io.WriteCloser
+	limiter   *Limiter
+	written   int64
+	truncated bool
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/gravitational/trace"
+)
+
+// Redacted is the value that replaces secrets in the report
+const Redacted = "redacted"
+
+// WriteRedactedJSON writes the JSON representation of the specified value
+// to w with the values of all attributes that might contain secrets
+// replaced with Redacted
+func WriteRedactedJSON(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var object interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return trace.Wrap(err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return trace.Wrap(enc.Encode(redact(object)))
+}
+
+// redact recursively replaces the values of secret attributes
+// in the specified decoded JSON object
+func redact(object interface{}) interface{} {
+	switch object := object.(type) {
+	case map[string]interface{}:
+		for key, value := range object {
+			if isSecretAttribute(key) && !isEmpty(value) {
+				object[key] = Redacted
+				continue
+			}
+			object[key] = redact(value)
+		}
+	case []interface{}:
+		for i, value := range object {
+			object[i] = redact(value)
+		}
+	}
+	return object
+}
+
+// isSecretAttribute returns true if the attribute with the specified
+// name might contain a secret
+func isSecretAttribute(name string) bool {
+	name = strings.Replace(strings.ToLower(name), "_", "", -1)
+	for _, secret := range secretAttributes {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmpty(value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	}
+	return false
+}
+
+// secretAttributes lists the substrings of the attribute names
+// (in lower case and without underscores) whose values are redacted
+var secretAttributes = []string{
+	"password",
+	"secret",
+	"token",
+	"license",
+	"privatekey",
+	"keypem",
+	"apikey",
+	"accesskey",
+	"credentials",
+}
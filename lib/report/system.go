@@ -37,6 +37,7 @@ func SystemInfo() Collectors {
 	add(syslogExportLogs())
 	add(systemFileLogs()...)
 	add(planetLogs()...)
+	add(registryInfo()...)
 	add(bashHistoryCollector{})
 
 	return collectors
@@ -79,6 +80,21 @@ func planetServices() Collectors {
 	}
 }
 
+// registryInfo fetches the statistics of the local docker registry:
+// the disk space used by the registry and the list of its repositories
+func registryInfo() Collectors {
+	const repositories = `
+#!/bin/bash
+cd %v 2> /dev/null || exit 0
+find . -type d -name _manifests -prune | sed -e 's|^\./||' -e 's|/_manifests$||' | sort`
+	dir := defaults.InGravity("planet/registry")
+	return Collectors{
+		Script("registry-usage", fmt.Sprintf("du -sh %v 2> /dev/null || true", dir)),
+		Script("registry-repositories", fmt.Sprintf(repositories,
+			filepath.Join(dir, "docker/registry/v2/repositories"))),
+	}
+}
+
 // syslogExportLogs fetches logs for gravity binary invocations
 // (including installation logs)
 func syslogExportLogs() Collector {
@@ -37,35 +37,59 @@ import (
 // The specified directory is expected to be on the same filesystem
 // as the Docker graph directory (which might not exist at this point).
 func ValidateDocker(d Docker, dir string) (failed []*pb.Probe, err error) {
-	var checkers []health.Checker
+	checkers := append(DockerKernelCheckers(d), DockerFilesystemCheckers(d, dir)...)
+	all := monitoring.NewCompositeChecker("docker", checkers)
+	var probes health.Probes
 
-	checkers = append(checkers,
-		monitoring.GetStorageDriverBootConfigParams(d.StorageDriver))
+	all.Check(context.TODO(), &probes)
+	return probes.GetFailed(), nil
+}
 
+// DockerKernelCheckers returns checkers that verify the kernel
+// supports the specified Docker storage driver
+func DockerKernelCheckers(d Docker) []health.Checker {
+	checkers := []health.Checker{
+		monitoring.GetStorageDriverBootConfigParams(d.StorageDriver),
+	}
 	switch d.StorageDriver {
 	case constants.DockerStorageDriverOverlay, constants.DockerStorageDriverOverlay2:
-		checkers = append(checkers,
-			monitoring.NewKernelModuleChecker(moduleName("overlay")),
-			monitoring.NewDTypeChecker(dir),
-		)
+		checkers = append(checkers, monitoring.NewKernelModuleChecker(moduleName("overlay")))
 	}
+	return checkers
+}
 
-	all := monitoring.NewCompositeChecker("docker", checkers)
-	var probes health.Probes
-
-	all.Check(context.TODO(), &probes)
-	return probes.GetFailed(), nil
+// DockerFilesystemCheckers returns checkers that verify the filesystem
+// of the specified directory is compatible with the Docker storage driver
+func DockerFilesystemCheckers(d Docker, dir string) []health.Checker {
+	switch d.StorageDriver {
+	case constants.DockerStorageDriverOverlay, constants.DockerStorageDriverOverlay2:
+		return []health.Checker{monitoring.NewDTypeChecker(dir)}
+	}
+	return nil
 }
 
 // ValidateKubelet will check kubelet configuration
 func ValidateKubelet(profile NodeProfile, manifest Manifest) (failed []*pb.Probe) {
-	hairpinMode := manifest.HairpinMode(profile)
-	if hairpinMode != constants.HairpinModePromiscuousBridge {
+	checkers := append(KubeletKernelCheckers(profile, manifest),
+		KubeletCgroupCheckers(profile, manifest)...)
+	if len(checkers) == 0 {
 		// No validation required
 		return nil
 	}
+	checker := monitoring.NewCompositeChecker("kubelet", checkers)
 
-	checkers := append([]health.Checker{},
+	var probes health.Probes
+	checker.Check(context.TODO(), &probes)
+	return probes.GetFailed()
+}
+
+// KubeletKernelCheckers returns checkers that verify the kernel modules
+// required by kubelet for the specified node profile
+func KubeletKernelCheckers(profile NodeProfile, manifest Manifest) []health.Checker {
+	if manifest.HairpinMode(profile) != constants.HairpinModePromiscuousBridge {
+		return nil
+	}
+	return []health.Checker{
 		monitoring.NewKernelModuleChecker(
 			moduleName("ebtables"),
 			moduleName("ip_tables"),
@@ -73,24 +97,60 @@ func ValidateKubelet(profile NodeProfile, manifest Manifest) (failed []*pb.Probe
 			moduleName("iptable_nat"),
 			moduleName("br_netfilter", "bridge"),
 		),
-		monitoring.NewCGroupChecker("cpu", "cpuacct", "cpuset", "memory"),
-	)
-	checker := monitoring.NewCompositeChecker("kubelet", checkers)
+	}
+}
 
-	var probes health.Probes
-	checker.Check(context.TODO(), &probes)
-	return probes.GetFailed()
+// GPUCheckers returns checkers that verify the kernel modules
+// of the GPU driver required by the profile are loaded
+func GPUCheckers(reqs Requirements) []health.Checker {
+	if !reqs.GPU.IsRequired() {
+		return nil
+	}
+	return []health.Checker{
+		monitoring.NewKernelModuleChecker(moduleName(reqs.GPU.GetVendor())),
+	}
+}
+
+// KubeletCgroupCheckers returns checkers that verify the cgroup
+// mounts required by kubelet for the specified node profile
+func KubeletCgroupCheckers(profile NodeProfile, manifest Manifest) []health.Checker {
+	if manifest.HairpinMode(profile) != constants.HairpinModePromiscuousBridge {
+		return nil
+	}
+	return []health.Checker{
+		monitoring.NewCGroupChecker("cpu", "cpuacct", "cpuset", "memory"),
+	}
 }
 
 // ValidateRequirements will assess local node to match requirements
 func ValidateRequirements(reqs Requirements, stateDir string) (failed []*pb.Probe, err error) {
-	var checkers []health.Checker
-	checkers = append(checkers, monitoring.NewHostChecker(
-		monitoring.HostConfig{
-			MinCPU:      reqs.CPU.Min,
-			MinRAMBytes: reqs.RAM.Min.Bytes(),
-		}),
-	)
+	checkers := HostCheckers(reqs)
+	portCheckers, err := PortCheckers(reqs)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	checkers = append(checkers, portCheckers...)
+	checkers = append(checkers, VolumeCheckers(reqs, stateDir)...)
+	for _, check := range reqs.CustomChecks {
+		checkers = append(checkers, check.Checker())
+	}
+
+	all := monitoring.NewCompositeChecker("common requirements", checkers)
+	var probes health.Probes
+
+	all.Check(context.TODO(), &probes)
+	return probes.GetFailed(), nil
+}
+
+// HostCheckers returns checkers that verify CPU, RAM and OS requirements
+func HostCheckers(reqs Requirements) []health.Checker {
+	checkers := []health.Checker{
+		monitoring.NewHostChecker(
+			monitoring.HostConfig{
+				MinCPU:      reqs.CPU.Min,
+				MinRAMBytes: reqs.RAM.Min.Bytes(),
+			}),
+	}
 
 	var releases []monitoring.OSRelease
 	for _, os := range reqs.OS {
@@ -104,7 +164,12 @@ func ValidateRequirements(reqs Requirements, stateDir string) (failed []*pb.Prob
 	if len(releases) > 0 {
 		checkers = append(checkers, monitoring.NewOSChecker(releases...))
 	}
+	return checkers
+}
 
+// PortCheckers returns checkers that verify availability of the ports
+// required by the profile
+func PortCheckers(reqs Requirements) ([]health.Checker, error) {
 	var portRanges = make([]monitoring.PortRange, 0, len(reqs.Network.Ports))
 	for _, port := range reqs.Network.Ports {
 		portRange, err := parsePortRanges(port.Protocol, port.Ranges)
@@ -113,8 +178,12 @@ func ValidateRequirements(reqs Requirements, stateDir string) (failed []*pb.Prob
 		}
 		portRanges = append(portRanges, portRange...)
 	}
-	checkers = append(checkers, monitoring.NewPortChecker(portRanges...))
+	return []health.Checker{monitoring.NewPortChecker(portRanges...)}, nil
+}
 
+// VolumeCheckers returns checkers that verify capacity, filesystem and
+// throughput of the volumes required by the profile
+func VolumeCheckers(reqs Requirements, stateDir string) (checkers []health.Checker) {
 	for _, vol := range reqs.Volumes {
 		if vol.Path == defaults.GravityDir {
 			// Use the correct system directory in the test
@@ -132,20 +201,15 @@ func ValidateRequirements(reqs Requirements, stateDir string) (failed []*pb.Prob
 			MinFreeBytes:      vol.Capacity.Bytes(),
 		}))
 	}
+	return checkers
+}
 
-	for _, check := range reqs.CustomChecks {
-		checkers = append(checkers,
-			monitoring.NewScriptChecker(monitoring.Script{
-				Reader:      strings.NewReader(check.Script),
-				Description: check.Description,
-			}))
-	}
-
-	all := monitoring.NewCompositeChecker("common requirements", checkers)
-	var probes health.Probes
-
-	all.Check(context.TODO(), &probes)
-	return probes.GetFailed(), nil
+// Checker returns a health checker that executes this custom check script
+func (r CustomCheck) Checker() health.Checker {
+	return monitoring.NewScriptChecker(monitoring.Script{
+		Reader:      strings.NewReader(r.Script),
+		Description: r.Description,
+	})
 }
 
 // shouldCheckVolume determines if this volume should be checked
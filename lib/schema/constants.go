@@ -33,6 +33,8 @@ const (
 	ProviderOnPrem = "onprem"
 	// ProviderGCE defines Google Compute Engine provider
 	ProviderGCE = "gce"
+	// ProviderAzure defines Microsoft Azure provider
+	ProviderAzure = "azure"
 
 	// ProvisionerAWSTerraform defines an operation provisioner based on terraform
 	ProvisionerAWSTerraform = "aws_terraform"
@@ -45,6 +47,17 @@ const (
 	NetworkingCalico = "calico"
 	// NetworkingFlannel defines a type of networking using Flannel VXLAN
 	NetworkingFlannel = "vxlan"
+	// NetworkingWireguard defines a type of networking using the
+	// WireGuard-encrypted overlay network
+	NetworkingWireguard = "wireguard"
+
+	// CalicoIPIPAlways enables IP-in-IP encapsulation for all pod traffic
+	CalicoIPIPAlways = "Always"
+	// CalicoIPIPCrossSubnet enables IP-in-IP encapsulation only for
+	// pod traffic crossing subnet boundaries
+	CalicoIPIPCrossSubnet = "CrossSubnet"
+	// CalicoIPIPNever disables IP-in-IP encapsulation
+	CalicoIPIPNever = "Never"
 
 	// DisplayRole defines a role used to identify a server instance in the inventory
 	// management console
@@ -105,6 +118,13 @@ const (
 	OpsCenterNode = "node"
 	// OpsCenterFlavor is the Ops Center app flavor
 	OpsCenterFlavor = "single"
+
+	// GPUVendorNvidia is the NVIDIA GPU vendor
+	GPUVendorNvidia = "nvidia"
+
+	// FlavorAuto is the name of the pseudo-flavor that selects the install
+	// flavor based on the hardware of the discovered nodes
+	FlavorAuto = "auto"
 )
 
 // ServiceRole defines the type for the node service role
@@ -28,13 +28,18 @@ import (
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWS) DeepCopyInto(out *AWS) {
 	*out = *in
-	out.Networking = in.Networking
+	in.Networking.DeepCopyInto(&out.Networking)
 	if in.Regions != nil {
 		in, out := &in.Regions, &out.Regions
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 	in.IAMPolicy.DeepCopyInto(&out.IAMPolicy)
+	if in.Terraform != nil {
+		in, out := &in.Terraform, &out.Terraform
+		*out = new(Terraform)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -51,6 +56,11 @@ func (in *AWS) DeepCopy() *AWS {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Azure) DeepCopyInto(out *Azure) {
 	*out = *in
+	if in.Terraform != nil {
+		in, out := &in.Terraform, &out.Terraform
+		*out = new(Terraform)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -80,6 +90,22 @@ func (in *CPU) DeepCopy() *CPU {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CalicoNetworking) DeepCopyInto(out *CalicoNetworking) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CalicoNetworking.
+func (in *CalicoNetworking) DeepCopy() *CalicoNetworking {
+	if in == nil {
+		return nil
+	}
+	out := new(CalicoNetworking)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigurationExtension) DeepCopyInto(out *ConfigurationExtension) {
 	*out = *in
@@ -357,10 +383,52 @@ func (in *Flavors) DeepCopy() *Flavors {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCE) DeepCopyInto(out *GCE) {
+	*out = *in
+	if in.Terraform != nil {
+		in, out := &in.Terraform, &out.Terraform
+		*out = new(Terraform)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCE.
+func (in *GCE) DeepCopy() *GCE {
+	if in == nil {
+		return nil
+	}
+	out := new(GCE)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GPU) DeepCopyInto(out *GPU) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GPU.
+func (in *GPU) DeepCopy() *GPU {
+	if in == nil {
+		return nil
+	}
+	out := new(GPU)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Generic) DeepCopyInto(out *Generic) {
 	*out = *in
-	out.Networking = in.Networking
+	in.Networking.DeepCopyInto(&out.Networking)
+	if in.Terraform != nil {
+		in, out := &in.Terraform, &out.Terraform
+		*out = new(Terraform)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -374,6 +442,22 @@ func (in *Generic) DeepCopy() *Generic {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPReadinessCheck) DeepCopyInto(out *HTTPReadinessCheck) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPReadinessCheck.
+func (in *HTTPReadinessCheck) DeepCopy() *HTTPReadinessCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPReadinessCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Header) DeepCopyInto(out *Header) {
 	*out = *in
@@ -546,6 +630,15 @@ func (in *Hooks) DeepCopyInto(out *Hooks) {
 			**out = **in
 		}
 	}
+	if in.NodeUpdating != nil {
+		in, out := &in.NodeUpdating, &out.NodeUpdating
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(Hook)
+			**out = **in
+		}
+	}
 	if in.Rollback != nil {
 		in, out := &in.Rollback, &out.Rollback
 		if *in == nil {
@@ -573,6 +666,15 @@ func (in *Hooks) DeepCopyInto(out *Hooks) {
 			**out = **in
 		}
 	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(Hook)
+			**out = **in
+		}
+	}
 	if in.Info != nil {
 		in, out := &in.Info, &out.Info
 		if *in == nil {
@@ -801,7 +903,6 @@ func (in *Manifest) DeepCopyInto(out *Manifest) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	in.Dependencies.DeepCopyInto(&out.Dependencies)
 	if in.Installer != nil {
 		in, out := &in.Installer, &out.Installer
 		if *in == nil {
@@ -845,6 +946,13 @@ func (in *Manifest) DeepCopyInto(out *Manifest) {
 			(*in).DeepCopyInto(*out)
 		}
 	}
+	if in.ReadinessChecks != nil {
+		in, out := &in.ReadinessChecks, &out.ReadinessChecks
+		*out = make([]ReadinessCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.SystemOptions != nil {
 		in, out := &in.SystemOptions, &out.SystemOptions
 		if *in == nil {
@@ -943,6 +1051,24 @@ func (in *Network) DeepCopy() *Network {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Networking) DeepCopyInto(out *Networking) {
 	*out = *in
+	if in.Calico != nil {
+		in, out := &in.Calico, &out.Calico
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(CalicoNetworking)
+			**out = **in
+		}
+	}
+	if in.Wireguard != nil {
+		in, out := &in.Wireguard, &out.Wireguard
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(WireguardNetworking)
+			**out = **in
+		}
+	}
 	return
 }
 
@@ -974,6 +1100,11 @@ func (in *NodeProfile) DeepCopyInto(out *NodeProfile) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Reserved != nil {
+		in, out := &in.Reserved, &out.Reserved
+		*out = new(ResourceReservations)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Providers.DeepCopyInto(&out.Providers)
 	return
 }
@@ -1068,12 +1199,29 @@ func (in *Port) DeepCopy() *Port {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusReadinessCheck) DeepCopyInto(out *PrometheusReadinessCheck) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusReadinessCheck.
+func (in *PrometheusReadinessCheck) DeepCopy() *PrometheusReadinessCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusReadinessCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Providers) DeepCopyInto(out *Providers) {
 	*out = *in
 	in.AWS.DeepCopyInto(&out.AWS)
-	out.Azure = in.Azure
-	out.Generic = in.Generic
+	in.Azure.DeepCopyInto(&out.Azure)
+	in.GCE.DeepCopyInto(&out.GCE)
+	in.Generic.DeepCopyInto(&out.Generic)
 	return
 }
 
@@ -1103,11 +1251,55 @@ func (in *RAM) DeepCopy() *RAM {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadinessCheck) DeepCopyInto(out *ReadinessCheck) {
+	*out = *in
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(HTTPReadinessCheck)
+			**out = **in
+		}
+	}
+	if in.Resource != nil {
+		in, out := &in.Resource, &out.Resource
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ResourceReadinessCheck)
+			**out = **in
+		}
+	}
+	if in.Prometheus != nil {
+		in, out := &in.Prometheus, &out.Prometheus
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(PrometheusReadinessCheck)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessCheck.
+func (in *ReadinessCheck) DeepCopy() *ReadinessCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadinessCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Requirements) DeepCopyInto(out *Requirements) {
 	*out = *in
 	out.CPU = in.CPU
 	out.RAM = in.RAM
+	out.GPU = in.GPU
 	if in.OS != nil {
 		in, out := &in.OS, &out.OS
 		*out = make([]OS, len(*in))
@@ -1136,6 +1328,52 @@ func (in *Requirements) DeepCopy() *Requirements {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceReadinessCheck) DeepCopyInto(out *ResourceReadinessCheck) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceReadinessCheck.
+func (in *ResourceReadinessCheck) DeepCopy() *ResourceReadinessCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceReadinessCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceReservations) DeepCopyInto(out *ResourceReservations) {
+	*out = *in
+	if in.System != nil {
+		in, out := &in.System, &out.System
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Kube != nil {
+		in, out := &in.Kube, &out.Kube
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceReservations.
+func (in *ResourceReservations) DeepCopy() *ResourceReservations {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceReservations)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Runtime) DeepCopyInto(out *Runtime) {
 	*out = *in
@@ -1153,6 +1391,70 @@ func (in *Runtime) DeepCopy() *Runtime {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceUser) DeepCopyInto(out *ServiceUser) {
+	*out = *in
+	if in.UID != nil {
+		in, out := &in.UID, &out.UID
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int)
+			**out = **in
+		}
+	}
+	if in.GID != nil {
+		in, out := &in.GID, &out.GID
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceUser.
+func (in *ServiceUser) DeepCopy() *ServiceUser {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SystemDependencies) DeepCopyInto(out *SystemDependencies) {
+	*out = *in
+	if in.Runtime != nil {
+		in, out := &in.Runtime, &out.Runtime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(Dependency)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.IntermediateRuntimes != nil {
+		in, out := &in.IntermediateRuntimes, &out.IntermediateRuntimes
+		*out = make([]Dependency, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SystemDependencies.
+func (in *SystemDependencies) DeepCopy() *SystemDependencies {
+	if in == nil {
+		return nil
+	}
+	out := new(SystemDependencies)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SystemOptions) DeepCopyInto(out *SystemOptions) {
 	*out = *in
@@ -1192,6 +1494,21 @@ func (in *SystemOptions) DeepCopyInto(out *SystemOptions) {
 			(*in).DeepCopyInto(*out)
 		}
 	}
+	in.Dependencies.DeepCopyInto(&out.Dependencies)
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceUser != nil {
+		in, out := &in.ServiceUser, &out.ServiceUser
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ServiceUser)
+			(*in).DeepCopyInto(*out)
+		}
+	}
 	return
 }
 
@@ -1205,6 +1522,29 @@ func (in *SystemOptions) DeepCopy() *SystemOptions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Terraform) DeepCopyInto(out *Terraform) {
+	*out = *in
+	if in.Variables != nil {
+		in, out := &in.Variables, &out.Variables
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Terraform.
+func (in *Terraform) DeepCopy() *Terraform {
+	if in == nil {
+		return nil
+	}
+	out := new(Terraform)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Volume) DeepCopyInto(out *Volume) {
 	*out = *in
@@ -1243,3 +1583,19 @@ func (in *Volume) DeepCopy() *Volume {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WireguardNetworking) DeepCopyInto(out *WireguardNetworking) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WireguardNetworking.
+func (in *WireguardNetworking) DeepCopy() *WireguardNetworking {
+	if in == nil {
+		return nil
+	}
+	out := new(WireguardNetworking)
+	in.DeepCopyInto(out)
+	return out
+}
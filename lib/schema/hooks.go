@@ -18,6 +18,7 @@ package schema
 
 import (
 	"reflect"
+	"time"
 
 	"github.com/gravitational/trace"
 
@@ -57,12 +58,17 @@ type Hooks struct {
 	Updating *Hook `json:"update,omitempty"`
 	// Updated is called after successful update
 	Updated *Hook `json:"postUpdate,omitempty"`
+	// NodeUpdating is called before each node is updated
+	NodeUpdating *Hook `json:"preNodeUpdate,omitempty"`
 	// Rollback performs application rollback after an unsuccessful update
 	Rollback *Hook `json:"rollback,omitempty"`
 	// RolledBack is called after successful rollback
 	RolledBack *Hook `json:"postRollback,omitempty"`
 	// Status is called every minute to check application status
 	Status *Hook `json:"status,omitempty"`
+	// HealthCheck verifies the application health after each node
+	// and after the application itself have been updated
+	HealthCheck *Hook `json:"healthCheck,omitempty"`
 	// Info is used to obtain application information
 	Info *Hook `json:"info,omitempty"`
 	// LicenseUpdated is called after license update
@@ -105,6 +111,14 @@ type Hook struct {
 	Type HookType `json:"type,omitempty"`
 	// Job is a URL of (file:// or http://) or a literal value of a k8s job
 	Job string `json:"job,omitempty"`
+	// Timeout is the optional limit on the hook running time, e.g. 5m.
+	// Defaults to the hook job deadline
+	Timeout string `json:"timeout,omitempty"`
+	// Retries is the number of times to retry the hook if it fails
+	Retries int `json:"retries,omitempty"`
+	// OnFailure defines what happens to the operation if the hook fails
+	// after all retries: it is either aborted (default) or continues
+	OnFailure HookFailurePolicy `json:"onFailure,omitempty"`
 }
 
 // Empty determines if the hook set is empty
@@ -112,6 +126,50 @@ func (h Hook) Empty() bool {
 	return h.Job == ""
 }
 
+// Check validates the hook timeout and failure policy
+func (h Hook) Check() error {
+	if _, err := h.GetTimeout(); err != nil {
+		return trace.Wrap(err)
+	}
+	if h.Retries < 0 {
+		return trace.BadParameter("retries cannot be negative")
+	}
+	switch h.OnFailure {
+	case "", HookFailureAbort, HookFailureContinue:
+	default:
+		return trace.BadParameter("unsupported failure policy %q, supported are %q and %q",
+			h.OnFailure, HookFailureAbort, HookFailureContinue)
+	}
+	return nil
+}
+
+// GetTimeout returns the hook timeout or 0 if the hook does not specify one
+func (h Hook) GetTimeout() (time.Duration, error) {
+	if h.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(h.Timeout)
+	if err != nil {
+		return 0, trace.BadParameter("invalid timeout %q: %v", h.Timeout, err)
+	}
+	if timeout <= 0 {
+		return 0, trace.BadParameter("timeout %q should be positive", h.Timeout)
+	}
+	return timeout, nil
+}
+
+// Attempts returns the number of times the hook is run before it is
+// considered failed
+func (h Hook) Attempts() int {
+	return h.Retries + 1
+}
+
+// ContinueOnFailure returns true if the operation should proceed
+// when the hook fails
+func (h Hook) ContinueOnFailure() bool {
+	return h.OnFailure == HookFailureContinue
+}
+
 // GetJob parses the hook's string with job spec and returns a job object
 func (h Hook) GetJob() (*v1.Job, error) {
 	if h.Job == "" {
@@ -134,6 +192,16 @@ func (h *Hook) SetJob(job v1.Job) error {
 	return nil
 }
 
+// HookFailurePolicy defines the operation behavior when a hook fails
+type HookFailurePolicy string
+
+const (
+	// HookFailureAbort fails the operation if the hook fails
+	HookFailureAbort HookFailurePolicy = "abort"
+	// HookFailureContinue proceeds with the operation if the hook fails
+	HookFailureContinue HookFailurePolicy = "continue"
+)
+
 // HookType defines the application hook type
 type HookType string
 
@@ -160,6 +228,8 @@ const (
 	HookUpdate HookType = "update"
 	// HookUpdated defines the post application update hook
 	HookUpdated HookType = "postUpdate"
+	// HookNodeUpdating defines the hook that runs before each node is updated
+	HookNodeUpdating HookType = "preNodeUpdate"
 	// HookRollback defines the application rollback hook
 	HookRollback HookType = "rollback"
 	// HookRolledBack defines the application post rollback hook
@@ -174,6 +244,9 @@ const (
 	HookNodeRemoved HookType = "postNodeRemove"
 	// HookStatus defines the application status hook
 	HookStatus HookType = "status"
+	// HookHealthCheck defines the hook that verifies the application
+	// health during the update
+	HookHealthCheck HookType = "healthCheck"
 	// HookInfo defines the application service info hook
 	HookInfo HookType = "info"
 	// HookLicenseUpdated defines the license update hook
@@ -218,6 +291,7 @@ func AllHooks() []HookType {
 		HookBeforeUpdate,
 		HookUpdate,
 		HookUpdated,
+		HookNodeUpdating,
 		HookRollback,
 		HookRolledBack,
 		HookNodeAdding,
@@ -225,6 +299,7 @@ func AllHooks() []HookType {
 		HookNodeRemoving,
 		HookNodeRemoved,
 		HookStatus,
+		HookHealthCheck,
 		HookInfo,
 		HookLicenseUpdated,
 		HookStart,
@@ -268,6 +343,8 @@ func HookFromString(hookType HookType, manifest Manifest) (*Hook, error) {
 		hook = manifest.Hooks.Updating
 	case HookUpdated:
 		hook = manifest.Hooks.Updated
+	case HookNodeUpdating:
+		hook = manifest.Hooks.NodeUpdating
 	case HookRollback:
 		hook = manifest.Hooks.Rollback
 	case HookRolledBack:
@@ -282,6 +359,8 @@ func HookFromString(hookType HookType, manifest Manifest) (*Hook, error) {
 		hook = manifest.Hooks.NodeRemoved
 	case HookStatus:
 		hook = manifest.Hooks.Status
+	case HookHealthCheck:
+		hook = manifest.Hooks.HealthCheck
 	case HookInfo:
 		hook = manifest.Hooks.Info
 	case HookLicenseUpdated:
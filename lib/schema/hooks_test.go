@@ -17,7 +17,9 @@ limitations under the License.
 package schema
 
 import (
+	"fmt"
 	"reflect"
+	"time"
 
 	. "gopkg.in/check.v1"
 	batchv1 "k8s.io/api/batch/v1"
@@ -135,3 +137,28 @@ hooks:
 	c.Assert(err, IsNil)
 	c.Assert(installJob, DeepEquals, job)
 }
+
+func (r *HooksSuite) TestHookPolicy(c *C) {
+	const manifest = `
+apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: test
+  resourceVersion: 0.0.1
+hooks:
+  healthCheck:
+    job: file://path/to/job.yaml
+    timeout: %v
+    retries: 2
+    onFailure: continue`
+	m, err := ParseManifestYAML([]byte(fmt.Sprintf(manifest, "5m")))
+	c.Assert(err, IsNil)
+	timeout, err := m.Hooks.HealthCheck.GetTimeout()
+	c.Assert(err, IsNil)
+	c.Assert(timeout, Equals, 5*time.Minute)
+	c.Assert(m.Hooks.HealthCheck.Attempts(), Equals, 3)
+	c.Assert(m.Hooks.HealthCheck.ContinueOnFailure(), Equals, true)
+
+	_, err = ParseManifestYAML([]byte(fmt.Sprintf(manifest, "5 minutes")))
+	c.Assert(err, ErrorMatches, `.*invalid timeout.*`)
+}
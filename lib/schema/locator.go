@@ -0,0 +1,189 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"bufio"
+	"bytes"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// position is a location in the YAML document
+type position struct {
+	// line is the 1-based line number
+	line int
+	// column is the 1-based column number
+	column int
+}
+
+// yamlLocator maps JSON pointers of the fields in a YAML document
+// to their locations in the document.
+//
+// Only block-style mappings and sequences are indexed: fields nested
+// inside flow collections resolve to the location of their closest
+// block-style ancestor
+type yamlLocator struct {
+	positions map[string]position
+}
+
+// newYAMLLocator indexes the field locations of the provided YAML document
+func newYAMLLocator(data []byte) *yamlLocator {
+	locator := &yamlLocator{positions: make(map[string]position)}
+	root := &yamlFrame{indent: -1}
+	stack := []*yamlFrame{root}
+	// blockIndent is the indentation of the key that started a block scalar,
+	// lines indented deeper than it belong to the scalar
+	blockIndent := -1
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		content := strings.TrimLeft(text, " ")
+		indent := len(text) - len(content)
+		if blockIndent >= 0 {
+			if content == "" || indent > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+		if content == "" || strings.HasPrefix(content, "#") ||
+			strings.HasPrefix(content, "---") || strings.HasPrefix(content, "...") {
+			continue
+		}
+		for len(stack) > 1 {
+			top := stack[len(stack)-1]
+			if top.indent < indent || (top.indent == indent && top.isKey && isSequenceItem(content)) {
+				break
+			}
+			stack = stack[:len(stack)-1]
+		}
+		for {
+			parent := stack[len(stack)-1]
+			if isSequenceItem(content) {
+				path := parent.path + "/" + strconv.Itoa(parent.items)
+				parent.items++
+				locator.positions[path] = position{line: line, column: indent + 1}
+				stack = append(stack, &yamlFrame{indent: indent, path: path})
+				item := strings.TrimLeft(content[1:], " ")
+				indent += len(content) - len(item)
+				content = item
+				if content == "" {
+					break
+				}
+				continue
+			}
+			key, value, ok := splitMappingKey(content)
+			if !ok {
+				break
+			}
+			path := parent.path + "/" + escapePointerToken(key)
+			locator.positions[path] = position{line: line, column: indent + 1}
+			stack = append(stack, &yamlFrame{indent: indent, path: path, isKey: true})
+			if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+				blockIndent = indent
+			}
+			break
+		}
+	}
+	return locator
+}
+
+// locate returns the location of the field specified with the JSON pointer.
+// If the field has not been indexed, the location of its closest indexed
+// ancestor is returned
+func (r *yamlLocator) locate(pointer string) (pos position, ok bool) {
+	path := strings.TrimPrefix(pointer, "#")
+	for path != "" {
+		if pos, ok = r.positions[path]; ok {
+			return pos, true
+		}
+		path = path[:strings.LastIndex(path, "/")]
+	}
+	return position{}, false
+}
+
+// yamlFrame describes a mapping key or a sequence item whose value
+// may span multiple lines
+type yamlFrame struct {
+	// indent is the indentation of the key or the sequence item indicator
+	indent int
+	// path is the JSON pointer to the value
+	path string
+	// isKey is true if the frame describes a mapping key
+	isKey bool
+	// items is the number of sequence items seen in the value
+	items int
+}
+
+// isSequenceItem returns true if the provided line content starts
+// a block sequence item
+func isSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// splitMappingKey splits the provided line content into a mapping key
+// and the (possibly empty) value that follows it.
+// Returns false if the content does not start with a mapping key
+func splitMappingKey(content string) (key, value string, ok bool) {
+	if strings.HasPrefix(content, `"`) || strings.HasPrefix(content, "'") {
+		quote := content[0]
+		end := strings.IndexByte(content[1:], quote)
+		if end < 0 {
+			return "", "", false
+		}
+		rest := content[end+2:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		key = content[1 : end+1]
+		if quote == '"' {
+			if unquoted, err := strconv.Unquote(content[:end+2]); err == nil {
+				key = unquoted
+			}
+		}
+		return key, strings.TrimSpace(rest[1:]), true
+	}
+	if strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
+		return "", "", false
+	}
+	if strings.HasSuffix(content, ":") {
+		return strings.TrimSpace(content[:len(content)-1]), "", true
+	}
+	index := strings.Index(content, ": ")
+	if index < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(content[:index]), strings.TrimSpace(content[index+2:]), true
+}
+
+// escapePointerToken escapes the provided token the same way
+// the schema validator does when it builds JSON pointers
+func escapePointerToken(token string) string {
+	token = strings.Replace(token, "~", "~0", -1)
+	token = strings.Replace(token, "/", "~1", -1)
+	return url.PathEscape(token)
+}
+
+// unescapePointerToken reverses escapePointerToken
+func unescapePointerToken(token string) string {
+	if unescaped, err := url.PathUnescape(token); err == nil {
+		token = unescaped
+	}
+	token = strings.Replace(token, "~1", "/", -1)
+	return strings.Replace(token, "~0", "~", -1)
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -32,6 +33,7 @@ import (
 
 	"github.com/gravitational/trace"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubeschema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -67,6 +69,9 @@ type Manifest struct {
 	License *License `json:"license,omitempty"`
 	// Hooks contains application-defined hooks
 	Hooks *Hooks `json:"hooks,omitempty"`
+	// ReadinessChecks lists the conditions the application must satisfy
+	// at the end of install or upgrade before the operation is completed
+	ReadinessChecks []ReadinessCheck `json:"readinessChecks,omitempty"`
 	// SystemOptions contains various global settings
 	SystemOptions *SystemOptions `json:"systemOptions,omitempty"`
 	// Extensions allows to enable/disable various custom features
@@ -205,11 +210,19 @@ func (m Manifest) SetupEndpoint() *Endpoint {
 
 // GetNetworkType looks up network type for the specified provider / provisioner pair
 func (m Manifest) GetNetworkType(provider, provisioner string) string {
+	return m.GetNetworking(provider, provisioner).Type
+}
+
+// GetNetworking looks up networking configuration for the specified provider / provisioner pair
+func (m Manifest) GetNetworking(provider, provisioner string) Networking {
+	if m.Providers == nil {
+		return Networking{}
+	}
 	switch provider {
 	case ProviderAWS, ProvisionerAWSTerraform:
-		return m.Providers.AWS.Networking.Type
+		return m.Providers.AWS.Networking
 	}
-	return m.Providers.Generic.Networking.Type
+	return m.Providers.Generic.Networking
 }
 
 // HasHook returns true if manifest defines hook of the specified type
@@ -323,6 +336,97 @@ func (m Manifest) RuntimePackageForProfile(profileName string) (*loc.Locator, er
 	return m.RuntimePackage(*profile)
 }
 
+// RuntimePackageForProfileArch returns the planet package for the specified
+// profile and the CPU architecture of the node
+func (m Manifest) RuntimePackageForProfileArch(profileName, arch string) (*loc.Locator, error) {
+	profile, err := m.NodeProfiles.ByName(profileName)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return m.RuntimePackageArch(*profile, arch)
+}
+
+// RuntimePackageArch returns the planet package for the specified profile
+// and the CPU architecture of the node
+func (m Manifest) RuntimePackageArch(profile NodeProfile, arch string) (*loc.Locator, error) {
+	if !m.SupportsArch(arch) {
+		return nil, trace.BadParameter("cluster image does not support %v architecture, supported architectures: %v",
+			arch, m.Architectures())
+	}
+	runtimePackage, err := m.RuntimePackage(profile)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	locator := PackageForArch(*runtimePackage, arch)
+	return &locator, nil
+}
+
+// Architectures returns the list of CPU architectures the cluster image
+// is built for
+func (m Manifest) Architectures() []string {
+	if m.SystemOptions == nil || len(m.SystemOptions.Architectures) == 0 {
+		return []string{ArchAMD64}
+	}
+	return m.SystemOptions.Architectures
+}
+
+// ServiceUser returns the default service user and group IDs specified
+// in the manifest. An ID is empty if the manifest does not specify it
+func (m Manifest) ServiceUser() (uid, gid string) {
+	if m.SystemOptions == nil || m.SystemOptions.ServiceUser == nil {
+		return "", ""
+	}
+	if id := m.SystemOptions.ServiceUser.UID; id != nil {
+		uid = strconv.Itoa(*id)
+	}
+	if id := m.SystemOptions.ServiceUser.GID; id != nil {
+		gid = strconv.Itoa(*id)
+	}
+	return uid, gid
+}
+
+// IsMultiArch returns true if the cluster image is built for more than
+// one CPU architecture
+func (m Manifest) IsMultiArch() bool {
+	return len(m.Architectures()) > 1
+}
+
+// SupportsArch returns true if the cluster image can be installed on nodes
+// with the specified CPU architecture.
+// Empty architecture is treated as the default architecture
+func (m Manifest) SupportsArch(arch string) bool {
+	if arch == "" {
+		arch = ArchAMD64
+	}
+	return utils.StringInSlice(m.Architectures(), arch)
+}
+
+// IntermediateRuntimes returns the intermediate runtime applications
+// embedded in the cluster image to upgrade through, in the ascending
+// version order
+func (m Manifest) IntermediateRuntimes() (runtimes []loc.Locator) {
+	if m.SystemOptions == nil {
+		return nil
+	}
+	for _, dep := range m.SystemOptions.Dependencies.IntermediateRuntimes {
+		runtimes = append(runtimes, dep.Locator)
+	}
+	return runtimes
+}
+
+// SetIntermediateRuntimes sets the intermediate runtime applications
+// to the provided list
+func (m *Manifest) SetIntermediateRuntimes(runtimes []loc.Locator) {
+	if m.SystemOptions == nil {
+		m.SystemOptions = &SystemOptions{}
+	}
+	m.SystemOptions.Dependencies.IntermediateRuntimes = nil
+	for _, runtime := range runtimes {
+		m.SystemOptions.Dependencies.IntermediateRuntimes = append(
+			m.SystemOptions.Dependencies.IntermediateRuntimes, Dependency{Locator: runtime})
+	}
+}
+
 // RuntimePackage returns the planet package for the specified profile.
 // If the profile does not specify a runtime package, the default runtime
 // package is returned
@@ -360,6 +464,20 @@ func (m Manifest) AllPackageDependencies() (deps []loc.Locator) {
 		deps = append(deps, m.SystemOptions.Dependencies.Runtime.Locator)
 	}
 	deps = append(deps, m.NodeProfiles.RuntimePackages()...)
+	// include runtime and gravity packages for all architectures
+	// the image is built for
+	archPackages := deps
+	if gravityPackage, err := m.Dependencies.ByName(constants.GravityPackage); err == nil {
+		archPackages = append(archPackages, *gravityPackage)
+	}
+	for _, arch := range m.Architectures() {
+		if arch == ArchAMD64 {
+			continue
+		}
+		for _, locator := range archPackages {
+			deps = append(deps, PackageForArch(locator, arch))
+		}
+	}
 	return loc.Deduplicate(append(m.Dependencies.GetPackages(), deps...))
 }
 
@@ -487,7 +605,9 @@ func (d *Dependency) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &locator); err != nil {
 		return trace.Wrap(err)
 	}
-	parsed, err := loc.ParseLocator(locator)
+	// Dependencies can specify a semver range which is resolved
+	// against the package repository during build or installation
+	parsed, err := loc.ParseConstraintLocator(locator)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -580,6 +700,9 @@ type NodeProfile struct {
 	Labels map[string]string `json:"labels,omitempty"`
 	// Tains is a list of taints to apply to this profile
 	Taints []v1.Taint `json:"taints,omitempty"`
+	// Reserved specifies the compute resources reserved on the nodes
+	// of this profile for the system and Kubernetes daemons
+	Reserved *ResourceReservations `json:"reserved,omitempty"`
 	// Providers contains some cloud provider specific settings
 	Providers NodeProviders `json:"providers,omitempty"`
 	// ExpandPolicy specifies whether nodes of this profile can
@@ -614,6 +737,60 @@ func (p NodeProfile) LabelValues() []string {
 	return labels
 }
 
+// ResourceReservations specifies the compute resources the kubelet
+// excludes from the node capacity available to pods
+type ResourceReservations struct {
+	// System reserves resources for the system daemons, e.g. sshd or journald
+	System map[string]string `json:"system,omitempty"`
+	// Kube reserves resources for the Kubernetes daemons, e.g. kubelet or docker
+	Kube map[string]string `json:"kube,omitempty"`
+}
+
+// Check makes sure the reservations specify valid resource quantities
+func (r ResourceReservations) Check() error {
+	var errors []error
+	for _, reserved := range []map[string]string{r.System, r.Kube} {
+		for name, value := range reserved {
+			if !utils.StringInSlice(ReservableResources, name) {
+				errors = append(errors, trace.BadParameter(
+					"unsupported reserved resource %q, supported are: %v",
+					name, strings.Join(ReservableResources, ", ")))
+				continue
+			}
+			if _, err := resource.ParseQuantity(value); err != nil {
+				errors = append(errors, trace.BadParameter(
+					"invalid quantity %q of reserved resource %q: %v", value, name, err))
+			}
+		}
+	}
+	return trace.NewAggregate(errors...)
+}
+
+// KubeletArgs returns the kubelet arguments that reserve the resources
+func (r ResourceReservations) KubeletArgs() (args []string) {
+	if len(r.System) != 0 {
+		args = append(args, fmt.Sprintf("--system-reserved=%v", formatResources(r.System)))
+	}
+	if len(r.Kube) != 0 {
+		args = append(args, fmt.Sprintf("--kube-reserved=%v", formatResources(r.Kube)))
+	}
+	return args
+}
+
+// formatResources formats the resources as comma-separated
+// name=quantity pairs sorted by name
+func formatResources(resources map[string]string) string {
+	pairs := make([]string, 0, len(resources))
+	for name, value := range resources {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", name, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// ReservableResources lists the resources that can be reserved on nodes
+var ReservableResources = []string{"cpu", "memory", "ephemeral-storage", "pid"}
+
 // TaintValues returns a list of all taints in this server profile as "key=value"
 func (p NodeProfile) TaintValues() []string {
 	if len(p.Taints) == 0 {
@@ -632,6 +809,8 @@ type Requirements struct {
 	CPU CPU `json:"cpu,omitempty"`
 	// RAM describes RAM requirements
 	RAM RAM `json:"ram,omitempty"`
+	// GPU describes GPU requirements
+	GPU GPU `json:"gpu,omitempty"`
 	// OS describes OS requirements
 	OS []OS `json:"os,omitempty"`
 	// Network describes network requirements
@@ -712,6 +891,9 @@ func (d Device) Format() string {
 
 // CustomCheck defines a script that runs a custom preflight check
 type CustomCheck struct {
+	// Name is an optional name of the check used to identify it
+	// in the preflight checks report
+	Name string `json:"name,omitempty"`
 	// Description provides a readable description for the check
 	Description string `json:"description,omitempty"`
 	// Script defines the contents of the check script.
@@ -736,6 +918,39 @@ type CPU struct {
 	Max int `json:"max,omitempty"`
 }
 
+// GPU describes GPU requirements
+type GPU struct {
+	// Vendor is the GPU vendor, only "nvidia" is supported
+	Vendor string `json:"vendor,omitempty"`
+	// Min is minimum required amount of GPUs
+	Min int `json:"min,omitempty"`
+}
+
+// IsRequired returns true if the GPUs are required
+func (r GPU) IsRequired() bool {
+	return r.Min > 0
+}
+
+// GetVendor returns the GPU vendor, "nvidia" by default
+func (r GPU) GetVendor() string {
+	if r.Vendor == "" {
+		return GPUVendorNvidia
+	}
+	return r.Vendor
+}
+
+// Check makes sure the GPU requirements are correct
+func (r GPU) Check() error {
+	if r.Min < 0 {
+		return trace.BadParameter("min GPU (%v) cannot be negative", r.Min)
+	}
+	if r.GetVendor() != GPUVendorNvidia {
+		return trace.BadParameter("unsupported GPU vendor %q, only %q is supported",
+			r.Vendor, GPUVendorNvidia)
+	}
+	return nil
+}
+
 // RAM describes RAM requirements
 type RAM struct {
 	// Min is minimum required amount of RAM
@@ -869,10 +1084,52 @@ type Providers struct {
 	AWS AWS `json:"aws,omitempty"`
 	// Azure defines Azure-specific settings
 	Azure Azure `json:"azure,omitempty"`
+	// GCE defines GCE-specific settings
+	GCE GCE `json:"gce,omitempty"`
 	// Generic defines settings for a generic provider (e.g. onprem)
 	Generic Generic `json:"generic,omitempty"`
 }
 
+// Terraform returns the Terraform configuration that provisions
+// the infrastructure with the specified provider, or nil if there is none
+func (r Providers) Terraform(provider string) *Terraform {
+	switch provider {
+	case ProviderAWS, ProvisionerAWSTerraform:
+		return r.AWS.Terraform
+	case ProviderAzure:
+		return r.Azure.Terraform
+	case ProviderGCE:
+		return r.GCE.Terraform
+	case ProviderGeneric, ProviderOnPrem:
+		return r.Generic.Terraform
+	}
+	return nil
+}
+
+// TerraformProviders returns the providers with Terraform configuration
+func (r Providers) TerraformProviders() (providers []string) {
+	for _, provider := range []string{ProviderAWS, ProviderAzure, ProviderGCE, ProviderGeneric} {
+		if r.Terraform(provider) != nil {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// Terraform defines the Terraform configuration bundled with the cluster
+// image that provisions the infrastructure before installation
+type Terraform struct {
+	// Script is the Terraform configuration; can be either a filename (file://),
+	// an HTTP address (http://) or plain text
+	Script string `json:"script,omitempty"`
+	// InstanceScript is the optional script passed to the configuration
+	// as the instance_script variable; can be either a filename (file://),
+	// an HTTP address (http://) or plain text
+	InstanceScript string `json:"instanceScript,omitempty"`
+	// Variables specifies the default values of the configuration variables
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
 // AWS defines AWS-specific settings
 type AWS struct {
 	// Networking describes networking configuration
@@ -881,6 +1138,8 @@ type AWS struct {
 	Regions []string `json:"regions,omitempty"`
 	// IAMPolicy is a list of permissions
 	IAMPolicy IAMPolicy `json:"iamPolicy,omitempty"`
+	// Terraform provisions the infrastructure before installation
+	Terraform *Terraform `json:"terraform,omitempty"`
 	// Disabled is whether this provider should be disabled
 	Disabled bool `json:"disabled,omitempty"`
 }
@@ -895,14 +1154,24 @@ type IAMPolicy struct {
 
 // Azure defines Azure-specific settings
 type Azure struct {
+	// Terraform provisions the infrastructure before installation
+	Terraform *Terraform `json:"terraform,omitempty"`
 	// Disabled is whether this provider should be disabled
 	Disabled bool `json:"disabled,omitempty"`
 }
 
+// GCE defines GCE-specific settings
+type GCE struct {
+	// Terraform provisions the infrastructure before installation
+	Terraform *Terraform `json:"terraform,omitempty"`
+}
+
 // Generic defines generic provider settings
 type Generic struct {
 	// Networking describes networking configuration
 	Networking Networking `json:"network,omitempty"`
+	// Terraform provisions the infrastructure before installation
+	Terraform *Terraform `json:"terraform,omitempty"`
 	// Disabled is whether this provider should be disabled
 	Disabled bool `json:"disabled,omitempty"`
 }
@@ -911,6 +1180,52 @@ type Generic struct {
 type Networking struct {
 	// Type is networking type
 	Type string `json:"type,omitempty"`
+	// Calico specifies optional settings for the calico network type
+	Calico *CalicoNetworking `json:"calico,omitempty"`
+	// Wireguard specifies optional settings for the wireguard network type
+	Wireguard *WireguardNetworking `json:"wireguard,omitempty"`
+}
+
+// Check makes sure the provider-specific settings match the network type
+func (r Networking) Check() error {
+	if r.Calico != nil && r.Type != NetworkingCalico {
+		return trace.BadParameter("calico settings require network type %q, not %q",
+			NetworkingCalico, r.Type)
+	}
+	if r.Wireguard != nil && r.Type != NetworkingWireguard {
+		return trace.BadParameter("wireguard settings require network type %q, not %q",
+			NetworkingWireguard, r.Type)
+	}
+	if r.Calico != nil {
+		switch r.Calico.IPIPMode {
+		case "", CalicoIPIPAlways, CalicoIPIPCrossSubnet, CalicoIPIPNever:
+		default:
+			return trace.BadParameter("unsupported calico IP-in-IP mode %q, supported modes: %v",
+				r.Calico.IPIPMode, []string{CalicoIPIPAlways, CalicoIPIPCrossSubnet, CalicoIPIPNever})
+		}
+	}
+	return nil
+}
+
+// IsBundled returns true if the network is installed by gravity itself
+// instead of the flannel overlay network built into the runtime
+func (r Networking) IsBundled() bool {
+	return r.Type == NetworkingCalico || r.Type == NetworkingWireguard
+}
+
+// CalicoNetworking describes settings of the calico network
+type CalicoNetworking struct {
+	// IPIPMode is the IP-in-IP encapsulation mode of the pod network:
+	// Always, CrossSubnet or Never
+	IPIPMode string `json:"ipipMode,omitempty"`
+	// MTU is the MTU of the pod network interfaces
+	MTU int `json:"mtu,omitempty"`
+}
+
+// WireguardNetworking describes settings of the WireGuard-encrypted overlay network
+type WireguardNetworking struct {
+	// Port is the UDP port the WireGuard tunnels listen on
+	Port int `json:"port,omitempty"`
 }
 
 // License describes an application license
@@ -980,6 +1295,61 @@ type SystemOptions struct {
 	BaseImage string `json:"baseImage,omitempty"`
 	// Dependencies defines additional package dependencies
 	Dependencies SystemDependencies `json:"dependencies"`
+	// Architectures lists the CPU architectures the cluster image is built for.
+	// Defaults to amd64.
+	// Only applicable to the cluster-level system options
+	Architectures []string `json:"architectures,omitempty"`
+	// ServiceUser optionally specifies the default service user for planet.
+	// Only applicable to the cluster-level system options
+	ServiceUser *ServiceUser `json:"serviceUser,omitempty"`
+}
+
+// ServiceUser defines the default IDs of the planet service user and group.
+// The defaults are used unless overridden on the installer command line
+type ServiceUser struct {
+	// UID is the service user ID
+	UID *int `json:"uid,omitempty"`
+	// GID is the service group ID
+	GID *int `json:"gid,omitempty"`
+}
+
+const (
+	// ArchAMD64 is the 64-bit x86 CPU architecture
+	ArchAMD64 = "amd64"
+	// ArchARM64 is the 64-bit ARM CPU architecture
+	ArchARM64 = "arm64"
+)
+
+// SupportedArchitectures lists all CPU architectures a cluster image can be built for
+var SupportedArchitectures = []string{ArchAMD64, ArchARM64}
+
+// PackageForArch returns the variant of the specified architecture-specific
+// package (runtime or gravity binary) for the given CPU architecture.
+//
+// Packages for the default architecture (amd64) keep their name,
+// packages for other architectures have the architecture appended
+// to the name, e.g. gravitational.io/planet-arm64:5.5.0
+func PackageForArch(locator loc.Locator, arch string) loc.Locator {
+	if arch == "" || arch == ArchAMD64 {
+		return locator
+	}
+	return loc.Locator{
+		Repository: locator.Repository,
+		Name:       fmt.Sprintf("%v-%v", locator.Name, arch),
+		Version:    locator.Version,
+	}
+}
+
+// MachineArch returns the CPU architecture name as reported by uname -m
+// for the specified architecture in Go notation
+func MachineArch(arch string) string {
+	switch arch {
+	case ArchAMD64:
+		return "x86_64"
+	case ArchARM64:
+		return "aarch64"
+	}
+	return arch
 }
 
 // Runtime describes the application runtime
@@ -1021,6 +1391,10 @@ func (r *Runtime) UnmarshalJSON(data []byte) error {
 type SystemDependencies struct {
 	// Runtime describes the runtime package
 	Runtime *Dependency `json:"runtimePackage,omitempty"`
+	// IntermediateRuntimes lists the runtime applications the cluster
+	// is upgraded through before upgrading to the runtime of this image.
+	// Only applicable to the cluster-level system options
+	IntermediateRuntimes []Dependency `json:"intermediateRuntimes,omitempty"`
 }
 
 // Docker describes docker options
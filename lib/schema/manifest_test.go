@@ -17,7 +17,9 @@ limitations under the License.
 package schema
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/gravitational/gravity/lib/compare"
 	"github.com/gravitational/gravity/lib/constants"
@@ -25,6 +27,7 @@ import (
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/utils"
 
+	"github.com/ghodss/yaml"
 	"github.com/gravitational/trace"
 	. "gopkg.in/check.v1"
 	"k8s.io/api/core/v1"
@@ -466,6 +469,117 @@ nodeProfiles:
 	c.Assert(err, NotNil)
 }
 
+func (s *ManifestSuite) TestGPURequirements(c *C) {
+	manifest := `apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+installer:
+  flavors:
+    items:
+      - name: one
+        nodes:
+          - profile: node
+            count: 1
+nodeProfiles:
+  - name: node
+    requirements:
+      gpu:
+        min: 2%v`
+	m, err := ParseManifestYAML([]byte(fmt.Sprintf(manifest, "")))
+	c.Assert(err, IsNil)
+	profile, err := m.NodeProfiles.ByName("node")
+	c.Assert(err, IsNil)
+	c.Assert(profile.Requirements.GPU.IsRequired(), Equals, true)
+	c.Assert(profile.Requirements.GPU.GetVendor(), Equals, GPUVendorNvidia)
+
+	_, err = ParseManifestYAML([]byte(fmt.Sprintf(manifest, "\n        vendor: amd")))
+	c.Assert(err, NotNil)
+}
+
+func (s *ManifestSuite) TestReservedResources(c *C) {
+	manifest := `apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+installer:
+  flavors:
+    items:
+      - name: one
+        nodes:
+          - profile: node
+            count: 1
+nodeProfiles:
+  - name: node
+    reserved:
+      system:
+        memory: %v
+        cpu: 500m
+      kube:
+        %v: 1Gi`
+	m, err := ParseManifestYAML([]byte(fmt.Sprintf(manifest, "512Mi", "ephemeral-storage")))
+	c.Assert(err, IsNil)
+	profile, err := m.NodeProfiles.ByName("node")
+	c.Assert(err, IsNil)
+	c.Assert(profile.Reserved.KubeletArgs(), DeepEquals, []string{
+		"--system-reserved=cpu=500m,memory=512Mi",
+		"--kube-reserved=ephemeral-storage=1Gi",
+	})
+
+	_, err = ParseManifestYAML([]byte(fmt.Sprintf(manifest, "lots", "ephemeral-storage")))
+	c.Assert(err, NotNil)
+	_, err = ParseManifestYAML([]byte(fmt.Sprintf(manifest, "512Mi", "gpu")))
+	c.Assert(err, NotNil)
+}
+
+func (s *ManifestSuite) TestReadinessChecks(c *C) {
+	manifest := `apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+readinessChecks:
+  - name: api
+    http:
+      url: https://api.default.svc.cluster.local/healthz
+      insecure: true
+  - name: backend
+    timeout: 10m
+    resource:
+      kind: %v
+      name: backend
+  - name: errors
+    prometheus:
+      query: sum(rate(http_errors_total[5m])) < 1`
+	m, err := ParseManifestYAML([]byte(fmt.Sprintf(manifest, "Deployment")))
+	c.Assert(err, IsNil)
+	c.Assert(m.ReadinessChecks, HasLen, 3)
+	c.Assert(m.ReadinessChecks[0].HTTP.Insecure, Equals, true)
+	c.Assert(m.ReadinessChecks[1].Resource.GetNamespace(), Equals, "default")
+	timeout, err := m.ReadinessChecks[1].GetTimeout()
+	c.Assert(err, IsNil)
+	c.Assert(timeout, Equals, 10*time.Minute)
+	c.Assert(m.ReadinessChecks[2].Prometheus.Query, Equals, "sum(rate(http_errors_total[5m])) < 1")
+
+	_, err = ParseManifestYAML([]byte(fmt.Sprintf(manifest, "ConfigMap")))
+	c.Assert(err, NotNil)
+
+	_, err = ParseManifestYAML([]byte(`apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+readinessChecks:
+  - name: ambiguous
+    http:
+      url: http://api.default.svc.cluster.local/healthz
+    prometheus:
+      query: up`))
+	c.Assert(err, NotNil)
+}
+
 func (s *ManifestSuite) TestInvalidFileMode(c *C) {
 	bytes := []byte(`apiVersion: bundle.gravitational.io/v2
 kind: Bundle
@@ -552,6 +666,293 @@ systemOptions:
 	c.Assert(err, NotNil)
 }
 
+func (s *ManifestSuite) TestNetworkingSettings(c *C) {
+	manifest, err := ParseManifestYAML([]byte(`apiVersion: cluster.gravitational.io/v2
+kind: Cluster
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+providers:
+  generic:
+    network:
+      type: calico
+      calico:
+        ipipMode: CrossSubnet
+        mtu: 1400`))
+	c.Assert(err, IsNil)
+	networking := manifest.GetNetworking(ProviderOnPrem, ProvisionerOnPrem)
+	c.Assert(networking, DeepEquals, Networking{
+		Type: NetworkingCalico,
+		Calico: &CalicoNetworking{
+			IPIPMode: CalicoIPIPCrossSubnet,
+			MTU:      1400,
+		},
+	})
+	c.Assert(networking.IsBundled(), Equals, true)
+	c.Assert(manifest.GetNetworking(ProviderAWS, ProvisionerAWSTerraform).Type, Equals, NetworkingAWSVPC)
+}
+
+func (s *ManifestSuite) TestInvalidNetworkingSettings(c *C) {
+	var testCases = []struct {
+		network string
+		comment string
+	}{
+		{
+			network: `
+      type: vxlan
+      calico:
+        mtu: 1400`,
+			comment: "calico settings with a different network type",
+		},
+		{
+			network: `
+      type: calico
+      wireguard:
+        port: 51820`,
+			comment: "wireguard settings with a different network type",
+		},
+		{
+			network: `
+      type: calico
+      calico:
+        ipipMode: Sometimes`,
+			comment: "unsupported IP-in-IP mode",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := ParseManifestYAML([]byte(`apiVersion: cluster.gravitational.io/v2
+kind: Cluster
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+providers:
+  generic:
+    network:` + tc.network))
+		c.Assert(err, NotNil, Commentf(tc.comment))
+	}
+}
+
+func (s *ManifestSuite) TestArchitectures(c *C) {
+	bytes := []byte(`apiVersion: cluster.gravitational.io/v2
+kind: Cluster
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+installer:
+  flavors:
+    items:
+      - name: one
+        nodes:
+          - profile: node
+            count: 1
+nodeProfiles:
+  - name: node
+  - name: worker
+    systemOptions:
+      dependencies:
+        runtimePackage: gravitational.io/planet-worker:0.0.2
+systemOptions:
+  runtime:
+    version: 0.0.1
+  architectures: [amd64, arm64]
+  dependencies:
+    runtimePackage: gravitational.io/planet:0.0.1`)
+	manifest, err := ParseManifestYAML(bytes)
+	c.Assert(err, IsNil)
+	c.Assert(manifest.Architectures(), DeepEquals, []string{ArchAMD64, ArchARM64})
+	c.Assert(manifest.IsMultiArch(), Equals, true)
+
+	runtimePackage, err := manifest.RuntimePackageForProfileArch("node", ArchARM64)
+	c.Assert(err, IsNil)
+	c.Assert(runtimePackage.String(), Equals, "gravitational.io/planet-arm64:0.0.1")
+	runtimePackage, err = manifest.RuntimePackageForProfileArch("worker", ArchAMD64)
+	c.Assert(err, IsNil)
+	c.Assert(runtimePackage.String(), Equals, "gravitational.io/planet-worker:0.0.2")
+
+	var deps []string
+	for _, dep := range manifest.AllPackageDependencies() {
+		deps = append(deps, dep.String())
+	}
+	c.Assert(deps, DeepEquals, []string{
+		"gravitational.io/planet:0.0.1",
+		"gravitational.io/planet-worker:0.0.2",
+		"gravitational.io/planet-arm64:0.0.1",
+		"gravitational.io/planet-worker-arm64:0.0.2",
+	})
+}
+
+func (s *ManifestSuite) TestServiceUser(c *C) {
+	bytes := []byte(`apiVersion: cluster.gravitational.io/v2
+kind: Cluster
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+installer:
+  flavors:
+    items:
+      - name: one
+        nodes:
+          - profile: node
+            count: 1
+nodeProfiles:
+  - name: node
+systemOptions:
+  runtime:
+    version: 0.0.1
+  serviceUser:
+    uid: 1001
+    gid: 1002`)
+	manifest, err := ParseManifestYAML(bytes)
+	c.Assert(err, IsNil)
+	uid, gid := manifest.ServiceUser()
+	c.Assert(uid, Equals, "1001")
+	c.Assert(gid, Equals, "1002")
+
+	uid, gid = Manifest{}.ServiceUser()
+	c.Assert(uid, Equals, "")
+	c.Assert(gid, Equals, "")
+}
+
+func (s *ManifestSuite) TestTerraformProviders(c *C) {
+	bytes := []byte(`apiVersion: cluster.gravitational.io/v2
+kind: Cluster
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+installer:
+  flavors:
+    items:
+      - name: one
+        nodes:
+          - profile: node
+            count: 1
+nodeProfiles:
+  - name: node
+providers:
+  aws:
+    terraform:
+      script: terraform/aws
+      variables:
+        instance_type: m5.xlarge
+  gce:
+    terraform:
+      script: terraform/gce
+systemOptions:
+  runtime:
+    version: 0.0.1`)
+	manifest, err := ParseManifestYAML(bytes)
+	c.Assert(err, IsNil)
+	c.Assert(manifest.Providers.TerraformProviders(), DeepEquals, []string{ProviderAWS, ProviderGCE})
+	c.Assert(manifest.Providers.Terraform(ProviderAWS), DeepEquals, &Terraform{
+		Script:    "terraform/aws",
+		Variables: map[string]string{"instance_type": "m5.xlarge"},
+	})
+	c.Assert(manifest.Providers.Terraform(ProviderOnPrem), IsNil)
+}
+
+func (s *ManifestSuite) TestDefaultArchitecture(c *C) {
+	manifest := Manifest{}
+	c.Assert(manifest.Architectures(), DeepEquals, []string{ArchAMD64})
+	c.Assert(manifest.IsMultiArch(), Equals, false)
+	c.Assert(manifest.SupportsArch(""), Equals, true)
+	c.Assert(manifest.SupportsArch(ArchARM64), Equals, false)
+}
+
+func (s *ManifestSuite) TestInvalidArchitectures(c *C) {
+	for _, architectures := range []string{"[ppc64le]", "[arm64, arm64]"} {
+		bytes := []byte(fmt.Sprintf(`apiVersion: cluster.gravitational.io/v2
+kind: Cluster
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+systemOptions:
+  runtime:
+    version: 0.0.1
+  architectures: %v`, architectures))
+		_, err := ParseManifestYAML(bytes)
+		c.Assert(err, NotNil, Commentf(architectures))
+	}
+}
+
+func (s *ManifestSuite) TestIntermediateRuntimes(c *C) {
+	bytes := []byte(`apiVersion: cluster.gravitational.io/v2
+kind: Cluster
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+systemOptions:
+  runtime:
+    version: 7.0.0
+  dependencies:
+    intermediateRuntimes:
+      - gravitational.io/kubernetes:5.5.40
+      - gravitational.io/kubernetes:6.1.20`)
+	manifest, err := ParseManifestYAML(bytes)
+	c.Assert(err, IsNil)
+	c.Assert(manifest.IntermediateRuntimes(), DeepEquals, []loc.Locator{
+		loc.MustParseLocator("gravitational.io/kubernetes:5.5.40"),
+		loc.MustParseLocator("gravitational.io/kubernetes:6.1.20"),
+	})
+
+	manifest.SetIntermediateRuntimes([]loc.Locator{
+		loc.MustParseLocator("gravitational.io/kubernetes:6.1.20"),
+	})
+	bytes, err = yaml.Marshal(manifest)
+	c.Assert(err, IsNil)
+	manifest, err = ParseManifestYAML(bytes)
+	c.Assert(err, IsNil)
+	c.Assert(manifest.IntermediateRuntimes(), DeepEquals, []loc.Locator{
+		loc.MustParseLocator("gravitational.io/kubernetes:6.1.20"),
+	})
+}
+
+func (s *ManifestSuite) TestInvalidIntermediateRuntimes(c *C) {
+	for _, runtimes := range []string{
+		"[gravitational.io/planet:6.1.20]",
+		"[gravitational.io/kubernetes:6.1.20, gravitational.io/kubernetes:5.5.40]",
+		"[gravitational.io/kubernetes:6.1.20, gravitational.io/kubernetes:6.1.20]",
+	} {
+		bytes := []byte(fmt.Sprintf(`apiVersion: cluster.gravitational.io/v2
+kind: Cluster
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+systemOptions:
+  runtime:
+    version: 7.0.0
+  dependencies:
+    intermediateRuntimes: %v`, runtimes))
+		_, err := ParseManifestYAML(bytes)
+		c.Assert(err, NotNil, Commentf(runtimes))
+	}
+}
+
+func (s *ManifestSuite) TestDuplicateCustomCheckNames(c *C) {
+	bytes := []byte(`apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: myapp
+  resourceVersion: 0.0.1
+installer:
+  flavors:
+    items:
+    - name: one
+      nodes:
+      - profile: node
+        count: 1
+nodeProfiles:
+  - name: node
+    requirements:
+      customChecks:
+      - name: check
+        script: exit 0
+      - name: check
+        script: exit 1
+`)
+	_, err := ParseManifestYAML(bytes)
+	c.Assert(err, NotNil)
+}
+
 func (s *ManifestSuite) TestCanOverrideBooleans(c *C) {
 	bytes := []byte(`apiVersion: bundle.gravitational.io/v2
 kind: Bundle
@@ -23,6 +23,7 @@ import (
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
 	schemadefaults "github.com/gravitational/gravity/lib/schema/defaults"
 	"github.com/gravitational/gravity/lib/schema/v1"
 	"github.com/gravitational/gravity/lib/utils"
@@ -154,6 +155,22 @@ func CheckAndSetDefaults(manifest *Manifest) error {
 		}
 	}
 
+	for _, hookType := range AllHooks() {
+		hook, err := HookFromString(hookType, *manifest)
+		if err != nil {
+			continue
+		}
+		if err := hook.Check(); err != nil {
+			errors = append(errors, trace.BadParameter("invalid %v hook: %v", hookType, err))
+		}
+	}
+
+	for _, check := range manifest.ReadinessChecks {
+		if err := check.Check(); err != nil {
+			errors = append(errors, trace.BadParameter("invalid readiness check %q: %v", check.Name, err))
+		}
+	}
+
 	for i, nodeProfile := range manifest.NodeProfiles {
 		for j := range nodeProfile.Requirements.Volumes {
 			if err := manifest.NodeProfiles[i].Requirements.Volumes[j].CheckAndSetDefaults(); err != nil {
@@ -162,10 +179,42 @@ func CheckAndSetDefaults(manifest *Manifest) error {
 		}
 	}
 
+	if manifest.Providers != nil {
+		if err := manifest.Providers.AWS.Networking.Check(); err != nil {
+			errors = append(errors, trace.Wrap(err))
+		}
+		if err := manifest.Providers.Generic.Networking.Check(); err != nil {
+			errors = append(errors, trace.Wrap(err))
+		}
+	}
+
 	if manifest.SystemOptions != nil {
 		if manifest.SystemOptions.Runtime == nil {
 			errors = append(errors, trace.NotFound("no runtime application defined"))
 		}
+		if err := checkArchitectures(manifest.SystemOptions.Architectures); err != nil {
+			errors = append(errors, err)
+		}
+		if manifest.IsMultiArch() && len(manifest.RuntimeImages()) != 0 {
+			errors = append(errors, trace.BadParameter(
+				"baseImage is not supported for multi-architecture cluster images"))
+		}
+		if err := checkIntermediateRuntimes(manifest.IntermediateRuntimes()); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	for _, profile := range manifest.NodeProfiles {
+		if profile.SystemOptions != nil && len(profile.SystemOptions.Architectures) != 0 {
+			errors = append(errors, trace.BadParameter(
+				"architectures can only be specified in cluster-level system options, not in node profile %q",
+				profile.Name))
+		}
+		if profile.SystemOptions != nil && len(profile.SystemOptions.Dependencies.IntermediateRuntimes) != 0 {
+			errors = append(errors, trace.BadParameter(
+				"intermediate runtimes can only be specified in cluster-level system options, not in node profile %q",
+				profile.Name))
+		}
 	}
 
 	if len(errors) > 0 {
@@ -213,6 +262,44 @@ func setProviderDefaults(manifest *Manifest) {
 	}
 }
 
+// checkArchitectures makes sure the list of CPU architectures only has
+// supported architectures without duplicates
+func checkArchitectures(architectures []string) error {
+	seen := make(map[string]struct{})
+	for _, arch := range architectures {
+		if !utils.StringInSlice(SupportedArchitectures, arch) {
+			return trace.BadParameter("unsupported architecture %q, supported architectures: %v",
+				arch, SupportedArchitectures)
+		}
+		if _, ok := seen[arch]; ok {
+			return trace.BadParameter("duplicate architecture %q", arch)
+		}
+		seen[arch] = struct{}{}
+	}
+	return nil
+}
+
+// checkIntermediateRuntimes makes sure the intermediate runtimes are
+// runtime applications listed in the strictly ascending version order
+func checkIntermediateRuntimes(runtimes []loc.Locator) error {
+	var prev *semver.Version
+	for _, runtime := range runtimes {
+		if runtime.Name != defaults.Runtime {
+			return trace.BadParameter("intermediate runtime %v is not a runtime application", runtime)
+		}
+		version, err := runtime.SemVer()
+		if err != nil {
+			return trace.BadParameter("intermediate runtime %v does not have a valid version", runtime)
+		}
+		if prev != nil && !prev.LessThan(*version) {
+			return trace.BadParameter("intermediate runtimes should be listed in the ascending version order: %v",
+				runtimes)
+		}
+		prev = version
+	}
+	return nil
+}
+
 // checkMetadata performs some sanity checks on manifest metadata
 func checkMetadata(metadata Metadata) error {
 	var errors []error
@@ -270,6 +357,13 @@ func checkProfile(profile NodeProfile) error {
 		errors = append(errors, trace.Wrap(err))
 	}
 
+	if profile.Reserved != nil {
+		err = profile.Reserved.Check()
+		if err != nil {
+			errors = append(errors, trace.Wrap(err, "invalid reserved resources of profile %q", profile.Name))
+		}
+	}
+
 	if profile.ExpandPolicy != "" {
 		if profile.ExpandPolicy != ExpandPolicyFixed && profile.ExpandPolicy != ExpandPolicyFixedInstance {
 			errors = append(errors, trace.BadParameter("supported expand policies are %q and %q, got: %q",
@@ -294,10 +388,26 @@ func checkRequirements(reqs Requirements) error {
 			"max RAM (%v) is less than min RAM (%v)", reqs.RAM.Max, reqs.RAM.Min))
 	}
 
+	if err := reqs.GPU.Check(); err != nil {
+		errors = append(errors, trace.Wrap(err))
+	}
+
 	for _, device := range reqs.Devices {
 		errors = append(errors, device.Check())
 	}
 
+	names := make(map[string]struct{})
+	for _, check := range reqs.CustomChecks {
+		if check.Name == "" {
+			continue
+		}
+		if _, exists := names[check.Name]; exists {
+			errors = append(errors, trace.BadParameter(
+				"duplicate custom check name %q", check.Name))
+		}
+		names[check.Name] = struct{}{}
+	}
+
 	return trace.NewAggregate(errors...)
 }
 
@@ -0,0 +1,162 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// ReadinessCheck defines a condition the application must satisfy
+// at the end of install or upgrade before the operation is completed.
+//
+// Exactly one of HTTP, Resource or Prometheus must be specified
+type ReadinessCheck struct {
+	// Name is the name of the check
+	Name string `json:"name"`
+	// HTTP specifies an HTTP endpoint that must respond successfully
+	HTTP *HTTPReadinessCheck `json:"http,omitempty"`
+	// Resource specifies a Kubernetes resource that must be ready
+	Resource *ResourceReadinessCheck `json:"resource,omitempty"`
+	// Prometheus specifies a PromQL expression that must evaluate to true
+	Prometheus *PrometheusReadinessCheck `json:"prometheus,omitempty"`
+	// Timeout is the optional limit on the time to wait for the check
+	// to succeed, e.g. 5m
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// HTTPReadinessCheck is satisfied when the endpoint responds
+// with the expected status code
+type HTTPReadinessCheck struct {
+	// URL is the URL of the endpoint
+	URL string `json:"url"`
+	// Status is the expected response status code. Any 2xx status
+	// code is accepted if unspecified
+	Status int `json:"status,omitempty"`
+	// Insecure disables verification of the endpoint certificate
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// ResourceReadinessCheck is satisfied when the Kubernetes resource is ready
+type ResourceReadinessCheck struct {
+	// Kind is the resource kind, one of ReadinessResourceKinds
+	Kind string `json:"kind"`
+	// Name is the resource name
+	Name string `json:"name"`
+	// Namespace is the resource namespace, defaults to "default"
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// PrometheusReadinessCheck is satisfied when the PromQL expression
+// returns a non-empty result without zero values
+type PrometheusReadinessCheck struct {
+	// Query is the PromQL expression to evaluate
+	Query string `json:"query"`
+}
+
+// Check validates the readiness check
+func (r ReadinessCheck) Check() error {
+	if r.Name == "" {
+		return trace.BadParameter("readiness check name is required")
+	}
+	var specified int
+	if r.HTTP != nil {
+		specified++
+		if err := r.HTTP.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if r.Resource != nil {
+		specified++
+		if err := r.Resource.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if r.Prometheus != nil {
+		specified++
+		if strings.TrimSpace(r.Prometheus.Query) == "" {
+			return trace.BadParameter("prometheus query is required")
+		}
+	}
+	if specified != 1 {
+		return trace.BadParameter("exactly one of http, resource or prometheus must be specified")
+	}
+	if _, err := r.GetTimeout(); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetTimeout returns the time to wait for the check to succeed
+func (r ReadinessCheck) GetTimeout() (time.Duration, error) {
+	if r.Timeout == "" {
+		return defaults.ReadinessCheckTimeout, nil
+	}
+	timeout, err := time.ParseDuration(r.Timeout)
+	if err != nil {
+		return 0, trace.BadParameter("invalid timeout %q: %v", r.Timeout, err)
+	}
+	if timeout <= 0 {
+		return 0, trace.BadParameter("timeout %q should be positive", r.Timeout)
+	}
+	return timeout, nil
+}
+
+// Check validates the HTTP readiness check
+func (r HTTPReadinessCheck) Check() error {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return trace.BadParameter("invalid URL %q: %v", r.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return trace.BadParameter("URL %q should be either http or https", r.URL)
+	}
+	if r.Status != 0 && (r.Status < 100 || r.Status > 599) {
+		return trace.BadParameter("invalid status code %v", r.Status)
+	}
+	return nil
+}
+
+// Check validates the resource readiness check
+func (r ResourceReadinessCheck) Check() error {
+	if !utils.StringInSlice(ReadinessResourceKinds, r.Kind) {
+		return trace.BadParameter("unsupported resource kind %q, supported are: %v",
+			r.Kind, strings.Join(ReadinessResourceKinds, ", "))
+	}
+	if r.Name == "" {
+		return trace.BadParameter("resource name is required")
+	}
+	return nil
+}
+
+// GetNamespace returns the namespace of the resource
+func (r ResourceReadinessCheck) GetNamespace() string {
+	if r.Namespace == "" {
+		return defaults.Namespace
+	}
+	return r.Namespace
+}
+
+// ReadinessResourceKinds lists the kinds of resources readiness
+// can be checked for
+var ReadinessResourceKinds = []string{"Deployment", "DaemonSet", "StatefulSet", "Job", "Pod"}
@@ -177,6 +177,14 @@ const manifestSchema = `
                       "max": {"type": "number"}
                     }
                   },
+                  "gpu": {
+                    "type": "object",
+                    "additionalProperties": false,
+                    "properties": {
+                      "vendor": {"type": "string"},
+                      "min": {"type": "number"}
+                    }
+                  },
                   "ram": {
                     "type": "object",
                     "additionalProperties": false,
@@ -268,6 +276,7 @@ const manifestSchema = `
                     "items": {
                       "type": "object",
                       "properties": {
+                        "name": {"type": "string"},
                         "description": {"type": "string"},
                         "script": {"type": "string"}
                       }
@@ -289,6 +298,24 @@ const manifestSchema = `
                    }
                 }
               },
+              "reserved": {
+                "type": "object",
+                "additionalProperties": false,
+                "properties": {
+                  "system": {
+                    "type": "object",
+                    "patternProperties": {
+                      "^.*$": {"type": "string"}
+                    }
+                  },
+                  "kube": {
+                    "type": "object",
+                    "patternProperties": {
+                      "^.*$": {"type": "string"}
+                    }
+                  }
+                }
+              },
               "providers": {
                 "type": "object",
                 "additionalProperties": false,
@@ -316,6 +343,7 @@ const manifestSchema = `
           "properties": {
             "aws": {"$ref": "#/definitions/providerAWS"},
             "azure": {"$ref": "#/definitions/providerAzure"},
+            "gce": {"$ref": "#/definitions/providerGCE"},
             "generic": {"$ref": "#/definitions/providerGeneric"}
           }
         },
@@ -336,7 +364,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "clusterProvision"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "clusterDeprovision": {
@@ -344,7 +375,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "clusterDeprovision"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "nodesProvision": {
@@ -352,7 +386,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "nodesProvision"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "nodesDeprovision": {
@@ -360,7 +397,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "nodesDeprovision"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "install": {
@@ -368,7 +408,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "install"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "postInstall": {
@@ -376,7 +419,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "postInstall"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "uninstall": {
@@ -384,7 +430,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "uninstall"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "preUninstall": {
@@ -392,7 +441,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "preUninstall"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "preNodeAdd": {
@@ -400,7 +452,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "preNodeAdd"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "postNodeAdd": {
@@ -408,7 +463,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "postNodeAdd"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "preNodeRemove": {
@@ -416,7 +474,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "preNodeRemove"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "postNodeRemove": {
@@ -424,7 +485,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "postNodeRemove"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "preUpdate": {
@@ -432,7 +496,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "preUpdate"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "update": {
@@ -440,7 +507,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "update"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "postUpdate": {
@@ -448,7 +518,21 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "postUpdate"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
+              }
+            },
+            "preNodeUpdate": {
+              "type": "object",
+              "additionalProperties": false,
+              "properties": {
+                "type": {"type": "string", "default": "preNodeUpdate"},
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "rollback": {
@@ -456,7 +540,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "rollback"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "postRollback": {
@@ -464,7 +551,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "postRollback"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "status": {
@@ -472,7 +562,21 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "status"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
+              }
+            },
+            "healthCheck": {
+              "type": "object",
+              "additionalProperties": false,
+              "properties": {
+                "type": {"type": "string", "default": "healthCheck"},
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "info": {
@@ -480,7 +584,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "info"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "licenseUpdated": {
@@ -488,7 +595,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "licenseUpdated"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "start": {
@@ -496,7 +606,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "start"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "stop": {
@@ -504,7 +617,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "stop"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "dump": {
@@ -512,7 +628,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "dump"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "backup": {
@@ -520,7 +639,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "backup"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "restore": {
@@ -528,7 +650,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "restore"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "networkInstall": {
@@ -536,7 +661,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "networkInstall"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "networkUpdate": {
@@ -544,7 +672,10 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "networkUpdate"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
               }
             },
             "networkRollback": {
@@ -552,7 +683,50 @@ const manifestSchema = `
               "additionalProperties": false,
               "properties": {
                 "type": {"type": "string", "default": "networkRollback"},
-                "job": {"type": "string"}
+                "job": {"type": "string"},
+                "timeout": {"type": "string"},
+                "retries": {"type": "integer", "minimum": 0},
+                "onFailure": {"type": "string", "enum": ["abort", "continue"]}
+              }
+            }
+          }
+        },
+        "readinessChecks": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": ["name"],
+            "additionalProperties": false,
+            "properties": {
+              "name": {"type": "string"},
+              "timeout": {"type": "string"},
+              "http": {
+                "type": "object",
+                "required": ["url"],
+                "additionalProperties": false,
+                "properties": {
+                  "url": {"type": "string"},
+                  "status": {"type": "number"},
+                  "insecure": {"type": "boolean"}
+                }
+              },
+              "resource": {
+                "type": "object",
+                "required": ["kind", "name"],
+                "additionalProperties": false,
+                "properties": {
+                  "kind": {"type": "string"},
+                  "name": {"type": "string"},
+                  "namespace": {"type": "string"}
+                }
+              },
+              "prometheus": {
+                "type": "object",
+                "required": ["query"],
+                "additionalProperties": false,
+                "properties": {
+                  "query": {"type": "string"}
+                }
               }
             }
           }
@@ -585,21 +759,8 @@ const manifestSchema = `
       "type": "object",
       "additionalProperties": false,
       "properties": {
-        "network": {
-          "type": "object",
-          "additionalProperties": false,
-          "properties": {
-            "type": {"type": "string"}
-          }
-        },
-        "terraform": {
-          "type": "object",
-          "additionalProperties": false,
-          "properties": {
-            "script": {"type": "string"},
-            "instanceScript": {"type": "string"}
-          }
-        },
+        "network": {"$ref": "#/definitions/network"},
+        "terraform": {"$ref": "#/definitions/terraform"},
         "regions": {
           "type": "array",
           "items": {"type": "string"}
@@ -622,21 +783,39 @@ const manifestSchema = `
       "type": "object",
       "additionalProperties": false,
       "properties": {
+        "terraform": {"$ref": "#/definitions/terraform"},
         "disabled": {"type": "boolean"}
       }
     },
+    "providerGCE": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "terraform": {"$ref": "#/definitions/terraform"}
+      }
+    },
     "providerGeneric": {
       "type": "object",
       "additionalProperties": false,
       "properties": {
-        "network": {
+        "network": {"$ref": "#/definitions/network"},
+        "terraform": {"$ref": "#/definitions/terraform"},
+        "disabled": {"type": "boolean"}
+      }
+    },
+    "terraform": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["script"],
+      "properties": {
+        "script": {"type": "string"},
+        "instanceScript": {"type": "string"},
+        "variables": {
           "type": "object",
-          "additionalProperties": false,
-          "properties": {
-            "type": {"type": "string"}
+          "patternProperties": {
+            "^.*$": {"type": "string"}
           }
-        },
-        "disabled": {"type": "boolean"}
+        }
       }
     },
     "systemOptions": {
@@ -684,7 +863,23 @@ const manifestSchema = `
         "dependencies": {
           "type": "object",
           "properties": {
-            "runtimePackage": {"type": "string"}
+            "runtimePackage": {"type": "string"},
+            "intermediateRuntimes": {
+              "type": "array",
+              "items": {"type": "string"}
+            }
+          }
+        },
+        "architectures": {
+          "type": "array",
+          "items": {"enum": ["amd64", "arm64"]}
+        },
+        "serviceUser": {
+          "type": "object",
+          "additionalProperties": false,
+          "properties": {
+            "uid": {"type": "integer", "minimum": 0},
+            "gid": {"type": "integer", "minimum": 0}
           }
         }
       }
@@ -699,6 +894,28 @@ const manifestSchema = `
         }
       }
     },
+    "network": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "type": {"type": "string"},
+        "calico": {
+          "type": "object",
+          "additionalProperties": false,
+          "properties": {
+            "ipipMode": {"type": "string", "enum": ["Always", "CrossSubnet", "Never"]},
+            "mtu": {"type": "integer", "minimum": 576}
+          }
+        },
+        "wireguard": {
+          "type": "object",
+          "additionalProperties": false,
+          "properties": {
+            "port": {"type": "integer", "minimum": 1, "maximum": 65535}
+          }
+        }
+      }
+    },
     "onOff": {
       "type": "object",
       "additionalProperties": false,
@@ -27,7 +27,9 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"text/template"
 
+	"github.com/Masterminds/sprig"
 	"github.com/gravitational/trace"
 )
 
@@ -41,6 +43,8 @@ import (
 //   .installer.eula.source
 //   .installer.flavors.description
 //   .hooks.*.job
+//   .providers.*.terraform.script
+//   .providers.*.terraform.instanceScript
 //   .webConfig
 func ProcessMultiSourceValues(manifest *Manifest, manifestPath string) error {
 	err := processText(&manifest.ReleaseNotes, manifestPath)
@@ -88,6 +92,20 @@ func ProcessMultiSourceValues(manifest *Manifest, manifestPath string) error {
 		}
 	}
 
+	if manifest.Providers != nil {
+		for _, provider := range manifest.Providers.TerraformProviders() {
+			terraform := manifest.Providers.Terraform(provider)
+			err = processText(&terraform.Script, manifestPath)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			err = processText(&terraform.InstanceScript, manifestPath)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -102,6 +120,30 @@ func ExpandEnvVars(manifest []byte) []byte {
 	return reEnvVar.ReplaceAllFunc(manifest, replaceFn)
 }
 
+// RenderManifestTemplate renders the provided manifest data as a Go template.
+//
+// The values are accessible in the template under .Values, for example
+// {{ .Values.image.tag }}. Referencing a value that has not been provided
+// is an error
+func RenderManifestTemplate(name string, manifest []byte, values map[string]interface{}) ([]byte, error) {
+	tmpl, err := template.New(name).
+		Funcs(sprig.TxtFuncMap()).
+		Option("missingkey=error").
+		Parse(string(manifest))
+	if err != nil {
+		return nil, trace.BadParameter("failed to parse manifest template: %v", err)
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{"Values": values})
+	if err != nil {
+		return nil, trace.BadParameter("failed to render manifest template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
 // processText replaces the value of "v" with the contents of the file
 // or downloaded content, or does not change it if it's neither "file://"
 // nor "http://"
@@ -17,8 +17,11 @@ limitations under the License.
 package schema
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
+	"github.com/gravitational/trace"
 	"gopkg.in/check.v1"
 )
 
@@ -35,3 +38,52 @@ func (s *TemplateSuite) TestExpandEnvVars(c *check.C) {
 	expanded := ExpandEnvVars(text)
 	c.Assert(string(expanded), check.Equals, "Hello world!")
 }
+
+func (s *TemplateSuite) TestRenderManifestTemplate(c *check.C) {
+	manifest := []byte(`metadata:
+  resourceVersion: {{ .Values.version }}
+image: nginx:{{ .Values.image.tag | default "latest" }}
+{{- if .Values.debug }}
+debug: true
+{{- end }}`)
+
+	rendered, err := RenderManifestTemplate("app.yaml", manifest, map[string]interface{}{
+		"version": "1.0.0",
+		"image":   map[string]interface{}{"tag": "1.15"},
+		"debug":   true,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(string(rendered), check.Equals, `metadata:
+  resourceVersion: 1.0.0
+image: nginx:1.15
+debug: true`)
+
+	_, err = RenderManifestTemplate("app.yaml", manifest, map[string]interface{}{
+		"image": map[string]interface{}{},
+	})
+	c.Assert(trace.IsBadParameter(err), check.Equals, true)
+	c.Assert(err, check.ErrorMatches, `.*map has no entry for key "version".*`)
+}
+
+func (s *TemplateSuite) TestProcessTerraformScripts(c *check.C) {
+	dir := c.MkDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "main.tf"), []byte(`resource "aws_vpc" "main" {}`), 0644)
+	c.Assert(err, check.IsNil)
+	manifest := &Manifest{
+		Providers: &Providers{
+			AWS: AWS{
+				Terraform: &Terraform{
+					Script:         "file://main.tf",
+					InstanceScript: "echo hello",
+				},
+			},
+		},
+	}
+
+	err = ProcessMultiSourceValues(manifest, filepath.Join(dir, "app.yaml"))
+	c.Assert(err, check.IsNil)
+	c.Assert(manifest.Providers.AWS.Terraform, check.DeepEquals, &Terraform{
+		Script:         `resource "aws_vpc" "main" {}`,
+		InstanceScript: "echo hello",
+	})
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	"github.com/santhosh-tekuri/jsonschema"
+)
+
+// ManifestIssue describes a single problem found in an application manifest
+type ManifestIssue struct {
+	// Path is the JSON pointer to the manifest field the issue refers to
+	Path string
+	// Line is the 1-based line of the field in the manifest,
+	// 0 if the location is unknown
+	Line int
+	// Column is the 1-based column of the field in the manifest
+	Column int
+	// Message describes the issue
+	Message string
+}
+
+// String returns a textual representation of this issue
+func (r ManifestIssue) String() string {
+	var location string
+	if r.Line != 0 {
+		location = fmt.Sprintf("line %v, column %v: ", r.Line, r.Column)
+	}
+	field := fieldName(r.Path)
+	if field == "" {
+		return location + r.Message
+	}
+	return fmt.Sprintf("%v%v: %v", location, field, r.Message)
+}
+
+// ValidationResult is the outcome of the application manifest validation
+type ValidationResult struct {
+	// Errors lists the violations of the manifest schema
+	Errors []ManifestIssue
+	// Warnings lists the usages of deprecated manifest fields
+	Warnings []ManifestIssue
+}
+
+// Check returns an error that describes all validation errors
+// or nil if the manifest is valid
+func (r ValidationResult) Check() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	var lines []string
+	for _, issue := range r.Errors {
+		lines = append(lines, fmt.Sprintf("  * %v", issue))
+	}
+	return trace.BadParameter("application manifest is invalid:\n%v",
+		strings.Join(lines, "\n"))
+}
+
+// ValidateManifestYAML validates the provided application manifest
+// against the manifest schema.
+//
+// Unlike the validation performed when the manifest is parsed, it
+// reports all schema violations at once, annotates them with the line
+// and column of the offending field and warns about deprecated fields.
+//
+// Returns an error only if the data is not a valid YAML document
+func ValidateManifestYAML(data []byte) (*ValidationResult, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, trace.BadParameter("failed to parse application manifest: %v", err)
+	}
+	var header Header
+	if err := json.Unmarshal(jsonData, &header); err != nil {
+		return nil, trace.BadParameter("application manifest is not an object: %v", err)
+	}
+	locator := newYAMLLocator(data)
+	result := &ValidationResult{}
+	switch header.APIVersion {
+	case APIVersionV2, APIVersionV2Cluster, APIVersionV2App:
+		doc, err := jsonschema.DecodeJSON(bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		validator := &schemaValidator{locator: locator}
+		validator.validate(schema, doc, "")
+		sort.SliceStable(validator.issues, func(i, j int) bool {
+			return validator.issues[i].Line < validator.issues[j].Line
+		})
+		result.Errors = validator.issues
+		result.Warnings = deprecationWarnings(header, locator)
+	case APIVersionV1:
+		result.Warnings = append(result.Warnings, newIssue(locator, "/apiVersion",
+			fmt.Sprintf("%q is deprecated, use %q instead",
+				header.APIVersion, APIVersionV2Cluster)))
+	case "":
+		result.Errors = append(result.Errors, newIssue(locator, "",
+			`missing properties: "apiVersion"`))
+	default:
+		result.Errors = append(result.Errors, newIssue(locator, "/apiVersion",
+			fmt.Sprintf("unknown API version %q, expected one of %q, %q",
+				header.APIVersion, APIVersionV2Cluster, APIVersionV2App)))
+	}
+	return result, nil
+}
+
+// schemaValidator collects the schema violations of a manifest
+type schemaValidator struct {
+	locator *yamlLocator
+	issues  []ManifestIssue
+}
+
+// validate validates the value at the specified JSON pointer against the schema.
+//
+// The validator stops at the first violation it encounters so objects and arrays
+// are validated field by field to find all violations in the manifest
+func (r *schemaValidator) validate(s *jsonschema.Schema, value interface{}, pointer string) {
+	for s.Ref != nil {
+		s = s.Ref
+	}
+	err := s.ValidateInterface(value)
+	if err == nil {
+		return
+	}
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		r.add(pointer, err.Error())
+		return
+	}
+	count := len(r.issues)
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if len(s.Properties) != 0 {
+			r.validateObject(s, value, pointer)
+		}
+	case []interface{}:
+		if items, ok := s.Items.(*jsonschema.Schema); ok {
+			for i, item := range value {
+				r.validate(items, item, fmt.Sprintf("%v/%v", pointer, i))
+			}
+		}
+	}
+	if len(r.issues) != count {
+		return
+	}
+	// The violation is not specific to any nested field
+	for _, cause := range leafErrors(validationErr) {
+		causePointer := pointer + strings.TrimPrefix(cause.InstancePtr, "#")
+		if names, ok := parseAdditionalProperties(cause.Message); ok {
+			for _, name := range names {
+				r.add(causePointer+"/"+escapePointerToken(name), "unknown field")
+			}
+			continue
+		}
+		r.add(causePointer, cause.Message)
+	}
+}
+
+// validateObject validates the fields of the object at the specified JSON pointer
+func (r *schemaValidator) validateObject(s *jsonschema.Schema, object map[string]interface{}, pointer string) {
+	var missing []string
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			missing = append(missing, strconv.Quote(name))
+		}
+	}
+	if len(missing) != 0 {
+		r.add(pointer, fmt.Sprintf("missing properties: %v", strings.Join(missing, ", ")))
+	}
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fieldPointer := pointer + "/" + escapePointerToken(name)
+		if property, ok := s.Properties[name]; ok {
+			r.validate(property, object[name], fieldPointer)
+			continue
+		}
+		if allowed, ok := s.AdditionalProperties.(bool); ok && !allowed && !matchesPattern(s, name) {
+			r.add(fieldPointer, "unknown field")
+		}
+	}
+}
+
+func (r *schemaValidator) add(pointer, message string) {
+	r.issues = append(r.issues, newIssue(r.locator, pointer, message))
+}
+
+// matchesPattern returns true if the specified field name matches
+// any of the pattern properties of the schema
+func matchesPattern(s *jsonschema.Schema, name string) bool {
+	for pattern := range s.PatternProperties {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// leafErrors returns the innermost causes of the specified validation error
+func leafErrors(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		leaves = append(leaves, leafErrors(cause)...)
+	}
+	return leaves
+}
+
+// parseAdditionalProperties extracts the names of the unknown fields
+// from the validation error message
+func parseAdditionalProperties(message string) (names []string, ok bool) {
+	const prefix, suffix = "additionalProperties ", " not allowed"
+	if !strings.HasPrefix(message, prefix) || !strings.HasSuffix(message, suffix) {
+		return nil, false
+	}
+	for _, quoted := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(message, prefix), suffix), ", ") {
+		name, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, false
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, true
+}
+
+// deprecationWarnings returns warnings for the deprecated values
+// used in the manifest header
+func deprecationWarnings(header Header, locator *yamlLocator) (warnings []ManifestIssue) {
+	if header.Kind == KindBundle {
+		warnings = append(warnings, newIssue(locator, "/kind",
+			fmt.Sprintf("%q is deprecated, use %q with API version %q instead",
+				KindBundle, KindCluster, APIVersionV2Cluster)))
+	}
+	return warnings
+}
+
+// newIssue returns a new issue for the field specified with the JSON pointer
+func newIssue(locator *yamlLocator, pointer, message string) ManifestIssue {
+	path := strings.TrimPrefix(pointer, "#")
+	issue := ManifestIssue{Path: path, Message: message}
+	if pos, ok := locator.locate(path); ok {
+		issue.Line = pos.line
+		issue.Column = pos.column
+	}
+	return issue
+}
+
+// fieldName converts the specified JSON pointer to a human-readable
+// field name, e.g. "/nodeProfiles/0/name" becomes "nodeProfiles[0].name"
+func fieldName(pointer string) string {
+	var name string
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(token); err == nil {
+			name = fmt.Sprintf("%v[%v]", name, token)
+			continue
+		}
+		if name != "" {
+			name += "."
+		}
+		name += unescapePointerToken(token)
+	}
+	return name
+}
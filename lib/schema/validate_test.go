@@ -0,0 +1,133 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type ValidateSuite struct{}
+
+var _ = Suite(&ValidateSuite{})
+
+func (s *ValidateSuite) TestReportsErrorLocations(c *C) {
+	result, err := ValidateManifestYAML([]byte(`apiVersion: cluster.gravitational.io/v2
+kind: Cluster
+metadata:
+  name: test
+  resourceVersion: 0.0.1
+  # comment with a colon: ignored
+  description: |
+    multi-line description
+    unknown: not a field
+nodeProfiles:
+  - name: node
+    description: "worker node"
+    requirements:
+      cpu:
+        min: two
+  - name: master
+    unexpected: true
+installer:
+  flavors:
+    items:
+    - name: one
+      profiles: {node: {count: "1"}}
+`))
+	c.Assert(err, IsNil)
+	c.Assert(result.Warnings, HasLen, 0)
+	c.Assert(result.Errors, DeepEquals, []ManifestIssue{
+		{
+			Path:    "/nodeProfiles/0/requirements/cpu/min",
+			Line:    15,
+			Column:  9,
+			Message: "expected number, but got string",
+		},
+		{
+			Path:    "/nodeProfiles/1/unexpected",
+			Line:    17,
+			Column:  5,
+			Message: "unknown field",
+		},
+		{
+			Path:    "/installer/flavors/items/0",
+			Line:    21,
+			Column:  5,
+			Message: `missing properties: "nodes"`,
+		},
+		{
+			Path:    "/installer/flavors/items/0/profiles",
+			Line:    22,
+			Column:  7,
+			Message: "unknown field",
+		},
+	})
+	c.Assert(result.Errors[1].String(), Equals,
+		"line 17, column 5: nodeProfiles[1].unexpected: unknown field")
+	err = result.Check()
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+}
+
+func (s *ValidateSuite) TestWarnsAboutDeprecatedHeaders(c *C) {
+	result, err := ValidateManifestYAML([]byte(`apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: test
+  resourceVersion: 0.0.1
+`))
+	c.Assert(err, IsNil)
+	c.Assert(result.Check(), IsNil)
+	c.Assert(result.Warnings, DeepEquals, []ManifestIssue{
+		{
+			Path:    "/kind",
+			Line:    2,
+			Column:  1,
+			Message: `"Bundle" is deprecated, use "Cluster" with API version "cluster.gravitational.io/v2" instead`,
+		},
+	})
+	c.Assert(result.Warnings[0].String(), Equals, `line 2, column 1: kind: "Bundle" is deprecated, `+
+		`use "Cluster" with API version "cluster.gravitational.io/v2" instead`)
+
+	result, err = ValidateManifestYAML([]byte(`# legacy manifest
+apiVersion: v1
+kind: Application
+`))
+	c.Assert(err, IsNil)
+	c.Assert(result.Warnings, DeepEquals, []ManifestIssue{{
+		Path:    "/apiVersion",
+		Line:    2,
+		Column:  1,
+		Message: `"v1" is deprecated, use "cluster.gravitational.io/v2" instead`,
+	}})
+}
+
+func (s *ValidateSuite) TestRejectsUnknownAPIVersion(c *C) {
+	result, err := ValidateManifestYAML([]byte(`apiVersion: example.com/v3
+kind: Cluster
+`))
+	c.Assert(err, IsNil)
+	c.Assert(result.Errors, DeepEquals, []ManifestIssue{{
+		Path:    "/apiVersion",
+		Line:    1,
+		Column:  1,
+		Message: `unknown API version "example.com/v3", expected one of "cluster.gravitational.io/v2", "app.gravitational.io/v2"`,
+	}})
+
+	_, err = ValidateManifestYAML([]byte("apiVersion: [unterminated"))
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package signature implements signing of cluster and application images
+// and verification of their signatures.
+//
+// The signature is detached: it is stored in a separate file inside the image
+// and covers the image manifest and the digests of its layers, i.e. package
+// blobs, registry layers and application resources.
+package signature
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/ed25519"
+)
+
+// Signature is the detached signature of a cluster or application image
+type Signature struct {
+	// Version is the signature format version
+	Version string `json:"version"`
+	// KeyID identifies the public key the signature can be verified with
+	KeyID string `json:"key_id"`
+	// Digests lists digests of the signed files
+	Digests []Digest `json:"digests"`
+	// Signature is the signature over the digests
+	Signature []byte `json:"signature"`
+}
+
+// Digest is the digest of a single signed file
+type Digest struct {
+	// Path is the slash-separated path of the file relative to the image root
+	Path string `json:"path"`
+	// SHA256 is the hex-encoded SHA256 hash of the file contents
+	SHA256 string `json:"sha256"`
+}
+
+// NewDigest returns the digest of data stored at the specified path
+func NewDigest(path string, data []byte) Digest {
+	hash := sha256.Sum256(data)
+	return Digest{
+		Path:   filepath.ToSlash(path),
+		SHA256: hex.EncodeToString(hash[:]),
+	}
+}
+
+// GenerateKey generates a new PEM-encoded key pair to sign images with
+func GenerateKey() (privateKeyPEM, publicKeyPEM []byte, err error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	privateKeyPEM = pem.EncodeToMemory(&pem.Block{Type: privateKeyType, Bytes: private})
+	publicKeyPEM = pem.EncodeToMemory(&pem.Block{Type: publicKeyType, Bytes: public})
+	return privateKeyPEM, publicKeyPEM, nil
+}
+
+// KeyID returns the ID of the specified PEM-encoded public key
+func KeyID(publicKeyPEM []byte) (string, error) {
+	public, err := parsePublicKey(publicKeyPEM)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return keyID(public), nil
+}
+
+// Sign signs the specified digests with the PEM-encoded private key
+func Sign(digests []Digest, privateKeyPEM []byte) (*Signature, error) {
+	private, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	digests = sortDigests(digests)
+	return &Signature{
+		Version:   version,
+		KeyID:     keyID(private.Public().(ed25519.PublicKey)),
+		Digests:   digests,
+		Signature: ed25519.Sign(private, payload(digests)),
+	}, nil
+}
+
+// Verify verifies the signature of the image unpacked in dir with
+// the provided trusted keys.
+//
+// Returns trace.NotFound if the image is not signed and trace.AccessDenied
+// if it has not been signed with any of the trusted keys or its contents
+// do not match the signature
+func Verify(dir string, keys []storage.TrustedKey) (*storage.TrustedKey, error) {
+	signature, err := Read(dir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	key, public, err := findKey(signature.KeyID, keys)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if !ed25519.Verify(public, payload(signature.Digests), signature.Signature) {
+		return nil, trace.AccessDenied("signature of %v is invalid", dir)
+	}
+	digests, err := Digests(dir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	signed := make(map[string]string, len(signature.Digests))
+	for _, digest := range signature.Digests {
+		signed[digest.Path] = digest.SHA256
+	}
+	for _, digest := range digests {
+		hash, ok := signed[digest.Path]
+		if !ok {
+			return nil, trace.AccessDenied("%v is not covered by the signature", digest.Path)
+		}
+		if hash != digest.SHA256 {
+			return nil, trace.AccessDenied("%v does not match the signature", digest.Path)
+		}
+		delete(signed, digest.Path)
+	}
+	for path := range signed {
+		return nil, trace.AccessDenied("signed file %v is missing", path)
+	}
+	return key, nil
+}
+
+// Read returns the signature of the image unpacked in dir.
+// Returns trace.NotFound if the image is not signed
+func Read(dir string) (*Signature, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, defaults.SignatureFileName))
+	if err != nil {
+		err = trace.ConvertSystemError(err)
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("%v is not signed", dir)
+		}
+		return nil, trace.Wrap(err)
+	}
+	var signature Signature
+	if err := json.Unmarshal(data, &signature); err != nil {
+		return nil, trace.Wrap(err, "failed to parse signature of %v", dir)
+	}
+	if signature.Version != version {
+		return nil, trace.BadParameter("unsupported signature version %q", signature.Version)
+	}
+	return &signature, nil
+}
+
+// Digests computes digests of the files of the image unpacked in dir
+// that are covered by its signature: the image manifest, application
+// resources, package blobs and registry layers
+func Digests(dir string) (digests []Digest, err error) {
+	for _, path := range []string{
+		defaults.ManifestFileName,
+		defaults.ResourcesDir,
+		filepath.Join(defaults.PackagesDir, blobsDir),
+		defaults.RegistryDir,
+	} {
+		err := filepath.Walk(filepath.Join(dir, path), func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return trace.ConvertSystemError(err)
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+			relPath, err := filepath.Rel(dir, path)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			digest, err := fileDigest(path)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			digest.Path = filepath.ToSlash(relPath)
+			digests = append(digests, *digest)
+			return nil
+		})
+		if err != nil && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return sortDigests(digests), nil
+}
+
+func fileDigest(path string) (*Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Digest{SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+func findKey(id string, keys []storage.TrustedKey) (*storage.TrustedKey, ed25519.PublicKey, error) {
+	for _, key := range keys {
+		public, err := parsePublicKey(key.PublicKey)
+		if err != nil {
+			return nil, nil, trace.Wrap(err, "invalid trusted key %q", key.Name)
+		}
+		if keyID(public) == id {
+			return &key, public, nil
+		}
+	}
+	return nil, nil, trace.AccessDenied("image has been signed with key %v which is not trusted", id)
+}
+
+// payload returns the signed representation of the digests
+func payload(digests []Digest) []byte {
+	var buf bytes.Buffer
+	for _, digest := range digests {
+		fmt.Fprintf(&buf, "%v  %v\n", digest.SHA256, digest.Path)
+	}
+	return buf.Bytes()
+}
+
+func sortDigests(digests []Digest) []Digest {
+	sorted := make([]Digest, len(digests))
+	copy(sorted, digests)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+	return sorted
+}
+
+func keyID(public ed25519.PublicKey) string {
+	hash := sha256.Sum256(public)
+	return hex.EncodeToString(hash[:keyIDSize])
+}
+
+func parsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != privateKeyType || len(block.Bytes) != ed25519.PrivateKeySize {
+		return nil, trace.BadParameter("expected PEM-encoded %v", privateKeyType)
+	}
+	return ed25519.PrivateKey(block.Bytes), nil
+}
+
+func parsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != publicKeyType || len(block.Bytes) != ed25519.PublicKeySize {
+		return nil, trace.BadParameter("expected PEM-encoded %v", publicKeyType)
+	}
+	return ed25519.PublicKey(block.Bytes), nil
+}
+
+const (
+	// version is the current signature format version
+	version = "v1"
+	// privateKeyType is the PEM block type of signing keys
+	privateKeyType = "ED25519 PRIVATE KEY"
+	// publicKeyType is the PEM block type of verification keys
+	publicKeyType = "ED25519 PUBLIC KEY"
+	// keyIDSize is the number of public key hash bytes used as key ID
+	keyIDSize = 8
+	// blobsDir is the name of the package blobs directory
+	blobsDir = "blobs"
+)
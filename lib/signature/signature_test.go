@@ -0,0 +1,142 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	check "gopkg.in/check.v1"
+)
+
+func TestSignature(t *testing.T) { check.TestingT(t) }
+
+type SignatureSuite struct {
+	dir        string
+	privateKey []byte
+	trusted    storage.TrustedKey
+}
+
+var _ = check.Suite(&SignatureSuite{})
+
+func (s *SignatureSuite) SetUpTest(c *check.C) {
+	s.dir = c.MkDir()
+	writeFile(c, s.dir, defaults.ManifestFileName, "kind: Cluster")
+	writeFile(c, s.dir, "packages/blobs/abc/abcdef", "package")
+	writeFile(c, s.dir, "gravity.db", "unsigned")
+	privateKey, publicKey, err := GenerateKey()
+	c.Assert(err, check.IsNil)
+	s.privateKey = privateKey
+	s.trusted = storage.TrustedKey{Name: "release", PublicKey: publicKey}
+}
+
+func (s *SignatureSuite) TestVerifiesSignedImage(c *check.C) {
+	signature := s.sign(c)
+	c.Assert(signature.Digests, check.HasLen, 2)
+
+	key, err := Verify(s.dir, []storage.TrustedKey{s.trusted})
+	c.Assert(err, check.IsNil)
+	c.Assert(key.Name, check.Equals, "release")
+
+	// files not covered by the signature can change
+	writeFile(c, s.dir, "gravity.db", "modified")
+	_, err = Verify(s.dir, []storage.TrustedKey{s.trusted})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *SignatureSuite) TestRejectsUnsignedImage(c *check.C) {
+	_, err := Verify(s.dir, []storage.TrustedKey{s.trusted})
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *SignatureSuite) TestRejectsUntrustedKey(c *check.C) {
+	s.sign(c)
+	_, publicKey, err := GenerateKey()
+	c.Assert(err, check.IsNil)
+	_, err = Verify(s.dir, []storage.TrustedKey{{Name: "other", PublicKey: publicKey}})
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+	_, err = Verify(s.dir, nil)
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *SignatureSuite) TestRejectsModifiedImage(c *check.C) {
+	for _, tc := range []struct {
+		comment string
+		modify  func()
+	}{
+		{
+			comment: "modified manifest",
+			modify: func() {
+				writeFile(c, s.dir, defaults.ManifestFileName, "kind: Application")
+			},
+		},
+		{
+			comment: "added layer",
+			modify: func() {
+				writeFile(c, s.dir, "packages/blobs/def/defabc", "injected")
+			},
+		},
+		{
+			comment: "removed layer",
+			modify: func() {
+				c.Assert(os.Remove(filepath.Join(s.dir, "packages/blobs/abc/abcdef")), check.IsNil)
+			},
+		},
+	} {
+		s.SetUpTest(c)
+		s.sign(c)
+		tc.modify()
+		_, err := Verify(s.dir, []storage.TrustedKey{s.trusted})
+		c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf(tc.comment))
+	}
+}
+
+func (s *SignatureSuite) TestRejectsForgedDigests(c *check.C) {
+	signature := s.sign(c)
+	writeFile(c, s.dir, defaults.ManifestFileName, "kind: Application")
+	signature.Digests[0] = NewDigest(defaults.ManifestFileName, []byte("kind: Application"))
+	data, err := json.Marshal(signature)
+	c.Assert(err, check.IsNil)
+	writeFile(c, s.dir, defaults.SignatureFileName, string(data))
+
+	_, err = Verify(s.dir, []storage.TrustedKey{s.trusted})
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *SignatureSuite) sign(c *check.C) *Signature {
+	digests, err := Digests(s.dir)
+	c.Assert(err, check.IsNil)
+	signature, err := Sign(digests, s.privateKey)
+	c.Assert(err, check.IsNil)
+	data, err := json.Marshal(signature)
+	c.Assert(err, check.IsNil)
+	writeFile(c, s.dir, defaults.SignatureFileName, string(data))
+	return signature
+}
+
+func writeFile(c *check.C, dir, path, data string) {
+	path = filepath.Join(dir, path)
+	c.Assert(os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask), check.IsNil)
+	c.Assert(ioutil.WriteFile(path, []byte(data), defaults.SharedReadMask), check.IsNil)
+}
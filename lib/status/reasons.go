@@ -0,0 +1,185 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	pb "github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/satellite/monitoring"
+)
+
+// NewProbeFailure returns the description of the failed probe
+// with the reason code, remediation hint and the command to display
+// the relevant logs
+func NewProbeFailure(p pb.Probe) storage.ProbeFailure {
+	reason, ok := probeReasons[p.Checker]
+	if !ok {
+		reason = unknownProbeReason
+	}
+	failure := storage.ProbeFailure{
+		Checker: p.Checker,
+		Detail:  p.Detail,
+		Error:   p.Error,
+		Code:    reason.code,
+		Hint:    reason.hint,
+		Logs:    reason.logs,
+	}
+	// systemd checker reports the name of the failed unit as detail
+	if p.Checker == systemdCheckerID && p.Detail != "" {
+		failure.Logs = unitLogs(p.Detail)
+	}
+	return failure
+}
+
+// probeReason describes the reason a probe of a specific checker fails
+type probeReason struct {
+	// code is the machine-readable reason code
+	code string
+	// hint suggests how to remediate the failure
+	hint string
+	// logs is the command that displays the relevant logs
+	logs string
+}
+
+// unitLogs returns the command that displays the logs of the specified
+// systemd unit running inside planet
+func unitLogs(unit string) string {
+	return fmt.Sprintf("sudo gravity exec journalctl -u %v --no-pager -n 100", unit)
+}
+
+var probeReasons = map[string]probeReason{
+	etcdCheckerID: {
+		code: ReasonEtcdUnhealthy,
+		hint: "Check etcd member health with 'gravity etcd status' and make sure the majority of masters are online",
+		logs: unitLogs("etcd"),
+	},
+	kubeAPIServerCheckerID: {
+		code: ReasonKubeAPIServerUnhealthy,
+		hint: "Make sure etcd is healthy and the kube-apiserver service is running on master nodes",
+		logs: unitLogs("kube-apiserver"),
+	},
+	kubeletCheckerID: {
+		code: ReasonKubeletUnhealthy,
+		hint: "Make sure the kube-kubelet service is running and the node has enough resources",
+		logs: unitLogs("kube-kubelet"),
+	},
+	dockerCheckerID: {
+		code: ReasonDockerUnhealthy,
+		hint: "Make sure the docker service is running and its storage is not exhausted",
+		logs: unitLogs("docker"),
+	},
+	systemdCheckerID: {
+		code: ReasonServiceFailed,
+		hint: "Inspect the failed service with 'sudo gravity exec systemctl status <service>'",
+		logs: unitLogs("planet-agent"),
+	},
+	monitoring.NodeStatusCheckerID: {
+		code: ReasonNodeNotReady,
+		hint: "Check the node conditions with 'kubectl describe node' and the kubelet logs",
+		logs: unitLogs("kube-kubelet"),
+	},
+	monitoring.NodesStatusCheckerID: {
+		code: ReasonNodesNotReady,
+		hint: "Some nodes are not ready, check them with 'kubectl get nodes'",
+		logs: unitLogs("kube-kubelet"),
+	},
+	monitoring.DiskSpaceCheckerID: {
+		code: ReasonDiskSpaceLow,
+		hint: "Free up disk space on the node, e.g. by removing unused images with 'gravity gc'",
+	},
+	timeDriftCheckerID: {
+		code: ReasonTimeDrift,
+		hint: "Make sure the node clocks are synchronized, e.g. with NTP",
+		logs: unitLogs("planet-agent"),
+	},
+	networkingCheckerID: {
+		code: ReasonNetworkingFailed,
+		hint: "Make sure the overlay network ports are open between nodes",
+		logs: unitLogs("flanneld"),
+	},
+	dnsCheckerID: {
+		code: ReasonDNSFailed,
+		hint: "Make sure the CoreDNS pods are running and the upstream nameservers are reachable",
+		logs: unitLogs("coredns"),
+	},
+	monitoring.KernelModuleCheckerID: {
+		code: ReasonKernelModuleMissing,
+		hint: "Load the missing kernel module with modprobe and configure it to load on boot",
+	},
+	monitoring.IPForwardCheckerID: {
+		code: ReasonSysctlMisconfigured,
+		hint: "Enable IP forwarding with 'sysctl -w net.ipv4.ip_forward=1'",
+	},
+	monitoring.NetfilterCheckerID: {
+		code: ReasonSysctlMisconfigured,
+		hint: "Load the br_netfilter kernel module and enable 'net.bridge.bridge-nf-call-iptables'",
+	},
+	monitoring.MountsCheckerID: {
+		code: ReasonSysctlMisconfigured,
+		hint: "Enable 'fs.may_detach_mounts' with sysctl",
+	},
+}
+
+var unknownProbeReason = probeReason{
+	code: ReasonProbeFailed,
+	logs: unitLogs("planet-agent"),
+}
+
+const (
+	// ReasonEtcdUnhealthy means etcd cluster is not healthy
+	ReasonEtcdUnhealthy = "EtcdUnhealthy"
+	// ReasonKubeAPIServerUnhealthy means Kubernetes API server is not healthy
+	ReasonKubeAPIServerUnhealthy = "KubeAPIServerUnhealthy"
+	// ReasonKubeletUnhealthy means kubelet is not healthy
+	ReasonKubeletUnhealthy = "KubeletUnhealthy"
+	// ReasonDockerUnhealthy means docker daemon is not healthy
+	ReasonDockerUnhealthy = "DockerUnhealthy"
+	// ReasonServiceFailed means a system service has failed
+	ReasonServiceFailed = "ServiceFailed"
+	// ReasonNodeNotReady means the Kubernetes node is not ready
+	ReasonNodeNotReady = "NodeNotReady"
+	// ReasonNodesNotReady means some Kubernetes nodes are not ready
+	ReasonNodesNotReady = "NodesNotReady"
+	// ReasonDiskSpaceLow means the node is running out of disk space
+	ReasonDiskSpaceLow = "DiskSpaceLow"
+	// ReasonTimeDrift means the node clocks are out of sync
+	ReasonTimeDrift = "TimeDrift"
+	// ReasonNetworkingFailed means pods cannot communicate across nodes
+	ReasonNetworkingFailed = "NetworkingFailed"
+	// ReasonDNSFailed means cluster DNS is not functional
+	ReasonDNSFailed = "DNSFailed"
+	// ReasonKernelModuleMissing means a required kernel module is not loaded
+	ReasonKernelModuleMissing = "KernelModuleMissing"
+	// ReasonSysctlMisconfigured means a required kernel parameter is not set
+	ReasonSysctlMisconfigured = "SysctlMisconfigured"
+	// ReasonProbeFailed is the reason of the probes without a more specific code
+	ReasonProbeFailed = "ProbeFailed"
+)
+
+const (
+	etcdCheckerID          = "etcd-healthz"
+	kubeAPIServerCheckerID = "kube-apiserver"
+	kubeletCheckerID       = "kubelet"
+	dockerCheckerID        = "docker"
+	systemdCheckerID       = "systemd"
+	timeDriftCheckerID     = "time-drift"
+	networkingCheckerID    = "networking"
+	dnsCheckerID           = "dns"
+)
@@ -21,7 +21,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/gravitational/gravity/lib/constants"
@@ -82,9 +84,9 @@ func FromCluster(ctx context.Context, operator ops.Operator, cluster ops.Site, o
 	masters := cluster.ClusterState.Servers.Masters()
 	for _, master := range masters {
 		status.Endpoints.Cluster.AuthGateway = append(status.Endpoints.Cluster.AuthGateway,
-			fmt.Sprintf("%v:%v", master.AdvertiseIP, defaults.GravitySiteNodePort))
+			net.JoinHostPort(master.AdvertiseIP, strconv.Itoa(defaults.GravitySiteNodePort)))
 		status.Endpoints.Cluster.UI = append(status.Endpoints.Cluster.UI,
-			fmt.Sprintf("https://%v:%v", master.AdvertiseIP, defaults.GravitySiteNodePort))
+			fmt.Sprintf("https://%v", net.JoinHostPort(master.AdvertiseIP, strconv.Itoa(defaults.GravitySiteNodePort))))
 	}
 
 	// FIXME: have status extension accept the operator/environment
@@ -141,6 +143,29 @@ func FromLocalPlanetAgent(ctx context.Context) (*Agent, error) {
 	return fromPlanetAgent(ctx, true, nil)
 }
 
+// PlanetAgentStatus returns the cluster status as reported by the planet agent
+func PlanetAgentStatus(ctx context.Context) (*pb.SystemStatus, error) {
+	status, err := planetAgentStatus(ctx, false)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to query cluster status from agent")
+	}
+	return status, nil
+}
+
+// FailedProbes returns the failed probes of the specified system status
+// keyed by the advertise IP of the node that reported them
+func FailedProbes(systemStatus pb.SystemStatus) map[string][]pb.Probe {
+	out := make(map[string][]pb.Probe)
+	for advertiseIP, node := range nodes(systemStatus) {
+		for _, probe := range node.Probes {
+			if probe.Status != pb.Probe_Running {
+				out[advertiseIP] = append(out[advertiseIP], *probe)
+			}
+		}
+	}
+	return out
+}
+
 func fromPlanetAgent(ctx context.Context, local bool, servers []storage.Server) (*Agent, error) {
 	status, err := planetAgentStatus(ctx, local)
 	if err != nil {
@@ -349,6 +374,9 @@ type ClusterServer struct {
 	Status string `json:"status"`
 	// FailedProbes lists all failed probes if the node is not healthy
 	FailedProbes []string `json:"failed_probes,omitempty"`
+	// Probes describes the failed probes with their reason codes
+	// and remediation hints
+	Probes []storage.ProbeFailure `json:"probes,omitempty"`
 }
 
 func (r ClusterOperation) isFailed() bool {
@@ -461,6 +489,7 @@ func fromNodeStatus(node pb.NodeStatus) (status ClusterServer) {
 		if probe.Status != pb.Probe_Running {
 			status.FailedProbes = append(status.FailedProbes,
 				probeErrorDetail(*probe))
+			status.Probes = append(status.Probes, NewProbeFailure(*probe))
 		}
 	}
 	if len(status.FailedProbes) != 0 {
@@ -0,0 +1,125 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/gravitational/trace"
+)
+
+// CatalogEntries persists the applications published
+// to the application catalog
+type CatalogEntries interface {
+	// UpsertCatalogEntry publishes an application to the catalog
+	// or updates its catalog metadata
+	UpsertCatalogEntry(CatalogEntry) (*CatalogEntry, error)
+	// GetCatalogEntry returns the catalog entry for the specified application
+	GetCatalogEntry(repository, name, version string) (*CatalogEntry, error)
+	// GetCatalogEntries returns all catalog entries in the specified repository
+	// ordered by application name and version
+	GetCatalogEntries(repository string) ([]CatalogEntry, error)
+	// DeleteCatalogEntry removes the specified application from the catalog
+	DeleteCatalogEntry(repository, name, version string) error
+}
+
+// CatalogEntry describes an application published to the application catalog
+type CatalogEntry struct {
+	// Repository is the repository the application is published to
+	Repository string `json:"repository"`
+	// Name is the application name
+	Name string `json:"name"`
+	// Version is the application version
+	Version string `json:"version"`
+	// Channel is the release channel the application is published to
+	Channel string `json:"channel"`
+	// Icon is the application icon, either a URL or a data URI
+	Icon string `json:"icon,omitempty"`
+	// Readme is the application description in Markdown format
+	Readme string `json:"readme,omitempty"`
+	// Upgrade describes how existing installations upgrade to this version
+	Upgrade CatalogUpgrade `json:"upgrade"`
+	// Published is the time the application was published
+	Published time.Time `json:"published"`
+	// PublishedBy is the user who published the application
+	PublishedBy string `json:"published_by,omitempty"`
+}
+
+// CatalogUpgrade describes the upgrade metadata of a catalog application
+type CatalogUpgrade struct {
+	// MinVersion is the minimum application version that can be
+	// upgraded to this version directly
+	MinVersion string `json:"min_version,omitempty"`
+	// ReleaseNotes describes the changes in this version
+	ReleaseNotes string `json:"release_notes,omitempty"`
+}
+
+// Locator returns the locator of the published application
+func (e CatalogEntry) Locator() loc.Locator {
+	return loc.Locator{
+		Repository: e.Repository,
+		Name:       e.Name,
+		Version:    e.Version,
+	}
+}
+
+// Check validates the catalog entry
+func (e CatalogEntry) Check() error {
+	if e.Repository == "" {
+		return trace.BadParameter("missing catalog entry repository")
+	}
+	if e.Name == "" {
+		return trace.BadParameter("missing catalog entry name")
+	}
+	version, err := semver.NewVersion(e.Version)
+	if err != nil {
+		return trace.BadParameter("invalid catalog entry version %q: %v", e.Version, err)
+	}
+	if !utils.StringInSlice(CatalogChannels, e.Channel) {
+		return trace.BadParameter("unsupported release channel %q, supported are: %v",
+			e.Channel, CatalogChannels)
+	}
+	if e.Upgrade.MinVersion != "" {
+		minVersion, err := semver.NewVersion(e.Upgrade.MinVersion)
+		if err != nil {
+			return trace.BadParameter("invalid minimum upgrade version %q: %v",
+				e.Upgrade.MinVersion, err)
+		}
+		if version.LessThan(*minVersion) {
+			return trace.BadParameter("minimum upgrade version %v is newer than %v",
+				minVersion, version)
+		}
+	}
+	if e.Published.IsZero() {
+		return trace.BadParameter("missing catalog entry publication time")
+	}
+	return nil
+}
+
+const (
+	// CatalogChannelStable is the release channel for stable applications
+	CatalogChannelStable = "stable"
+	// CatalogChannelBeta is the release channel for pre-release applications
+	CatalogChannelBeta = "beta"
+)
+
+// CatalogChannels lists the supported release channels
+var CatalogChannels = []string{CatalogChannelStable, CatalogChannelBeta}
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+type CatalogSuite struct{}
+
+var _ = check.Suite(&CatalogSuite{})
+
+func (s *CatalogSuite) TestValidation(c *check.C) {
+	valid := CatalogEntry{
+		Repository: "example.com",
+		Name:       "app",
+		Version:    "1.2.0",
+		Channel:    CatalogChannelStable,
+		Upgrade:    CatalogUpgrade{MinVersion: "1.0.0"},
+		Published:  time.Now(),
+	}
+	c.Assert(valid.Check(), check.IsNil)
+
+	testCases := []struct {
+		update  func(*CatalogEntry)
+		comment string
+	}{
+		{
+			update:  func(e *CatalogEntry) { e.Version = "latest" },
+			comment: "invalid version",
+		},
+		{
+			update:  func(e *CatalogEntry) { e.Channel = "nightly" },
+			comment: "unsupported channel",
+		},
+		{
+			update:  func(e *CatalogEntry) { e.Upgrade.MinVersion = "1.3.0" },
+			comment: "minimum upgrade version newer than the application",
+		},
+		{
+			update:  func(e *CatalogEntry) { e.Published = time.Time{} },
+			comment: "missing publication time",
+		},
+	}
+	for _, tc := range testCases {
+		entry := valid
+		tc.update(&entry)
+		c.Assert(entry.Check(), check.NotNil, check.Commentf(tc.comment))
+	}
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/configure/cstrings"
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+)
+
+// ClusterDNS defines a resource that configures the upstream nameservers
+// and the host and zone overrides of the cluster DNS service
+type ClusterDNS interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// CheckAndSetDefaults validates the resource and fills in defaults
+	CheckAndSetDefaults() error
+	// GetUpstreams returns the nameservers the cluster DNS forwards
+	// queries to
+	GetUpstreams() []string
+	// IsRotate returns true if queries should be distributed among
+	// the upstream nameservers in random order
+	IsRotate() bool
+	// GetHosts returns the mapping of hostnames to the IP addresses
+	// they resolve to
+	GetHosts() map[string]string
+	// GetZones returns the mapping of DNS zones to the nameservers
+	// they are served by
+	GetZones() map[string][]string
+}
+
+// NewClusterDNS creates a new cluster DNS resource for the provided spec
+func NewClusterDNS(spec ClusterDNSSpecV1) ClusterDNS {
+	return &ClusterDNSV1{
+		Kind:    KindClusterDNS,
+		Version: teleservices.V1,
+		Metadata: teleservices.Metadata{
+			Name:      KindClusterDNS,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// ClusterDNSV1 defines the cluster DNS resource
+type ClusterDNSV1 struct {
+	// Kind is the resource kind
+	Kind string `json:"kind"`
+	// Version is the resource version
+	Version string `json:"version"`
+	// Metadata is the resource metadata
+	teleservices.Metadata `json:"metadata"`
+	// Spec is the resource spec
+	Spec ClusterDNSSpecV1 `json:"spec"`
+}
+
+// ClusterDNSSpecV1 defines the cluster DNS configuration
+type ClusterDNSSpecV1 struct {
+	// Upstreams lists the nameservers to forward queries to
+	// in the format <ip> or <ip>:<port>.
+	// If unspecified, the nameservers of the host are used
+	Upstreams []string `json:"upstreams,omitempty"`
+	// Rotate distributes queries among the upstream nameservers
+	// in random order instead of trying them sequentially
+	Rotate bool `json:"rotate,omitempty"`
+	// Hosts maps a hostname to an IP address it will resolve to
+	Hosts map[string]string `json:"hosts,omitempty"`
+	// Zones maps a DNS zone to nameservers it will be served by
+	Zones map[string][]string `json:"zones,omitempty"`
+}
+
+// GetUpstreams returns the nameservers the cluster DNS forwards queries to
+func (r *ClusterDNSV1) GetUpstreams() []string {
+	return r.Spec.Upstreams
+}
+
+// IsRotate returns true if queries should be distributed among
+// the upstream nameservers in random order
+func (r *ClusterDNSV1) IsRotate() bool {
+	return r.Spec.Rotate
+}
+
+// GetHosts returns the mapping of hostnames to the IP addresses
+// they resolve to
+func (r *ClusterDNSV1) GetHosts() map[string]string {
+	return r.Spec.Hosts
+}
+
+// GetZones returns the mapping of DNS zones to the nameservers
+// they are served by
+func (r *ClusterDNSV1) GetZones() map[string][]string {
+	return r.Spec.Zones
+}
+
+// CheckAndSetDefaults validates the resource and fills in defaults
+func (r *ClusterDNSV1) CheckAndSetDefaults() error {
+	if r.Metadata.Name == "" {
+		r.Metadata.Name = KindClusterDNS
+	}
+	if err := r.Metadata.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	for _, nameserver := range r.Spec.Upstreams {
+		if err := utils.ValidateNameserver(nameserver); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	for hostname, ip := range r.Spec.Hosts {
+		if !cstrings.IsValidDomainName(hostname) {
+			return trace.BadParameter("%q is not a valid domain name", hostname)
+		}
+		if net.ParseIP(ip) == nil {
+			return trace.BadParameter("%q is not a valid IP address", ip)
+		}
+	}
+	for zone, nameservers := range r.Spec.Zones {
+		if !cstrings.IsValidDomainName(zone) {
+			return trace.BadParameter("%q is not a valid domain name", zone)
+		}
+		if len(nameservers) == 0 {
+			return trace.BadParameter("zone %q has no nameservers", zone)
+		}
+		for _, nameserver := range nameservers {
+			if err := utils.ValidateNameserver(nameserver); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+	}
+	return nil
+}
+
+// GetName returns the resource name
+func (r *ClusterDNSV1) GetName() string {
+	return r.Metadata.Name
+}
+
+// SetName sets the resource name
+func (r *ClusterDNSV1) SetName(name string) {
+	r.Metadata.Name = name
+}
+
+// GetMetadata returns the resource metadata
+func (r *ClusterDNSV1) GetMetadata() teleservices.Metadata {
+	return r.Metadata
+}
+
+// SetExpiry sets the resource expiration time
+func (r *ClusterDNSV1) SetExpiry(expires time.Time) {
+	r.Metadata.SetExpiry(expires)
+}
+
+// Expiry returns the resource expiration time
+func (r *ClusterDNSV1) Expiry() time.Time {
+	return r.Metadata.Expiry()
+}
+
+// SetTTL sets the resource TTL
+func (r *ClusterDNSV1) SetTTL(clock clockwork.Clock, ttl time.Duration) {
+	r.Metadata.SetTTL(clock, ttl)
+}
+
+// String returns the object's string representation
+func (r ClusterDNSV1) String() string {
+	return fmt.Sprintf("ClusterDNSV1(Upstreams=%v, Rotate=%v, Hosts=%v, Zones=%v)",
+		r.Spec.Upstreams, r.Spec.Rotate, r.Spec.Hosts, r.Spec.Zones)
+}
+
+// UnmarshalClusterDNS unmarshals cluster DNS resource from the provided JSON data
+func UnmarshalClusterDNS(data []byte) (ClusterDNS, error) {
+	if len(data) == 0 {
+		return nil, trace.BadParameter("empty input")
+	}
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var header teleservices.ResourceHeader
+	err = json.Unmarshal(jsonData, &header)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch header.Version {
+	case teleservices.V1:
+		var dns ClusterDNSV1
+		err := teleutils.UnmarshalWithSchema(GetClusterDNSSchema(), &dns, jsonData)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		err = dns.CheckAndSetDefaults()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &dns, nil
+	}
+	return nil, trace.BadParameter("%v resource version %q is not supported",
+		KindClusterDNS, header.Version)
+}
+
+// MarshalClusterDNS marshals the provided cluster DNS resource to JSON
+func MarshalClusterDNS(dns ClusterDNS, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(dns)
+}
+
+// GetClusterDNSSchema returns the full cluster DNS resource schema
+func GetClusterDNSSchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, MetadataSchema,
+		ClusterDNSSpecV1Schema, "")
+}
+
+// ClusterDNSSpecV1Schema defines the cluster DNS spec schema
+const ClusterDNSSpecV1Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "upstreams": {"type": "array", "items": {"type": "string"}},
+    "rotate": {"type": "boolean"},
+    "hosts": {
+      "type": "object",
+      "patternProperties": {
+        "^.*$": {"type": "string"}
+      }
+    },
+    "zones": {
+      "type": "object",
+      "patternProperties": {
+        "^.*$": {"type": "array", "items": {"type": "string"}}
+      }
+    }
+  }
+}`
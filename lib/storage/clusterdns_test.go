@@ -0,0 +1,87 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"github.com/gravitational/gravity/lib/compare"
+
+	check "gopkg.in/check.v1"
+)
+
+type ClusterDNSSuite struct{}
+
+var _ = check.Suite(&ClusterDNSSuite{})
+
+func (s *ClusterDNSSuite) TestResourceParsing(c *check.C) {
+	spec := `kind: clusterdns
+version: v1
+spec:
+  upstreams: ["1.1.1.1", "8.8.8.8:53"]
+  rotate: true
+  hosts:
+    example.com: 10.0.0.1
+  zones:
+    corp.example.com: ["10.0.0.2", "10.0.0.3:5353"]
+`
+	dns, err := UnmarshalClusterDNS([]byte(spec))
+	c.Assert(err, check.IsNil)
+	expected := NewClusterDNS(ClusterDNSSpecV1{
+		Upstreams: []string{"1.1.1.1", "8.8.8.8:53"},
+		Rotate:    true,
+		Hosts:     map[string]string{"example.com": "10.0.0.1"},
+		Zones:     map[string][]string{"corp.example.com": {"10.0.0.2", "10.0.0.3:5353"}},
+	})
+	c.Assert(dns, compare.DeepEquals, expected)
+}
+
+func (s *ClusterDNSSuite) TestValidation(c *check.C) {
+	testCases := []struct {
+		spec    string
+		comment string
+	}{
+		{
+			spec: `kind: clusterdns
+version: v1
+spec:
+  upstreams: ["dns.example.com"]
+`,
+			comment: "upstream is not an IP address",
+		},
+		{
+			spec: `kind: clusterdns
+version: v1
+spec:
+  hosts:
+    example.com: localhost
+`,
+			comment: "host override is not an IP address",
+		},
+		{
+			spec: `kind: clusterdns
+version: v1
+spec:
+  zones:
+    example.com: []
+`,
+			comment: "zone without nameservers",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := UnmarshalClusterDNS([]byte(tc.spec))
+		c.Assert(err, check.NotNil, check.Commentf(tc.comment))
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gravitational/gravity/lib/utils"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+)
+
+// ConfigResources persists configuration resources of the kinds
+// registered with RegisterResourceKind
+type ConfigResources interface {
+	// UpsertConfigResource creates or updates a configuration resource
+	UpsertConfigResource(UnknownResource) error
+	// GetConfigResource returns a configuration resource by kind and name
+	GetConfigResource(kind, name string) (*UnknownResource, error)
+	// GetConfigResources returns all configuration resources
+	// of the specified kind sorted by name
+	GetConfigResources(kind string) ([]UnknownResource, error)
+	// DeleteConfigResource deletes a configuration resource by kind and name
+	DeleteConfigResource(kind, name string) error
+}
+
+// ResourceKind describes a kind of configuration resources managed
+// generically: resources of a registered kind are validated against
+// its schema and persisted in the backend, and can be managed with
+// "gravity resource" commands without resource-specific code
+type ResourceKind struct {
+	// Kind is the resource kind
+	Kind string
+	// Aliases lists alternative names of the kind, e.g. plural form
+	Aliases []string
+	// Description is a human-readable description of the kind
+	Description string
+	// SpecSchema is the JSON schema of the resource spec
+	SpecSchema string
+}
+
+// Check validates the resource kind
+func (k ResourceKind) Check() error {
+	if k.Kind == "" {
+		return trace.BadParameter("missing resource kind")
+	}
+	for _, name := range k.Names() {
+		if name != strings.ToLower(name) {
+			return trace.BadParameter("resource kind %q should be lowercase", name)
+		}
+	}
+	if k.SpecSchema == "" {
+		return trace.BadParameter("resource kind %q: missing spec schema", k.Kind)
+	}
+	return nil
+}
+
+// Names returns the kind and all its aliases
+func (k ResourceKind) Names() []string {
+	return append([]string{k.Kind}, k.Aliases...)
+}
+
+// Unmarshal validates the specified resource data against the kind schema
+// and returns the resource
+func (k ResourceKind) Unmarshal(data []byte) (*UnknownResource, error) {
+	var header teleservices.ResourceHeader
+	err := teleutils.UnmarshalWithSchema(k.schema(), &header, data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if header.Version != teleservices.V2 {
+		return nil, trace.BadParameter("resource %q version %q is not supported",
+			k.Kind, header.Version)
+	}
+	if !utils.StringInSlice(k.Names(), strings.ToLower(header.Kind)) {
+		return nil, trace.BadParameter("expected resource of kind %q, got %q",
+			k.Kind, header.Kind)
+	}
+	header.Kind = k.Kind
+	if err := header.Metadata.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// re-encode the resource with the canonical kind and metadata defaults
+	var resource map[string]interface{}
+	if err := json.Unmarshal(data, &resource); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	resource["kind"] = header.Kind
+	resource["metadata"] = header.Metadata
+	raw, err := json.Marshal(resource)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &UnknownResource{ResourceHeader: header, Raw: raw}, nil
+}
+
+func (k ResourceKind) schema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, teleservices.MetadataSchema,
+		k.SpecSchema, "")
+}
+
+// RegisterResourceKind registers a new kind of configuration resources
+func RegisterResourceKind(kind ResourceKind) error {
+	if err := kind.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	for _, name := range kind.Names() {
+		if utils.StringInSlice(SupportedGravityResources, CanonicalKind(name)) {
+			return trace.AlreadyExists("resource kind %q is built in", name)
+		}
+	}
+	resourceKindsMutex.Lock()
+	defer resourceKindsMutex.Unlock()
+	for _, name := range kind.Names() {
+		if _, exists := resourceKindNames[name]; exists {
+			return trace.AlreadyExists("resource kind %q is already registered", name)
+		}
+	}
+	for _, name := range kind.Names() {
+		resourceKindNames[name] = kind.Kind
+	}
+	resourceKinds[kind.Kind] = kind
+	return nil
+}
+
+// GetResourceKind returns the registered resource kind
+// by its name or alias
+func GetResourceKind(name string) (*ResourceKind, error) {
+	resourceKindsMutex.RLock()
+	defer resourceKindsMutex.RUnlock()
+	kind, ok := resourceKinds[resourceKindNames[strings.ToLower(name)]]
+	if !ok {
+		return nil, trace.NotFound("resource kind %q is not registered", name)
+	}
+	return &kind, nil
+}
+
+// ResourceKinds returns the names of all registered resource kinds
+func ResourceKinds() (kinds []string) {
+	resourceKindsMutex.RLock()
+	defer resourceKindsMutex.RUnlock()
+	for kind := range resourceKinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// canonicalResourceKind returns the registered resource kind
+// for the specified name or alias
+func canonicalResourceKind(name string) (string, bool) {
+	resourceKindsMutex.RLock()
+	defer resourceKindsMutex.RUnlock()
+	kind, ok := resourceKindNames[name]
+	return kind, ok
+}
+
+var (
+	resourceKindsMutex sync.RWMutex
+	// resourceKinds maps registered kinds to their descriptions
+	resourceKinds = make(map[string]ResourceKind)
+	// resourceKindNames maps registered kinds and their aliases to kinds
+	resourceKindNames = make(map[string]string)
+)
@@ -0,0 +1,273 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+)
+
+// HealingPolicy defines a resource that controls the automated
+// remediations executed by the cluster health controller
+type HealingPolicy interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// CheckAndSetDefaults validates the resource and fills in defaults
+	CheckAndSetDefaults() error
+	// IsEnabled returns true if automated remediations are enabled
+	IsEnabled() bool
+	// IsDryRun returns true if remediations should only be reported
+	// but not executed
+	IsDryRun() bool
+	// GetFailureThreshold returns the number of consecutive failed health
+	// checks after which a remediation is attempted
+	GetFailureThreshold() int
+	// GetCooldown returns the minimum interval between two
+	// remediations of the same kind on the same node
+	GetCooldown() time.Duration
+	// IsAllowed returns true if the specified remediation is permitted
+	IsAllowed(remediation string) bool
+}
+
+// NewHealingPolicy creates a new healing policy resource for the provided spec
+func NewHealingPolicy(spec HealingPolicySpecV1) HealingPolicy {
+	return &HealingPolicyV1{
+		Kind:    KindHealingPolicy,
+		Version: teleservices.V1,
+		Metadata: teleservices.Metadata{
+			Name:      KindHealingPolicy,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// DefaultHealingPolicy returns the policy in effect when no policy
+// has been configured: problems are detected and logged but no
+// remediation is executed
+func DefaultHealingPolicy() HealingPolicy {
+	policy := NewHealingPolicy(HealingPolicySpecV1{})
+	policy.CheckAndSetDefaults()
+	return policy
+}
+
+// HealingPolicyV1 defines the healing policy resource
+type HealingPolicyV1 struct {
+	// Kind is the resource kind
+	Kind string `json:"kind"`
+	// Version is the resource version
+	Version string `json:"version"`
+	// Metadata is the resource metadata
+	teleservices.Metadata `json:"metadata"`
+	// Spec is the resource spec
+	Spec HealingPolicySpecV1 `json:"spec"`
+}
+
+// HealingPolicySpecV1 defines the healing policy
+type HealingPolicySpecV1 struct {
+	// Enabled enables automated remediations
+	Enabled bool `json:"enabled"`
+	// DryRun only reports the remediations without executing them
+	DryRun bool `json:"dry_run,omitempty"`
+	// FailureThreshold is the number of consecutive failed health checks
+	// after which a remediation is attempted
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+	// Cooldown is the minimum interval between two remediations
+	// of the same kind on the same node
+	Cooldown teleservices.Duration `json:"cooldown,omitempty"`
+	// Remediations lists the permitted remediations.
+	// All remediations are permitted if unspecified
+	Remediations []string `json:"remediations,omitempty"`
+}
+
+// IsEnabled returns true if automated remediations are enabled
+func (r *HealingPolicyV1) IsEnabled() bool {
+	return r.Spec.Enabled
+}
+
+// IsDryRun returns true if remediations should only be reported
+// but not executed
+func (r *HealingPolicyV1) IsDryRun() bool {
+	return r.Spec.DryRun
+}
+
+// GetFailureThreshold returns the number of consecutive failed health
+// checks after which a remediation is attempted
+func (r *HealingPolicyV1) GetFailureThreshold() int {
+	return r.Spec.FailureThreshold
+}
+
+// GetCooldown returns the minimum interval between two
+// remediations of the same kind on the same node
+func (r *HealingPolicyV1) GetCooldown() time.Duration {
+	return r.Spec.Cooldown.Value()
+}
+
+// IsAllowed returns true if the specified remediation is permitted
+func (r *HealingPolicyV1) IsAllowed(remediation string) bool {
+	return len(r.Spec.Remediations) == 0 ||
+		utils.StringInSlice(r.Spec.Remediations, remediation)
+}
+
+// CheckAndSetDefaults validates the resource and fills in defaults
+func (r *HealingPolicyV1) CheckAndSetDefaults() error {
+	if r.Metadata.Name == "" {
+		r.Metadata.Name = KindHealingPolicy
+	}
+	if err := r.Metadata.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Spec.FailureThreshold < 0 {
+		return trace.BadParameter("failure threshold cannot be negative")
+	}
+	if r.Spec.FailureThreshold == 0 {
+		r.Spec.FailureThreshold = defaults.HealingFailureThreshold
+	}
+	if r.Spec.Cooldown.Value() < 0 {
+		return trace.BadParameter("cooldown cannot be negative")
+	}
+	if r.Spec.Cooldown.Value() == 0 {
+		r.Spec.Cooldown = teleservices.NewDuration(defaults.HealingCooldown)
+	}
+	for _, remediation := range r.Spec.Remediations {
+		if !utils.StringInSlice(Remediations, remediation) {
+			return trace.BadParameter("unsupported remediation %q, supported are: %v",
+				remediation, strings.Join(Remediations, ", "))
+		}
+	}
+	return nil
+}
+
+// GetName returns the resource name
+func (r *HealingPolicyV1) GetName() string {
+	return r.Metadata.Name
+}
+
+// SetName sets the resource name
+func (r *HealingPolicyV1) SetName(name string) {
+	r.Metadata.Name = name
+}
+
+// GetMetadata returns the resource metadata
+func (r *HealingPolicyV1) GetMetadata() teleservices.Metadata {
+	return r.Metadata
+}
+
+// SetExpiry sets the resource expiration time
+func (r *HealingPolicyV1) SetExpiry(expires time.Time) {
+	r.Metadata.SetExpiry(expires)
+}
+
+// Expiry returns the resource expiration time
+func (r *HealingPolicyV1) Expiry() time.Time {
+	return r.Metadata.Expiry()
+}
+
+// SetTTL sets the resource TTL
+func (r *HealingPolicyV1) SetTTL(clock clockwork.Clock, ttl time.Duration) {
+	r.Metadata.SetTTL(clock, ttl)
+}
+
+// String returns the object's string representation
+func (r HealingPolicyV1) String() string {
+	return fmt.Sprintf("HealingPolicyV1(Enabled=%v, DryRun=%v, FailureThreshold=%v, Cooldown=%v, Remediations=%v)",
+		r.Spec.Enabled, r.Spec.DryRun, r.Spec.FailureThreshold, r.Spec.Cooldown.Value(),
+		r.Spec.Remediations)
+}
+
+// UnmarshalHealingPolicy unmarshals healing policy resource from the provided JSON data
+func UnmarshalHealingPolicy(data []byte) (HealingPolicy, error) {
+	if len(data) == 0 {
+		return nil, trace.BadParameter("empty input")
+	}
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var header teleservices.ResourceHeader
+	err = json.Unmarshal(jsonData, &header)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch header.Version {
+	case teleservices.V1:
+		var policy HealingPolicyV1
+		err := teleutils.UnmarshalWithSchema(GetHealingPolicySchema(), &policy, jsonData)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		err = policy.CheckAndSetDefaults()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &policy, nil
+	}
+	return nil, trace.BadParameter("%v resource version %q is not supported",
+		KindHealingPolicy, header.Version)
+}
+
+// MarshalHealingPolicy marshals the provided healing policy resource to JSON
+func MarshalHealingPolicy(policy HealingPolicy, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(policy)
+}
+
+// GetHealingPolicySchema returns the full healing policy resource schema
+func GetHealingPolicySchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, MetadataSchema,
+		HealingPolicySpecV1Schema, "")
+}
+
+// HealingPolicySpecV1Schema defines the healing policy spec schema
+const HealingPolicySpecV1Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "enabled": {"type": "boolean"},
+    "dry_run": {"type": "boolean"},
+    "failure_threshold": {"type": "integer"},
+    "cooldown": {"type": "string"},
+    "remediations": {"type": "array", "items": {"type": "string"}}
+  }
+}`
+
+const (
+	// RemediationRestartPlanet restarts the planet container on a node
+	RemediationRestartPlanet = "restart-planet"
+	// RemediationResyncRegistry pushes the cluster application images
+	// into the cluster registry
+	RemediationResyncRegistry = "resync-registry"
+	// RemediationRecreateDNSConfig recreates the cluster DNS configuration
+	// and restarts the cluster DNS service
+	RemediationRecreateDNSConfig = "recreate-dns-config"
+)
+
+// Remediations lists all supported remediations
+var Remediations = []string{
+	RemediationRestartPlanet,
+	RemediationResyncRegistry,
+	RemediationRecreateDNSConfig,
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	check "gopkg.in/check.v1"
+)
+
+type HealingPolicySuite struct{}
+
+var _ = check.Suite(&HealingPolicySuite{})
+
+func (s *HealingPolicySuite) TestResourceParsing(c *check.C) {
+	spec := `kind: healingpolicy
+version: v1
+spec:
+  enabled: true
+  failure_threshold: 5
+  cooldown: 1h
+  remediations: ["restart-planet", "resync-registry"]
+`
+	policy, err := UnmarshalHealingPolicy([]byte(spec))
+	c.Assert(err, check.IsNil)
+	expected := NewHealingPolicy(HealingPolicySpecV1{
+		Enabled:          true,
+		FailureThreshold: 5,
+		Cooldown:         teleservices.NewDuration(time.Hour),
+		Remediations:     []string{RemediationRestartPlanet, RemediationResyncRegistry},
+	})
+	c.Assert(policy, compare.DeepEquals, expected)
+	c.Assert(policy.IsAllowed(RemediationRestartPlanet), check.Equals, true)
+	c.Assert(policy.IsAllowed(RemediationRecreateDNSConfig), check.Equals, false)
+}
+
+func (s *HealingPolicySuite) TestDefaults(c *check.C) {
+	policy := DefaultHealingPolicy()
+	c.Assert(policy.IsEnabled(), check.Equals, false)
+	c.Assert(policy.GetFailureThreshold(), check.Equals, defaults.HealingFailureThreshold)
+	c.Assert(policy.GetCooldown(), check.Equals, defaults.HealingCooldown)
+	for _, remediation := range Remediations {
+		c.Assert(policy.IsAllowed(remediation), check.Equals, true)
+	}
+}
+
+func (s *HealingPolicySuite) TestValidation(c *check.C) {
+	testCases := []struct {
+		spec    string
+		comment string
+	}{
+		{
+			spec: `kind: healingpolicy
+version: v1
+spec:
+  enabled: true
+  remediations: ["reboot-node"]
+`,
+			comment: "unsupported remediation",
+		},
+		{
+			spec: `kind: healingpolicy
+version: v1
+spec:
+  failure_threshold: -1
+`,
+			comment: "negative failure threshold",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := UnmarshalHealingPolicy([]byte(tc.spec))
+		c.Assert(err, check.NotNil, check.Commentf(tc.comment))
+	}
+}
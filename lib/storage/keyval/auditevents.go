@@ -0,0 +1,71 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// CreateAuditEvent saves the audit event for the specified duration
+func (b *backend) CreateAuditEvent(event storage.AuditEvent, ttl time.Duration) error {
+	if err := event.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	err := b.createVal(b.key(auditEventsP, event.ID), event, ttl)
+	if err != nil {
+		if trace.IsAlreadyExists(err) {
+			return trace.AlreadyExists("audit event %v already exists", event.ID)
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetAuditEvents returns the audit events recorded since the specified
+// time ordered by their time
+func (b *backend) GetAuditEvents(since time.Time) ([]storage.AuditEvent, error) {
+	ids, err := b.getKeys(b.key(auditEventsP))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var events []storage.AuditEvent
+	for _, id := range ids {
+		var event storage.AuditEvent
+		err := b.getVal(b.key(auditEventsP, id), &event)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		utils.UTC(&event.Time)
+		if event.Time.Before(since) {
+			continue
+		}
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events, nil
+}
@@ -19,8 +19,11 @@ package keyval
 import (
 	"encoding/base64"
 	"encoding/json"
+	"io"
 	"time"
 
+	"github.com/gravitational/gravity/lib/storage"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/gravitational/trace"
 	"github.com/jonboulle/clockwork"
@@ -51,6 +54,55 @@ func (b *backend) Close() error {
 	return b.kvengine.Close()
 }
 
+// Compact compacts the database file of the specified BoltDB-backed backend
+func Compact(backend storage.Backend) error {
+	engine, err := boltEngineOf(backend)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(engine.Compact())
+}
+
+// Snapshot writes a consistent copy of the database of the specified
+// BoltDB-backed backend to w
+func Snapshot(backend storage.Backend, w io.Writer) error {
+	engine, err := boltEngineOf(backend)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(engine.Snapshot(w))
+}
+
+// boltEngine is implemented by BoltDB-backed engines
+type boltEngine interface {
+	// Compact rewrites the database file without the space left by deleted data
+	Compact() error
+	// Snapshot writes a consistent copy of the database to w
+	Snapshot(w io.Writer) error
+}
+
+func boltEngineOf(b storage.Backend) (boltEngine, error) {
+	engine, err := engineOf(b)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if engine, ok := engine.(boltEngine); ok {
+		return engine, nil
+	}
+	return nil, trace.BadParameter("backend %T is not BoltDB-backed", b)
+}
+
+// engineOf returns the engine of the specified backend
+func engineOf(b storage.Backend) (kvengine, error) {
+	switch b := b.(type) {
+	case *backend:
+		return b.kvengine, nil
+	case *electingBackend:
+		return engineOf(b.Backend)
+	}
+	return nil, trace.BadParameter("unsupported backend %T", b)
+}
+
 // Codec is responsible for encoding/decoding objects
 type Codec interface {
 	EncodeToString(val interface{}) (string, error)
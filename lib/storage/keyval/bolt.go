@@ -18,6 +18,8 @@ package keyval
 
 import (
 	"bytes"
+	"context"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -63,13 +65,19 @@ func NewBolt(cfg BoltConfig) (storage.Backend, error) {
 // BoltConfig is a BoltDB configuration
 type BoltConfig struct {
 	// Path is a path to DB file
-	Path string `json:"path"`
+	Path string `json:"path" yaml:"path"`
 	// Clock is a clock interface, used in tests
-	Clock clockwork.Clock `json:"-"`
+	Clock clockwork.Clock `json:"-" yaml:"-"`
 	// Readonly sets bolt to read only mode
-	Readonly bool `json:"readonly"`
+	Readonly bool `json:"readonly" yaml:"readonly"`
 	// Multi enables multi-client support
-	Multi bool `json:"multi"`
+	Multi bool `json:"multi" yaml:"multi"`
+	// AutoCompactSize is the amount of free space in the database file
+	// that triggers compaction after deletions, defaults.BoltAutoCompactSize
+	// if unspecified. Negative value disables automatic compaction
+	AutoCompactSize int64 `json:"auto_compact_size" yaml:"auto_compact_size"`
+	// Encryption enables encryption of the values at rest if set
+	Encryption *EncryptionConfig `json:"encryption,omitempty" yaml:"encryption,omitempty"`
 }
 
 func (b *BoltConfig) Check() error {
@@ -97,10 +105,19 @@ type blt struct {
 	logrus.FieldLogger
 
 	codec Codec
+	// dbMu guards db against being replaced during compaction
+	dbMu  sync.RWMutex
 	db    *bolt.DB
 	clock clockwork.Clock
 	path  string
 	locks map[string]time.Time
+	// watchers receives the changes made with this engine
+	watchers *watchers
+	// autoCompactSize is the amount of free space in the database file
+	// that triggers compaction after deletions
+	autoCompactSize int64
+	// cipher encrypts the values at rest, nil if encryption is disabled
+	cipher *valueCipher
 }
 
 // newBolt returns a new instance of BoltDB backend
@@ -111,10 +128,12 @@ func newBolt(cfg BoltConfig, codec Codec) (*blt, error) {
 	}
 
 	b := &blt{
-		locks: make(map[string]time.Time),
-		clock: cfg.Clock,
-		codec: codec,
-		path:  path,
+		locks:           make(map[string]time.Time),
+		watchers:        newWatchers(),
+		clock:           cfg.Clock,
+		codec:           codec,
+		path:            path,
+		autoCompactSize: cfg.AutoCompactSize,
 		FieldLogger: logrus.WithFields(logrus.Fields{
 			trace.Component: "boltdb",
 			"path":          path,
@@ -123,6 +142,13 @@ func newBolt(cfg BoltConfig, codec Codec) (*blt, error) {
 	if b.clock == nil {
 		b.clock = clockwork.NewRealClock()
 	}
+	if b.autoCompactSize == 0 {
+		b.autoCompactSize = defaults.BoltAutoCompactSize
+	}
+	b.cipher, err = newValueCipher(cfg.Encryption)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 
 	// When opening bolt in read-only mode, make sure bolt properly initializes
 	// the database file in case no database file exists before applying
@@ -247,14 +273,14 @@ func getBucket(tx *bolt.Tx, buckets []string) (*bolt.Bucket, error) {
 }
 
 func (b *blt) createDir(key key, ttl time.Duration) error {
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		_, err := createBucket(tx, key)
 		return trace.Wrap(boltErr(err))
 	})
 }
 
 func (b *blt) upsertDir(key key, ttl time.Duration) error {
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		_, err := upsertBucket(tx, key)
 		return trace.Wrap(boltErr(err))
 	})
@@ -262,7 +288,7 @@ func (b *blt) upsertDir(key key, ttl time.Duration) error {
 
 func (b *blt) createValBytes(k key, data []byte, ttl time.Duration) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -271,7 +297,7 @@ func (b *blt) createValBytes(k key, data []byte, ttl time.Duration) error {
 		if val != nil {
 			return trace.AlreadyExists("%v already exists", key)
 		}
-		return bkt.Put([]byte(key), data)
+		return trace.Wrap(b.put(tx, bkt, k, data, storage.WatchEventCreated))
 	})
 }
 
@@ -281,7 +307,7 @@ func (b *blt) createVal(k key, val interface{}, ttl time.Duration) error {
 		return trace.Wrap(err)
 	}
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -290,18 +316,18 @@ func (b *blt) createVal(k key, val interface{}, ttl time.Duration) error {
 		if val != nil {
 			return trace.AlreadyExists("'%v' already exists", key)
 		}
-		return bkt.Put([]byte(key), encoded)
+		return trace.Wrap(b.put(tx, bkt, k, encoded, storage.WatchEventCreated))
 	})
 }
 
 func (b *blt) upsertValBytes(k key, encoded []byte, ttl time.Duration) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
 		}
-		return bkt.Put([]byte(key), encoded)
+		return trace.Wrap(b.put(tx, bkt, k, encoded, putEventType(bkt.Get([]byte(key)))))
 	})
 }
 
@@ -311,18 +337,18 @@ func (b *blt) upsertVal(k key, val interface{}, ttl time.Duration) error {
 		return trace.Wrap(err)
 	}
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
 		}
-		return bkt.Put([]byte(key), encoded)
+		return trace.Wrap(b.put(tx, bkt, k, encoded, putEventType(bkt.Get([]byte(key)))))
 	})
 }
 
 func (b *blt) updateValBytes(k key, data []byte, ttl time.Duration) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -331,7 +357,7 @@ func (b *blt) updateValBytes(k key, data []byte, ttl time.Duration) error {
 		if val == nil {
 			return trace.NotFound("%q not found", key)
 		}
-		return bkt.Put([]byte(key), data)
+		return trace.Wrap(b.put(tx, bkt, k, data, storage.WatchEventUpdated))
 	})
 }
 
@@ -341,7 +367,7 @@ func (b *blt) updateVal(k key, val interface{}, ttl time.Duration) error {
 		return trace.Wrap(err)
 	}
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -350,7 +376,7 @@ func (b *blt) updateVal(k key, val interface{}, ttl time.Duration) error {
 		if val == nil {
 			return trace.NotFound("%q not found", key)
 		}
-		return bkt.Put([]byte(key), encoded)
+		return trace.Wrap(b.put(tx, bkt, k, encoded, storage.WatchEventUpdated))
 	})
 }
 
@@ -387,17 +413,20 @@ func (b *blt) compareAndSwap(k key, val interface{}, prevVal interface{}, outVal
 
 func (b *blt) compareAndSwapBytes(k key, val, prevVal []byte, outVal *[]byte, ttl time.Duration) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
 		}
-		currentVal := bkt.Get([]byte(key))
+		currentVal, err := b.cipher.decrypt(bkt.Get([]byte(key)))
+		if err != nil {
+			return trace.Wrap(err)
+		}
 		if prevVal == nil { // we don't expect the value to exist
 			if currentVal != nil {
 				return trace.AlreadyExists("key %q already exists", key)
 			}
-			return trace.Wrap(bkt.Put([]byte(key), val))
+			return trace.Wrap(b.put(tx, bkt, k, val, storage.WatchEventCreated))
 		} else { // we expect the previous value to exist
 			if val == nil {
 				return trace.NotFound("key %q not found", key)
@@ -406,7 +435,7 @@ func (b *blt) compareAndSwapBytes(k key, val, prevVal []byte, outVal *[]byte, tt
 				return trace.CompareFailed("expected %q got %q",
 					string(prevVal), string(currentVal))
 			}
-			err = bkt.Put([]byte(key), val)
+			err = b.put(tx, bkt, k, val, storage.WatchEventUpdated)
 			if err != nil {
 				return trace.Wrap(err)
 			}
@@ -419,7 +448,7 @@ func (b *blt) compareAndSwapBytes(k key, val, prevVal []byte, outVal *[]byte, tt
 func (b *blt) getValBytes(k key) ([]byte, error) {
 	buckets, key := b.split(k)
 	var out []byte
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bkt, err := getBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -432,8 +461,12 @@ func (b *blt) getValBytes(k key) ([]byte, error) {
 			}
 			return trace.NotFound("%q %q not found", buckets, key)
 		}
-		out = make([]byte, len(bytes))
-		copy(out, bytes)
+		data, err := b.cipher.decrypt(bytes)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		out = make([]byte, len(data))
+		copy(out, data)
 		return nil
 	})
 	if err != nil {
@@ -444,7 +477,7 @@ func (b *blt) getValBytes(k key) ([]byte, error) {
 
 func (b *blt) getVal(k key, outVal interface{}) error {
 	buckets, key := b.split(k)
-	return b.db.View(func(tx *bolt.Tx) error {
+	return b.view(func(tx *bolt.Tx) error {
 		bkt, err := getBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -457,13 +490,17 @@ func (b *blt) getVal(k key, outVal interface{}) error {
 			}
 			return trace.NotFound("%v %v not found", buckets, key)
 		}
-		return b.codec.DecodeFromBytes(bytes, outVal)
+		data, err := b.cipher.decrypt(bytes)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return b.codec.DecodeFromBytes(data, outVal)
 	})
 }
 
 func (b *blt) compareAndDelete(k key, prevVal interface{}) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := getBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -472,21 +509,25 @@ func (b *blt) compareAndDelete(k key, prevVal interface{}) error {
 		if bytes == nil {
 			return trace.NotFound("%v is not found", key)
 		}
+		data, err := b.cipher.decrypt(bytes)
+		if err != nil {
+			return trace.Wrap(err)
+		}
 		var outVal interface{}
-		err = b.codec.DecodeFromBytes(bytes, &outVal)
+		err = b.codec.DecodeFromBytes(data, &outVal)
 		if err != nil {
 			return trace.Wrap(err)
 		}
 		if outVal != prevVal {
 			return trace.BadParameter("%v: expected %v, but got %v", key, prevVal, outVal)
 		}
-		return bkt.Delete([]byte(key))
+		return trace.Wrap(b.delete(tx, bkt, k))
 	})
 }
 
 func (b *blt) deleteKey(k key) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	err := b.update(func(tx *bolt.Tx) error {
 		bkt, err := getBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -494,23 +535,101 @@ func (b *blt) deleteKey(k key) error {
 		if bkt.Get([]byte(key)) == nil {
 			return trace.NotFound("%v is not found", key)
 		}
-		return bkt.Delete([]byte(key))
+		return trace.Wrap(b.delete(tx, bkt, k))
 	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	b.compactIfNeeded()
+	return nil
 }
 
 func (b *blt) deleteDir(k key) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	err := b.update(func(tx *bolt.Tx) error {
 		bkt, err := getBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
 		}
+		if dir := bkt.Bucket([]byte(key)); dir != nil {
+			b.notifyDeleted(tx, dir, k)
+		}
 		err = bkt.DeleteBucket([]byte(key))
 		if err != nil {
 			return trace.NotFound("%v is not found", key)
 		}
 		return nil
 	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	b.compactIfNeeded()
+	return nil
+}
+
+// put sets the value of the key k in bucket bkt and notifies watchers
+// with the event of the specified type once the transaction is committed
+func (b *blt) put(tx *bolt.Tx, bkt *bolt.Bucket, k key, value []byte, eventType storage.WatchEventType) error {
+	_, key := b.split(k)
+	encrypted, err := b.cipher.encrypt(value)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := bkt.Put([]byte(key), encrypted); err != nil {
+		return trace.Wrap(err)
+	}
+	// the value may be modified by the caller after the function returns
+	value = append([]byte(nil), value...)
+	tx.OnCommit(func() {
+		b.watchers.notify(eventType, k, value)
+	})
+	return nil
+}
+
+// delete deletes the key k from bucket bkt and notifies watchers
+// once the transaction is committed
+func (b *blt) delete(tx *bolt.Tx, bkt *bolt.Bucket, k key) error {
+	_, key := b.split(k)
+	if err := bkt.Delete([]byte(key)); err != nil {
+		return trace.Wrap(err)
+	}
+	tx.OnCommit(func() {
+		b.watchers.notify(storage.WatchEventDeleted, k, nil)
+	})
+	return nil
+}
+
+// notifyDeleted notifies watchers about deletion of all keys in bucket bkt
+// with the key k once the transaction is committed
+func (b *blt) notifyDeleted(tx *bolt.Tx, bkt *bolt.Bucket, k key) {
+	c := bkt.Cursor()
+	for name, value := c.First(); name != nil; name, value = c.Next() {
+		child := append(append(key{}, k...), string(name))
+		if value == nil {
+			if nested := bkt.Bucket(name); nested != nil {
+				b.notifyDeleted(tx, nested, child)
+				continue
+			}
+		}
+		tx.OnCommit(func() {
+			b.watchers.notify(storage.WatchEventDeleted, child, nil)
+		})
+	}
+}
+
+// watch returns a watcher for the changes made with this engine
+// to the keys with the specified prefix
+func (b *blt) watch(ctx context.Context, prefix key) (storage.Watcher, error) {
+	return b.watchers.add(ctx, prefix), nil
+}
+
+// putEventType returns the type of the event for updating the key
+// with the existing value
+func putEventType(existing []byte) storage.WatchEventType {
+	if existing == nil {
+		return storage.WatchEventCreated
+	}
+	return storage.WatchEventUpdated
 }
 
 func (b *blt) acquireLock(token key, ttl time.Duration) error {
@@ -538,7 +657,7 @@ func (b *blt) releaseLock(key key) error {
 func (b *blt) getKeys(key key) ([]string, error) {
 	out := []string{}
 	buckets := key
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bkt, err := getBucket(tx, buckets)
 		if err != nil {
 			if trace.IsNotFound(err) {
@@ -559,8 +678,173 @@ func (b *blt) getKeys(key key) ([]string, error) {
 	return out, nil
 }
 
+// Compact rewrites the database file without the space left by deleted
+// data. BoltDB reuses free pages but never shrinks the file on its own.
+//
+// The compacted data is written over the original file instead of replacing
+// it so the file lock other clients may be waiting on remains valid
+func (b *blt) Compact() error {
+	b.dbMu.Lock()
+	defer b.dbMu.Unlock()
+	if b.db == nil {
+		return trace.BadParameter("database %v is closed", b.path)
+	}
+	if b.db.IsReadOnly() {
+		return trace.BadParameter("database %v is open in read-only mode", b.path)
+	}
+	compactPath := b.path + ".compact"
+	defer os.Remove(compactPath)
+	sizeBefore, sizeAfter, err := b.compactTo(compactPath)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// The database is not accessed until it is reopened below since
+	// the original file contents are replaced while it is still mapped
+	if err := overwriteFile(b.path, compactPath); err != nil {
+		return trace.Wrap(err)
+	}
+	err = b.db.Close()
+	b.db = nil
+	if err != nil {
+		b.Warnf("Failed to close database: %v.", err)
+	}
+	if err := b.open(false); err != nil {
+		return trace.Wrap(err)
+	}
+	b.Infof("Compacted database from %v to %v bytes.", sizeBefore, sizeAfter)
+	return nil
+}
+
+// compactTo copies the contents of the database into a new database
+// at the specified path and returns the sizes of both databases
+func (b *blt) compactTo(path string) (sizeBefore, sizeAfter int64, err error) {
+	db, err := bolt.Open(path, defaults.PrivateFileMask, &bolt.Options{
+		Timeout: defaults.DBOpenTimeout,
+	})
+	if err != nil {
+		return 0, 0, trace.Wrap(err)
+	}
+	defer db.Close()
+	err = b.db.View(func(src *bolt.Tx) error {
+		sizeBefore = src.Size()
+		return db.Update(func(dst *bolt.Tx) error {
+			err := src.ForEach(func(name []byte, bkt *bolt.Bucket) error {
+				nested, err := dst.CreateBucket(name)
+				if err != nil {
+					return trace.Wrap(err)
+				}
+				return trace.Wrap(copyBucket(nested, bkt))
+			})
+			sizeAfter = dst.Size()
+			return trace.Wrap(err)
+		})
+	})
+	if err != nil {
+		return 0, 0, trace.Wrap(err)
+	}
+	return sizeBefore, sizeAfter, trace.Wrap(db.Sync())
+}
+
+// overwriteFile replaces the contents of the file at path with the contents
+// of the file at srcPath keeping the same inode
+func overwriteFile(path, srcPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer src.Close()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, defaults.PrivateFileMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, src); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return trace.ConvertSystemError(f.Sync())
+}
+
+// copyBucket recursively copies the contents of bucket src into bucket dst
+func copyBucket(dst, src *bolt.Bucket) error {
+	// the compacted database is not expected to be modified much
+	// so the pages are packed as tightly as possible
+	dst.FillPercent = 1.0
+	if err := dst.SetSequence(src.Sequence()); err != nil {
+		return trace.Wrap(err)
+	}
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return trace.Wrap(dst.Put(k, v))
+		}
+		nested, err := dst.CreateBucket(k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(copyBucket(nested, src.Bucket(k)))
+	})
+}
+
+// compactIfNeeded compacts the database if deletions have left more free
+// space in the file than configured by autoCompactSize
+func (b *blt) compactIfNeeded() {
+	if b.autoCompactSize < 0 {
+		return
+	}
+	b.dbMu.RLock()
+	var free, size int64
+	if b.db != nil && !b.db.IsReadOnly() {
+		free = int64(b.db.Stats().FreeAlloc)
+		b.db.View(func(tx *bolt.Tx) error {
+			size = tx.Size()
+			return nil
+		})
+	}
+	b.dbMu.RUnlock()
+	// only compact if most of the file is free to avoid compacting
+	// databases that would regrow to the same size soon
+	if free < b.autoCompactSize || free < size/2 {
+		return
+	}
+	b.Debugf("Database has %v free bytes out of %v, compacting.", free, size)
+	if err := b.Compact(); err != nil {
+		b.Warnf("Failed to compact database: %v.", trace.DebugReport(err))
+	}
+}
+
+// Snapshot writes a consistent copy of the database to w.
+// The database remains available for reads and writes while
+// the snapshot is being written
+func (b *blt) Snapshot(w io.Writer) error {
+	return b.view(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return trace.Wrap(err)
+	})
+}
+
+// update executes fn in a read-write transaction
+func (b *blt) update(fn func(*bolt.Tx) error) error {
+	b.dbMu.RLock()
+	defer b.dbMu.RUnlock()
+	if b.db == nil {
+		return trace.BadParameter("database %v is closed", b.path)
+	}
+	return b.db.Update(fn)
+}
+
+// view executes fn in a read-only transaction
+func (b *blt) view(fn func(*bolt.Tx) error) error {
+	b.dbMu.RLock()
+	defer b.dbMu.RUnlock()
+	if b.db == nil {
+		return trace.BadParameter("database %v is closed", b.path)
+	}
+	return b.db.View(fn)
+}
+
 // Close closes the backend resources
 func (b *blt) Close() error {
+	b.dbMu.Lock()
+	defer b.dbMu.Unlock()
 	b.Lock()
 	defer b.Unlock()
 	if b.db == nil {
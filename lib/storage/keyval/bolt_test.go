@@ -17,6 +17,8 @@ limitations under the License.
 package keyval
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -197,6 +199,10 @@ func (s *BSuite) TestPeersCRUD(c *C) {
 	s.suite.PeersCRUD(c)
 }
 
+func (s *BSuite) TestWatch(c *C) {
+	s.suite.Watch(c)
+}
+
 func (s *BSuite) TestObjectsCRUD(c *C) {
 	s.suite.ObjectsCRUD(c)
 }
@@ -240,3 +246,122 @@ func (s *BSuite) TestClusterLogin(c *C) {
 func (s *BSuite) TestIndexFile(c *C) {
 	s.suite.IndexFile(c)
 }
+
+func (s *BSuite) TestTrustedKeysCRUD(c *C) {
+	s.suite.TrustedKeysCRUD(c)
+}
+
+func (s *BSuite) TestAuditEventsCRUD(c *C) {
+	s.suite.AuditEventsCRUD(c)
+}
+
+func (s *BSuite) TestStatusHistoryCRUD(c *C) {
+	s.suite.StatusHistoryCRUD(c)
+}
+
+func (s *BSuite) TestFleetUpgradesCRUD(c *C) {
+	s.suite.FleetUpgradesCRUD(c)
+}
+
+func (s *BSuite) TestScheduledOperationsCRUD(c *C) {
+	s.suite.ScheduledOperationsCRUD(c)
+}
+
+func (s *BSuite) TestRoleMappingsCRUD(c *C) {
+	s.suite.RoleMappingsCRUD(c)
+}
+
+func (s *BSuite) TestCatalogEntriesCRUD(c *C) {
+	s.suite.CatalogEntriesCRUD(c)
+}
+
+func (s *BSuite) TestConfigResourcesCRUD(c *C) {
+	s.suite.ConfigResourcesCRUD(c)
+}
+
+func (s *BSuite) TestWebhooksCRUD(c *C) {
+	s.suite.WebhooksCRUD(c)
+}
+
+func (s *BSuite) TestWebhooksIsolatedByCluster(c *C) {
+	s.suite.WebhooksIsolatedByCluster(c)
+}
+
+func (s *BSuite) TestProvisionersCRUD(c *C) {
+	s.suite.ProvisionersCRUD(c)
+}
+
+func (s *BSuite) TestTerraformStatesCRUD(c *C) {
+	s.suite.TerraformStatesCRUD(c)
+}
+
+func (s *BSuite) TestSnapshot(c *C) {
+	account, err := s.backend.backend.CreateAccount(storage.Account{Org: "example.com"})
+	c.Assert(err, IsNil)
+
+	path := filepath.Join(c.MkDir(), "backup.db")
+	f, err := os.Create(path)
+	c.Assert(err, IsNil)
+	c.Assert(Snapshot(s.backend.backend, f), IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	backup, err := NewBolt(BoltConfig{Path: path, Readonly: true})
+	c.Assert(err, IsNil)
+	defer backup.Close()
+	out, err := backup.GetAccount(account.ID)
+	c.Assert(err, IsNil)
+	c.Assert(out.Org, Equals, account.Org)
+}
+
+func (s *BSuite) TestCompact(c *C) {
+	path := filepath.Join(c.MkDir(), "bolt.db")
+	b, err := newBolt(BoltConfig{Path: path, AutoCompactSize: -1}, &v1codec{})
+	c.Assert(err, IsNil)
+	defer b.Close()
+	c.Assert(b.upsertVal(b.key("keep", "key"), "value", forever), IsNil)
+	fillBolt(c, b, "big", 1000)
+	c.Assert(b.deleteDir(b.key("big")), IsNil)
+	sizeBefore := fileSize(c, path)
+
+	c.Assert(b.Compact(), IsNil)
+	c.Assert(fileSize(c, path) < sizeBefore/2, Equals, true,
+		Commentf("expected database to shrink from %v bytes", sizeBefore))
+	var value string
+	c.Assert(b.getVal(b.key("keep", "key"), &value), IsNil)
+	c.Assert(value, Equals, "value")
+	// make sure the database is still writable after compaction
+	c.Assert(b.upsertVal(b.key("keep", "key"), "new value", forever), IsNil)
+}
+
+func (s *BSuite) TestCompactsAfterLargeDeletions(c *C) {
+	path := filepath.Join(c.MkDir(), "bolt.db")
+	b, err := newMultiBolt(BoltConfig{Path: path, AutoCompactSize: 1024 * 1024})
+	c.Assert(err, IsNil)
+	defer b.Close()
+	c.Assert(b.upsertVal(b.key("keep", "key"), "value", forever), IsNil)
+
+	fillBolt(c, b, "big", 1000)
+	sizeFilled := fileSize(c, path)
+
+	c.Assert(b.deleteDir(b.key("big")), IsNil)
+	c.Assert(fileSize(c, path) < sizeFilled/2, Equals, true,
+		Commentf("expected database to shrink from %v bytes", sizeFilled))
+	var value string
+	c.Assert(b.getVal(b.key("keep", "key"), &value), IsNil)
+	c.Assert(value, Equals, "value")
+}
+
+// fillBolt creates count 4KiB values in the specified directory
+func fillBolt(c *C, engine kvengine, dir string, count int) {
+	data := bytes.Repeat([]byte("x"), 4096)
+	for i := 0; i < count; i++ {
+		err := engine.upsertValBytes(engine.key(dir, fmt.Sprint(i)), data, forever)
+		c.Assert(err, IsNil)
+	}
+}
+
+func fileSize(c *C, path string) int64 {
+	fi, err := os.Stat(path)
+	c.Assert(err, IsNil)
+	return fi.Size()
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"sort"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/gravitational/trace"
+)
+
+// UpsertCatalogEntry publishes an application to the catalog
+// or updates its catalog metadata
+func (b *backend) UpsertCatalogEntry(entry storage.CatalogEntry) (*storage.CatalogEntry, error) {
+	if err := entry.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err := b.upsertVal(b.key(catalogP, entry.Repository, entry.Name, entry.Version), entry, forever)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &entry, nil
+}
+
+// GetCatalogEntry returns the catalog entry for the specified application
+func (b *backend) GetCatalogEntry(repository, name, version string) (*storage.CatalogEntry, error) {
+	var entry storage.CatalogEntry
+	err := b.getVal(b.key(catalogP, repository, name, version), &entry)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("application %v/%v:%v is not published",
+				repository, name, version)
+		}
+		return nil, trace.Wrap(err)
+	}
+	utils.UTC(&entry.Published)
+	return &entry, nil
+}
+
+// GetCatalogEntries returns all catalog entries in the specified repository
+// ordered by application name and version
+func (b *backend) GetCatalogEntries(repository string) ([]storage.CatalogEntry, error) {
+	names, err := b.getKeys(b.key(catalogP, repository))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var entries []storage.CatalogEntry
+	for _, name := range names {
+		versions, err := b.getKeys(b.key(catalogP, repository, name))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, version := range versions {
+			entry, err := b.GetCatalogEntry(repository, name, version)
+			if err != nil {
+				if trace.IsNotFound(err) {
+					continue
+				}
+				return nil, trace.Wrap(err)
+			}
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return semver.New(entries[i].Version).LessThan(*semver.New(entries[j].Version))
+	})
+	return entries, nil
+}
+
+// DeleteCatalogEntry removes the specified application from the catalog
+func (b *backend) DeleteCatalogEntry(repository, name, version string) error {
+	err := b.deleteKey(b.key(catalogP, repository, name, version))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("application %v/%v:%v is not published",
+				repository, name, version)
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
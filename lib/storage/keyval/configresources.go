@@ -0,0 +1,107 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"sort"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// UpsertConfigResource creates or updates a configuration resource
+func (b *backend) UpsertConfigResource(resource storage.UnknownResource) error {
+	kind, err := storage.GetResourceKind(resource.Kind)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	validated, err := kind.Unmarshal(resource.Raw)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = b.upsertValBytes(b.key(configResourcesP, kind.Kind, validated.Metadata.Name),
+		validated.Raw, forever)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetConfigResource returns a configuration resource by kind and name
+func (b *backend) GetConfigResource(kind, name string) (*storage.UnknownResource, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing %v name", kind)
+	}
+	resourceKind, err := storage.GetResourceKind(kind)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	data, err := b.getValBytes(b.key(configResourcesP, resourceKind.Kind, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("%v %q is not found", resourceKind.Kind, name)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return resourceKind.Unmarshal(data)
+}
+
+// GetConfigResources returns all configuration resources
+// of the specified kind sorted by name
+func (b *backend) GetConfigResources(kind string) ([]storage.UnknownResource, error) {
+	resourceKind, err := storage.GetResourceKind(kind)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	names, err := b.getKeys(b.key(configResourcesP, resourceKind.Kind))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Strings(names)
+	var out []storage.UnknownResource
+	for _, name := range names {
+		resource, err := b.GetConfigResource(resourceKind.Kind, name)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		out = append(out, *resource)
+	}
+	return out, nil
+}
+
+// DeleteConfigResource deletes a configuration resource by kind and name
+func (b *backend) DeleteConfigResource(kind, name string) error {
+	if name == "" {
+		return trace.BadParameter("missing %v name", kind)
+	}
+	resourceKind, err := storage.GetResourceKind(kind)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = b.deleteKey(b.key(configResourcesP, resourceKind.Kind, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("%v %q is not found", resourceKind.Kind, name)
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
@@ -77,6 +77,17 @@ const (
 	dnsP                        = "dns"
 	chartsP                     = "charts"
 	indexP                      = "index"
+	trustedKeysP                = "trustedkeys"
+	auditEventsP                = "auditevents"
+	statusHistoryP              = "statushistory"
+	fleetUpgradesP              = "fleetupgrades"
+	scheduledOperationsP        = "scheduledoperations"
+	roleMappingsP               = "rolemappings"
+	catalogP                    = "catalog"
+	configResourcesP            = "configresources"
+	webhooksP                   = "webhooks"
+	provisionersP               = "provisioners"
+	terraformP                  = "terraform"
 
 	// AllCollectionIDs identifies a collection without a specification (an ID)
 	AllCollectionIDs = "__all__"
@@ -0,0 +1,389 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/gravitational/trace"
+)
+
+// EncryptionConfig configures encryption of the backend values at rest.
+//
+// The values are encrypted with AES-256-GCM using the key from either
+// a node-local file or an AWS Systems Manager parameter encrypted with KMS
+type EncryptionConfig struct {
+	// KeyFile is the path to the node-local file with the base64-encoded
+	// encryption key. The file must exist: for the cluster backend it must
+	// contain the same key on all master nodes
+	KeyFile string `json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	// SSMParameter is the name of the AWS Systems Manager SecureString
+	// parameter with the base64-encoded encryption key
+	SSMParameter string `json:"ssm_parameter,omitempty" yaml:"ssm_parameter,omitempty"`
+	// AWSRegion is the region of the Systems Manager parameter,
+	// taken from the AWS environment if unspecified
+	AWSRegion string `json:"aws_region,omitempty" yaml:"aws_region,omitempty"`
+	// PreviousKeyFiles lists the files with the keys that were used before
+	// the key has been rotated. The values encrypted with these keys can still
+	// be read until they are re-encrypted with the current key
+	PreviousKeyFiles []string `json:"previous_key_files,omitempty" yaml:"previous_key_files,omitempty"`
+}
+
+// Check makes sure the configuration is valid
+func (c EncryptionConfig) Check() error {
+	if c.KeyFile == "" && c.SSMParameter == "" {
+		return trace.BadParameter("either key file or SSM parameter is required for encryption")
+	}
+	if c.KeyFile != "" && c.SSMParameter != "" {
+		return trace.BadParameter("key file and SSM parameter are mutually exclusive")
+	}
+	return nil
+}
+
+// GenerateEncryptionKey generates a new base64-encoded encryption key
+func GenerateEncryptionKey() ([]byte, error) {
+	key := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(key)), nil
+}
+
+// WriteEncryptionKey writes the base64-encoded encryption key to the file
+// at path. An existing key file is never overwritten as the values
+// encrypted with the key it contains would become unreadable
+func WriteEncryptionKey(path string, key []byte) error {
+	if _, err := decodeKey(string(key)); err != nil {
+		return trace.Wrap(err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, defaults.PrivateFileMask)
+	if err != nil {
+		if os.IsExist(err) {
+			return trace.AlreadyExists("encryption key file %v already exists", path)
+		}
+		return trace.ConvertSystemError(err)
+	}
+	_, err = f.Write(key)
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		os.Remove(path)
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+// newValueCipher returns the cipher for the specified encryption configuration.
+// Returns nil cipher if the configuration is nil, i.e. encryption is disabled
+func newValueCipher(config *EncryptionConfig) (*valueCipher, error) {
+	if config == nil {
+		return nil, nil
+	}
+	if err := config.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var key []byte
+	var err error
+	if config.KeyFile != "" {
+		key, err = readKey(config.KeyFile)
+	} else {
+		key, err = readSSMKey(config.SSMParameter, config.AWSRegion)
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	keys := [][]byte{key}
+	for _, path := range config.PreviousKeyFiles {
+		key, err := readKey(path)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		keys = append(keys, key)
+	}
+	return newValueCipherFromKeys(keys...)
+}
+
+func newValueCipherFromKeys(keys ...[]byte) (*valueCipher, error) {
+	var c valueCipher
+	for _, key := range keys {
+		k, err := newCipherKey(key)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		c.keys = append(c.keys, *k)
+	}
+	return &c, nil
+}
+
+// valueCipher encrypts and decrypts the backend values.
+//
+// Encryption is deterministic: the nonce is derived from the value so equal
+// values have equal ciphertexts. This allows comparing encrypted values which
+// is what conditional updates in etcd rely on, at the expense of revealing
+// which values are equal.
+//
+// Nil cipher leaves the values unencrypted
+type valueCipher struct {
+	// keys lists the keys the values can be decrypted with,
+	// the first key is used for encryption
+	keys []cipherKey
+}
+
+// cipherKey is a single encryption key
+type cipherKey struct {
+	// id identifies the key the value has been encrypted with
+	id []byte
+	// aead encrypts the values
+	aead cipher.AEAD
+	// nonceKey is the key to derive the nonces with
+	nonceKey []byte
+}
+
+func newCipherKey(key []byte) (*cipherKey, error) {
+	if len(key) != encryptionKeySize {
+		return nil, trace.BadParameter("expected %v-byte encryption key, got %v bytes",
+			encryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(deriveKey(key, "encryption"))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	id := sha256.Sum256(key)
+	return &cipherKey{
+		id:       id[:keyIDSize],
+		aead:     aead,
+		nonceKey: deriveKey(key, "nonce"),
+	}, nil
+}
+
+// encrypt returns the encrypted value
+func (c *valueCipher) encrypt(data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+	key := c.keys[0]
+	mac := hmac.New(sha256.New, key.nonceKey)
+	mac.Write(data)
+	nonce := mac.Sum(nil)[:key.aead.NonceSize()]
+	header := append(append([]byte(nil), encryptedPrefix...), key.id...)
+	out := append(header, nonce...)
+	return key.aead.Seal(out, nonce, data, header), nil
+}
+
+// decrypt returns the decrypted value.
+// Values that are not encrypted are returned as is
+func (c *valueCipher) decrypt(data []byte) ([]byte, error) {
+	if !isEncrypted(data) {
+		return data, nil
+	}
+	if c == nil {
+		return nil, trace.BadParameter("value is encrypted but encryption is not configured")
+	}
+	headerSize := len(encryptedPrefix) + keyIDSize
+	if len(data) < headerSize {
+		return nil, trace.BadParameter("encrypted value is truncated")
+	}
+	header, id := data[:headerSize], data[len(encryptedPrefix):headerSize]
+	for _, key := range c.keys {
+		if !bytes.Equal(key.id, id) {
+			continue
+		}
+		nonceSize := key.aead.NonceSize()
+		if len(data) < headerSize+nonceSize {
+			return nil, trace.BadParameter("encrypted value is truncated")
+		}
+		nonce := data[headerSize : headerSize+nonceSize]
+		plaintext, err := key.aead.Open(nil, nonce, data[headerSize+nonceSize:], header)
+		if err != nil {
+			return nil, trace.BadParameter("failed to decrypt value: %v", err)
+		}
+		return plaintext, nil
+	}
+	return nil, trace.BadParameter("value is encrypted with unknown key %x", id)
+}
+
+// needsReencryption returns true if the value is not encrypted
+// with the current key
+func (c *valueCipher) needsReencryption(data []byte) bool {
+	if c == nil {
+		return isEncrypted(data)
+	}
+	return !bytes.HasPrefix(data, append(append([]byte(nil), encryptedPrefix...), c.keys[0].id...))
+}
+
+// reencrypt returns the value encrypted with the current key
+func (c *valueCipher) reencrypt(data []byte) ([]byte, error) {
+	plaintext, err := c.decrypt(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return c.encrypt(plaintext)
+}
+
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedPrefix)
+}
+
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// newEncryptingCodec returns a codec that encrypts the values
+// encoded with the specified codec
+func newEncryptingCodec(codec Codec, cipher *valueCipher) Codec {
+	if cipher == nil {
+		return codec
+	}
+	return &encryptingCodec{Codec: codec, cipher: cipher}
+}
+
+// encryptingCodec encrypts the values encoded by the wrapped codec.
+// The values encoded by the wrapped codec before encryption has been
+// enabled are decoded as is
+type encryptingCodec struct {
+	Codec
+	cipher *valueCipher
+}
+
+func (c *encryptingCodec) EncodeToString(val interface{}) (string, error) {
+	data, err := c.EncodeToBytes(val)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return c.Codec.EncodeBytesToString(data)
+}
+
+func (c *encryptingCodec) EncodeBytesToString(data []byte) (string, error) {
+	encrypted, err := c.cipher.encrypt(data)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return c.Codec.EncodeBytesToString(encrypted)
+}
+
+func (c *encryptingCodec) EncodeToBytes(val interface{}) ([]byte, error) {
+	data, err := c.Codec.EncodeToBytes(val)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return c.cipher.encrypt(data)
+}
+
+func (c *encryptingCodec) DecodeFromString(val string, in interface{}) error {
+	data, err := c.Codec.DecodeBytesFromString(val)
+	if err != nil || !isEncrypted(data) {
+		return c.Codec.DecodeFromString(val, in)
+	}
+	return c.DecodeFromBytes(data, in)
+}
+
+func (c *encryptingCodec) DecodeBytesFromString(val string) ([]byte, error) {
+	data, err := c.Codec.DecodeBytesFromString(val)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return c.cipher.decrypt(data)
+}
+
+func (c *encryptingCodec) DecodeFromBytes(data []byte, in interface{}) error {
+	plaintext, err := c.cipher.decrypt(data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return c.Codec.DecodeFromBytes(plaintext, in)
+}
+
+// readKey reads the encryption key from the file at path.
+//
+// The key is never generated here: every master node would generate
+// a different key for the shared cluster backend and a lost key file
+// would silently replace the key the existing values are encrypted with
+func readKey(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, trace.NotFound("encryption key file %v does not exist. "+
+				"Generate the key with 'gravity system backend generate-key' which "+
+				"distributes it to all master nodes, or restore the key file the "+
+				"values have been encrypted with", path)
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+	return decodeKey(string(data))
+}
+
+func readSSMKey(name, region string) ([]byte, error) {
+	config := aws.NewConfig()
+	if region != "" {
+		config = config.WithRegion(region)
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	resp, err := ssm.New(sess).GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to read encryption key from SSM parameter %v", name)
+	}
+	return decodeKey(aws.StringValue(resp.Parameter.Value))
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, trace.BadParameter("encryption key is not base64-encoded")
+	}
+	return key, nil
+}
+
+const (
+	// encryptionKeySize is the size of the encryption key in bytes
+	encryptionKeySize = 32
+	// keyIDSize is the size of the key ID stored with the encrypted values
+	keyIDSize = 4
+)
+
+// encryptedPrefix marks encrypted values. It starts with a byte
+// that is not valid in JSON or text values
+var encryptedPrefix = []byte("\x00genc1")
@@ -0,0 +1,247 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/suite"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	. "gopkg.in/check.v1"
+)
+
+type EncryptionSuite struct {
+	dir   string
+	suite suite.StorageSuite
+}
+
+var _ = Suite(&EncryptionSuite{})
+
+func (s *EncryptionSuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+	clock := clockwork.NewFakeClock()
+	backend, err := NewBolt(BoltConfig{
+		Path:       filepath.Join(s.dir, "bolt.db"),
+		Clock:      clock,
+		Encryption: &EncryptionConfig{KeyFile: newKeyFile(c, filepath.Join(s.dir, "key"))},
+	})
+	c.Assert(err, IsNil)
+	s.suite.Backend = backend
+	s.suite.Clock = clock
+}
+
+func (s *EncryptionSuite) TearDownTest(c *C) {
+	if s.suite.Backend != nil {
+		c.Assert(s.suite.Backend.Close(), IsNil)
+	}
+}
+
+func (s *EncryptionSuite) TestAccountsCRUD(c *C) {
+	s.suite.AccountsCRUD(c)
+}
+
+func (s *EncryptionSuite) TestConnectorsCRUD(c *C) {
+	s.suite.ConnectorsCRUD(c)
+}
+
+func (s *EncryptionSuite) TestOperationsCRUD(c *C) {
+	s.suite.OperationsCRUD(c)
+}
+
+func (s *EncryptionSuite) TestLocksCRUD(c *C) {
+	s.suite.LocksCRUD(c)
+}
+
+func (s *EncryptionSuite) TestWatch(c *C) {
+	s.suite.Watch(c)
+}
+
+func (s *EncryptionSuite) TestEncryptsValues(c *C) {
+	_, err := s.suite.Backend.CreateAccount(storage.Account{Org: "secret-org.example.com"})
+	c.Assert(err, IsNil)
+	c.Assert(s.suite.Backend.Close(), IsNil)
+	s.suite.Backend = nil
+
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, "bolt.db"))
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Contains(data, []byte("secret-org")), Equals, false)
+
+	// the values cannot be read without the key
+	backend, err := NewBolt(BoltConfig{Path: filepath.Join(s.dir, "bolt.db")})
+	c.Assert(err, IsNil)
+	defer backend.Close()
+	_, err = backend.GetAccounts()
+	c.Assert(err, NotNil)
+}
+
+func (s *EncryptionSuite) TestReencryptsValues(c *C) {
+	path := filepath.Join(c.MkDir(), "bolt.db")
+	backend, err := NewBolt(BoltConfig{Path: path, Multi: true})
+	c.Assert(err, IsNil)
+	account, err := backend.CreateAccount(storage.Account{Org: "example.com"})
+	c.Assert(err, IsNil)
+	c.Assert(backend.Close(), IsNil)
+
+	// enable encryption and encrypt the existing values
+	oldKey := filepath.Join(s.dir, "key")
+	backend, err = NewBolt(BoltConfig{
+		Path:       path,
+		Multi:      true,
+		Encryption: &EncryptionConfig{KeyFile: oldKey},
+	})
+	c.Assert(err, IsNil)
+	count, err := Reencrypt(backend)
+	c.Assert(err, IsNil)
+	c.Assert(count > 0, Equals, true)
+	count, err = Reencrypt(backend)
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 0)
+	c.Assert(backend.Close(), IsNil)
+
+	// rotate the key
+	newKey := newKeyFile(c, filepath.Join(s.dir, "new-key"))
+	backend, err = NewBolt(BoltConfig{
+		Path:  path,
+		Multi: true,
+		Encryption: &EncryptionConfig{
+			KeyFile:          newKey,
+			PreviousKeyFiles: []string{oldKey},
+		},
+	})
+	c.Assert(err, IsNil)
+	out, err := backend.GetAccount(account.ID)
+	c.Assert(err, IsNil)
+	c.Assert(out.Org, Equals, account.Org)
+	count, err = Reencrypt(backend)
+	c.Assert(err, IsNil)
+	c.Assert(count > 0, Equals, true)
+	c.Assert(backend.Close(), IsNil)
+
+	// the values can be read without the previous key
+	backend, err = NewBolt(BoltConfig{
+		Path:       path,
+		Encryption: &EncryptionConfig{KeyFile: newKey},
+	})
+	c.Assert(err, IsNil)
+	defer backend.Close()
+	out, err = backend.GetAccount(account.ID)
+	c.Assert(err, IsNil)
+	c.Assert(out.Org, Equals, account.Org)
+}
+
+func (s *EncryptionSuite) TestRequiresExistingKeyFile(c *C) {
+	path := filepath.Join(c.MkDir(), "key")
+	_, err := NewBolt(BoltConfig{
+		Path:       filepath.Join(c.MkDir(), "bolt.db"),
+		Encryption: &EncryptionConfig{KeyFile: path},
+	})
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), Equals, true, Commentf("key file should not be generated"))
+}
+
+func (s *EncryptionSuite) TestDoesNotOverwriteKeyFile(c *C) {
+	path := newKeyFile(c, filepath.Join(c.MkDir(), "key"))
+	existing, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	key, err := GenerateEncryptionKey()
+	c.Assert(err, IsNil)
+	err = WriteEncryptionKey(path, key)
+	c.Assert(trace.IsAlreadyExists(err), Equals, true, Commentf("%v", err))
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, string(existing))
+}
+
+func (s *EncryptionSuite) TestCipher(c *C) {
+	key1, key2 := bytes.Repeat([]byte("1"), encryptionKeySize), bytes.Repeat([]byte("2"), encryptionKeySize)
+	cipher, err := newValueCipherFromKeys(key1)
+	c.Assert(err, IsNil)
+
+	encrypted, err := cipher.encrypt([]byte(`{"key":"value"}`))
+	c.Assert(err, IsNil)
+	c.Assert(isEncrypted(encrypted), Equals, true)
+	again, err := cipher.encrypt([]byte(`{"key":"value"}`))
+	c.Assert(err, IsNil)
+	c.Assert(again, DeepEquals, encrypted, Commentf("expected deterministic encryption"))
+	decrypted, err := cipher.decrypt(encrypted)
+	c.Assert(err, IsNil)
+	c.Assert(string(decrypted), Equals, `{"key":"value"}`)
+
+	// unencrypted values are read as is
+	decrypted, err = cipher.decrypt([]byte(`"plain"`))
+	c.Assert(err, IsNil)
+	c.Assert(string(decrypted), Equals, `"plain"`)
+	c.Assert(cipher.needsReencryption([]byte(`"plain"`)), Equals, true)
+	c.Assert(cipher.needsReencryption(encrypted), Equals, false)
+
+	// tampered values are rejected
+	tampered := append([]byte(nil), encrypted...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = cipher.decrypt(tampered)
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+
+	rotated, err := newValueCipherFromKeys(key2, key1)
+	c.Assert(err, IsNil)
+	decrypted, err = rotated.decrypt(encrypted)
+	c.Assert(err, IsNil)
+	c.Assert(string(decrypted), Equals, `{"key":"value"}`)
+	c.Assert(rotated.needsReencryption(encrypted), Equals, true)
+
+	other, err := newValueCipherFromKeys(key2)
+	c.Assert(err, IsNil)
+	_, err = other.decrypt(encrypted)
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+}
+
+func (s *EncryptionSuite) TestEncryptingCodec(c *C) {
+	cipher, err := newValueCipherFromKeys(bytes.Repeat([]byte("1"), encryptionKeySize))
+	c.Assert(err, IsNil)
+	codec := newEncryptingCodec(&v1codec{}, cipher)
+
+	encoded, err := codec.EncodeToString(map[string]string{"key": "value"})
+	c.Assert(err, IsNil)
+	var out map[string]string
+	c.Assert(codec.DecodeFromString(encoded, &out), IsNil)
+	c.Assert(out, DeepEquals, map[string]string{"key": "value"})
+
+	// values encoded before encryption has been enabled are decoded as is
+	plain, err := (&v1codec{}).EncodeToString(map[string]string{"key": "plain"})
+	c.Assert(err, IsNil)
+	c.Assert(codec.DecodeFromString(plain, &out), IsNil)
+	c.Assert(out, DeepEquals, map[string]string{"key": "plain"})
+
+	encoded, err = codec.EncodeBytesToString([]byte("data"))
+	c.Assert(err, IsNil)
+	data, err := codec.DecodeBytesFromString(encoded)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "data")
+}
+
+// newKeyFile writes a new encryption key to the file at path
+func newKeyFile(c *C, path string) string {
+	key, err := GenerateEncryptionKey()
+	c.Assert(err, IsNil)
+	c.Assert(WriteEncryptionKey(path, key), IsNil)
+	return path
+}
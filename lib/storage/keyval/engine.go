@@ -0,0 +1,240 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+)
+
+// Engine is a key-value store that can be plugged in as a storage backend.
+//
+// Keys are hierarchical: all components of a key but the last one name
+// the directories the value is stored in. Values are opaque to the engine.
+// Zero ttl means the value or directory does not expire.
+type Engine interface {
+	io.Closer
+	// Key returns the key with the specified path components
+	// under the specified top-level prefix
+	Key(prefix string, keys ...string) Key
+	// CreateVal creates a new value.
+	// Returns trace.AlreadyExists if the value already exists
+	CreateVal(key Key, val []byte, ttl time.Duration) error
+	// UpsertVal creates a new or updates an existing value
+	UpsertVal(key Key, val []byte, ttl time.Duration) error
+	// UpdateVal updates an existing value.
+	// Returns trace.NotFound if the value does not exist
+	UpdateVal(key Key, val []byte, ttl time.Duration) error
+	// UpdateTTL updates the expiration time of an existing value or directory
+	UpdateTTL(key Key, ttl time.Duration) error
+	// CompareAndSwap replaces the value only if it matches prevVal and returns
+	// the replaced value. Nil prevVal means the value is expected to not exist.
+	// Returns trace.CompareFailed if the value does not match prevVal
+	CompareAndSwap(key Key, val, prevVal []byte, ttl time.Duration) ([]byte, error)
+	// GetVal returns the value.
+	// Returns trace.NotFound if the value does not exist
+	GetVal(key Key) ([]byte, error)
+	// DeleteKey deletes the value.
+	// Returns trace.NotFound if the value does not exist
+	DeleteKey(key Key) error
+	// CompareAndDelete deletes the value only if it matches prevVal.
+	// Returns trace.CompareFailed if the value does not match prevVal
+	CompareAndDelete(key Key, prevVal []byte) error
+	// CreateDir creates a new directory.
+	// Returns trace.AlreadyExists if the directory already exists
+	CreateDir(key Key, ttl time.Duration) error
+	// UpsertDir creates a new or updates an existing directory
+	UpsertDir(key Key, ttl time.Duration) error
+	// DeleteDir deletes the directory with all its contents.
+	// Returns trace.NotFound if the directory does not exist
+	DeleteDir(key Key) error
+	// AcquireLock blocks until it acquires the lock with the specified token
+	AcquireLock(token Key, ttl time.Duration) error
+	// TryAcquireLock acquires the lock with the specified token.
+	// Returns trace.AlreadyExists if the lock is held
+	TryAcquireLock(token Key, ttl time.Duration) error
+	// ReleaseLock releases the lock with the specified token
+	ReleaseLock(token Key) error
+	// GetKeys returns the sorted names of the values and directories
+	// in the specified directory
+	GetKeys(key Key) ([]string, error)
+	// Watch returns a watcher for the changes of the keys with the specified prefix
+	Watch(ctx context.Context, prefix Key) (storage.Watcher, error)
+}
+
+// NewBackend returns a storage backend that keeps its data in the specified engine
+func NewBackend(engine Engine, clock clockwork.Clock) storage.Backend {
+	if clock == nil {
+		clock = clockwork.NewRealClock()
+	}
+	return &backend{
+		Clock: clock,
+		kvengine: &engineAdapter{
+			Engine: engine,
+			codec:  &v1codec{},
+		},
+	}
+}
+
+// engineAdapter adapts an Engine to the internal engine interface
+// by encoding the values with the codec
+type engineAdapter struct {
+	Engine
+	codec Codec
+}
+
+func (e *engineAdapter) key(prefix string, keys ...string) key {
+	return e.Key(prefix, keys...)
+}
+
+func (e *engineAdapter) createVal(key key, val interface{}, ttl time.Duration) error {
+	data, err := e.codec.EncodeToBytes(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.CreateVal(key, data, ttl))
+}
+
+func (e *engineAdapter) createValBytes(key key, data []byte, ttl time.Duration) error {
+	return trace.Wrap(e.CreateVal(key, data, ttl))
+}
+
+func (e *engineAdapter) upsertVal(key key, val interface{}, ttl time.Duration) error {
+	data, err := e.codec.EncodeToBytes(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.UpsertVal(key, data, ttl))
+}
+
+func (e *engineAdapter) upsertValBytes(key key, data []byte, ttl time.Duration) error {
+	return trace.Wrap(e.UpsertVal(key, data, ttl))
+}
+
+func (e *engineAdapter) updateVal(key key, val interface{}, ttl time.Duration) error {
+	data, err := e.codec.EncodeToBytes(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.UpdateVal(key, data, ttl))
+}
+
+func (e *engineAdapter) updateValBytes(key key, data []byte, ttl time.Duration) error {
+	return trace.Wrap(e.UpdateVal(key, data, ttl))
+}
+
+func (e *engineAdapter) updateTTL(key key, ttl time.Duration) error {
+	return trace.Wrap(e.UpdateTTL(key, ttl))
+}
+
+func (e *engineAdapter) compareAndSwap(key key, val, prevVal, outVal interface{}, ttl time.Duration) error {
+	data, err := e.codec.EncodeToBytes(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var prevData []byte
+	if prevVal != nil {
+		prevData, err = e.codec.EncodeToBytes(prevVal)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	outData, err := e.CompareAndSwap(key, data, prevData, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if prevVal != nil {
+		return trace.Wrap(e.codec.DecodeFromBytes(outData, outVal))
+	}
+	return nil
+}
+
+func (e *engineAdapter) compareAndSwapBytes(key key, val, prevVal []byte, outVal *[]byte, ttl time.Duration) error {
+	outData, err := e.CompareAndSwap(key, val, prevVal, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if outVal != nil {
+		*outVal = outData
+	}
+	return nil
+}
+
+func (e *engineAdapter) getVal(key key, val interface{}) error {
+	data, err := e.GetVal(key)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.codec.DecodeFromBytes(data, val))
+}
+
+func (e *engineAdapter) getValBytes(key key) ([]byte, error) {
+	data, err := e.GetVal(key)
+	return data, trace.Wrap(err)
+}
+
+func (e *engineAdapter) deleteKey(key key) error {
+	return trace.Wrap(e.DeleteKey(key))
+}
+
+func (e *engineAdapter) compareAndDelete(key key, prevVal interface{}) error {
+	data, err := e.codec.EncodeToBytes(prevVal)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.CompareAndDelete(key, data))
+}
+
+func (e *engineAdapter) createDir(key key, ttl time.Duration) error {
+	return trace.Wrap(e.CreateDir(key, ttl))
+}
+
+func (e *engineAdapter) upsertDir(key key, ttl time.Duration) error {
+	return trace.Wrap(e.UpsertDir(key, ttl))
+}
+
+func (e *engineAdapter) deleteDir(key key) error {
+	return trace.Wrap(e.DeleteDir(key))
+}
+
+func (e *engineAdapter) acquireLock(token key, ttl time.Duration) error {
+	return trace.Wrap(e.AcquireLock(token, ttl))
+}
+
+func (e *engineAdapter) tryAcquireLock(token key, ttl time.Duration) error {
+	return trace.Wrap(e.TryAcquireLock(token, ttl))
+}
+
+func (e *engineAdapter) releaseLock(token key) error {
+	return trace.Wrap(e.ReleaseLock(token))
+}
+
+func (e *engineAdapter) getKeys(key key) ([]string, error) {
+	keys, err := e.GetKeys(key)
+	return keys, trace.Wrap(err)
+}
+
+func (e *engineAdapter) watch(ctx context.Context, prefix key) (storage.Watcher, error) {
+	watcher, err := e.Watch(ctx, prefix)
+	return watcher, trace.Wrap(err)
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/state"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/cenkalti/backoff"
@@ -44,7 +45,18 @@ func NewETCD(cfg ETCDConfig) (*electingBackend, error) {
 		return nil, trace.Wrap(err)
 	}
 
-	engine, err := newEngine(cfg, &v1codec{})
+	cipher, err := newValueCipher(cfg.Encryption)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	engine, err := newEngine(cfg, newEncryptingCodec(&v1codec{}, cipher))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	engine.cipher = cipher
+
+	kv, err := selectEngine(cfg, engine)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -62,23 +74,83 @@ func NewETCD(cfg ETCDConfig) (*electingBackend, error) {
 	return &electingBackend{
 		Backend: &backend{
 			Clock:    clock,
-			kvengine: engine,
+			kvengine: kv,
 		},
 		Leader: leader,
 		client: engine.client,
 	}, nil
 }
 
+// selectEngine returns the engine for the API version specified in the configuration.
+// The v2 engine is used unless the v3 API is selected explicitly or
+// the data has been migrated to the v3 keyspace.
+//
+// The v2 engine switches to the v3 API once the data has been migrated
+// while the process is running, and the v3 engine refuses to open the v2 data
+// that has not been migrated yet, so processes never write to the copy
+// of the data the other processes do not see
+func selectEngine(cfg ETCDConfig, v2 *engine) (kvengine, error) {
+	if cfg.APIVersion != ETCDAPIVersion3 {
+		state, err := v2.migrationFenceState()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if state != migrationCompleted {
+			return newSwitchingEngine(v2), nil
+		}
+		log.Infof("Data under %v has been migrated to etcd v3, set %q to %q in the backend configuration.",
+			cfg.Key, "api_version", ETCDAPIVersion3)
+	}
+	v3, err := newEngineV3(cfg)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	v3.cipher = v2.cipher
+	migrated, err := v3.isMigrated()
+	if err != nil {
+		v3.Close()
+		return nil, trace.Wrap(err)
+	}
+	if migrated {
+		return v3, nil
+	}
+	hasData, err := v2.hasData()
+	if err != nil {
+		v3.Close()
+		return nil, trace.Wrap(err)
+	}
+	if hasData {
+		v3.Close()
+		return nil, trace.BadParameter("data under %v has not been migrated to etcd v3, "+
+			"migrate it with 'gravity system backend migrate' first", cfg.Key)
+	}
+	return v3, nil
+}
+
 // ETCDConfig represents JSON config for ETCD backend
 type ETCDConfig struct {
-	Clock         clockwork.Clock `json:"-"`
+	Clock         clockwork.Clock `json:"-" yaml:"-"`
 	Nodes         []string        `json:"nodes" yaml:"nodes"`
 	Key           string          `json:"key" yaml:"key"`
 	TLSKeyFile    string          `json:"tls_key_file" yaml:"tls_key_file"`
 	TLSCertFile   string          `json:"tls_cert_file" yaml:"tls_cert_file"`
 	TLSCAFile     string          `json:"tls_ca_file" yaml:"tls_ca_file"`
 	RetryInterval time.Duration   `json:"retry_interval" yaml:"retry_interval"`
-}
+	// APIVersion selects the etcd API version: v2 or v3.
+	// Defaults to v2. Existing v2 data must be migrated with MigrateToV3
+	// before the v3 API can be selected, the v3 API is used for the
+	// migrated data regardless of this setting
+	APIVersion string `json:"api_version" yaml:"api_version"`
+	// Encryption enables encryption of the values at rest if set
+	Encryption *EncryptionConfig `json:"encryption,omitempty" yaml:"encryption,omitempty"`
+}
+
+const (
+	// ETCDAPIVersion2 selects the etcd v2 API
+	ETCDAPIVersion2 = "v2"
+	// ETCDAPIVersion3 selects the etcd v3 API
+	ETCDAPIVersion3 = "v3"
+)
 
 // LocalEtcdConfig returns config for local etcd
 func LocalEtcdConfig(retryTimeout time.Duration) (*ETCDConfig, error) {
@@ -115,6 +187,17 @@ func (cfg *ETCDConfig) Check() error {
 	if cfg.TLSCertFile == "" {
 		return trace.BadParameter(`TLSCertFile: please supply a path to TLS certificate file`)
 	}
+	switch cfg.APIVersion {
+	case "", ETCDAPIVersion2, ETCDAPIVersion3:
+	default:
+		return trace.BadParameter(`APIVersion: expected %q or %q, got %q`,
+			ETCDAPIVersion2, ETCDAPIVersion3, cfg.APIVersion)
+	}
+	if cfg.Encryption != nil {
+		if err := cfg.Encryption.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	return nil
 }
 
@@ -146,11 +229,18 @@ type engine struct {
 	client  client.Client
 	cancelC chan bool
 	stopC   chan bool
+	// cipher encrypts the values at rest, nil if encryption is disabled
+	cipher *valueCipher
 }
 
 func (e *engine) key(prefix string, keys ...string) key {
-	key := make([]string, 0, len(e.etcdKey)+len(keys)+1)
-	key = append(key, e.etcdKey...)
+	return makeKey(e.etcdKey, prefix, keys...)
+}
+
+// makeKey returns the key with the specified components under the root key
+func makeKey(root []string, prefix string, keys ...string) key {
+	key := make([]string, 0, len(root)+len(keys)+1)
+	key = append(key, root...)
 	key = append(key, prefix)
 	key = append(key, keys...)
 	for i := range key {
@@ -453,6 +543,79 @@ func (e *engine) getKeys(key key) ([]string, error) {
 	return vals, nil
 }
 
+// watch returns a watcher for the changes of the keys with the specified prefix.
+// The watch starts at the current etcd index so no changes made after
+// the function returns are missed
+func (e *engine) watch(ctx context.Context, prefix key) (storage.Watcher, error) {
+	var index uint64
+	re, err := client.NewKeysAPI(e.client).Get(ctx, ekey(prefix), nil)
+	switch etcdErr := err.(type) {
+	case nil:
+		index = re.Index
+	case client.Error:
+		if etcdErr.Code != client.ErrorCodeKeyNotFound {
+			return nil, trace.Wrap(convertErr(err))
+		}
+		index = etcdErr.Index
+	default:
+		return nil, trace.Wrap(convertErr(err))
+	}
+	w := newWatcher(ctx)
+	etcdWatcher := e.Watcher(ekey(prefix), &client.WatcherOptions{
+		AfterIndex: index,
+		Recursive:  true,
+	})
+	go func() {
+		for {
+			re, err := etcdWatcher.Next(w.ctx)
+			if err != nil {
+				if w.ctx.Err() == nil {
+					w.stop(trace.Wrap(convertErr(err)))
+				}
+				return
+			}
+			event, ok := e.watchEvent(re)
+			if ok && !w.push(event) {
+				return
+			}
+		}
+	}()
+	return w, nil
+}
+
+// watchEvent converts the etcd response to a watch event.
+// Returns false if the response does not describe a change of a key
+func (e *engine) watchEvent(re *client.Response) (event storage.WatchEvent, ok bool) {
+	if re.Node == nil {
+		return event, false
+	}
+	event.Key, ok = relativeKey(e.etcdKey, re.Node.Key)
+	if !ok {
+		return event, false
+	}
+	switch re.Action {
+	case "delete", "expire", "compareAndDelete":
+		event.Type = storage.WatchEventDeleted
+		return event, true
+	case "create", "set", "update", "compareAndSwap":
+		if re.Node.Dir {
+			return event, false
+		}
+		event.Type = storage.WatchEventUpdated
+		if re.PrevNode == nil {
+			event.Type = storage.WatchEventCreated
+		}
+		value, err := e.codec.DecodeBytesFromString(re.Node.Value)
+		if err != nil {
+			// locks are not encoded
+			value = []byte(re.Node.Value)
+		}
+		event.Value = value
+		return event, true
+	}
+	return event, false
+}
+
 func convertErr(e error) error {
 	if e == nil {
 		return nil
@@ -31,6 +31,7 @@ import (
 	"github.com/gravitational/trace"
 
 	"github.com/coreos/etcd/client"
+	"github.com/coreos/etcd/clientv3"
 	"github.com/jonboulle/clockwork"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
@@ -53,9 +54,21 @@ type tempBackend struct {
 	prefix  string
 	clock   clockwork.FakeClock
 	backend storage.Backend
+	config  ETCDConfig
 }
 
 func (t *tempBackend) Delete() error {
+	if t.config.APIVersion == ETCDAPIVersion3 {
+		clt, err := newClientV3(t.config)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		defer clt.Close()
+		_, err = clt.Delete(context.Background(), t.prefix, clientv3.WithPrefix())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	var err error
 	if t.api != nil {
 		_, err = t.api.Delete(context.Background(), t.prefix, &client.DeleteOptions{Recursive: true, Dir: true})
@@ -68,7 +81,7 @@ func (t *tempBackend) Delete() error {
 	return nil
 }
 
-func newBackend(configJSON string) (*tempBackend, error) {
+func newBackend(configJSON, apiVersion string) (*tempBackend, error) {
 	if configJSON == "" {
 		return nil, trace.BadParameter("missing ETCD configuration")
 	}
@@ -78,6 +91,7 @@ func newBackend(configJSON string) (*tempBackend, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	cfg.APIVersion = apiVersion
 	token, err := teleutils.CryptoRandomHex(6)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -90,10 +104,14 @@ func newBackend(configJSON string) (*tempBackend, error) {
 		return nil, trace.Wrap(err)
 	}
 
-	return &tempBackend{prefix: cfg.Key, api: b.api(), clock: fakeClock, backend: b}, nil
+	return &tempBackend{prefix: cfg.Key, api: b.api(), clock: fakeClock, backend: b, config: cfg}, nil
 }
 
 func (s *ESuite) SetUpTest(c *C) {
+	s.setUp(c, ETCDAPIVersion2)
+}
+
+func (s *ESuite) setUp(c *C, apiVersion string) {
 	log.SetOutput(os.Stderr)
 
 	testETCD := os.Getenv(defaults.TestETCD)
@@ -104,7 +122,7 @@ func (s *ESuite) SetUpTest(c *C) {
 	}
 
 	var err error
-	s.backend, err = newBackend(os.Getenv(defaults.TestETCDConfig))
+	s.backend, err = newBackend(os.Getenv(defaults.TestETCDConfig), apiVersion)
 	c.Assert(err, IsNil)
 
 	s.suite.Backend = s.backend.backend
@@ -221,6 +239,10 @@ func (s *ESuite) TestPeersCRUD(c *C) {
 	s.suite.PeersCRUD(c)
 }
 
+func (s *ESuite) TestWatch(c *C) {
+	s.suite.Watch(c)
+}
+
 func (s *ESuite) TestObjectsCRUD(c *C) {
 	s.suite.ObjectsCRUD(c)
 }
@@ -264,3 +286,51 @@ func (s *ESuite) TestClusterLogin(c *C) {
 func (s *ESuite) TestIndexFile(c *C) {
 	s.suite.IndexFile(c)
 }
+
+func (s *ESuite) TestTrustedKeysCRUD(c *C) {
+	s.suite.TrustedKeysCRUD(c)
+}
+
+func (s *ESuite) TestAuditEventsCRUD(c *C) {
+	s.suite.AuditEventsCRUD(c)
+}
+
+func (s *ESuite) TestStatusHistoryCRUD(c *C) {
+	s.suite.StatusHistoryCRUD(c)
+}
+
+func (s *ESuite) TestFleetUpgradesCRUD(c *C) {
+	s.suite.FleetUpgradesCRUD(c)
+}
+
+func (s *ESuite) TestScheduledOperationsCRUD(c *C) {
+	s.suite.ScheduledOperationsCRUD(c)
+}
+
+func (s *ESuite) TestRoleMappingsCRUD(c *C) {
+	s.suite.RoleMappingsCRUD(c)
+}
+
+func (s *ESuite) TestCatalogEntriesCRUD(c *C) {
+	s.suite.CatalogEntriesCRUD(c)
+}
+
+func (s *ESuite) TestConfigResourcesCRUD(c *C) {
+	s.suite.ConfigResourcesCRUD(c)
+}
+
+func (s *ESuite) TestWebhooksCRUD(c *C) {
+	s.suite.WebhooksCRUD(c)
+}
+
+func (s *ESuite) TestWebhooksIsolatedByCluster(c *C) {
+	s.suite.WebhooksIsolatedByCluster(c)
+}
+
+func (s *ESuite) TestProvisionersCRUD(c *C) {
+	s.suite.ProvisionersCRUD(c)
+}
+
+func (s *ESuite) TestTerraformStatesCRUD(c *C) {
+	s.suite.TerraformStatesCRUD(c)
+}
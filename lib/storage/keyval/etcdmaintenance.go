@@ -0,0 +1,187 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// EtcdMemberStatus describes the status of a single etcd cluster member
+type EtcdMemberStatus struct {
+	// ID is the hex-encoded member ID
+	ID string `json:"id"`
+	// Name is the member name
+	Name string `json:"name"`
+	// Endpoint is the client URL the member was queried on
+	Endpoint string `json:"endpoint"`
+	// Version is the etcd version the member is running
+	Version string `json:"version,omitempty"`
+	// DBSize is the size of the member database in bytes
+	DBSize int64 `json:"db_size"`
+	// Leader is true if the member is the cluster leader
+	Leader bool `json:"leader"`
+	// Healthy is true if the member has responded to the status query
+	Healthy bool `json:"healthy"`
+	// Error is the reason the member is not healthy
+	Error string `json:"error,omitempty"`
+}
+
+// EtcdMembers returns the status of all members of the etcd cluster
+// specified with config.
+//
+// Members that fail to respond are reported as unhealthy instead of
+// failing the whole query
+func EtcdMembers(ctx context.Context, config ETCDConfig) ([]EtcdMemberStatus, error) {
+	client, err := newClientV3(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer client.Close()
+	return etcdMembers(ctx, client)
+}
+
+// DefragmentEtcd defragments the database of every member of the etcd
+// cluster specified with config one member at a time to avoid a cluster-wide
+// pause, and returns the status of the members after defragmentation
+func DefragmentEtcd(ctx context.Context, config ETCDConfig) ([]EtcdMemberStatus, error) {
+	client, err := newClientV3(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer client.Close()
+
+	members, err := etcdMembers(ctx, client)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var errors []error
+	for _, member := range members {
+		if !member.Healthy {
+			errors = append(errors, trace.BadParameter(
+				"skipped unhealthy member %v: %v", member.Name, member.Error))
+			continue
+		}
+		log.Infof("Defragmenting etcd member %v (%v).", member.Name, member.Endpoint)
+		if _, err := client.Defragment(ctx, member.Endpoint); err != nil {
+			errors = append(errors, trace.Wrap(err, "failed to defragment member %v", member.Name))
+		}
+	}
+	members, err = etcdMembers(ctx, client)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return members, trace.NewAggregate(errors...)
+}
+
+// SnapshotFilename returns the name of the periodic snapshot file
+// taken at the specified time
+func SnapshotFilename(now time.Time) string {
+	return fmt.Sprintf("%v%v%v", snapshotFilePrefix,
+		now.UTC().Format(snapshotTimeFormat), snapshotFileSuffix)
+}
+
+// PruneSnapshots removes all but the keep most recent periodic snapshots
+// from dir and returns the paths of the removed files.
+// Files not named with SnapshotFilename are left intact
+func PruneSnapshots(dir string, keep int) (removed []string, err error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	var names []string
+	for _, file := range files {
+		if !file.Mode().IsRegular() || !isSnapshotFilename(file.Name()) {
+			continue
+		}
+		names = append(names, file.Name())
+	}
+	if len(names) <= keep {
+		return nil, nil
+	}
+	// timestamps in the names sort chronologically
+	sort.Strings(names)
+	for _, name := range names[:len(names)-keep] {
+		path := filepath.Join(dir, name)
+		if err := os.Remove(path); err != nil {
+			return removed, trace.ConvertSystemError(err)
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+func etcdMembers(ctx context.Context, client *clientv3.Client) ([]EtcdMemberStatus, error) {
+	resp, err := client.MemberList(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	members := make([]EtcdMemberStatus, 0, len(resp.Members))
+	for _, member := range resp.Members {
+		status := EtcdMemberStatus{
+			ID:   fmt.Sprintf("%x", member.ID),
+			Name: member.Name,
+		}
+		if len(member.ClientURLs) == 0 {
+			status.Error = "member has not started"
+			members = append(members, status)
+			continue
+		}
+		status.Endpoint = member.ClientURLs[0]
+		statusCtx, cancel := context.WithTimeout(ctx, defaults.EtcdStatusTimeout)
+		resp, err := client.Status(statusCtx, status.Endpoint)
+		cancel()
+		if err != nil {
+			status.Error = trace.UserMessage(err)
+			members = append(members, status)
+			continue
+		}
+		status.Healthy = true
+		status.Version = resp.Version
+		status.DBSize = resp.DbSize
+		status.Leader = resp.Leader == member.ID
+		members = append(members, status)
+	}
+	return members, nil
+}
+
+func isSnapshotFilename(name string) bool {
+	if !strings.HasPrefix(name, snapshotFilePrefix) || !strings.HasSuffix(name, snapshotFileSuffix) {
+		return false
+	}
+	timestamp := strings.TrimSuffix(strings.TrimPrefix(name, snapshotFilePrefix), snapshotFileSuffix)
+	_, err := time.Parse(snapshotTimeFormat, timestamp)
+	return err == nil
+}
+
+const (
+	snapshotFilePrefix = "etcd-"
+	snapshotFileSuffix = ".db"
+	snapshotTimeFormat = "20060102-150405"
+)
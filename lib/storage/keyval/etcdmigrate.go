@@ -0,0 +1,305 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/coreos/etcd/client"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// MigrationStatus describes the outcome of the backend data migration
+type MigrationStatus struct {
+	// Keys is the number of migrated keys
+	Keys int `json:"keys"`
+	// Dirs is the number of migrated directories
+	Dirs int `json:"dirs"`
+}
+
+// MigrateToV3 copies the data stored with the etcd v2 API under the root key
+// of the specified configuration into the v3 keyspace and marks the data
+// as migrated.
+//
+// The migration is online: the processes using the backend keep running.
+// Before the data is copied, the v2 data is fenced and the migration waits
+// until the running processes observe the fence and finish the writes
+// in flight. Writes are held while the data is being copied, reads are
+// served from the v2 data. Once the data has been copied, the fence is
+// marked completed and the processes switch to the v3 API, as do processes
+// that open the backend afterwards regardless of the configured API version.
+// If the migration fails, the fence is removed and the processes resume
+// writing to the v2 data. A migration interrupted before the fence has been
+// removed holds the writes until it is run again.
+//
+// The leader election of the cluster controller and the v2 data are
+// left intact
+func MigrateToV3(ctx context.Context, config ETCDConfig) (*MigrationStatus, error) {
+	if err := config.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cipher, err := newValueCipher(config.Encryption)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	v2, err := newEngine(config, newEncryptingCodec(&v1codec{}, cipher))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	v3, err := newEngineV3(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer v3.Close()
+	v3.cipher = cipher
+
+	migrated, err := v3.isMigrated()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if migrated {
+		// complete the fence in case the previous migration
+		// was interrupted right after the data has been copied
+		if err := v2.setMigrationFence(migrationCompleted); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return nil, trace.AlreadyExists("data under %v has already been migrated", config.Key)
+	}
+
+	state, err := v2.migrationFenceState()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	m := migration{
+		v2:     v2,
+		v3:     v3,
+		rootV2: "/" + strings.Trim(config.Key, "/"),
+		rootV3: strings.Join(v3.etcdKey, "/"),
+		// the data copied by an interrupted migration is replaced
+		resume: state == migrationInProgress,
+	}
+	if err := v2.setMigrationFence(migrationInProgress); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	status, err := m.fencedRun(ctx)
+	if err != nil {
+		if errUnfence := v2.unfenceMigration(); errUnfence != nil {
+			log.Warnf("Failed to remove migration fence: %v.", trace.DebugReport(errUnfence))
+		}
+		return nil, trace.Wrap(err)
+	}
+	if err := v2.setMigrationFence(migrationCompleted); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	log.Infof("Migrated %v keys and %v directories under %v to etcd v3.",
+		status.Keys, status.Dirs, config.Key)
+	return status, nil
+}
+
+// migration copies etcd v2 nodes to the v3 keyspace
+type migration struct {
+	v2     *engine
+	v3     *engineV3
+	rootV2 string
+	rootV3 string
+	// resume is set if the migration has been interrupted before
+	resume bool
+	status MigrationStatus
+}
+
+// fencedRun waits for the writes of the processes that have not yet
+// observed the migration fence and migrates the data
+func (m *migration) fencedRun(ctx context.Context) (*MigrationStatus, error) {
+	wait := migrationFenceWait(m.v2.cfg)
+	log.Infof("Waiting %v for the writes in flight to complete.", wait)
+	select {
+	case <-time.After(wait):
+	case <-ctx.Done():
+		return nil, trace.Wrap(ctx.Err())
+	}
+	if m.resume {
+		err := m.v3.retry(func(ctx context.Context) error {
+			_, err := m.v3.client.Delete(ctx, m.rootV3+"/", clientv3.WithPrefix())
+			return err
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return m.run(ctx)
+}
+
+// run copies all nodes under the root key except the transient ones
+// and marks the data as migrated
+func (m *migration) run(ctx context.Context) (*MigrationStatus, error) {
+	re, err := m.v2.Get(ctx, m.rootV2, &client.GetOptions{Recursive: true})
+	err = convertErr(err)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if re != nil && re.Node != nil {
+		for _, node := range re.Node.Nodes {
+			if isTransientKey(suffix(node.Key)) {
+				continue
+			}
+			if err := m.migrate(node, clientv3.NoLease); err != nil {
+				return nil, trace.Wrap(err)
+			}
+		}
+	}
+	if err := m.v3.put(m.v3.migrationKey(), nil, clientv3.NoLease); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &m.status, nil
+}
+
+// migrate copies the specified node and all its children.
+// Nodes without a TTL of their own inherit the lease of the parent directory
+func (m *migration) migrate(node *client.Node, parentLease clientv3.LeaseID) error {
+	key := m.rootV3 + strings.TrimPrefix(node.Key, m.rootV2)
+	lease := parentLease
+	if node.TTL > 0 {
+		var err error
+		lease, err = m.v3.grant(time.Duration(node.TTL) * time.Second)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if !node.Dir {
+		// values are decoded with the codec of the v2 engine and
+		// stored as the v3 engine stores them
+		value, err := m.v2.codec.DecodeBytesFromString(node.Value)
+		if err != nil {
+			return trace.Wrap(err, "failed to decode key %q", node.Key)
+		}
+		value, err = m.v3.cipher.encrypt(value)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := m.v3.put(key, value, lease); err != nil {
+			return trace.Wrap(err, "failed to migrate key %q", node.Key)
+		}
+		m.status.Keys++
+		return nil
+	}
+	// directories with a TTL are recorded explicitly to preserve
+	// their expiration, empty directories - to keep them listed
+	if node.TTL > 0 || len(node.Nodes) == 0 {
+		if err := m.v3.put(key+"/"+dirMarker, nil, lease); err != nil {
+			return trace.Wrap(err, "failed to migrate directory %q", node.Key)
+		}
+	}
+	m.status.Dirs++
+	for _, child := range node.Nodes {
+		if err := m.migrate(child, lease); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// setMigrationFence sets the state of the migration of the v2 data
+// to the v3 keyspace
+func (e *engine) setMigrationFence(state string) error {
+	_, err := e.Set(context.TODO(), e.migrationFenceKey(), state, nil)
+	return convertErr(err)
+}
+
+// unfenceMigration removes the migration fence from the v2 data
+func (e *engine) unfenceMigration() error {
+	_, err := e.Delete(context.TODO(), e.migrationFenceKey(), nil)
+	return convertErr(err)
+}
+
+// migrationFenceState returns the state of the migration of the v2 data
+// to the v3 keyspace or an empty string if the data is not being migrated
+func (e *engine) migrationFenceState() (string, error) {
+	re, err := e.Get(context.TODO(), e.migrationFenceKey(), nil)
+	err = convertErr(err)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return "", nil
+		}
+		return "", trace.Wrap(err)
+	}
+	if re.Node.Value != migrationInProgress {
+		return migrationCompleted, nil
+	}
+	return migrationInProgress, nil
+}
+
+// hasData returns true if there is data other than transient keys
+// under the root key
+func (e *engine) hasData() (bool, error) {
+	keys, err := e.getKeys(e.etcdKey)
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	for _, key := range keys {
+		if !isTransientKey(key) && key != locksP {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (e *engine) migrationFenceKey() string {
+	return ekey(e.key(migrationFenceP))
+}
+
+// migrationFenceWait returns how long the migration waits after the fence
+// has been set: the processes observe the fence within
+// migrationFenceCheckInterval and a write started before that
+// completes within the retry interval and a request timeout
+func migrationFenceWait(config ETCDConfig) time.Duration {
+	retryInterval := defaults.RetrySmallerMaxInterval
+	if config.RetryInterval != 0 {
+		retryInterval = config.RetryInterval
+	}
+	return migrationFenceCheckInterval + retryInterval + defaults.ReadHeadersTimeout
+}
+
+// isTransientKey returns true if the specified key under the root key
+// is not migrated to the v3 keyspace
+func isTransientKey(key string) bool {
+	switch key {
+	case leaderP, migrationFenceP:
+		return true
+	}
+	return false
+}
+
+const (
+	// leaderP is the leader election key of the cluster controller
+	// processes, the election is run with the v2 API
+	leaderP = "leader"
+	// migrationFenceP is the key with the state of the migration
+	// of the v2 data to the v3 keyspace
+	migrationFenceP = "migratedv3"
+	// migrationInProgress is the state of the migration fence
+	// while the data is being copied
+	migrationInProgress = "migrating"
+	// migrationCompleted is the state of the migration fence
+	// once the data has been migrated
+	migrationCompleted = "migrated"
+)
@@ -0,0 +1,370 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// newSwitchingEngine returns an engine that uses the specified v2 engine
+// until the data is migrated to the v3 keyspace with MigrateToV3 and
+// the v3 engine afterwards, so the data can be migrated while
+// the processes using the backend are running.
+//
+// The engine follows the state of the migration fence of the v2 data:
+// writes are held while the data is being copied and the engine switches
+// to the v3 API once the migration has completed. The fence is checked
+// at least every migrationFenceCheckInterval, so after the fence is set
+// the migration waits for longer than that before it copies the data.
+//
+// Watchers of the v2 data are stopped with an error when the engine
+// switches so their clients watch again with the v3 API
+func newSwitchingEngine(v2 *engine) *switchingEngine {
+	return &switchingEngine{
+		v2:       v2,
+		current:  v2,
+		watchers: make(map[*watcher]struct{}),
+	}
+}
+
+type switchingEngine struct {
+	v2 *engine
+	// mu guards the fields below. Requests hold the read lock
+	// so the engine switches between the requests
+	mu sync.RWMutex
+	// current is the engine requests are served with
+	current kvengine
+	// v3 is set once the engine has switched to the v3 API
+	v3 *engineV3
+	// checked is the time the migration fence was last checked
+	checked time.Time
+	// migrating is set while the data is being migrated
+	migrating bool
+
+	// watchersMu guards watchers
+	watchersMu sync.Mutex
+	// watchers lists the active watchers of the v2 data
+	watchers map[*watcher]struct{}
+}
+
+func (e *switchingEngine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.v3 != nil {
+		return trace.Wrap(e.v3.Close())
+	}
+	return trace.Wrap(e.v2.Close())
+}
+
+func (e *switchingEngine) key(prefix string, keys ...string) key {
+	return e.v2.key(prefix, keys...)
+}
+
+func (e *switchingEngine) createVal(key key, val interface{}, ttl time.Duration) error {
+	return e.write(func(engine kvengine) error {
+		return engine.createVal(key, val, ttl)
+	})
+}
+
+func (e *switchingEngine) createValBytes(key key, data []byte, ttl time.Duration) error {
+	return e.write(func(engine kvengine) error {
+		return engine.createValBytes(key, data, ttl)
+	})
+}
+
+func (e *switchingEngine) upsertVal(key key, val interface{}, ttl time.Duration) error {
+	return e.write(func(engine kvengine) error {
+		return engine.upsertVal(key, val, ttl)
+	})
+}
+
+func (e *switchingEngine) upsertValBytes(key key, data []byte, ttl time.Duration) error {
+	return e.write(func(engine kvengine) error {
+		return engine.upsertValBytes(key, data, ttl)
+	})
+}
+
+func (e *switchingEngine) updateVal(key key, val interface{}, ttl time.Duration) error {
+	return e.write(func(engine kvengine) error {
+		return engine.updateVal(key, val, ttl)
+	})
+}
+
+func (e *switchingEngine) updateValBytes(key key, data []byte, ttl time.Duration) error {
+	return e.write(func(engine kvengine) error {
+		return engine.updateValBytes(key, data, ttl)
+	})
+}
+
+func (e *switchingEngine) updateTTL(key key, ttl time.Duration) error {
+	return e.write(func(engine kvengine) error {
+		return engine.updateTTL(key, ttl)
+	})
+}
+
+func (e *switchingEngine) compareAndSwap(key key, val, prevVal, outVal interface{}, ttl time.Duration) error {
+	return e.write(func(engine kvengine) error {
+		return engine.compareAndSwap(key, val, prevVal, outVal, ttl)
+	})
+}
+
+func (e *switchingEngine) compareAndSwapBytes(key key, val, prevVal []byte, outVal *[]byte, ttl time.Duration) error {
+	return e.write(func(engine kvengine) error {
+		return engine.compareAndSwapBytes(key, val, prevVal, outVal, ttl)
+	})
+}
+
+func (e *switchingEngine) getVal(key key, val interface{}) error {
+	return e.read(func(engine kvengine) error {
+		return engine.getVal(key, val)
+	})
+}
+
+func (e *switchingEngine) getValBytes(key key) (data []byte, err error) {
+	err = e.read(func(engine kvengine) (err error) {
+		data, err = engine.getValBytes(key)
+		return err
+	})
+	return data, err
+}
+
+func (e *switchingEngine) deleteKey(key key) error {
+	return e.write(func(engine kvengine) error {
+		return engine.deleteKey(key)
+	})
+}
+
+func (e *switchingEngine) compareAndDelete(key key, prevVal interface{}) error {
+	return e.write(func(engine kvengine) error {
+		return engine.compareAndDelete(key, prevVal)
+	})
+}
+
+func (e *switchingEngine) createDir(key key, ttl time.Duration) error {
+	return e.write(func(engine kvengine) error {
+		return engine.createDir(key, ttl)
+	})
+}
+
+func (e *switchingEngine) upsertDir(key key, ttl time.Duration) error {
+	return e.write(func(engine kvengine) error {
+		return engine.upsertDir(key, ttl)
+	})
+}
+
+func (e *switchingEngine) deleteDir(key key) error {
+	return e.write(func(engine kvengine) error {
+		return engine.deleteDir(key)
+	})
+}
+
+// acquireLock retries to acquire the lock with individual writes
+// so the engine can switch while the lock is held by another process
+func (e *switchingEngine) acquireLock(token key, ttl time.Duration) error {
+	for {
+		err := e.tryAcquireLock(token, ttl)
+		if err != nil {
+			if !trace.IsCompareFailed(err) && !trace.IsAlreadyExists(err) {
+				return trace.Wrap(err)
+			}
+			time.Sleep(delayBetweenLockAttempts)
+		} else {
+			return nil
+		}
+	}
+}
+
+func (e *switchingEngine) tryAcquireLock(token key, ttl time.Duration) error {
+	return e.write(func(engine kvengine) error {
+		return engine.tryAcquireLock(token, ttl)
+	})
+}
+
+func (e *switchingEngine) releaseLock(token key) error {
+	return e.write(func(engine kvengine) error {
+		return engine.releaseLock(token)
+	})
+}
+
+func (e *switchingEngine) getKeys(key key) (keys []string, err error) {
+	err = e.read(func(engine kvengine) (err error) {
+		keys, err = engine.getKeys(key)
+		return err
+	})
+	return keys, err
+}
+
+func (e *switchingEngine) watch(ctx context.Context, prefix key) (w storage.Watcher, err error) {
+	err = e.read(func(engine kvengine) (err error) {
+		w, err = engine.watch(ctx, prefix)
+		if err != nil {
+			return err
+		}
+		if v2watcher, ok := w.(*watcher); ok && engine == e.v2 {
+			e.addWatcher(v2watcher)
+		}
+		return nil
+	})
+	return w, err
+}
+
+func (e *switchingEngine) reencrypt() (count int, err error) {
+	err = e.write(func(engine kvengine) (err error) {
+		r, ok := engine.(reencrypter)
+		if !ok {
+			return trace.BadParameter("engine %T does not support encryption", engine)
+		}
+		count, err = r.reencrypt()
+		return err
+	})
+	return count, err
+}
+
+// read invokes fn with the current engine
+func (e *switchingEngine) read(fn func(kvengine) error) error {
+	if err := e.refresh(); err != nil {
+		return trace.Wrap(err)
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return fn(e.current)
+}
+
+// write invokes fn with the current engine.
+// While the data is being migrated, the write is held until the migration
+// completes or fails, or fails with a ConnectionProblem error
+// after migrationWriteTimeout
+func (e *switchingEngine) write(fn func(kvengine) error) error {
+	deadline := time.Now().Add(migrationWriteTimeout)
+	for {
+		if err := e.refresh(); err != nil {
+			return trace.Wrap(err)
+		}
+		e.mu.RLock()
+		if e.v3 != nil || (!e.migrating && time.Since(e.checked) < migrationFenceCheckInterval) {
+			defer e.mu.RUnlock()
+			return fn(e.current)
+		}
+		e.mu.RUnlock()
+		if time.Now().After(deadline) {
+			return trace.ConnectionProblem(nil, "data under %v is being migrated to etcd v3", e.v2.cfg.Key)
+		}
+		time.Sleep(migrationPollInterval)
+	}
+}
+
+// refresh checks the migration fence unless the engine has already
+// switched to the v3 API or the fence has been checked recently
+func (e *switchingEngine) refresh() error {
+	e.mu.RLock()
+	interval := migrationFenceCheckInterval
+	if e.migrating {
+		interval = migrationPollInterval
+	}
+	fresh := e.v3 != nil || time.Since(e.checked) < interval
+	e.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+	// the check time is recorded before the fence is read
+	// so writes never rely on a stale state of the fence
+	checked := time.Now()
+	state, err := e.v2.migrationFenceState()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if state == migrationCompleted {
+		return trace.Wrap(e.switchToV3())
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if checked.After(e.checked) {
+		e.checked = checked
+		e.migrating = state == migrationInProgress
+	}
+	return nil
+}
+
+// switchToV3 switches the engine to the v3 API and stops
+// the watchers of the v2 data
+func (e *switchingEngine) switchToV3() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.v3 != nil {
+		return nil
+	}
+	v3, err := newEngineV3(e.v2.cfg)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	v3.cipher = e.v2.cipher
+	migrated, err := v3.isMigrated()
+	if err != nil {
+		v3.Close()
+		return trace.Wrap(err)
+	}
+	if !migrated {
+		v3.Close()
+		return trace.BadParameter("data under %v is fenced but has not been migrated to etcd v3", e.v2.cfg.Key)
+	}
+	e.v3 = v3
+	e.current = v3
+	log.Infof("Data under %v has been migrated, switched to etcd v3.", e.v2.cfg.Key)
+	e.stopWatchers()
+	return nil
+}
+
+// addWatcher registers the watcher of the v2 data
+func (e *switchingEngine) addWatcher(w *watcher) {
+	e.watchersMu.Lock()
+	e.watchers[w] = struct{}{}
+	e.watchersMu.Unlock()
+	go func() {
+		<-w.ctx.Done()
+		e.watchersMu.Lock()
+		delete(e.watchers, w)
+		e.watchersMu.Unlock()
+	}()
+}
+
+// stopWatchers stops the watchers of the v2 data
+func (e *switchingEngine) stopWatchers() {
+	e.watchersMu.Lock()
+	defer e.watchersMu.Unlock()
+	for w := range e.watchers {
+		w.stop(trace.ConnectionProblem(nil, "backend has switched to etcd v3"))
+	}
+}
+
+const (
+	// migrationFenceCheckInterval is the maximum age of the state of
+	// the migration fence a write is allowed with
+	migrationFenceCheckInterval = 5 * time.Second
+	// migrationPollInterval is how often the migration fence is checked
+	// while the data is being migrated
+	migrationPollInterval = time.Second
+	// migrationWriteTimeout is how long a write is held while the data
+	// is being migrated
+	migrationWriteTimeout = time.Minute
+)
@@ -0,0 +1,707 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/cenkalti/backoff"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newEngineV3 returns a new engine that talks to etcd using the v3 (gRPC) API.
+//
+// Unlike the v2 engine, values are stored as is: JSON for objects and
+// raw bytes for binary data, TTLs are implemented with leases and
+// conditional updates with transactions.
+//
+// The v3 data model has no directories, so they are emulated with key
+// prefixes: a directory exists as long as there are keys under its prefix.
+// Explicitly created directories are recorded with a marker key which
+// also carries the directory TTL - keys created in a directory with a TTL
+// share the directory's lease so they expire together with it.
+// Note that refreshing the TTL of an existing directory does not
+// extend the lifetime of the keys already in it
+func newEngineV3(cfg ETCDConfig) (*engineV3, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	client, err := newClientV3(cfg)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &engineV3{
+		cfg:     cfg,
+		client:  client,
+		etcdKey: strings.Split(cfg.Key, "/"),
+	}, nil
+}
+
+type engineV3 struct {
+	cfg     ETCDConfig
+	client  *clientv3.Client
+	etcdKey []string
+	// cipher encrypts the values at rest, nil if encryption is disabled
+	cipher *valueCipher
+}
+
+func (e *engineV3) key(prefix string, keys ...string) key {
+	return makeKey(e.etcdKey, prefix, keys...)
+}
+
+func (e *engineV3) Close() error {
+	return e.client.Close()
+}
+
+func (e *engineV3) createValBytes(key key, data []byte, ttl time.Duration) error {
+	return trace.Wrap(e.create(key, data, ttl))
+}
+
+func (e *engineV3) createVal(key key, val interface{}, ttl time.Duration) error {
+	encoded, err := json.Marshal(val)
+	if err != nil {
+		return trace.Wrap(err, "failed to encode object")
+	}
+	return trace.Wrap(e.create(key, encoded, ttl))
+}
+
+func (e *engineV3) upsertValBytes(key key, data []byte, ttl time.Duration) error {
+	return trace.Wrap(e.upsert(key, data, ttl))
+}
+
+func (e *engineV3) upsertVal(key key, val interface{}, ttl time.Duration) error {
+	encoded, err := json.Marshal(val)
+	if err != nil {
+		return trace.Wrap(err, "failed to encode object")
+	}
+	return trace.Wrap(e.upsert(key, encoded, ttl))
+}
+
+func (e *engineV3) updateValBytes(key key, data []byte, ttl time.Duration) error {
+	return trace.Wrap(e.update(key, data, ttl))
+}
+
+func (e *engineV3) updateVal(key key, val interface{}, ttl time.Duration) error {
+	encoded, err := json.Marshal(val)
+	if err != nil {
+		return trace.Wrap(err, "failed to encode object")
+	}
+	return trace.Wrap(e.update(key, encoded, ttl))
+}
+
+func (e *engineV3) updateTTL(key key, ttl time.Duration) error {
+	existing, err := e.get(ekey(key))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	lease, err := e.lease(key, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	re, err := e.txn(func(txn clientv3.Txn) clientv3.Txn {
+		return txn.If(clientv3.Compare(clientv3.ModRevision(ekey(key)), "=", existing.ModRevision)).
+			Then(clientv3.OpPut(ekey(key), string(existing.Value), clientv3.WithLease(lease)))
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !re.Succeeded {
+		return trace.CompareFailed("%q has been modified concurrently", ekey(key))
+	}
+	return nil
+}
+
+func (e *engineV3) compareAndSwap(key key, val interface{}, prevVal interface{}, outVal interface{}, ttl time.Duration) error {
+	encoded, err := json.Marshal(val)
+	if err != nil {
+		return trace.Wrap(err, "failed to encode object")
+	}
+	var encodedPrev []byte
+	if prevVal != nil {
+		encodedPrev, err = json.Marshal(prevVal)
+		if err != nil {
+			return trace.Wrap(err, "failed to encode object")
+		}
+	}
+	prev, err := e.swap(key, encoded, encodedPrev, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if prev != nil {
+		return trace.Wrap(json.Unmarshal(prev, &outVal))
+	}
+	return nil
+}
+
+func (e *engineV3) compareAndSwapBytes(key key, val, prevVal []byte, outVal *[]byte, ttl time.Duration) error {
+	prev, err := e.swap(key, val, prevVal, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if prev != nil {
+		*outVal = prev
+	}
+	return nil
+}
+
+func (e *engineV3) getValBytes(key key) ([]byte, error) {
+	kv, err := e.get(ekey(key))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return e.cipher.decrypt(kv.Value)
+}
+
+func (e *engineV3) getVal(key key, val interface{}) error {
+	kv, err := e.get(ekey(key))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	data, err := e.cipher.decrypt(kv.Value)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := json.Unmarshal(data, &val); err != nil {
+		log.Errorf("failed to decode: %s", data)
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+func (e *engineV3) compareAndDelete(key key, prevVal interface{}) error {
+	encoded, err := json.Marshal(prevVal)
+	if err != nil {
+		return trace.Wrap(err, "failed to encode object")
+	}
+	cmp, err := e.compareValue(key, encoded)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	re, err := e.txn(func(txn clientv3.Txn) clientv3.Txn {
+		return txn.If(cmp).
+			Then(clientv3.OpDelete(ekey(key))).
+			Else(clientv3.OpGet(ekey(key), clientv3.WithKeysOnly()))
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !re.Succeeded {
+		return trace.Wrap(compareFailed(key, re))
+	}
+	return nil
+}
+
+func (e *engineV3) deleteKey(key key) error {
+	var re *clientv3.DeleteResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		re, err = e.client.Delete(ctx, ekey(key))
+		return err
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if re.Deleted == 0 {
+		return trace.NotFound("%q is not found", ekey(key))
+	}
+	return nil
+}
+
+func (e *engineV3) createDir(key key, ttl time.Duration) error {
+	lease, err := e.lease(key, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	marker := dirMarkerKey(key)
+	re, err := e.txn(func(txn clientv3.Txn) clientv3.Txn {
+		return txn.If(clientv3.Compare(clientv3.CreateRevision(marker), "=", 0)).
+			Then(clientv3.OpPut(marker, "", clientv3.WithLease(lease)))
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !re.Succeeded {
+		return trace.AlreadyExists("%q already exists", ekey(key))
+	}
+	return nil
+}
+
+func (e *engineV3) upsertDir(key key, ttl time.Duration) error {
+	lease, err := e.lease(key, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.put(dirMarkerKey(key), nil, lease))
+}
+
+func (e *engineV3) deleteDir(key key) error {
+	var re *clientv3.TxnResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		re, err = e.client.Txn(ctx).Then(
+			clientv3.OpDelete(ekey(key)),
+			clientv3.OpDelete(dirPrefix(key), clientv3.WithPrefix()),
+		).Commit()
+		return err
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var deleted int64
+	for _, resp := range re.Responses {
+		deleted += resp.GetResponseDeleteRange().Deleted
+	}
+	if deleted == 0 {
+		return trace.NotFound("%q is not found", ekey(key))
+	}
+	return nil
+}
+
+func (e *engineV3) acquireLock(token key, ttl time.Duration) error {
+	for {
+		err := e.tryAcquireLock(token, ttl)
+		if err != nil {
+			if !trace.IsCompareFailed(err) && !trace.IsAlreadyExists(err) {
+				return trace.Wrap(err)
+			}
+			time.Sleep(delayBetweenLockAttempts)
+		} else {
+			return nil
+		}
+	}
+}
+
+func (e *engineV3) tryAcquireLock(key key, ttl time.Duration) error {
+	return trace.Wrap(e.create(key, []byte("locked"), ttl))
+}
+
+func (e *engineV3) releaseLock(key key) error {
+	return trace.Wrap(e.deleteKey(key))
+}
+
+func (e *engineV3) getKeys(key key) ([]string, error) {
+	var re *clientv3.GetResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		re, err = e.client.Get(ctx, dirPrefix(key), clientv3.WithPrefix(), clientv3.WithKeysOnly())
+		return err
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(re.Kvs) == 0 {
+		exists, err := e.exists(ekey(key))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if exists {
+			return nil, trace.BadParameter("'%v': expected directory", key)
+		}
+	}
+	var vals []string
+	for _, kv := range re.Kvs {
+		name := strings.SplitN(strings.TrimPrefix(string(kv.Key), dirPrefix(key)), "/", 2)[0]
+		if name == dirMarker {
+			continue
+		}
+		if len(vals) == 0 || vals[len(vals)-1] != name {
+			vals = append(vals, name)
+		}
+	}
+	sort.Strings(vals)
+	return vals, nil
+}
+
+// watch returns a watcher for the changes of the keys with the specified prefix.
+// The watch starts at the current store revision so no changes made after
+// the function returns are missed
+func (e *engineV3) watch(ctx context.Context, prefix key) (storage.Watcher, error) {
+	var re *clientv3.GetResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		re, err = e.client.Get(ctx, ekey(prefix), clientv3.WithCountOnly())
+		return err
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	w := newWatcher(ctx)
+	watchC := e.client.Watch(clientv3.WithRequireLeader(w.ctx), ekey(prefix),
+		clientv3.WithRange(clientv3.GetPrefixRangeEnd(dirPrefix(prefix))),
+		clientv3.WithRev(re.Header.Revision+1))
+	go func() {
+		for resp := range watchC {
+			if err := resp.Err(); err != nil {
+				w.stop(trace.Wrap(convertErrV3(err)))
+				return
+			}
+			for _, ev := range resp.Events {
+				event, ok := e.watchEvent(prefix, ev)
+				if ok && !w.push(event) {
+					return
+				}
+			}
+		}
+		if w.ctx.Err() == nil {
+			w.stop(trace.ConnectionProblem(nil, "watch has been interrupted"))
+		}
+	}()
+	return w, nil
+}
+
+// watchEvent converts the etcd event to a watch event.
+// Returns false if the event does not describe a change of a key under prefix
+// or its value cannot be decrypted
+func (e *engineV3) watchEvent(prefix key, ev *clientv3.Event) (event storage.WatchEvent, ok bool) {
+	etcdKey := string(ev.Kv.Key)
+	if etcdKey != ekey(prefix) && !strings.HasPrefix(etcdKey, dirPrefix(prefix)) {
+		return event, false
+	}
+	event.Key, ok = relativeKey(e.etcdKey, etcdKey)
+	if !ok || event.Key[len(event.Key)-1] == dirMarker {
+		return event, false
+	}
+	switch {
+	case ev.Type == clientv3.EventTypeDelete:
+		event.Type = storage.WatchEventDeleted
+	case ev.IsCreate():
+		event.Type = storage.WatchEventCreated
+	default:
+		event.Type = storage.WatchEventUpdated
+	}
+	if event.Type != storage.WatchEventDeleted {
+		value, err := e.cipher.decrypt(ev.Kv.Value)
+		if err != nil {
+			log.Warnf("Failed to decrypt value of %q: %v.", etcdKey, err)
+			return event, false
+		}
+		event.Value = value
+	}
+	return event, true
+}
+
+// create creates the key with the specified value if it does not exist
+func (e *engineV3) create(key key, data []byte, ttl time.Duration) error {
+	data, err := e.cipher.encrypt(data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	lease, err := e.lease(key, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	re, err := e.txn(func(txn clientv3.Txn) clientv3.Txn {
+		return txn.If(clientv3.Compare(clientv3.CreateRevision(ekey(key)), "=", 0)).
+			Then(clientv3.OpPut(ekey(key), string(data), clientv3.WithLease(lease)))
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !re.Succeeded {
+		return trace.AlreadyExists("%q already exists", ekey(key))
+	}
+	return nil
+}
+
+// update updates the value of the existing key
+func (e *engineV3) update(key key, data []byte, ttl time.Duration) error {
+	data, err := e.cipher.encrypt(data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	lease, err := e.lease(key, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	re, err := e.txn(func(txn clientv3.Txn) clientv3.Txn {
+		return txn.If(clientv3.Compare(clientv3.CreateRevision(ekey(key)), ">", 0)).
+			Then(clientv3.OpPut(ekey(key), string(data), clientv3.WithLease(lease)))
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !re.Succeeded {
+		return trace.NotFound("%q is not found", ekey(key))
+	}
+	return nil
+}
+
+// upsert creates or updates the key with the specified value
+func (e *engineV3) upsert(key key, data []byte, ttl time.Duration) error {
+	data, err := e.cipher.encrypt(data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	lease, err := e.lease(key, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.put(ekey(key), data, lease))
+}
+
+// swap replaces the value of the key if it matches prevVal, or creates
+// the key if prevVal is nil. Returns the replaced value
+func (e *engineV3) swap(key key, val, prevVal []byte, ttl time.Duration) (prev []byte, err error) {
+	val, err = e.cipher.encrypt(val)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cmp := clientv3.Compare(clientv3.CreateRevision(ekey(key)), "=", 0)
+	if prevVal != nil {
+		cmp, err = e.compareValue(key, prevVal)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	lease, err := e.lease(key, ttl)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	re, err := e.txn(func(txn clientv3.Txn) clientv3.Txn {
+		return txn.If(cmp).
+			Then(clientv3.OpPut(ekey(key), string(val), clientv3.WithLease(lease), clientv3.WithPrevKV())).
+			Else(clientv3.OpGet(ekey(key), clientv3.WithKeysOnly()))
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if !re.Succeeded {
+		if prevVal == nil {
+			return nil, trace.AlreadyExists("%q already exists", ekey(key))
+		}
+		return nil, trace.Wrap(compareFailed(key, re))
+	}
+	if prevKV := re.Responses[0].GetResponsePut().PrevKv; prevKV != nil {
+		return e.cipher.decrypt(prevKV.Value)
+	}
+	return nil, nil
+}
+
+// compareValue returns the transaction condition that holds as long as
+// the key has the specified value.
+//
+// The stored value may be encrypted with a previous encryption key or not
+// encrypted at all, so the ciphertext cannot be compared directly: the value
+// is compared after decryption and the transaction is guarded by
+// the modification revision of the value that has been compared
+func (e *engineV3) compareValue(key key, prevVal []byte) (clientv3.Cmp, error) {
+	existing, err := e.get(ekey(key))
+	if err != nil {
+		return clientv3.Cmp{}, trace.Wrap(err)
+	}
+	value, err := e.cipher.decrypt(existing.Value)
+	if err != nil {
+		return clientv3.Cmp{}, trace.Wrap(err)
+	}
+	if !bytes.Equal(value, prevVal) {
+		return clientv3.Cmp{}, trace.CompareFailed("%q does not match the expected value", ekey(key))
+	}
+	return clientv3.Compare(clientv3.ModRevision(ekey(key)), "=", existing.ModRevision), nil
+}
+
+// lease returns a lease for a key with the specified TTL.
+// If ttl is forever, the key inherits the lease of the closest
+// parent directory that has been created with a TTL, if any
+func (e *engineV3) lease(key key, ttl time.Duration) (clientv3.LeaseID, error) {
+	if ttl == forever {
+		return e.inheritedLease(key)
+	}
+	return e.grant(ttl)
+}
+
+// grant returns a new lease with the specified TTL
+func (e *engineV3) grant(ttl time.Duration) (clientv3.LeaseID, error) {
+	seconds := int64(math.Ceil(ttl.Seconds()))
+	var re *clientv3.LeaseGrantResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		re, err = e.client.Grant(ctx, seconds)
+		return err
+	})
+	if err != nil {
+		return clientv3.NoLease, trace.Wrap(err)
+	}
+	return re.ID, nil
+}
+
+// inheritedLease returns the lease of the closest parent directory of key
+// below the root key
+func (e *engineV3) inheritedLease(key key) (clientv3.LeaseID, error) {
+	var ops []clientv3.Op
+	for i := len(key) - 1; i > len(e.etcdKey); i-- {
+		ops = append(ops, clientv3.OpGet(dirMarkerKey(key[:i]), clientv3.WithKeysOnly()))
+	}
+	if len(ops) == 0 {
+		return clientv3.NoLease, nil
+	}
+	var re *clientv3.TxnResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		re, err = e.client.Txn(ctx).Then(ops...).Commit()
+		return err
+	})
+	if err != nil {
+		return clientv3.NoLease, trace.Wrap(err)
+	}
+	for _, resp := range re.Responses {
+		for _, kv := range resp.GetResponseRange().Kvs {
+			if kv.Lease != 0 {
+				return clientv3.LeaseID(kv.Lease), nil
+			}
+		}
+	}
+	return clientv3.NoLease, nil
+}
+
+func (e *engineV3) put(key string, data []byte, lease clientv3.LeaseID) error {
+	return e.retry(func(ctx context.Context) error {
+		_, err := e.client.Put(ctx, key, string(data), clientv3.WithLease(lease))
+		return err
+	})
+}
+
+// isMigrated returns true if the data has been migrated from the v2 keyspace
+func (e *engineV3) isMigrated() (bool, error) {
+	return e.exists(e.migrationKey())
+}
+
+// migrationKey returns the key that marks the data as migrated from the v2 keyspace
+func (e *engineV3) migrationKey() string {
+	return strings.Join(e.etcdKey, "/") + "/" + migrationMarker
+}
+
+// get returns the specified key or NotFound error if the key does not exist
+func (e *engineV3) get(key string) (*kv, error) {
+	var re *clientv3.GetResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		re, err = e.client.Get(ctx, key)
+		return err
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(re.Kvs) == 0 {
+		return nil, trace.NotFound("%q is not found", key)
+	}
+	return &kv{Value: re.Kvs[0].Value, ModRevision: re.Kvs[0].ModRevision}, nil
+}
+
+func (e *engineV3) exists(key string) (bool, error) {
+	_, err := e.get(key)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return false, nil
+		}
+		return false, trace.Wrap(err)
+	}
+	return true, nil
+}
+
+func (e *engineV3) txn(fn func(clientv3.Txn) clientv3.Txn) (re *clientv3.TxnResponse, err error) {
+	err = e.retry(func(ctx context.Context) error {
+		re, err = fn(e.client.Txn(ctx)).Commit()
+		return err
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return re, nil
+}
+
+// retry invokes fn with a request-scoped context retrying on transient
+// cluster errors for up to the configured retry interval
+func (e *engineV3) retry(fn func(ctx context.Context) error) error {
+	interval := backoff.NewExponentialBackOff()
+	interval.MaxElapsedTime = defaults.RetrySmallerMaxInterval
+	if e.cfg.RetryInterval != 0 {
+		interval.MaxElapsedTime = e.cfg.RetryInterval
+	}
+	err := backoff.Retry(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), defaults.ReadHeadersTimeout)
+		defer cancel()
+		err := convertErrV3(fn(ctx))
+		if utils.IsTransientClusterError(err) {
+			log.Debugf("retrying on transient etcd error: %v", err)
+			return trace.Wrap(err)
+		}
+		if err != nil {
+			return &backoff.PermanentError{Err: err}
+		}
+		return nil
+	}, interval)
+	if perr, ok := err.(*backoff.PermanentError); ok {
+		return perr.Err
+	}
+	return err
+}
+
+// kv is a value of a key with its modification revision
+type kv struct {
+	Value       []byte
+	ModRevision int64
+}
+
+// compareFailed returns the error for a failed conditional update
+// given the response of the transaction that reads the key on failure
+func compareFailed(key key, re *clientv3.TxnResponse) error {
+	if len(re.Responses) != 0 && len(re.Responses[0].GetResponseRange().Kvs) == 0 {
+		return trace.NotFound("%q is not found", ekey(key))
+	}
+	return trace.CompareFailed("%q does not match the expected value", ekey(key))
+}
+
+// dirPrefix returns the prefix shared by all keys in the directory
+func dirPrefix(key key) string {
+	return ekey(key) + "/"
+}
+
+// dirMarkerKey returns the key of the marker of an explicitly created directory
+func dirMarkerKey(key key) string {
+	return dirPrefix(key) + dirMarker
+}
+
+func convertErrV3(err error) error {
+	if err == nil {
+		return nil
+	}
+	if err == context.DeadlineExceeded {
+		return trace.ConnectionProblem(err, "etcd request timed out")
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return trace.ConnectionProblem(err, "failed to connect to the etcd cluster")
+		}
+	}
+	return err
+}
+
+// dirMarker is the name of the key that marks an explicitly created directory.
+// It starts with a zero byte to never clash with regular keys
+const dirMarker = "\x00dir"
+
+// migrationMarker is the name of the key under the root key that marks
+// the data as migrated from the v2 keyspace
+const migrationMarker = "\x00migrated"
@@ -0,0 +1,133 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+// E3Suite runs the storage suite against the etcd v3 engine
+type E3Suite struct {
+	ESuite
+}
+
+var _ = Suite(&E3Suite{})
+
+func (s *E3Suite) SetUpTest(c *C) {
+	s.setUp(c, ETCDAPIVersion3)
+}
+
+func (s *E3Suite) TestMigratesToV3(c *C) {
+	v2, err := newBackend(os.Getenv(defaults.TestETCDConfig), ETCDAPIVersion2)
+	c.Assert(err, IsNil)
+	defer func() {
+		v2.backend.Close()
+		v2.config.APIVersion = ETCDAPIVersion3
+		c.Assert(v2.Delete(), IsNil)
+	}()
+
+	account, err := v2.backend.CreateAccount(storage.Account{Org: "test"})
+	c.Assert(err, IsNil)
+	_, err = v2.backend.CreateRepository(storage.NewRepository("test"))
+	c.Assert(err, IsNil)
+	err = v2.backend.UpsertObjectPeers("hash", []string{"peer1", "peer2"}, time.Hour)
+	c.Assert(err, IsNil)
+
+	config := v2.config
+	config.APIVersion = ETCDAPIVersion3
+	_, err = NewETCD(config)
+	c.Assert(err, NotNil, Commentf("expected v3 engine to refuse unmigrated data"))
+
+	// the migration does not need the cluster controller to be stopped
+	_, err = v2.api.Set(context.TODO(), config.Key+"/"+leaderP, "process", nil)
+	c.Assert(err, IsNil)
+
+	status, err := MigrateToV3(context.TODO(), config)
+	c.Assert(err, IsNil)
+	c.Assert(status.Keys > 0, Equals, true)
+
+	_, err = MigrateToV3(context.TODO(), config)
+	c.Assert(err, NotNil, Commentf("expected the data to be migrated only once"))
+
+	// the running v2 backend switches to the migrated data
+	_, err = v2.backend.CreateRepository(storage.NewRepository("after-migration"))
+	c.Assert(err, IsNil)
+	switching, ok := v2.backend.(*electingBackend).Backend.(*backend).kvengine.(*switchingEngine)
+	c.Assert(ok, Equals, true)
+	c.Assert(switching.v3, NotNil)
+
+	config.APIVersion = ""
+	b, err := NewETCD(config)
+	c.Assert(err, IsNil)
+	defer b.Close()
+	_, ok = b.Backend.(*backend).kvengine.(*engineV3)
+	c.Assert(ok, Equals, true, Commentf("expected migrated data to be opened with the v3 engine"))
+
+	out, err := b.GetAccount(account.ID)
+	c.Assert(err, IsNil)
+	c.Assert(out, DeepEquals, account)
+	repos, err := b.GetRepositories()
+	c.Assert(err, IsNil)
+	c.Assert(repos, HasLen, 2)
+	peers, err := b.GetObjectPeers("hash")
+	c.Assert(err, IsNil)
+	c.Assert(peers, DeepEquals, []string{"peer1", "peer2"})
+}
+
+func (s *E3Suite) TestComparesValuesEncryptedWithPreviousKey(c *C) {
+	dir := c.MkDir()
+	oldKey := newKeyFile(c, filepath.Join(dir, "old-key"))
+	newKey := newKeyFile(c, filepath.Join(dir, "new-key"))
+
+	config := s.backend.config
+	config.Encryption = &EncryptionConfig{KeyFile: oldKey}
+	b, err := NewETCD(config)
+	c.Assert(err, IsNil)
+	engine, err := engineOf(b)
+	c.Assert(err, IsNil)
+	keyBytes, keyVal := engine.key(chartsP, "bytes"), engine.key(chartsP, "val")
+	c.Assert(engine.createValBytes(keyBytes, []byte("v1"), forever), IsNil)
+	c.Assert(engine.createVal(keyVal, "v1", forever), IsNil)
+	c.Assert(b.Close(), IsNil)
+
+	// rotate the key without re-encrypting the values
+	config.Encryption = &EncryptionConfig{KeyFile: newKey, PreviousKeyFiles: []string{oldKey}}
+	b, err = NewETCD(config)
+	c.Assert(err, IsNil)
+	defer b.Close()
+	engine, err = engineOf(b)
+	c.Assert(err, IsNil)
+
+	var out []byte
+	err = engine.compareAndSwapBytes(keyBytes, []byte("v2"), []byte("v1"), &out, forever)
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Equals, "v1")
+	err = engine.compareAndSwapBytes(keyBytes, []byte("v3"), []byte("v1"), &out, forever)
+	c.Assert(trace.IsCompareFailed(err), Equals, true, Commentf("expected stale value to fail, got %v", err))
+
+	c.Assert(engine.compareAndDelete(keyVal, "v2"), NotNil)
+	c.Assert(engine.compareAndDelete(keyVal, "v1"), IsNil)
+}
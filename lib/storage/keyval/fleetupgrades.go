@@ -0,0 +1,93 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"sort"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// CreateFleetUpgrade saves a new fleet upgrade
+func (b *backend) CreateFleetUpgrade(upgrade storage.FleetUpgrade) (*storage.FleetUpgrade, error) {
+	if err := upgrade.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err := b.createVal(b.key(fleetUpgradesP, upgrade.ID), upgrade, forever)
+	if err != nil {
+		if trace.IsAlreadyExists(err) {
+			return nil, trace.AlreadyExists("fleet upgrade %v already exists", upgrade.ID)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return &upgrade, nil
+}
+
+// UpdateFleetUpgrade updates an existing fleet upgrade
+func (b *backend) UpdateFleetUpgrade(upgrade storage.FleetUpgrade) (*storage.FleetUpgrade, error) {
+	if err := upgrade.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err := b.updateVal(b.key(fleetUpgradesP, upgrade.ID), upgrade, forever)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("fleet upgrade %v not found", upgrade.ID)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return &upgrade, nil
+}
+
+// GetFleetUpgrade returns the fleet upgrade with the specified ID
+func (b *backend) GetFleetUpgrade(id string) (*storage.FleetUpgrade, error) {
+	var upgrade storage.FleetUpgrade
+	err := b.getVal(b.key(fleetUpgradesP, id), &upgrade)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("fleet upgrade %v not found", id)
+		}
+		return nil, trace.Wrap(err)
+	}
+	utils.UTC(&upgrade.Created)
+	return &upgrade, nil
+}
+
+// GetFleetUpgrades returns all fleet upgrades ordered by their creation time
+func (b *backend) GetFleetUpgrades() ([]storage.FleetUpgrade, error) {
+	ids, err := b.getKeys(b.key(fleetUpgradesP))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var upgrades []storage.FleetUpgrade
+	for _, id := range ids {
+		upgrade, err := b.GetFleetUpgrade(id)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		upgrades = append(upgrades, *upgrade)
+	}
+	sort.Slice(upgrades, func(i, j int) bool {
+		return upgrades[i].Created.Before(upgrades[j].Created)
+	})
+	return upgrades, nil
+}
@@ -17,8 +17,11 @@ limitations under the License.
 package keyval
 
 import (
+	"context"
 	"io"
 	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
 )
 
 type kvengine interface {
@@ -45,9 +48,15 @@ type kvengine interface {
 	tryAcquireLock(token key, ttl time.Duration) error
 	releaseLock(token key) error
 	getKeys(key key) ([]string, error)
+	// watch returns a watcher for the changes of the keys with the specified prefix
+	watch(ctx context.Context, prefix key) (storage.Watcher, error)
 }
 
-type key []string
+// Key identifies a value or a directory in a key-value engine
+// as a list of path components
+type Key []string
+
+type key = Key
 
 func (k key) split() ([]string, string) {
 	if len(k) == 0 {
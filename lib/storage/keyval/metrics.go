@@ -0,0 +1,128 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The methods below shadow the ones of the embedded engine to record
+// the latency of the requests the backend makes.
+// Locks and watches are not instrumented as they block by design
+
+func (b *backend) createVal(key key, val interface{}, ttl time.Duration) error {
+	defer observeLatency("create", time.Now())
+	return b.kvengine.createVal(key, val, ttl)
+}
+
+func (b *backend) createValBytes(key key, data []byte, ttl time.Duration) error {
+	defer observeLatency("create", time.Now())
+	return b.kvengine.createValBytes(key, data, ttl)
+}
+
+func (b *backend) upsertVal(key key, val interface{}, ttl time.Duration) error {
+	defer observeLatency("upsert", time.Now())
+	return b.kvengine.upsertVal(key, val, ttl)
+}
+
+func (b *backend) upsertValBytes(key key, val []byte, ttl time.Duration) error {
+	defer observeLatency("upsert", time.Now())
+	return b.kvengine.upsertValBytes(key, val, ttl)
+}
+
+func (b *backend) updateVal(key key, val interface{}, ttl time.Duration) error {
+	defer observeLatency("update", time.Now())
+	return b.kvengine.updateVal(key, val, ttl)
+}
+
+func (b *backend) updateValBytes(key key, data []byte, ttl time.Duration) error {
+	defer observeLatency("update", time.Now())
+	return b.kvengine.updateValBytes(key, data, ttl)
+}
+
+func (b *backend) updateTTL(key key, ttl time.Duration) error {
+	defer observeLatency("update_ttl", time.Now())
+	return b.kvengine.updateTTL(key, ttl)
+}
+
+func (b *backend) compareAndSwap(key key, val, prevVal, outVal interface{}, ttl time.Duration) error {
+	defer observeLatency("compare_and_swap", time.Now())
+	return b.kvengine.compareAndSwap(key, val, prevVal, outVal, ttl)
+}
+
+func (b *backend) compareAndSwapBytes(key key, val, prevVal []byte, outVal *[]byte, ttl time.Duration) error {
+	defer observeLatency("compare_and_swap", time.Now())
+	return b.kvengine.compareAndSwapBytes(key, val, prevVal, outVal, ttl)
+}
+
+func (b *backend) getVal(key key, val interface{}) error {
+	defer observeLatency("get", time.Now())
+	return b.kvengine.getVal(key, val)
+}
+
+func (b *backend) getValBytes(key key) ([]byte, error) {
+	defer observeLatency("get", time.Now())
+	return b.kvengine.getValBytes(key)
+}
+
+func (b *backend) deleteKey(key key) error {
+	defer observeLatency("delete", time.Now())
+	return b.kvengine.deleteKey(key)
+}
+
+func (b *backend) compareAndDelete(key key, prevVal interface{}) error {
+	defer observeLatency("compare_and_delete", time.Now())
+	return b.kvengine.compareAndDelete(key, prevVal)
+}
+
+func (b *backend) createDir(key key, ttl time.Duration) error {
+	defer observeLatency("create_dir", time.Now())
+	return b.kvengine.createDir(key, ttl)
+}
+
+func (b *backend) upsertDir(key key, ttl time.Duration) error {
+	defer observeLatency("upsert_dir", time.Now())
+	return b.kvengine.upsertDir(key, ttl)
+}
+
+func (b *backend) deleteDir(key key) error {
+	defer observeLatency("delete_dir", time.Now())
+	return b.kvengine.deleteDir(key)
+}
+
+func (b *backend) getKeys(key key) ([]string, error) {
+	defer observeLatency("get_keys", time.Now())
+	return b.kvengine.getKeys(key)
+}
+
+func observeLatency(operation string, start time.Time) {
+	requestLatency.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+var requestLatency = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "keyval_request_duration_seconds",
+		Help: "Latency of the requests to the key-value storage backend",
+	},
+	[]string{"operation"},
+)
+
+func init() {
+	prometheus.MustRegister(requestLatency)
+}
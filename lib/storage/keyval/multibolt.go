@@ -17,8 +17,12 @@ limitations under the License.
 package keyval
 
 import (
+	"context"
+	"io"
 	"time"
 
+	"github.com/gravitational/gravity/lib/storage"
+
 	"github.com/gravitational/trace"
 )
 
@@ -28,13 +32,26 @@ import (
 // This is achieved by opening/closing the database file on each operation
 // because in regular mode bolt keeps an exclusive lock on the file.
 func newMultiBolt(cfg BoltConfig) (*multiBolt, error) {
+	cipher, err := newValueCipher(cfg.Encryption)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// the cipher is shared by the engines opened for each operation
+	cfg.Encryption = nil
 	return &multiBolt{
-		cfg: cfg,
+		cfg:      cfg,
+		cipher:   cipher,
+		watchers: newWatchers(),
 	}, nil
 }
 
 type multiBolt struct {
 	cfg BoltConfig
+	// cipher encrypts the values at rest, nil if encryption is disabled
+	cipher *valueCipher
+	// watchers receives the changes made with this engine,
+	// the changes made by other clients are not observed
+	watchers *watchers
 }
 
 func (b *multiBolt) createDir(key key, ttl time.Duration) error {
@@ -171,11 +188,31 @@ func (b *multiBolt) Close() error {
 	}))
 }
 
+func (b *multiBolt) watch(ctx context.Context, prefix key) (storage.Watcher, error) {
+	return b.watchers.add(ctx, prefix), nil
+}
+
+// Compact rewrites the database file without the space left by deleted data
+func (b *multiBolt) Compact() error {
+	return trace.Wrap(b.withBolt(func(b *blt) error {
+		return trace.Wrap(b.Compact())
+	}))
+}
+
+// Snapshot writes a consistent copy of the database to w
+func (b *multiBolt) Snapshot(w io.Writer) error {
+	return trace.Wrap(b.withBolt(func(b *blt) error {
+		return trace.Wrap(b.Snapshot(w))
+	}))
+}
+
 func (b *multiBolt) withBolt(fn func(b *blt) error) error {
 	bolt, err := newBolt(b.cfg, &v1codec{})
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	defer bolt.Close()
+	bolt.watchers = b.watchers
+	bolt.cipher = b.cipher
 	return trace.Wrap(fn(bolt))
 }
@@ -0,0 +1,91 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"sort"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// UpsertProvisioner creates or updates a provisioner
+func (b *backend) UpsertProvisioner(provisioner storage.Provisioner) error {
+	if err := provisioner.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	data, err := storage.MarshalProvisioner(provisioner)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = b.upsertValBytes(b.key(provisionersP, provisioner.GetName()), data, forever)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetProvisioner returns a provisioner by name
+func (b *backend) GetProvisioner(name string) (storage.Provisioner, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing provisioner name")
+	}
+	data, err := b.getValBytes(b.key(provisionersP, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("provisioner %q is not found", name)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return storage.UnmarshalProvisioner(data)
+}
+
+// GetProvisioners returns all provisioners sorted by name
+func (b *backend) GetProvisioners() ([]storage.Provisioner, error) {
+	keys, err := b.getKeys(b.key(provisionersP))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Strings(keys)
+	var out []storage.Provisioner
+	for _, name := range keys {
+		provisioner, err := b.GetProvisioner(name)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		out = append(out, provisioner)
+	}
+	return out, nil
+}
+
+// DeleteProvisioner deletes a provisioner by name
+func (b *backend) DeleteProvisioner(name string) error {
+	if name == "" {
+		return trace.BadParameter("missing provisioner name")
+	}
+	err := b.deleteKey(b.key(provisionersP, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("provisioner %q is not found", name)
+		}
+	}
+	return trace.Wrap(err)
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/boltdb/bolt"
+	"github.com/coreos/etcd/client"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Reencrypt encrypts the values of the specified backend that are either
+// not encrypted or have been encrypted with a previous key with the current
+// encryption key. Returns the number of re-encrypted values.
+//
+// The values of etcd-backed backends that are neither encrypted nor JSON
+// are left as is since they can belong to other etcd clients,
+// e.g. leader election
+func Reencrypt(backend storage.Backend) (count int, err error) {
+	engine, err := engineOf(backend)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	r, ok := engine.(reencrypter)
+	if !ok {
+		return 0, trace.BadParameter("backend %T does not support encryption", backend)
+	}
+	return r.reencrypt()
+}
+
+// reencrypter is implemented by engines that support encryption at rest
+type reencrypter interface {
+	// reencrypt encrypts the values with the current encryption key
+	reencrypt() (count int, err error)
+}
+
+func (b *blt) reencrypt() (count int, err error) {
+	if b.cipher == nil {
+		return 0, trace.BadParameter("encryption is not configured for %v", b.path)
+	}
+	err = b.update(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, bkt *bolt.Bucket) error {
+			n, err := b.reencryptBucket(bkt)
+			count += n
+			return trace.Wrap(err)
+		})
+	})
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	return count, nil
+}
+
+// reencryptBucket re-encrypts the values of the bucket and its nested buckets
+func (b *blt) reencryptBucket(bkt *bolt.Bucket) (count int, err error) {
+	var keys, nested [][]byte
+	err = bkt.ForEach(func(k, v []byte) error {
+		if v == nil {
+			nested = append(nested, k)
+		} else if b.cipher.needsReencryption(v) {
+			keys = append(keys, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	// the bucket cannot be modified while it is iterated over
+	for _, k := range keys {
+		data, err := b.cipher.reencrypt(bkt.Get(k))
+		if err != nil {
+			return 0, trace.Wrap(err, "failed to re-encrypt %s", k)
+		}
+		if err := bkt.Put(k, data); err != nil {
+			return 0, trace.Wrap(err)
+		}
+	}
+	count = len(keys)
+	for _, k := range nested {
+		n, err := b.reencryptBucket(bkt.Bucket(k))
+		if err != nil {
+			return 0, trace.Wrap(err)
+		}
+		count += n
+	}
+	return count, nil
+}
+
+func (b *multiBolt) reencrypt() (count int, err error) {
+	err = b.withBolt(func(b *blt) (err error) {
+		count, err = b.reencrypt()
+		return trace.Wrap(err)
+	})
+	return count, trace.Wrap(err)
+}
+
+func (e *engine) reencrypt() (count int, err error) {
+	if e.cipher == nil {
+		return 0, trace.BadParameter("encryption is not configured")
+	}
+	root := "/" + strings.Trim(e.cfg.Key, "/")
+	re, err := e.Get(context.TODO(), root, &client.GetOptions{Recursive: true})
+	if err != nil {
+		err = convertErr(err)
+		if trace.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, trace.Wrap(err)
+	}
+	return e.reencryptNode(re.Node)
+}
+
+// reencryptNode re-encrypts the value of the node and its children
+func (e *engine) reencryptNode(node *client.Node) (count int, err error) {
+	if node.Dir {
+		for _, child := range node.Nodes {
+			n, err := e.reencryptNode(child)
+			if err != nil {
+				return 0, trace.Wrap(err)
+			}
+			count += n
+		}
+		return count, nil
+	}
+	// values are stored base64-encoded with the exception of locks
+	data, err := base64.StdEncoding.DecodeString(node.Value)
+	if err != nil || !e.needsReencryption(data) {
+		return 0, nil
+	}
+	data, err = e.cipher.reencrypt(data)
+	if err != nil {
+		return 0, trace.Wrap(err, "failed to re-encrypt %v", node.Key)
+	}
+	_, err = e.Set(context.TODO(), node.Key, base64.StdEncoding.EncodeToString(data),
+		&client.SetOptions{
+			PrevIndex: node.ModifiedIndex,
+			TTL:       time.Duration(node.TTL) * time.Second,
+		})
+	if err != nil {
+		err = convertErr(err)
+		if trace.IsCompareFailed(err) {
+			// the value has been modified concurrently and is
+			// therefore encrypted with the current key
+			log.Debugf("%v has been modified concurrently.", node.Key)
+			return 0, nil
+		}
+		return 0, trace.Wrap(err)
+	}
+	return 1, nil
+}
+
+func (e *engine) needsReencryption(data []byte) bool {
+	return e.cipher.needsReencryption(data) && (isEncrypted(data) || json.Valid(data))
+}
+
+func (e *engineV3) reencrypt() (count int, err error) {
+	if e.cipher == nil {
+		return 0, trace.BadParameter("encryption is not configured")
+	}
+	var re *clientv3.GetResponse
+	err = e.retry(func(ctx context.Context) (err error) {
+		re, err = e.client.Get(ctx, strings.Join(e.etcdKey, "/")+"/", clientv3.WithPrefix())
+		return err
+	})
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	for _, kv := range re.Kvs {
+		if !e.cipher.needsReencryption(kv.Value) || (!isEncrypted(kv.Value) && !json.Valid(kv.Value)) {
+			continue
+		}
+		data, err := e.cipher.reencrypt(kv.Value)
+		if err != nil {
+			return 0, trace.Wrap(err, "failed to re-encrypt %s", kv.Key)
+		}
+		// the value keeps its lease to preserve the expiration
+		key := string(kv.Key)
+		txn, err := e.txn(func(txn clientv3.Txn) clientv3.Txn {
+			return txn.If(clientv3.Compare(clientv3.ModRevision(key), "=", kv.ModRevision)).
+				Then(clientv3.OpPut(key, string(data), clientv3.WithLease(clientv3.LeaseID(kv.Lease))))
+		})
+		if err != nil {
+			return 0, trace.Wrap(err)
+		}
+		if !txn.Succeeded {
+			// the value has been modified concurrently and is
+			// therefore encrypted with the current key
+			log.Debugf("%v has been modified concurrently.", key)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
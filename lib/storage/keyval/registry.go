@@ -0,0 +1,162 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/yaml.v2"
+)
+
+// Factory creates a storage backend from the specified configuration
+type Factory func(FactoryConfig) (storage.Backend, error)
+
+// FactoryConfig is the configuration passed to backend factories
+type FactoryConfig struct {
+	// DataDir is the directory with the local process data
+	DataDir string
+	// Params are the backend-specific configuration parameters
+	Params Params
+}
+
+// Params are the backend-specific configuration parameters
+type Params map[string]interface{}
+
+// NewParams returns parameters with the values of the specified
+// configuration structure
+func NewParams(config interface{}) (Params, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var params Params
+	if err := yaml.Unmarshal(data, &params); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return params, nil
+}
+
+// Decode decodes the parameters into the specified configuration structure
+// using its yaml field tags
+func (p Params) Decode(config interface{}) error {
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(yaml.Unmarshal(data, config))
+}
+
+// Register makes the backend type available with the specified factory.
+// It is meant to be called from the init function of the package
+// implementing the backend and panics if the type is already registered
+func Register(backendType string, factory Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+	if factory == nil {
+		panic(fmt.Sprintf("nil factory for backend type %q", backendType))
+	}
+	if _, ok := registry.factories[backendType]; ok {
+		panic(fmt.Sprintf("backend type %q is already registered", backendType))
+	}
+	registry.factories[backendType] = factory
+}
+
+// IsRegistered returns true if the specified backend type has been registered
+func IsRegistered(backendType string) bool {
+	registry.RLock()
+	defer registry.RUnlock()
+	_, ok := registry.factories[backendType]
+	return ok
+}
+
+// Types returns the sorted list of registered backend types
+func Types() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	types := make([]string, 0, len(registry.factories))
+	for backendType := range registry.factories {
+		types = append(types, backendType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// New creates a storage backend of the specified registered type
+func New(backendType string, config FactoryConfig) (storage.Backend, error) {
+	registry.RLock()
+	factory, ok := registry.factories[backendType]
+	registry.RUnlock()
+	if !ok {
+		return nil, trace.BadParameter("unsupported backend type %q, supported types are %v",
+			backendType, Types())
+	}
+	backend, err := factory(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return backend, nil
+}
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]Factory
+}{
+	factories: make(map[string]Factory),
+}
+
+func init() {
+	Register(constants.BoltBackend, newBoltFromConfig)
+	Register(constants.ETCDBackend, newETCDFromConfig)
+}
+
+// newBoltFromConfig creates BoltDB-backed backend.
+// The database is placed in the data directory unless the path parameter is set
+func newBoltFromConfig(config FactoryConfig) (storage.Backend, error) {
+	var cfg BoltConfig
+	if err := config.Params.Decode(&cfg); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if cfg.Path == "" {
+		cfg.Path = filepath.Join(config.DataDir, defaults.GravityDBFile)
+	}
+	backend, err := NewBolt(cfg)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return backend, nil
+}
+
+// newETCDFromConfig creates etcd-backed backend
+func newETCDFromConfig(config FactoryConfig) (storage.Backend, error) {
+	var cfg ETCDConfig
+	if err := config.Params.Decode(&cfg); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	backend, err := NewETCD(cfg)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return backend, nil
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/suite"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	. "gopkg.in/check.v1"
+)
+
+type RegistrySuite struct {
+	engine *exportedEngine
+	suite  suite.StorageSuite
+}
+
+var _ = Suite(&RegistrySuite{})
+
+func init() {
+	Register(testBackendType, func(config FactoryConfig) (storage.Backend, error) {
+		engine, err := newExportedEngine(filepath.Join(config.DataDir, "test.db"))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return NewBackend(engine, nil), nil
+	})
+}
+
+func (s *RegistrySuite) SetUpTest(c *C) {
+	var err error
+	s.engine, err = newExportedEngine(filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+	clock := clockwork.NewFakeClock()
+	s.suite.Backend = NewBackend(s.engine, clock)
+	s.suite.Clock = clock
+}
+
+func (s *RegistrySuite) TearDownTest(c *C) {
+	c.Assert(s.suite.Backend.Close(), IsNil)
+}
+
+func (s *RegistrySuite) TestCreatesRegisteredBackend(c *C) {
+	c.Assert(IsRegistered(testBackendType), Equals, true)
+	dir := c.MkDir()
+	backend, err := New(testBackendType, FactoryConfig{DataDir: dir})
+	c.Assert(err, IsNil)
+	defer backend.Close()
+	_, err = backend.CreateAccount(storage.Account{Org: "example.com"})
+	c.Assert(err, IsNil)
+	_, err = os.Stat(filepath.Join(dir, "test.db"))
+	c.Assert(err, IsNil)
+}
+
+func (s *RegistrySuite) TestCreatesBoltBackend(c *C) {
+	path := filepath.Join(c.MkDir(), "bolt.db")
+	params, err := NewParams(BoltConfig{Path: path})
+	c.Assert(err, IsNil)
+	backend, err := New("bolt", FactoryConfig{DataDir: c.MkDir(), Params: params})
+	c.Assert(err, IsNil)
+	defer backend.Close()
+	_, err = os.Stat(path)
+	c.Assert(err, IsNil)
+}
+
+func (s *RegistrySuite) TestRejectsUnknownBackend(c *C) {
+	c.Assert(IsRegistered("unknown"), Equals, false)
+	_, err := New("unknown", FactoryConfig{DataDir: c.MkDir()})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *RegistrySuite) TestAccountsCRUD(c *C) {
+	s.suite.AccountsCRUD(c)
+}
+
+func (s *RegistrySuite) TestSitesCRUD(c *C) {
+	s.suite.SitesCRUD(c)
+}
+
+func (s *RegistrySuite) TestOperationsCRUD(c *C) {
+	s.suite.OperationsCRUD(c)
+}
+
+func (s *RegistrySuite) TestLocksCRUD(c *C) {
+	s.suite.LocksCRUD(c)
+}
+
+func (s *RegistrySuite) TestWatch(c *C) {
+	s.suite.Watch(c)
+}
+
+// newExportedEngine returns an Engine backed by BoltDB at the specified path
+func newExportedEngine(path string) (*exportedEngine, error) {
+	engine, err := newBolt(BoltConfig{Path: path}, &v1codec{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &exportedEngine{kvengine: engine}, nil
+}
+
+// exportedEngine implements Engine using only the exported API
+// the way third-party engines do
+type exportedEngine struct {
+	kvengine
+}
+
+func (e *exportedEngine) Key(prefix string, keys ...string) Key {
+	return e.key(prefix, keys...)
+}
+
+func (e *exportedEngine) CreateVal(key Key, val []byte, ttl time.Duration) error {
+	return e.createValBytes(key, val, ttl)
+}
+
+func (e *exportedEngine) UpsertVal(key Key, val []byte, ttl time.Duration) error {
+	return e.upsertValBytes(key, val, ttl)
+}
+
+func (e *exportedEngine) UpdateVal(key Key, val []byte, ttl time.Duration) error {
+	return e.updateValBytes(key, val, ttl)
+}
+
+func (e *exportedEngine) UpdateTTL(key Key, ttl time.Duration) error {
+	return e.updateTTL(key, ttl)
+}
+
+func (e *exportedEngine) CompareAndSwap(key Key, val, prevVal []byte, ttl time.Duration) ([]byte, error) {
+	var out []byte
+	err := e.compareAndSwapBytes(key, val, prevVal, &out, ttl)
+	return out, err
+}
+
+func (e *exportedEngine) GetVal(key Key) ([]byte, error) {
+	return e.getValBytes(key)
+}
+
+func (e *exportedEngine) DeleteKey(key Key) error {
+	return e.deleteKey(key)
+}
+
+func (e *exportedEngine) CompareAndDelete(key Key, prevVal []byte) error {
+	var val interface{}
+	if err := json.Unmarshal(prevVal, &val); err != nil {
+		return trace.Wrap(err)
+	}
+	return e.compareAndDelete(key, val)
+}
+
+func (e *exportedEngine) CreateDir(key Key, ttl time.Duration) error {
+	return e.createDir(key, ttl)
+}
+
+func (e *exportedEngine) UpsertDir(key Key, ttl time.Duration) error {
+	return e.upsertDir(key, ttl)
+}
+
+func (e *exportedEngine) DeleteDir(key Key) error {
+	return e.deleteDir(key)
+}
+
+func (e *exportedEngine) AcquireLock(token Key, ttl time.Duration) error {
+	return e.acquireLock(token, ttl)
+}
+
+func (e *exportedEngine) TryAcquireLock(token Key, ttl time.Duration) error {
+	return e.tryAcquireLock(token, ttl)
+}
+
+func (e *exportedEngine) ReleaseLock(token Key) error {
+	return e.releaseLock(token)
+}
+
+func (e *exportedEngine) GetKeys(key Key) ([]string, error) {
+	return e.getKeys(key)
+}
+
+func (e *exportedEngine) Watch(ctx context.Context, prefix Key) (storage.Watcher, error) {
+	return e.watch(ctx, prefix)
+}
+
+const testBackendType = "test"
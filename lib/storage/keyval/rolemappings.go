@@ -0,0 +1,88 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"sort"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// UpsertRoleMapping creates or updates a role mapping
+func (b *backend) UpsertRoleMapping(mapping storage.RoleMapping) error {
+	if err := mapping.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	data, err := storage.MarshalRoleMapping(mapping)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = b.upsertValBytes(b.key(authP, roleMappingsP, mapping.GetName()), data, forever)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetRoleMapping returns a role mapping by name
+func (b *backend) GetRoleMapping(name string) (storage.RoleMapping, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing role mapping name")
+	}
+	data, err := b.getValBytes(b.key(authP, roleMappingsP, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("role mapping %q is not found", name)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return storage.UnmarshalRoleMapping(data)
+}
+
+// GetRoleMappings returns all role mappings sorted by name
+func (b *backend) GetRoleMappings() ([]storage.RoleMapping, error) {
+	keys, err := b.getKeys(b.key(authP, roleMappingsP))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Strings(keys)
+	var out []storage.RoleMapping
+	for _, name := range keys {
+		mapping, err := b.GetRoleMapping(name)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		out = append(out, mapping)
+	}
+	return out, nil
+}
+
+// DeleteRoleMapping deletes a role mapping by name
+func (b *backend) DeleteRoleMapping(name string) error {
+	err := b.deleteKey(b.key(authP, roleMappingsP, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("role mapping %q is not found", name)
+		}
+	}
+	return trace.Wrap(err)
+}
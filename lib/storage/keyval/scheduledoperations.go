@@ -0,0 +1,95 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"sort"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// CreateScheduledOperation saves a new scheduled operation
+func (b *backend) CreateScheduledOperation(op storage.ScheduledOperation) (*storage.ScheduledOperation, error) {
+	if err := op.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err := b.createVal(b.key(sitesP, op.ClusterName, scheduledOperationsP, op.ID), op, forever)
+	if err != nil {
+		if trace.IsAlreadyExists(err) {
+			return nil, trace.AlreadyExists("scheduled operation %v already exists", op.ID)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return &op, nil
+}
+
+// UpdateScheduledOperation updates an existing scheduled operation
+func (b *backend) UpdateScheduledOperation(op storage.ScheduledOperation) (*storage.ScheduledOperation, error) {
+	if err := op.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err := b.updateVal(b.key(sitesP, op.ClusterName, scheduledOperationsP, op.ID), op, forever)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("scheduled operation %v not found", op.ID)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return &op, nil
+}
+
+// GetScheduledOperation returns the scheduled operation of the specified cluster
+func (b *backend) GetScheduledOperation(clusterName, id string) (*storage.ScheduledOperation, error) {
+	var op storage.ScheduledOperation
+	err := b.getVal(b.key(sitesP, clusterName, scheduledOperationsP, id), &op)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("scheduled operation %v not found", id)
+		}
+		return nil, trace.Wrap(err)
+	}
+	utils.UTC(&op.Created)
+	utils.UTC(&op.Schedule.NotBefore)
+	return &op, nil
+}
+
+// GetScheduledOperations returns all scheduled operations of the specified
+// cluster ordered by their creation time
+func (b *backend) GetScheduledOperations(clusterName string) ([]storage.ScheduledOperation, error) {
+	ids, err := b.getKeys(b.key(sitesP, clusterName, scheduledOperationsP))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var ops []storage.ScheduledOperation
+	for _, id := range ids {
+		op, err := b.GetScheduledOperation(clusterName, id)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		ops = append(ops, *op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].Created.Before(ops[j].Created)
+	})
+	return ops, nil
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/boltdb/bolt"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/coreos/etcd/pkg/transport"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// SnapshotStatus describes an etcd snapshot file
+type SnapshotStatus struct {
+	// Hash is the hex-encoded SHA256 checksum of the snapshot database
+	Hash string `json:"hash"`
+	// Revision is the latest store revision captured in the snapshot
+	Revision int64 `json:"revision"`
+	// TotalKeys is the number of live keys in the snapshot matching the prefix
+	TotalKeys int `json:"total_keys"`
+	// Size is the size of the snapshot file in bytes
+	Size int64 `json:"size"`
+}
+
+// SaveSnapshot streams a consistent point-in-time snapshot of the etcd
+// cluster specified with config into the file at path.
+//
+// The snapshot is produced with the etcd maintenance API and is compatible
+// with `etcdctl snapshot restore`. The file is verified before it is
+// moved into its final location
+func SaveSnapshot(ctx context.Context, config ETCDConfig, path string) (*SnapshotStatus, error) {
+	client, err := newClientV3(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer client.Close()
+
+	reader, err := client.Snapshot(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer reader.Close()
+
+	partPath := path + ".part"
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, defaults.PrivateFileMask)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer os.Remove(partPath)
+	_, err = io.Copy(file, reader)
+	if err == nil {
+		err = file.Sync()
+	}
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+
+	status, err := VerifySnapshot(partPath, "")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return status, nil
+}
+
+// VerifySnapshot validates integrity of the etcd snapshot file at path
+// and returns its status. Only keys with the specified prefix are counted,
+// an empty prefix selects the whole keyspace
+func VerifySnapshot(path, prefix string) (*SnapshotStatus, error) {
+	snapshot, err := readSnapshot(path, prefix)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &snapshot.SnapshotStatus, nil
+}
+
+// RestoreSnapshot writes the keys with the specified prefix from the etcd
+// snapshot file at path into the running etcd cluster specified with config.
+// An empty prefix restores the whole keyspace.
+//
+// Leases are not restored: keys that were attached to a lease at the time
+// the snapshot was taken are written without expiration
+func RestoreSnapshot(ctx context.Context, config ETCDConfig, path, prefix string) (*SnapshotStatus, error) {
+	snapshot, err := readSnapshot(path, prefix)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	client, err := newClientV3(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer client.Close()
+
+	for _, kv := range snapshot.kvs {
+		_, err = client.Put(ctx, string(kv.Key), string(kv.Value))
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to restore key %q", kv.Key)
+		}
+	}
+	log.Infof("Restored %v keys from %v.", len(snapshot.kvs), path)
+	return &snapshot.SnapshotStatus, nil
+}
+
+// newClientV3 returns a new etcd v3 API client for the specified configuration
+func newClientV3(config ETCDConfig) (*clientv3.Client, error) {
+	if err := config.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	info := transport.TLSInfo{
+		CAFile:   config.TLSCAFile,
+		CertFile: config.TLSCertFile,
+		KeyFile:  config.TLSKeyFile,
+	}
+	tlsConfig, err := info.ClientConfig()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   config.Nodes,
+		DialTimeout: defaults.DialTimeout,
+		TLS:         tlsConfig,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client, nil
+}
+
+// snapshot is a decoded etcd snapshot
+type snapshot struct {
+	SnapshotStatus
+	// kvs lists live keys sorted by modification revision
+	kvs []mvccpb.KeyValue
+}
+
+// readSnapshot verifies the snapshot file at path and returns
+// the latest versions of all live keys with the specified prefix
+func readSnapshot(path, prefix string) (*snapshot, error) {
+	// bolt can only open files so the database part is copied
+	// into a scratch location while its checksum is verified
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+	dbPath := filepath.Join(dir, "db")
+	result, err := copySnapshotDB(path, dbPath)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	db, err := bolt.Open(dbPath, defaults.PrivateFileMask, &bolt.Options{
+		Timeout:  defaults.DBOpenTimeout,
+		ReadOnly: true,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err, "%v is not a valid etcd snapshot", path)
+	}
+	defer db.Close()
+
+	live := make(map[string]mvccpb.KeyValue)
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(snapshotKeyBucket)
+		if bucket == nil {
+			return trace.BadParameter("%v is not a valid etcd snapshot: missing key bucket", path)
+		}
+		return bucket.ForEach(func(revision, value []byte) error {
+			if len(revision) < revisionBytesLen {
+				return trace.BadParameter("invalid revision %x", revision)
+			}
+			main := int64(binary.BigEndian.Uint64(revision[:8]))
+			if main > result.Revision {
+				result.Revision = main
+			}
+			var kv mvccpb.KeyValue
+			if err := kv.Unmarshal(value); err != nil {
+				return trace.Wrap(err)
+			}
+			if !strings.HasPrefix(string(kv.Key), prefix) {
+				return nil
+			}
+			if isTombstone(revision) {
+				delete(live, string(kv.Key))
+				return nil
+			}
+			live[string(kv.Key)] = kv
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, kv := range live {
+		result.kvs = append(result.kvs, kv)
+	}
+	sort.Slice(result.kvs, func(i, j int) bool {
+		return result.kvs[i].ModRevision < result.kvs[j].ModRevision
+	})
+	result.TotalKeys = len(result.kvs)
+	return result, nil
+}
+
+// copySnapshotDB copies the database part of the snapshot file at path
+// to dbPath and verifies it against the SHA256 checksum etcd appends
+// to the snapshot
+func copySnapshotDB(path, dbPath string) (*snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	// etcd pads the database file to the page boundary and appends
+	// the checksum so the total size is a page multiple plus the hash size
+	if fi.Size()%snapshotPageSize != sha256.Size {
+		return nil, trace.BadParameter("snapshot %v is missing integrity hash", path)
+	}
+
+	db, err := os.OpenFile(dbPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, defaults.PrivateFileMask)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer db.Close()
+	hasher := sha256.New()
+	_, err = io.CopyN(io.MultiWriter(db, hasher), file, fi.Size()-sha256.Size)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	expected := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(file, expected); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	computed := hasher.Sum(nil)
+	if !bytes.Equal(computed, expected) {
+		return nil, trace.BadParameter("snapshot %v integrity hash mismatch: expected %x, got %x",
+			path, expected, computed)
+	}
+	return &snapshot{
+		SnapshotStatus: SnapshotStatus{
+			Hash: hex.EncodeToString(computed),
+			Size: fi.Size(),
+		},
+	}, nil
+}
+
+func isTombstone(revision []byte) bool {
+	return len(revision) == revisionBytesLen+1 && revision[revisionBytesLen] == tombstoneMark
+}
+
+var (
+	// snapshotKeyBucket is the name of the etcd backend bucket with key revisions
+	snapshotKeyBucket = []byte("key")
+)
+
+const (
+	// revisionBytesLen is the length of the encoded revision:
+	// 8 bytes main revision, separator and 8 bytes sub revision
+	revisionBytesLen = 8 + 1 + 8
+	// tombstoneMark marks revisions that delete the key
+	tombstoneMark = 't'
+	// snapshotPageSize is the size of the database page used to pad snapshots
+	snapshotPageSize = 512
+)
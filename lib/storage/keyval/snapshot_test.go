@@ -0,0 +1,163 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/boltdb/bolt"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type SnapshotSuite struct {
+	dir string
+}
+
+var _ = Suite(&SnapshotSuite{})
+
+func (s *SnapshotSuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+}
+
+func (s *SnapshotSuite) TestReadsLiveKeys(c *C) {
+	path := filepath.Join(s.dir, "snapshot.db")
+	err := writeTestSnapshot(path, []testRevision{
+		{main: 2, kv: mvccpb.KeyValue{Key: []byte("/gravity/local/a"), Value: []byte("1"), ModRevision: 2}},
+		{main: 3, kv: mvccpb.KeyValue{Key: []byte("/gravity/local/b"), Value: []byte("2"), ModRevision: 3}},
+		{main: 4, kv: mvccpb.KeyValue{Key: []byte("/other/c"), Value: []byte("3"), ModRevision: 4}},
+		{main: 5, kv: mvccpb.KeyValue{Key: []byte("/gravity/local/a"), Value: []byte("4"), ModRevision: 5}},
+		{main: 6, kv: mvccpb.KeyValue{Key: []byte("/gravity/local/b")}, tombstone: true},
+	})
+	c.Assert(err, IsNil)
+
+	snapshot, err := readSnapshot(path, "/gravity/local")
+	c.Assert(err, IsNil)
+	c.Assert(snapshot.Revision, Equals, int64(6))
+	c.Assert(snapshot.TotalKeys, Equals, 1)
+	c.Assert(string(snapshot.kvs[0].Key), Equals, "/gravity/local/a")
+	c.Assert(string(snapshot.kvs[0].Value), Equals, "4")
+
+	status, err := VerifySnapshot(path, "")
+	c.Assert(err, IsNil)
+	c.Assert(status.TotalKeys, Equals, 2)
+}
+
+func (s *SnapshotSuite) TestDetectsCorruption(c *C) {
+	path := filepath.Join(s.dir, "snapshot.db")
+	err := writeTestSnapshot(path, []testRevision{
+		{main: 2, kv: mvccpb.KeyValue{Key: []byte("/gravity/local/a"), Value: []byte("1"), ModRevision: 2}},
+	})
+	c.Assert(err, IsNil)
+
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	data[0] ^= 0xff
+	c.Assert(ioutil.WriteFile(path, data, defaults.PrivateFileMask), IsNil)
+
+	_, err = VerifySnapshot(path, "")
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+
+	c.Assert(ioutil.WriteFile(path, data[:len(data)-1], defaults.PrivateFileMask), IsNil)
+	_, err = VerifySnapshot(path, "")
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *SnapshotSuite) TestPrunesOldSnapshots(c *C) {
+	now := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	var names []string
+	for i := 0; i < 4; i++ {
+		name := SnapshotFilename(now.Add(time.Duration(i) * time.Hour))
+		c.Assert(ioutil.WriteFile(filepath.Join(s.dir, name), nil, defaults.PrivateFileMask), IsNil)
+		names = append(names, name)
+	}
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "etcd-manual.db"), nil, defaults.PrivateFileMask), IsNil)
+
+	removed, err := PruneSnapshots(s.dir, 2)
+	c.Assert(err, IsNil)
+	c.Assert(removed, DeepEquals, []string{
+		filepath.Join(s.dir, names[0]),
+		filepath.Join(s.dir, names[1]),
+	})
+	for _, name := range append(names[2:], "etcd-manual.db") {
+		_, err := os.Stat(filepath.Join(s.dir, name))
+		c.Assert(err, IsNil)
+	}
+
+	removed, err = PruneSnapshots(s.dir, 2)
+	c.Assert(err, IsNil)
+	c.Assert(removed, HasLen, 0)
+}
+
+type testRevision struct {
+	main      uint64
+	kv        mvccpb.KeyValue
+	tombstone bool
+}
+
+// writeTestSnapshot creates a file in etcd snapshot format
+// with the specified revisions at path
+func writeTestSnapshot(path string, revisions []testRevision) error {
+	dbPath := path + ".db"
+	db, err := bolt.Open(dbPath, defaults.PrivateFileMask, nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket(snapshotKeyBucket)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, revision := range revisions {
+			key := make([]byte, revisionBytesLen, revisionBytesLen+1)
+			binary.BigEndian.PutUint64(key, revision.main)
+			key[8] = '_'
+			if revision.tombstone {
+				key = append(key, tombstoneMark)
+			}
+			value, err := revision.kv.Marshal()
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			if err := bucket.Put(key, value); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		return nil
+	})
+	if errClose := db.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	data, err := ioutil.ReadFile(dbPath)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer os.Remove(dbPath)
+	hash := sha256.Sum256(data)
+	return trace.Wrap(ioutil.WriteFile(path, append(data, hash[:]...), defaults.PrivateFileMask))
+}
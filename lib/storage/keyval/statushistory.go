@@ -0,0 +1,74 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// CreateStatusSnapshot saves the cluster status snapshot for the specified duration
+func (b *backend) CreateStatusSnapshot(snapshot storage.StatusSnapshot, ttl time.Duration) error {
+	if err := snapshot.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	err := b.createVal(b.key(sitesP, snapshot.ClusterName, statusHistoryP, snapshot.ID), snapshot, ttl)
+	if err != nil {
+		if trace.IsAlreadyExists(err) {
+			return trace.AlreadyExists("status snapshot %v already exists", snapshot.ID)
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetStatusSnapshots returns the status snapshots of the specified cluster
+// recorded since the specified time ordered by their time
+func (b *backend) GetStatusSnapshots(clusterName string, since time.Time) ([]storage.StatusSnapshot, error) {
+	if clusterName == "" {
+		return nil, trace.BadParameter("missing cluster name")
+	}
+	ids, err := b.getKeys(b.key(sitesP, clusterName, statusHistoryP))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var snapshots []storage.StatusSnapshot
+	for _, id := range ids {
+		var snapshot storage.StatusSnapshot
+		err := b.getVal(b.key(sitesP, clusterName, statusHistoryP, id), &snapshot)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		utils.UTC(&snapshot.Time)
+		if snapshot.Time.Before(since) {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.Before(snapshots[j].Time)
+	})
+	return snapshots, nil
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"github.com/gravitational/trace"
+)
+
+// UpsertTerraformState creates or updates the state of the specified
+// Terraform configuration
+func (b *backend) UpsertTerraformState(name string, state []byte) error {
+	if name == "" {
+		return trace.BadParameter("missing Terraform configuration name")
+	}
+	err := b.upsertValBytes(b.key(terraformP, name), state, forever)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetTerraformState returns the state of the specified Terraform configuration
+func (b *backend) GetTerraformState(name string) ([]byte, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing Terraform configuration name")
+	}
+	state, err := b.getValBytes(b.key(terraformP, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("no Terraform state for %q", name)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return state, nil
+}
+
+// DeleteTerraformState deletes the state of the specified Terraform configuration
+func (b *backend) DeleteTerraformState(name string) error {
+	if name == "" {
+		return trace.BadParameter("missing Terraform configuration name")
+	}
+	err := b.deleteKey(b.key(terraformP, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("no Terraform state for %q", name)
+		}
+	}
+	return trace.Wrap(err)
+}
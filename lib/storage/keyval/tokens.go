@@ -64,6 +64,23 @@ func (b *backend) GetProvisioningToken(token string) (*storage.ProvisioningToken
 	return &t, nil
 }
 
+// CompareAndSwapProvisioningToken updates the token if the existing
+// value matches existing parameter
+func (b *backend) CompareAndSwapProvisioningToken(new, existing storage.ProvisioningToken) error {
+	if err := new.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	var out storage.ProvisioningToken
+	err := b.compareAndSwap(b.key(provisioningTokensP, new.Token), new, existing, &out, b.ttl(new.Expires))
+	if err != nil {
+		if trace.IsCompareFailed(err) {
+			return trace.CompareFailed("provisioning token has been updated, try again")
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
 func (b *backend) GetOperationProvisioningToken(clusterName, operationID string) (*storage.ProvisioningToken, error) {
 	tokens, err := b.getKeys(b.key(provisioningTokensP))
 	if err != nil {
@@ -0,0 +1,82 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// UpsertTrustedKey creates or updates the trusted key
+func (b *backend) UpsertTrustedKey(key storage.TrustedKey) error {
+	if err := key.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if key.Created.IsZero() {
+		key.Created = b.Now().UTC()
+	}
+	err := b.upsertVal(b.key(trustedKeysP, key.Name), key, forever)
+	return trace.Wrap(err)
+}
+
+// GetTrustedKey returns the trusted key by name
+func (b *backend) GetTrustedKey(name string) (*storage.TrustedKey, error) {
+	var key storage.TrustedKey
+	err := b.getVal(b.key(trustedKeysP, name), &key)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("trusted key %q not found", name)
+		}
+		return nil, trace.Wrap(err)
+	}
+	utils.UTC(&key.Created)
+	return &key, nil
+}
+
+// GetTrustedKeys returns all trusted keys
+func (b *backend) GetTrustedKeys() ([]storage.TrustedKey, error) {
+	names, err := b.getKeys(b.key(trustedKeysP))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var keys []storage.TrustedKey
+	for _, name := range names {
+		key, err := b.GetTrustedKey(name)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+// DeleteTrustedKey deletes the trusted key by name
+func (b *backend) DeleteTrustedKey(name string) error {
+	err := b.deleteKey(b.key(trustedKeysP, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("trusted key %q not found", name)
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
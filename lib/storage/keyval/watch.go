@@ -0,0 +1,195 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// Watch returns a watcher that streams changes of the keys with the specified prefix
+func (b *backend) Watch(ctx context.Context, prefix ...string) (storage.Watcher, error) {
+	if len(prefix) == 0 {
+		return nil, trace.BadParameter("missing watch prefix")
+	}
+	watcher, err := b.watch(ctx, b.key(prefix[0], prefix[1:]...))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return watcher, nil
+}
+
+// newWatcher returns a new watcher bound to the specified context
+func newWatcher(ctx context.Context) *watcher {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &watcher{
+		ctx:     ctx,
+		cancel:  cancel,
+		eventsC: make(chan storage.WatchEvent, watchQueueSize),
+	}
+	go func() {
+		<-ctx.Done()
+		w.stop(nil)
+	}()
+	return w
+}
+
+// watcher implements storage.Watcher.
+//
+// Events are queued without blocking the producer: a watcher that lags
+// behind by more than watchQueueSize events is stopped with an error
+type watcher struct {
+	// ctx is cancelled when the watcher stops
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	eventsC chan storage.WatchEvent
+	// mu guards the fields below
+	mu      sync.Mutex
+	stopped bool
+	err     error
+}
+
+// Events returns the channel with change events
+func (w *watcher) Events() <-chan storage.WatchEvent {
+	return w.eventsC
+}
+
+// Error returns the reason the watcher has stopped
+func (w *watcher) Error() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Close stops the watcher
+func (w *watcher) Close() error {
+	w.stop(nil)
+	return nil
+}
+
+// push queues the event for delivery.
+// Returns false if the watcher has stopped
+func (w *watcher) push(event storage.WatchEvent) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return false
+	}
+	select {
+	case w.eventsC <- event:
+		return true
+	default:
+		w.stopLocked(trace.LimitExceeded("watcher has fallen behind by more than %v events", watchQueueSize))
+		return false
+	}
+}
+
+// stop stops the watcher with the specified error
+func (w *watcher) stop(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopLocked(err)
+}
+
+func (w *watcher) stopLocked(err error) {
+	if w.stopped {
+		return
+	}
+	w.stopped = true
+	w.err = err
+	close(w.eventsC)
+	w.cancel()
+}
+
+// watchers dispatches events generated by the local process
+// to the matching watchers. Keys are bolt keys with the root
+// bucket as the first component
+type watchers struct {
+	mu       sync.Mutex
+	watchers map[*watcher]key
+}
+
+func newWatchers() *watchers {
+	return &watchers{watchers: make(map[*watcher]key)}
+}
+
+// add returns a new watcher for the keys with the specified prefix
+func (r *watchers) add(ctx context.Context, prefix key) *watcher {
+	w := newWatcher(ctx)
+	r.mu.Lock()
+	r.watchers[w] = prefix
+	r.mu.Unlock()
+	go func() {
+		<-w.ctx.Done()
+		r.mu.Lock()
+		delete(r.watchers, w)
+		r.mu.Unlock()
+	}()
+	return w
+}
+
+// notify delivers the event to the watchers with the prefix matching the event key
+func (r *watchers) notify(eventType storage.WatchEventType, key key, value []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for w, prefix := range r.watchers {
+		if !hasPrefix(key, prefix) {
+			continue
+		}
+		w.push(storage.WatchEvent{
+			Type:  eventType,
+			Key:   key[1:],
+			Value: value,
+		})
+	}
+}
+
+// hasPrefix returns true if the key starts with the specified components
+func hasPrefix(key, prefix key) bool {
+	if len(key) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if key[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// watchQueueSize is the maximum number of events queued for a watcher
+const watchQueueSize = 1024
+
+// relativeKey returns the path of the etcd key relative to the root key.
+// Returns false if the key is not under the root key
+func relativeKey(root []string, etcdKey string) ([]string, bool) {
+	prefix := strings.Join(root, "/") + "/"
+	if !strings.HasPrefix(etcdKey, prefix) {
+		return nil, false
+	}
+	path := strings.Split(strings.TrimPrefix(etcdKey, prefix), "/")
+	for i := range path {
+		path[i] = strings.Replace(path[i], "%2F", "/", -1)
+	}
+	return path, true
+}
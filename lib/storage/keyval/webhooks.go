@@ -0,0 +1,103 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"sort"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// UpsertWebhook creates or updates a webhook of the specified cluster
+func (b *backend) UpsertWebhook(clusterName string, webhook storage.Webhook) error {
+	if clusterName == "" {
+		return trace.BadParameter("missing cluster name")
+	}
+	if err := webhook.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	data, err := storage.MarshalWebhook(webhook)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = b.upsertValBytes(b.key(sitesP, clusterName, webhooksP, webhook.GetName()), data, forever)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetWebhook returns a webhook of the specified cluster by name
+func (b *backend) GetWebhook(clusterName, name string) (storage.Webhook, error) {
+	if clusterName == "" {
+		return nil, trace.BadParameter("missing cluster name")
+	}
+	if name == "" {
+		return nil, trace.BadParameter("missing webhook name")
+	}
+	data, err := b.getValBytes(b.key(sitesP, clusterName, webhooksP, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("webhook %q is not found", name)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return storage.UnmarshalWebhook(data)
+}
+
+// GetWebhooks returns all webhooks of the specified cluster sorted by name
+func (b *backend) GetWebhooks(clusterName string) ([]storage.Webhook, error) {
+	if clusterName == "" {
+		return nil, trace.BadParameter("missing cluster name")
+	}
+	keys, err := b.getKeys(b.key(sitesP, clusterName, webhooksP))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Strings(keys)
+	var out []storage.Webhook
+	for _, name := range keys {
+		webhook, err := b.GetWebhook(clusterName, name)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		out = append(out, webhook)
+	}
+	return out, nil
+}
+
+// DeleteWebhook deletes a webhook of the specified cluster by name
+func (b *backend) DeleteWebhook(clusterName, name string) error {
+	if clusterName == "" {
+		return trace.BadParameter("missing cluster name")
+	}
+	if name == "" {
+		return trace.BadParameter("missing webhook name")
+	}
+	err := b.deleteKey(b.key(sitesP, clusterName, webhooksP, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("webhook %q is not found", name)
+		}
+	}
+	return trace.Wrap(err)
+}
@@ -18,11 +18,15 @@ package storage
 
 import (
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
 
 	teleservices "github.com/gravitational/teleport/lib/services"
 	teleutils "github.com/gravitational/teleport/lib/utils"
@@ -37,6 +41,12 @@ type LogForwarder interface {
 	GetAddress() string
 	// GetProtocol returns log forwarder protocol
 	GetProtocol() string
+	// GetSink returns the type of the remote log endpoint
+	GetSink() string
+	// GetSources returns the log sources to forward
+	GetSources() []string
+	// GetSpec returns the log forwarder spec
+	GetSpec() LogForwarderSpecV2
 	// CheckAndSetDefaults validates log forwarder configuration
 	CheckAndSetDefaults() error
 }
@@ -115,6 +125,27 @@ func (l *LogForwarderV2) GetProtocol() string {
 	return l.Spec.Protocol
 }
 
+// GetSink returns the type of the remote log endpoint
+func (l *LogForwarderV2) GetSink() string {
+	if l.Spec.Sink == "" {
+		return LogSinkSyslog
+	}
+	return l.Spec.Sink
+}
+
+// GetSources returns the log sources to forward
+func (l *LogForwarderV2) GetSources() []string {
+	if len(l.Spec.Sources) == 0 {
+		return LogSources
+	}
+	return l.Spec.Sources
+}
+
+// GetSpec returns the log forwarder spec
+func (l *LogForwarderV2) GetSpec() LogForwarderSpecV2 {
+	return l.Spec
+}
+
 // CheckAndSetDefaults validates log forwarder configuration
 func (l *LogForwarderV2) CheckAndSetDefaults() error {
 	if l.Metadata.Name == "" {
@@ -123,6 +154,66 @@ func (l *LogForwarderV2) CheckAndSetDefaults() error {
 	if l.Spec.Address == "" {
 		return trace.BadParameter("missing parameter Address")
 	}
+	if l.Spec.Sink == "" {
+		l.Spec.Sink = LogSinkSyslog
+	}
+	if !utils.StringInSlice(LogSinks, l.Spec.Sink) {
+		return trace.BadParameter(
+			"unsupported sink %q, must be one of: %v", l.Spec.Sink, strings.Join(LogSinks, ", "))
+	}
+	for _, source := range l.Spec.Sources {
+		if !utils.StringInSlice(LogSources, source) {
+			return trace.BadParameter(
+				"unsupported log source %q, must be one of: %v", source, strings.Join(LogSources, ", "))
+		}
+	}
+	if l.Spec.Elasticsearch != nil && l.Spec.Sink != LogSinkElasticsearch {
+		return trace.BadParameter("elasticsearch settings require %q sink", LogSinkElasticsearch)
+	}
+	if l.Spec.Loki != nil && l.Spec.Sink != LogSinkLoki {
+		return trace.BadParameter("loki settings require %q sink", LogSinkLoki)
+	}
+	switch l.Spec.Sink {
+	case LogSinkSyslog:
+		if err := l.checkSyslog(); err != nil {
+			return trace.Wrap(err)
+		}
+	case LogSinkElasticsearch:
+		if err := l.checkEndpointURL(); err != nil {
+			return trace.Wrap(err)
+		}
+		if l.Spec.Elasticsearch == nil {
+			l.Spec.Elasticsearch = &LogForwarderElasticsearch{}
+		}
+		if l.Spec.Elasticsearch.Index == "" {
+			l.Spec.Elasticsearch.Index = defaults.LogForwarderElasticsearchIndex
+		}
+	case LogSinkLoki:
+		if err := l.checkEndpointURL(); err != nil {
+			return trace.Wrap(err)
+		}
+		if l.Spec.Loki != nil {
+			for name := range l.Spec.Loki.Labels {
+				if !lokiLabelRegexp.MatchString(name) {
+					return trace.BadParameter("invalid loki label name %q", name)
+				}
+			}
+		}
+	}
+	if l.Spec.TLS != nil {
+		if err := l.Spec.TLS.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if l.Spec.Buffer != nil {
+		if err := l.Spec.Buffer.CheckAndSetDefaults(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func (l *LogForwarderV2) checkSyslog() error {
 	if l.Spec.Protocol != "" {
 		if l.Spec.Protocol != "tcp" && l.Spec.Protocol != "udp" {
 			return trace.BadParameter(
@@ -131,15 +222,123 @@ func (l *LogForwarderV2) CheckAndSetDefaults() error {
 	} else {
 		l.Spec.Protocol = "tcp"
 	}
+	if l.Spec.TLS != nil && l.Spec.Protocol != "tcp" {
+		return trace.BadParameter("TLS is only supported with tcp protocol")
+	}
+	return nil
+}
+
+func (l *LogForwarderV2) checkEndpointURL() error {
+	if l.Spec.Protocol != "" {
+		return trace.BadParameter("protocol is not supported with %q sink", l.Spec.Sink)
+	}
+	u, err := url.Parse(l.Spec.Address)
+	if err != nil {
+		return trace.BadParameter("invalid %v address %q: %v", l.Spec.Sink, l.Spec.Address, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return trace.BadParameter("%v address should be an http(s) URL, e.g. https://logs.example.com:9200, got %q",
+			l.Spec.Sink, l.Spec.Address)
+	}
+	if l.Spec.TLS != nil && u.Scheme != "https" {
+		return trace.BadParameter("TLS settings require an https address, got %q", l.Spec.Address)
+	}
 	return nil
 }
 
 // LogForwarderSpecV2 is the log forwarder spec
 type LogForwarderSpecV2 struct {
-	// Address is log forwarder address
+	// Address is log forwarder address: host:port for syslog
+	// or endpoint URL for Elasticsearch and Loki
 	Address string `json:"address"`
-	// Protocol is log forwarder protocol
+	// Protocol is log forwarder protocol, only applies to syslog
 	Protocol string `json:"protocol,omitempty"`
+	// Sink is the type of the remote log endpoint: syslog, elasticsearch or loki
+	Sink string `json:"sink,omitempty"`
+	// Sources lists the logs to forward: container and/or system logs.
+	// All logs are forwarded if unspecified
+	Sources []string `json:"sources,omitempty"`
+	// Elasticsearch specifies Elasticsearch sink settings
+	Elasticsearch *LogForwarderElasticsearch `json:"elasticsearch,omitempty"`
+	// Loki specifies Loki sink settings
+	Loki *LogForwarderLoki `json:"loki,omitempty"`
+	// TLS specifies TLS settings of the connection to the remote endpoint
+	TLS *LogForwarderTLS `json:"tls,omitempty"`
+	// Buffer specifies buffering settings of the log collector
+	Buffer *LogForwarderBuffer `json:"buffer,omitempty"`
+}
+
+// LogForwarderElasticsearch defines Elasticsearch sink settings
+type LogForwarderElasticsearch struct {
+	// Index is the name of the index to write logs to
+	Index string `json:"index,omitempty"`
+	// Username is the basic auth username
+	Username string `json:"username,omitempty"`
+	// Password is the basic auth password
+	Password string `json:"password,omitempty"`
+}
+
+// LogForwarderLoki defines Loki sink settings
+type LogForwarderLoki struct {
+	// TenantID is the tenant to push logs as
+	TenantID string `json:"tenant_id,omitempty"`
+	// Labels are the static labels attached to all log streams
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// LogForwarderTLS defines TLS settings of the connection to the remote endpoint
+type LogForwarderTLS struct {
+	// CAData is the PEM-encoded CA certificate to verify the endpoint with
+	CAData string `json:"ca_data,omitempty"`
+	// CertData is the PEM-encoded client certificate
+	CertData string `json:"cert_data,omitempty"`
+	// KeyData is the PEM-encoded client private key
+	KeyData string `json:"key_data,omitempty"`
+	// InsecureSkipVerify disables verification of the endpoint certificate
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// Check validates TLS settings
+func (t LogForwarderTLS) Check() error {
+	if (t.CertData == "") != (t.KeyData == "") {
+		return trace.BadParameter("client certificate and key should be specified together")
+	}
+	for name, data := range map[string]string{
+		"CA certificate":     t.CAData,
+		"client certificate": t.CertData,
+		"client key":         t.KeyData,
+	} {
+		if data == "" {
+			continue
+		}
+		if block, _ := pem.Decode([]byte(data)); block == nil {
+			return trace.BadParameter("%v is not PEM-encoded", name)
+		}
+	}
+	return nil
+}
+
+// LogForwarderBuffer defines buffering settings of the log collector
+type LogForwarderBuffer struct {
+	// MaxSize is the maximum size of logs buffered while the remote
+	// endpoint is unavailable
+	MaxSize utils.Capacity `json:"max_size,omitempty"`
+	// FlushInterval is how often buffered logs are sent to the remote endpoint
+	FlushInterval teleservices.Duration `json:"flush_interval,omitempty"`
+}
+
+// CheckAndSetDefaults validates buffering settings and sets defaults
+func (b *LogForwarderBuffer) CheckAndSetDefaults() error {
+	if b.FlushInterval.Duration < 0 {
+		return trace.BadParameter("flush interval can't be negative")
+	}
+	if b.MaxSize == 0 {
+		b.MaxSize = defaults.LogForwarderBufferSize
+	}
+	if b.FlushInterval.Duration == 0 {
+		b.FlushInterval = teleservices.NewDuration(defaults.LogForwarderFlushInterval)
+	}
+	return nil
 }
 
 // LogForwarderV2Scheme is the log forwarder JSON schema
@@ -149,10 +348,70 @@ const LogForwarderV2Schema = `{
   "required": ["address"],
   "properties": {
     "address": {"type": "string"},
-    "protocol": {"type": "string"}
+    "protocol": {"type": "string"},
+    "sink": {"type": "string"},
+    "sources": {"type": "array", "items": {"type": "string"}},
+    "elasticsearch": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "index": {"type": "string"},
+        "username": {"type": "string"},
+        "password": {"type": "string"}
+      }
+    },
+    "loki": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "tenant_id": {"type": "string"},
+        "labels": {"type": "object", "patternProperties": {"^.*$": {"type": "string"}}}
+      }
+    },
+    "tls": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "ca_data": {"type": "string"},
+        "cert_data": {"type": "string"},
+        "key_data": {"type": "string"},
+        "insecure_skip_verify": {"type": "boolean"}
+      }
+    },
+    "buffer": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "max_size": {"type": "string"},
+        "flush_interval": {"type": "string"}
+      }
+    }
   }
 }`
 
+const (
+	// LogSinkSyslog forwards logs to a remote syslog server
+	LogSinkSyslog = "syslog"
+	// LogSinkElasticsearch forwards logs to Elasticsearch
+	LogSinkElasticsearch = "elasticsearch"
+	// LogSinkLoki forwards logs to Loki
+	LogSinkLoki = "loki"
+
+	// LogSourceContainers is the logs of all containers running in the cluster
+	LogSourceContainers = "containers"
+	// LogSourceSystem is the system logs of cluster nodes
+	LogSourceSystem = "system"
+)
+
+// LogSinks lists the supported log forwarder sinks
+var LogSinks = []string{LogSinkSyslog, LogSinkElasticsearch, LogSinkLoki}
+
+// LogSources lists the supported log sources
+var LogSources = []string{LogSourceContainers, LogSourceSystem}
+
+// lokiLabelRegexp matches valid Loki label names
+var lokiLabelRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
 // GetLogForwarderMarshaler returns log forwarder marshaler
 func GetLogForwarderMarshaler() LogForwarderMarshaler {
 	return &logForwarderMarshaler{}
@@ -0,0 +1,131 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	check "gopkg.in/check.v1"
+)
+
+type LogForwarderSuite struct{}
+
+var _ = check.Suite(&LogForwarderSuite{})
+
+func (s *LogForwarderSuite) TestSyslogDefaults(c *check.C) {
+	forwarder := NewLogForwarder("f1", "192.168.100.1:514", "")
+	c.Assert(forwarder.CheckAndSetDefaults(), check.IsNil)
+	c.Assert(forwarder.GetSink(), check.Equals, LogSinkSyslog)
+	c.Assert(forwarder.GetProtocol(), check.Equals, "tcp")
+	c.Assert(forwarder.GetSources(), check.DeepEquals, LogSources)
+}
+
+func (s *LogForwarderSuite) TestResourceParsing(c *check.C) {
+	spec := `kind: logforwarder
+version: v2
+metadata:
+  name: es
+spec:
+  sink: elasticsearch
+  address: https://logs.example.com:9200
+  sources: [containers]
+  elasticsearch:
+    username: gravity
+    password: secret
+  tls:
+    insecure_skip_verify: true
+  buffer:
+    flush_interval: 10s
+`
+	forwarder, err := GetLogForwarderMarshaler().Unmarshal([]byte(spec))
+	c.Assert(err, check.IsNil)
+	c.Assert(forwarder.CheckAndSetDefaults(), check.IsNil)
+	c.Assert(forwarder.GetSpec(), compare.DeepEquals, LogForwarderSpecV2{
+		Sink:    LogSinkElasticsearch,
+		Address: "https://logs.example.com:9200",
+		Sources: []string{LogSourceContainers},
+		Elasticsearch: &LogForwarderElasticsearch{
+			Index:    defaults.LogForwarderElasticsearchIndex,
+			Username: "gravity",
+			Password: "secret",
+		},
+		TLS: &LogForwarderTLS{InsecureSkipVerify: true},
+		Buffer: &LogForwarderBuffer{
+			MaxSize:       utils.Capacity(defaults.LogForwarderBufferSize),
+			FlushInterval: teleservices.NewDuration(10 * time.Second),
+		},
+	})
+}
+
+func (s *LogForwarderSuite) TestValidation(c *check.C) {
+	testCases := []struct {
+		spec    LogForwarderSpecV2
+		comment string
+	}{
+		{
+			spec:    LogForwarderSpecV2{Address: "127.0.0.1:514", Sink: "kafka"},
+			comment: "unsupported sink",
+		},
+		{
+			spec:    LogForwarderSpecV2{Address: "127.0.0.1:514", Sources: []string{"audit"}},
+			comment: "unsupported source",
+		},
+		{
+			spec:    LogForwarderSpecV2{Address: "127.0.0.1:514", Protocol: "udp", TLS: &LogForwarderTLS{}},
+			comment: "syslog TLS over udp",
+		},
+		{
+			spec:    LogForwarderSpecV2{Address: "logs.example.com:9200", Sink: LogSinkElasticsearch},
+			comment: "elasticsearch address is not a URL",
+		},
+		{
+			spec: LogForwarderSpecV2{Address: "http://loki:3100", Sink: LogSinkLoki,
+				TLS: &LogForwarderTLS{InsecureSkipVerify: true}},
+			comment: "TLS with http address",
+		},
+		{
+			spec: LogForwarderSpecV2{Address: "http://loki:3100", Sink: LogSinkLoki,
+				Loki: &LogForwarderLoki{Labels: map[string]string{"cluster-name": "example"}}},
+			comment: "invalid loki label",
+		},
+		{
+			spec: LogForwarderSpecV2{Address: "127.0.0.1:514",
+				Loki: &LogForwarderLoki{TenantID: "example"}},
+			comment: "loki settings with syslog sink",
+		},
+		{
+			spec: LogForwarderSpecV2{Address: "127.0.0.1:514",
+				TLS: &LogForwarderTLS{CertData: "cert"}},
+			comment: "client certificate without key",
+		},
+		{
+			spec: LogForwarderSpecV2{Address: "127.0.0.1:514",
+				TLS: &LogForwarderTLS{CAData: "not a certificate"}},
+			comment: "CA certificate is not PEM-encoded",
+		},
+	}
+	for _, tc := range testCases {
+		forwarder := NewLogForwarder("f1", "", "").(*LogForwarderV2)
+		forwarder.Spec = tc.spec
+		c.Assert(forwarder.CheckAndSetDefaults(), check.NotNil, check.Commentf(tc.comment))
+	}
+}
@@ -19,10 +19,14 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+
+	"github.com/gravitational/gravity/lib/utils"
 
 	teleservices "github.com/gravitational/teleport/lib/services"
 	teleutils "github.com/gravitational/teleport/lib/utils"
 	"github.com/gravitational/trace"
+	"github.com/prometheus/common/model"
 )
 
 // Alert describes a monitoring alert
@@ -33,6 +37,8 @@ type Alert interface {
 	CheckAndSetDefaults() error
 	// GetFormula returns the kapacitor formula
 	GetFormula() string
+	// GetRule returns the Prometheus alerting rule
+	GetRule() *AlertRule
 }
 
 // AlertV2 defines a monitoring alert
@@ -52,10 +58,23 @@ func (r *AlertV2) GetFormula() string {
 	return r.Spec.Formula
 }
 
+// GetRule returns alert's Prometheus alerting rule
+func (r *AlertV2) GetRule() *AlertRule {
+	return r.Spec.Rule
+}
+
 // CheckAndSetDefaults checks validity of all parameters and sets defaults
 func (r *AlertV2) CheckAndSetDefaults() error {
-	if r.Spec.Formula == "" {
-		return trace.BadParameter("missing parameter Formula")
+	if r.Spec.Formula == "" && r.Spec.Rule == nil {
+		return trace.BadParameter("either Formula or Rule should be specified")
+	}
+	if r.Spec.Formula != "" && r.Spec.Rule != nil {
+		return trace.BadParameter("Formula and Rule are mutually exclusive")
+	}
+	if r.Spec.Rule != nil {
+		if err := r.Spec.Rule.CheckAndSetDefaults(); err != nil {
+			return trace.Wrap(err)
+		}
 	}
 
 	if r.Metadata.Name == "" {
@@ -104,16 +123,76 @@ func MarshalAlert(alert Alert, opts ...teleservices.MarshalOption) ([]byte, erro
 // AlertSpecV2 defines a monitoring alert
 type AlertSpecV2 struct {
 	// Formula defines a formula for kapacitor
-	Formula string `json:"formula"`
+	Formula string `json:"formula,omitempty"`
+	// Rule defines a Prometheus alerting rule
+	Rule *AlertRule `json:"rule,omitempty"`
 }
 
+// AlertRule defines a Prometheus alerting rule
+type AlertRule struct {
+	// Expr is the PromQL expression to evaluate
+	Expr string `json:"expr"`
+	// For is how long the expression should hold before the alert fires,
+	// in Prometheus duration format, e.g. "5m"
+	For string `json:"for,omitempty"`
+	// Severity is the alert severity: info, warning or critical
+	Severity string `json:"severity,omitempty"`
+	// Labels are additional labels attached to the alert
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are informational labels, e.g. summary or description
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// CheckAndSetDefaults validates the rule and sets defaults
+func (r *AlertRule) CheckAndSetDefaults() error {
+	if r.Expr == "" {
+		return trace.BadParameter("missing alerting rule expression")
+	}
+	if r.For != "" {
+		if _, err := model.ParseDuration(r.For); err != nil {
+			return trace.BadParameter("invalid alerting rule duration %q: %v", r.For, err)
+		}
+	}
+	if r.Severity == "" {
+		r.Severity = AlertSeverityWarning
+	}
+	if !utils.StringInSlice(AlertSeverities, r.Severity) {
+		return trace.BadParameter("unsupported alert severity %q, supported are: %v",
+			r.Severity, AlertSeverities)
+	}
+	return nil
+}
+
+const (
+	// AlertSeverityInfo is the severity of informational alerts
+	AlertSeverityInfo = "info"
+	// AlertSeverityWarning is the severity of alerts that need attention
+	AlertSeverityWarning = "warning"
+	// AlertSeverityCritical is the severity of alerts that need immediate action
+	AlertSeverityCritical = "critical"
+)
+
+// AlertSeverities lists the supported alert severities
+var AlertSeverities = []string{AlertSeverityInfo, AlertSeverityWarning, AlertSeverityCritical}
+
 // AlertSpecV2Schema is JSON schema for a monitoring alert
 const AlertSpecV2Schema = `{
   "type": "object",
   "additionalProperties": false,
-  "required": ["formula"],
   "properties": {
-    "formula": {"type": "string"}
+    "formula": {"type": "string"},
+    "rule": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["expr"],
+      "properties": {
+        "expr": {"type": "string"},
+        "for": {"type": "string"},
+        "severity": {"type": "string"},
+        "labels": {"type": "object", "patternProperties": {"^.*$": {"type": "string"}}},
+        "annotations": {"type": "object", "patternProperties": {"^.*$": {"type": "string"}}}
+      }
+    }
   }
 }`
 
@@ -131,6 +210,8 @@ type AlertTarget interface {
 	CheckAndSetDefaults() error
 	// GetEmail returns the recipient's email
 	GetEmail() string
+	// GetWebhookURL returns the URL alerts are posted to
+	GetWebhookURL() string
 }
 
 // AlertTargetV2 defines a monitoring alert target
@@ -150,10 +231,22 @@ func (r *AlertTargetV2) GetEmail() string {
 	return r.Spec.Email
 }
 
+// GetWebhookURL returns the URL alerts are posted to
+func (r *AlertTargetV2) GetWebhookURL() string {
+	return r.Spec.WebhookURL
+}
+
 // CheckAndSetDefaults checks validity of all parameters and sets defaults
 func (r *AlertTargetV2) CheckAndSetDefaults() error {
-	if r.Spec.Email == "" {
-		return trace.BadParameter("missing parameter Email")
+	if r.Spec.Email == "" && r.Spec.WebhookURL == "" {
+		return trace.BadParameter("either Email or WebhookURL should be specified")
+	}
+	if r.Spec.WebhookURL != "" {
+		u, err := url.Parse(r.Spec.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return trace.BadParameter("webhook URL should be an http(s) URL, got %q",
+				r.Spec.WebhookURL)
+		}
 	}
 
 	return nil
@@ -198,16 +291,18 @@ func MarshalAlertTarget(target AlertTarget, opts ...teleservices.MarshalOption)
 // AlertTargetSpecV2 defines a monitoring alert target
 type AlertTargetSpecV2 struct {
 	// Email specifies recipient's email
-	Email string `json:"email"`
+	Email string `json:"email,omitempty"`
+	// WebhookURL specifies the URL alerts are posted to
+	WebhookURL string `json:"webhook_url,omitempty"`
 }
 
 // AlertTargetSpecV2Schema is JSON schema for a monitoring alert target
 const AlertTargetSpecV2Schema = `{
   "type": "object",
   "additionalProperties": false,
-  "required": ["email"],
   "properties": {
-    "email": {"type": "string"}
+    "email": {"type": "string"},
+    "webhook_url": {"type": "string"}
   }
 }`
 
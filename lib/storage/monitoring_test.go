@@ -0,0 +1,123 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"github.com/gravitational/gravity/lib/compare"
+
+	check "gopkg.in/check.v1"
+)
+
+type MonitoringSuite struct{}
+
+var _ = check.Suite(&MonitoringSuite{})
+
+func (s *MonitoringSuite) TestAlertRuleParsing(c *check.C) {
+	spec := `kind: alert
+version: v2
+metadata:
+  name: high-cpu
+spec:
+  rule:
+    expr: node_load1 > 4
+    for: 5m
+    labels:
+      team: ops
+    annotations:
+      summary: High CPU load
+`
+	alert, err := UnmarshalAlert([]byte(spec))
+	c.Assert(err, check.IsNil)
+	c.Assert(alert.CheckAndSetDefaults(), check.IsNil)
+	c.Assert(alert.GetFormula(), check.Equals, "")
+	c.Assert(alert.GetRule(), compare.DeepEquals, &AlertRule{
+		Expr:        "node_load1 > 4",
+		For:         "5m",
+		Severity:    AlertSeverityWarning,
+		Labels:      map[string]string{"team": "ops"},
+		Annotations: map[string]string{"summary": "High CPU load"},
+	})
+}
+
+func (s *MonitoringSuite) TestAlertValidation(c *check.C) {
+	testCases := []struct {
+		spec    string
+		comment string
+	}{
+		{
+			spec: `kind: alert
+version: v2
+metadata:
+  name: empty
+spec: {}
+`,
+			comment: "neither formula nor rule",
+		},
+		{
+			spec: `kind: alert
+version: v2
+metadata:
+  name: both
+spec:
+  formula: 'var cpu = stream'
+  rule:
+    expr: up == 0
+`,
+			comment: "both formula and rule",
+		},
+		{
+			spec: `kind: alert
+version: v2
+metadata:
+  name: duration
+spec:
+  rule:
+    expr: up == 0
+    for: 5 minutes
+`,
+			comment: "invalid duration",
+		},
+		{
+			spec: `kind: alert
+version: v2
+metadata:
+  name: severity
+spec:
+  rule:
+    expr: up == 0
+    severity: fatal
+`,
+			comment: "unsupported severity",
+		},
+	}
+	for _, tc := range testCases {
+		alert, err := UnmarshalAlert([]byte(tc.spec))
+		c.Assert(err, check.IsNil, check.Commentf(tc.comment))
+		c.Assert(alert.CheckAndSetDefaults(), check.NotNil, check.Commentf(tc.comment))
+	}
+}
+
+func (s *MonitoringSuite) TestAlertTargetValidation(c *check.C) {
+	target := AlertTargetV2{Spec: AlertTargetSpecV2{WebhookURL: "https://hooks.example.com/alerts"}}
+	c.Assert(target.CheckAndSetDefaults(), check.IsNil)
+
+	target = AlertTargetV2{}
+	c.Assert(target.CheckAndSetDefaults(), check.NotNil)
+
+	target = AlertTargetV2{Spec: AlertTargetSpecV2{WebhookURL: "hooks.example.com"}}
+	c.Assert(target.CheckAndSetDefaults(), check.NotNil)
+}
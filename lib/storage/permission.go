@@ -0,0 +1,301 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/utils"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+)
+
+// ResourcePermission grants a set of actions on a kind of resources,
+// e.g. on the packages and applications of a repository
+type ResourcePermission struct {
+	// Resource is the kind of resources the permission applies to,
+	// one of PermissionResources, "*" matches all resources.
+	// Defaults to package repositories and applications
+	Resource string `json:"resource,omitempty"`
+	// Repository is the name of the repository the permission applies to,
+	// "*" matches all repositories. Only applies to repositories and applications
+	Repository string `json:"repository,omitempty"`
+	// Actions is a list of actions allowed on the resources
+	Actions []string `json:"actions"`
+}
+
+// ParseResourcePermission parses the permission in the
+// "resource[/repository]:action[,action...]" format, e.g. "app:publish"
+// or "app/example.com:read,delete"
+func ParseResourcePermission(permission string) (*ResourcePermission, error) {
+	parts := strings.Split(permission, ":")
+	if len(parts) != 2 {
+		return nil, trace.BadParameter("invalid permission %q, expected resource:action, e.g. app:publish", permission)
+	}
+	result := ResourcePermission{Resource: parts[0], Actions: strings.Split(parts[1], ",")}
+	if resource := strings.SplitN(parts[0], "/", 2); len(resource) == 2 {
+		result.Resource, result.Repository = resource[0], resource[1]
+	} else if result.Resource == PermissionResourceApp {
+		result.Repository = teleservices.Wildcard
+	}
+	if err := result.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &result, nil
+}
+
+// Check validates the permission
+func (p ResourcePermission) Check() error {
+	resource := p.resource()
+	if _, ok := permissionResourceKinds[resource]; !ok && resource != teleservices.Wildcard {
+		return trace.BadParameter("unsupported resource %q, supported are: %v",
+			resource, PermissionResources())
+	}
+	if resource == PermissionResourceApp && p.Repository == "" {
+		return trace.BadParameter("missing repository name")
+	}
+	if resource != PermissionResourceApp && resource != teleservices.Wildcard && p.Repository != "" {
+		return trace.BadParameter("%v: repository only applies to %v permissions",
+			resource, PermissionResourceApp)
+	}
+	if len(p.Actions) == 0 {
+		return trace.BadParameter("%v: missing actions", p.name())
+	}
+	for _, action := range p.Actions {
+		if _, ok := permissionActionVerbs[action]; !ok && action != teleservices.Wildcard {
+			return trace.BadParameter("%v: unsupported action %q, supported are: %v",
+				p.name(), action, PermissionActions())
+		}
+	}
+	return nil
+}
+
+// String returns the permission representation, e.g. "app/example.com:read,publish"
+func (p ResourcePermission) String() string {
+	return fmt.Sprintf("%v:%v", p.name(), strings.Join(p.Actions, ","))
+}
+
+// Allows returns true if the permission allows the specified verb on the
+// resources of the specified kind. The repository only applies to
+// repositories and applications
+func (p ResourcePermission) Allows(kind, repository, verb string) bool {
+	resource := p.resource()
+	if resource != teleservices.Wildcard && !utils.StringInSlice(permissionResourceKinds[resource], kind) {
+		return false
+	}
+	if !p.matchesRepository(kind, repository) {
+		return false
+	}
+	return utils.StringInSlice(p.Verbs(), teleservices.Wildcard) || utils.StringInSlice(p.Verbs(), verb)
+}
+
+// Verbs returns the resource verbs granted by this permission
+func (p ResourcePermission) Verbs() (verbs []string) {
+	for _, action := range p.Actions {
+		if action == teleservices.Wildcard {
+			return []string{teleservices.Wildcard}
+		}
+		for _, verb := range permissionActionVerbs[action] {
+			if !utils.StringInSlice(verbs, verb) {
+				verbs = append(verbs, verb)
+			}
+		}
+	}
+	return verbs
+}
+
+// Rule returns the role rule granting this permission
+func (p ResourcePermission) Rule() teleservices.Rule {
+	rule := teleservices.Rule{
+		Resources: []string{teleservices.Wildcard},
+		Verbs:     p.Verbs(),
+	}
+	if resource := p.resource(); resource != teleservices.Wildcard {
+		rule.Resources = permissionRuleResources[resource]
+	}
+	if p.Repository != "" && p.Repository != teleservices.Wildcard {
+		rule.Where = EqualsExpr{
+			Left:  ResourceNameExpr,
+			Right: StringExpr(p.Repository),
+		}.String()
+	}
+	return rule
+}
+
+// matchesRepository returns true if the permission applies to the specified
+// repository. Resources other than repositories and applications do not
+// belong to a repository
+func (p ResourcePermission) matchesRepository(kind, repository string) bool {
+	if kind != KindRepository && kind != KindApp {
+		return true
+	}
+	return p.Repository == "" || p.Repository == teleservices.Wildcard || p.Repository == repository
+}
+
+// resource returns the kind of resources the permission applies to
+func (p ResourcePermission) resource() string {
+	if p.Resource == "" {
+		return PermissionResourceApp
+	}
+	return p.Resource
+}
+
+// name returns the resources the permission applies to, e.g. "app/example.com"
+func (p ResourcePermission) name() string {
+	if p.Repository == "" || p.Repository == teleservices.Wildcard {
+		return p.resource()
+	}
+	return fmt.Sprintf("%v/%v", p.resource(), p.Repository)
+}
+
+// ResourcePermissions is a list of resource permissions
+type ResourcePermissions []ResourcePermission
+
+// ParseResourcePermissions parses the list of permissions in the
+// "resource[/repository]:action[,action...]" format
+func ParseResourcePermissions(permissions []string) (ResourcePermissions, error) {
+	result := make(ResourcePermissions, 0, len(permissions))
+	for _, permission := range permissions {
+		parsed, err := ParseResourcePermission(permission)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		result = append(result, *parsed)
+	}
+	return result, nil
+}
+
+// Check validates all permissions in the list
+func (r ResourcePermissions) Check() error {
+	for _, permission := range r {
+		if err := permission.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// String returns the representation of the permissions list
+func (r ResourcePermissions) String() string {
+	permissions := make([]string, 0, len(r))
+	for _, permission := range r {
+		permissions = append(permissions, permission.String())
+	}
+	return strings.Join(permissions, " ")
+}
+
+// Allows returns true if any of the permissions allows the specified verb
+// on the resources of the specified kind in the specified repository
+func (r ResourcePermissions) Allows(kind, repository, verb string) bool {
+	for _, permission := range r {
+		if permission.Allows(kind, repository, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns the role rules granting these permissions
+func (r ResourcePermissions) Rules() (rules []teleservices.Rule) {
+	for _, permission := range r {
+		rules = append(rules, permission.Rule())
+	}
+	return rules
+}
+
+// PermissionResources returns the supported permission resources
+func PermissionResources() (resources []string) {
+	for resource := range permissionResourceKinds {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	return resources
+}
+
+// PermissionActions returns the supported permission actions
+func PermissionActions() (actions []string) {
+	for action := range permissionActionVerbs {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+const (
+	// PermissionResourceApp grants actions on package repositories,
+	// their packages and applications
+	PermissionResourceApp = "app"
+	// PermissionResourceCluster grants actions on clusters and their operations
+	PermissionResourceCluster = "cluster"
+	// PermissionResourceUser grants actions on users
+	PermissionResourceUser = "user"
+)
+
+const (
+	// PermissionActionRead allows to list and download resources
+	PermissionActionRead = "read"
+	// PermissionActionPublish allows to push packages and applications
+	// and publish them to the catalog
+	PermissionActionPublish = "publish"
+	// PermissionActionUpdate allows to create and update resources
+	PermissionActionUpdate = "update"
+	// PermissionActionDelete allows to delete resources
+	PermissionActionDelete = "delete"
+)
+
+// permissionResourceKinds maps permission resources to resource kinds
+var permissionResourceKinds = map[string][]string{
+	PermissionResourceApp:     {KindApp, KindRepository},
+	PermissionResourceCluster: {KindCluster},
+	PermissionResourceUser:    {teleservices.KindUser},
+}
+
+// permissionRuleResources maps permission resources to the resources of
+// the role rules granting them. Applications are granted by the rules
+// for their repositories
+var permissionRuleResources = map[string][]string{
+	PermissionResourceApp:     {KindRepository},
+	PermissionResourceCluster: {KindCluster},
+	PermissionResourceUser:    {teleservices.KindUser},
+}
+
+// permissionActionVerbs maps permission actions to resource verbs.
+// All actions imply read access since it is required to perform them
+var permissionActionVerbs = map[string][]string{
+	PermissionActionRead:    {teleservices.VerbList, teleservices.VerbRead},
+	PermissionActionPublish: {teleservices.VerbList, teleservices.VerbRead, teleservices.VerbCreate, teleservices.VerbUpdate, VerbPublish},
+	PermissionActionUpdate:  {teleservices.VerbList, teleservices.VerbRead, teleservices.VerbCreate, teleservices.VerbUpdate},
+	PermissionActionDelete:  {teleservices.VerbList, teleservices.VerbRead, teleservices.VerbDelete},
+}
+
+// ResourcePermissionsSchema is the JSON schema of permissions
+const ResourcePermissionsSchema = `{
+  "type": "array",
+  "items": {
+    "type": "object",
+    "additionalProperties": false,
+    "required": ["actions"],
+    "properties": {
+      "resource": {"type": "string"},
+      "repository": {"type": "string"},
+      "actions": {"type": "array", "items": {"type": "string"}}
+    }
+  }
+}`
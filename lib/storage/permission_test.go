@@ -0,0 +1,138 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	teleservices "github.com/gravitational/teleport/lib/services"
+	check "gopkg.in/check.v1"
+)
+
+type ResourcePermissionSuite struct{}
+
+var _ = check.Suite(&ResourcePermissionSuite{})
+
+func (s *ResourcePermissionSuite) TestValidation(c *check.C) {
+	testCases := []struct {
+		permission ResourcePermission
+		valid      bool
+		comment    string
+	}{
+		{
+			permission: ResourcePermission{Repository: "example.com", Actions: PermissionActions()},
+			valid:      true,
+			comment:    "all actions",
+		},
+		{
+			permission: ResourcePermission{Resource: PermissionResourceCluster, Actions: []string{teleservices.Wildcard}},
+			valid:      true,
+			comment:    "cluster permission",
+		},
+		{
+			permission: ResourcePermission{Actions: []string{PermissionActionRead}},
+			comment:    "missing repository",
+		},
+		{
+			permission: ResourcePermission{Resource: PermissionResourceUser, Repository: "example.com", Actions: []string{PermissionActionRead}},
+			comment:    "repository of a user permission",
+		},
+		{
+			permission: ResourcePermission{Repository: "example.com"},
+			comment:    "missing actions",
+		},
+		{
+			permission: ResourcePermission{Repository: "example.com", Actions: []string{"write"}},
+			comment:    "unsupported action",
+		},
+		{
+			permission: ResourcePermission{Resource: "node", Actions: []string{PermissionActionRead}},
+			comment:    "unsupported resource",
+		},
+	}
+	for _, tc := range testCases {
+		err := tc.permission.Check()
+		if tc.valid {
+			c.Assert(err, check.IsNil, check.Commentf(tc.comment))
+		} else {
+			c.Assert(err, check.NotNil, check.Commentf(tc.comment))
+		}
+	}
+}
+
+func (s *ResourcePermissionSuite) TestParse(c *check.C) {
+	permissions, err := ParseResourcePermissions([]string{"app:publish", "app/example.com:read,delete", "cluster:read", "*:*"})
+	c.Assert(err, check.IsNil)
+	c.Assert(permissions, check.DeepEquals, ResourcePermissions{
+		{Resource: PermissionResourceApp, Repository: teleservices.Wildcard, Actions: []string{PermissionActionPublish}},
+		{Resource: PermissionResourceApp, Repository: "example.com", Actions: []string{PermissionActionRead, PermissionActionDelete}},
+		{Resource: PermissionResourceCluster, Actions: []string{PermissionActionRead}},
+		{Resource: teleservices.Wildcard, Actions: []string{teleservices.Wildcard}},
+	})
+	c.Assert(permissions.String(), check.Equals, "app:publish app/example.com:read,delete cluster:read *:*")
+
+	for _, permission := range []string{"app", "app:publish:now", "node:read", "app:destroy", "cluster/example.com:read"} {
+		_, err := ParseResourcePermission(permission)
+		c.Assert(err, check.NotNil, check.Commentf(permission))
+	}
+}
+
+func (s *ResourcePermissionSuite) TestAllows(c *check.C) {
+	permissions := ResourcePermissions{
+		{Repository: "example.com", Actions: []string{PermissionActionPublish}},
+		{Repository: teleservices.Wildcard, Actions: []string{PermissionActionRead}},
+		{Resource: PermissionResourceCluster, Actions: []string{PermissionActionUpdate}},
+	}
+	var tests = []struct {
+		kind       string
+		repository string
+		verb       string
+		allowed    bool
+	}{
+		{kind: KindRepository, repository: "example.com", verb: teleservices.VerbCreate, allowed: true},
+		{kind: KindApp, repository: "example.com", verb: VerbPublish, allowed: true},
+		{kind: KindRepository, repository: "example.com", verb: teleservices.VerbDelete, allowed: false},
+		{kind: KindRepository, repository: "other.com", verb: teleservices.VerbRead, allowed: true},
+		{kind: KindApp, repository: "other.com", verb: teleservices.VerbUpdate, allowed: false},
+		{kind: KindCluster, verb: teleservices.VerbUpdate, allowed: true},
+		{kind: KindCluster, verb: teleservices.VerbDelete, allowed: false},
+		{kind: teleservices.KindUser, verb: teleservices.VerbRead, allowed: false},
+	}
+	for _, tc := range tests {
+		c.Assert(permissions.Allows(tc.kind, tc.repository, tc.verb), check.Equals, tc.allowed,
+			check.Commentf("%v %v in %q", tc.verb, tc.kind, tc.repository))
+	}
+	c.Assert(permissions.String(), check.Equals, "app/example.com:publish app:read cluster:update")
+
+	all := ResourcePermissions{{Resource: teleservices.Wildcard, Actions: []string{PermissionActionRead}}}
+	c.Assert(all.Allows(teleservices.KindUser, "", teleservices.VerbList), check.Equals, true)
+	c.Assert(all.Allows(teleservices.KindUser, "", teleservices.VerbCreate), check.Equals, false)
+}
+
+func (s *ResourcePermissionSuite) TestTokenRoundtrip(c *check.C) {
+	token := NewTokenFromV1(APIKey{
+		Token:     "token",
+		UserEmail: "alice@example.com",
+		Permissions: ResourcePermissions{
+			{Repository: "example.com", Actions: []string{PermissionActionRead}},
+			{Resource: PermissionResourceCluster, Actions: []string{PermissionActionRead}},
+		},
+	})
+	data, err := GetTokenMarshaler().MarshalToken(token)
+	c.Assert(err, check.IsNil)
+	out, err := GetTokenMarshaler().UnmarshalToken(data)
+	c.Assert(err, check.IsNil)
+	c.Assert(out.GetPermissions(), check.DeepEquals, token.GetPermissions())
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package storage
 
 import (
+	"sort"
 	"time"
 
 	"github.com/gravitational/gravity/lib/loc"
@@ -83,10 +84,78 @@ type OperationPhase struct {
 	Parallel bool `json:"parallel"`
 	// Updated is the last phase update time
 	Updated time.Time `json:"updated,omitempty" yaml:"updated,omitempty"`
+	// Started is the time the phase was last started
+	Started time.Time `json:"started,omitempty" yaml:"started,omitempty"`
 	// Data is optional phase-specific data attached to the phase
 	Data *OperationPhaseData `json:"data,omitempty" yaml:"data,omitempty"`
 	// Error is the error that happened during phase execution
 	Error *trace.RawTrace `json:"error,omitempty"`
+	// Retry is the optional policy of retrying failed phase execution.
+	// A phase without the policy is executed once
+	Retry *RetryPolicy `json:"retry,omitempty" yaml:"retry,omitempty"`
+	// Attempts lists the failed attempts to execute the phase
+	Attempts []PhaseAttempt `json:"attempts,omitempty" yaml:"attempts,omitempty"`
+}
+
+// RetryPolicy defines how a failed phase is retried
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times the phase is executed
+	MaxAttempts int `json:"max_attempts" yaml:"max_attempts"`
+	// InitialBackoff is the delay before the first retry which doubles
+	// with every following retry
+	InitialBackoff time.Duration `json:"initial_backoff,omitempty" yaml:"initial_backoff,omitempty"`
+	// MaxBackoff limits the delay between retries
+	MaxBackoff time.Duration `json:"max_backoff,omitempty" yaml:"max_backoff,omitempty"`
+	// RetryOn lists the classes of errors the phase is retried on.
+	// Connection problems, exceeded limits and explicit retry errors
+	// are retried if unspecified
+	RetryOn []string `json:"retry_on,omitempty" yaml:"retry_on,omitempty"`
+}
+
+// Check validates the retry policy
+func (r RetryPolicy) Check() error {
+	if r.MaxAttempts < 1 {
+		return trace.BadParameter("retry policy requires at least one attempt")
+	}
+	if r.InitialBackoff < 0 || r.MaxBackoff < 0 {
+		return trace.BadParameter("retry backoff cannot be negative")
+	}
+	for _, class := range r.RetryOn {
+		if !utils.StringInSlice(RetryErrorClasses, class) {
+			return trace.BadParameter("unknown error class %q, supported are: %v",
+				class, RetryErrorClasses)
+		}
+	}
+	return nil
+}
+
+// PhaseAttempt describes a failed attempt to execute a phase
+type PhaseAttempt struct {
+	// Attempt is the attempt number starting from 1
+	Attempt int `json:"attempt"`
+	// Time is the time the attempt failed
+	Time time.Time `json:"time"`
+	// Error is the error the attempt failed with
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+const (
+	// RetryOnConnectionProblem retries phases failed due to connection problems
+	RetryOnConnectionProblem = "connection_problem"
+	// RetryOnLimitExceeded retries phases failed due to exceeded limits or timeouts
+	RetryOnLimitExceeded = "limit_exceeded"
+	// RetryOnRetryError retries phases that failed with an explicit retry error
+	RetryOnRetryError = "retry"
+	// RetryOnAny retries phases failed with any error
+	RetryOnAny = "any"
+)
+
+// RetryErrorClasses lists the supported classes of retried errors
+var RetryErrorClasses = []string{
+	RetryOnConnectionProblem,
+	RetryOnLimitExceeded,
+	RetryOnRetryError,
+	RetryOnAny,
 }
 
 // OperationPhaseData represents data attached to an operation phase
@@ -125,6 +194,8 @@ type OperationPhaseData struct {
 	Update *UpdateOperationData `json:"update,omitempty" yaml:"garbage_collect,omitempty"`
 	// Install specifies configuration specific to install operation
 	Install *InstallOperationData `json:"install,omitempty" yaml:"install,omitempty"`
+	// Drain specifies optional parameters for draining a node
+	Drain *DrainOptions `json:"drain,omitempty" yaml:"drain,omitempty"`
 }
 
 // ElectionChange describes changes to make to cluster elections
@@ -147,6 +218,20 @@ type UpdateOperationData struct {
 	// The list might be a subset of all cluster servers in case
 	// the operation only operates on a specific part
 	Servers []UpdateServer `json:"updates,omitempty"`
+	// IntermediateSteps lists the configuration updates for each intermediate
+	// runtime the cluster is upgraded through before the target runtime
+	IntermediateSteps []IntermediateUpdateStep `json:"intermediate_steps,omitempty"`
+	// ChangesetID optionally specifies the ID of the package changeset
+	// for the system update step. Defaults to the operation ID
+	ChangesetID string `json:"changeset_id,omitempty"`
+}
+
+// IntermediateUpdateStep describes the update to an intermediate runtime
+type IntermediateUpdateStep struct {
+	// Runtime identifies the intermediate runtime application
+	Runtime loc.Locator `json:"runtime"`
+	// Servers lists the configuration updates for cluster servers
+	Servers []UpdateServer `json:"updates,omitempty"`
 }
 
 // UpdateServer describes an intent to update runtime/teleport configuration
@@ -232,6 +317,8 @@ type PlanChange struct {
 	Created time.Time `json:"created"`
 	// Error is the error that happened during phase execution
 	Error *trace.RawTrace `json:"error"`
+	// Attempt is the number of the phase execution attempt
+	Attempt int `json:"attempt,omitempty"`
 }
 
 // PlanChangelog is a list of plan state changes
@@ -251,6 +338,46 @@ func (c PlanChangelog) Latest(phaseID string) *PlanChange {
 	return latest
 }
 
+// LatestInState returns the most recent plan change entry that moved
+// the specified phase into the specified state
+func (c PlanChangelog) LatestInState(phaseID, state string) *PlanChange {
+	var latest *PlanChange
+	for i, change := range c {
+		if change.PhaseID != phaseID || change.NewState != state {
+			continue
+		}
+		if latest == nil || change.Created.After(latest.Created) {
+			latest = &(c[i])
+		}
+	}
+	return latest
+}
+
+// Attempts returns the failed attempts to execute the specified phase
+// ordered by time
+func (c PlanChangelog) Attempts(phaseID string) (attempts []PhaseAttempt) {
+	for _, change := range c {
+		if change.PhaseID != phaseID || change.NewState != OperationPhaseStateFailed {
+			continue
+		}
+		attempt := PhaseAttempt{
+			Attempt: change.Attempt,
+			Time:    change.Created,
+		}
+		if change.Error != nil {
+			var traceErr trace.TraceErr
+			if err := utils.UnmarshalError(change.Error.Err, &traceErr); err == nil && traceErr.Err != nil {
+				attempt.Error = traceErr.Err.Error()
+			}
+		}
+		attempts = append(attempts, attempt)
+	}
+	sort.Slice(attempts, func(i, j int) bool {
+		return attempts[i].Time.Before(attempts[j].Time)
+	})
+	return attempts
+}
+
 // HasSubphases returns true if the phase has 1 or more subphases
 func (p OperationPhase) HasSubphases() bool {
 	return len(p.Phases) > 0
@@ -295,6 +422,22 @@ func (p OperationPhase) GetLastUpdateTime() time.Time {
 	return last
 }
 
+// GetStartTime returns the time the phase was started which for
+// a phase with subphases is the earliest start time of its subphases
+func (p OperationPhase) GetStartTime() time.Time {
+	if len(p.Phases) == 0 {
+		return p.Started
+	}
+	var first time.Time
+	for _, phase := range p.Phases {
+		started := phase.GetStartTime()
+		if !started.IsZero() && (first.IsZero() || started.Before(first)) {
+			first = started
+		}
+	}
+	return first
+}
+
 // GetState returns the phase state based on the states of all its subphases
 func (p OperationPhase) GetState() string {
 	// if the phase doesn't have subphases, then just return its state from property
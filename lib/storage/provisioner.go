@@ -0,0 +1,483 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+)
+
+// Provisioner defines the cloud provider account and the machine
+// configuration used to create the machines of new cluster nodes
+type Provisioner interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// GetType returns the cloud provider type
+	GetType() string
+	// GetAWS returns the AWS provisioner configuration
+	GetAWS() *AWSProvisionerSpec
+	// GetGCE returns the GCE provisioner configuration
+	GetGCE() *GCEProvisionerSpec
+	// GetVSphere returns the vSphere provisioner configuration
+	GetVSphere() *VSphereProvisionerSpec
+	// GetProfiles returns the names of the node profiles
+	// the provisioner can create machines for
+	GetProfiles() []string
+	// CheckAndSetDefaults validates the resource and sets defaults
+	CheckAndSetDefaults() error
+}
+
+// NewProvisioner creates a new provisioner resource
+func NewProvisioner(name string, spec ProvisionerSpecV2) Provisioner {
+	return &ProvisionerV2{
+		Kind:    KindProvisioner,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      name,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// ProvisionerV2 defines the provisioner resource
+type ProvisionerV2 struct {
+	// Kind is the resource kind
+	Kind string `json:"kind"`
+	// Version is the resource version
+	Version string `json:"version"`
+	// Metadata is the resource metadata
+	Metadata teleservices.Metadata `json:"metadata"`
+	// Spec is the resource specification
+	Spec ProvisionerSpecV2 `json:"spec"`
+}
+
+// ProvisionerSpecV2 defines the provisioner resource specification
+type ProvisionerSpecV2 struct {
+	// Type is the cloud provider type: aws, gce or vsphere
+	Type string `json:"type"`
+	// AWS configures the provisioner of AWS instances
+	AWS *AWSProvisionerSpec `json:"aws,omitempty"`
+	// GCE configures the provisioner of GCE instances
+	GCE *GCEProvisionerSpec `json:"gce,omitempty"`
+	// VSphere configures the provisioner of vSphere virtual machines
+	VSphere *VSphereProvisionerSpec `json:"vsphere,omitempty"`
+}
+
+// AWSProvisionerSpec configures the provisioner of AWS instances
+type AWSProvisionerSpec struct {
+	// Region is the AWS region instances are created in
+	Region string `json:"region"`
+	// AccessKey is the AWS access key ID. If empty, the credentials
+	// of the instance role of the master node are used
+	AccessKey string `json:"access_key,omitempty"`
+	// SecretKey is the AWS secret access key
+	SecretKey string `json:"secret_key,omitempty"`
+	// Profiles maps node profile names to the instance configuration
+	Profiles map[string]AWSMachineSpec `json:"profiles"`
+}
+
+// AWSMachineSpec defines the configuration of AWS instances of a node profile
+type AWSMachineSpec struct {
+	// InstanceType is the instance type, e.g. m5.xlarge
+	InstanceType string `json:"instance_type"`
+	// ImageID is the ID of the AMI to boot the instance from
+	ImageID string `json:"image_id"`
+	// SubnetID is the ID of the subnet to launch the instance in
+	SubnetID string `json:"subnet_id,omitempty"`
+	// SecurityGroupIDs lists the IDs of the security groups of the instance
+	SecurityGroupIDs []string `json:"security_group_ids,omitempty"`
+	// KeyName is the name of the SSH key pair
+	KeyName string `json:"key_name,omitempty"`
+	// InstanceProfile is the name of the IAM instance profile
+	InstanceProfile string `json:"instance_profile,omitempty"`
+	// DiskSizeGB is the size of the root volume in gigabytes
+	DiskSizeGB int64 `json:"disk_size_gb,omitempty"`
+}
+
+// GCEProvisionerSpec configures the provisioner of GCE instances
+type GCEProvisionerSpec struct {
+	// Project is the ID of the project instances are created in
+	Project string `json:"project"`
+	// Zone is the zone instances are created in
+	Zone string `json:"zone"`
+	// Credentials is the JSON key of the service account
+	Credentials string `json:"credentials"`
+	// Profiles maps node profile names to the instance configuration
+	Profiles map[string]GCEMachineSpec `json:"profiles"`
+}
+
+// GCEMachineSpec defines the configuration of GCE instances of a node profile
+type GCEMachineSpec struct {
+	// MachineType is the machine type, e.g. n1-standard-4
+	MachineType string `json:"machine_type"`
+	// Image is the source image of the boot disk,
+	// e.g. projects/centos-cloud/global/images/family/centos-7
+	Image string `json:"image"`
+	// Network is the network of the instance, defaults to the default network
+	Network string `json:"network,omitempty"`
+	// Subnetwork is the subnetwork of the instance
+	Subnetwork string `json:"subnetwork,omitempty"`
+	// DiskSizeGB is the size of the boot disk in gigabytes
+	DiskSizeGB int64 `json:"disk_size_gb,omitempty"`
+	// Tags lists the network tags of the instance
+	Tags []string `json:"tags,omitempty"`
+}
+
+// VSphereProvisionerSpec configures the provisioner of vSphere virtual machines
+type VSphereProvisionerSpec struct {
+	// Server is the address of the vCenter server
+	Server string `json:"server"`
+	// Username is the vCenter user name
+	Username string `json:"username"`
+	// Password is the vCenter user password
+	Password string `json:"password"`
+	// Insecure disables the verification of the vCenter certificate
+	Insecure bool `json:"insecure,omitempty"`
+	// Profiles maps node profile names to the virtual machine configuration
+	Profiles map[string]VSphereMachineSpec `json:"profiles"`
+}
+
+// VSphereMachineSpec defines the configuration of vSphere virtual machines
+// of a node profile
+type VSphereMachineSpec struct {
+	// Template is the identifier of the virtual machine to clone, e.g. vm-42.
+	// The template should have cloud-init installed
+	Template string `json:"template"`
+	// Folder is the identifier of the folder to create the virtual machines in
+	Folder string `json:"folder,omitempty"`
+	// ResourcePool is the identifier of the resource pool of the virtual machines
+	ResourcePool string `json:"resource_pool,omitempty"`
+	// Datastore is the identifier of the datastore of the virtual machines
+	Datastore string `json:"datastore,omitempty"`
+}
+
+// GetType returns the cloud provider type
+func (r *ProvisionerV2) GetType() string {
+	return r.Spec.Type
+}
+
+// GetAWS returns the AWS provisioner configuration
+func (r *ProvisionerV2) GetAWS() *AWSProvisionerSpec {
+	return r.Spec.AWS
+}
+
+// GetGCE returns the GCE provisioner configuration
+func (r *ProvisionerV2) GetGCE() *GCEProvisionerSpec {
+	return r.Spec.GCE
+}
+
+// GetVSphere returns the vSphere provisioner configuration
+func (r *ProvisionerV2) GetVSphere() *VSphereProvisionerSpec {
+	return r.Spec.VSphere
+}
+
+// GetProfiles returns the sorted names of the node profiles
+// the provisioner can create machines for
+func (r *ProvisionerV2) GetProfiles() (profiles []string) {
+	switch {
+	case r.Spec.AWS != nil:
+		for profile := range r.Spec.AWS.Profiles {
+			profiles = append(profiles, profile)
+		}
+	case r.Spec.GCE != nil:
+		for profile := range r.Spec.GCE.Profiles {
+			profiles = append(profiles, profile)
+		}
+	case r.Spec.VSphere != nil:
+		for profile := range r.Spec.VSphere.Profiles {
+			profiles = append(profiles, profile)
+		}
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// CheckAndSetDefaults validates the resource and sets defaults
+func (r *ProvisionerV2) CheckAndSetDefaults() error {
+	if r.Metadata.Name == "" {
+		return trace.BadParameter("provisioner name can't be empty")
+	}
+	var configured []string
+	var err error
+	if r.Spec.AWS != nil {
+		configured = append(configured, ProvisionerTypeAWS)
+		err = r.Spec.AWS.check()
+	}
+	if r.Spec.GCE != nil {
+		configured = append(configured, ProvisionerTypeGCE)
+		err = r.Spec.GCE.check()
+	}
+	if r.Spec.VSphere != nil {
+		configured = append(configured, ProvisionerTypeVSphere)
+		err = r.Spec.VSphere.check()
+	}
+	if !utils.StringInSlice(ProvisionerTypes, r.Spec.Type) {
+		return trace.BadParameter("provisioner %q: unsupported type %q, supported are: %v",
+			r.Metadata.Name, r.Spec.Type, strings.Join(ProvisionerTypes, ", "))
+	}
+	if len(configured) != 1 || configured[0] != r.Spec.Type {
+		return trace.BadParameter("provisioner %q: exactly the %v section should be set for type %q",
+			r.Metadata.Name, r.Spec.Type, r.Spec.Type)
+	}
+	if err != nil {
+		return trace.BadParameter("provisioner %q: %v", r.Metadata.Name, err)
+	}
+	return nil
+}
+
+func (r AWSProvisionerSpec) check() error {
+	if r.Region == "" {
+		return trace.BadParameter("region can't be empty")
+	}
+	if (r.AccessKey == "") != (r.SecretKey == "") {
+		return trace.BadParameter("access key and secret key should be set together")
+	}
+	if len(r.Profiles) == 0 {
+		return trace.BadParameter("at least one profile should be configured")
+	}
+	for name, profile := range r.Profiles {
+		if profile.InstanceType == "" || profile.ImageID == "" {
+			return trace.BadParameter("profile %q: instance type and image ID are required", name)
+		}
+	}
+	return nil
+}
+
+func (r GCEProvisionerSpec) check() error {
+	if r.Project == "" || r.Zone == "" {
+		return trace.BadParameter("project and zone can't be empty")
+	}
+	if r.Credentials == "" {
+		return trace.BadParameter("service account credentials can't be empty")
+	}
+	if len(r.Profiles) == 0 {
+		return trace.BadParameter("at least one profile should be configured")
+	}
+	for name, profile := range r.Profiles {
+		if profile.MachineType == "" || profile.Image == "" {
+			return trace.BadParameter("profile %q: machine type and image are required", name)
+		}
+	}
+	return nil
+}
+
+func (r VSphereProvisionerSpec) check() error {
+	if r.Server == "" || r.Username == "" || r.Password == "" {
+		return trace.BadParameter("server, username and password can't be empty")
+	}
+	if len(r.Profiles) == 0 {
+		return trace.BadParameter("at least one profile should be configured")
+	}
+	for name, profile := range r.Profiles {
+		if profile.Template == "" {
+			return trace.BadParameter("profile %q: template is required", name)
+		}
+	}
+	return nil
+}
+
+// GetName returns the resource name
+func (r *ProvisionerV2) GetName() string {
+	return r.Metadata.Name
+}
+
+// SetName sets the resource name
+func (r *ProvisionerV2) SetName(name string) {
+	r.Metadata.Name = name
+}
+
+// GetMetadata returns the resource metadata
+func (r *ProvisionerV2) GetMetadata() teleservices.Metadata {
+	return r.Metadata
+}
+
+// SetExpiry sets the resource expiration time
+func (r *ProvisionerV2) SetExpiry(expires time.Time) {
+	r.Metadata.SetExpiry(expires)
+}
+
+// Expiry returns the resource expiration time
+func (r *ProvisionerV2) Expiry() time.Time {
+	return r.Metadata.Expiry()
+}
+
+// SetTTL sets the resource TTL
+func (r *ProvisionerV2) SetTTL(clock clockwork.Clock, ttl time.Duration) {
+	r.Metadata.SetTTL(clock, ttl)
+}
+
+// String returns the object's string representation
+func (r *ProvisionerV2) String() string {
+	return fmt.Sprintf("Provisioner(Name=%v, Type=%v, Profiles=%v)",
+		r.Metadata.Name, r.Spec.Type, r.GetProfiles())
+}
+
+// UnmarshalProvisioner unmarshals provisioner resource from the provided JSON or YAML data
+func UnmarshalProvisioner(data []byte) (Provisioner, error) {
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var header teleservices.ResourceHeader
+	err = json.Unmarshal(jsonData, &header)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch header.Version {
+	case teleservices.V2:
+		var provisioner ProvisionerV2
+		err := teleutils.UnmarshalWithSchema(GetProvisionerSchema(), &provisioner, jsonData)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		provisioner.Metadata.CheckAndSetDefaults()
+		err = provisioner.CheckAndSetDefaults()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &provisioner, nil
+	}
+	return nil, trace.BadParameter("%v resource version %q is not supported",
+		KindProvisioner, header.Version)
+}
+
+// MarshalProvisioner marshals provisioner resource to JSON
+func MarshalProvisioner(provisioner Provisioner, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(provisioner)
+}
+
+// GetProvisionerSchema returns the full provisioner resource schema
+func GetProvisionerSchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, MetadataSchema,
+		ProvisionerSpecV2Schema, "")
+}
+
+// ProvisionerSpecV2Schema defines the provisioner spec schema
+var ProvisionerSpecV2Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["type"],
+  "properties": {
+    "type": {"type": "string"},
+    "aws": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "region": {"type": "string"},
+        "access_key": {"type": "string"},
+        "secret_key": {"type": "string"},
+        "profiles": {
+          "type": "object",
+          "patternProperties": {
+            "^.+$": {
+              "type": "object",
+              "additionalProperties": false,
+              "properties": {
+                "instance_type": {"type": "string"},
+                "image_id": {"type": "string"},
+                "subnet_id": {"type": "string"},
+                "security_group_ids": {"type": "array", "items": {"type": "string"}},
+                "key_name": {"type": "string"},
+                "instance_profile": {"type": "string"},
+                "disk_size_gb": {"type": "number"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "gce": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "project": {"type": "string"},
+        "zone": {"type": "string"},
+        "credentials": {"type": "string"},
+        "profiles": {
+          "type": "object",
+          "patternProperties": {
+            "^.+$": {
+              "type": "object",
+              "additionalProperties": false,
+              "properties": {
+                "machine_type": {"type": "string"},
+                "image": {"type": "string"},
+                "network": {"type": "string"},
+                "subnetwork": {"type": "string"},
+                "disk_size_gb": {"type": "number"},
+                "tags": {"type": "array", "items": {"type": "string"}}
+              }
+            }
+          }
+        }
+      }
+    },
+    "vsphere": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "server": {"type": "string"},
+        "username": {"type": "string"},
+        "password": {"type": "string"},
+        "insecure": {"type": "boolean"},
+        "profiles": {
+          "type": "object",
+          "patternProperties": {
+            "^.+$": {
+              "type": "object",
+              "additionalProperties": false,
+              "properties": {
+                "template": {"type": "string"},
+                "folder": {"type": "string"},
+                "resource_pool": {"type": "string"},
+                "datastore": {"type": "string"}
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+const (
+	// ProvisionerTypeAWS is the type of the provisioner of AWS instances
+	ProvisionerTypeAWS = "aws"
+	// ProvisionerTypeGCE is the type of the provisioner of GCE instances
+	ProvisionerTypeGCE = "gce"
+	// ProvisionerTypeVSphere is the type of the provisioner of vSphere virtual machines
+	ProvisionerTypeVSphere = "vsphere"
+)
+
+// ProvisionerTypes lists the supported provisioner types
+var ProvisionerTypes = []string{
+	ProvisionerTypeAWS,
+	ProvisionerTypeGCE,
+	ProvisionerTypeVSphere,
+}
@@ -49,6 +49,9 @@ const (
 	VerbConnect = "connect"
 	// VerbReadSecrets is used to allow reading secrets
 	VerbReadSecrets = "readsecrets"
+	// VerbPublish is used to allow publishing applications
+	// to the application catalog of a repository
+	VerbPublish = "publish"
 	// KindLogForwarder is log forwarder resource kind
 	KindLogForwarder = "logforwarder"
 	// KindTLSKeyPair is a TLS key pair
@@ -73,6 +76,17 @@ const (
 	KindRelease = "release"
 	// KindInvite defines the user invite token.
 	KindInvite = "invite"
+	// KindRoleMapping defines the resource that maps OIDC claims to roles
+	KindRoleMapping = "rolemapping"
+	// KindHealingPolicy defines the resource that controls automated remediations
+	KindHealingPolicy = "healingpolicy"
+	// KindWebhook defines the resource that configures operation event webhooks
+	KindWebhook = "webhook"
+	// KindProvisioner defines the resource that configures cloud provisioning
+	// of the machines of new cluster nodes
+	KindProvisioner = "provisioner"
+	// KindClusterDNS defines the resource that configures the cluster DNS service
+	KindClusterDNS = "clusterdns"
 )
 
 // CanonicalKind translates the specified kind to canonical form.
@@ -81,6 +95,8 @@ func CanonicalKind(kind string) string {
 	switch strings.ToLower(kind) {
 	case teleservices.KindGithubConnector:
 		return teleservices.KindGithubConnector
+	case teleservices.KindSAMLConnector:
+		return teleservices.KindSAMLConnector
 	case teleservices.KindAuthConnector, "auth":
 		return teleservices.KindAuthConnector
 	case teleservices.KindUser, "users":
@@ -105,6 +121,19 @@ func CanonicalKind(kind string) string {
 		return KindClusterConfiguration
 	case KindAuthGateway, "gw":
 		return KindAuthGateway
+	case KindRoleMapping, "rolemappings":
+		return KindRoleMapping
+	case KindHealingPolicy, "healing":
+		return KindHealingPolicy
+	case KindWebhook, "webhooks":
+		return KindWebhook
+	case KindProvisioner, "provisioners":
+		return KindProvisioner
+	case KindClusterDNS, "dns":
+		return KindClusterDNS
+	}
+	if registered, ok := canonicalResourceKind(strings.ToLower(kind)); ok {
+		return registered
 	}
 	return kind
 }
@@ -160,6 +189,7 @@ func Encode(resources []UnknownResource, w io.Writer) error {
 var SupportedGravityResources = []string{
 	teleservices.KindClusterAuthPreference,
 	teleservices.KindGithubConnector,
+	teleservices.KindSAMLConnector,
 	teleservices.KindAuthConnector,
 	teleservices.KindUser,
 	KindToken,
@@ -171,12 +201,18 @@ var SupportedGravityResources = []string{
 	KindAuthGateway,
 	KindRuntimeEnvironment,
 	KindClusterConfiguration,
+	KindRoleMapping,
+	KindHealingPolicy,
+	KindWebhook,
+	KindProvisioner,
+	KindClusterDNS,
 }
 
 // SupportedGravityResourcesToRemove is a list of resources supported by
 // "gravity resource rm" subcommand
 var SupportedGravityResourcesToRemove = []string{
 	teleservices.KindGithubConnector,
+	teleservices.KindSAMLConnector,
 	teleservices.KindUser,
 	KindToken,
 	KindLogForwarder,
@@ -186,6 +222,11 @@ var SupportedGravityResourcesToRemove = []string{
 	KindTLSKeyPair,
 	KindRuntimeEnvironment,
 	KindClusterConfiguration,
+	KindRoleMapping,
+	KindHealingPolicy,
+	KindWebhook,
+	KindProvisioner,
+	KindClusterDNS,
 }
 
 // MetadataSchema is a copy of teleport/lib/services.MetadataSchema but with
@@ -0,0 +1,279 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+)
+
+// RoleMapping defines a resource that assigns cluster roles to users
+// authenticated with an OIDC or SAML connector based on their identity claims
+// or attribute statements
+type RoleMapping interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// GetConnector returns the name of the connector the mapping applies to
+	GetConnector() string
+	// GetGroups returns the groups the mapping matches
+	GetGroups() []string
+	// GetEmailDomains returns the email domains the mapping matches
+	GetEmailDomains() []string
+	// GetRoles returns the roles assigned to matching users
+	GetRoles() []string
+	// AppliesTo returns true if the mapping applies to the specified connector
+	AppliesTo(connector string) bool
+	// ClaimMappings returns the OIDC claim mappings equivalent to this mapping
+	ClaimMappings() []teleservices.ClaimMapping
+	// AttributeMappings returns the SAML attribute mappings equivalent to this mapping
+	AttributeMappings() []teleservices.AttributeMapping
+	// CheckAndSetDefaults validates the resource and sets defaults
+	CheckAndSetDefaults() error
+}
+
+// NewRoleMapping creates a new role mapping resource
+func NewRoleMapping(name string, spec RoleMappingSpecV1) RoleMapping {
+	return &RoleMappingV1{
+		Kind:    KindRoleMapping,
+		Version: teleservices.V1,
+		Metadata: teleservices.Metadata{
+			Name:      name,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// RoleMappingV1 defines the role mapping resource
+type RoleMappingV1 struct {
+	// Kind is the resource kind
+	Kind string `json:"kind"`
+	// Version is the resource version
+	Version string `json:"version"`
+	// Metadata is the resource metadata
+	Metadata teleservices.Metadata `json:"metadata"`
+	// Spec is the resource specification
+	Spec RoleMappingSpecV1 `json:"spec"`
+}
+
+// RoleMappingSpecV1 defines the role mapping resource specification.
+// A user is assigned the roles if they belong to any of the groups
+// or their email belongs to any of the email domains
+type RoleMappingSpecV1 struct {
+	// Connector is the name of the OIDC or SAML connector the mapping applies to.
+	// If empty, the mapping applies to all OIDC and SAML connectors
+	Connector string `json:"connector,omitempty"`
+	// GroupsClaim is the name of the claim or SAML attribute listing the user groups
+	GroupsClaim string `json:"groups_claim,omitempty"`
+	// Groups lists the groups that are assigned the roles
+	Groups []string `json:"groups,omitempty"`
+	// EmailDomains lists the email domains that are assigned the roles.
+	// OIDC users are only matched by their email if the identity provider
+	// has verified it
+	EmailDomains []string `json:"email_domains,omitempty"`
+	// Roles lists the roles to assign
+	Roles []string `json:"roles"`
+}
+
+// GetConnector returns the name of the connector the mapping applies to
+func (m *RoleMappingV1) GetConnector() string {
+	return m.Spec.Connector
+}
+
+// GetGroups returns the groups the mapping matches
+func (m *RoleMappingV1) GetGroups() []string {
+	return m.Spec.Groups
+}
+
+// GetEmailDomains returns the email domains the mapping matches
+func (m *RoleMappingV1) GetEmailDomains() []string {
+	return m.Spec.EmailDomains
+}
+
+// GetRoles returns the roles assigned to matching users
+func (m *RoleMappingV1) GetRoles() []string {
+	return m.Spec.Roles
+}
+
+// AppliesTo returns true if the mapping applies to the specified connector
+func (m *RoleMappingV1) AppliesTo(connector string) bool {
+	return m.Spec.Connector == "" || m.Spec.Connector == connector
+}
+
+// ClaimMappings returns the OIDC claim mappings equivalent to this mapping
+func (m *RoleMappingV1) ClaimMappings() (mappings []teleservices.ClaimMapping) {
+	for _, group := range m.Spec.Groups {
+		mappings = append(mappings, teleservices.ClaimMapping{
+			Claim: m.Spec.GroupsClaim,
+			Value: group,
+			Roles: m.Spec.Roles,
+		})
+	}
+	for _, domain := range m.Spec.EmailDomains {
+		mappings = append(mappings, teleservices.ClaimMapping{
+			Claim: EmailClaim,
+			Value: fmt.Sprintf("*@%v", domain),
+			Roles: m.Spec.Roles,
+		})
+	}
+	return mappings
+}
+
+// AttributeMappings returns the SAML attribute mappings equivalent to this mapping
+func (m *RoleMappingV1) AttributeMappings() (mappings []teleservices.AttributeMapping) {
+	for _, claim := range m.ClaimMappings() {
+		mappings = append(mappings, teleservices.AttributeMapping{
+			Name:  claim.Claim,
+			Value: claim.Value,
+			Roles: claim.Roles,
+		})
+	}
+	return mappings
+}
+
+// CheckAndSetDefaults validates the resource and sets defaults
+func (m *RoleMappingV1) CheckAndSetDefaults() error {
+	if m.Metadata.Name == "" {
+		return trace.BadParameter("role mapping name can't be empty")
+	}
+	if len(m.Spec.Roles) == 0 {
+		return trace.BadParameter("role mapping %q does not assign any roles", m.Metadata.Name)
+	}
+	if len(m.Spec.Groups) == 0 && len(m.Spec.EmailDomains) == 0 {
+		return trace.BadParameter("role mapping %q should match at least one group or email domain",
+			m.Metadata.Name)
+	}
+	for _, domain := range m.Spec.EmailDomains {
+		if domain == "" || strings.Contains(domain, "@") {
+			return trace.BadParameter("invalid email domain %q, expected a domain like example.com",
+				domain)
+		}
+	}
+	if m.Spec.GroupsClaim == "" {
+		m.Spec.GroupsClaim = GroupsClaim
+	}
+	return nil
+}
+
+// GetName returns the resource name
+func (m *RoleMappingV1) GetName() string {
+	return m.Metadata.Name
+}
+
+// SetName sets the resource name
+func (m *RoleMappingV1) SetName(name string) {
+	m.Metadata.Name = name
+}
+
+// GetMetadata returns the resource metadata
+func (m *RoleMappingV1) GetMetadata() teleservices.Metadata {
+	return m.Metadata
+}
+
+// SetExpiry sets the resource expiration time
+func (m *RoleMappingV1) SetExpiry(expires time.Time) {
+	m.Metadata.SetExpiry(expires)
+}
+
+// Expiry returns the resource expiration time
+func (m *RoleMappingV1) Expiry() time.Time {
+	return m.Metadata.Expiry()
+}
+
+// SetTTL sets the resource TTL
+func (m *RoleMappingV1) SetTTL(clock clockwork.Clock, ttl time.Duration) {
+	m.Metadata.SetTTL(clock, ttl)
+}
+
+// String returns the object's string representation
+func (m *RoleMappingV1) String() string {
+	return fmt.Sprintf("RoleMapping(Name=%v, Connector=%v, Groups=%v, EmailDomains=%v, Roles=%v)",
+		m.Metadata.Name, m.Spec.Connector, m.Spec.Groups, m.Spec.EmailDomains, m.Spec.Roles)
+}
+
+// UnmarshalRoleMapping unmarshals role mapping resource from the provided JSON or YAML data
+func UnmarshalRoleMapping(data []byte) (RoleMapping, error) {
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var header teleservices.ResourceHeader
+	err = json.Unmarshal(jsonData, &header)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch header.Version {
+	case teleservices.V1:
+		var mapping RoleMappingV1
+		err := teleutils.UnmarshalWithSchema(GetRoleMappingSchema(), &mapping, jsonData)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		mapping.Metadata.CheckAndSetDefaults()
+		err = mapping.CheckAndSetDefaults()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &mapping, nil
+	}
+	return nil, trace.BadParameter("%v resource version %q is not supported",
+		KindRoleMapping, header.Version)
+}
+
+// MarshalRoleMapping marshals role mapping resource to JSON
+func MarshalRoleMapping(mapping RoleMapping, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(mapping)
+}
+
+// GetRoleMappingSchema returns the full role mapping resource schema
+func GetRoleMappingSchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, MetadataSchema,
+		RoleMappingSpecV1Schema, "")
+}
+
+// RoleMappingSpecV1Schema defines the role mapping spec schema
+var RoleMappingSpecV1Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["roles"],
+  "properties": {
+    "connector": {"type": "string"},
+    "groups_claim": {"type": "string"},
+    "groups": {"type": "array", "items": {"type": "string"}},
+    "email_domains": {"type": "array", "items": {"type": "string"}},
+    "roles": {"type": "array", "items": {"type": "string"}}
+  }
+}`
+
+const (
+	// GroupsClaim is the default name of the claim listing user groups
+	GroupsClaim = "groups"
+	// EmailClaim is the name of the claim with the user email
+	EmailClaim = "email"
+	// EmailVerifiedClaim is the name of the claim that is set to true
+	// if the identity provider has verified the user email
+	EmailVerifiedClaim = "email_verified"
+)
@@ -0,0 +1,109 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"github.com/gravitational/gravity/lib/compare"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+
+	check "gopkg.in/check.v1"
+)
+
+type RoleMappingSuite struct{}
+
+var _ = check.Suite(&RoleMappingSuite{})
+
+func (s *RoleMappingSuite) TestResourceParsing(c *check.C) {
+	spec := `kind: rolemapping
+version: v1
+metadata:
+  name: developers
+spec:
+  connector: google
+  groups: ["dev", "qa"]
+  email_domains: ["example.com"]
+  roles: ["developer"]
+`
+	mapping, err := UnmarshalRoleMapping([]byte(spec))
+	c.Assert(err, check.IsNil)
+	expected := NewRoleMapping("developers", RoleMappingSpecV1{
+		Connector:    "google",
+		GroupsClaim:  GroupsClaim,
+		Groups:       []string{"dev", "qa"},
+		EmailDomains: []string{"example.com"},
+		Roles:        []string{"developer"},
+	})
+	c.Assert(mapping, compare.DeepEquals, expected)
+	c.Assert(mapping.AppliesTo("google"), check.Equals, true)
+	c.Assert(mapping.AppliesTo("github"), check.Equals, false)
+	c.Assert(mapping.ClaimMappings(), compare.DeepEquals, []teleservices.ClaimMapping{
+		{Claim: "groups", Value: "dev", Roles: []string{"developer"}},
+		{Claim: "groups", Value: "qa", Roles: []string{"developer"}},
+		{Claim: "email", Value: "*@example.com", Roles: []string{"developer"}},
+	})
+	c.Assert(mapping.AttributeMappings(), compare.DeepEquals, []teleservices.AttributeMapping{
+		{Name: "groups", Value: "dev", Roles: []string{"developer"}},
+		{Name: "groups", Value: "qa", Roles: []string{"developer"}},
+		{Name: "email", Value: "*@example.com", Roles: []string{"developer"}},
+	})
+}
+
+func (s *RoleMappingSuite) TestValidation(c *check.C) {
+	testCases := []struct {
+		spec    string
+		comment string
+	}{
+		{
+			spec: `kind: rolemapping
+version: v1
+metadata:
+  name: test
+spec:
+  groups: ["dev"]
+  roles: []
+`,
+			comment: "no roles",
+		},
+		{
+			spec: `kind: rolemapping
+version: v1
+metadata:
+  name: test
+spec:
+  roles: ["developer"]
+`,
+			comment: "no groups or email domains",
+		},
+		{
+			spec: `kind: rolemapping
+version: v1
+metadata:
+  name: test
+spec:
+  email_domains: ["user@example.com"]
+  roles: ["developer"]
+`,
+			comment: "email instead of domain",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := UnmarshalRoleMapping([]byte(tc.spec))
+		c.Assert(trace.IsBadParameter(err), check.Equals, true, check.Commentf(tc.comment))
+	}
+}
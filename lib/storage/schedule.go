@@ -0,0 +1,146 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// ScheduledOperation describes a cluster operation queued
+// to start at a later time
+type ScheduledOperation struct {
+	// ID is the unique scheduled operation ID
+	ID string `json:"id"`
+	// AccountID is the ID of the account the cluster belongs to
+	AccountID string `json:"account_id"`
+	// ClusterName is the name of the cluster the operation is scheduled for
+	ClusterName string `json:"cluster_name"`
+	// Type is the type of the scheduled operation
+	Type string `json:"type"`
+	// Created is the time the operation was scheduled
+	Created time.Time `json:"created"`
+	// CreatedBy is the user who scheduled the operation
+	CreatedBy string `json:"created_by,omitempty"`
+	// Schedule defines when the operation is started
+	Schedule OperationSchedule `json:"schedule"`
+	// App is the application package to update the cluster to
+	App string `json:"app,omitempty"`
+	// State is the scheduled operation state
+	State string `json:"state"`
+	// OperationID is the ID of the cluster operation once it has been started
+	OperationID string `json:"operation_id,omitempty"`
+	// Error is the error the operation has failed to start with
+	Error string `json:"error,omitempty"`
+}
+
+// Check validates the scheduled operation
+func (o ScheduledOperation) Check() error {
+	if o.ID == "" {
+		return trace.BadParameter("missing scheduled operation ID")
+	}
+	if o.ClusterName == "" {
+		return trace.BadParameter("missing scheduled operation cluster name")
+	}
+	if o.Type == "" {
+		return trace.BadParameter("missing scheduled operation type")
+	}
+	if o.Created.IsZero() {
+		return trace.BadParameter("missing scheduled operation creation time")
+	}
+	if o.State == "" {
+		return trace.BadParameter("missing scheduled operation state")
+	}
+	return trace.Wrap(o.Schedule.Check())
+}
+
+// IsPending returns true if the operation has not been started or canceled yet
+func (o ScheduledOperation) IsPending() bool {
+	return o.State == ScheduledOperationStatePending
+}
+
+// OperationSchedule defines when a scheduled operation is started
+type OperationSchedule struct {
+	// NotBefore is the earliest time the operation can be started
+	NotBefore time.Time `json:"not_before,omitempty"`
+	// Window optionally restricts the operation to start
+	// within a recurring maintenance window
+	Window *MaintenanceWindow `json:"window,omitempty"`
+}
+
+// MaintenanceWindow is a recurring time interval cluster operations
+// are allowed to start within
+type MaintenanceWindow struct {
+	// Cron is the cron expression specifying when the window opens,
+	// e.g. "0 2 * * 6" for every Saturday at 2am
+	Cron string `json:"cron"`
+	// Duration is how long the window stays open
+	Duration time.Duration `json:"duration"`
+}
+
+// Check validates the schedule
+func (s OperationSchedule) Check() error {
+	if s.NotBefore.IsZero() && s.Window == nil {
+		return trace.BadParameter("either not-before time or maintenance window should be specified")
+	}
+	if s.Window != nil {
+		if _, err := utils.ParseCron(s.Window.Cron); err != nil {
+			return trace.Wrap(err)
+		}
+		if s.Window.Duration < time.Minute {
+			return trace.BadParameter("maintenance window should be at least a minute long")
+		}
+	}
+	return nil
+}
+
+// NextStart returns the earliest time not before now the operation
+// with this schedule can be started at.
+// It returns zero time if the maintenance window never opens
+func (s OperationSchedule) NextStart(now time.Time) (time.Time, error) {
+	start := now
+	if s.NotBefore.After(start) {
+		start = s.NotBefore
+	}
+	if s.Window == nil {
+		return start, nil
+	}
+	cron, err := utils.ParseCron(s.Window.Cron)
+	if err != nil {
+		return time.Time{}, trace.Wrap(err)
+	}
+	// find the latest window that could still be open at the start time
+	opens := cron.Next(start.Add(-s.Window.Duration))
+	if opens.IsZero() || opens.After(start) {
+		return opens, nil
+	}
+	return start, nil
+}
+
+const (
+	// ScheduledOperationStatePending is the state of an operation waiting to be started
+	ScheduledOperationStatePending = "pending"
+	// ScheduledOperationStateStarted is the state of a started operation
+	ScheduledOperationStateStarted = "started"
+	// ScheduledOperationStateFailed is the state of an operation that has failed to start
+	ScheduledOperationStateFailed = "failed"
+	// ScheduledOperationStateCanceled is the state of a canceled operation
+	ScheduledOperationStateCanceled = "canceled"
+)
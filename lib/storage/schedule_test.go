@@ -0,0 +1,88 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+type ScheduleSuite struct{}
+
+var _ = check.Suite(&ScheduleSuite{})
+
+func (s *ScheduleSuite) TestNextStart(c *check.C) {
+	// Wednesday
+	now := time.Date(2019, time.October, 16, 10, 30, 0, 0, time.UTC)
+	// every Saturday 2am-6am
+	window := &MaintenanceWindow{Cron: "0 2 * * 6", Duration: 4 * time.Hour}
+	var tests = []struct {
+		schedule OperationSchedule
+		start    time.Time
+		comment  string
+	}{
+		{
+			schedule: OperationSchedule{NotBefore: now.Add(-time.Hour)},
+			start:    now,
+			comment:  "not-before time in the past",
+		},
+		{
+			schedule: OperationSchedule{NotBefore: now.Add(time.Hour)},
+			start:    now.Add(time.Hour),
+			comment:  "not-before time in the future",
+		},
+		{
+			schedule: OperationSchedule{Window: window},
+			start:    time.Date(2019, time.October, 19, 2, 0, 0, 0, time.UTC),
+			comment:  "next maintenance window",
+		},
+		{
+			schedule: OperationSchedule{
+				NotBefore: time.Date(2019, time.October, 19, 3, 0, 0, 0, time.UTC),
+				Window:    window,
+			},
+			start:   time.Date(2019, time.October, 19, 3, 0, 0, 0, time.UTC),
+			comment: "not-before time within maintenance window",
+		},
+		{
+			schedule: OperationSchedule{
+				NotBefore: time.Date(2019, time.October, 19, 6, 0, 0, 0, time.UTC),
+				Window:    window,
+			},
+			start:   time.Date(2019, time.October, 26, 2, 0, 0, 0, time.UTC),
+			comment: "not-before time after maintenance window closes",
+		},
+	}
+	for _, tt := range tests {
+		comment := check.Commentf(tt.comment)
+		c.Assert(tt.schedule.Check(), check.IsNil, comment)
+		start, err := tt.schedule.NextStart(now)
+		c.Assert(err, check.IsNil, comment)
+		c.Assert(start, check.DeepEquals, tt.start, comment)
+	}
+}
+
+func (s *ScheduleSuite) TestCheck(c *check.C) {
+	c.Assert(OperationSchedule{}.Check(), check.NotNil)
+	c.Assert(OperationSchedule{
+		Window: &MaintenanceWindow{Cron: "0 2 * *", Duration: time.Hour},
+	}.Check(), check.NotNil)
+	c.Assert(OperationSchedule{
+		Window: &MaintenanceWindow{Cron: "0 2 * * *"},
+	}.Check(), check.NotNil)
+}
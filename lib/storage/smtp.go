@@ -41,6 +41,8 @@ type SMTPConfig interface {
 	GetUsername() string
 	// GetPassword returns SMTP password
 	GetPassword() string
+	// GetFrom returns the sender address of alert notifications
+	GetFrom() string
 }
 
 // SMTPConfigV2 defines SMTP configuration
@@ -75,6 +77,15 @@ func (r *SMTPConfigV2) GetPassword() string {
 	return r.Spec.Password
 }
 
+// GetFrom returns the sender address of alert notifications,
+// defaults to the SMTP username
+func (r *SMTPConfigV2) GetFrom() string {
+	if r.Spec.From == "" {
+		return r.Spec.Username
+	}
+	return r.Spec.From
+}
+
 // CheckAndSetDefaults checks validity of all parameters and sets defaults
 func (r *SMTPConfigV2) CheckAndSetDefaults() error {
 	if r.Spec.Host == "" {
@@ -142,6 +153,8 @@ type SMTPConfigSpecV2 struct {
 	Username string `json:"username"`
 	// Password specifies the password
 	Password string `json:"password"`
+	// From specifies the sender address of alert notifications
+	From string `json:"from,omitempty"`
 }
 
 // SMTPConfigSpecV2Schema is JSON schema for SMTP configuration
@@ -153,7 +166,8 @@ const SMTPConfigSpecV2Schema = `{
     "host": {"type": "string"},
     "port": {"type": "integer"},
     "username": {"type": "string"},
-    "password": {"type": "string"}
+    "password": {"type": "string"},
+    "from": {"type": "string"}
   }
 }`
 
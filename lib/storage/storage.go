@@ -26,8 +26,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -104,6 +106,8 @@ type APIKey struct {
 	Expires time.Time `json:"expires"`
 	// UserEmail is the name of the user the api key belongs to
 	UserEmail string `json:"user_email"`
+	// Permissions optionally restricts the api key to the specified permissions
+	Permissions ResourcePermissions `json:"permissions,omitempty"`
 }
 
 // V2 returns V2 from token spec
@@ -118,7 +122,8 @@ func (a *APIKey) V2() *TokenV2 {
 			Namespace: defaults.Namespace,
 		},
 		Spec: TokenSpecV2{
-			User: a.UserEmail,
+			User:        a.UserEmail,
+			Permissions: a.Permissions,
 		},
 	}
 }
@@ -128,6 +133,9 @@ func (a *APIKey) Check() error {
 	if a.Token == "" {
 		return trace.BadParameter("missing API Key token")
 	}
+	if err := a.Permissions.Check(); err != nil {
+		return trace.Wrap(err)
+	}
 	return nil
 }
 
@@ -145,6 +153,68 @@ type APIKeys interface {
 	DeleteAPIKey(username, token string) error
 }
 
+// RoleMappings manages mappings of OIDC claims to cluster roles
+type RoleMappings interface {
+	// UpsertRoleMapping creates or updates a role mapping
+	UpsertRoleMapping(RoleMapping) error
+	// GetRoleMapping returns a role mapping by name
+	GetRoleMapping(name string) (RoleMapping, error)
+	// GetRoleMappings returns all role mappings
+	GetRoleMappings() ([]RoleMapping, error)
+	// DeleteRoleMapping deletes a role mapping by name
+	DeleteRoleMapping(name string) error
+}
+
+// Webhooks manages endpoints notified about operation events
+// of a cluster
+type Webhooks interface {
+	// UpsertWebhook creates or updates a webhook of the specified cluster
+	UpsertWebhook(clusterName string, webhook Webhook) error
+	// GetWebhook returns a webhook of the specified cluster by name
+	GetWebhook(clusterName, name string) (Webhook, error)
+	// GetWebhooks returns all webhooks of the specified cluster
+	GetWebhooks(clusterName string) ([]Webhook, error)
+	// DeleteWebhook deletes a webhook of the specified cluster by name
+	DeleteWebhook(clusterName, name string) error
+}
+
+// Provisioners manages cloud provisioners of new cluster nodes
+type Provisioners interface {
+	// UpsertProvisioner creates or updates a provisioner
+	UpsertProvisioner(Provisioner) error
+	// GetProvisioner returns a provisioner by name
+	GetProvisioner(name string) (Provisioner, error)
+	// GetProvisioners returns all provisioners
+	GetProvisioners() ([]Provisioner, error)
+	// DeleteProvisioner deletes a provisioner by name
+	DeleteProvisioner(name string) error
+}
+
+// ScheduledOperations persists cluster operations queued to start
+// at a later time or within a maintenance window
+type ScheduledOperations interface {
+	// CreateScheduledOperation saves a new scheduled operation
+	CreateScheduledOperation(ScheduledOperation) (*ScheduledOperation, error)
+	// UpdateScheduledOperation updates an existing scheduled operation
+	UpdateScheduledOperation(ScheduledOperation) (*ScheduledOperation, error)
+	// GetScheduledOperation returns the scheduled operation of the specified cluster
+	GetScheduledOperation(clusterName, id string) (*ScheduledOperation, error)
+	// GetScheduledOperations returns all scheduled operations of the specified
+	// cluster ordered by their creation time
+	GetScheduledOperations(clusterName string) ([]ScheduledOperation, error)
+}
+
+// TerraformStates stores the state of the Terraform configurations
+// that provision the cluster infrastructure
+type TerraformStates interface {
+	// UpsertTerraformState creates or updates the state of the specified configuration
+	UpsertTerraformState(name string, state []byte) error
+	// GetTerraformState returns the state of the specified configuration
+	GetTerraformState(name string) ([]byte, error)
+	// DeleteTerraformState deletes the state of the specified configuration
+	DeleteTerraformState(name string) error
+}
+
 // Connectors manages OIDC connectors (OpenID connect configurations)
 type Connectors interface {
 	// UpsertOIDCConnector upserts OIDC Connector
@@ -302,6 +372,9 @@ type UserInvite struct {
 	Roles []string `json:"roles"`
 	// ExpiresIn sets the token expiry time
 	ExpiresIn time.Duration `json:"expires_in"`
+	// AccountID is the ID of the account the invited user joins.
+	// Empty value means the system account
+	AccountID string `json:"account_id,omitempty"`
 }
 
 // CheckAndSetDefaults checks and sets defaults for user invite
@@ -439,8 +512,23 @@ type SiteOperation struct {
 	UpdateEnviron *UpdateEnvarsOperationState `json:"update_environ,omitempty"`
 	// UpdateConfig defines the state of the cluster configuration update operation
 	UpdateConfig *UpdateConfigOperationState `json:"update_config,omitempty"`
+	// RotateCertificates defines the state of the certificate rotation operation
+	RotateCertificates *RotateCertificatesOperationState `json:"rotate_certificates,omitempty"`
+	// Patch defines the state of the configuration patch operation
+	Patch *PatchOperationState `json:"patch,omitempty"`
+	// Interrupt is the pending request to pause or cancel the operation
+	Interrupt string `json:"interrupt,omitempty"`
 }
 
+const (
+	// OperationInterruptPause requests the operation to stop after
+	// the phase in progress so it can be resumed later
+	OperationInterruptPause = "pause"
+	// OperationInterruptCancel requests the operation to abort and
+	// roll back the phase in progress
+	OperationInterruptCancel = "cancel"
+)
+
 func (s *SiteOperation) Check() error {
 	if s.Type == "" {
 		return trace.BadParameter("missing operation type")
@@ -579,6 +667,9 @@ type Site struct {
 	DNSOverrides DNSOverrides `json:"dns_overrides"`
 	// DNSConfig defines cluster local DNS configuration
 	DNSConfig DNSConfig `json:"dns_config"`
+	// PKI optionally describes the user-provided certificate authority
+	// the cluster certificates are issued from
+	PKI *ExternalPKI `json:"pki,omitempty"`
 }
 
 func (s *Site) Check() error {
@@ -603,6 +694,9 @@ type ClusterState struct {
 	Servers Servers `json:"servers"`
 	// Docker specifies current cluster Docker configuration
 	Docker DockerConfig `json:"docker"`
+	// FIPS specifies whether the cluster has been installed in FIPS mode
+	// and only accepts nodes running FIPS-compliant binaries
+	FIPS bool `json:"fips,omitempty"`
 }
 type nodeKey struct {
 	profile      string
@@ -1078,7 +1172,7 @@ var LegacyDNSConfig = DNSConfig{
 func (r DNSConfig) String() string {
 	var addrs []string
 	for _, addr := range r.Addrs {
-		addrs = append(addrs, fmt.Sprintf("%v:%v", addr, r.Port))
+		addrs = append(addrs, net.JoinHostPort(addr, strconv.Itoa(r.Port)))
 	}
 	return strings.Join(addrs, ",")
 }
@@ -1086,7 +1180,7 @@ func (r DNSConfig) String() string {
 // Addr returns the DNS server address as ip:port.
 // Requires that !r.IsEmpty.
 func (r DNSConfig) Addr() string {
-	return fmt.Sprintf("%v:%v", r.Addrs[0], r.Port)
+	return net.JoinHostPort(r.Addrs[0], strconv.Itoa(r.Port))
 }
 
 // IsEmpty returns whether this configuration is empty
@@ -1102,6 +1196,23 @@ type DNSConfig struct {
 	Port int `json:"port"`
 }
 
+// ExternalPKI describes the user-provided certificate authority the cluster
+// certificates are issued from instead of a self-generated one
+type ExternalPKI struct {
+	// CertPEM is the PEM-encoded certificate of the issuing certificate
+	// authority optionally followed by the rest of its chain
+	CertPEM []byte `json:"cert_pem"`
+	// SignerURL is the URL of the external signer the certificate signing
+	// requests are sent to. If unset, the certificates are signed locally
+	// with the private key of the issuing certificate authority
+	SignerURL string `json:"signer_url,omitempty"`
+}
+
+// UsesSigner returns true if the certificates are issued by the external signer
+func (r ExternalPKI) UsesSigner() bool {
+	return r.SignerURL != ""
+}
+
 // PackageChangeset is a set of package updates from one version to another
 type PackageChangeset struct {
 	ID string `json:"id"`
@@ -1201,6 +1312,42 @@ type ProvisioningToken struct {
 	// UserEmail links this token to the user with permissions,
 	// usually it's a site agent user
 	UserEmail string `json:"user_email"`
+	// MaxUses limits the number of nodes that can join with this token,
+	// zero means no limit
+	MaxUses int `json:"max_uses,omitempty"`
+	// Uses is the number of nodes that have joined with this token
+	Uses int `json:"uses,omitempty"`
+	// Roles restricts the service roles (master or node) of the nodes
+	// joining with this token, empty means any role
+	Roles []string `json:"roles,omitempty"`
+}
+
+// IsScopedJoinToken returns true if this is a join token limited
+// in time, number of uses or node roles as opposed to the long-lived
+// cluster join token or the token of a particular operation
+func (p ProvisioningToken) IsScopedJoinToken() bool {
+	return p.Type == ProvisioningTokenTypeExpand && p.OperationID == "" &&
+		(!p.Expires.IsZero() || p.MaxUses != 0 || len(p.Roles) != 0)
+}
+
+// CheckJoin returns an error if a node with the specified service role
+// cannot join the cluster with this token at the specified time
+func (p ProvisioningToken) CheckJoin(role string, now time.Time) error {
+	if !p.Expires.IsZero() && !now.Before(p.Expires) {
+		return trace.AccessDenied("join token has expired")
+	}
+	if p.MaxUses != 0 && p.Uses >= p.MaxUses {
+		return trace.AccessDenied("join token has been used %v times and is exhausted", p.Uses)
+	}
+	if len(p.Roles) != 0 && role == "" {
+		return trace.AccessDenied("join token only allows nodes with roles %v but the node profile does not define a role",
+			p.Roles)
+	}
+	if len(p.Roles) != 0 && !utils.StringInSlice(p.Roles, role) {
+		return trace.AccessDenied("join token does not allow nodes with role %q, allowed roles: %v",
+			role, p.Roles)
+	}
+	return nil
 }
 
 func (p *ProvisioningToken) Check() error {
@@ -1216,6 +1363,9 @@ func (p *ProvisioningToken) Check() error {
 	if p.SiteDomain == "" {
 		return trace.BadParameter("missing SiteDomain")
 	}
+	if p.MaxUses < 0 {
+		return trace.BadParameter("max uses can't be negative")
+	}
 	return nil
 }
 
@@ -1248,6 +1398,9 @@ type Tokens interface {
 	DeleteProvisioningToken(token string) error
 	// GetProvisioningToken returns a token if it has not expired yet
 	GetProvisioningToken(token string) (*ProvisioningToken, error)
+	// CompareAndSwapProvisioningToken updates the token if the existing
+	// value matches existing parameter
+	CompareAndSwapProvisioningToken(new, existing ProvisioningToken) error
 	// GetOperationProvisioningToken returns an existing token for the particular operation if
 	// it has not expired yet
 	GetOperationProvisioningToken(clusterName, operationID string) (*ProvisioningToken, error)
@@ -1480,6 +1633,18 @@ type Backend interface {
 	LegacyRoles
 	SystemMetadata
 	Charts
+	TrustedKeys
+	AuditEvents
+	StatusHistory
+	FleetUpgrades
+	ScheduledOperations
+	RoleMappings
+	Watches
+	CatalogEntries
+	ConfigResources
+	Webhooks
+	Provisioners
+	TerraformStates
 }
 
 const (
@@ -1589,6 +1754,15 @@ func (s *Server) KubeNodeID() string {
 	return s.AdvertiseIP
 }
 
+// Arch returns the CPU architecture of the server.
+// Servers that did not report their architecture are assumed to be amd64
+func (s *Server) Arch() string {
+	if s.OSInfo.Arch != "" {
+		return s.OSInfo.Arch
+	}
+	return schema.ArchAMD64
+}
+
 // IsMaster returns true if the server has a master role
 func (s *Server) IsMaster() bool {
 	return s.ClusterRole == string(schema.ServiceRoleMaster)
@@ -1984,6 +2158,30 @@ type ShrinkOperationState struct {
 	// Used in cases where we recieve an event where the node is being terminated, but may
 	// not have disconnected from the cluster yet.
 	NodeRemoved bool `json:"node_removed"`
+	// Drain specifies optional parameters for draining the node before removal
+	Drain *DrainOptions `json:"drain,omitempty"`
+}
+
+// DrainOptions defines the parameters of draining a Kubernetes node
+type DrainOptions struct {
+	// Timeout specifies the maximum amount of time to wait for the pods
+	// to be evicted. Pod eviction honors pod disruption budgets.
+	// If unspecified, defaults.DrainEvictionTimeout is used
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Force specifies whether to delete the pods that could not be evicted
+	// within Timeout, bypassing pod disruption budgets
+	Force bool `json:"force,omitempty"`
+}
+
+// Check validates these options
+func (r DrainOptions) Check() error {
+	if r.Timeout < 0 {
+		return trace.BadParameter("drain timeout cannot be negative")
+	}
+	if r.Timeout > defaults.DrainTimeout {
+		return trace.BadParameter("drain timeout cannot exceed %v", defaults.DrainTimeout)
+	}
+	return nil
 }
 
 // UpdateOperationState describes the state of the update operation.
@@ -2000,6 +2198,31 @@ type UpdateOperationState struct {
 	ServerUpdates []ServerUpdate `json:"server_updates,omitempty"`
 	// Manual specifies whether this update operation was created in manual mode
 	Manual bool `json:"manual"`
+	// Drain specifies optional parameters for draining the nodes being updated
+	Drain *DrainOptions `json:"drain,omitempty"`
+	// Canary optionally specifies the subset of regular nodes
+	// to update before the rest of the cluster
+	Canary *CanaryOptions `json:"canary,omitempty"`
+}
+
+// CanaryOptions defines the canary strategy of the update operation:
+// the specified number of regular nodes is updated and verified first
+// before the update proceeds with the rest of the nodes
+type CanaryOptions struct {
+	// Nodes is the number of regular nodes to update first
+	Nodes int `json:"nodes"`
+	// Pause specifies whether to pause the operation after the canary
+	// nodes have been updated until the update is approved with
+	// 'gravity plan resume'
+	Pause bool `json:"pause,omitempty"`
+}
+
+// Check validates these options
+func (r CanaryOptions) Check() error {
+	if r.Nodes <= 0 {
+		return trace.BadParameter("number of canary nodes must be positive")
+	}
+	return nil
 }
 
 // UpdateEnvarsOperationState describes the state of the operation to update cluster environment variables.
@@ -2027,6 +2250,24 @@ type UpdateConfigOperationState struct {
 	Config []byte `json:"config,omitempty"`
 }
 
+// RotateCertificatesOperationState describes the state of the operation to rotate cluster certificates
+type RotateCertificatesOperationState struct {
+	// RotateCA specifies whether the cluster certificate authority is replaced
+	// in addition to reissuing the node certificates
+	RotateCA bool `json:"rotate_ca"`
+}
+
+// PatchOperationState describes the state of the operation to apply
+// configuration-only changes to the cluster.
+// Runtime environment changes are described by the UpdateEnviron state
+// of the same operation
+type PatchOperationState struct {
+	// DNSUpstreams specifies the new upstream nameservers for the cluster DNS
+	DNSUpstreams []string `json:"dns_upstreams,omitempty"`
+	// PrevCorefile specifies the cluster DNS configuration before the patch
+	PrevCorefile string `json:"prev_corefile,omitempty"`
+}
+
 // ServerUpdate represents server that is being updated
 type ServerUpdate struct {
 	// Server is a server being updated
@@ -2094,6 +2335,274 @@ type CloudConfig struct {
 	GCENodeTags []string `json:"gce_node_tags,omitempty"`
 }
 
+// TrustedKeys manages public keys trusted to sign cluster and application images
+type TrustedKeys interface {
+	// UpsertTrustedKey creates or updates the trusted key
+	UpsertTrustedKey(TrustedKey) error
+	// GetTrustedKey returns the trusted key by name
+	GetTrustedKey(name string) (*TrustedKey, error)
+	// GetTrustedKeys returns all trusted keys
+	GetTrustedKeys() ([]TrustedKey, error)
+	// DeleteTrustedKey deletes the trusted key by name
+	DeleteTrustedKey(name string) error
+}
+
+// TrustedKey is a public key trusted to sign cluster and application images
+type TrustedKey struct {
+	// Name is the unique key name
+	Name string `json:"name"`
+	// PublicKey is the PEM-encoded public key
+	PublicKey []byte `json:"public_key"`
+	// Created is the time the key has been added
+	Created time.Time `json:"created"`
+}
+
+// Check validates the trusted key
+func (k TrustedKey) Check() error {
+	if k.Name == "" {
+		return trace.BadParameter("missing trusted key name")
+	}
+	if len(k.PublicKey) == 0 {
+		return trace.BadParameter("missing public key of trusted key %q", k.Name)
+	}
+	return nil
+}
+
+// AuditEvents persists the audit log of operator API calls
+type AuditEvents interface {
+	// CreateAuditEvent saves the audit event for the specified duration
+	CreateAuditEvent(event AuditEvent, ttl time.Duration) error
+	// GetAuditEvents returns the audit events recorded since the specified
+	// time ordered by their time
+	GetAuditEvents(since time.Time) ([]AuditEvent, error)
+}
+
+// AuditEvent describes a single operator API call
+type AuditEvent struct {
+	// ID is the unique event ID
+	ID string `json:"id"`
+	// Time is the time of the call
+	Time time.Time `json:"time"`
+	// User is the name of the user who made the call
+	User string `json:"user"`
+	// AccountID is the ID of the account the call was made in
+	AccountID string `json:"account_id,omitempty"`
+	// Action identifies the called API as the request method
+	// and the route, e.g. "POST /portal/v1/accounts/:account_id/sites"
+	Action string `json:"action"`
+	// Path is the route template the request has been matched with
+	Path string `json:"path"`
+	// Params lists the route parameters of the call.
+	// Values of the parameters that carry secrets are redacted
+	Params map[string]string `json:"params,omitempty"`
+	// Error is the error the call failed with, if any
+	Error string `json:"error,omitempty"`
+}
+
+// Check validates the audit event
+func (e AuditEvent) Check() error {
+	if e.ID == "" {
+		return trace.BadParameter("missing audit event ID")
+	}
+	if e.Time.IsZero() {
+		return trace.BadParameter("missing audit event time")
+	}
+	if e.Action == "" {
+		return trace.BadParameter("missing audit event action")
+	}
+	return nil
+}
+
+// StatusHistory persists periodic snapshots of the cluster status
+type StatusHistory interface {
+	// CreateStatusSnapshot saves the cluster status snapshot for the specified duration
+	CreateStatusSnapshot(snapshot StatusSnapshot, ttl time.Duration) error
+	// GetStatusSnapshots returns the status snapshots of the specified cluster
+	// recorded since the specified time ordered by their time
+	GetStatusSnapshots(clusterName string, since time.Time) ([]StatusSnapshot, error)
+}
+
+// StatusSnapshot describes the cluster status at a point in time
+type StatusSnapshot struct {
+	// ID is the unique snapshot ID
+	ID string `json:"id"`
+	// ClusterName is the name of the cluster the snapshot belongs to
+	ClusterName string `json:"cluster_name"`
+	// Time is the time the snapshot was taken
+	Time time.Time `json:"time"`
+	// State is the cluster state, e.g. active or degraded
+	State string `json:"state"`
+	// Reason is the reason the cluster is degraded, if any
+	Reason Reason `json:"reason,omitempty"`
+	// Error is the error reported by the failed status check, if any
+	Error string `json:"error,omitempty"`
+	// Nodes lists the status of individual cluster nodes
+	Nodes []NodeStatusSnapshot `json:"nodes,omitempty"`
+}
+
+// NodeStatusSnapshot describes the status of a cluster node at a point in time
+type NodeStatusSnapshot struct {
+	// Hostname is the node hostname
+	Hostname string `json:"hostname"`
+	// AdvertiseIP is the node advertise IP address
+	AdvertiseIP string `json:"advertise_ip"`
+	// Status is the node status, e.g. healthy, degraded or offline
+	Status string `json:"status"`
+	// FailedProbes lists failed health probes if the node is not healthy
+	FailedProbes []string `json:"failed_probes,omitempty"`
+	// Probes describes the failed health probes in detail
+	Probes []ProbeFailure `json:"probes,omitempty"`
+}
+
+// ProbeFailure describes a failed node health probe
+type ProbeFailure struct {
+	// Checker is the name of the checker that reported the failure
+	Checker string `json:"checker"`
+	// Detail is the optional detail the checker reported, e.g. the name
+	// of the failed systemd unit
+	Detail string `json:"detail,omitempty"`
+	// Error is the error the checker reported
+	Error string `json:"error,omitempty"`
+	// Code is the machine-readable reason of the failure
+	Code string `json:"code"`
+	// Hint suggests how to remediate the failure
+	Hint string `json:"hint,omitempty"`
+	// Logs is the command that displays the logs relevant to the failure
+	Logs string `json:"logs,omitempty"`
+}
+
+// Check validates the status snapshot
+func (s StatusSnapshot) Check() error {
+	if s.ID == "" {
+		return trace.BadParameter("missing status snapshot ID")
+	}
+	if s.ClusterName == "" {
+		return trace.BadParameter("missing status snapshot cluster name")
+	}
+	if s.Time.IsZero() {
+		return trace.BadParameter("missing status snapshot time")
+	}
+	if s.State == "" {
+		return trace.BadParameter("missing status snapshot state")
+	}
+	return nil
+}
+
+// IsTransition returns true if this snapshot describes a different cluster
+// state than the specified previous snapshot
+func (s StatusSnapshot) IsTransition(prev StatusSnapshot) bool {
+	if s.State != prev.State || s.Reason != prev.Reason || len(s.Nodes) != len(prev.Nodes) {
+		return true
+	}
+	for i, node := range s.Nodes {
+		if node.AdvertiseIP != prev.Nodes[i].AdvertiseIP || node.Status != prev.Nodes[i].Status {
+			return true
+		}
+	}
+	return false
+}
+
+// FleetUpgrades persists the upgrades of multiple remote clusters
+// to a new application version
+type FleetUpgrades interface {
+	// CreateFleetUpgrade saves a new fleet upgrade
+	CreateFleetUpgrade(FleetUpgrade) (*FleetUpgrade, error)
+	// UpdateFleetUpgrade updates an existing fleet upgrade
+	UpdateFleetUpgrade(FleetUpgrade) (*FleetUpgrade, error)
+	// GetFleetUpgrade returns the fleet upgrade with the specified ID
+	GetFleetUpgrade(id string) (*FleetUpgrade, error)
+	// GetFleetUpgrades returns all fleet upgrades ordered by their creation time
+	GetFleetUpgrades() ([]FleetUpgrade, error)
+}
+
+// FleetUpgrade describes an upgrade of multiple remote clusters
+// to a new application version
+type FleetUpgrade struct {
+	// ID is the unique fleet upgrade ID
+	ID string `json:"id"`
+	// Created is the time the upgrade was started
+	Created time.Time `json:"created"`
+	// CreatedBy is the user who started the upgrade
+	CreatedBy string `json:"created_by,omitempty"`
+	// AccountID is the ID of the account the upgraded clusters belong to
+	AccountID string `json:"account_id"`
+	// App is the application package the clusters are upgraded to
+	App string `json:"app"`
+	// Selector is the labels of the upgraded clusters
+	Selector map[string]string `json:"selector,omitempty"`
+	// Canaries is the number of clusters upgraded one at a time
+	// before the remaining clusters
+	Canaries int `json:"canaries"`
+	// Concurrency is the maximum number of clusters upgraded at the same time
+	Concurrency int `json:"concurrency"`
+	// MaxFailures is the number of failed cluster upgrades that halts the upgrade
+	MaxFailures int `json:"max_failures"`
+	// State is the fleet upgrade state
+	State string `json:"state"`
+	// Clusters lists the upgraded clusters in the order they are upgraded
+	Clusters []FleetUpgradeCluster `json:"clusters"`
+}
+
+// FleetUpgradeCluster describes the upgrade of a single cluster
+// that is a part of a fleet upgrade
+type FleetUpgradeCluster struct {
+	// Name is the cluster name
+	Name string `json:"name"`
+	// State is the state of the cluster upgrade
+	State string `json:"state"`
+	// OperationID is the ID of the update operation in the cluster
+	OperationID string `json:"operation_id,omitempty"`
+	// Error is the error the cluster upgrade failed with
+	Error string `json:"error,omitempty"`
+}
+
+// Check validates the fleet upgrade
+func (u FleetUpgrade) Check() error {
+	if u.ID == "" {
+		return trace.BadParameter("missing fleet upgrade ID")
+	}
+	if u.Created.IsZero() {
+		return trace.BadParameter("missing fleet upgrade creation time")
+	}
+	if u.App == "" {
+		return trace.BadParameter("missing fleet upgrade application")
+	}
+	if u.State == "" {
+		return trace.BadParameter("missing fleet upgrade state")
+	}
+	return nil
+}
+
+// Failures returns the number of clusters that have failed to upgrade
+func (u FleetUpgrade) Failures() (failures int) {
+	for _, cluster := range u.Clusters {
+		if cluster.State == FleetUpgradeStateFailed {
+			failures++
+		}
+	}
+	return failures
+}
+
+const (
+	// FleetUpgradeStatePending is the state of a cluster that has not been upgraded yet
+	FleetUpgradeStatePending = "pending"
+	// FleetUpgradeStateInProgress is the state of a fleet or cluster upgrade in progress
+	FleetUpgradeStateInProgress = "in_progress"
+	// FleetUpgradeStateCompleted is the state of a successfully finished upgrade
+	FleetUpgradeStateCompleted = "completed"
+	// FleetUpgradeStateFailed is the state of a cluster that has failed to upgrade
+	FleetUpgradeStateFailed = "failed"
+	// FleetUpgradeStateHalted is the state of a fleet upgrade stopped
+	// after too many clusters have failed to upgrade
+	FleetUpgradeStateHalted = "halted"
+	// FleetUpgradeStateSkipped is the state of a cluster that has not been
+	// upgraded because the fleet upgrade has been halted or canceled
+	FleetUpgradeStateSkipped = "skipped"
+	// FleetUpgradeStateCanceled is the state of a canceled fleet upgrade
+	// and of the clusters whose upgrade was in progress when it was canceled
+	FleetUpgradeStateCanceled = "canceled"
+)
+
 // Charts defines methods related to Helm chart repository functionality.
 type Charts interface {
 	// GetIndexFile returns the chart repository index file.
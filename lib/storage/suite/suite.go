@@ -19,6 +19,8 @@ limitations under the License.
 package suite
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"time"
@@ -1388,6 +1390,20 @@ func (s *StorageSuite) ProvisioningTokensCRUD(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(tokens, DeepEquals, []storage.ProvisioningToken{token1, token2})
 
+	// record a use of the token
+	used := token2
+	used.Uses = 1
+	err = s.Backend.CompareAndSwapProvisioningToken(used, token2)
+	c.Assert(err, IsNil)
+
+	tokout, err = s.Backend.GetProvisioningToken(token2.Token)
+	c.Assert(err, IsNil)
+	c.Assert(*tokout, DeepEquals, used)
+
+	// stale value is rejected
+	err = s.Backend.CompareAndSwapProvisioningToken(used, token2)
+	c.Assert(trace.IsCompareFailed(err), Equals, true, Commentf("%v", err))
+
 	// explicitly delete long lived token
 	err = s.Backend.DeleteProvisioningToken(token2.Token)
 	c.Assert(err, IsNil)
@@ -1624,6 +1640,61 @@ func (s *StorageSuite) PeersCRUD(c *C) {
 	c.Assert(len(out), Equals, 0)
 }
 
+// Watch tests streaming of backend changes
+func (s *StorageSuite) Watch(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watcher, err := s.Backend.Watch(ctx, "peers")
+	c.Assert(err, IsNil)
+	otherWatcher, err := s.Backend.Watch(ctx, "objects")
+	c.Assert(err, IsNil)
+
+	peer := storage.Peer{ID: "p1", AdvertiseAddr: "https://127.0.0.1:4444"}
+	c.Assert(s.Backend.UpsertPeer(peer), IsNil)
+	event := expectWatchEvent(c, watcher)
+	c.Assert(event.Type, Equals, storage.WatchEventCreated)
+	c.Assert(event.Key, DeepEquals, []string{"peers", "p1"})
+	var out storage.Peer
+	c.Assert(json.Unmarshal(event.Value, &out), IsNil)
+	c.Assert(out.AdvertiseAddr, Equals, peer.AdvertiseAddr)
+
+	c.Assert(s.Backend.UpsertPeer(peer), IsNil)
+	event = expectWatchEvent(c, watcher)
+	c.Assert(event.Type, Equals, storage.WatchEventUpdated)
+
+	c.Assert(s.Backend.DeletePeer(peer.ID), IsNil)
+	event = expectWatchEvent(c, watcher)
+	c.Assert(event.Type, Equals, storage.WatchEventDeleted)
+	c.Assert(event.Key, DeepEquals, []string{"peers", "p1"})
+
+	select {
+	case event := <-otherWatcher.Events():
+		c.Fatalf("unexpected event %v", event)
+	default:
+	}
+
+	c.Assert(watcher.Close(), IsNil)
+	_, ok := <-watcher.Events()
+	c.Assert(ok, Equals, false, Commentf("expected the events channel to be closed"))
+	c.Assert(watcher.Error(), IsNil)
+
+	cancel()
+	for range otherWatcher.Events() {
+	}
+	c.Assert(otherWatcher.Error(), IsNil)
+}
+
+func expectWatchEvent(c *C, watcher storage.Watcher) storage.WatchEvent {
+	select {
+	case event, ok := <-watcher.Events():
+		c.Assert(ok, Equals, true, Commentf("watcher has stopped: %v", watcher.Error()))
+		return event
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for watch event")
+	}
+	return storage.WatchEvent{}
+}
+
 // ObjectsCRUD tests objects peers operations
 func (s *StorageSuite) ObjectsCRUD(c *C) {
 	out, err := s.Backend.GetObjects()
@@ -1957,6 +2028,354 @@ func (s *StorageSuite) IndexFile(c *C) {
 	compare.DeepCompare(c, retrievedFile, updatedIndex2)
 }
 
+func (s *StorageSuite) TrustedKeysCRUD(c *C) {
+	keys, err := s.Backend.GetTrustedKeys()
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 0)
+
+	key := storage.TrustedKey{
+		Name:      "release",
+		PublicKey: []byte("public key"),
+		Created:   now,
+	}
+	c.Assert(s.Backend.UpsertTrustedKey(key), IsNil)
+
+	out, err := s.Backend.GetTrustedKey(key.Name)
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, &key)
+
+	key.PublicKey = []byte("rotated public key")
+	c.Assert(s.Backend.UpsertTrustedKey(key), IsNil)
+	keys, err = s.Backend.GetTrustedKeys()
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, keys, []storage.TrustedKey{key})
+
+	c.Assert(s.Backend.DeleteTrustedKey(key.Name), IsNil)
+	_, err = s.Backend.GetTrustedKey(key.Name)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+	err = s.Backend.DeleteTrustedKey(key.Name)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	err = s.Backend.UpsertTrustedKey(storage.TrustedKey{Name: "empty"})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *StorageSuite) AuditEventsCRUD(c *C) {
+	events, err := s.Backend.GetAuditEvents(time.Time{})
+	c.Assert(err, IsNil)
+	c.Assert(events, HasLen, 0)
+
+	older := storage.AuditEvent{
+		ID:     "1",
+		Time:   now.Add(-time.Hour),
+		User:   "alice@example.com",
+		Action: "POST /portal/v1/accounts/:account_id/sites",
+		Path:   "/portal/v1/accounts/system/sites",
+		Params: map[string]string{"account_id": "system"},
+	}
+	newer := storage.AuditEvent{
+		ID:     "2",
+		Time:   now,
+		User:   "bob@example.com",
+		Action: "DELETE /portal/v1/accounts/:account_id/sites/:site_domain",
+		Path:   "/portal/v1/accounts/system/sites/example.com",
+		Params: map[string]string{"account_id": "system", "site_domain": "example.com"},
+		Error:  "access denied",
+	}
+	c.Assert(s.Backend.CreateAuditEvent(newer, storage.Forever), IsNil)
+	c.Assert(s.Backend.CreateAuditEvent(older, storage.Forever), IsNil)
+
+	err = s.Backend.CreateAuditEvent(older, storage.Forever)
+	c.Assert(trace.IsAlreadyExists(err), Equals, true, Commentf("%v", err))
+
+	events, err = s.Backend.GetAuditEvents(time.Time{})
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, events, []storage.AuditEvent{older, newer})
+
+	events, err = s.Backend.GetAuditEvents(now.Add(-time.Minute))
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, events, []storage.AuditEvent{newer})
+
+	err = s.Backend.CreateAuditEvent(storage.AuditEvent{ID: "3"}, storage.Forever)
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *StorageSuite) StatusHistoryCRUD(c *C) {
+	const clusterName = "example.com"
+
+	snapshots, err := s.Backend.GetStatusSnapshots(clusterName, time.Time{})
+	c.Assert(err, IsNil)
+	c.Assert(snapshots, HasLen, 0)
+
+	older := storage.StatusSnapshot{
+		ID:          "1",
+		ClusterName: clusterName,
+		Time:        now.Add(-time.Hour),
+		State:       "active",
+		Nodes: []storage.NodeStatusSnapshot{
+			{Hostname: "node-1", AdvertiseIP: "10.0.0.1", Status: "healthy"},
+		},
+	}
+	newer := storage.StatusSnapshot{
+		ID:          "2",
+		ClusterName: clusterName,
+		Time:        now,
+		State:       "degraded",
+		Reason:      storage.ReasonClusterDegraded,
+		Error:       "cluster is not healthy",
+		Nodes: []storage.NodeStatusSnapshot{
+			{
+				Hostname:     "node-1",
+				AdvertiseIP:  "10.0.0.1",
+				Status:       "degraded",
+				FailedProbes: []string{"docker is not running"},
+			},
+		},
+	}
+	c.Assert(s.Backend.CreateStatusSnapshot(newer, storage.Forever), IsNil)
+	c.Assert(s.Backend.CreateStatusSnapshot(older, storage.Forever), IsNil)
+
+	err = s.Backend.CreateStatusSnapshot(older, storage.Forever)
+	c.Assert(trace.IsAlreadyExists(err), Equals, true, Commentf("%v", err))
+
+	snapshots, err = s.Backend.GetStatusSnapshots(clusterName, time.Time{})
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, snapshots, []storage.StatusSnapshot{older, newer})
+
+	snapshots, err = s.Backend.GetStatusSnapshots(clusterName, now.Add(-time.Minute))
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, snapshots, []storage.StatusSnapshot{newer})
+	c.Assert(newer.IsTransition(older), Equals, true)
+	c.Assert(newer.IsTransition(newer), Equals, false)
+
+	err = s.Backend.CreateStatusSnapshot(storage.StatusSnapshot{ID: "3"}, storage.Forever)
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *StorageSuite) FleetUpgradesCRUD(c *C) {
+	upgrades, err := s.Backend.GetFleetUpgrades()
+	c.Assert(err, IsNil)
+	c.Assert(upgrades, HasLen, 0)
+
+	older := storage.FleetUpgrade{
+		ID:          "1",
+		Created:     now.Add(-time.Hour),
+		App:         "example.com/app:1.0.0",
+		Canaries:    1,
+		Concurrency: 1,
+		MaxFailures: 1,
+		State:       storage.FleetUpgradeStateCompleted,
+	}
+	newer := storage.FleetUpgrade{
+		ID:          "2",
+		Created:     now,
+		CreatedBy:   "alice@example.com",
+		AccountID:   "system",
+		App:         "example.com/app:2.0.0",
+		Selector:    map[string]string{"env": "prod"},
+		Canaries:    1,
+		Concurrency: 2,
+		MaxFailures: 1,
+		State:       storage.FleetUpgradeStateInProgress,
+		Clusters: []storage.FleetUpgradeCluster{
+			{Name: "a.example.com", State: storage.FleetUpgradeStatePending},
+			{Name: "b.example.com", State: storage.FleetUpgradeStatePending},
+		},
+	}
+	_, err = s.Backend.CreateFleetUpgrade(newer)
+	c.Assert(err, IsNil)
+	_, err = s.Backend.CreateFleetUpgrade(older)
+	c.Assert(err, IsNil)
+	_, err = s.Backend.CreateFleetUpgrade(older)
+	c.Assert(trace.IsAlreadyExists(err), Equals, true, Commentf("%v", err))
+
+	newer.Clusters[0].State = storage.FleetUpgradeStateFailed
+	newer.Clusters[0].Error = "update operation failed"
+	newer.Clusters[1].State = storage.FleetUpgradeStateSkipped
+	newer.State = storage.FleetUpgradeStateHalted
+	_, err = s.Backend.UpdateFleetUpgrade(newer)
+	c.Assert(err, IsNil)
+	c.Assert(newer.Failures(), Equals, 1)
+
+	out, err := s.Backend.GetFleetUpgrade(newer.ID)
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, &newer)
+
+	upgrades, err = s.Backend.GetFleetUpgrades()
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, upgrades, []storage.FleetUpgrade{older, newer})
+
+	_, err = s.Backend.GetFleetUpgrade("3")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+	_, err = s.Backend.CreateFleetUpgrade(storage.FleetUpgrade{ID: "3"})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *StorageSuite) ScheduledOperationsCRUD(c *C) {
+	ops, err := s.Backend.GetScheduledOperations("example.com")
+	c.Assert(err, IsNil)
+	c.Assert(ops, HasLen, 0)
+
+	older := storage.ScheduledOperation{
+		ID:          "1",
+		AccountID:   "system",
+		ClusterName: "example.com",
+		Type:        "operation_update",
+		Created:     now.Add(-time.Hour),
+		Schedule:    storage.OperationSchedule{NotBefore: now},
+		App:         "example.com/app:2.0.0",
+		State:       storage.ScheduledOperationStatePending,
+	}
+	newer := storage.ScheduledOperation{
+		ID:          "2",
+		AccountID:   "system",
+		ClusterName: "example.com",
+		Type:        "operation_update",
+		Created:     now,
+		CreatedBy:   "alice@example.com",
+		Schedule: storage.OperationSchedule{
+			Window: &storage.MaintenanceWindow{Cron: "0 2 * * 6", Duration: 4 * time.Hour},
+		},
+		App:   "example.com/app:3.0.0",
+		State: storage.ScheduledOperationStatePending,
+	}
+	other := storage.ScheduledOperation{
+		ID:          "3",
+		ClusterName: "other.example.com",
+		Type:        "operation_update",
+		Created:     now,
+		Schedule:    storage.OperationSchedule{NotBefore: now},
+		State:       storage.ScheduledOperationStatePending,
+	}
+	for _, op := range []storage.ScheduledOperation{newer, older, other} {
+		_, err = s.Backend.CreateScheduledOperation(op)
+		c.Assert(err, IsNil)
+	}
+	_, err = s.Backend.CreateScheduledOperation(older)
+	c.Assert(trace.IsAlreadyExists(err), Equals, true, Commentf("%v", err))
+
+	older.State = storage.ScheduledOperationStateStarted
+	older.OperationID = "op-1"
+	_, err = s.Backend.UpdateScheduledOperation(older)
+	c.Assert(err, IsNil)
+
+	out, err := s.Backend.GetScheduledOperation(older.ClusterName, older.ID)
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, &older)
+
+	ops, err = s.Backend.GetScheduledOperations("example.com")
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, ops, []storage.ScheduledOperation{older, newer})
+
+	_, err = s.Backend.GetScheduledOperation("example.com", "3")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+	_, err = s.Backend.CreateScheduledOperation(storage.ScheduledOperation{ID: "4"})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *StorageSuite) RoleMappingsCRUD(c *C) {
+	mappings, err := s.Backend.GetRoleMappings()
+	c.Assert(err, IsNil)
+	c.Assert(mappings, HasLen, 0)
+
+	admins := storage.NewRoleMapping("admins", storage.RoleMappingSpecV1{
+		Connector: "google",
+		Groups:    []string{"ops"},
+		Roles:     []string{"@teleadmin"},
+	})
+	users := storage.NewRoleMapping("users", storage.RoleMappingSpecV1{
+		EmailDomains: []string{"example.com"},
+		Roles:        []string{"user"},
+	})
+	c.Assert(s.Backend.UpsertRoleMapping(users), IsNil)
+	c.Assert(s.Backend.UpsertRoleMapping(admins), IsNil)
+
+	out, err := s.Backend.GetRoleMapping("admins")
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, admins)
+
+	mappings, err = s.Backend.GetRoleMappings()
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, mappings, []storage.RoleMapping{admins, users})
+
+	c.Assert(s.Backend.DeleteRoleMapping("admins"), IsNil)
+	_, err = s.Backend.GetRoleMapping("admins")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+	err = s.Backend.DeleteRoleMapping("admins")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	err = s.Backend.UpsertRoleMapping(storage.NewRoleMapping("empty", storage.RoleMappingSpecV1{
+		Roles: []string{"user"},
+	}))
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *StorageSuite) CatalogEntriesCRUD(c *C) {
+	entries, err := s.Backend.GetCatalogEntries("example.com")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 0)
+
+	stable := storage.CatalogEntry{
+		Repository:  "example.com",
+		Name:        "app",
+		Version:     "1.10.0",
+		Channel:     storage.CatalogChannelStable,
+		Icon:        "https://example.com/app.png",
+		Readme:      "# Application",
+		Upgrade:     storage.CatalogUpgrade{MinVersion: "1.2.0", ReleaseNotes: "Bug fixes"},
+		Published:   now,
+		PublishedBy: "alice@example.com",
+	}
+	older := storage.CatalogEntry{
+		Repository: "example.com",
+		Name:       "app",
+		Version:    "1.9.0",
+		Channel:    storage.CatalogChannelStable,
+		Published:  now.Add(-time.Hour),
+	}
+	beta := storage.CatalogEntry{
+		Repository: "example.com",
+		Name:       "app",
+		Version:    "2.0.0-beta.1",
+		Channel:    storage.CatalogChannelBeta,
+		Published:  now,
+	}
+	other := storage.CatalogEntry{
+		Repository: "other.example.com",
+		Name:       "app",
+		Version:    "1.0.0",
+		Channel:    storage.CatalogChannelStable,
+		Published:  now,
+	}
+	for _, entry := range []storage.CatalogEntry{beta, stable, older, other} {
+		_, err = s.Backend.UpsertCatalogEntry(entry)
+		c.Assert(err, IsNil)
+	}
+
+	out, err := s.Backend.GetCatalogEntry("example.com", "app", "1.10.0")
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, &stable)
+
+	entries, err = s.Backend.GetCatalogEntries("example.com")
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, entries, []storage.CatalogEntry{older, stable, beta})
+
+	c.Assert(s.Backend.DeleteCatalogEntry("example.com", "app", "1.9.0"), IsNil)
+	_, err = s.Backend.GetCatalogEntry("example.com", "app", "1.9.0")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+	err = s.Backend.DeleteCatalogEntry("example.com", "app", "1.9.0")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	_, err = s.Backend.UpsertCatalogEntry(storage.CatalogEntry{
+		Repository: "example.com",
+		Name:       "app",
+		Version:    "3.0.0",
+		Channel:    "nightly",
+		Published:  now,
+	})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
 func newIndex() *repo.IndexFile {
 	return &repo.IndexFile{
 		APIVersion: repo.APIVersionV1,
@@ -1973,3 +2392,192 @@ func addToIndex(indexFile repo.IndexFile, name, version string) (updated, previo
 	}}
 	return indexCopy, &indexFile
 }
+
+func (s *StorageSuite) ConfigResourcesCRUD(c *C) {
+	err := storage.RegisterResourceKind(storage.ResourceKind{
+		Kind:    "testgreeting",
+		Aliases: []string{"testgreetings"},
+		SpecSchema: `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["message"],
+  "properties": {"message": {"type": "string"}}
+}`,
+	})
+	if err != nil && !trace.IsAlreadyExists(err) {
+		c.Assert(err, IsNil)
+	}
+
+	resources, err := s.Backend.GetConfigResources("testgreeting")
+	c.Assert(err, IsNil)
+	c.Assert(resources, HasLen, 0)
+
+	for _, name := range []string{"world", "hello"} {
+		err = s.Backend.UpsertConfigResource(storage.UnknownResource{
+			ResourceHeader: teleservices.ResourceHeader{Kind: "testgreeting"},
+			Raw: []byte(fmt.Sprintf(`{"kind": "testgreeting", "version": "v2",
+"metadata": {"name": %q}, "spec": {"message": "hi"}}`, name)),
+		})
+		c.Assert(err, IsNil)
+	}
+
+	out, err := s.Backend.GetConfigResource("testgreetings", "hello")
+	c.Assert(err, IsNil)
+	c.Assert(out.Kind, Equals, "testgreeting")
+	c.Assert(out.Metadata.Name, Equals, "hello")
+	c.Assert(out.Metadata.Namespace, Equals, defaults.Namespace)
+
+	resources, err = s.Backend.GetConfigResources("testgreeting")
+	c.Assert(err, IsNil)
+	c.Assert(resources, HasLen, 2)
+	c.Assert(resources[0].Metadata.Name, Equals, "hello")
+	c.Assert(resources[1].Metadata.Name, Equals, "world")
+
+	err = s.Backend.UpsertConfigResource(storage.UnknownResource{
+		ResourceHeader: teleservices.ResourceHeader{Kind: "testgreeting"},
+		Raw: []byte(`{"kind": "testgreeting", "version": "v2",
+"metadata": {"name": "invalid"}, "spec": {"greeting": "hi"}}`),
+	})
+	c.Assert(err, NotNil)
+
+	err = s.Backend.UpsertConfigResource(storage.UnknownResource{
+		ResourceHeader: teleservices.ResourceHeader{Kind: "unregistered"},
+		Raw:            []byte(`{"kind": "unregistered", "version": "v2", "metadata": {"name": "x"}, "spec": {}}`),
+	})
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	c.Assert(s.Backend.DeleteConfigResource("testgreeting", "hello"), IsNil)
+	_, err = s.Backend.GetConfigResource("testgreeting", "hello")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+	err = s.Backend.DeleteConfigResource("testgreeting", "hello")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *StorageSuite) WebhooksCRUD(c *C) {
+	webhooks, err := s.Backend.GetWebhooks("example.com")
+	c.Assert(err, IsNil)
+	c.Assert(webhooks, HasLen, 0)
+
+	slack := storage.NewWebhook("slack", storage.WebhookSpecV2{
+		URL:    "https://hooks.slack.com/services/T00/B00/XXX",
+		Secret: "secret",
+		Events: []string{storage.WebhookEventOperationFailed},
+	})
+	ci := storage.NewWebhook("ci", storage.WebhookSpecV2{
+		URL: "https://ci.example.com/gravity",
+	})
+	c.Assert(s.Backend.UpsertWebhook("example.com", slack), IsNil)
+	c.Assert(s.Backend.UpsertWebhook("example.com", ci), IsNil)
+
+	out, err := s.Backend.GetWebhook("example.com", "slack")
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, slack)
+
+	webhooks, err = s.Backend.GetWebhooks("example.com")
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, webhooks, []storage.Webhook{ci, slack})
+
+	c.Assert(s.Backend.DeleteWebhook("example.com", "slack"), IsNil)
+	_, err = s.Backend.GetWebhook("example.com", "slack")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+	err = s.Backend.DeleteWebhook("example.com", "slack")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	err = s.Backend.UpsertWebhook("example.com", storage.NewWebhook("plain", storage.WebhookSpecV2{
+		URL: "http://ci.example.com/gravity",
+	}))
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *StorageSuite) WebhooksIsolatedByCluster(c *C) {
+	webhook := storage.NewWebhook("ci", storage.WebhookSpecV2{
+		URL:    "https://ci.example.com/gravity",
+		Secret: "secret",
+	})
+	c.Assert(s.Backend.UpsertWebhook("a.example.com", webhook), IsNil)
+
+	webhooks, err := s.Backend.GetWebhooks("b.example.com")
+	c.Assert(err, IsNil)
+	c.Assert(webhooks, HasLen, 0)
+	_, err = s.Backend.GetWebhook("b.example.com", "ci")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+	err = s.Backend.DeleteWebhook("b.example.com", "ci")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	// Webhook with the same name in another cluster does not overwrite it
+	c.Assert(s.Backend.UpsertWebhook("b.example.com", storage.NewWebhook("ci", storage.WebhookSpecV2{
+		URL: "https://attacker.example.com",
+	})), IsNil)
+	out, err := s.Backend.GetWebhook("a.example.com", "ci")
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, webhook)
+}
+
+func (s *StorageSuite) TerraformStatesCRUD(c *C) {
+	_, err := s.Backend.GetTerraformState("aws")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	c.Assert(s.Backend.UpsertTerraformState("aws", []byte(`{"version": 4}`)), IsNil)
+	c.Assert(s.Backend.UpsertTerraformState("aws", []byte(`{"version": 4, "serial": 2}`)), IsNil)
+	state, err := s.Backend.GetTerraformState("aws")
+	c.Assert(err, IsNil)
+	c.Assert(string(state), Equals, `{"version": 4, "serial": 2}`)
+
+	c.Assert(s.Backend.DeleteTerraformState("aws"), IsNil)
+	err = s.Backend.DeleteTerraformState("aws")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *StorageSuite) ProvisionersCRUD(c *C) {
+	provisioners, err := s.Backend.GetProvisioners()
+	c.Assert(err, IsNil)
+	c.Assert(provisioners, HasLen, 0)
+
+	aws := storage.NewProvisioner("aws", storage.ProvisionerSpecV2{
+		Type: storage.ProvisionerTypeAWS,
+		AWS: &storage.AWSProvisionerSpec{
+			Region: "us-west-2",
+			Profiles: map[string]storage.AWSMachineSpec{
+				"worker": {InstanceType: "m5.xlarge", ImageID: "ami-0123456789"},
+			},
+		},
+	})
+	vsphere := storage.NewProvisioner("lab", storage.ProvisionerSpecV2{
+		Type: storage.ProvisionerTypeVSphere,
+		VSphere: &storage.VSphereProvisionerSpec{
+			Server:   "vcenter.example.com",
+			Username: "admin",
+			Password: "secret",
+			Profiles: map[string]storage.VSphereMachineSpec{
+				"worker": {Template: "vm-42"},
+			},
+		},
+	})
+	c.Assert(s.Backend.UpsertProvisioner(aws), IsNil)
+	c.Assert(s.Backend.UpsertProvisioner(vsphere), IsNil)
+
+	out, err := s.Backend.GetProvisioner("aws")
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, aws)
+
+	provisioners, err = s.Backend.GetProvisioners()
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, provisioners, []storage.Provisioner{aws, vsphere})
+
+	c.Assert(s.Backend.DeleteProvisioner("aws"), IsNil)
+	_, err = s.Backend.GetProvisioner("aws")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+	err = s.Backend.DeleteProvisioner("aws")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	err = s.Backend.UpsertProvisioner(storage.NewProvisioner("mismatch", storage.ProvisionerSpecV2{
+		Type: storage.ProvisionerTypeGCE,
+		AWS: &storage.AWSProvisionerSpec{
+			Region: "us-west-2",
+			Profiles: map[string]storage.AWSMachineSpec{
+				"worker": {InstanceType: "m5.xlarge", ImageID: "ami-0123456789"},
+			},
+		},
+	}))
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
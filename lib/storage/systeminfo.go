@@ -60,6 +60,8 @@ type System interface {
 	GetSwap() Swap
 	// GetNumCPU returns the number of CPUs
 	GetNumCPU() uint
+	// GetGPUs returns the list of GPUs
+	GetGPUs() []GPU
 	// GetProcesses returns the list of running processes
 	GetProcesses() []Process
 	// GetDevices returns the list of unallocated devices
@@ -150,6 +152,11 @@ func (r *SystemV2) GetNumCPU() uint {
 	return r.Spec.NumCPU
 }
 
+// GetGPUs returns the list of GPUs
+func (r *SystemV2) GetGPUs() []GPU {
+	return r.Spec.GPUs
+}
+
 // GetProcesses returns the list of running processes
 func (r *SystemV2) GetProcesses() []Process {
 	return r.Spec.Processes
@@ -208,6 +215,8 @@ type SystemSpecV2 struct {
 	Swap Swap `json:"swap"`
 	// NumCPU specifies the CPU count
 	NumCPU uint `json:"cpus"`
+	// GPUs lists the GPUs
+	GPUs []GPU `json:"gpus,omitempty"`
 	// Processes lists running processes
 	Processes []Process `json:"processes"`
 	// Devices lists the disks/partitions
@@ -231,10 +240,11 @@ func (r SystemV2) String() string {
 	for name, iface := range r.Spec.NetworkInterfaces {
 		ifaces = append(ifaces, fmt.Sprintf("%v=%v", name, iface.IPv4))
 	}
-	return fmt.Sprintf("sysinfo(hostname=%v, interfaces=%v, cpus=%v, ramMB=%v, OS=%v, user=%v, lvm_dir=%v)",
+	return fmt.Sprintf("sysinfo(hostname=%v, interfaces=%v, cpus=%v, gpus=%v, ramMB=%v, OS=%v, user=%v, lvm_dir=%v)",
 		r.Spec.Hostname,
 		strings.Join(ifaces, ","),
 		r.Spec.NumCPU,
+		len(r.Spec.GPUs),
 		r.Spec.Memory.Total/1000/1000,
 		r.Spec.OS,
 		r.Spec.User,
@@ -309,6 +319,18 @@ const SystemSpecV2Schema = `{
       }
     },
     "cpus": {"type": "integer"},
+    "gpus": {
+      "type": ["array", "null"],
+      "items": {
+        "type": "object",
+        "required": ["vendor", "address"],
+        "additionalProperties": false,
+        "properties": {
+          "vendor": {"type": "string"},
+          "address": {"type": "string"}
+        }
+      }
+    },
     "processes": {
       "type": ["array", "null"],
       "required": ["name", "pid"],
@@ -351,7 +373,8 @@ const SystemSpecV2Schema = `{
       "properties": {
         "name": {"type": "string"},
         "like": {"type": "array", "items": {"type": "string"}},
-        "version": {"type": "string"}
+        "version": {"type": "string"},
+        "arch": {"type": "string"}
       }
     },
     "lvm_system_dir": {"type": "string"},
@@ -406,6 +429,14 @@ type Swap struct {
 	Free uint64 `json:"free"`
 }
 
+// GPU describes a GPU
+type GPU struct {
+	// Vendor is the GPU vendor, e.g. "nvidia"
+	Vendor string `json:"vendor"`
+	// Address is the PCI address of the GPU
+	Address string `json:"address"`
+}
+
 // NetworkInterface represents a network interface
 type NetworkInterface struct {
 	// IPv4 address assigned to the interface
@@ -431,6 +462,8 @@ type OSInfo struct {
 	Like []string `json:"like,omitempty"`
 	// Version defines the numeric version of the system: `7.2`
 	Version string `json:"version"`
+	// Arch is the CPU architecture of the system in Go notation: `amd64` or `arm64`
+	Arch string `json:"arch,omitempty"`
 }
 
 // OSUser describes a user on host.
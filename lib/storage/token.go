@@ -37,6 +37,8 @@ type Token interface {
 	GetUser() string
 	// SetUser sets the token owner
 	SetUser(name string)
+	// GetPermissions returns the permissions the token is restricted to, if any
+	GetPermissions() ResourcePermissions
 	// CheckAndSetDefaults makes sure the token is valid
 	CheckAndSetDefaults() error
 }
@@ -58,7 +60,18 @@ func NewToken(name string, user string) Token {
 
 // NewTokenFromV1 creates token from API key
 func NewTokenFromV1(key APIKey) Token {
-	token := NewToken(key.Token, key.UserEmail)
+	token := &TokenV2{
+		Kind:    KindToken,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      key.Token,
+			Namespace: defaults.Namespace,
+		},
+		Spec: TokenSpecV2{
+			User:        key.UserEmail,
+			Permissions: key.Permissions,
+		},
+	}
 	if !key.Expires.IsZero() {
 		token.SetExpiry(key.Expires)
 	}
@@ -117,6 +130,11 @@ func (t *TokenV2) GetUser() string {
 	return t.Spec.User
 }
 
+// GetPermissions returns the permissions the token is restricted to
+func (t *TokenV2) GetPermissions() ResourcePermissions {
+	return t.Spec.Permissions
+}
+
 // Check checks validity of all parameters and sets defaults
 func (t *TokenV2) CheckAndSetDefaults() error {
 	if t.Metadata.Name == "" {
@@ -125,14 +143,18 @@ func (t *TokenV2) CheckAndSetDefaults() error {
 	if t.Spec.User == "" {
 		return trace.BadParameter("missing parameter User")
 	}
+	if err := t.Spec.Permissions.Check(); err != nil {
+		return trace.Wrap(err)
+	}
 	return nil
 }
 
 func (t *TokenV2) ToV1() *APIKey {
 	return &APIKey{
-		Token:     t.Metadata.Name,
-		Expires:   t.Metadata.Expiry(),
-		UserEmail: t.Spec.User,
+		Token:       t.Metadata.Name,
+		Expires:     t.Metadata.Expiry(),
+		UserEmail:   t.Spec.User,
+		Permissions: t.Spec.Permissions,
 	}
 }
 
@@ -192,6 +214,8 @@ func (*tokenMarshaler) MarshalToken(token Token, opts ...teleservices.MarshalOpt
 type TokenSpecV2 struct {
 	// User is username associated with this token
 	User string `json:"user"`
+	// Permissions optionally restricts the token to the specified permissions
+	Permissions ResourcePermissions `json:"permissions,omitempty"`
 }
 
 // TokenV2Schema is JSON schema for server
@@ -200,12 +224,13 @@ const TokenSpecV2Schema = `{
   "additionalProperties": false,
   "required": ["user"],
   "properties": {
-    "user": {"type": "string"}
+    "user": {"type": "string"},
+    "permissions": %v
   }
 }`
 
 // GetTokenSchema returns token schema for V2 resource
 func GetTokenSchema() string {
 	return fmt.Sprintf(teleservices.V2SchemaTemplate, teleservices.MetadataSchema,
-		TokenSpecV2Schema, "")
+		fmt.Sprintf(TokenSpecV2Schema, ResourcePermissionsSchema), "")
 }
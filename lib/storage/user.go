@@ -151,6 +151,9 @@ type User interface {
 	GetHOTP() []byte
 	// GetAccountID returns user account ID
 	GetAccountID() string
+	// GetPermissions returns the permissions granted to the user
+	// in addition to the permissions of the user roles
+	GetPermissions() ResourcePermissions
 	// GetClusterName returns cluster name of this user
 	GetClusterName() string
 	// SetClusterName sets cluster name of this user
@@ -327,7 +330,8 @@ const UserSpecV2Extension = `
   "hotp": {"type": "string"},
   "password": {"type": "string"},
   "ops_center": {"type": "string"},
-  "full_name": {"type": "string"}
+  "full_name": {"type": "string"},
+  "permissions": ` + ResourcePermissionsSchema + `
 `
 
 // UserSpecV2 is a specification for V2 user
@@ -382,6 +386,10 @@ type UserSpecV2 struct {
 	// FullName is full user name
 	FullName string `json:"full_name"`
 
+	// Permissions lists the permissions granted to the user
+	// in addition to the permissions of the user roles
+	Permissions ResourcePermissions `json:"permissions,omitempty"`
+
 	// Traits are key/value pairs received from an identity provider (through
 	// OIDC claims or SAML assertions) or from a system administrator for local
 	// accounts. Traits are used to populate role variables.
@@ -483,6 +491,12 @@ func (u *UserV2) GetAccountID() string {
 	return u.Spec.AccountID
 }
 
+// GetPermissions returns the permissions granted to the user
+// in addition to the permissions of the user roles
+func (u *UserV2) GetPermissions() ResourcePermissions {
+	return u.Spec.Permissions
+}
+
 // SetHOTP sets HOTP token value
 func (u *UserV2) SetHOTP(h []byte) {
 	u.Spec.HOTP = h
@@ -537,6 +551,9 @@ func (u *UserV2) Check() error {
 			return trace.Wrap(err)
 		}
 	}
+	if err := u.Spec.Permissions.Check(); err != nil {
+		return trace.Wrap(err)
+	}
 	return nil
 }
 
@@ -594,7 +611,14 @@ func (u *UserV1) V1() *UserV1 {
 }
 
 //V2 converts UserV1 to UserV2 format
+//
+// Users created before accounts were enforced are assigned
+// to the system account
 func (u *UserV1) V2() *UserV2 {
+	accountID := u.AccountID
+	if accountID == "" {
+		accountID = defaults.SystemAccountID
+	}
 	return &UserV2{
 		Kind:    teleservices.KindUser,
 		Version: teleservices.V2,
@@ -603,7 +627,7 @@ func (u *UserV1) V2() *UserV2 {
 			Namespace: teledefaults.Namespace,
 		},
 		Spec: UserSpecV2{
-			AccountID:      u.AccountID,
+			AccountID:      accountID,
 			OIDCIdentities: u.Identities,
 			ClusterName:    u.SiteDomain,
 			Type:           u.Type,
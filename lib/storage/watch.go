@@ -0,0 +1,74 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Watches allows to subscribe to backend changes
+type Watches interface {
+	// Watch returns a watcher that streams changes of the keys
+	// with the specified prefix until either the context is cancelled
+	// or the watcher is closed.
+	//
+	// The prefix is given as a path of key components in the backend
+	// layout, e.g. "sites", domain, "ops" selects operations of a cluster
+	Watch(ctx context.Context, prefix ...string) (Watcher, error)
+}
+
+// Watcher streams backend change events
+type Watcher interface {
+	// Events returns the channel with change events.
+	// The channel is closed when the watcher stops
+	Events() <-chan WatchEvent
+	// Error returns the reason the watcher has stopped
+	// or nil if it has been closed or its context has been cancelled
+	Error() error
+	// Close stops the watcher
+	Close() error
+}
+
+// WatchEvent describes a change of a backend key
+type WatchEvent struct {
+	// Type is the event type
+	Type WatchEventType
+	// Key is the path of the changed key
+	Key []string
+	// Value is the new value of the key, empty for deleted keys
+	Value []byte
+}
+
+// String returns a textual representation of this event
+func (e WatchEvent) String() string {
+	return fmt.Sprintf("%v(%v)", e.Type, strings.Join(e.Key, "/"))
+}
+
+// WatchEventType defines the type of the change
+type WatchEventType string
+
+const (
+	// WatchEventCreated is emitted when a key is created
+	WatchEventCreated WatchEventType = "created"
+	// WatchEventUpdated is emitted when the value of a key changes
+	WatchEventUpdated WatchEventType = "updated"
+	// WatchEventDeleted is emitted when a key is deleted or expires.
+	// Deleting a directory may generate a single event for the directory
+	WatchEventDeleted WatchEventType = "deleted"
+)
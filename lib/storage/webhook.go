@@ -0,0 +1,258 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+)
+
+// Webhook defines an HTTPS endpoint notified about cluster operation events
+type Webhook interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// GetURL returns the URL events are posted to
+	GetURL() string
+	// GetSecret returns the secret used to sign event payloads
+	GetSecret() string
+	// GetEvents returns the types of events the webhook is notified about
+	GetEvents() []string
+	// GetCAData returns the PEM-encoded certificate authority used to
+	// verify the endpoint certificate
+	GetCAData() string
+	// Matches returns true if the webhook should be notified about
+	// the event of the specified type
+	Matches(event string) bool
+	// CheckAndSetDefaults validates the resource and sets defaults
+	CheckAndSetDefaults() error
+}
+
+// NewWebhook creates a new webhook resource
+func NewWebhook(name string, spec WebhookSpecV2) Webhook {
+	return &WebhookV2{
+		Kind:    KindWebhook,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      name,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// WebhookV2 defines the webhook resource
+type WebhookV2 struct {
+	// Kind is the resource kind
+	Kind string `json:"kind"`
+	// Version is the resource version
+	Version string `json:"version"`
+	// Metadata is the resource metadata
+	Metadata teleservices.Metadata `json:"metadata"`
+	// Spec is the resource specification
+	Spec WebhookSpecV2 `json:"spec"`
+}
+
+// WebhookSpecV2 defines the webhook resource specification
+type WebhookSpecV2 struct {
+	// URL is the HTTPS URL events are posted to
+	URL string `json:"url"`
+	// Secret is the shared secret used to compute the HMAC-SHA256
+	// signature of event payloads. If empty, payloads are not signed
+	Secret string `json:"secret,omitempty"`
+	// Events lists the types of events the webhook is notified about.
+	// If empty, the webhook is notified about all events
+	Events []string `json:"events,omitempty"`
+	// CAData is the optional PEM-encoded certificate authority used to
+	// verify the endpoint certificate in addition to the system roots
+	CAData string `json:"ca_data,omitempty"`
+}
+
+// GetURL returns the URL events are posted to
+func (r *WebhookV2) GetURL() string {
+	return r.Spec.URL
+}
+
+// GetSecret returns the secret used to sign event payloads
+func (r *WebhookV2) GetSecret() string {
+	return r.Spec.Secret
+}
+
+// GetEvents returns the types of events the webhook is notified about
+func (r *WebhookV2) GetEvents() []string {
+	if len(r.Spec.Events) == 0 {
+		return WebhookEvents
+	}
+	return r.Spec.Events
+}
+
+// GetCAData returns the PEM-encoded certificate authority used to
+// verify the endpoint certificate
+func (r *WebhookV2) GetCAData() string {
+	return r.Spec.CAData
+}
+
+// Matches returns true if the webhook should be notified about
+// the event of the specified type
+func (r *WebhookV2) Matches(event string) bool {
+	return utils.StringInSlice(r.GetEvents(), event)
+}
+
+// CheckAndSetDefaults validates the resource and sets defaults
+func (r *WebhookV2) CheckAndSetDefaults() error {
+	if r.Metadata.Name == "" {
+		return trace.BadParameter("webhook name can't be empty")
+	}
+	u, err := url.Parse(r.Spec.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return trace.BadParameter("webhook %q: URL should be an https URL, got %q",
+			r.Metadata.Name, r.Spec.URL)
+	}
+	for _, event := range r.Spec.Events {
+		if !utils.StringInSlice(WebhookEvents, event) {
+			return trace.BadParameter("webhook %q: unsupported event %q, supported are: %v",
+				r.Metadata.Name, event, strings.Join(WebhookEvents, ", "))
+		}
+	}
+	if r.Spec.CAData != "" {
+		if block, _ := pem.Decode([]byte(r.Spec.CAData)); block == nil {
+			return trace.BadParameter("webhook %q: CA data is not PEM-encoded",
+				r.Metadata.Name)
+		}
+	}
+	return nil
+}
+
+// GetName returns the resource name
+func (r *WebhookV2) GetName() string {
+	return r.Metadata.Name
+}
+
+// SetName sets the resource name
+func (r *WebhookV2) SetName(name string) {
+	r.Metadata.Name = name
+}
+
+// GetMetadata returns the resource metadata
+func (r *WebhookV2) GetMetadata() teleservices.Metadata {
+	return r.Metadata
+}
+
+// SetExpiry sets the resource expiration time
+func (r *WebhookV2) SetExpiry(expires time.Time) {
+	r.Metadata.SetExpiry(expires)
+}
+
+// Expiry returns the resource expiration time
+func (r *WebhookV2) Expiry() time.Time {
+	return r.Metadata.Expiry()
+}
+
+// SetTTL sets the resource TTL
+func (r *WebhookV2) SetTTL(clock clockwork.Clock, ttl time.Duration) {
+	r.Metadata.SetTTL(clock, ttl)
+}
+
+// String returns the object's string representation
+func (r *WebhookV2) String() string {
+	return fmt.Sprintf("Webhook(Name=%v, URL=%v, Events=%v)",
+		r.Metadata.Name, r.Spec.URL, r.GetEvents())
+}
+
+// UnmarshalWebhook unmarshals webhook resource from the provided JSON or YAML data
+func UnmarshalWebhook(data []byte) (Webhook, error) {
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var header teleservices.ResourceHeader
+	err = json.Unmarshal(jsonData, &header)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch header.Version {
+	case teleservices.V2:
+		var webhook WebhookV2
+		err := teleutils.UnmarshalWithSchema(GetWebhookSchema(), &webhook, jsonData)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		webhook.Metadata.CheckAndSetDefaults()
+		err = webhook.CheckAndSetDefaults()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &webhook, nil
+	}
+	return nil, trace.BadParameter("%v resource version %q is not supported",
+		KindWebhook, header.Version)
+}
+
+// MarshalWebhook marshals webhook resource to JSON
+func MarshalWebhook(webhook Webhook, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(webhook)
+}
+
+// GetWebhookSchema returns the full webhook resource schema
+func GetWebhookSchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, MetadataSchema,
+		WebhookSpecV2Schema, "")
+}
+
+// WebhookSpecV2Schema defines the webhook spec schema
+var WebhookSpecV2Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["url"],
+  "properties": {
+    "url": {"type": "string"},
+    "secret": {"type": "string"},
+    "events": {"type": "array", "items": {"type": "string"}},
+    "ca_data": {"type": "string"}
+  }
+}`
+
+const (
+	// WebhookEventOperationStarted is sent when an operation starts
+	WebhookEventOperationStarted = "operation.started"
+	// WebhookEventOperationPhaseChanged is sent when an operation plan phase
+	// changes its state
+	WebhookEventOperationPhaseChanged = "operation.phase_changed"
+	// WebhookEventOperationCompleted is sent when an operation completes successfully
+	WebhookEventOperationCompleted = "operation.completed"
+	// WebhookEventOperationFailed is sent when an operation fails
+	WebhookEventOperationFailed = "operation.failed"
+)
+
+// WebhookEvents lists the types of events webhooks can be notified about
+var WebhookEvents = []string{
+	WebhookEventOperationStarted,
+	WebhookEventOperationPhaseChanged,
+	WebhookEventOperationCompleted,
+	WebhookEventOperationFailed,
+}
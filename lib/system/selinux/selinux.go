@@ -0,0 +1,115 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// package selinux implements support for running on hosts
+// with SELinux enabled
+package selinux
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// State describes the SELinux mode of the host
+type State string
+
+const (
+	// StateEnforcing is the mode that enforces the loaded policy
+	StateEnforcing State = "enforcing"
+	// StatePermissive is the mode that only logs policy violations
+	StatePermissive State = "permissive"
+	// StateDisabled is the state of the host without SELinux
+	StateDisabled State = "disabled"
+)
+
+// Enabled returns true if SELinux is either in enforcing or permissive mode
+func (r State) Enabled() bool {
+	return r == StateEnforcing || r == StatePermissive
+}
+
+// GetState returns the SELinux state of this host
+func GetState() (State, error) {
+	return getState(fsDir)
+}
+
+// LoadPolicy installs the policy module from the specified file
+// and labels the gravity state directory stateDir according to the policy
+func LoadPolicy(ctx context.Context, path, stateDir string, logger logrus.FieldLogger) error {
+	out, err := run(ctx, logger, semoduleCmd, "--install", path)
+	if err != nil {
+		return trace.Wrap(err, "failed to install SELinux policy module %v: %s", path, out)
+	}
+	logger.Infof("Installed SELinux policy module %v.", path)
+	if stateDir != defaults.GravityDir {
+		// Label the custom state directory the same as the default one
+		out, err := run(ctx, logger, semanageCmd, "fcontext",
+			"--add", "--equal", defaults.GravityDir, stateDir)
+		if err != nil && !strings.Contains(string(out), "already defined") {
+			return trace.Wrap(err, "failed to label %v: %s", stateDir, out)
+		}
+	}
+	out, err = run(ctx, logger, restoreconCmd, "-R", stateDir)
+	if err != nil {
+		return trace.Wrap(err, "failed to restore SELinux labels on %v: %s", stateDir, out)
+	}
+	logger.Infof("Labeled state directory %v.", stateDir)
+	return nil
+}
+
+// PolicyTools lists the tools required to load the policy module
+var PolicyTools = []string{semoduleCmd, semanageCmd, restoreconCmd}
+
+// run executes the command specified with args and returns its combined output
+func run(ctx context.Context, logger logrus.FieldLogger, args ...string) ([]byte, error) {
+	logger.Debugf("Executing %v.", strings.Join(args, " "))
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	return out, trace.Wrap(err)
+}
+
+func getState(dir string) (State, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "enforce"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return StateDisabled, nil
+		}
+		return "", trace.ConvertSystemError(err)
+	}
+	switch strings.TrimSpace(string(data)) {
+	case "1":
+		return StateEnforcing, nil
+	case "0":
+		return StatePermissive, nil
+	}
+	return "", trace.BadParameter("unexpected SELinux enforcing mode %q", data)
+}
+
+const (
+	// fsDir is the mount point of the SELinux filesystem
+	fsDir = "/sys/fs/selinux"
+
+	semoduleCmd   = "semodule"
+	semanageCmd   = "semanage"
+	restoreconCmd = "restorecon"
+)
@@ -0,0 +1,59 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selinux
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+func TestSELinux(t *testing.T) { check.TestingT(t) }
+
+type SELinuxSuite struct{}
+
+var _ = check.Suite(&SELinuxSuite{})
+
+func (s *SELinuxSuite) TestDetectsState(c *check.C) {
+	dir := c.MkDir()
+	state, err := getState(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(state, check.Equals, StateDisabled)
+	c.Assert(state.Enabled(), check.Equals, false)
+
+	var testCases = []struct {
+		enforce string
+		state   State
+	}{
+		{enforce: "1", state: StateEnforcing},
+		{enforce: "0\n", state: StatePermissive},
+	}
+	for _, tc := range testCases {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, "enforce"), []byte(tc.enforce), 0644), check.IsNil)
+		state, err := getState(dir)
+		c.Assert(err, check.IsNil)
+		c.Assert(state, check.Equals, tc.state)
+		c.Assert(state.Enabled(), check.Equals, true)
+	}
+
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "enforce"), []byte("2"), 0644), check.IsNil)
+	_, err = getState(dir)
+	c.Assert(trace.IsBadParameter(err), check.Equals, true)
+}
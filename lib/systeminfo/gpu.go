@@ -0,0 +1,89 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package systeminfo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// queryGPUs returns the GPUs found among the PCI devices in the specified
+// sysfs directory. The GPUs are detected by the PCI vendor and device class
+// so they are found even if the driver has not been installed
+func queryGPUs(pciDevicesDir string) (gpus []storage.GPU, err error) {
+	dirs, err := ioutil.ReadDir(pciDevicesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(pciDevicesDir, dir.Name())
+		class, err := readPCIAttribute(path, "class")
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if !strings.HasPrefix(class, pciClassDisplayController) {
+			continue
+		}
+		vendorID, err := readPCIAttribute(path, "vendor")
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		vendor, ok := gpuVendors[vendorID]
+		if !ok {
+			continue
+		}
+		gpus = append(gpus, storage.GPU{
+			Vendor:  vendor,
+			Address: dir.Name(),
+		})
+	}
+	return gpus, nil
+}
+
+func readPCIAttribute(devicePath, name string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(devicePath, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", trace.ConvertSystemError(err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// gpuVendors maps the PCI vendor IDs to the supported GPU vendors
+var gpuVendors = map[string]string{
+	"0x10de": schema.GPUVendorNvidia,
+}
+
+const (
+	// pciDevicesDir is the sysfs directory with PCI devices
+	pciDevicesDir = "/sys/bus/pci/devices"
+	// pciClassDisplayController is the PCI base class of display
+	// controllers which includes VGA and 3D controllers
+	pciClassDisplayController = "0x03"
+)
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package systeminfo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	. "gopkg.in/check.v1"
+)
+
+type GPUSuite struct{}
+
+var _ = Suite(&GPUSuite{})
+
+func (*GPUSuite) TestQueriesNvidiaGPUs(c *C) {
+	dir := c.MkDir()
+	devices := []struct {
+		address string
+		vendor  string
+		class   string
+	}{
+		// NVIDIA 3D controller
+		{address: "0000:00:1e.0", vendor: "0x10de", class: "0x030200"},
+		// NVIDIA VGA controller
+		{address: "0000:00:1f.0", vendor: "0x10de", class: "0x030000"},
+		// NVIDIA audio device of the same card
+		{address: "0000:00:1f.1", vendor: "0x10de", class: "0x040300"},
+		// on-board VGA controller
+		{address: "0000:00:02.0", vendor: "0x1013", class: "0x030000"},
+	}
+	for _, device := range devices {
+		path := filepath.Join(dir, device.address)
+		c.Assert(os.Mkdir(path, 0755), IsNil)
+		c.Assert(ioutil.WriteFile(filepath.Join(path, "vendor"), []byte(device.vendor+"\n"), 0644), IsNil)
+		c.Assert(ioutil.WriteFile(filepath.Join(path, "class"), []byte(device.class+"\n"), 0644), IsNil)
+	}
+
+	gpus, err := queryGPUs(dir)
+	c.Assert(err, IsNil)
+	c.Assert(gpus, DeepEquals, []storage.GPU{
+		{Vendor: "nvidia", Address: "0000:00:1e.0"},
+		{Vendor: "nvidia", Address: "0000:00:1f.0"},
+	})
+
+	gpus, err = queryGPUs(filepath.Join(dir, "missing"))
+	c.Assert(err, IsNil)
+	c.Assert(gpus, HasLen, 0)
+}
@@ -23,6 +23,7 @@ import (
 	"io/ioutil"
 	"os"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 
@@ -48,7 +49,12 @@ func OSInfo() (info *OS, err error) {
 		return nil, trace.Wrap(err)
 	}
 	defer file.Close()
-	return getInfo(file)
+	info, err = getInfo(file)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	info.Arch = runtime.GOARCH
+	return info, nil
 }
 
 func getInfo(r io.Reader) (info *OS, err error) {
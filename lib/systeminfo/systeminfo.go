@@ -135,6 +135,11 @@ func collect() (*storage.SystemSpecV2, error) {
 		return nil, trace.Wrap(err)
 	}
 
+	info.GPUs, err = queryGPUs(pciDevicesDir)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to query GPUs")
+	}
+
 	info.FilesystemStats, err = collectFilesystemUsage(info.Filesystems)
 	if err != nil {
 		return nil, trace.Wrap(err)
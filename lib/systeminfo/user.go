@@ -133,6 +133,57 @@ func checkUser(userName, userID string) error {
 	return nil
 }
 
+// CheckUser verifies that the specified user and group IDs do not collide
+// with existing system users and groups.
+//
+// If a user with the given user ID exists, it is used as-is and its primary
+// group must match the group ID, if one has been specified.
+// Otherwise, the user with the specified name and group is to be created,
+// so neither the names nor the IDs should be taken by other users or groups
+func CheckUser(name, group, uid, gid string) error {
+	if uid != "" {
+		id, err := strconv.Atoi(uid)
+		if err != nil {
+			return trace.BadParameter("expected a numeric user ID: %v (%v)", uid, err)
+		}
+		usr, err := LookupUserByUID(id)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		if usr != nil {
+			if gid != "" && strconv.Itoa(usr.GID) != gid {
+				return trace.AlreadyExists("user %q with uid %v already exists "+
+					"with primary gid %v, not %v", usr.Name, uid, usr.GID, gid)
+			}
+			return nil
+		}
+		if err := checkUser(name, uid); err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+	}
+	if gid == "" {
+		return nil
+	}
+	if _, err := strconv.Atoi(gid); err != nil {
+		return trace.BadParameter("expected a numeric group ID: %v (%v)", gid, err)
+	}
+	if err := checkGroup(group, gid); err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	grp, err := user.LookupGroupId(gid)
+	if err != nil {
+		if err = convertUserError(err); trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	if grp.Name != group {
+		return trace.AlreadyExists("group %q already exists with gid %v",
+			grp.Name, gid)
+	}
+	return nil
+}
+
 // NewUser creates a new user with the specified name and group.
 //
 // If group ID has been specified, the group is created with the given ID.
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package systeminfo
+
+import (
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type UserSuite struct{}
+
+var _ = Suite(&UserSuite{})
+
+func (r *UserSuite) TestChecksUserCollisions(c *C) {
+	// the tests rely on the root user and group which exist on every system
+	var testCases = []struct {
+		name, group, uid, gid string
+		collides              bool
+		comment               string
+	}{
+		{
+			name: "planet", group: "planet", uid: "0", gid: "0",
+			comment: "existing user is used as-is",
+		},
+		{
+			name: "planet", group: "planet", uid: "0",
+			comment: "group ID is optional for existing user",
+		},
+		{
+			name: "planet", group: "planet", uid: "0", gid: "65533",
+			collides: true,
+			comment:  "existing user has a different primary group",
+		},
+		{
+			name: "root", group: "planet", uid: "65533",
+			collides: true,
+			comment:  "user name is taken by a user with a different ID",
+		},
+		{
+			name: "planet", group: "root", uid: "65533", gid: "65533",
+			collides: true,
+			comment:  "group name is taken by a group with a different ID",
+		},
+		{
+			name: "planet", group: "planet", uid: "65533", gid: "0",
+			collides: true,
+			comment:  "group ID is taken by another group",
+		},
+	}
+	for _, tc := range testCases {
+		comment := Commentf(tc.comment)
+		err := CheckUser(tc.name, tc.group, tc.uid, tc.gid)
+		if tc.collides {
+			c.Assert(trace.IsAlreadyExists(err), Equals, true, comment)
+		} else {
+			c.Assert(err, IsNil, comment)
+		}
+	}
+	c.Assert(trace.IsBadParameter(CheckUser("planet", "planet", "planet", "")), Equals, true)
+}
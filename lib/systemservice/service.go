@@ -267,6 +267,11 @@ func New() (ServiceManager, error) {
 	return &systemdManager{}, nil
 }
 
+// PackageServiceName returns the name of the service that runs the specified package
+func PackageServiceName(pkg loc.Locator) string {
+	return newSystemdUnit(pkg).serviceName()
+}
+
 // CheckAndSetDefaults verifies that this specification object is valid
 func (r *MountServiceSpec) CheckAndSetDefaults() error {
 	if r.What == "" {
@@ -0,0 +1,205 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package terraform executes the Terraform configurations bundled with
+// the cluster image to provision the infrastructure before installation.
+//
+// The Terraform state is kept in the backend between runs so the same
+// infrastructure can be planned and updated repeatedly.
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// Config defines the Terraform configuration to execute
+type Config struct {
+	// Name identifies the state of the configuration in the backend
+	Name string
+	// Terraform is the Terraform configuration from the application manifest
+	Terraform schema.Terraform
+	// Variables overrides the values of the configuration variables
+	Variables map[string]string
+	// Backend stores the Terraform state
+	Backend storage.TerraformStates
+	// Binary is the path to the terraform binary
+	Binary string
+	// Out receives the output of the terraform commands
+	Out io.Writer
+	// FieldLogger is used for logging
+	log.FieldLogger
+}
+
+// CheckAndSetDefaults validates the config and sets defaults
+func (r *Config) CheckAndSetDefaults() error {
+	if r.Name == "" {
+		return trace.BadParameter("missing Name")
+	}
+	if r.Terraform.Script == "" {
+		return trace.BadParameter("missing Terraform script")
+	}
+	if r.Backend == nil {
+		return trace.BadParameter("missing Backend")
+	}
+	if r.Binary == "" {
+		r.Binary = defaults.TerraformBin
+	}
+	if r.Out == nil {
+		r.Out = os.Stdout
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "terraform")
+	}
+	return nil
+}
+
+// Plan displays the changes applying the configuration would make
+// to the infrastructure
+func Plan(ctx context.Context, config Config) error {
+	return trace.Wrap(run(ctx, config, "plan"))
+}
+
+// Apply provisions the infrastructure described by the configuration
+// and saves the resulting state in the backend
+func Apply(ctx context.Context, config Config) error {
+	return trace.Wrap(run(ctx, config, "apply", "-auto-approve"))
+}
+
+func run(ctx context.Context, config Config, args ...string) error {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	workDir, err := ioutil.TempDir("", "terraform")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(workDir)
+	err = ioutil.WriteFile(filepath.Join(workDir, configFilename),
+		[]byte(config.Terraform.Script), defaults.SharedReadMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	stateFile := filepath.Join(workDir, stateFilename)
+	if err := restoreState(config, stateFile); err != nil {
+		return trace.Wrap(err)
+	}
+	err = config.exec(ctx, workDir, "init", "-input=false", "-no-color")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	args = append(args, "-input=false", "-no-color", fmt.Sprintf("-state=%v", stateFile))
+	args = append(args, variableArgs(config.variables())...)
+	err = config.exec(ctx, workDir, args...)
+	// a failed apply can still have created some resources
+	// so the state is saved regardless of the result
+	if saveErr := saveState(config, stateFile); saveErr != nil {
+		return trace.NewAggregate(err, saveErr)
+	}
+	return trace.Wrap(err)
+}
+
+// variables returns the values of the configuration variables
+func (r Config) variables() map[string]string {
+	variables := make(map[string]string)
+	for name, value := range r.Terraform.Variables {
+		variables[name] = value
+	}
+	if r.Terraform.InstanceScript != "" {
+		variables[InstanceScriptVariable] = r.Terraform.InstanceScript
+	}
+	for name, value := range r.Variables {
+		variables[name] = value
+	}
+	return variables
+}
+
+func (r Config) exec(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, r.Binary, args...)
+	r.WithField("args", args).Info("Execute terraform.")
+	err := utils.Exec(cmd, r.Out, utils.Dir(dir))
+	if err != nil {
+		return trace.Wrap(err, "terraform %v failed", args[0])
+	}
+	return nil
+}
+
+// restoreState writes the state saved in the backend into the state file
+func restoreState(config Config, path string) error {
+	state, err := config.Backend.GetTerraformState(config.Name)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	err = ioutil.WriteFile(path, state, defaults.PrivateFileMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+// saveState saves the state file in the backend
+func saveState(config Config, path string) error {
+	state, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return trace.ConvertSystemError(err)
+	}
+	return trace.Wrap(config.Backend.UpsertTerraformState(config.Name, state))
+}
+
+// variableArgs returns the command line arguments that set the variables
+// sorted by name
+func variableArgs(variables map[string]string) (args []string) {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "-var", fmt.Sprintf("%v=%v", name, variables[name]))
+	}
+	return args
+}
+
+const (
+	// InstanceScriptVariable is the name of the variable with the contents
+	// of the instance script from the application manifest
+	InstanceScriptVariable = "instance_script"
+
+	// configFilename is the name of the file with the Terraform configuration
+	configFilename = "main.tf"
+	// stateFilename is the name of the Terraform state file
+	stateFilename = "terraform.tfstate"
+)
@@ -0,0 +1,146 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+func TestTerraform(t *testing.T) { TestingT(t) }
+
+type TerraformSuite struct {
+	// args records the arguments of the fake terraform
+	args   string
+	binary string
+}
+
+var _ = Suite(&TerraformSuite{})
+
+func (s *TerraformSuite) SetUpTest(c *C) {
+	dir := c.MkDir()
+	s.args = filepath.Join(dir, "args")
+	// the fake terraform records its arguments and the configuration,
+	// and appends to the state on apply
+	s.binary = filepath.Join(dir, "terraform")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %[1]v
+if [ "$1" = "init" ]; then
+  cat main.tf >> %[1]v; echo >> %[1]v
+fi
+if [ "$1" = "apply" ]; then
+  for arg in "$@"; do
+    case "$arg" in -state=*) state="${arg#-state=}";; esac
+  done
+  echo "$(cat "$state" 2>/dev/null)+" > "$state"
+  [ -n "$FAIL_APPLY" ] && exit 1
+fi
+exit 0
+`, s.args)
+	c.Assert(ioutil.WriteFile(s.binary, []byte(script), 0755), IsNil)
+}
+
+func (s *TerraformSuite) TestAppliesWithStateInBackend(c *C) {
+	backend := newStates()
+	config := s.newConfig(backend)
+
+	c.Assert(Plan(context.TODO(), config), IsNil)
+	_, err := backend.GetTerraformState("aws")
+	c.Assert(trace.IsNotFound(err), Equals, true)
+
+	c.Assert(Apply(context.TODO(), config), IsNil)
+	c.Assert(string(backend.states["aws"]), Equals, "+\n")
+
+	// the second run continues from the saved state
+	c.Assert(Apply(context.TODO(), config), IsNil)
+	c.Assert(string(backend.states["aws"]), Equals, "++\n")
+
+	args, err := ioutil.ReadFile(s.args)
+	c.Assert(err, IsNil)
+	lines := strings.Split(strings.TrimSpace(string(args)), "\n")
+	c.Assert(lines, HasLen, 9)
+	variables := " -var instance_script=echo hello -var nodes=5 -var region=us-west-2"
+	for i, action := range []string{"plan", "apply -auto-approve", "apply -auto-approve"} {
+		c.Assert(lines[i*3], Equals, "init -input=false -no-color")
+		c.Assert(lines[i*3+1], Equals, `resource "aws_vpc" "main" {}`)
+		c.Assert(lines[i*3+2], Matches, action+" -input=false -no-color -state=.*/terraform.tfstate"+variables)
+	}
+}
+
+func (s *TerraformSuite) TestSavesStateOfFailedApply(c *C) {
+	os.Setenv("FAIL_APPLY", "true")
+	defer os.Unsetenv("FAIL_APPLY")
+	backend := newStates()
+	err := Apply(context.TODO(), s.newConfig(backend))
+	c.Assert(err, NotNil)
+	c.Assert(string(backend.states["aws"]), Equals, "+\n")
+}
+
+func (s *TerraformSuite) newConfig(backend *states) Config {
+	return Config{
+		Name: "aws",
+		Terraform: schema.Terraform{
+			Script:         `resource "aws_vpc" "main" {}`,
+			InstanceScript: "echo hello",
+			Variables: map[string]string{
+				"region": "us-east-1",
+				"nodes":  "5",
+			},
+		},
+		Variables: map[string]string{"region": "us-west-2"},
+		Backend:   backend,
+		Binary:    s.binary,
+		Out:       &bytes.Buffer{},
+	}
+}
+
+func newStates() *states {
+	return &states{states: make(map[string][]byte)}
+}
+
+type states struct {
+	states map[string][]byte
+}
+
+func (r *states) UpsertTerraformState(name string, state []byte) error {
+	r.states[name] = state
+	return nil
+}
+
+func (r *states) GetTerraformState(name string) ([]byte, error) {
+	state, ok := r.states[name]
+	if !ok {
+		return nil, trace.NotFound("no Terraform state for %q", name)
+	}
+	return state, nil
+}
+
+func (r *states) DeleteTerraformState(name string) error {
+	delete(r.states, name)
+	return nil
+}
@@ -66,6 +66,8 @@ type S3App struct {
 	Data []byte
 	// Checksum is the application file sha256 checksum
 	Checksum string
+	// Labels is the application labels
+	Labels map[string]string
 }
 
 // NewS3 returns a new fake S3 implementation
@@ -94,12 +96,16 @@ func (s *S3) addToIndex(c *check.C, app S3App) {
 		err := yaml.Unmarshal(o.Data, indexFile)
 		c.Assert(err, check.IsNil)
 	}
+	annotations := map[string]string{
+		constants.AnnotationSize: fmt.Sprintf("%v", len(app.Data)),
+	}
+	for key, value := range app.Labels {
+		annotations[constants.AnnotationLabelPrefix+key] = value
+	}
 	indexFile.Add(&chart.Metadata{
-		Name:    app.Name,
-		Version: app.Version,
-		Annotations: map[string]string{
-			constants.AnnotationSize: fmt.Sprintf("%v", len(app.Data)),
-		},
+		Name:        app.Name,
+		Version:     app.Version,
+		Annotations: annotations,
 	}, "", "", app.Checksum)
 	bytes, err := yaml.Marshal(indexFile)
 	c.Assert(err, check.IsNil)
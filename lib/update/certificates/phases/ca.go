@@ -0,0 +1,102 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewRotateCA returns a new executor to move the cluster certificate authority
+// to the rotation stage specified in the phase
+func NewRotateCA(
+	params libfsm.ExecutorParams,
+	operator caRotator,
+	operation ops.SiteOperation,
+	logger log.FieldLogger,
+) (*rotateCA, error) {
+	if params.Phase.Data == nil || params.Phase.Data.Data == "" {
+		return nil, trace.NotFound("no certificate authority stage specified for phase %q",
+			params.Phase.ID)
+	}
+	stage := params.Phase.Data.Data
+	var prevStage string
+	for i, s := range ops.CertAuthorityStages {
+		if s == stage && i > 0 {
+			prevStage = ops.CertAuthorityStages[i-1]
+		}
+	}
+	if prevStage == "" {
+		return nil, trace.BadParameter("invalid certificate authority stage %q for phase %q",
+			stage, params.Phase.ID)
+	}
+	return &rotateCA{
+		FieldLogger: logger,
+		operator:    operator,
+		operation:   operation,
+		stage:       stage,
+		prevStage:   prevStage,
+	}, nil
+}
+
+// Execute moves the certificate authority to the next rotation stage
+func (r *rotateCA) Execute(context.Context) error {
+	r.WithField("stage", r.stage).Info("Rotate certificate authority.")
+	err := r.operator.RotateCertAuthority(ops.RotateCertAuthorityRequest{
+		Key:   r.operation.Key(),
+		Stage: r.stage,
+	})
+	return trace.Wrap(err)
+}
+
+// Rollback moves the certificate authority back to the previous rotation stage
+func (r *rotateCA) Rollback(context.Context) error {
+	r.WithField("stage", r.prevStage).Info("Roll back certificate authority.")
+	err := r.operator.RotateCertAuthority(ops.RotateCertAuthorityRequest{
+		Key:   r.operation.Key(),
+		Stage: r.prevStage,
+	})
+	return trace.Wrap(err)
+}
+
+// PreCheck is a no-op
+func (*rotateCA) PreCheck(context.Context) error {
+	return nil
+}
+
+// PostCheck is a no-op
+func (*rotateCA) PostCheck(context.Context) error {
+	return nil
+}
+
+type rotateCA struct {
+	// FieldLogger specifies the logger for the phase
+	log.FieldLogger
+	operator  caRotator
+	operation ops.SiteOperation
+	stage     string
+	prevStage string
+}
+
+type caRotator interface {
+	RotateCertAuthority(ops.RotateCertAuthorityRequest) error
+}
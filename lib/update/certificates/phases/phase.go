@@ -0,0 +1,29 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+const (
+	// RotateCA defines the phase to move the cluster certificate authority
+	// to the next rotation stage
+	RotateCA = "rotate-ca"
+	// Secrets defines the phase to generate new secrets and runtime
+	// configuration packages for cluster nodes
+	Secrets = "secrets"
+	// WebCertificate defines the phase to reissue the cluster web certificate
+	// from the user-provided certificate authority
+	WebCertificate = "web-certificate"
+)
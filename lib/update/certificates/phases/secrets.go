@@ -0,0 +1,180 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+	"io"
+
+	"github.com/gravitational/gravity/lib/app"
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/clusterconfig"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewSecrets returns a new executor to generate the secrets and runtime
+// configuration packages for the specified nodes
+func NewSecrets(
+	params libfsm.ExecutorParams,
+	operator operator,
+	operation ops.SiteOperation,
+	apps appGetter,
+	packages, hostPackages packageService,
+	logger log.FieldLogger,
+) (*secrets, error) {
+	if params.Phase.Data == nil || params.Phase.Data.Package == nil {
+		return nil, trace.NotFound("no installed application package specified for phase %q",
+			params.Phase.ID)
+	}
+	if params.Phase.Data.Update == nil || len(params.Phase.Data.Update.Servers) == 0 {
+		return nil, trace.BadParameter("expected at least one server update")
+	}
+	app, err := apps.GetApp(*params.Phase.Data.Package)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to query installed application")
+	}
+	return &secrets{
+		FieldLogger:  logger,
+		operator:     operator,
+		operation:    operation,
+		packages:     packages,
+		hostPackages: hostPackages,
+		servers:      params.Phase.Data.Update.Servers,
+		manifest:     app.Manifest,
+	}, nil
+}
+
+// Execute generates new secrets signed by the current certificate authority
+// as well as new runtime configuration with the existing cluster configuration
+// and environment
+func (r *secrets) Execute(ctx context.Context) error {
+	env, err := r.operator.GetClusterEnvironmentVariables(r.operation.ClusterKey())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	config, err := r.operator.GetClusterConfiguration(r.operation.ClusterKey())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	configBytes, err := clusterconfig.Marshal(config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, update := range r.servers {
+		if update.Runtime.SecretsPackage == nil {
+			return trace.NotFound("no secrets package specified for %v", update.Server)
+		}
+		r.Infof("Generate new secrets package for %v.", update.Server)
+		resp, err := r.operator.RotateSecrets(ops.RotateSecretsRequest{
+			AccountID:   r.operation.AccountID,
+			ClusterName: r.operation.SiteDomain,
+			Server:      update.Server,
+			Locator:     update.Runtime.SecretsPackage,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := r.upsertPackage(*resp); err != nil {
+			return trace.Wrap(err)
+		}
+		r.Infof("Generate new runtime configuration package for %v.", update.Server)
+		resp, err = r.operator.RotatePlanetConfig(ops.RotatePlanetConfigRequest{
+			Key:            r.operation.Key(),
+			Server:         update.Server,
+			Manifest:       r.manifest,
+			RuntimePackage: update.Runtime.Update.Package,
+			Locator:        &update.Runtime.Update.ConfigPackage,
+			Env:            env.GetKeyValues(),
+			Config:         configBytes,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := r.upsertPackage(*resp); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// Rollback removes the generated packages
+func (r *secrets) Rollback(context.Context) error {
+	for _, update := range r.servers {
+		locators := []loc.Locator{update.Runtime.Update.ConfigPackage}
+		if update.Runtime.SecretsPackage != nil {
+			locators = append(locators, *update.Runtime.SecretsPackage)
+		}
+		for _, locator := range locators {
+			for _, packages := range []packageService{r.packages, r.hostPackages} {
+				err := packages.DeletePackage(locator)
+				if err != nil && !trace.IsNotFound(err) {
+					return trace.Wrap(err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// PreCheck is a no-op
+func (*secrets) PreCheck(context.Context) error {
+	return nil
+}
+
+// PostCheck is a no-op
+func (*secrets) PostCheck(context.Context) error {
+	return nil
+}
+
+func (r *secrets) upsertPackage(resp ops.RotatePackageResponse) error {
+	_, err := r.packages.UpsertPackage(resp.Locator, resp.Reader, pack.WithLabels(resp.Labels))
+	return trace.Wrap(err)
+}
+
+type secrets struct {
+	// FieldLogger specifies the logger for the phase
+	log.FieldLogger
+	operator     operator
+	operation    ops.SiteOperation
+	packages     packageService
+	hostPackages packageService
+	servers      []storage.UpdateServer
+	manifest     schema.Manifest
+}
+
+type operator interface {
+	RotateSecrets(ops.RotateSecretsRequest) (*ops.RotatePackageResponse, error)
+	RotatePlanetConfig(ops.RotatePlanetConfigRequest) (*ops.RotatePackageResponse, error)
+	GetClusterEnvironmentVariables(ops.SiteKey) (storage.EnvironmentVariables, error)
+	GetClusterConfiguration(ops.SiteKey) (clusterconfig.Interface, error)
+}
+
+type appGetter interface {
+	GetApp(loc.Locator) (*app.Application, error)
+}
+
+type packageService interface {
+	UpsertPackage(loc.Locator, io.Reader, ...pack.PackageOption) (*pack.PackageEnvelope, error)
+	DeletePackage(loc.Locator) error
+}
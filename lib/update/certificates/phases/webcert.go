@@ -0,0 +1,74 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/ops"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewWebCertificate returns a new executor to reissue the cluster web
+// certificate from the user-provided certificate authority
+func NewWebCertificate(
+	operator certificateIssuer,
+	operation ops.SiteOperation,
+	logger log.FieldLogger,
+) *webCertificate {
+	return &webCertificate{
+		FieldLogger: logger,
+		operator:    operator,
+		operation:   operation,
+	}
+}
+
+// Execute issues and installs a new cluster web certificate
+func (r *webCertificate) Execute(context.Context) error {
+	r.Info("Reissue cluster web certificate.")
+	_, err := r.operator.IssueClusterCertificate(r.operation.ClusterKey())
+	return trace.Wrap(err)
+}
+
+// Rollback is a no-op: the previously installed certificate
+// has been issued by the same certificate authority
+func (*webCertificate) Rollback(context.Context) error {
+	return nil
+}
+
+// PreCheck is a no-op
+func (*webCertificate) PreCheck(context.Context) error {
+	return nil
+}
+
+// PostCheck is a no-op
+func (*webCertificate) PostCheck(context.Context) error {
+	return nil
+}
+
+type webCertificate struct {
+	// FieldLogger specifies the logger for the phase
+	log.FieldLogger
+	operator  certificateIssuer
+	operation ops.SiteOperation
+}
+
+type certificateIssuer interface {
+	IssueClusterCertificate(ops.SiteKey) (*ops.ClusterCertificate, error)
+}
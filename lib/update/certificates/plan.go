@@ -0,0 +1,255 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/update"
+	"github.com/gravitational/gravity/lib/update/certificates/phases"
+	"github.com/gravitational/gravity/lib/update/internal/rollingupdate"
+
+	"github.com/gravitational/trace"
+)
+
+// NewOperationPlan creates a new plan for the specified certificate rotation operation
+func NewOperationPlan(
+	operator ops.Operator,
+	apps app.Applications,
+	operation ops.SiteOperation,
+	servers []storage.Server,
+) (plan *storage.OperationPlan, err error) {
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	app, err := apps.GetApp(cluster.App.Package)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to query installed application")
+	}
+	plan, err = newOperationPlan(*app, *cluster, operator, operation, servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = operator.CreateOperationPlan(operation.Key(), *plan)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotImplemented(
+				"cluster operator does not implement the API required to rotate certificates. " +
+					"Please make sure you're running the command on a compatible cluster.")
+		}
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
+
+// newOperationPlan returns a new plan for the specified operation
+// and the given set of servers
+func newOperationPlan(
+	app app.Application,
+	cluster ops.Site,
+	operator packageRotator,
+	operation ops.SiteOperation,
+	servers []storage.Server,
+) (*storage.OperationPlan, error) {
+	if operation.RotateCertificates == nil {
+		return nil, trace.BadParameter("operation %v is not a certificate rotation operation", operation.ID)
+	}
+	updates, err := rollingupdate.RuntimeConfigUpdates(app.Manifest, operator, operation.Key(), servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for i, update := range updates {
+		secretsPackage, err := operator.RotateSecrets(ops.RotateSecretsRequest{
+			AccountID:   operation.AccountID,
+			ClusterName: operation.SiteDomain,
+			Server:      update.Server,
+			DryRun:      true,
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		updates[i].Runtime.SecretsPackage = &secretsPackage.Locator
+	}
+	passes := []pass{{id: "reissue", description: "Reissue node certificates"}}
+	if operation.RotateCertificates.RotateCA {
+		passes = []pass{
+			{
+				id:          "trust",
+				description: "Trust new certificate authority",
+				stage:       ops.CertAuthorityStageTrust,
+			},
+			{
+				id:          "reissue",
+				description: "Reissue node certificates with new certificate authority",
+				stage:       ops.CertAuthorityStageSwitch,
+			},
+			{
+				id:          "cleanup",
+				description: "Remove trust in previous certificate authority",
+				stage:       ops.CertAuthorityStageFinalize,
+			},
+		}
+	}
+	var phases update.Phases
+	for _, pass := range passes {
+		phase, err := pass.build(app.Package, operation, forPass(updates, pass.id))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if len(phases) != 0 {
+			phase.Require(phases[len(phases)-1])
+		}
+		phases = append(phases, *phase)
+	}
+	if cluster.PKI != nil {
+		phase := webCertificatePhase()
+		phase.Require(phases[len(phases)-1])
+		phases = append(phases, phase)
+	}
+
+	plan := &storage.OperationPlan{
+		OperationID:   operation.ID,
+		OperationType: operation.Type,
+		AccountID:     operation.AccountID,
+		ClusterName:   operation.SiteDomain,
+		Phases:        phases.AsPhases(),
+		Servers:       servers,
+		DNSConfig:     cluster.DNSConfig,
+	}
+	update.ResolvePlan(plan)
+
+	return plan, nil
+}
+
+// build returns the phase that rolls out new certificates to all nodes.
+// If the pass moves the certificate authority to a new stage, this is done
+// before any certificates are generated
+func (r pass) build(app loc.Locator, operation ops.SiteOperation, updates []storage.UpdateServer) (*update.Phase, error) {
+	masters, nodes := update.SplitServers(updates)
+	if len(masters) == 0 {
+		return nil, trace.NotFound("no master servers found in cluster state")
+	}
+	root := update.RootPhase(update.Phase{
+		ID:          r.id,
+		Description: r.description,
+	})
+	if r.stage != "" {
+		root.AddSequential(update.Phase{
+			ID:          "ca",
+			Executor:    phases.RotateCA,
+			Description: fmt.Sprintf("Move certificate authority to %q stage", r.stage),
+			Data: &storage.OperationPhaseData{
+				Data: r.stage,
+			},
+		})
+	}
+	root.AddSequential(update.Phase{
+		ID:          "secrets",
+		Executor:    phases.Secrets,
+		Description: "Generate new node certificates",
+		Data: &storage.OperationPhaseData{
+			Package: &app,
+			Update: &storage.UpdateOperationData{
+				Servers: updates,
+			},
+		},
+	})
+	builder := rollingupdate.Builder{
+		App:         app,
+		ChangesetID: fmt.Sprintf("%v-%v", operation.ID, r.id),
+	}
+	root.AddSequential(nested(*builder.Masters(
+		masters,
+		"Restart masters with new certificates",
+		"Restart node %q with new certificates",
+	)))
+	if len(nodes) != 0 {
+		root.AddSequential(nested(*builder.Nodes(
+			nodes, masters[0].Server,
+			"Restart nodes with new certificates",
+			"Restart node %q with new certificates",
+		)))
+	}
+	return &root, nil
+}
+
+// webCertificatePhase returns the phase that reissues the cluster web certificate
+// from the user-provided certificate authority.
+// The web certificate is not managed by the runtime and is reissued separately
+func webCertificatePhase() update.Phase {
+	return update.RootPhase(update.Phase{
+		ID:          "web",
+		Executor:    phases.WebCertificate,
+		Description: "Reissue cluster web certificate",
+	})
+}
+
+// forPass returns a copy of updates with package locators unique to the specified pass
+func forPass(updates []storage.UpdateServer, passID string) (result []storage.UpdateServer) {
+	result = make([]storage.UpdateServer, 0, len(updates))
+	for _, update := range updates {
+		runtimeUpdate := *update.Runtime.Update
+		runtimeUpdate.ConfigPackage = withBuildMetadata(runtimeUpdate.ConfigPackage, passID)
+		secretsPackage := withBuildMetadata(*update.Runtime.SecretsPackage, passID)
+		update.Runtime.Update = &runtimeUpdate
+		update.Runtime.SecretsPackage = &secretsPackage
+		result = append(result, update)
+	}
+	return result
+}
+
+// withBuildMetadata returns a copy of locator with the specified
+// identifier appended to the build metadata of its version
+func withBuildMetadata(locator loc.Locator, identifier string) loc.Locator {
+	separator := "+"
+	if strings.Contains(locator.Version, "+") {
+		separator = "."
+	}
+	locator.Version = locator.Version + separator + identifier
+	return locator
+}
+
+// nested turns the specified root phase into a phase that can be nested
+// under another phase
+func nested(phase update.Phase) update.Phase {
+	phase.ID = strings.TrimPrefix(phase.ID, "/")
+	return phase
+}
+
+// pass describes a single rolling restart of all cluster nodes
+type pass struct {
+	// id identifies the pass
+	id string
+	// description is the pass phase description
+	description string
+	// stage is the optional certificate authority stage
+	// to move to before the node certificates are generated
+	stage string
+}
+
+// packageRotator defines the subset of Operator for generating
+// the secrets and runtime configuration packages
+type packageRotator interface {
+	rollingupdate.ConfigPackageRotator
+	RotateSecrets(ops.RotateSecretsRequest) (*ops.RotatePackageResponse, error)
+}
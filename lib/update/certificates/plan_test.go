@@ -0,0 +1,215 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"testing"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/update/certificates/phases"
+	libphase "github.com/gravitational/gravity/lib/update/internal/rollingupdate/phases"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestCertificates(t *testing.T) { TestingT(t) }
+
+type S struct{}
+
+var _ = Suite(&S{})
+
+func (S) TestReissuesCertificatesWithExistingCA(c *C) {
+	operation := newOperation(false)
+	servers := []storage.Server{
+		{Hostname: "node-1", Role: "node", ClusterRole: string(schema.ServiceRoleMaster)},
+	}
+
+	plan, err := newOperationPlan(testApp, testCluster, testOperator, operation, servers)
+	c.Assert(err, IsNil)
+	c.Assert(phaseIDs(plan.Phases), compare.DeepEquals, []string{
+		"/reissue",
+		"/reissue/secrets",
+		"/reissue/masters",
+		"/reissue/masters/node-1",
+		"/reissue/masters/node-1/drain",
+		"/reissue/masters/node-1/restart",
+		"/reissue/masters/node-1/taint",
+		"/reissue/masters/node-1/uncordon",
+		"/reissue/masters/node-1/endpoints",
+		"/reissue/masters/node-1/untaint",
+		"/reissue/masters/node-1/health",
+	})
+	reissue := plan.Phases[0]
+	c.Assert(reissue.Phases[1].Requires, compare.DeepEquals, []string{"/reissue/secrets"})
+	update := storage.UpdateServer{
+		Server: servers[0],
+		Runtime: storage.RuntimePackage{
+			Installed:      runtimeLoc,
+			SecretsPackage: locator("gravitational.io/planet-secrets:0.0.1+reissue"),
+			Update: &storage.RuntimeUpdate{
+				Package:       runtimeLoc,
+				ConfigPackage: *locator("gravitational.io/planet-config:0.0.1+1.reissue"),
+			},
+		},
+	}
+	c.Assert(reissue.Phases[0].Data, compare.DeepEquals, &storage.OperationPhaseData{
+		Package: &testApp.Package,
+		Update: &storage.UpdateOperationData{
+			Servers: []storage.UpdateServer{update},
+		},
+	})
+	restart := reissue.Phases[1].Phases[0].Phases[1]
+	c.Assert(restart.Executor, Equals, libphase.RestartContainer)
+	c.Assert(restart.Data.Update, compare.DeepEquals, &storage.UpdateOperationData{
+		Servers:     []storage.UpdateServer{update},
+		ChangesetID: "1-reissue",
+	})
+}
+
+func (S) TestRotatesCertAuthorityInThreePasses(c *C) {
+	operation := newOperation(true)
+	servers := []storage.Server{
+		{Hostname: "node-1", Role: "node", ClusterRole: string(schema.ServiceRoleMaster)},
+		{Hostname: "node-2", Role: "knode", ClusterRole: string(schema.ServiceRoleNode)},
+	}
+
+	plan, err := newOperationPlan(testApp, testCluster, testOperator, operation, servers)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Phases, HasLen, 3)
+
+	expected := []struct {
+		id       string
+		stage    string
+		requires []string
+	}{
+		{id: "/trust", stage: ops.CertAuthorityStageTrust},
+		{id: "/reissue", stage: ops.CertAuthorityStageSwitch, requires: []string{"/trust"}},
+		{id: "/cleanup", stage: ops.CertAuthorityStageFinalize, requires: []string{"/reissue"}},
+	}
+	for i, pass := range plan.Phases {
+		comment := Commentf("pass %v", expected[i].id)
+		c.Assert(pass.ID, Equals, expected[i].id, comment)
+		c.Assert(pass.Requires, compare.DeepEquals, expected[i].requires, comment)
+		c.Assert(phaseIDs(pass.Phases[:1]), compare.DeepEquals, []string{pass.ID + "/ca"}, comment)
+		c.Assert(pass.Phases[0].Executor, Equals, phases.RotateCA, comment)
+		c.Assert(pass.Phases[0].Data.Data, Equals, expected[i].stage, comment)
+		c.Assert(pass.Phases[1].ID, Equals, pass.ID+"/secrets", comment)
+		c.Assert(pass.Phases[1].Requires, compare.DeepEquals, []string{pass.ID + "/ca"}, comment)
+		c.Assert(pass.Phases[2].ID, Equals, pass.ID+"/masters", comment)
+		c.Assert(pass.Phases[3].ID, Equals, pass.ID+"/nodes", comment)
+		c.Assert(pass.Phases[3].Requires, compare.DeepEquals, []string{pass.ID + "/masters"}, comment)
+		secrets := pass.Phases[1].Data.Update.Servers
+		c.Assert(secrets, HasLen, 2, comment)
+		c.Assert(secrets[1].Runtime.SecretsPackage.Version, Equals, "0.0.1+"+pass.ID[1:], comment)
+	}
+}
+
+func (S) TestReissuesWebCertificateWithExternalPKI(c *C) {
+	operation := newOperation(false)
+	servers := []storage.Server{
+		{Hostname: "node-1", Role: "node", ClusterRole: string(schema.ServiceRoleMaster)},
+	}
+	cluster := testCluster
+	cluster.PKI = &storage.ExternalPKI{SignerURL: "https://signer.example.com"}
+
+	plan, err := newOperationPlan(testApp, cluster, testOperator, operation, servers)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Phases, HasLen, 2)
+	c.Assert(plan.Phases[1].ID, Equals, "/web")
+	c.Assert(plan.Phases[1].Executor, Equals, phases.WebCertificate)
+	c.Assert(plan.Phases[1].Requires, compare.DeepEquals, []string{"/reissue"})
+}
+
+func (S) TestRequiresRotationState(c *C) {
+	operation := newOperation(false)
+	operation.RotateCertificates = nil
+	_, err := newOperationPlan(testApp, testCluster, testOperator, operation, nil)
+	c.Assert(err, NotNil)
+}
+
+func newOperation(rotateCA bool) ops.SiteOperation {
+	return ops.SiteOperation{
+		ID:         "1",
+		AccountID:  "0",
+		Type:       ops.OperationRotateCertificates,
+		SiteDomain: "cluster",
+		RotateCertificates: &storage.RotateCertificatesOperationState{
+			RotateCA: rotateCA,
+		},
+	}
+}
+
+func phaseIDs(phases []storage.OperationPhase) (ids []string) {
+	for _, phase := range phases {
+		ids = append(ids, phase.ID)
+		ids = append(ids, phaseIDs(phase.Phases)...)
+	}
+	return ids
+}
+
+func locator(s string) *loc.Locator {
+	locator := loc.MustParseLocator(s)
+	return &locator
+}
+
+func (r testRotator) RotatePlanetConfig(ops.RotatePlanetConfigRequest) (*ops.RotatePackageResponse, error) {
+	return &ops.RotatePackageResponse{Locator: r.runtimeConfigPackage}, nil
+}
+
+func (r testRotator) RotateSecrets(ops.RotateSecretsRequest) (*ops.RotatePackageResponse, error) {
+	return &ops.RotatePackageResponse{Locator: r.secretsPackage}, nil
+}
+
+var testOperator = testRotator{
+	runtimeConfigPackage: loc.MustParseLocator("gravitational.io/planet-config:0.0.1+1"),
+	secretsPackage:       loc.MustParseLocator("gravitational.io/planet-secrets:0.0.1"),
+}
+
+type testRotator struct {
+	runtimeConfigPackage loc.Locator
+	secretsPackage       loc.Locator
+}
+
+var testCluster = ops.Site{DNSConfig: storage.DefaultDNSConfig}
+
+var runtimeLoc = loc.MustParseLocator("gravitational.io/planet:0.0.1")
+
+var testApp = app.Application{
+	Package: loc.MustParseLocator("gravitational.io/app:0.0.1"),
+	Manifest: schema.Manifest{
+		NodeProfiles: schema.NodeProfiles{
+			{
+				Name:        "node",
+				ServiceRole: "master",
+			},
+			{
+				Name:        "knode",
+				ServiceRole: "node",
+			},
+		},
+		SystemOptions: &schema.SystemOptions{
+			Dependencies: schema.SystemDependencies{
+				Runtime: &schema.Dependency{Locator: runtimeLoc},
+			},
+		},
+	},
+}
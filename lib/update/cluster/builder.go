@@ -18,14 +18,17 @@ package cluster
 
 import (
 	"fmt"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/update"
 	libphase "github.com/gravitational/gravity/lib/update/cluster/phases"
@@ -48,13 +51,115 @@ func (r phaseBuilder) init(leadMaster storage.Server) *update.Phase {
 			ExecServer:       &leadMaster,
 			InstalledPackage: &r.installedApp.Package,
 			Update: &storage.UpdateOperationData{
-				Servers: r.servers,
+				Servers:           r.servers,
+				IntermediateSteps: r.intermediateUpdateSteps(),
 			},
 		},
 	})
 	return &phase
 }
 
+// intermediateUpdateSteps returns the configuration updates
+// for all intermediate runtime upgrades
+func (r phaseBuilder) intermediateUpdateSteps() (steps []storage.IntermediateUpdateStep) {
+	for _, step := range r.intermediateSteps {
+		steps = append(steps, storage.IntermediateUpdateStep{
+			Runtime: step.runtime.Package,
+			Servers: step.servers,
+		})
+	}
+	return steps
+}
+
+// intermediate returns a new phase for upgrading the cluster to the intermediate
+// runtime described by step from the specified installed runtime.
+//
+// The phase upgrades the system software on all nodes and the runtime
+// applications to the versions of the intermediate runtime
+func (r phaseBuilder) intermediate(step intermediateStep, installedRuntime app.Application,
+	supportsTaints bool) (*update.Phase, error) {
+	version := step.runtime.Package.Version
+	builder := phaseBuilder{planConfig: r.planConfig}
+	builder.servers = step.servers
+	builder.installedRuntime = installedRuntime
+	builder.updateRuntime = step.runtime
+	builder.changesetID = fmt.Sprintf("%v-%v", r.operation.ID, version)
+
+	masters, nodes := update.SplitServers(step.servers)
+	if len(masters) == 0 {
+		return nil, trace.NotFound("no master servers found")
+	}
+	leadMaster := masters[0]
+	root := update.RootPhase(update.Phase{
+		ID:          fmt.Sprintf("upgrade-%v", version),
+		Description: fmt.Sprintf("Upgrade cluster to intermediate runtime %v", version),
+	})
+
+	mastersPhase := *builder.masters(leadMaster, masters[1:], supportsTaints)
+	root.Add(mastersPhase)
+	if len(nodes) != 0 {
+		root.Add(*builder.nodes(leadMaster, nodes, supportsTaints).Require(mastersPhase))
+	}
+
+	updateEtcd, currentVersion, desiredVersion, err := r.shouldUpdateEtcd(builder.planConfig)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if updateEtcd {
+		root.Add(*builder.etcdPlan(leadMaster.Server, servers(masters[1:]...), servers(nodes...),
+			currentVersion, desiredVersion))
+	}
+
+	runtimeUpdates, err := builder.runtimeUpdates()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	configPhase := *builder.config(servers(masters...)).Require(mastersPhase)
+	runtimePhase := *builder.runtime(runtimeUpdates).Require(mastersPhase)
+	root.Add(configPhase, runtimePhase)
+
+	// Phases are generated with absolute IDs so move them under the root phase
+	for i := range root.Phases {
+		reparent(&root.Phases[i], root.ID)
+	}
+	return &root, nil
+}
+
+// runtimeUpdates returns the runtime applications to update
+// from the installed to the update runtime.
+// Applications the update application opted out of are skipped
+func (r phaseBuilder) runtimeUpdates() ([]loc.Locator, error) {
+	allRuntimeUpdates, err := r.updatedDependencies(r.installedRuntime, r.updateRuntime)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	// some system apps may need to be skipped depending on the manifest settings
+	runtimeUpdates := allRuntimeUpdates[:0]
+	for _, locator := range allRuntimeUpdates {
+		if !schema.ShouldSkipApp(r.updateApp.Manifest, locator) {
+			runtimeUpdates = append(runtimeUpdates, locator)
+		}
+	}
+	return runtimeUpdates, nil
+}
+
+// reparent moves the specified phase along with its sub-phases under
+// the parent phase with the given ID by prefixing all absolute phase IDs
+// and requirements with the parent ID
+func reparent(phase *storage.OperationPhase, parentID string) {
+	if path.IsAbs(phase.ID) {
+		phase.ID = path.Join(parentID, phase.ID)
+	}
+	for i, req := range phase.Requires {
+		if path.IsAbs(req) {
+			phase.Requires[i] = path.Join(parentID, req)
+		}
+	}
+	for i := range phase.Phases {
+		reparent(&phase.Phases[i], parentID)
+	}
+}
+
 func (r phaseBuilder) checks() *update.Phase {
 	phase := update.RootPhase(update.Phase{
 		ID:          "checks",
@@ -294,6 +399,7 @@ func (r phaseBuilder) nodes(leadMaster storage.UpdateServer, nodes []storage.Upd
 	root := update.RootPhase(update.Phase{
 		ID:          "nodes",
 		Description: "Update regular nodes",
+		Parallel:    true,
 	})
 
 	for i, server := range nodes {
@@ -305,6 +411,57 @@ func (r phaseBuilder) nodes(leadMaster storage.UpdateServer, nodes []storage.Upd
 	return &root
 }
 
+// canary returns a new phase for updating the specified canary nodes
+// before the rest of the regular nodes.
+//
+// After the nodes have been updated, the phase runs the application health
+// check and, if requested, pauses the operation so the update of the
+// remaining nodes can be approved with 'gravity plan resume'
+func (r phaseBuilder) canary(leadMaster storage.UpdateServer, nodes []storage.UpdateServer, supportsTaints bool) *update.Phase {
+	root := update.RootPhase(update.Phase{
+		ID:          "canary",
+		Description: "Update canary nodes",
+	})
+
+	nodesPhase := update.Phase{
+		ID:          root.ChildLiteral("nodes"),
+		Description: "Update system software on canary nodes",
+		Parallel:    true,
+	}
+	for i, server := range nodes {
+		node := r.node(server.Server, &nodesPhase, "Update system software on canary node %q")
+		node.AddSequential(r.commonNode(nodes[i], leadMaster, supportsTaints,
+			waitsForEndpoints(true))...)
+		nodesPhase.AddParallel(node)
+	}
+	root.AddSequential(nodesPhase)
+
+	if r.updateApp.Manifest.HasHook(schema.HookHealthCheck) {
+		root.AddSequential(update.Phase{
+			ID:          root.ChildLiteral("health"),
+			Executor:    healthCheck,
+			Description: "Run application health check with updated canary nodes",
+			Data: &storage.OperationPhaseData{
+				Package:    &r.updateApp.Package,
+				ExecServer: &leadMaster.Server,
+			},
+			Retry: healthCheckRetryPolicy(),
+		})
+	}
+
+	if options := r.canaryOptions(); options != nil && options.Pause {
+		root.AddSequential(update.Phase{
+			ID:          root.ChildLiteral("approve"),
+			Executor:    canaryApproval,
+			Description: "Pause the operation for approval of the canary update",
+			Data: &storage.OperationPhaseData{
+				ExecServer: &leadMaster.Server,
+			},
+		})
+	}
+	return &root
+}
+
 func (r phaseBuilder) etcdPlan(
 	leadMaster storage.Server,
 	otherMasters []storage.Server,
@@ -420,6 +577,17 @@ func (r phaseBuilder) etcdPlan(
 	})
 	root.Add(restartMasters)
 
+	// Verify that all members came back with the new version
+	root.AddSequential(update.Phase{
+		ID:          root.ChildLiteral("verify"),
+		Description: fmt.Sprintf("Verify etcd cluster runs version %v", desiredVersion),
+		Executor:    updateEtcdVerify,
+		Data: &storage.OperationPhaseData{
+			Server: &leadMaster,
+			Data:   desiredVersion,
+		},
+	})
+
 	return &root
 }
 
@@ -478,7 +646,19 @@ func (r phaseBuilder) node(server storage.Server, parent update.ParentPhase, for
 // commonNode returns a list of operations required for any node role to upgrade its system software
 func (r phaseBuilder) commonNode(server, leadMaster storage.UpdateServer, supportsTaints bool,
 	waitsForEndpoints waitsForEndpoints) []update.Phase {
-	phases := []update.Phase{
+	var phases []update.Phase
+	if r.updateApp.Manifest.HasHook(schema.HookNodeUpdating) {
+		phases = append(phases, update.Phase{
+			ID:          "pre-update",
+			Executor:    preNodeUpdate,
+			Description: fmt.Sprintf("Run pre-update application hook for node %q", server.Hostname),
+			Data: &storage.OperationPhaseData{
+				Package:    &r.updateApp.Package,
+				Server:     &server.Server,
+				ExecServer: &leadMaster.Server,
+			}})
+	}
+	phases = append(phases, []update.Phase{
 		{
 			ID:          "drain",
 			Executor:    drainNode,
@@ -486,6 +666,7 @@ func (r phaseBuilder) commonNode(server, leadMaster storage.UpdateServer, suppor
 			Data: &storage.OperationPhaseData{
 				Server:     &server.Server,
 				ExecServer: &leadMaster.Server,
+				Drain:      r.drainOptions(),
 			}},
 		{
 			ID:          "system-upgrade",
@@ -494,10 +675,11 @@ func (r phaseBuilder) commonNode(server, leadMaster storage.UpdateServer, suppor
 			Data: &storage.OperationPhaseData{
 				ExecServer: &server.Server,
 				Update: &storage.UpdateOperationData{
-					Servers: []storage.UpdateServer{server},
+					Servers:     []storage.UpdateServer{server},
+					ChangesetID: r.changesetID,
 				},
 			}},
-	}
+	}...)
 	if supportsTaints {
 		phases = append(phases, update.Phase{
 			ID:          "taint",
@@ -536,9 +718,69 @@ func (r phaseBuilder) commonNode(server, leadMaster storage.UpdateServer, suppor
 				ExecServer: &leadMaster.Server,
 			}})
 	}
+	if r.updateApp.Manifest.HasHook(schema.HookHealthCheck) {
+		phases = append(phases, update.Phase{
+			ID:          "health",
+			Executor:    healthCheck,
+			Description: fmt.Sprintf("Run application health check for node %q", server.Hostname),
+			Data: &storage.OperationPhaseData{
+				Package:    &r.updateApp.Package,
+				Server:     &server.Server,
+				ExecServer: &leadMaster.Server,
+			},
+			Retry: healthCheckRetryPolicy(),
+		})
+	}
 	return phases
 }
 
+// healthCheck returns the phase that runs the application health check hook
+// after the application has been updated or nil if the application
+// does not have the hook
+func (r phaseBuilder) healthCheck() *update.Phase {
+	if !r.updateApp.Manifest.HasHook(schema.HookHealthCheck) {
+		return nil
+	}
+	phase := update.RootPhase(update.Phase{
+		ID:          "health",
+		Description: "Run application health check",
+		Executor:    healthCheck,
+		Data: &storage.OperationPhaseData{
+			Package: &r.updateApp.Package,
+		},
+		Retry: healthCheckRetryPolicy(),
+	})
+	return &phase
+}
+
+// readiness returns the phase that waits for the readiness checks
+// declared in the application manifest or nil if the application
+// does not declare any
+func (r phaseBuilder) readiness() *update.Phase {
+	if len(r.updateApp.Manifest.ReadinessChecks) == 0 {
+		return nil
+	}
+	phase := update.RootPhase(update.Phase{
+		ID:          "readiness",
+		Description: "Wait for the application to become ready",
+		Executor:    readinessCheck,
+		Data: &storage.OperationPhaseData{
+			Package: &r.updateApp.Package,
+		},
+	})
+	return &phase
+}
+
+// healthCheckRetryPolicy returns the retry policy for the health check phases.
+// The application may take a while to become healthy after the update
+// so any health check failure is retried
+func healthCheckRetryPolicy() *storage.RetryPolicy {
+	return &storage.RetryPolicy{
+		MaxAttempts: defaults.HealthCheckPhaseAttempts,
+		RetryOn:     []string{storage.RetryOnAny},
+	}
+}
+
 func (r phaseBuilder) cleanup() *update.Phase {
 	root := update.RootPhase(update.Phase{
 		ID:          "gc",
@@ -558,6 +800,41 @@ func (r phaseBuilder) cleanup() *update.Phase {
 
 type phaseBuilder struct {
 	planConfig
+	// changesetID optionally specifies the ID of the package changeset
+	// for system updates on nodes
+	changesetID string
+}
+
+// drainOptions returns the node drain parameters specified for the operation
+func (r phaseBuilder) drainOptions() *storage.DrainOptions {
+	if r.operation.Update == nil {
+		return nil
+	}
+	return r.operation.Update.Drain
+}
+
+// canaryOptions returns the canary strategy specified for the operation
+func (r phaseBuilder) canaryOptions() *storage.CanaryOptions {
+	if r.operation.Update == nil {
+		return nil
+	}
+	return r.operation.Update.Canary
+}
+
+// splitCanaryNodes splits the specified regular nodes into the canary
+// nodes to update first and the rest according to the canary strategy
+// of the operation. Without the canary strategy, all nodes are returned
+// as the rest
+func (r phaseBuilder) splitCanaryNodes(nodes []storage.UpdateServer) (canaries, rest []storage.UpdateServer) {
+	options := r.canaryOptions()
+	if options == nil || options.Nodes <= 0 {
+		return nil, nodes
+	}
+	count := options.Nodes
+	if count > len(nodes) {
+		count = len(nodes)
+	}
+	return nodes[:count], nodes[count:]
 }
 
 func shouldUpdateCoreDNS(client *kubernetes.Clientset) (bool, error) {
@@ -630,6 +907,9 @@ func shouldUpdateEtcd(p planConfig) (updateEtcd bool, installedEtcdVersion strin
 	if err != nil {
 		return false, "", "", trace.Wrap(err)
 	}
+	if err := checkEtcdUpgradePath(installedVersion, *updateVersion); err != nil {
+		return false, "", "", trace.Wrap(err)
+	}
 	if installedVersion == nil || installedVersion.Compare(*updateVersion) < 0 {
 		updateEtcd = true
 	}
@@ -640,6 +920,29 @@ func shouldUpdateEtcd(p planConfig) (updateEtcd bool, installedEtcdVersion strin
 	return updateEtcd, installedEtcdVersion, updateEtcdVersion, nil
 }
 
+// checkEtcdUpgradePath returns an error if etcd cannot be upgraded from
+// the installed to the update version.
+//
+// The upgrade restores the data into a new data directory so it can skip
+// minor releases, but etcd data cannot be downgraded and the restore only
+// migrates the data one major version forward.
+// The installed version is nil if it is not known
+func checkEtcdUpgradePath(installedVersion *semver.Version, updateVersion semver.Version) error {
+	if installedVersion == nil {
+		return nil
+	}
+	if updateVersion.LessThan(*installedVersion) {
+		return trace.BadParameter("downgrading etcd from %v to %v is not supported",
+			installedVersion, updateVersion)
+	}
+	if updateVersion.Major > installedVersion.Major+1 {
+		return trace.BadParameter("upgrading etcd from %v to %v is not supported, "+
+			"upgrade to an intermediate version with etcd %v.x first",
+			installedVersion, updateVersion, installedVersion.Major+1)
+	}
+	return nil
+}
+
 func getEtcdVersion(searchLabel string, locator loc.Locator, packageService pack.PackageService) (*semver.Version, error) {
 	manifest, err := pack.GetPackageManifest(packageService, locator)
 	if err != nil {
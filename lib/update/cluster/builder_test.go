@@ -17,11 +17,14 @@ limitations under the License.
 package cluster
 
 import (
+	"time"
+
 	"github.com/gravitational/gravity/lib/app"
 	apptest "github.com/gravitational/gravity/lib/app/service/test"
 	"github.com/gravitational/gravity/lib/archive"
 	"github.com/gravitational/gravity/lib/compare"
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/ops/opsservice"
@@ -30,7 +33,9 @@ import (
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/update"
 
+	"github.com/coreos/go-semver/semver"
 	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
 	"gopkg.in/check.v1"
 )
 
@@ -120,6 +125,112 @@ func (s *PlanSuite) TestPlanWithRuntimeUpdate(c *check.C) {
 	c.Assert(*obtainedPlan, compare.DeepEquals, plan)
 }
 
+func (s *PlanSuite) TestPlanWithIntermediateRuntime(c *check.C) {
+	// setup
+	runtimeLoc1 := loc.MustParseLocator("gravitational.io/runtime:1.0.0")
+	appLoc1 := loc.MustParseLocator("gravitational.io/app:1.0.0")
+	intermediateRuntimeLoc := loc.MustParseLocator("gravitational.io/runtime:1.5.0")
+	runtimeLoc2 := loc.MustParseLocator("gravitational.io/runtime:2.0.0")
+	appLoc2 := loc.MustParseLocator("gravitational.io/app:2.0.0")
+
+	params := newTestPlan(c, params{
+		installedRuntime:         runtimeLoc1,
+		installedApp:             appLoc1,
+		updateRuntime:            runtimeLoc2,
+		updateApp:                appLoc2,
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    updateRuntimeManifest,
+		updateAppManifest:        updateAppManifest,
+	})
+	intermediateRuntime := app.Application{
+		Package:  intermediateRuntimeLoc,
+		Manifest: schema.MustParseManifestYAML([]byte(intermediateRuntimeManifest)),
+		PackageEnvelope: pack.PackageEnvelope{
+			Manifest: []byte(intermediateRuntimeManifest),
+		},
+	}
+	params.intermediateSteps = []intermediateStep{
+		{runtime: intermediateRuntime, servers: params.servers},
+	}
+	plan := params.plan
+
+	leadMaster := params.servers[0]
+	builder := phaseBuilder{planConfig: params}
+	init := *builder.init(leadMaster.Server)
+	checks := *builder.checks().Require(init)
+	preUpdate := *builder.preUpdate().Require(init)
+	bootstrap := *builder.bootstrap().Require(init)
+	intermediate, err := builder.intermediate(params.intermediateSteps[0], params.installedRuntime, false)
+	c.Assert(err, check.IsNil)
+	intermediate.Require(checks, bootstrap, preUpdate)
+	masters := *builder.masters(leadMaster, params.servers[1:2], false).Require(checks, bootstrap, preUpdate, *intermediate)
+	nodes := *builder.nodes(leadMaster, params.servers[2:], false).Require(masters)
+	etcd := *builder.etcdPlan(leadMaster.Server, plan.Servers[1:2], plan.Servers[2:], "1.0.0", "2.0.0")
+	migration := builder.migration(leadMaster.Server).Require(etcd)
+	c.Assert(migration, check.NotNil)
+	config := *builder.config(servers(params.servers[:2]...)).Require(masters)
+
+	// Runtime updates are computed against the intermediate runtime
+	runtimeLocs := []loc.Locator{
+		loc.MustParseLocator("gravitational.io/runtime-dep-2:2.0.0"),
+		loc.MustParseLocator("gravitational.io/rbac-app:2.0.0"),
+		runtimeLoc2,
+	}
+	runtime := *builder.runtime(runtimeLocs).Require(masters)
+
+	appLocs := []loc.Locator{loc.MustParseLocator("gravitational.io/app-dep-2:2.0.0"), appLoc2}
+	app := *builder.app(appLocs).Require(runtime)
+	cleanup := *builder.cleanup().Require(app)
+
+	plan.Phases = update.Phases{
+		init,
+		checks,
+		preUpdate,
+		bootstrap,
+		*intermediate,
+		masters,
+		nodes,
+		etcd,
+		*migration,
+		config,
+		runtime,
+		app,
+		cleanup,
+	}.AsPhases()
+	update.ResolvePlan(&plan)
+
+	// exercise
+	obtainedPlan, err := newOperationPlan(params)
+	c.Assert(err, check.IsNil)
+	// Reset the capacity so the plans can be compared
+	obtainedPlan.Phases = resetCap(obtainedPlan.Phases)
+	update.ResolvePlan(obtainedPlan)
+
+	// verify
+	c.Assert(*obtainedPlan, compare.DeepEquals, plan)
+	c.Assert(obtainedPlan.Phases[0].Data.Update.IntermediateSteps, compare.DeepEquals,
+		[]storage.IntermediateUpdateStep{{Runtime: intermediateRuntimeLoc, Servers: params.servers}})
+
+	intermediatePhase := obtainedPlan.Phases[4]
+	c.Assert(intermediatePhase.ID, check.Equals, "/upgrade-1.5.0")
+	var ids []string
+	for _, phase := range intermediatePhase.Phases {
+		ids = append(ids, phase.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{
+		"/upgrade-1.5.0/masters",
+		"/upgrade-1.5.0/nodes",
+		"/upgrade-1.5.0/etcd",
+		"/upgrade-1.5.0/config",
+		"/upgrade-1.5.0/runtime",
+	})
+	c.Assert(intermediatePhase.Phases[1].Requires, check.DeepEquals, []string{"/upgrade-1.5.0/masters"})
+	systemUpgrade, err := fsm.FindPhase(obtainedPlan, "/upgrade-1.5.0/nodes/node-3/system-upgrade")
+	c.Assert(err, check.IsNil)
+	c.Assert(systemUpgrade.Data.Update.ChangesetID, check.Equals, "123-1.5.0")
+}
+
 func (s *PlanSuite) TestPlanWithoutRuntimeUpdate(c *check.C) {
 	// setup
 	runtimeLoc1 := loc.MustParseLocator("gravitational.io/runtime:1.0.0")
@@ -161,6 +272,153 @@ func (s *PlanSuite) TestPlanWithoutRuntimeUpdate(c *check.C) {
 	c.Assert(*obtainedPlan, compare.DeepEquals, plan)
 }
 
+func (s *PlanSuite) TestPlanWithUpdateHooks(c *check.C) {
+	// setup
+	runtimeLoc1 := loc.MustParseLocator("gravitational.io/runtime:1.0.0")
+	appLoc1 := loc.MustParseLocator("gravitational.io/app:1.0.0")
+	appLoc2 := loc.MustParseLocator("gravitational.io/app:2.0.0")
+
+	params := newTestPlan(c, params{
+		installedRuntime:         runtimeLoc1,
+		installedApp:             appLoc1,
+		updateRuntime:            runtimeLoc1,
+		updateApp:                appLoc2,
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    installedRuntimeManifest,
+		updateAppManifest:        updateAppManifestWithHooks,
+	})
+	plan := params.plan
+
+	leadMaster := params.servers[0]
+	builder := phaseBuilder{planConfig: params}
+	init := *builder.init(leadMaster.Server)
+	checks := *builder.checks().Require(init)
+	preUpdate := *builder.preUpdate().Require(init)
+	appLocs := []loc.Locator{loc.MustParseLocator("gravitational.io/app-dep-2:2.0.0"), appLoc2}
+	app := *builder.app(appLocs).Require(preUpdate)
+	health := *builder.healthCheck().Require(app)
+	cleanup := *builder.cleanup().Require(health)
+
+	plan.Phases = update.Phases{init, checks, preUpdate, app, health, cleanup}.AsPhases()
+	update.ResolvePlan(&plan)
+
+	// exercise
+	obtainedPlan, err := newOperationPlan(params)
+	c.Assert(err, check.IsNil)
+	// Reset the capacity so the plans can be compared
+	obtainedPlan.Phases = resetCap(obtainedPlan.Phases)
+	update.ResolvePlan(obtainedPlan)
+
+	// verify
+	c.Assert(*obtainedPlan, compare.DeepEquals, plan)
+
+	var ids []string
+	for _, phase := range builder.commonNode(leadMaster, leadMaster, false, waitsForEndpoints(false)) {
+		ids = append(ids, phase.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{"pre-update", "drain", "system-upgrade", "uncordon", "health"})
+}
+
+func (s *PlanSuite) TestDrainPhaseUsesOperationDrainOptions(c *check.C) {
+	// setup
+	runtimeLoc := loc.MustParseLocator("gravitational.io/runtime:1.0.0")
+	params := newTestPlan(c, params{
+		installedRuntime:         runtimeLoc,
+		installedApp:             loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		updateRuntime:            runtimeLoc,
+		updateApp:                loc.MustParseLocator("gravitational.io/app:2.0.0"),
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    installedRuntimeManifest,
+		updateAppManifest:        updateAppManifest,
+	})
+	options := storage.DrainOptions{Timeout: 5 * time.Minute, Force: true}
+	params.operation.Update = &storage.UpdateOperationState{Drain: &options}
+	leadMaster := params.servers[0]
+	builder := phaseBuilder{planConfig: params}
+
+	// exercise
+	phases := builder.commonNode(leadMaster, leadMaster, false, waitsForEndpoints(false))
+
+	// verify
+	c.Assert(phases[0].ID, check.Equals, "drain")
+	c.Assert(phases[0].Data.Drain, check.DeepEquals, &options)
+}
+
+func (s *PlanSuite) TestPlanWithCanaryNodes(c *check.C) {
+	// setup
+	params := newTestPlan(c, params{
+		installedRuntime:         loc.MustParseLocator("gravitational.io/runtime:1.0.0"),
+		installedApp:             loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		updateRuntime:            loc.MustParseLocator("gravitational.io/runtime:2.0.0"),
+		updateApp:                loc.MustParseLocator("gravitational.io/app:2.0.0"),
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    updateRuntimeManifest,
+		updateAppManifest:        updateAppManifestWithHooks,
+	})
+	node := params.servers[2]
+	node.Hostname = "node-4"
+	node.AdvertiseIP = "192.168.0.4"
+	params.servers = append(params.servers, node)
+	params.plan.Servers = append(params.plan.Servers, node.Server)
+	params.operation.Update = &storage.UpdateOperationState{
+		Canary: &storage.CanaryOptions{Nodes: 1, Pause: true},
+	}
+
+	// exercise
+	plan, err := newOperationPlan(params)
+	c.Assert(err, check.IsNil)
+
+	// verify
+	canary, err := fsm.FindPhase(plan, "/canary")
+	c.Assert(err, check.IsNil)
+	c.Assert(canary.Requires, check.DeepEquals, []string{"/masters"})
+	var ids []string
+	for _, phase := range canary.Phases {
+		ids = append(ids, phase.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{"/canary/nodes", "/canary/health", "/canary/approve"})
+	c.Assert(canary.Phases[0].Phases, check.HasLen, 1)
+	c.Assert(canary.Phases[0].Phases[0].ID, check.Equals, "/canary/nodes/node-3")
+	c.Assert(canary.Phases[2].Executor, check.Equals, canaryApproval)
+
+	nodes, err := fsm.FindPhase(plan, "/nodes")
+	c.Assert(err, check.IsNil)
+	c.Assert(nodes.Requires, check.DeepEquals, []string{"/masters", "/canary"})
+	c.Assert(nodes.Phases, check.HasLen, 1)
+	c.Assert(nodes.Phases[0].ID, check.Equals, "/nodes/node-4")
+}
+
+func (s *PlanSuite) TestPlanWithReadinessChecks(c *check.C) {
+	// setup
+	params := newTestPlan(c, params{
+		installedRuntime:         loc.MustParseLocator("gravitational.io/runtime:1.0.0"),
+		installedApp:             loc.MustParseLocator("gravitational.io/app:1.0.0"),
+		updateRuntime:            loc.MustParseLocator("gravitational.io/runtime:1.0.0"),
+		updateApp:                loc.MustParseLocator("gravitational.io/app:2.0.0"),
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    installedRuntimeManifest,
+		updateAppManifest:        updateAppManifestWithReadinessChecks,
+	})
+
+	// exercise
+	plan, err := newOperationPlan(params)
+	c.Assert(err, check.IsNil)
+
+	// verify
+	phase, err := fsm.FindPhase(plan, "/readiness")
+	c.Assert(err, check.IsNil)
+	c.Assert(phase.Executor, check.Equals, readinessCheck)
+	c.Assert(phase.Requires, check.DeepEquals, []string{"/app"})
+	c.Assert(*phase.Data.Package, check.Equals, loc.MustParseLocator("gravitational.io/app:2.0.0"))
+	gc, err := fsm.FindPhase(plan, "/gc")
+	c.Assert(err, check.IsNil)
+	c.Assert(gc.Requires, check.DeepEquals, []string{"/readiness"})
+}
+
 func (s *PlanSuite) TestUpdatesEtcdFromManifestWithoutLabels(c *check.C) {
 	services := opsservice.SetupTestServices(c)
 	files := []*archive.Item{
@@ -269,6 +527,61 @@ func (s *PlanSuite) TestCorrectlyDeterminesWhetherToUpdateEtcd(c *check.C) {
 	c.Assert(updateVersion, check.Equals, "3.3.3")
 }
 
+func (s *PlanSuite) TestValidatesEtcdUpgradePath(c *check.C) {
+	var testCases = []struct {
+		installed *semver.Version
+		update    semver.Version
+		valid     bool
+		comment   string
+	}{
+		{
+			installed: nil,
+			update:    *semver.New("3.3.3"),
+			valid:     true,
+			comment:   "unknown installed version",
+		},
+		{
+			installed: semver.New("3.2.24"),
+			update:    *semver.New("3.3.11"),
+			valid:     true,
+			comment:   "minor upgrade",
+		},
+		{
+			installed: semver.New("2.3.8"),
+			update:    *semver.New("3.3.11"),
+			valid:     true,
+			comment:   "major upgrade",
+		},
+		{
+			installed: semver.New("3.3.11"),
+			update:    *semver.New("3.3.11"),
+			valid:     true,
+			comment:   "same version",
+		},
+		{
+			installed: semver.New("3.3.11"),
+			update:    *semver.New("3.2.24"),
+			valid:     false,
+			comment:   "downgrade",
+		},
+		{
+			installed: semver.New("2.3.8"),
+			update:    *semver.New("4.0.0"),
+			valid:     false,
+			comment:   "skips major version",
+		},
+	}
+	for _, tc := range testCases {
+		comment := check.Commentf(tc.comment)
+		err := checkEtcdUpgradePath(tc.installed, tc.update)
+		if tc.valid {
+			c.Assert(err, check.IsNil, comment)
+		} else {
+			c.Assert(trace.IsBadParameter(err), check.Equals, true, comment)
+		}
+	}
+}
+
 func newTestPlan(c *check.C, p params) planConfig {
 	runtimeLoc := loc.MustParseLocator("gravitational.io/planet:2.0.0")
 	servers := []storage.Server{
@@ -461,6 +774,20 @@ dependencies:
     - gravitational.io/rbac-app:2.0.0
 `
 
+const intermediateRuntimeManifest = `apiVersion: bundle.gravitational.io/v2
+kind: Runtime
+metadata:
+  name: runtime
+  resourceVersion: 1.5.0
+dependencies:
+  packages:
+    - gravitational.io/gravity:1.5.0
+  apps:
+    - gravitational.io/runtime-dep-1:1.0.0
+    - gravitational.io/runtime-dep-2:1.5.0
+    - gravitational.io/rbac-app:1.0.0
+`
+
 const updateAppManifest = `apiVersion: bundle.gravitational.io/v2
 kind: Bundle
 metadata:
@@ -476,3 +803,48 @@ systemOptions:
   dependencies:
     runtimePackage: gravitational.io/planet:2.0.0
 `
+
+const updateAppManifestWithHooks = `apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: app
+  resourceVersion: 2.0.0
+dependencies:
+  apps:
+    - gravitational.io/app-dep-1:1.0.0
+    - gravitational.io/app-dep-2:2.0.0
+nodeProfiles:
+  - name: node
+systemOptions:
+  dependencies:
+    runtimePackage: gravitational.io/planet:2.0.0
+hooks:
+  preNodeUpdate:
+    job: file://path/to/job.yaml
+  healthCheck:
+    job: file://path/to/job.yaml
+    timeout: 5m
+    retries: 3
+    onFailure: continue
+`
+
+const updateAppManifestWithReadinessChecks = `apiVersion: bundle.gravitational.io/v2
+kind: Bundle
+metadata:
+  name: app
+  resourceVersion: 2.0.0
+dependencies:
+  apps:
+    - gravitational.io/app-dep-1:1.0.0
+    - gravitational.io/app-dep-2:2.0.0
+nodeProfiles:
+  - name: node
+systemOptions:
+  dependencies:
+    runtimePackage: gravitational.io/planet:1.0.0
+readinessChecks:
+  - name: backend
+    resource:
+      kind: Deployment
+      name: backend
+`
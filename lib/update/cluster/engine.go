@@ -100,13 +100,15 @@ func newMachine(ctx context.Context, c Config) (*fsm.FSM, error) {
 		return nil, trace.Wrap(err)
 	}
 	fsm, err := fsm.New(fsm.Config{
-		Engine: engine,
-		Logger: logger,
-		Runner: c.Runner,
+		Engine:      engine,
+		Logger:      logger,
+		Runner:      c.Runner,
+		Parallelism: c.Parallelism,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	fsm.SetInterruptCheck(engine.CheckInterrupt)
 	return fsm, nil
 }
 
@@ -184,6 +186,15 @@ func (f *engine) PreRollback(ctx context.Context, p fsm.Params) error {
 	return nil
 }
 
+// CheckInterrupt returns the pending pause or cancellation request of the operation
+func (f *engine) CheckInterrupt(ctx context.Context) (string, error) {
+	op, err := f.Operator.GetSiteOperation(f.Operation.Key())
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return op.Interrupt, nil
+}
+
 // Complete marks the provided update operation as completed or failed
 // and moves the cluster into active state
 func (f *engine) Complete(fsmErr error) error {
@@ -287,6 +298,7 @@ func (f *engine) ChangePhaseState(ctx context.Context, change fsm.StateChange) e
 		NewState:    change.State,
 		Error:       utils.ToRawTrace(change.Error),
 		Created:     time.Now().UTC(),
+		Attempt:     change.Attempt,
 	})
 	if err != nil {
 		f.WithError(err).Warnf("Error recording phase state change %+v.", change)
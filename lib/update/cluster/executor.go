@@ -20,6 +20,8 @@ import (
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
 	libphase "github.com/gravitational/gravity/lib/update/cluster/phases"
 
 	"github.com/gravitational/trace"
@@ -37,6 +39,12 @@ const (
 	updateSystem = "update_system"
 	// preUpdate is the phase to run pre-update application hook
 	preUpdate = "pre_update"
+	// preNodeUpdate is the phase to run application hook before a node is updated
+	preNodeUpdate = "pre_node_update"
+	// healthCheck is the phase to run application health check hook
+	healthCheck = "health_check"
+	// readinessCheck is the phase to wait for the application readiness checks
+	readinessCheck = "readiness_check"
 	// coredns is a phase to create coredns related roles
 	coredns = "coredns"
 	// updateApp is the phase to update the application
@@ -75,10 +83,21 @@ const (
 	updateEtcdRestart = "etcd_restart"
 	// updateEtcdRestartGravity is the phase that restarts gravity-site
 	updateEtcdRestartGravity = "etcd_restart_gravity"
+	// updateEtcdVerify is the phase that verifies the etcd cluster after upgrade
+	updateEtcdVerify = "etcd_verify"
 	// cleanupNode is the phase to clean up a node after the upgrade
 	cleanupNode = "cleanup_node"
+	// canaryApproval is the phase to pause the operation after
+	// the canary nodes have been updated
+	canaryApproval = "canary_approval"
 )
 
+// CreatesBootstrapResources returns true if the specified phase creates
+// Kubernetes bootstrap resources of the application being updated
+func CreatesBootstrapResources(phase storage.OperationPhase) bool {
+	return phase.Executor == updateApp
+}
+
 // fsmSpec returns the function that returns an appropriate phase executor
 func fsmSpec(c Config) fsm.FSMSpecFunc {
 	return func(p fsm.ExecutorParams, remote fsm.Remote) (fsm.PhaseExecutor, error) {
@@ -106,7 +125,7 @@ func fsmSpec(c Config) fsm.FSMSpecFunc {
 				c.Backend, c.LocalBackend, c.ClusterPackages, c.Users,
 				logger)
 		case updateChecks:
-			return libphase.NewUpdatePhaseChecks(p, c.Operator, c.Apps, c.Runner, logger)
+			return libphase.NewUpdatePhaseChecks(p, c.Operator, c.Apps, c.ClusterPackages, c.Runner, logger)
 		case updateBootstrap:
 			return libphase.NewUpdatePhaseBootstrap(p, c.Operator,
 				c.Backend, c.LocalBackend, c.HostLocalBackend,
@@ -122,6 +141,14 @@ func fsmSpec(c Config) fsm.FSMSpecFunc {
 			return libphase.NewUpdatePhaseBeforeApp(p, c.Apps, c.Client, logger)
 		case updateApp:
 			return libphase.NewUpdatePhaseApp(p, c.Operator, c.Apps, c.Client, logger)
+		case preNodeUpdate:
+			return libphase.NewUpdatePhaseHook(p, c.Operator, c.Apps, c.Client, logger,
+				schema.HookNodeUpdating)
+		case healthCheck:
+			return libphase.NewUpdatePhaseHook(p, c.Operator, c.Apps, c.Client, logger,
+				schema.HookHealthCheck)
+		case readinessCheck:
+			return libphase.NewPhaseReadiness(p, c.Apps, c.Client, logger)
 		case electionStatus:
 			return libphase.NewPhaseElectionChange(p, c.Operator, remote, logger)
 		case taintNode:
@@ -156,8 +183,12 @@ func fsmSpec(c Config) fsm.FSMSpecFunc {
 			return libphase.NewPhaseUpgradeEtcdRestart(p.Phase, logger)
 		case updateEtcdRestartGravity:
 			return libphase.NewPhaseUpgradeGravitySiteRestart(p.Phase, c.Client, logger)
+		case updateEtcdVerify:
+			return libphase.NewPhaseUpgradeEtcdVerify(p.Phase, logger)
 		case cleanupNode:
 			return libphase.NewGarbageCollectPhase(p, remote, logger)
+		case canaryApproval:
+			return libphase.NewPhaseCanaryApproval(p, c.Operator, logger)
 		default:
 			return nil, trace.BadParameter(
 				"phase %q requires executor %q (potential mismatch between upgrade versions)",
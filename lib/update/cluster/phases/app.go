@@ -157,6 +157,64 @@ func (p *updatePhaseBeforeApp) Rollback(context.Context) error {
 	return nil
 }
 
+// updatePhaseHook is an executor for the application hooks run during
+// the update, either for a specific node or for the whole cluster
+type updatePhaseHook struct {
+	phaseApp
+	// hook is the type of the hook to run
+	hook schema.HookType
+}
+
+// NewUpdatePhaseHook returns a new executor for running the specified application hook.
+// If the phase specifies a server, the hook is run for the Kubernetes node of this server
+func NewUpdatePhaseHook(
+	p fsm.ExecutorParams,
+	operator ops.Operator,
+	apps app.Applications,
+	client *kubernetes.Clientset,
+	logger log.FieldLogger,
+	hook schema.HookType,
+) (*updatePhaseHook, error) {
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if p.Phase.Data.Package == nil {
+		return nil, trace.NotFound("no package specified for phase %q", p.Phase.ID)
+	}
+	env := map[string]string{}
+	if p.Phase.Data.Server != nil {
+		env[constants.UpdateNodeEnvVar] = p.Phase.Data.Server.KubeNodeID()
+	}
+	return &updatePhaseHook{
+		phaseApp: phaseApp{
+			FieldLogger:    logger,
+			Apps:           apps,
+			Client:         client,
+			GravityPackage: p.Plan.GravityPackage,
+			Package:        *p.Phase.Data.Package,
+			Servers:        p.Plan.Servers,
+			ServiceUser:    cluster.ServiceUser,
+			Env:            env,
+		},
+		hook: hook,
+	}, nil
+}
+
+// Execute runs the application hook
+func (p *updatePhaseHook) Execute(ctx context.Context) error {
+	err := p.runHooks(ctx, p.hook)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// Rollback is a no-op for this phase
+func (p *updatePhaseHook) Rollback(context.Context) error {
+	return nil
+}
+
 type phaseApp struct {
 	// Apps is the cluster apps service
 	Apps app.Applications
@@ -170,6 +228,8 @@ type phaseApp struct {
 	Servers []storage.Server
 	// ServiceUser is the user used for services and system storage
 	ServiceUser storage.OSUser
+	// Env is the additional environment to run hooks with
+	Env map[string]string
 	log.FieldLogger
 }
 
@@ -195,7 +255,10 @@ func (p *phaseApp) runHooks(ctx context.Context, hooks ...schema.HookType) error
 			},
 			ServiceUser: p.ServiceUser,
 		}
-		_, err := app.CheckHasAppHook(p.Apps, req)
+		for name, value := range p.Env {
+			req.Env[name] = value
+		}
+		spec, err := app.CheckHasAppHook(p.Apps, req)
 		if err != nil {
 			if trace.IsNotFound(err) {
 				p.Debugf("%v does not have %v hook.", p.Package, hook)
@@ -203,11 +266,14 @@ func (p *phaseApp) runHooks(ctx context.Context, hooks ...schema.HookType) error
 			}
 			return trace.Wrap(err)
 		}
-		p.Infof("Execute %v(%v) hook.", p.Package, hook)
-		reader, writer := io.Pipe()
-		defer writer.Close()
-		go streamHook(hook, reader, p.FieldLogger)
-		_, err = app.StreamAppHook(ctx, p.Apps, req, writer)
+		err = app.RunHookWithPolicy(ctx, *spec, hook, p.FieldLogger, func() error {
+			p.Infof("Execute %v(%v) hook.", p.Package, hook)
+			reader, writer := io.Pipe()
+			defer writer.Close()
+			go streamHook(hook, reader, p.FieldLogger)
+			_, err := app.StreamAppHook(ctx, p.Apps, req, writer)
+			return trace.Wrap(err)
+		})
 		if err != nil {
 			return trace.Wrap(err, "%v(%v) hook failed", p.Package, hook)
 		}
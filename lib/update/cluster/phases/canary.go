@@ -0,0 +1,70 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewPhaseCanaryApproval returns a new executor that pauses the operation
+// after the canary nodes have been updated
+func NewPhaseCanaryApproval(p fsm.ExecutorParams, operator ops.Operator, logger log.FieldLogger) (*phaseCanaryApproval, error) {
+	return &phaseCanaryApproval{
+		FieldLogger: logger,
+		operator:    operator,
+		key:         p.Key(),
+	}, nil
+}
+
+// Execute requests the operation to pause before the rest of the nodes
+// are updated. The operation stops after this phase and continues
+// once the update is approved with 'gravity plan resume'
+func (p *phaseCanaryApproval) Execute(ctx context.Context) error {
+	p.Info("Canary nodes have been updated, pause the operation for approval.")
+	err := p.operator.PauseSiteOperation(ctx, p.key)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// Rollback is a no-op for this phase
+func (*phaseCanaryApproval) Rollback(context.Context) error {
+	return nil
+}
+
+// PreCheck is no-op for this phase
+func (*phaseCanaryApproval) PreCheck(context.Context) error {
+	return nil
+}
+
+// PostCheck is no-op for this phase
+func (*phaseCanaryApproval) PostCheck(context.Context) error {
+	return nil
+}
+
+type phaseCanaryApproval struct {
+	log.FieldLogger
+	operator ops.Operator
+	key      ops.SiteOperationKey
+}
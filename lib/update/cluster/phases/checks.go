@@ -20,9 +20,11 @@ import (
 	"context"
 
 	"github.com/gravitational/gravity/lib/app"
+	appservice "github.com/gravitational/gravity/lib/app/service"
 	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/storage"
 
 	"github.com/gravitational/trace"
@@ -35,6 +37,8 @@ type updatePhaseChecks struct {
 	log.FieldLogger
 	// apps is the cluster apps service
 	apps app.Applications
+	// packages is the cluster package service
+	packages pack.PackageService
 	// servers is the list of local cluster servers
 	servers []storage.Server
 	// updatePackage specifies the updated application package
@@ -51,6 +55,7 @@ func NewUpdatePhaseChecks(
 	p fsm.ExecutorParams,
 	operator ops.Operator,
 	apps app.Applications,
+	packages pack.PackageService,
 	remote fsm.AgentRepository,
 	logger log.FieldLogger,
 ) (*updatePhaseChecks, error) {
@@ -67,6 +72,7 @@ func NewUpdatePhaseChecks(
 	return &updatePhaseChecks{
 		FieldLogger:      logger,
 		apps:             apps,
+		packages:         packages,
 		servers:          p.Plan.Servers,
 		updatePackage:    *p.Phase.Data.Package,
 		installedPackage: *p.Phase.Data.InstalledPackage,
@@ -95,6 +101,9 @@ func (p *updatePhaseChecks) Execute(ctx context.Context) error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if err := p.verifyDependencies(app); err != nil {
+		return trace.Wrap(err)
+	}
 
 	dockerConfig := storage.DockerConfig{
 		StorageDriver: p.existingDocker.StorageDriver,
@@ -109,6 +118,31 @@ func (p *updatePhaseChecks) Execute(ctx context.Context) error {
 	return trace.Wrap(err, "failed to validate requirements")
 }
 
+// verifyDependencies makes sure that all packages of the update application
+// are available in the cluster. Delta upgrades only ship packages that have
+// changed and rely on the rest being present in the cluster already
+func (p *updatePhaseChecks) verifyDependencies(update *app.Application) error {
+	err := app.VerifyDependencies(update, p.apps, p.packages)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("some packages of %v are missing from the cluster, "+
+				"if this is a delta upgrade make sure it has been generated "+
+				"against the installed version %v: %v",
+				p.updatePackage, p.installedPackage, err)
+		}
+		return trace.Wrap(err)
+	}
+	base, err := appservice.GetDeltaBase(p.packages, p.updatePackage)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if base != nil {
+		return trace.BadParameter("image layers of %v have not been restored "+
+			"from %v, please upload the upgrade again", p.updatePackage, base)
+	}
+	return nil
+}
+
 // Rollback is a no-op for this phase
 func (p *updatePhaseChecks) Rollback(context.Context) error {
 	return nil
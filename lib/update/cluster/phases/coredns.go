@@ -20,9 +20,9 @@ import (
 	"context"
 
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/coredns"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/fsm"
-	libinstall "github.com/gravitational/gravity/lib/install/phases"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/systeminfo"
@@ -138,7 +138,7 @@ func (p *updatePhaseCoreDNS) generateCorefile(context.Context) error {
 		return trace.Wrap(err)
 	}
 
-	conf, err := libinstall.GenerateCorefile(libinstall.CorednsConfig{
+	conf, err := coredns.GenerateCorefile(coredns.Config{
 		UpstreamNameservers: resolvConf.Servers,
 		Rotate:              resolvConf.Rotate,
 		Hosts:               p.DNSOverrides.Hosts,
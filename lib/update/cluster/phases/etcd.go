@@ -19,6 +19,7 @@ package phases
 import (
 	"context"
 	"path/filepath"
+	"strings"
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
@@ -26,6 +27,7 @@ import (
 	"github.com/gravitational/gravity/lib/kubernetes"
 	"github.com/gravitational/gravity/lib/state"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
 	"github.com/gravitational/gravity/lib/update"
 	"github.com/gravitational/gravity/lib/utils"
 
@@ -45,7 +47,7 @@ import (
 // 2. Planet when started, will determine the version of etcd to use (planet etcd init)
 //      This is done by assuming the oldest possible etcd release
 //      During an upgrade, the verison of etcd to use is written to the etcd data directory
-// 3. Backup all etcd data via API
+// 3. Backup all etcd data via API, along with the v3 snapshot if the running version supports it
 // 4. Shutdown etcd (all servers) // API outage starts
 // 6. Start the cluster masters, but with clients bound to an alternative address (127.0.0.2) and using new data dir
 //      The data directory is chosen as /ext/etcd/<version>, so when upgrading, etcd will start with a blank database
@@ -54,6 +56,7 @@ import (
 // 7. Restore the etcd data using the API to the new version, and migrate /registry (kubernetes) data to v3 datastore
 // 8. Restart etcd on the correct ports// API outage ends
 // 9. Restart gravity-site to fix elections
+// 10. Verify that all members are healthy and run the new version
 //
 //
 // Rollback
@@ -81,6 +84,14 @@ func backupFile() (string, error) {
 	return filepath.Join(state.GravityUpdateDir(stateDir), defaults.EtcdUpgradeBackupFile), nil
 }
 
+func snapshotFile() (string, error) {
+	stateDir, err := state.GetStateDir()
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return filepath.Join(state.GravityUpdateDir(stateDir), defaults.EtcdUpgradeSnapshotFile), nil
+}
+
 func (p *PhaseUpgradeEtcdBackup) Execute(ctx context.Context) error {
 	p.Info("Backup etcd.")
 	backupFile, err := backupFile()
@@ -91,6 +102,31 @@ func (p *PhaseUpgradeEtcdBackup) Execute(ctx context.Context) error {
 	if err != nil {
 		return trace.Wrap(err, "failed to backup etcd")
 	}
+	// The snapshot complements the backup above with the data that can be
+	// restored with `gravity etcd restore` should the upgrade fail midway.
+	// Versions of etcd without v3 API cannot produce it so it is optional
+	if err := p.saveSnapshot(ctx); err != nil {
+		p.WithError(err).Warn("Failed to save etcd snapshot.")
+	}
+	return nil
+}
+
+func (p *PhaseUpgradeEtcdBackup) saveSnapshot(ctx context.Context) error {
+	snapshotFile, err := snapshotFile()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	config, err := keyval.LocalEtcdConfig(0)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, defaults.EtcdSnapshotTimeout)
+	defer cancel()
+	status, err := keyval.SaveSnapshot(ctx, *config, snapshotFile)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	p.Infof("Saved etcd snapshot at revision %v to %v.", status.Revision, snapshotFile)
 	return nil
 }
 
@@ -320,6 +356,79 @@ func (*PhaseUpgradeEtcdRestart) PostCheck(context.Context) error {
 	return nil
 }
 
+// PhaseUpgradeEtcdVerify verifies that all etcd members are healthy
+// and run the new version of etcd after the upgrade
+type PhaseUpgradeEtcdVerify struct {
+	log.FieldLogger
+	// Version is the version of etcd members are expected to run
+	Version string
+}
+
+// NewPhaseUpgradeEtcdVerify creates a phase that verifies the etcd cluster after the upgrade
+func NewPhaseUpgradeEtcdVerify(phase storage.OperationPhase, logger log.FieldLogger) (fsm.PhaseExecutor, error) {
+	if phase.Data == nil || phase.Data.Data == "" {
+		return nil, trace.BadParameter("phase %q does not specify the etcd version", phase.ID)
+	}
+	return &PhaseUpgradeEtcdVerify{
+		FieldLogger: logger,
+		Version:     phase.Data.Data,
+	}, nil
+}
+
+// Execute waits for all etcd members to become healthy and report the new version
+func (p *PhaseUpgradeEtcdVerify) Execute(ctx context.Context) error {
+	p.Infof("Verify etcd cluster runs version %v.", p.Version)
+	config, err := keyval.LocalEtcdConfig(0)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = update.Retry(ctx, func() error {
+		members, err := keyval.EtcdMembers(ctx, *config)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(verifyEtcdMembers(members, p.Version))
+	}, defaults.EtcdUpgradeVerifyTimeout)
+	return trace.Wrap(err)
+}
+
+// Rollback is a no-op for this phase
+func (*PhaseUpgradeEtcdVerify) Rollback(context.Context) error {
+	return nil
+}
+
+// PreCheck is a no-op for this phase
+func (*PhaseUpgradeEtcdVerify) PreCheck(context.Context) error {
+	return nil
+}
+
+// PostCheck is a no-op for this phase
+func (*PhaseUpgradeEtcdVerify) PostCheck(context.Context) error {
+	return nil
+}
+
+// verifyEtcdMembers returns an error if any of the members is unhealthy
+// or runs a version other than the specified one
+func verifyEtcdMembers(members []keyval.EtcdMemberStatus, version string) error {
+	if len(members) == 0 {
+		return trace.NotFound("no etcd members found")
+	}
+	version = strings.TrimPrefix(version, "v")
+	var errors []error
+	for _, member := range members {
+		if !member.Healthy {
+			errors = append(errors, trace.BadParameter("etcd member %v is unhealthy: %v",
+				member.Name, member.Error))
+			continue
+		}
+		if strings.TrimPrefix(member.Version, "v") != version {
+			errors = append(errors, trace.BadParameter("etcd member %v runs version %v, expected %v",
+				member.Name, member.Version, version))
+		}
+	}
+	return trace.NewAggregate(errors...)
+}
+
 // PhaseUpgradeGravitySiteRestart restarts gravity-site pod
 type PhaseUpgradeGravitySiteRestart struct {
 	log.FieldLogger
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"testing"
+
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestPhases(t *testing.T) { TestingT(t) }
+
+type EtcdSuite struct{}
+
+var _ = Suite(&EtcdSuite{})
+
+func (*EtcdSuite) TestVerifiesEtcdMembers(c *C) {
+	healthy := []keyval.EtcdMemberStatus{
+		{Name: "node-1", Healthy: true, Version: "3.3.11", Leader: true},
+		{Name: "node-2", Healthy: true, Version: "3.3.11"},
+	}
+	c.Assert(verifyEtcdMembers(healthy, "v3.3.11"), IsNil)
+
+	outdated := []keyval.EtcdMemberStatus{
+		{Name: "node-1", Healthy: true, Version: "3.3.11", Leader: true},
+		{Name: "node-2", Healthy: true, Version: "3.2.24"},
+	}
+	c.Assert(verifyEtcdMembers(outdated, "3.3.11"), ErrorMatches, ".*node-2 runs version 3.2.24.*")
+
+	unhealthy := []keyval.EtcdMemberStatus{
+		{Name: "node-1", Healthy: true, Version: "3.3.11", Leader: true},
+		{Name: "node-2", Error: "connection refused"},
+	}
+	c.Assert(verifyEtcdMembers(unhealthy, "3.3.11"), ErrorMatches, ".*node-2 is unhealthy.*")
+
+	c.Assert(verifyEtcdMembers(nil, "3.3.11"), NotNil)
+}
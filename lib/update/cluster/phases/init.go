@@ -61,6 +61,8 @@ type updatePhaseInit struct {
 	Operation ops.SiteOperation
 	// Servers is the list of local cluster servers
 	Servers []storage.UpdateServer
+	// IntermediateSteps lists the server updates for intermediate runtimes
+	IntermediateSteps []storage.IntermediateUpdateStep
 	// FieldLogger is used for logging
 	log.FieldLogger
 	// updateManifest specifies the manifest of the update application
@@ -138,6 +140,7 @@ func NewUpdatePhaseInit(
 		Cluster:               *cluster,
 		Operation:             *operation,
 		Servers:               p.Phase.Data.Update.Servers,
+		IntermediateSteps:     p.Phase.Data.Update.IntermediateSteps,
 		FieldLogger:           logger,
 		updateManifest:        app.Manifest,
 		installedApp:          *installedApp,
@@ -192,11 +195,28 @@ func (p *updatePhaseInit) Execute(context.Context) error {
 			}
 		}
 		if server.Teleport.Update != nil {
-			if err := p.rotateTeleportConfig(server); err != nil {
+			if err := p.rotateTeleportConfig(server, true); err != nil {
 				return trace.Wrap(err, "failed to rotate teleport configuration for %v", server)
 			}
 		}
 	}
+	for _, step := range p.IntermediateSteps {
+		for _, server := range step.Servers {
+			if server.Runtime.Update != nil {
+				if err := p.rotatePlanetConfig(server); err != nil {
+					return trace.Wrap(err, "failed to rotate planet configuration for %v (intermediate runtime %v)",
+						server, step.Runtime)
+				}
+			}
+			if server.Teleport.Update != nil {
+				// Teleport master configuration is only generated for the final runtime
+				if err := p.rotateTeleportConfig(server, false); err != nil {
+					return trace.Wrap(err, "failed to rotate teleport configuration for %v (intermediate runtime %v)",
+						server, step.Runtime)
+				}
+			}
+		}
+	}
 	return nil
 }
 
@@ -377,7 +397,9 @@ func (p *updatePhaseInit) rotatePlanetConfig(server storage.UpdateServer) error
 	return nil
 }
 
-func (p *updatePhaseInit) rotateTeleportConfig(server storage.UpdateServer) error {
+// rotateTeleportConfig generates new teleport node configuration package for the specified server.
+// If withMasterConfig is set, it also generates the teleport master configuration package for masters
+func (p *updatePhaseInit) rotateTeleportConfig(server storage.UpdateServer, withMasterConfig bool) error {
 	masterConf, nodeConf, err := p.Operator.RotateTeleportConfig(ops.RotateTeleportConfigRequest{
 		Key:       p.Operation.Key(),
 		Server:    server.Server,
@@ -387,7 +409,7 @@ func (p *updatePhaseInit) rotateTeleportConfig(server storage.UpdateServer) erro
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	if masterConf != nil {
+	if masterConf != nil && withMasterConfig {
 		_, err = p.Packages.UpsertPackage(masterConf.Locator, masterConf.Reader, pack.WithLabels(masterConf.Labels))
 		if err != nil {
 			return trace.Wrap(err)
@@ -105,6 +105,8 @@ func (p *phaseUntaint) Rollback(context.Context) error {
 // phaseDrain defines the operation of draining a node
 type phaseDrain struct {
 	kubernetesOperation
+	// options specifies the drain parameters
+	options storage.DrainOptions
 }
 
 // NewPhaseDrain returns a new executor for draining a node
@@ -115,6 +117,7 @@ func NewPhaseDrain(p fsm.ExecutorParams, client *kubeapi.Clientset, logger log.F
 	}
 	return &phaseDrain{
 		kubernetesOperation: *op,
+		options:             drainOptions(p.Phase.Data),
 	}, nil
 }
 
@@ -124,7 +127,7 @@ func (p *phaseDrain) Execute(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, defaults.DrainTimeout)
 	defer cancel()
 	err := update.Retry(ctx, func() error {
-		return trace.Wrap(drain(ctx, p.Client, p.Server.KubeNodeID()))
+		return trace.Wrap(drain(ctx, p.Client, p.Server.KubeNodeID(), p.options))
 	}, defaults.DrainErrorTimeout)
 	return trace.Wrap(err)
 }
@@ -288,11 +291,19 @@ func taint(ctx context.Context, client corev1.NodeInterface, node string, add ad
 	return nil
 }
 
-func drain(ctx context.Context, client *kubeapi.Clientset, node string) error {
-	err := kubernetes.Drain(ctx, client, node)
+func drain(ctx context.Context, client *kubeapi.Clientset, node string, options storage.DrainOptions) error {
+	err := kubernetes.Drain(ctx, client, node, options)
 	return trace.Wrap(err)
 }
 
+// drainOptions returns the drain parameters from the specified phase data
+func drainOptions(data *storage.OperationPhaseData) storage.DrainOptions {
+	if data == nil || data.Drain == nil {
+		return storage.DrainOptions{}
+	}
+	return *data.Drain
+}
+
 func uncordon(ctx context.Context, client corev1.NodeInterface, node string) error {
 	err := kubernetes.SetUnschedulable(ctx, client, node, false)
 	return trace.Wrap(err)
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/readiness"
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NewPhaseReadiness returns a new executor that waits for the readiness
+// checks declared in the manifest of the updated application to succeed
+func NewPhaseReadiness(p fsm.ExecutorParams, apps app.Applications, client *kubernetes.Clientset, logger log.FieldLogger) (*phaseReadiness, error) {
+	if p.Phase.Data == nil || p.Phase.Data.Package == nil {
+		return nil, trace.NotFound("no package specified for phase %q", p.Phase.ID)
+	}
+	application, err := apps.GetApp(*p.Phase.Data.Package)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	checker, err := readiness.New(readiness.Config{
+		Client:      client,
+		DNSAddr:     p.Plan.DNSConfig.Addr(),
+		FieldLogger: logger,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &phaseReadiness{
+		FieldLogger: logger,
+		checker:     checker,
+		checks:      application.Manifest.ReadinessChecks,
+	}, nil
+}
+
+// Execute waits for the application readiness checks to succeed
+func (p *phaseReadiness) Execute(ctx context.Context) error {
+	err := p.checker.Wait(ctx, p.checks)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	p.Info("Application is ready.")
+	return nil
+}
+
+// Rollback is a no-op for this phase
+func (*phaseReadiness) Rollback(context.Context) error {
+	return nil
+}
+
+// PreCheck is no-op for this phase
+func (*phaseReadiness) PreCheck(context.Context) error {
+	return nil
+}
+
+// PostCheck is no-op for this phase
+func (*phaseReadiness) PostCheck(context.Context) error {
+	return nil
+}
+
+type phaseReadiness struct {
+	log.FieldLogger
+	checker *readiness.Checker
+	checks  []schema.ReadinessCheck
+}
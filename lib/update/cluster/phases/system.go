@@ -41,6 +41,8 @@ import (
 type updatePhaseSystem struct {
 	// OperationID is the id of the current update operation
 	OperationID string
+	// ChangesetID is the id of the package changeset for the system update
+	ChangesetID string
 	// Server is the server currently being updated
 	Server storage.UpdateServer
 	// Backend specifies the backend used for the update operation
@@ -68,8 +70,13 @@ func NewUpdatePhaseSystem(
 	if p.Phase.Data.Update == nil || len(p.Phase.Data.Update.Servers) == 0 {
 		return nil, trace.NotFound("no server specified for phase %q", p.Phase.ID)
 	}
+	changesetID := p.Plan.OperationID
+	if p.Phase.Data.Update.ChangesetID != "" {
+		changesetID = p.Phase.Data.Update.ChangesetID
+	}
 	return &updatePhaseSystem{
 		OperationID:       p.Plan.OperationID,
+		ChangesetID:       changesetID,
 		Server:            p.Phase.Data.Update.Servers[0],
 		GravityPackage:    p.Plan.GravityPackage,
 		Backend:           backend,
@@ -93,7 +100,7 @@ func (p *updatePhaseSystem) PostCheck(context.Context) error {
 // Execute runs system update on the node
 func (p *updatePhaseSystem) Execute(ctx context.Context) error {
 	config := system.Config{
-		ChangesetID: p.OperationID,
+		ChangesetID: p.ChangesetID,
 		Backend:     p.Backend,
 		Packages:    p.HostLocalPackages,
 		PackageUpdates: system.PackageUpdates{
@@ -141,7 +148,7 @@ func (p *updatePhaseSystem) Execute(ctx context.Context) error {
 // Rollback runs rolls back the system upgrade on the node
 func (p *updatePhaseSystem) Rollback(ctx context.Context) error {
 	updater, err := system.New(system.Config{
-		ChangesetID: p.OperationID,
+		ChangesetID: p.ChangesetID,
 		Backend:     p.Backend,
 		Packages:    p.HostLocalPackages,
 	})
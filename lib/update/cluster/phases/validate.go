@@ -216,6 +216,10 @@ func requirementsFromManifests(old, new schema.Manifest, profiles map[string]str
 				Ports: checks.Ports{TCP: tcp, UDP: udp},
 			},
 		}
+		// only check the GPUs if the new version requires more of them
+		if newProfile.Requirements.GPU.Min > oldProfile.Requirements.GPU.Min {
+			req.GPU = &newProfile.Requirements.GPU
+		}
 		result[profileName] = req
 	}
 
@@ -174,9 +174,36 @@ func NewOperationPlan(config PlanConfig) (*storage.OperationPlan, error) {
 		return nil, trace.Wrap(err)
 	}
 
+	upgradePath, err := pack.RuntimeUpgradePath(installedRuntime.Package, updateRuntime.Package,
+		updateApp.Manifest.IntermediateRuntimes())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	operationKey := (*ops.SiteOperation)(config.Operation).Key()
+	installedManifest := installedApp.Manifest
+	var intermediateSteps []intermediateStep
+	for _, runtimePackage := range upgradePath {
+		runtime, err := config.Apps.GetApp(runtimePackage)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		manifest := intermediateManifest(updateApp.Manifest, *runtime)
+		updates, err := configUpdates(installedManifest, manifest,
+			config.Operator, operationKey, servers)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		intermediateSteps = append(intermediateSteps, intermediateStep{
+			runtime: *runtime,
+			servers: updates,
+		})
+		installedManifest = manifest
+	}
+
 	updates, err := configUpdates(
-		installedApp.Manifest, updateApp.Manifest,
-		config.Operator, (*ops.SiteOperation)(config.Operation).Key(), servers)
+		installedManifest, updateApp.Manifest,
+		config.Operator, operationKey, servers)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -211,6 +238,8 @@ func NewOperationPlan(config PlanConfig) (*storage.OperationPlan, error) {
 		dnsConfig:         config.DNSConfig,
 		updateDNSAppEarly: updateDNSAppEarly,
 		roles:             roles,
+		intermediateSteps: intermediateSteps,
+		apps:              config.Apps,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -287,6 +316,59 @@ type planConfig struct {
 	updateDNSAppEarly bool
 	// roles is the existing cluster roles
 	roles []teleservices.Role
+	// intermediateSteps lists the intermediate runtime upgrades
+	// to perform before upgrading to the update runtime
+	intermediateSteps []intermediateStep
+	// apps optionally specifies the application service used to look up
+	// transitive dependencies. If unset, only direct dependencies are compared
+	apps app.Getter
+}
+
+// updatedDependencies returns the updated dependencies of the update application.
+// If the application service is available, dependencies are compared at any depth
+func (r planConfig) updatedDependencies(installed, update app.Application) ([]loc.Locator, error) {
+	if r.apps == nil {
+		return app.GetUpdatedDependencies(installed, update)
+	}
+	diff, err := app.GetUpdatedDependenciesRecursive(r.apps, installed, update)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return diff.Updates(), nil
+}
+
+// intermediateStep describes the upgrade to an intermediate runtime
+type intermediateStep struct {
+	// runtime is the intermediate runtime application
+	runtime app.Application
+	// servers lists the configuration updates for cluster servers
+	servers []storage.UpdateServer
+}
+
+// intermediateManifest returns the manifest of the update application
+// as it would be with the specified intermediate runtime as its base:
+// system packages shared with the runtime are replaced with the versions
+// from the runtime
+func intermediateManifest(manifest schema.Manifest, runtime app.Application) schema.Manifest {
+	result := *manifest.DeepCopy()
+	result.SetBase(runtime.Package)
+	for i, dep := range result.Dependencies.Packages {
+		if locator, err := runtime.Manifest.Dependencies.ByName(dep.Locator.Name); err == nil {
+			result.Dependencies.Packages[i].Locator = *locator
+		}
+	}
+	if runtimePackage, err := runtime.Manifest.DefaultRuntimePackage(); err == nil {
+		if result.SystemOptions == nil {
+			result.SystemOptions = &schema.SystemOptions{}
+		}
+		result.SystemOptions.Dependencies.Runtime = &schema.Dependency{Locator: *runtimePackage}
+	}
+	for i := range result.NodeProfiles {
+		if result.NodeProfiles[i].SystemOptions != nil {
+			result.NodeProfiles[i].SystemOptions.Dependencies.Runtime = nil
+		}
+	}
+	return result
 }
 
 func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
@@ -316,38 +398,63 @@ func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
 		log.Debugf("No support for taints/tolerations for %v.", installedGravityPackage)
 	}
 
+	// Upgrade the cluster through each intermediate runtime in order
+	// before upgrading to the update runtime
+	var intermediatePhases []update.Phase
+	installedRuntime := p.installedRuntime
+	for _, step := range p.intermediateSteps {
+		intermediatePhase, err := builder.intermediate(step, installedRuntime, supportsTaints)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if len(intermediatePhases) == 0 {
+			intermediatePhase.Require(checksPhase, bootstrapPhase, preUpdatePhase)
+		} else {
+			intermediatePhase.Require(intermediatePhases[len(intermediatePhases)-1])
+		}
+		intermediatePhases = append(intermediatePhases, *intermediatePhase)
+		installedRuntime = step.runtime
+	}
+
 	mastersPhase := *builder.masters(leadMaster, masters[1:], supportsTaints).
 		Require(checksPhase, bootstrapPhase, preUpdatePhase)
-	nodesPhase := *builder.nodes(leadMaster, nodes, supportsTaints).
+	if len(intermediatePhases) != 0 {
+		mastersPhase = *mastersPhase.Require(intermediatePhases[len(intermediatePhases)-1])
+	}
+	// with the canary strategy, the canary nodes are updated and verified
+	// before the rest of the regular nodes
+	canaryNodes, otherNodes := builder.splitCanaryNodes(nodes)
+	var canaryPhase *update.Phase
+	nodesPhase := *builder.nodes(leadMaster, otherNodes, supportsTaints).
 		Require(mastersPhase)
-
-	allRuntimeUpdates, err := app.GetUpdatedDependencies(p.installedRuntime, p.updateRuntime)
-	if err != nil && !trace.IsNotFound(err) {
-		return nil, trace.Wrap(err)
+	if len(canaryNodes) != 0 {
+		canaryPhase = builder.canary(leadMaster, canaryNodes, supportsTaints).Require(mastersPhase)
+		nodesPhase = *nodesPhase.Require(*canaryPhase)
 	}
 
-	// some system apps may need to be skipped depending on the manifest settings
-	runtimeUpdates := allRuntimeUpdates[:0]
-	for _, locator := range allRuntimeUpdates {
-		if !schema.ShouldSkipApp(p.updateApp.Manifest, locator) {
-			runtimeUpdates = append(runtimeUpdates, locator)
-		}
+	// the remaining runtime updates are computed against the last
+	// intermediate runtime, if any
+	finalConfig := p
+	finalConfig.installedRuntime = installedRuntime
+	runtimeUpdates, err := phaseBuilder{planConfig: finalConfig}.runtimeUpdates()
+	if err != nil {
+		return nil, trace.Wrap(err)
 	}
 
-	appUpdates, err := app.GetUpdatedDependencies(p.installedApp, p.updateApp)
+	appUpdates, err := p.updatedDependencies(p.installedApp, p.updateApp)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
 	// check if etcd upgrade is required or not
-	updateEtcd, currentVersion, desiredVersion, err := p.shouldUpdateEtcd(p)
+	updateEtcd, currentVersion, desiredVersion, err := p.shouldUpdateEtcd(finalConfig)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
 	var root update.Phase
 	root.Add(initPhase, checksPhase, preUpdatePhase)
-	if len(runtimeUpdates) > 0 {
+	if len(runtimeUpdates) > 0 || len(intermediatePhases) > 0 {
 		if p.updateCoreDNS {
 			corednsPhase := *builder.corednsPhase(leadMaster.Server)
 			mastersPhase = *mastersPhase.Require(corednsPhase)
@@ -364,7 +471,12 @@ func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
 			}
 		}
 
-		root.Add(bootstrapPhase, mastersPhase)
+		root.Add(bootstrapPhase)
+		root.Add(intermediatePhases...)
+		root.Add(mastersPhase)
+		if canaryPhase != nil {
+			root.Add(*canaryPhase)
+		}
 		if len(nodesPhase.Phases) > 0 {
 			root.Add(nodesPhase)
 		}
@@ -392,7 +504,14 @@ func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
 		root.Add(configPhase, runtimePhase)
 	}
 
-	root.AddSequential(*builder.app(appUpdates), *builder.cleanup())
+	root.AddSequential(*builder.app(appUpdates))
+	if healthCheckPhase := builder.healthCheck(); healthCheckPhase != nil {
+		root.AddSequential(*healthCheckPhase)
+	}
+	if readinessPhase := builder.readiness(); readinessPhase != nil {
+		root.AddSequential(*readinessPhase)
+	}
+	root.AddSequential(*builder.cleanup())
 	plan := p.plan
 	plan.Phases = root.Phases
 	update.ResolvePlan(&plan)
@@ -432,7 +551,7 @@ func configUpdates(
 		updateServer := storage.UpdateServer{
 			Server: server,
 			Runtime: storage.RuntimePackage{
-				Installed:      *installedRuntime,
+				Installed:      schema.PackageForArch(*installedRuntime, server.Arch()),
 				SecretsPackage: &secretsUpdate.Locator,
 			},
 			Teleport: storage.TeleportPackage{
@@ -446,7 +565,7 @@ func configUpdates(
 			return nil, trace.Wrap(err)
 		}
 		if needsPlanetUpdate {
-			updateRuntime, err := update.RuntimePackageForProfile(server.Role)
+			updateRuntime, err := update.RuntimePackageForProfileArch(server.Role, server.Arch())
 			if err != nil {
 				return nil, trace.Wrap(err)
 			}
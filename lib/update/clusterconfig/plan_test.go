@@ -173,6 +173,15 @@ func (S) TestSingleNodePlan(c *C) {
 								},
 								Requires: []string{"/masters/node-1/endpoints"},
 							},
+							{
+								ID:          "/masters/node-1/health",
+								Executor:    libphase.Health,
+								Description: `Wait for node "node-1" to become healthy`,
+								Data: &storage.OperationPhaseData{
+									Server: &servers[0],
+								},
+								Requires: []string{"/masters/node-1/untaint"},
+							},
 						},
 					},
 				},
@@ -346,6 +355,15 @@ func (S) TestMultiNodePlan(c *C) {
 								},
 								Requires: []string{"/masters/node-1/endpoints"},
 							},
+							{
+								ID:          "/masters/node-1/health",
+								Executor:    libphase.Health,
+								Description: `Wait for node "node-1" to become healthy`,
+								Data: &storage.OperationPhaseData{
+									Server: &servers[0],
+								},
+								Requires: []string{"/masters/node-1/untaint"},
+							},
 							{
 								ID:          "/masters/node-1/elect",
 								Executor:    libphase.Elections,
@@ -357,7 +375,7 @@ func (S) TestMultiNodePlan(c *C) {
 										DisableServers: []storage.Server{servers[2]},
 									},
 								},
-								Requires: []string{"/masters/node-1/untaint"},
+								Requires: []string{"/masters/node-1/health"},
 							},
 						},
 					},
@@ -432,6 +450,15 @@ func (S) TestMultiNodePlan(c *C) {
 								},
 								Requires: []string{"/masters/node-3/endpoints"},
 							},
+							{
+								ID:          "/masters/node-3/health",
+								Executor:    libphase.Health,
+								Description: `Wait for node "node-3" to become healthy`,
+								Data: &storage.OperationPhaseData{
+									Server: &servers[2],
+								},
+								Requires: []string{"/masters/node-3/untaint"},
+							},
 							{
 								ID:          "/masters/node-3/enable-elections",
 								Executor:    libphase.Elections,
@@ -442,7 +469,7 @@ func (S) TestMultiNodePlan(c *C) {
 										EnableServers: []storage.Server{servers[2]},
 									},
 								},
-								Requires: []string{"/masters/node-3/untaint"},
+								Requires: []string{"/masters/node-3/health"},
 							},
 						},
 						Requires: []string{"/masters/node-1"},
@@ -610,6 +637,15 @@ address: "0.0.0.0"`),
 								},
 								Requires: []string{"/masters/node-1/endpoints"},
 							},
+							{
+								ID:          "/masters/node-1/health",
+								Executor:    libphase.Health,
+								Description: `Wait for node "node-1" to become healthy`,
+								Data: &storage.OperationPhaseData{
+									Server: &servers[0],
+								},
+								Requires: []string{"/masters/node-1/untaint"},
+							},
 						},
 					},
 				},
@@ -695,6 +731,16 @@ address: "0.0.0.0"`),
 								},
 								Requires: []string{"/nodes/node-2/endpoints"},
 							},
+							{
+								ID:          "/nodes/node-2/health",
+								Executor:    libphase.Health,
+								Description: `Wait for node "node-2" to become healthy`,
+								Data: &storage.OperationPhaseData{
+									Server:     &servers[1],
+									ExecServer: &servers[0],
+								},
+								Requires: []string{"/nodes/node-2/untaint"},
+							},
 						},
 					},
 				},
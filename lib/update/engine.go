@@ -39,13 +39,15 @@ func NewMachine(ctx context.Context, config Config, engine *Engine) (*fsm.FSM, e
 		return nil, trace.Wrap(err)
 	}
 	machine, err := fsm.New(fsm.Config{
-		Engine: engine,
-		Runner: config.Runner,
+		Engine:      engine,
+		Runner:      config.Runner,
+		Parallelism: config.Parallelism,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 	machine.SetPreExec(engine.UpdateProgress)
+	machine.SetInterruptCheck(engine.CheckInterrupt)
 	return machine, nil
 }
 
@@ -107,6 +109,15 @@ func (r *Engine) UpdateProgress(ctx context.Context, params fsm.Params) error {
 	return nil
 }
 
+// CheckInterrupt returns the pending pause or cancellation request of the operation
+func (r *Engine) CheckInterrupt(ctx context.Context) (string, error) {
+	operation, err := r.operator.GetSiteOperation(r.Operation.Key())
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return operation.Interrupt, nil
+}
+
 // Complete marks the operation as either completed or failed based
 // on the state of the operation plan
 func (r *Engine) Complete(fsmErr error) error {
@@ -148,6 +159,7 @@ func (r *Engine) ChangePhaseState(ctx context.Context, change fsm.StateChange) e
 		NewState:    change.State,
 		Error:       utils.ToRawTrace(change.Error),
 		Created:     time.Now().UTC(),
+		Attempt:     change.Attempt,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -206,4 +218,5 @@ type operator interface {
 	CreateProgressEntry(ops.SiteOperationKey, ops.ProgressEntry) error
 	SetOperationState(ops.SiteOperationKey, ops.SetOperationStateRequest) error
 	ActivateSite(ops.ActivateSiteRequest) error
+	GetSiteOperation(ops.SiteOperationKey) (*ops.SiteOperation, error)
 }
@@ -172,6 +172,15 @@ func (S) TestSingleNodePlan(c *C) {
 								},
 								Requires: []string{"/masters/node-1/endpoints"},
 							},
+							{
+								ID:          "/masters/node-1/health",
+								Executor:    libphase.Health,
+								Description: `Wait for node "node-1" to become healthy`,
+								Data: &storage.OperationPhaseData{
+									Server: &servers[0],
+								},
+								Requires: []string{"/masters/node-1/untaint"},
+							},
 						},
 					},
 				},
@@ -366,6 +375,15 @@ func (S) TestMultiNodePlan(c *C) {
 								},
 								Requires: []string{"/masters/node-1/endpoints"},
 							},
+							{
+								ID:          "/masters/node-1/health",
+								Executor:    libphase.Health,
+								Description: `Wait for node "node-1" to become healthy`,
+								Data: &storage.OperationPhaseData{
+									Server: &servers[0],
+								},
+								Requires: []string{"/masters/node-1/untaint"},
+							},
 							{
 								ID:          "/masters/node-1/elect",
 								Executor:    libphase.Elections,
@@ -377,7 +395,7 @@ func (S) TestMultiNodePlan(c *C) {
 										DisableServers: []storage.Server{servers[2]},
 									},
 								},
-								Requires: []string{"/masters/node-1/untaint"},
+								Requires: []string{"/masters/node-1/health"},
 							},
 						},
 					},
@@ -452,6 +470,15 @@ func (S) TestMultiNodePlan(c *C) {
 								},
 								Requires: []string{"/masters/node-3/endpoints"},
 							},
+							{
+								ID:          "/masters/node-3/health",
+								Executor:    libphase.Health,
+								Description: `Wait for node "node-3" to become healthy`,
+								Data: &storage.OperationPhaseData{
+									Server: &servers[2],
+								},
+								Requires: []string{"/masters/node-3/untaint"},
+							},
 							{
 								ID:          "/masters/node-3/enable-elections",
 								Executor:    libphase.Elections,
@@ -462,7 +489,7 @@ func (S) TestMultiNodePlan(c *C) {
 										EnableServers: []storage.Server{servers[2]},
 									},
 								},
-								Requires: []string{"/masters/node-3/untaint"},
+								Requires: []string{"/masters/node-3/health"},
 							},
 						},
 						Requires: []string{"/masters/node-1"},
@@ -550,6 +577,16 @@ func (S) TestMultiNodePlan(c *C) {
 								},
 								Requires: []string{"/nodes/node-2/endpoints"},
 							},
+							{
+								ID:          "/nodes/node-2/health",
+								Executor:    libphase.Health,
+								Description: `Wait for node "node-2" to become healthy`,
+								Data: &storage.OperationPhaseData{
+									Server:     &servers[1],
+									ExecServer: &servers[0],
+								},
+								Requires: []string{"/nodes/node-2/untaint"},
+							},
 						},
 					},
 					{
@@ -628,6 +665,16 @@ func (S) TestMultiNodePlan(c *C) {
 								},
 								Requires: []string{"/nodes/node-4/endpoints"},
 							},
+							{
+								ID:          "/nodes/node-4/health",
+								Executor:    libphase.Health,
+								Description: `Wait for node "node-4" to become healthy`,
+								Data: &storage.OperationPhaseData{
+									Server:     &servers[3],
+									ExecServer: &servers[0],
+								},
+								Requires: []string{"/nodes/node-4/untaint"},
+							},
 						},
 						Requires: []string{"/nodes/node-2"},
 					},
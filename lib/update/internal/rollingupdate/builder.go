@@ -22,7 +22,7 @@ func RuntimeConfigUpdates(
 ) (updates []storage.UpdateServer, err error) {
 	updates = make([]storage.UpdateServer, 0, len(servers))
 	for _, server := range servers {
-		runtimePackage, err := manifest.RuntimePackageForProfile(server.Role)
+		runtimePackage, err := manifest.RuntimePackageForProfileArch(server.Role, server.Arch())
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
@@ -116,6 +116,27 @@ func (r Builder) Nodes(servers []storage.UpdateServer, master storage.Server, ro
 	return &root
 }
 
+// Restart returns a new phase to restart the runtime container on the specified
+// list of servers one at a time.
+// Unlike Masters and Nodes, the servers are not drained before the restart
+// which makes it suitable for configuration-only changes.
+// master specifies the server to run the health checks from
+func (r Builder) Restart(servers []storage.UpdateServer, master storage.Server, rootText, nodeTextFormat string) *update.Phase {
+	root := update.RootPhase(update.Phase{
+		ID:          "restart",
+		Description: rootText,
+	})
+	for i, server := range servers {
+		node := r.node(server.Hostname, nodeTextFormat, server.Hostname)
+		node.AddSequential(
+			r.restart(servers[i]),
+			r.health(&servers[i].Server, &master),
+		)
+		root.AddSequential(node)
+	}
+	return &root
+}
+
 func (r Builder) common(server storage.UpdateServer, master *storage.Server) (phases []update.Phase) {
 	phases = append(phases,
 		r.drain(&server.Server, master),
@@ -124,6 +145,7 @@ func (r Builder) common(server storage.UpdateServer, master *storage.Server) (ph
 		r.uncordon(&server.Server, master),
 		r.endpoints(&server.Server, master),
 		r.untaint(&server.Server, master),
+		r.health(&server.Server, master),
 	)
 	return phases
 }
@@ -134,7 +156,8 @@ func (r Builder) restart(server storage.UpdateServer) update.Phase {
 	node.Data = &storage.OperationPhaseData{
 		Package: &r.App,
 		Update: &storage.UpdateOperationData{
-			Servers: []storage.UpdateServer{server},
+			Servers:     []storage.UpdateServer{server},
+			ChangesetID: r.ChangesetID,
 		},
 	}
 	return node
@@ -188,6 +211,18 @@ func (r Builder) endpoints(server, execer *storage.Server) update.Phase {
 	return node
 }
 
+func (r Builder) health(server, execer *storage.Server) update.Phase {
+	node := r.node("health", "Wait for node %q to become healthy", server.Hostname)
+	node.Executor = libphase.Health
+	node.Data = &storage.OperationPhaseData{
+		Server: server,
+	}
+	if execer != nil {
+		node.Data.ExecServer = execer
+	}
+	return node
+}
+
 func (r Builder) drain(server, execer *storage.Server) update.Phase {
 	node := r.node("drain", "Drain node %q", server.Hostname)
 	node.Executor = libphase.Drain
@@ -211,6 +246,9 @@ func (r Builder) node(id, format string, args ...interface{}) update.Phase {
 type Builder struct {
 	// App specifies the cluster application
 	App loc.Locator
+	// ChangesetID optionally specifies the ID of the package changeset
+	// for the container restart steps. Defaults to the operation ID
+	ChangesetID string
 }
 
 // setLeaderElection creates a phase that will change the leader election state in the cluster
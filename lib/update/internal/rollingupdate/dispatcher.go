@@ -51,6 +51,8 @@ func (r *dispatcher) Dispatch(config Config, params fsm.ExecutorParams, remote f
 		return libphase.NewUncordon(params, config.Client, logger)
 	case libphase.Endpoints:
 		return libphase.NewEndpoints(params, config.Client, logger)
+	case libphase.Health:
+		return libphase.NewHealth(params, config.Client, logger)
 	default:
 		return nil, trace.BadParameter("unknown executor %v for phase %q",
 			params.Phase.Executor, params.Phase.ID)
@@ -29,9 +29,11 @@ import (
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/cenkalti/backoff"
+	"github.com/gravitational/rigging"
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubeapi "k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
@@ -99,6 +101,7 @@ func NewDrain(params libfsm.ExecutorParams, client *kubeapi.Clientset, logger lo
 	}
 	return &drainer{
 		kubernetesOperation: *op,
+		options:             drainOptions(params.Phase.Data),
 	}, nil
 }
 
@@ -108,7 +111,7 @@ func (p *drainer) Execute(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, defaults.DrainTimeout)
 	defer cancel()
 	err := retry(ctx, func() error {
-		return trace.Wrap(drain(ctx, p.Client, p.Server.KubeNodeID()))
+		return trace.Wrap(drain(ctx, p.Client, p.Server.KubeNodeID(), p.options))
 	}, defaults.DrainErrorTimeout)
 	return trace.Wrap(err)
 }
@@ -168,6 +171,33 @@ func (*endpoints) Rollback(context.Context) error {
 	return nil
 }
 
+// NewHealth returns a new executor for waiting for a node
+// to become healthy after the update
+func NewHealth(params libfsm.ExecutorParams, client *kubeapi.Clientset, logger log.FieldLogger) (*health, error) {
+	op, err := newKubernetesOperation(params, client, logger)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &health{
+		kubernetesOperation: *op,
+	}, nil
+}
+
+// Execute waits for the node to report ready status so the update
+// does not proceed to the next node while this one is unhealthy
+func (p *health) Execute(ctx context.Context) error {
+	p.Infof("Wait for %v to become healthy.", p.Server)
+	err := update.Retry(ctx, func() error {
+		return trace.Wrap(checkNodeReady(p.Client.CoreV1().Nodes(), p.Server.KubeNodeID()))
+	}, defaults.NodeStatusTimeout)
+	return trace.Wrap(err)
+}
+
+// Rollback is a no-op for this phase
+func (*health) Rollback(context.Context) error {
+	return nil
+}
+
 func newKubernetesOperation(params libfsm.ExecutorParams, client *kubeapi.Clientset, logger log.FieldLogger) (*kubernetesOperation, error) {
 	if params.Phase.Data == nil || params.Phase.Data.Server == nil {
 		return nil, trace.NotFound("no server specified for phase %q", params.Phase.ID)
@@ -234,11 +264,36 @@ func taint(ctx context.Context, client corev1.NodeInterface, node string, add ad
 	return nil
 }
 
-func drain(ctx context.Context, client *kubeapi.Clientset, node string) error {
-	err := kubernetes.Drain(ctx, client, node)
+func drain(ctx context.Context, client *kubeapi.Clientset, node string, options storage.DrainOptions) error {
+	err := kubernetes.Drain(ctx, client, node, options)
 	return trace.Wrap(err)
 }
 
+// drainOptions returns the drain parameters from the specified phase data
+func drainOptions(data *storage.OperationPhaseData) storage.DrainOptions {
+	if data == nil || data.Drain == nil {
+		return storage.DrainOptions{}
+	}
+	return *data.Drain
+}
+
+func checkNodeReady(client corev1.NodeInterface, name string) error {
+	node, err := client.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return trace.Wrap(rigging.ConvertError(err))
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != v1.NodeReady {
+			continue
+		}
+		if condition.Status != v1.ConditionTrue {
+			return trace.BadParameter("node %q is not ready: %v", name, condition.Message)
+		}
+		return nil
+	}
+	return trace.NotFound("node %q has not reported ready status", name)
+}
+
 func uncordon(ctx context.Context, client corev1.NodeInterface, node string) error {
 	err := kubernetes.SetUnschedulable(ctx, client, node, false)
 	return trace.Wrap(err)
@@ -263,6 +318,8 @@ type untainter struct {
 // drainer defines the operation of draining a node
 type drainer struct {
 	kubernetesOperation
+	// options specifies the drain parameters
+	options storage.DrainOptions
 }
 
 // uncordoner defines the operation of uncordoning a node
@@ -276,4 +333,9 @@ type endpoints struct {
 	kubernetesOperation
 }
 
+// health defines the operation of waiting for a node to become healthy
+type health struct {
+	kubernetesOperation
+}
+
 type addTaint bool
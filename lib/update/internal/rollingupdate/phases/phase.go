@@ -40,6 +40,8 @@ const (
 	Uncordon = "uncordon"
 	// Endpoints defines the phase to wait for endpoints on a node to be become active
 	Endpoints = "endpoints"
+	// Health defines the phase to wait for a node to become healthy
+	Health = "health"
 )
 
 type appGetter interface {
@@ -32,7 +32,7 @@ import (
 )
 
 // NewRestart returns a new executor to restart the runtime container to apply
-// the environment variables update
+// the environment variables update and, optionally, install new secrets
 func NewRestart(
 	params libfsm.ExecutorParams,
 	operator localClusterGetter,
@@ -55,9 +55,13 @@ func NewRestart(
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	changesetID := operationID
+	if params.Phase.Data.Update.ChangesetID != "" {
+		changesetID = params.Phase.Data.Update.ChangesetID
+	}
 	return &restart{
 		FieldLogger:   logger,
-		operationID:   operationID,
+		changesetID:   changesetID,
 		backend:       backend,
 		packages:      packages,
 		localPackages: localPackages,
@@ -72,19 +76,25 @@ func (r *restart) Execute(ctx context.Context) error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	updater, err := system.New(system.Config{
-		ChangesetID: r.operationID,
-		Backend:     r.backend,
-		Packages:    r.localPackages,
-		PackageUpdates: system.PackageUpdates{
-			Runtime: storage.PackageUpdate{
-				From: r.update.Runtime.Installed,
-				To:   r.update.Runtime.Update.Package,
-				ConfigPackage: &storage.PackageUpdate{
-					To: r.update.Runtime.Update.ConfigPackage,
-				},
+	updates := system.PackageUpdates{
+		Runtime: storage.PackageUpdate{
+			From: r.update.Runtime.Installed,
+			To:   r.update.Runtime.Update.Package,
+			ConfigPackage: &storage.PackageUpdate{
+				To: r.update.Runtime.Update.ConfigPackage,
 			},
 		},
+	}
+	if r.update.Runtime.SecretsPackage != nil {
+		updates.RuntimeSecrets = &storage.PackageUpdate{
+			To: *r.update.Runtime.SecretsPackage,
+		}
+	}
+	updater, err := system.New(system.Config{
+		ChangesetID:    r.changesetID,
+		Backend:        r.backend,
+		Packages:       r.localPackages,
+		PackageUpdates: updates,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -97,7 +107,7 @@ func (r *restart) Execute(ctx context.Context) error {
 // configuration package
 func (r *restart) Rollback(ctx context.Context) error {
 	updater, err := system.New(system.Config{
-		ChangesetID: r.operationID,
+		ChangesetID: r.changesetID,
 		Backend:     r.backend,
 		Packages:    r.localPackages,
 	})
@@ -120,6 +130,9 @@ func (*restart) PostCheck(context.Context) error {
 
 func (r *restart) pullUpdates() error {
 	updates := []loc.Locator{r.update.Runtime.Update.Package, r.update.Runtime.Update.ConfigPackage}
+	if r.update.Runtime.SecretsPackage != nil {
+		updates = append(updates, *r.update.Runtime.SecretsPackage)
+	}
 	for _, update := range updates {
 		r.Infof("Pulling package update: %v.", update)
 		_, err := libapp.PullPackage(libapp.PackagePullRequest{
@@ -142,5 +155,5 @@ type restart struct {
 	localPackages update.LocalPackageService
 	update        storage.UpdateServer
 	serviceUser   storage.OSUser
-	operationID   string
+	changesetID   string
 }
@@ -0,0 +1,137 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/coredns"
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	libkubernetes "github.com/gravitational/gravity/lib/kubernetes"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NewDNS returns a new executor to update the upstream nameservers of the cluster DNS
+func NewDNS(
+	params libfsm.ExecutorParams,
+	client kubernetes.Interface,
+	operator localClusterGetter,
+	operation ops.SiteOperation,
+	logger log.FieldLogger,
+) (*updateDNS, error) {
+	if operation.Patch == nil || len(operation.Patch.DNSUpstreams) == 0 {
+		return nil, trace.BadParameter("no DNS upstreams specified for phase %q",
+			params.Phase.ID)
+	}
+	if client == nil {
+		return nil, trace.BadParameter("phase %q must be executed on a master node",
+			params.Phase.ID)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &updateDNS{
+		FieldLogger:  logger,
+		client:       client,
+		upstreams:    operation.Patch.DNSUpstreams,
+		prevCorefile: operation.Patch.PrevCorefile,
+		overrides:    cluster.DNSOverrides,
+	}, nil
+}
+
+// Execute replaces the cluster DNS configuration with the one that uses
+// the new upstream nameservers and restarts the cluster DNS pods
+func (r *updateDNS) Execute(ctx context.Context) error {
+	conf, err := coredns.GenerateCorefile(coredns.Config{
+		UpstreamNameservers: r.upstreams,
+		Hosts:               r.overrides.Hosts,
+		Zones:               r.overrides.Zones,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	r.Infof("Update cluster DNS upstream nameservers to %v.", r.upstreams)
+	return trace.Wrap(r.updateCorefile(ctx, conf))
+}
+
+// Rollback restores the previous cluster DNS configuration
+func (r *updateDNS) Rollback(ctx context.Context) error {
+	if r.prevCorefile == "" {
+		return nil
+	}
+	r.Info("Restore previous cluster DNS configuration.")
+	return trace.Wrap(r.updateCorefile(ctx, r.prevCorefile))
+}
+
+// PreCheck is a no-op
+func (r *updateDNS) PreCheck(context.Context) error {
+	return nil
+}
+
+// PostCheck is a no-op
+func (r *updateDNS) PostCheck(context.Context) error {
+	return nil
+}
+
+// updateCorefile replaces the cluster DNS configuration and restarts
+// the cluster DNS pods to make them reload it
+func (r *updateDNS) updateCorefile(ctx context.Context, conf string) error {
+	configMaps := r.client.CoreV1().ConfigMaps(constants.KubeSystemNamespace)
+	err := libkubernetes.Retry(ctx, func() error {
+		configMap, err := configMaps.Get(constants.CoreDNSConfigMap, metav1.GetOptions{})
+		if err != nil {
+			return trace.Wrap(rigging.ConvertError(err))
+		}
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		configMap.Data[constants.CoreDNSConfigKey] = conf
+		_, err = configMaps.Update(configMap)
+		return trace.Wrap(err)
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = r.client.CoreV1().Pods(constants.KubeSystemNamespace).DeleteCollection(
+		&metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: dnsPodSelector})
+	return trace.Wrap(rigging.ConvertError(err))
+}
+
+type updateDNS struct {
+	// FieldLogger specifies the logger for the phase
+	log.FieldLogger
+	client       kubernetes.Interface
+	upstreams    []string
+	prevCorefile string
+	overrides    storage.DNSOverrides
+}
+
+type localClusterGetter interface {
+	GetLocalSite() (*ops.Site, error)
+}
+
+// dnsPodSelector selects the cluster DNS pods
+const dnsPodSelector = "k8s-app in (kube-dns, kube-dns-worker)"
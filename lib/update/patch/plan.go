@@ -0,0 +1,130 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package patch
+
+import (
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/update"
+	"github.com/gravitational/gravity/lib/update/internal/rollingupdate"
+	"github.com/gravitational/gravity/lib/update/patch/phases"
+
+	"github.com/gravitational/trace"
+)
+
+// NewOperationPlan creates a new operation plan for the specified operation
+func NewOperationPlan(
+	operator ops.Operator,
+	apps app.Applications,
+	operation ops.SiteOperation,
+	servers []storage.Server,
+) (plan *storage.OperationPlan, err error) {
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	app, err := apps.GetApp(cluster.App.Package)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to query installed application")
+	}
+	plan, err = newOperationPlan(*app, cluster.DNSConfig, operator, operation, servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = operator.CreateOperationPlan(operation.Key(), *plan)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotImplemented(
+				"cluster operator does not implement the API required to patch cluster configuration. " +
+					"Please make sure you're running the command on a compatible cluster.")
+		}
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
+
+// newOperationPlan returns a new plan for the specified operation
+// and the given set of servers.
+// The plan only includes the phases for the changes requested by the operation
+func newOperationPlan(
+	app app.Application,
+	dnsConfig storage.DNSConfig,
+	operator rollingupdate.ConfigPackageRotator,
+	operation ops.SiteOperation,
+	servers []storage.Server,
+) (*storage.OperationPlan, error) {
+	master := firstMaster(servers)
+	if master == nil {
+		return nil, trace.NotFound("no master servers found in cluster state")
+	}
+	var phases update.Phases
+	if operation.Patch != nil && len(operation.Patch.DNSUpstreams) != 0 {
+		phases = append(phases, dnsPhase(*master))
+	}
+	if operation.UpdateEnviron != nil {
+		builder := rollingupdate.Builder{App: app.Package}
+		configUpdates, err := rollingupdate.RuntimeConfigUpdates(app.Manifest, operator, operation.Key(), servers)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		masters, nodes := update.SplitServers(configUpdates)
+		config := *builder.Config("Update runtime configuration", configUpdates)
+		restart := *builder.Restart(
+			append(masters, nodes...), *master,
+			"Restart runtime containers",
+			"Restart runtime container on node %q",
+		).Require(config)
+		phases = append(phases, config, restart)
+	}
+	if len(phases) == 0 {
+		return nil, trace.BadParameter("operation %v has no configuration changes", operation.ID)
+	}
+
+	plan := &storage.OperationPlan{
+		OperationID:   operation.ID,
+		OperationType: operation.Type,
+		AccountID:     operation.AccountID,
+		ClusterName:   operation.SiteDomain,
+		Phases:        phases.AsPhases(),
+		Servers:       servers,
+		DNSConfig:     dnsConfig,
+	}
+	update.ResolvePlan(plan)
+
+	return plan, nil
+}
+
+func dnsPhase(master storage.Server) update.Phase {
+	return update.RootPhase(update.Phase{
+		ID:          "dns",
+		Executor:    phases.DNS,
+		Description: "Update cluster DNS upstream nameservers",
+		Data: &storage.OperationPhaseData{
+			Server: &master,
+		},
+	})
+}
+
+func firstMaster(servers []storage.Server) *storage.Server {
+	for i := range servers {
+		if servers[i].IsMaster() {
+			return &servers[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package patch
+
+import (
+	"testing"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	libphase "github.com/gravitational/gravity/lib/update/internal/rollingupdate/phases"
+	"github.com/gravitational/gravity/lib/update/patch/phases"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestPatch(t *testing.T) { TestingT(t) }
+
+type S struct{}
+
+var _ = Suite(&S{})
+
+func (S) TestUpdatesDNSOnly(c *C) {
+	operation := newOperation()
+	operation.Patch.DNSUpstreams = []string{"8.8.8.8"}
+
+	plan, err := newOperationPlan(testApp, storage.DefaultDNSConfig, testOperator, operation, testServers)
+	c.Assert(err, IsNil)
+	c.Assert(phaseIDs(plan.Phases), compare.DeepEquals, []string{"/dns"})
+	c.Assert(plan.Phases[0].Executor, Equals, phases.DNS)
+	c.Assert(plan.Phases[0].Data.Server, compare.DeepEquals, &testServers[0])
+}
+
+func (S) TestRestartsContainersWithoutDrain(c *C) {
+	operation := newOperation()
+	operation.UpdateEnviron = &storage.UpdateEnvarsOperationState{
+		Env: map[string]string{"HTTP_PROXY": "proxy.example.com:3128"},
+	}
+
+	plan, err := newOperationPlan(testApp, storage.DefaultDNSConfig, testOperator, operation, testServers)
+	c.Assert(err, IsNil)
+	c.Assert(phaseIDs(plan.Phases), compare.DeepEquals, []string{
+		"/update-config",
+		"/restart",
+		"/restart/node-1",
+		"/restart/node-1/restart",
+		"/restart/node-1/health",
+		"/restart/node-2",
+		"/restart/node-2/restart",
+		"/restart/node-2/health",
+	})
+	c.Assert(plan.Phases[0].Executor, Equals, libphase.UpdateConfig)
+	c.Assert(plan.Phases[1].Requires, compare.DeepEquals, []string{"/update-config"})
+	restart := plan.Phases[1]
+	c.Assert(restart.Phases[1].Requires, compare.DeepEquals, []string{"/restart/node-1"})
+	node := restart.Phases[1]
+	c.Assert(node.Phases[0].Executor, Equals, libphase.RestartContainer)
+	c.Assert(node.Phases[0].Data.Update.Servers[0].Server, compare.DeepEquals, testServers[1])
+	c.Assert(node.Phases[1].Executor, Equals, libphase.Health)
+	c.Assert(node.Phases[1].Requires, compare.DeepEquals, []string{"/restart/node-2/restart"})
+	c.Assert(node.Phases[1].Data, compare.DeepEquals, &storage.OperationPhaseData{
+		Server:     &testServers[1],
+		ExecServer: &testServers[0],
+	})
+}
+
+func (S) TestUpdatesDNSBeforeRuntime(c *C) {
+	operation := newOperation()
+	operation.Patch.DNSUpstreams = []string{"8.8.8.8"}
+	operation.UpdateEnviron = &storage.UpdateEnvarsOperationState{
+		Env: map[string]string{"HTTP_PROXY": "proxy.example.com:3128"},
+	}
+
+	plan, err := newOperationPlan(testApp, storage.DefaultDNSConfig, testOperator, operation, testServers)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Phases, HasLen, 3)
+	c.Assert(plan.Phases[0].ID, Equals, "/dns")
+	c.Assert(plan.Phases[1].ID, Equals, "/update-config")
+	c.Assert(plan.Phases[2].ID, Equals, "/restart")
+}
+
+func (S) TestRequiresChanges(c *C) {
+	_, err := newOperationPlan(testApp, storage.DefaultDNSConfig, testOperator, newOperation(), testServers)
+	c.Assert(err, NotNil)
+}
+
+func newOperation() ops.SiteOperation {
+	return ops.SiteOperation{
+		ID:         "1",
+		AccountID:  "0",
+		Type:       ops.OperationPatch,
+		SiteDomain: "cluster",
+		Patch:      &storage.PatchOperationState{},
+	}
+}
+
+func phaseIDs(phases []storage.OperationPhase) (ids []string) {
+	for _, phase := range phases {
+		ids = append(ids, phase.ID)
+		ids = append(ids, phaseIDs(phase.Phases)...)
+	}
+	return ids
+}
+
+func (r testRotator) RotatePlanetConfig(ops.RotatePlanetConfigRequest) (*ops.RotatePackageResponse, error) {
+	return &ops.RotatePackageResponse{Locator: r.runtimeConfigPackage}, nil
+}
+
+var testOperator = testRotator{
+	runtimeConfigPackage: loc.MustParseLocator("gravitational.io/planet-config:0.0.1"),
+}
+
+type testRotator struct {
+	runtimeConfigPackage loc.Locator
+}
+
+var testServers = []storage.Server{
+	{Hostname: "node-1", Role: "node", ClusterRole: string(schema.ServiceRoleMaster)},
+	{Hostname: "node-2", Role: "knode", ClusterRole: string(schema.ServiceRoleNode)},
+}
+
+var runtimeLoc = loc.MustParseLocator("gravitational.io/planet:0.0.1")
+
+var testApp = app.Application{
+	Package: loc.MustParseLocator("gravitational.io/app:0.0.1"),
+	Manifest: schema.Manifest{
+		NodeProfiles: schema.NodeProfiles{
+			{
+				Name:        "node",
+				ServiceRole: "master",
+			},
+			{
+				Name:        "knode",
+				ServiceRole: "node",
+			},
+		},
+		SystemOptions: &schema.SystemOptions{
+			Dependencies: schema.SystemDependencies{
+				Runtime: &schema.Dependency{Locator: runtimeLoc},
+			},
+		},
+	},
+}
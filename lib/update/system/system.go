@@ -202,13 +202,15 @@ func (r *PackageUpdates) checkAndSetDefaults() error {
 }
 
 func (r *PackageUpdates) updates() (result []storage.PackageUpdate) {
+	// Secrets are installed before the runtime package so that
+	// the restarted runtime container picks up the new certificates
+	if r.RuntimeSecrets != nil {
+		result = append(result, *r.RuntimeSecrets)
+	}
 	result = append(result, r.Runtime)
 	if r.Gravity != nil {
 		result = append(result, *r.Gravity)
 	}
-	if r.RuntimeSecrets != nil {
-		result = append(result, *r.RuntimeSecrets)
-	}
 	if r.Teleport != nil {
 		result = append(result, *r.Teleport)
 	}
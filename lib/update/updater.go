@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/localenv"
@@ -145,6 +146,11 @@ func (r *Updater) executePlan(ctx context.Context, force bool) error {
 	defer progress.Stop()
 
 	planErr := r.machine.ExecutePlan(ctx, progress, force)
+	if fsm.IsPaused(planErr) {
+		// Leave the operation active so it can be resumed
+		r.Info(planErr.Error())
+		return trace.Wrap(planErr)
+	}
 	if planErr != nil {
 		r.Warnf("Failed to execute plan: %v.", trace.DebugReport(planErr))
 	}
@@ -202,6 +208,9 @@ func (r *Config) CheckAndSetDefaults() error {
 			"operation":     r.Operation,
 		})
 	}
+	if r.Parallelism == 0 {
+		r.Parallelism = defaults.UpdateParallelism
+	}
 	return nil
 }
 
@@ -221,6 +230,9 @@ type Config struct {
 	log.FieldLogger
 	// Silent controls whether the process outputs messages to stdout
 	localenv.Silent
+	// Parallelism limits the number of phases executed concurrently
+	// as part of a parallel phase, e.g. the number of nodes updated at once
+	Parallelism int
 }
 
 // Updater manages the operation specified with machine
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package users
+
+import (
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+)
+
+// IsSystemAccount returns true if the specified account ID refers
+// to the system account
+func IsSystemAccount(accountID string) bool {
+	return accountID == defaults.SystemAccountID
+}
+
+// IsSystemAccountUser returns true if the user belongs to the system account.
+// Users of the system account are not restricted to a single account
+func IsSystemAccountUser(user storage.User) bool {
+	return IsSystemAccount(user.GetAccountID())
+}
+
+// CheckAccountAccess returns an error if the user cannot access the resources
+// of the specified account
+func CheckAccountAccess(user storage.User, accountID string) error {
+	if err := checkUserAccount(user); err != nil {
+		return trace.Wrap(err)
+	}
+	if IsSystemAccountUser(user) || user.GetAccountID() == accountID {
+		return nil
+	}
+	return trace.AccessDenied("user %v does not belong to account %v",
+		user.GetName(), accountID)
+}
+
+// CheckRepositoryAccess returns an error if the user cannot perform the action
+// specified with verb on the package repository because of its account:
+//
+//  * users of the system account are not restricted
+//  * other users have read-only access to the system repository and
+//    no access to the repositories of other accounts
+//
+// Access to other repositories is left to the user's roles
+func CheckRepositoryAccess(accounts Accounts, user storage.User, repository, verb string) error {
+	if err := checkUserAccount(user); err != nil {
+		return trace.Wrap(err)
+	}
+	if IsSystemAccountUser(user) {
+		return nil
+	}
+	if repository == defaults.SystemAccountOrg {
+		if verb == teleservices.VerbRead || verb == teleservices.VerbList {
+			return nil
+		}
+		return trace.AccessDenied("user %v has read-only access to repository %v",
+			user.GetName(), repository)
+	}
+	owner, err := repositoryAccount(accounts, repository)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if owner != nil && owner.ID != user.GetAccountID() {
+		return trace.AccessDenied("user %v does not have access to repository %v",
+			user.GetName(), repository)
+	}
+	return nil
+}
+
+// checkUserAccount returns an error if the user does not belong to any account.
+// Such users are denied access instead of being treated as users
+// of the system account
+func checkUserAccount(user storage.User) error {
+	if user.GetAccountID() == "" {
+		return trace.AccessDenied("user %v does not belong to any account", user.GetName())
+	}
+	return nil
+}
+
+// repositoryAccount returns the account that owns the specified package
+// repository or nil, if the repository is not owned by any account
+func repositoryAccount(accounts Accounts, repository string) (*Account, error) {
+	all, err := accounts.GetAccounts()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for i, account := range all {
+		if account.Org == repository {
+			return &all[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -759,6 +759,50 @@ func (i *IdentityACL) UpsertSAMLConnector(connector teleservices.SAMLConnector)
 	return i.identity.UpsertSAMLConnector(connector)
 }
 
+// UpsertRoleMapping creates or updates a mapping of OIDC claims to roles
+func (i *IdentityACL) UpsertRoleMapping(mapping storage.RoleMapping) error {
+	if err := i.roleMappingAction(teleservices.VerbCreate, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return i.identity.UpsertRoleMapping(mapping)
+}
+
+// GetRoleMapping returns a role mapping by name
+func (i *IdentityACL) GetRoleMapping(name string) (storage.RoleMapping, error) {
+	if err := i.roleMappingAction(teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return i.identity.GetRoleMapping(name)
+}
+
+// GetRoleMappings returns all role mappings
+func (i *IdentityACL) GetRoleMappings() ([]storage.RoleMapping, error) {
+	if err := i.roleMappingAction(teleservices.VerbList, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return i.identity.GetRoleMappings()
+}
+
+// DeleteRoleMapping deletes a role mapping by name
+func (i *IdentityACL) DeleteRoleMapping(name string) error {
+	if err := i.roleMappingAction(teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return i.identity.DeleteRoleMapping(name)
+}
+
+// roleMappingAction checks access to role mappings. Role mappings grant
+// roles to users so they require the same permissions as roles
+func (i *IdentityACL) roleMappingAction(verbs ...string) error {
+	for _, verb := range verbs {
+		err := i.checker.CheckAccessToRule(i.context(), teledefaults.Namespace, teleservices.KindRole, verb, false)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
 // DeleteOIDCConnector deletes OIDC Connector
 func (i *IdentityACL) DeleteOIDCConnector(connectorID string) error {
 	if err := i.authConnectorAction(teleservices.KindOIDCConnector, teleservices.VerbDelete); err != nil {
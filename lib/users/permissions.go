@@ -0,0 +1,114 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package users
+
+import (
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+)
+
+// NewPermissionRole returns a role that grants the specified
+// permissions to the user
+func NewPermissionRole(user string, permissions storage.ResourcePermissions) (teleservices.Role, error) {
+	return NewSystemRole(fmt.Sprintf("%v-%v", constants.RolePermissions, user), teleservices.RoleSpecV3{
+		Allow: teleservices.RoleConditions{
+			Namespaces: []string{defaults.Namespace},
+			Logins:     noLogins(),
+			Rules:      permissions.Rules(),
+		},
+	})
+}
+
+// NewPermissionChecker returns an access checker that further restricts
+// access granted by the provided checker to the specified permissions.
+// It is used for API keys restricted to specific permissions
+func NewPermissionChecker(checker teleservices.AccessChecker, permissions storage.ResourcePermissions) teleservices.AccessChecker {
+	return &permissionChecker{
+		AccessChecker: checker,
+		permissions:   permissions,
+	}
+}
+
+// IsRestrictedChecker returns true if the provided checker has been
+// restricted to specific permissions
+func IsRestrictedChecker(checker teleservices.AccessChecker) bool {
+	_, ok := checker.(*permissionChecker)
+	return ok
+}
+
+type permissionChecker struct {
+	teleservices.AccessChecker
+	permissions storage.ResourcePermissions
+}
+
+// CheckAccessToRule checks access to the rule with the wrapped checker and
+// additionally checks that the rule is allowed by the permissions
+func (c *permissionChecker) CheckAccessToRule(ctx teleservices.RuleContext, namespace string, rule string, verb string, silent bool) error {
+	err := c.AccessChecker.CheckAccessToRule(ctx, namespace, rule, verb, silent)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	repository, ok := contextRepository(ctx)
+	if !ok {
+		// listing repositories is allowed with any permission to list
+		// them, otherwise only the permissions for all repositories apply
+		if rule == storage.KindRepository && verb == teleservices.VerbList {
+			for _, permission := range c.permissions {
+				if permission.Allows(rule, permission.Repository, verb) {
+					return nil
+				}
+			}
+		}
+		repository = teleservices.Wildcard
+	}
+	if !c.permissions.Allows(rule, repository, verb) {
+		return trace.AccessDenied("access to %v %v is outside of the permissions %v",
+			verb, rule, c.permissions)
+	}
+	return nil
+}
+
+// CheckAccessToServer denies server access unless the permissions
+// cover all resources
+func (c *permissionChecker) CheckAccessToServer(login string, server teleservices.Server) error {
+	if !c.permissions.Allows(teleservices.KindNode, "", teleservices.VerbRead) {
+		return trace.AccessDenied("server access is outside of the permissions %v", c.permissions)
+	}
+	return c.AccessChecker.CheckAccessToServer(login, server)
+}
+
+// contextRepository returns the name of the repository
+// of the resource specified in the rule context
+func contextRepository(ctx teleservices.RuleContext) (string, bool) {
+	resource, err := ctx.GetResource()
+	if err != nil || resource == nil {
+		return "", false
+	}
+	switch r := resource.(type) {
+	case storage.Repository:
+		return r.GetName(), true
+	case storage.App:
+		return r.GetRepository(), true
+	}
+	return "", false
+}
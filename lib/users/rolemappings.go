@@ -0,0 +1,175 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package users
+
+import (
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/coreos/go-oidc/jose"
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+)
+
+// WithRoleMappings returns the identity service that extends OIDC and SAML
+// connectors with the mappings defined by role mapping resources.
+//
+// Teleport assigns roles to SSO users based on the connector mappings
+// every time they log in, so users matched by a role mapping are created and
+// kept in sync with the mapping without having to be created manually
+func WithRoleMappings(identity Identity) Identity {
+	return &roleMappingIdentity{Identity: identity}
+}
+
+type roleMappingIdentity struct {
+	Identity
+}
+
+// GetOIDCConnector returns the OIDC connector with the claim mappings
+// of the applicable role mappings added
+func (i *roleMappingIdentity) GetOIDCConnector(id string, withSecrets bool) (teleservices.OIDCConnector, error) {
+	connector, err := i.Identity.GetOIDCConnector(id, withSecrets)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	mappings, err := i.GetRoleMappings()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return ApplyRoleMappings(connector, mappings), nil
+}
+
+// GetOIDCConnectors returns all OIDC connectors with the claim mappings
+// of the applicable role mappings added
+func (i *roleMappingIdentity) GetOIDCConnectors(withSecrets bool) ([]teleservices.OIDCConnector, error) {
+	connectors, err := i.Identity.GetOIDCConnectors(withSecrets)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	mappings, err := i.GetRoleMappings()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for i, connector := range connectors {
+		connectors[i] = ApplyRoleMappings(connector, mappings)
+	}
+	return connectors, nil
+}
+
+// GetSAMLConnector returns the SAML connector with the attribute mappings
+// of the applicable role mappings added
+func (i *roleMappingIdentity) GetSAMLConnector(id string, withSecrets bool) (teleservices.SAMLConnector, error) {
+	connector, err := i.Identity.GetSAMLConnector(id, withSecrets)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	mappings, err := i.GetRoleMappings()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	ApplySAMLRoleMappings(connector, mappings)
+	return connector, nil
+}
+
+// GetSAMLConnectors returns all SAML connectors with the attribute mappings
+// of the applicable role mappings added
+func (i *roleMappingIdentity) GetSAMLConnectors(withSecrets bool) ([]teleservices.SAMLConnector, error) {
+	connectors, err := i.Identity.GetSAMLConnectors(withSecrets)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	mappings, err := i.GetRoleMappings()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, connector := range connectors {
+		ApplySAMLRoleMappings(connector, mappings)
+	}
+	return connectors, nil
+}
+
+// ApplySAMLRoleMappings adds attribute mappings of the role mappings that
+// apply to the specified connector to the connector's own attribute mappings
+func ApplySAMLRoleMappings(connector teleservices.SAMLConnector, mappings []storage.RoleMapping) {
+	attributes := connector.GetAttributesToRoles()
+	for _, mapping := range mappings {
+		if mapping.AppliesTo(connector.GetName()) {
+			attributes = append(attributes, mapping.AttributeMappings()...)
+		}
+	}
+	connector.SetAttributesToRoles(attributes)
+}
+
+// ApplyRoleMappings adds claim mappings of the role mappings that apply
+// to the specified connector to the connector's own claim mappings and
+// returns the resulting connector.
+//
+// Some identity providers let users sign up with an arbitrary email address,
+// so the email domain mappings only match users whose email has been verified,
+// i.e. with the email_verified claim set to true
+func ApplyRoleMappings(connector teleservices.OIDCConnector, mappings []storage.RoleMapping) teleservices.OIDCConnector {
+	claims := connector.GetClaimsToRoles()
+	unverified := append([]teleservices.ClaimMapping(nil), claims...)
+	var emailMappings bool
+	for _, mapping := range mappings {
+		if !mapping.AppliesTo(connector.GetName()) {
+			continue
+		}
+		for _, claim := range mapping.ClaimMappings() {
+			claims = append(claims, claim)
+			if claim.Claim == storage.EmailClaim {
+				emailMappings = true
+				continue
+			}
+			unverified = append(unverified, claim)
+		}
+	}
+	if !emailMappings {
+		connector.SetClaimsToRoles(claims)
+		return connector
+	}
+	connectorV2, ok := connector.(interface {
+		V2() *teleservices.OIDCConnectorV2
+	})
+	if !ok {
+		// the email verification cannot be enforced for the connector
+		// so the email domain mappings are not applied
+		connector.SetClaimsToRoles(unverified)
+		return connector
+	}
+	connector.SetClaimsToRoles(claims)
+	return &verifiedEmailConnector{
+		OIDCConnectorV2: connectorV2.V2(),
+		unverified: teleservices.NewOIDCConnector(connector.GetName(),
+			teleservices.OIDCConnectorSpecV2{ClaimsToRoles: unverified}),
+	}
+}
+
+// verifiedEmailConnector is the OIDC connector that maps claims
+// of users with unverified emails without the email domain mappings
+type verifiedEmailConnector struct {
+	*teleservices.OIDCConnectorV2
+	// unverified is the connector without the email domain mappings
+	unverified teleservices.OIDCConnector
+}
+
+// MapClaims returns the roles the specified claims are mapped to
+func (c *verifiedEmailConnector) MapClaims(claims jose.Claims) []string {
+	if verified, ok := claims[storage.EmailVerifiedClaim].(bool); ok && verified {
+		return c.OIDCConnectorV2.MapClaims(claims)
+	}
+	return c.unverified.MapClaims(claims)
+}
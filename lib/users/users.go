@@ -43,6 +43,7 @@ type Identity interface {
 	Accounts
 	teleservices.Presence
 	storage.Locks
+	storage.RoleMappings
 	teleservices.ClusterConfiguration
 	teleservices.Trust
 	teleservices.Access
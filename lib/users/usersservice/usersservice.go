@@ -326,18 +326,53 @@ func (c *UsersService) AuthenticateUser(creds httplib.AuthCreds) (storage.User,
 	if err != nil {
 		return nil, nil, trace.Wrap(err)
 	}
+	key, err := c.getAPIKey(user, creds)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	if key != nil && len(key.Permissions) != 0 {
+		checker = users.NewPermissionChecker(checker, key.Permissions)
+	}
 	return user, checker, nil
 }
 
 // GetAccessChecker returns access checker for user based on users roles
+// and the permissions assigned to the user
 func (c *UsersService) GetAccessChecker(user storage.User) (teleservices.AccessChecker, error) {
 	roles, err := c.backend.GetUserRoles(user.GetName())
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	if permissions := user.GetPermissions(); len(permissions) != 0 {
+		role, err := users.NewPermissionRole(user.GetName(), permissions)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		roles = append(roles, role)
+	}
 	return teleservices.NewRoleSet(roles...), nil
 }
 
+// getAPIKey returns the API key used as the provided credentials.
+// Returns nil if the credentials are not an API key
+func (c *UsersService) getAPIKey(user storage.User, creds httplib.AuthCreds) (*storage.APIKey, error) {
+	keys, err := c.backend.GetAPIKeys(user.GetName())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(key.Token), []byte(creds.Password)) == 1 {
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+// isExpired returns true if the API key has expired
+func (c *UsersService) isExpired(key storage.APIKey) bool {
+	return !key.Expires.IsZero() && key.Expires.Before(c.clock.Now().UTC())
+}
+
 // AuthenticateUserBasicAuth authenticates user using basic auth, where password's hash
 // is checked against stored hash for AdminUser and token is compared as is
 // for AgentUser (treated as API key)
@@ -360,7 +395,7 @@ func (c *UsersService) AuthenticateUserBasicAuth(username, password string) (sto
 			return nil, trace.Wrap(err)
 		}
 		for _, k := range keys {
-			if subtle.ConstantTimeCompare([]byte(k.Token), []byte(password)) == 1 {
+			if subtle.ConstantTimeCompare([]byte(k.Token), []byte(password)) == 1 && !c.isExpired(k) {
 				match = true
 			}
 		}
@@ -375,6 +410,9 @@ func (c *UsersService) AuthenticateUserBasicAuth(username, password string) (sto
 		}
 		for _, k := range keys {
 			if subtle.ConstantTimeCompare([]byte(k.Token), []byte(password)) == 1 {
+				if c.isExpired(k) {
+					return nil, trace.AccessDenied("expired api key")
+				}
 				return user, nil
 			}
 		}
@@ -406,6 +444,9 @@ func (c *UsersService) authenticateAPIKey(token string) (storage.User, error) {
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	if c.isExpired(*key) {
+		return nil, trace.AccessDenied("expired api key")
+	}
 	u, err := c.backend.GetUser(key.UserEmail)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -921,6 +962,34 @@ func (c *UsersService) GetOIDCConnectors(withSecrets bool) ([]teleservices.OIDCC
 	return c.backend.GetOIDCConnectors(withSecrets)
 }
 
+// UpsertRoleMapping creates or updates a mapping of OIDC claims to roles
+func (c *UsersService) UpsertRoleMapping(mapping storage.RoleMapping) error {
+	if err := mapping.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	for _, role := range mapping.GetRoles() {
+		if _, err := c.backend.GetRole(role); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(c.backend.UpsertRoleMapping(mapping))
+}
+
+// GetRoleMapping returns a role mapping by name
+func (c *UsersService) GetRoleMapping(name string) (storage.RoleMapping, error) {
+	return c.backend.GetRoleMapping(name)
+}
+
+// GetRoleMappings returns all role mappings
+func (c *UsersService) GetRoleMappings() ([]storage.RoleMapping, error) {
+	return c.backend.GetRoleMappings()
+}
+
+// DeleteRoleMapping deletes a role mapping by name
+func (c *UsersService) DeleteRoleMapping(name string) error {
+	return trace.Wrap(c.backend.DeleteRoleMapping(name))
+}
+
 // CreateOIDCAuthRequest creates new auth request
 func (c *UsersService) CreateOIDCAuthRequest(req teleservices.OIDCAuthRequest, ttl time.Duration) error {
 	return c.backend.CreateOIDCAuthRequest(req)
@@ -1327,11 +1396,33 @@ func (c *UsersService) CreateInviteToken(advertiseURL string, userInvite storage
 
 // GetUserInvites returns user invites
 func (c *UsersService) GetUserInvites(accountID string) ([]storage.UserInvite, error) {
-	return c.backend.GetUserInvites()
+	invites, err := c.backend.GetUserInvites()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if users.IsSystemAccount(accountID) {
+		return invites, nil
+	}
+	var result []storage.UserInvite
+	for _, invite := range invites {
+		if invite.AccountID == accountID {
+			result = append(result, invite)
+		}
+	}
+	return result, nil
 }
 
 // DeleteUserInvite deletes user invite
 func (c *UsersService) DeleteUserInvite(accountID, email string) error {
+	if !users.IsSystemAccount(accountID) {
+		invite, err := c.backend.GetUserInvite(email)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if invite.AccountID != accountID {
+			return trace.NotFound("user invite %v not found", email)
+		}
+	}
 	return c.backend.DeleteUserInvite(email)
 }
 
@@ -1434,11 +1525,16 @@ func (c *UsersService) CreateUserWithToken(completeReq users.UserTokenCompleteRe
 		return nil, trace.Wrap(err)
 	}
 
+	accountID := invite.AccountID
+	if accountID == "" {
+		accountID = defaults.SystemAccountID
+	}
+
 	user, err := c.backend.CreateUser(storage.NewUser(invite.Name, storage.UserSpecV2{
 		Type:      storage.AdminUser,
 		HOTP:      otpBytes,
 		Password:  string(hash),
-		AccountID: defaults.SystemAccountID,
+		AccountID: accountID,
 		Roles:     roles,
 		CreatedBy: teleservices.CreatedBy{
 			User: teleservices.UserRef{Name: invite.CreatedBy},
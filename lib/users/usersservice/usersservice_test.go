@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
@@ -32,10 +33,12 @@ import (
 
 	"github.com/gravitational/teleport"
 	teledefaults "github.com/gravitational/teleport/lib/defaults"
+	telefixtures "github.com/gravitational/teleport/lib/fixtures"
 	teleservices "github.com/gravitational/teleport/lib/services"
 	teleutils "github.com/gravitational/teleport/lib/utils"
 	"github.com/gravitational/trace"
 
+	"github.com/coreos/go-oidc/jose"
 	"github.com/gokyle/hotp"
 	"github.com/jonboulle/clockwork"
 	log "github.com/sirupsen/logrus"
@@ -526,3 +529,334 @@ func newClusterAgentRole(name string, clusterName string) storage.RoleV2 {
 		KubernetesGroups: users.GetAdminKubernetesGroups(),
 	})
 }
+
+func (s *UsersSuite) TestRoleMappings(c *C) {
+	role, err := users.NewAdminRole()
+	c.Assert(err, IsNil)
+	c.Assert(s.suite.Users.UpsertRole(role, 0), IsNil)
+
+	err = s.suite.Users.UpsertRoleMapping(storage.NewRoleMapping("unknown", storage.RoleMappingSpecV1{
+		Groups: []string{"ops"},
+		Roles:  []string{"unknown"},
+	}))
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("expected unknown role to be rejected: %v", err))
+
+	c.Assert(s.suite.Users.UpsertRoleMapping(storage.NewRoleMapping("admins", storage.RoleMappingSpecV1{
+		Connector:    "example",
+		Groups:       []string{"ops"},
+		EmailDomains: []string{"example.com"},
+		Roles:        []string{role.GetName()},
+	})), IsNil)
+	c.Assert(s.suite.Users.UpsertRoleMapping(storage.NewRoleMapping("other", storage.RoleMappingSpecV1{
+		Connector: "other",
+		Groups:    []string{"dev"},
+		Roles:     []string{role.GetName()},
+	})), IsNil)
+
+	connector := teleservices.NewOIDCConnector("example", teleservices.OIDCConnectorSpecV2{
+		IssuerURL:    "https://accounts.example.com",
+		ClientID:     "id",
+		ClientSecret: "secret",
+		RedirectURL:  "https://localhost:3080/v1/webapi/oidc/callback",
+	})
+	c.Assert(s.suite.Users.UpsertOIDCConnector(connector), IsNil)
+
+	identity := users.WithRoleMappings(s.suite.Users)
+	out, err := identity.GetOIDCConnector("example", true)
+	c.Assert(err, IsNil)
+	testCases := []struct {
+		claims  jose.Claims
+		roles   []string
+		comment string
+	}{
+		{
+			claims:  jose.Claims{"groups": []string{"dev", "ops"}},
+			roles:   []string{role.GetName()},
+			comment: "matching group",
+		},
+		{
+			claims:  jose.Claims{"email": "alice@example.com", "email_verified": true},
+			roles:   []string{role.GetName()},
+			comment: "matching verified email domain",
+		},
+		{
+			claims:  jose.Claims{"email": "alice@example.com", "email_verified": false},
+			comment: "matching unverified email domain",
+		},
+		{
+			claims:  jose.Claims{"email": "alice@example.com"},
+			comment: "matching email domain without verification",
+		},
+		{
+			claims:  jose.Claims{"groups": []string{"ops"}, "email": "alice@example.org"},
+			roles:   []string{role.GetName()},
+			comment: "matching group with unverified email",
+		},
+		{
+			claims:  jose.Claims{"groups": []string{"dev"}, "email": "alice@example.org"},
+			comment: "mapping of another connector",
+		},
+	}
+	for _, tc := range testCases {
+		c.Assert(out.MapClaims(tc.claims), DeepEquals, tc.roles, Commentf(tc.comment))
+	}
+
+	// the connector with role mappings can be marshaled
+	_, err = teleservices.GetOIDCConnectorMarshaler().MarshalOIDCConnector(out)
+	c.Assert(err, IsNil)
+
+	// the stored connector is not modified
+	out, err = s.suite.Users.GetOIDCConnector("example", true)
+	c.Assert(err, IsNil)
+	c.Assert(out.GetClaimsToRoles(), HasLen, 0)
+
+	samlConnector := &teleservices.SAMLConnectorV2{
+		Kind:    teleservices.KindSAMLConnector,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      "example",
+			Namespace: teledefaults.Namespace,
+		},
+		Spec: teleservices.SAMLConnectorSpecV2{
+			Issuer:                   "http://example.com",
+			SSO:                      "https://example.com/saml/sso",
+			AssertionConsumerService: "https://localhost/portalapi/v1/saml/callback",
+			Cert:                     telefixtures.SigningCertPEM,
+			SigningKeyPair: &teleservices.SigningKeyPair{
+				PrivateKey: telefixtures.SigningKeyPEM,
+				Cert:       telefixtures.SigningCertPEM,
+			},
+			AttributesToRoles: []teleservices.AttributeMapping{
+				{Name: "groups", Value: "admins", Roles: []string{role.GetName()}},
+			},
+		},
+	}
+	c.Assert(samlConnector.CheckAndSetDefaults(), IsNil)
+	c.Assert(s.suite.Users.UpsertSAMLConnector(samlConnector), IsNil)
+	outSAML, err := identity.GetSAMLConnector("example", true)
+	c.Assert(err, IsNil)
+	c.Assert(outSAML.GetAttributesToRoles(), DeepEquals, []teleservices.AttributeMapping{
+		{Name: "groups", Value: "admins", Roles: []string{role.GetName()}},
+		{Name: "groups", Value: "ops", Roles: []string{role.GetName()}},
+		{Name: "email", Value: "*@example.com", Roles: []string{role.GetName()}},
+	})
+}
+
+func (s *UsersSuite) TestRepositoryPermissions(c *C) {
+	const email = "ci@example.com"
+	err := s.suite.Users.UpsertUser(storage.NewUser(email, storage.UserSpecV2{
+		Type: storage.AgentUser,
+		Permissions: storage.ResourcePermissions{
+			{Repository: "team-a", Actions: []string{storage.PermissionActionRead, storage.PermissionActionPublish}},
+			{Repository: "team-b", Actions: []string{storage.PermissionActionRead}},
+		},
+	}))
+	c.Assert(err, IsNil)
+
+	unrestricted, err := s.suite.Users.CreateAPIKey(storage.APIKey{UserEmail: email}, false)
+	c.Assert(err, IsNil)
+	restricted, err := s.suite.Users.CreateAPIKey(storage.APIKey{
+		UserEmail: email,
+		Permissions: storage.ResourcePermissions{
+			{Repository: "team-a", Actions: []string{storage.PermissionActionRead}},
+		},
+	}, false)
+	c.Assert(err, IsNil)
+
+	type check struct {
+		repository string
+		verb       string
+		hasAccess  bool
+	}
+	testCases := []struct {
+		comment string
+		token   string
+		checks  []check
+	}{
+		{
+			comment: "user permissions apply to an unrestricted key",
+			token:   unrestricted.Token,
+			checks: []check{
+				{repository: "team-a", verb: teleservices.VerbRead, hasAccess: true},
+				{repository: "team-a", verb: teleservices.VerbCreate, hasAccess: true},
+				{repository: "team-a", verb: storage.VerbPublish, hasAccess: true},
+				{repository: "team-a", verb: teleservices.VerbDelete, hasAccess: false},
+				{repository: "team-b", verb: teleservices.VerbRead, hasAccess: true},
+				{repository: "team-b", verb: teleservices.VerbCreate, hasAccess: false},
+				{repository: "team-c", verb: teleservices.VerbRead, hasAccess: false},
+			},
+		},
+		{
+			comment: "key permissions further restrict user permissions",
+			token:   restricted.Token,
+			checks: []check{
+				{repository: "team-a", verb: teleservices.VerbRead, hasAccess: true},
+				{repository: "team-a", verb: teleservices.VerbCreate, hasAccess: false},
+				{repository: "team-b", verb: teleservices.VerbRead, hasAccess: false},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		comment := Commentf(tc.comment)
+		_, checker, err := s.suite.Users.AuthenticateUser(httplib.AuthCreds{
+			Type:     httplib.AuthBearer,
+			Password: tc.token,
+		})
+		c.Assert(err, IsNil, comment)
+		for _, check := range tc.checks {
+			ctx := &users.Context{Context: teleservices.Context{
+				Resource: storage.NewRepository(check.repository),
+			}}
+			err := checker.CheckAccessToRule(ctx, teledefaults.Namespace,
+				storage.KindRepository, check.verb, true)
+			c.Assert(err == nil, Equals, check.hasAccess,
+				Commentf("%v: %v %v: %v", tc.comment, check.verb, check.repository, err))
+		}
+	}
+}
+
+func (s *UsersSuite) TestAccountInvites(c *C) {
+	role, err := users.NewAdminRole()
+	c.Assert(err, IsNil)
+	c.Assert(s.suite.Users.UpsertRole(role, 0), IsNil)
+
+	_, err = s.suite.Users.CreateInviteToken("https://localhost:3009", storage.UserInvite{
+		Name:      "alice@example.com",
+		CreatedBy: "admin@example.com",
+		Roles:     []string{role.GetName()},
+		AccountID: "tenant-a",
+	})
+	c.Assert(err, IsNil)
+
+	invites, err := s.suite.Users.GetUserInvites("tenant-a")
+	c.Assert(err, IsNil)
+	c.Assert(len(invites), Equals, 1)
+	invites, err = s.suite.Users.GetUserInvites("tenant-b")
+	c.Assert(err, IsNil)
+	c.Assert(len(invites), Equals, 0)
+	invites, err = s.suite.Users.GetUserInvites(defaults.SystemAccountID)
+	c.Assert(err, IsNil)
+	c.Assert(len(invites), Equals, 1, Commentf("system account should see all invites"))
+
+	err = s.suite.Users.DeleteUserInvite("tenant-b", "alice@example.com")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("expected NotFound, got %v", err))
+	c.Assert(s.suite.Users.DeleteUserInvite("tenant-a", "alice@example.com"), IsNil)
+}
+
+func (s *UsersSuite) TestAccountRepositoryAccess(c *C) {
+	for _, account := range []users.Account{
+		{ID: defaults.SystemAccountID, Org: defaults.SystemAccountOrg},
+		{ID: "tenant-a", Org: "a.example.com"},
+		{ID: "tenant-b", Org: "b.example.com"},
+	} {
+		_, err := s.suite.Users.CreateAccount(account)
+		c.Assert(err, IsNil)
+	}
+	system := storage.NewUser("admin@example.com", storage.UserSpecV2{AccountID: defaults.SystemAccountID})
+	tenant := storage.NewUser("alice@a.example.com", storage.UserSpecV2{AccountID: "tenant-a"})
+
+	testCases := []struct {
+		user       storage.User
+		repository string
+		verb       string
+		hasAccess  bool
+	}{
+		{user: system, repository: "b.example.com", verb: teleservices.VerbCreate, hasAccess: true},
+		{user: system, repository: defaults.SystemAccountOrg, verb: teleservices.VerbDelete, hasAccess: true},
+		{user: tenant, repository: "a.example.com", verb: teleservices.VerbCreate, hasAccess: true},
+		{user: tenant, repository: defaults.SystemAccountOrg, verb: teleservices.VerbRead, hasAccess: true},
+		{user: tenant, repository: defaults.SystemAccountOrg, verb: teleservices.VerbCreate, hasAccess: false},
+		{user: tenant, repository: "b.example.com", verb: teleservices.VerbRead, hasAccess: false},
+		{user: tenant, repository: "cluster.example.com", verb: teleservices.VerbRead, hasAccess: true},
+	}
+	for _, tc := range testCases {
+		err := users.CheckRepositoryAccess(s.suite.Users, tc.user, tc.repository, tc.verb)
+		c.Assert(err == nil, Equals, tc.hasAccess,
+			Commentf("%v: %v %v: %v", tc.user.GetName(), tc.verb, tc.repository, err))
+	}
+
+	c.Assert(users.CheckAccountAccess(system, "tenant-b"), IsNil)
+	c.Assert(users.CheckAccountAccess(tenant, "tenant-a"), IsNil)
+	c.Assert(trace.IsAccessDenied(users.CheckAccountAccess(tenant, "tenant-b")), Equals, true)
+	c.Assert(trace.IsAccessDenied(users.CheckAccountAccess(tenant, defaults.SystemAccountID)), Equals, true)
+}
+
+func (s *UsersSuite) TestDeniesUsersWithoutAccount(c *C) {
+	_, err := s.suite.Users.CreateAccount(users.Account{ID: "tenant-a", Org: "a.example.com"})
+	c.Assert(err, IsNil)
+	user := storage.NewUser("bob@example.com", storage.UserSpecV2{})
+	c.Assert(users.IsSystemAccountUser(user), Equals, false)
+
+	for _, accountID := range []string{defaults.SystemAccountID, "tenant-a", ""} {
+		err := users.CheckAccountAccess(user, accountID)
+		c.Assert(trace.IsAccessDenied(err), Equals, true,
+			Commentf("account %q: expected AccessDenied, got %v", accountID, err))
+	}
+	for _, repository := range []string{defaults.SystemAccountOrg, "a.example.com", "cluster.example.com"} {
+		err := users.CheckRepositoryAccess(s.suite.Users, user, repository, teleservices.VerbRead)
+		c.Assert(trace.IsAccessDenied(err), Equals, true,
+			Commentf("repository %v: expected AccessDenied, got %v", repository, err))
+	}
+}
+
+func (s *UsersSuite) TestAPIKeyPermissions(c *C) {
+	const email = "ci@example.com"
+	role, err := users.NewAdminRole()
+	c.Assert(err, IsNil)
+	err = s.suite.Users.UpsertRole(role, 0)
+	c.Assert(err, IsNil)
+	err = s.suite.Users.UpsertUser(storage.NewUser(email, storage.UserSpecV2{
+		Type:  storage.AgentUser,
+		Roles: []string{role.GetName()},
+	}))
+	c.Assert(err, IsNil)
+
+	permissions, err := storage.ParseResourcePermissions([]string{"app:publish"})
+	c.Assert(err, IsNil)
+	restricted, err := s.suite.Users.CreateAPIKey(storage.APIKey{
+		UserEmail:   email,
+		Permissions: permissions,
+	}, false)
+	c.Assert(err, IsNil)
+	expired, err := s.suite.Users.CreateAPIKey(storage.APIKey{
+		UserEmail: email,
+		Expires:   s.clock.Now().Add(time.Hour),
+	}, false)
+	c.Assert(err, IsNil)
+
+	user, checker, err := s.suite.Users.AuthenticateUser(httplib.AuthCreds{
+		Type:     httplib.AuthBearer,
+		Password: restricted.Token,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(users.IsRestrictedChecker(checker), Equals, true)
+	ctx := &users.Context{Context: teleservices.Context{User: user}}
+	testCases := []struct {
+		kind      string
+		verb      string
+		hasAccess bool
+	}{
+		{kind: storage.KindApp, verb: teleservices.VerbCreate, hasAccess: true},
+		{kind: storage.KindRepository, verb: teleservices.VerbList, hasAccess: true},
+		{kind: storage.KindApp, verb: teleservices.VerbDelete, hasAccess: false},
+		{kind: storage.KindCluster, verb: teleservices.VerbRead, hasAccess: false},
+		{kind: teleservices.KindUser, verb: teleservices.VerbCreate, hasAccess: false},
+	}
+	for _, tc := range testCases {
+		err := checker.CheckAccessToRule(ctx, teledefaults.Namespace, tc.kind, tc.verb, true)
+		c.Assert(err == nil, Equals, tc.hasAccess,
+			Commentf("%v %v: %v", tc.verb, tc.kind, err))
+	}
+
+	_, _, err = s.suite.Users.AuthenticateUser(httplib.AuthCreds{
+		Type:     httplib.AuthBearer,
+		Password: expired.Token,
+	})
+	c.Assert(err, IsNil)
+	s.clock.Advance(2 * time.Hour)
+	_, _, err = s.suite.Users.AuthenticateUser(httplib.AuthCreds{
+		Type:     httplib.AuthBearer,
+		Password: expired.Token,
+	})
+	c.Assert(trace.IsAccessDenied(err), Equals, true, Commentf("%v", err))
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// CronSchedule is a parsed standard 5-field cron expression:
+// minute, hour, day of month, month and day of week.
+//
+// Each field is either '*', a number, a range (1-5) or a list (1,3,5),
+// optionally followed by a step (*/15, 0-30/10)
+type CronSchedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	// anyDay and anyWeekday are set when the respective field is '*'.
+	// As in cron, if both day of month and day of week are restricted,
+	// a day matches if it matches either of them
+	anyDay     bool
+	anyWeekday bool
+}
+
+// ParseCron parses the cron expression
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, trace.BadParameter(
+			"cron expression %q should have 5 fields: minute, hour, day of month, month and day of week", expr)
+	}
+	var schedule CronSchedule
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if schedule.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if schedule.weekdays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// both 0 and 7 denote Sunday
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+	schedule.anyDay = fields[2] == "*"
+	schedule.anyWeekday = fields[4] == "*"
+	return &schedule, nil
+}
+
+// Next returns the earliest time strictly after t that matches the schedule.
+// It returns zero time if no such time exists within the next 5 years
+func (s CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s CronSchedule) matchesDay(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// parseCronField parses a single cron field into a bit set of values
+func parseCronField(field string, min, max int) (bits uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		rangeExpr, step := part, 1
+		if i := strings.Index(part, "/"); i != -1 {
+			rangeExpr = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, trace.BadParameter("invalid step in cron field %q", field)
+			}
+		}
+		start, end := min, max
+		switch {
+		case rangeExpr == "*":
+		case strings.Contains(rangeExpr, "-"):
+			bounds := strings.SplitN(rangeExpr, "-", 2)
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, trace.BadParameter("invalid range in cron field %q", field)
+			}
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, trace.BadParameter("invalid range in cron field %q", field)
+			}
+		default:
+			if start, err = strconv.Atoi(rangeExpr); err != nil {
+				return 0, trace.BadParameter("invalid value in cron field %q", field)
+			}
+			end = start
+			if step != 1 {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, trace.BadParameter("cron field %q is out of range %v-%v", field, min, max)
+		}
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+type CronSuite struct{}
+
+var _ = check.Suite(&CronSuite{})
+
+func (s *CronSuite) TestNext(c *check.C) {
+	// Wednesday
+	now := time.Date(2019, time.October, 16, 10, 30, 15, 0, time.UTC)
+	var tests = []struct {
+		expr    string
+		next    time.Time
+		comment string
+	}{
+		{
+			expr:    "* * * * *",
+			next:    time.Date(2019, time.October, 16, 10, 31, 0, 0, time.UTC),
+			comment: "every minute",
+		},
+		{
+			expr:    "*/15 * * * *",
+			next:    time.Date(2019, time.October, 16, 10, 45, 0, 0, time.UTC),
+			comment: "every 15 minutes",
+		},
+		{
+			expr:    "0 2 * * *",
+			next:    time.Date(2019, time.October, 17, 2, 0, 0, 0, time.UTC),
+			comment: "daily at 2am",
+		},
+		{
+			expr:    "0 2 * * 6,0",
+			next:    time.Date(2019, time.October, 19, 2, 0, 0, 0, time.UTC),
+			comment: "weekends at 2am",
+		},
+		{
+			expr:    "30 22 * * 7",
+			next:    time.Date(2019, time.October, 20, 22, 30, 0, 0, time.UTC),
+			comment: "sunday as 7",
+		},
+		{
+			expr:    "0 0 1 1-3 *",
+			next:    time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			comment: "first day of the first quarter",
+		},
+		{
+			expr:    "0 0 13 * 5",
+			next:    time.Date(2019, time.October, 18, 0, 0, 0, 0, time.UTC),
+			comment: "either the 13th or a friday",
+		},
+		{
+			expr:    "0 0 31 2 *",
+			comment: "never",
+		},
+	}
+	for _, tt := range tests {
+		comment := check.Commentf(tt.comment)
+		schedule, err := ParseCron(tt.expr)
+		c.Assert(err, check.IsNil, comment)
+		c.Assert(schedule.Next(now), check.DeepEquals, tt.next, comment)
+	}
+}
+
+func (s *CronSuite) TestParseErrors(c *check.C) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	} {
+		_, err := ParseCron(expr)
+		c.Assert(err, check.NotNil, check.Commentf(expr))
+	}
+}
@@ -293,6 +293,25 @@ func NewUnsupportedFilesystemError(err error, path string) *UnsupportedFilesyste
 	return &UnsupportedFilesystemError{Err: err, Path: path}
 }
 
+// ExitCodeError is an error that carries the exit code the command
+// should terminate with
+type ExitCodeError struct {
+	// Err is the original error
+	Err error
+	// Code is the exit code
+	Code int
+}
+
+// Error returns the string representation of the error
+func (e *ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
+// NewExitCodeError creates a new error with the specified exit code
+func NewExitCodeError(err error, code int) *ExitCodeError {
+	return &ExitCodeError{Err: err, Code: code}
+}
+
 // IsContextCancelledError returns true if the provided error is a result
 // of a context cancellation
 func IsContextCancelledError(err error) bool {
@@ -0,0 +1,50 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"crypto/sha256"
+	"reflect"
+
+	"github.com/gravitational/trace"
+)
+
+// IsFIPS returns true if this binary has been built with BoringCrypto,
+// the FIPS-validated crypto module
+func IsFIPS() bool {
+	// BoringCrypto builds substitute the standard library hash
+	// implementations with the ones from the boring package
+	return reflect.TypeOf(sha256.New()).Elem().PkgPath() == boringCryptoPackage
+}
+
+// CheckFIPS returns an error if the FIPS mode of this binary does not
+// match the FIPS mode of the cluster
+func CheckFIPS(clusterFIPS bool) error {
+	if clusterFIPS && !IsFIPS() {
+		return trace.BadParameter("the cluster has been installed in FIPS mode " +
+			"and only accepts FIPS-compliant gravity binaries")
+	}
+	if !clusterFIPS && IsFIPS() {
+		return trace.BadParameter("the cluster has not been installed in FIPS mode " +
+			"and does not accept FIPS-compliant gravity binaries")
+	}
+	return nil
+}
+
+// boringCryptoPackage is the package with the BoringCrypto
+// implementations of the standard library crypto primitives
+const boringCryptoPackage = "crypto/internal/boring"
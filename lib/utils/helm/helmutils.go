@@ -41,6 +41,12 @@ func Vals(valueFiles valueFiles, values []string, stringValues []string, fileVal
 	return yaml.Marshal(base)
 }
 
+// ParseValues merges values from files specified via --values and directly
+// via --set into a single map
+func ParseValues(valueFiles []string, values []string) (map[string]interface{}, error) {
+	return merge(valueFiles, values, nil, nil, "", "", "")
+}
+
 func merge(valueFiles valueFiles, values []string, stringValues []string, fileValues []string, CertFile, KeyFile, CAFile string) (map[string]interface{}, error) {
 	base := map[string]interface{}{}
 
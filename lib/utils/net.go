@@ -17,8 +17,9 @@ limitations under the License.
 package utils
 
 import (
-	"fmt"
 	"net"
+	"strconv"
+	"strings"
 
 	"github.com/gravitational/trace"
 	netutils "k8s.io/apimachinery/pkg/util/net"
@@ -58,7 +59,7 @@ func (a Address) EqualPort(other Address) bool {
 
 // String returns the address string
 func (a Address) String() string {
-	return fmt.Sprintf("%v:%v", a.Addr, a.Port)
+	return net.JoinHostPort(a.Addr, strconv.Itoa(int(a.Port)))
 }
 
 // SelectVPCSubnet returns a /24 subnet that does not overlap with the provided subnet blocks
@@ -109,47 +110,136 @@ func SelectSubnet(blocks []string) (string, error) {
 }
 
 // ValidateKubernetesSubnets makes sure that the provided CIDR ranges can be used as
-// pod/service Kubernetes subnets
+// pod/service Kubernetes subnets.
+//
+// Each subnet is either a single IPv4 or IPv6 CIDR range or a comma-separated
+// pair of an IPv4 and an IPv6 range for dual-stack networking. The first range
+// of the pod and service subnets must belong to the same IP family
 func ValidateKubernetesSubnets(podCIDR, serviceCIDR string) error {
-	var podNet, serviceNet *net.IPNet
-	var err error
-
-	// make sure the pod subnet is valid
-	if podCIDR != "" {
-		_, podNet, err = net.ParseCIDR(podCIDR)
-		if err != nil {
+	podNets, err := parseSubnets(podCIDR, "pod")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, podNet := range podNets {
+		// the pod network should leave room for at least 256 per-node ranges:
+		// Kubernetes allocates /24 per node for IPv4 and /64 for IPv6
+		ones, bits := podNet.Mask.Size()
+		if bits == net.IPv4len*8 && ones > 16 {
 			return trace.BadParameter(
-				"invalid pod network CIDR: %v", podCIDR)
+				"pod network should be a minimum of /16: %v", podNet)
 		}
-
-		// the pod network should be /16 minimum so k8s can allocate /24 to each node
-		ones, _ := podNet.Mask.Size()
-		if ones > 16 {
+		if bits == net.IPv6len*8 && ones > 56 {
 			return trace.BadParameter(
-				"pod network should be a minimum of /16: %v", podCIDR)
+				"IPv6 pod network should be a minimum of /56: %v", podNet)
 		}
 	}
 
-	// make sure the service subnet is valid
-	if serviceCIDR != "" {
-		_, serviceNet, err = net.ParseCIDR(serviceCIDR)
-		if err != nil {
+	serviceNets, err := parseSubnets(serviceCIDR, "service")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, serviceNet := range serviceNets {
+		// Kubernetes limits the IPv6 service network to 20 bits
+		ones, bits := serviceNet.Mask.Size()
+		if bits == net.IPv6len*8 && ones < 108 {
 			return trace.BadParameter(
-				"invalid service network CIDR: %v", serviceCIDR)
+				"IPv6 service network should be a maximum of /108: %v", serviceNet)
 		}
 	}
 
+	if len(podNets) != 0 && len(serviceNets) != 0 &&
+		isIPv6Net(podNets[0]) != isIPv6Net(serviceNets[0]) {
+		return trace.BadParameter(
+			"pod and service subnets should start with the same IP family")
+	}
+
 	// make sure the subnets do not overlap
-	if podNet != nil && serviceNet != nil {
-		if podNet.Contains(serviceNet.IP) || serviceNet.Contains(podNet.IP) {
-			return trace.BadParameter(
-				"pod and service subnets should not overlap")
+	for _, podNet := range podNets {
+		for _, serviceNet := range serviceNets {
+			if podNet.Contains(serviceNet.IP) || serviceNet.Contains(podNet.IP) {
+				return trace.BadParameter(
+					"pod and service subnets should not overlap")
+			}
 		}
 	}
 
 	return nil
 }
 
+// SplitSubnets returns the list of CIDR ranges from the specified
+// comma-separated subnet specification
+func SplitSubnets(subnets string) (result []string) {
+	for _, subnet := range strings.Split(subnets, ",") {
+		if subnet = strings.TrimSpace(subnet); subnet != "" {
+			result = append(result, subnet)
+		}
+	}
+	return result
+}
+
+// HasIPv6Subnet returns true if any of the specified subnet specifications
+// contains an IPv6 CIDR range
+func HasIPv6Subnet(subnets ...string) bool {
+	for _, spec := range subnets {
+		for _, subnet := range SplitSubnets(spec) {
+			_, ipNet, err := net.ParseCIDR(subnet)
+			if err == nil && isIPv6Net(*ipNet) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FirstSubnetIPs returns the first usable IP address of each CIDR range
+// in the specified subnet specification
+func FirstSubnetIPs(subnets string) (ips []net.IP, err error) {
+	for _, subnet := range SplitSubnets(subnets) {
+		_, ipNet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return nil, trace.BadParameter("invalid CIDR %q: %v", subnet, err)
+		}
+		ip := make(net.IP, len(ipNet.IP))
+		copy(ip, ipNet.IP)
+		for j := len(ip) - 1; j >= 0; j-- {
+			ip[j]++
+			if ip[j] > 0 {
+				break
+			}
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// parseSubnets parses the specified subnet specification which is
+// either a single CIDR range or a pair of IPv4 and IPv6 ranges
+func parseSubnets(subnets, kind string) (result []net.IPNet, err error) {
+	ranges := SplitSubnets(subnets)
+	if len(ranges) > 2 {
+		return nil, trace.BadParameter(
+			"%v network should have at most two CIDR ranges: %v", kind, subnets)
+	}
+	for _, subnet := range ranges {
+		_, ipNet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return nil, trace.BadParameter(
+				"invalid %v network CIDR: %v", kind, subnet)
+		}
+		result = append(result, *ipNet)
+	}
+	if len(result) == 2 && isIPv6Net(result[0]) == isIPv6Net(result[1]) {
+		return nil, trace.BadParameter(
+			"dual-stack %v network requires one IPv4 and one IPv6 CIDR range: %v", kind, subnets)
+	}
+	return result, nil
+}
+
+// isIPv6Net returns true if the specified network is an IPv6 network
+func isIPv6Net(ipNet net.IPNet) bool {
+	return ipNet.IP.To4() == nil
+}
+
 // PickAdvertiseIP selects an advertise IP among the host's interfaces
 func PickAdvertiseIP() (string, error) {
 	ip, err := netutils.ChooseHostInterface()
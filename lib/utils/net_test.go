@@ -157,6 +157,45 @@ func (s *NetSuite) TestValidateKubernetesSubnets(c *check.C) {
 			ok:          false,
 			description: "pod and service subnets overlap",
 		},
+		{
+			podCIDR:     "fd00:244::/56",
+			serviceCIDR: "fd00:100::/108",
+			ok:          true,
+			description: "IPv6-only subnets should validate",
+		},
+		{
+			podCIDR:     "10.244.0.0/16,fd00:244::/56",
+			serviceCIDR: "10.100.0.0/16,fd00:100::/108",
+			ok:          true,
+			description: "dual-stack subnets should validate",
+		},
+		{
+			podCIDR:     "fd00:244::/64",
+			ok:          false,
+			description: "IPv6 pod subnet is too small",
+		},
+		{
+			serviceCIDR: "fd00:100::/64",
+			ok:          false,
+			description: "IPv6 service subnet is too large",
+		},
+		{
+			podCIDR:     "10.244.0.0/16,10.245.0.0/16",
+			ok:          false,
+			description: "dual-stack pod subnet with two IPv4 ranges",
+		},
+		{
+			podCIDR:     "fd00:244::/56",
+			serviceCIDR: "10.100.0.0/16",
+			ok:          false,
+			description: "pod and service subnets start with different IP families",
+		},
+		{
+			podCIDR:     "10.244.0.0/16,fd00:244::/56",
+			serviceCIDR: "10.100.0.0/16,fd00:244::/108",
+			ok:          false,
+			description: "IPv6 pod and service subnets overlap",
+		},
 	}
 	for _, tc := range testCases {
 		err := ValidateKubernetesSubnets(tc.podCIDR, tc.serviceCIDR)
@@ -167,3 +206,13 @@ func (s *NetSuite) TestValidateKubernetesSubnets(c *check.C) {
 		}
 	}
 }
+
+func (s *NetSuite) TestFirstSubnetIPs(c *check.C) {
+	ips, err := FirstSubnetIPs("10.100.0.0/16, fd00:100::/108")
+	c.Assert(err, check.IsNil)
+	c.Assert(ips, check.HasLen, 2)
+	c.Assert(ips[0].String(), check.Equals, "10.100.0.1")
+	c.Assert(ips[1].String(), check.Equals, "fd00:100::1")
+	c.Assert(HasIPv6Subnet("10.244.0.0/16", "10.100.0.0/16,fd00:100::/108"), check.Equals, true)
+	c.Assert(HasIPv6Subnet("10.244.0.0/16", "10.100.0.0/16"), check.Equals, false)
+}
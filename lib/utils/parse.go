@@ -406,28 +406,37 @@ func ParseZoneOverride(override string) (zone, nameserver string, err error) {
 	if !cstrings.IsValidDomainName(zone) {
 		return "", "", trace.BadParameter("%q is not a valid domain name", zone)
 	}
+	if err := ValidateNameserver(nameserver); err != nil {
+		return "", "", trace.Wrap(err)
+	}
+	return zone, nameserver, nil
+}
+
+// ValidateNameserver validates the nameserver address in the format
+// <ip> or <ip>:<port>
+func ValidateNameserver(nameserver string) error {
 	// see if it's just an IP address
 	if net.ParseIP(nameserver) != nil {
-		return zone, nameserver, nil
+		return nil
 	}
 	// otherwise it includes port
 	host, portS, err := net.SplitHostPort(nameserver)
 	if err != nil {
-		return "", "", trace.Wrap(err, "expected nameserver as <ip> or <ip>:<port>, got: %q", nameserver)
+		return trace.Wrap(err, "expected nameserver as <ip> or <ip>:<port>, got: %q", nameserver)
 	}
 	// host must be a valid IP address
 	if net.ParseIP(host) == nil {
-		return "", "", trace.BadParameter("%q is not a valid IP address", host)
+		return trace.BadParameter("%q is not a valid IP address", host)
 	}
 	// port must be numeric and in the correct range
 	port, err := strconv.Atoi(portS)
 	if err != nil {
-		return "", "", trace.BadParameter("expected numeric port, got: %q", portS)
+		return trace.BadParameter("expected numeric port, got: %q", portS)
 	}
 	if port < 1 || port > 65535 {
-		return "", "", trace.BadParameter("invalid port: %q", port)
+		return trace.BadParameter("invalid port: %q", port)
 	}
-	return zone, nameserver, nil
+	return nil
 }
 
 // ToUnknownResource converts the provided resource to a generic resource type
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/constants"
+
+	"github.com/gravitational/trace"
+)
+
+// ProxyConfig describes the HTTP proxy settings
+type ProxyConfig struct {
+	// HTTPProxy is the proxy for HTTP requests
+	HTTPProxy string
+	// HTTPSProxy is the proxy for HTTPS requests
+	HTTPSProxy string
+	// NoProxy is the comma-separated list of hosts, domains and CIDR ranges
+	// that are accessed without the proxy
+	NoProxy string
+}
+
+// ProxyConfigFromEnvironment returns the proxy settings of the process environment.
+// Upper-case variable names take precedence over lower-case ones
+func ProxyConfigFromEnvironment() ProxyConfig {
+	return ProxyConfig{
+		HTTPProxy:  getenvAnyCase(constants.HTTPProxyEnvVar),
+		HTTPSProxy: getenvAnyCase(constants.HTTPSProxyEnvVar),
+		NoProxy:    getenvAnyCase(constants.NoProxyEnvVar),
+	}
+}
+
+// IsEmpty returns true if no proxy is configured
+func (r ProxyConfig) IsEmpty() bool {
+	return r.HTTPProxy == "" && r.HTTPSProxy == ""
+}
+
+// Override returns a copy of these settings with the non-empty
+// values from other taking precedence
+func (r ProxyConfig) Override(other ProxyConfig) ProxyConfig {
+	if other.HTTPProxy != "" {
+		r.HTTPProxy = other.HTTPProxy
+	}
+	if other.HTTPSProxy != "" {
+		r.HTTPSProxy = other.HTTPSProxy
+	}
+	if other.NoProxy != "" {
+		r.NoProxy = other.NoProxy
+	}
+	return r
+}
+
+// WithNoProxy returns a copy of these settings with the specified hosts
+// added to the list of hosts accessed without the proxy
+func (r ProxyConfig) WithNoProxy(hosts ...string) ProxyConfig {
+	var noProxy []string
+	seen := make(map[string]struct{})
+	for _, host := range append(strings.Split(r.NoProxy, ","), hosts...) {
+		host = strings.TrimSpace(host)
+		if _, ok := seen[host]; ok || host == "" {
+			continue
+		}
+		seen[host] = struct{}{}
+		noProxy = append(noProxy, host)
+	}
+	r.NoProxy = strings.Join(noProxy, ",")
+	return r
+}
+
+// Env returns the settings as environment variables.
+// Each variable is returned in both upper and lower case as not all
+// tools recognize both
+func (r ProxyConfig) Env() map[string]string {
+	env := make(map[string]string)
+	for name, value := range map[string]string{
+		constants.HTTPProxyEnvVar:  r.HTTPProxy,
+		constants.HTTPSProxyEnvVar: r.HTTPSProxy,
+		constants.NoProxyEnvVar:    r.NoProxy,
+	} {
+		if value != "" {
+			env[name] = value
+			env[strings.ToLower(name)] = value
+		}
+	}
+	return env
+}
+
+// Setenv exports the settings into the process environment so that
+// they are honored by HTTP clients and inherited by child processes.
+// It has to be invoked before any HTTP request is made as the proxy
+// configuration is read from the environment only once
+func (r ProxyConfig) Setenv() error {
+	for name, value := range r.Env() {
+		if err := os.Setenv(name, value); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	return nil
+}
+
+func getenvAnyCase(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return os.Getenv(strings.ToLower(name))
+}
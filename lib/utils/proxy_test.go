@@ -0,0 +1,51 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import . "gopkg.in/check.v1"
+
+type ProxySuite struct{}
+
+var _ = Suite(&ProxySuite{})
+
+func (s *ProxySuite) TestOverride(c *C) {
+	config := ProxyConfig{
+		HTTPProxy:  "http://proxy:3128",
+		HTTPSProxy: "http://proxy:3128",
+		NoProxy:    "example.com",
+	}.Override(ProxyConfig{HTTPSProxy: "http://secure-proxy:3129"})
+	c.Assert(config, DeepEquals, ProxyConfig{
+		HTTPProxy:  "http://proxy:3128",
+		HTTPSProxy: "http://secure-proxy:3129",
+		NoProxy:    "example.com",
+	})
+}
+
+func (s *ProxySuite) TestWithNoProxy(c *C) {
+	config := ProxyConfig{NoProxy: "example.com, .local"}.WithNoProxy(".local", "10.100.0.0/16", "")
+	c.Assert(config.NoProxy, Equals, "example.com,.local,10.100.0.0/16")
+}
+
+func (s *ProxySuite) TestEnv(c *C) {
+	config := ProxyConfig{HTTPSProxy: "http://proxy:3128"}
+	c.Assert(config.IsEmpty(), Equals, false)
+	c.Assert(config.Env(), DeepEquals, map[string]string{
+		"HTTPS_PROXY": "http://proxy:3128",
+		"https_proxy": "http://proxy:3128",
+	})
+	c.Assert(ProxyConfig{NoProxy: "example.com"}.IsEmpty(), Equals, true)
+}
@@ -67,6 +67,17 @@ func (c *Capacity) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// Set parses the capacity from a human friendly form, e.g. "10GB".
+// It allows capacity to be used as a command line flag value
+func (c *Capacity) Set(value string) error {
+	bytes, err := humanize.ParseBytes(value)
+	if err != nil {
+		return trace.Wrap(err, "could not parse %q as bytes", value)
+	}
+	*c = Capacity(bytes)
+	return nil
+}
+
 // MustParseCapacity parses the provided string as capacity or panics
 func MustParseCapacity(data string) Capacity {
 	bytes, err := humanize.ParseBytes(data)
@@ -182,6 +182,7 @@ func (r *engine) ChangePhaseState(ctx context.Context, change libfsm.StateChange
 			NewState:    change.State,
 			Error:       utils.ToRawTrace(change.Error),
 			Created:     time.Now().UTC(),
+			Attempt:     change.Attempt,
 		})
 	if err != nil {
 		return trace.Wrap(err)
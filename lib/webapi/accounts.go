@@ -0,0 +1,88 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webapi
+
+import (
+	"net/http"
+
+	"github.com/gravitational/gravity/lib/httplib"
+	"github.com/gravitational/gravity/lib/ops"
+
+	telehttplib "github.com/gravitational/teleport/lib/httplib"
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+)
+
+// getAccounts returns the accounts the user has access to
+//
+// GET /portalapi/v1/hub/accounts
+//
+// Output:
+// [{
+//   "id": "account-id",
+//   "org": "example.com"
+// }]
+func (m *Handler) getAccounts(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *AuthContext) (interface{}, error) {
+	accounts, err := ctx.Operator.GetAccounts()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if accounts == nil {
+		accounts = []ops.Account{}
+	}
+	return accounts, nil
+}
+
+// createAccount creates a new account
+//
+// POST /portalapi/v1/hub/accounts
+//
+// Input:
+// {
+//   "org": "example.com"
+// }
+//
+// Output:
+// {
+//   "id": "account-id",
+//   "org": "example.com"
+// }
+func (m *Handler) createAccount(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *AuthContext) (interface{}, error) {
+	var req ops.NewAccountRequest
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if req.Org == "" {
+		return nil, trace.BadParameter("missing org")
+	}
+	account, err := ctx.Operator.CreateAccount(req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return account, nil
+}
+
+// deleteAccount deletes the specified account
+//
+// DELETE /portalapi/v1/hub/accounts/:id
+//
+func (m *Handler) deleteAccount(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *AuthContext) (interface{}, error) {
+	if err := ctx.Operator.DeleteAccount(p.ByName("id")); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return httplib.OK(), nil
+}
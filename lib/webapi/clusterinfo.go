@@ -19,6 +19,7 @@ package webapi
 import (
 	"bytes"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 	"text/template"
@@ -41,6 +42,8 @@ type webClusterInfo struct {
 	InternalURLs []string `json:"internalURLs"`
 	// Commands contains various commands that can be run on the cluster.
 	Commands webClusterCommands `json:"commands"`
+	// License is the status of the cluster license, if the cluster has one.
+	License *ops.LicenseStatus `json:"license,omitempty"`
 }
 
 // webClusterCommands contains commands displayed to a user for cluster
@@ -66,8 +69,8 @@ func getClusterInfo(operator ops.Operator, cluster ops.Site) (*webClusterInfo, e
 	}
 	var internalAddrs []string
 	for _, node := range cluster.Masters() {
-		internalAddrs = append(internalAddrs, fmt.Sprintf("%v:%v",
-			node.AdvertiseIP, defaults.GravitySiteNodePort))
+		internalAddrs = append(internalAddrs, net.JoinHostPort(
+			node.AdvertiseIP, strconv.Itoa(defaults.GravitySiteNodePort)))
 	}
 	var publicAddrs []string
 	for _, webAddr := range authGateway.GetWebPublicAddrs() {
@@ -109,6 +112,10 @@ func getClusterInfo(operator ops.Operator, cluster ops.Site) (*webClusterInfo, e
 			return nil, trace.Wrap(err)
 		}
 	}
+	var licenseStatus *ops.LicenseStatus
+	if cluster.License != nil {
+		licenseStatus = &cluster.License.Status
+	}
 	return &webClusterInfo{
 		ClusterState: cluster.State,
 		PublicURLs:   makeURLs(publicAddrs),
@@ -118,6 +125,7 @@ func getClusterInfo(operator ops.Operator, cluster ops.Site) (*webClusterInfo, e
 			GravityDownload: gravityDownloadCommand,
 			GravityJoin:     gravityJoinCommands,
 		},
+		License: licenseStatus,
 	}, nil
 }
 
@@ -148,7 +156,7 @@ var (
 		"gravity join {{.node}} --token={{.token}} --role={{.role}}"))
 	// gravityDownloadTpl is the gravity download command template.
 	gravityDownloadTpl = template.Must(template.New("gravity").Parse(
-		`curl -k -H "Authorization: Bearer {{.token}}" https://{{.node}}:{{.port}}/portal/v1/gravity -o gravity`))
+		`curl -k -H "Authorization: Bearer {{.token}}" "https://{{.node}}:{{.port}}/portal/v1/gravity?arch=$(uname -m)" -o gravity`))
 	// tshLoginTpl is the tsh login command template.
 	tshLoginTpl = template.Must(template.New("tsh").Parse(
 		"tsh login --proxy={{.proxyAddr}}"))
@@ -71,6 +71,9 @@ type PodParams struct {
 // Returns a bi-directional websocket stream on success
 //
 func (m *Handler) clusterContainerConnect(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *AuthContext) (interface{}, error) {
+	if ctx.SessionContext == nil {
+		return nil, trace.AccessDenied("terminal sessions require a web session")
+	}
 	q := r.URL.Query()
 	params := q.Get("params")
 	if params == "" {
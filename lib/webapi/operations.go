@@ -22,6 +22,7 @@ import (
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/trace"
 	"github.com/julienschmidt/httprouter"
 
@@ -185,6 +186,82 @@ func (m *Handler) deleteOperation(w http.ResponseWriter, r *http.Request, p http
 	return httplib.OK(), nil
 }
 
+// getScheduledOperations returns the operations scheduled for this site
+//
+// GET /portalapi/v1/sites/:domain/scheduledoperations
+//
+// [{
+//    "id": "1dbb12a2-5123-4385-aeb2-876c8dc76319",
+//    "cluster_name": "example.com",
+//    "type": "operation_update",
+//    "created": "timestamp RFC 3339",
+//    "schedule": {
+//      "window": {"cron": "0 2 * * 6", "duration": 14400000000000}
+//    },
+//    "app": "example.com/app:2.0.0",
+//    "state": "pending"
+// }]
+func (m *Handler) getScheduledOperations(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *AuthContext) (interface{}, error) {
+	siteKey := ops.SiteKey{AccountID: ctx.User.GetAccountID(), SiteDomain: p.ByName("domain")}
+	scheduled, err := ctx.Operator.GetScheduledOperations(r.Context(), siteKey)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return scheduled, nil
+}
+
+type scheduledUpdateInput struct {
+	// Package is the application package to update the site to
+	Package string `json:"package"`
+	// Schedule defines when the update is started
+	Schedule storage.OperationSchedule `json:"schedule"`
+}
+
+// createScheduledOperation schedules the update of the application installed on the site
+//
+// POST /portalapi/v1/sites/:domain/scheduledoperations
+//
+// Input: scheduledUpdateInput
+//
+// Output: storage.ScheduledOperation
+func (m *Handler) createScheduledOperation(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *AuthContext) (interface{}, error) {
+	var input scheduledUpdateInput
+	if err := telehttplib.ReadJSON(r, &input); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	op, err := ctx.Operator.CreateScheduledOperation(r.Context(), ops.CreateScheduledOperationRequest{
+		AccountID:   ctx.User.GetAccountID(),
+		ClusterName: p.ByName("domain"),
+		Type:        ops.OperationUpdate,
+		App:         input.Package,
+		Schedule:    input.Schedule,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return op, nil
+}
+
+// cancelScheduledOperation cancels the pending scheduled operation
+//
+// DELETE /portalapi/v1/sites/:domain/scheduledoperations/:id
+//
+// Output:
+// {
+//   "message": "ok"
+// }
+func (m *Handler) cancelScheduledOperation(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *AuthContext) (interface{}, error) {
+	err := ctx.Operator.CancelScheduledOperation(r.Context(), ops.ScheduledOperationKey{
+		AccountID:   ctx.User.GetAccountID(),
+		ClusterName: p.ByName("domain"),
+		ID:          p.ByName("id"),
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return httplib.OK(), nil
+}
+
 func init() {
 	operationStates[operationInstall] = operationProgress{
 		{Step: 0, Message: "Provisioning Instances"},
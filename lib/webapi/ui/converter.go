@@ -91,6 +91,33 @@ func (c *converter) ToConfigItems(resources []teleservices.UnknownResource) ([]C
 			if err != nil {
 				return nil, trace.Wrap(err)
 			}
+		case storage.KindAlert:
+			alert, err := storage.UnmarshalAlert(resource.Raw)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			item, err = NewConfigItem(resource.Kind, alert.GetName(), alert)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+		case storage.KindAlertTarget:
+			target, err := storage.UnmarshalAlertTarget(resource.Raw)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			item, err = NewConfigItem(resource.Kind, target.GetName(), target)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+		case storage.KindSMTPConfig:
+			config, err := storage.UnmarshalSMTPConfig(resource.Raw)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			item, err = NewConfigItem(resource.Kind, config.GetName(), config)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
 		case "":
 			return nil, trace.BadParameter("resource kind is empty")
 		default:
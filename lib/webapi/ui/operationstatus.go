@@ -0,0 +1,217 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ui
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// OperationStatus describes the status of a cluster operation, such as
+// install, expand, shrink, update or uninstall
+type OperationStatus struct {
+	// ClusterName is cluster name
+	ClusterName string `json:"siteDomain"`
+	// OperationID is ID of the operation
+	OperationID string `json:"operationId"`
+	// Type is the operation type
+	Type string `json:"type,omitempty"`
+	// State is a state of the operation
+	State string `json:"state"`
+	// Step is a step of the operation
+	Step int `json:"step"`
+	// Completion is the operation completion percentage
+	Completion int `json:"completion"`
+	// Message is the last progress message of the operation
+	Message string `json:"message"`
+	// Created is the operation creation time
+	Created time.Time `json:"created,omitempty"`
+	// Updated is the operation last update time
+	Updated time.Time `json:"updated,omitempty"`
+	// Phases is the operation plan phase tree
+	Phases []PhaseStatus `json:"phases,omitempty"`
+	// Servers lists states of the servers affected by the operation
+	Servers []ServerStatus `json:"servers,omitempty"`
+}
+
+// PhaseStatus describes the status of a single operation plan phase
+type PhaseStatus struct {
+	// ID is the phase ID
+	ID string `json:"id"`
+	// Description is the phase description
+	Description string `json:"description,omitempty"`
+	// State is the phase state
+	State string `json:"state"`
+	// Step is the step on the UI progress screen the phase corresponds to
+	Step int `json:"step"`
+	// Updated is the phase last update time
+	Updated time.Time `json:"updated,omitempty"`
+	// Error is the phase execution error message
+	Error string `json:"error,omitempty"`
+	// Phases lists the sub-phases of the phase
+	Phases []PhaseStatus `json:"phases,omitempty"`
+}
+
+// ServerStatus describes the state of a server affected by the operation
+type ServerStatus struct {
+	// Hostname is the server hostname
+	Hostname string `json:"hostname"`
+	// AdvertiseIP is the server advertise IP
+	AdvertiseIP string `json:"advertiseIp"`
+	// Role is the server application role
+	Role string `json:"role"`
+	// State is the state of the operation on this server
+	State string `json:"state"`
+}
+
+// GetOperationStatus returns the status of the specified operation
+func GetOperationStatus(key ops.SiteOperationKey, operator ops.Operator) (*OperationStatus, error) {
+	operation, err := operator.GetSiteOperation(key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	progress, err := operator.GetSiteOperationProgress(key)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	plan, err := operator.GetOperationPlan(key)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	return NewOperationStatus(*operation, progress, plan), nil
+}
+
+// GetUninstallStatus returns a status of uninstall operation. Since 'not-found' cluster indicates that
+// a cluster has been successfully deleted, it's to be treated as such.
+func GetUninstallStatus(accountID string, clusterName string, operator ops.Operator) (*OperationStatus, error) {
+	siteKey := ops.SiteKey{
+		AccountID:  accountID,
+		SiteDomain: clusterName,
+	}
+
+	operation, progressEntry, err := ops.GetLastUninstallOperation(siteKey, operator)
+	if err != nil && trace.IsNotFound(err) {
+		// not found indicates that uninstall operation has been completed
+		return &OperationStatus{
+			ClusterName: clusterName,
+			Type:        ops.OperationUninstall,
+			State:       ops.OperationStateCompleted,
+		}, nil
+	}
+
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return NewOperationStatus(*operation, progressEntry, nil), nil
+}
+
+// NewOperationStatus returns the status of the provided operation
+// given its last progress entry and plan, both of which are optional
+func NewOperationStatus(operation ops.SiteOperation, progress *ops.ProgressEntry, plan *storage.OperationPlan) *OperationStatus {
+	status := &OperationStatus{
+		ClusterName: operation.SiteDomain,
+		OperationID: operation.ID,
+		Type:        operation.Type,
+		State:       operationState(operation),
+		Created:     operation.Created,
+		Updated:     operation.Updated,
+	}
+	if progress != nil {
+		status.State = progress.State
+		status.Step = progress.Step
+		status.Completion = progress.Completion
+		status.Message = progress.Message
+	}
+	if plan != nil {
+		status.Phases = newPhaseStatuses(plan.Phases)
+	}
+	for _, server := range operation.Servers {
+		status.Servers = append(status.Servers, ServerStatus{
+			Hostname:    server.Hostname,
+			AdvertiseIP: server.AdvertiseIP,
+			Role:        server.Role,
+			State:       serverState(operation, server, plan),
+		})
+	}
+	return status
+}
+
+func newPhaseStatuses(phases []storage.OperationPhase) (statuses []PhaseStatus) {
+	for _, phase := range phases {
+		status := PhaseStatus{
+			ID:          phase.ID,
+			Description: phase.Description,
+			State:       phase.GetState(),
+			Step:        phase.Step,
+			Updated:     phase.GetLastUpdateTime(),
+			Phases:      newPhaseStatuses(phase.Phases),
+		}
+		if phase.Error != nil {
+			status.Error = phase.Error.Message
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// serverState returns the combined state of the plan phases that operate
+// on the specified server.
+//
+// If the plan has no phases for the server, the state is derived from
+// the state of the operation
+func serverState(operation ops.SiteOperation, server storage.Server, plan *storage.OperationPlan) string {
+	var phases []storage.OperationPhase
+	if plan != nil {
+		phases = serverPhases(plan.Phases, server.AdvertiseIP)
+	}
+	if len(phases) != 0 {
+		return storage.OperationPhase{Phases: phases}.GetState()
+	}
+	return operationState(operation)
+}
+
+// operationState returns the progress state of the operation
+func operationState(operation ops.SiteOperation) string {
+	switch {
+	case operation.IsCompleted():
+		return ops.ProgressStateCompleted
+	case operation.IsFailed():
+		return ops.ProgressStateFailed
+	default:
+		return ops.ProgressStateInProgress
+	}
+}
+
+// serverPhases returns the leaf phases that operate on the server
+// with the specified advertise IP
+func serverPhases(phases []storage.OperationPhase, advertiseIP string) (result []storage.OperationPhase) {
+	for _, phase := range phases {
+		if phase.HasSubphases() {
+			result = append(result, serverPhases(phase.Phases, advertiseIP)...)
+			continue
+		}
+		if phase.Data != nil && phase.Data.Server != nil && phase.Data.Server.AdvertiseIP == advertiseIP {
+			result = append(result, phase)
+		}
+	}
+	return result
+}
@@ -72,6 +72,12 @@ type userACL struct {
 	Users access `json:"users"`
 	// LogForwarders defines access to log forwarders
 	LogForwarders access `json:"logForwarders"`
+	// Alerts defines access to monitoring alerts
+	Alerts access `json:"alerts"`
+	// AlertTargets defines access to monitoring alert targets
+	AlertTargets access `json:"alertTargets"`
+	// SMTPConfig defines access to SMTP configuration
+	SMTPConfig access `json:"smtpConfig"`
 	// SSHLogins defines access to servers
 	SSHLogins []string `json:"sshLogins"`
 }
@@ -84,6 +90,19 @@ type webContext struct {
 	UserACL userACL `json:"userAcl"`
 	// ServerVersion represents gravity server version
 	ServerVersion version.Info `json:"serverVersion"`
+	// Account describes the account of the user
+	Account Account `json:"account"`
+}
+
+// Account describes the user account consumed by web ui
+type Account struct {
+	// ID is the account ID
+	ID string `json:"id"`
+	// Org is the account organization name
+	Org string `json:"org"`
+	// System is a flag indicating the system account whose users
+	// can access all accounts
+	System bool `json:"system"`
 }
 
 // User describes user role consumed by web ui
@@ -123,6 +142,9 @@ func NewUserACL(storageUser storage.User, userRoles teleservices.RoleSet) userAC
 	licenseAccess := newAccess(userRoles, ctx, storage.KindLicense)
 	repositoryAccess := newAccess(userRoles, ctx, storage.KindRepository)
 	logForwarderAccess := newAccess(userRoles, ctx, storage.KindLogForwarder)
+	alertAccess := newAccess(userRoles, ctx, storage.KindAlert)
+	alertTargetAccess := newAccess(userRoles, ctx, storage.KindAlertTarget)
+	smtpConfigAccess := newAccess(userRoles, ctx, storage.KindSMTPConfig)
 	logins := getLogins(userRoles)
 
 	acl := userACL{
@@ -135,6 +157,9 @@ func NewUserACL(storageUser storage.User, userRoles teleservices.RoleSet) userAC
 		Repositories:    repositoryAccess,
 		Users:           userAccess,
 		LogForwarders:   logForwarderAccess,
+		Alerts:          alertAccess,
+		AlertTargets:    alertTargetAccess,
+		SMTPConfig:      smtpConfigAccess,
 		SSHLogins:       logins,
 	}
 
@@ -152,6 +177,18 @@ func NewWebContext(storageUser storage.User, identity users.Identity) (*webConte
 		ServerVersion: version.Get(),
 		User:          NewUserByStorageUser(storageUser),
 		UserACL:       NewUserACL(storageUser, userRoles),
+		Account: Account{
+			ID:     storageUser.GetAccountID(),
+			System: users.IsSystemAccountUser(storageUser),
+		},
+	}
+
+	account, err := identity.GetAccount(storageUser.GetAccountID())
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if account != nil {
+		webCtx.Account.Org = account.Org
 	}
 
 	return &webCtx, nil
@@ -160,8 +197,9 @@ func NewWebContext(storageUser storage.User, identity users.Identity) (*webConte
 // NewUserByInvite creates an instance of UIUser using invite
 func NewUserByInvite(invite storage.UserInvite) User {
 	return User{
-		AuthType: authLocal,
-		Email:    invite.Name,
+		AuthType:  authLocal,
+		AccountID: invite.AccountID,
+		Email:     invite.Name,
 		Status:   inviteStatus,
 		Created:  invite.Created,
 		Roles:    invite.Roles,
@@ -154,11 +154,11 @@ func (c Config) Check() error {
 type Plugin interface {
 	// Resources returns resource controller
 	Resources(*AuthContext) (resources.Resources, error)
-	// CallbackHandler is the OAuth2 provider callback handler
+	// CallbackHandler is the OAuth2 and SAML provider callback handler
 	CallbackHandler(http.ResponseWriter, *http.Request, CallbackParams) error
 }
 
-// CallbackParams combines necessary parameters for OAuth2 callback handler
+// CallbackParams combines necessary parameters for OAuth2 and SAML callback handler
 type CallbackParams struct {
 	// Username is the name of the authenticated user
 	Username string
@@ -199,6 +199,9 @@ func NewAPI(cfg Config) (*Handler, error) {
 	// OAuth2 callbacks
 	h.GET("/github/callback", telehttplib.MakeHandler(h.githubCallback))
 
+	// SAML assertion consumer service
+	h.POST("/saml/callback", telehttplib.MakeHandler(h.samlCallback))
+
 	// TODO(r0mant): Delete legacy handlers when 6.0 user interface is merged.
 	h.GET("/accounts/existing/invites", h.needsAuth(h.getUserInvites))
 	h.DELETE("/accounts/existing/invites/:username", h.needsAuth(h.deleteUserInvite))
@@ -236,6 +239,9 @@ func NewAPI(cfg Config) (*Handler, error) {
 	h.GET("/domains/:domain_name", h.needsAuth(h.validateDomainName))
 
 	h.GET("/sites/:domain/operations/:operation_id/progress", h.needsAuth(h.getSiteOperationProgress))
+	h.GET("/sites/:domain/operations/:operation_id/progress/stream", h.needsAuth(h.watchSiteOperationProgress))
+	h.GET("/sites/:domain/operations/:operation_id/status", h.needsAuth(h.getSiteOperationStatus))
+	h.GET("/sites/:domain/operations/:operation_id/logs/phases", h.needsAuth(h.getOperationPhaseLogs))
 
 	// Operations
 	h.GET("/sites/:domain/operations/:operation_id/agent", h.needsAuth(h.agentReport))
@@ -244,6 +250,16 @@ func NewAPI(cfg Config) (*Handler, error) {
 	h.GET("/sites/:domain/operations", h.needsAuth(h.getOperations))
 	h.POST("/sites/:domain/operations/:operation_id/prechecks", h.needsAuth(h.validateServers))
 
+	// Scheduled operations
+	h.GET("/sites/:domain/scheduledoperations", h.needsAuth(h.getScheduledOperations))
+	h.POST("/sites/:domain/scheduledoperations", h.needsAuth(h.createScheduledOperation))
+	h.DELETE("/sites/:domain/scheduledoperations/:id", h.needsAuth(h.cancelScheduledOperation))
+
+	// Accounts
+	h.GET("/hub/accounts", h.needsAuth(h.getAccounts))
+	h.POST("/hub/accounts", h.needsAuth(h.createAccount))
+	h.DELETE("/hub/accounts/:id", h.needsAuth(h.deleteAccount))
+
 	// Sites
 	h.POST("/sites", h.needsAuth(h.createSite))
 	h.POST("/sites/:domain/expand", h.needsAuth(h.expandSite))
@@ -265,6 +281,13 @@ func NewAPI(cfg Config) (*Handler, error) {
 	h.GET("/sites/:domain/monitoring/retention", h.needsAuth(h.getRetentionPolicies))
 	h.PUT("/sites/:domain/monitoring/retention", h.needsAuth(h.updateRetentionPolicy))
 
+	// Registry
+	h.GET("/sites/:domain/registry", h.needsAuth(h.getRegistryStatus))
+	h.GET("/sites/:domain/health", h.needsAuth(h.getClusterHealth))
+
+	// Package integrity
+	h.GET("/sites/:domain/packages/verification", h.needsAuth(h.getPackageVerificationStatus))
+
 	// Certificates
 	h.GET("/sites/:domain/certificate", h.needsAuth(h.getCertificate))
 	h.PUT("/sites/:domain/certificate", h.needsAuth(h.updateCertificate))
@@ -308,7 +331,7 @@ func (m *Handler) GetConfig() Config {
 	return m.cfg
 }
 
-// CallbackHandler is the generic OAuth2 provider callback handler
+// CallbackHandler is the generic OAuth2 and SAML provider callback handler
 func (m *Handler) CallbackHandler(w http.ResponseWriter, r *http.Request, p CallbackParams) error {
 	if p.CreateWebSession {
 		err := csrf.VerifyToken(p.CSRFToken, r)
@@ -372,6 +395,39 @@ func (m *Handler) githubCallback(w http.ResponseWriter, r *http.Request, p httpr
 	})
 }
 
+// samlCallback is the SAML assertion consumer service that handles the response
+// of the SAML identity provider posted during authentication flow
+//
+//   POST /saml/callback
+//
+func (m *Handler) samlCallback(w http.ResponseWriter, r *http.Request, p httprouter.Params) (interface{}, error) {
+	var samlResponse string
+	err := form.Parse(r, form.String("SAMLResponse", &samlResponse, form.Required()))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result, err := m.cfg.Auth.ValidateSAMLResponse(samlResponse)
+	if err != nil {
+		m.Warnf("Error validating SAML response: %v.", err)
+		http.Redirect(w, r, "/web/msg/error/login_failed", http.StatusFound)
+		return nil, nil
+	}
+	m.Infof("SAML callback: %v %v.", result.Username, result.Identity)
+	return nil, m.plugin.CallbackHandler(w, r, CallbackParams{
+		Username:          result.Username,
+		Identity:          result.Identity,
+		Session:           result.Session,
+		Cert:              result.Cert,
+		TLSCert:           result.TLSCert,
+		HostSigners:       result.HostSigners,
+		Type:              result.Req.Type,
+		CreateWebSession:  result.Req.CreateWebSession,
+		CSRFToken:         result.Req.CSRFToken,
+		PublicKey:         result.Req.PublicKey,
+		ClientRedirectURL: result.Req.ClientRedirectURL,
+	})
+}
+
 func (m *Handler) getUserStatus(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *AuthContext) (interface{}, error) {
 	return httplib.OK(), nil
 }
@@ -660,6 +716,12 @@ func (m *Handler) GetHandlerContext(w http.ResponseWriter, r *http.Request) (*Au
 	if authCreds.Type == httplib.AuthBasic {
 		return nil, trace.AccessDenied("method not supported")
 	}
+	cookie, err := r.Cookie("session")
+	if err != nil || cookie == nil || cookie.Value == "" {
+		// requests without a web session are made by automation
+		// and are authenticated with API keys
+		return m.getAPIKeyHandlerContext(r, *authCreds)
+	}
 	session, err := m.cfg.WebAuthenticator(w, r, true)
 	if err != nil {
 		return nil, trace.AccessDenied("bad username or password")
@@ -685,6 +747,26 @@ func (m *Handler) GetHandlerContext(w http.ResponseWriter, r *http.Request) (*Au
 	}, nil
 }
 
+// getAPIKeyHandlerContext authenticates the request with the API key
+// and returns an appropriate handler context
+func (m *Handler) getAPIKeyHandlerContext(r *http.Request, authCreds httplib.AuthCreds) (*AuthContext, error) {
+	user, checker, err := m.cfg.Identity.AuthenticateUser(authCreds)
+	if err != nil {
+		log.Debugf("Authentication error: %v.", err)
+		// hide the error from remote user for security reasons
+		return nil, trace.AccessDenied("bad username or password")
+	}
+	return &AuthContext{
+		Context:      context.WithValue(r.Context(), constants.UserContext, user.GetName()),
+		User:         user,
+		Operator:     ops.OperatorWithACL(m.cfg.Operator, m.cfg.Identity, user, checker),
+		Applications: app.ApplicationsWithACL(m.cfg.Applications, m.cfg.Identity, user, checker),
+		Packages:     pack.PackagesWithACL(m.cfg.Packages, m.cfg.Identity, user, checker),
+		Identity:     users.IdentityWithACL(m.cfg.Backend, m.cfg.Identity, user, checker),
+		Checker:      checker,
+	}, nil
+}
+
 func (m *Handler) needsAuth(fn authenticatedHandler) httprouter.Handle {
 	return telehttplib.MakeHandler(func(w http.ResponseWriter, r *http.Request, params httprouter.Params) (interface{}, error) {
 		context, err := m.GetHandlerContext(w, r)
@@ -880,6 +962,87 @@ func (m *Handler) getSiteOperationProgress(w http.ResponseWriter, r *http.Reques
 	return progressEntry, nil
 }
 
+// getSiteOperationStatus returns the status of this operation: its progress,
+// the state of its plan phases and of the servers it affects.
+//
+// The same status resource is returned for install, expand, shrink, update
+// and uninstall operations
+//
+// GET /sites/:domain/operations/:operation_id/status
+func (m *Handler) getSiteOperationStatus(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *AuthContext) (interface{}, error) {
+	siteDomain, operationID := p[0].Value, p[1].Value
+	site, err := context.Operator.GetSiteByDomain(siteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	status, err := ui.GetOperationStatus(ops.SiteOperationKey{
+		AccountID:   site.AccountID,
+		SiteDomain:  site.Domain,
+		OperationID: operationID,
+	}, context.Operator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return status, nil
+}
+
+// watchSiteOperationProgress streams progress entries of this operation
+// over a web socket connection
+//
+// GET /sites/:domain/operations/:operation_id/progress/stream
+//
+// Each progress entry is sent as a separate JSON-encoded frame when it changes.
+// The stream is closed once the operation completes
+func (m *Handler) watchSiteOperationProgress(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *AuthContext) (interface{}, error) {
+	siteDomain, operationID := p[0].Value, p[1].Value
+	site, err := ctx.Operator.GetSiteByDomain(siteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	opKey := ops.SiteOperationKey{
+		AccountID:   site.AccountID,
+		SiteDomain:  site.Domain,
+		OperationID: operationID,
+	}
+	ws := &httplib.WebSocketJSONWriter{
+		Stream: func(streamCtx context.Context, send func(interface{}) error) error {
+			return ops.SendOperationProgress(streamCtx, opKey, ctx.Operator,
+				func(entry ops.ProgressEntry) error {
+					return send(entry)
+				})
+		},
+	}
+	ws.Handler().ServeHTTP(w, r)
+	return nil, nil
+}
+
+// getOperationPhaseLogs returns the log entries recorded while executing
+// the specified phase of this operation and any of its subphases
+//
+// GET /sites/:domain/operations/:operation_id/logs/phases?phase=<phase ID>
+//
+// Output: []ops.LogEntry
+func (m *Handler) getOperationPhaseLogs(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *AuthContext) (interface{}, error) {
+	siteDomain, operationID := p[0].Value, p[1].Value
+	phaseID := r.URL.Query().Get("phase")
+	if phaseID == "" {
+		return nil, trace.BadParameter("missing phase ID")
+	}
+	site, err := context.Operator.GetSiteByDomain(siteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	entries, err := context.Operator.GetOperationPhaseLogs(ops.SiteOperationKey{
+		AccountID:   site.AccountID,
+		SiteDomain:  site.Domain,
+		OperationID: operationID,
+	}, phaseID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return entries, nil
+}
+
 // agentReport provides update on the specified active operation
 //
 // GET /sites/:domain/portalapi/v1/operations/:operation_id/agent
@@ -1737,9 +1900,11 @@ func (m *Handler) getSiteEndpoints(w http.ResponseWriter, r *http.Request, p htt
 //
 //   report.tar
 func (m *Handler) getSiteReport(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *AuthContext) (interface{}, error) {
-	reader, err := context.Operator.GetSiteReport(ops.SiteKey{
-		AccountID:  context.User.GetAccountID(),
-		SiteDomain: p.ByName("domain"),
+	reader, err := context.Operator.GetSiteReport(ops.GetClusterReportRequest{
+		SiteKey: ops.SiteKey{
+			AccountID:  context.User.GetAccountID(),
+			SiteDomain: p.ByName("domain"),
+		},
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -2042,6 +2207,62 @@ func (m *Handler) getRetentionPolicies(w http.ResponseWriter, r *http.Request, p
 	})
 }
 
+// getRegistryStatus returns the health and the storage usage of the cluster registry
+//
+//   GET /sites/:domain/registry
+//
+// Input:
+//
+//   -
+//
+// Output:
+//
+//   ops.RegistryStatus
+func (m *Handler) getRegistryStatus(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *AuthContext) (interface{}, error) {
+	return context.Operator.GetRegistryStatus(r.Context(), ops.SiteKey{
+		AccountID:  context.User.GetAccountID(),
+		SiteDomain: p.ByName("domain"),
+	})
+}
+
+// getPackageVerificationStatus returns the results of the last integrity
+// verification of the cluster packages
+//
+//   GET /sites/:domain/packages/verification
+//
+// Input:
+//
+//   -
+//
+// Output:
+//
+//   ops.PackageVerificationStatus
+func (m *Handler) getPackageVerificationStatus(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *AuthContext) (interface{}, error) {
+	return context.Operator.GetPackageVerificationStatus(r.Context(), ops.SiteKey{
+		AccountID:  context.User.GetAccountID(),
+		SiteDomain: p.ByName("domain"),
+	})
+}
+
+// getClusterHealth returns the health of cluster nodes along with the reason
+// code, remediation hint and the logs command of each failed health probe
+//
+//   GET /sites/:domain/health
+//
+// Input:
+//
+//   -
+//
+// Output:
+//
+//   ops.ClusterHealth
+func (m *Handler) getClusterHealth(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *AuthContext) (interface{}, error) {
+	return context.Operator.GetClusterHealth(r.Context(), ops.SiteKey{
+		AccountID:  context.User.GetAccountID(),
+		SiteDomain: p.ByName("domain"),
+	})
+}
+
 // updateRetentionPolicy updates site's retention policies
 //
 //   PUT /sites/:domain/monitoring/retention
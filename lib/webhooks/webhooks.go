@@ -0,0 +1,310 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhooks implements delivery of cluster operation events
+// to the HTTPS endpoints registered with webhook resources.
+//
+// Each event is posted as JSON. If the webhook has a secret, the payload
+// is signed with HMAC-SHA256 and the signature is sent in the
+// X-Gravity-Signature header as "sha256=<hex digest>".
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/httplib"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/cenkalti/backoff"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/pborman/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Event is the payload posted to webhooks
+type Event struct {
+	// ID is the unique event ID
+	ID string `json:"id"`
+	// Type is the event type, e.g. operation.started
+	Type string `json:"type"`
+	// Time is the event timestamp
+	Time time.Time `json:"time"`
+	// Cluster is the name of the cluster the event happened on
+	Cluster string `json:"cluster"`
+	// Operation describes the operation the event is for
+	Operation Operation `json:"operation"`
+	// Phase describes the plan phase that changed its state.
+	// Only set for operation.phase_changed events
+	Phase *Phase `json:"phase,omitempty"`
+}
+
+// Operation describes an operation in the event payload
+type Operation struct {
+	// ID is the operation ID
+	ID string `json:"id"`
+	// Type is the operation type
+	Type string `json:"type"`
+	// State is the operation state
+	State string `json:"state"`
+	// CreatedBy is the user who started the operation
+	CreatedBy string `json:"created_by,omitempty"`
+	// Created is the operation creation timestamp
+	Created time.Time `json:"created"`
+}
+
+// Phase describes a plan phase in the event payload
+type Phase struct {
+	// ID is the phase ID
+	ID string `json:"id"`
+	// State is the state the phase moved into
+	State string `json:"state"`
+	// Error is the phase error if the phase has failed
+	Error string `json:"error,omitempty"`
+}
+
+// NewOperationEvent returns a new event of the specified type for the operation
+func NewOperationEvent(eventType string, operation ops.SiteOperation) Event {
+	return Event{
+		Type:      eventType,
+		Cluster:   operation.SiteDomain,
+		Operation: newOperation(operation),
+	}
+}
+
+// NewPhaseEvent returns a new event about the operation plan phase
+// changing its state
+func NewPhaseEvent(operation ops.SiteOperation, change storage.PlanChange) Event {
+	phase := &Phase{
+		ID:    change.PhaseID,
+		State: change.NewState,
+	}
+	if change.Error != nil {
+		phase.Error = phaseError(*change.Error)
+	}
+	return Event{
+		Type:      storage.WebhookEventOperationPhaseChanged,
+		Time:      change.Created,
+		Cluster:   operation.SiteDomain,
+		Operation: newOperation(operation),
+		Phase:     phase,
+	}
+}
+
+// EventTypeForOperation returns the type of the event for the current
+// state of the specified operation
+func EventTypeForOperation(operation ops.SiteOperation) string {
+	switch {
+	case operation.IsCompleted():
+		return storage.WebhookEventOperationCompleted
+	case operation.IsFailed():
+		return storage.WebhookEventOperationFailed
+	default:
+		return storage.WebhookEventOperationStarted
+	}
+}
+
+// Sign returns the signature of the payload computed with the specified secret
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Getter returns the registered webhooks
+type Getter interface {
+	// GetWebhooks returns all webhooks of the specified cluster
+	GetWebhooks(clusterName string) ([]storage.Webhook, error)
+}
+
+// Config defines the notifier configuration
+type Config struct {
+	// Webhooks returns the webhooks to notify
+	Webhooks Getter
+	// Timeout is how long the delivery of a single event is retried
+	Timeout time.Duration
+	// Clock is used to timestamp events
+	Clock clockwork.Clock
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Webhooks == nil {
+		return trace.BadParameter("missing Webhooks")
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaults.WebhookDeliveryTimeout
+	}
+	if c.Clock == nil {
+		c.Clock = clockwork.NewRealClock()
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "webhooks")
+	}
+	return nil
+}
+
+// Notifier delivers events to webhooks
+type Notifier struct {
+	Config
+}
+
+// New returns a new notifier
+func New(config Config) (*Notifier, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Notifier{Config: config}, nil
+}
+
+// Notify sends the event to all webhooks of the event's cluster
+// subscribed to its type in the background.
+//
+// Failure to deliver the event is logged but not returned
+func (r *Notifier) Notify(event Event) {
+	if event.ID == "" {
+		event.ID = uuid.New()
+	}
+	if event.Time.IsZero() {
+		event.Time = r.Clock.Now().UTC()
+	}
+	// Webhooks only receive the events of the cluster they are registered for
+	webhooks, err := r.Webhooks.GetWebhooks(event.Cluster)
+	if err != nil {
+		r.Warnf("Failed to query webhooks: %v.", trace.DebugReport(err))
+		return
+	}
+	for _, webhook := range webhooks {
+		if !webhook.Matches(event.Type) {
+			continue
+		}
+		go func(webhook storage.Webhook) {
+			if err := r.Deliver(context.Background(), webhook, event); err != nil {
+				r.Warnf("Failed to deliver event %v to webhook %v: %v.",
+					event.ID, webhook.GetName(), trace.DebugReport(err))
+			}
+		}(webhook)
+	}
+}
+
+// Deliver posts the event to the specified webhook retrying on transient failures
+func (r *Notifier) Deliver(ctx context.Context, webhook storage.Webhook, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	options := []httplib.ClientOption{httplib.WithTimeout(defaults.WebhookRequestTimeout)}
+	if webhook.GetCAData() != "" {
+		options = append(options, httplib.WithCA([]byte(webhook.GetCAData())))
+	}
+	client := httplib.GetClient(false, options...)
+	return utils.RetryWithInterval(ctx, utils.NewExponentialBackOff(r.Timeout), func() error {
+		err := post(ctx, client, webhook, event, payload)
+		if err != nil && !isRetryable(err) {
+			return &backoff.PermanentError{Err: err}
+		}
+		return trace.Wrap(err)
+	})
+}
+
+func post(ctx context.Context, client *http.Client, webhook storage.Webhook, event Event, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, webhook.GetURL(), bytes.NewReader(payload))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(DeliveryHeader, event.ID)
+	if webhook.GetSecret() != "" {
+		req.Header.Set(SignatureHeader, Sign(webhook.GetSecret(), payload))
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &deliveryError{url: webhook.GetURL(), status: resp.Status, code: resp.StatusCode}
+	}
+	return nil
+}
+
+// isRetryable returns true if the delivery failed with the error that
+// can be retried: network errors, server errors and rate limiting
+func isRetryable(err error) bool {
+	deliveryErr, ok := trace.Unwrap(err).(*deliveryError)
+	if !ok {
+		return true
+	}
+	return deliveryErr.code >= http.StatusInternalServerError ||
+		deliveryErr.code == http.StatusTooManyRequests
+}
+
+type deliveryError struct {
+	url    string
+	status string
+	code   int
+}
+
+// Error returns the error message
+func (r *deliveryError) Error() string {
+	return "webhook " + r.url + " responded with " + r.status
+}
+
+// phaseError returns the error message from the phase error
+func phaseError(err trace.RawTrace) string {
+	var message struct {
+		// Message is the error message
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(err.Err, &message) == nil && message.Message != "" {
+		return message.Message
+	}
+	return err.Message
+}
+
+func newOperation(operation ops.SiteOperation) Operation {
+	return Operation{
+		ID:        operation.ID,
+		Type:      operation.Type,
+		State:     operation.State,
+		CreatedBy: operation.CreatedBy,
+		Created:   operation.Created,
+	}
+}
+
+const (
+	// EventHeader is the header with the event type
+	EventHeader = "X-Gravity-Event"
+	// DeliveryHeader is the header with the unique event ID
+	DeliveryHeader = "X-Gravity-Delivery"
+	// SignatureHeader is the header with the payload signature
+	SignatureHeader = "X-Gravity-Signature"
+
+	signaturePrefix = "sha256="
+)
@@ -0,0 +1,215 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	. "gopkg.in/check.v1"
+)
+
+func TestWebhooks(t *testing.T) { TestingT(t) }
+
+type WebhooksSuite struct {
+	clock clockwork.FakeClock
+}
+
+var _ = Suite(&WebhooksSuite{})
+
+func (s *WebhooksSuite) SetUpTest(c *C) {
+	s.clock = clockwork.NewFakeClockAt(time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC))
+}
+
+func (s *WebhooksSuite) TestSign(c *C) {
+	c.Assert(Sign("secret", []byte(`{"id":"1"}`)), Equals,
+		"sha256=6146142a2ce0159e84c0767881e4ec80bc397da62526e7d19f70795eb79460c0")
+}
+
+func (s *WebhooksSuite) TestDeliversSignedEvent(c *C) {
+	received := make(chan *http.Request, 1)
+	payloads := make(chan []byte, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- r
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	notifier := s.newNotifier(c)
+	event := NewOperationEvent(storage.WebhookEventOperationStarted, ops.SiteOperation{
+		ID:         "op-1",
+		SiteDomain: "example.com",
+		Type:       ops.OperationUpdate,
+		State:      ops.OperationStateUpdateInProgress,
+		CreatedBy:  "alice@example.com",
+	})
+	event.ID = "event-1"
+	err := notifier.Deliver(context.TODO(), newWebhook(c, server, "secret"), event)
+	c.Assert(err, IsNil)
+
+	r := <-received
+	payload := <-payloads
+	c.Assert(r.Header.Get(EventHeader), Equals, storage.WebhookEventOperationStarted)
+	c.Assert(r.Header.Get(DeliveryHeader), Equals, "event-1")
+	c.Assert(r.Header.Get(SignatureHeader), Equals, Sign("secret", payload))
+	var decoded Event
+	c.Assert(json.Unmarshal(payload, &decoded), IsNil)
+	c.Assert(decoded.Cluster, Equals, "example.com")
+	c.Assert(decoded.Operation.ID, Equals, "op-1")
+	c.Assert(decoded.Operation.CreatedBy, Equals, "alice@example.com")
+	c.Assert(decoded.Phase, IsNil)
+}
+
+func (s *WebhooksSuite) TestRetriesServerErrors(c *C) {
+	var attempts int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	notifier := s.newNotifier(c)
+	err := notifier.Deliver(context.TODO(), newWebhook(c, server, ""), Event{ID: "event-1"})
+	c.Assert(err, IsNil)
+	c.Assert(atomic.LoadInt32(&attempts), Equals, int32(3))
+}
+
+func (s *WebhooksSuite) TestDoesNotRetryClientErrors(c *C) {
+	var attempts int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	notifier := s.newNotifier(c)
+	err := notifier.Deliver(context.TODO(), newWebhook(c, server, ""), Event{ID: "event-1"})
+	c.Assert(err, NotNil)
+	c.Assert(atomic.LoadInt32(&attempts), Equals, int32(1))
+}
+
+func (s *WebhooksSuite) TestNotifiesSubscribedWebhooks(c *C) {
+	received := make(chan Event, 2)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	all := newWebhook(c, server, "")
+	failures := newWebhook(c, server, "")
+	failures.(*storage.WebhookV2).Spec.Events = []string{storage.WebhookEventOperationFailed}
+	// The webhook of another cluster should not receive the event
+	other := newWebhook(c, server, "")
+	notifier, err := New(Config{
+		Webhooks: clusterWebhooks{
+			"example.com":       {all, failures},
+			"other.example.com": {other},
+		},
+		Clock:    s.clock,
+	})
+	c.Assert(err, IsNil)
+
+	notifier.Notify(NewPhaseEvent(ops.SiteOperation{ID: "op-1", SiteDomain: "example.com"},
+		storage.PlanChange{
+			PhaseID:  "/masters",
+			NewState: storage.OperationPhaseStateFailed,
+			Created:  s.clock.Now(),
+			Error:    utils.ToRawTrace(trace.BadParameter("phase failed").(trace.Error)),
+		}))
+
+	select {
+	case event := <-received:
+		c.Assert(event.ID, Not(Equals), "")
+		c.Assert(event.Type, Equals, storage.WebhookEventOperationPhaseChanged)
+		c.Assert(event.Phase, DeepEquals, &Phase{
+			ID:    "/masters",
+			State: storage.OperationPhaseStateFailed,
+			Error: "phase failed",
+		})
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for event")
+	}
+	select {
+	case event := <-received:
+		c.Fatalf("unexpected event %v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func (s *WebhooksSuite) TestEventTypeForOperation(c *C) {
+	c.Assert(EventTypeForOperation(ops.SiteOperation{State: ops.OperationStateExpandPrechecks}),
+		Equals, storage.WebhookEventOperationStarted)
+	c.Assert(EventTypeForOperation(ops.SiteOperation{State: ops.OperationStateCompleted}),
+		Equals, storage.WebhookEventOperationCompleted)
+	c.Assert(EventTypeForOperation(ops.SiteOperation{State: ops.OperationStateFailed}),
+		Equals, storage.WebhookEventOperationFailed)
+}
+
+func (s *WebhooksSuite) newNotifier(c *C) *Notifier {
+	notifier, err := New(Config{
+		Webhooks: clusterWebhooks{},
+		Timeout:  time.Minute,
+		Clock:    s.clock,
+	})
+	c.Assert(err, IsNil)
+	return notifier
+}
+
+func newWebhook(c *C, server *httptest.Server, secret string) storage.Webhook {
+	caData := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	})
+	webhook := storage.NewWebhook("test", storage.WebhookSpecV2{
+		URL:    server.URL,
+		Secret: secret,
+		CAData: string(caData),
+	})
+	c.Assert(webhook.CheckAndSetDefaults(), IsNil)
+	return webhook
+}
+
+// clusterWebhooks maps cluster names to their webhooks
+type clusterWebhooks map[string][]storage.Webhook
+
+func (r clusterWebhooks) GetWebhooks(clusterName string) ([]storage.Webhook, error) {
+	return r[clusterName], nil
+}
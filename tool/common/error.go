@@ -38,3 +38,15 @@ func ProcessRunError(runErr error) error {
 	}
 	return runErr
 }
+
+// ExitCode returns the exit code the command should terminate with
+// after failing with the specified error
+func ExitCode(err error) int {
+	if exitErr, ok := trace.Unwrap(err).(*utils.ExitCodeError); ok && exitErr.Code != 0 {
+		return exitErr.Code
+	}
+	return defaultExitCode
+}
+
+// defaultExitCode is the exit code of a failed command
+const defaultExitCode = 255
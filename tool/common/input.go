@@ -24,7 +24,7 @@ import (
 	"os"
 	"strings"
 
-	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/utils"
 
 	teleutils "github.com/gravitational/teleport/lib/utils"
 	"github.com/gravitational/trace"
@@ -57,9 +57,9 @@ func GetReader(filename string) (io.ReadCloser, error) {
 	return teleutils.OpenFile(filename)
 }
 
-// Format is the CLI parser for output format flag
-func Format(s kingpin.Settings) *constants.Format {
-	var f constants.Format
-	s.SetValue(&f)
-	return &f
+// Capacity is the CLI parser for capacity flags in a human friendly form, e.g. "10GB"
+func Capacity(s kingpin.Settings) *utils.Capacity {
+	var c utils.Capacity
+	s.SetValue(&c)
+	return &c
 }
@@ -0,0 +1,148 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gravitational/gravity/lib/constants"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Output registers the application-wide output format flag used by all
+// listing and status commands of app.
+// The text format is meant for humans while json and yaml provide stable structures
+// for automation.
+//
+// Commands that write files, like 'tele build', accept the path of the file
+// with the same flag for compatibility with the flag they used to define
+func Output(app *kingpin.Application) *OutputFlag {
+	f := &OutputFlag{Format: constants.EncodingText}
+	app.Flag("output", fmt.Sprintf("Output format of listing and status commands, one of: %v, or the output path of commands that write files",
+		constants.OutputFormats)).Short('o').SetValue(f)
+	return f
+}
+
+// DeprecatedFormat registers the hidden --format flag that commands
+// accepted before the application-wide output flag as an alias for it
+func DeprecatedFormat(cmd *kingpin.CmdClause, output *OutputFlag) {
+	cmd.Flag("format", "Deprecated, use --output").Hidden().
+		PreAction(func(*kingpin.ParseContext) error {
+			fmt.Fprintln(os.Stderr, "Flag --format is deprecated, use --output instead.")
+			return nil
+		}).
+		SetValue(outputFormatValue{output})
+}
+
+// PrintStructured writes the specified value to w in the specified
+// machine-readable format.
+// The structure must define json tags for its fields which are also
+// used for the yaml encoding
+func PrintStructured(w io.Writer, format constants.Format, v interface{}) error {
+	var bytes []byte
+	var err error
+	switch format {
+	case constants.EncodingJSON:
+		bytes, err = json.MarshalIndent(v, "", "  ")
+		if err == nil {
+			bytes = append(bytes, '\n')
+		}
+	case constants.EncodingYAML:
+		bytes, err = yaml.Marshal(v)
+	default:
+		return trace.BadParameter("unsupported output format %q, supported are: %v",
+			format, []constants.Format{constants.EncodingJSON, constants.EncodingYAML})
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = w.Write(bytes)
+	return trace.Wrap(err)
+}
+
+// OutputFlag is the value of the application-wide output flag
+type OutputFlag struct {
+	// Format is the output format, text by default
+	Format constants.Format
+	// value is the value the flag was set to
+	value string
+}
+
+// Set records the value and sets the format if the value
+// is one of the recognized output formats
+func (f *OutputFlag) Set(v string) error {
+	f.value = v
+	if isOutputFormat(v) {
+		f.Format = constants.Format(v)
+	}
+	return nil
+}
+
+// String returns the value of the flag
+func (f *OutputFlag) String() string {
+	return f.value
+}
+
+// Check returns an error if the flag is set to a value other than
+// one of the recognized output formats
+func (f *OutputFlag) Check() error {
+	if f.value != "" && !isOutputFormat(f.value) {
+		return trace.BadParameter("unknown output format %q, supported are: %v",
+			f.value, constants.OutputFormats)
+	}
+	return nil
+}
+
+// Path returns the path of the output file of commands that write files:
+// path if it has been specified with the command's own flag and
+// the value of the output flag otherwise
+func (f *OutputFlag) Path(path string) string {
+	if path != "" {
+		return path
+	}
+	return f.value
+}
+
+// outputFormatValue sets the format of the output flag and
+// only accepts the recognized output formats
+type outputFormatValue struct {
+	*OutputFlag
+}
+
+// Set validates and sets the format value
+func (f outputFormatValue) Set(v string) error {
+	if !isOutputFormat(v) {
+		return trace.BadParameter("unknown output format %q, supported are: %v",
+			v, constants.OutputFormats)
+	}
+	return trace.Wrap(f.OutputFlag.Set(v))
+}
+
+func isOutputFormat(v string) bool {
+	for _, format := range constants.OutputFormats {
+		if v == string(format) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"github.com/gravitational/gravity/lib/utils"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// ProxyFlags defines the flags that override the HTTP proxy
+// settings of the environment for a single command
+type ProxyFlags struct {
+	// HTTPProxy is the proxy for HTTP requests
+	HTTPProxy *string
+	// HTTPSProxy is the proxy for HTTPS requests
+	HTTPSProxy *string
+	// NoProxy lists hosts that are accessed without the proxy
+	NoProxy *string
+}
+
+// Proxy registers the HTTP proxy flags with the specified command
+func Proxy(cmd *kingpin.CmdClause) ProxyFlags {
+	return ProxyFlags{
+		HTTPProxy:  cmd.Flag("http-proxy", "Proxy for HTTP requests, overrides HTTP_PROXY environment variable").String(),
+		HTTPSProxy: cmd.Flag("https-proxy", "Proxy for HTTPS requests, overrides HTTPS_PROXY environment variable").String(),
+		NoProxy:    cmd.Flag("no-proxy", "Comma-separated list of hosts, domains and CIDR ranges to access without the proxy, overrides NO_PROXY environment variable").String(),
+	}
+}
+
+// Config returns the proxy settings of the environment
+// overridden with the values of the flags
+func (r ProxyFlags) Config() utils.ProxyConfig {
+	return utils.ProxyConfigFromEnvironment().Override(utils.ProxyConfig{
+		HTTPProxy:  *r.HTTPProxy,
+		HTTPSProxy: *r.HTTPSProxy,
+		NoProxy:    *r.NoProxy,
+	})
+}
@@ -20,21 +20,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 
 	appservice "github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/docker"
 	"github.com/gravitational/gravity/lib/app/resources"
 	"github.com/gravitational/gravity/lib/app/service"
 	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/systeminfo"
 	"github.com/gravitational/gravity/lib/utils"
@@ -44,28 +47,43 @@ import (
 	"github.com/gravitational/trace"
 )
 
-// outputAppHook runs an application hook and prints the output
-func outputAppHook(env *localenv.LocalEnvironment, req appservice.HookRunRequest) error {
-	out, err := runAppHook(env, req)
-	fmt.Printf("%s", out)
-	return trace.Wrap(err)
-}
-
-// runAppHook runs an application hook specified with hook
-func runAppHook(env *localenv.LocalEnvironment, req appservice.HookRunRequest) (string, error) {
+// runAppHook runs the specified application hook and streams its output
+// to the console until the hook job completes.
+//
+// If the hook fails, the returned error carries the exit code of the
+// failed hook container
+func runAppHook(env *localenv.LocalEnvironment, req appservice.HookRunRequest) error {
 	registryURL, err := localAppEnviron()
 	if err != nil {
-		return "", trace.Wrap(err)
+		return trace.Wrap(err)
 	}
 	apps, err := env.AppServiceLocal(localenv.AppConfig{RegistryURL: registryURL})
 	if err != nil {
-		return "", trace.Wrap(err)
+		return trace.Wrap(err)
+	}
+	_, err = appservice.CheckHasAppHook(apps, req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	utils.WatchTerminationSignals(ctx, cancel, utils.StopperFunc(func(context.Context) error {
+		return nil
+	}), log)
+	ref, err := appservice.StreamAppHook(ctx, apps, req, utils.NopWriteCloser(os.Stdout))
+	if ref != nil {
+		errDelete := apps.DeleteAppHookJob(context.Background(), appservice.DeleteAppHookJobRequest{
+			HookRef: *ref,
+			Cascade: true,
+		})
+		if errDelete != nil {
+			log.Warnf("Failed to delete hook job %v: %v.", ref, trace.DebugReport(errDelete))
+		}
 	}
-	_, out, err := appservice.RunAppHook(context.TODO(), apps, req)
 	if err != nil {
-		return string(out), trace.Wrap(err, "failed to run hook: %s", out)
+		return trace.Wrap(err, "%v hook of %v failed", req.Hook, req.Application)
 	}
-	return string(out), nil
+	return nil
 }
 
 // statusApp prints application status in json format
@@ -123,14 +141,19 @@ func listApps(env *localenv.LocalEnvironment, repository, appType string, showHi
 	return nil
 }
 
-// uninstallAppPackage uninstalls gravity application from cluster
-func uninstallAppPackage(env *localenv.LocalEnvironment, appPackage loc.Locator) error {
+// uninstallAppPackage uninstalls gravity application from cluster.
+// With force, the namespaces and persistent volumes of the application
+// that have not been removed by its uninstall hooks are forcibly finalized
+func uninstallAppPackage(env *localenv.LocalEnvironment, appPackage loc.Locator, force bool) error {
 	apps, err := env.SiteApps()
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	app, err := apps.UninstallApp(appPackage)
+	app, err := apps.UninstallApp(appservice.UninstallAppRequest{
+		Package: appPackage,
+		Force:   force,
+	})
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -141,8 +164,44 @@ func uninstallAppPackage(env *localenv.LocalEnvironment, appPackage loc.Locator)
 
 // importApp imports an application from the specified directory creating a new
 // package named packageName.
+// unpackArchive unpacks the possibly compressed archive at the specified
+// path into a temporary directory and returns the directory
+func unpackArchive(path string) (dir string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	dir, err = ioutil.TempDir("", "import")
+	if err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	if err := dockerarchive.Untar(f, dir, archive.DefaultOptions()); err != nil {
+		os.RemoveAll(dir)
+		return "", trace.Wrap(err)
+	}
+	return dir, nil
+}
+
+// validateAppManifest validates the manifest of the application in the specified
+// directory against the manifest schema and prints warnings about deprecated values
+func validateAppManifest(dir string, progress utils.Progress) error {
+	data, err := ioutil.ReadFile(filepath.Join(dir, defaults.ResourcesDir, defaults.ManifestFileName))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	result, err := schema.ValidateManifestYAML(data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, warning := range result.Warnings {
+		progress.PrintWarn(nil, "Warning: %v", warning)
+	}
+	return trace.Wrap(result.Check())
+}
+
 func importApp(env *localenv.LocalEnvironment, registryURL, dockerURL, source string, req *appservice.ImportRequest,
-	opsCenterURL string, silent bool, parallel int) error {
+	opsCenterURL string, silent bool, parallel int, trustedKeys []string, requireSignature bool) error {
 	apps, err := env.AppService(opsCenterURL, localenv.AppConfig{
 		DockerURL:   dockerURL,
 		RegistryURL: registryURL,
@@ -167,17 +226,29 @@ func importApp(env *localenv.LocalEnvironment, registryURL, dockerURL, source st
 	progress := utils.NewProgress(context.TODO(), "app import", steps, silent)
 	defer progress.Stop()
 
-	var stream io.ReadCloser
+	dir := source
 	if fileInfo.IsDir() {
 		progress.NextStep("importing directory %v", source)
-		if stream, err = dockerarchive.Tar(source, dockerarchive.Uncompressed); err != nil {
-			return trace.Wrap(err)
-		}
 	} else {
 		progress.NextStep("importing archive %v", source)
-		if stream, err = os.Open(source); err != nil {
+		// the archive is unpacked so the signature is verified against
+		// the same contents that are imported
+		if dir, err = unpackArchive(source); err != nil {
 			return trace.Wrap(err)
 		}
+		defer os.RemoveAll(dir)
+	}
+	err = verifyImageSignature(env, dir, trustedKeys, requireSignature)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = validateAppManifest(dir, progress)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	stream, err := dockerarchive.Tar(dir, dockerarchive.Uncompressed)
+	if err != nil {
+		return trace.Wrap(err)
 	}
 
 	if req.Vendor {
@@ -242,13 +313,22 @@ func importApp(env *localenv.LocalEnvironment, registryURL, dockerURL, source st
 }
 
 // exportApp exports containers of the specified application package packageName
-// to the private docker registry identified with registryHostPort
-func exportApp(env *localenv.LocalEnvironment, packageName, portalURL, registryHostPort string) error {
-	apps, err := env.AppService(portalURL, localenv.AppConfig{})
+// to the private docker registry identified with registryHostPort or,
+// with the oci format, to outputDir as an OCI image layout
+func exportApp(env *localenv.LocalEnvironment, packageName, portalURL, registryHostPort string, parallel int, format, outputDir string) error {
+	locator, err := loc.ParseLocator(packageName)
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	locator, err := loc.ParseLocator(packageName)
+	switch format {
+	case constants.ImageFormatRegistry:
+	case constants.ImageFormatOCI:
+		return exportAppOCI(env, *locator, portalURL, outputDir)
+	default:
+		return trace.BadParameter("unsupported export format %q, supported formats are %q and %q",
+			format, constants.ImageFormatRegistry, constants.ImageFormatOCI)
+	}
+	apps, err := env.AppService(portalURL, localenv.AppConfig{})
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -256,6 +336,7 @@ func exportApp(env *localenv.LocalEnvironment, packageName, portalURL, registryH
 	if err = apps.ExportApp(appservice.ExportAppRequest{
 		Package:         *locator,
 		RegistryAddress: registryHostPort,
+		Parallel:        parallel,
 	}); err != nil {
 		return trace.Wrap(err)
 	}
@@ -264,6 +345,60 @@ func exportApp(env *localenv.LocalEnvironment, packageName, portalURL, registryH
 	return nil
 }
 
+// exportAppOCI writes containers of the specified application package
+// to outputDir as an OCI image layout
+func exportAppOCI(env *localenv.LocalEnvironment, locator loc.Locator, portalURL, outputDir string) error {
+	if outputDir == "" {
+		return trace.BadParameter("output directory is required for the %v format", constants.ImageFormatOCI)
+	}
+	packages, err := env.PackageService(portalURL)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	images, err := service.ExportOCILayout(context.TODO(), packages, locator, outputDir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, image := range images {
+		env.Printf("Exported %v.\n", image)
+	}
+	env.Printf("%v exported to `%v`\n", locator, outputDir)
+	return nil
+}
+
+// importImages pushes the images from the OCI image layout in dir
+// to the docker registry identified with registryHostPort
+func importImages(env *localenv.LocalEnvironment, dir, registryHostPort string) error {
+	registryDir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(registryDir)
+	ctx := context.TODO()
+	images, err := docker.ImportOCILayout(ctx, dir, registryDir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(images) == 0 {
+		return trace.NotFound("no tagged images found in %v", dir)
+	}
+	imageService, err := docker.NewImageService(docker.RegistryConnectionRequest{
+		RegistryAddress: registryHostPort,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	pushed, err := imageService.Sync(ctx, registryDir, utils.NopEmitter())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, image := range pushed {
+		env.Printf("Pushed %v.\n", image)
+	}
+	env.Printf("Images from `%v` pushed to `%v`\n", dir, registryHostPort)
+	return nil
+}
+
 // deleteApp deletes an application record from database
 func deleteApp(env *localenv.LocalEnvironment, packageName, portalURL string, force bool) error {
 	locator, err := loc.ParseLocator(packageName)
@@ -380,7 +515,7 @@ func pushApp(env *localenv.LocalEnvironment, appPackage loc.Locator, portalURL s
 	return nil
 }
 
-func unpackAppResources(env *localenv.LocalEnvironment, loc loc.Locator, dir, opsCenterURL, serviceUID string) error {
+func unpackAppResources(env *localenv.LocalEnvironment, loc loc.Locator, dir, opsCenterURL, serviceUID, serviceGID string) error {
 	apps, err := env.AppService(opsCenterURL, localenv.AppConfig{}, httplib.WithDialTimeout(dialTimeout))
 	if err != nil {
 		return trace.Wrap(err)
@@ -441,8 +576,15 @@ func unpackAppResources(env *localenv.LocalEnvironment, loc loc.Locator, dir, op
 			return trace.BadParameter("invalid numeric user ID %q: %v", serviceUID, err)
 		}
 	}
+	gid := defaults.ServiceGID
+	if serviceGID != "" {
+		gid, err = strconv.Atoi(serviceGID)
+		if err != nil {
+			return trace.BadParameter("invalid numeric group ID %q: %v", serviceGID, err)
+		}
+	}
 	err = resources.UpdateSecurityContextInDir(filepath.Join(dir, defaults.ResourcesDir),
-		systeminfo.User{UID: uid})
+		systeminfo.User{UID: uid, GID: gid})
 	if err != nil {
 		return trace.Wrap(err, "failed to render application resources")
 	}
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/httplib"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/tool/common"
+
+	"github.com/gravitational/trace"
+)
+
+// diffApps displays changes between the specified application versions
+func diffApps(env *localenv.LocalEnvironment, from, to loc.Locator, opsCenterURL string, format constants.Format) error {
+	apps, err := env.AppService(opsCenterURL, localenv.AppConfig{}, httplib.WithDialTimeout(dialTimeout))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	packages, err := env.PackageService(opsCenterURL, httplib.WithDialTimeout(dialTimeout))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	diff, err := service.DiffApps(apps, packages, from, to)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, diff))
+	case constants.EncodingText:
+		printAppDiff(os.Stdout, *diff)
+		return nil
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+}
+
+func printAppDiff(w io.Writer, diff service.AppDiff) {
+	fmt.Fprintf(w, "Changes from %v to %v:\n", diff.From, diff.To)
+	if diff.IsEmpty() {
+		fmt.Fprintln(w, "No changes.")
+		return
+	}
+	if diff.Manifest != "" {
+		fmt.Fprintf(w, "\nManifest:\n%v", diff.Manifest)
+	}
+	if diff.Dependencies != nil && len(diff.Dependencies.Dependencies) != 0 {
+		fmt.Fprintf(w, "\nUpdated dependencies:\n")
+		for _, dep := range diff.Dependencies.Dependencies {
+			fmt.Fprint(w, dep.String())
+		}
+	}
+	if len(diff.ImagesAdded) != 0 {
+		fmt.Fprintf(w, "\nImages added:\n")
+		for _, image := range diff.ImagesAdded {
+			fmt.Fprintf(w, "+ %v\n", image)
+		}
+	}
+	if len(diff.ImagesRemoved) != 0 {
+		fmt.Fprintf(w, "\nImages removed:\n")
+		for _, image := range diff.ImagesRemoved {
+			fmt.Fprintf(w, "- %v\n", image)
+		}
+	}
+	for _, resource := range diff.Resources {
+		fmt.Fprintf(w, "\nResource %v:\n%v", resource.Path, resource.Diff)
+	}
+}
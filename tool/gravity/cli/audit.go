@@ -0,0 +1,69 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/tool/common"
+
+	"github.com/gravitational/trace"
+)
+
+// listAuditEvents displays audit events recorded in the cluster
+// within the specified duration
+func listAuditEvents(env *localenv.LocalEnvironment, since time.Duration, format constants.Format) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	events, err := operator.GetAuditEvents(context.TODO(), ops.GetAuditEventsRequest{
+		SiteKey: cluster.Key(),
+		Since:   time.Now().UTC().Add(-since),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, events))
+	case constants.EncodingText:
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "Time\tUser\tAction\tError\n")
+		fmt.Fprintf(w, "----\t----\t------\t-----\n")
+		for _, event := range events {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n",
+				event.Time.Format(constants.HumanDateFormatSeconds),
+				event.User, event.Action, event.Error)
+		}
+		return trace.Wrap(w.Flush())
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/rpc"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/gravitational/teleport/lib/client"
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/ssh"
+)
+
+// saveBackendSnapshot saves etcd snapshot of the local cluster backend at path
+func saveBackendSnapshot(env *localenv.LocalEnvironment, path string) error {
+	config, err := keyval.LocalEtcdConfig(0)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaults.EtcdSnapshotTimeout)
+	defer cancel()
+	status, err := keyval.SaveSnapshot(ctx, *config, path)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Saved snapshot at revision %v to %v (sha256:%v).\n",
+		status.Revision, path, status.Hash)
+	return nil
+}
+
+// verifyBackendSnapshot verifies integrity of etcd snapshot at path
+func verifyBackendSnapshot(env *localenv.LocalEnvironment, path, prefix string) error {
+	status, err := keyval.VerifySnapshot(path, prefix)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Snapshot %v is valid: revision %v, %v keys, sha256:%v.\n",
+		path, status.Revision, status.TotalKeys, status.Hash)
+	return nil
+}
+
+// restoreBackendSnapshot restores keys with the specified prefix from etcd snapshot
+// at path into the local cluster backend
+func restoreBackendSnapshot(env *localenv.LocalEnvironment, path, prefix string, confirmed bool) error {
+	if !confirmed {
+		env.Printf("This will overwrite existing keys with the prefix %q "+
+			"with the contents of %v. Are you sure?\n", prefix, path)
+		resp, err := confirm()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if !resp {
+			env.Println("Action cancelled by user.")
+			return nil
+		}
+	}
+	config, err := keyval.LocalEtcdConfig(0)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaults.EtcdSnapshotTimeout)
+	defer cancel()
+	status, err := keyval.RestoreSnapshot(ctx, *config, path, prefix)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Restored %v keys at revision %v from %v.\n",
+		status.TotalKeys, status.Revision, path)
+	return nil
+}
+
+// migrateBackend copies the local cluster backend data to the etcd v3 keyspace.
+//
+// The migration is online: gravity-site keeps running on the master nodes,
+// its writes are held while the data is being copied and it switches
+// to the etcd v3 API once the migration has completed
+func migrateBackend(env *localenv.LocalEnvironment, confirmed bool) error {
+	if !confirmed {
+		env.Println("This will copy the cluster data to the etcd v3 keyspace. " +
+			"Changes to the cluster data are held while the data is being copied, " +
+			"after the migration gravity-site will use the etcd v3 API. Are you sure?")
+		resp, err := confirm()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if !resp {
+			env.Println("Action cancelled by user.")
+			return nil
+		}
+	}
+	config, err := keyval.LocalEtcdConfig(0)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaults.EtcdSnapshotTimeout)
+	defer cancel()
+	status, err := keyval.MigrateToV3(ctx, *config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Migrated %v keys and %v directories. Set api_version to %v in the etcd section "+
+		"of the gravity-site configuration (gravity-opscenter config map).\n",
+		status.Keys, status.Dirs, keyval.ETCDAPIVersion3)
+	return nil
+}
+
+// backupLocalBackend saves a consistent copy of the local BoltDB backend
+// at path, optionally compacting it first
+func backupLocalBackend(env *localenv.LocalEnvironment, path string, compact bool) error {
+	if compact {
+		if err := keyval.Compact(env.Backend); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	partPath := path + ".part"
+	f, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, defaults.PrivateFileMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.Remove(partPath)
+	err = keyval.Snapshot(env.Backend, f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	env.Printf("Saved backup of %v to %v.\n", env.StateDir, path)
+	return nil
+}
+
+// generateBackendKey generates a new backend encryption key and writes it
+// to the key file at path on all master nodes, or only on this node
+// if local is set.
+//
+// All master nodes share the cluster backend so they must use the same key.
+// Existing key files are never overwritten: the key is only written once it
+// has been verified that none of the master nodes has the key file
+func generateBackendKey(env *localenv.LocalEnvironment, path string, local bool) error {
+	if !filepath.IsAbs(path) {
+		return trace.BadParameter("key file path %q is not absolute", path)
+	}
+	key, err := keyval.GenerateEncryptionKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if local {
+		if err := keyval.WriteEncryptionKey(path, key); err != nil {
+			return trace.Wrap(err)
+		}
+		env.Printf("Saved encryption key to %v.\n", path)
+		return nil
+	}
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	teleportClient, err := env.TeleportClient(constants.Localhost)
+	if err != nil {
+		return trace.Wrap(err, "failed to create a teleport client")
+	}
+	ctx := context.TODO()
+	proxy, err := teleportClient.ConnectToProxy(ctx)
+	if err != nil {
+		return trace.Wrap(err, "failed to connect to teleport proxy")
+	}
+	defer proxy.Close()
+	masters := cluster.ClusterState.Servers.Masters()
+	nodes := make([]*client.NodeClient, 0, len(masters))
+	defer func() {
+		for _, node := range nodes {
+			node.Close()
+		}
+	}()
+	for _, master := range masters {
+		node, err := proxy.ConnectToNode(ctx, rpc.NewDeployServer(master).NodeAddr, defaults.SSHUser, false)
+		if err != nil {
+			return trace.Wrap(err, "failed to connect to node %v", master.Hostname)
+		}
+		nodes = append(nodes, node)
+		err = runWithInput(node.Client, nil, fmt.Sprintf("test ! -e %v", shellQuote(path)))
+		if err != nil {
+			return trace.AlreadyExists("encryption key file %v already exists on node %v",
+				path, master.Hostname)
+		}
+	}
+	for i, node := range nodes {
+		// The key is passed on stdin so it does not appear in the command
+		// line or the session log
+		err := runWithInput(node.Client, key, fmt.Sprintf("umask 077 && set -o noclobber && cat > %v",
+			shellQuote(path)))
+		if err != nil {
+			return trace.Wrap(err, "failed to write encryption key on node %v", masters[i].Hostname)
+		}
+	}
+	env.Printf("Saved encryption key to %v on %v master nodes.\n", path, len(masters))
+	return nil
+}
+
+// runWithInput runs the command in a new SSH session with the specified input
+func runWithInput(client *ssh.Client, input []byte, command string) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer session.Close()
+	session.Stdin = bytes.NewReader(input)
+	out, err := session.CombinedOutput(command)
+	if err != nil {
+		return trace.Wrap(err, string(out))
+	}
+	return nil
+}
+
+// shellQuote quotes the specified value for use as a single shell word
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// reencryptBackend encrypts the values of the cluster backend, or the local
+// state database if local is set, that are not encrypted with the current key
+func reencryptBackend(env *localenv.LocalEnvironment, local bool, config keyval.EncryptionConfig) error {
+	if err := config.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	var backend storage.Backend
+	var err error
+	if local {
+		backend, err = keyval.NewBolt(keyval.BoltConfig{
+			Path:       filepath.Join(env.StateDir, defaults.GravityDBFile),
+			Multi:      true,
+			Encryption: &config,
+		})
+	} else {
+		var etcdConfig *keyval.ETCDConfig
+		etcdConfig, err = keyval.LocalEtcdConfig(0)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		etcdConfig.Encryption = &config
+		backend, err = keyval.NewETCD(*etcdConfig)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer backend.Close()
+	count, err := keyval.Reencrypt(backend)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Re-encrypted %v values.\n", count)
+	return nil
+}
@@ -22,56 +22,79 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
 	"time"
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/app/hooks"
 	"github.com/gravitational/gravity/lib/archive"
+	libbackup "github.com/gravitational/gravity/lib/backup"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage/keyval"
 	"github.com/gravitational/gravity/lib/utils"
 
 	dockerarchive "github.com/docker/docker/pkg/archive"
 	teleutils "github.com/gravitational/teleport/lib/utils"
 	"github.com/gravitational/trace"
+	"github.com/gravitational/version"
 	v1 "k8s.io/api/core/v1"
 )
 
-func backup(env *localenv.LocalEnvironment, tarball string, timeout time.Duration, follow, silent bool) (err error) {
+func backup(env *localenv.LocalEnvironment, tarball string, timeout time.Duration, follow, silent bool) error {
 	ctx := context.Background()
 	// if we're streaming logs to stdout, no much sense in showing our progress indicator
 	noProgress := silent || follow
-	progress := utils.NewProgress(ctx, "backup", 2, noProgress)
+	progress := utils.NewProgress(ctx, "backup", 4, noProgress)
 	defer progress.Stop()
 	progress.NextStep("backing up to %v", tarball)
 	return runBackupRestore(env, "backup",
-		func(env *localenv.LocalEnvironment, backupPath string, req *app.HookRunRequest) error {
+		func(env *localenv.LocalEnvironment, cluster ops.Site, backupPath string, newHookRequest hookRequestFunc) error {
+			defer func() {
+				if err := os.RemoveAll(backupPath); err != nil {
+					log.Errorf("failed to remove backup directory %s: %v", backupPath, err)
+				}
+			}()
+			err := os.MkdirAll(backupPath, defaults.SharedDirMask)
+			if err != nil {
+				return trace.ConvertSystemError(err)
+			}
+			clusterPackages, err := env.ClusterPackages()
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			packages, err := libbackup.GetPackages(clusterPackages)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			progress.NextStep("saving cluster state")
+			err = saveClusterState(filepath.Join(backupPath, libbackup.SnapshotFile))
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			progress.NextStep("backing up application")
+			req := newHookRequest(libbackup.AppDir)
 			req.Hook = schema.HookBackup
 			if timeout != 0 {
 				req.Timeout = timeout
 			}
-			apps, err := env.SiteApps()
+			hasAppBackup, err := runBackupRestoreHook(ctx, env, req, getStreamingWriter(silent, follow))
 			if err != nil {
 				return trace.Wrap(err)
 			}
-			ref, err := app.StreamAppHook(
-				ctx, apps, *req, getStreamingWriter(silent, follow))
+			err = libbackup.WriteMetadata(backupPath, libbackup.Metadata{
+				Version:      version.Get().Version,
+				Application:  cluster.App.Package,
+				Created:      time.Now().UTC(),
+				Packages:     packages,
+				HasAppBackup: hasAppBackup,
+			})
 			if err != nil {
 				return trace.Wrap(err)
 			}
-			defer func() {
-				err := apps.DeleteAppHookJob(ctx, app.DeleteAppHookJobRequest{
-					HookRef: *ref,
-				})
-				if err != nil {
-					log.Warningf("failed to delete hook %v: %v",
-						ref, trace.DebugReport(err))
-				}
-				if err = os.RemoveAll(backupPath); err != nil {
-					log.Errorf("failed to remove backup directory %s: %v", backupPath, err)
-				}
-			}()
 			err = compressDirectory(backupPath, tarball)
 			if err != nil {
 				return trace.Wrap(err)
@@ -81,15 +104,27 @@ func backup(env *localenv.LocalEnvironment, tarball string, timeout time.Duratio
 		})
 }
 
-func restore(env *localenv.LocalEnvironment, tarball string, timeout time.Duration, follow, silent bool) error {
+func restore(env *localenv.LocalEnvironment, tarball string, timeout time.Duration, follow, silent, confirmed bool) error {
+	if !confirmed {
+		env.Printf("This will overwrite the cluster and application state "+
+			"with the contents of %v. Are you sure?\n", tarball)
+		resp, err := confirm()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if !resp {
+			env.Println("Action cancelled by user.")
+			return nil
+		}
+	}
 	ctx := context.Background()
 	// if we're streaming logs to stdout, no much sense in showing our progress indicator
 	noProgress := silent || follow
-	progress := utils.NewProgress(ctx, "restore", 2, noProgress)
+	progress := utils.NewProgress(ctx, "restore", 4, noProgress)
 	defer progress.Stop()
 	progress.NextStep("restoring from %v", tarball)
 	return runBackupRestore(env, "restore",
-		func(env *localenv.LocalEnvironment, backupPath string, req *app.HookRunRequest) error {
+		func(env *localenv.LocalEnvironment, cluster ops.Site, backupPath string, newHookRequest hookRequestFunc) error {
 			f, err := os.Open(tarball)
 			if err != nil {
 				return trace.Wrap(err, "failed to open the tarball %q with backed up data", tarball)
@@ -104,25 +139,130 @@ func restore(env *localenv.LocalEnvironment, tarball string, timeout time.Durati
 					log.Errorf("failed to remove restore directory %s: %v", backupPath, err)
 				}
 			}()
-			req.Hook = schema.HookRestore
-			if timeout != 0 {
-				req.Timeout = timeout
-			}
-			apps, err := env.SiteApps()
-			if err != nil {
+			metadata, err := libbackup.ReadMetadata(backupPath)
+			if err != nil && !trace.IsNotFound(err) {
 				return trace.Wrap(err)
 			}
-			_, err = app.StreamAppHook(ctx, apps, *req, getStreamingWriter(silent, follow))
-			if err != nil {
-				return trace.Wrap(err)
+			// backups without metadata only contain the results
+			// of the application backup hook
+			req := newHookRequest("")
+			hasAppBackup := true
+			if metadata != nil {
+				progress.NextStep("restoring cluster state")
+				err = restoreClusterState(env, cluster, *metadata, backupPath)
+				if err != nil {
+					return trace.Wrap(err)
+				}
+				req = newHookRequest(libbackup.AppDir)
+				hasAppBackup = metadata.HasAppBackup
+			}
+			progress.NextStep("restoring application")
+			if hasAppBackup {
+				req.Hook = schema.HookRestore
+				if timeout != 0 {
+					req.Timeout = timeout
+				}
+				_, err = runBackupRestoreHook(ctx, env, req, getStreamingWriter(silent, follow))
+				if err != nil {
+					return trace.Wrap(err)
+				}
+			}
+			if metadata != nil {
+				progress.NextStep("restored from %v, restart gravity-site "+
+					"to pick up the restored cluster state", tarball)
+			} else {
+				progress.NextStep("restored from %v", tarball)
 			}
-			progress.NextStep("restored from %v", tarball)
 			return nil
 		})
 }
 
+// saveClusterState saves etcd snapshot of the cluster backend at path
+func saveClusterState(path string) error {
+	config, err := keyval.LocalEtcdConfig(0)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaults.EtcdSnapshotTimeout)
+	defer cancel()
+	status, err := keyval.SaveSnapshot(ctx, *config, path)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	log.Infof("Saved cluster state at revision %v (sha256:%v).", status.Revision, status.Hash)
+	return nil
+}
+
+// restoreClusterState restores the cluster backend from the backup
+// in backupPath after making sure the backup is compatible with the cluster
+func restoreClusterState(env *localenv.LocalEnvironment, cluster ops.Site, metadata libbackup.Metadata, backupPath string) error {
+	err := metadata.CheckCompatible(cluster.App.Package, version.Get().Version)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	clusterPackages, err := env.ClusterPackages()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = libbackup.CheckPackages(clusterPackages, metadata.Packages)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	path := filepath.Join(backupPath, libbackup.SnapshotFile)
+	_, err = keyval.VerifySnapshot(path, defaults.EtcdKey)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	config, err := keyval.LocalEtcdConfig(0)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaults.EtcdSnapshotTimeout)
+	defer cancel()
+	status, err := keyval.RestoreSnapshot(ctx, *config, path, defaults.EtcdKey)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	log.Infof("Restored %v keys of cluster state at revision %v.", status.TotalKeys, status.Revision)
+	return nil
+}
+
+// runBackupRestoreHook runs the specified backup or restore hook and returns
+// false if the application does not have the hook
+func runBackupRestoreHook(ctx context.Context, env *localenv.LocalEnvironment, req app.HookRunRequest, w io.WriteCloser) (bool, error) {
+	apps, err := env.SiteApps()
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	_, err = app.CheckHasAppHook(apps, req)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			log.Infof("%v does not have %v hook.", req.Application, req.Hook)
+			return false, nil
+		}
+		return false, trace.Wrap(err)
+	}
+	ref, err := app.StreamAppHook(ctx, apps, req, w)
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	err = apps.DeleteAppHookJob(ctx, app.DeleteAppHookJobRequest{
+		HookRef: *ref,
+	})
+	if err != nil {
+		log.Warningf("failed to delete hook %v: %v",
+			ref, trace.DebugReport(err))
+	}
+	return true, nil
+}
+
+// hookRequestFunc returns the request to run a backup or restore hook
+// with the specified subdirectory of the backup directory mounted
+// into the hook containers
+type hookRequestFunc func(subdir string) app.HookRunRequest
+
 func runBackupRestore(env *localenv.LocalEnvironment, operation string,
-	fn func(env *localenv.LocalEnvironment, backupPath string, req *app.HookRunRequest) error) (err error) {
+	fn func(env *localenv.LocalEnvironment, cluster ops.Site, backupPath string, newHookRequest hookRequestFunc) error) (err error) {
 
 	operator, err := env.SiteOperator()
 	if err != nil {
@@ -146,23 +286,25 @@ func runBackupRestore(env *localenv.LocalEnvironment, operation string,
 		return trace.Wrap(err, "failed to generate random ID")
 	}
 
-	req := &app.HookRunRequest{
-		Application: site.App.Package,
-		Volumes: []v1.Volume{{
-			Name: hooks.VolumeBackup,
-			VolumeSource: v1.VolumeSource{
-				HostPath: &v1.HostPathVolumeSource{
-					Path: fmt.Sprintf("/ext/state/%v/backup", id),
+	newHookRequest := func(subdir string) app.HookRunRequest {
+		return app.HookRunRequest{
+			Application: site.App.Package,
+			Volumes: []v1.Volume{{
+				Name: hooks.VolumeBackup,
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{
+						Path: path.Join(fmt.Sprintf("/ext/state/%v/backup", id), subdir),
+					},
 				},
+			}},
+			VolumeMounts: []v1.VolumeMount{{
+				Name:      hooks.VolumeBackup,
+				MountPath: hooks.ContainerBackupDir,
+			}},
+			NodeSelector: map[string]string{
+				defaults.KubernetesHostnameLabel: node.KubeNodeID(),
 			},
-		}},
-		VolumeMounts: []v1.VolumeMount{{
-			Name:      hooks.VolumeBackup,
-			MountPath: hooks.ContainerBackupDir,
-		}},
-		NodeSelector: map[string]string{
-			defaults.KubernetesHostnameLabel: node.KubeNodeID(),
-		},
+		}
 	}
 
 	backupDir, err := localenv.InGravity(fmt.Sprintf("planet/state/%v/backup", id))
@@ -170,7 +312,7 @@ func runBackupRestore(env *localenv.LocalEnvironment, operation string,
 		return trace.Wrap(err)
 	}
 
-	err = fn(env, backupDir, req)
+	err = fn(env, *site, backupDir, newHookRequest)
 	return trace.Wrap(err)
 }
 
@@ -0,0 +1,198 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/fsm"
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/update"
+	"github.com/gravitational/gravity/lib/update/certificates"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// rotateClusterCertificates executes the loop to rotate certificates on all cluster nodes.
+// If keepCA is false, the cluster certificate authority is replaced as well
+func rotateClusterCertificates(ctx context.Context, localEnv, updateEnv *localenv.LocalEnvironment, keepCA, manual, confirmed bool) error {
+	if !confirmed {
+		if manual {
+			localEnv.Println(rotateCertsBannerManual)
+		} else {
+			localEnv.Println(rotateCertsBanner)
+		}
+		resp, err := confirm()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if !resp {
+			localEnv.Println("Action cancelled by user.")
+			return nil
+		}
+	}
+	updater, err := newUpdater(ctx, localEnv, updateEnv, certsInitializer{rotateCA: !keepCA})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer updater.Close()
+	if !manual {
+		err = updater.Run(ctx, false)
+		return trace.Wrap(err)
+	}
+	localEnv.Println(updateConfigManualOperationBanner)
+	return nil
+}
+
+func executeCertsPhase(env, updateEnv *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
+	updater, err := getCertsUpdater(env, updateEnv, operation)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer updater.Close()
+	err = updater.RunPhase(context.TODO(), params.PhaseID, params.Timeout, params.Force)
+	return trace.Wrap(err)
+}
+
+func rollbackCertsPhase(env, updateEnv *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
+	updater, err := getCertsUpdater(env, updateEnv, operation)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer updater.Close()
+	err = updater.RollbackPhase(context.TODO(), params.PhaseID, params.Timeout, params.Force)
+	return trace.Wrap(err)
+}
+
+func completeCertsPlan(env, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation) error {
+	updater, err := getCertsUpdater(env, updateEnv, operation)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer updater.Close()
+	return trace.Wrap(updater.Complete(nil))
+}
+
+func getCertsUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation) (*update.Updater, error) {
+	clusterEnv, err := localEnv.NewClusterEnvironment()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	creds, err := libfsm.GetClientCredentials()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	runner := libfsm.NewAgentRunner(creds)
+	return certsInitializer{}.newUpdater(context.TODO(), clusterEnv.Operator, operation,
+		localEnv, updateEnv, clusterEnv, runner)
+}
+
+func (r certsInitializer) validatePreconditions(*localenv.LocalEnvironment, ops.Operator, ops.Site) error {
+	return nil
+}
+
+func (r certsInitializer) newOperation(operator ops.Operator, cluster ops.Site) (*ops.SiteOperationKey, error) {
+	key, err := operator.CreateRotateCertificatesOperation(context.TODO(),
+		ops.CreateRotateCertificatesOperationRequest{
+			ClusterKey: cluster.Key(),
+			RotateCA:   r.rotateCA,
+		},
+	)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotImplemented(
+				"cluster operator does not implement the API required for rotating certificates. " +
+					"Please make sure you're running the command on a compatible cluster.")
+		}
+		return nil, trace.Wrap(err)
+	}
+	return key, nil
+}
+
+func (r certsInitializer) newOperationPlan(
+	ctx context.Context,
+	operator ops.Operator,
+	cluster ops.Site,
+	operation ops.SiteOperation,
+	localEnv, updateEnv *localenv.LocalEnvironment,
+	clusterEnv *localenv.ClusterEnvironment,
+) (*storage.OperationPlan, error) {
+	plan, err := certificates.NewOperationPlan(operator, clusterEnv.Apps, operation, cluster.ClusterState.Servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
+
+func (certsInitializer) newUpdater(
+	ctx context.Context,
+	operator ops.Operator,
+	operation ops.SiteOperation,
+	localEnv, updateEnv *localenv.LocalEnvironment,
+	clusterEnv *localenv.ClusterEnvironment,
+	runner fsm.AgentRepository,
+) (*update.Updater, error) {
+	config := certificates.Config{
+		Config: update.Config{
+			Operation:    &operation,
+			Operator:     operator,
+			Backend:      clusterEnv.Backend,
+			LocalBackend: updateEnv.Backend,
+			Runner:       runner,
+			Silent:       localEnv.Silent,
+			FieldLogger: logrus.WithFields(logrus.Fields{
+				trace.Component: "update:certificates",
+				"operation":     operation,
+			}),
+		},
+		Apps:              clusterEnv.Apps,
+		Client:            clusterEnv.Client,
+		ClusterPackages:   clusterEnv.ClusterPackages,
+		HostLocalPackages: localEnv.Packages,
+	}
+	return certificates.New(ctx, config)
+}
+
+func (certsInitializer) updateDeployRequest(req deployAgentsRequest) deployAgentsRequest {
+	return req
+}
+
+type certsInitializer struct {
+	// rotateCA specifies whether the cluster certificate authority is replaced
+	rotateCA bool
+}
+
+const (
+	rotateCertsBanner = `Rotating certificates will restart runtime containers on all cluster nodes, one node at a time.
+If the certificate authority is replaced, every node is restarted three times.
+The operation might take a while to complete.
+
+The operation will start automatically once you approve it.
+If you want to review the operation plan first or execute it manually step by step,
+run the operation in manual mode by specifying '--manual' flag.
+
+Are you sure?`
+	rotateCertsBannerManual = `Rotating certificates will restart runtime containers on all cluster nodes, one node at a time.
+If the certificate authority is replaced, every node is restarted three times.
+The operation might take a while to complete.
+
+Are you sure?`
+)
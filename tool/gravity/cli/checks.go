@@ -19,17 +19,33 @@ package cli
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 
 	"github.com/gravitational/gravity/lib/checks"
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/tool/common"
 
 	pb "github.com/gravitational/satellite/agent/proto/agentpb"
 	"github.com/gravitational/trace"
 )
 
-func checkManifest(env *localenv.LocalEnvironment, manifestPath, profileName string, autoFix bool) error {
-	data, err := ioutil.ReadFile(manifestPath)
+type checkConfig struct {
+	// manifestPath is the path to the application manifest
+	manifestPath string
+	// profileName is the name of the node profile to check against
+	profileName string
+	// autoFix enables automatic fixing of some failed checks
+	autoFix bool
+	// checks optionally lists names of the checks to run
+	checks []string
+	// format is the report output format
+	format constants.Format
+}
+
+func checkManifest(env *localenv.LocalEnvironment, config checkConfig) error {
+	data, err := ioutil.ReadFile(config.manifestPath)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -41,13 +57,23 @@ func checkManifest(env *localenv.LocalEnvironment, manifestPath, profileName str
 
 	result, err := checks.ValidateLocal(checks.LocalChecksRequest{
 		Manifest: *manifest,
-		Role:     profileName,
-		AutoFix:  autoFix,
+		Role:     config.profileName,
+		AutoFix:  config.autoFix,
+		Checks:   config.checks,
 	})
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
+	if config.format != constants.EncodingText {
+		return trace.Wrap(printCheckReport(result, config.format))
+	}
+
+	if len(result.Warnings) > 0 {
+		env.Printf("The following checks reported warnings:\n%v",
+			checks.FormatFailedChecks(result.Warnings))
+	}
+
 	var failedErr, fixableErr error
 	if len(result.Failed) > 0 {
 		failedErr = trace.BadParameter(fmt.Sprintf("The following checks failed:\n%v",
@@ -61,6 +87,19 @@ func checkManifest(env *localenv.LocalEnvironment, manifestPath, profileName str
 	return trace.NewAggregate(failedErr, fixableErr)
 }
 
+// printCheckReport outputs the checks report in the specified
+// machine-readable format.
+// Returns an error if any of the checks have failed
+func printCheckReport(result *checks.LocalChecksResult, format constants.Format) error {
+	if err := common.PrintStructured(os.Stdout, format, result); err != nil {
+		return trace.Wrap(err)
+	}
+	if failed := result.GetFailed(); len(failed) != 0 {
+		return trace.BadParameter("%v pre-flight check(s) failed", len(failed))
+	}
+	return nil
+}
+
 func printFailedChecks(failed []*pb.Probe) {
 	if len(failed) == 0 {
 		return
@@ -59,9 +59,12 @@ func updateTrigger(
 	updateEnv *localenv.LocalEnvironment,
 	updatePackage string,
 	manual, block, noValidateVersion bool,
+	parallel int,
+	drain storage.DrainOptions,
+	canary *storage.CanaryOptions,
 ) error {
 	ctx := context.TODO()
-	updater, err := newClusterUpdater(ctx, localEnv, updateEnv, updatePackage, manual, block, noValidateVersion)
+	updater, err := newClusterUpdater(ctx, localEnv, updateEnv, updatePackage, manual, block, noValidateVersion, parallel, drain, canary)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -83,11 +86,25 @@ func newClusterUpdater(
 	localEnv, updateEnv *localenv.LocalEnvironment,
 	updatePackage string,
 	manual, block, noValidateVersion bool,
+	parallel int,
+	drain storage.DrainOptions,
+	canary *storage.CanaryOptions,
 ) (updater, error) {
+	if err := drain.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if canary != nil {
+		if err := canary.Check(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
 	unattended := !manual && !block
 	init := &clusterInitializer{
 		updatePackage: updatePackage,
 		unattended:    unattended,
+		parallel:      parallel,
+		drain:         drain,
+		canary:        canary,
 	}
 	updater, err := newUpdater(ctx, localEnv, updateEnv, init)
 	if err != nil {
@@ -103,7 +120,7 @@ func newClusterUpdater(
 }
 
 func executeUpdatePhase(env, updateEnv *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
-	updater, err := getClusterUpdater(env, updateEnv, operation, params.SkipVersionCheck)
+	updater, err := getClusterUpdater(env, updateEnv, operation, params.SkipVersionCheck, params.Parallel)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -113,7 +130,7 @@ func executeUpdatePhase(env, updateEnv *localenv.LocalEnvironment, params PhaseP
 }
 
 func rollbackUpdatePhase(env, updateEnv *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
-	updater, err := getClusterUpdater(env, updateEnv, operation, params.SkipVersionCheck)
+	updater, err := getClusterUpdater(env, updateEnv, operation, params.SkipVersionCheck, 0)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -123,7 +140,7 @@ func rollbackUpdatePhase(env, updateEnv *localenv.LocalEnvironment, params Phase
 }
 
 func completeUpdatePlan(env, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation) error {
-	updater, err := getClusterUpdater(env, updateEnv, operation, true)
+	updater, err := getClusterUpdater(env, updateEnv, operation, true, 0)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -131,7 +148,7 @@ func completeUpdatePlan(env, updateEnv *localenv.LocalEnvironment, operation ops
 	return trace.Wrap(updater.Complete(nil))
 }
 
-func getClusterUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation, noValidateVersion bool) (*update.Updater, error) {
+func getClusterUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation, noValidateVersion bool, parallel int) (*update.Updater, error) {
 	clusterEnv, err := localEnv.NewClusterEnvironment()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -152,6 +169,7 @@ func getClusterUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation
 			LocalBackend: updateEnv.Backend,
 			Runner:       runner,
 			Silent:       localEnv.Silent,
+			Parallelism:  parallel,
 		},
 		Apps:              clusterEnv.Apps,
 		Client:            clusterEnv.Client,
@@ -191,6 +209,8 @@ func (r clusterInitializer) newOperation(operator ops.Operator, cluster ops.Site
 		AccountID:  cluster.AccountID,
 		SiteDomain: cluster.Domain,
 		App:        r.updateLoc.String(),
+		Drain:      &r.drain,
+		Canary:     r.canary,
 	})
 }
 
@@ -211,7 +231,7 @@ func (r clusterInitializer) newOperationPlan(
 	return plan, nil
 }
 
-func (clusterInitializer) newUpdater(
+func (r clusterInitializer) newUpdater(
 	ctx context.Context,
 	operator ops.Operator,
 	operation ops.SiteOperation,
@@ -226,6 +246,7 @@ func (clusterInitializer) newUpdater(
 			Backend:      clusterEnv.Backend,
 			LocalBackend: updateEnv.Backend,
 			Runner:       runner,
+			Parallelism:  r.parallel,
 		},
 		HostLocalBackend:  localEnv.Backend,
 		HostLocalPackages: localEnv.Packages,
@@ -249,6 +270,24 @@ type clusterInitializer struct {
 	updateLoc     loc.Locator
 	updatePackage string
 	unattended    bool
+	// parallel limits the number of nodes updated concurrently
+	parallel int
+	// drain specifies the parameters for draining the nodes
+	drain storage.DrainOptions
+	// canary optionally specifies the canary nodes update strategy
+	canary *storage.CanaryOptions
+}
+
+// canaryOptions returns the canary update strategy for the specified
+// command line parameters or nil, if no canary nodes have been requested
+func canaryOptions(nodes int, pause bool) *storage.CanaryOptions {
+	if nodes == 0 && !pause {
+		return nil
+	}
+	return &storage.CanaryOptions{
+		Nodes: nodes,
+		Pause: pause,
+	}
 }
 
 const (
@@ -272,6 +311,15 @@ Installed runtime version (%q) is too old and cannot be updated by this package.
 Please update this installation to a minimum required runtime version (%q) before using this update.`,
 			existingGravityPackage.Version, defaults.BaseUpdateVersion)
 	}
+	installedRuntime, updateRuntime := cluster.App.Manifest.Base(), manifest.Base()
+	if installedRuntime != nil && updateRuntime != nil {
+		// Make sure the update can reach its runtime from the installed one
+		// using the intermediate runtimes embedded in the cluster image
+		_, err = pack.RuntimeUpgradePath(*installedRuntime, *updateRuntime, manifest.IntermediateRuntimes())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	return nil
 }
 
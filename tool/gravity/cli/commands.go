@@ -20,9 +20,10 @@ import (
 	"net"
 	"time"
 
-	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/tool/common"
 
 	"github.com/gravitational/configure"
 	"gopkg.in/alecthomas/kingpin.v2"
@@ -54,6 +55,8 @@ type Application struct {
 	UserLogFile *string
 	// SystemLogFile is the path to the system log file
 	SystemLogFile *string
+	// Output is the output format of listing and status commands
+	Output *common.OutputFlag
 	// VersionCmd output the binary version
 	VersionCmd VersionCmd
 	// InstallCmd launches cluster installation
@@ -62,6 +65,14 @@ type Application struct {
 	JoinCmd JoinCmd
 	// AutoJoinCmd uses cloud provider info to join existing cluster
 	AutoJoinCmd AutoJoinCmd
+	// ExpandCmd provisions new nodes with a cloud provisioner
+	ExpandCmd ExpandCmd
+	// InfraCmd runs the Terraform configuration bundled with the cluster image
+	InfraCmd InfraCmd
+	// InfraPlanCmd displays the infrastructure changes
+	InfraPlanCmd InfraPlanCmd
+	// InfraApplyCmd provisions the infrastructure
+	InfraApplyCmd InfraApplyCmd
 	// LeaveCmd removes the current node from the cluster
 	LeaveCmd LeaveCmd
 	// RemoveCmd removes the specified node from the cluster
@@ -70,6 +81,10 @@ type Application struct {
 	PlanCmd PlanCmd
 	// UpdatePlanInitCmd creates a new update operation plan
 	UpdatePlanInitCmd UpdatePlanInitCmd
+	// UpdateScheduledCmd lists scheduled update operations
+	UpdateScheduledCmd UpdateScheduledCmd
+	// UpdateCancelCmd cancels a scheduled update operation
+	UpdateCancelCmd UpdateCancelCmd
 	// PlanDisplayCmd displays plan of an operation
 	PlanDisplayCmd PlanDisplayCmd
 	// PlanExecuteCmd executes a phase of an active operation
@@ -80,6 +95,12 @@ type Application struct {
 	PlanResumeCmd PlanResumeCmd
 	// PlanCompleteCmd completes the operation plan
 	PlanCompleteCmd PlanCompleteCmd
+	// PlanPauseCmd pauses the active operation
+	PlanPauseCmd PlanPauseCmd
+	// PlanCancelCmd cancels the active operation
+	PlanCancelCmd PlanCancelCmd
+	// PlanLogsCmd displays logs of an operation phase
+	PlanLogsCmd PlanLogsCmd
 	// UpdateCmd combines app update related commands
 	UpdateCmd UpdateCmd
 	// UpdateCheckCmd checks if a new app version is available
@@ -96,11 +117,21 @@ type Application struct {
 	UpgradeCmd UpgradeCmd
 	// StatusCmd displays cluster status
 	StatusCmd StatusCmd
+	// StatusClusterCmd displays the current cluster status
+	StatusClusterCmd StatusClusterCmd
+	// StatusHistoryCmd displays the history of cluster status transitions
+	StatusHistoryCmd StatusHistoryCmd
+	// StatusRegistryCmd displays the health and the storage usage of the cluster registry
+	StatusRegistryCmd StatusRegistryCmd
+	// StatusPackagesCmd displays the results of the last cluster package verification
+	StatusPackagesCmd StatusPackagesCmd
+	// StatusLicenseCmd displays the status of the cluster license
+	StatusLicenseCmd StatusLicenseCmd
 	// StatusResetCmd resets the cluster to active state
 	StatusResetCmd StatusResetCmd
-	// BackupCmd launches app backup hook
+	// BackupCmd backs up the cluster state and launches app backup hook
 	BackupCmd BackupCmd
-	// RestoreCmd launches app restore hook
+	// RestoreCmd restores the cluster state and launches app restore hook
 	RestoreCmd RestoreCmd
 	// CheckCmd checks that the host satisfies app manifest requirements
 	CheckCmd CheckCmd
@@ -130,6 +161,8 @@ type Application struct {
 	AppImportCmd AppImportCmd
 	// AppExportCmd exports specified app into registry
 	AppExportCmd AppExportCmd
+	// AppImportImagesCmd pushes images from an OCI image layout into registry
+	AppImportImagesCmd AppImportImagesCmd
 	// AppDeleteCmd deletes the specified app
 	AppDeleteCmd AppDeleteCmd
 	// AppPackageListCmd lists all app packages
@@ -146,6 +179,16 @@ type Application struct {
 	AppHookCmd AppHookCmd
 	// AppUnpackCmd unpacks specified app resources
 	AppUnpackCmd AppUnpackCmd
+	// AppDiffCmd shows changes between application versions
+	AppDiffCmd AppDiffCmd
+	// AppTrustedKeyCmd manages keys trusted to sign images
+	AppTrustedKeyCmd AppTrustedKeyCmd
+	// AppTrustedKeyAddCmd adds a key trusted to sign images
+	AppTrustedKeyAddCmd AppTrustedKeyAddCmd
+	// AppTrustedKeyListCmd lists keys trusted to sign images
+	AppTrustedKeyListCmd AppTrustedKeyListCmd
+	// AppTrustedKeyRemoveCmd removes a key trusted to sign images
+	AppTrustedKeyRemoveCmd AppTrustedKeyRemoveCmd
 	// WizardCmd starts installer in UI mode
 	WizardCmd WizardCmd
 	// AppPackageCmd displays the name of app in installer tarball
@@ -182,6 +225,12 @@ type Application struct {
 	PackPullCmd PackPullCmd
 	// PackLabelsCmd updates package labels
 	PackLabelsCmd PackLabelsCmd
+	// PackMigrateCmd converts the local package store to the chunked storage
+	PackMigrateCmd PackMigrateCmd
+	// PackGCCmd removes unused packages from the cluster package service
+	PackGCCmd PackGCCmd
+	// PackVerifyCmd verifies the integrity of the stored packages
+	PackVerifyCmd PackVerifyCmd
 	// UserCmd combines user related subcommands
 	UserCmd UserCmd
 	// UserCreateCmd creates a new user
@@ -194,6 +243,22 @@ type Application struct {
 	UsersInviteCmd UsersInviteCmd
 	// UsersResetCmd generates a user password reset link
 	UsersResetCmd UsersResetCmd
+	// UsersTokenCmd combines user API token subcommands
+	UsersTokenCmd UsersTokenCmd
+	// UsersTokenCreateCmd creates a new user API token
+	UsersTokenCreateCmd UsersTokenCreateCmd
+	// UsersTokenListCmd lists user API tokens
+	UsersTokenListCmd UsersTokenListCmd
+	// UsersTokenRemoveCmd revokes a user API token
+	UsersTokenRemoveCmd UsersTokenRemoveCmd
+	// JoinTokenCmd combines scoped join token subcommands
+	JoinTokenCmd JoinTokenCmd
+	// JoinTokenCreateCmd generates a new scoped join token
+	JoinTokenCreateCmd JoinTokenCreateCmd
+	// JoinTokenListCmd lists active scoped join tokens
+	JoinTokenListCmd JoinTokenListCmd
+	// JoinTokenRemoveCmd revokes a scoped join token
+	JoinTokenRemoveCmd JoinTokenRemoveCmd
 	// APIKeyCmd combines subcommands for API tokens
 	APIKeyCmd APIKeyCmd
 	// APIKeyCreateCmd creates a new token
@@ -220,6 +285,8 @@ type Application struct {
 	SiteCompleteCmd SiteCompleteCmd
 	// SiteResetPasswordCmd resets password for local cluster user
 	SiteResetPasswordCmd SiteResetPasswordCmd
+	// SiteUpdateLicenseCmd replaces the license installed on the local cluster
+	SiteUpdateLicenseCmd SiteUpdateLicenseCmd
 	// LocalSiteCmd displays local cluster name
 	LocalSiteCmd LocalSiteCmd
 	// RPCAgentCmd combines subcommands for RPC agents
@@ -266,6 +333,26 @@ type Application struct {
 	SystemReportCmd SystemReportCmd
 	// SystemStateDirCmd shows local state directory
 	SystemStateDirCmd SystemStateDirCmd
+	// SystemBackendCmd combines subcommands for the local cluster backend
+	SystemBackendCmd SystemBackendCmd
+	// SystemBackendSnapshotCmd saves etcd snapshot of the cluster backend
+	SystemBackendSnapshotCmd SystemBackendSnapshotCmd
+	// SystemBackendVerifyCmd verifies etcd snapshot of the cluster backend
+	SystemBackendVerifyCmd SystemBackendVerifyCmd
+	// SystemBackendRestoreCmd restores the cluster backend from etcd snapshot
+	SystemBackendRestoreCmd SystemBackendRestoreCmd
+	// SystemBackendMigrateCmd migrates the cluster backend data to etcd v3 API
+	SystemBackendMigrateCmd SystemBackendMigrateCmd
+	// SystemBackendBackupCmd saves a copy of the local BoltDB backend
+	SystemBackendBackupCmd SystemBackendBackupCmd
+	// SystemBackendGenerateKeyCmd generates a new backend encryption key
+	SystemBackendGenerateKeyCmd SystemBackendGenerateKeyCmd
+	// SystemBackendReencryptCmd encrypts the backend values with the current key
+	SystemBackendReencryptCmd SystemBackendReencryptCmd
+	// SystemRegistryCmd combines subcommands for docker registries
+	SystemRegistryCmd SystemRegistryCmd
+	// SystemRegistryReplicateCmd replicates images between docker registries
+	SystemRegistryReplicateCmd SystemRegistryReplicateCmd
 	// SystemDevicemapperCmd combines devicemapper related subcommands
 	SystemDevicemapperCmd SystemDevicemapperCmd
 	// SystemDevicemapperMountCmd configures devicemapper environment
@@ -291,6 +378,24 @@ type Application struct {
 	// GarbageCollectCmd prunes unused resources (package/journal files/docker images)
 	// in the cluster
 	GarbageCollectCmd GarbageCollectCmd
+	// CertsCmd combines cluster certificate subcommands
+	CertsCmd CertsCmd
+	// CertsRotateCmd rotates certificates on all cluster nodes
+	CertsRotateCmd CertsRotateCmd
+	// PatchCmd applies configuration-only changes to the cluster
+	PatchCmd PatchCmd
+	// EtcdCmd combines subcommands for managing the cluster etcd
+	EtcdCmd EtcdCmd
+	// EtcdBackupCmd saves etcd snapshot
+	EtcdBackupCmd EtcdBackupCmd
+	// EtcdRestoreCmd restores the cluster data from etcd snapshot
+	EtcdRestoreCmd EtcdRestoreCmd
+	// EtcdDefragCmd defragments the databases of etcd members
+	EtcdDefragCmd EtcdDefragCmd
+	// EtcdStatusCmd displays the health of etcd members
+	EtcdStatusCmd EtcdStatusCmd
+	// EtcdScheduleCmd configures periodic etcd snapshots on this node
+	EtcdScheduleCmd EtcdScheduleCmd
 	// PlanetCmd combines planet subcommands
 	PlanetCmd PlanetCmd
 	// [DEPRECATED] PlanetEnterCmd enters planet container
@@ -311,13 +416,15 @@ type Application struct {
 	ResourceRemoveCmd ResourceRemoveCmd
 	// ResourceGetCmd shows specified resource
 	ResourceGetCmd ResourceGetCmd
+	// AuditCmd combines audit log subcommands
+	AuditCmd AuditCmd
+	// AuditListCmd lists audit events
+	AuditListCmd AuditListCmd
 }
 
 // VersionCmd displays the binary version
 type VersionCmd struct {
 	*kingpin.CmdClause
-	// Output is output format
-	Output *constants.Format
 }
 
 // DNSConfig returns DNS configuration
@@ -394,6 +501,21 @@ type InstallCmd struct {
 	DNSHosts *[]string
 	// DNSZones is a list of DNS zone overrides
 	DNSZones *[]string
+	// TrustedKeys lists paths to additional public keys trusted to sign the installer
+	TrustedKeys *[]string
+	// RequireSignature rejects installers that are not signed
+	RequireSignature *bool
+	// CACertPath is the path to the intermediate certificate authority
+	// to issue the cluster certificates from
+	CACertPath *string
+	// CAKeyPath is the path to the private key of the intermediate certificate authority
+	CAKeyPath *string
+	// CASignerURL is the URL of the external certificate signer
+	CASignerURL *string
+	// FIPS installs the cluster in FIPS mode
+	FIPS *bool
+	// Proxy overrides the HTTP proxy settings of the environment
+	Proxy common.ProxyFlags
 }
 
 // JoinCmd joins to the installer or existing cluster
@@ -446,6 +568,50 @@ type AutoJoinCmd struct {
 	Mounts *configure.KeyVal
 }
 
+// ExpandCmd provisions new nodes with a cloud provisioner
+type ExpandCmd struct {
+	*kingpin.CmdClause
+	// Count is the number of nodes to provision
+	Count *int
+	// Profile is the node profile of the new nodes
+	Profile *string
+	// Provisioner is the name of the provisioner to create the machines with
+	Provisioner *string
+}
+
+// InfraCmd runs the Terraform configuration bundled with the cluster image
+// to provision the infrastructure before installation
+type InfraCmd struct {
+	*kingpin.CmdClause
+}
+
+// InfraPlanCmd displays the infrastructure changes the Terraform
+// configuration would make
+type InfraPlanCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to the unpacked installer
+	Path *string
+	// Provider selects the Terraform configuration from the manifest
+	Provider *string
+	// Vars overrides the configuration variables
+	Vars *map[string]string
+	// Terraform is the path to the terraform binary
+	Terraform *string
+}
+
+// InfraApplyCmd provisions the infrastructure with the Terraform configuration
+type InfraApplyCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to the unpacked installer
+	Path *string
+	// Provider selects the Terraform configuration from the manifest
+	Provider *string
+	// Vars overrides the configuration variables
+	Vars *map[string]string
+	// Terraform is the path to the terraform binary
+	Terraform *string
+}
+
 // LeaveCmd removes the current node from the cluster
 type LeaveCmd struct {
 	*kingpin.CmdClause
@@ -453,6 +619,10 @@ type LeaveCmd struct {
 	Force *bool
 	// Confirm suppresses confirmation prompt
 	Confirm *bool
+	// DrainTimeout is the maximum time to wait for the pods to be evicted
+	DrainTimeout *time.Duration
+	// ForceDrain deletes the pods that could not be evicted
+	ForceDrain *bool
 }
 
 // RemoveCmd removes the specified node from the cluster
@@ -464,6 +634,10 @@ type RemoveCmd struct {
 	Force *bool
 	// Confirm suppresses confirmation prompt
 	Confirm *bool
+	// DrainTimeout is the maximum time to wait for the pods to be evicted
+	DrainTimeout *time.Duration
+	// ForceDrain deletes the pods that could not be evicted
+	ForceDrain *bool
 }
 
 // PlanCmd manages an operation plan
@@ -478,8 +652,8 @@ type PlanCmd struct {
 // PlanDisplayCmd displays plan of a specific operation
 type PlanDisplayCmd struct {
 	*kingpin.CmdClause
-	// Output is output format
-	Output *constants.Format
+	// Format is the optional graph format to render the plan in
+	Format *string
 }
 
 // PlanExecuteCmd executes a phase of an active operation
@@ -491,6 +665,10 @@ type PlanExecuteCmd struct {
 	Force *bool
 	// PhaseTimeout is the execution timeout
 	PhaseTimeout *time.Duration
+	// Parallel limits the number of phases executed concurrently
+	Parallel *int
+	// DryRun only prints the phases that would be executed
+	DryRun *bool
 }
 
 // PlanRollbackCmd rolls back a phase of an active operation
@@ -511,6 +689,17 @@ type PlanResumeCmd struct {
 	Force *bool
 	// PhaseTimeout is the rollback timeout
 	PhaseTimeout *time.Duration
+	// Parallel limits the number of phases executed concurrently
+	Parallel *int
+	// DryRun only prints the phases that would be executed
+	DryRun *bool
+}
+
+// PlanLogsCmd displays the logs recorded by a phase of an operation
+type PlanLogsCmd struct {
+	*kingpin.CmdClause
+	// Phase is the ID of the phase to display logs for
+	Phase *string
 }
 
 // PlanCompleteCmd completes the operation plan
@@ -518,6 +707,16 @@ type PlanCompleteCmd struct {
 	*kingpin.CmdClause
 }
 
+// PlanPauseCmd pauses the active operation after the phase in progress
+type PlanPauseCmd struct {
+	*kingpin.CmdClause
+}
+
+// PlanCancelCmd cancels the active operation rolling back the phase in progress
+type PlanCancelCmd struct {
+	*kingpin.CmdClause
+}
+
 // InstallPlanCmd combines subcommands for install plan
 type InstallPlanCmd struct {
 	*kingpin.CmdClause
@@ -526,8 +725,6 @@ type InstallPlanCmd struct {
 // InstallPlanDisplayCmd displays install operation plan
 type InstallPlanDisplayCmd struct {
 	*kingpin.CmdClause
-	// Output is output format
-	Output *constants.Format
 }
 
 // UpgradePlanCmd combines subcommands for upgrade plan
@@ -538,8 +735,6 @@ type UpgradePlanCmd struct {
 // UpgradePlanDisplayCmd displays upgrade operation plan
 type UpgradePlanDisplayCmd struct {
 	*kingpin.CmdClause
-	// Output is output format
-	Output *constants.Format
 }
 
 // UpdateCmd combines update related subcommands
@@ -600,6 +795,18 @@ type UpdatePlanInitCmd struct {
 	*kingpin.CmdClause
 }
 
+// UpdateScheduledCmd lists scheduled update operations
+type UpdateScheduledCmd struct {
+	*kingpin.CmdClause
+}
+
+// UpdateCancelCmd cancels a scheduled update operation
+type UpdateCancelCmd struct {
+	*kingpin.CmdClause
+	// ID is the ID of the scheduled operation
+	ID *string
+}
+
 // UpgradeCmd launches app upgrade
 type UpgradeCmd struct {
 	*kingpin.CmdClause
@@ -620,6 +827,23 @@ type UpgradeCmd struct {
 	Resume *bool
 	// SkipVersionCheck suppresses version mismatch errors
 	SkipVersionCheck *bool
+	// Parallel limits the number of nodes updated concurrently
+	Parallel *int
+	// DrainTimeout is the maximum time to wait for the pods to be evicted
+	DrainTimeout *time.Duration
+	// ForceDrain deletes the pods that could not be evicted
+	ForceDrain *bool
+	// Canary is the number of regular nodes to update first
+	Canary *int
+	// CanaryPause pauses the operation after the canary nodes have been updated
+	CanaryPause *bool
+	// NotBefore schedules the operation to start at the specified time
+	NotBefore *string
+	// Window schedules the operation to start within a maintenance
+	// window opening at times specified with a cron expression
+	Window *string
+	// WindowDuration is how long the maintenance window stays open
+	WindowDuration *time.Duration
 }
 
 // StatusCmd displays cluster status
@@ -629,12 +853,43 @@ type StatusCmd struct {
 	Token *bool
 	// Tail follows current operation logs
 	Tail *bool
+	// Follow follows current operation progress
+	Follow *bool
 	// OperationID displays operation status
 	OperationID *string
 	// Seconds displays status continuously
 	Seconds *int
-	// Output is output format
-	Output *constants.Format
+	// Verbose displays the reasons and remediation hints of failed probes
+	Verbose *bool
+}
+
+// StatusClusterCmd displays the current cluster status
+type StatusClusterCmd struct {
+	*kingpin.CmdClause
+}
+
+// StatusHistoryCmd displays the history of cluster status transitions
+type StatusHistoryCmd struct {
+	*kingpin.CmdClause
+	// Since limits the history to the specified duration
+	Since *time.Duration
+	// All displays all recorded snapshots instead of only transitions
+	All *bool
+}
+
+// StatusRegistryCmd displays the health and the storage usage of the cluster registry
+type StatusRegistryCmd struct {
+	*kingpin.CmdClause
+}
+
+// StatusPackagesCmd displays the results of the last cluster package verification
+type StatusPackagesCmd struct {
+	*kingpin.CmdClause
+}
+
+// StatusLicenseCmd displays the status of the cluster license
+type StatusLicenseCmd struct {
+	*kingpin.CmdClause
 }
 
 // StatusResetCmd resets cluster to active state
@@ -642,7 +897,7 @@ type StatusResetCmd struct {
 	*kingpin.CmdClause
 }
 
-// BackupCmd launches app backup hook
+// BackupCmd backs up the cluster state and launches app backup hook
 type BackupCmd struct {
 	*kingpin.CmdClause
 	// Tarball is backup tarball name
@@ -653,7 +908,7 @@ type BackupCmd struct {
 	Follow *bool
 }
 
-// RestoreCmd launches app restore hook
+// RestoreCmd restores the cluster state and launches app restore hook
 type RestoreCmd struct {
 	*kingpin.CmdClause
 	// Tarball is tarball to restore from
@@ -662,6 +917,8 @@ type RestoreCmd struct {
 	Timeout *time.Duration
 	// Follow tails operation logs
 	Follow *bool
+	// Confirmed suppresses confirmation prompt
+	Confirmed *bool
 }
 
 // CheckCmd checks that the host satisfies app manifest requirements
@@ -673,6 +930,8 @@ type CheckCmd struct {
 	Profile *string
 	// AutoFix enables automatic fixing of some failed checks
 	AutoFix *bool
+	// Checks lists names of the checks to run
+	Checks *[]string
 }
 
 // AppCmd combines subcommands for app service
@@ -827,6 +1086,10 @@ type AppImportCmd struct {
 	SetDeps *loc.Locators
 	// Parallel defines the number of tasks to execute concurrently
 	Parallel *int
+	// TrustedKeys lists paths to additional public keys trusted to sign the application
+	TrustedKeys *[]string
+	// RequireSignature rejects applications that are not signed
+	RequireSignature *bool
 }
 
 // AppExportCmd exports specified app into registry
@@ -838,6 +1101,21 @@ type AppExportCmd struct {
 	RegistryURL *string
 	// OpsCenterURL is app service URL
 	OpsCenterURL *string
+	// Parallel defines the number of image layers to push concurrently
+	Parallel *int
+	// Format is the export format, registry or oci
+	Format *string
+	// OutputDir is the directory to write the OCI image layout to
+	OutputDir *string
+}
+
+// AppImportImagesCmd pushes images from an OCI image layout into registry
+type AppImportImagesCmd struct {
+	*kingpin.CmdClause
+	// Dir is the OCI image layout directory
+	Dir *string
+	// RegistryURL is Docker registry URL
+	RegistryURL *string
 }
 
 // AppDeleteCmd deletes the specified app
@@ -869,6 +1147,8 @@ type AppPackageUninstallCmd struct {
 	*kingpin.CmdClause
 	// Locator is the application locator
 	Locator *loc.Locator
+	// Force forcibly finalizes application resources left after uninstall hooks
+	Force *bool
 }
 
 // AppStatusCmd shows app status
@@ -924,6 +1204,45 @@ type AppUnpackCmd struct {
 	OpsCenterURL *string
 	// ServiceUID is user ID to change unpacked resources ownership to
 	ServiceUID *string
+	// ServiceGID is group ID to change unpacked resources ownership to
+	ServiceGID *string
+}
+
+// AppDiffCmd shows changes between application versions
+type AppDiffCmd struct {
+	*kingpin.CmdClause
+	// From is the application version to compare against
+	From *loc.Locator
+	// To is the application version to compare
+	To *loc.Locator
+	// OpsCenterURL is optional app service URL to read apps from
+	OpsCenterURL *string
+}
+
+// AppTrustedKeyCmd manages keys trusted to sign images
+type AppTrustedKeyCmd struct {
+	*kingpin.CmdClause
+}
+
+// AppTrustedKeyAddCmd adds a key trusted to sign images
+type AppTrustedKeyAddCmd struct {
+	*kingpin.CmdClause
+	// Name is the key name
+	Name *string
+	// Path is the path to the public key
+	Path *string
+}
+
+// AppTrustedKeyListCmd lists keys trusted to sign images
+type AppTrustedKeyListCmd struct {
+	*kingpin.CmdClause
+}
+
+// AppTrustedKeyRemoveCmd removes a key trusted to sign images
+type AppTrustedKeyRemoveCmd struct {
+	*kingpin.CmdClause
+	// Name is the key name
+	Name *string
 }
 
 // WizardCmd starts installer in UI mode
@@ -1114,6 +1433,35 @@ type PackLabelsCmd struct {
 	Remove *[]string
 }
 
+// PackMigrateCmd converts the local package store to the chunked storage
+type PackMigrateCmd struct {
+	*kingpin.CmdClause
+	// Dir is the package store directory
+	Dir *string
+}
+
+// PackGCCmd removes unused packages from the cluster package service
+type PackGCCmd struct {
+	*kingpin.CmdClause
+	// DryRun displays the packages to remove without removing them
+	DryRun *bool
+	// ProtectionWindow is the period after import during which
+	// unused packages are kept
+	ProtectionWindow *time.Duration
+}
+
+// PackVerifyCmd verifies the integrity of the stored packages
+type PackVerifyCmd struct {
+	*kingpin.CmdClause
+	// Cluster verifies the copy of the cluster packages stored on this node
+	// instead of the local package store
+	Cluster *bool
+	// Repair restores the data of corrupted packages
+	Repair *bool
+	// OpsCenterURL is the Ops Center to restore the package data from
+	OpsCenterURL *string
+}
+
 // UserCmd combines user related subcommands
 type UserCmd struct {
 	*kingpin.CmdClause
@@ -1166,6 +1514,72 @@ type UsersResetCmd struct {
 	TTL *time.Duration
 }
 
+// UsersTokenCmd combines user API token subcommands
+type UsersTokenCmd struct {
+	*kingpin.CmdClause
+}
+
+// UsersTokenCreateCmd creates a new user API token
+type UsersTokenCreateCmd struct {
+	*kingpin.CmdClause
+	// User is the user to create the token for, defaults to the current user
+	User *string
+	// Permissions optionally restricts the token, e.g. app:publish
+	Permissions *[]string
+	// TTL is the token TTL, the token does not expire if unset
+	TTL *time.Duration
+	// OpsCenterURL is the optional Ops Center URL
+	OpsCenterURL *string
+}
+
+// UsersTokenListCmd lists user API tokens
+type UsersTokenListCmd struct {
+	*kingpin.CmdClause
+	// User is the user to list the tokens for, defaults to the current user
+	User *string
+	// OpsCenterURL is the optional Ops Center URL
+	OpsCenterURL *string
+}
+
+// UsersTokenRemoveCmd revokes a user API token
+type UsersTokenRemoveCmd struct {
+	*kingpin.CmdClause
+	// Token is the token to revoke
+	Token *string
+	// User is the user the token belongs to, defaults to the current user
+	User *string
+	// OpsCenterURL is the optional Ops Center URL
+	OpsCenterURL *string
+}
+
+// JoinTokenCmd combines scoped join token subcommands
+type JoinTokenCmd struct {
+	*kingpin.CmdClause
+}
+
+// JoinTokenCreateCmd generates a new scoped join token
+type JoinTokenCreateCmd struct {
+	*kingpin.CmdClause
+	// TTL is the token TTL
+	TTL *time.Duration
+	// MaxUses is the maximum number of nodes that can join with the token
+	MaxUses *int
+	// Roles lists the service roles of the nodes allowed to join with the token
+	Roles *[]string
+}
+
+// JoinTokenListCmd lists active scoped join tokens
+type JoinTokenListCmd struct {
+	*kingpin.CmdClause
+}
+
+// JoinTokenRemoveCmd revokes a scoped join token
+type JoinTokenRemoveCmd struct {
+	*kingpin.CmdClause
+	// Token is the token to revoke
+	Token *string
+}
+
 // APIKeyCmd combines subcommands for API tokens
 type APIKeyCmd struct {
 	*kingpin.CmdClause
@@ -1205,6 +1619,12 @@ type ReportCmd struct {
 	*kingpin.CmdClause
 	// FilePath is the report tarball path
 	FilePath *string
+	// MaxFileSize is the maximum size of a single file in the report
+	MaxFileSize *utils.Capacity
+	// MaxSize is the maximum total size of the files in the report
+	MaxSize *utils.Capacity
+	// Operations is the number of recent operations to include
+	Operations *int
 }
 
 // SiteCmd combines cluster related subcommands
@@ -1247,8 +1667,6 @@ type SiteInfoCmd struct {
 	*kingpin.CmdClause
 	// DomainName is cluster name
 	DomainName *string
-	// Format is output format
-	Format *constants.Format
 }
 
 // SiteCompleteCmd marks cluster as finished final install step
@@ -1263,6 +1681,13 @@ type SiteResetPasswordCmd struct {
 	*kingpin.CmdClause
 }
 
+// SiteUpdateLicenseCmd replaces the license installed on the local cluster
+type SiteUpdateLicenseCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to the new license file
+	Path *string
+}
+
 // LocalSiteCmd displays local cluster name
 type LocalSiteCmd struct {
 	*kingpin.CmdClause
@@ -1333,6 +1758,38 @@ type SystemUninstallCmd struct {
 	Confirmed *bool
 }
 
+// SystemRegistryCmd combines subcommands for docker registries
+type SystemRegistryCmd struct {
+	*kingpin.CmdClause
+}
+
+// SystemRegistryReplicateCmd replicates images between docker registries
+type SystemRegistryReplicateCmd struct {
+	*kingpin.CmdClause
+	// Source is the address of the registry to replicate images from
+	Source *string
+	// SourceCA is the source registry CA certificate path
+	SourceCA *string
+	// SourceCert is the source registry client certificate path
+	SourceCert *string
+	// SourceKey is the source registry client private key path
+	SourceKey *string
+	// Destination is the address of the registry to replicate images to
+	Destination *string
+	// DestinationCA is the destination registry CA certificate path
+	DestinationCA *string
+	// DestinationCert is the destination registry client certificate path
+	DestinationCert *string
+	// DestinationKey is the destination registry client private key path
+	DestinationKey *string
+	// Repositories limits replication to the specified repositories
+	Repositories *[]string
+	// MissingOnly only replicates images whose tags are missing in the destination
+	MissingOnly *bool
+	// Parallel defines the number of images to replicate concurrently
+	Parallel *int
+}
+
 // SystemPullUpdatesCmd pulls updates for system packages
 type SystemPullUpdatesCmd struct {
 	*kingpin.CmdClause
@@ -1458,6 +1915,76 @@ type SystemStateDirCmd struct {
 	*kingpin.CmdClause
 }
 
+// SystemBackendCmd combines subcommands for the local cluster backend
+type SystemBackendCmd struct {
+	*kingpin.CmdClause
+}
+
+// SystemBackendSnapshotCmd saves etcd snapshot of the cluster backend
+type SystemBackendSnapshotCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to save the snapshot to
+	Path *string
+}
+
+// SystemBackendVerifyCmd verifies etcd snapshot of the cluster backend
+type SystemBackendVerifyCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to the snapshot
+	Path *string
+	// Prefix limits the keys to count to the specified prefix
+	Prefix *string
+}
+
+// SystemBackendRestoreCmd restores the cluster backend from etcd snapshot
+type SystemBackendRestoreCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to the snapshot
+	Path *string
+	// Prefix limits the keys to restore to the specified prefix
+	Prefix *string
+	// Confirmed suppresses confirmation prompt
+	Confirmed *bool
+}
+
+// SystemBackendMigrateCmd migrates the cluster backend data to etcd v3 API
+type SystemBackendMigrateCmd struct {
+	*kingpin.CmdClause
+	// Confirmed suppresses confirmation prompt
+	Confirmed *bool
+}
+
+// SystemBackendBackupCmd saves a copy of the local BoltDB backend
+type SystemBackendBackupCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to save the copy to
+	Path *string
+	// Compact compacts the backend before saving the copy
+	Compact *bool
+}
+
+// SystemBackendGenerateKeyCmd generates a new backend encryption key
+type SystemBackendGenerateKeyCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to the key file
+	Path *string
+	// Local only writes the key on this node
+	Local *bool
+}
+
+// SystemBackendReencryptCmd encrypts the backend values with the current key
+type SystemBackendReencryptCmd struct {
+	*kingpin.CmdClause
+	// Local selects the local state database instead of the cluster backend
+	Local *bool
+	// KeyFile is the path to the file with the current encryption key
+	KeyFile *string
+	// SSMParameter is the name of the AWS SSM parameter with the current encryption key
+	SSMParameter *string
+	// PreviousKeyFiles lists the files with the keys used before the rotation
+	PreviousKeyFiles *[]string
+}
+
 // SystemDevicemapperCmd combines devicemapper related subcommands
 type SystemDevicemapperCmd struct {
 	*kingpin.CmdClause
@@ -1549,11 +2076,92 @@ type GarbageCollectCmd struct {
 	Confirmed *bool
 }
 
+// CertsCmd combines cluster certificate subcommands
+type CertsCmd struct {
+	*kingpin.CmdClause
+}
+
+// CertsRotateCmd rotates certificates on all cluster nodes
+type CertsRotateCmd struct {
+	*kingpin.CmdClause
+	// KeepCA specifies whether to keep the existing certificate authority
+	// and only reissue the node certificates
+	KeepCA *bool
+	// Manual is whether the operation is not executed automatically
+	Manual *bool
+	// Confirmed suppresses confirmation prompt
+	Confirmed *bool
+}
+
+// PatchCmd applies configuration-only changes to the cluster
+type PatchCmd struct {
+	*kingpin.CmdClause
+	// Env specifies the runtime environment variables to set
+	Env *map[string]string
+	// DNSUpstreams specifies the new upstream nameservers for the cluster DNS
+	DNSUpstreams *[]string
+	// Manual is whether the operation is not executed automatically
+	Manual *bool
+	// Confirmed suppresses confirmation prompt
+	Confirmed *bool
+}
+
+// EtcdCmd combines subcommands for managing the cluster etcd
+type EtcdCmd struct {
+	*kingpin.CmdClause
+}
+
+// EtcdBackupCmd saves etcd snapshot
+type EtcdBackupCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to save the snapshot to
+	Path *string
+	// Dir is the directory to save timestamped snapshots to
+	Dir *string
+	// Keep is the number of timestamped snapshots to keep
+	Keep *int
+	// Interval is the interval between snapshots, the snapshot is
+	// taken once if unset
+	Interval *time.Duration
+}
+
+// EtcdRestoreCmd restores the cluster data from etcd snapshot
+type EtcdRestoreCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to the snapshot
+	Path *string
+	// Prefix limits the keys to restore to the specified prefix
+	Prefix *string
+	// Confirmed suppresses confirmation prompt
+	Confirmed *bool
+}
+
+// EtcdDefragCmd defragments the databases of etcd members
+type EtcdDefragCmd struct {
+	*kingpin.CmdClause
+}
+
+// EtcdStatusCmd displays the health of etcd members
+type EtcdStatusCmd struct {
+	*kingpin.CmdClause
+}
+
+// EtcdScheduleCmd configures periodic etcd snapshots on this node
+type EtcdScheduleCmd struct {
+	*kingpin.CmdClause
+	// Dir is the directory to save snapshots to
+	Dir *string
+	// Keep is the number of snapshots to keep
+	Keep *int
+	// Interval is the interval between snapshots
+	Interval *time.Duration
+	// Disable removes the schedule
+	Disable *bool
+}
+
 // GarbageCollectPlanCmd displays the plan of the garbage collection operation
 type GarbageCollectPlanCmd struct {
 	*kingpin.CmdClause
-	// Format is the output format
-	Format *constants.Format
 }
 
 // PlanetCmd combines planet subcommands
@@ -1594,6 +2202,8 @@ type ExecCmd struct {
 // ShellCmd is an alias for exec with -ti /bin/bash
 type ShellCmd struct {
 	*kingpin.CmdClause
+	// Record enables recording of the shell session
+	Record *bool
 }
 
 // ResourceCmd combines resource related subcommands
@@ -1644,10 +2254,20 @@ type ResourceGetCmd struct {
 	Kind *string
 	// Name is resource name
 	Name *string
-	// Format is output format
-	Format *constants.Format
 	// WithSecrets show normally hidden resource fields
 	WithSecrets *bool
 	// User is resource owner
 	User *string
 }
+
+// AuditCmd combines audit log subcommands
+type AuditCmd struct {
+	*kingpin.CmdClause
+}
+
+// AuditListCmd lists audit events recorded in the cluster
+type AuditListCmd struct {
+	*kingpin.CmdClause
+	// Since limits the events to those recorded within the specified duration
+	Since *time.Duration
+}
@@ -28,10 +28,12 @@ import (
 	"github.com/gravitational/gravity/lib/install"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/ops/resources"
 	"github.com/gravitational/gravity/lib/process"
 	"github.com/gravitational/gravity/lib/rpc/proto"
 	rpcserver "github.com/gravitational/gravity/lib/rpc/server"
+	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/clusterconfig"
 	"github.com/gravitational/gravity/lib/systeminfo"
@@ -110,6 +112,22 @@ type InstallConfig struct {
 	NodeTags []string
 	// NewProcess is used to launch gravity API server process
 	NewProcess process.NewGravityProcess
+	// TrustedKeys lists paths to additional public keys trusted to sign the installer
+	TrustedKeys []string
+	// RequireSignature rejects installers that are not signed
+	RequireSignature bool
+	// CACertPath is the path to the intermediate certificate authority
+	// to issue the cluster certificates from
+	CACertPath string
+	// CAKeyPath is the path to the private key of the intermediate certificate authority
+	CAKeyPath string
+	// CASignerURL is the URL of the external certificate signer
+	CASignerURL string
+	// FIPS specifies whether to install the cluster in FIPS mode
+	FIPS bool
+	// Proxy specifies the HTTP proxy settings for the installer
+	// and the cluster runtime environment
+	Proxy utils.ProxyConfig
 }
 
 // NewInstallConfig creates install config from the passed CLI args and flags
@@ -147,11 +165,18 @@ func NewInstallConfig(g *Application) InstallConfig {
 			StorageDriver: g.InstallCmd.DockerStorageDriver.value,
 			Args:          *g.InstallCmd.DockerArgs,
 		},
-		DNSConfig:  g.InstallCmd.DNSConfig(),
-		Manual:     *g.InstallCmd.Manual,
-		ServiceUID: *g.InstallCmd.ServiceUID,
-		ServiceGID: *g.InstallCmd.ServiceGID,
-		NodeTags:   *g.InstallCmd.GCENodeTags,
+		DNSConfig:        g.InstallCmd.DNSConfig(),
+		Manual:           *g.InstallCmd.Manual,
+		ServiceUID:       *g.InstallCmd.ServiceUID,
+		ServiceGID:       *g.InstallCmd.ServiceGID,
+		NodeTags:         *g.InstallCmd.GCENodeTags,
+		TrustedKeys:      *g.InstallCmd.TrustedKeys,
+		RequireSignature: *g.InstallCmd.RequireSignature,
+		CACertPath:       *g.InstallCmd.CACertPath,
+		CAKeyPath:        *g.InstallCmd.CAKeyPath,
+		CASignerURL:      *g.InstallCmd.CASignerURL,
+		FIPS:             *g.InstallCmd.FIPS,
+		Proxy:            g.InstallCmd.Proxy.Config(),
 	}
 }
 
@@ -191,6 +216,9 @@ func (i *InstallConfig) CheckAndSetDefaults() (err error) {
 		}
 		log.Infof("Generated install token: %v.", i.InstallToken)
 	}
+	if err := i.setServiceUserDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
 	serviceUser, err := install.GetOrCreateServiceUser(i.ServiceUID, i.ServiceGID)
 	if err != nil {
 		return trace.Wrap(err)
@@ -201,6 +229,15 @@ func (i *InstallConfig) CheckAndSetDefaults() (err error) {
 	if err := i.validateDNSConfig(); err != nil {
 		return trace.Wrap(err)
 	}
+	if err := i.validateCertAuthority(); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := i.validateFIPS(); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := i.Proxy.Setenv(); err != nil {
+		return trace.Wrap(err)
+	}
 	i.ServiceUser = *serviceUser
 	if i.NewProcess == nil {
 		i.NewProcess = process.NewProcess
@@ -252,6 +289,56 @@ func (i *InstallConfig) GetAppPackage() (*loc.Locator, error) {
 	return locator, nil
 }
 
+// setServiceUserDefaults sets the service user and group IDs that have not
+// been specified on the command line to the values from the application manifest
+// or the defaults. The IDs are validated to not collide with existing users and groups
+func (i *InstallConfig) setServiceUserDefaults() error {
+	if i.ServiceUID == "" || i.ServiceGID == "" {
+		manifest, err := i.getAppManifest()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		uid, gid := manifest.ServiceUser()
+		if i.ServiceUID == "" {
+			i.ServiceUID = uid
+		}
+		if i.ServiceGID == "" {
+			i.ServiceGID = gid
+		}
+	}
+	err := systeminfo.CheckUser(defaults.ServiceUser, defaults.ServiceUserGroup,
+		i.ServiceUID, i.ServiceGID)
+	if err != nil {
+		return trace.Wrap(err, "invalid service user configuration, "+
+			"use --service-uid/--service-gid to specify a different user or group")
+	}
+	if i.ServiceUID == "" {
+		i.ServiceUID = defaults.ServiceUserID
+	}
+	if i.ServiceGID == "" {
+		i.ServiceGID = defaults.ServiceGroupID
+	}
+	return nil
+}
+
+// getAppManifest returns the manifest of the application being installed
+func (i *InstallConfig) getAppManifest() (*schema.Manifest, error) {
+	locator, err := i.GetAppPackage()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	env, err := localenv.New(i.ReadStateDir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer env.Close()
+	app, err := env.Apps.GetApp(*locator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &app.Manifest, nil
+}
+
 // GetResouces returns additional Kubernetes resources
 func (i *InstallConfig) GetResources() ([]byte, error) {
 	if i.ResourcesPath == "" {
@@ -313,6 +400,14 @@ func (i *InstallConfig) ToInstallerConfig(env *localenv.LocalEnvironment, valida
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	gravityResources, err = i.updateRuntimeEnvironment(gravityResources, advertiseAddr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	certAuthority, err := i.getCertAuthority()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	return &install.Config{
 		Context:            ctx,
@@ -351,6 +446,8 @@ func (i *InstallConfig) ToInstallerConfig(env *localenv.LocalEnvironment, valida
 		LocalClusterClient: env.SiteOperator,
 		RuntimeResources:   kubernetesResources,
 		ClusterResources:   gravityResources,
+		CertAuthority:      certAuthority,
+		FIPS:               i.FIPS,
 	}, nil
 }
 
@@ -418,6 +515,61 @@ func (i *InstallConfig) updateClusterConfig(resources []storage.UnknownResource)
 	return updated, nil
 }
 
+// updateRuntimeEnvironment propagates the HTTP proxy settings of the installer
+// into the cluster runtime environment.
+// The variables explicitly set in the runtime environment resource take precedence.
+// Cluster-local addresses are always accessed without the proxy
+func (i *InstallConfig) updateRuntimeEnvironment(resources []storage.UnknownResource, advertiseAddr string) (updated []storage.UnknownResource, err error) {
+	if i.Proxy.IsEmpty() {
+		// Return the resources unchanged
+		return resources, nil
+	}
+	env := make(map[string]string)
+	updated = resources[:0]
+	for _, res := range resources {
+		if res.Kind == storage.KindRuntimeEnvironment {
+			runtimeEnv, err := storage.UnmarshalEnvironmentVariables(res.Raw)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			env = runtimeEnv.GetKeyValues()
+			continue
+		}
+		updated = append(updated, res)
+	}
+	proxy := i.Proxy.WithNoProxy(i.clusterLocalHosts(advertiseAddr)...)
+	for name, value := range proxy.Env() {
+		if _, ok := env[name]; !ok {
+			env[name] = value
+		}
+	}
+	bytes, err := storage.MarshalEnvironment(storage.NewEnvironment(env))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var envResource storage.UnknownResource
+	if err := envResource.UnmarshalJSON(bytes); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	updated = append(updated, envResource)
+	return updated, nil
+}
+
+// clusterLocalHosts returns the hosts, domains and subnets of the cluster
+// that are accessed without the proxy
+func (i *InstallConfig) clusterLocalHosts(advertiseAddr string) []string {
+	hosts := []string{"localhost", "127.0.0.1", advertiseAddr, ".local", ".svc"}
+	podCIDR, serviceCIDR := i.PodCIDR, i.ServiceCIDR
+	if podCIDR == "" {
+		podCIDR = defaults.PodSubnet
+	}
+	if serviceCIDR == "" {
+		serviceCIDR = defaults.ServiceSubnet
+	}
+	hosts = append(hosts, utils.SplitSubnets(podCIDR)...)
+	return append(hosts, utils.SplitSubnets(serviceCIDR)...)
+}
+
 func (i *InstallConfig) validateDNSConfig() error {
 	blocks, err := utils.LocalIPNetworks()
 	if err != nil {
@@ -433,6 +585,64 @@ func (i *InstallConfig) validateDNSConfig() error {
 	return nil
 }
 
+// validateCertAuthority makes sure the intermediate certificate authority
+// flags are consistent
+func (i *InstallConfig) validateCertAuthority() error {
+	if i.CACertPath == "" {
+		if i.CAKeyPath != "" || i.CASignerURL != "" {
+			return trace.BadParameter("--ca-key and --ca-signer-url require --ca-cert")
+		}
+		return nil
+	}
+	if i.CAKeyPath == "" && i.CASignerURL == "" {
+		return trace.BadParameter("--ca-cert requires either --ca-key or --ca-signer-url")
+	}
+	if i.CAKeyPath != "" && i.CASignerURL != "" {
+		return trace.BadParameter("--ca-key and --ca-signer-url are mutually exclusive")
+	}
+	return nil
+}
+
+// validateFIPS makes sure the FIPS mode of the installer binary
+// matches the requested FIPS mode of the cluster
+func (i *InstallConfig) validateFIPS() error {
+	if i.FIPS && !utils.IsFIPS() {
+		return trace.BadParameter("--fips requires the installer built " +
+			"with FIPS-validated crypto, see tele build --fips")
+	}
+	if !i.FIPS && utils.IsFIPS() {
+		return trace.BadParameter("the installer has been built with " +
+			"FIPS-validated crypto, use --fips to install the cluster in FIPS mode")
+	}
+	return nil
+}
+
+// getCertAuthority returns the user-provided intermediate certificate authority
+// or nil, if the cluster certificate authority is to be generated
+func (i *InstallConfig) getCertAuthority() (*ops.ExternalCertAuthority, error) {
+	if i.CACertPath == "" {
+		return nil, nil
+	}
+	certPEM, err := ioutil.ReadFile(i.CACertPath)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	certAuthority := &ops.ExternalCertAuthority{
+		CertPEM:   certPEM,
+		SignerURL: i.CASignerURL,
+	}
+	if i.CAKeyPath != "" {
+		certAuthority.KeyPEM, err = ioutil.ReadFile(i.CAKeyPath)
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+	}
+	if err := certAuthority.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return certAuthority, nil
+}
+
 func validateIP(blocks []net.IPNet, ip net.IP) bool {
 	for _, block := range blocks {
 		if block.Contains(ip) {
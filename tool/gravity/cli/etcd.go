@@ -0,0 +1,223 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+	"github.com/gravitational/gravity/lib/systemservice"
+	"github.com/gravitational/gravity/tool/common"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/gravitational/trace"
+)
+
+// backupEtcd saves etcd snapshot at path.
+// If path is empty, a timestamped snapshot is saved to dir and all but
+// the keep most recent snapshots are removed. With a non-zero interval,
+// timestamped snapshots are saved until the process is terminated
+func backupEtcd(env *localenv.LocalEnvironment, path, dir string, keep int, interval time.Duration) error {
+	if path != "" {
+		if interval != 0 {
+			return trace.BadParameter("--interval can only be used with snapshots saved to --dir")
+		}
+		return trace.Wrap(saveBackendSnapshot(env, path))
+	}
+	if keep < 1 {
+		return trace.BadParameter("--keep should be at least 1, got %v", keep)
+	}
+	if interval < 0 {
+		return trace.BadParameter("--interval should be positive, got %v", interval)
+	}
+	if err := os.MkdirAll(dir, defaults.PrivateDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if interval == 0 {
+		return trace.Wrap(saveTimestampedSnapshot(env, dir, keep))
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// Keep running on failure so a transient etcd outage
+		// does not stop the periodic snapshots
+		if err := saveTimestampedSnapshot(env, dir, keep); err != nil {
+			log.WithError(err).Warn("Failed to save etcd snapshot.")
+		}
+		<-ticker.C
+	}
+}
+
+// saveTimestampedSnapshot saves etcd snapshot named after the current time
+// to dir and removes all but the keep most recent snapshots
+func saveTimestampedSnapshot(env *localenv.LocalEnvironment, dir string, keep int) error {
+	path := filepath.Join(dir, keyval.SnapshotFilename(time.Now()))
+	if err := saveBackendSnapshot(env, path); err != nil {
+		return trace.Wrap(err)
+	}
+	removed, err := keyval.PruneSnapshots(dir, keep)
+	for _, path := range removed {
+		env.Printf("Removed old snapshot %v.\n", path)
+	}
+	return trace.Wrap(err)
+}
+
+// scheduleEtcdBackup installs a system service on this node that saves
+// etcd snapshots to dir with the specified interval
+func scheduleEtcdBackup(env *localenv.LocalEnvironment, dir string, keep int, interval time.Duration) error {
+	if keep < 1 {
+		return trace.BadParameter("--keep should be at least 1, got %v", keep)
+	}
+	if interval <= 0 {
+		return trace.BadParameter("--interval should be positive, got %v", interval)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	gravityPath, err := exec.LookPath(constants.GravityBin)
+	if err != nil {
+		return trace.Wrap(err, "failed to find %v binary in PATH",
+			constants.GravityBin)
+	}
+	services, err := systemservice.New()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = services.InstallService(systemservice.NewServiceRequest{
+		Name:    defaults.EtcdBackupServiceName,
+		NoBlock: true,
+		ServiceSpec: systemservice.ServiceSpec{
+			User: constants.RootUIDString,
+			StartCommand: fmt.Sprintf("%v etcd backup --dir=%v --keep=%v --interval=%v",
+				gravityPath, dir, keep, interval),
+			Restart:    "always",
+			RestartSec: defaults.SystemServiceRestartSec,
+			WantedBy:   defaults.SystemServiceWantedBy,
+		},
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Etcd snapshots will be saved to %v every %v, keeping the last %v.\n",
+		dir, interval, keep)
+	return nil
+}
+
+// unscheduleEtcdBackup removes the system service that saves
+// periodic etcd snapshots on this node
+func unscheduleEtcdBackup(env *localenv.LocalEnvironment) error {
+	services, err := systemservice.New()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := services.UninstallService(defaults.EtcdBackupServiceName); err != nil {
+		return trace.Wrap(err)
+	}
+	env.Println("Periodic etcd snapshots have been disabled.")
+	return nil
+}
+
+// defragEtcd defragments the databases of all etcd members
+func defragEtcd(env *localenv.LocalEnvironment) error {
+	config, err := keyval.LocalEtcdConfig(0)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaults.EtcdSnapshotTimeout)
+	defer cancel()
+	before, err := keyval.EtcdMembers(ctx, *config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	sizes := make(map[string]int64, len(before))
+	for _, member := range before {
+		sizes[member.ID] = member.DBSize
+	}
+	after, err := keyval.DefragmentEtcd(ctx, *config)
+	for _, member := range after {
+		if member.Healthy {
+			env.Printf("Member %v: database size %v -> %v.\n", member.Name,
+				humanize.Bytes(uint64(sizes[member.ID])), humanize.Bytes(uint64(member.DBSize)))
+		}
+	}
+	return trace.Wrap(err)
+}
+
+// printEtcdStatus displays the health and database size of etcd members
+func printEtcdStatus(env *localenv.LocalEnvironment, format constants.Format) error {
+	members, err := queryEtcdMembers()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, members))
+	case constants.EncodingText:
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		printEtcdMembers(members, w)
+		return trace.Wrap(w.Flush())
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+}
+
+// queryEtcdMembers returns the status of the members of the local etcd cluster
+func queryEtcdMembers() ([]keyval.EtcdMemberStatus, error) {
+	config, err := keyval.LocalEtcdConfig(0)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaults.EtcdStatusTimeout)
+	defer cancel()
+	members, err := keyval.EtcdMembers(ctx, *config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return members, nil
+}
+
+func printEtcdMembers(members []keyval.EtcdMemberStatus, w io.Writer) {
+	fmt.Fprintln(w, "Etcd members:")
+	for _, member := range members {
+		fmt.Fprintf(w, "    * %v (%v)\n", unknownFallback(member.Name), unknownFallback(member.Endpoint))
+		if !member.Healthy {
+			fmt.Fprintf(w, "        Status:\t%v\n", color.RedString("unhealthy"))
+			fmt.Fprintf(w, "        [%v]\t%v\n", constants.FailureMark, color.RedString(member.Error))
+			continue
+		}
+		status := color.GreenString("healthy")
+		if member.Leader {
+			status = fmt.Sprintf("%v, leader", status)
+		}
+		fmt.Fprintf(w, "        Status:\t%v\n", status)
+		fmt.Fprintf(w, "        DB size:\t%v\n", humanize.Bytes(uint64(member.DBSize)))
+		fmt.Fprintf(w, "        Version:\t%v\n", member.Version)
+	}
+}
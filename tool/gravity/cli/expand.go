@@ -0,0 +1,66 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+
+	"github.com/gravitational/trace"
+)
+
+// provisionNodes creates the machines of new cluster nodes with the specified
+// provisioner. The machines join the cluster once they have booted
+func provisionNodes(env *localenv.LocalEnvironment, provisioner, profile string, count int) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if provisioner == "" {
+		provisioners, err := operator.GetProvisioners(cluster.Key())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if len(provisioners) != 1 {
+			return trace.BadParameter("cluster has %v provisioners, specify one with --provisioner",
+				len(provisioners))
+		}
+		provisioner = provisioners[0].GetName()
+	}
+	env.Printf("Provisioning %v %v node(s) with %v.\n", count, profile, provisioner)
+	resp, err := operator.ProvisionNodes(context.TODO(), ops.ProvisionNodesRequest{
+		SiteKey:     cluster.Key(),
+		Provisioner: provisioner,
+		Profile:     profile,
+		Count:       count,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, machine := range resp.Machines {
+		env.Printf("Created machine %v (%v).\n", machine.Name, machine.ID)
+	}
+	env.Println("The nodes will join the cluster once the machines have booted, " +
+		"watch the progress with 'gravity status'.")
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/catalog"
@@ -36,6 +37,7 @@ import (
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/schema"
 	helmutils "github.com/gravitational/gravity/lib/utils/helm"
+	"github.com/gravitational/gravity/tool/common"
 
 	"github.com/ghodss/yaml"
 	"github.com/gravitational/trace"
@@ -97,6 +99,8 @@ type releaseUninstallConfig struct {
 type releaseHistoryConfig struct {
 	// Release is a release name to display revisions for.
 	Release string
+	// Format is the output format
+	Format constants.Format
 }
 
 type valuesConfig struct {
@@ -187,7 +191,7 @@ func releaseInstall(env *localenv.LocalEnvironment, conf releaseInstallConfig) e
 	return nil
 }
 
-func releaseList(env *localenv.LocalEnvironment, all bool) error {
+func releaseList(env *localenv.LocalEnvironment, all bool, format constants.Format) error {
 	helmClient, err := helm.NewClient(helm.ClientConfig{
 		DNSAddress: env.DNS.Addr(),
 	})
@@ -201,6 +205,20 @@ func releaseList(env *localenv.LocalEnvironment, all bool) error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if format != constants.EncodingText {
+		infos := make([]releaseInfo, 0, len(releases))
+		for _, r := range releases {
+			infos = append(infos, releaseInfo{
+				Name:      r.GetName(),
+				Status:    r.GetStatus(),
+				Chart:     r.GetChart(),
+				Revision:  r.GetRevision(),
+				Namespace: r.GetMetadata().Namespace,
+				Updated:   r.GetUpdated(),
+			})
+		}
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, infos))
+	}
 	w := new(tabwriter.Writer)
 	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
 	fmt.Fprintf(w, "Release\tStatus\tChart\tRevision\tNamespace\tUpdated\n")
@@ -218,6 +236,22 @@ func releaseList(env *localenv.LocalEnvironment, all bool) error {
 	return nil
 }
 
+// releaseInfo describes an application release in machine-readable output
+type releaseInfo struct {
+	// Name is the release name
+	Name string `json:"name"`
+	// Status is the release status
+	Status string `json:"status"`
+	// Chart is the release chart name and version
+	Chart string `json:"chart"`
+	// Revision is the release revision number
+	Revision int `json:"revision"`
+	// Namespace is the namespace the release is deployed to
+	Namespace string `json:"namespace"`
+	// Updated is when the release was last updated
+	Updated time.Time `json:"updated"`
+}
+
 func releaseUpgrade(env *localenv.LocalEnvironment, conf releaseUpgradeConfig) error {
 	err := conf.setDefaults(env)
 	if err != nil {
@@ -333,6 +367,19 @@ func releaseHistory(env *localenv.LocalEnvironment, conf releaseHistoryConfig) e
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if conf.Format != constants.EncodingText {
+		revisions := make([]revisionInfo, 0, len(releases))
+		for _, r := range releases {
+			revisions = append(revisions, revisionInfo{
+				Revision:    r.GetRevision(),
+				Chart:       r.GetChart(),
+				Status:      r.GetStatus(),
+				Updated:     r.GetUpdated(),
+				Description: r.GetMetadata().Description,
+			})
+		}
+		return trace.Wrap(common.PrintStructured(os.Stdout, conf.Format, revisions))
+	}
 	w := new(tabwriter.Writer)
 	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
 	fmt.Fprintf(w, "Revision\tChart\tStatus\tUpdated\tDescription\n")
@@ -349,7 +396,21 @@ func releaseHistory(env *localenv.LocalEnvironment, conf releaseHistoryConfig) e
 	return nil
 }
 
-func appSearch(env *localenv.LocalEnvironment, pattern string, remoteOnly, all bool) error {
+// revisionInfo describes a release revision in machine-readable output
+type revisionInfo struct {
+	// Revision is the revision number
+	Revision int `json:"revision"`
+	// Chart is the chart name and version of the revision
+	Chart string `json:"chart"`
+	// Status is the revision status
+	Status string `json:"status"`
+	// Updated is when the revision was deployed
+	Updated time.Time `json:"updated"`
+	// Description describes the revision
+	Description string `json:"description"`
+}
+
+func appSearch(env *localenv.LocalEnvironment, pattern string, remoteOnly, all bool, format constants.Format) error {
 	result, err := catalog.Search(catalog.SearchRequest{
 		Pattern: pattern,
 		Local:   !remoteOnly || all,
@@ -358,6 +419,23 @@ func appSearch(env *localenv.LocalEnvironment, pattern string, remoteOnly, all b
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if format != constants.EncodingText {
+		apps := []searchResult{}
+		for repository, items := range result.Apps {
+			for _, app := range items {
+				if app.Manifest.Kind == schema.KindApplication {
+					apps = append(apps, searchResult{
+						Repository:  repository,
+						Name:        app.Package.Name,
+						Version:     app.Package.Version,
+						Description: app.Manifest.Metadata.Description,
+						Created:     app.PackageEnvelope.Created,
+					})
+				}
+			}
+		}
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, apps))
+	}
 	w := new(tabwriter.Writer)
 	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
 	fmt.Fprintf(w, "Name\tVersion\tDescription\tCreated\n")
@@ -378,6 +456,21 @@ func appSearch(env *localenv.LocalEnvironment, pattern string, remoteOnly, all b
 	return nil
 }
 
+// searchResult describes an application found by search
+// in machine-readable output
+type searchResult struct {
+	// Repository is the repository the application was found in
+	Repository string `json:"repository"`
+	// Name is the application name
+	Name string `json:"name"`
+	// Version is the application version
+	Version string `json:"version"`
+	// Description is the application description
+	Description string `json:"description"`
+	// Created is when the application was published
+	Created time.Time `json:"created"`
+}
+
 func appRebuildIndex(env *localenv.LocalEnvironment) error {
 	env.PrintStep("Rebuilding charts repository index, this might take a while...")
 	clusterEnv, err := env.NewClusterEnvironment()
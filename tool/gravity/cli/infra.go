@@ -0,0 +1,129 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/install"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/terraform"
+
+	"github.com/gravitational/trace"
+)
+
+// infraConfig defines the Terraform configuration to execute
+type infraConfig struct {
+	// stateDir is the directory with the unpacked installer
+	stateDir string
+	// provider selects the Terraform configuration from the manifest
+	provider string
+	// variables overrides the configuration variables
+	variables map[string]string
+	// binary is the path to the terraform binary
+	binary string
+}
+
+// planInfra displays the infrastructure changes the Terraform configuration
+// bundled with the cluster image would make
+func planInfra(env *localenv.LocalEnvironment, config infraConfig) error {
+	return trace.Wrap(runTerraform(env, config, terraform.Plan))
+}
+
+// applyInfra provisions the infrastructure with the Terraform configuration
+// bundled with the cluster image
+func applyInfra(env *localenv.LocalEnvironment, config infraConfig) error {
+	err := runTerraform(env, config, terraform.Apply)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Println("Infrastructure has been provisioned, install the cluster " +
+		"on the new machines with 'gravity install'.")
+	return nil
+}
+
+func runTerraform(env *localenv.LocalEnvironment, config infraConfig, run func(context.Context, terraform.Config) error) error {
+	if config.stateDir == "" {
+		var err error
+		if config.stateDir, err = os.Getwd(); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	installerEnv, err := localenv.New(config.stateDir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer installerEnv.Close()
+	locator, err := install.GetAppPackage(installerEnv.Apps)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("the specified directory %v does not "+
+				"contain application data, please provide a path to the "+
+				"unpacked installer tarball", config.stateDir)
+		}
+		return trace.Wrap(err)
+	}
+	app, err := installerEnv.Apps.GetApp(*locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	provider, spec, err := getTerraform(app.Manifest, config.provider)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.PrintStep("Running Terraform configuration for %v", provider)
+	return trace.Wrap(run(context.TODO(), terraform.Config{
+		Name:      provider,
+		Terraform: *spec,
+		Variables: config.variables,
+		Backend:   installerEnv.Backend,
+		Binary:    config.binary,
+		Out:       os.Stdout,
+	}))
+}
+
+// getTerraform returns the Terraform configuration for the specified provider
+// from the manifest. If the provider is not specified, the manifest must
+// have a single Terraform configuration
+func getTerraform(manifest schema.Manifest, provider string) (string, *schema.Terraform, error) {
+	if manifest.Providers == nil {
+		return "", nil, trace.NotFound("%v does not bundle Terraform configuration",
+			manifest.Locator())
+	}
+	if provider == "" {
+		providers := manifest.Providers.TerraformProviders()
+		switch len(providers) {
+		case 0:
+			return "", nil, trace.NotFound("%v does not bundle Terraform configuration",
+				manifest.Locator())
+		case 1:
+			provider = providers[0]
+		default:
+			return "", nil, trace.BadParameter("%v bundles Terraform configuration for %v, "+
+				"select one with --provider", manifest.Locator(), strings.Join(providers, ", "))
+		}
+	}
+	spec := manifest.Providers.Terraform(provider)
+	if spec == nil {
+		return "", nil, trace.NotFound("%v does not bundle Terraform configuration for %v",
+			manifest.Locator(), provider)
+	}
+	return provider, spec, nil
+}
@@ -59,6 +59,11 @@ func startInstall(env *localenv.LocalEnvironment, i InstallConfig) error {
 		return trace.Wrap(err)
 	}
 
+	err = verifyImageSignature(env, i.ReadStateDir, i.TrustedKeys, i.RequireSignature)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	installerConfig, err := i.ToInstallerConfig(env, resources.ValidateFunc(gravity.Validate))
 	if err != nil {
 		return trace.Wrap(err)
@@ -136,6 +141,8 @@ func Join(env, joinEnv *localenv.LocalEnvironment, j JoinConfig) error {
 type leaveConfig struct {
 	force     bool
 	confirmed bool
+	// drain specifies the parameters for draining the node
+	drain storage.DrainOptions
 }
 
 func leave(env *localenv.LocalEnvironment, c leaveConfig) error {
@@ -193,6 +200,7 @@ func tryLeave(env *localenv.LocalEnvironment, c leaveConfig) error {
 		server:    server.Hostname,
 		confirmed: true,
 		force:     c.force,
+		drain:     c.drain,
 	})
 	if err != nil {
 		return trace.BadParameter(
@@ -206,13 +214,15 @@ func (r *removeConfig) checkAndSetDefaults() error {
 	if r.server == "" {
 		return trace.BadParameter("server flag is required")
 	}
-	return nil
+	return trace.Wrap(r.drain.Check())
 }
 
 type removeConfig struct {
 	server    string
 	force     bool
 	confirmed bool
+	// drain specifies the parameters for draining the node
+	drain storage.DrainOptions
 }
 
 func remove(env *localenv.LocalEnvironment, c removeConfig) error {
@@ -253,6 +263,7 @@ func remove(env *localenv.LocalEnvironment, c removeConfig) error {
 			SiteDomain: site.Domain,
 			Servers:    []string{server.Hostname},
 			Force:      c.force,
+			Drain:      &c.drain,
 		})
 	if err != nil {
 		return trace.Wrap(err)
@@ -0,0 +1,128 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/tool/common"
+
+	"github.com/gravitational/trace"
+)
+
+// createJoinToken generates a new join token limited in time, number of uses
+// or roles of the joining nodes
+func createJoinToken(env *localenv.LocalEnvironment, ttl time.Duration, maxUses int, roles []string) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	token, err := operator.CreateJoinToken(context.TODO(), ops.CreateJoinTokenRequest{
+		SiteKey: cluster.Key(),
+		TTL:     ttl,
+		MaxUses: maxUses,
+		Roles:   utils.FlattenStringSlice(roles),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Join token %v has been created, it expires at %v.\n",
+		token.Token, token.Expires.Format(constants.HumanDateFormatSeconds))
+	return nil
+}
+
+// listJoinTokens displays the active join tokens of the local cluster
+func listJoinTokens(env *localenv.LocalEnvironment, format constants.Format) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	tokens, err := operator.GetJoinTokens(context.TODO(), cluster.Key())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, tokens))
+	case constants.EncodingText:
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "Token\tExpires\tUses\tRoles\n")
+		fmt.Fprintf(w, "-----\t-------\t----\t-----\n")
+		for _, token := range tokens {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", token.Token,
+				token.Expires.Format(constants.HumanDateFormatSeconds),
+				formatJoinTokenUses(token), formatJoinTokenRoles(token))
+		}
+		return trace.Wrap(w.Flush())
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+}
+
+// removeJoinToken revokes the specified join token
+func removeJoinToken(env *localenv.LocalEnvironment, token string) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = operator.DeleteJoinToken(context.TODO(), ops.DeleteJoinTokenRequest{
+		SiteKey: cluster.Key(),
+		Token:   token,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Join token %v revoked\n", token)
+	return nil
+}
+
+func formatJoinTokenUses(token storage.ProvisioningToken) string {
+	if token.MaxUses == 0 {
+		return fmt.Sprintf("%v", token.Uses)
+	}
+	return fmt.Sprintf("%v/%v", token.Uses, token.MaxUses)
+}
+
+func formatJoinTokenRoles(token storage.ProvisioningToken) string {
+	if len(token.Roles) == 0 {
+		return "any"
+	}
+	return strings.Join(token.Roles, ",")
+}
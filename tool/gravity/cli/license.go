@@ -0,0 +1,100 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/tool/common"
+
+	"github.com/gravitational/trace"
+)
+
+// updateLicense replaces the license installed on the local cluster
+// with the license read from the specified file
+func updateLicense(env *localenv.LocalEnvironment, path string) error {
+	license, err := ioutil.ReadFile(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = operator.UpdateLicense(context.TODO(), ops.UpdateLicenseRequest{
+		AccountID:  cluster.AccountID,
+		SiteDomain: cluster.Domain,
+		License:    string(license),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Println("License has been updated.")
+	return nil
+}
+
+// statusLicense displays the status of the license installed on the local cluster
+func statusLicense(env *localenv.LocalEnvironment, format constants.Format) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if cluster.License == nil {
+		return trace.NotFound("cluster %v does not have a license", cluster.Domain)
+	}
+	status := cluster.License.Status
+	switch format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, status))
+	case constants.EncodingText:
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "Status:\t%v\n", status.State)
+		if status.MaxNodes != 0 {
+			fmt.Fprintf(w, "Nodes:\t%v of %v\n", status.Nodes, status.MaxNodes)
+		} else {
+			fmt.Fprintf(w, "Nodes:\t%v\n", status.Nodes)
+		}
+		if !status.Expiration.IsZero() {
+			fmt.Fprintf(w, "Expires:\t%v\n", status.Expiration.Format(constants.ShortDateFormat))
+		}
+		if !status.GracePeriodEnd.IsZero() {
+			fmt.Fprintf(w, "Grace period ends:\t%v\n", status.GracePeriodEnd.Format(constants.ShortDateFormat))
+		}
+		if status.Reason != "" {
+			fmt.Fprintf(w, "Reason:\t%v\n", status.Reason)
+		}
+		return trace.Wrap(w.Flush())
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+}
@@ -17,10 +17,12 @@ limitations under the License.
 package cli
 
 import (
+	"context"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
@@ -43,6 +45,11 @@ type PhaseParams struct {
 	Timeout time.Duration
 	// SkipVersionCheck overrides the verification of binary version compatibility
 	SkipVersionCheck bool
+	// Parallel limits the number of phases executed concurrently.
+	// If unspecified, the operation default is used
+	Parallel int
+	// DryRun only prints the phases that would be executed
+	DryRun bool
 }
 
 func executePhase(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, params PhaseParams) error {
@@ -50,6 +57,9 @@ func executePhase(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, param
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if params.DryRun {
+		return dryRunPhase(localEnv, updateEnv, joinEnv, params, *op)
+	}
 	switch op.Type {
 	case ops.OperationInstall:
 		return executeInstallPhase(localEnv, params, op)
@@ -61,6 +71,10 @@ func executePhase(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, param
 		return executeEnvironPhase(localEnv, updateEnv, params, *op)
 	case ops.OperationUpdateConfig:
 		return executeConfigPhase(localEnv, updateEnv, params, *op)
+	case ops.OperationRotateCertificates:
+		return executeCertsPhase(localEnv, updateEnv, params, *op)
+	case ops.OperationPatch:
+		return executePatchPhase(localEnv, updateEnv, params, *op)
 	case ops.OperationGarbageCollect:
 		return executeGarbageCollectPhase(localEnv, params, op)
 	default:
@@ -84,6 +98,10 @@ func rollbackPhase(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, para
 		return rollbackEnvironPhase(localEnv, updateEnv, params, *op)
 	case ops.OperationUpdateConfig:
 		return rollbackConfigPhase(localEnv, updateEnv, params, *op)
+	case ops.OperationRotateCertificates:
+		return rollbackCertsPhase(localEnv, updateEnv, params, *op)
+	case ops.OperationPatch:
+		return rollbackPatchPhase(localEnv, updateEnv, params, *op)
 	default:
 		return trace.BadParameter("operation type %q does not support plan rollback", op.Type)
 	}
@@ -106,11 +124,93 @@ func completeOperationPlan(localEnv, updateEnv, joinEnv *localenv.LocalEnvironme
 		return completeEnvironPlan(localEnv, updateEnv, *op)
 	case ops.OperationUpdateConfig:
 		return completeConfigPlan(localEnv, updateEnv, *op)
+	case ops.OperationRotateCertificates:
+		return completeCertsPlan(localEnv, updateEnv, *op)
+	case ops.OperationPatch:
+		return completePatchPlan(localEnv, updateEnv, *op)
 	default:
 		return trace.BadParameter("operation type %q does not support plan completion", op.Type)
 	}
 }
 
+// resumeOperation clears the pause request of the active operation
+// and resumes its plan
+func resumeOperation(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, params PhaseParams) error {
+	op, err := getActiveOperation(localEnv, updateEnv, joinEnv, params.OperationID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if supportsInterrupt(op.Type) && !params.DryRun {
+		// The cluster might be partially unavailable if the operation
+		// has failed so the operation can be resumed regardless
+		if err := clearPauseRequest(localEnv, op.Key()); err != nil {
+			if trace.IsBadParameter(err) {
+				return trace.Wrap(err)
+			}
+			log.WithError(err).Warn("Failed to clear operation pause request.")
+		}
+	}
+	params.PhaseID = fsm.RootPhase
+	return executePhase(localEnv, updateEnv, joinEnv, params)
+}
+
+// interruptOperation requests the active operation to either pause after
+// the phase in progress or to cancel and roll back the phase in progress
+func interruptOperation(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, operationID, interrupt string) error {
+	op, err := getActiveOperation(localEnv, updateEnv, joinEnv, operationID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !supportsInterrupt(op.Type) {
+		return trace.BadParameter("operation type %q does not support %v", op.Type, interrupt)
+	}
+	clusterEnv, err := localEnv.NewClusterEnvironment()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch interrupt {
+	case storage.OperationInterruptPause:
+		err = clusterEnv.Operator.PauseSiteOperation(context.TODO(), op.Key())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		localEnv.Printf("Operation %v will pause after the phase in progress.\n"+
+			"Use 'gravity plan resume' to continue.\n", op.ID)
+	case storage.OperationInterruptCancel:
+		err = clusterEnv.Operator.CancelSiteOperation(context.TODO(), op.Key())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		localEnv.Printf("Operation %v will be cancelled and the phase in progress rolled back.\n"+
+			"Use 'gravity plan rollback' to roll back the remaining phases.\n", op.ID)
+	default:
+		return trace.BadParameter("unsupported interrupt request %q", interrupt)
+	}
+	return nil
+}
+
+func clearPauseRequest(localEnv *localenv.LocalEnvironment, key ops.SiteOperationKey) error {
+	clusterEnv, err := localEnv.NewClusterEnvironment()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(clusterEnv.Operator.ResumeSiteOperation(context.TODO(), key))
+}
+
+// supportsInterrupt returns true if operations of the specified type
+// can be paused and cancelled
+func supportsInterrupt(operationType string) bool {
+	switch operationType {
+	case ops.OperationUpdate,
+		ops.OperationUpdateRuntimeEnviron,
+		ops.OperationUpdateConfig,
+		ops.OperationRotateCertificates,
+		ops.OperationPatch:
+		return true
+	}
+	return false
+}
+
 func getLastOperation(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, operationID string) (*ops.SiteOperation, error) {
 	operations, err := getBackendOperations(localEnv, updateEnv, joinEnv, operationID)
 	if err != nil {
@@ -153,6 +153,23 @@ func uploadUpdate(env *localenv.LocalEnvironment, opsURL string) error {
 		return trace.Wrap(err)
 	}
 
+	deltaBase, err := appservice.GetDeltaBase(tarballPackages, *appPackage)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if deltaBase != nil {
+		if !deltaBase.IsEqualTo(cluster.App.Package) {
+			return trace.BadParameter("This upgrade can only be applied to "+
+				"clusters running %v, but the cluster is running %v. Please "+
+				"use the full upgrade tarball.", deltaBase, cluster.App.Package)
+		}
+		env.PrintStep("Restoring delta of %v against %v", appPackage, deltaBase)
+		err = appservice.MergeDelta(tarballPackages, clusterPackages, log)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
 	env.PrintStep("Importing application %v v%v", appPackage.Name, appPackage.Version)
 	_, err = appservice.PullApp(appservice.AppPullRequest{
 		SrcPack: tarballPackages,
@@ -21,20 +21,25 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/blob/chunked"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/webpack"
 	"github.com/gravitational/gravity/lib/utils"
 	"github.com/gravitational/gravity/tool/common"
 
 	"github.com/docker/docker/pkg/archive"
+	"github.com/dustin/go-humanize"
 	"github.com/gravitational/configure"
 	"github.com/gravitational/trace"
 )
@@ -246,20 +251,94 @@ func updatePackageLabels(s *localenv.LocalEnvironment, loc loc.Locator, opsCente
 	return nil
 }
 
+// migratePackageStore converts the package store in the specified directory
+// to the chunked storage that stores data shared between packages once
+func migratePackageStore(env *localenv.LocalEnvironment, dir string) error {
+	if dir == "" {
+		dir = filepath.Join(env.StateDir, defaults.PackagesDir)
+	}
+	objects, err := chunked.NewWithConfig(chunked.Config{Path: dir})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer objects.Close()
+	result, err := chunked.Migrate(objects, log)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Migrated %v packages in %v, removed %v unreferenced chunks.\n",
+		result.Migrated, dir, result.Pruned)
+	env.Printf("Total package size is %v, %v stored on disk.\n",
+		humanize.Bytes(uint64(result.LogicalBytes)), humanize.Bytes(uint64(result.StoredBytes)))
+	if len(result.Corrupted) != 0 {
+		return trace.BadParameter("failed to verify %v packages, they were left intact: %v",
+			len(result.Corrupted), result.Corrupted)
+	}
+	return nil
+}
+
+func collectPackageGarbage(env *localenv.LocalEnvironment, dryRun bool, protectionWindow time.Duration) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	resp, err := operator.CollectPackageGarbage(context.TODO(), ops.CollectPackageGarbageRequest{
+		ClusterKey:       cluster.Key(),
+		DryRun:           dryRun,
+		ProtectionWindow: protectionWindow,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	action := "Deleted"
+	if dryRun {
+		action = "Would delete"
+	}
+	for _, locator := range resp.Deleted {
+		env.Printf("%v package %v.\n", action, locator)
+	}
+	for _, locator := range resp.Protected {
+		env.Printf("Kept package %v imported within the last %v.\n", locator, protectionWindow)
+	}
+	if dryRun {
+		env.Printf("Would reclaim %v.\n", humanize.Bytes(uint64(resp.ReclaimedBytes)))
+		return nil
+	}
+	env.Printf("Reclaimed %v.\n", humanize.Bytes(uint64(resp.ReclaimedBytes)))
+	return nil
+}
+
 func executePackageCommand(s *localenv.LocalEnvironment, cmd string, loc loc.Locator, confLoc *loc.Locator, execArgs []string) error {
+	command, err := packageCommand(s, cmd, loc, confLoc, execArgs)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := os.Chdir(command.Dir); err != nil {
+		return trace.Wrap(err)
+	}
+	return syscall.Exec(command.Path, command.Args, command.Env)
+}
+
+// packageCommand returns the command cmd from the manifest of the specified
+// package configured to run with the given configuration package
+func packageCommand(s *localenv.LocalEnvironment, cmd string, loc loc.Locator, confLoc *loc.Locator, execArgs []string) (*exec.Cmd, error) {
 	log.Infof("exec with config %v %v", loc, confLoc)
 
 	// in case if user supplies "+installed" we provide a special treatment,
 	// using currently installed version of the package and configuration
 	ver, err := loc.SemVer()
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
 	log.Infof("metadata: %v", ver.Metadata)
 	if ver.Metadata == pack.InstalledLabel {
 		ploc, pconfLoc, err := pack.FindInstalledPackageWithConfig(s.Packages, loc)
 		if err != nil {
-			return trace.Wrap(err)
+			return nil, trace.Wrap(err)
 		}
 		loc = *ploc
 		if confLoc == nil {
@@ -269,16 +348,16 @@ func executePackageCommand(s *localenv.LocalEnvironment, cmd string, loc loc.Loc
 
 	manifest, err := s.Packages.GetPackageManifest(loc)
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
 
 	if err := s.Packages.Unpack(loc, ""); err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
 
 	command, err := manifest.Command(cmd)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	env := []string{fmt.Sprintf("PATH=%v", os.Getenv("PATH"))}
@@ -286,13 +365,13 @@ func executePackageCommand(s *localenv.LocalEnvironment, cmd string, loc loc.Loc
 	if confLoc.Name != "" {
 		_, reader, err := s.Packages.ReadPackage(*confLoc)
 		if err != nil {
-			return trace.Wrap(err)
+			return nil, trace.Wrap(err)
 		}
 		defer reader.Close()
 
 		vars, err := pack.ReadConfigPackage(reader)
 		if err != nil {
-			return trace.Wrap(err)
+			return nil, trace.Wrap(err)
 		}
 		for k, v := range vars {
 			env = append(env, fmt.Sprintf("%v=%v", k, v))
@@ -303,12 +382,14 @@ func executePackageCommand(s *localenv.LocalEnvironment, cmd string, loc loc.Loc
 	log.Infof("calling: %v with env %v", args, env)
 	path, err := s.Packages.UnpackedPath(loc)
 	if err != nil {
-		return trace.Wrap(err)
-	}
-	if err := os.Chdir(path); err != nil {
-		return trace.Wrap(err)
-	}
-	return syscall.Exec(command.Args[0], args, env)
+		return nil, trace.Wrap(err)
+	}
+	return &exec.Cmd{
+		Path: command.Args[0],
+		Args: args,
+		Env:  env,
+		Dir:  path,
+	}, nil
 }
 
 func pushPackage(app *localenv.LocalEnvironment, loc loc.Locator, opsCenterURL string) error {
@@ -317,6 +398,15 @@ func pushPackage(app *localenv.LocalEnvironment, loc loc.Locator, opsCenterURL s
 		return trace.Wrap(err)
 	}
 
+	if client, ok := dstPackages.(*webpack.Client); ok {
+		// remote package services support resumable uploads
+		if err := uploadPackage(app, client, loc); err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Printf("%v pushed to %v\n", loc, opsCenterURL)
+		return nil
+	}
+
 	req := service.PackagePullRequest{
 		SrcPack:  app.Packages,
 		DstPack:  dstPackages,
@@ -340,6 +430,15 @@ func pullPackage(app *localenv.LocalEnvironment, loc loc.Locator, opsCenterURL s
 		return trace.Wrap(err)
 	}
 
+	if client, ok := sourcePackages.(*webpack.Client); ok {
+		// remote package services support resumable downloads
+		if err := downloadPackage(app, client, loc, labels, force); err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Printf("%v pulled from %v\n", loc, opsCenterURL)
+		return nil
+	}
+
 	req := service.PackagePullRequest{
 		SrcPack:  sourcePackages,
 		DstPack:  app.Packages,
@@ -357,6 +456,95 @@ func pullPackage(app *localenv.LocalEnvironment, loc loc.Locator, opsCenterURL s
 	return nil
 }
 
+// uploadPackage uploads the local package to the remote package service.
+// The upload progress is persisted in the local state directory so an
+// interrupted upload is resumed by pushing the same package again
+func uploadPackage(app *localenv.LocalEnvironment, client *webpack.Client, loc loc.Locator) error {
+	_, err := client.ReadPackageEnvelope(loc)
+	if err == nil {
+		return trace.AlreadyExists("package %v already exists", loc)
+	}
+	if !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	env, reader, err := app.Packages.ReadPackage(loc)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	seeker, ok := reader.(io.ReadSeeker)
+	if !ok {
+		return trace.BadParameter("package %v does not support resumable uploads", loc)
+	}
+	stateDir := filepath.Join(app.StateDir, defaults.UploadDir)
+	if err := os.MkdirAll(stateDir, defaults.PrivateDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	err = client.UpsertRepository(loc.Repository, time.Time{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = client.UploadPackage(context.TODO(), webpack.UploadPackageRequest{
+		Locator:   loc,
+		Reader:    seeker,
+		StateFile: filepath.Join(stateDir, transferFileName(loc)+".json"),
+		Options:   []pack.PackageOption{pack.WithLabels(env.RuntimeLabels)},
+	})
+	return trace.Wrap(err)
+}
+
+// downloadPackage downloads the package from the remote package service into
+// the local package service. The package data is kept in the local state
+// directory until the download completes so an interrupted download is resumed
+// by pulling the same package again
+func downloadPackage(app *localenv.LocalEnvironment, client *webpack.Client, loc loc.Locator, labels map[string]string, force bool) error {
+	_, err := app.Packages.ReadPackageEnvelope(loc)
+	if err == nil && !force {
+		return trace.AlreadyExists("package %v already exists", loc)
+	}
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	dir := filepath.Join(app.StateDir, defaults.TempDir)
+	if err := os.MkdirAll(dir, defaults.PrivateDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	path := filepath.Join(dir, transferFileName(loc))
+	env, err := client.DownloadPackage(context.TODO(), loc, path)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer os.Remove(path)
+	f, err := os.Open(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for label, value := range env.RuntimeLabels {
+		if _, exists := labels[label]; !exists {
+			labels[label] = value
+		}
+	}
+	err = app.Packages.UpsertRepository(loc.Repository, time.Time{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if force {
+		_, err = app.Packages.UpsertPackage(loc, f, pack.WithLabels(labels))
+	} else {
+		_, err = app.Packages.CreatePackage(loc, f, pack.WithLabels(labels))
+	}
+	return trace.Wrap(err)
+}
+
+// transferFileName returns the name of the file with the transfer state of the specified package
+func transferFileName(loc loc.Locator) string {
+	return fmt.Sprintf("%v-%v-%v", loc.Repository, loc.Name, loc.Version)
+}
+
 func foreachRepository(repository string, packageService pack.PackageService, fn func(repository string) error) (err error) {
 	var repositories []string
 	if repository != "" {
@@ -0,0 +1,165 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/httplib"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/verify"
+	"github.com/gravitational/gravity/tool/common"
+
+	"github.com/gravitational/trace"
+)
+
+type verifyPackagesConfig struct {
+	// cluster verifies the copy of the cluster packages stored on this node
+	cluster bool
+	// repair restores the data of corrupted packages
+	repair bool
+	// opsCenterURL is the Ops Center to restore the package data from
+	opsCenterURL string
+	// format is the output format
+	format constants.Format
+}
+
+// verifyPackages recomputes the checksums of the stored package data and
+// reports the corrupted packages, optionally restoring their data
+func verifyPackages(env *localenv.LocalEnvironment, config verifyPackagesConfig) error {
+	var packages verify.PackageService
+	var objects blob.Objects
+	if config.cluster {
+		clusterEnv, err := env.NewClusterEnvironment()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		packages, objects = clusterEnv.Packages, clusterEnv.Objects
+	} else {
+		packages, objects = env.Packages, env.Objects
+	}
+	var repair verify.RepairFunc
+	if config.repair {
+		source, err := verifyRepairSource(env, config)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		repair = verify.RefetchFrom(objects, source)
+	}
+	report, err := verify.Verify(context.TODO(), verify.Config{
+		Packages: packages,
+		Objects:  objects,
+		Repair:   repair,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch config.format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		err = common.PrintStructured(os.Stdout, config.format, report)
+	case constants.EncodingText:
+		err = printVerifyReport(os.Stdout, *report)
+	default:
+		return trace.BadParameter("unsupported output format %q", config.format)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var unrepaired int
+	for _, corrupted := range report.Corrupted {
+		if !corrupted.Repaired {
+			unrepaired++
+		}
+	}
+	if unrepaired != 0 {
+		return trace.CompareFailed("%v corrupted packages have not been repaired", unrepaired)
+	}
+	return nil
+}
+
+// verifyRepairSource returns the package service to restore
+// the data of corrupted packages from
+func verifyRepairSource(env *localenv.LocalEnvironment, config verifyPackagesConfig) (pack.PackageService, error) {
+	if config.opsCenterURL != "" {
+		return env.PackageService(config.opsCenterURL, httplib.WithDialTimeout(dialTimeout))
+	}
+	if config.cluster {
+		// The cluster package service would serve the corrupted copy
+		// stored on this node
+		return nil, trace.BadParameter("specify --ops-url to restore the cluster packages from, " +
+			"the cluster controller restores corrupted copies from the other master nodes automatically")
+	}
+	return env.ClusterPackages()
+}
+
+// statusPackages displays the results of the last integrity verification
+// of the cluster packages
+func statusPackages(env *localenv.LocalEnvironment, format constants.Format) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	status, err := operator.GetPackageVerificationStatus(context.TODO(), cluster.Key())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, status))
+	case constants.EncodingText:
+		return trace.Wrap(printVerifyReport(os.Stdout, status.Report))
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+}
+
+func printVerifyReport(out io.Writer, report verify.Report) error {
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 8, 1, '\t', 0)
+	health := "healthy"
+	if !report.Healthy() {
+		health = "corrupted"
+	}
+	fmt.Fprintf(w, "Status:\t%v\n", health)
+	fmt.Fprintf(w, "Verified:\t%v\n", report.Completed.Format(constants.HumanDateFormatSeconds))
+	fmt.Fprintf(w, "Packages:\t%v\n", report.Verified)
+	if len(report.Corrupted) != 0 {
+		fmt.Fprintf(w, "Corrupted packages:\n")
+		for _, corrupted := range report.Corrupted {
+			status := corrupted.Error
+			switch {
+			case corrupted.Repaired:
+				status = "repaired"
+			case corrupted.RepairError != "":
+				status = fmt.Sprintf("%v, repair failed: %v", corrupted.Error, corrupted.RepairError)
+			}
+			fmt.Fprintf(w, "    %v:\t%v\n", corrupted.Locator, status)
+		}
+	}
+	return trace.Wrap(w.Flush())
+}
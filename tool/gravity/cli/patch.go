@@ -0,0 +1,217 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/fsm"
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/update"
+	"github.com/gravitational/gravity/lib/update/patch"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// patchCluster executes the operation to apply configuration-only changes
+// to the cluster: new runtime environment variables and/or cluster DNS upstream nameservers
+func patchCluster(
+	ctx context.Context,
+	localEnv, updateEnv *localenv.LocalEnvironment,
+	env map[string]string,
+	dnsUpstreams []string,
+	manual, confirmed bool,
+) error {
+	err := ops.CreatePatchOperationRequest{
+		Env:          env,
+		DNSUpstreams: dnsUpstreams,
+	}.Check()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !confirmed {
+		banner := patchBanner
+		if len(env) != 0 {
+			banner = patchRestartBanner
+		}
+		if !manual {
+			banner += patchAutomaticBanner
+		}
+		localEnv.Println(banner + "\nAre you sure?")
+		resp, err := confirm()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if !resp {
+			localEnv.Println("Action cancelled by user.")
+			return nil
+		}
+	}
+	updater, err := newUpdater(ctx, localEnv, updateEnv, patchInitializer{
+		env:          env,
+		dnsUpstreams: dnsUpstreams,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer updater.Close()
+	if !manual {
+		err = updater.Run(ctx, false)
+		return trace.Wrap(err)
+	}
+	localEnv.Println(updateEnvironManualOperationBanner)
+	return nil
+}
+
+func executePatchPhase(env, updateEnv *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
+	updater, err := getPatchUpdater(env, updateEnv, operation)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer updater.Close()
+	err = updater.RunPhase(context.TODO(), params.PhaseID, params.Timeout, params.Force)
+	return trace.Wrap(err)
+}
+
+func rollbackPatchPhase(env, updateEnv *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
+	updater, err := getPatchUpdater(env, updateEnv, operation)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer updater.Close()
+	err = updater.RollbackPhase(context.TODO(), params.PhaseID, params.Timeout, params.Force)
+	return trace.Wrap(err)
+}
+
+func completePatchPlan(env, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation) error {
+	updater, err := getPatchUpdater(env, updateEnv, operation)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer updater.Close()
+	return trace.Wrap(updater.Complete(nil))
+}
+
+func getPatchUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation) (*update.Updater, error) {
+	clusterEnv, err := localEnv.NewClusterEnvironment()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	creds, err := libfsm.GetClientCredentials()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	runner := libfsm.NewAgentRunner(creds)
+	return patchInitializer{}.newUpdater(context.TODO(), clusterEnv.Operator, operation,
+		localEnv, updateEnv, clusterEnv, runner)
+}
+
+func (r patchInitializer) validatePreconditions(*localenv.LocalEnvironment, ops.Operator, ops.Site) error {
+	return nil
+}
+
+func (r patchInitializer) newOperation(operator ops.Operator, cluster ops.Site) (*ops.SiteOperationKey, error) {
+	key, err := operator.CreatePatchOperation(context.TODO(),
+		ops.CreatePatchOperationRequest{
+			ClusterKey:   cluster.Key(),
+			Env:          r.env,
+			DNSUpstreams: r.dnsUpstreams,
+		},
+	)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotImplemented(
+				"cluster operator does not implement the API required for patching cluster configuration. " +
+					"Please make sure you're running the command on a compatible cluster.")
+		}
+		return nil, trace.Wrap(err)
+	}
+	return key, nil
+}
+
+func (patchInitializer) newOperationPlan(
+	ctx context.Context,
+	operator ops.Operator,
+	cluster ops.Site,
+	operation ops.SiteOperation,
+	localEnv, updateEnv *localenv.LocalEnvironment,
+	clusterEnv *localenv.ClusterEnvironment,
+) (*storage.OperationPlan, error) {
+	plan, err := patch.NewOperationPlan(operator, clusterEnv.Apps, operation, cluster.ClusterState.Servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
+
+func (patchInitializer) newUpdater(
+	ctx context.Context,
+	operator ops.Operator,
+	operation ops.SiteOperation,
+	localEnv, updateEnv *localenv.LocalEnvironment,
+	clusterEnv *localenv.ClusterEnvironment,
+	runner fsm.AgentRepository,
+) (*update.Updater, error) {
+	config := patch.Config{
+		Config: update.Config{
+			Operation:    &operation,
+			Operator:     operator,
+			Backend:      clusterEnv.Backend,
+			LocalBackend: updateEnv.Backend,
+			Runner:       runner,
+			Silent:       localEnv.Silent,
+			FieldLogger: logrus.WithFields(logrus.Fields{
+				trace.Component: "update:patch",
+				"operation":     operation,
+			}),
+		},
+		Apps:              clusterEnv.Apps,
+		Client:            clusterEnv.Client,
+		ClusterPackages:   clusterEnv.ClusterPackages,
+		HostLocalPackages: localEnv.Packages,
+	}
+	return patch.New(ctx, config)
+}
+
+func (patchInitializer) updateDeployRequest(req deployAgentsRequest) deployAgentsRequest {
+	return req
+}
+
+type patchInitializer struct {
+	// env specifies the runtime environment variables to set
+	env map[string]string
+	// dnsUpstreams specifies the new cluster DNS upstream nameservers
+	dnsUpstreams []string
+}
+
+const (
+	patchBanner = `Patching cluster configuration updates the cluster DNS without restarting the nodes.
+`
+	patchRestartBanner = `Patching runtime environment restarts runtime containers on all nodes, one node at a time.
+The nodes are not drained before the restart.
+The operation might take several minutes to complete depending on the cluster size.
+`
+	patchAutomaticBanner = `
+The operation will start automatically once you approve it.
+If you want to review the operation plan first or execute it manually step by step,
+run the operation in manual mode by specifying '--manual' flag.
+`
+)
@@ -22,14 +22,18 @@ import (
 	"os"
 	"time"
 
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/resources"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/fsm"
+	installphases "github.com/gravitational/gravity/lib/install/phases"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/update"
 	clusterupdate "github.com/gravitational/gravity/lib/update/cluster"
 	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/tool/common"
 
 	"github.com/fatih/color"
 	"github.com/gravitational/trace"
@@ -79,6 +83,10 @@ func displayOperationPlan(localEnv, updateEnv, joinEnv *localenv.LocalEnvironmen
 		return displayUpdateOperationPlan(localEnv, updateEnv, op.Key(), format)
 	case ops.OperationUpdateConfig:
 		return displayUpdateOperationPlan(localEnv, updateEnv, op.Key(), format)
+	case ops.OperationRotateCertificates:
+		return displayUpdateOperationPlan(localEnv, updateEnv, op.Key(), format)
+	case ops.OperationPatch:
+		return displayUpdateOperationPlan(localEnv, updateEnv, op.Key(), format)
 	case ops.OperationGarbageCollect:
 		return displayClusterOperationPlan(localEnv, op.Key(), format)
 	default:
@@ -158,6 +166,123 @@ func displayExpandOperationPlan(joinEnv *localenv.LocalEnvironment, opKey ops.Si
 	return outputPlan(*plan, format)
 }
 
+// displayPhaseLogs shows the log entries recorded by the specified phase
+// of the operation and its subphases
+func displayPhaseLogs(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, operationID, phaseID string, format constants.Format) error {
+	op, err := getLastOperation(localEnv, updateEnv, joinEnv, operationID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var operator ops.Operator
+	if op.Type == ops.OperationInstall && !op.IsCompleted() {
+		wizardEnv, err := localenv.NewRemoteEnvironment()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if wizardEnv.Operator == nil {
+			return trace.NotFound("could not connect to the installer process, " +
+				"make sure to run the command from the directory where \"gravity install\" was run")
+		}
+		operator = wizardEnv.Operator
+	} else {
+		clusterEnv, err := localEnv.NewClusterEnvironment()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		operator = clusterEnv.Operator
+	}
+	entries, err := operator.GetOperationPhaseLogs(op.Key(), phaseID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, entries))
+	case constants.EncodingText:
+		if len(entries) == 0 {
+			localEnv.Printf("No logs have been recorded for phase %v.\n", phaseID)
+			return nil
+		}
+		for _, entry := range entries {
+			fmt.Print(entry.String())
+		}
+		return nil
+	default:
+		return trace.BadParameter("unknown output format %q", format)
+	}
+}
+
+// dryRunPhase prints the phases of the specified operation that would be
+// executed for the given phase without executing them
+func dryRunPhase(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, params PhaseParams, op ops.SiteOperation) error {
+	var plan *storage.OperationPlan
+	var lister fsm.ResourceLister
+	var err error
+	switch op.Type {
+	case ops.OperationInstall:
+		wizardEnv, err := localenv.NewRemoteEnvironment()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if wizardEnv.Operator == nil {
+			return trace.NotFound("could not retrieve install operation plan, " +
+				"make sure the command is invoked from the installer directory")
+		}
+		plan, err = wizardEnv.Operator.GetOperationPlan(op.Key())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		lister = bootstrapResourceLister(wizardEnv.Apps, func(phase storage.OperationPhase) bool {
+			return phase.ID == installphases.RBACPhase
+		})
+	case ops.OperationExpand:
+		plan, err = fsm.GetOperationPlan(joinEnv.Backend, op.SiteDomain, op.ID)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	case ops.OperationUpdate, ops.OperationUpdateRuntimeEnviron, ops.OperationUpdateConfig,
+		ops.OperationRotateCertificates, ops.OperationPatch:
+		plan, err = fsm.GetOperationPlan(updateEnv.Backend, op.SiteDomain, op.ID)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		clusterEnv, err := localEnv.NewClusterEnvironment()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		lister = bootstrapResourceLister(clusterEnv.Apps, clusterupdate.CreatesBootstrapResources)
+	case ops.OperationGarbageCollect:
+		clusterEnv, err := localEnv.NewClusterEnvironment()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		plan, err = clusterEnv.Operator.GetOperationPlan(op.Key())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	default:
+		return trace.BadParameter("operation type %q does not support plan execution", op.Type)
+	}
+	return trace.Wrap(fsm.DryRun(os.Stdout, fsm.DryRunConfig{
+		Plan:      *plan,
+		PhaseID:   params.PhaseID,
+		Force:     params.Force,
+		Resources: lister,
+	}))
+}
+
+// bootstrapResourceLister returns a resource lister that lists bootstrap
+// resources of the application package of the phases matched by the
+// provided predicate
+func bootstrapResourceLister(apps app.Applications, matches func(storage.OperationPhase) bool) fsm.ResourceLister {
+	return func(phase storage.OperationPhase, fn resources.ResourceFunc) error {
+		if !matches(phase) || phase.Data == nil || phase.Data.Package == nil {
+			return nil
+		}
+		return trace.Wrap(app.ForEachResource(apps, *phase.Data.Package, fn))
+	}
+}
+
 func outputPlan(plan storage.OperationPlan, format constants.Format) (err error) {
 	switch format {
 	case constants.EncodingYAML:
@@ -167,6 +292,10 @@ func outputPlan(plan storage.OperationPlan, format constants.Format) (err error)
 	case constants.EncodingText:
 		fsm.FormatOperationPlanText(os.Stdout, plan)
 		err = explainPlan(plan.Phases)
+	case constants.EncodingDOT:
+		err = fsm.FormatOperationPlanDot(os.Stdout, plan, time.Now().UTC())
+	case constants.EncodingMermaid:
+		err = fsm.FormatOperationPlanMermaid(os.Stdout, plan, time.Now().UTC())
 	default:
 		return trace.BadParameter("unknown output format %q", format)
 	}
@@ -194,13 +323,19 @@ func explainPlan(phases []storage.OperationPhase) (err error) {
 
 func outputPhaseError(phase storage.OperationPhase) error {
 	fmt.Printf(color.RedString("The %v phase (%q) has failed", phase.ID, phase.Description))
+	if len(phase.Attempts) > 1 {
+		fmt.Print(color.RedString(" after %v attempts", len(phase.Attempts)))
+	}
 	if phase.Error != nil {
 		var phaseErr trace.TraceErr
 		if err := utils.UnmarshalError(phase.Error.Err, &phaseErr); err != nil {
 			return trace.Wrap(err, "failed to unmarshal phase error from JSON")
 		}
 		fmt.Printf(color.RedString("\n\t%v\n", phaseErr.Err))
+	} else {
+		fmt.Println()
 	}
+	fmt.Printf("Use 'gravity plan logs %v' to see the logs of the failed phase.\n", phase.ID)
 	return nil
 }
 
@@ -46,8 +46,12 @@ func planetEnter(env *localenv.LocalEnvironment, args []string) error {
 }
 
 // planetShell is a shortcut that finds installed planet in this cluster
-// and enters it
-func planetShell(env *localenv.LocalEnvironment) error {
+// and enters it.
+// If record is set, the shell session is recorded
+func planetShell(env *localenv.LocalEnvironment, record bool) error {
+	if record {
+		return recordedPlanetShell(env)
+	}
 	return planetExec(env, true, true, "/bin/bash", nil)
 }
 
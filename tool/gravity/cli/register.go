@@ -24,9 +24,11 @@ import (
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/modules"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/utils"
 	"github.com/gravitational/gravity/tool/common"
 
@@ -50,9 +52,9 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.ProfileTo = g.Flag("profile-dir", "store periodic state snapshots in the specified directory").Default("").Hidden().String()
 	g.UserLogFile = g.Flag("log-file", "log file with diagnostic information").Default(defaults.GravityUserLog).String()
 	g.SystemLogFile = g.Flag("system-log-file", "log file with system level logs").Default(defaults.GravitySystemLog).Hidden().String()
+	g.Output = common.Output(app)
 
 	g.VersionCmd.CmdClause = g.Command("version", "Print gravity version")
-	g.VersionCmd.Output = common.Format(g.VersionCmd.Flag("output", "Output format, text or json").Short('o').Default(string(constants.EncodingText)))
 
 	g.InstallCmd.CmdClause = g.Command("install", "Install cluster on this node")
 	g.InstallCmd.Path = g.InstallCmd.Arg("appdir", "Path to directory with application package. Uses current directory by default").String()
@@ -61,7 +63,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.InstallCmd.CloudProvider = g.InstallCmd.Flag("cloud-provider", fmt.Sprintf("Cloud provider integration: %v. If not set, autodetect environment", schema.SupportedProviders)).String()
 	g.InstallCmd.Cluster = g.InstallCmd.Flag("cluster", "Cluster name, optional").String()
 	g.InstallCmd.App = g.InstallCmd.Flag("app", "Application to install, optional").Hidden().String()
-	g.InstallCmd.Flavor = g.InstallCmd.Flag("flavor", "Application flavor, optional").String()
+	g.InstallCmd.Flavor = g.InstallCmd.Flag("flavor", "Application flavor, optional. Use 'auto' to select the flavor that best matches the hardware of this node").String()
 	g.InstallCmd.Role = g.InstallCmd.Flag("role", "Role of this node, optional").String()
 	g.InstallCmd.ResourcesPath = g.InstallCmd.Flag("config", "Kubernetes configuration resources, will be injected at cluster creation time").String()
 	g.InstallCmd.Wizard = g.InstallCmd.Flag("wizard", "(Obsolete, superseded by 'mode') Start installer with web wizard interface").Bool()
@@ -70,8 +72,8 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.InstallCmd.DockerDevice = g.InstallCmd.Flag("docker-device", "Device to use for docker storage").Hidden().String()
 	g.InstallCmd.SystemDevice = g.InstallCmd.Flag("system-device", "Device to use for system data directory").Hidden().String()
 	g.InstallCmd.Mounts = configure.KeyValParam(g.InstallCmd.Flag("mount", "One or several mounts in form <mount-name>:<path>, e.g. data:/var/lib/data"))
-	g.InstallCmd.PodCIDR = g.InstallCmd.Flag("pod-network-cidr", "Subnet range for pods. Must be a minimum of /16").Default(defaults.PodSubnet).String()
-	g.InstallCmd.ServiceCIDR = g.InstallCmd.Flag("service-cidr", "Subnet range for services").Default(defaults.ServiceSubnet).String()
+	g.InstallCmd.PodCIDR = g.InstallCmd.Flag("pod-network-cidr", "Subnet range for pods. Must be a minimum of /16 for IPv4 or /56 for IPv6. Specify an IPv4 and an IPv6 range separated with a comma for dual-stack networking").Default(defaults.PodSubnet).String()
+	g.InstallCmd.ServiceCIDR = g.InstallCmd.Flag("service-cidr", "Subnet range for services. Must be a maximum of /108 for IPv6. Specify an IPv4 and an IPv6 range separated with a comma for dual-stack networking").Default(defaults.ServiceSubnet).String()
 	g.InstallCmd.VxlanPort = g.InstallCmd.Flag("vxlan-port", "Custom overlay network port").Default(strconv.Itoa(defaults.VxlanPort)).Int()
 	g.InstallCmd.DNSListenAddrs = g.InstallCmd.Flag("dns-listen-addr", "Custom listen address for in-cluster DNS").
 		Default(defaults.DNSListenAddr).IPList()
@@ -86,16 +88,21 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.InstallCmd.Resume = g.InstallCmd.Flag("resume", "Resume installation from last failed step").Bool()
 	g.InstallCmd.Manual = g.InstallCmd.Flag("manual", "Manually execute install operation phases").Bool()
 	g.InstallCmd.ServiceUID = g.InstallCmd.Flag("service-uid",
-		fmt.Sprintf("Service user ID for planet. %q user will created and used if none specified", defaults.ServiceUser)).
-		Default(defaults.ServiceUserID).
+		fmt.Sprintf("Service user ID for planet. Defaults to the ID from the application manifest or %v. %q user will created and used if none specified", defaults.ServiceUserID, defaults.ServiceUser)).
 		OverrideDefaultFromEnvar(constants.ServiceUserEnvVar).
 		String()
 	g.InstallCmd.ServiceGID = g.InstallCmd.Flag("service-gid",
-		fmt.Sprintf("Service group ID for planet. %q group will created and used if none specified", defaults.ServiceUserGroup)).
-		Default(defaults.ServiceGroupID).
+		fmt.Sprintf("Service group ID for planet. Defaults to the ID from the application manifest or %v. %q group will created and used if none specified", defaults.ServiceGroupID, defaults.ServiceUserGroup)).
 		OverrideDefaultFromEnvar(constants.ServiceGroupEnvVar).
 		String()
 	g.InstallCmd.GCENodeTags = g.InstallCmd.Flag("gce-node-tag", "Override node tag on the instance in GCE required for load balanacing. Defaults to cluster name.").Strings()
+	g.InstallCmd.TrustedKeys = g.InstallCmd.Flag("trusted-key", "Path to a public key trusted to sign the installer in addition to the keys added with 'gravity app trusted-key add'. Can be specified multiple times.").Strings()
+	g.InstallCmd.RequireSignature = g.InstallCmd.Flag("require-signature", "Refuse to install from an installer that is not signed").Bool()
+	g.InstallCmd.CACertPath = g.InstallCmd.Flag("ca-cert", "Path to the PEM-encoded intermediate certificate authority, optionally followed by the rest of its chain, to issue cluster certificates from instead of a self-generated one").String()
+	g.InstallCmd.CAKeyPath = g.InstallCmd.Flag("ca-key", "Path to the PEM-encoded private key of the intermediate certificate authority").String()
+	g.InstallCmd.CASignerURL = g.InstallCmd.Flag("ca-signer-url", "URL of the external signer to send certificate signing requests to instead of signing them with the certificate authority private key").String()
+	g.InstallCmd.Proxy = common.Proxy(g.InstallCmd.CmdClause)
+	g.InstallCmd.FIPS = g.InstallCmd.Flag("fips", "Install the cluster in FIPS mode: restrict TLS to FIPS-approved cipher suites and only accept nodes running FIPS-compliant binaries").Bool()
 	g.InstallCmd.DNSHosts = g.InstallCmd.Flag("dns-host", "Specify an IP address that will be returned for the given domain within the cluster. Accepts <domain>/<ip> format. Can be specified multiple times.").Hidden().Strings()
 	g.InstallCmd.DNSZones = g.InstallCmd.Flag("dns-zone", "Specify an upstream server for the given zone within the cluster. Accepts <zone>/<nameserver> format where <nameserver> can be either <ip> or <ip>:<port>. Can be specified multiple times.").Strings()
 
@@ -123,39 +130,73 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.AutoJoinCmd.SystemDevice = g.AutoJoinCmd.Flag("system-device", "Device to use for system data directory").Hidden().String()
 	g.AutoJoinCmd.Mounts = configure.KeyValParam(g.AutoJoinCmd.Flag("mount", "One or several mounts in form <mount-name>:<path>, e.g. data:/var/lib/data"))
 
+	g.ExpandCmd.CmdClause = g.Command("expand", "Provision new nodes with a cloud provisioner and join them to the cluster")
+	g.ExpandCmd.Count = g.ExpandCmd.Flag("count", "Number of nodes to provision").Default("1").Int()
+	g.ExpandCmd.Profile = g.ExpandCmd.Flag("profile", "Node profile of the new nodes").Required().String()
+	g.ExpandCmd.Provisioner = g.ExpandCmd.Flag("provisioner", "Name of the provisioner to create the machines with, can be omitted if the cluster has a single provisioner").String()
+
+	g.InfraCmd.CmdClause = g.Command("infra", "Provision the infrastructure with the Terraform configuration bundled with the cluster image")
+
+	g.InfraPlanCmd.CmdClause = g.InfraCmd.Command("plan", "Display the infrastructure changes the Terraform configuration would make")
+	g.InfraPlanCmd.Path = g.InfraPlanCmd.Arg("appdir", "Path to directory with the unpacked installer. Uses current directory by default").String()
+	g.InfraPlanCmd.Provider = g.InfraPlanCmd.Flag("provider", "Provider to run the Terraform configuration for, can be omitted if the cluster image has a single configuration").String()
+	g.InfraPlanCmd.Vars = g.InfraPlanCmd.Flag("var", "Set the Terraform variable as key=value pair. Can be specified multiple times").StringMap()
+	g.InfraPlanCmd.Terraform = g.InfraPlanCmd.Flag("terraform", "Path to the terraform binary").Default(defaults.TerraformBin).String()
+
+	g.InfraApplyCmd.CmdClause = g.InfraCmd.Command("apply", "Provision the infrastructure with the Terraform configuration")
+	g.InfraApplyCmd.Path = g.InfraApplyCmd.Arg("appdir", "Path to directory with the unpacked installer. Uses current directory by default").String()
+	g.InfraApplyCmd.Provider = g.InfraApplyCmd.Flag("provider", "Provider to run the Terraform configuration for, can be omitted if the cluster image has a single configuration").String()
+	g.InfraApplyCmd.Vars = g.InfraApplyCmd.Flag("var", "Set the Terraform variable as key=value pair. Can be specified multiple times").StringMap()
+	g.InfraApplyCmd.Terraform = g.InfraApplyCmd.Flag("terraform", "Path to the terraform binary").Default(defaults.TerraformBin).String()
+
 	g.LeaveCmd.CmdClause = g.Command("leave", "Decommission this node from the cluster")
 	g.LeaveCmd.Force = g.LeaveCmd.Flag("force", "Force local state cleanup").Bool()
 	g.LeaveCmd.Confirm = g.LeaveCmd.Flag("confirm", "Do not ask for confirmation").Bool()
+	g.LeaveCmd.DrainTimeout = g.LeaveCmd.Flag("drain-timeout", "Maximum time to wait for the pods to be evicted from the node").Duration()
+	g.LeaveCmd.ForceDrain = g.LeaveCmd.Flag("force-drain", "Delete the pods that could not be evicted within the drain timeout, ignoring pod disruption budgets").Bool()
 
 	g.RemoveCmd.CmdClause = g.Command("remove", "Remove a node from the cluster")
 	g.RemoveCmd.Node = g.RemoveCmd.Arg("node", "Node to remove: can be IP address, hostname or name from `kubectl get nodes` output)").
 		Required().String()
 	g.RemoveCmd.Force = g.RemoveCmd.Flag("force", "Force removal of offline node").Bool()
 	g.RemoveCmd.Confirm = g.RemoveCmd.Flag("confirm", "Do not ask for confirmation").Bool()
+	g.RemoveCmd.DrainTimeout = g.RemoveCmd.Flag("drain-timeout", "Maximum time to wait for the pods to be evicted from the node").Duration()
+	g.RemoveCmd.ForceDrain = g.RemoveCmd.Flag("force-drain", "Delete the pods that could not be evicted within the drain timeout, ignoring pod disruption budgets").Bool()
 
 	g.PlanCmd.CmdClause = g.Command("plan", "Manage operation plan")
 	g.PlanCmd.OperationID = g.PlanCmd.Flag("operation-id", "ID of the active operation. It not specified, the last operation will be used").Hidden().String()
 	g.PlanCmd.SkipVersionCheck = g.PlanCmd.Flag("skip-version-check", "Bypass version compatibility check").Hidden().Bool()
 
 	g.PlanDisplayCmd.CmdClause = g.PlanCmd.Command("display", "Display a plan for an ongoing operation").Default()
-	g.PlanDisplayCmd.Output = common.Format(g.PlanDisplayCmd.Flag("output", "Output format for the plan, text, json or yaml").Short('o').Default(string(constants.EncodingText)))
+	g.PlanDisplayCmd.Format = g.PlanDisplayCmd.Flag("format", fmt.Sprintf("Render the plan as a graph of phases with their dependencies, states and durations, one of: %v, %v", constants.EncodingDOT, constants.EncodingMermaid)).Enum(string(constants.EncodingDOT), string(constants.EncodingMermaid))
 
 	g.PlanExecuteCmd.CmdClause = g.PlanCmd.Command("execute", "Execute specified operation phase")
 	g.PlanExecuteCmd.Phase = g.PlanExecuteCmd.Flag("phase", "Phase ID to execute").String()
 	g.PlanExecuteCmd.Force = g.PlanExecuteCmd.Flag("force", "Force execution of specified phase").Bool()
 	g.PlanExecuteCmd.PhaseTimeout = g.PlanExecuteCmd.Flag("timeout", "Phase timeout").Default(defaults.PhaseTimeout).Hidden().Duration()
+	g.PlanExecuteCmd.Parallel = g.PlanExecuteCmd.Flag("parallel", "Maximum number of independent phases to execute concurrently. If unspecified, the operation default is used").Int()
+	g.PlanExecuteCmd.DryRun = g.PlanExecuteCmd.Flag("dry-run", "Print the phases that would be executed along with the nodes, packages and Kubernetes resources they affect without executing them").Bool()
 
-	g.PlanRollbackCmd.CmdClause = g.PlanCmd.Command("rollback", "Rollback specified operation phase")
-	g.PlanRollbackCmd.Phase = g.PlanRollbackCmd.Flag("phase", "Phase ID to execute").String()
+	g.PlanRollbackCmd.CmdClause = g.PlanCmd.Command("rollback", "Rollback specified operation phase or the entire operation plan")
+	g.PlanRollbackCmd.Phase = g.PlanRollbackCmd.Flag("phase", "Phase ID to rollback. If unspecified, all executed phases are rolled back in reverse order").Default(fsm.RootPhase).String()
 	g.PlanRollbackCmd.Force = g.PlanRollbackCmd.Flag("force", "Force rollback of specified phase").Bool()
 	g.PlanRollbackCmd.PhaseTimeout = g.PlanRollbackCmd.Flag("timeout", "Phase timeout").Default(defaults.PhaseTimeout).Hidden().Duration()
 
-	g.PlanResumeCmd.CmdClause = g.PlanCmd.Command("resume", "Resume last aborted operation")
+	g.PlanResumeCmd.CmdClause = g.PlanCmd.Command("resume", "Resume last aborted or paused operation")
 	g.PlanResumeCmd.Force = g.PlanResumeCmd.Flag("force", "Force execution of specified phase").Bool()
 	g.PlanResumeCmd.PhaseTimeout = g.PlanResumeCmd.Flag("timeout", "Phase timeout").Default(defaults.PhaseTimeout).Hidden().Duration()
+	g.PlanResumeCmd.Parallel = g.PlanResumeCmd.Flag("parallel", "Maximum number of independent phases to execute concurrently. If unspecified, the operation default is used").Int()
+	g.PlanResumeCmd.DryRun = g.PlanResumeCmd.Flag("dry-run", "Print the phases that would be executed along with the nodes, packages and Kubernetes resources they affect without executing them").Bool()
 
 	g.PlanCompleteCmd.CmdClause = g.PlanCmd.Command("complete", "Mark operation as completed")
 
+	g.PlanPauseCmd.CmdClause = g.PlanCmd.Command("pause", "Pause the active operation after the phase in progress")
+
+	g.PlanCancelCmd.CmdClause = g.PlanCmd.Command("cancel", "Cancel the active operation rolling back the phase in progress")
+
+	g.PlanLogsCmd.CmdClause = g.PlanCmd.Command("logs", "Display the logs recorded by the specified operation phase and its subphases")
+	g.PlanLogsCmd.Phase = g.PlanLogsCmd.Arg("phase", "Phase ID to display logs for").Default(fsm.RootPhase).String()
+
 	g.UpdateCmd.CmdClause = g.Command("update", "Update actions on cluster")
 
 	g.UpdateCheckCmd.CmdClause = g.UpdateCmd.Command("check", "Check if an update is available for the specified application").Hidden()
@@ -172,6 +213,11 @@ func RegisterCommands(app *kingpin.Application) *Application {
 
 	g.UpdatePlanInitCmd.CmdClause = g.UpdateCmd.Command("init-plan", "Initialize operation plan").Hidden()
 
+	g.UpdateScheduledCmd.CmdClause = g.UpdateCmd.Command("scheduled", "Show update operations scheduled to start later")
+
+	g.UpdateCancelCmd.CmdClause = g.UpdateCmd.Command("cancel", "Cancel a scheduled update operation")
+	g.UpdateCancelCmd.ID = g.UpdateCancelCmd.Arg("id", "ID of the scheduled operation").Required().String()
+
 	// upgrade is aliased to "update trigger"
 	g.UpgradeCmd.CmdClause = g.Command("upgrade", "Trigger an update operation for given application").Hidden()
 	g.UpgradeCmd.App = g.UpgradeCmd.Arg("app", "Application version to update to, in the 'name:version' or 'name' (for latest version) format. If unspecified, currently installed application is updated").String()
@@ -185,6 +231,14 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.UpgradeCmd.Force = g.UpgradeCmd.Flag("force", "Force phase execution even if pre-conditions are not satisfied").Bool()
 	g.UpgradeCmd.Resume = g.UpgradeCmd.Flag("resume", "Resume upgrade from the last failed step").Bool()
 	g.UpgradeCmd.SkipVersionCheck = g.UpgradeCmd.Flag("skip-version-check", "Bypass version compatibility check").Hidden().Bool()
+	g.UpgradeCmd.Parallel = g.UpgradeCmd.Flag("parallel", "Maximum number of regular nodes to update concurrently").Default(strconv.Itoa(defaults.UpdateParallelism)).Int()
+	g.UpgradeCmd.DrainTimeout = g.UpgradeCmd.Flag("drain-timeout", "Maximum time to wait for the pods to be evicted from a node").Duration()
+	g.UpgradeCmd.ForceDrain = g.UpgradeCmd.Flag("force-drain", "Delete the pods that could not be evicted within the drain timeout, ignoring pod disruption budgets").Bool()
+	g.UpgradeCmd.Canary = g.UpgradeCmd.Flag("canary", "Number of regular nodes to update and verify first, before the rest of the nodes").Int()
+	g.UpgradeCmd.CanaryPause = g.UpgradeCmd.Flag("canary-pause", "Pause the operation after the canary nodes have been updated until approved with 'gravity plan resume'").Bool()
+	g.UpgradeCmd.NotBefore = g.UpgradeCmd.Flag("not-before", "Schedule the operation to start at the specified time, in RFC3339 format, e.g. 2019-10-19T02:00:00Z").String()
+	g.UpgradeCmd.Window = g.UpgradeCmd.Flag("window", "Schedule the operation to start within a maintenance window opening at times given by a cron expression, e.g. '0 2 * * 6'").String()
+	g.UpgradeCmd.WindowDuration = g.UpgradeCmd.Flag("window-duration", "How long the maintenance window stays open").Default(defaults.MaintenanceWindowDuration.String()).Duration()
 
 	g.UpdateUploadCmd.CmdClause = g.UpdateCmd.Command("upload", "Upload update package to locally running site").Hidden()
 	g.UpdateUploadCmd.OpsCenterURL = g.UpdateUploadCmd.Flag("ops-url", "Optional OpsCenter URL to upload new packages to (defaults to local gravity site)").Default(defaults.GravityServiceURL).String()
@@ -203,16 +257,29 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.StatusCmd.CmdClause = g.Command("status", "Show the status of the cluster and the application running in it")
 	g.StatusCmd.Token = g.StatusCmd.Flag("token", "Show only the cluster token").Bool()
 	g.StatusCmd.Tail = g.StatusCmd.Flag("tail", "Tail the logs of the currently running operation until it completes").Bool()
-	g.StatusCmd.OperationID = g.StatusCmd.Flag("operation-id", "Check status of operation with given ID").Short('o').String()
+	g.StatusCmd.Follow = g.StatusCmd.Flag("follow", "Display progress of the currently running operation as it changes until it completes").Short('f').Bool()
+	g.StatusCmd.OperationID = g.StatusCmd.Flag("operation-id", "Check status of operation with given ID").String()
 	g.StatusCmd.Seconds = g.StatusCmd.Flag("seconds", "Continuously display status every N seconds").Short('s').Int()
-	g.StatusCmd.Output = common.Format(g.StatusCmd.Flag("output", "output format: json or text").Default(string(constants.EncodingText)))
+	g.StatusCmd.Verbose = g.StatusCmd.Flag("verbose", "Display the reason, remediation hint and logs command of each failed health probe").Short('v').Bool()
+
+	g.StatusClusterCmd.CmdClause = g.StatusCmd.Command("cluster", "Show the current status of the cluster").Default()
+
+	g.StatusHistoryCmd.CmdClause = g.StatusCmd.Command("history", "Show when and why the cluster transitioned between healthy and degraded states")
+	g.StatusHistoryCmd.Since = g.StatusHistoryCmd.Flag("since", "Only show history recorded within the specified duration, e.g. 1h").Default("168h").Duration()
+	g.StatusHistoryCmd.All = g.StatusHistoryCmd.Flag("all", "Show all recorded status snapshots instead of only state transitions").Bool()
+
+	g.StatusRegistryCmd.CmdClause = g.StatusCmd.Command("registry", "Show the health and the storage usage of the cluster registry")
+
+	g.StatusPackagesCmd.CmdClause = g.StatusCmd.Command("packages", "Show the results of the last integrity verification of the cluster packages")
+
+	g.StatusLicenseCmd.CmdClause = g.StatusCmd.Command("license", "Show the status of the cluster license")
 
 	// reset cluster state, for debugging/emergencies
 	g.StatusResetCmd.CmdClause = g.Command("status-reset", "Reset the cluster state to 'active'").Hidden()
 
 	// backup
-	g.BackupCmd.CmdClause = g.Command("backup", "Backup the local application state")
-	g.BackupCmd.Tarball = g.BackupCmd.Arg("to", "Tarball to create with results of the backup hook").Required().String()
+	g.BackupCmd.CmdClause = g.Command("backup", "Backup the cluster and application state, must be run on a master node")
+	g.BackupCmd.Tarball = g.BackupCmd.Arg("to", "Tarball to create with the cluster state and results of the backup hook").Required().String()
 	g.BackupCmd.Timeout = g.BackupCmd.Flag("timeout", "Active deadline for the backup job, in Go duration format (e.g. 30s, 5m, etc.). If not specified, the value from manifest is used. If that is not specified as well, the default value of 20 minutes is used").Duration()
 	g.BackupCmd.Follow = g.BackupCmd.Flag("follow", "Output backup job logs to the stdout").Bool()
 
@@ -220,12 +287,15 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.CheckCmd.ManifestFile = g.CheckCmd.Arg("manifest", "application manifest in YAML format").Default(defaults.ManifestFileName).String()
 	g.CheckCmd.Profile = g.CheckCmd.Flag("profile", "profile to check").Short('p').Required().String()
 	g.CheckCmd.AutoFix = g.CheckCmd.Flag("autofix", "attempt to fix some of the problems").Bool()
+	g.CheckCmd.Checks = g.CheckCmd.Flag("check", "name of the check to run, can be repeated. Runs all checks if unspecified").Strings()
+	common.DeprecatedFormat(g.CheckCmd.CmdClause, g.Output)
 
 	// restore
-	g.RestoreCmd.CmdClause = g.Command("restore", "Restore state of the local application from a previously taken backup")
+	g.RestoreCmd.CmdClause = g.Command("restore", "Restore the cluster and application state from a previously taken backup, must be run on a master node")
 	g.RestoreCmd.Tarball = g.RestoreCmd.Arg("from", "Tarball with backup data to restore from").Required().String()
 	g.RestoreCmd.Follow = g.RestoreCmd.Flag("follow", "Output restore job logs to the stdout").Bool()
 	g.RestoreCmd.Timeout = g.RestoreCmd.Flag("timeout", fmt.Sprintf("Maximum time a restore job is active. Defaults to the value from the manifest or %v if unspecified", defaults.HookJobDeadline)).Duration()
+	g.RestoreCmd.Confirmed = g.RestoreCmd.Flag("confirm", "Do not ask for confirmation").Bool()
 
 	// operations on gravity applications
 	g.AppCmd.CmdClause = g.Command("app", "Operations with application images and releases.")
@@ -300,12 +370,22 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.AppImportCmd.SetImages = loc.ImagesSlice(g.AppImportCmd.Flag("set-image", "rewrite docker image versions in the app's resource files during vendoring, e.g. 'postgres:9.3.4' will rewrite all images with name 'postgres' to 'postgres:9.3.4'"))
 	g.AppImportCmd.SetDeps = loc.LocatorSlice(g.AppImportCmd.Flag("set-dep", "rewrite dependencies section in app's manifest file during vendoring, e.g. 'gravitational.io/site-app:0.0.39' will overwrite dependency to 'gravitational.io/site-app:0.0.39'"))
 	g.AppImportCmd.Parallel = g.AppImportCmd.Flag("parallel", "specifies number of concurrent tasks. If < 0, the number of tasks is not restricted, if unspecified, then tasks are capped at the number of logical CPU cores.").Hidden().Int()
+	g.AppImportCmd.TrustedKeys = g.AppImportCmd.Flag("trusted-key", "path to a public key trusted to sign the application in addition to the keys added with 'gravity app trusted-key add'").Strings()
+	g.AppImportCmd.RequireSignature = g.AppImportCmd.Flag("require-signature", "refuse to import an application that is not signed").Bool()
 
 	// export gravity application
 	g.AppExportCmd.CmdClause = g.AppCmd.Command("export", "export gravity application").Hidden()
 	g.AppExportCmd.Locator = g.AppExportCmd.Arg("pkg", "package name with application to export").Required().String()
 	g.AppExportCmd.RegistryURL = g.AppExportCmd.Flag("registry-url", "docker registry URL to use for export").Default(constants.DockerRegistry).String()
 	g.AppExportCmd.OpsCenterURL = g.AppExportCmd.Flag("ops-url", "optional remote opscenter URL").String()
+	g.AppExportCmd.Parallel = g.AppExportCmd.Flag("parallel", "specifies number of image layers to push concurrently. If < 0, the number of tasks is not restricted, if unspecified, then tasks are capped at the number of logical CPU cores.").Hidden().Int()
+	g.AppExportCmd.Format = g.AppExportCmd.Flag("format", fmt.Sprintf("export format: %q to push images to the registry or %q to write them as an OCI image layout", constants.ImageFormatRegistry, constants.ImageFormatOCI)).Default(constants.ImageFormatRegistry).String()
+	g.AppExportCmd.OutputDir = g.AppExportCmd.Flag("output-dir", "directory to write the OCI image layout to").Short('O').String()
+
+	// push images from an OCI image layout
+	g.AppImportImagesCmd.CmdClause = g.AppCmd.Command("import-images", "push images from an OCI image layout into docker registry").Hidden()
+	g.AppImportImagesCmd.Dir = g.AppImportImagesCmd.Arg("dir", "OCI image layout directory").Required().String()
+	g.AppImportImagesCmd.RegistryURL = g.AppImportImagesCmd.Flag("registry-url", "docker registry URL to push images to").Default(constants.DockerRegistry).String()
 
 	// delete gravity application
 	g.AppDeleteCmd.CmdClause = g.AppCmd.Command("delete", "delete gravity application").Hidden()
@@ -323,6 +403,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	// uninstall app
 	g.AppPackageUninstallCmd.CmdClause = g.AppCmd.Command("package-uninstall", "uninstall application").Hidden()
 	g.AppPackageUninstallCmd.Locator = Locator(g.AppPackageUninstallCmd.Arg("pkg", "package name with application").Required())
+	g.AppPackageUninstallCmd.Force = g.AppPackageUninstallCmd.Flag("force", "forcibly finalize namespaces and persistent volumes not removed by the uninstall hooks").Bool()
 
 	// get status of an application
 	g.AppStatusCmd.CmdClause = g.AppCmd.Command("status", "get app status").Hidden()
@@ -342,7 +423,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.AppPushCmd.OpsCenterURL = g.AppPushCmd.Flag("ops-url", "remote ops center url").Required().String()
 
 	// run an application hook
-	g.AppHookCmd.CmdClause = g.AppCmd.Command("hook", "run the specified application hook").Hidden()
+	g.AppHookCmd.CmdClause = g.AppCmd.Command("hook", "run the specified application hook and stream its output")
 	g.AppHookCmd.Package = Locator(g.AppHookCmd.Arg("pkg", "application package").Required())
 	g.AppHookCmd.HookName = g.AppHookCmd.Arg("hook-name", fmt.Sprintf("name of the hook (one of %v)", schema.AllHooks())).Required().String()
 	g.AppHookCmd.Env = g.AppHookCmd.Flag("env", "additional environment variables to provide to hook job as key=value pairs. Can be specified multiple times").StringMap()
@@ -353,6 +434,21 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.AppUnpackCmd.Dir = g.AppUnpackCmd.Arg("dir", "output directory").Required().String()
 	g.AppUnpackCmd.OpsCenterURL = g.AppUnpackCmd.Flag("ops-url", "optional remote OpsCenter URL").String()
 	g.AppUnpackCmd.ServiceUID = g.AppUnpackCmd.Flag("service-uid", "optional service user ID").String()
+	g.AppUnpackCmd.ServiceGID = g.AppUnpackCmd.Flag("service-gid", "optional service group ID").String()
+
+	// show changes between application versions
+	g.AppDiffCmd.CmdClause = g.AppCmd.Command("diff", "Show changes between two application versions, e.g. 'gravity app diff repo/app:1.0.0 repo/app:2.0.0'.")
+	g.AppDiffCmd.From = Locator(g.AppDiffCmd.Arg("from", "Application version to compare against.").Required())
+	g.AppDiffCmd.To = Locator(g.AppDiffCmd.Arg("to", "Application version to compare.").Required())
+	g.AppDiffCmd.OpsCenterURL = g.AppDiffCmd.Flag("ops-url", "Optional remote Ops Center URL.").String()
+
+	g.AppTrustedKeyCmd.CmdClause = g.AppCmd.Command("trusted-key", "Manage public keys trusted to sign cluster and application images.")
+	g.AppTrustedKeyAddCmd.CmdClause = g.AppTrustedKeyCmd.Command("add", "Trust images signed with the specified public key.")
+	g.AppTrustedKeyAddCmd.Name = g.AppTrustedKeyAddCmd.Arg("name", "Key name.").Required().String()
+	g.AppTrustedKeyAddCmd.Path = g.AppTrustedKeyAddCmd.Arg("path", "Path to the public key generated with 'tele keygen'.").Required().String()
+	g.AppTrustedKeyListCmd.CmdClause = g.AppTrustedKeyCmd.Command("ls", "Show trusted keys.").Alias("list")
+	g.AppTrustedKeyRemoveCmd.CmdClause = g.AppTrustedKeyCmd.Command("rm", "Stop trusting images signed with the specified key.").Alias("remove")
+	g.AppTrustedKeyRemoveCmd.Name = g.AppTrustedKeyRemoveCmd.Arg("name", "Key name.").Required().String()
 
 	g.WizardCmd.CmdClause = g.Command("wizard", "start wizard that will guide you through install process").Hidden()
 	g.WizardCmd.ServiceUID = g.WizardCmd.Flag("service-uid", fmt.Sprintf("Service user ID for planet. %q user will created and used if none specified", defaults.ServiceUser)).Default(defaults.ServiceUserID).OverrideDefaultFromEnvar(constants.ServiceUserEnvVar).String()
@@ -386,7 +482,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.OpsAgentCmd.CloudProvider = g.OpsAgentCmd.Flag("cloud-provider", "Cloud provider integration e.g. 'generic', 'aws'. If not set, autodetect environment").String()
 
 	// operations on packages
-	g.PackCmd.CmdClause = g.Command("package", "operations on gravity system packages")
+	g.PackCmd.CmdClause = g.Command("package", "operations on gravity system packages").Alias("pack")
 
 	// import package
 	g.PackImportCmd.CmdClause = g.PackCmd.Command("import", "import file or directory into package").Hidden()
@@ -452,6 +548,20 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.PackLabelsCmd.Add = configure.KeyValParam(g.PackLabelsCmd.Flag("add", "labels to add to the package"))
 	g.PackLabelsCmd.Remove = g.PackLabelsCmd.Flag("remove", "labels to remove from the package").Strings()
 
+	// migrate-store converts package store to deduplicated storage
+	g.PackMigrateCmd.CmdClause = g.PackCmd.Command("migrate-store", "convert package store to deduplicated chunk storage, services using the store should be stopped").Hidden()
+	g.PackMigrateCmd.Dir = g.PackMigrateCmd.Flag("dir", "package store directory, defaults to the packages directory of the local state directory").String()
+
+	// gc removes packages unused by cluster applications
+	g.PackGCCmd.CmdClause = g.PackCmd.Command("gc", "remove packages not used by the cluster applications, active operations or the runtime")
+	g.PackGCCmd.DryRun = g.PackGCCmd.Flag("dry-run", "display the packages to remove without removing them").Bool()
+	g.PackGCCmd.ProtectionWindow = g.PackGCCmd.Flag("protect", "keep unused packages imported within this period").Default(defaults.PackageGCProtectionWindow.String()).Duration()
+
+	g.PackVerifyCmd.CmdClause = g.PackCmd.Command("verify", "verify the checksums of the stored package data")
+	g.PackVerifyCmd.Cluster = g.PackVerifyCmd.Flag("cluster", "verify the copy of the cluster packages stored on this master node instead of the local package store").Bool()
+	g.PackVerifyCmd.Repair = g.PackVerifyCmd.Flag("repair", "restore the data of corrupted packages").Bool()
+	g.PackVerifyCmd.OpsCenterURL = g.PackVerifyCmd.Flag("ops-url", "Ops Center URL to restore the package data from, defaults to the cluster package service for the local package store").String()
+
 	// operations with users
 	g.UserCmd.CmdClause = g.Command("user", "operations with gravity users, only agent users are supported")
 
@@ -488,6 +598,33 @@ func RegisterCommands(app *kingpin.Application) *Application {
 			int(defaults.MaxUserResetTokenTTL/time.Hour))).
 		Default(fmt.Sprintf("%v", defaults.UserResetTokenTTL)).Duration()
 
+	// manage user API tokens
+	g.UsersTokenCmd.CmdClause = g.UsersCmd.Command("token", "Manage long-lived API tokens for automation")
+	g.UsersTokenCreateCmd.CmdClause = g.UsersTokenCmd.Command("create", "Create a new API token")
+	g.UsersTokenCreateCmd.User = g.UsersTokenCreateCmd.Flag("user", "User to create the token for, defaults to the current user").String()
+	g.UsersTokenCreateCmd.Permissions = g.UsersTokenCreateCmd.Flag("scope",
+		fmt.Sprintf("Restrict the token to the resource[/repository]:action permission, e.g. app:publish or app/example.com:read, can be repeated. Resources: %v, actions: %v",
+			strings.Join(storage.PermissionResources(), ", "),
+			strings.Join(storage.PermissionActions(), ", "))).Strings()
+	g.UsersTokenCreateCmd.TTL = g.UsersTokenCreateCmd.Flag("ttl", "Time the token is valid for, the token does not expire if not set").Duration()
+	g.UsersTokenCreateCmd.OpsCenterURL = g.UsersTokenCreateCmd.Flag("ops-url", "Optional Ops Center URL, defaults to the local cluster").String()
+	g.UsersTokenListCmd.CmdClause = g.UsersTokenCmd.Command("ls", "Show API tokens").Alias("list")
+	g.UsersTokenListCmd.User = g.UsersTokenListCmd.Flag("user", "User to show the tokens for, defaults to the current user").String()
+	g.UsersTokenListCmd.OpsCenterURL = g.UsersTokenListCmd.Flag("ops-url", "Optional Ops Center URL, defaults to the local cluster").String()
+	g.UsersTokenRemoveCmd.CmdClause = g.UsersTokenCmd.Command("rm", "Revoke an API token").Alias("remove")
+	g.UsersTokenRemoveCmd.Token = g.UsersTokenRemoveCmd.Arg("token", "Token to revoke").Required().String()
+	g.UsersTokenRemoveCmd.User = g.UsersTokenRemoveCmd.Flag("user", "User the token belongs to, defaults to the current user").String()
+	g.UsersTokenRemoveCmd.OpsCenterURL = g.UsersTokenRemoveCmd.Flag("ops-url", "Optional Ops Center URL, defaults to the local cluster").String()
+
+	g.JoinTokenCmd.CmdClause = g.Command("join-token", "Manage join tokens limited in time, number of uses or node roles")
+	g.JoinTokenCreateCmd.CmdClause = g.JoinTokenCmd.Command("create", "Generate a new join token")
+	g.JoinTokenCreateCmd.TTL = g.JoinTokenCreateCmd.Flag("ttl", "Time the token is valid for").Default(defaults.JoinTokenTTL.String()).Duration()
+	g.JoinTokenCreateCmd.MaxUses = g.JoinTokenCreateCmd.Flag("max-uses", "Maximum number of nodes that can join with the token, 0 for unlimited").Int()
+	g.JoinTokenCreateCmd.Roles = g.JoinTokenCreateCmd.Flag("role", fmt.Sprintf("Only allow nodes with the specified role (%v or %v) to join, can be repeated", schema.ServiceRoleMaster, schema.ServiceRoleNode)).Strings()
+	g.JoinTokenListCmd.CmdClause = g.JoinTokenCmd.Command("ls", "Show active join tokens").Alias("list")
+	g.JoinTokenRemoveCmd.CmdClause = g.JoinTokenCmd.Command("rm", "Revoke a join token").Alias("remove")
+	g.JoinTokenRemoveCmd.Token = g.JoinTokenRemoveCmd.Arg("token", "Token to revoke").Required().String()
+
 	// operations with api keys
 	g.APIKeyCmd.CmdClause = g.Command("apikey", "operations with api keys")
 
@@ -510,6 +647,9 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	// get cluster diagnostics report
 	g.ReportCmd.CmdClause = g.Command("report", "Generate cluster diagnostics report")
 	g.ReportCmd.FilePath = g.ReportCmd.Flag("file", "target report file name").Default("report.tar.gz").String()
+	g.ReportCmd.MaxFileSize = common.Capacity(g.ReportCmd.Flag("max-file-size", "Maximum size of a single file in the report, larger files are truncated").Default(defaults.ReportMaxFileSize))
+	g.ReportCmd.MaxSize = common.Capacity(g.ReportCmd.Flag("max-size", "Maximum total size of the collected diagnostics").Default(defaults.ReportMaxSize))
+	g.ReportCmd.Operations = g.ReportCmd.Flag("operations", "Number of most recent operations to include the logs and plans of").Default(strconv.Itoa(defaults.ReportOperations)).Int()
 
 	// operations on sites
 	g.SiteCmd.CmdClause = g.Command("site", "operations on gravity sites")
@@ -533,7 +673,6 @@ func RegisterCommands(app *kingpin.Application) *Application {
 
 	// info
 	g.SiteInfoCmd.CmdClause = g.SiteCmd.Command("info", "Prints local cluster information to the console").Hidden()
-	g.SiteInfoCmd.Format = common.Format(g.SiteInfoCmd.Flag("output", "Output format, supported formats: json, text"))
 
 	// complete install step
 	g.SiteCompleteCmd.CmdClause = g.SiteCmd.Command("complete", "Marks the final install step as completed").Hidden()
@@ -542,6 +681,9 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	// password reset for local gravity site user
 	g.SiteResetPasswordCmd.CmdClause = g.SiteCmd.Command("reset-password", "reset password for local user").Hidden()
 
+	g.SiteUpdateLicenseCmd.CmdClause = g.SiteCmd.Command("update-license", "Replace the license installed on the cluster")
+	g.SiteUpdateLicenseCmd.Path = g.SiteUpdateLicenseCmd.Arg("path", "Path to the new license file").Required().String()
+
 	// local site
 	g.LocalSiteCmd.CmdClause = g.Command("local-site", "Prints the local cluster domain name to the console").Hidden()
 
@@ -636,6 +778,46 @@ func RegisterCommands(app *kingpin.Application) *Application {
 
 	g.SystemStateDirCmd.CmdClause = g.SystemCmd.Command("state-dir", "show where all gravity data is stored on the node").Hidden()
 
+	// manage cluster backend
+	g.SystemBackendCmd.CmdClause = g.SystemCmd.Command("backend", "operations on the cluster backend").Hidden()
+	g.SystemBackendSnapshotCmd.CmdClause = g.SystemBackendCmd.Command("snapshot", "Save etcd snapshot of the cluster backend, must be run on a master node").Hidden()
+	g.SystemBackendSnapshotCmd.Path = g.SystemBackendSnapshotCmd.Arg("path", "File path to save the snapshot to").Required().String()
+	g.SystemBackendVerifyCmd.CmdClause = g.SystemBackendCmd.Command("verify", "Verify integrity of etcd snapshot").Hidden()
+	g.SystemBackendVerifyCmd.Path = g.SystemBackendVerifyCmd.Arg("path", "Path to the snapshot").Required().String()
+	g.SystemBackendVerifyCmd.Prefix = g.SystemBackendVerifyCmd.Flag("prefix", "Only count keys with the specified prefix, count all keys if empty").Default(defaults.EtcdKey).String()
+	g.SystemBackendRestoreCmd.CmdClause = g.SystemBackendCmd.Command("restore", "Restore the cluster backend from etcd snapshot, must be run on a master node").Hidden()
+	g.SystemBackendRestoreCmd.Path = g.SystemBackendRestoreCmd.Arg("path", "Path to the snapshot").Required().String()
+	g.SystemBackendRestoreCmd.Prefix = g.SystemBackendRestoreCmd.Flag("prefix", "Only restore keys with the specified prefix, restore all keys if empty").Default(defaults.EtcdKey).String()
+	g.SystemBackendRestoreCmd.Confirmed = g.SystemBackendRestoreCmd.Flag("confirm", "Do not ask for confirmation").Bool()
+	g.SystemBackendMigrateCmd.CmdClause = g.SystemBackendCmd.Command("migrate", "Migrate the cluster backend data to etcd v3 API, must be run on a master node").Hidden()
+	g.SystemBackendMigrateCmd.Confirmed = g.SystemBackendMigrateCmd.Flag("confirm", "Do not ask for confirmation").Bool()
+	g.SystemBackendBackupCmd.CmdClause = g.SystemBackendCmd.Command("backup", "Save a copy of the local state database while it is in use").Hidden()
+	g.SystemBackendBackupCmd.Path = g.SystemBackendBackupCmd.Arg("path", "File path to save the copy to").Required().String()
+	g.SystemBackendBackupCmd.Compact = g.SystemBackendBackupCmd.Flag("compact", "Compact the database before saving the copy").Bool()
+	g.SystemBackendGenerateKeyCmd.CmdClause = g.SystemBackendCmd.Command("generate-key", "Generate a new backend encryption key and write it to the key file on all master nodes, must be run on a master node").Hidden()
+	g.SystemBackendGenerateKeyCmd.Path = g.SystemBackendGenerateKeyCmd.Arg("path", "Absolute path to the key file, existing key files are not overwritten").Required().String()
+	g.SystemBackendGenerateKeyCmd.Local = g.SystemBackendGenerateKeyCmd.Flag("local", "Only write the key on this node, for the local state database").Bool()
+	g.SystemBackendReencryptCmd.CmdClause = g.SystemBackendCmd.Command("reencrypt", "Encrypt the backend values that are unencrypted or encrypted with a previous key, must be run on a master node").Hidden()
+	g.SystemBackendReencryptCmd.Local = g.SystemBackendReencryptCmd.Flag("local", "Re-encrypt the local state database instead of the cluster backend").Bool()
+	g.SystemBackendReencryptCmd.KeyFile = g.SystemBackendReencryptCmd.Flag("key-file", "Path to the existing file with the encryption key, see 'gravity system backend generate-key'").String()
+	g.SystemBackendReencryptCmd.SSMParameter = g.SystemBackendReencryptCmd.Flag("ssm-parameter", "Name of the AWS SSM parameter with the encryption key").String()
+	g.SystemBackendReencryptCmd.PreviousKeyFiles = g.SystemBackendReencryptCmd.Flag("previous-key-file", "Path to the file with the key used before the rotation, can be repeated").Strings()
+
+	// manage docker registries
+	g.SystemRegistryCmd.CmdClause = g.SystemCmd.Command("registry", "operations on docker registries").Hidden()
+	g.SystemRegistryReplicateCmd.CmdClause = g.SystemRegistryCmd.Command("replicate", "Replicate images missing in the destination registry from the source registry").Hidden()
+	g.SystemRegistryReplicateCmd.Source = g.SystemRegistryReplicateCmd.Arg("source", "Address of the registry to replicate images from").Required().String()
+	g.SystemRegistryReplicateCmd.Destination = g.SystemRegistryReplicateCmd.Arg("destination", "Address of the registry to replicate images to").Required().String()
+	g.SystemRegistryReplicateCmd.SourceCA = g.SystemRegistryReplicateCmd.Flag("source-ca", "Source registry CA certificate path").String()
+	g.SystemRegistryReplicateCmd.SourceCert = g.SystemRegistryReplicateCmd.Flag("source-cert", "Source registry client certificate path").String()
+	g.SystemRegistryReplicateCmd.SourceKey = g.SystemRegistryReplicateCmd.Flag("source-key", "Source registry client private key path").String()
+	g.SystemRegistryReplicateCmd.DestinationCA = g.SystemRegistryReplicateCmd.Flag("destination-ca", "Destination registry CA certificate path").String()
+	g.SystemRegistryReplicateCmd.DestinationCert = g.SystemRegistryReplicateCmd.Flag("destination-cert", "Destination registry client certificate path").String()
+	g.SystemRegistryReplicateCmd.DestinationKey = g.SystemRegistryReplicateCmd.Flag("destination-key", "Destination registry client private key path").String()
+	g.SystemRegistryReplicateCmd.Repositories = g.SystemRegistryReplicateCmd.Flag("repository", "Only replicate the specified repository, can be repeated. Replicates all repositories if unspecified").Strings()
+	g.SystemRegistryReplicateCmd.MissingOnly = g.SystemRegistryReplicateCmd.Flag("missing-only", "Only replicate images whose tags are missing in the destination registry, leave the tags pointing to different images intact").Bool()
+	g.SystemRegistryReplicateCmd.Parallel = g.SystemRegistryReplicateCmd.Flag("parallel", "Number of images to replicate concurrently. If < 0, the number of tasks is not restricted, if unspecified, then tasks are capped at the number of logical CPU cores").Int()
+
 	// manage docker devicemapper environment
 	g.SystemDevicemapperCmd.CmdClause = g.SystemCmd.Command("devicemapper", "manage docker devicemapper environment").Hidden()
 	g.SystemDevicemapperMountCmd.CmdClause = g.SystemDevicemapperCmd.Command("mount", "configure devicemapper environment").Hidden()
@@ -651,7 +833,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.SystemDisablePromiscModeCmd.Iface = g.SystemDisablePromiscModeCmd.Arg("name", "Name of the interface (i.e. docker0)").Default(defaults.DockerBridge).String()
 
 	g.SystemExportRuntimeJournalCmd.CmdClause = g.SystemCmd.Command("export-runtime-journal", "Export runtime journal logs to a file").Hidden()
-	g.SystemExportRuntimeJournalCmd.OutputFile = g.SystemExportRuntimeJournalCmd.Flag("output", "Name of resulting tarball. Output to stdout if unspecified").String()
+	g.SystemExportRuntimeJournalCmd.OutputFile = g.SystemExportRuntimeJournalCmd.Flag("output-file", "Name of resulting tarball. Output to stdout if unspecified").String()
 
 	g.SystemStreamRuntimeJournalCmd.CmdClause = g.SystemCmd.Command("stream-runtime-journal", "Stream runtime journal to stdout").Hidden()
 
@@ -660,6 +842,36 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.GarbageCollectCmd.Manual = g.GarbageCollectCmd.Flag("manual", "Do not start the operation automatically").Short('m').Bool()
 	g.GarbageCollectCmd.Confirmed = g.GarbageCollectCmd.Flag("confirm", "Confirm to remove unrelated docker images").Short('c').Bool()
 
+	g.CertsCmd.CmdClause = g.Command("certs", "Manage cluster certificates")
+	g.CertsRotateCmd.CmdClause = g.CertsCmd.Command("rotate", "Rotate certificates on all cluster nodes")
+	g.CertsRotateCmd.KeepCA = g.CertsRotateCmd.Flag("keep-ca", "Keep the existing certificate authority and only reissue node certificates").Bool()
+	g.CertsRotateCmd.Manual = g.CertsRotateCmd.Flag("manual", "Do not start the operation automatically").Short('m').Bool()
+	g.CertsRotateCmd.Confirmed = g.CertsRotateCmd.Flag("confirm", "Do not ask for confirmation").Bool()
+
+	g.PatchCmd.CmdClause = g.Command("patch", "Apply configuration changes to the cluster without a full upgrade")
+	g.PatchCmd.Env = g.PatchCmd.Flag("env", "Set the runtime environment variable as key=value pair. Can be specified multiple times").StringMap()
+	g.PatchCmd.DNSUpstreams = g.PatchCmd.Flag("dns-upstream", "Use the nameserver as upstream for the cluster DNS. Accepts <ip> or <ip>:<port> format. Can be specified multiple times").Strings()
+	g.PatchCmd.Manual = g.PatchCmd.Flag("manual", "Do not start the operation automatically").Short('m').Bool()
+	g.PatchCmd.Confirmed = g.PatchCmd.Flag("confirm", "Do not ask for confirmation").Bool()
+
+	g.EtcdCmd.CmdClause = g.Command("etcd", "Manage the cluster etcd, must be run on a master node")
+	g.EtcdBackupCmd.CmdClause = g.EtcdCmd.Command("backup", "Save etcd snapshot")
+	g.EtcdBackupCmd.Path = g.EtcdBackupCmd.Arg("path", "File path to save the snapshot to. If omitted, a timestamped snapshot is saved to --dir").String()
+	g.EtcdBackupCmd.Dir = g.EtcdBackupCmd.Flag("dir", "Directory to save timestamped snapshots to").Default(defaults.EtcdBackupDir).String()
+	g.EtcdBackupCmd.Keep = g.EtcdBackupCmd.Flag("keep", "Number of timestamped snapshots to keep in --dir").Default(strconv.Itoa(defaults.EtcdBackupKeep)).Int()
+	g.EtcdBackupCmd.Interval = g.EtcdBackupCmd.Flag("interval", "Keep saving timestamped snapshots with the specified interval").Duration()
+	g.EtcdRestoreCmd.CmdClause = g.EtcdCmd.Command("restore", "Restore the cluster data from etcd snapshot")
+	g.EtcdRestoreCmd.Path = g.EtcdRestoreCmd.Arg("path", "Path to the snapshot").Required().String()
+	g.EtcdRestoreCmd.Prefix = g.EtcdRestoreCmd.Flag("prefix", "Only restore keys with the specified prefix, restore all keys if empty").Default(defaults.EtcdKey).String()
+	g.EtcdRestoreCmd.Confirmed = g.EtcdRestoreCmd.Flag("confirm", "Do not ask for confirmation").Bool()
+	g.EtcdDefragCmd.CmdClause = g.EtcdCmd.Command("defrag", "Defragment the databases of etcd members one at a time")
+	g.EtcdStatusCmd.CmdClause = g.EtcdCmd.Command("status", "Display health and database size of etcd members")
+	g.EtcdScheduleCmd.CmdClause = g.EtcdCmd.Command("schedule", "Save etcd snapshots periodically on this node")
+	g.EtcdScheduleCmd.Dir = g.EtcdScheduleCmd.Flag("dir", "Directory to save snapshots to").Default(defaults.EtcdBackupDir).String()
+	g.EtcdScheduleCmd.Keep = g.EtcdScheduleCmd.Flag("keep", "Number of snapshots to keep").Default(strconv.Itoa(defaults.EtcdBackupKeep)).Int()
+	g.EtcdScheduleCmd.Interval = g.EtcdScheduleCmd.Flag("interval", "Interval between snapshots").Default(defaults.EtcdBackupInterval.String()).Duration()
+	g.EtcdScheduleCmd.Disable = g.EtcdScheduleCmd.Flag("disable", "Stop saving snapshots periodically").Bool()
+
 	// system clean up tasks
 	systemGCCmd := g.SystemCmd.Command("gc", "Run system clean up tasks")
 
@@ -698,6 +910,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.ExecCmd.Args = g.ExecCmd.Arg("arg", "Additional arguments to command").Strings()
 
 	g.ShellCmd.CmdClause = g.Command("shell", "Start an interactive shell in a planet container")
+	g.ShellCmd.Record = g.ShellCmd.Flag("record", "Record the session and upload the recording to the cluster audit log").Default("true").Bool()
 
 	// resource management
 	g.ResourceCmd.CmdClause = g.Command("resource", "Management of configuration resources")
@@ -726,9 +939,13 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.ResourceGetCmd.Kind = g.ResourceGetCmd.Arg("kind", fmt.Sprintf("resource kind, one of %v",
 		modules.GetResources().SupportedResources())).Required().String()
 	g.ResourceGetCmd.Name = g.ResourceGetCmd.Arg("name", fmt.Sprintf("optional resource name, lists all resources if omitted")).String()
-	g.ResourceGetCmd.Format = common.Format(g.ResourceGetCmd.Flag("format", "resource format, e.g. 'text', 'json' or 'yaml'").Default(string(constants.EncodingText)))
 	g.ResourceGetCmd.WithSecrets = g.ResourceGetCmd.Flag("with-secrets", "include secret properties like private keys").Default("false").Bool()
 	g.ResourceGetCmd.User = g.ResourceGetCmd.Flag("user", "user to display resources for, defaults to currently logged in user").String()
+	common.DeprecatedFormat(g.ResourceGetCmd.CmdClause, g.Output)
+
+	g.AuditCmd.CmdClause = g.Command("audit", "Inspect the audit log of cluster API calls")
+	g.AuditListCmd.CmdClause = g.AuditCmd.Command("ls", "Show audit events").Alias("list")
+	g.AuditListCmd.Since = g.AuditListCmd.Flag("since", "Only show events recorded within the specified duration, e.g. 1h").Default("24h").Duration()
 
 	return g
 }
@@ -0,0 +1,64 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/app/docker"
+	"github.com/gravitational/gravity/lib/localenv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gravitational/trace"
+)
+
+type replicateRegistryConfig struct {
+	// source is the registry to replicate images from
+	source registryConfig
+	// destination is the registry to replicate images to
+	destination registryConfig
+	// repositories optionally limits replication to the specified repositories
+	repositories []string
+	// missingOnly only replicates images whose tags are missing in the destination
+	missingOnly bool
+	// parallel defines the number of images to replicate concurrently
+	parallel int
+}
+
+// replicateRegistry transfers images missing in the destination registry
+// from the source registry
+func replicateRegistry(env *localenv.LocalEnvironment, conf replicateRegistryConfig) error {
+	result, err := docker.Replicate(context.TODO(), docker.ReplicateRequest{
+		Source:       conf.source.connectionRequest(),
+		Destination:  conf.destination.connectionRequest(),
+		Repositories: conf.repositories,
+		MissingOnly:  conf.missingOnly,
+		Parallel:     conf.parallel,
+		Progress:     env,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Replicated %v images (%v layers, %v), %v images were up-to-date.\n",
+		len(result.Replicated), result.Blobs, humanize.Bytes(uint64(result.Bytes)),
+		len(result.UpToDate))
+	if len(result.Skipped) != 0 {
+		env.Printf("Skipped %v images that differ in the destination registry.\n",
+			len(result.Skipped))
+	}
+	return nil
+}
@@ -62,6 +62,11 @@ func rotateCertificates(env *localenv.LocalEnvironment, o rotateOptions) (err er
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if len(caKeyPair.KeyPEM) == 0 {
+		return trace.BadParameter("certificate authority private key is not available: " +
+			"cluster certificates are issued by an external signer, " +
+			"use the certificate rotation operation to renew them")
+	}
 	baseKeyPair, err := archive.GetKeyPair(constants.APIServerKeyPair)
 	if err != nil {
 		return trace.Wrap(err)
@@ -36,6 +36,8 @@ import (
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/process"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
 	"github.com/gravitational/gravity/lib/systemservice"
 	"github.com/gravitational/gravity/lib/utils"
 
@@ -109,6 +111,17 @@ func InitAndCheck(g *Application, cmd string) error {
 	}
 	logrus.SetFormatter(&trace.TextFormatter{})
 
+	// commands that write files accept the path of the file with
+	// the output flag, all others only accept output formats
+	switch cmd {
+	case g.AppExportCmd.FullCommand(),
+		g.SystemExportRuntimeJournalCmd.FullCommand():
+	default:
+		if err := g.Output.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
 	// the following commands write logs to the system log file (in
 	// addition to journald)
 	switch cmd {
@@ -181,6 +194,9 @@ func InitAndCheck(g *Application, cmd string) error {
 		g.PlanRollbackCmd.FullCommand(),
 		g.PlanResumeCmd.FullCommand(),
 		g.PlanCompleteCmd.FullCommand(),
+		g.PlanPauseCmd.FullCommand(),
+		g.PlanCancelCmd.FullCommand(),
+		g.PlanLogsCmd.FullCommand(),
 		g.InstallCmd.FullCommand(),
 		g.JoinCmd.FullCommand(),
 		g.AutoJoinCmd.FullCommand(),
@@ -189,7 +205,18 @@ func InitAndCheck(g *Application, cmd string) error {
 		g.BackupCmd.FullCommand(),
 		g.RestoreCmd.FullCommand(),
 		g.GarbageCollectCmd.FullCommand(),
+		g.CertsRotateCmd.FullCommand(),
+		g.PatchCmd.FullCommand(),
+		g.EtcdBackupCmd.FullCommand(),
+		g.EtcdRestoreCmd.FullCommand(),
+		g.EtcdDefragCmd.FullCommand(),
+		g.EtcdStatusCmd.FullCommand(),
+		g.EtcdScheduleCmd.FullCommand(),
 		g.SystemGCRegistryCmd.FullCommand(),
+		g.SystemBackendSnapshotCmd.FullCommand(),
+		g.SystemBackendRestoreCmd.FullCommand(),
+		g.SystemBackendMigrateCmd.FullCommand(),
+		g.SystemBackendReencryptCmd.FullCommand(),
 		g.CheckCmd.FullCommand():
 		if err := checkRunningAsRoot(); err != nil {
 			return trace.Wrap(err)
@@ -226,7 +253,7 @@ func InitAndCheck(g *Application, cmd string) error {
 func Execute(g *Application, cmd string, extraArgs []string) error {
 	switch cmd {
 	case g.VersionCmd.FullCommand():
-		return printVersion(*g.VersionCmd.Output)
+		return printVersion(g.Output.Format)
 	case g.SiteStartCmd.FullCommand():
 		return startSite(*g.SiteStartCmd.ConfigPath, *g.SiteStartCmd.InitPath)
 	case g.SiteInitCmd.FullCommand():
@@ -346,9 +373,16 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.UpdateTriggerCmd.Manual,
 			*g.UpdateTriggerCmd.Block,
 			*g.UpdateTriggerCmd.SkipVersionCheck,
+			defaults.UpdateParallelism,
+			storage.DrainOptions{},
+			nil,
 		)
 	case g.UpdatePlanInitCmd.FullCommand():
 		return initUpdateOperationPlan(localEnv, updateEnv)
+	case g.UpdateScheduledCmd.FullCommand():
+		return listScheduledUpdates(localEnv, g.Output.Format)
+	case g.UpdateCancelCmd.FullCommand():
+		return cancelScheduledUpdate(localEnv, *g.UpdateCancelCmd.ID)
 	case g.UpgradeCmd.FullCommand():
 		if *g.UpgradeCmd.Resume {
 			*g.UpgradeCmd.Phase = fsm.RootPhase
@@ -360,14 +394,32 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 					Force:            *g.UpgradeCmd.Force,
 					Timeout:          *g.UpgradeCmd.Timeout,
 					SkipVersionCheck: *g.UpgradeCmd.SkipVersionCheck,
+					Parallel:         *g.UpgradeCmd.Parallel,
 				})
 		}
+		if *g.UpgradeCmd.NotBefore != "" || *g.UpgradeCmd.Window != "" {
+			if *g.UpgradeCmd.Manual {
+				return trace.BadParameter("scheduled operations cannot be run in manual mode")
+			}
+			return scheduleUpdate(localEnv,
+				*g.UpgradeCmd.App,
+				*g.UpgradeCmd.NotBefore,
+				*g.UpgradeCmd.Window,
+				*g.UpgradeCmd.WindowDuration,
+			)
+		}
 		return updateTrigger(localEnv,
 			updateEnv,
 			*g.UpgradeCmd.App,
 			*g.UpgradeCmd.Manual,
 			*g.UpgradeCmd.Block,
 			*g.UpgradeCmd.SkipVersionCheck,
+			*g.UpgradeCmd.Parallel,
+			storage.DrainOptions{
+				Timeout: *g.UpgradeCmd.DrainTimeout,
+				Force:   *g.UpgradeCmd.ForceDrain,
+			},
+			canaryOptions(*g.UpgradeCmd.Canary, *g.UpgradeCmd.CanaryPause),
 		)
 	case g.PlanExecuteCmd.FullCommand():
 		return executePhase(localEnv, updateEnv, joinEnv,
@@ -377,15 +429,18 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 				Timeout:          *g.PlanExecuteCmd.PhaseTimeout,
 				SkipVersionCheck: *g.PlanCmd.SkipVersionCheck,
 				OperationID:      *g.PlanCmd.OperationID,
+				Parallel:         *g.PlanExecuteCmd.Parallel,
+				DryRun:           *g.PlanExecuteCmd.DryRun,
 			})
 	case g.PlanResumeCmd.FullCommand():
-		return executePhase(localEnv, updateEnv, joinEnv,
+		return resumeOperation(localEnv, updateEnv, joinEnv,
 			PhaseParams{
-				PhaseID:          fsm.RootPhase,
 				Force:            *g.PlanResumeCmd.Force,
 				Timeout:          *g.PlanResumeCmd.PhaseTimeout,
 				SkipVersionCheck: *g.PlanCmd.SkipVersionCheck,
 				OperationID:      *g.PlanCmd.OperationID,
+				Parallel:         *g.PlanResumeCmd.Parallel,
+				DryRun:           *g.PlanResumeCmd.DryRun,
 			})
 	case g.PlanRollbackCmd.FullCommand():
 		return rollbackPhase(localEnv, updateEnv, joinEnv,
@@ -397,31 +452,84 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 				OperationID:      *g.PlanCmd.OperationID,
 			})
 	case g.PlanDisplayCmd.FullCommand():
+		format := g.Output.Format
+		if *g.PlanDisplayCmd.Format != "" {
+			format = constants.Format(*g.PlanDisplayCmd.Format)
+		}
 		return displayOperationPlan(localEnv, updateEnv, joinEnv,
-			*g.PlanCmd.OperationID, *g.PlanDisplayCmd.Output)
+			*g.PlanCmd.OperationID, format)
+	case g.PlanLogsCmd.FullCommand():
+		return displayPhaseLogs(localEnv, updateEnv, joinEnv,
+			*g.PlanCmd.OperationID, *g.PlanLogsCmd.Phase, g.Output.Format)
 	case g.PlanCompleteCmd.FullCommand():
 		return completeOperationPlan(localEnv, updateEnv, joinEnv, *g.PlanCmd.OperationID)
+	case g.PlanPauseCmd.FullCommand():
+		return interruptOperation(localEnv, updateEnv, joinEnv, *g.PlanCmd.OperationID,
+			storage.OperationInterruptPause)
+	case g.PlanCancelCmd.FullCommand():
+		return interruptOperation(localEnv, updateEnv, joinEnv, *g.PlanCmd.OperationID,
+			storage.OperationInterruptCancel)
+	case g.ExpandCmd.FullCommand():
+		return provisionNodes(localEnv, *g.ExpandCmd.Provisioner, *g.ExpandCmd.Profile, *g.ExpandCmd.Count)
+	case g.InfraPlanCmd.FullCommand():
+		return planInfra(localEnv, infraConfig{
+			stateDir:  *g.InfraPlanCmd.Path,
+			provider:  *g.InfraPlanCmd.Provider,
+			variables: *g.InfraPlanCmd.Vars,
+			binary:    *g.InfraPlanCmd.Terraform,
+		})
+	case g.InfraApplyCmd.FullCommand():
+		return applyInfra(localEnv, infraConfig{
+			stateDir:  *g.InfraApplyCmd.Path,
+			provider:  *g.InfraApplyCmd.Provider,
+			variables: *g.InfraApplyCmd.Vars,
+			binary:    *g.InfraApplyCmd.Terraform,
+		})
 	case g.LeaveCmd.FullCommand():
 		return leave(localEnv, leaveConfig{
 			force:     *g.LeaveCmd.Force,
 			confirmed: *g.LeaveCmd.Confirm,
+			drain: storage.DrainOptions{
+				Timeout: *g.LeaveCmd.DrainTimeout,
+				Force:   *g.LeaveCmd.ForceDrain,
+			},
 		})
 	case g.RemoveCmd.FullCommand():
 		return remove(localEnv, removeConfig{
 			server:    *g.RemoveCmd.Node,
 			force:     *g.RemoveCmd.Force,
 			confirmed: *g.RemoveCmd.Confirm,
+			drain: storage.DrainOptions{
+				Timeout: *g.RemoveCmd.DrainTimeout,
+				Force:   *g.RemoveCmd.ForceDrain,
+			},
 		})
-	case g.StatusCmd.FullCommand():
+	case g.StatusHistoryCmd.FullCommand():
+		return statusHistory(localEnv, statusHistoryConfig{
+			since:  *g.StatusHistoryCmd.Since,
+			all:    *g.StatusHistoryCmd.All,
+			format: g.Output.Format,
+		})
+	case g.StatusRegistryCmd.FullCommand():
+		return statusRegistry(localEnv, g.Output.Format)
+	case g.StatusPackagesCmd.FullCommand():
+		return statusPackages(localEnv, g.Output.Format)
+	case g.StatusLicenseCmd.FullCommand():
+		return statusLicense(localEnv, g.Output.Format)
+	case g.StatusClusterCmd.FullCommand():
 		printOptions := printOptions{
 			token:       *g.StatusCmd.Token,
 			operationID: *g.StatusCmd.OperationID,
 			quiet:       *g.Silent,
-			format:      *g.StatusCmd.Output,
+			verbose:     *g.StatusCmd.Verbose,
+			format:      g.Output.Format,
 		}
 		if *g.StatusCmd.Tail {
 			return tailStatus(localEnv, *g.StatusCmd.OperationID)
 		}
+		if *g.StatusCmd.Follow {
+			return followStatus(localEnv, *g.StatusCmd.OperationID)
+		}
 		if *g.StatusCmd.Seconds != 0 {
 			return statusPeriodic(localEnv, printOptions, *g.StatusCmd.Seconds)
 		} else {
@@ -450,7 +558,7 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		})
 	case g.AppListCmd.FullCommand():
 		return releaseList(localEnv,
-			*g.AppListCmd.All)
+			*g.AppListCmd.All, g.Output.Format)
 	case g.AppUpgradeCmd.FullCommand():
 		return releaseUpgrade(localEnv, releaseUpgradeConfig{
 			Release: *g.AppUpgradeCmd.Release,
@@ -478,6 +586,7 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 	case g.AppHistoryCmd.FullCommand():
 		return releaseHistory(localEnv, releaseHistoryConfig{
 			Release: *g.AppHistoryCmd.Release,
+			Format:  g.Output.Format,
 		})
 	case g.AppSyncCmd.FullCommand():
 		return appSync(localEnv, appSyncConfig{
@@ -493,7 +602,8 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		return appSearch(localEnv,
 			*g.AppSearchCmd.Pattern,
 			*g.AppSearchCmd.Remote,
-			*g.AppSearchCmd.All)
+			*g.AppSearchCmd.All,
+			g.Output.Format)
 	case g.AppRebuildIndexCmd.FullCommand():
 		return appRebuildIndex(localEnv)
 	case g.AppIndexCmd.FullCommand():
@@ -530,12 +640,21 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			req,
 			*g.AppImportCmd.OpsCenterURL,
 			*g.Silent,
-			*g.AppImportCmd.Parallel)
+			*g.AppImportCmd.Parallel,
+			*g.AppImportCmd.TrustedKeys,
+			*g.AppImportCmd.RequireSignature)
 	case g.AppExportCmd.FullCommand():
 		return exportApp(localEnv,
 			*g.AppExportCmd.Locator,
 			*g.AppExportCmd.OpsCenterURL,
-			*g.AppExportCmd.RegistryURL)
+			*g.AppExportCmd.RegistryURL,
+			*g.AppExportCmd.Parallel,
+			*g.AppExportCmd.Format,
+			g.Output.Path(*g.AppExportCmd.OutputDir))
+	case g.AppImportImagesCmd.FullCommand():
+		return importImages(localEnv,
+			*g.AppImportImagesCmd.Dir,
+			*g.AppImportImagesCmd.RegistryURL)
 	case g.AppDeleteCmd.FullCommand():
 		return deleteApp(localEnv,
 			*g.AppDeleteCmd.Locator,
@@ -553,7 +672,8 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.AppStatusCmd.OpsCenterURL)
 	case g.AppPackageUninstallCmd.FullCommand():
 		return uninstallAppPackage(localEnv,
-			*g.AppPackageUninstallCmd.Locator)
+			*g.AppPackageUninstallCmd.Locator,
+			*g.AppPackageUninstallCmd.Force)
 	case g.AppPullCmd.FullCommand():
 		return pullApp(localEnv,
 			*g.AppPullCmd.Package,
@@ -570,13 +690,28 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			Hook:        schema.HookType(*g.AppHookCmd.HookName),
 			Env:         *g.AppHookCmd.Env,
 		}
-		return outputAppHook(localEnv, req)
+		return runAppHook(localEnv, req)
 	case g.AppUnpackCmd.FullCommand():
 		return unpackAppResources(localEnv,
 			*g.AppUnpackCmd.Package,
 			*g.AppUnpackCmd.Dir,
 			*g.AppUnpackCmd.OpsCenterURL,
-			*g.AppUnpackCmd.ServiceUID)
+			*g.AppUnpackCmd.ServiceUID,
+			*g.AppUnpackCmd.ServiceGID)
+	case g.AppDiffCmd.FullCommand():
+		return diffApps(localEnv,
+			*g.AppDiffCmd.From,
+			*g.AppDiffCmd.To,
+			*g.AppDiffCmd.OpsCenterURL,
+			g.Output.Format)
+	case g.AppTrustedKeyAddCmd.FullCommand():
+		return addTrustedKey(localEnv,
+			*g.AppTrustedKeyAddCmd.Name,
+			*g.AppTrustedKeyAddCmd.Path)
+	case g.AppTrustedKeyListCmd.FullCommand():
+		return listTrustedKeys(localEnv, g.Output.Format)
+	case g.AppTrustedKeyRemoveCmd.FullCommand():
+		return removeTrustedKey(localEnv, *g.AppTrustedKeyRemoveCmd.Name)
 	// package commands
 	case g.PackImportCmd.FullCommand():
 		return importPackage(localEnv,
@@ -637,6 +772,17 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.PackLabelsCmd.OpsCenterURL,
 			*g.PackLabelsCmd.Add,
 			*g.PackLabelsCmd.Remove)
+	case g.PackMigrateCmd.FullCommand():
+		return migratePackageStore(localEnv, *g.PackMigrateCmd.Dir)
+	case g.PackGCCmd.FullCommand():
+		return collectPackageGarbage(localEnv, *g.PackGCCmd.DryRun, *g.PackGCCmd.ProtectionWindow)
+	case g.PackVerifyCmd.FullCommand():
+		return verifyPackages(localEnv, verifyPackagesConfig{
+			cluster:      *g.PackVerifyCmd.Cluster,
+			repair:       *g.PackVerifyCmd.Repair,
+			opsCenterURL: *g.PackVerifyCmd.OpsCenterURL,
+			format:       g.Output.Format,
+		})
 		// OpsCenter commands
 	case g.OpsConnectCmd.FullCommand():
 		return connectToOpsCenter(localEnv,
@@ -672,18 +818,21 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.APIKeyDeleteCmd.Email,
 			*g.APIKeyDeleteCmd.Token)
 	case g.ReportCmd.FullCommand():
-		return getClusterReport(localEnv, *g.ReportCmd.FilePath)
+		return getClusterReport(localEnv, *g.ReportCmd.FilePath,
+			*g.ReportCmd.MaxFileSize, *g.ReportCmd.MaxSize, *g.ReportCmd.Operations)
 	// cluster commands
 	case g.SiteListCmd.FullCommand():
 		return listSites(localEnv, *g.SiteListCmd.OpsCenterURL)
 	case g.SiteInfoCmd.FullCommand():
 		return printLocalClusterInfo(localEnv,
-			*g.SiteInfoCmd.Format)
+			g.Output.Format)
 	case g.SiteCompleteCmd.FullCommand():
 		return completeInstallerStep(localEnv,
 			*g.SiteCompleteCmd.Support)
 	case g.SiteResetPasswordCmd.FullCommand():
 		return resetPassword(localEnv)
+	case g.SiteUpdateLicenseCmd.FullCommand():
+		return updateLicense(localEnv, *g.SiteUpdateLicenseCmd.Path)
 	case g.StatusResetCmd.FullCommand():
 		return resetClusterState(localEnv)
 	case g.LocalSiteCmd.FullCommand():
@@ -740,7 +889,8 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.RestoreCmd.Tarball,
 			*g.RestoreCmd.Timeout,
 			*g.RestoreCmd.Follow,
-			*g.Silent)
+			*g.Silent,
+			*g.RestoreCmd.Confirmed)
 	case g.SystemServiceInstallCmd.FullCommand():
 		req := &systemservice.NewPackageServiceRequest{
 			Package:       *g.SystemServiceInstallCmd.Package,
@@ -777,16 +927,98 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.SystemReportCmd.Compressed)
 	case g.SystemStateDirCmd.FullCommand():
 		return printStateDir()
+	case g.SystemBackendSnapshotCmd.FullCommand():
+		return saveBackendSnapshot(localEnv,
+			*g.SystemBackendSnapshotCmd.Path)
+	case g.SystemBackendVerifyCmd.FullCommand():
+		return verifyBackendSnapshot(localEnv,
+			*g.SystemBackendVerifyCmd.Path,
+			*g.SystemBackendVerifyCmd.Prefix)
+	case g.SystemBackendRestoreCmd.FullCommand():
+		return restoreBackendSnapshot(localEnv,
+			*g.SystemBackendRestoreCmd.Path,
+			*g.SystemBackendRestoreCmd.Prefix,
+			*g.SystemBackendRestoreCmd.Confirmed)
+	case g.SystemBackendMigrateCmd.FullCommand():
+		return migrateBackend(localEnv,
+			*g.SystemBackendMigrateCmd.Confirmed)
+	case g.SystemBackendBackupCmd.FullCommand():
+		return backupLocalBackend(localEnv,
+			*g.SystemBackendBackupCmd.Path,
+			*g.SystemBackendBackupCmd.Compact)
+	case g.SystemBackendGenerateKeyCmd.FullCommand():
+		return generateBackendKey(localEnv,
+			*g.SystemBackendGenerateKeyCmd.Path,
+			*g.SystemBackendGenerateKeyCmd.Local)
+	case g.SystemBackendReencryptCmd.FullCommand():
+		return reencryptBackend(localEnv, *g.SystemBackendReencryptCmd.Local,
+			keyval.EncryptionConfig{
+				KeyFile:          *g.SystemBackendReencryptCmd.KeyFile,
+				SSMParameter:     *g.SystemBackendReencryptCmd.SSMParameter,
+				PreviousKeyFiles: *g.SystemBackendReencryptCmd.PreviousKeyFiles,
+			})
+	case g.SystemRegistryReplicateCmd.FullCommand():
+		return replicateRegistry(localEnv, replicateRegistryConfig{
+			source: registryConfig{
+				Registry: *g.SystemRegistryReplicateCmd.Source,
+				CAPath:   *g.SystemRegistryReplicateCmd.SourceCA,
+				CertPath: *g.SystemRegistryReplicateCmd.SourceCert,
+				KeyPath:  *g.SystemRegistryReplicateCmd.SourceKey,
+			},
+			destination: registryConfig{
+				Registry: *g.SystemRegistryReplicateCmd.Destination,
+				CAPath:   *g.SystemRegistryReplicateCmd.DestinationCA,
+				CertPath: *g.SystemRegistryReplicateCmd.DestinationCert,
+				KeyPath:  *g.SystemRegistryReplicateCmd.DestinationKey,
+			},
+			repositories: *g.SystemRegistryReplicateCmd.Repositories,
+			missingOnly:  *g.SystemRegistryReplicateCmd.MissingOnly,
+			parallel:     *g.SystemRegistryReplicateCmd.Parallel,
+		})
 	case g.SystemEnablePromiscModeCmd.FullCommand():
 		return enablePromiscMode(localEnv, *g.SystemEnablePromiscModeCmd.Iface)
 	case g.SystemDisablePromiscModeCmd.FullCommand():
 		return disablePromiscMode(localEnv, *g.SystemDisablePromiscModeCmd.Iface)
 	case g.SystemExportRuntimeJournalCmd.FullCommand():
-		return exportRuntimeJournal(localEnv, *g.SystemExportRuntimeJournalCmd.OutputFile)
+		return exportRuntimeJournal(localEnv, g.Output.Path(*g.SystemExportRuntimeJournalCmd.OutputFile))
 	case g.SystemStreamRuntimeJournalCmd.FullCommand():
 		return streamRuntimeJournal(localEnv)
 	case g.GarbageCollectCmd.FullCommand():
 		return garbageCollect(localEnv, *g.GarbageCollectCmd.Manual, *g.GarbageCollectCmd.Confirmed)
+	case g.CertsRotateCmd.FullCommand():
+		return rotateClusterCertificates(context.TODO(), localEnv, updateEnv,
+			*g.CertsRotateCmd.KeepCA,
+			*g.CertsRotateCmd.Manual,
+			*g.CertsRotateCmd.Confirmed)
+	case g.PatchCmd.FullCommand():
+		return patchCluster(context.TODO(), localEnv, updateEnv,
+			*g.PatchCmd.Env,
+			*g.PatchCmd.DNSUpstreams,
+			*g.PatchCmd.Manual,
+			*g.PatchCmd.Confirmed)
+	case g.EtcdBackupCmd.FullCommand():
+		return backupEtcd(localEnv,
+			*g.EtcdBackupCmd.Path,
+			*g.EtcdBackupCmd.Dir,
+			*g.EtcdBackupCmd.Keep,
+			*g.EtcdBackupCmd.Interval)
+	case g.EtcdRestoreCmd.FullCommand():
+		return restoreBackendSnapshot(localEnv,
+			*g.EtcdRestoreCmd.Path,
+			*g.EtcdRestoreCmd.Prefix,
+			*g.EtcdRestoreCmd.Confirmed)
+	case g.EtcdDefragCmd.FullCommand():
+		return defragEtcd(localEnv)
+	case g.EtcdStatusCmd.FullCommand():
+		return printEtcdStatus(localEnv, g.Output.Format)
+	case g.EtcdScheduleCmd.FullCommand():
+		if *g.EtcdScheduleCmd.Disable {
+			return unscheduleEtcdBackup(localEnv)
+		}
+		return scheduleEtcdBackup(localEnv,
+			*g.EtcdScheduleCmd.Dir,
+			*g.EtcdScheduleCmd.Keep,
+			*g.EtcdScheduleCmd.Interval)
 	case g.SystemGCJournalCmd.FullCommand():
 		return removeUnusedJournalFiles(localEnv,
 			*g.SystemGCJournalCmd.MachineIDFile,
@@ -808,7 +1040,7 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.ExecCmd.Cmd,
 			*g.ExecCmd.Args)
 	case g.ShellCmd.FullCommand():
-		return planetShell(localEnv)
+		return planetShell(localEnv, *g.ShellCmd.Record)
 	case g.PlanetStatusCmd.FullCommand():
 		return getPlanetStatus(localEnv, extraArgs)
 	case g.SystemDevicemapperMountCmd.FullCommand():
@@ -826,6 +1058,31 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		return resetUser(localEnv,
 			*g.UsersResetCmd.Name,
 			*g.UsersResetCmd.TTL)
+	case g.UsersTokenCreateCmd.FullCommand():
+		return createUserToken(localEnv,
+			*g.UsersTokenCreateCmd.OpsCenterURL,
+			*g.UsersTokenCreateCmd.User,
+			*g.UsersTokenCreateCmd.Permissions,
+			*g.UsersTokenCreateCmd.TTL)
+	case g.UsersTokenListCmd.FullCommand():
+		return listUserTokens(localEnv,
+			*g.UsersTokenListCmd.OpsCenterURL,
+			*g.UsersTokenListCmd.User,
+			g.Output.Format)
+	case g.UsersTokenRemoveCmd.FullCommand():
+		return removeUserToken(localEnv,
+			*g.UsersTokenRemoveCmd.OpsCenterURL,
+			*g.UsersTokenRemoveCmd.User,
+			*g.UsersTokenRemoveCmd.Token)
+	case g.JoinTokenCreateCmd.FullCommand():
+		return createJoinToken(localEnv,
+			*g.JoinTokenCreateCmd.TTL,
+			*g.JoinTokenCreateCmd.MaxUses,
+			*g.JoinTokenCreateCmd.Roles)
+	case g.JoinTokenListCmd.FullCommand():
+		return listJoinTokens(localEnv, g.Output.Format)
+	case g.JoinTokenRemoveCmd.FullCommand():
+		return removeJoinToken(localEnv, *g.JoinTokenRemoveCmd.Token)
 	case g.ResourceCreateCmd.FullCommand():
 		return createResource(localEnv, g,
 			*g.ResourceCreateCmd.Filename,
@@ -846,8 +1103,10 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.ResourceGetCmd.Kind,
 			*g.ResourceGetCmd.Name,
 			*g.ResourceGetCmd.WithSecrets,
-			*g.ResourceGetCmd.Format,
+			g.Output.Format,
 			*g.ResourceGetCmd.User)
+	case g.AuditListCmd.FullCommand():
+		return listAuditEvents(localEnv, *g.AuditListCmd.Since, g.Output.Format)
 	case g.RPCAgentDeployCmd.FullCommand():
 		return rpcAgentDeploy(localEnv, updateEnv,
 			*g.RPCAgentDeployCmd.LeaderArgs,
@@ -860,10 +1119,13 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 	case g.RPCAgentShutdownCmd.FullCommand():
 		return rpcAgentShutdown(localEnv)
 	case g.CheckCmd.FullCommand():
-		return checkManifest(localEnv,
-			*g.CheckCmd.ManifestFile,
-			*g.CheckCmd.Profile,
-			*g.CheckCmd.AutoFix)
+		return checkManifest(localEnv, checkConfig{
+			manifestPath: *g.CheckCmd.ManifestFile,
+			profileName:  *g.CheckCmd.Profile,
+			autoFix:      *g.CheckCmd.AutoFix,
+			checks:       *g.CheckCmd.Checks,
+			format:       g.Output.Format,
+		})
 	}
 	return trace.NotFound("unknown command %v", cmd)
 }
@@ -0,0 +1,174 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/tool/common"
+
+	"github.com/gravitational/trace"
+)
+
+// scheduleUpdate queues the cluster update to start at the specified time
+// or within the specified maintenance window
+func scheduleUpdate(env *localenv.LocalEnvironment, updatePackage, notBefore, window string, windowDuration time.Duration) error {
+	schedule, err := parseOperationSchedule(notBefore, window, windowDuration)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	updateApp, err := checkForUpdate(env, operator, cluster.App.Package, updatePackage)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = checkCanUpdate(*cluster, operator, updateApp.Manifest)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	op, err := operator.CreateScheduledOperation(context.TODO(), ops.CreateScheduledOperationRequest{
+		AccountID:   cluster.AccountID,
+		ClusterName: cluster.Domain,
+		Type:        ops.OperationUpdate,
+		App:         updateApp.Package.String(),
+		Schedule:    *schedule,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Update to %v has been scheduled with ID %v.\n", op.App, op.ID)
+	env.Println("Use 'gravity update scheduled' to view and 'gravity update cancel' to cancel it.")
+	return nil
+}
+
+// listScheduledUpdates displays the scheduled operations of the local cluster
+func listScheduledUpdates(env *localenv.LocalEnvironment, format constants.Format) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	scheduled, err := operator.GetScheduledOperations(context.TODO(), cluster.Key())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, scheduled))
+	case constants.EncodingText:
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "ID\tApplication\tSchedule\tState\tOperation\n")
+		fmt.Fprintf(w, "--\t-----------\t--------\t-----\t---------\n")
+		for _, op := range scheduled {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", op.ID, op.App,
+				formatOperationSchedule(op.Schedule), op.State,
+				formatScheduledOperationResult(op))
+		}
+		return trace.Wrap(w.Flush())
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+}
+
+// cancelScheduledUpdate cancels the specified scheduled operation
+func cancelScheduledUpdate(env *localenv.LocalEnvironment, id string) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = operator.CancelScheduledOperation(context.TODO(), ops.ScheduledOperationKey{
+		AccountID:   cluster.AccountID,
+		ClusterName: cluster.Domain,
+		ID:          id,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Scheduled operation %v canceled\n", id)
+	return nil
+}
+
+// parseOperationSchedule returns the operation schedule for the specified
+// command line parameters
+func parseOperationSchedule(notBefore, window string, windowDuration time.Duration) (*storage.OperationSchedule, error) {
+	var schedule storage.OperationSchedule
+	if notBefore != "" {
+		var err error
+		schedule.NotBefore, err = time.Parse(time.RFC3339, notBefore)
+		if err != nil {
+			return nil, trace.BadParameter("invalid not-before time %q, expected RFC3339 format, e.g. 2019-10-19T02:00:00Z", notBefore)
+		}
+		schedule.NotBefore = schedule.NotBefore.UTC()
+	}
+	if window != "" {
+		schedule.Window = &storage.MaintenanceWindow{
+			Cron:     window,
+			Duration: windowDuration,
+		}
+	}
+	if err := schedule.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &schedule, nil
+}
+
+func formatOperationSchedule(schedule storage.OperationSchedule) string {
+	var result string
+	if !schedule.NotBefore.IsZero() {
+		result = fmt.Sprintf("after %v", schedule.NotBefore.Format(constants.HumanDateFormatSeconds))
+	}
+	if schedule.Window != nil {
+		if result != "" {
+			result += ", "
+		}
+		result += fmt.Sprintf("window %q for %v", schedule.Window.Cron, schedule.Window.Duration)
+	}
+	return result
+}
+
+func formatScheduledOperationResult(op storage.ScheduledOperation) string {
+	switch {
+	case op.OperationID != "":
+		return op.OperationID
+	case op.Error != "":
+		return op.Error
+	}
+	return "-"
+}
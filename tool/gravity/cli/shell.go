@@ -0,0 +1,174 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"os/user"
+	"syscall"
+
+	"github.com/gravitational/gravity/lib/audit"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/pack"
+
+	"github.com/docker/docker/pkg/term"
+	"github.com/gravitational/teleport/lib/session"
+	"github.com/gravitational/trace"
+	"github.com/kr/pty"
+)
+
+// recordedPlanetShell starts an interactive shell in the planet container
+// and records the session.
+// The recording is uploaded to the cluster once the shell exits and can be
+// played back from the audit log of the web UI
+func recordedPlanetShell(env *localenv.LocalEnvironment) error {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return trace.BadParameter("recorded shell requires a terminal, " +
+			"use gravity exec to run non-interactive commands")
+	}
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	clusterUser, err := operator.GetCurrentUser()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	localUser, err := user.Current()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	planetPackage, planetConfigPackage, err := pack.FindAnyRuntimePackageWithConfig(env.Packages)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cmd, err := packageCommand(env, "exec", *planetPackage, planetConfigPackage,
+		[]string{"-t", "-i", "/bin/bash"})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	dir, err := ioutil.TempDir("", "session")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+	recorder, err := audit.NewSessionRecorder(context.TODO(), audit.SessionConfig{
+		Operator:   operator,
+		ClusterKey: cluster.Key(),
+		DataDir:    dir,
+		ServerID:   hostname,
+		User:       clusterUser.GetName(),
+		Login:      localUser.Username,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	size, err := term.GetWinsize(os.Stdin.Fd())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	params, err := terminalParams(size)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := recorder.Start(*params); err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Session %v is being recorded.\n", recorder.ID())
+	err = runInTerminal(cmd, size, recorder)
+	if errStop := recorder.Stop(); errStop != nil {
+		return trace.Wrap(errStop, "failed to upload recording of session %v", recorder.ID())
+	}
+	env.Printf("Recording of session %v has been uploaded.\n", recorder.ID())
+	if _, ok := trace.Unwrap(err).(*exec.ExitError); ok {
+		// the exit status of an interactive shell is the one
+		// of the last command executed and is not an error
+		return nil
+	}
+	return trace.Wrap(err)
+}
+
+// runInTerminal runs the command with a pseudo-terminal attached to the
+// terminal of this process and duplicates the command output to the recorder
+func runInTerminal(cmd *exec.Cmd, size *term.Winsize, recorder *audit.SessionRecorder) error {
+	tty, err := pty.Start(cmd)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer tty.Close()
+	if err := term.SetWinsize(tty.Fd(), size); err != nil {
+		return trace.Wrap(err)
+	}
+	state, err := term.SetRawTerminal(os.Stdin.Fd())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer term.RestoreTerminal(os.Stdin.Fd(), state)
+
+	signalC := make(chan os.Signal, 1)
+	signal.Notify(signalC, syscall.SIGWINCH)
+	defer signal.Stop(signalC)
+	go func() {
+		for range signalC {
+			resizeTerminal(tty, recorder)
+		}
+	}()
+
+	go io.Copy(tty, os.Stdin)
+	// reading from the pseudo-terminal fails once the command has exited
+	io.Copy(io.MultiWriter(os.Stdout, recorder), tty)
+	return trace.Wrap(cmd.Wait())
+}
+
+// resizeTerminal propagates the size of the terminal of this process
+// to the provided pseudo-terminal and records the change
+func resizeTerminal(tty *os.File, recorder *audit.SessionRecorder) {
+	size, err := term.GetWinsize(os.Stdin.Fd())
+	if err != nil {
+		log.Warnf("Failed to query terminal size: %v.", err)
+		return
+	}
+	if err := term.SetWinsize(tty.Fd(), size); err != nil {
+		log.Warnf("Failed to resize terminal: %v.", err)
+		return
+	}
+	params, err := terminalParams(size)
+	if err != nil {
+		log.Warnf("Invalid terminal size: %v.", err)
+		return
+	}
+	if err := recorder.Resize(*params); err != nil {
+		log.Warnf("Failed to record terminal resize: %v.", trace.DebugReport(err))
+	}
+}
+
+func terminalParams(size *term.Winsize) (*session.TerminalParams, error) {
+	return session.NewTerminalParamsFromUint32(uint32(size.Width), uint32(size.Height))
+}
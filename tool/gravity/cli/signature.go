@@ -0,0 +1,139 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/signature"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/tool/common"
+
+	"github.com/gravitational/trace"
+)
+
+// verifyImageSignature verifies the signature of the image unpacked in dir
+// with the keys trusted in the local environment and the public keys
+// at the specified paths.
+//
+// Unsigned images are only accepted if require is false
+func verifyImageSignature(env *localenv.LocalEnvironment, dir string, keyPaths []string, require bool) error {
+	keys, err := env.Backend.GetTrustedKeys()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, path := range keyPaths {
+		publicKey, err := ioutil.ReadFile(path)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		keys = append(keys, storage.TrustedKey{Name: path, PublicKey: publicKey})
+	}
+	key, err := signature.Verify(dir, keys)
+	if err != nil {
+		if !trace.IsNotFound(err) {
+			return trace.Wrap(err, "failed to verify image signature")
+		}
+		if require {
+			return trace.AccessDenied("image is not signed")
+		}
+		log.Warnf("Image in %v is not signed.", dir)
+		return nil
+	}
+	env.PrintStep("Verified image signature with trusted key %v", key.Name)
+	return nil
+}
+
+// addTrustedKey adds the public key at the specified path to the keys
+// trusted to sign images
+func addTrustedKey(env *localenv.LocalEnvironment, name, path string) error {
+	publicKey, err := ioutil.ReadFile(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	keyID, err := signature.KeyID(publicKey)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = env.Backend.UpsertTrustedKey(storage.TrustedKey{
+		Name:      name,
+		PublicKey: publicKey,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Trusted key %v (%v) added\n", name, keyID)
+	return nil
+}
+
+// listTrustedKeys displays the keys trusted to sign images
+func listTrustedKeys(env *localenv.LocalEnvironment, format constants.Format) error {
+	keys, err := env.Backend.GetTrustedKeys()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	infos := make([]trustedKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		keyID, err := signature.KeyID(key.PublicKey)
+		if err != nil {
+			keyID = "<invalid>"
+		}
+		infos = append(infos, trustedKeyInfo{
+			Name:    key.Name,
+			KeyID:   keyID,
+			Created: key.Created,
+		})
+	}
+	if format != constants.EncodingText {
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, infos))
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(w, "Name\tKey ID\tAdded\n")
+	fmt.Fprintf(w, "----\t------\t-----\n")
+	for _, key := range infos {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", key.Name, key.KeyID,
+			key.Created.Format(constants.HumanDateFormatSeconds))
+	}
+	return trace.Wrap(w.Flush())
+}
+
+// trustedKeyInfo describes a trusted key in machine-readable output
+type trustedKeyInfo struct {
+	// Name is the key name
+	Name string `json:"name"`
+	// KeyID is the key fingerprint
+	KeyID string `json:"key_id"`
+	// Created is when the key was added
+	Created time.Time `json:"created"`
+}
+
+// removeTrustedKey removes the key with the specified name from the keys
+// trusted to sign images
+func removeTrustedKey(env *localenv.LocalEnvironment, name string) error {
+	if err := env.Backend.DeleteTrustedKey(name); err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Trusted key %v removed\n", name)
+	return nil
+}
@@ -19,7 +19,6 @@ package cli
 import (
 	"context"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -34,8 +33,9 @@ import (
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/process"
 	gcfg "github.com/gravitational/gravity/lib/processconfig"
+	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/tool/common"
 
-	yaml "github.com/ghodss/yaml"
 	"github.com/gravitational/trace"
 	"github.com/olekukonko/tablewriter"
 )
@@ -122,7 +122,7 @@ func listSites(env *localenv.LocalEnvironment, opsCenterURL string) error {
 	return nil
 }
 
-func getClusterReport(env *localenv.LocalEnvironment, targetFile string) error {
+func getClusterReport(env *localenv.LocalEnvironment, targetFile string, maxFileSize, maxSize utils.Capacity, operations int) error {
 	f, err := os.Create(targetFile)
 	if err != nil {
 		return trace.Wrap(err)
@@ -139,9 +139,11 @@ func getClusterReport(env *localenv.LocalEnvironment, targetFile string) error {
 		return trace.Wrap(err)
 	}
 
-	report, err := operator.GetSiteReport(ops.SiteKey{
-		AccountID:  site.AccountID,
-		SiteDomain: site.Domain,
+	report, err := operator.GetSiteReport(ops.GetClusterReportRequest{
+		SiteKey:     site.Key(),
+		MaxFileSize: int64(maxFileSize.Bytes()),
+		MaxSize:     int64(maxSize.Bytes()),
+		Operations:  operations,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -200,23 +202,11 @@ func printLocalClusterInfo(env *localenv.LocalEnvironment, outFormat constants.F
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	switch outFormat {
-	case constants.EncodingText, constants.EncodingYAML:
-		bytes, err := yaml.Marshal(info)
-		if err != nil {
-			return trace.Wrap(err)
-		}
-		fmt.Println(string(bytes))
-	case constants.EncodingJSON:
-		bytes, err := json.Marshal(info)
-		if err != nil {
-			return trace.Wrap(err)
-		}
-		fmt.Println(string(bytes))
-	default:
-		return trace.BadParameter("unknown output format: %s", outFormat)
+	// The cluster info is structured so it is displayed as yaml in text mode
+	if outFormat == constants.EncodingText {
+		outFormat = constants.EncodingYAML
 	}
-	return nil
+	return trace.Wrap(common.PrintStructured(os.Stdout, outFormat, info))
 }
 
 func completeInstallerStep(env *localenv.LocalEnvironment, supportAction string) error {
@@ -18,7 +18,6 @@ package cli
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -32,6 +31,9 @@ import (
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/schema"
 	statusapi "github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+	"github.com/gravitational/gravity/tool/common"
 
 	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
@@ -55,7 +57,7 @@ func status(env *localenv.LocalEnvironment, printOptions printOptions) error {
 
 	status, err := statusOnce(context.TODO(), operator, printOptions.operationID)
 	if err == nil {
-		err = printStatus(operator, clusterStatus{*status, nil}, printOptions)
+		err = printStatus(operator, newClusterStatus(*status, nil), printOptions)
 		return trace.Wrap(err)
 	} else {
 		log.Errorf(trace.DebugReport(err))
@@ -93,8 +95,7 @@ func status(env *localenv.LocalEnvironment, printOptions printOptions) error {
 		failed = checks.RunBasicChecks(ctx, nil)
 	}
 
-	clusterStatus := clusterStatus{*status, failed}
-	return trace.Wrap(printStatus(operator, clusterStatus, printOptions))
+	return trace.Wrap(printStatus(operator, newClusterStatus(*status, failed), printOptions))
 }
 
 func tailStatus(env *localenv.LocalEnvironment, operationID string) error {
@@ -103,16 +104,62 @@ func tailStatus(env *localenv.LocalEnvironment, operationID string) error {
 		return trace.Wrap(err)
 	}
 
+	opKey, err := activeOperationKey(operator, operationID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if opKey == nil {
+		return nil
+	}
+
+	return trace.Wrap(tailOperationLogs(operator, *opKey))
+}
+
+// followStatus prints progress of the currently running operation
+// (or the operation with the specified ID) as it changes until it completes
+func followStatus(env *localenv.LocalEnvironment, operationID string) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	opKey, err := activeOperationKey(operator, operationID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if opKey == nil {
+		return nil
+	}
+
+	var last ops.ProgressEntry
+	err = ops.SendOperationProgress(context.TODO(), *opKey, operator, func(progress ops.ProgressEntry) error {
+		fmt.Printf("%v\t%3v%%\t%v\n", progress.Created.Format(constants.HumanDateFormatSeconds),
+			progress.Completion, progress.Message)
+		last = progress
+		return nil
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if last.State == ops.ProgressStateFailed {
+		return trace.Errorf("%v", last.Message)
+	}
+	return nil
+}
+
+// activeOperationKey returns the key of the operation with the specified ID
+// or the currently active operation if the ID is empty
+func activeOperationKey(operator ops.Operator, operationID string) (*ops.SiteOperationKey, error) {
 	status, err := statusOnce(context.TODO(), operator, operationID)
 	if err != nil {
 		log.Warnf("Failed to determine cluster status: %v.", trace.DebugReport(err))
 		if status == nil || status.Cluster == nil {
-			return trace.BadParameter("unknown cluster state")
+			return nil, trace.BadParameter("unknown cluster state")
 		}
 	}
 
 	if status.Cluster.Operation == nil && len(status.Cluster.ActiveOperations) == 0 {
-		return trace.NotFound("there is no operation in progress")
+		return nil, trace.NotFound("there is no operation in progress")
 	}
 
 	var opKey ops.SiteOperationKey
@@ -121,15 +168,14 @@ func tailStatus(env *localenv.LocalEnvironment, operationID string) error {
 		opKey = status.Operation.Key()
 	case len(status.Cluster.ActiveOperations) != 0:
 		if len(status.Cluster.ActiveOperations) > 1 {
-			return trace.BadParameter("multiple active operations in progress. " +
+			return nil, trace.BadParameter("multiple active operations in progress. " +
 				"Please specify the operation with --operation-id")
 		}
 		opKey = status.Cluster.ActiveOperations[0].Key()
 	default:
-		return nil
+		return nil, nil
 	}
-
-	return trace.Wrap(tailOperationLogs(operator, opKey))
+	return &opKey, nil
 }
 
 // statusPeriodic continuously polls for site status with the provided interval and prints it
@@ -148,7 +194,7 @@ func statusPeriodic(env *localenv.LocalEnvironment, printOptions printOptions, s
 			if err != nil {
 				return trace.Wrap(err)
 			}
-			printStatus(operator, clusterStatus{*status, nil}, printOptions)
+			printStatus(operator, newClusterStatus(*status, nil), printOptions)
 		}
 	}
 }
@@ -194,13 +240,11 @@ func printStatus(operator ops.Operator, status clusterStatus, printOptions print
 }
 
 func printStatusWithOptions(status clusterStatus, printOptions printOptions) error {
-	switch printOptions.format {
-	case constants.EncodingJSON:
-		return trace.Wrap(printStatusJSON(status))
-	default:
-		printStatusText(status)
+	if printOptions.format == constants.EncodingText {
+		printStatusText(status, printOptions.verbose)
+		return nil
 	}
-	return nil
+	return trace.Wrap(common.PrintStructured(os.Stdout, printOptions.format, status))
 }
 
 // tailOperationLogs follows the logs of the currently ongoing operation until the operation completes
@@ -245,18 +289,7 @@ func tailOperationLogs(operator ops.Operator, operationKey ops.SiteOperationKey)
 	}
 }
 
-func printStatusJSON(status clusterStatus) error {
-	log.Debugf("status: %#v", status)
-	bytes, err := json.Marshal(&status)
-	if err != nil {
-		return trace.Wrap(err, "failed to marshal")
-	}
-
-	fmt.Fprint(os.Stdout, string(bytes))
-	return nil
-}
-
-func printStatusText(cluster clusterStatus) {
+func printStatusText(cluster clusterStatus, verbose bool) {
 	w := new(tabwriter.Writer)
 
 	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
@@ -276,7 +309,11 @@ func printStatusText(cluster clusterStatus) {
 			domain = cluster.Cluster.Domain
 		}
 		fmt.Fprintf(w, "Cluster nodes:\t%v\n", unknownFallback(domain))
-		printAgentStatus(*cluster.Agent, w)
+		printAgentStatus(*cluster.Agent, verbose, w)
+	}
+
+	if len(cluster.Etcd) != 0 {
+		printEtcdMembers(cluster.Etcd, w)
 	}
 
 	w.Flush()
@@ -333,7 +370,7 @@ func printOperation(operation *statusapi.ClusterOperation, w io.Writer) {
 	}
 }
 
-func printAgentStatus(status statusapi.Agent, w io.Writer) {
+func printAgentStatus(status statusapi.Agent, verbose bool, w io.Writer) {
 	if len(status.Nodes) == 0 {
 		fmt.Fprintln(w, color.YellowString("Failed to collect system status from nodes"))
 	}
@@ -348,18 +385,18 @@ func printAgentStatus(status statusapi.Agent, w io.Writer) {
 	if len(masters) > 0 {
 		fmt.Fprintln(w, "    Masters:")
 		for _, node := range masters {
-			printNodeStatus(node, w)
+			printNodeStatus(node, verbose, w)
 		}
 	}
 	if len(nodes) > 0 {
 		fmt.Fprintln(w, "    Nodes:")
 		for _, node := range nodes {
-			printNodeStatus(node, w)
+			printNodeStatus(node, verbose, w)
 		}
 	}
 }
 
-func printNodeStatus(node statusapi.ClusterServer, w io.Writer) {
+func printNodeStatus(node statusapi.ClusterServer, verbose bool, w io.Writer) {
 	description := node.AdvertiseIP
 	if node.Profile != "" {
 		description = fmt.Sprintf("%v, %v", description, node.Profile)
@@ -372,12 +409,38 @@ func printNodeStatus(node statusapi.ClusterServer, w io.Writer) {
 		fmt.Fprintf(w, "            Status:\t%v\n", color.GreenString("healthy"))
 	case statusapi.NodeDegraded:
 		fmt.Fprintf(w, "            Status:\t%v\n", color.RedString("degraded"))
+		if verbose {
+			printProbeFailures(node.Probes, w)
+			return
+		}
 		for _, probe := range node.FailedProbes {
 			fmt.Fprintf(w, "            [%v]\t%v\n", constants.FailureMark, color.New(color.FgRed).SprintFunc()(probe))
 		}
 	}
 }
 
+// printProbeFailures displays the failed probes along with their reason codes,
+// remediation hints and the commands to display the relevant logs
+func printProbeFailures(probes []storage.ProbeFailure, w io.Writer) {
+	for _, probe := range probes {
+		description := probe.Checker
+		if probe.Detail != "" {
+			description = fmt.Sprintf("%v (%v)", description, probe.Detail)
+		}
+		fmt.Fprintf(w, "            [%v]\t%v: %v\n", constants.FailureMark,
+			color.RedString(probe.Code), color.RedString(description))
+		if probe.Error != "" {
+			fmt.Fprintf(w, "                Error:\t%v\n", probe.Error)
+		}
+		if probe.Hint != "" {
+			fmt.Fprintf(w, "                Hint:\t%v\n", probe.Hint)
+		}
+		if probe.Logs != "" {
+			fmt.Fprintf(w, "                Logs:\t%v\n", probe.Logs)
+		}
+	}
+}
+
 func unknownFallback(text string) string {
 	if text != "" {
 		return text
@@ -393,6 +456,8 @@ type printOptions struct {
 	quiet bool
 	// operationID limits output to that of a particular operation
 	operationID string
+	// verbose means display the reasons of failed probes
+	verbose bool
 	// format specifies the output format (JSON or text)
 	format constants.Format
 }
@@ -402,6 +467,22 @@ type clusterStatus struct {
 	statusapi.Status `json:"cluster"`
 	// FailedLocalProbes lists all failed local checks
 	FailedLocalProbes []*pb.Probe `json:"local_checks,omitempty"`
+	// Etcd lists the status of etcd members
+	Etcd []keyval.EtcdMemberStatus `json:"etcd,omitempty"`
+}
+
+// newClusterStatus returns the cluster status extended with the status
+// of etcd members if etcd is accessible from this node
+func newClusterStatus(status statusapi.Status, failed []*pb.Probe) clusterStatus {
+	members, err := queryEtcdMembers()
+	if err != nil {
+		log.WithError(err).Warn("Failed to query etcd members.")
+	}
+	return clusterStatus{
+		Status:            status,
+		FailedLocalProbes: failed,
+		Etcd:              members,
+	}
 }
 
 // GetApplicationEndpoints returns the list of application endpoints
@@ -0,0 +1,113 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	statusapi "github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/tool/common"
+
+	"github.com/gravitational/trace"
+)
+
+type statusHistoryConfig struct {
+	// since limits the history to the specified duration
+	since time.Duration
+	// all displays all recorded snapshots instead of only transitions
+	all bool
+	// format is the output format
+	format constants.Format
+}
+
+// statusHistory displays the history of cluster status transitions
+func statusHistory(env *localenv.LocalEnvironment, config statusHistoryConfig) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	snapshots, err := operator.GetClusterStatusHistory(context.TODO(), ops.GetClusterStatusHistoryRequest{
+		SiteKey: cluster.Key(),
+		Since:   time.Now().UTC().Add(-config.since),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !config.all {
+		snapshots = statusTransitions(snapshots)
+	}
+	switch config.format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		return trace.Wrap(common.PrintStructured(os.Stdout, config.format, snapshots))
+	case constants.EncodingText:
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "Time\tState\tReason\tDetails\n")
+		fmt.Fprintf(w, "----\t-----\t------\t-------\n")
+		for _, snapshot := range snapshots {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n",
+				snapshot.Time.Format(constants.HumanDateFormatSeconds),
+				snapshot.State, snapshot.Reason, formatStatusDetails(snapshot))
+		}
+		return trace.Wrap(w.Flush())
+	default:
+		return trace.BadParameter("unsupported output format %q", config.format)
+	}
+}
+
+// statusTransitions returns the snapshots at which the cluster status changed
+func statusTransitions(snapshots []storage.StatusSnapshot) (transitions []storage.StatusSnapshot) {
+	for i, snapshot := range snapshots {
+		if i == 0 || snapshot.IsTransition(snapshots[i-1]) {
+			transitions = append(transitions, snapshot)
+		}
+	}
+	return transitions
+}
+
+// formatStatusDetails describes the unhealthy nodes of the snapshot
+// or the status check error if all nodes are healthy
+func formatStatusDetails(snapshot storage.StatusSnapshot) string {
+	var details []string
+	for _, node := range snapshot.Nodes {
+		if node.Status == statusapi.NodeHealthy {
+			continue
+		}
+		detail := fmt.Sprintf("%v (%v) is %v", node.Hostname, node.AdvertiseIP, node.Status)
+		if len(node.FailedProbes) != 0 {
+			detail = fmt.Sprintf("%v: %v", detail, strings.Join(node.FailedProbes, ", "))
+		}
+		details = append(details, detail)
+	}
+	if len(details) == 0 && snapshot.Error != "" {
+		return snapshot.Error
+	}
+	return strings.Join(details, "; ")
+}
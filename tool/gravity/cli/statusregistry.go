@@ -0,0 +1,79 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/tool/common"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gravitational/trace"
+)
+
+// statusRegistry displays the health and the storage usage of the cluster registry
+func statusRegistry(env *localenv.LocalEnvironment, format constants.Format) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	status, err := operator.GetRegistryStatus(context.TODO(), cluster.Key())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, status))
+	case constants.EncodingText:
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		health := "healthy"
+		if !status.Healthy {
+			health = "degraded"
+		}
+		fmt.Fprintf(w, "Status:\t%v\n", health)
+		fmt.Fprintf(w, "Storage driver:\t%v\n", status.StorageDriver)
+		fmt.Fprintf(w, "Repositories:\t%v\n", status.Repositories)
+		fmt.Fprintf(w, "Manifests:\t%v\n", status.Manifests)
+		fmt.Fprintf(w, "Blobs:\t%v\n", status.Blobs)
+		fmt.Fprintf(w, "Disk usage:\t%v\n", humanize.Bytes(uint64(status.SizeBytes)))
+		if len(status.FailedChecks) != 0 {
+			fmt.Fprintf(w, "Failed checks:\n")
+			var names []string
+			for name := range status.FailedChecks {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Fprintf(w, "    %v:\t%v\n", name, status.FailedChecks[name])
+			}
+		}
+		return trace.Wrap(w.Flush())
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+}
@@ -48,12 +48,17 @@ type registryConfig struct {
 
 // imageService returns a new registry client for this config.
 func (c registryConfig) imageService() (docker.ImageService, error) {
-	return docker.NewImageService(docker.RegistryConnectionRequest{
+	return docker.NewImageService(c.connectionRequest())
+}
+
+// connectionRequest returns a registry connection request for this config.
+func (c registryConfig) connectionRequest() docker.RegistryConnectionRequest {
+	return docker.RegistryConnectionRequest{
 		RegistryAddress: c.Registry,
 		CACertPath:      c.CAPath,
 		ClientCertPath:  c.CertPath,
 		ClientKeyPath:   c.KeyPath,
-	})
+	}
 }
 
 func appSync(env *localenv.LocalEnvironment, conf appSyncConfig) error {
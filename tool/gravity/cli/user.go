@@ -23,9 +23,13 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/tool/common"
+
 	"github.com/gravitational/trace"
 )
 
@@ -168,3 +172,114 @@ func inviteUser(env *localenv.LocalEnvironment, username string, roles []string,
 
 	return nil
 }
+
+// createUserToken creates a new long-lived API token for the specified user
+// restricted to the specified permissions
+func createUserToken(env *localenv.LocalEnvironment, opsCenterURL, username string, permissions []string, ttl time.Duration) error {
+	keyPermissions, err := storage.ParseResourcePermissions(permissions)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if ttl < 0 {
+		return trace.BadParameter("token TTL can't be negative")
+	}
+	operator, username, err := userTokenOperator(env, opsCenterURL, username)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req := ops.NewAPIKeyRequest{
+		UserEmail:   username,
+		Permissions: keyPermissions,
+	}
+	if ttl != 0 {
+		req.Expires = time.Now().UTC().Add(ttl)
+	}
+	key, err := operator.CreateAPIKey(req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("API token for %v has been created, expires: %v, permissions: %v.\n",
+		username, formatTokenExpiry(key.Expires), formatTokenPermissions(key.Permissions))
+	env.Println("Pass it to the API in the 'Authorization: Bearer <token>' header.")
+	fmt.Println(key.Token)
+	return nil
+}
+
+// listUserTokens displays API tokens of the specified user
+func listUserTokens(env *localenv.LocalEnvironment, opsCenterURL, username string, format constants.Format) error {
+	operator, username, err := userTokenOperator(env, opsCenterURL, username)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	keys, err := operator.GetAPIKeys(username)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, keys))
+	case constants.EncodingText:
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "Token\tExpires\tPermissions\n")
+		fmt.Fprintf(w, "-----\t-------\t-----------\n")
+		for _, key := range keys {
+			fmt.Fprintf(w, "%v\t%v\t%v\n", key.Token,
+				formatTokenExpiry(key.Expires), formatTokenPermissions(key.Permissions))
+		}
+		return trace.Wrap(w.Flush())
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+}
+
+// removeUserToken revokes the API token of the specified user
+func removeUserToken(env *localenv.LocalEnvironment, opsCenterURL, username, token string) error {
+	operator, username, err := userTokenOperator(env, opsCenterURL, username)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := operator.DeleteAPIKey(username, token); err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("API token %v has been revoked.\n", token)
+	return nil
+}
+
+// userTokenOperator returns the operator service of the specified Ops Center,
+// or of the local cluster if the URL is empty, along with the name of the user
+// to manage tokens for which defaults to the currently logged in user
+func userTokenOperator(env *localenv.LocalEnvironment, opsCenterURL, username string) (ops.Operator, string, error) {
+	var operator ops.Operator
+	var err error
+	if opsCenterURL != "" {
+		operator, err = env.OperatorService(opsCenterURL)
+	} else {
+		operator, err = env.SiteOperator()
+	}
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	if username != "" {
+		return operator, username, nil
+	}
+	user, err := operator.GetCurrentUser()
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	return operator, user.GetName(), nil
+}
+
+func formatTokenExpiry(expires time.Time) string {
+	if expires.IsZero() {
+		return "never"
+	}
+	return expires.Format(constants.HumanDateFormatSeconds)
+}
+
+func formatTokenPermissions(permissions storage.ResourcePermissions) string {
+	if len(permissions) == 0 {
+		return "all"
+	}
+	return permissions.String()
+}
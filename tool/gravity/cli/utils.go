@@ -153,9 +153,14 @@ func (g *Application) isUpdateCommand(cmd string) bool {
 		g.PlanRollbackCmd.FullCommand(),
 		g.PlanResumeCmd.FullCommand(),
 		g.PlanCompleteCmd.FullCommand(),
+		g.PlanPauseCmd.FullCommand(),
+		g.PlanCancelCmd.FullCommand(),
+		g.PlanLogsCmd.FullCommand(),
 		g.UpdatePlanInitCmd.FullCommand(),
 		g.UpdateTriggerCmd.FullCommand(),
-		g.UpgradeCmd.FullCommand():
+		g.UpgradeCmd.FullCommand(),
+		g.CertsRotateCmd.FullCommand(),
+		g.PatchCmd.FullCommand():
 		return true
 	case g.RPCAgentRunCmd.FullCommand():
 		return len(*g.RPCAgentRunCmd.Args) > 0
@@ -176,6 +181,9 @@ func (g *Application) isExpandCommand(cmd string) bool {
 		g.PlanExecuteCmd.FullCommand(),
 		g.PlanRollbackCmd.FullCommand(),
 		g.PlanCompleteCmd.FullCommand(),
+		g.PlanPauseCmd.FullCommand(),
+		g.PlanCancelCmd.FullCommand(),
+		g.PlanLogsCmd.FullCommand(),
 		g.PlanResumeCmd.FullCommand():
 		return true
 	}
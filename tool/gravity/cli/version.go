@@ -17,26 +17,21 @@ limitations under the License.
 package cli
 
 import (
-	"encoding/json"
 	"fmt"
+	"os"
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/modules"
+	"github.com/gravitational/gravity/tool/common"
 
 	"github.com/gravitational/trace"
 )
 
 func printVersion(format constants.Format) error {
 	ver := modules.Get().Version()
-	switch format {
-	case constants.EncodingText:
+	if format == constants.EncodingText {
 		fmt.Println(ver.String())
-	case constants.EncodingJSON:
-		bytes, err := json.Marshal(ver)
-		if err != nil {
-			return trace.Wrap(err)
-		}
-		fmt.Printf(string(bytes))
+		return nil
 	}
-	return nil
+	return trace.Wrap(common.PrintStructured(os.Stdout, format, ver))
 }
@@ -36,7 +36,7 @@ func main() {
 	if err := run(app); err != nil {
 		log.Error(trace.DebugReport(err))
 		common.PrintError(err)
-		os.Exit(255)
+		os.Exit(common.ExitCode(err))
 	}
 }
 
@@ -18,10 +18,13 @@ package cli
 
 import (
 	"context"
+	"io/ioutil"
 
 	"github.com/gravitational/gravity/lib/app/service"
 	"github.com/gravitational/gravity/lib/builder"
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/utils"
+	helmutils "github.com/gravitational/gravity/lib/utils/helm"
 
 	"github.com/gravitational/trace"
 )
@@ -44,10 +47,53 @@ type BuildParameters struct {
 	Silent bool
 	// Insecure turns on insecure verify mode
 	Insecure bool
+	// CacheSize is the size limit of the image cache
+	CacheSize utils.Capacity
+	// NoCache disables the image cache
+	NoCache bool
+	// Scan enables scanning of application images for vulnerabilities
+	Scan bool
+	// ScannerPath is the path to the image scanner binary
+	ScannerPath string
+	// ScanReport is the path of the file to write the vulnerability report to
+	ScanReport string
+	// ScanReportFormat is the format of the vulnerability report
+	ScanReportFormat constants.Format
+	// ScanFailOn is the vulnerability severity that fails the build
+	ScanFailOn string
+	// SigningKeyPath is the path to the private key to sign the installer with
+	SigningKeyPath string
+	// DeltaFrom is the application version to generate a delta installer against
+	DeltaFrom string
+	// UpgradeVia lists the intermediate runtime versions to embed into the installer
+	UpgradeVia []string
+	// Offline resolves all dependencies from the state directory without network access
+	Offline bool
+	// ValueFiles lists the files with values to render the manifest template with
+	ValueFiles []string
+	// Values lists the values to render the manifest template with in key=value format
+	Values []string
+	// FIPS builds the cluster image with the base image built with FIPS-validated crypto
+	FIPS bool
 }
 
 // build builds an installer tarball according to the provided parameters
 func build(ctx context.Context, params BuildParameters, req service.VendorRequest) (err error) {
+	scanConfig, err := params.scanConfig()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var signingKey []byte
+	if params.SigningKeyPath != "" {
+		signingKey, err = ioutil.ReadFile(params.SigningKeyPath)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	values, err := helmutils.ParseValues(params.ValueFiles, params.Values)
+	if err != nil {
+		return trace.Wrap(err)
+	}
 	installerBuilder, err := builder.New(builder.Config{
 		Context:          ctx,
 		StateDir:         params.StateDir,
@@ -59,6 +105,16 @@ func build(ctx context.Context, params BuildParameters, req service.VendorReques
 		SkipVersionCheck: params.SkipVersionCheck,
 		VendorReq:        req,
 		Progress:         utils.NewProgress(ctx, "Build", 6, params.Silent),
+		Silent:           params.Silent,
+		ImageCacheSize:   params.CacheSize,
+		NoCache:          params.NoCache,
+		Scan:             scanConfig,
+		SigningKey:       signingKey,
+		DeltaFrom:        params.DeltaFrom,
+		UpgradeVia:       params.UpgradeVia,
+		Offline:          params.Offline,
+		Values:           values,
+		FIPS:             params.FIPS,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -66,3 +122,27 @@ func build(ctx context.Context, params BuildParameters, req service.VendorReques
 	defer installerBuilder.Close()
 	return builder.Build(ctx, installerBuilder)
 }
+
+// scanConfig returns the image scanning configuration, nil if scanning
+// has not been requested
+func (p BuildParameters) scanConfig() (*builder.ScanConfig, error) {
+	if !p.Scan {
+		if p.ScanReport != "" || p.ScanFailOn != "" {
+			return nil, trace.BadParameter("--scan-report and --scan-fail-on require --scan")
+		}
+		return nil, nil
+	}
+	config := &builder.ScanConfig{
+		Scanner:      builder.NewTrivyScanner(p.ScannerPath),
+		ReportPath:   p.ScanReport,
+		ReportFormat: p.ScanReportFormat,
+	}
+	if p.ScanFailOn != "" {
+		severity, err := builder.ParseSeverity(p.ScanFailOn)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		config.FailSeverity = severity
+	}
+	return config, nil
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// clearCache removes the local package cache and the image cache
+// used by tele build
+func clearCache(quiet bool) error {
+	for _, localDir := range []string{defaults.LocalCacheDir, defaults.LocalImageCacheDir} {
+		dir, err := utils.EnsureLocalPath("", localDir, "")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		log.Debugf("Removing %v.", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		if !quiet {
+			fmt.Printf("Removed %v\n", dir)
+		}
+	}
+	return nil
+}
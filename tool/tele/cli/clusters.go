@@ -0,0 +1,116 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/tool/common"
+
+	"github.com/gravitational/trace"
+)
+
+// remoteCluster describes a remote cluster connected to an Ops Center
+type remoteCluster struct {
+	// Name is the cluster name
+	Name string `json:"name"`
+	// State is the last known cluster state
+	State string `json:"state"`
+	// Reason describes the cluster state
+	Reason string `json:"reason,omitempty"`
+	// App is the application installed in the cluster
+	App string `json:"app"`
+	// Labels are the cluster labels
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// listClusters displays the remote clusters connected to the Ops Center
+// that match the selector together with their states
+func listClusters(env localenv.LocalEnvironment, opsURL string, selector map[string]string, format constants.Format) error {
+	operator, err := env.OperatorService(opsURL)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	sites, err := operator.GetSites(defaults.SystemAccountID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	clusters := []remoteCluster{}
+	for _, site := range sites {
+		if site.Local || !ops.IsInstalledState(site.State) {
+			continue
+		}
+		if !utils.MatchesLabels(site.Labels, selector) {
+			continue
+		}
+		clusters = append(clusters, remoteCluster{
+			Name:   site.Domain,
+			State:  site.State,
+			Reason: string(site.Reason),
+			App:    site.App.Package.String(),
+			Labels: site.Labels,
+		})
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Name < clusters[j].Name
+	})
+	switch format {
+	case constants.EncodingJSON, constants.EncodingYAML:
+		return trace.Wrap(common.PrintStructured(os.Stdout, format, clusters))
+	case constants.EncodingText:
+		return trace.Wrap(printClusters(os.Stdout, clusters))
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+}
+
+func printClusters(out io.Writer, clusters []remoteCluster) error {
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(w, "Name\tState\tApplication\tLabels\n")
+	fmt.Fprintf(w, "----\t-----\t-----------\t------\n")
+	for _, cluster := range clusters {
+		state := cluster.State
+		if cluster.Reason != "" {
+			state = fmt.Sprintf("%v (%v)", cluster.State, cluster.Reason)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", cluster.Name, state, cluster.App,
+			formatLabels(cluster.Labels))
+	}
+	return trace.Wrap(w.Flush())
+}
+
+// formatLabels returns the labels as a sorted comma-separated
+// list of key=value pairs
+func formatLabels(labels map[string]string) string {
+	var pairs []string
+	for k, v := range labels {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -19,8 +19,9 @@ package cli
 import (
 	"gopkg.in/alecthomas/kingpin.v2"
 
-	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/tool/common"
 )
 
 // Application represents the command-line "tele" application and contains
@@ -33,6 +34,10 @@ type Application struct {
 	Insecure *bool
 	// StateDir is the local state directory
 	StateDir *string
+	// Quiet suppresses progress and informational output
+	Quiet *bool
+	// Output is the output format of listing and status commands
+	Output *common.OutputFlag
 	// VersionCmd outputs the binary version
 	VersionCmd VersionCmd
 	// BuildCmd builds app installer tarball
@@ -41,19 +46,38 @@ type Application struct {
 	ListCmd ListCmd
 	// PullCmd downloads app installer from Ops Center
 	PullCmd PullCmd
+	// ValidateCmd validates application resources
+	ValidateCmd ValidateCmd
+	// CacheCmd manages the local build cache
+	CacheCmd CacheCmd
+	// CacheClearCmd removes all cached packages and images
+	CacheClearCmd CacheClearCmd
+	// KeygenCmd generates a key pair to sign installers with
+	KeygenCmd KeygenCmd
+	// ExportCmd exports application images from an installer
+	ExportCmd ExportCmd
+	// ClustersCmd lists remote clusters connected to an Ops Center
+	ClustersCmd ClustersCmd
+	// UpgradeCmd upgrades remote clusters connected to an Ops Center
+	UpgradeCmd UpgradeCmd
+	// CancelUpgradeCmd cancels an upgrade of remote clusters
+	CancelUpgradeCmd CancelUpgradeCmd
+	// PublishCmd publishes an application to the Ops Center catalog
+	PublishCmd PublishCmd
+	// UnpublishCmd removes an application from the Ops Center catalog
+	UnpublishCmd UnpublishCmd
 }
 
 // VersionCmd outputs the binary version
 type VersionCmd struct {
 	*kingpin.CmdClause
-	// Output is output format
-	Output *constants.Format
 }
 
 // BuildCmd builds app installer tarball
 type BuildCmd struct {
 	*kingpin.CmdClause
-	// ManifestPath is the path to app manifest file
+	// ManifestPath is the path to app manifest file, Helm chart directory
+	// or packaged Helm chart
 	ManifestPath *string
 	// OutFile is the output tarball file
 	OutFile *string
@@ -77,18 +101,61 @@ type BuildCmd struct {
 	SkipVersionCheck *bool
 	// Parallel defines the number of tasks to execute concurrently
 	Parallel *int
-	// Quiet allows to suppress console output
-	Quiet *bool
+	// CacheSize is the size limit of the image cache
+	CacheSize *utils.Capacity
+	// NoCache disables the image cache
+	NoCache *bool
+	// Scan enables scanning of application images for vulnerabilities
+	Scan *bool
+	// ScannerPath is the path to the image scanner binary
+	ScannerPath *string
+	// ScanReport is the path of the file to write the vulnerability report to
+	ScanReport *string
+	// ScanReportFormat is the format of the vulnerability report
+	ScanReportFormat *string
+	// ScanFailOn is the vulnerability severity that fails the build
+	ScanFailOn *string
+	// SigningKey is the path to the private key to sign the installer with
+	SigningKey *string
+	// DeltaFrom is the application version to generate a delta installer against
+	DeltaFrom *string
+	// UpgradeVia lists the intermediate runtime versions to embed into the installer
+	UpgradeVia *[]string
+	// Offline resolves all dependencies from the state directory without network access
+	Offline *bool
+	// ValueFiles lists the files with values to render the manifest template with
+	ValueFiles *[]string
+	// Values lists the values to render the manifest template with
+	Values *[]string
+	// FIPS builds the cluster image with the base image built with FIPS-validated crypto
+	FIPS *bool
+	// Proxy overrides the HTTP proxy settings of the environment
+	Proxy common.ProxyFlags
 }
 
+// ListCmd lists applications and clusters images published in the hub
 type ListCmd struct {
 	*kingpin.CmdClause
 	// Runtimes shows available runtimes
 	Runtimes *bool
-	// Format is the output format
-	Format *constants.Format
 	// All displays all available versions
 	All *bool
+	// Hub is the address of the hub to query
+	Hub *string
+	// Repository shows only images from the specified repository
+	Repository *string
+	// Name shows only images with names matching the pattern
+	Name *string
+	// Version shows only images with versions matching the constraint
+	Version *string
+	// Selector shows only images with the specified labels
+	Selector *string
+	// OpsCenterURL lists the catalog of the specified Ops Center instead of the hub
+	OpsCenterURL *string
+	// Channel shows only images from the specified catalog release channel
+	Channel *string
+	// Proxy overrides the HTTP proxy settings of the environment
+	Proxy common.ProxyFlags
 }
 
 // PullCmd downloads app installer from Ops Center
@@ -100,6 +167,115 @@ type PullCmd struct {
 	OutFile *string
 	// Force overwrites existing tarball
 	Force *bool
-	// Quiet allows to suppress console output
-	Quiet *bool
+	// Proxy overrides the HTTP proxy settings of the environment
+	Proxy common.ProxyFlags
+}
+
+// ValidateCmd validates application resources
+type ValidateCmd struct {
+	*kingpin.CmdClause
+	// ManifestPath is the path to app manifest file
+	ManifestPath *string
+	// VendorPatterns is file pattern to search for resources
+	VendorPatterns *[]string
+	// VendorIgnorePatterns is file pattern to ignore when searching for resources
+	VendorIgnorePatterns *[]string
+	// LocalRegistries lists registries that do not require image pull secrets
+	LocalRegistries *[]string
+}
+
+// CacheCmd manages the local build cache
+type CacheCmd struct {
+	*kingpin.CmdClause
+}
+
+// CacheClearCmd removes all cached packages and images
+type CacheClearCmd struct {
+	*kingpin.CmdClause
+}
+
+// KeygenCmd generates a key pair to sign installers with
+type KeygenCmd struct {
+	*kingpin.CmdClause
+	// Path is the path of the private key file, the public
+	// key is written next to it with .pub extension
+	Path *string
+	// Overwrite overwrites existing key files
+	Overwrite *bool
+}
+
+// ExportCmd exports application images from an installer
+type ExportCmd struct {
+	*kingpin.CmdClause
+	// Path is the path to the installer tarball
+	Path *string
+	// Format is the export format
+	Format *string
+	// OutputDir is the directory to export the images to
+	OutputDir *string
+}
+
+// ClustersCmd lists remote clusters connected to an Ops Center
+type ClustersCmd struct {
+	*kingpin.CmdClause
+	// Clusters selects the listed clusters by their labels
+	Clusters *string
+	// OpsCenterURL is the address of the Ops Center the clusters are connected to
+	OpsCenterURL *string
+}
+
+// UpgradeCmd upgrades remote clusters connected to an Ops Center
+// to a new application version
+type UpgradeCmd struct {
+	*kingpin.CmdClause
+	// App is the application package to upgrade the clusters to
+	App *string
+	// Clusters selects the upgraded clusters by their labels
+	Clusters *string
+	// Canaries is the number of clusters upgraded one at a time first
+	Canaries *int
+	// Concurrency is the maximum number of clusters upgraded at the same time
+	Concurrency *int
+	// MaxFailures is the number of failed cluster upgrades that halts the upgrade
+	MaxFailures *int
+	// OpsCenterURL is the address of the Ops Center the clusters are connected to
+	OpsCenterURL *string
+}
+
+// CancelUpgradeCmd cancels an upgrade of remote clusters
+// connected to an Ops Center
+type CancelUpgradeCmd struct {
+	*kingpin.CmdClause
+	// ID is the ID of the upgrade to cancel
+	ID *string
+	// OpsCenterURL is the address of the Ops Center the clusters are connected to
+	OpsCenterURL *string
+}
+
+// PublishCmd publishes an application to the Ops Center catalog
+type PublishCmd struct {
+	*kingpin.CmdClause
+	// App is the application to publish
+	App *string
+	// Channel is the release channel to publish to
+	Channel *string
+	// Icon is the path to the application icon or its URL
+	Icon *string
+	// Readme is the path to the application readme
+	Readme *string
+	// UpgradeFrom is the minimum version that can upgrade to this version
+	UpgradeFrom *string
+	// ReleaseNotes describes the changes in this version
+	ReleaseNotes *string
+	// OpsCenterURL is the address of the Ops Center to publish to
+	OpsCenterURL *string
+}
+
+// UnpublishCmd removes an application from the Ops Center catalog
+type UnpublishCmd struct {
+	*kingpin.CmdClause
+	// App is the application to remove from the catalog
+	App *string
+	// OpsCenterURL is the address of the Ops Center to unpublish from
+	OpsCenterURL *string
 }
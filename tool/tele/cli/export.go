@@ -0,0 +1,92 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/install"
+	"github.com/gravitational/gravity/lib/localenv"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+)
+
+// export writes the images of the application in the installer tarball
+// at path and its dependencies to outputDir in the specified format
+func export(ctx context.Context, path, format, outputDir string, quiet bool) error {
+	if format != constants.ImageFormatOCI {
+		return trace.BadParameter("unsupported export format %q, only %q is supported",
+			format, constants.ImageFormatOCI)
+	}
+	if outputDir == "" {
+		return trace.BadParameter("specify the directory to export the images to with --output-dir")
+	}
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := unpackInstaller(path, dir); err != nil {
+		return trace.Wrap(err)
+	}
+	env, err := localenv.New(dir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer env.Close()
+	apps, err := env.AppServiceLocal(localenv.AppConfig{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	locator, err := install.GetAppPackage(apps)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	images, err := service.ExportOCILayout(ctx, env.Packages, *locator, outputDir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !quiet {
+		for _, image := range images {
+			fmt.Printf("Exported %v\n", image)
+		}
+	}
+	fmt.Printf("Images of %v exported to %v\n", locator, outputDir)
+	return nil
+}
+
+// unpackInstaller extracts the installer tarball at path into dir
+func unpackInstaller(path, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	stream, err := dockerarchive.DecompressStream(f)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer stream.Close()
+	return trace.Wrap(archive.Extract(stream, dir))
+}
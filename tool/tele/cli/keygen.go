@@ -0,0 +1,60 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/signature"
+
+	"github.com/gravitational/trace"
+)
+
+// generateSigningKey generates a key pair to sign installers with and
+// writes the private key to path and the public key to path.pub
+func generateSigningKey(path string, overwrite bool) error {
+	publicPath := path + ".pub"
+	if !overwrite {
+		for _, path := range []string{path, publicPath} {
+			if _, err := os.Stat(path); err == nil {
+				return trace.AlreadyExists("%v already exists, please remove it "+
+					"first or provide -f (--overwrite) flag to overwrite it", path)
+			}
+		}
+	}
+	privateKey, publicKey, err := signature.GenerateKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := ioutil.WriteFile(path, privateKey, defaults.PrivateFileMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if err := ioutil.WriteFile(publicPath, publicKey, defaults.SharedReadMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	keyID, err := signature.KeyID(publicKey)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	fmt.Printf("Generated signing key %v\n", keyID)
+	fmt.Printf("Private key: %v\n", path)
+	fmt.Printf("Public key:  %v\n", publicPath)
+	return nil
+}
@@ -17,21 +17,74 @@ limitations under the License.
 package cli
 
 import (
+	"net/url"
+	"strings"
+
 	"github.com/gravitational/gravity/lib/catalog"
-	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/hub"
 	"github.com/gravitational/gravity/lib/localenv"
 
 	"github.com/gravitational/trace"
 )
 
-func list(env localenv.LocalEnvironment, all bool, format constants.Format) error {
-	lister, err := catalog.NewLister()
+func list(env localenv.LocalEnvironment, hubAddr, opsURL, channel string, req catalog.ListRequest) error {
+	if err := req.Filter.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	lister, err := newLister(env, hubAddr, opsURL, channel)
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	err = catalog.List(lister, all, format)
+	err = catalog.List(lister, req)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	return nil
 }
+
+// newLister returns the lister for the application catalog of the Ops Center
+// if one has been specified, and for the hub otherwise
+func newLister(env localenv.LocalEnvironment, hubAddr, opsURL, channel string) (catalog.Lister, error) {
+	if opsURL == "" {
+		if channel != "" {
+			return nil, trace.BadParameter("--channel can only be used with --ops-url")
+		}
+		config, err := parseHubAddress(hubAddr)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return catalog.NewListerFor(*config)
+	}
+	if hubAddr != "" {
+		return nil, trace.BadParameter("--hub and --ops-url are mutually exclusive")
+	}
+	apps, err := env.AppService(opsURL, localenv.AppConfig{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return catalog.NewCatalogLister(apps, channel), nil
+}
+
+// parseHubAddress returns the hub configuration for the address in
+// the s3://<bucket>/<prefix> format.
+//
+// Empty address selects the default hub
+func parseHubAddress(addr string) (*hub.Config, error) {
+	if addr == "" {
+		return &hub.Config{}, nil
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "s3://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return nil, trace.BadParameter("unsupported hub address %q, expected s3://<bucket>/<prefix>", addr)
+	}
+	return &hub.Config{
+		Bucket: u.Host,
+		Prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
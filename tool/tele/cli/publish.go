@@ -0,0 +1,144 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// publishRequest describes the application to publish to the catalog
+type publishRequest struct {
+	// App is the application to publish
+	App string
+	// Channel is the release channel to publish to
+	Channel string
+	// IconPath is the path to the application icon file or its URL
+	IconPath string
+	// ReadmePath is the path to the application readme file
+	ReadmePath string
+	// Upgrade is the application upgrade metadata
+	Upgrade storage.CatalogUpgrade
+}
+
+// publish publishes the application pushed to the Ops Center
+// to the Ops Center application catalog
+func publish(env localenv.LocalEnvironment, opsURL string, req publishRequest) error {
+	locator, err := catalogLocator(req.App)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	icon, err := readIcon(req.IconPath)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var readme string
+	if req.ReadmePath != "" {
+		data, err := ioutil.ReadFile(req.ReadmePath)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		readme = string(data)
+	}
+	apps, err := catalogService(env, opsURL)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	entry, err := apps.PublishApp(app.PublishAppRequest{
+		Package: *locator,
+		Channel: req.Channel,
+		Icon:    icon,
+		Readme:  readme,
+		Upgrade: req.Upgrade,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Published %v to the %v channel.\n", entry.Locator(), entry.Channel)
+	return nil
+}
+
+// unpublish removes the application from the Ops Center application catalog
+func unpublish(env localenv.LocalEnvironment, opsURL, appName string) error {
+	locator, err := catalogLocator(appName)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	apps, err := catalogService(env, opsURL)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := apps.UnpublishApp(*locator); err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Removed %v from the catalog.\n", locator)
+	return nil
+}
+
+// catalogService returns the application service of the specified
+// or the current Ops Center
+func catalogService(env localenv.LocalEnvironment, opsURL string) (app.Applications, error) {
+	opsURL, err := env.SelectOpsCenter(opsURL)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	apps, err := env.AppService(opsURL, localenv.AppConfig{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return apps, nil
+}
+
+func catalogLocator(appName string) (*loc.Locator, error) {
+	locator, err := loc.MakeLocator(appName)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if locator.Version == loc.LatestVersion || locator.Version == loc.StableVersion {
+		return nil, trace.BadParameter("please specify the application version, e.g. %v:1.2.3",
+			locator.Name)
+	}
+	return locator, nil
+}
+
+// readIcon returns the icon at the specified path as a data URI.
+// URLs are returned as-is
+func readIcon(path string) (string, error) {
+	if path == "" || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", trace.BadParameter("%v is not an image: %v", path, contentType)
+	}
+	return fmt.Sprintf("data:%v;base64,%v", contentType,
+		base64.StdEncoding.EncodeToString(data)), nil
+}
@@ -21,7 +21,9 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/gravitational/gravity/lib/builder"
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/hub"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/localenv"
@@ -30,7 +32,11 @@ import (
 	"github.com/gravitational/trace"
 )
 
-func pull(env localenv.LocalEnvironment, app, outFile string, force, quiet bool) error {
+// pull downloads the specified application installer from the hub.
+//
+// If seedCache is set, the application and its dependencies are also imported
+// into the package cache in the state directory for offline builds
+func pull(env localenv.LocalEnvironment, app, outFile string, force, quiet, seedCache bool) error {
 	locator, err := loc.MakeLocator(app)
 	if err != nil {
 		return trace.Wrap(err)
@@ -67,9 +73,12 @@ func pull(env localenv.LocalEnvironment, app, outFile string, force, quiet bool)
 			"flag to overwrite it", outFile)
 	}
 
-	f, err := os.Create(outFile)
+	// Download into a partial file first so an interrupted download
+	// can be resumed by running the same command again
+	partialFile := outFile + defaults.PartialFileSuffix
+	f, err := os.OpenFile(partialFile, os.O_CREATE|os.O_RDWR, defaults.SharedReadWriteMask)
 	if err != nil {
-		return trace.Wrap(err)
+		return trace.ConvertSystemError(err)
 	}
 	defer f.Close()
 
@@ -78,8 +87,36 @@ func pull(env localenv.LocalEnvironment, app, outFile string, force, quiet bool)
 
 	err = hub.Download(f, *locator, progress)
 	if err != nil {
+		if trace.IsCompareFailed(err) {
+			// The partial file is corrupted, start from scratch next time
+			os.Remove(partialFile)
+		}
 		return trace.Wrap(err)
 	}
 
+	if err := os.Rename(partialFile, outFile); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if seedCache {
+		return trace.Wrap(seedPackageCache(env, outFile, quiet))
+	}
+	return nil
+}
+
+// seedPackageCache imports the application from the specified installer
+// tarball into the package cache in the state directory
+func seedPackageCache(env localenv.LocalEnvironment, path string, quiet bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	locator, err := builder.SeedPackageCache(&env, f, log)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !quiet {
+		env.Printf("Imported %v into the package cache in %v.\n", locator, env.StateDir)
+	}
 	return nil
 }
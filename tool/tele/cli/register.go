@@ -22,6 +22,7 @@ import (
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/tool/common"
 
 	"gopkg.in/alecthomas/kingpin.v2"
@@ -35,14 +36,15 @@ func RegisterCommands(app *kingpin.Application) Application {
 
 	tele.Debug = app.Flag("debug", "Enable debug mode").Bool()
 	tele.Insecure = app.Flag("insecure", "Skip TLS verification when making HTTP requests").Default("false").Bool()
-	tele.StateDir = app.Flag("state-dir", "Directory for temporary local state").Hidden().String()
+	tele.Output = common.Output(app)
+	tele.StateDir = app.Flag("state-dir", "Directory with the local package cache used instead of the default one, populated by 'tele pull' and used by 'tele build --offline'").String()
+	tele.Quiet = app.Flag("quiet", "Suppress progress indicators and informational output, only print results and errors").Short('q').Bool()
 
 	tele.VersionCmd.CmdClause = app.Command("version", "Print version and exit")
-	tele.VersionCmd.Output = common.Format(tele.VersionCmd.Flag("output", "Output format, text or json").Short('o').Default(string(constants.EncodingText)))
 
 	tele.BuildCmd.CmdClause = app.Command("build", "Build an application installer")
-	tele.BuildCmd.ManifestPath = tele.BuildCmd.Arg("manifest-path", fmt.Sprintf("Path to the application manifest file, must be %q", defaults.ManifestFileName)).Default(defaults.ManifestFileName).String()
-	tele.BuildCmd.OutFile = tele.BuildCmd.Flag("output", "Name of the generated tarball, defaults to <dirname>.tar.gz where <dirname> is the name of the directory where app manifest is located").Short('o').String()
+	tele.BuildCmd.ManifestPath = tele.BuildCmd.Arg("manifest-path", fmt.Sprintf("Path to the application manifest file, must be %q, or to a Helm chart directory or packaged chart (.tgz)", defaults.ManifestFileName)).Default(defaults.ManifestFileName).String()
+	tele.BuildCmd.OutFile = tele.BuildCmd.Flag("output-file", "Name of the generated tarball, defaults to <dirname>.tar.gz where <dirname> is the name of the directory where app manifest is located").Short('O').String()
 	tele.BuildCmd.Overwrite = tele.BuildCmd.Flag("overwrite", "Overwrite the existing tarball").Short('f').Bool()
 	tele.BuildCmd.Repository = tele.BuildCmd.Flag("repository", "Optional address of Ops Center to download dependencies from").Hidden().String()
 	tele.BuildCmd.Name = tele.BuildCmd.Flag("name", "Optional application name, overrides the one specified in the manifest file").Hidden().String()
@@ -53,18 +55,87 @@ func RegisterCommands(app *kingpin.Application) Application {
 	tele.BuildCmd.SetDeps = loc.LocatorSlice(tele.BuildCmd.Flag("set-dep", "Rewrite dependencies section in the application manifest file during vendoring, e.g. 'gravitational.io/site-app:0.0.39' will overwrite dependency to 'gravitational.io/site-app:0.0.39'").Hidden())
 	tele.BuildCmd.SkipVersionCheck = tele.BuildCmd.Flag("skip-version-check", "Skip version compatibility check").Hidden().Bool()
 	tele.BuildCmd.Parallel = tele.BuildCmd.Flag("parallel", "Specifies the number of concurrent tasks. If < 0, the number of tasks is not restricted, if unspecified, then tasks are capped at the number of logical CPU cores").Int()
-	tele.BuildCmd.Quiet = tele.BuildCmd.Flag("quiet", "Suppress any extra output to stdout").Short('q').Bool()
+	tele.BuildCmd.CacheSize = common.Capacity(tele.BuildCmd.Flag("cache-size", "Size limit of the local image cache, least recently used images are removed from the cache when it grows larger").Default(defaults.ImageCacheSize))
+	tele.BuildCmd.NoCache = tele.BuildCmd.Flag("no-cache", "Do not use the local image cache").Bool()
+	tele.BuildCmd.Scan = tele.BuildCmd.Flag("scan", "Scan application container images for vulnerabilities").Bool()
+	tele.BuildCmd.ScannerPath = tele.BuildCmd.Flag("scanner", "Path to the trivy image scanner binary").Default(defaults.ImageScannerPath).String()
+	tele.BuildCmd.ScanReport = tele.BuildCmd.Flag("scan-report", "Write the vulnerability report to the specified file").String()
+	tele.BuildCmd.ScanReportFormat = tele.BuildCmd.Flag("scan-report-format", "Format of the vulnerability report, text or json").Default(string(constants.EncodingText)).Enum(string(constants.EncodingText), string(constants.EncodingJSON))
+	tele.BuildCmd.ScanFailOn = tele.BuildCmd.Flag("scan-fail-on", "Fail the build if vulnerabilities with this or higher severity are found: unknown, low, medium, high or critical").String()
+	tele.BuildCmd.SigningKey = tele.BuildCmd.Flag("sign-key", "Sign the installer with the private key at the specified path, see 'tele keygen'").String()
+	tele.BuildCmd.DeltaFrom = tele.BuildCmd.Flag("delta-from", "Build a delta upgrade installer containing only packages and image layers missing from the specified version of the application").String()
+	tele.BuildCmd.UpgradeVia = tele.BuildCmd.Flag("upgrade-via", "Embed the specified intermediate base image version to upgrade clusters through, can be repeated, e.g. --upgrade-via=5.5.40 --upgrade-via=6.1.20").Strings()
+	tele.BuildCmd.Offline = tele.BuildCmd.Flag("offline", "Resolve all base image and dependency packages from the package cache in --state-dir previously populated with 'tele pull' without accessing the network").Bool()
+	tele.BuildCmd.ValueFiles = tele.BuildCmd.Flag("values", "Render the application manifest as a template with values from the specified YAML file, can be repeated").Strings()
+	tele.BuildCmd.Values = tele.BuildCmd.Flag("set", "Render the application manifest as a template with the specified value, e.g. --set image.tag=1.0.0, can be repeated").Strings()
+	tele.BuildCmd.FIPS = tele.BuildCmd.Flag("fips", "Build the cluster image with the base image variant built with FIPS-validated crypto for installing clusters in FIPS mode").Bool()
+	tele.BuildCmd.Proxy = common.Proxy(tele.BuildCmd.CmdClause)
 
 	tele.ListCmd.CmdClause = app.Command("ls", "Display a list of user applications published in remote Ops Center")
 	tele.ListCmd.Runtimes = tele.ListCmd.Flag("runtimes", "Show only runtimes").Short('r').Hidden().Bool()
-	tele.ListCmd.Format = common.Format(tele.ListCmd.Flag("format", fmt.Sprintf("Output format, one of: %v", constants.OutputFormats)).Default(string(constants.EncodingText)))
 	tele.ListCmd.All = tele.ListCmd.Flag("all", "Display all available versions").Bool()
+	tele.ListCmd.Hub = tele.ListCmd.Flag("hub", "Address of the hub to query, e.g. s3://hub.example.com/gravity/oss, defaults to the public hub").String()
+	tele.ListCmd.Repository = tele.ListCmd.Flag("repository", "Display only images from the specified repository").String()
+	tele.ListCmd.Name = tele.ListCmd.Flag("name", "Display only images with names matching the pattern, e.g. 'kube*'").String()
+	tele.ListCmd.Version = tele.ListCmd.Flag("version", "Display only images with versions matching the constraint, e.g. '>= 5.5, < 6.0'").String()
+	tele.ListCmd.Selector = tele.ListCmd.Flag("selector", "Display only images with the specified labels, e.g. 'channel=stable,tier=web'").Short('l').String()
+	tele.ListCmd.OpsCenterURL = tele.ListCmd.Flag("ops-url", "List applications published to the catalog of the specified Ops Center instead of the hub").String()
+	tele.ListCmd.Channel = tele.ListCmd.Flag("channel", fmt.Sprintf("Display only applications from the specified Ops Center catalog release channel, one of: %v", storage.CatalogChannels)).String()
+	tele.ListCmd.Proxy = common.Proxy(tele.ListCmd.CmdClause)
+	common.DeprecatedFormat(tele.ListCmd.CmdClause, tele.Output)
 
 	tele.PullCmd.CmdClause = app.Command("pull", "Pull an application from remote Ops Center")
 	tele.PullCmd.App = tele.PullCmd.Arg("app", "Name of application to download: <name>:<version> or just <name> to download the latest").Required().String()
-	tele.PullCmd.OutFile = tele.PullCmd.Flag("output", "Name of downloaded tarball, defaults to <name>-<version>.tar").Short('o').String()
+	tele.PullCmd.OutFile = tele.PullCmd.Flag("output-file", "Name of downloaded tarball, defaults to <name>-<version>.tar").Short('O').String()
 	tele.PullCmd.Force = tele.PullCmd.Flag("force", "Overwrite existing tarball").Short('f').Bool()
-	tele.PullCmd.Quiet = tele.PullCmd.Flag("quiet", "Suppress any extra output to stdout").Short('q').Bool()
+	tele.PullCmd.Proxy = common.Proxy(tele.PullCmd.CmdClause)
+
+	tele.ValidateCmd.CmdClause = app.Command("validate", "Validate application resources for common problems")
+	tele.ValidateCmd.ManifestPath = tele.ValidateCmd.Arg("manifest-path", fmt.Sprintf("Path to the application manifest file, must be %q", defaults.ManifestFileName)).Default(defaults.ManifestFileName).String()
+	tele.ValidateCmd.VendorPatterns = tele.ValidateCmd.Flag("glob", "File pattern to search for resource files").Default(defaults.VendorPattern).Hidden().Strings()
+	tele.ValidateCmd.VendorIgnorePatterns = tele.ValidateCmd.Flag("ignore", "Ignore files matching this regular expression when searching for resource files").Hidden().Strings()
+	tele.ValidateCmd.LocalRegistries = tele.ValidateCmd.Flag("local-registry", "Registry address that does not require image pull secrets, defaults to the cluster-local registry").Strings()
+
+	tele.CacheCmd.CmdClause = app.Command("cache", "Manage the local build cache")
+	tele.CacheClearCmd.CmdClause = tele.CacheCmd.Command("clear", "Remove all cached packages and images")
+
+	tele.KeygenCmd.CmdClause = app.Command("keygen", "Generate a key pair to sign installers with")
+	tele.KeygenCmd.Path = tele.KeygenCmd.Arg("path", "Path of the private key file, the public key is written to <path>.pub").Required().String()
+	tele.KeygenCmd.Overwrite = tele.KeygenCmd.Flag("overwrite", "Overwrite existing key files").Short('f').Bool()
+
+	tele.ExportCmd.CmdClause = app.Command("export", "Export application images from an installer")
+	tele.ExportCmd.Path = tele.ExportCmd.Arg("path", "Path to the installer tarball").Required().String()
+	tele.ExportCmd.Format = tele.ExportCmd.Flag("format", fmt.Sprintf("Export format, only %q (OCI image layout) is supported", constants.ImageFormatOCI)).Default(constants.ImageFormatOCI).String()
+	tele.ExportCmd.OutputDir = tele.ExportCmd.Flag("output-dir", "Directory to write the exported images to").Short('O').String()
+
+	tele.ClustersCmd.CmdClause = app.Command("clusters", "Display remote clusters connected to an Ops Center and their states")
+	tele.ClustersCmd.Clusters = tele.ClustersCmd.Flag("clusters", "Display only clusters with the specified labels, e.g. 'env=prod,region=us'").Short('l').String()
+	tele.ClustersCmd.OpsCenterURL = tele.ClustersCmd.Flag("ops-url", "Ops Center the clusters are connected to, defaults to the current Ops Center").String()
+
+	tele.UpgradeCmd.CmdClause = app.Command("upgrade", "Upgrade remote clusters connected to an Ops Center to a new application version")
+	tele.UpgradeCmd.App = tele.UpgradeCmd.Arg("app", "Application to upgrade the clusters to: <name>:<version>").Required().String()
+	tele.UpgradeCmd.Clusters = tele.UpgradeCmd.Flag("clusters", "Upgrade only clusters with the specified labels, e.g. 'env=prod,region=us'").Short('l').String()
+	tele.UpgradeCmd.Canaries = tele.UpgradeCmd.Flag("canaries", "Number of clusters to upgrade one at a time before upgrading the rest").Default("1").Int()
+	tele.UpgradeCmd.Concurrency = tele.UpgradeCmd.Flag("concurrency", "Maximum number of clusters to upgrade at the same time").Default("1").Int()
+	tele.UpgradeCmd.MaxFailures = tele.UpgradeCmd.Flag("max-failures", "Number of failed cluster upgrades that halts the upgrade").Default("1").Int()
+	tele.UpgradeCmd.OpsCenterURL = tele.UpgradeCmd.Flag("ops-url", "Ops Center the clusters are connected to, defaults to the current Ops Center").String()
+
+	tele.CancelUpgradeCmd.CmdClause = app.Command("cancel-upgrade", "Cancel an upgrade of remote clusters connected to an Ops Center")
+	tele.CancelUpgradeCmd.ID = tele.CancelUpgradeCmd.Arg("id", "ID of the upgrade to cancel").Required().String()
+	tele.CancelUpgradeCmd.OpsCenterURL = tele.CancelUpgradeCmd.Flag("ops-url", "Ops Center the clusters are connected to, defaults to the current Ops Center").String()
+
+	tele.PublishCmd.CmdClause = app.Command("publish", "Publish an application pushed to the Ops Center to its application catalog")
+	tele.PublishCmd.App = tele.PublishCmd.Arg("app", "Application to publish: <name>:<version> or <repository>/<name>:<version>").Required().String()
+	tele.PublishCmd.Channel = tele.PublishCmd.Flag("channel", fmt.Sprintf("Release channel to publish to, one of: %v, defaults to beta for pre-release versions and stable otherwise", storage.CatalogChannels)).String()
+	tele.PublishCmd.Icon = tele.PublishCmd.Flag("icon", "Path to the application icon image or its URL").String()
+	tele.PublishCmd.Readme = tele.PublishCmd.Flag("readme", "Path to the application readme in Markdown format").String()
+	tele.PublishCmd.UpgradeFrom = tele.PublishCmd.Flag("upgrade-from", "Minimum application version that can be upgraded to this version directly").String()
+	tele.PublishCmd.ReleaseNotes = tele.PublishCmd.Flag("release-notes", "Description of the changes in this version").String()
+	tele.PublishCmd.OpsCenterURL = tele.PublishCmd.Flag("ops-url", "Ops Center to publish to, defaults to the current Ops Center").String()
+
+	tele.UnpublishCmd.CmdClause = app.Command("unpublish", "Remove an application from the Ops Center application catalog")
+	tele.UnpublishCmd.App = tele.UnpublishCmd.Arg("app", "Application to remove from the catalog: <name>:<version> or <repository>/<name>:<version>").Required().String()
+	tele.UnpublishCmd.OpsCenterURL = tele.UnpublishCmd.Flag("ops-url", "Ops Center to remove the application from, defaults to the current Ops Center").String()
 
 	return tele
 }
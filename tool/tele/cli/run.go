@@ -22,7 +22,14 @@ import (
 	"os"
 
 	"github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/catalog"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/tool/common"
 
 	teleutils "github.com/gravitational/teleport/lib/utils"
 	"github.com/gravitational/trace"
@@ -40,25 +47,51 @@ func Run(tele Application) error {
 	}
 
 	trace.SetDebug(*tele.Debug)
-	if *tele.Debug {
-		teleutils.InitLogger(teleutils.LoggingForDaemon, logrus.DebugLevel)
-	} else {
-		teleutils.InitLogger(teleutils.LoggingForCLI, logrus.InfoLevel)
+	initLogger(*tele.Debug, *tele.Quiet)
+
+	if err := setProxy(tele, cmd); err != nil {
+		return trace.Wrap(err)
+	}
+
+	// commands that write files accept the path of the file with
+	// the output flag, all others only accept output formats
+	switch cmd {
+	case tele.BuildCmd.FullCommand(),
+		tele.PullCmd.FullCommand(),
+		tele.ExportCmd.FullCommand():
+	default:
+		if err := tele.Output.Check(); err != nil {
+			return trace.Wrap(err)
+		}
 	}
 
 	switch cmd {
 	case tele.VersionCmd.FullCommand():
-		return printVersion(*tele.VersionCmd.Output)
+		return printVersion(tele.Output.Format)
 	case tele.BuildCmd.FullCommand():
 		return build(context.Background(), BuildParameters{
 			StateDir:         *tele.StateDir,
 			ManifestPath:     *tele.BuildCmd.ManifestPath,
-			OutPath:          *tele.BuildCmd.OutFile,
+			OutPath:          tele.Output.Path(*tele.BuildCmd.OutFile),
 			Overwrite:        *tele.BuildCmd.Overwrite,
 			Repository:       *tele.BuildCmd.Repository,
 			SkipVersionCheck: *tele.BuildCmd.SkipVersionCheck,
-			Silent:           *tele.BuildCmd.Quiet,
+			Silent:           *tele.Quiet,
 			Insecure:         *tele.Insecure,
+			CacheSize:        *tele.BuildCmd.CacheSize,
+			NoCache:          *tele.BuildCmd.NoCache,
+			Scan:             *tele.BuildCmd.Scan,
+			ScannerPath:      *tele.BuildCmd.ScannerPath,
+			ScanReport:       *tele.BuildCmd.ScanReport,
+			ScanReportFormat: constants.Format(*tele.BuildCmd.ScanReportFormat),
+			ScanFailOn:       *tele.BuildCmd.ScanFailOn,
+			SigningKeyPath:   *tele.BuildCmd.SigningKey,
+			DeltaFrom:        *tele.BuildCmd.DeltaFrom,
+			UpgradeVia:       *tele.BuildCmd.UpgradeVia,
+			Offline:          *tele.BuildCmd.Offline,
+			ValueFiles:       *tele.BuildCmd.ValueFiles,
+			Values:           *tele.BuildCmd.Values,
+			FIPS:             *tele.BuildCmd.FIPS,
 		}, service.VendorRequest{
 			PackageName:            *tele.BuildCmd.Name,
 			PackageVersion:         *tele.BuildCmd.Version,
@@ -69,6 +102,24 @@ func Run(tele Application) error {
 			Parallel:               *tele.BuildCmd.Parallel,
 			VendorRuntime:          true,
 		})
+	case tele.ValidateCmd.FullCommand():
+		return validate(ValidateParameters{
+			ManifestPath:    *tele.ValidateCmd.ManifestPath,
+			Patterns:        *tele.ValidateCmd.VendorPatterns,
+			IgnorePatterns:  *tele.ValidateCmd.VendorIgnorePatterns,
+			LocalRegistries: *tele.ValidateCmd.LocalRegistries,
+			Silent:          *tele.Quiet,
+		})
+	case tele.CacheClearCmd.FullCommand():
+		return clearCache(*tele.Quiet)
+	case tele.KeygenCmd.FullCommand():
+		return generateSigningKey(*tele.KeygenCmd.Path, *tele.KeygenCmd.Overwrite)
+	case tele.ExportCmd.FullCommand():
+		return export(context.Background(),
+			*tele.ExportCmd.Path,
+			*tele.ExportCmd.Format,
+			tele.Output.Path(*tele.ExportCmd.OutputDir),
+			*tele.Quiet)
 	}
 
 	keystoreDir := *tele.StateDir
@@ -95,14 +146,87 @@ func Run(tele Application) error {
 	case tele.PullCmd.FullCommand():
 		return pull(*env,
 			*tele.PullCmd.App,
-			*tele.PullCmd.OutFile,
+			tele.Output.Path(*tele.PullCmd.OutFile),
 			*tele.PullCmd.Force,
-			*tele.PullCmd.Quiet)
+			*tele.Quiet,
+			keystoreDir != "")
 	case tele.ListCmd.FullCommand():
-		return list(*env,
-			*tele.ListCmd.All,
-			*tele.ListCmd.Format)
+		return list(*env, *tele.ListCmd.Hub, *tele.ListCmd.OpsCenterURL, *tele.ListCmd.Channel, catalog.ListRequest{
+			All:    *tele.ListCmd.All,
+			Quiet:  *tele.Quiet,
+			Format: tele.Output.Format,
+			Filter: catalog.Filter{
+				Repository: *tele.ListCmd.Repository,
+				Name:       *tele.ListCmd.Name,
+				Version:    *tele.ListCmd.Version,
+				Labels:     utils.ParseLabels(*tele.ListCmd.Selector),
+			},
+		})
+	case tele.ClustersCmd.FullCommand():
+		return listClusters(*env, *tele.ClustersCmd.OpsCenterURL,
+			utils.ParseLabels(*tele.ClustersCmd.Clusters), tele.Output.Format)
+	case tele.UpgradeCmd.FullCommand():
+		return upgradeClusters(context.Background(), *env, *tele.UpgradeCmd.OpsCenterURL,
+			ops.CreateFleetUpgradeRequest{
+				AccountID:   defaults.SystemAccountID,
+				App:         *tele.UpgradeCmd.App,
+				Selector:    utils.ParseLabels(*tele.UpgradeCmd.Clusters),
+				Canaries:    *tele.UpgradeCmd.Canaries,
+				Concurrency: *tele.UpgradeCmd.Concurrency,
+				MaxFailures: *tele.UpgradeCmd.MaxFailures,
+			})
+	case tele.CancelUpgradeCmd.FullCommand():
+		return cancelUpgrade(context.Background(), *env, *tele.CancelUpgradeCmd.OpsCenterURL,
+			ops.FleetUpgradeKey{
+				AccountID: defaults.SystemAccountID,
+				ID:        *tele.CancelUpgradeCmd.ID,
+			})
+	case tele.PublishCmd.FullCommand():
+		return publish(*env, *tele.PublishCmd.OpsCenterURL, publishRequest{
+			App:        *tele.PublishCmd.App,
+			Channel:    *tele.PublishCmd.Channel,
+			IconPath:   *tele.PublishCmd.Icon,
+			ReadmePath: *tele.PublishCmd.Readme,
+			Upgrade: storage.CatalogUpgrade{
+				MinVersion:   *tele.PublishCmd.UpgradeFrom,
+				ReleaseNotes: *tele.PublishCmd.ReleaseNotes,
+			},
+		})
+	case tele.UnpublishCmd.FullCommand():
+		return unpublish(*env, *tele.UnpublishCmd.OpsCenterURL, *tele.UnpublishCmd.App)
 	}
 
 	return trace.NotFound("unknown command %v", cmd)
 }
+
+// setProxy exports the HTTP proxy settings of the specified command
+// into the environment so they are honored by all HTTP clients and
+// inherited by child processes
+func setProxy(tele Application, cmd string) error {
+	var flags common.ProxyFlags
+	switch cmd {
+	case tele.BuildCmd.FullCommand():
+		flags = tele.BuildCmd.Proxy
+	case tele.PullCmd.FullCommand():
+		flags = tele.PullCmd.Proxy
+	case tele.ListCmd.FullCommand():
+		flags = tele.ListCmd.Proxy
+	default:
+		return nil
+	}
+	return trace.Wrap(flags.Config().Setenv())
+}
+
+// initLogger configures logging for the specified global flags.
+// In quiet mode only errors are logged so that the command output
+// can be consumed by scripts
+func initLogger(debug, quiet bool) {
+	switch {
+	case debug:
+		teleutils.InitLogger(teleutils.LoggingForDaemon, logrus.DebugLevel)
+	case quiet:
+		teleutils.InitLogger(teleutils.LoggingForCLI, logrus.ErrorLevel)
+	default:
+		teleutils.InitLogger(teleutils.LoggingForCLI, logrus.InfoLevel)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// upgradeClusters starts upgrading the remote clusters connected to the
+// Ops Center and follows the upgrade progress until it finishes
+func upgradeClusters(ctx context.Context, env localenv.LocalEnvironment, opsURL string, req ops.CreateFleetUpgradeRequest) error {
+	locator, err := loc.MakeLocator(req.App)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if locator.Version == loc.LatestVersion {
+		return trace.BadParameter("please specify the application version to upgrade to, e.g. %v:1.2.3",
+			locator.Name)
+	}
+	req.App = locator.String()
+	operator, err := env.OperatorService(opsURL)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	upgrade, err := operator.CreateFleetUpgrade(ctx, req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Upgrading %v clusters to %v, upgrade ID %v.\n",
+		len(upgrade.Clusters), upgrade.App, upgrade.ID)
+	env.Printf("Interrupting this command does not stop the upgrade, "+
+		"use 'tele cancel-upgrade %v' to cancel it.\n", upgrade.ID)
+	states := make(map[string]string)
+	ticker := time.NewTicker(defaults.FleetUpgradePollInterval)
+	defer ticker.Stop()
+	for {
+		for _, cluster := range upgrade.Clusters {
+			if states[cluster.Name] == cluster.State {
+				continue
+			}
+			states[cluster.Name] = cluster.State
+			if cluster.Error != "" {
+				env.Printf("%v: %v (%v)\n", cluster.Name, cluster.State, cluster.Error)
+			} else {
+				env.Printf("%v: %v\n", cluster.Name, cluster.State)
+			}
+		}
+		switch upgrade.State {
+		case storage.FleetUpgradeStateCompleted:
+			if failures := upgrade.Failures(); failures != 0 {
+				return trace.BadParameter("%v clusters failed to upgrade", failures)
+			}
+			env.Println("All clusters have been upgraded.")
+			return nil
+		case storage.FleetUpgradeStateHalted:
+			return trace.BadParameter("upgrade halted after %v clusters failed to upgrade",
+				upgrade.Failures())
+		case storage.FleetUpgradeStateCanceled:
+			return trace.BadParameter("upgrade has been canceled")
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return trace.Wrap(ctx.Err())
+		}
+		upgrade, err = operator.GetFleetUpgrade(ctx, ops.FleetUpgradeKey{
+			AccountID: req.AccountID,
+			ID:        upgrade.ID,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+}
+
+// cancelUpgrade cancels the upgrade of remote clusters specified with key.
+//
+// The clusters that have not started upgrading are skipped
+func cancelUpgrade(ctx context.Context, env localenv.LocalEnvironment, opsURL string, key ops.FleetUpgradeKey) error {
+	operator, err := env.OperatorService(opsURL)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := operator.CancelFleetUpgrade(ctx, key); err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Upgrade %v has been canceled.\n", key.ID)
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/app/resources"
+	"github.com/gravitational/gravity/lib/app/service"
+
+	"github.com/gravitational/trace"
+)
+
+// ValidateParameters represents the arguments provided for validating an application
+type ValidateParameters struct {
+	// ManifestPath holds the path to the application manifest
+	ManifestPath string
+	// Patterns specifies file patterns to search for resource files
+	Patterns []string
+	// IgnorePatterns specifies file patterns to exclude from search
+	IgnorePatterns []string
+	// LocalRegistries lists registries that do not require image pull secrets
+	LocalRegistries []string
+	// Silent suppresses informational output
+	Silent bool
+}
+
+// validate inspects resources of the application in the manifest directory
+// and reports problems that are likely to prevent it from installing
+func validate(params ValidateParameters) error {
+	resourceFiles, chartResources, err := service.ResourcesFromPath(
+		filepath.Dir(params.ManifestPath), params.Patterns, params.IgnorePatterns)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	objects := append(resourceFiles.Objects(), chartResources.Objects()...)
+	findings := resources.Validate(objects,
+		resources.ValidateImagePullSecrets(params.LocalRegistries...),
+		resources.ValidateServiceSelectors(),
+		resources.ValidateLimits())
+	if len(findings) == 0 {
+		if !params.Silent {
+			fmt.Println("No problems found.")
+		}
+		return nil
+	}
+	for _, finding := range findings {
+		fmt.Printf("* %v\n", finding)
+	}
+	return trace.BadParameter("found %v potential problem(s)", len(findings))
+}
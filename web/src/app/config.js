@@ -213,6 +213,7 @@ let cfg = {
     // operations
     operationPath: '/portalapi/v1/sites/:siteId/operations(/:opId)',
     operationProgressPath: '/portalapi/v1/sites/:siteId/operations/:opId/progress',
+    operationPhaseLogsPath: '/portalapi/v1/sites/:siteId/operations/:opId/logs/phases?phase=:phaseId',
     operationAgentPath: '/portalapi/v1/sites/:siteId/operations/:opId/agent',
     operationStartPath: '/portalapi/v1/sites/:siteId/operations/:opId/start',
     operationPrecheckPath: '/portalapi/v1/sites/:siteId/operations/:opId/prechecks',
@@ -246,6 +247,7 @@ let cfg = {
     accountDeleteInvitePath: '/portalapi/v1/accounts/existing/invites/:id',
     userStatusPath: '/portalapi/v1/user/status',
     userContextPath: '/portalapi/v1/user/context',
+    accountsPath: '/portalapi/v1/hub/accounts(/:accountId)',
 
     // terminal
     ttyWsAddr: ':fqdm/proxy/v1/webapi/sites/:cluster/connect?access_token=:token&params=:params',
@@ -443,6 +445,10 @@ let cfg = {
     return formatPattern(cfg.api.operationProgressPath, {siteId, opId});
   },
 
+  getOperationPhaseLogsUrl(siteId, opId, phaseId){
+    return formatPattern(cfg.api.operationPhaseLogsPath, {siteId, opId, phaseId});
+  },
+
   getOperationStartUrl(siteId, opId){
     return formatPattern(cfg.api.operationStartPath, {siteId, opId});
   },
@@ -487,6 +493,10 @@ let cfg = {
     return formatPattern(cfg.api.checkDomainNamePath, {domainName})
   },
 
+  getAccountsUrl(accountId){
+    return formatPattern(cfg.api.accountsPath, {accountId});
+  },
+
   getNodesUrl(id){
     return formatPattern(cfg.api.siteNodesPath, {id});
   },
@@ -27,3 +27,8 @@ export function fetchOpProgress(siteId, opId){
   });
 }
 
+export function fetchOpPhaseLogs(siteId, opId, phaseId){
+  let url = cfg.getOperationPhaseLogsUrl(siteId, opId, phaseId);
+  return api.get(url);
+}
+
@@ -170,6 +170,11 @@ describe('flux/user/actions', () => {
         authType: 'local',
         accountId: '111' 
       },
+      account: {
+        id: '111',
+        org: 'example.com',
+        system: false
+      },
       userAcl: {
         authConnectors: {
           connect: false,
@@ -191,7 +196,11 @@ describe('flux/user/actions', () => {
       
       actions.fetchUserContext();
       expect(api.get).toHaveBeenCalledWith(cfg.api.userContextPath);
-      expect(actualUser).toEqual(userContext.user);
+      expect(actualUser).toEqual({
+        ...userContext.user,
+        accountOrg: 'example.com',
+        isSystemAccount: false
+      });
       expect(actualUserAcl.authConnectors).toEqual(userContext.userAcl.authConnectors);
       expect(cfg.getServerVersion()).toEqual(userContext.serverVersion);
     })
@@ -63,7 +63,11 @@ export function loginWithSso(providerName, redirectUrl) {
 export function fetchUserContext(){
   return api.get(cfg.api.userContextPath).done(json=>{      
     cfg.setServerVersion(json.serverVersion);
-    reactor.dispatch(USER_RECEIVE_DATA, json.user);
+    reactor.dispatch(USER_RECEIVE_DATA, {
+      ...json.user,
+      accountOrg: json.account ? json.account.org : '',
+      isSystemAccount: json.account ? json.account.system : true
+    });
     reactor.dispatch(USERACL_RECEIVE, json.userAcl);
   })
   .fail(err => {
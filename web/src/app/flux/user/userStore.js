@@ -24,7 +24,9 @@ class UserRec extends Record({
   email: '',
   name: '',
   authType: '',
-  accountId: ''  
+  accountId: '',
+  accountOrg: '',
+  isSystemAccount: true
 }){
 
   constructor(props){    